@@ -0,0 +1,229 @@
+package keysmith
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+)
+
+const (
+	// DefaultAdaptiveWindow is the window over which a key's error ratio is
+	// measured.
+	DefaultAdaptiveWindow = time.Minute
+
+	// DefaultAdaptiveMinRequests is the fewest requests in the window before
+	// a key's error ratio is acted on.
+	DefaultAdaptiveMinRequests = 20
+
+	// DefaultAdaptiveErrorRatio is the error ratio above which a key's rate
+	// limit is reduced.
+	DefaultAdaptiveErrorRatio = 0.5
+
+	// DefaultAdaptivePenalty is the factor a penalized key's rate limit is
+	// multiplied by.
+	DefaultAdaptivePenalty = 0.1
+
+	// DefaultAdaptiveCooldown is the least time a penalty lasts.
+	DefaultAdaptiveCooldown = 5 * time.Minute
+
+	// maxTrackedAdaptiveKeys bounds the tracker's memory.
+	maxTrackedAdaptiveKeys = 10_000
+)
+
+// AdaptiveLimiting configures [WithAdaptiveLimiting]. Zero fields use the
+// defaults.
+type AdaptiveLimiting struct {
+	// Window is the sliding window over which each key's error ratio is
+	// measured. Defaults to [DefaultAdaptiveWindow].
+	Window time.Duration
+
+	// MinRequests is the fewest requests in the window before the error
+	// ratio is acted on, so that a handful of failures cannot penalize a
+	// quiet key. Defaults to [DefaultAdaptiveMinRequests].
+	MinRequests int
+
+	// ErrorRatio is the fraction of failed requests, above 0 and at most 1,
+	// over which a key is penalized. Defaults to [DefaultAdaptiveErrorRatio].
+	ErrorRatio float64
+
+	// Penalty, above 0 and below 1, multiplies a penalized key's rate limit.
+	// Defaults to [DefaultAdaptivePenalty].
+	Penalty float64
+
+	// MinLimit is the floor of a penalized rate limit. A penalized limit is
+	// never below 1. Defaults to 1.
+	MinLimit int
+
+	// Cooldown is the least time a penalty lasts. It restarts whenever the
+	// key is checked with its error ratio still over the threshold.
+	// Defaults to [DefaultAdaptiveCooldown].
+	Cooldown time.Duration
+
+	// IsError reports whether a recorded response status counts as a
+	// failure. Defaults to 401 and 403.
+	IsError func(status int) bool
+}
+
+func (c *AdaptiveLimiting) validate() error {
+	switch {
+	case c.Window < 0, c.MinRequests < 0, c.MinLimit < 0, c.Cooldown < 0:
+		return fmt.Errorf("%w: window, min requests, min limit, and cooldown must not be negative", ErrInvalidAdaptiveLimiting)
+	case c.ErrorRatio < 0 || c.ErrorRatio > 1:
+		return fmt.Errorf("%w: error ratio %v is not between 0 and 1", ErrInvalidAdaptiveLimiting, c.ErrorRatio)
+	case c.Penalty < 0 || c.Penalty >= 1:
+		return fmt.Errorf("%w: penalty %v is not between 0 and 1", ErrInvalidAdaptiveLimiting, c.Penalty)
+	}
+	return nil
+}
+
+func (c *AdaptiveLimiting) withDefaults() AdaptiveLimiting {
+	out := *c
+	if out.Window == 0 {
+		out.Window = DefaultAdaptiveWindow
+	}
+	if out.MinRequests == 0 {
+		out.MinRequests = DefaultAdaptiveMinRequests
+	}
+	if out.ErrorRatio == 0 {
+		out.ErrorRatio = DefaultAdaptiveErrorRatio
+	}
+	if out.Penalty == 0 {
+		out.Penalty = DefaultAdaptivePenalty
+	}
+	out.MinLimit = max(out.MinLimit, 1)
+	if out.Cooldown == 0 {
+		out.Cooldown = DefaultAdaptiveCooldown
+	}
+	if out.IsError == nil {
+		out.IsError = defaultAdaptiveIsError
+	}
+	return out
+}
+
+func defaultAdaptiveIsError(status int) bool {
+	return status == http.StatusUnauthorized || status == http.StatusForbidden
+}
+
+// adaptiveCounts holds the requests and failures seen in one window.
+type adaptiveCounts struct{ requests, errors int }
+
+type adaptiveEntry struct {
+	// start is the beginning of the window cur counts; prev counts the
+	// window before it.
+	start     time.Time
+	cur, prev adaptiveCounts
+
+	// penalized is set from since until at least until.
+	penalized    bool
+	since, until time.Time
+}
+
+// roll moves the entry's windows forward to the one containing now.
+func (ent *adaptiveEntry) roll(now time.Time, window time.Duration) {
+	start := now.Truncate(window)
+	switch {
+	case start.Equal(ent.start):
+	case start.Equal(ent.start.Add(window)):
+		ent.prev, ent.cur = ent.cur, adaptiveCounts{}
+	default:
+		ent.prev, ent.cur = adaptiveCounts{}, adaptiveCounts{}
+	}
+	ent.start = start
+}
+
+// ratio estimates the error ratio over the sliding window ending at now,
+// weighting the previous window by how much of it the sliding window still
+// covers.
+func (ent *adaptiveEntry) ratio(now time.Time, window time.Duration) (ratio, requests float64) {
+	weight := 1 - float64(now.Sub(ent.start))/float64(window)
+	requests = float64(ent.cur.requests) + weight*float64(ent.prev.requests)
+	if requests == 0 {
+		return 0, 0
+	}
+	errs := float64(ent.cur.errors) + weight*float64(ent.prev.errors)
+	return errs / requests, requests
+}
+
+// adaptiveTracker keeps in-memory error ratios per key and the penalties
+// they have caused.
+type adaptiveTracker struct {
+	cfg AdaptiveLimiting
+
+	mu      sync.Mutex
+	entries map[id.KeyID]*adaptiveEntry
+}
+
+func newAdaptiveTracker(cfg AdaptiveLimiting) *adaptiveTracker {
+	return &adaptiveTracker{cfg: cfg.withDefaults(), entries: make(map[id.KeyID]*adaptiveEntry)}
+}
+
+// observe counts a request made with kid that ended with status.
+func (t *adaptiveTracker) observe(kid id.KeyID, status int, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ent, ok := t.entries[kid]
+	if !ok {
+		if len(t.entries) >= maxTrackedAdaptiveKeys {
+			t.pruneLocked(now)
+			if len(t.entries) >= maxTrackedAdaptiveKeys {
+				return
+			}
+		}
+		ent = &adaptiveEntry{}
+		t.entries[kid] = ent
+	}
+	ent.roll(now, t.cfg.Window)
+	ent.cur.requests++
+	if t.cfg.IsError(status) {
+		ent.cur.errors++
+	}
+}
+
+// check returns the penalty in effect for a key with the given rate limit,
+// or nil. started is set when the penalty begins with this call.
+func (t *adaptiveTracker) check(kid id.KeyID, limit int, now time.Time) (p *key.AdaptivePenalty, started bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ent, ok := t.entries[kid]
+	if !ok {
+		return nil, false
+	}
+	ent.roll(now, t.cfg.Window)
+	ratio, requests := ent.ratio(now, t.cfg.Window)
+
+	if requests >= float64(t.cfg.MinRequests) && ratio > t.cfg.ErrorRatio {
+		if !ent.penalized {
+			ent.penalized, ent.since, started = true, now, true
+		}
+		ent.until = now.Add(t.cfg.Cooldown)
+	} else if ent.penalized && !now.Before(ent.until) {
+		ent.penalized = false
+	}
+	if !ent.penalized {
+		return nil, false
+	}
+	return &key.AdaptivePenalty{
+		ErrorRatio: ratio,
+		Requests:   int(requests),
+		BaseLimit:  limit,
+		Limit:      min(limit, max(int(float64(limit)*t.cfg.Penalty), t.cfg.MinLimit)),
+		Since:      ent.since,
+		Until:      ent.until,
+	}, started
+}
+
+// pruneLocked drops keys that are not penalized and have had no requests
+// for two windows. t.mu must be held.
+func (t *adaptiveTracker) pruneLocked(now time.Time) {
+	for kid, ent := range t.entries {
+		if !ent.penalized && now.Sub(ent.start) >= 2*t.cfg.Window {
+			delete(t.entries, kid)
+		}
+	}
+}
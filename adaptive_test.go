@@ -0,0 +1,204 @@
+package keysmith_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keysmithtest"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/store/memory"
+	"github.com/xraph/keysmith/usage"
+)
+
+// newAdaptiveEngine returns an engine with adaptive limiting acting on ten
+// requests, and a policy allowing 100 validations a minute.
+func newAdaptiveEngine(t *testing.T, cfg keysmith.AdaptiveLimiting) (*keysmith.Engine, *fakeClock, *keysmithtest.Recorder, *policy.Policy) {
+	t.Helper()
+	clock := &fakeClock{t: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)}
+	rec := keysmithtest.NewRecorder()
+	if cfg.MinRequests == 0 {
+		cfg.MinRequests = 10
+	}
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithRateLimiter(newCountingLimiter()),
+		keysmith.WithClock(clock.Now),
+		keysmith.WithExtension(rec),
+		keysmith.WithAdaptiveLimiting(cfg),
+	)
+	require.NoError(t, err)
+
+	pol := &policy.Policy{Name: "Per Key", RateLimit: 100, RateLimitWindow: time.Minute}
+	require.NoError(t, eng.CreatePolicy(testCtx(), pol))
+	return eng, clock, rec, pol
+}
+
+// sendStatuses records n requests made with kid that ended with status.
+func sendStatuses(t *testing.T, eng *keysmith.Engine, kid id.KeyID, status, n int) {
+	t.Helper()
+	for range n {
+		require.NoError(t, eng.RecordUsage(testCtx(), &usage.Record{
+			KeyID:      kid,
+			Endpoint:   "/v1/orders",
+			Method:     "GET",
+			StatusCode: status,
+		}))
+	}
+}
+
+func validateLimit(t *testing.T, eng *keysmith.Engine, raw string) *keysmith.RateLimitInfo {
+	t.Helper()
+	vr, err := eng.ValidateKey(testCtx(), raw)
+	require.NoError(t, err)
+	require.NotNil(t, vr.RateLimit)
+	return vr.RateLimit
+}
+
+func TestAdaptiveLimiting_ErrorStorm(t *testing.T) {
+	eng, clock, rec, pol := newAdaptiveEngine(t, keysmith.AdaptiveLimiting{})
+	storm, healthy := createLimitedKeyResult(t, eng, pol), createLimitedKeyResult(t, eng, pol)
+
+	assert.Nil(t, validateLimit(t, eng, storm.RawKey).Penalty, "no traffic yet")
+
+	// A client in a retry loop gets nothing but 401s; another key is fine.
+	sendStatuses(t, eng, storm.Key.ID, 401, 30)
+	sendStatuses(t, eng, healthy.Key.ID, 200, 30)
+	sendStatuses(t, eng, healthy.Key.ID, 403, 5)
+
+	rl := validateLimit(t, eng, storm.RawKey)
+	require.NotNil(t, rl.Penalty)
+	assert.Equal(t, 10, rl.Limit, "100 times the default penalty of 0.1")
+	assert.Equal(t, 100, rl.Penalty.BaseLimit)
+	assert.Equal(t, 1.0, rl.Penalty.ErrorRatio)
+	assert.Equal(t, 30, rl.Penalty.Requests)
+	assert.Equal(t, clock.Now().Add(keysmith.DefaultAdaptiveCooldown), rl.Penalty.Until)
+
+	events := rec.Filter("KeyAdaptivelyLimited")
+	require.Len(t, events, 1)
+	assert.Equal(t, storm.Key.ID, events[0].Key.ID)
+	assert.Equal(t, 10, events[0].Penalty.Limit)
+	assert.Equal(t, plugin.TriggerValidation, events[0].Meta.Trigger)
+	assert.Equal(t, plugin.ReasonErrorRate, events[0].Meta.ReasonCode)
+
+	validateLimit(t, eng, storm.RawKey)
+	assert.Equal(t, 1, rec.Count("KeyAdaptivelyLimited"), "the hook fires when the penalty starts")
+
+	rl = validateLimit(t, eng, healthy.RawKey)
+	assert.Nil(t, rl.Penalty, "a low error ratio on another key is not penalized")
+	assert.Equal(t, 100, rl.Limit)
+
+	// Two minutes on the errors have left the window, but the cooldown has
+	// not passed.
+	clock.Set(clock.Now().Add(2 * time.Minute))
+	assert.NotNil(t, validateLimit(t, eng, storm.RawKey).Penalty)
+
+	clock.Set(clock.Now().Add(4 * time.Minute))
+	rl = validateLimit(t, eng, storm.RawKey)
+	assert.Nil(t, rl.Penalty, "recovered once the ratio normalized and the cooldown passed")
+	assert.Equal(t, 100, rl.Limit)
+}
+
+func TestAdaptiveLimiting_StillFailingAfterCooldown(t *testing.T) {
+	eng, clock, rec, pol := newAdaptiveEngine(t, keysmith.AdaptiveLimiting{Cooldown: time.Minute})
+	created := createLimitedKeyResult(t, eng, pol)
+
+	sendStatuses(t, eng, created.Key.ID, 403, 20)
+	require.NotNil(t, validateLimit(t, eng, created.RawKey).Penalty)
+
+	clock.Set(clock.Now().Add(90 * time.Second))
+	sendStatuses(t, eng, created.Key.ID, 403, 20)
+	rl := validateLimit(t, eng, created.RawKey)
+	require.NotNil(t, rl.Penalty, "the penalty lasts while the key keeps failing")
+	assert.Equal(t, clock.Now().Add(time.Minute), rl.Penalty.Until)
+	assert.Equal(t, 1, rec.Count("KeyAdaptivelyLimited"))
+}
+
+func TestAdaptiveLimiting_Thresholds(t *testing.T) {
+	for name, tc := range map[string]struct {
+		errors, ok int
+		penalized  bool
+	}{
+		"too few requests":         {errors: 9},
+		"ratio at the threshold":   {errors: 10, ok: 10},
+		"ratio over the threshold": {errors: 11, ok: 9, penalized: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			eng, _, _, pol := newAdaptiveEngine(t, keysmith.AdaptiveLimiting{})
+			created := createLimitedKeyResult(t, eng, pol)
+			sendStatuses(t, eng, created.Key.ID, 401, tc.errors)
+			sendStatuses(t, eng, created.Key.ID, 200, tc.ok)
+			assert.Equal(t, tc.penalized, validateLimit(t, eng, created.RawKey).Penalty != nil)
+		})
+	}
+}
+
+func TestAdaptiveLimiting_ReducedLimitRefuses(t *testing.T) {
+	eng, _, rec, pol := newAdaptiveEngine(t, keysmith.AdaptiveLimiting{Penalty: 0.001})
+	created := createLimitedKeyResult(t, eng, pol)
+	sendStatuses(t, eng, created.Key.ID, 401, 20)
+
+	rl := validateLimit(t, eng, created.RawKey)
+	assert.Equal(t, 1, rl.Limit, "a penalized limit never drops below 1")
+
+	_, err := eng.ValidateKey(testCtx(), created.RawKey)
+	assert.ErrorIs(t, err, keysmith.ErrRateLimited)
+	assert.Equal(t, 1, rec.Count("KeyRateLimited"))
+}
+
+func TestAdaptiveLimiting_CustomErrors(t *testing.T) {
+	eng, _, _, pol := newAdaptiveEngine(t, keysmith.AdaptiveLimiting{
+		IsError:  func(status int) bool { return status >= 500 },
+		MinLimit: 25,
+	})
+	created := createLimitedKeyResult(t, eng, pol)
+
+	sendStatuses(t, eng, created.Key.ID, 401, 20)
+	assert.Nil(t, validateLimit(t, eng, created.RawKey).Penalty)
+
+	sendStatuses(t, eng, created.Key.ID, 503, 40)
+	assert.Equal(t, 25, validateLimit(t, eng, created.RawKey).Limit)
+}
+
+func TestAdaptiveLimiting_OffByDefault(t *testing.T) {
+	eng, _, _, pol := newTenantLimitEngine(t)
+	created := createLimitedKeyResult(t, eng, pol)
+	sendStatuses(t, eng, created.Key.ID, 401, 100)
+
+	rl := validateLimit(t, eng, created.RawKey)
+	assert.Nil(t, rl.Penalty)
+	assert.Equal(t, 10, rl.Limit)
+}
+
+func TestAdaptiveLimiting_InvalidConfig(t *testing.T) {
+	for name, cfg := range map[string]keysmith.AdaptiveLimiting{
+		"negative window":  {Window: -time.Second},
+		"ratio above one":  {ErrorRatio: 1.5},
+		"penalty of one":   {Penalty: 1},
+		"negative min":     {MinLimit: -1},
+		"negative penalty": {Penalty: -0.5},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithAdaptiveLimiting(cfg))
+			assert.ErrorIs(t, err, keysmith.ErrInvalidAdaptiveLimiting)
+		})
+	}
+}
+
+func createLimitedKeyResult(t *testing.T, eng *keysmith.Engine, pol *policy.Policy) *key.CreateResult {
+	t.Helper()
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+		Name:        "Adaptive",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+		PolicyID:    &pol.ID,
+	})
+	require.NoError(t, err)
+	return created
+}
@@ -0,0 +1,293 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/xraph/forge"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/tombstone"
+	"github.com/xraph/keysmith/usage"
+)
+
+func (a *API) maintainStore(ctx forge.Context, req *MaintainStoreRequest) (*MaintenanceResponse, error) {
+	report, err := a.eng.MaintainStoreWith(ctx.Context(), store.MaintenanceOptions{
+		StatsOnly: req.StatsOnly,
+		Full:      req.Full,
+	})
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toMaintenanceResponse(report)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) getStorageReport(ctx forge.Context, req *GetStorageReportRequest) (*StorageReportResponse, error) {
+	if !systemScoped(ctx.Context()) {
+		return nil, forge.Forbidden("storage reports span tenants and require a system-scoped caller")
+	}
+	if req.Top < 0 {
+		return nil, forge.BadRequest("top must not be negative")
+	}
+	report, err := a.eng.TenantStorageReportWith(ctx.Context(), keysmith.StorageReportOptions{
+		TenantID: req.TenantID,
+		Top:      req.Top,
+	})
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toStorageReportResponse(report)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) revokeByHash(ctx forge.Context, req *RevokeByHashRequest) (*HashReportsResponse, error) {
+	if err := checkHashBatch(ctx.Context(), req.Hashes); err != nil {
+		return nil, err
+	}
+	reports, err := a.eng.RevokeByHashes(engineContext(ctx, req.DryRun), req.Hashes, req.Reason)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toHashReportsResponse(reports)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) validateHashes(ctx forge.Context, req *ValidateHashesRequest) (*HashReportsResponse, error) {
+	if err := checkHashBatch(ctx.Context(), req.Hashes); err != nil {
+		return nil, err
+	}
+	reports, err := a.eng.BulkValidateHashes(ctx.Context(), req.Hashes)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toHashReportsResponse(reports)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) listKeysByCreator(ctx forge.Context, req *ListKeysByCreatorRequest) (*KeysByCreatorResponse, error) {
+	if err := checkCrossTenant(ctx.Context()); err != nil {
+		return nil, err
+	}
+	if err := validateEnum("state", req.State); err != nil {
+		return nil, err
+	}
+	limit := a.pageLimit(ctx, req.Limit, keysmith.DefaultPageSize)
+	keys, err := a.eng.ListKeysByCreator(ctx.Context(), req.CreatedBy, keysmith.CreatorOptions{
+		TenantID: req.TenantID,
+		AppID:    req.AppID,
+		State:    key.State(req.State),
+		Limit:    limit,
+		Offset:   req.Offset,
+	})
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := &KeysByCreatorResponse{CreatedBy: req.CreatedBy, Keys: make([]*KeyResponse, len(keys)), Limit: limit}
+	for i, k := range keys {
+		resp.Keys[i] = toKeyResponse(k)
+	}
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) revokeByCreator(ctx forge.Context, req *RevokeByCreatorRequest) (*CreatorRevokeResponse, error) {
+	if err := checkCrossTenant(ctx.Context()); err != nil {
+		return nil, err
+	}
+	res, err := a.eng.BulkRevokeByCreator(engineContext(ctx, req.DryRun), req.CreatedBy, req.Reason, keysmith.CreatorOptions{
+		TenantID: req.TenantID,
+		AppID:    req.AppID,
+	})
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toCreatorRevokeResponse(req.CreatedBy, res)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) listHashTombstones(ctx forge.Context, req *ListHashTombstonesRequest) (*HashTombstonesResponse, error) {
+	limit := a.pageLimit(ctx, req.Limit, keysmith.DefaultPageSize)
+	filter := &tombstone.ListFilter{TenantID: req.TenantID, AppID: req.AppID, Reason: req.Reason}
+	counts, err := a.eng.CountHashTombstonesByReason(ctx.Context(), filter)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+	filter.Limit, filter.Offset = limit, req.Offset
+	tombstones, err := a.eng.ListHashTombstones(ctx.Context(), filter)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toHashTombstonesResponse(tombstones, counts)
+	resp.Limit = limit
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) lockdownTenant(ctx forge.Context, req *LockdownTenantRequest) (*TenantLockdownResultResponse, error) {
+	if !systemScoped(ctx.Context()) {
+		return nil, forge.Forbidden("tenant lockdowns require a system-scoped caller")
+	}
+	if req.Reason == "" {
+		return nil, forge.BadRequest("reason is required")
+	}
+	res, err := a.eng.LockdownTenant(engineContext(ctx, req.DryRun), req.TenantID, req.Reason)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toTenantLockdownResultResponse(res)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) getTenantLockdown(ctx forge.Context, req *GetTenantLockdownRequest) (*TenantLockdownStatusResponse, error) {
+	if !systemScoped(ctx.Context()) {
+		return nil, forge.Forbidden("tenant lockdowns require a system-scoped caller")
+	}
+	st, err := a.eng.LockdownStatus(ctx.Context(), req.TenantID)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toTenantLockdownStatusResponse(st)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) recoverTenant(ctx forge.Context, req *RecoverTenantRequest) (*TenantLockdownStatusResponse, error) {
+	if !systemScoped(ctx.Context()) {
+		return nil, forge.Forbidden("tenant lockdowns require a system-scoped caller")
+	}
+	if req.BatchSize < 0 {
+		return nil, forge.BadRequest("batch_size must not be negative")
+	}
+	if req.BatchInterval < 0 {
+		return nil, forge.BadRequest("batch_interval must not be negative")
+	}
+	st, err := a.eng.RecoverTenant(engineContext(ctx, req.DryRun), req.TenantID, keysmith.RecoveryOptions{
+		BatchSize:     req.BatchSize,
+		BatchInterval: time.Duration(req.BatchInterval),
+	})
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toTenantLockdownStatusResponse(st)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) getUsageRecording(ctx forge.Context, _ *GetUsageRecordingRequest) (*UsageRecordingResponse, error) {
+	resp := toUsageRecordingResponse(a.eng.UsageRecording())
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) setUsageRecording(ctx forge.Context, req *SetUsageRecordingRequest) (*UsageRecordingResponse, error) {
+	if !systemScoped(ctx.Context()) {
+		return nil, forge.Forbidden("usage recording applies to every tenant and requires a system-scoped caller")
+	}
+	p := usage.RecordingPolicy{
+		Rules:      req.Rules,
+		Default:    usage.RecordMode(req.Default),
+		SampleRate: req.SampleRate,
+	}
+	if err := a.eng.SetUsageRecording(p); err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toUsageRecordingResponse(a.eng.UsageRecording())
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) setReadOnly(ctx forge.Context, req *SetReadOnlyRequest) (*ReadOnlyResponse, error) {
+	if !systemScoped(ctx.Context()) {
+		return nil, forge.Forbidden("read-only mode applies to every tenant and requires a system-scoped caller")
+	}
+	a.eng.SetReadOnly(req.Enabled)
+
+	resp := &ReadOnlyResponse{ReadOnly: a.eng.ReadOnly(), UsageRecording: a.eng.UsageRecordingInReadOnly()}
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) getRuntimeConfig(ctx forge.Context, _ *GetRuntimeConfigRequest) (*RuntimeConfigResponse, error) {
+	if !systemScoped(ctx.Context()) {
+		return nil, forge.Forbidden("runtime configuration applies to every tenant and requires a system-scoped caller")
+	}
+	resp := toRuntimeConfigResponse(a.eng.GetRuntimeConfig())
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) updateRuntimeConfig(ctx forge.Context, req *UpdateRuntimeConfigRequest) (*RuntimeConfigResponse, error) {
+	if !systemScoped(ctx.Context()) {
+		return nil, forge.Forbidden("runtime configuration applies to every tenant and requires a system-scoped caller")
+	}
+	patch := &keysmith.RuntimeConfigPatch{
+		FailureThreshold: req.FailureThreshold,
+		UsageSampleRate:  req.UsageSampleRate,
+	}
+	for _, d := range []struct {
+		name string
+		in   *Duration
+		out  **time.Duration
+	}{
+		{"validation_cache_ttl", req.ValidationCacheTTL, &patch.ValidationCacheTTL},
+		{"endpoint_flush_interval", req.EndpointFlushInterval, &patch.EndpointFlushInterval},
+		{"capture_purge_interval", req.CapturePurgeInterval, &patch.CapturePurgeInterval},
+		{"maintenance_interval", req.MaintenanceInterval, &patch.MaintenanceInterval},
+		{"quota_forecast_interval", req.QuotaForecastInterval, &patch.QuotaForecastInterval},
+		{"failure_window", req.FailureWindow, &patch.FailureWindow},
+	} {
+		if d.in == nil {
+			continue
+		}
+		v := time.Duration(*d.in)
+		if v == 0 {
+			return nil, forge.BadRequest(fmt.Sprintf("%s: %s is not a positive duration", d.name, d.in))
+		}
+		*d.out = &v
+	}
+
+	cfg, err := a.eng.UpdateRuntimeConfig(engineContext(ctx, req.DryRun), patch)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toRuntimeConfigResponse(cfg)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+// systemScoped reports whether ctx carries neither a tenant nor an app.
+func systemScoped(ctx context.Context) bool {
+	return keysmith.TenantIDFromContext(ctx) == "" && keysmith.AppIDFromContext(ctx) == ""
+}
+
+// checkHashBatch admits only system-scoped callers, since a leaked dump spans
+// tenants, and bounds the batch before any lookup.
+// checkCrossTenant refuses tenant-scoped callers, which can filter GET
+// /v1/keys by created_by instead.
+func checkCrossTenant(ctx context.Context) error {
+	if keysmith.TenantIDFromContext(ctx) != "" {
+		return forge.Forbidden("listing keys across tenants requires an app- or system-scoped caller")
+	}
+	return nil
+}
+
+func checkHashBatch(ctx context.Context, hashes []string) error {
+	if !systemScoped(ctx) {
+		return forge.Forbidden("hash lookups require a system-scoped caller")
+	}
+	if len(hashes) == 0 {
+		return forge.BadRequest("hashes is required")
+	}
+	if len(hashes) > keysmith.MaxHashBatch {
+		return forge.BadRequest(fmt.Sprintf("at most %d hashes per request", keysmith.MaxHashBatch))
+	}
+	return nil
+}
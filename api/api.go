@@ -3,21 +3,83 @@ package api
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/xraph/forge"
 
-	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/middleware"
 )
 
 // API wires all Forge-style HTTP handlers together for the keysmith system.
 type API struct {
-	eng    *keysmith.Engine
+	eng    Engine
 	router forge.Router
+
+	// basePath and middlewareOpts describe how the API and the key
+	// middleware are mounted, for the integration guide.
+	basePath       string
+	middlewareOpts []middleware.Option
+
+	// edgeCacheTTL is how long edge caches may keep a successful header
+	// validation; zero forbids caching.
+	edgeCacheTTL time.Duration
+
+	// eventHeartbeat is how often an idle event stream sends a comment.
+	eventHeartbeat time.Duration
+
+	// deprecations are the deprecated routes by method and path.
+	deprecations map[string]RouteDeprecation
+}
+
+// MaxEdgeCacheTTL caps WithEdgeCacheTTL. A cached success outlives a
+// revocation by up to the TTL, so it stays short.
+const MaxEdgeCacheTTL = 30 * time.Second
+
+// Option configures an API.
+type Option func(*API)
+
+// WithBasePath sets the URL prefix the routes are mounted under, such as
+// "/keysmith", so the integration guide reports full URLs.
+func WithBasePath(path string) Option {
+	return func(a *API) { a.basePath = path }
+}
+
+// WithMiddlewareOptions sets the options the key middleware is built with,
+// so the integration guide lists the headers it actually reads.
+func WithMiddlewareOptions(opts ...middleware.Option) Option {
+	return func(a *API) { a.middlewareOpts = opts }
+}
+
+// WithEdgeCacheTTL lets edge platforms cache successful validations on GET
+// and HEAD /v1/keys/validate for d, by sending Cache-Control: private,
+// max-age instead of no-store. d is capped at MaxEdgeCacheTTL; zero, the
+// default, forbids caching. Failures are never cacheable.
+func WithEdgeCacheTTL(d time.Duration) Option {
+	return func(a *API) { a.edgeCacheTTL = min(max(d, 0), MaxEdgeCacheTTL) }
+}
+
+// DefaultEventHeartbeat is how often an idle event stream sends a
+// heartbeat comment. See WithEventHeartbeat.
+const DefaultEventHeartbeat = 15 * time.Second
+
+// WithEventHeartbeat sets how often GET /v1/events/stream sends a heartbeat
+// comment while no event is due, so proxies do not close an idle stream.
+// Non-positive values keep DefaultEventHeartbeat.
+func WithEventHeartbeat(d time.Duration) Option {
+	return func(a *API) {
+		if d > 0 {
+			a.eventHeartbeat = d
+		}
+	}
 }
 
 // New creates an API from a Keysmith Engine.
-func New(eng *keysmith.Engine, router forge.Router) *API {
-	return &API{eng: eng, router: router}
+func New(eng Engine, router forge.Router, opts ...Option) *API {
+	a := &API{eng: eng, router: router, eventHeartbeat: DefaultEventHeartbeat}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
 }
 
 // Handler returns the fully assembled http.Handler with all routes.
@@ -32,12 +94,23 @@ func (a *API) Handler() http.Handler {
 // RegisterRoutes registers all keysmith API routes into the given Forge router
 // with full OpenAPI metadata.
 func (a *API) RegisterRoutes(router forge.Router) {
+	if len(a.deprecations) > 0 {
+		router = &deprecationRouter{Router: router, deps: a.deprecations}
+	}
 	a.registerKeyRoutes(router)
 	a.registerPolicyRoutes(router)
 	a.registerScopeRoutes(router)
+	a.registerGroupRoutes(router)
+	a.registerQuotaPoolRoutes(router)
+	a.registerTransferRoutes(router)
 	a.registerUsageRoutes(router)
 	a.registerRotationRoutes(router)
 	a.registerValidationRoutes(router)
+	a.registerTenantRoutes(router)
+	a.registerRevocationRoutes(router)
+	a.registerEventRoutes(router)
+	a.registerDebugRoutes(router)
+	a.registerAdminRoutes(router)
 }
 
 func (a *API) registerKeyRoutes(router forge.Router) {
@@ -45,8 +118,9 @@ func (a *API) registerKeyRoutes(router forge.Router) {
 
 	_ = g.POST("/keys", a.createKey,
 		forge.WithSummary("Create API key"),
-		forge.WithDescription("Creates a new API key. The raw key is returned only once."),
+		forge.WithDescription("Creates a new API key. The raw key is returned only once. If a key in the tenant already has the request's external_ref, nothing is created and that key is returned with 200 and duplicate_of_existing set. When the tenant requires unique key names, a name another key has, ignoring case, is rejected with 409 naming that key. An inline_policy gives the key limits of its own instead of a policy_id; it is validated like a policy and deleted with the key."),
 		forge.WithOperationID("createKey"),
+		withExamples("createKey"),
 		forge.WithRequestSchema(CreateKeyRequest{}),
 		forge.WithResponseSchema(http.StatusCreated, "Created key with raw value", &KeyCreateResponse{}),
 		forge.WithErrorResponses(),
@@ -54,26 +128,89 @@ func (a *API) registerKeyRoutes(router forge.Router) {
 
 	_ = g.GET("/keys", a.listKeys,
 		forge.WithSummary("List API keys"),
-		forge.WithDescription("Returns API keys for the current tenant. Raw keys are never returned."),
+		forge.WithDescription("Returns API keys for the current tenant. Raw keys are never returned. Tenant-scoped responses carry a weak ETag; a matching If-None-Match gets 304 Not Modified."),
 		forge.WithOperationID("listKeys"),
+		withExamples("listKeys"),
 		forge.WithRequestSchema(ListKeysRequest{}),
 		forge.WithResponseSchema(http.StatusOK, "Key list", []*KeyResponse{}),
 		forge.WithErrorResponses(),
 	)
 
+	_ = g.GET("/keys/overview", a.keyOverview,
+		forge.WithSummary("Get key overview"),
+		forge.WithDescription("Counts the keys the caller may see by state and environment, the active keys expiring within each of expiring_within_days, the active keys never used, and the keys created within created_within_days, in one store query."),
+		forge.WithOperationID("getKeyOverview"),
+		withExamples("getKeyOverview"),
+		forge.WithRequestSchema(KeyOverviewRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Key counts by lifecycle", &KeyOverviewResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.GET("/search", a.search,
+		forge.WithSummary("Search"),
+		forge.WithDescription("Finds the caller's keys whose name, description, or metadata values mention every word and double-quoted phrase of q, grouped by resource type and ranked best first: a match in the name outranks one in the description, which outranks one in a metadata value. Words match whole and ignoring case, and a word with punctuation such as acme-mobile matches as a phrase. Each match lists the fields it was found in and a snippet. Every store backend returns the same results. A search is confined to one tenant: the caller's, or tenant_id for an unscoped caller."),
+		forge.WithOperationID("search"),
+		withExamples("search"),
+		forge.WithRequestSchema(SearchRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Search results", &SearchResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.GET("/keys/duplicate-names", a.listDuplicateKeyNames,
+		forge.WithSummary("List duplicate key names"),
+		forge.WithDescription("Returns each name shared by more than one of the caller's keys, ignoring case, with the keys that have it, oldest first. Use it to rename keys before turning on the tenant's unique_key_names setting, which leaves existing duplicates alone."),
+		forge.WithOperationID("listDuplicateKeyNames"),
+		withExamples("listDuplicateKeyNames"),
+		forge.WithRequestSchema(ListDuplicateKeyNamesRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Duplicate key names", []*KeyNameDuplicateResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.GET("/keys/live/top", a.listLiveTopKeys,
+		forge.WithSummary("List busiest keys"),
+		forge.WithDescription("Returns up to n of the caller's keys with the highest live validation rate, busiest first by QPS and then by 1-minute rate. Rates come from an in-memory tracker on the serving instance; they are not shared between instances and start over on restart. Responds 501 when live stats are not enabled."),
+		forge.WithOperationID("listLiveTopKeys"),
+		withExamples("listLiveTopKeys"),
+		forge.WithRequestSchema(ListLiveTopKeysRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Busiest keys", []*LiveKeyStatsResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.POST("/keys/revoke-by-labels", a.revokeByLabels,
+		forge.WithSummary("Revoke keys by labels"),
+		forge.WithDescription("Revokes every key in the current tenant whose labels match label_selector and reports the revoked key IDs. Re-running is safe. Accepts dry_run to report the keys first."),
+		forge.WithOperationID("revokeByLabels"),
+		withExamples("revokeByLabels"),
+		forge.WithRequestSchema(RevokeByLabelsRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Revoked keys", &LabelRevokeResponse{}),
+		forge.WithErrorResponses(),
+	)
+
 	_ = g.GET("/keys/:keyId", a.getKey,
 		forge.WithSummary("Get API key"),
-		forge.WithDescription("Returns details of a specific API key."),
+		forge.WithDescription("Returns details of a specific API key. Tenant-scoped responses carry a weak ETag; a matching If-None-Match gets 304 Not Modified."),
 		forge.WithOperationID("getKey"),
+		withExamples("getKey"),
 		forge.WithRequestSchema(GetKeyRequest{}),
 		forge.WithResponseSchema(http.StatusOK, "Key details", &KeyResponse{}),
 		forge.WithErrorResponses(),
 	)
 
+	_ = g.PATCH("/keys/:keyId", a.updateKey,
+		forge.WithSummary("Update API key"),
+		forge.WithDescription("Updates a key's name, description, metadata, labels, or allowed origins. When the tenant requires unique key names, a name another key has, ignoring case, is rejected with 409 naming that key."),
+		forge.WithOperationID("updateKey"),
+		withExamples("updateKey"),
+		forge.WithRequestSchema(UpdateKeyRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Updated key", &KeyResponse{}),
+		forge.WithErrorResponses(),
+	)
+
 	_ = g.DELETE("/keys/:keyId", a.deleteKey,
 		forge.WithSummary("Delete API key"),
 		forge.WithDescription("Permanently deletes an API key."),
 		forge.WithOperationID("deleteKey"),
+		withExamples("deleteKey"),
 		forge.WithRequestSchema(DeleteKeyRequest{}),
 		forge.WithNoContentResponse(),
 		forge.WithErrorResponses(),
@@ -83,6 +220,7 @@ func (a *API) registerKeyRoutes(router forge.Router) {
 		forge.WithSummary("Rotate API key"),
 		forge.WithDescription("Rotates an API key, returning the new raw key."),
 		forge.WithOperationID("rotateKey"),
+		withExamples("rotateKey"),
 		forge.WithRequestSchema(RotateKeyRequest{}),
 		forge.WithResponseSchema(http.StatusOK, "Rotated key with new raw value", &KeyCreateResponse{}),
 		forge.WithErrorResponses(),
@@ -92,15 +230,27 @@ func (a *API) registerKeyRoutes(router forge.Router) {
 		forge.WithSummary("Revoke API key"),
 		forge.WithDescription("Permanently revokes an API key."),
 		forge.WithOperationID("revokeKey"),
+		withExamples("revokeKey"),
 		forge.WithRequestSchema(RevokeKeyRequest{}),
 		forge.WithNoContentResponse(),
 		forge.WithErrorResponses(),
 	)
 
+	_ = g.POST("/keys/:keyId/compromise", a.reportCompromise,
+		forge.WithSummary("Report compromised key"),
+		forge.WithDescription("Revokes or rotates a leaked key with no grace period, records the report, and notifies plugins."),
+		forge.WithOperationID("reportKeyCompromise"),
+		withExamples("reportKeyCompromise"),
+		forge.WithRequestSchema(ReportCompromiseRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Compromise remediation outcome", &CompromiseResponse{}),
+		forge.WithErrorResponses(),
+	)
+
 	_ = g.POST("/keys/:keyId/suspend", a.suspendKey,
 		forge.WithSummary("Suspend API key"),
 		forge.WithDescription("Temporarily suspends an API key."),
 		forge.WithOperationID("suspendKey"),
+		withExamples("suspendKey"),
 		forge.WithRequestSchema(SuspendKeyRequest{}),
 		forge.WithNoContentResponse(),
 		forge.WithErrorResponses(),
@@ -110,10 +260,41 @@ func (a *API) registerKeyRoutes(router forge.Router) {
 		forge.WithSummary("Reactivate API key"),
 		forge.WithDescription("Reactivates a suspended API key."),
 		forge.WithOperationID("reactivateKey"),
+		withExamples("reactivateKey"),
 		forge.WithRequestSchema(ReactivateKeyRequest{}),
 		forge.WithNoContentResponse(),
 		forge.WithErrorResponses(),
 	)
+
+	_ = g.GET("/keys/:keyId/contacts", a.getKeyContacts,
+		forge.WithSummary("Get key contacts"),
+		forge.WithDescription("Returns where lifecycle notifications about a key go: the key's own contacts, the tenant's defaults, and the effective list, which is the key's contacts when it has any and the tenant defaults otherwise."),
+		forge.WithOperationID("getKeyContacts"),
+		withExamples("getKeyContacts"),
+		forge.WithRequestSchema(GetKeyContactsRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Key contacts", &KeyContactsResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.GET("/keys/:keyId/live", a.getLiveKeyStats,
+		forge.WithSummary("Get live key stats"),
+		forge.WithDescription("Returns a key's validation rate over the last 10 seconds (qps), 1 minute, and 5 minutes, and the fraction of the last minute's validations that failed, without usage records. Rates come from an in-memory tracker on the serving instance; they are not shared between instances and start over on restart. A key with no recent traffic reports zeros. Responds 501 when live stats are not enabled."),
+		forge.WithOperationID("getLiveKeyStats"),
+		withExamples("getLiveKeyStats"),
+		forge.WithRequestSchema(GetLiveKeyStatsRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Live key stats", &LiveKeyStatsResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.PUT("/keys/:keyId/contacts", a.putKeyContacts,
+		forge.WithSummary("Replace key contacts"),
+		forge.WithDescription("Replaces a key's contacts. Email targets must be bare addresses, webhook targets http or https URLs, and Slack targets channel references without spaces. An empty list clears them, so the tenant's defaults apply again."),
+		forge.WithOperationID("putKeyContacts"),
+		withExamples("putKeyContacts"),
+		forge.WithRequestSchema(PutKeyContactsRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Key contacts", &KeyContactsResponse{}),
+		forge.WithErrorResponses(),
+	)
 }
 
 func (a *API) registerPolicyRoutes(router forge.Router) {
@@ -123,15 +304,27 @@ func (a *API) registerPolicyRoutes(router forge.Router) {
 		forge.WithSummary("Create policy"),
 		forge.WithDescription("Creates a new key policy with rate limits, scopes, and restrictions."),
 		forge.WithOperationID("keysmithCreatePolicy"),
+		withExamples("keysmithCreatePolicy"),
 		forge.WithRequestSchema(CreatePolicyRequest{}),
 		forge.WithResponseSchema(http.StatusCreated, "Created policy", &PolicyResponse{}),
 		forge.WithErrorResponses(),
 	)
 
+	_ = g.POST("/policies/from-template", a.createPolicyFromTemplate,
+		forge.WithSummary("Create policy from template"),
+		forge.WithDescription("Creates a policy from a built-in or registered template, applying non-zero overrides field by field."),
+		forge.WithOperationID("keysmithCreatePolicyFromTemplate"),
+		withExamples("keysmithCreatePolicyFromTemplate"),
+		forge.WithRequestSchema(CreatePolicyFromTemplateRequest{}),
+		forge.WithResponseSchema(http.StatusCreated, "Created policy", &PolicyResponse{}),
+		forge.WithErrorResponses(),
+	)
+
 	_ = g.GET("/policies", a.listPolicies,
 		forge.WithSummary("List policies"),
-		forge.WithDescription("Returns key policies for the current tenant."),
+		forge.WithDescription("Returns key policies for the current tenant, without the inline policies of single keys unless include_inline is set. Tenant-scoped responses carry a weak ETag; a matching If-None-Match gets 304 Not Modified."),
 		forge.WithOperationID("keysmithListPolicies"),
+		withExamples("keysmithListPolicies"),
 		forge.WithRequestSchema(ListPoliciesRequest{}),
 		forge.WithResponseSchema(http.StatusOK, "Policy list", []*PolicyResponse{}),
 		forge.WithErrorResponses(),
@@ -139,17 +332,29 @@ func (a *API) registerPolicyRoutes(router forge.Router) {
 
 	_ = g.GET("/policies/:policyId", a.getPolicy,
 		forge.WithSummary("Get policy"),
-		forge.WithDescription("Returns details of a specific key policy."),
+		forge.WithDescription("Returns details of a specific key policy. Tenant-scoped responses carry a weak ETag; a matching If-None-Match gets 304 Not Modified."),
 		forge.WithOperationID("keysmithGetPolicy"),
+		withExamples("keysmithGetPolicy"),
 		forge.WithRequestSchema(GetPolicyRequest{}),
 		forge.WithResponseSchema(http.StatusOK, "Policy details", &PolicyResponse{}),
 		forge.WithErrorResponses(),
 	)
 
+	_ = g.GET("/policies/:policyId/effective", a.getEffectivePolicy,
+		forge.WithSummary("Get effective policy"),
+		forge.WithDescription("Returns the policy merged with the base policies it inherits from, as applied when validating its keys, and the inheritance chain."),
+		forge.WithOperationID("keysmithGetEffectivePolicy"),
+		withExamples("keysmithGetEffectivePolicy"),
+		forge.WithRequestSchema(GetEffectivePolicyRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Effective policy", &EffectivePolicyResponse{}),
+		forge.WithErrorResponses(),
+	)
+
 	_ = g.PUT("/policies/:policyId", a.updatePolicy,
 		forge.WithSummary("Update policy"),
 		forge.WithDescription("Updates an existing key policy."),
 		forge.WithOperationID("keysmithUpdatePolicy"),
+		withExamples("keysmithUpdatePolicy"),
 		forge.WithRequestSchema(UpdatePolicyRequest{}),
 		forge.WithResponseSchema(http.StatusOK, "Updated policy", &PolicyResponse{}),
 		forge.WithErrorResponses(),
@@ -159,6 +364,7 @@ func (a *API) registerPolicyRoutes(router forge.Router) {
 		forge.WithSummary("Delete policy"),
 		forge.WithDescription("Deletes a key policy. Fails if keys are assigned to it."),
 		forge.WithOperationID("keysmithDeletePolicy"),
+		withExamples("keysmithDeletePolicy"),
 		forge.WithRequestSchema(DeletePolicyRequest{}),
 		forge.WithNoContentResponse(),
 		forge.WithErrorResponses(),
@@ -172,6 +378,7 @@ func (a *API) registerScopeRoutes(router forge.Router) {
 		forge.WithSummary("Create scope"),
 		forge.WithDescription("Creates a new permission scope."),
 		forge.WithOperationID("createScope"),
+		withExamples("createScope"),
 		forge.WithRequestSchema(CreateScopeRequest{}),
 		forge.WithResponseSchema(http.StatusCreated, "Created scope", &ScopeResponse{}),
 		forge.WithErrorResponses(),
@@ -181,6 +388,7 @@ func (a *API) registerScopeRoutes(router forge.Router) {
 		forge.WithSummary("List scopes"),
 		forge.WithDescription("Returns permission scopes for the current tenant."),
 		forge.WithOperationID("listScopes"),
+		withExamples("listScopes"),
 		forge.WithRequestSchema(ListScopesRequest{}),
 		forge.WithResponseSchema(http.StatusOK, "Scope list", []*ScopeResponse{}),
 		forge.WithErrorResponses(),
@@ -190,6 +398,7 @@ func (a *API) registerScopeRoutes(router forge.Router) {
 		forge.WithSummary("Delete scope"),
 		forge.WithDescription("Deletes a permission scope."),
 		forge.WithOperationID("deleteScope"),
+		withExamples("deleteScope"),
 		forge.WithRequestSchema(DeleteScopeRequest{}),
 		forge.WithNoContentResponse(),
 		forge.WithErrorResponses(),
@@ -199,6 +408,7 @@ func (a *API) registerScopeRoutes(router forge.Router) {
 		forge.WithSummary("Assign scopes to key"),
 		forge.WithDescription("Assigns permission scopes to an API key."),
 		forge.WithOperationID("assignScopes"),
+		withExamples("assignScopes"),
 		forge.WithRequestSchema(AssignScopesRequest{}),
 		forge.WithNoContentResponse(),
 		forge.WithErrorResponses(),
@@ -208,12 +418,245 @@ func (a *API) registerScopeRoutes(router forge.Router) {
 		forge.WithSummary("Remove scopes from key"),
 		forge.WithDescription("Removes permission scopes from an API key."),
 		forge.WithOperationID("removeScopes"),
+		withExamples("removeScopes"),
 		forge.WithRequestSchema(RemoveScopesRequest{}),
 		forge.WithNoContentResponse(),
 		forge.WithErrorResponses(),
 	)
 }
 
+func (a *API) registerGroupRoutes(router forge.Router) {
+	g := router.Group("/v1", forge.WithGroupTags("groups"))
+
+	_ = g.POST("/groups", a.createGroup,
+		forge.WithSummary("Create key group"),
+		forge.WithDescription("Creates a named group of keys, such as Mobile apps or Partner X."),
+		forge.WithOperationID("createGroup"),
+		withExamples("createGroup"),
+		forge.WithRequestSchema(CreateGroupRequest{}),
+		forge.WithResponseSchema(http.StatusCreated, "Created group", &GroupResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.GET("/groups", a.listGroups,
+		forge.WithSummary("List key groups"),
+		forge.WithDescription("Returns key groups for the current tenant, ordered by name. List a group's keys with GET /v1/keys?group_id=."),
+		forge.WithOperationID("listGroups"),
+		withExamples("listGroups"),
+		forge.WithRequestSchema(ListGroupsRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Group list", []*GroupResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.GET("/groups/:groupId", a.getGroup,
+		forge.WithSummary("Get key group"),
+		forge.WithDescription("Returns details of a specific key group."),
+		forge.WithOperationID("getGroup"),
+		withExamples("getGroup"),
+		forge.WithRequestSchema(GetGroupRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Group details", &GroupResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.PATCH("/groups/:groupId", a.updateGroup,
+		forge.WithSummary("Update key group"),
+		forge.WithDescription("Updates a key group's name or description. Omitted fields are left unchanged."),
+		forge.WithOperationID("updateGroup"),
+		withExamples("updateGroup"),
+		forge.WithRequestSchema(UpdateGroupRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Updated group", &GroupResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.DELETE("/groups/:groupId", a.deleteGroup,
+		forge.WithSummary("Delete key group"),
+		forge.WithDescription("Deletes a key group. Its keys are left intact and lose the membership."),
+		forge.WithOperationID("deleteGroup"),
+		withExamples("deleteGroup"),
+		forge.WithRequestSchema(DeleteGroupRequest{}),
+		forge.WithNoContentResponse(),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.POST("/groups/:groupId/keys", a.addGroupKeys,
+		forge.WithSummary("Add keys to group"),
+		forge.WithDescription("Adds keys in the group's tenant and app to the group. Keys that are already members are ignored."),
+		forge.WithOperationID("addGroupKeys"),
+		withExamples("addGroupKeys"),
+		forge.WithRequestSchema(AddGroupKeysRequest{}),
+		forge.WithNoContentResponse(),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.DELETE("/groups/:groupId/keys", a.removeGroupKeys,
+		forge.WithSummary("Remove keys from group"),
+		forge.WithDescription("Removes keys from the group. Keys that are not members are ignored."),
+		forge.WithOperationID("removeGroupKeys"),
+		withExamples("removeGroupKeys"),
+		forge.WithRequestSchema(RemoveGroupKeysRequest{}),
+		forge.WithNoContentResponse(),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.POST("/groups/:groupId/rotate", a.rotateGroup,
+		forge.WithSummary("Rotate group keys"),
+		forge.WithDescription("Rotates every key in the group that is not revoked, each with its policy's grace period, and reports the outcome per key. New raw keys are returned only once."),
+		forge.WithOperationID("rotateGroup"),
+		withExamples("rotateGroup"),
+		forge.WithRequestSchema(RotateGroupRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Per-key rotation results", &GroupOperationResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.POST("/groups/:groupId/revoke", a.revokeGroup,
+		forge.WithSummary("Revoke group keys"),
+		forge.WithDescription("Revokes every key in the group and reports the outcome per key."),
+		forge.WithOperationID("revokeGroup"),
+		withExamples("revokeGroup"),
+		forge.WithRequestSchema(RevokeGroupRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Per-key revocation results", &GroupOperationResponse{}),
+		forge.WithErrorResponses(),
+	)
+}
+
+func (a *API) registerQuotaPoolRoutes(router forge.Router) {
+	g := router.Group("/v1", forge.WithGroupTags("quota-pools"))
+
+	_ = g.POST("/quota-pools", a.createQuotaPool,
+		forge.WithSummary("Create quota pool"),
+		forge.WithDescription("Creates a daily and monthly quota shared by its member keys, such as a customer plan of 1M requests a month. A member's validations count toward the pool instead of its policy's quotas."),
+		forge.WithOperationID("createQuotaPool"),
+		withExamples("createQuotaPool"),
+		forge.WithRequestSchema(CreateQuotaPoolRequest{}),
+		forge.WithResponseSchema(http.StatusCreated, "Created quota pool", &QuotaPoolResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.GET("/quota-pools", a.listQuotaPools,
+		forge.WithSummary("List quota pools"),
+		forge.WithDescription("Returns quota pools for the current tenant, ordered by name. List a pool's keys with GET /v1/keys?pool_id=."),
+		forge.WithOperationID("listQuotaPools"),
+		withExamples("listQuotaPools"),
+		forge.WithRequestSchema(ListQuotaPoolsRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Quota pool list", []*QuotaPoolResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.GET("/quota-pools/:poolId", a.getQuotaPool,
+		forge.WithSummary("Get quota pool"),
+		forge.WithDescription("Returns details of a specific quota pool."),
+		forge.WithOperationID("getQuotaPool"),
+		withExamples("getQuotaPool"),
+		forge.WithRequestSchema(GetQuotaPoolRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Quota pool details", &QuotaPoolResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.PATCH("/quota-pools/:poolId", a.updateQuotaPool,
+		forge.WithSummary("Update quota pool"),
+		forge.WithDescription("Updates a quota pool's name, description, limits, or time zone. Omitted fields are left unchanged; new limits apply to the current day and month."),
+		forge.WithOperationID("updateQuotaPool"),
+		withExamples("updateQuotaPool"),
+		forge.WithRequestSchema(UpdateQuotaPoolRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Updated quota pool", &QuotaPoolResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.DELETE("/quota-pools/:poolId", a.deleteQuotaPool,
+		forge.WithSummary("Delete quota pool"),
+		forge.WithDescription("Deletes a quota pool and its counts. Its keys are left intact and fall back to their policies' quotas."),
+		forge.WithOperationID("deleteQuotaPool"),
+		withExamples("deleteQuotaPool"),
+		forge.WithRequestSchema(DeleteQuotaPoolRequest{}),
+		forge.WithNoContentResponse(),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.POST("/quota-pools/:poolId/keys", a.addQuotaPoolKeys,
+		forge.WithSummary("Add keys to quota pool"),
+		forge.WithDescription("Adds keys in the pool's tenant and app to the pool. A key is in one pool at most, so a key in another pool moves to this one."),
+		forge.WithOperationID("addQuotaPoolKeys"),
+		withExamples("addQuotaPoolKeys"),
+		forge.WithRequestSchema(AddQuotaPoolKeysRequest{}),
+		forge.WithNoContentResponse(),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.DELETE("/quota-pools/:poolId/keys", a.removeQuotaPoolKeys,
+		forge.WithSummary("Remove keys from quota pool"),
+		forge.WithDescription("Removes keys from the pool; they fall back to their policies' quotas. Keys that are not members are ignored."),
+		forge.WithOperationID("removeQuotaPoolKeys"),
+		withExamples("removeQuotaPoolKeys"),
+		forge.WithRequestSchema(RemoveQuotaPoolKeysRequest{}),
+		forge.WithNoContentResponse(),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.GET("/quota-pools/:poolId/usage", a.getQuotaPoolUsage,
+		forge.WithSummary("Get quota pool usage"),
+		forge.WithDescription("Reports the pool's use of its current day and month: the limit, used, remaining, and each key's share. Counts other servers have not written yet are missing; see the quota consistency model in the usage docs."),
+		forge.WithOperationID("getQuotaPoolUsage"),
+		withExamples("getQuotaPoolUsage"),
+		forge.WithRequestSchema(GetQuotaPoolUsageRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Quota pool usage", &QuotaPoolUsageResponse{}),
+		forge.WithErrorResponses(),
+	)
+}
+
+func (a *API) registerTransferRoutes(router forge.Router) {
+	g := router.Group("/v1", forge.WithGroupTags("transfers"))
+
+	_ = g.POST("/keys/:keyId/transfer", a.initiateKeyTransfer,
+		forge.WithSummary("Initiate key transfer"),
+		forge.WithDescription("Starts the transfer of a key to another tenant of its app, which must accept it before the transfer expires. The key keeps validating while the transfer is pending. Revoked and expired keys cannot be transferred, nor keys whose name or external reference would clash in the target tenant. A key has at most one pending transfer; a second responds 409."),
+		forge.WithOperationID("initiateKeyTransfer"),
+		withExamples("initiateKeyTransfer"),
+		forge.WithRequestSchema(InitiateKeyTransferRequest{}),
+		forge.WithResponseSchema(http.StatusCreated, "Pending transfer", &KeyTransferResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.GET("/keys/:keyId/transfer", a.getKeyTransfer,
+		forge.WithSummary("Get pending key transfer"),
+		forge.WithDescription("Returns a key's pending transfer, to the source or the target tenant."),
+		forge.WithOperationID("getKeyTransfer"),
+		withExamples("getKeyTransfer"),
+		forge.WithRequestSchema(GetKeyTransferRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Key transfer", &KeyTransferResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.POST("/keys/:keyId/transfer/accept", a.acceptKeyTransfer,
+		forge.WithSummary("Accept key transfer"),
+		forge.WithDescription("Accepts a key's pending transfer. The caller must be scoped to the target tenant; others get 403. The key moves to the target tenant with its scopes and policy mapped to the target's of the same names; those without a match are dropped and listed in unmapped_scopes and unmapped_policy. The key leaves its groups and keeps its secret. A transfer past its expiry is marked expired and responds 409."),
+		forge.WithOperationID("acceptKeyTransfer"),
+		withExamples("acceptKeyTransfer"),
+		forge.WithRequestSchema(AcceptKeyTransferRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Accepted transfer", &KeyTransferResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.DELETE("/keys/:keyId/transfer", a.cancelKeyTransfer,
+		forge.WithSummary("Cancel key transfer"),
+		forge.WithDescription("Cancels a key's pending transfer. Either tenant may cancel it."),
+		forge.WithOperationID("cancelKeyTransfer"),
+		withExamples("cancelKeyTransfer"),
+		forge.WithRequestSchema(CancelKeyTransferRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Cancelled transfer", &KeyTransferResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.GET("/transfers", a.listKeyTransfers,
+		forge.WithSummary("List key transfers"),
+		forge.WithDescription("Lists transfers from or to the caller's tenant, newest first, in every state."),
+		forge.WithOperationID("listKeyTransfers"),
+		withExamples("listKeyTransfers"),
+		forge.WithRequestSchema(ListKeyTransfersRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Key transfers", []*KeyTransferResponse{}),
+		forge.WithErrorResponses(),
+	)
+}
+
 func (a *API) registerUsageRoutes(router forge.Router) {
 	g := router.Group("/v1", forge.WithGroupTags("usage"))
 
@@ -221,6 +664,7 @@ func (a *API) registerUsageRoutes(router forge.Router) {
 		forge.WithSummary("Get key usage"),
 		forge.WithDescription("Returns usage records for a specific key."),
 		forge.WithOperationID("getKeyUsage"),
+		withExamples("getKeyUsage"),
 		forge.WithRequestSchema(GetKeyUsageRequest{}),
 		forge.WithResponseSchema(http.StatusOK, "Usage records", []*UsageResponse{}),
 		forge.WithErrorResponses(),
@@ -230,15 +674,37 @@ func (a *API) registerUsageRoutes(router forge.Router) {
 		forge.WithSummary("Get key usage aggregate"),
 		forge.WithDescription("Returns aggregated usage statistics for a key."),
 		forge.WithOperationID("getKeyUsageAggregate"),
+		withExamples("getKeyUsageAggregate"),
 		forge.WithRequestSchema(GetKeyUsageAggregateRequest{}),
 		forge.WithResponseSchema(http.StatusOK, "Aggregated usage", []*AggregationResponse{}),
 		forge.WithErrorResponses(),
 	)
 
+	_ = g.GET("/keys/:keyId/quota-forecast", a.getQuotaForecast,
+		forge.WithSummary("Get key quota forecast"),
+		forge.WithDescription("Projects the key's usage this month (UTC) against its policy's monthly quota from the daily counts so far: the burn rate per day, the month-end total at that rate, and when the quota runs out, omitted when the projection stays under it. Returns 404 when the key's policy sets no monthly quota."),
+		forge.WithOperationID("getKeyQuotaForecast"),
+		withExamples("getKeyQuotaForecast"),
+		forge.WithRequestSchema(GetQuotaForecastRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Quota forecast", &QuotaForecastResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.GET("/keys/:keyId/endpoints", a.listEndpointActivity,
+		forge.WithSummary("List key endpoint activity"),
+		forge.WithDescription("Returns when a key last called each endpoint and how many times. Endpoints beyond the per-key cap are grouped under \"_other\"."),
+		forge.WithOperationID("listKeyEndpointActivity"),
+		withExamples("listKeyEndpointActivity"),
+		forge.WithRequestSchema(ListEndpointActivityRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Endpoint activity", []*EndpointActivityResponse{}),
+		forge.WithErrorResponses(),
+	)
+
 	_ = g.GET("/usage", a.listUsage,
 		forge.WithSummary("List usage across all keys"),
-		forge.WithDescription("Returns aggregated usage for the tenant."),
+		forge.WithDescription("Returns aggregated usage for the tenant. Filter by environment to keep sandbox or test traffic out of production figures."),
 		forge.WithOperationID("listUsage"),
+		withExamples("listUsage"),
 		forge.WithRequestSchema(ListUsageRequest{}),
 		forge.WithResponseSchema(http.StatusOK, "Tenant usage", []*AggregationResponse{}),
 		forge.WithErrorResponses(),
@@ -252,21 +718,354 @@ func (a *API) registerRotationRoutes(router forge.Router) {
 		forge.WithSummary("List key rotations"),
 		forge.WithDescription("Returns rotation history for a specific key."),
 		forge.WithOperationID("listKeyRotations"),
+		withExamples("listKeyRotations"),
 		forge.WithRequestSchema(ListRotationsRequest{}),
 		forge.WithResponseSchema(http.StatusOK, "Rotation history", []*RotationResponse{}),
 		forge.WithErrorResponses(),
 	)
+
+	_ = g.GET("/rotations", a.listTenantRotations,
+		forge.WithSummary("List rotations"),
+		forge.WithDescription("Returns rotations across every key the caller may see, newest first, filtered by reason and creation time. Reasons recorded before validation was added are returned and matched as stored."),
+		forge.WithOperationID("listRotations"),
+		withExamples("listRotations"),
+		forge.WithRequestSchema(ListTenantRotationsRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Rotations", &RotationListResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.GET("/rotations/summary", a.rotationSummary,
+		forge.WithSummary("Summarize rotations by reason"),
+		forge.WithDescription("Counts rotations per reason over a creation time range, most frequent first, for security reporting."),
+		forge.WithOperationID("getRotationSummary"),
+		withExamples("getRotationSummary"),
+		forge.WithRequestSchema(RotationSummaryRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Rotation counts per reason", &RotationSummaryResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.GET("/keys/:keyId/lineage", a.getLineage,
+		forge.WithSummary("Get key lineage"),
+		forge.WithDescription("Returns the key's hash eras, oldest first, with grace overlaps marked. With at, also reports which eras were live then. Hashes are never returned."),
+		forge.WithOperationID("getKeyLineage"),
+		withExamples("getKeyLineage"),
+		forge.WithRequestSchema(GetLineageRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Key lineage", &LineageResponse{}),
+		forge.WithErrorResponses(),
+	)
 }
 
 func (a *API) registerValidationRoutes(router forge.Router) {
 	g := router.Group("/v1", forge.WithGroupTags("validation"))
 
-	_ = g.POST("/keys/validate", a.validateKey,
+	_ = g.POST(validateKeyPath, a.validateKey,
 		forge.WithSummary("Validate API key"),
-		forge.WithDescription("Validates a raw API key and returns its metadata if valid."),
+		forge.WithDescription("Validates a raw API key and returns its metadata if valid. When replay protection is enabled, the request must carry a nonce and a timestamp: a nonce already used with the key is rejected with 409, and a timestamp outside the window with 400."),
 		forge.WithOperationID("validateKey"),
+		withExamples("validateKey"),
 		forge.WithRequestSchema(ValidateKeyRequest{}),
 		forge.WithResponseSchema(http.StatusOK, "Validation result", &ValidationResponse{}),
 		forge.WithErrorResponses(),
 	)
+
+	_ = g.GET(validateKeyPath, a.validateKeyHeader,
+		forge.WithSummary("Validate API key from headers"),
+		forge.WithDescription("Validates the key in the Authorization (Bearer) or X-API-Key header, for edge and CDN subrequest auth. Responds 204 with the key ID, tenant, scopes, and rate-limit headers, or with the error status POST /v1/keys/validate returns. Responses are not cacheable unless the server sets an edge cache TTL. Usage is counted without writing usage records unless a recording rule says otherwise."),
+		forge.WithOperationID("validateKeyHeader"),
+		withExamples("validateKeyHeader"),
+		forge.WithRequestSchema(ValidateKeyHeaderRequest{}),
+		forge.WithNoContentResponse(),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.HEAD(validateKeyPath, a.validateKeyHeader,
+		forge.WithSummary("Check API key from headers"),
+		forge.WithDescription("Same as GET /v1/keys/validate, for platforms that send HEAD subrequests."),
+		forge.WithOperationID("checkKeyHeader"),
+		withExamples("checkKeyHeader"),
+		forge.WithRequestSchema(ValidateKeyHeaderRequest{}),
+		forge.WithNoContentResponse(),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.GET("/validation/suspicious", a.listSuspiciousFingerprints,
+		forge.WithSummary("List suspicious validation fingerprints"),
+		forge.WithDescription("Returns the fingerprints (key prefix plus a truncated SHA-256, never the raw key) with the most validation failures in the current window, for incident response."),
+		forge.WithOperationID("listSuspiciousFingerprints"),
+		withExamples("listSuspiciousFingerprints"),
+		forge.WithRequestSchema(ListSuspiciousFingerprintsRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Top failure fingerprints", []*FailurePatternResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.GET("/integration-guide", a.getIntegrationGuide,
+		forge.WithSummary("Get integration guide"),
+		forge.WithDescription("Returns how to call the API with the caller tenant's keys, generated from live configuration: the key prefixes and environments in use, the validation endpoint URL, the headers the key middleware reads, curl, Go, and JavaScript snippets using a fake key with the tenant's prefix, and the tenant's scope catalog. Set format=markdown for a Markdown document. No key material is included. Tenant-scoped callers only."),
+		forge.WithOperationID("getIntegrationGuide"),
+		withExamples("getIntegrationGuide"),
+		forge.WithRequestSchema(GetIntegrationGuideRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Integration guide", &IntegrationGuideResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.GET("/slo", a.getSLO,
+		forge.WithSummary("Get validation SLO status"),
+		forge.WithDescription("Returns each configured validation objective with the error budget left over its window and the burn rate over each burn window (5m, 1h, and 6h by default). The list is empty when no objectives are configured."),
+		forge.WithOperationID("getSLO"),
+		withExamples("getSLO"),
+		forge.WithRequestSchema(GetSLORequest{}),
+		forge.WithResponseSchema(http.StatusOK, "SLO status", &SLOResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.GET("/validation/replay", a.getReplayStats,
+		forge.WithSummary("Get replay protection counters"),
+		forge.WithDescription("Returns the validate endpoint's replay protection counters: nonces checked, replays and stale timestamps rejected, the replay hit rate, and the size and evictions of the in-memory nonce cache. All counters are zero when replay protection is disabled."),
+		forge.WithOperationID("getReplayStats"),
+		withExamples("getReplayStats"),
+		forge.WithRequestSchema(GetReplayStatsRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Replay protection counters", &ReplayStatsResponse{}),
+		forge.WithErrorResponses(),
+	)
+}
+
+func (a *API) registerTenantRoutes(router forge.Router) {
+	g := router.Group("/v1", forge.WithGroupTags("tenants"))
+
+	_ = g.GET("/tenants/:tenantId/settings", a.getTenantSettings,
+		forge.WithSummary("Get tenant settings"),
+		forge.WithDescription("Returns tenant-wide settings such as the default scopes granted to new keys."),
+		forge.WithOperationID("getTenantSettings"),
+		withExamples("getTenantSettings"),
+		forge.WithRequestSchema(GetTenantSettingsRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Tenant settings", &TenantSettingsResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.PUT("/tenants/:tenantId/settings", a.updateTenantSettings,
+		forge.WithSummary("Update tenant settings"),
+		forge.WithDescription("Replaces tenant-wide settings. Every default scope must already exist in the tenant. The metadata schema is kept."),
+		forge.WithOperationID("updateTenantSettings"),
+		withExamples("updateTenantSettings"),
+		forge.WithRequestSchema(UpdateTenantSettingsRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Updated tenant settings", &TenantSettingsResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.PUT("/tenants/:tenantId/metadata-schema", a.putMetadataSchema,
+		forge.WithSummary("Replace tenant metadata schema"),
+		forge.WithDescription("Sets the schema that key metadata written in the tenant must match. Existing keys are not re-checked."),
+		forge.WithOperationID("putMetadataSchema"),
+		withExamples("putMetadataSchema"),
+		forge.WithRequestSchema(PutMetadataSchemaRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Updated tenant settings", &TenantSettingsResponse{}),
+		forge.WithErrorResponses(),
+	)
+}
+
+func (a *API) registerRevocationRoutes(router forge.Router) {
+	g := router.Group("/v1", forge.WithGroupTags("revocations"))
+
+	_ = g.GET("/revocations", a.listRevocations,
+		forge.WithSummary("Read the revocation feed"),
+		forge.WithDescription("Returns keys that were revoked, expired, or suspended, and keys that validate again, in the order the changes happened. Poll with the returned next cursor. Responds 304 Not Modified when If-None-Match matches the ETag or nothing changed since If-Modified-Since. Cursors older than the lookback window are rejected with 400."),
+		forge.WithOperationID("listRevocations"),
+		withExamples("listRevocations"),
+		forge.WithRequestSchema(ListRevocationsRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Revocation feed page", &RevocationFeedResponse{}),
+		forge.WithErrorResponses(),
+	)
+}
+
+func (a *API) registerEventRoutes(router forge.Router) {
+	g := router.Group("/v1", forge.WithGroupTags("events"))
+
+	_ = g.GET("/events/stream", a.streamKeyEvents,
+		forge.WithSummary("Stream key events"),
+		forge.WithDescription("Streams the lifecycle events of the caller's keys as Server-Sent Events: key.created, key.state_changed, key.rotated, key.revoked, and key.expired. Each event's id resumes the stream: a request with Last-Event-ID first replays the events recorded after it, each exactly once, then continues live. IDs older than the lookback window are rejected with 400. Heartbeat comments are sent while the stream is idle. A consumer that falls behind is disconnected and should reconnect with Last-Event-ID. Events never carry hashes or raw keys."),
+		forge.WithOperationID("streamKeyEvents"),
+		withExamples("streamKeyEvents"),
+		forge.WithRequestSchema(StreamKeyEventsRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Key event (SSE data)", &KeyEventResponse{}),
+		forge.WithResponseContentTypes("text/event-stream"),
+		forge.WithErrorResponses(),
+	)
+}
+
+func (a *API) registerDebugRoutes(router forge.Router) {
+	g := router.Group("/v1", forge.WithGroupTags("debug"))
+
+	_ = g.POST("/keys/:keyId/debug", a.setKeyDebug,
+		forge.WithSummary("Set key debug capture"),
+		forge.WithDescription("Captures the given fraction of a key's requests (method, path, allow-listed headers, truncated body) for the given duration, at most 24h. A sample rate of 0 turns capture off. Refused for live keys unless the engine permits it. Enabling capture is audited."),
+		forge.WithOperationID("setKeyDebug"),
+		withExamples("setKeyDebug"),
+		forge.WithRequestSchema(SetKeyDebugRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Updated key", &KeyResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.GET("/keys/:keyId/captures", a.listCaptures,
+		forge.WithSummary("List key debug captures"),
+		forge.WithDescription("Returns the most recent debug captures for a key, newest first. Authorization and any header containing the raw key are always redacted. Captures are purged after the retention period."),
+		forge.WithOperationID("listKeyCaptures"),
+		withExamples("listKeyCaptures"),
+		forge.WithRequestSchema(ListCapturesRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Debug captures", []*CaptureResponse{}),
+		forge.WithErrorResponses(),
+	)
+}
+
+func (a *API) registerAdminRoutes(router forge.Router) {
+	g := router.Group("/v1/admin", forge.WithGroupTags("admin"))
+
+	_ = g.POST("/maintenance", a.maintainStore,
+		forge.WithSummary("Run store maintenance"),
+		forge.WithDescription("Runs the store's maintenance (VACUUM and ANALYZE in PostgreSQL, incremental_vacuum and ANALYZE in SQLite, compact in MongoDB when full) and returns row counts and sizes for every keysmith table. Returns 501 when the store does not support maintenance."),
+		forge.WithOperationID("maintainStore"),
+		withExamples("maintainStore"),
+		forge.WithRequestSchema(MaintainStoreRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Maintenance report", &MaintenanceResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.GET("/storage", a.getStorageReport,
+		forge.WithSummary("Get storage report"),
+		forge.WithDescription("Counts the keys by state, policies, scopes, rotations, and usage records of every tenant, or of tenant_id, estimates the bytes their usage records take, and ranks the top tenants by usage volume, for billing and capacity planning. Counting scans every table, so call it off-peak on large stores. Byte counts are estimates that leave out indexes. Returns 501 when the store does not support storage reports. System-scoped callers only."),
+		forge.WithOperationID("getStorageReport"),
+		withExamples("getStorageReport"),
+		forge.WithRequestSchema(GetStorageReportRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Storage report", &StorageReportResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.POST("/revoke-by-hash", a.revokeByHash,
+		forge.WithSummary("Revoke keys by hash"),
+		forge.WithDescription("Revokes the keys whose key_hash is in the list, for responding to a leaked database dump, and reports found, revoked, already-revoked, and not-found per hash. Re-running a batch is safe. System-scoped callers only; at most 1000 hashes."),
+		forge.WithOperationID("revokeByHash"),
+		withExamples("revokeByHash"),
+		forge.WithRequestSchema(RevokeByHashRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Per-hash report", &HashReportsResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.POST("/validate-hashes", a.validateHashes,
+		forge.WithSummary("Look up keys by hash"),
+		forge.WithDescription("Reports which keys the given key_hash values belong to and their states without changing anything, to scope a leak before revoking. System-scoped callers only; at most 1000 hashes."),
+		forge.WithOperationID("validateHashes"),
+		withExamples("validateHashes"),
+		forge.WithRequestSchema(ValidateHashesRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Per-hash report", &HashReportsResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.GET("/keys/by-creator", a.listKeysByCreator,
+		forge.WithSummary("List keys by creator"),
+		forge.WithDescription("Returns the keys a user or service created, across every tenant the caller may see, for offboarding reviews. App- and system-scoped callers only; tenant-scoped callers can filter GET /v1/keys by created_by."),
+		forge.WithOperationID("listKeysByCreator"),
+		withExamples("listKeysByCreator"),
+		forge.WithRequestSchema(ListKeysByCreatorRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Keys by creator", &KeysByCreatorResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.POST("/keys/revoke-by-creator", a.revokeByCreator,
+		forge.WithSummary("Revoke keys by creator"),
+		forge.WithDescription("Revokes every key a user or service created, across every tenant the caller may see, and reports the revoked key IDs. Re-running is safe. Accepts dry_run to report the keys first. App- and system-scoped callers only."),
+		forge.WithOperationID("revokeByCreator"),
+		withExamples("revokeByCreator"),
+		forge.WithRequestSchema(RevokeByCreatorRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Revoked keys", &CreatorRevokeResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.GET("/hash-tombstones", a.listHashTombstones,
+		forge.WithSummary("List hash tombstones"),
+		forge.WithDescription("Returns the hash tombstones of keys revoked for a security reason (ReportCompromise or revoke-by-hash), or for any reason when the engine tombstones every revocation, newest first, with counts per reason. A tombstoned hash can never be written to a key again: creating, importing, or restoring a key with it fails with 409. Only the first characters of each hash are shown. Tenant-scoped callers see their own tenant's tombstones."),
+		forge.WithOperationID("listHashTombstones"),
+		withExamples("listHashTombstones"),
+		forge.WithRequestSchema(ListHashTombstonesRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Hash tombstones", &HashTombstonesResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.POST("/tenants/:tenantId/lockdown", a.lockdownTenant,
+		forge.WithSummary("Lock a tenant down"),
+		forge.WithDescription("Locks a tenant down for an incident in one call: marks it locked, so that creating keys in it responds 409, and suspends every active key of the tenant in every app. Locking down a locked tenant again suspends the keys reactivated since and stops its recovery. Records a critical audit event. Accepts dry_run to list the keys first. System-scoped callers only."),
+		forge.WithOperationID("lockdownTenant"),
+		withExamples("lockdownTenant"),
+		forge.WithRequestSchema(LockdownTenantRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Tenant lockdown", &TenantLockdownResultResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.GET("/tenants/:tenantId/lockdown", a.getTenantLockdown,
+		forge.WithSummary("Get tenant lockdown"),
+		forge.WithDescription("Returns whether a tenant is locked down, its lockdown and recovery progress, and how many of the keys the lockdown suspended are still waiting to be reactivated. System-scoped callers only."),
+		forge.WithOperationID("getTenantLockdown"),
+		withExamples("getTenantLockdown"),
+		forge.WithRequestSchema(GetTenantLockdownRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Tenant lockdown status", &TenantLockdownStatusResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.POST("/tenants/:tenantId/recover", a.recoverTenant,
+		forge.WithSummary("Recover a locked down tenant"),
+		forge.WithDescription("Starts, or resumes after a halt, the staged recovery of a locked down tenant: the keys the lockdown suspended, and only those, are reactivated in batches of batch_size every batch_interval, the first batch right away. Before each batch the recovery checks for renewed trouble, such as a key reported compromised, and halts if it finds any. The tenant stays locked until every key is reactivated; a tenant not locked down responds 409. Records critical audit events for each batch. Accepts dry_run to report the status only. System-scoped callers only."),
+		forge.WithOperationID("recoverTenant"),
+		withExamples("recoverTenant"),
+		forge.WithRequestSchema(RecoverTenantRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Tenant lockdown status", &TenantLockdownStatusResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.GET("/usage-recording", a.getUsageRecording,
+		forge.WithSummary("Get usage recording policy"),
+		forge.WithDescription("Returns the path rules and default mode that decide how each request passed to RecordUsage is kept: full, sampled 1-in-N, counter_only (endpoint activity counts without a usage record), or off."),
+		forge.WithOperationID("getUsageRecording"),
+		withExamples("getUsageRecording"),
+		forge.WithRequestSchema(GetUsageRecordingRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Usage recording policy", &UsageRecordingResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.PUT("/usage-recording", a.setUsageRecording,
+		forge.WithSummary("Set usage recording policy"),
+		forge.WithDescription("Replaces the usage recording policy for this process. Rules are evaluated in order and the first glob match on the route or path wins. Sampled modes still count every request in endpoint activity. The change is not persisted; the configured policy returns on restart. System-scoped callers only."),
+		forge.WithOperationID("setUsageRecording"),
+		withExamples("setUsageRecording"),
+		forge.WithRequestSchema(SetUsageRecordingRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Usage recording policy", &UsageRecordingResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.GET("/config", a.getRuntimeConfig,
+		forge.WithSummary("Get runtime configuration"),
+		forge.WithDescription("Returns the engine settings that can change without a restart: validation cache TTL, worker intervals, failure fingerprinting thresholds, and the usage sample rate. A setting whose feature is off reads 0s or 0. System-scoped callers only."),
+		forge.WithOperationID("getRuntimeConfig"),
+		withExamples("getRuntimeConfig"),
+		forge.WithRequestSchema(GetRuntimeConfigRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Runtime configuration", &RuntimeConfigResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.PATCH("/config", a.updateRuntimeConfig,
+		forge.WithSummary("Update runtime configuration"),
+		forge.WithDescription("Changes the given runtime settings for this process; background workers pick up a new interval after their current cycle. Durations must be positive and at most 24h, and a feature that is off cannot be tuned. Fires the RuntimeConfigChanged hook with the old and new values. The change is not persisted; the configured values return on restart. Accepts dry_run. System-scoped callers only."),
+		forge.WithOperationID("updateRuntimeConfig"),
+		withExamples("updateRuntimeConfig"),
+		forge.WithRequestSchema(UpdateRuntimeConfigRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Runtime configuration", &RuntimeConfigResponse{}),
+		forge.WithErrorResponses(),
+	)
+
+	_ = g.POST("/readonly", a.setReadOnly,
+		forge.WithSummary("Set read-only mode"),
+		forge.WithDescription("Turns read-only mode on or off for this process, for database maintenance windows. While it is on, every endpoint that writes returns 503 and keys keep validating; usage_recording reports whether usage is still recorded. The change is not persisted. System-scoped callers only."),
+		forge.WithOperationID("setReadOnly"),
+		withExamples("setReadOnly"),
+		forge.WithRequestSchema(SetReadOnlyRequest{}),
+		forge.WithResponseSchema(http.StatusOK, "Read-only mode", &ReadOnlyResponse{}),
+		forge.WithErrorResponses(),
+	)
 }
@@ -0,0 +1,588 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xraph/forge"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/api"
+	"github.com/xraph/keysmith/apitypes"
+	"github.com/xraph/keysmith/client"
+	"github.com/xraph/keysmith/key"
+)
+
+// The client is tested here, against the real handlers, for the same
+// reason as revocationfeed. TestClient_CoversEveryRoute makes the suite a
+// contract test: a route added without a client method, or a client
+// method whose route the server does not serve, fails it.
+
+// clientServer serves the keysmith API with the X-Test-Tenant header as the
+// caller's tenant, requests without one calling in the system scope, and
+// records which routes answered. The header leaves Authorization free for
+// the keys under test.
+type clientServer struct {
+	*httptest.Server
+	eng    *keysmith.Engine
+	routes []forge.RouteInfo
+
+	mu     sync.Mutex
+	served map[string]bool // "METHOD pattern" answered by a handler
+	stray  []string        // requests no route matched
+}
+
+func newClientServer(t *testing.T) *clientServer {
+	t.Helper()
+	router := forge.NewRouter()
+	cs := &clientServer{eng: newEngine(t), served: make(map[string]bool)}
+	h := api.New(cs.eng, router).Handler()
+	cs.routes = router.Routes()
+	cs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if tenant := r.Header.Get("X-Test-Tenant"); tenant != "" {
+			ctx = keysmith.WithTenant(ctx, "app_1", tenant)
+		}
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(rec, r.WithContext(ctx))
+		cs.record(r, rec)
+	}))
+	t.Cleanup(cs.Close)
+	return cs
+}
+
+// record notes the route r reached. A 404 or 405 without a JSON body came
+// from the router, not a handler.
+func (cs *clientServer) record(r *http.Request, rec *statusRecorder) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	fromRouter := (rec.status == http.StatusNotFound || rec.status == http.StatusMethodNotAllowed) &&
+		!strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json")
+	// Like the router, prefer the route with the fewest parameters, so that
+	// /v1/keys/validate is not taken for /v1/keys/:keyId.
+	best, params := "", -1
+	for _, route := range cs.routes {
+		n := strings.Count(route.Path, ":")
+		if route.Method == r.Method && routePattern(route.Path).MatchString(r.URL.Path) && (params < 0 || n < params) {
+			best, params = route.Path, n
+		}
+	}
+	if best == "" || fromRouter {
+		cs.stray = append(cs.stray, r.Method+" "+r.URL.Path)
+		return
+	}
+	cs.served[r.Method+" "+best] = true
+}
+
+func (cs *clientServer) wasServed(route string) bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.served[route]
+}
+
+var paramSegment = regexp.MustCompile(`:[A-Za-z]+`)
+
+func routePattern(path string) *regexp.Regexp {
+	return regexp.MustCompile("^" + paramSegment.ReplaceAllString(regexp.QuoteMeta(path), `[^/]+`) + "$")
+}
+
+// client returns a client calling as tenant, or in the system scope when
+// tenant is empty.
+func (cs *clientServer) client(tenant string) *client.Client {
+	opts := []client.Option{client.WithRetry(0, 0, 0)}
+	if tenant != "" {
+		opts = append(opts, client.WithHeader("X-Test-Tenant", tenant))
+	}
+	return client.New(cs.URL, opts...)
+}
+
+func TestClient_CoversEveryRoute(t *testing.T) {
+	cs := newClientServer(t)
+	c, admin := cs.client("tenant_acme"), cs.client("")
+	ctx := context.Background()
+	start := time.Now()
+
+	// Scopes.
+	for _, name := range []string{"read:orders", "write:orders", "read:invoices"} {
+		_, err := c.CreateScope(ctx, &apitypes.CreateScopeRequest{Name: name})
+		require.NoError(t, err, name)
+	}
+	scopes, err := c.ListScopes(ctx, &apitypes.ListScopesRequest{})
+	require.NoError(t, err)
+	require.Len(t, scopes, 3)
+	var iterated []string
+	require.NoError(t, c.IterateScopes(ctx, &apitypes.ListScopesRequest{Limit: 2}, func(s *apitypes.ScopeResponse) error {
+		iterated = append(iterated, s.Name)
+		return nil
+	}))
+	assert.Len(t, iterated, 3, "pages of two cover all three scopes")
+
+	// Policies.
+	pol, err := c.CreatePolicy(ctx, &apitypes.CreatePolicyRequest{
+		Name: "Standard", RateLimit: 100, RateLimitWindow: apitypes.Duration(time.Minute), AllowedScopes: []string{"read:orders", "write:orders"},
+	})
+	require.NoError(t, err)
+	tmpl, err := c.CreatePolicyFromTemplate(ctx, &apitypes.CreatePolicyFromTemplateRequest{Template: "standard"})
+	require.NoError(t, err)
+	got, err := c.GetPolicy(ctx, &apitypes.GetPolicyRequest{PolicyID: pol.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "Standard", got.Name)
+	_, err = c.GetEffectivePolicy(ctx, &apitypes.GetEffectivePolicyRequest{PolicyID: pol.ID})
+	require.NoError(t, err)
+	updated, err := c.UpdatePolicy(ctx, &apitypes.UpdatePolicyRequest{
+		PolicyID:            pol.ID,
+		CreatePolicyRequest: apitypes.CreatePolicyRequest{Name: "Standard", RateLimit: 200, RateLimitWindow: apitypes.Duration(time.Minute)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 200, updated.RateLimit)
+	policies, err := c.ListPolicies(ctx, &apitypes.ListPoliciesRequest{})
+	require.NoError(t, err)
+	assert.Len(t, policies, 2)
+	n := 0
+	require.NoError(t, c.IteratePolicies(ctx, nil, func(*apitypes.PolicyResponse) error { n++; return nil }))
+	assert.Equal(t, 2, n)
+
+	// Keys.
+	created, err := c.CreateKey(ctx, &apitypes.CreateKeyRequest{
+		Name: "orders", Prefix: "sk", Environment: "test", PolicyID: pol.ID,
+		Scopes: []string{"read:orders"}, Labels: key.Labels{"team": "orders"},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, created.RawKey)
+	keyID := created.Key.ID
+	for range 4 {
+		_, err := c.CreateKey(ctx, &apitypes.CreateKeyRequest{Name: "bulk", Prefix: "sk", Environment: "test"})
+		require.NoError(t, err)
+	}
+	k, err := c.GetKey(ctx, &apitypes.GetKeyRequest{KeyID: keyID})
+	require.NoError(t, err)
+	assert.Equal(t, "orders", k.Name)
+	keys, err := c.ListKeys(ctx, &apitypes.ListKeysRequest{Limit: 2})
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+	var ids []string
+	require.NoError(t, c.IterateKeys(ctx, &apitypes.ListKeysRequest{Limit: 2}, func(k *apitypes.KeyResponse) error {
+		ids = append(ids, k.ID)
+		return nil
+	}))
+	assert.Len(t, ids, 5, "pages of two cover all five keys")
+	overview, err := c.KeyOverview(ctx, &apitypes.KeyOverviewRequest{ExpiringWithinDays: "7,30"})
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, overview.Total)
+	assert.Equal(t, map[apitypes.KeyEnvironment]int64{apitypes.KeyEnvironmentTest: 5}, overview.ByEnvironment)
+	assert.Equal(t, []*apitypes.OverviewWindowResponse{{WithinDays: 7}, {WithinDays: 30}}, overview.Expiring)
+	assert.Equal(t, &apitypes.OverviewWindowResponse{WithinDays: 30, Count: 5}, overview.CreatedRecently)
+	_, err = c.KeyOverview(ctx, &apitypes.KeyOverviewRequest{ExpiringWithinDays: "30,7"})
+	require.ErrorIs(t, err, keysmith.ErrInvalidOverviewQuery)
+	found, err := c.Search(ctx, &apitypes.SearchRequest{Query: "ORDERS"})
+	require.NoError(t, err)
+	require.Len(t, found.Keys, 1)
+	assert.Equal(t, keyID, found.Keys[0].Key.ID)
+	assert.Equal(t, []string{"name"}, found.Keys[0].Fields)
+	_, err = c.Search(ctx, &apitypes.SearchRequest{Query: `""`})
+	require.ErrorIs(t, err, keysmith.ErrInvalidSearchQuery)
+	name := "orders-api"
+	k, err = c.UpdateKey(ctx, &apitypes.UpdateKeyRequest{KeyID: keyID, Name: &name})
+	require.NoError(t, err)
+	assert.Equal(t, name, k.Name)
+	_, err = c.GetKeyContacts(ctx, &apitypes.GetKeyContactsRequest{KeyID: keyID})
+	require.NoError(t, err)
+	dups, err := c.ListDuplicateKeyNames(ctx, &apitypes.ListDuplicateKeyNamesRequest{})
+	require.NoError(t, err)
+	require.Len(t, dups, 1)
+	assert.Equal(t, "bulk", dups[0].Name)
+	assert.Len(t, dups[0].KeyIDs, 4)
+	_, err = c.GetLiveKeyStats(ctx, &apitypes.GetLiveKeyStatsRequest{KeyID: keyID})
+	require.Equal(t, http.StatusNotImplemented, client.StatusCode(err), "live stats are off by default")
+	_, err = c.ListLiveTopKeys(ctx, &apitypes.ListLiveTopKeysRequest{N: 5})
+	assert.ErrorIs(t, err, keysmith.ErrLiveStatsDisabled)
+	_, err = c.PutKeyContacts(ctx, &apitypes.PutKeyContactsRequest{
+		KeyID: keyID, Contacts: key.Contacts{{Type: key.ContactEmail, Target: "ops@example.com"}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, c.AssignScopes(ctx, &apitypes.AssignScopesRequest{KeyID: keyID, Scopes: []string{"write:orders"}}))
+	require.NoError(t, c.RemoveScopes(ctx, &apitypes.RemoveScopesRequest{KeyID: keyID, Scopes: []string{"write:orders"}}))
+
+	// Groups.
+	grp, err := c.CreateGroup(ctx, &apitypes.CreateGroupRequest{Name: "Partner X"})
+	require.NoError(t, err)
+	require.NoError(t, c.AddGroupKeys(ctx, &apitypes.AddGroupKeysRequest{GroupID: grp.ID, KeyIDs: []string{keyID, ids[0]}}))
+	require.NoError(t, c.RemoveGroupKeys(ctx, &apitypes.RemoveGroupKeysRequest{GroupID: grp.ID, KeyIDs: []string{keyID}}))
+	members, err := c.ListKeys(ctx, &apitypes.ListKeysRequest{GroupID: grp.ID})
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	assert.Equal(t, []string{grp.ID}, members[0].Groups)
+	desc := "Partner X integration keys"
+	grp, err = c.UpdateGroup(ctx, &apitypes.UpdateGroupRequest{GroupID: grp.ID, Description: &desc})
+	require.NoError(t, err)
+	assert.Equal(t, "Partner X", grp.Name)
+	_, err = c.GetGroup(ctx, &apitypes.GetGroupRequest{GroupID: grp.ID})
+	require.NoError(t, err)
+	groups, err := c.ListGroups(ctx, &apitypes.ListGroupsRequest{})
+	require.NoError(t, err)
+	assert.Len(t, groups, 1)
+	grpRotated, err := c.RotateGroup(ctx, &apitypes.RotateGroupRequest{GroupID: grp.ID})
+	require.NoError(t, err)
+	require.Len(t, grpRotated.Keys, 1)
+	assert.NotEmpty(t, grpRotated.Keys[0].RawKey)
+	grpRevoked, err := c.RevokeGroup(ctx, &apitypes.RevokeGroupRequest{GroupID: grp.ID, Reason: "partner offboarded"})
+	require.NoError(t, err)
+	assert.Equal(t, "revoked", grpRevoked.Keys[0].Status)
+	require.NoError(t, c.DeleteGroup(ctx, &apitypes.DeleteGroupRequest{GroupID: grp.ID}))
+	_, err = c.GetGroup(ctx, &apitypes.GetGroupRequest{GroupID: grp.ID})
+	require.Error(t, err)
+
+	// Quota pools.
+	pool, err := c.CreateQuotaPool(ctx, &apitypes.CreateQuotaPoolRequest{Name: "Acme Pro", MonthlyLimit: 1000})
+	require.NoError(t, err)
+	require.NoError(t, c.AddQuotaPoolKeys(ctx, &apitypes.AddQuotaPoolKeysRequest{PoolID: pool.ID, KeyIDs: []string{keyID, ids[0]}}))
+	require.NoError(t, c.RemoveQuotaPoolKeys(ctx, &apitypes.RemoveQuotaPoolKeysRequest{PoolID: pool.ID, KeyIDs: []string{ids[0]}}))
+	pooled, err := c.ListKeys(ctx, &apitypes.ListKeysRequest{PoolID: pool.ID})
+	require.NoError(t, err)
+	require.Len(t, pooled, 1)
+	assert.Equal(t, pool.ID, pooled[0].PoolID)
+	daily := int64(100)
+	pool, err = c.UpdateQuotaPool(ctx, &apitypes.UpdateQuotaPoolRequest{PoolID: pool.ID, DailyLimit: &daily})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1000, pool.MonthlyLimit)
+	_, err = c.GetQuotaPool(ctx, &apitypes.GetQuotaPoolRequest{PoolID: pool.ID})
+	require.NoError(t, err)
+	pools, err := c.ListQuotaPools(ctx, &apitypes.ListQuotaPoolsRequest{})
+	require.NoError(t, err)
+	assert.Len(t, pools, 1)
+	poolUsage, err := c.GetQuotaPoolUsage(ctx, &apitypes.GetQuotaPoolUsageRequest{PoolID: pool.ID})
+	require.NoError(t, err)
+	assert.EqualValues(t, 100, poolUsage.Day.Remaining)
+	daily = 5000
+	_, err = c.UpdateQuotaPool(ctx, &apitypes.UpdateQuotaPoolRequest{PoolID: pool.ID, DailyLimit: &daily})
+	require.ErrorIs(t, err, keysmith.ErrInvalidQuotaPool)
+	require.NoError(t, c.DeleteQuotaPool(ctx, &apitypes.DeleteQuotaPoolRequest{PoolID: pool.ID}))
+	_, err = c.GetQuotaPool(ctx, &apitypes.GetQuotaPoolRequest{PoolID: pool.ID})
+	require.Error(t, err)
+
+	// Key transfers.
+	moving, err := c.CreateKey(ctx, &apitypes.CreateKeyRequest{
+		Name: "handover", Prefix: "sk", Environment: "test", Scopes: []string{"read:orders"},
+	})
+	require.NoError(t, err)
+	beta := cs.client("tenant_beta")
+	xfr, err := c.InitiateKeyTransfer(ctx, &apitypes.InitiateKeyTransferRequest{KeyID: moving.Key.ID, TargetTenantID: "tenant_beta"})
+	require.NoError(t, err)
+	assert.Equal(t, "pending", xfr.State)
+	_, err = beta.CancelKeyTransfer(ctx, &apitypes.CancelKeyTransferRequest{KeyID: moving.Key.ID})
+	require.NoError(t, err)
+	_, err = c.InitiateKeyTransfer(ctx, &apitypes.InitiateKeyTransferRequest{KeyID: moving.Key.ID, TargetTenantID: "tenant_beta"})
+	require.NoError(t, err)
+	_, err = beta.GetKeyTransfer(ctx, &apitypes.GetKeyTransferRequest{KeyID: moving.Key.ID})
+	require.NoError(t, err)
+	_, err = c.AcceptKeyTransfer(ctx, &apitypes.AcceptKeyTransferRequest{KeyID: moving.Key.ID})
+	require.ErrorIs(t, err, keysmith.ErrKeyTransferNotTarget)
+	xfr, err = beta.AcceptKeyTransfer(ctx, &apitypes.AcceptKeyTransferRequest{KeyID: moving.Key.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "accepted", xfr.State)
+	assert.Equal(t, []string{"read:orders"}, xfr.UnmappedScopes, "tenant_beta has no scopes")
+	xfrs, err := c.ListKeyTransfers(ctx, &apitypes.ListKeyTransfersRequest{KeyID: moving.Key.ID})
+	require.NoError(t, err)
+	require.Len(t, xfrs, 2)
+	assert.Equal(t, "accepted", xfrs[0].State)
+	_, err = beta.GetKey(ctx, &apitypes.GetKeyRequest{KeyID: moving.Key.ID})
+	require.NoError(t, err)
+
+	// Validation.
+	v, err := c.ValidateKey(ctx, &apitypes.ValidateKeyRequest{RawKey: created.RawKey})
+	require.NoError(t, err)
+	assert.Equal(t, keyID, v.Key.ID)
+	hv, err := c.ValidateKeyHeader(ctx, &apitypes.ValidateKeyHeaderRequest{APIKey: created.RawKey})
+	require.NoError(t, err)
+	assert.Equal(t, keyID, hv.KeyID)
+	assert.Equal(t, "tenant_acme", hv.TenantID)
+	_, err = c.ValidateKey(ctx, &apitypes.ValidateKeyRequest{RawKey: "sk_test_nope"})
+	require.ErrorIs(t, err, client.ErrUnauthorized)
+	_, err = c.ListSuspiciousFingerprints(ctx, &apitypes.ListSuspiciousFingerprintsRequest{})
+	require.NoError(t, err)
+	guide, err := c.GetIntegrationGuide(ctx, &apitypes.GetIntegrationGuideRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "tenant_acme", guide.TenantID)
+	_, err = c.GetSLO(ctx)
+	require.NoError(t, err)
+	_, err = c.GetReplayStats(ctx)
+	require.NoError(t, err)
+
+	// Usage.
+	_, err = c.GetKeyUsage(ctx, &apitypes.GetKeyUsageRequest{KeyID: keyID})
+	require.NoError(t, err)
+	_, err = c.GetKeyUsageAggregate(ctx, &apitypes.GetKeyUsageAggregateRequest{KeyID: keyID, Period: "day"})
+	require.NoError(t, err)
+	_, err = c.GetQuotaForecast(ctx, &apitypes.GetQuotaForecastRequest{KeyID: keyID})
+	require.ErrorIs(t, err, keysmith.ErrNoMonthlyQuota, "the policy has no monthly quota")
+	require.ErrorIs(t, err, client.ErrNotFound)
+	_, err = c.ListEndpointActivity(ctx, &apitypes.ListEndpointActivityRequest{KeyID: keyID})
+	require.NoError(t, err)
+	_, err = c.ListUsage(ctx, &apitypes.ListUsageRequest{Period: "day"})
+	require.NoError(t, err)
+
+	// Rotation.
+	rotated, err := c.RotateKey(ctx, &apitypes.RotateKeyRequest{KeyID: keyID})
+	require.NoError(t, err)
+	assert.NotEqual(t, created.RawKey, rotated.RawKey)
+	rotations, err := c.ListRotations(ctx, &apitypes.ListRotationsRequest{KeyID: keyID})
+	require.NoError(t, err)
+	assert.Len(t, rotations, 1)
+	_, err = c.ListTenantRotations(ctx, &apitypes.ListTenantRotationsRequest{})
+	require.NoError(t, err)
+	_, err = c.RotationSummary(ctx, &apitypes.RotationSummaryRequest{})
+	require.NoError(t, err)
+	_, err = c.GetLineage(ctx, &apitypes.GetLineageRequest{KeyID: keyID})
+	require.NoError(t, err)
+
+	// Debug capture.
+	_, err = c.SetKeyDebug(ctx, &apitypes.SetKeyDebugRequest{KeyID: keyID, SampleRate: 1, Duration: apitypes.Duration(30 * time.Minute)})
+	require.NoError(t, err)
+	_, err = c.ListCaptures(ctx, &apitypes.ListCapturesRequest{KeyID: keyID})
+	require.NoError(t, err)
+
+	// Tenant settings.
+	_, err = c.UpdateTenantSettings(ctx, &apitypes.UpdateTenantSettingsRequest{TenantID: "tenant_acme", DefaultScopes: []string{"read:invoices"}})
+	require.NoError(t, err)
+	settings, err := c.GetTenantSettings(ctx, &apitypes.GetTenantSettingsRequest{TenantID: "tenant_acme"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read:invoices"}, settings.DefaultScopes)
+	_, err = c.PutMetadataSchema(ctx, &apitypes.PutMetadataSchemaRequest{TenantID: "tenant_acme", AllowUnknown: true})
+	require.NoError(t, err)
+
+	// Lifecycle.
+	require.NoError(t, c.SuspendKey(ctx, &apitypes.SuspendKeyRequest{KeyID: keyID}))
+	_, err = c.ValidateKey(ctx, &apitypes.ValidateKeyRequest{RawKey: rotated.RawKey})
+	require.ErrorIs(t, err, keysmith.ErrKeyInactive)
+	require.ErrorIs(t, err, client.ErrForbidden)
+	require.NoError(t, c.ReactivateKey(ctx, &apitypes.ReactivateKeyRequest{KeyID: keyID}))
+	_, err = c.ReportCompromise(ctx, &apitypes.ReportCompromiseRequest{KeyID: ids[1], Source: "customer", Action: "revoke"})
+	require.NoError(t, err)
+	require.NoError(t, c.RevokeKey(ctx, &apitypes.RevokeKeyRequest{KeyID: ids[2], Reason: "retired"}))
+	revoked, err := c.RevokeByLabels(ctx, &apitypes.RevokeByLabelsRequest{LabelSelector: "team=orders", Reason: "team retired"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{keyID}, revoked.KeyIDs)
+	require.NoError(t, c.DeleteKey(ctx, &apitypes.DeleteKeyRequest{KeyID: ids[3]}))
+	deleted, err := c.GetKey(ctx, &apitypes.GetKeyRequest{KeyID: ids[3]})
+	require.NoError(t, err)
+	assert.Equal(t, apitypes.KeyStateRevoked, deleted.State, "deleting a key revokes it")
+	require.NoError(t, c.DeletePolicy(ctx, &apitypes.DeletePolicyRequest{PolicyID: tmpl.ID}))
+	require.NoError(t, c.DeleteScope(ctx, &apitypes.DeleteScopeRequest{ScopeID: scopes[0].ID}))
+	feed, err := c.ListRevocations(ctx, &apitypes.ListRevocationsRequest{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, feed.Entries)
+	var streamed *apitypes.KeyEventResponse
+	err = c.StreamKeyEvents(ctx, &apitypes.StreamKeyEventsRequest{LastEventID: fmt.Sprintf("%d.", start.UnixNano())},
+		func(e *apitypes.KeyEventResponse) error {
+			streamed = e
+			return errStopStream
+		})
+	require.ErrorIs(t, err, errStopStream)
+	assert.Equal(t, "key.created", streamed.Type)
+	// The stream handler records its route once it sees the disconnect.
+	require.Eventually(t, func() bool { return cs.wasServed("GET /v1/events/stream") }, 5*time.Second, 10*time.Millisecond)
+
+	// Admin routes, in the system scope.
+	_, err = admin.MaintainStore(ctx, &apitypes.MaintainStoreRequest{StatsOnly: true})
+	if err != nil {
+		require.Equal(t, http.StatusNotImplemented, client.StatusCode(err), "the memory store may not support maintenance")
+	}
+	storage, err := admin.GetStorageReport(ctx, &apitypes.GetStorageReportRequest{Top: 1})
+	require.NoError(t, err)
+	assert.NotEmpty(t, storage.Tenants)
+	assert.LessOrEqual(t, len(storage.TopUsage), 1)
+	_, err = admin.ValidateHashes(ctx, &apitypes.ValidateHashesRequest{Hashes: []string{"nope"}})
+	require.NoError(t, err)
+	tbs, err := admin.ListHashTombstones(ctx, &apitypes.ListHashTombstonesRequest{Limit: 10})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, tbs.Total, "the compromised key's hash")
+	_, err = admin.RevokeByHash(ctx, &apitypes.RevokeByHashRequest{Hashes: []string{"nope"}, Reason: "leak", DryRun: true})
+	require.NoError(t, err)
+	_, err = admin.ListKeysByCreator(ctx, &apitypes.ListKeysByCreatorRequest{CreatedBy: "ci"})
+	require.NoError(t, err)
+	_, err = admin.RevokeByCreator(ctx, &apitypes.RevokeByCreatorRequest{CreatedBy: "ci", Reason: "offboarded", DryRun: true})
+	require.NoError(t, err)
+	locked, err := admin.LockdownTenant(ctx, &apitypes.LockdownTenantRequest{TenantID: "tenant_acme", Reason: "incident", DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, "locked", locked.Lockdown.Phase)
+	status, err := admin.GetTenantLockdown(ctx, &apitypes.GetTenantLockdownRequest{TenantID: "tenant_acme"})
+	require.NoError(t, err)
+	assert.False(t, status.Locked, "a dry-run lockdown changes nothing")
+	_, err = admin.RecoverTenant(ctx, &apitypes.RecoverTenantRequest{TenantID: "tenant_acme", BatchSize: 10})
+	require.ErrorIs(t, err, keysmith.ErrTenantNotLocked)
+	_, err = admin.SetUsageRecording(ctx, &apitypes.SetUsageRecordingRequest{Default: "full"})
+	require.NoError(t, err)
+	_, err = admin.GetUsageRecording(ctx)
+	require.NoError(t, err)
+	threshold := 7
+	cfg, err := admin.UpdateRuntimeConfig(ctx, &apitypes.UpdateRuntimeConfigRequest{FailureThreshold: &threshold, DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, threshold, cfg.FailureThreshold)
+	_, err = admin.GetRuntimeConfig(ctx)
+	require.NoError(t, err)
+	ro, err := admin.SetReadOnly(ctx, &apitypes.SetReadOnlyRequest{Enabled: true})
+	require.NoError(t, err)
+	assert.True(t, ro.ReadOnly)
+	_, err = admin.SetReadOnly(ctx, &apitypes.SetReadOnlyRequest{Enabled: false})
+	require.NoError(t, err)
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	assert.Empty(t, cs.stray, "client requests no route serves")
+	for _, route := range cs.routes {
+		if route.Method == http.MethodHead {
+			// HEAD /v1/keys/validate shares the GET handler.
+			continue
+		}
+		assert.True(t, cs.served[route.Method+" "+route.Path], "no client call reached %s %s", route.Method, route.Path)
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	cs := newClientServer(t)
+	c := cs.client("tenant_acme")
+	ctx := context.Background()
+
+	_, err := c.GetKey(ctx, &apitypes.GetKeyRequest{KeyID: "bad"})
+	var apiErr *client.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Equal(t, "400", apiErr.Code)
+	assert.Contains(t, apiErr.Message, "invalid key ID")
+	assert.ErrorIs(t, err, client.ErrInvalidRequest)
+
+	_, err = c.GetKey(ctx, &apitypes.GetKeyRequest{})
+	require.Error(t, err, "a missing path parameter fails before sending")
+	assert.Zero(t, client.StatusCode(err))
+
+	_, err = c.CreatePolicy(ctx, &apitypes.CreatePolicyRequest{Name: "Broken", RateLimit: 10})
+	assert.ErrorIs(t, err, client.ErrInvalidRequest, "a rate limit needs a window")
+
+	_, err = c.CreateKey(ctx, &apitypes.CreateKeyRequest{Name: "k", Prefix: "sk", Environment: "prod"})
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnprocessableEntity, apiErr.StatusCode)
+	assert.Contains(t, apiErr.Message, `invalid environment: "prod" is not a key environment; allowed values are live, test, staging`)
+	assert.ErrorIs(t, err, client.ErrInvalidRequest)
+
+	created, err := c.CreateKey(ctx, &apitypes.CreateKeyRequest{Name: "k", Prefix: "sk", Environment: "test"})
+	require.NoError(t, err)
+	require.NoError(t, c.RevokeKey(ctx, &apitypes.RevokeKeyRequest{KeyID: created.Key.ID, Reason: "done"}))
+	_, err = c.ValidateKey(ctx, &apitypes.ValidateKeyRequest{RawKey: created.RawKey})
+	assert.ErrorIs(t, err, keysmith.ErrKeyInactive)
+	assert.NotErrorIs(t, err, keysmith.ErrKeyExpired)
+	assert.True(t, errors.Is(err, client.ErrForbidden))
+}
+
+func TestClient_DryRun(t *testing.T) {
+	cs := newClientServer(t)
+	c := cs.client("tenant_acme")
+	ctx := context.Background()
+	created, err := c.CreateKey(ctx, &apitypes.CreateKeyRequest{Name: "k", Prefix: "sk", Environment: "test"})
+	require.NoError(t, err)
+
+	require.NoError(t, c.RevokeKey(ctx, &apitypes.RevokeKeyRequest{KeyID: created.Key.ID, Reason: "check", DryRun: true}))
+	k, err := c.GetKey(ctx, &apitypes.GetKeyRequest{KeyID: created.Key.ID, Fields: "id,state"})
+	require.NoError(t, err)
+	assert.Equal(t, apitypes.KeyStateActive, k.State, "dry_run travels as a query parameter")
+	assert.Empty(t, k.Name, "fields travels as a query parameter")
+}
+
+func TestClient_CreateKeyExternalRef(t *testing.T) {
+	cs := newClientServer(t)
+	c := cs.client("tenant_acme")
+	ctx := context.Background()
+	req := &apitypes.CreateKeyRequest{Name: "k", Prefix: "sk", Environment: "test", ExternalRef: "order-1001"}
+
+	created, err := c.CreateKey(ctx, req)
+	require.NoError(t, err)
+	assert.False(t, created.DuplicateOfExisting)
+	assert.Equal(t, "order-1001", created.Key.ExternalRef)
+
+	again, err := c.CreateKey(ctx, req)
+	require.NoError(t, err)
+	assert.True(t, again.DuplicateOfExisting)
+	assert.Empty(t, again.RawKey)
+	assert.Equal(t, created.Key.ID, again.Key.ID)
+
+	keys, err := c.ListKeys(ctx, &apitypes.ListKeysRequest{ExternalRef: "order-1001"})
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, created.Key.ID, keys[0].ID)
+
+	other, err := cs.client("tenant_globex").CreateKey(ctx, req)
+	require.NoError(t, err)
+	assert.False(t, other.DuplicateOfExisting, "another tenant may reuse the reference")
+}
+
+func TestClient_CreateKeyInlinePolicy(t *testing.T) {
+	cs := newClientServer(t)
+	c := cs.client("tenant_acme")
+	ctx := context.Background()
+
+	created, err := c.CreateKey(ctx, &apitypes.CreateKeyRequest{
+		Name: "k", Prefix: "sk", Environment: "test",
+		InlinePolicy: &apitypes.InlinePolicyRequest{RateLimit: 10, RateLimitWindow: apitypes.Duration(time.Minute), DailyQuota: 500},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, created.Key.PolicyID)
+	pol, err := c.GetPolicy(ctx, &apitypes.GetPolicyRequest{PolicyID: created.Key.PolicyID})
+	require.NoError(t, err)
+	assert.True(t, pol.Inline)
+	assert.Equal(t, 10, pol.RateLimit)
+	assert.Equal(t, int64(500), pol.DailyQuota)
+
+	policies, err := c.ListPolicies(ctx, &apitypes.ListPoliciesRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, policies)
+	policies, err = c.ListPolicies(ctx, &apitypes.ListPoliciesRequest{IncludeInline: true})
+	require.NoError(t, err)
+	assert.Len(t, policies, 1)
+
+	_, err = c.UpdateKey(ctx, &apitypes.UpdateKeyRequest{
+		KeyID:        created.Key.ID,
+		InlinePolicy: &apitypes.InlinePolicyRequest{RateLimit: 20, RateLimitWindow: apitypes.Duration(time.Minute)},
+	})
+	require.NoError(t, err)
+	pol, err = c.GetPolicy(ctx, &apitypes.GetPolicyRequest{PolicyID: created.Key.PolicyID})
+	require.NoError(t, err)
+	assert.Equal(t, 20, pol.RateLimit)
+	assert.Zero(t, pol.DailyQuota)
+
+	_, err = c.CreateKey(ctx, &apitypes.CreateKeyRequest{
+		Name: "k", Prefix: "sk", Environment: "test",
+		InlinePolicy: &apitypes.InlinePolicyRequest{RateLimit: 10},
+	})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidPolicy, "a rate limit needs a window")
+}
+
+func TestClient_RetriesUnavailable(t *testing.T) {
+	cs := newClientServer(t)
+	var mu sync.Mutex
+	failures := 2
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fail := failures > 0
+		failures--
+		mu.Unlock()
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		cs.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(flaky.Close)
+	c := client.New(flaky.URL, client.WithHeader("X-Test-Tenant", "tenant_acme"), client.WithRetry(3, time.Millisecond, 10*time.Millisecond))
+
+	_, err := c.ListKeys(context.Background(), &apitypes.ListKeysRequest{})
+	require.NoError(t, err, "a GET survives two 503s")
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xraph/forge"
+
+	"github.com/xraph/keysmith"
+)
+
+// revisionETag returns the weak ETag of a tenant-scoped GET response: the
+// tenant's revision, which every engine mutation bumps, and a hash of the
+// caller's scope and the request URI, so that equal revisions of different
+// resources do not compare equal. It reads the revision before the handler
+// queries, so a mutation racing the query gives a newer body under an older
+// tag, never the reverse. It is empty for system-scoped callers, whose
+// results span tenants, and when the revision cannot be read.
+func (a *API) revisionETag(ctx forge.Context) string {
+	c := ctx.Context()
+	tenantID := keysmith.TenantIDFromContext(c)
+	if tenantID == "" {
+		return ""
+	}
+	rev, err := a.eng.TenantRevision(c, tenantID)
+	if err != nil {
+		return ""
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(tenantID + "\x00" + keysmith.AppIDFromContext(c) + "\x00" + ctx.Request().URL.RequestURI()))
+	return `W/"` + strconv.FormatUint(rev, 10) + "-" + strconv.FormatUint(h.Sum64(), 16) + `"`
+}
+
+// revisionMatches reports whether the request's If-None-Match matches
+// etag, by the weak comparison RFC 9110 prescribes for GET, and if so sets
+// the validators a 304 carries. If-Modified-Since is not consulted: a
+// deleted item leaves no timestamp behind, so Last-Modified alone cannot
+// tell that a list changed.
+func revisionMatches(ctx forge.Context, etag string) bool {
+	if etag == "" || !etagMatches(ctx.Header("If-None-Match"), etag) {
+		return false
+	}
+	setValidators(ctx, etag, time.Time{})
+	return true
+}
+
+// etagMatches reports whether the If-None-Match value header lists etag or
+// is "*".
+func etagMatches(header, etag string) bool {
+	want := strings.TrimPrefix(etag, "W/")
+	for tag := range strings.SplitSeq(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
+
+// setValidators sets the ETag and, when modified is set, Last-Modified of
+// a response. Cache-Control asks caches to revalidate every time, since a
+// revision only says that nothing changed since the tag was issued.
+func setValidators(ctx forge.Context, etag string, modified time.Time) {
+	if etag == "" {
+		return
+	}
+	ctx.SetHeader("ETag", etag)
+	ctx.SetHeader("Cache-Control", "private, no-cache")
+	if !modified.IsZero() {
+		ctx.SetHeader("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// lastModified returns the latest updatedAt of items, zero when there are
+// none.
+func lastModified[T any](items []T, updatedAt func(T) time.Time) time.Time {
+	var latest time.Time
+	for _, item := range items {
+		if t := updatedAt(item); t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
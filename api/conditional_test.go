@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/policy"
+)
+
+func (f *headerFixture) get(t *testing.T, ctx context.Context, path, ifNoneMatch string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, path, nil).WithContext(ctx)
+	if ifNoneMatch != "" {
+		r.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	w := httptest.NewRecorder()
+	f.handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestConditionalGet(t *testing.T) {
+	f := newHeaderFixture(t)
+	pols, err := f.eng.ListPolicies(f.ctx, &policy.ListFilter{})
+	require.NoError(t, err)
+	require.NotEmpty(t, pols)
+
+	for name, path := range map[string]string{
+		"key":    "/v1/keys/" + f.activeKey.ID.String(),
+		"policy": "/v1/policies/" + pols[0].ID.String(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := f.get(t, f.ctx, path, "")
+			require.Equal(t, http.StatusOK, w.Code)
+			etag := w.Header().Get("ETag")
+			require.NotEmpty(t, etag)
+			assert.Regexp(t, `^W/"\d+-[0-9a-f]+"$`, etag)
+			assert.Equal(t, "private, no-cache", w.Header().Get("Cache-Control"))
+			modified, err := http.ParseTime(w.Header().Get("Last-Modified"))
+			require.NoError(t, err)
+			assert.False(t, modified.IsZero())
+
+			for _, inm := range []string{etag, etag[2:], `"other", ` + etag, "*"} {
+				w = f.get(t, f.ctx, path, inm)
+				assert.Equal(t, http.StatusNotModified, w.Code, inm)
+				assert.Empty(t, w.Body.String())
+				assert.Equal(t, etag, w.Header().Get("ETag"))
+			}
+			assert.Equal(t, http.StatusOK, f.get(t, f.ctx, path, `W/"0-0"`).Code)
+
+			name := "renamed " + path
+			_, err = f.eng.UpdateKey(f.ctx, f.activeKey.ID, &keysmith.UpdateKeyInput{Name: &name})
+			require.NoError(t, err)
+			w = f.get(t, f.ctx, path, etag)
+			assert.Equal(t, http.StatusOK, w.Code, "any mutation in the tenant invalidates the tag")
+			assert.NotEqual(t, etag, w.Header().Get("ETag"))
+		})
+	}
+}
+
+func TestConditionalGet_NoETag(t *testing.T) {
+	f := newHeaderFixture(t)
+	quota := &policy.Policy{Name: "Metered", MonthlyQuota: 1000}
+	require.NoError(t, f.eng.CreatePolicy(f.ctx, quota))
+	metered, err := f.eng.CreateKey(f.ctx, &keysmith.CreateKeyInput{
+		Name: "metered", Prefix: "sk", Environment: key.EnvTest, PolicyID: &quota.ID,
+	})
+	require.NoError(t, err)
+	path := "/v1/keys/" + metered.Key.ID.String()
+
+	w := f.get(t, f.ctx, path, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "quota_forecast")
+	assert.Empty(t, w.Header().Get("ETag"), "the quota forecast follows usage, which the revision does not track")
+	assert.Equal(t, http.StatusOK, f.get(t, f.ctx, path, "*").Code)
+	assert.NotEmpty(t, f.get(t, f.ctx, path+"?fields=id,name", "").Header().Get("ETag"), "without the forecast the tag is back")
+
+	w = f.get(t, context.Background(), path, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("ETag"), "system-scoped responses span tenants")
+}
+
+func TestConditionalGet_DistinctURIs(t *testing.T) {
+	f := newHeaderFixture(t)
+	a := f.get(t, f.ctx, "/v1/keys/"+f.activeKey.ID.String(), "").Header().Get("ETag")
+	b := f.get(t, f.ctx, "/v1/keys/"+f.activeKey.ID.String()+"?fields=id", "").Header().Get("ETag")
+	require.NotEmpty(t, a)
+	assert.NotEqual(t, a, b, "one revision still gives each URI its own tag")
+}
+
+func TestLastModified(t *testing.T) {
+	base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	times := []time.Time{base, base.Add(time.Hour), base.Add(-time.Hour)}
+	assert.Equal(t, base.Add(time.Hour), lastModified(times, func(t time.Time) time.Time { return t }))
+	assert.True(t, lastModified([]time.Time(nil), func(t time.Time) time.Time { return t }).IsZero())
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/xraph/forge"
+
+	"github.com/xraph/keysmith/id"
+)
+
+func (a *API) setKeyDebug(ctx forge.Context, req *SetKeyDebugRequest) (*KeyResponse, error) {
+	keyID, err := id.ParseKeyID(ctx.Param("keyId"))
+	if err != nil {
+		return nil, forge.BadRequest(fmt.Sprintf("invalid key ID: %v", err))
+	}
+
+	k, err := a.eng.SetKeyDebug(ctx.Context(), keyID, req.SampleRate, time.Duration(req.Duration))
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toKeyResponse(k)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) listCaptures(ctx forge.Context, _ *ListCapturesRequest) (*struct{}, error) {
+	keyID, err := id.ParseKeyID(ctx.Param("keyId"))
+	if err != nil {
+		return nil, forge.BadRequest(fmt.Sprintf("invalid key ID: %v", err))
+	}
+
+	captures, err := a.eng.ListCaptures(ctx.Context(), keyID)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := make([]*CaptureResponse, len(captures))
+	for i, c := range captures {
+		resp[i] = toCaptureResponse(c)
+	}
+	return nil, ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/xraph/forge"
+)
+
+// RouteDeprecation announces the retirement of one route, for
+// [WithRouteDeprecations]. Its responses carry a Deprecation header
+// (RFC 9745) and, when set, a Sunset header (RFC 8594) and a Link to the
+// migration documentation, and the route is marked deprecated in the
+// OpenAPI spec. The route keeps working after the sunset.
+type RouteDeprecation struct {
+	// Method and Path identify the route as registered, without the base
+	// path the API is mounted under, such as "GET" and "/v1/keys/:keyId".
+	Method string `json:"method" mapstructure:"method" yaml:"method"`
+	Path   string `json:"path" mapstructure:"path" yaml:"path"`
+
+	// Since is when the route was deprecated. When zero, the Deprecation
+	// header is "true".
+	Since time.Time `json:"since" mapstructure:"since" yaml:"since"`
+
+	// Sunset is when the route is planned to be removed.
+	Sunset time.Time `json:"sunset" mapstructure:"sunset" yaml:"sunset"`
+
+	// Link points to migration documentation.
+	Link string `json:"link" mapstructure:"link" yaml:"link"`
+}
+
+// WithRouteDeprecations flags routes deprecated. It may be repeated; a
+// later deprecation of the same route replaces the earlier one, and
+// deprecations of routes the API does not register are ignored.
+func WithRouteDeprecations(deps ...RouteDeprecation) Option {
+	return func(a *API) {
+		if a.deprecations == nil {
+			a.deprecations = make(map[string]RouteDeprecation, len(deps))
+		}
+		for _, d := range deps {
+			a.deprecations[routeKey(d.Method, d.Path)] = d
+		}
+	}
+}
+
+func routeKey(method, path string) string { return method + " " + path }
+
+// headers returns the response headers announcing d.
+func (d RouteDeprecation) headers() http.Header {
+	h := make(http.Header, 3)
+	if d.Since.IsZero() {
+		h.Set("Deprecation", "true")
+	} else {
+		h.Set("Deprecation", "@"+strconv.FormatInt(d.Since.Unix(), 10))
+	}
+	if !d.Sunset.IsZero() {
+		h.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if d.Link != "" {
+		h.Set("Link", "<"+d.Link+`>; rel="deprecation"; type="text/html"`)
+	}
+	return h
+}
+
+// deprecationRouter registers routes into a forge.Router, adding the
+// headers and OpenAPI flag of the deprecated ones. prefix is the path of
+// the groups it was obtained through.
+type deprecationRouter struct {
+	forge.Router
+	prefix string
+	deps   map[string]RouteDeprecation
+}
+
+func (r *deprecationRouter) Group(prefix string, opts ...forge.GroupOption) forge.Router {
+	return &deprecationRouter{Router: r.Router.Group(prefix, opts...), prefix: r.prefix + prefix, deps: r.deps}
+}
+
+// options appends the deprecation of the route, if any, to opts.
+func (r *deprecationRouter) options(method, path string, opts []forge.RouteOption) []forge.RouteOption {
+	d, ok := r.deps[routeKey(method, r.prefix+path)]
+	if !ok {
+		return opts
+	}
+	h := d.headers()
+	return append(opts, forge.WithDeprecated(), forge.WithMiddleware(func(next forge.Handler) forge.Handler {
+		return func(ctx forge.Context) error {
+			for name := range h {
+				ctx.Response().Header().Set(name, h.Get(name))
+			}
+			return next(ctx)
+		}
+	}))
+}
+
+func (r *deprecationRouter) GET(path string, handler any, opts ...forge.RouteOption) error {
+	return r.Router.GET(path, handler, r.options(http.MethodGet, path, opts)...)
+}
+
+func (r *deprecationRouter) POST(path string, handler any, opts ...forge.RouteOption) error {
+	return r.Router.POST(path, handler, r.options(http.MethodPost, path, opts)...)
+}
+
+func (r *deprecationRouter) PUT(path string, handler any, opts ...forge.RouteOption) error {
+	return r.Router.PUT(path, handler, r.options(http.MethodPut, path, opts)...)
+}
+
+func (r *deprecationRouter) PATCH(path string, handler any, opts ...forge.RouteOption) error {
+	return r.Router.PATCH(path, handler, r.options(http.MethodPatch, path, opts)...)
+}
+
+func (r *deprecationRouter) DELETE(path string, handler any, opts ...forge.RouteOption) error {
+	return r.Router.DELETE(path, handler, r.options(http.MethodDelete, path, opts)...)
+}
+
+func (r *deprecationRouter) HEAD(path string, handler any, opts ...forge.RouteOption) error {
+	return r.Router.HEAD(path, handler, r.options(http.MethodHead, path, opts)...)
+}
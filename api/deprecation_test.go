@@ -0,0 +1,56 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xraph/forge"
+
+	"github.com/xraph/keysmith/api"
+)
+
+func TestRouteDeprecations_Headers(t *testing.T) {
+	eng := newEngine(t)
+	k := createKey(t, eng)
+	router := forge.NewRouter()
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := api.New(eng, router, api.WithRouteDeprecations(
+		api.RouteDeprecation{
+			Method: http.MethodGet, Path: "/v1/keys/:keyId",
+			Since: since, Sunset: time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC),
+			Link: "https://example.com/v2",
+		},
+		api.RouteDeprecation{Method: http.MethodGet, Path: "/v1/scopes"},
+	)).Handler()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/v1/keys/" + k.ID.String())
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "@1767225600", rec.Header().Get("Deprecation"))
+	assert.Equal(t, "Wed, 01 Jul 2026 00:00:00 GMT", rec.Header().Get("Sunset"))
+	assert.Equal(t, `<https://example.com/v2>; rel="deprecation"; type="text/html"`, rec.Header().Get("Link"))
+
+	rec = get("/v1/scopes")
+	assert.Equal(t, "true", rec.Header().Get("Deprecation"), "no since date")
+	assert.Empty(t, rec.Header().Get("Sunset"))
+	assert.Empty(t, rec.Header().Get("Link"))
+
+	rec = get("/v1/keys")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Deprecation"), "other routes are not flagged")
+
+	for _, route := range router.Routes() {
+		flagged := (route.Method == http.MethodGet && route.Path == "/v1/keys/:keyId") ||
+			(route.Method == http.MethodGet && route.Path == "/v1/scopes")
+		assert.Equal(t, flagged, route.Deprecated, "%s %s", route.Method, route.Path)
+	}
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/capture"
+	"github.com/xraph/keysmith/group"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keyevent"
+	"github.com/xraph/keysmith/metaschema"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/quotapool"
+	"github.com/xraph/keysmith/revocation"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/slo"
+	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/tenant"
+	"github.com/xraph/keysmith/tombstone"
+	"github.com/xraph/keysmith/transfer"
+	"github.com/xraph/keysmith/usage"
+)
+
+// Compile-time interface check.
+var _ Engine = (*keysmith.Engine)(nil)
+
+// Engine is what the handlers call: the [keysmith.KeyService] core and the
+// engine methods behind the other routes. [*keysmith.Engine] implements it.
+// Unlike KeyService it grows with the routes, so implement it only
+// alongside this package's version.
+type Engine interface {
+	keysmith.KeyService
+
+	// Lists.
+	PageLimit(limit, def int) int
+
+	// Keys.
+	IterateKeys(ctx context.Context, filter *key.ListFilter, fn func(*key.Key) error) error
+	TenantKeyOverview(ctx context.Context, q *key.OverviewQuery) (*key.Overview, error)
+	SearchKeys(ctx context.Context, query string, opts keysmith.SearchOptions) ([]*key.SearchMatch, error)
+	DuplicateKeyNames(ctx context.Context, tenantID string) ([]*keysmith.KeyNameDuplicate, error)
+	ListKeysByCreator(ctx context.Context, creator string, opts keysmith.CreatorOptions) ([]*key.Key, error)
+	BulkRevokeByCreator(ctx context.Context, creator, reason string, opts keysmith.CreatorOptions) (*keysmith.CreatorRevokeResult, error)
+	BulkRevokeByLabels(ctx context.Context, filter *key.ListFilter, reason string) (*keysmith.BulkRevokeResult, error)
+	KeyContacts(ctx context.Context, keyID id.KeyID) (*keysmith.KeyContacts, error)
+	SetKeyContacts(ctx context.Context, keyID id.KeyID, contacts key.Contacts) (*keysmith.KeyContacts, error)
+	SetKeyDebug(ctx context.Context, keyID id.KeyID, rate float64, d time.Duration) (*key.Key, error)
+	ListCaptures(ctx context.Context, keyID id.KeyID) ([]*capture.Capture, error)
+	ReportCompromise(ctx context.Context, keyID id.KeyID, report *key.CompromiseReport) (*keysmith.CompromiseResult, error)
+	SuspiciousFingerprints(limit int) []*key.FailurePattern
+	LiveKeyStats(ctx context.Context, keyID id.KeyID) (*keysmith.LiveKeyStats, error)
+	LiveTopKeys(ctx context.Context, n int) ([]*keysmith.LiveKeyStats, error)
+	CheckReplay(ctx context.Context, rawKey, nonce string, timestamp time.Time) error
+
+	// Key transfers.
+	InitiateKeyTransfer(ctx context.Context, keyID id.KeyID, targetTenantID string) (*transfer.Transfer, error)
+	AcceptKeyTransfer(ctx context.Context, keyID id.KeyID) (*transfer.Transfer, error)
+	CancelKeyTransfer(ctx context.Context, keyID id.KeyID) (*transfer.Transfer, error)
+	GetKeyTransfer(ctx context.Context, keyID id.KeyID) (*transfer.Transfer, error)
+	ListKeyTransfers(ctx context.Context, filter *transfer.ListFilter) ([]*transfer.Transfer, error)
+
+	// Hashes, rotations, and revocations.
+	BulkValidateHashes(ctx context.Context, hashes []string) ([]keysmith.HashReport, error)
+	RevokeByHashes(ctx context.Context, hashes []string, reason string) ([]keysmith.HashReport, error)
+	RotationChain(ctx context.Context, keyID id.KeyID) ([]*keysmith.HashEra, error)
+	ListRotations(ctx context.Context, filter *rotation.ListFilter) ([]*rotation.Record, error)
+	CountRotationsByReason(ctx context.Context, filter *rotation.ListFilter) ([]*rotation.ReasonCount, error)
+	RevocationsSince(ctx context.Context, after revocation.Cursor, limit int) (*keysmith.RevocationPage, error)
+	ListHashTombstones(ctx context.Context, filter *tombstone.ListFilter) ([]*tombstone.Tombstone, error)
+	CountHashTombstonesByReason(ctx context.Context, filter *tombstone.ListFilter) ([]*tombstone.ReasonCount, error)
+	LockdownTenant(ctx context.Context, tenantID, reason string) (*keysmith.LockdownResult, error)
+	LockdownStatus(ctx context.Context, tenantID string) (*keysmith.LockdownStatus, error)
+	RecoverTenant(ctx context.Context, tenantID string, opts keysmith.RecoveryOptions) (*keysmith.LockdownStatus, error)
+
+	// Key events.
+	SubscribeKeyEvents(ctx context.Context, filter *keyevent.Filter) (<-chan *keyevent.Event, func())
+	KeyEventsSince(ctx context.Context, after keyevent.Cursor, filter *keyevent.Filter, limit int) (*keysmith.KeyEventPage, error)
+
+	// Policies and scopes.
+	EffectivePolicy(ctx context.Context, polID id.PolicyID) (*policy.Effective, error)
+	CreatePolicyFromTemplate(ctx context.Context, templateName string, overrides *policy.Policy, clearFields ...string) (*policy.Policy, error)
+	IterateScopes(ctx context.Context, filter *scope.ListFilter, fn func(*scope.Scope) error) error
+
+	// Groups.
+	CreateGroup(ctx context.Context, g *group.Group) error
+	GetGroup(ctx context.Context, groupID id.GroupID) (*group.Group, error)
+	UpdateGroup(ctx context.Context, g *group.Group) error
+	DeleteGroup(ctx context.Context, groupID id.GroupID) error
+	ListGroups(ctx context.Context, filter *group.ListFilter) ([]*group.Group, error)
+	AddKeysToGroup(ctx context.Context, groupID id.GroupID, keyIDs []id.KeyID) error
+	RemoveKeysFromGroup(ctx context.Context, groupID id.GroupID, keyIDs []id.KeyID) error
+	KeyGroups(ctx context.Context, keys []*key.Key) (map[id.KeyID][]id.GroupID, error)
+	RotateGroup(ctx context.Context, groupID id.GroupID, reason rotation.Reason) (*keysmith.GroupResult, error)
+	RevokeGroup(ctx context.Context, groupID id.GroupID, reason string) (*keysmith.GroupResult, error)
+
+	// Quota pools.
+	CreateQuotaPool(ctx context.Context, p *quotapool.Pool) error
+	GetQuotaPool(ctx context.Context, poolID id.QuotaPoolID) (*quotapool.Pool, error)
+	UpdateQuotaPool(ctx context.Context, p *quotapool.Pool) error
+	DeleteQuotaPool(ctx context.Context, poolID id.QuotaPoolID) error
+	ListQuotaPools(ctx context.Context, filter *quotapool.ListFilter) ([]*quotapool.Pool, error)
+	AddKeysToQuotaPool(ctx context.Context, poolID id.QuotaPoolID, keyIDs []id.KeyID) error
+	RemoveKeysFromQuotaPool(ctx context.Context, poolID id.QuotaPoolID, keyIDs []id.KeyID) error
+	QuotaPoolUsage(ctx context.Context, poolID id.QuotaPoolID) (*quotapool.Consumption, error)
+
+	// Usage.
+	QueryUsage(ctx context.Context, filter *usage.QueryFilter) ([]*usage.Record, error)
+	AggregateUsage(ctx context.Context, filter *usage.QueryFilter) ([]*usage.Aggregation, error)
+	ListEndpointActivity(ctx context.Context, keyID id.KeyID) ([]*usage.EndpointActivity, error)
+	QuotaForecast(ctx context.Context, keyID id.KeyID) (*key.QuotaForecast, error)
+	UsageRecording() usage.RecordingPolicy
+	SetUsageRecording(p usage.RecordingPolicy) error
+
+	// Tenants.
+	TenantRevision(ctx context.Context, tenantID string) (uint64, error)
+	GetTenantSettings(ctx context.Context, tenantID string) (*tenant.Settings, error)
+	SetTenantSettings(ctx context.Context, ts *tenant.Settings) error
+	SetMetadataSchema(ctx context.Context, tenantID string, schema *metaschema.Schema) (*tenant.Settings, error)
+
+	// Operations.
+	ReadOnly() bool
+	SetReadOnly(on bool)
+	UsageRecordingInReadOnly() bool
+	GetRuntimeConfig() keysmith.RuntimeConfig
+	UpdateRuntimeConfig(ctx context.Context, patch *keysmith.RuntimeConfigPatch) (keysmith.RuntimeConfig, error)
+	ReplayStats() *keysmith.ReplayStats
+	SLOStatus() []slo.Status
+	MaintainStoreWith(ctx context.Context, opts store.MaintenanceOptions) (*store.MaintenanceReport, error)
+	TenantStorageReportWith(ctx context.Context, opts keysmith.StorageReportOptions) (*store.StorageReport, error)
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/xraph/forge"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/keyevent"
+)
+
+// streamKeyEvents serves the key event stream. It subscribes before reading
+// the history, so no event falls between the replay and the live stream,
+// and skips the live copies of replayed events so each is sent once.
+func (a *API) streamKeyEvents(ctx forge.Context, req *StreamKeyEventsRequest) (*KeyEventResponse, error) {
+	filter, err := keyEventFilter(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var after keyevent.Cursor
+	resume := req.LastEventID
+	if resume == "" {
+		resume = req.After
+	}
+	if resume != "" {
+		if after, err = keyevent.ParseCursor(resume); err != nil {
+			return nil, forge.BadRequest(err.Error())
+		}
+	}
+
+	reqCtx := ctx.Context()
+	events, cancel := a.eng.SubscribeKeyEvents(reqCtx, filter)
+	defer cancel()
+
+	// Read the first page before the stream starts, so a cursor outside the
+	// lookback window is still answered with 400.
+	var page *keysmith.KeyEventPage
+	if resume != "" {
+		if page, err = a.eng.KeyEventsSince(reqCtx, after, filter, 0); err != nil {
+			return nil, mapStoreError(err)
+		}
+	}
+
+	h := ctx.Response().Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	ctx.Response().WriteHeader(http.StatusOK)
+	if err := ctx.Flush(); err != nil {
+		return nil, nil
+	}
+
+	// Once the stream has started, errors end it; the client reconnects
+	// with the last event's ID.
+	replayed := make(map[string]struct{})
+	for page != nil {
+		for _, e := range page.Events {
+			if writeKeyEvent(ctx, e) != nil {
+				return nil, nil
+			}
+			replayed[e.ID()] = struct{}{}
+		}
+		if !page.HasMore {
+			break
+		}
+		next, _ := keyevent.ParseCursor(page.Next)
+		if page, err = a.eng.KeyEventsSince(reqCtx, next, filter, 0); err != nil {
+			return nil, nil
+		}
+	}
+
+	heartbeat := time.NewTicker(a.eventHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-reqCtx.Done():
+			return nil, nil
+		case <-heartbeat.C:
+			if _, err := ctx.Response().Write([]byte(": heartbeat\n\n")); err != nil || ctx.Flush() != nil {
+				return nil, nil
+			}
+		case e, ok := <-events:
+			if !ok {
+				// Dropped as a slow consumer, or the engine is stopping.
+				return nil, nil
+			}
+			if _, dup := replayed[e.ID()]; dup {
+				delete(replayed, e.ID())
+				continue
+			}
+			if writeKeyEvent(ctx, e) != nil {
+				return nil, nil
+			}
+		}
+	}
+}
+
+// keyEventFilter parses the stream's type and key filters.
+func keyEventFilter(req *StreamKeyEventsRequest) (*keyevent.Filter, error) {
+	filter := &keyevent.Filter{}
+	if req.Types != "" {
+		for _, t := range strings.Split(req.Types, ",") {
+			typ := keyevent.Type(strings.TrimSpace(t))
+			if !typ.Valid() {
+				return nil, forge.BadRequest(fmt.Sprintf("unknown event type %q", typ))
+			}
+			filter.Types = append(filter.Types, typ)
+		}
+	}
+	if req.KeyID != "" {
+		keyID, err := id.ParseKeyID(req.KeyID)
+		if err != nil {
+			return nil, forge.BadRequest(fmt.Sprintf("invalid key ID: %v", err))
+		}
+		filter.KeyID = &keyID
+	}
+	return filter, nil
+}
+
+// writeKeyEvent writes e as one SSE frame and flushes it.
+func writeKeyEvent(ctx forge.Context, e *keyevent.Event) error {
+	data, err := json.Marshal(toKeyEventResponse(e))
+	if err != nil {
+		return err
+	}
+	frame := fmt.Sprintf("id: %s\nevent: %s\ndata: %s\n\n", e.ID(), e.Type, data)
+	if _, err := ctx.Response().Write([]byte(frame)); err != nil {
+		return err
+	}
+	return ctx.Flush()
+}
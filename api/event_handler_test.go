@@ -0,0 +1,190 @@
+package api_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/api"
+	"github.com/xraph/keysmith/apitypes"
+	"github.com/xraph/keysmith/client"
+	"github.com/xraph/keysmith/key"
+)
+
+var errStopStream = errors.New("stop stream")
+
+// newEventServer serves the keysmith API for eng with every request in
+// tenant_acme.
+func newEventServer(t *testing.T, eng *keysmith.Engine, opts ...api.Option) *httptest.Server {
+	t.Helper()
+	h := api.New(eng, nil, opts...).Handler()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(keysmith.WithTenant(r.Context(), "app_1", "tenant_acme")))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func acmeCtx() context.Context {
+	return keysmith.WithTenant(context.Background(), "app_1", "tenant_acme")
+}
+
+func createAcmeKey(t *testing.T, eng *keysmith.Engine, name string) *key.Key {
+	t.Helper()
+	result, err := eng.CreateKey(acmeCtx(), &keysmith.CreateKeyInput{Name: name, Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	return result.Key
+}
+
+// streamInto streams events into a channel until the test ends or cancel is
+// called.
+func streamInto(t *testing.T, c *client.Client, req *apitypes.StreamKeyEventsRequest) (<-chan *apitypes.KeyEventResponse, context.CancelFunc) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	ch := make(chan *apitypes.KeyEventResponse, 16)
+	go func() {
+		_ = c.StreamKeyEvents(ctx, req, func(e *apitypes.KeyEventResponse) error {
+			ch <- e
+			return nil
+		})
+		close(ch)
+	}()
+	return ch, cancel
+}
+
+func nextEvent(t *testing.T, ch <-chan *apitypes.KeyEventResponse) *apitypes.KeyEventResponse {
+	t.Helper()
+	select {
+	case e, ok := <-ch:
+		require.True(t, ok, "stream ended")
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event")
+		return nil
+	}
+}
+
+// cursorNow returns an event ID positioned now. Streams resumed from it
+// replay whatever they subscribe too late to see live.
+func cursorNow() string {
+	return fmt.Sprintf("%d.", time.Now().UnixNano())
+}
+
+func TestStreamKeyEvents_Framing(t *testing.T) {
+	eng := newEngine(t)
+	srv := newEventServer(t, eng, api.WithEventHeartbeat(20*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/v1/events/stream", http.NoBody)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "no-cache", resp.Header.Get("Cache-Control"))
+
+	lines := bufio.NewScanner(resp.Body)
+	require.True(t, lines.Scan())
+	assert.Equal(t, ": heartbeat", lines.Text(), "an idle stream sends heartbeat comments")
+
+	k := createAcmeKey(t, eng, "framed")
+	var frame []string
+	for lines.Scan() {
+		line := lines.Text()
+		if line == "" && len(frame) > 0 {
+			break
+		}
+		if line != "" && !strings.HasPrefix(line, ":") {
+			frame = append(frame, line)
+		}
+	}
+	require.Len(t, frame, 3)
+	assert.Regexp(t, `^id: \d+\.`+k.ID.String()+`$`, frame[0])
+	assert.Equal(t, "event: key.created", frame[1])
+	require.True(t, strings.HasPrefix(frame[2], "data: {"))
+	assert.Contains(t, frame[2], `"event_id":"`+strings.TrimPrefix(frame[0], "id: ")+`"`)
+	assert.Contains(t, frame[2], `"key_id":"`+k.ID.String()+`"`)
+	assert.NotContains(t, frame[2], k.KeyHash, "events never carry hashes")
+	assert.NotContains(t, frame[2], "hash")
+}
+
+func TestStreamKeyEvents_TypeFilter(t *testing.T) {
+	eng := newEngine(t)
+	c := client.New(newEventServer(t, eng).URL)
+
+	events, _ := streamInto(t, c, &apitypes.StreamKeyEventsRequest{Types: "key.revoked", LastEventID: cursorNow()})
+	a := createAcmeKey(t, eng, "a")
+	require.NoError(t, eng.RevokeKey(acmeCtx(), a.ID, "retired"))
+
+	e := nextEvent(t, events)
+	assert.Equal(t, "key.revoked", e.Type)
+	assert.Equal(t, a.ID.String(), e.KeyID)
+	assert.Equal(t, "retired", e.Reason)
+}
+
+func TestStreamKeyEvents_ResumeAfterDisconnect(t *testing.T) {
+	eng := newEngine(t)
+	c := client.New(newEventServer(t, eng).URL)
+
+	events, disconnect := streamInto(t, c, &apitypes.StreamKeyEventsRequest{LastEventID: cursorNow()})
+	a := createAcmeKey(t, eng, "a")
+	last := nextEvent(t, events)
+	require.Equal(t, a.ID.String(), last.KeyID)
+	disconnect()
+	for range events {
+	}
+
+	// Missed while disconnected.
+	b := createAcmeKey(t, eng, "b")
+	require.NoError(t, eng.RevokeKey(acmeCtx(), a.ID, "leaked"))
+
+	events, _ = streamInto(t, c, &apitypes.StreamKeyEventsRequest{LastEventID: last.EventID})
+	d := createAcmeKey(t, eng, "d")
+
+	var got []string
+	for len(got) < 3 {
+		e := nextEvent(t, events)
+		got = append(got, e.Type+" "+e.KeyID)
+	}
+	assert.Equal(t, []string{
+		"key.created " + b.ID.String(),
+		"key.revoked " + a.ID.String(),
+		"key.created " + d.ID.String(),
+	}, got, "the missed events are replayed once, then the stream goes live")
+
+	select {
+	case e := <-events:
+		t.Fatalf("unexpected event %s %s", e.Type, e.KeyID)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestStreamKeyEvents_Errors(t *testing.T) {
+	eng := newEngine(t)
+	c := client.New(newEventServer(t, eng).URL)
+	ctx := context.Background()
+	never := func(*apitypes.KeyEventResponse) error { return nil }
+
+	err := c.StreamKeyEvents(ctx, &apitypes.StreamKeyEventsRequest{Types: "key.deleted"}, never)
+	assert.ErrorIs(t, err, client.ErrInvalidRequest)
+
+	err = c.StreamKeyEvents(ctx, &apitypes.StreamKeyEventsRequest{LastEventID: "yesterday"}, never)
+	assert.ErrorIs(t, err, client.ErrInvalidRequest)
+
+	stale := fmt.Sprintf("%d.", time.Now().Add(-keysmith.DefaultKeyEventLookback-time.Hour).UnixNano())
+	err = c.StreamKeyEvents(ctx, &apitypes.StreamKeyEventsRequest{LastEventID: stale}, never)
+	assert.ErrorIs(t, err, keysmith.ErrKeyEventRangeTooLarge)
+}
@@ -0,0 +1,1318 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/xraph/forge"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/capture"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/metaschema"
+	"github.com/xraph/keysmith/middleware"
+	"github.com/xraph/keysmith/transfer"
+	"github.com/xraph/keysmith/usage"
+)
+
+// Example values shared by the route examples. Raw keys are recognisably
+// fake and IDs are valid TypeIDs, so copied examples behave predictably
+// against a sandbox: a pasted key fails validation instead of matching a
+// real one, and a pasted ID returns 404 instead of 400.
+const (
+	exampleKeyID        = "akey_01m4wms908fh99berht8ytte6h"
+	exampleRotatedKeyID = "akey_01m4wms908fh9t0nteck3kczjx"
+	examplePolicyID     = "kpol_01m4wms908fha80h1h4fmq1kpn"
+	exampleScopeID      = "kscp_01m4wms908fhar5rgjb8jtryba"
+	exampleRotationID   = "krot_01m4wms908fhb8cjhh73cey9sf"
+	exampleUsageID      = "kusg_01m4wms908fhbsxmesxmg8eq85"
+	exampleCaptureID    = "kcap_01m4wms908fhcbxdbap7shzh6s"
+	exampleGroupID      = "kgrp_01m4wms908fhd3k2a6y1x9q7zr"
+	exampleTransferID   = "kxfr_01m4wms908fhdnb4d3gv2w3pj5"
+	examplePoolID       = "kqpl_01m4wms908fhe6yd8cv0hpsdzw"
+	exampleTenantID     = "tenant_123"
+	exampleAppID        = "app_1"
+
+	exampleRawKey        = "sk_test_examplexxxxxxxxxxxxxxxxxxxx"
+	exampleRotatedRawKey = "sk_test_exampleyyyyyyyyyyyyyyyyyyyy"
+
+	// Example key_hash values; neither is the hash of a real key.
+	exampleKeyHash     = "sha256:4f1c2a9e7b3d5f60a8c1e2d3b4a5968778695a4b3c2d1e0f9a8b7c6d5e4f3a2b"
+	exampleMissingHash = "sha256:0d9e8f7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e"
+)
+
+// exampleTime is the reference time used by every example.
+var exampleTime = time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+// Example is a sample request and response for one API operation.
+type Example struct {
+	// Request is the operation's request DTO. Path and query fields fill in
+	// the URL; the remaining fields are the JSON body.
+	Request any
+
+	// Status is the success status code, and Response the body returned
+	// with it. Response is nil for operations that return 204.
+	Status   int
+	Response any
+}
+
+// Examples returns the example for every API operation, keyed by OpenAPI
+// operation ID. The same examples are published in the OpenAPI document.
+func Examples() map[string]Example {
+	return buildExamples()
+}
+
+// registeredExamples backs withExamples.
+var registeredExamples = buildExamples()
+
+// withExamples attaches the request and response examples for an
+// operation to its route.
+func withExamples(operationID string) forge.RouteOption {
+	return exampleOption(operationID)
+}
+
+type exampleOption string
+
+func (o exampleOption) Apply(cfg *forge.RouteConfig) {
+	ex, ok := registeredExamples[string(o)]
+	if !ok {
+		return
+	}
+	if body := exampleBody(ex.Request); len(body) > 0 {
+		forge.WithRequestExample("example", body).Apply(cfg)
+	}
+	if ex.Response != nil {
+		forge.WithResponseExample(ex.Status, "example", ex.Response).Apply(cfg)
+	}
+}
+
+// exampleBody returns the JSON body fields of a request DTO, dropping the
+// path and query fields that belong in the URL.
+func exampleBody(req any) map[string]any {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil
+	}
+	var body map[string]any
+	if err := json.Unmarshal(b, &body); err != nil {
+		return nil
+	}
+	for _, name := range urlFields(reflect.TypeOf(req)) {
+		delete(body, name)
+	}
+	return body
+}
+
+// urlFields returns the Go names of t's path and query fields, which
+// encoding/json writes under those names since they have no json tag.
+func urlFields(t reflect.Type) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var names []string
+	for i := range t.NumField() {
+		f := t.Field(i)
+		switch {
+		case f.Anonymous:
+			names = append(names, urlFields(f.Type)...)
+		case f.Tag.Get("path") != "" || f.Tag.Get("query") != "":
+			names = append(names, f.Name)
+		}
+	}
+	return names
+}
+
+// exampleTenantLockdownStatus is a tenant part way through its recovery.
+func exampleTenantLockdownStatus() *TenantLockdownStatusResponse {
+	started := exampleTime.Add(2 * time.Hour)
+	return &TenantLockdownStatusResponse{
+		TenantID: exampleTenantID,
+		Locked:   true,
+		Lockdown: &TenantLockdownResponse{
+			Phase:     "recovering",
+			Reason:    "account takeover INC-4521",
+			ActorID:   "user_42",
+			LockedAt:  exampleTime,
+			Suspended: 40,
+			Recovery: &TenantRecoveryResponse{
+				StartedAt:     started,
+				BatchSize:     25,
+				BatchInterval: Duration(10 * time.Minute),
+				NextBatchAt:   started.Add(10 * time.Minute),
+				Batches:       1,
+				Reactivated:   25,
+				Remaining:     15,
+			},
+		},
+		Pending: 15,
+	}
+}
+
+func exampleKey() *KeyResponse {
+	expires := exampleTime.AddDate(1, 0, 0)
+	return &KeyResponse{
+		ID:          exampleKeyID,
+		TenantID:    exampleTenantID,
+		AppID:       exampleAppID,
+		Name:        "Production Key",
+		Prefix:      "sk",
+		Hint:        "xxxx",
+		Environment: "test",
+		State:       "active",
+		PolicyID:    examplePolicyID,
+		Scopes:      []string{"read:users", "write:users"},
+		Metadata:    map[string]any{"plan": "pro"},
+		CreatedBy:   "user_42",
+		ExternalRef: "order-1001",
+		ExpiresAt:   &expires,
+		CreatedAt:   exampleTime,
+		UpdatedAt:   exampleTime,
+
+		Labels: key.Labels{"team": "payments"},
+
+		Groups: []string{exampleGroupID},
+
+		AcceptedTermsVersion: "2024-01",
+		AcceptedTermsAt:      &exampleTime,
+	}
+}
+
+// exampleLiveKeyStats is a busy key with a few failed validations.
+func exampleLiveKeyStats() *LiveKeyStatsResponse {
+	since, last := exampleTime, exampleTime.Add(10*time.Minute)
+	return &LiveKeyStatsResponse{
+		KeyID:         exampleKeyID,
+		TenantID:      exampleTenantID,
+		AppID:         exampleAppID,
+		QPS:           42.5,
+		Rate1m:        39.8,
+		Rate5m:        31.2,
+		FailureRatio:  0.02,
+		Validations5m: 9360,
+		TrackedSince:  &since,
+		LastSeen:      &last,
+	}
+}
+
+// exampleKeyTransfer is a pending transfer of the example key to another
+// tenant.
+func exampleKeyTransfer() *KeyTransferResponse {
+	return &KeyTransferResponse{
+		ID:             exampleTransferID,
+		KeyID:          exampleKeyID,
+		AppID:          exampleAppID,
+		SourceTenantID: exampleTenantID,
+		TargetTenantID: "tenant_456",
+		State:          string(transfer.StatePending),
+		InitiatedBy:    "user_42",
+		ExpiresAt:      exampleTime.Add(keysmith.DefaultKeyTransferTTL),
+		CreatedAt:      exampleTime,
+	}
+}
+
+// exampleAcceptedKeyTransfer is the example transfer after the target
+// tenant accepted it, without a scope of the key's.
+func exampleAcceptedKeyTransfer() *KeyTransferResponse {
+	t := exampleKeyTransfer()
+	resolved := exampleTime.Add(2 * time.Hour)
+	t.State = string(transfer.StateAccepted)
+	t.ResolvedBy = "user_77"
+	t.ResolvedAt = &resolved
+	t.UnmappedScopes = []string{"billing:write"}
+	return t
+}
+
+// exampleCancelledKeyTransfer is the example transfer after its initiator
+// cancelled it.
+func exampleCancelledKeyTransfer() *KeyTransferResponse {
+	t := exampleKeyTransfer()
+	resolved := exampleTime.Add(time.Hour)
+	t.State = string(transfer.StateCancelled)
+	t.ResolvedBy = "user_42"
+	t.ResolvedAt = &resolved
+	return t
+}
+
+// exampleKeyContacts is a key whose owning team overrides the tenant's
+// security mailbox.
+func exampleKeyContacts() *KeyContactsResponse {
+	own := key.Contacts{
+		{Type: key.ContactEmail, Target: "payments-team@example.com"},
+		{Type: key.ContactSlack, Target: "#payments-oncall"},
+	}
+	return &KeyContactsResponse{
+		KeyID:          exampleKeyID,
+		Contacts:       own,
+		TenantDefaults: key.Contacts{{Type: key.ContactEmail, Target: "security@example.com"}},
+		Effective:      own,
+	}
+}
+
+// exampleQuotaForecast is ten days into a 31-day month at 20,000 requests a
+// day against a 500,000 quota.
+func exampleQuotaForecast() *QuotaForecastResponse {
+	month := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	exhaustion := month.AddDate(0, 0, 25)
+	return &QuotaForecastResponse{
+		KeyID:               exampleKeyID,
+		Month:               month,
+		AsOf:                month.AddDate(0, 0, 10),
+		Quota:               500000,
+		Used:                200000,
+		BurnRate:            20000,
+		ProjectedTotal:      620000,
+		ProjectedExhaustion: &exhaustion,
+	}
+}
+
+func exampleKeyDetails() *KeyResponse {
+	k := exampleKey()
+	f := exampleQuotaForecast()
+	k.QuotaForecast = &QuotaForecastSummary{
+		Used:                f.Used,
+		Quota:               f.Quota,
+		ProjectedTotal:      f.ProjectedTotal,
+		ProjectedExhaustion: f.ProjectedExhaustion,
+	}
+	return k
+}
+
+func exampleRotatedKey() *KeyResponse {
+	k := exampleKey()
+	k.ID = exampleRotatedKeyID
+	k.Hint = "yyyy"
+	k.CreatedAt = exampleTime.Add(time.Hour)
+	k.UpdatedAt = k.CreatedAt
+	return k
+}
+
+func examplePolicyRequest() CreatePolicyRequest {
+	return CreatePolicyRequest{
+		Name:            "Standard",
+		Description:     "Default limits for partner integrations",
+		RateLimit:       1000,
+		RateLimitWindow: Duration(time.Minute),
+		BurstLimit:      50,
+		AllowedScopes:   []string{"read:users", "write:users"},
+		AllowedOrigins:  []string{"https://*.example.com"},
+		GracePeriod:     Duration(24 * time.Hour),
+		DailyQuota:      100000,
+		QuotaTimezone:   "America/New_York",
+		MinTLSVersion:   "1.2",
+	}
+}
+
+func examplePolicy() *PolicyResponse {
+	return &PolicyResponse{
+		ID:              examplePolicyID,
+		TenantID:        exampleTenantID,
+		AppID:           exampleAppID,
+		Name:            "Standard",
+		Description:     "Default limits for partner integrations",
+		RateLimit:       1000,
+		RateLimitWindow: Duration(time.Minute),
+		BurstLimit:      50,
+		AllowedScopes:   []string{"read:users", "write:users"},
+		AllowedOrigins:  []string{"https://*.example.com"},
+		GracePeriod:     Duration(24 * time.Hour),
+		DailyQuota:      100000,
+		QuotaTimezone:   "America/New_York",
+		MinTLSVersion:   "1.2",
+		CreatedAt:       exampleTime,
+		UpdatedAt:       exampleTime,
+	}
+}
+
+func exampleScope() *ScopeResponse {
+	return &ScopeResponse{
+		ID:          exampleScopeID,
+		TenantID:    exampleTenantID,
+		AppID:       exampleAppID,
+		Name:        "read:users",
+		Description: "Read user profiles",
+		Parent:      "read",
+		CreatedAt:   exampleTime,
+	}
+}
+
+func exampleGroup() *GroupResponse {
+	return &GroupResponse{
+		ID:          exampleGroupID,
+		TenantID:    exampleTenantID,
+		AppID:       exampleAppID,
+		Name:        "Mobile apps",
+		Description: "Keys shipped in the iOS and Android apps",
+		CreatedAt:   exampleTime,
+		UpdatedAt:   exampleTime,
+	}
+}
+
+func exampleQuotaPool() *QuotaPoolResponse {
+	return &QuotaPoolResponse{
+		ID:           examplePoolID,
+		TenantID:     exampleTenantID,
+		AppID:        exampleAppID,
+		Name:         "Acme Pro plan",
+		Description:  "Shared by all of Acme's keys",
+		MonthlyLimit: 1000000,
+		CreatedAt:    exampleTime,
+		UpdatedAt:    exampleTime,
+	}
+}
+
+// exampleQuotaPoolUsage is the pool of exampleQuotaPool at the example
+// time, with two member keys.
+func exampleQuotaPoolUsage() *QuotaPoolUsageResponse {
+	day := exampleTime.Truncate(24 * time.Hour)
+	month := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return &QuotaPoolUsageResponse{
+		PoolID: examplePoolID,
+		AsOf:   exampleTime,
+		Day: QuotaWindowUsageResponse{
+			Period:  "day",
+			Start:   day,
+			End:     day.AddDate(0, 0, 1),
+			Used:    21000,
+			Members: []QuotaMemberUsageResponse{{KeyID: exampleKeyID, Used: 15000}, {KeyID: exampleRotatedKeyID, Used: 6000}},
+		},
+		Month: QuotaWindowUsageResponse{
+			Period:    "month",
+			Start:     month,
+			End:       month.AddDate(0, 1, 0),
+			Limit:     1000000,
+			Used:      300000,
+			Remaining: 700000,
+			Members:   []QuotaMemberUsageResponse{{KeyID: exampleKeyID, Used: 210000}, {KeyID: exampleRotatedKeyID, Used: 90000}},
+		},
+	}
+}
+
+func exampleAggregation() []*AggregationResponse {
+	return []*AggregationResponse{{
+		KeyID:        exampleKeyID,
+		TenantID:     exampleTenantID,
+		Period:       "day",
+		PeriodStart:  exampleTime.Truncate(24 * time.Hour),
+		RequestCount: 1200,
+		ErrorCount:   3,
+		TotalLatency: 14400,
+		P50Latency:   9,
+		P99Latency:   85,
+	}}
+}
+
+func exampleTenantSettings() *TenantSettingsResponse {
+	return &TenantSettingsResponse{
+		TenantID:        exampleTenantID,
+		DefaultScopes:   []string{"read:users"},
+		RateLimit:       10000,
+		RateLimitWindow: Duration(time.Minute),
+		IPHandling:      usage.IPTruncate,
+		QuotaTimezone:   "Europe/Berlin",
+		UniqueKeyNames:  true,
+		CreatedAt:       exampleTime,
+		UpdatedAt:       exampleTime,
+	}
+}
+
+func examplePlanSchema() map[string]metaschema.Field {
+	return map[string]metaschema.Field{
+		"plan": {Type: metaschema.TypeString, Required: true, Enum: []any{"free", "pro"}},
+	}
+}
+
+func buildExamples() map[string]Example {
+	name := "Storefront widget"
+	consumer := "storefront-web"
+	groupDescription := "Keys shipped in the iOS and Android apps"
+	poolLimit := int64(2000000)
+	expires := exampleTime.AddDate(1, 0, 0)
+
+	updatedPool := exampleQuotaPool()
+	updatedPool.MonthlyLimit = poolLimit
+
+	updated := exampleKey()
+	updated.Name = name
+	updated.AllowedOrigins = []string{"https://*.example.com"}
+	updated.IntendedConsumer = consumer
+	updated.UpdatedAt = exampleTime.Add(time.Hour)
+
+	debugUntil := exampleTime.Add(30 * time.Minute)
+	debugged := exampleKey()
+	debugged.DebugSampleRate = 0.1
+	debugged.DebugUntil = &debugUntil
+
+	replacedAt, replacedGraceEnds := exampleTime.Add(time.Hour), exampleTime.Add(25*time.Hour)
+	summaryEnd := exampleTime.AddDate(0, 3, 0)
+
+	schemaSettings := exampleTenantSettings()
+	schemaSettings.MetadataSchema = &metaschema.Schema{Fields: examplePlanSchema()}
+
+	return map[string]Example{
+		// Keys.
+		"createKey": {
+			Request: CreateKeyRequest{
+				Name:        "Production Key",
+				Prefix:      "sk",
+				Environment: "test",
+				PolicyID:    examplePolicyID,
+				Scopes:      []string{"read:users", "write:users"},
+				Metadata:    map[string]any{"plan": "pro"},
+				ExpiresAt:   &expires,
+
+				Labels: key.Labels{"team": "payments"},
+
+				AcceptedTermsVersion: "2024-01",
+
+				ExternalRef: "order-1001",
+			},
+			Status: http.StatusCreated,
+			Response: &KeyCreateResponse{
+				Key:    exampleKey(),
+				RawKey: exampleRawKey,
+				Scopes: []string{"read:users", "write:users"},
+			},
+		},
+		"listKeys": {
+			Request:  ListKeysRequest{Environment: "test", State: "active", LabelSelector: "team=payments", Limit: 50},
+			Status:   http.StatusOK,
+			Response: []*KeyResponse{exampleKey()},
+		},
+		"getKeyOverview": {
+			Request: KeyOverviewRequest{ExpiringWithinDays: "7,30,90", CreatedWithinDays: 30},
+			Status:  http.StatusOK,
+			Response: &KeyOverviewResponse{
+				Total:         42,
+				ByState:       map[KeyState]int64{"active": 35, "revoked": 5, "suspended": 2},
+				ByEnvironment: map[KeyEnvironment]int64{"live": 30, "test": 12},
+				Expiring: []*OverviewWindowResponse{
+					{WithinDays: 7, Count: 2},
+					{WithinDays: 30, Count: 6},
+					{WithinDays: 90, Count: 11},
+				},
+				NeverUsed:       4,
+				CreatedRecently: &OverviewWindowResponse{WithinDays: 30, Count: 9},
+			},
+		},
+		"getKey": {
+			Request:  GetKeyRequest{KeyID: exampleKeyID},
+			Status:   http.StatusOK,
+			Response: exampleKeyDetails(),
+		},
+		"updateKey": {
+			Request: UpdateKeyRequest{
+				KeyID:          exampleKeyID,
+				Name:           &name,
+				AllowedOrigins: []string{"https://*.example.com"},
+
+				IntendedConsumer: &consumer,
+			},
+			Status:   http.StatusOK,
+			Response: updated,
+		},
+		"revokeByLabels": {
+			Request: RevokeByLabelsRequest{LabelSelector: "team=payments", Reason: "team disbanded"},
+			Status:  http.StatusOK,
+			Response: &LabelRevokeResponse{
+				LabelSelector: "team=payments",
+				KeyIDs:        []string{exampleKeyID},
+			},
+		},
+		"deleteKey": {
+			Request: DeleteKeyRequest{KeyID: exampleKeyID},
+			Status:  http.StatusNoContent,
+		},
+		"rotateKey": {
+			Request: RotateKeyRequest{KeyID: exampleKeyID, Reason: "manual"},
+			Status:  http.StatusOK,
+			Response: &KeyCreateResponse{
+				Key:    exampleRotatedKey(),
+				RawKey: exampleRotatedRawKey,
+				Scopes: []string{"read:users", "write:users"},
+			},
+		},
+		"revokeKey": {
+			Request: RevokeKeyRequest{KeyID: exampleKeyID, Reason: "integration retired"},
+			Status:  http.StatusNoContent,
+		},
+		"reportKeyCompromise": {
+			Request: ReportCompromiseRequest{
+				KeyID:   exampleKeyID,
+				Source:  "github-secret-scanning",
+				Details: "Found in a public gist",
+				Action:  "rotate",
+			},
+			Status: http.StatusOK,
+			Response: &CompromiseResponse{
+				Action: "rotate",
+				Key:    exampleRotatedKey(),
+				RawKey: exampleRotatedRawKey,
+			},
+		},
+		"suspendKey": {
+			Request: SuspendKeyRequest{KeyID: exampleKeyID},
+			Status:  http.StatusNoContent,
+		},
+		"reactivateKey": {
+			Request: ReactivateKeyRequest{KeyID: exampleKeyID},
+			Status:  http.StatusNoContent,
+		},
+		"getKeyContacts": {
+			Request:  GetKeyContactsRequest{KeyID: exampleKeyID},
+			Status:   http.StatusOK,
+			Response: exampleKeyContacts(),
+		},
+		"search": {
+			Request: SearchRequest{Query: `production "pro"`, Limit: 20},
+			Status:  http.StatusOK,
+			Response: &SearchResponse{Keys: []*KeySearchMatchResponse{{
+				Key:     exampleKey(),
+				Fields:  []string{"name", "metadata.plan"},
+				Snippet: "Production Key",
+				Score:   4,
+			}}},
+		},
+		"listDuplicateKeyNames": {
+			Request: ListDuplicateKeyNamesRequest{},
+			Status:  http.StatusOK,
+			Response: []*KeyNameDuplicateResponse{{
+				TenantID: exampleTenantID,
+				Name:     "Production Key",
+				KeyIDs:   []string{exampleKeyID, "akey_01m4wmsb8rfgjtnzeh2b5ycpq0"},
+			}},
+		},
+		"getLiveKeyStats": {
+			Request:  GetLiveKeyStatsRequest{KeyID: exampleKeyID},
+			Status:   http.StatusOK,
+			Response: exampleLiveKeyStats(),
+		},
+		"listLiveTopKeys": {
+			Request:  ListLiveTopKeysRequest{N: 20},
+			Status:   http.StatusOK,
+			Response: []*LiveKeyStatsResponse{exampleLiveKeyStats()},
+		},
+		"putKeyContacts": {
+			Request:  PutKeyContactsRequest{KeyID: exampleKeyID, Contacts: exampleKeyContacts().Contacts},
+			Status:   http.StatusOK,
+			Response: exampleKeyContacts(),
+		},
+
+		// Policies.
+		"keysmithCreatePolicy": {
+			Request:  examplePolicyRequest(),
+			Status:   http.StatusCreated,
+			Response: examplePolicy(),
+		},
+		"keysmithCreatePolicyFromTemplate": {
+			Request: CreatePolicyFromTemplateRequest{
+				Template:  "standard",
+				Overrides: &CreatePolicyRequest{Name: "Standard", RateLimit: 1000},
+				Clear:     []string{"daily_quota"},
+			},
+			Status:   http.StatusCreated,
+			Response: examplePolicy(),
+		},
+		"keysmithListPolicies": {
+			Request:  ListPoliciesRequest{Limit: 50},
+			Status:   http.StatusOK,
+			Response: []*PolicyResponse{examplePolicy()},
+		},
+		"keysmithGetPolicy": {
+			Request:  GetPolicyRequest{PolicyID: examplePolicyID},
+			Status:   http.StatusOK,
+			Response: examplePolicy(),
+		},
+		"keysmithGetEffectivePolicy": {
+			Request: GetEffectivePolicyRequest{PolicyID: examplePolicyID},
+			Status:  http.StatusOK,
+			Response: &EffectivePolicyResponse{
+				Policy: examplePolicy(),
+				Chain:  []string{examplePolicyID},
+			},
+		},
+		"keysmithUpdatePolicy": {
+			Request:  UpdatePolicyRequest{PolicyID: examplePolicyID, CreatePolicyRequest: examplePolicyRequest()},
+			Status:   http.StatusOK,
+			Response: examplePolicy(),
+		},
+		"keysmithDeletePolicy": {
+			Request: DeletePolicyRequest{PolicyID: examplePolicyID},
+			Status:  http.StatusNoContent,
+		},
+
+		// Scopes.
+		"createScope": {
+			Request:  CreateScopeRequest{Name: "read:users", Description: "Read user profiles", Parent: "read"},
+			Status:   http.StatusCreated,
+			Response: exampleScope(),
+		},
+		"listScopes": {
+			Request:  ListScopesRequest{Parent: "read", Limit: 50},
+			Status:   http.StatusOK,
+			Response: []*ScopeResponse{exampleScope()},
+		},
+		"deleteScope": {
+			Request: DeleteScopeRequest{ScopeID: exampleScopeID},
+			Status:  http.StatusNoContent,
+		},
+		"assignScopes": {
+			Request: AssignScopesRequest{KeyID: exampleKeyID, Scopes: []string{"read:users"}},
+			Status:  http.StatusNoContent,
+		},
+		"removeScopes": {
+			Request: RemoveScopesRequest{KeyID: exampleKeyID, Scopes: []string{"write:users"}},
+			Status:  http.StatusNoContent,
+		},
+
+		// Key transfers.
+		"initiateKeyTransfer": {
+			Request:  InitiateKeyTransferRequest{KeyID: exampleKeyID, TargetTenantID: "tenant_456"},
+			Status:   http.StatusCreated,
+			Response: exampleKeyTransfer(),
+		},
+		"getKeyTransfer": {
+			Request:  GetKeyTransferRequest{KeyID: exampleKeyID},
+			Status:   http.StatusOK,
+			Response: exampleKeyTransfer(),
+		},
+		"acceptKeyTransfer": {
+			Request:  AcceptKeyTransferRequest{KeyID: exampleKeyID},
+			Status:   http.StatusOK,
+			Response: exampleAcceptedKeyTransfer(),
+		},
+		"cancelKeyTransfer": {
+			Request:  CancelKeyTransferRequest{KeyID: exampleKeyID},
+			Status:   http.StatusOK,
+			Response: exampleCancelledKeyTransfer(),
+		},
+		"listKeyTransfers": {
+			Request:  ListKeyTransfersRequest{Limit: 50},
+			Status:   http.StatusOK,
+			Response: []*KeyTransferResponse{exampleAcceptedKeyTransfer()},
+		},
+
+		// Groups.
+		"createGroup": {
+			Request:  CreateGroupRequest{Name: "Mobile apps", Description: "Keys shipped in the iOS and Android apps"},
+			Status:   http.StatusCreated,
+			Response: exampleGroup(),
+		},
+		"listGroups": {
+			Request:  ListGroupsRequest{Limit: 50},
+			Status:   http.StatusOK,
+			Response: []*GroupResponse{exampleGroup()},
+		},
+		"getGroup": {
+			Request:  GetGroupRequest{GroupID: exampleGroupID},
+			Status:   http.StatusOK,
+			Response: exampleGroup(),
+		},
+		"updateGroup": {
+			Request:  UpdateGroupRequest{GroupID: exampleGroupID, Description: &groupDescription},
+			Status:   http.StatusOK,
+			Response: exampleGroup(),
+		},
+		"deleteGroup": {
+			Request: DeleteGroupRequest{GroupID: exampleGroupID},
+			Status:  http.StatusNoContent,
+		},
+		"addGroupKeys": {
+			Request: AddGroupKeysRequest{GroupID: exampleGroupID, KeyIDs: []string{exampleKeyID}},
+			Status:  http.StatusNoContent,
+		},
+		"removeGroupKeys": {
+			Request: RemoveGroupKeysRequest{GroupID: exampleGroupID, KeyIDs: []string{exampleKeyID}},
+			Status:  http.StatusNoContent,
+		},
+		"rotateGroup": {
+			Request: RotateGroupRequest{GroupID: exampleGroupID, Reason: "scheduled"},
+			Status:  http.StatusOK,
+			Response: &GroupOperationResponse{
+				GroupID: exampleGroupID,
+				Keys: []GroupKeyResultEntry{{
+					KeyID:  exampleKeyID,
+					Status: "rotated",
+					RawKey: exampleRotatedRawKey,
+					Rotation: &RotationResponse{
+						ID:        exampleRotationID,
+						KeyID:     exampleKeyID,
+						TenantID:  exampleTenantID,
+						AppID:     exampleAppID,
+						OldHint:   "xxxx",
+						NewHint:   "yyyy",
+						Reason:    "scheduled",
+						GraceTTL:  Duration(24 * time.Hour),
+						GraceEnds: exampleTime.Add(24 * time.Hour),
+						CreatedAt: exampleTime,
+					},
+				}},
+			},
+		},
+		"revokeGroup": {
+			Request: RevokeGroupRequest{GroupID: exampleGroupID, Reason: "partner offboarded"},
+			Status:  http.StatusOK,
+			Response: &GroupOperationResponse{
+				GroupID: exampleGroupID,
+				Keys:    []GroupKeyResultEntry{{KeyID: exampleKeyID, Status: "revoked"}},
+			},
+		},
+
+		// Quota pools.
+		"createQuotaPool": {
+			Request:  CreateQuotaPoolRequest{Name: "Acme Pro plan", Description: "Shared by all of Acme's keys", MonthlyLimit: 1000000},
+			Status:   http.StatusCreated,
+			Response: exampleQuotaPool(),
+		},
+		"listQuotaPools": {
+			Request:  ListQuotaPoolsRequest{Limit: 50},
+			Status:   http.StatusOK,
+			Response: []*QuotaPoolResponse{exampleQuotaPool()},
+		},
+		"getQuotaPool": {
+			Request:  GetQuotaPoolRequest{PoolID: examplePoolID},
+			Status:   http.StatusOK,
+			Response: exampleQuotaPool(),
+		},
+		"updateQuotaPool": {
+			Request:  UpdateQuotaPoolRequest{PoolID: examplePoolID, MonthlyLimit: &poolLimit},
+			Status:   http.StatusOK,
+			Response: updatedPool,
+		},
+		"deleteQuotaPool": {
+			Request: DeleteQuotaPoolRequest{PoolID: examplePoolID},
+			Status:  http.StatusNoContent,
+		},
+		"addQuotaPoolKeys": {
+			Request: AddQuotaPoolKeysRequest{PoolID: examplePoolID, KeyIDs: []string{exampleKeyID}},
+			Status:  http.StatusNoContent,
+		},
+		"removeQuotaPoolKeys": {
+			Request: RemoveQuotaPoolKeysRequest{PoolID: examplePoolID, KeyIDs: []string{exampleKeyID}},
+			Status:  http.StatusNoContent,
+		},
+		"getQuotaPoolUsage": {
+			Request:  GetQuotaPoolUsageRequest{PoolID: examplePoolID},
+			Status:   http.StatusOK,
+			Response: exampleQuotaPoolUsage(),
+		},
+
+		// Usage.
+		"getKeyUsage": {
+			Request: GetKeyUsageRequest{KeyID: exampleKeyID, After: "2024-01-15T00:00:00Z", Limit: 100},
+			Status:  http.StatusOK,
+			Response: []*UsageResponse{{
+				ID:          exampleUsageID,
+				KeyID:       exampleKeyID,
+				TenantID:    exampleTenantID,
+				AppID:       exampleAppID,
+				Environment: string(key.EnvLive),
+				Endpoint:    "/v1/users",
+				Method:      http.MethodGet,
+				StatusCode:  http.StatusOK,
+				IPAddress:   "203.0.113.7",
+				UserAgent:   "example-client/1.0",
+				LatencyMs:   12,
+				CreatedAt:   exampleTime,
+			}},
+		},
+		"getKeyUsageAggregate": {
+			Request:  GetKeyUsageAggregateRequest{KeyID: exampleKeyID, Period: "day"},
+			Status:   http.StatusOK,
+			Response: exampleAggregation(),
+		},
+		"getKeyQuotaForecast": {
+			Request:  GetQuotaForecastRequest{KeyID: exampleKeyID},
+			Status:   http.StatusOK,
+			Response: exampleQuotaForecast(),
+		},
+		"listKeyEndpointActivity": {
+			Request: ListEndpointActivityRequest{KeyID: exampleKeyID},
+			Status:  http.StatusOK,
+			Response: []*EndpointActivityResponse{{
+				Endpoint:   "/v1/users",
+				Method:     http.MethodGet,
+				LastSeenAt: exampleTime,
+				Count:      1200,
+			}},
+		},
+		"listUsage": {
+			Request:  ListUsageRequest{Period: "day", Environment: "live"},
+			Status:   http.StatusOK,
+			Response: exampleAggregation(),
+		},
+
+		// Rotations.
+		"listKeyRotations": {
+			Request: ListRotationsRequest{KeyID: exampleKeyID, Limit: 50},
+			Status:  http.StatusOK,
+			Response: []*RotationResponse{{
+				ID:        exampleRotationID,
+				KeyID:     exampleKeyID,
+				TenantID:  exampleTenantID,
+				AppID:     exampleAppID,
+				OldHint:   "xxxx",
+				NewHint:   "yyyy",
+				Reason:    "manual",
+				GraceTTL:  Duration(24 * time.Hour),
+				GraceEnds: exampleTime.Add(25 * time.Hour),
+				RotatedBy: "user_42",
+				CreatedAt: exampleTime.Add(time.Hour),
+			}},
+		},
+
+		"listRotations": {
+			Request: ListTenantRotationsRequest{
+				Reason:        "compromise",
+				CreatedAfter:  exampleTime.Format(time.RFC3339),
+				CreatedBefore: exampleTime.AddDate(0, 3, 0).Format(time.RFC3339),
+				Limit:         50,
+			},
+			Status: http.StatusOK,
+			Response: &RotationListResponse{Rotations: []*RotationResponse{{
+				ID:        exampleRotationID,
+				KeyID:     exampleKeyID,
+				TenantID:  exampleTenantID,
+				AppID:     exampleAppID,
+				OldHint:   "xxxx",
+				NewHint:   "yyyy",
+				Reason:    "compromise",
+				GraceTTL:  0,
+				GraceEnds: exampleTime.Add(time.Hour),
+				RotatedBy: "user_42",
+				CreatedAt: exampleTime.Add(time.Hour),
+			}}, Limit: 50},
+		},
+
+		"getRotationSummary": {
+			Request: RotationSummaryRequest{
+				CreatedAfter:  exampleTime.Format(time.RFC3339),
+				CreatedBefore: exampleTime.AddDate(0, 3, 0).Format(time.RFC3339),
+			},
+			Status: http.StatusOK,
+			Response: &RotationSummaryResponse{
+				CreatedAfter:  &exampleTime,
+				CreatedBefore: &summaryEnd,
+				Total:         17,
+				Reasons: []*ReasonCountResponse{
+					{Reason: "scheduled", Count: 12},
+					{Reason: "manual", Count: 3},
+					{Reason: "compromise", Count: 2},
+				},
+			},
+		},
+
+		"getKeyLineage": {
+			Request: GetLineageRequest{KeyID: exampleKeyID, At: exampleTime.Add(2 * time.Hour).Format(time.RFC3339)},
+			Status:  http.StatusOK,
+			Response: &LineageResponse{
+				KeyID: exampleKeyID,
+				Eras: []*HashEraResponse{
+					{
+						Index:         0,
+						Start:         exampleTime,
+						End:           &replacedAt,
+						GraceEnds:     &replacedGraceEnds,
+						Hint:          "xxxx",
+						GraceOverlaps: []int{1},
+					},
+					{
+						Index: 1,
+						Start: exampleTime.Add(time.Hour),
+						Hint:  "yyyy",
+						Rotation: &RotationResponse{
+							ID:        exampleRotationID,
+							KeyID:     exampleKeyID,
+							TenantID:  exampleTenantID,
+							AppID:     exampleAppID,
+							OldHint:   "xxxx",
+							NewHint:   "yyyy",
+							Reason:    "manual",
+							GraceTTL:  Duration(24 * time.Hour),
+							GraceEnds: exampleTime.Add(25 * time.Hour),
+							RotatedBy: "user_42",
+							CreatedAt: exampleTime.Add(time.Hour),
+						},
+					},
+				},
+				ActiveAt: &HashActivityResponse{At: exampleTime.Add(2 * time.Hour), Current: 1, Grace: []int{0}},
+			},
+		},
+
+		// Validation.
+		"validateKeyHeader": {
+			Request: ValidateKeyHeaderRequest{Authorization: "Bearer " + exampleRawKey},
+			Status:  http.StatusNoContent,
+		},
+		"checkKeyHeader": {
+			Request: ValidateKeyHeaderRequest{APIKey: exampleRawKey},
+			Status:  http.StatusNoContent,
+		},
+		"validateKey": {
+			Request: ValidateKeyRequest{
+				RawKey:          exampleRawKey,
+				Origin:          "https://shop.example.com",
+				ConsumerService: "storefront-web",
+				TLSVersion:      "1.3",
+				Method:          http.MethodGet,
+				Path:            "/v1/orders",
+				RemoteIP:        "203.0.113.7",
+				Attributes:      map[string]string{"country": "DE"},
+				Nonce:           "b7e1c0a4-5f2d-4c1e-9a8b-3d6f0e2c9a71",
+				Timestamp:       "2026-03-02T09:00:00Z",
+			},
+			Status: http.StatusOK,
+			Response: &ValidationResponse{
+				Valid:  true,
+				Key:    exampleKey(),
+				Scopes: []string{"read:users", "write:users"},
+				RateLimit: &RateLimitResponse{
+					Limit:           1000,
+					Remaining:       998,
+					Window:          Duration(time.Minute),
+					TenantLimit:     10000,
+					TenantRemaining: 9412,
+					TenantWindow:    Duration(time.Minute),
+				},
+				Quota: &QuotaResponse{
+					MonthlyLimit:     100000,
+					MonthlyRemaining: 61234,
+					MonthlyReset:     time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+				},
+				RotationDue: &RotationDueResponse{
+					DueAt:         time.Date(2026, 3, 9, 9, 0, 0, 0, time.UTC),
+					DaysRemaining: 7,
+				},
+			},
+		},
+		"getIntegrationGuide": {
+			Request:  GetIntegrationGuideRequest{},
+			Status:   http.StatusOK,
+			Response: exampleIntegrationGuide(),
+		},
+		"getReplayStats": {
+			Request: GetReplayStatsRequest{},
+			Status:  http.StatusOK,
+			Response: &ReplayStatsResponse{
+				Enabled:   true,
+				Checked:   48210,
+				Replays:   12,
+				Stale:     3,
+				HitRate:   0.00025,
+				Size:      9874,
+				Evictions: 0,
+			},
+		},
+		"getSLO": {
+			Request: GetSLORequest{},
+			Status:  http.StatusOK,
+			Response: &SLOResponse{Objectives: []SLOStatusResponse{{
+				Name:             "validation",
+				Target:           0.999,
+				LatencyThreshold: Duration(20 * time.Millisecond),
+				Window:           Duration(24 * time.Hour),
+				Good:             1843200,
+				Bad:              921,
+				BudgetRemaining:  0.5,
+				Burn: []SLOBurnResponse{
+					{Window: Duration(5 * time.Minute), Good: 6398, Bad: 2, Rate: 0.31},
+					{Window: Duration(time.Hour), Good: 76750, Bad: 50, Rate: 0.65},
+					{Window: Duration(6 * time.Hour), Good: 460520, Bad: 280, Rate: 0.61},
+				},
+			}}},
+		},
+		"listSuspiciousFingerprints": {
+			Request: ListSuspiciousFingerprintsRequest{Limit: 50},
+			Status:  http.StatusOK,
+			Response: []*FailurePatternResponse{{
+				Fingerprint: "sk_test:3f9a1c",
+				Count:       42,
+				Window:      Duration(5 * time.Minute),
+				FirstSeen:   exampleTime,
+				LastSeen:    exampleTime.Add(3 * time.Minute),
+			}},
+		},
+
+		// Tenants.
+		"getTenantSettings": {
+			Request:  GetTenantSettingsRequest{TenantID: exampleTenantID},
+			Status:   http.StatusOK,
+			Response: exampleTenantSettings(),
+		},
+		"updateTenantSettings": {
+			Request: UpdateTenantSettingsRequest{
+				TenantID:        exampleTenantID,
+				DefaultScopes:   []string{"read:users"},
+				RateLimit:       10000,
+				RateLimitWindow: Duration(time.Minute),
+				IPHandling:      usage.IPTruncate,
+				QuotaTimezone:   "Europe/Berlin",
+				UniqueKeyNames:  true,
+			},
+			Status:   http.StatusOK,
+			Response: exampleTenantSettings(),
+		},
+		"putMetadataSchema": {
+			Request:  PutMetadataSchemaRequest{TenantID: exampleTenantID, Fields: examplePlanSchema()},
+			Status:   http.StatusOK,
+			Response: schemaSettings,
+		},
+
+		// Revocations.
+		"listRevocations": {
+			Request: ListRevocationsRequest{Since: "2024-01-15T00:00:00Z", Limit: 500},
+			Status:  http.StatusOK,
+			Response: &RevocationFeedResponse{
+				Entries: []*RevocationEntryResponse{{
+					KeyID:      exampleKeyID,
+					TenantID:   exampleTenantID,
+					HashPrefix: "3f9a1c2b7d4e",
+					State:      "revoked",
+					Revoked:    true,
+					At:         exampleTime,
+				}},
+				Next: "1705314600000000000." + exampleKeyID,
+			},
+		},
+
+		// Key events.
+		"streamKeyEvents": {
+			Request: StreamKeyEventsRequest{Types: "key.revoked,key.state_changed", LastEventID: "1705314600000000000." + exampleKeyID},
+			Status:  http.StatusOK,
+			Response: &KeyEventResponse{
+				EventID:  "1705314660000000000." + exampleKeyID,
+				Type:     "key.revoked",
+				KeyID:    exampleKeyID,
+				TenantID: exampleTenantID,
+				AppID:    exampleAppID,
+				State:    "revoked",
+				Reason:   "compromised",
+				ActorID:  "user_42",
+				At:       exampleTime.Add(time.Minute),
+			},
+		},
+
+		// Debug capture.
+		"setKeyDebug": {
+			Request:  SetKeyDebugRequest{KeyID: exampleKeyID, SampleRate: 0.1, Duration: Duration(30 * time.Minute)},
+			Status:   http.StatusOK,
+			Response: debugged,
+		},
+		"listKeyCaptures": {
+			Request: ListCapturesRequest{KeyID: exampleKeyID},
+			Status:  http.StatusOK,
+			Response: []*CaptureResponse{{
+				ID:     exampleCaptureID,
+				KeyID:  exampleKeyID,
+				Method: http.MethodPost,
+				Path:   "/v1/charges",
+				Headers: map[string]string{
+					"Authorization": capture.Redacted,
+					"Content-Type":  "application/json",
+				},
+				Body:       `{"amount":100}`,
+				CapturedAt: exampleTime,
+			}},
+		},
+		"maintainStore": {
+			Request: MaintainStoreRequest{},
+			Status:  http.StatusOK,
+			Response: &MaintenanceResponse{
+				Backend: "postgres",
+				Actions: []string{
+					"VACUUM (ANALYZE) keysmith_keys",
+					"VACUUM (ANALYZE) keysmith_usage",
+				},
+				Tables: []TableStatsResponse{
+					{Name: "keysmith_keys", Rows: 1204, DeadRows: 3, SizeBytes: 1343488},
+					{Name: "keysmith_usage", Rows: 2841930, DeadRows: 120, SizeBytes: 912261120},
+				},
+				Advice: []string{
+					"keysmith_usage has no index led by created_at, so usage purges scan the whole table; consider CREATE INDEX CONCURRENTLY idx_keysmith_usage_created ON keysmith_usage (created_at)",
+				},
+				StartedAt: exampleTime,
+				Duration:  Duration(4200 * time.Millisecond),
+			},
+		},
+		"getStorageReport": {
+			Request: GetStorageReportRequest{Top: 2},
+			Status:  http.StatusOK,
+			Response: &StorageReportResponse{
+				Tenants: []TenantStorageResponse{
+					{TenantID: "tenant_123", Keys: 42, KeysByState: map[string]int64{"active": 38, "revoked": 4}, Policies: 3, Scopes: 12, Rotations: 17, UsageRows: 1840233, UsageBytes: 331241940},
+					{TenantID: "tenant_456", Keys: 5, KeysByState: map[string]int64{"active": 5}, Policies: 1, Scopes: 2, UsageRows: 20418, UsageBytes: 3675240},
+				},
+				Total:       TenantStorageResponse{Keys: 47, KeysByState: map[string]int64{"active": 43, "revoked": 4}, Policies: 4, Scopes: 14, Rotations: 17, UsageRows: 1860651, UsageBytes: 334917180},
+				TopUsage:    []TenantUsageResponse{{TenantID: "tenant_123", UsageRows: 1840233, UsageBytes: 331241940}, {TenantID: "tenant_456", UsageRows: 20418, UsageBytes: 3675240}},
+				GeneratedAt: exampleTime,
+				Duration:    Duration(1800 * time.Millisecond),
+			},
+		},
+		"revokeByHash": {
+			Request: RevokeByHashRequest{
+				Hashes: []string{exampleKeyHash, exampleMissingHash},
+				Reason: "exposed in backup leak",
+			},
+			Status: http.StatusOK,
+			Response: &HashReportsResponse{
+				Reports: []HashReportResponse{
+					{Hash: exampleKeyHash, Status: "revoked", KeyID: exampleKeyID, State: "active"},
+					{Hash: exampleMissingHash, Status: "not_found"},
+				},
+				Revoked:  1,
+				NotFound: 1,
+			},
+		},
+		"validateHashes": {
+			Request: ValidateHashesRequest{Hashes: []string{exampleKeyHash, exampleMissingHash}},
+			Status:  http.StatusOK,
+			Response: &HashReportsResponse{
+				Reports: []HashReportResponse{
+					{Hash: exampleKeyHash, Status: "found", KeyID: exampleKeyID, State: "active"},
+					{Hash: exampleMissingHash, Status: "not_found"},
+				},
+				Found:    1,
+				NotFound: 1,
+			},
+		},
+		"listKeysByCreator": {
+			Request:  ListKeysByCreatorRequest{CreatedBy: "user_42", AppID: exampleAppID, Limit: 50},
+			Status:   http.StatusOK,
+			Response: &KeysByCreatorResponse{CreatedBy: "user_42", Keys: []*KeyResponse{exampleKey()}, Limit: 50},
+		},
+		"revokeByCreator": {
+			Request: RevokeByCreatorRequest{CreatedBy: "user_42", AppID: exampleAppID, Reason: "offboarded"},
+			Status:  http.StatusOK,
+			Response: &CreatorRevokeResponse{
+				CreatedBy: "user_42",
+				KeyIDs:    []string{exampleKeyID},
+			},
+		},
+		"listHashTombstones": {
+			Request: ListHashTombstonesRequest{TenantID: exampleTenantID, Limit: 50},
+			Status:  http.StatusOK,
+			Response: &HashTombstonesResponse{
+				Tombstones: []*HashTombstoneResponse{{
+					HashPrefix: "3f9a1c2b7d4e",
+					KeyID:      exampleKeyID,
+					TenantID:   exampleTenantID,
+					AppID:      exampleAppID,
+					Reason:     "compromised",
+					CreatedAt:  exampleTime,
+				}},
+				Total:   1,
+				Reasons: []*TombstoneReasonCountResponse{{Reason: "compromised", Count: 1}},
+				Limit:   50,
+			},
+		},
+		"lockdownTenant": {
+			Request: LockdownTenantRequest{TenantID: exampleTenantID, Reason: "account takeover INC-4521"},
+			Status:  http.StatusOK,
+			Response: &TenantLockdownResultResponse{
+				Lockdown: &TenantLockdownResponse{
+					Phase:     "locked",
+					Reason:    "account takeover INC-4521",
+					ActorID:   "user_42",
+					LockedAt:  exampleTime,
+					Suspended: 1,
+				},
+				KeyIDs: []string{exampleKeyID},
+			},
+		},
+		"getTenantLockdown": {
+			Request:  GetTenantLockdownRequest{TenantID: exampleTenantID},
+			Status:   http.StatusOK,
+			Response: exampleTenantLockdownStatus(),
+		},
+		"recoverTenant": {
+			Request: RecoverTenantRequest{
+				TenantID:      exampleTenantID,
+				BatchSize:     25,
+				BatchInterval: Duration(10 * time.Minute),
+			},
+			Status:   http.StatusOK,
+			Response: exampleTenantLockdownStatus(),
+		},
+		"getUsageRecording": {
+			Request:  GetUsageRecordingRequest{},
+			Status:   http.StatusOK,
+			Response: exampleUsageRecording,
+		},
+		"setUsageRecording": {
+			Request: SetUsageRecordingRequest{
+				Rules:      exampleUsageRecording.Rules,
+				Default:    exampleUsageRecording.Default,
+				SampleRate: exampleUsageRecording.SampleRate,
+			},
+			Status:   http.StatusOK,
+			Response: exampleUsageRecording,
+		},
+		"getRuntimeConfig": {
+			Request:  GetRuntimeConfigRequest{},
+			Status:   http.StatusOK,
+			Response: exampleRuntimeConfig(Duration(30 * time.Second)),
+		},
+		"updateRuntimeConfig": {
+			Request:  UpdateRuntimeConfigRequest{ValidationCacheTTL: &exampleCacheTTL},
+			Status:   http.StatusOK,
+			Response: exampleRuntimeConfig(Duration(time.Minute)),
+		},
+		"setReadOnly": {
+			Request:  SetReadOnlyRequest{Enabled: true},
+			Status:   http.StatusOK,
+			Response: &ReadOnlyResponse{ReadOnly: true, UsageRecording: true},
+		},
+	}
+}
+
+// exampleIntegrationGuide is the guide for a tenant with two live keys and
+// one test key under the default base path.
+func exampleIntegrationGuide() *IntegrationGuideResponse {
+	validation := IntegrationEndpointResponse{
+		Method: http.MethodPost,
+		URL:    "https://api.example.com/keysmith/v1" + validateKeyPath,
+		Body:   fmt.Sprintf(`{"raw_key": %q}`, exampleKeyFor("sk", key.EnvLive)),
+	}
+	return &IntegrationGuideResponse{
+		TenantID:   exampleTenantID,
+		BaseURL:    "https://api.example.com/keysmith",
+		Validation: validation,
+		Keys: []IntegrationKeyResponse{
+			{Prefix: "sk", Environment: "live", ActiveKeys: 2, ExampleKey: exampleKeyFor("sk", key.EnvLive)},
+			{Prefix: "sk", Environment: "test", ActiveKeys: 1, ExampleKey: exampleRawKey},
+		},
+		Headers:  middleware.Headers(),
+		Snippets: integrationSnippets(validation),
+		Scopes: []IntegrationScopeResponse{
+			{Name: "read", Description: "Read access"},
+			{Name: "read:users", Description: "Read users", Parent: "read"},
+		},
+	}
+}
+
+// exampleRuntimeConfig is the runtime configuration of an engine with the
+// validation cache on and the default workers, with cacheTTL as its TTL.
+func exampleRuntimeConfig(cacheTTL Duration) *RuntimeConfigResponse {
+	return &RuntimeConfigResponse{
+		ValidationCacheTTL:    cacheTTL,
+		EndpointFlushInterval: Duration(10 * time.Second),
+		CapturePurgeInterval:  Duration(10 * time.Minute),
+		MaintenanceInterval:   0,
+		QuotaForecastInterval: 0,
+		FailureThreshold:      20,
+		FailureWindow:         Duration(5 * time.Minute),
+	}
+}
+
+var exampleCacheTTL = Duration(time.Minute)
+
+var exampleUsageRecording = &UsageRecordingResponse{
+	Rules: []usage.RecordingRule{
+		{Pattern: "/healthz", Mode: usage.RecordOff},
+		{Pattern: "/static/**", Mode: usage.RecordCounterOnly},
+		{Pattern: "/v1/search", Mode: usage.RecordSampled, SampleRate: 100},
+	},
+	Default: string(usage.RecordFull),
+}
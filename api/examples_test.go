@@ -0,0 +1,177 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xraph/forge"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+func newExampleRouter(t *testing.T) forge.Router {
+	t.Helper()
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()))
+	require.NoError(t, err)
+	r := forge.NewRouter(forge.WithOpenAPI(forge.OpenAPIConfig{Title: "Keysmith", Version: "test"}))
+	New(eng, r).RegisterRoutes(r)
+	return r
+}
+
+// successSchema returns the lowest 2xx response registered for a route.
+func successSchema(t *testing.T, route forge.RouteInfo) (int, any) {
+	t.Helper()
+	schemas, ok := route.Metadata["openapi.responseSchemas"].(map[int]*forge.ResponseSchemaDef)
+	require.True(t, ok, "route has response schemas")
+	status := 0
+	for code := range schemas {
+		if code >= 200 && code < 300 && (status == 0 || code < status) {
+			status = code
+		}
+	}
+	require.NotZero(t, status, "route has a success response")
+	return status, schemas[status].Schema
+}
+
+// roundTrip decodes v's JSON into a new value of type t, rejecting unknown
+// fields, and returns both encodings.
+func roundTrip(t *testing.T, v any, typ reflect.Type) (want, got []byte) {
+	t.Helper()
+	want, err := json.Marshal(v)
+	require.NoError(t, err)
+
+	dst := reflect.New(typ)
+	dec := json.NewDecoder(bytes.NewReader(want))
+	dec.DisallowUnknownFields()
+	require.NoError(t, dec.Decode(dst.Interface()))
+
+	got, err = json.Marshal(dst.Elem().Interface())
+	require.NoError(t, err)
+	return want, got
+}
+
+func TestExamples_EveryRoute(t *testing.T) {
+	examples := Examples()
+	seen := make(map[string]bool)
+
+	for _, route := range newExampleRouter(t).Routes() {
+		t.Run(route.OperationID, func(t *testing.T) {
+			ex, ok := examples[route.OperationID]
+			require.True(t, ok, "no example for %s %s", route.Method, route.Path)
+			seen[route.OperationID] = true
+
+			// The request example is the route's request DTO and survives a
+			// JSON round trip through it.
+			reqSchema := route.Metadata["openapi.requestSchema.unified"]
+			require.NotNil(t, ex.Request)
+			require.Equal(t, reflect.TypeOf(reqSchema), reflect.TypeOf(ex.Request))
+			want, got := roundTrip(t, ex.Request, reflect.TypeOf(reqSchema))
+			assert.JSONEq(t, string(want), string(got))
+
+			// The response example matches the route's success response.
+			status, respSchema := successSchema(t, route)
+			require.Equal(t, status, ex.Status)
+			if respSchema == nil {
+				assert.Nil(t, ex.Response, "204 routes have no response body")
+				return
+			}
+			require.NotNil(t, ex.Response)
+			require.Equal(t, reflect.TypeOf(respSchema), reflect.TypeOf(ex.Response))
+			respType := reflect.TypeOf(respSchema)
+			if respType.Kind() == reflect.Pointer {
+				respType = respType.Elem()
+			}
+			want, got = roundTrip(t, ex.Response, respType)
+			assert.JSONEq(t, string(want), string(got))
+
+			examples, ok := route.Metadata["openapi.responseExamples"].(map[int]map[string]any)
+			require.True(t, ok, "response example is published")
+			assert.NotEmpty(t, examples[status])
+		})
+	}
+
+	assert.NotEmpty(t, seen)
+}
+
+func TestExamples_PublishedInSpec(t *testing.T) {
+	spec := newExampleRouter(t).OpenAPISpec()
+	require.NotNil(t, spec)
+
+	body := spec.Paths["/v1/keys"].Post.RequestBody.Content["application/json"]
+	require.Contains(t, body.Examples, "example")
+	assert.Equal(t, "Production Key", body.Examples["example"].Value.(map[string]any)["name"])
+
+	resp := spec.Paths["/v1/keys"].Post.Responses["201"].Content["application/json"]
+	require.Contains(t, resp.Examples, "example")
+
+	// Path parameters carry examples, so body-less routes are covered too.
+	get := spec.Paths["/v1/keys/{keyId}"].Get
+	var found bool
+	for _, p := range get.Parameters {
+		if p.Name == "keyId" {
+			found = true
+			assert.Equal(t, exampleKeyID, p.Schema.Example)
+		}
+	}
+	assert.True(t, found)
+
+	// URL fields are not repeated in body examples.
+	rotate := spec.Paths["/v1/keys/{keyId}/rotate"].Post.RequestBody.Content["application/json"]
+	assert.Equal(t, map[string]any{"reason": "manual"}, rotate.Examples["example"].Value)
+}
+
+// idFields maps JSON and Go field names to the TypeID prefix their values
+// must carry.
+var idFields = map[string]id.Prefix{
+	"key_id":    id.PrefixKey,
+	"KeyID":     id.PrefixKey,
+	"policy_id": id.PrefixPolicy,
+	"PolicyID":  id.PrefixPolicy,
+	"ScopeID":   id.PrefixScope,
+	"group_id":  id.PrefixGroup,
+	"GroupID":   id.PrefixGroup,
+}
+
+func checkExampleValues(t *testing.T, path string, v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if s, ok := child.(string); ok && s != "" {
+				switch {
+				case k == "raw_key" || k == "RawKey":
+					assert.True(t, strings.HasPrefix(s, "sk_test_example"), "%s.%s = %q is not a fake key", path, k, s)
+				case k == "id":
+					_, err := id.Parse(s)
+					assert.NoError(t, err, "%s.id", path)
+				case idFields[k] != "":
+					_, err := id.ParseWithPrefix(s, idFields[k])
+					assert.NoError(t, err, "%s.%s", path, k)
+				}
+			}
+			checkExampleValues(t, path+"."+k, child)
+		}
+	case []any:
+		for _, child := range v {
+			checkExampleValues(t, path+"[]", child)
+		}
+	}
+}
+
+func TestExamples_FakeKeysAndValidIDs(t *testing.T) {
+	for op, ex := range Examples() {
+		for _, v := range []any{ex.Request, ex.Response} {
+			b, err := json.Marshal(v)
+			require.NoError(t, err)
+			var decoded any
+			require.NoError(t, json.Unmarshal(b, &decoded))
+			checkExampleValues(t, op, decoded)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/xraph/forge"
+)
+
+// Field whitelists for the endpoints that accept a fields parameter.
+var (
+	keyFields         = responseFields(KeyResponse{})
+	usageFields       = responseFields(UsageResponse{})
+	aggregationFields = responseFields(AggregationResponse{})
+)
+
+// fieldSet lists the JSON fields of a response DTO in declaration order.
+// Map-typed fields, such as metadata, accept one level of sub-key selection.
+type fieldSet struct {
+	names []string
+	maps  map[string]bool
+}
+
+func responseFields(v any) *fieldSet {
+	fs := &fieldSet{maps: make(map[string]bool)}
+	t := reflect.TypeOf(v)
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		fs.names = append(fs.names, name)
+		if f.Type.Kind() == reflect.Map {
+			fs.maps[name] = true
+		}
+	}
+	return fs
+}
+
+// fieldSelector projects response DTOs onto the fields a client asked for
+// with ?fields=id,name,metadata.plan. A nil selector keeps the full shape.
+type fieldSelector struct {
+	set *fieldSet
+
+	// fields holds the selected top-level fields. A nil sub-key list selects
+	// the whole field; otherwise only the listed map entries are kept.
+	fields map[string][]string
+}
+
+// parseFields validates raw against the whitelist. An empty raw returns a
+// nil selector.
+func parseFields(raw string, set *fieldSet) (*fieldSelector, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	sel := &fieldSelector{set: set, fields: make(map[string][]string)}
+	for _, f := range strings.Split(raw, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		name, sub, nested := strings.Cut(f, ".")
+		switch {
+		case !slices.Contains(set.names, name):
+			return nil, forge.BadRequest(fmt.Sprintf("unknown field %q; allowed fields: %s", f, strings.Join(set.names, ", ")))
+		case nested && (!set.maps[name] || sub == "" || strings.Contains(sub, ".")):
+			return nil, forge.BadRequest(fmt.Sprintf("field %q cannot be selected; only one level of %s is supported", f, strings.Join(set.mapNames(), ", ")))
+		}
+
+		subs, seen := sel.fields[name]
+		switch {
+		case !nested:
+			sel.fields[name] = nil
+		case !seen || subs != nil:
+			sel.fields[name] = append(subs, sub)
+		}
+	}
+	if len(sel.fields) == 0 {
+		return nil, nil
+	}
+	return sel, nil
+}
+
+func (s *fieldSet) mapNames() []string {
+	var names []string
+	for _, n := range s.names {
+		if s.maps[n] {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// has reports whether the field is part of the response; a nil selector
+// keeps every field.
+func (sel *fieldSelector) has(name string) bool {
+	if sel == nil {
+		return true
+	}
+	_, ok := sel.fields[name]
+	return ok
+}
+
+// project encodes v with only the selected fields, in declaration order.
+// Omitted-when-empty fields stay omitted.
+func (sel *fieldSelector) project(v any) (json.RawMessage, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &all); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for _, name := range sel.set.names {
+		val, ok := all[name]
+		subs, selected := sel.fields[name]
+		if !ok || !selected {
+			continue
+		}
+		if subs != nil {
+			if val, err = selectEntries(val, subs); err != nil {
+				return nil, err
+			}
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// selectEntries keeps only the named entries of a JSON object.
+func selectEntries(raw json.RawMessage, names []string) (json.RawMessage, error) {
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, err
+	}
+	kept := make(map[string]json.RawMessage, len(names))
+	for _, n := range names {
+		if v, ok := entries[n]; ok {
+			kept[n] = v
+		}
+	}
+	return json.Marshal(kept)
+}
+
+// writeSelected writes v with status 200, projected onto sel when the
+// client selected fields.
+func writeSelected(ctx forge.Context, sel *fieldSelector, v any) error {
+	if sel == nil {
+		return ctx.JSON(http.StatusOK, v)
+	}
+	p, err := sel.project(v)
+	if err != nil {
+		return fmt.Errorf("select fields: %w", err)
+	}
+	return ctx.JSON(http.StatusOK, p)
+}
+
+// writeSelectedList is writeSelected for list responses; sel applies to
+// each element.
+func writeSelectedList[T any](ctx forge.Context, sel *fieldSelector, items []T) error {
+	if sel == nil {
+		return ctx.JSON(http.StatusOK, items)
+	}
+	out := make([]json.RawMessage, len(items))
+	for i, item := range items {
+		p, err := sel.project(item)
+		if err != nil {
+			return fmt.Errorf("select fields: %w", err)
+		}
+		out[i] = p
+	}
+	return ctx.JSON(http.StatusOK, out)
+}
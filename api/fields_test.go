@@ -0,0 +1,101 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleKeyResponse() *KeyResponse {
+	used := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	return &KeyResponse{
+		ID:          "akey_01h455vb4pex5vsknk084sn02q",
+		TenantID:    "tenant_123",
+		AppID:       "app_1",
+		Name:        "Mobile",
+		Prefix:      "sk",
+		Hint:        "a1b2",
+		Environment: "live",
+		State:       "active",
+		Metadata:    map[string]any{"plan": "pro", "region": "eu", "seats": 5},
+		LastUsedAt:  &used,
+		CreatedAt:   used.Add(-time.Hour),
+		UpdatedAt:   used.Add(-time.Hour),
+	}
+}
+
+func TestFieldSelector_Project(t *testing.T) {
+	tests := []struct {
+		fields string
+		want   string
+	}{
+		{"id,name,state", `{"id":"akey_01h455vb4pex5vsknk084sn02q","name":"Mobile","state":"active"}`},
+		{"state, id ,hint", `{"id":"akey_01h455vb4pex5vsknk084sn02q","hint":"a1b2","state":"active"}`},
+		{"id,last_used_at,revoked_at", `{"id":"akey_01h455vb4pex5vsknk084sn02q","last_used_at":"2024-01-15T10:30:00Z"}`},
+		{"id,metadata.plan", `{"id":"akey_01h455vb4pex5vsknk084sn02q","metadata":{"plan":"pro"}}`},
+		{"metadata.plan,metadata.seats,metadata.missing", `{"metadata":{"plan":"pro","seats":5}}`},
+		{"metadata.plan,metadata", `{"metadata":{"plan":"pro","region":"eu","seats":5}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fields, func(t *testing.T) {
+			sel, err := parseFields(tt.fields, keyFields)
+			require.NoError(t, err)
+			got, err := sel.project(sampleKeyResponse())
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(got))
+			assert.Equal(t, tt.want, string(got), "fields keep declaration order")
+		})
+	}
+}
+
+func TestFieldSelector_Omitted(t *testing.T) {
+	sel, err := parseFields("", keyFields)
+	require.NoError(t, err)
+	assert.Nil(t, sel, "no fields parameter keeps the full shape")
+
+	sel, err = parseFields(" , ", keyFields)
+	require.NoError(t, err)
+	assert.Nil(t, sel)
+}
+
+func TestFieldSelector_Unknown(t *testing.T) {
+	for _, fields := range []string{"id,secret", "name.first", "metadata.plan.tier", "metadata."} {
+		_, err := parseFields(fields, keyFields)
+		assert.Error(t, err, fields)
+	}
+
+	_, err := parseFields("id,secret", keyFields)
+	assert.Contains(t, err.Error(), "allowed fields: id, tenant_id, app_id")
+}
+
+func TestFieldSelector_Usage(t *testing.T) {
+	sel, err := parseFields("endpoint,status_code", usageFields)
+	require.NoError(t, err)
+
+	got, err := sel.project(&UsageResponse{ID: "kusg_1", KeyID: "akey_1", Endpoint: "/v1/charges", Method: "POST", StatusCode: 201})
+	require.NoError(t, err)
+	assert.Equal(t, `{"endpoint":"/v1/charges","status_code":201}`, string(got))
+
+	_, err = parseFields("request_count,period", aggregationFields)
+	assert.NoError(t, err)
+	_, err = parseFields("endpoint", aggregationFields)
+	assert.Error(t, err)
+}
+
+func TestFieldSelector_ListElements(t *testing.T) {
+	sel, err := parseFields("id", keyFields)
+	require.NoError(t, err)
+
+	items := []*KeyResponse{sampleKeyResponse(), {ID: "akey_2", Name: "Other"}}
+	out := make([]json.RawMessage, len(items))
+	for i, item := range items {
+		out[i], err = sel.project(item)
+		require.NoError(t, err)
+	}
+	buf, err := json.Marshal(out)
+	require.NoError(t, err)
+	assert.Equal(t, `[{"id":"akey_01h455vb4pex5vsknk084sn02q"},{"id":"akey_2"}]`, string(buf))
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/xraph/forge"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/group"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/rotation"
+)
+
+func (a *API) createGroup(ctx forge.Context, req *CreateGroupRequest) (*GroupResponse, error) {
+	g := &group.Group{
+		Name:        req.Name,
+		Description: req.Description,
+	}
+
+	if err := a.eng.CreateGroup(ctx.Context(), g); err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toGroupResponse(g)
+	return resp, ctx.JSON(http.StatusCreated, resp)
+}
+
+func (a *API) listGroups(ctx forge.Context, req *ListGroupsRequest) (*struct{}, error) {
+	groups, err := a.eng.ListGroups(ctx.Context(), &group.ListFilter{
+		AppID:  req.AppID,
+		Limit:  a.pageLimit(ctx, req.Limit, keysmith.DefaultPageSize),
+		Offset: req.Offset,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list groups: %w", err)
+	}
+
+	resp := make([]*GroupResponse, len(groups))
+	for i, g := range groups {
+		resp[i] = toGroupResponse(g)
+	}
+	return nil, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) getGroup(ctx forge.Context, _ *GetGroupRequest) (*GroupResponse, error) {
+	groupID, err := id.ParseGroupID(ctx.Param("groupId"))
+	if err != nil {
+		return nil, forge.BadRequest(fmt.Sprintf("invalid group ID: %v", err))
+	}
+
+	g, err := a.eng.GetGroup(ctx.Context(), groupID)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toGroupResponse(g)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) updateGroup(ctx forge.Context, req *UpdateGroupRequest) (*GroupResponse, error) {
+	groupID, err := id.ParseGroupID(ctx.Param("groupId"))
+	if err != nil {
+		return nil, forge.BadRequest(fmt.Sprintf("invalid group ID: %v", err))
+	}
+
+	g, err := a.eng.GetGroup(ctx.Context(), groupID)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+	if req.Name != nil {
+		g.Name = *req.Name
+	}
+	if req.Description != nil {
+		g.Description = *req.Description
+	}
+	if err := a.eng.UpdateGroup(ctx.Context(), g); err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toGroupResponse(g)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) deleteGroup(ctx forge.Context, _ *DeleteGroupRequest) (*struct{}, error) {
+	groupID, err := id.ParseGroupID(ctx.Param("groupId"))
+	if err != nil {
+		return nil, forge.BadRequest(fmt.Sprintf("invalid group ID: %v", err))
+	}
+
+	if err := a.eng.DeleteGroup(ctx.Context(), groupID); err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	return nil, ctx.NoContent(http.StatusNoContent)
+}
+
+func (a *API) addGroupKeys(ctx forge.Context, req *AddGroupKeysRequest) (*struct{}, error) {
+	groupID, keyIDs, err := parseGroupKeys(ctx, req.KeyIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.eng.AddKeysToGroup(ctx.Context(), groupID, keyIDs); err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	return nil, ctx.NoContent(http.StatusNoContent)
+}
+
+func (a *API) removeGroupKeys(ctx forge.Context, req *RemoveGroupKeysRequest) (*struct{}, error) {
+	groupID, keyIDs, err := parseGroupKeys(ctx, req.KeyIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.eng.RemoveKeysFromGroup(ctx.Context(), groupID, keyIDs); err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	return nil, ctx.NoContent(http.StatusNoContent)
+}
+
+// parseGroupKeys parses the groupId path parameter and a membership
+// request's key IDs.
+func parseGroupKeys(ctx forge.Context, raw []string) (id.GroupID, []id.KeyID, error) {
+	groupID, err := id.ParseGroupID(ctx.Param("groupId"))
+	if err != nil {
+		return id.GroupID{}, nil, forge.BadRequest(fmt.Sprintf("invalid group ID: %v", err))
+	}
+	keyIDs := make([]id.KeyID, len(raw))
+	for i, s := range raw {
+		if keyIDs[i], err = id.ParseKeyID(s); err != nil {
+			return id.GroupID{}, nil, forge.BadRequest(fmt.Sprintf("invalid key ID %q: %v", s, err))
+		}
+	}
+	return groupID, keyIDs, nil
+}
+
+func (a *API) rotateGroup(ctx forge.Context, req *RotateGroupRequest) (*GroupOperationResponse, error) {
+	if err := validateEnum("reason", req.Reason); err != nil {
+		return nil, err
+	}
+	groupID, err := id.ParseGroupID(ctx.Param("groupId"))
+	if err != nil {
+		return nil, forge.BadRequest(fmt.Sprintf("invalid group ID: %v", err))
+	}
+
+	res, err := a.eng.RotateGroup(engineContext(ctx, req.DryRun), groupID, rotation.Reason(req.Reason))
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toGroupOperationResponse(res)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) revokeGroup(ctx forge.Context, req *RevokeGroupRequest) (*GroupOperationResponse, error) {
+	groupID, err := id.ParseGroupID(ctx.Param("groupId"))
+	if err != nil {
+		return nil, forge.BadRequest(fmt.Sprintf("invalid group ID: %v", err))
+	}
+
+	res, err := a.eng.RevokeGroup(engineContext(ctx, req.DryRun), groupID, req.Reason)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toGroupOperationResponse(res)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
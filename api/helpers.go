@@ -1,25 +1,48 @@
 package api
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/xraph/forge"
 
 	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/apitypes"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/usage"
 )
 
+// engineContext returns the request context, marked with keysmith.WithDryRun
+// when dryRun is set.
+func engineContext(ctx forge.Context, dryRun bool) context.Context {
+	if dryRun {
+		return keysmith.WithDryRun(ctx.Context())
+	}
+	return ctx.Context()
+}
+
 // mapStoreError converts keysmith sentinel errors to forge HTTP errors.
 func mapStoreError(err error) error {
 	if err == nil {
 		return nil
 	}
 	switch {
+	// Validator errors may wrap other sentinels; they are still input errors.
+	case errors.Is(err, keysmith.ErrInvalidKeyInput):
+		return forge.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
 	case errors.Is(err, keysmith.ErrKeyNotFound),
 		errors.Is(err, keysmith.ErrPolicyNotFound),
 		errors.Is(err, keysmith.ErrScopeNotFound),
-		errors.Is(err, keysmith.ErrRotationNotFound):
+		errors.Is(err, keysmith.ErrGroupNotFound),
+		errors.Is(err, keysmith.ErrQuotaPoolNotFound),
+		errors.Is(err, keysmith.ErrRotationNotFound),
+		errors.Is(err, keysmith.ErrNoHashAt),
+		errors.Is(err, keysmith.ErrNoMonthlyQuota),
+		errors.Is(err, keysmith.ErrKeyTransferNotFound):
 		return forge.NotFound(err.Error())
 	case errors.Is(err, keysmith.ErrInvalidKey):
 		return forge.Unauthorized(err.Error())
@@ -29,27 +52,131 @@ func mapStoreError(err error) error {
 		errors.Is(err, keysmith.ErrKeyInactive):
 		return forge.Forbidden(err.Error())
 	case errors.Is(err, keysmith.ErrRateLimited),
+		errors.Is(err, keysmith.ErrTenantRateLimited),
 		errors.Is(err, keysmith.ErrQuotaExceeded):
 		return forge.NewHTTPError(http.StatusTooManyRequests, err.Error())
 	case errors.Is(err, keysmith.ErrPolicyInUse),
-		errors.Is(err, keysmith.ErrInvalidStateTransition):
+		errors.Is(err, keysmith.ErrExternalRefInUse),
+		errors.Is(err, keysmith.ErrDuplicateKeyName),
+		errors.Is(err, keysmith.ErrRequestReplayed),
+		errors.Is(err, keysmith.ErrInvalidStateTransition),
+		errors.Is(err, keysmith.ErrKeyTransferPending),
+		errors.Is(err, keysmith.ErrKeyTransferExpired),
+		errors.Is(err, keysmith.ErrHashTombstoned),
+		errors.Is(err, keysmith.ErrTenantLocked),
+		errors.Is(err, keysmith.ErrTenantNotLocked):
 		return forge.NewHTTPError(http.StatusConflict, err.Error())
+	case errors.Is(err, keysmith.ErrInvalidCompromiseAction),
+		errors.Is(err, keysmith.ErrInvalidPolicy),
+		errors.Is(err, keysmith.ErrPolicyInheritance),
+		errors.Is(err, keysmith.ErrPolicyTemplateNotFound),
+		errors.Is(err, keysmith.ErrRevocationRangeTooLarge),
+		errors.Is(err, keysmith.ErrKeyEventRangeTooLarge),
+		errors.Is(err, keysmith.ErrInvalidDebugCapture),
+		errors.Is(err, keysmith.ErrInvalidMetadataSchema),
+		errors.Is(err, keysmith.ErrInvalidTenantSettings),
+		errors.Is(err, keysmith.ErrInvalidScope),
+		errors.Is(err, keysmith.ErrInvalidGroup),
+		errors.Is(err, keysmith.ErrInvalidQuotaPool),
+		errors.Is(err, keysmith.ErrInvalidOrigin),
+		errors.Is(err, keysmith.ErrInvalidCertFingerprint),
+		errors.Is(err, keysmith.ErrInvalidKeyFlag),
+		errors.Is(err, keysmith.ErrInvalidContact),
+		errors.Is(err, keysmith.ErrInvalidLabels),
+		errors.Is(err, keysmith.ErrInvalidLabelSelector),
+		errors.Is(err, keysmith.ErrInvalidErasure),
+		errors.Is(err, keysmith.ErrInvalidRuntimeConfig),
+		errors.Is(err, keysmith.ErrInvalidRotationReason),
+		errors.Is(err, keysmith.ErrHashBatchTooLarge),
+		errors.Is(err, keysmith.ErrInvalidOverviewQuery),
+		errors.Is(err, keysmith.ErrInvalidSearchQuery),
+		errors.Is(err, keysmith.ErrCreatorRequired),
+		errors.Is(err, keysmith.ErrTermsVersionRequired),
+		errors.Is(err, keysmith.ErrNonceRequired),
+		errors.Is(err, keysmith.ErrUnknownPrefix),
+		errors.Is(err, keysmith.ErrPrefixRuleViolation),
+		errors.Is(err, keysmith.ErrRequestStale),
+		errors.Is(err, keysmith.ErrInvalidKeyTransfer),
+		errors.Is(err, keysmith.ErrInvalidLockdown),
+		errors.Is(err, usage.ErrInvalidRecordingPolicy):
+		return forge.BadRequest(err.Error())
+	case errors.Is(err, keysmith.ErrInvalidMetadata),
+		errors.Is(err, keysmith.ErrTermsNotAccepted):
+		return forge.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, keysmith.ErrEngineStopping),
+		errors.Is(err, keysmith.ErrReadOnlyMode),
+		errors.Is(err, keysmith.ErrAuthorizerUnavailable):
+		return forge.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	case errors.Is(err, keysmith.ErrMaintenanceUnsupported),
+		errors.Is(err, keysmith.ErrStorageReportUnsupported),
+		errors.Is(err, keysmith.ErrLiveStatsDisabled):
+		return forge.NewHTTPError(http.StatusNotImplemented, err.Error())
 	case errors.Is(err, keysmith.ErrIPNotAllowed),
 		errors.Is(err, keysmith.ErrOriginNotAllowed),
-		errors.Is(err, keysmith.ErrScopeNotAllowed):
+		errors.Is(err, keysmith.ErrMethodNotAllowed),
+		errors.Is(err, keysmith.ErrPathNotAllowed),
+		errors.Is(err, keysmith.ErrConsumerNotAllowed),
+		errors.Is(err, keysmith.ErrTransportNotAllowed),
+		errors.Is(err, keysmith.ErrClientCertMismatch),
+		errors.Is(err, keysmith.ErrAuthzDenied),
+		errors.Is(err, keysmith.ErrPrefixNotAccepted),
+		errors.Is(err, keysmith.ErrTermsOutdated),
+		errors.Is(err, keysmith.ErrKeyFlagNotAllowed),
+		errors.Is(err, keysmith.ErrScopeNotAllowed),
+		errors.Is(err, keysmith.ErrDebugCaptureNotAllowed),
+		errors.Is(err, keysmith.ErrErasureNotAllowed),
+		errors.Is(err, keysmith.ErrKeyTransferNotTarget):
 		return forge.Forbidden(err.Error())
 	default:
 		return err
 	}
 }
 
-func defaultLimit(limit int) int {
-	if limit <= 0 {
-		return 50
+// validateEnum answers 422 when a request field holds none of its enum's
+// values, naming the field and the values it allows. Enum fields arrive
+// lowercased, so only unknown values are rejected, not differently cased
+// ones.
+func validateEnum(field string, v interface{ Validate() error }) error {
+	if err := v.Validate(); err != nil {
+		return forge.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("invalid %s: %v", field, err))
+	}
+	return nil
+}
+
+// toKeyFlags converts request flags, keeping nil so that an omitted list
+// leaves a key's flags unchanged.
+func toKeyFlags(ss []string) key.Flags {
+	if ss == nil {
+		return nil
+	}
+	flags := make(key.Flags, len(ss))
+	for i, s := range ss {
+		flags[i] = key.Flag(s)
+	}
+	return flags
+}
+
+func flagStrings(flags []key.Flag) []string {
+	if len(flags) == 0 {
+		return nil
 	}
-	if limit > 1000 {
-		return 1000
+	ss := make([]string, len(flags))
+	for i, f := range flags {
+		ss[i] = string(f)
 	}
+	return ss
+}
+
+// PageLimitHeader is set on list responses to the limit the list was
+// served with.
+const PageLimitHeader = apitypes.PageLimitHeader
+
+// pageLimit returns the limit the engine lists with when asked for limit,
+// def for none, and reports it in PageLimitHeader so that a client can tell
+// a clamped limit from a short last page.
+func (a *API) pageLimit(ctx forge.Context, limit, def int) int {
+	limit = a.eng.PageLimit(limit, def)
+	ctx.SetHeader(PageLimitHeader, strconv.Itoa(limit))
 	return limit
 }
 
@@ -64,41 +191,34 @@ func parseTime(s string) *time.Time {
 	return &t
 }
 
-func parseDuration(s string) time.Duration {
+// parseTimeParam parses an optional RFC 3339 query parameter, rejecting a
+// malformed value instead of ignoring it like parseTime.
+func parseTimeParam(name, s string) (*time.Time, error) {
 	if s == "" {
-		return 0
-	}
-	d, err := time.ParseDuration(s)
-	if err == nil {
-		return d
+		return nil, nil
 	}
-	// Handle "30d", "90d", "2w" style durations.
-	if len(s) > 1 {
-		val := s[:len(s)-1]
-		switch s[len(s)-1] {
-		case 'd':
-			var days int
-			if err := parseIntFromString(val, &days); err == nil {
-				return time.Duration(days) * 24 * time.Hour
-			}
-		case 'w':
-			var weeks int
-			if err := parseIntFromString(val, &weeks); err == nil {
-				return time.Duration(weeks) * 7 * 24 * time.Hour
-			}
-		}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, forge.BadRequest(fmt.Sprintf("invalid %s: %v", name, err))
 	}
-	return 0
+	return &t, nil
+}
+
+// timeBounds holds the parsed created_after, created_before, and
+// updated_after query parameters of a list request.
+type timeBounds struct {
+	createdAfter, createdBefore, updatedAfter *time.Time
 }
 
-func parseIntFromString(s string, out *int) error {
-	n := 0
-	for _, c := range s {
-		if c < '0' || c > '9' {
-			return errors.New("not a number")
-		}
-		n = n*10 + int(c-'0')
+func parseTimeBounds(createdAfter, createdBefore, updatedAfter string) (timeBounds, error) {
+	var b timeBounds
+	var err error
+	if b.createdAfter, err = parseTimeParam("created_after", createdAfter); err != nil {
+		return b, err
 	}
-	*out = n
-	return nil
+	if b.createdBefore, err = parseTimeParam("created_before", createdBefore); err != nil {
+		return b, err
+	}
+	b.updatedAfter, err = parseTimeParam("updated_after", updatedAfter)
+	return b, err
 }
@@ -0,0 +1,206 @@
+package api
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/xraph/forge"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/middleware"
+	"github.com/xraph/keysmith/scope"
+)
+
+// validateKeyPath is the validation route under /v1, shared with the
+// integration guide so the documented URL cannot drift from the route.
+const validateKeyPath = "/keys/validate"
+
+// Prefix and environment used in the guide's snippets for a tenant without
+// active keys.
+const (
+	guideDefaultPrefix = "sk"
+	guideDefaultEnv    = key.EnvTest
+)
+
+func (a *API) getIntegrationGuide(ctx forge.Context, req *GetIntegrationGuideRequest) (*IntegrationGuideResponse, error) {
+	if keysmith.TenantIDFromContext(ctx.Context()) == "" {
+		return nil, forge.Forbidden("the integration guide is generated per tenant and requires a tenant-scoped caller")
+	}
+	if req.Format != "" && req.Format != "json" && req.Format != "markdown" {
+		return nil, forge.BadRequest(fmt.Sprintf("unknown format %q: use json or markdown", req.Format))
+	}
+
+	resp, err := a.integrationGuide(ctx.Context(), a.guideBaseURL(ctx.Request()))
+	if err != nil {
+		return nil, fmt.Errorf("integration guide: %w", err)
+	}
+	if req.Format == "markdown" {
+		ctx.SetHeader("Content-Type", "text/markdown; charset=utf-8")
+		return resp, ctx.Bytes(http.StatusOK, []byte(renderIntegrationGuide(resp)))
+	}
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+// integrationGuide builds the guide for the context's tenant. It reads key
+// prefixes and environments, never key material, and templates snippets
+// with fake keys.
+func (a *API) integrationGuide(ctx context.Context, baseURL string) (*IntegrationGuideResponse, error) {
+	type prefixEnv struct {
+		prefix string
+		env    key.Environment
+	}
+	counts := make(map[prefixEnv]int)
+	err := a.eng.IterateKeys(ctx, &key.ListFilter{}, func(k *key.Key) error {
+		if k.State == key.StateActive {
+			counts[prefixEnv{k.Prefix, k.Environment}]++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list keys: %w", err)
+	}
+	keys := make([]IntegrationKeyResponse, 0, len(counts))
+	for pe, n := range counts {
+		keys = append(keys, IntegrationKeyResponse{
+			Prefix:      pe.prefix,
+			Environment: KeyEnvironment(pe.env),
+			ActiveKeys:  n,
+			ExampleKey:  exampleKeyFor(pe.prefix, pe.env),
+		})
+	}
+	// Most used first, so snippets show the tenant's main prefix.
+	slices.SortFunc(keys, func(x, y IntegrationKeyResponse) int {
+		return cmp.Or(
+			cmp.Compare(y.ActiveKeys, x.ActiveKeys),
+			cmp.Compare(x.Prefix, y.Prefix),
+			cmp.Compare(x.Environment, y.Environment),
+		)
+	})
+
+	catalog := []IntegrationScopeResponse{}
+	err = a.eng.IterateScopes(ctx, &scope.ListFilter{}, func(s *scope.Scope) error {
+		catalog = append(catalog, IntegrationScopeResponse{Name: s.Name, Description: s.Description, Parent: s.Parent})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list scopes: %w", err)
+	}
+	slices.SortFunc(catalog, func(x, y IntegrationScopeResponse) int { return cmp.Compare(x.Name, y.Name) })
+
+	example := exampleKeyFor(guideDefaultPrefix, guideDefaultEnv)
+	if len(keys) > 0 {
+		example = keys[0].ExampleKey
+	}
+	validation := IntegrationEndpointResponse{
+		Method: http.MethodPost,
+		URL:    baseURL + "/v1" + validateKeyPath,
+		Body:   fmt.Sprintf(`{"raw_key": %q}`, example),
+	}
+
+	return &IntegrationGuideResponse{
+		TenantID:   keysmith.TenantIDFromContext(ctx),
+		AppID:      keysmith.AppIDFromContext(ctx),
+		BaseURL:    baseURL,
+		Validation: validation,
+		Keys:       keys,
+		Headers:    middleware.Headers(a.middlewareOpts...),
+		Snippets:   integrationSnippets(validation),
+		Scopes:     catalog,
+	}, nil
+}
+
+// exampleKeyFor returns a fake key with the given prefix and environment,
+// in the same shape as exampleRawKey. Its "x" padding is not hex, so it can
+// never be a key the default generator produced.
+func exampleKeyFor(prefix string, env key.Environment) string {
+	return fmt.Sprintf("%s_%s_examplexxxxxxxxxxxxxxxxxxxx", prefix, env)
+}
+
+func integrationSnippets(v IntegrationEndpointResponse) []IntegrationSnippetResponse {
+	return []IntegrationSnippetResponse{
+		{
+			Language: "curl",
+			Code: fmt.Sprintf("curl -X %s %s \\\n  -H \"Content-Type: application/json\" \\\n  -d '%s'",
+				v.Method, v.URL, v.Body),
+		},
+		{
+			Language: "go",
+			Code: fmt.Sprintf("body := strings.NewReader(`%s`)\nresp, err := http.Post(%q, \"application/json\", body)",
+				v.Body, v.URL),
+		},
+		{
+			Language: "javascript",
+			Code: fmt.Sprintf("const res = await fetch(%q, {\n  method: %q,\n  headers: { \"Content-Type\": \"application/json\" },\n  body: JSON.stringify(%s),\n});",
+				v.URL, v.Method, v.Body),
+		},
+	}
+}
+
+// guideBaseURL returns the URL the API is mounted at: the scheme and host
+// the request was made to, honouring X-Forwarded-Proto from a proxy, and the
+// base path.
+func (a *API) guideBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if p := r.Header.Get("X-Forwarded-Proto"); p == "http" || p == "https" {
+		scheme = p
+	}
+	return scheme + "://" + r.Host + a.basePath
+}
+
+// renderIntegrationGuide renders g as a Markdown document.
+func renderIntegrationGuide(g *IntegrationGuideResponse) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Integrating with %s\n\n", g.TenantID)
+	fmt.Fprintf(&b, "Base URL: `%s`\n\n", g.BaseURL)
+
+	b.WriteString("## Keys\n\n")
+	if len(g.Keys) == 0 {
+		b.WriteString("No active keys yet. Examples use a `" + guideDefaultPrefix + "_" + string(guideDefaultEnv) + "_` key.\n\n")
+	} else {
+		b.WriteString("| Prefix | Environment | Active keys | Example |\n| --- | --- | --- | --- |\n")
+		for _, k := range g.Keys {
+			fmt.Fprintf(&b, "| `%s` | %s | %d | `%s` |\n", k.Prefix, k.Environment, k.ActiveKeys, k.ExampleKey)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## Headers\n\nSend the key in one of the `key` headers.\n\n")
+	b.WriteString("| Header | Value | Purpose |\n| --- | --- | --- |\n")
+	for _, h := range g.Headers {
+		fmt.Fprintf(&b, "| `%s` | `%s` | %s |\n", h.Name, h.Value, h.Purpose)
+	}
+	b.WriteString("\n")
+
+	fmt.Fprintf(&b, "## Validating a key\n\n`%s %s`\n\n", g.Validation.Method, g.Validation.URL)
+	for _, s := range g.Snippets {
+		fmt.Fprintf(&b, "```%s\n%s\n```\n\n", markdownLang(s.Language), s.Code)
+	}
+
+	b.WriteString("## Scopes\n\n")
+	if len(g.Scopes) == 0 {
+		b.WriteString("No scopes defined.\n")
+	}
+	for _, s := range g.Scopes {
+		fmt.Fprintf(&b, "- `%s`", s.Name)
+		if s.Description != "" {
+			fmt.Fprintf(&b, ": %s", s.Description)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func markdownLang(language string) string {
+	if language == "curl" {
+		return "sh"
+	}
+	return language
+}
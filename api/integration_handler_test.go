@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/middleware"
+	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+// guideFixture is an engine with a fixed tenant configuration and the raw
+// keys and hashes created for it.
+type guideFixture struct {
+	api     *API
+	ctx     context.Context
+	secrets []string
+}
+
+func newGuideFixture(t *testing.T) *guideFixture {
+	t.Helper()
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()))
+	require.NoError(t, err)
+	f := &guideFixture{
+		api: New(eng, nil,
+			WithBasePath("/keysmith"),
+			WithMiddlewareOptions(middleware.WithConsumerHeader("X-Service-Name")),
+		),
+		ctx: keysmith.WithTenant(context.Background(), "app_1", "tenant_acme"),
+	}
+
+	for _, in := range []keysmith.CreateKeyInput{
+		{Name: "web", Prefix: "acme", Environment: key.EnvLive},
+		{Name: "worker", Prefix: "acme", Environment: key.EnvLive},
+		{Name: "ci", Prefix: "acme", Environment: key.EnvTest},
+		{Name: "old", Prefix: "legacy", Environment: key.EnvLive},
+	} {
+		created, err := eng.CreateKey(f.ctx, &in)
+		require.NoError(t, err)
+		f.secrets = append(f.secrets, created.RawKey, created.Key.KeyHash)
+		if in.Prefix == "legacy" {
+			require.NoError(t, eng.RevokeKey(f.ctx, created.Key.ID, "retired"))
+		}
+	}
+	for _, s := range []scope.Scope{
+		{Name: "read", Description: "Read access"},
+		{Name: "read:invoices", Description: "Read invoices", Parent: "read"},
+	} {
+		require.NoError(t, eng.CreateScope(f.ctx, &s))
+	}
+
+	// Another tenant's keys never appear.
+	other, err := eng.CreateKey(keysmith.WithTenant(context.Background(), "app_1", "tenant_other"),
+		&keysmith.CreateKeyInput{Name: "other", Prefix: "other", Environment: key.EnvLive})
+	require.NoError(t, err)
+	f.secrets = append(f.secrets, other.RawKey, other.Key.KeyHash)
+	return f
+}
+
+func (f *guideFixture) guide(t *testing.T) *IntegrationGuideResponse {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/keysmith/v1/integration-guide", nil)
+	r.Host = "api.acme.test"
+	r.Header.Set("X-Forwarded-Proto", "https")
+	g, err := f.api.integrationGuide(f.ctx, f.api.guideBaseURL(r))
+	require.NoError(t, err)
+	return g
+}
+
+// checkGolden compares got with testdata/name, rewriting it under -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		require.NoError(t, os.WriteFile(path, got, 0o644))
+	}
+	want, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(got), "run go test ./api -run IntegrationGuide -update to accept changes")
+}
+
+func TestIntegrationGuide_Golden(t *testing.T) {
+	g := newGuideFixture(t).guide(t)
+
+	b, err := json.MarshalIndent(g, "", "  ")
+	require.NoError(t, err)
+	checkGolden(t, "integration_guide.json", append(b, '\n'))
+	checkGolden(t, "integration_guide.md", []byte(renderIntegrationGuide(g)))
+}
+
+func TestIntegrationGuide_NoKeyMaterial(t *testing.T) {
+	f := newGuideFixture(t)
+	g := f.guide(t)
+	b, err := json.Marshal(g)
+	require.NoError(t, err)
+	out := string(b) + renderIntegrationGuide(g)
+
+	for _, s := range f.secrets {
+		assert.NotContains(t, out, s)
+		if i := strings.LastIndexByte(s, '_'); i >= 0 {
+			assert.NotContains(t, out, s[i+1:], "the random part of a raw key")
+		}
+	}
+
+	// The example keys do not validate.
+	for _, k := range g.Keys {
+		_, err := f.api.eng.ValidateKey(f.ctx, k.ExampleKey)
+		assert.ErrorIs(t, err, keysmith.ErrInvalidKey, k.ExampleKey)
+	}
+}
+
+func TestIntegrationGuide_TracksConfig(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()))
+	require.NoError(t, err)
+	a := New(eng, nil, WithMiddlewareOptions(middleware.WithRequestIDHeader("")))
+	ctx := keysmith.WithTenant(context.Background(), "", "tenant_new")
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/integration-guide", nil)
+	g, err := a.integrationGuide(ctx, a.guideBaseURL(r))
+	require.NoError(t, err)
+
+	assert.Equal(t, "http://example.com", g.BaseURL)
+	assert.Equal(t, "http://example.com/v1/keys/validate", g.Validation.URL)
+	assert.Empty(t, g.Keys)
+	assert.Contains(t, g.Validation.Body, exampleRawKey, "a tenant without keys gets the default prefix")
+	assert.Equal(t, middleware.Headers(middleware.WithRequestIDHeader("")), g.Headers)
+	assert.Empty(t, g.Scopes)
+}
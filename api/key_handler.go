@@ -3,6 +3,9 @@ package api
 import (
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/xraph/forge"
 
@@ -13,6 +16,9 @@ import (
 )
 
 func (a *API) createKey(ctx forge.Context, req *CreateKeyRequest) (*KeyCreateResponse, error) {
+	if err := validateEnum("environment", req.Environment); err != nil {
+		return nil, err
+	}
 	input := &keysmith.CreateKeyInput{
 		Name:        req.Name,
 		Description: req.Description,
@@ -21,6 +27,26 @@ func (a *API) createKey(ctx forge.Context, req *CreateKeyRequest) (*KeyCreateRes
 		Scopes:      req.Scopes,
 		Metadata:    req.Metadata,
 		ExpiresAt:   req.ExpiresAt,
+
+		AllowedOrigins: req.AllowedOrigins,
+
+		IntendedConsumer: req.IntendedConsumer,
+		EnforceConsumer:  req.EnforceConsumer,
+		CertFingerprint:  req.CertFingerprint,
+
+		Flags: toKeyFlags(req.Flags),
+
+		Contacts: req.Contacts,
+
+		Labels: req.Labels,
+
+		AcceptedTermsVersion: req.AcceptedTermsVersion,
+
+		SkipDefaultScopes: req.SkipDefaultScopes,
+
+		ExternalRef: req.ExternalRef,
+
+		InlinePolicy: inlinePolicyFromRequest(req.InlinePolicy),
 	}
 
 	if req.PolicyID != "" {
@@ -30,59 +56,229 @@ func (a *API) createKey(ctx forge.Context, req *CreateKeyRequest) (*KeyCreateRes
 		}
 		input.PolicyID = &polID
 	}
+	if req.PoolID != "" {
+		poolID, err := id.ParseQuotaPoolID(req.PoolID)
+		if err != nil {
+			return nil, forge.BadRequest(fmt.Sprintf("invalid quota pool ID: %v", err))
+		}
+		input.PoolID = &poolID
+	}
 
 	result, err := a.eng.CreateKey(ctx.Context(), input)
 	if err != nil {
 		return nil, fmt.Errorf("create key: %w", err)
 	}
 
+	scopes := result.Key.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
 	resp := &KeyCreateResponse{
 		Key:    toKeyResponse(result.Key),
 		RawKey: result.RawKey,
+		Scopes: scopes,
+
+		DuplicateOfExisting: result.DuplicateOfExisting,
+	}
+	if result.DuplicateOfExisting {
+		return resp, ctx.JSON(http.StatusOK, resp)
 	}
 	return resp, ctx.JSON(http.StatusCreated, resp)
 }
 
-func (a *API) getKey(ctx forge.Context, _ *GetKeyRequest) (*KeyResponse, error) {
+func (a *API) getKey(ctx forge.Context, req *GetKeyRequest) (*KeyResponse, error) {
 	keyID, err := id.ParseKeyID(ctx.Param("keyId"))
 	if err != nil {
 		return nil, forge.BadRequest(fmt.Sprintf("invalid key ID: %v", err))
 	}
+	sel, err := parseFields(req.Fields, keyFields)
+	if err != nil {
+		return nil, err
+	}
+	etag := a.revisionETag(ctx)
 
 	k, err := a.eng.GetKey(ctx.Context(), keyID)
 	if err != nil {
 		return nil, mapStoreError(err)
 	}
 
+	groups, err := a.eng.KeyGroups(ctx.Context(), []*key.Key{k})
+	if err != nil {
+		return nil, fmt.Errorf("list key groups: %w", err)
+	}
+
 	resp := toKeyResponse(k)
-	return resp, ctx.JSON(http.StatusOK, resp)
+	resp.Groups = groupIDStrings(groups[k.ID])
+	if sel.has("quota_forecast") {
+		// The forecast is an extra; a key without a monthly quota, or a
+		// usage store that cannot be read, leaves it out. It follows usage,
+		// which does not bump the revision, so a response with one has no
+		// ETag.
+		if f, err := a.eng.QuotaForecast(ctx.Context(), keyID); err == nil {
+			resp.QuotaForecast = toQuotaForecastSummary(f)
+			etag = ""
+		}
+	}
+	if revisionMatches(ctx, etag) {
+		return nil, ctx.NoContent(http.StatusNotModified)
+	}
+	setValidators(ctx, etag, k.UpdatedAt)
+	return resp, writeSelected(ctx, sel, resp)
 }
 
-func (a *API) listKeys(ctx forge.Context, req *ListKeysRequest) ([]*KeyResponse, error) {
+func (a *API) listKeys(ctx forge.Context, req *ListKeysRequest) (*struct{}, error) {
+	if err := validateEnum("environment", req.Environment); err != nil {
+		return nil, err
+	}
+	if err := validateEnum("state", req.State); err != nil {
+		return nil, err
+	}
+	sel, err := parseFields(req.Fields, keyFields)
+	if err != nil {
+		return nil, err
+	}
+	bounds, err := parseTimeBounds(req.CreatedAfter, req.CreatedBefore, req.UpdatedAfter)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := keysmith.ParseLabelSelector(req.LabelSelector)
+	if err != nil {
+		return nil, forge.BadRequest(err.Error())
+	}
+	var groupID *id.GroupID
+	if req.GroupID != "" {
+		gid, err := id.ParseGroupID(req.GroupID)
+		if err != nil {
+			return nil, forge.BadRequest(fmt.Sprintf("invalid group_id: %v", err))
+		}
+		groupID = &gid
+	}
+	var poolID *id.QuotaPoolID
+	if req.PoolID != "" {
+		pid, err := id.ParseQuotaPoolID(req.PoolID)
+		if err != nil {
+			return nil, forge.BadRequest(fmt.Sprintf("invalid pool_id: %v", err))
+		}
+		poolID = &pid
+	}
+	etag := a.revisionETag(ctx)
+	if revisionMatches(ctx, etag) {
+		return nil, ctx.NoContent(http.StatusNotModified)
+	}
+
 	keys, err := a.eng.ListKeys(ctx.Context(), &key.ListFilter{
-		Environment: key.Environment(req.Environment),
-		State:       key.State(req.State),
-		Limit:       defaultLimit(req.Limit),
-		Offset:      req.Offset,
+		AppID:         req.AppID,
+		Environment:   key.Environment(req.Environment),
+		State:         key.State(req.State),
+		CreatedBy:     req.CreatedBy,
+		ExternalRef:   req.ExternalRef,
+		CreatedAfter:  bounds.createdAfter,
+		CreatedBefore: bounds.createdBefore,
+		UpdatedAfter:  bounds.updatedAfter,
+		OutdatedTerms: req.OutdatedTerms,
+		LabelSelector: labels,
+		GroupID:       groupID,
+		PoolID:        poolID,
+		Limit:         a.pageLimit(ctx, req.Limit, keysmith.DefaultPageSize),
+		Offset:        req.Offset,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("list keys: %w", err)
 	}
+	setValidators(ctx, etag, lastModified(keys, func(k *key.Key) time.Time { return k.UpdatedAt }))
+
+	groups, err := a.eng.KeyGroups(ctx.Context(), keys)
+	if err != nil {
+		return nil, fmt.Errorf("list key groups: %w", err)
+	}
 
 	resp := make([]*KeyResponse, len(keys))
 	for i, k := range keys {
 		resp[i] = toKeyResponse(k)
+		resp[i].Groups = groupIDStrings(groups[k.ID])
+	}
+	return nil, writeSelectedList(ctx, sel, resp)
+}
+
+func (a *API) keyOverview(ctx forge.Context, req *KeyOverviewRequest) (*KeyOverviewResponse, error) {
+	q := &key.OverviewQuery{
+		TenantID:      req.TenantID,
+		AppID:         req.AppID,
+		CreatedWithin: time.Duration(req.CreatedWithinDays) * 24 * time.Hour,
+	}
+	if req.ExpiringWithinDays != "" {
+		for _, f := range strings.Split(req.ExpiringWithinDays, ",") {
+			days, err := strconv.Atoi(strings.TrimSpace(f))
+			if err != nil {
+				return nil, forge.BadRequest(fmt.Sprintf("invalid expiring_within_days: %q is not a number of days", f))
+			}
+			q.ExpiringWithin = append(q.ExpiringWithin, time.Duration(days)*24*time.Hour)
+		}
+	}
+
+	o, err := a.eng.TenantKeyOverview(ctx.Context(), q)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toKeyOverviewResponse(o)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) search(ctx forge.Context, req *SearchRequest) (*SearchResponse, error) {
+	matches, err := a.eng.SearchKeys(ctx.Context(), req.Query, keysmith.SearchOptions{
+		TenantID: req.TenantID,
+		AppID:    req.AppID,
+		Limit:    req.Limit,
+	})
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := &SearchResponse{Keys: make([]*KeySearchMatchResponse, len(matches))}
+	for i, m := range matches {
+		resp.Keys[i] = toKeySearchMatchResponse(m)
 	}
 	return resp, ctx.JSON(http.StatusOK, resp)
 }
 
-func (a *API) deleteKey(ctx forge.Context, _ *DeleteKeyRequest) (*struct{}, error) {
+func (a *API) updateKey(ctx forge.Context, req *UpdateKeyRequest) (*KeyResponse, error) {
 	keyID, err := id.ParseKeyID(ctx.Param("keyId"))
 	if err != nil {
 		return nil, forge.BadRequest(fmt.Sprintf("invalid key ID: %v", err))
 	}
 
-	if err := a.eng.RevokeKey(ctx.Context(), keyID, "deleted via API"); err != nil {
+	k, err := a.eng.UpdateKey(engineContext(ctx, req.DryRun), keyID, &keysmith.UpdateKeyInput{
+		Name:           req.Name,
+		Description:    req.Description,
+		Metadata:       req.Metadata,
+		AllowedOrigins: req.AllowedOrigins,
+
+		IntendedConsumer: req.IntendedConsumer,
+		EnforceConsumer:  req.EnforceConsumer,
+		CertFingerprint:  req.CertFingerprint,
+
+		Flags: toKeyFlags(req.Flags),
+
+		Labels: req.Labels,
+
+		InlinePolicy: inlinePolicyFromRequest(req.InlinePolicy),
+	})
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toKeyResponse(k)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) deleteKey(ctx forge.Context, req *DeleteKeyRequest) (*struct{}, error) {
+	keyID, err := id.ParseKeyID(ctx.Param("keyId"))
+	if err != nil {
+		return nil, forge.BadRequest(fmt.Sprintf("invalid key ID: %v", err))
+	}
+
+	if err := a.eng.RevokeKey(engineContext(ctx, req.DryRun), keyID, "deleted via API"); err != nil {
 		return nil, mapStoreError(err)
 	}
 
@@ -90,14 +286,17 @@ func (a *API) deleteKey(ctx forge.Context, _ *DeleteKeyRequest) (*struct{}, erro
 }
 
 func (a *API) rotateKey(ctx forge.Context, req *RotateKeyRequest) (*KeyCreateResponse, error) {
+	if err := validateEnum("reason", req.Reason); err != nil {
+		return nil, err
+	}
 	keyID, err := id.ParseKeyID(ctx.Param("keyId"))
 	if err != nil {
 		return nil, forge.BadRequest(fmt.Sprintf("invalid key ID: %v", err))
 	}
 
-	result, err := a.eng.RotateKey(ctx.Context(), keyID, rotation.Reason(req.Reason))
+	result, err := a.eng.RotateKey(engineContext(ctx, req.DryRun), keyID, rotation.Reason(req.Reason))
 	if err != nil {
-		return nil, fmt.Errorf("rotate key: %w", err)
+		return nil, mapStoreError(err)
 	}
 
 	resp := &KeyCreateResponse{
@@ -113,20 +312,65 @@ func (a *API) revokeKey(ctx forge.Context, req *RevokeKeyRequest) (*struct{}, er
 		return nil, forge.BadRequest(fmt.Sprintf("invalid key ID: %v", err))
 	}
 
-	if err := a.eng.RevokeKey(ctx.Context(), keyID, req.Reason); err != nil {
+	if err := a.eng.RevokeKey(engineContext(ctx, req.DryRun), keyID, req.Reason); err != nil {
 		return nil, mapStoreError(err)
 	}
 
 	return nil, ctx.NoContent(http.StatusNoContent)
 }
 
-func (a *API) suspendKey(ctx forge.Context, _ *SuspendKeyRequest) (*struct{}, error) {
+func (a *API) revokeByLabels(ctx forge.Context, req *RevokeByLabelsRequest) (*LabelRevokeResponse, error) {
+	if err := validateEnum("environment", req.Environment); err != nil {
+		return nil, err
+	}
+	sel, err := keysmith.ParseLabelSelector(req.LabelSelector)
+	if err != nil {
+		return nil, forge.BadRequest(err.Error())
+	}
+
+	res, err := a.eng.BulkRevokeByLabels(engineContext(ctx, req.DryRun), &key.ListFilter{
+		AppID:         req.AppID,
+		Environment:   key.Environment(req.Environment),
+		LabelSelector: sel,
+	}, req.Reason)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toLabelRevokeResponse(sel, res)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) reportCompromise(ctx forge.Context, req *ReportCompromiseRequest) (*CompromiseResponse, error) {
+	keyID, err := id.ParseKeyID(ctx.Param("keyId"))
+	if err != nil {
+		return nil, forge.BadRequest(fmt.Sprintf("invalid key ID: %v", err))
+	}
+
+	result, err := a.eng.ReportCompromise(engineContext(ctx, req.DryRun), keyID, &key.CompromiseReport{
+		Source:  req.Source,
+		Details: req.Details,
+		Action:  key.CompromiseAction(req.Action),
+	})
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := &CompromiseResponse{
+		Action: string(result.Action),
+		Key:    toKeyResponse(result.Key),
+		RawKey: result.RawKey,
+	}
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) suspendKey(ctx forge.Context, req *SuspendKeyRequest) (*struct{}, error) {
 	keyID, err := id.ParseKeyID(ctx.Param("keyId"))
 	if err != nil {
 		return nil, forge.BadRequest(fmt.Sprintf("invalid key ID: %v", err))
 	}
 
-	if err := a.eng.SuspendKey(ctx.Context(), keyID); err != nil {
+	if err := a.eng.SuspendKey(engineContext(ctx, req.DryRun), keyID); err != nil {
 		return nil, mapStoreError(err)
 	}
 
@@ -145,3 +389,74 @@ func (a *API) reactivateKey(ctx forge.Context, _ *ReactivateKeyRequest) (*struct
 
 	return nil, ctx.NoContent(http.StatusNoContent)
 }
+
+func (a *API) getKeyContacts(ctx forge.Context, _ *GetKeyContactsRequest) (*KeyContactsResponse, error) {
+	keyID, err := id.ParseKeyID(ctx.Param("keyId"))
+	if err != nil {
+		return nil, forge.BadRequest(fmt.Sprintf("invalid key ID: %v", err))
+	}
+
+	kc, err := a.eng.KeyContacts(ctx.Context(), keyID)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toKeyContactsResponse(keyID.String(), kc)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) listDuplicateKeyNames(ctx forge.Context, req *ListDuplicateKeyNamesRequest) (*struct{}, error) {
+	dups, err := a.eng.DuplicateKeyNames(ctx.Context(), req.TenantID)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := make([]*KeyNameDuplicateResponse, len(dups))
+	for i, d := range dups {
+		resp[i] = toKeyNameDuplicateResponse(d)
+	}
+	return nil, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) getLiveKeyStats(ctx forge.Context, _ *GetLiveKeyStatsRequest) (*LiveKeyStatsResponse, error) {
+	keyID, err := id.ParseKeyID(ctx.Param("keyId"))
+	if err != nil {
+		return nil, forge.BadRequest(fmt.Sprintf("invalid key ID: %v", err))
+	}
+
+	st, err := a.eng.LiveKeyStats(ctx.Context(), keyID)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toLiveKeyStatsResponse(st)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) listLiveTopKeys(ctx forge.Context, req *ListLiveTopKeysRequest) (*struct{}, error) {
+	top, err := a.eng.LiveTopKeys(ctx.Context(), req.N)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := make([]*LiveKeyStatsResponse, len(top))
+	for i, st := range top {
+		resp[i] = toLiveKeyStatsResponse(st)
+	}
+	return nil, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) putKeyContacts(ctx forge.Context, req *PutKeyContactsRequest) (*KeyContactsResponse, error) {
+	keyID, err := id.ParseKeyID(ctx.Param("keyId"))
+	if err != nil {
+		return nil, forge.BadRequest(fmt.Sprintf("invalid key ID: %v", err))
+	}
+
+	kc, err := a.eng.SetKeyContacts(ctx.Context(), keyID, req.Contacts)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toKeyContactsResponse(keyID.String(), kc)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
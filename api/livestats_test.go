@@ -0,0 +1,56 @@
+package api_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/apitypes"
+	"github.com/xraph/keysmith/client"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+func TestLiveKeyStats_Endpoints(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithLiveStats(0))
+	require.NoError(t, err)
+	c := client.New(newEventServer(t, eng).URL)
+	ctx := context.Background()
+
+	busy, err := eng.CreateKey(acmeCtx(), &keysmith.CreateKeyInput{Name: "busy", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	quiet, err := eng.CreateKey(acmeCtx(), &keysmith.CreateKeyInput{Name: "quiet", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	idle := createAcmeKey(t, eng, "idle")
+	for range 20 {
+		_, err := eng.ValidateKey(acmeCtx(), busy.RawKey)
+		require.NoError(t, err)
+	}
+	_, err = eng.ValidateKey(acmeCtx(), quiet.RawKey)
+	require.NoError(t, err)
+
+	st, err := c.GetLiveKeyStats(ctx, &apitypes.GetLiveKeyStatsRequest{KeyID: busy.Key.ID.String()})
+	require.NoError(t, err)
+	assert.Equal(t, "tenant_acme", st.TenantID)
+	assert.Equal(t, int64(20), st.Validations5m)
+	assert.InDelta(t, 2.0, st.QPS, 1e-9, "20 validations over the 10s window")
+	require.NotNil(t, st.LastSeen)
+	assert.WithinDuration(t, time.Now(), *st.LastSeen, 2*time.Second)
+
+	st, err = c.GetLiveKeyStats(ctx, &apitypes.GetLiveKeyStatsRequest{KeyID: idle.ID.String()})
+	require.NoError(t, err)
+	assert.Zero(t, st.Validations5m)
+	assert.Nil(t, st.TrackedSince, "an idle key is not tracked")
+
+	top, err := c.ListLiveTopKeys(ctx, &apitypes.ListLiveTopKeysRequest{N: 1})
+	require.NoError(t, err)
+	require.Len(t, top, 1)
+	assert.Equal(t, busy.Key.ID.String(), top[0].KeyID)
+
+	_, err = c.GetLiveKeyStats(ctx, &apitypes.GetLiveKeyStatsRequest{KeyID: "nope"})
+	assert.ErrorIs(t, err, client.ErrInvalidRequest)
+}
@@ -0,0 +1,98 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/api"
+	"github.com/xraph/keysmith/apitypes"
+	"github.com/xraph/keysmith/client"
+	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+// newPagingServer serves the API in tenant_test for an engine whose max page
+// size is 3, with five scopes.
+func newPagingServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithMaxPageSize(3))
+	require.NoError(t, err)
+	ctx := keysmith.WithTenant(context.Background(), "app_1", "tenant_test")
+	for i := range 5 {
+		require.NoError(t, eng.CreateScope(ctx, &scope.Scope{Name: fmt.Sprintf("read:%d", i)}))
+	}
+	h := api.New(eng, nil).Handler()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(keysmith.WithTenant(r.Context(), "app_1", "tenant_test")))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestListLimits_ClampedAndReported(t *testing.T) {
+	srv := newPagingServer(t)
+
+	for _, tt := range []struct {
+		query string
+		want  int
+	}{
+		{"", 3},
+		{"?limit=0", 3},
+		{"?limit=-1", 3},
+		{"?limit=2", 2},
+		{"?limit=3", 3},
+		{"?limit=4", 3},
+	} {
+		resp, err := http.Get(srv.URL + "/v1/scopes" + tt.query)
+		require.NoError(t, err)
+		var scopes []*api.ScopeResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&scopes))
+		_ = resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode, tt.query)
+		assert.Len(t, scopes, tt.want, tt.query)
+		assert.Equal(t, fmt.Sprint(tt.want), resp.Header.Get(api.PageLimitHeader), tt.query)
+	}
+}
+
+func TestListLimits_InEnvelope(t *testing.T) {
+	srv := newPagingServer(t)
+
+	resp, err := http.Get(srv.URL + "/v1/rotations?limit=500")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var body api.RotationListResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, 3, body.Limit, "the envelope reports the clamped limit")
+}
+
+func TestListLimits_IntegrationGuideSeesEveryScope(t *testing.T) {
+	srv := newPagingServer(t)
+
+	resp, err := http.Get(srv.URL + "/v1/integration-guide")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var guide api.IntegrationGuideResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&guide))
+	assert.Len(t, guide.Scopes, 5, "the guide iterates past the max page size")
+}
+
+func TestListLimits_ClientIteratesPastMax(t *testing.T) {
+	srv := newPagingServer(t)
+
+	var names []string
+	err := client.New(srv.URL).IterateScopes(context.Background(), &apitypes.ListScopesRequest{Limit: 100}, func(s *apitypes.ScopeResponse) error {
+		names = append(names, s.Name)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Len(t, names, 5)
+}
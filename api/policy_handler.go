@@ -7,32 +7,40 @@ import (
 
 	"github.com/xraph/forge"
 
+	"github.com/xraph/keysmith"
 	"github.com/xraph/keysmith/id"
 	"github.com/xraph/keysmith/policy"
 )
 
 func (a *API) createPolicy(ctx forge.Context, req *CreatePolicyRequest) (*PolicyResponse, error) {
-	pol := &policy.Policy{
-		ID:              id.NewPolicyID(),
-		Name:            req.Name,
-		Description:     req.Description,
-		RateLimit:       req.RateLimit,
-		RateLimitWindow: parseDuration(req.RateLimitWindow),
-		BurstLimit:      req.BurstLimit,
-		AllowedScopes:   req.AllowedScopes,
-		AllowedIPs:      req.AllowedIPs,
-		AllowedOrigins:  req.AllowedOrigins,
-		MaxKeyLifetime:  parseDuration(req.MaxKeyLifetime),
-		RotationPeriod:  parseDuration(req.RotationPeriod),
-		GracePeriod:     parseDuration(req.GracePeriod),
-		DailyQuota:      req.DailyQuota,
-		MonthlyQuota:    req.MonthlyQuota,
-		CreatedAt:       time.Now(),
-		UpdatedAt:       time.Now(),
+	pol, err := policyFromRequest(req)
+	if err != nil {
+		return nil, err
 	}
+	pol.ID = id.NewPolicyID()
+	pol.CreatedAt = time.Now()
+	pol.UpdatedAt = time.Now()
 
 	if err := a.eng.CreatePolicy(ctx.Context(), pol); err != nil {
-		return nil, fmt.Errorf("create policy: %w", err)
+		return nil, mapStoreError(err)
+	}
+
+	resp := toPolicyResponse(pol)
+	return resp, ctx.JSON(http.StatusCreated, resp)
+}
+
+func (a *API) createPolicyFromTemplate(ctx forge.Context, req *CreatePolicyFromTemplateRequest) (*PolicyResponse, error) {
+	var overrides *policy.Policy
+	if req.Overrides != nil {
+		var err error
+		if overrides, err = policyFromRequest(req.Overrides); err != nil {
+			return nil, err
+		}
+	}
+
+	pol, err := a.eng.CreatePolicyFromTemplate(ctx.Context(), req.Template, overrides, req.Clear...)
+	if err != nil {
+		return nil, mapStoreError(err)
 	}
 
 	resp := toPolicyResponse(pol)
@@ -44,30 +52,65 @@ func (a *API) getPolicy(ctx forge.Context, _ *GetPolicyRequest) (*PolicyResponse
 	if err != nil {
 		return nil, forge.BadRequest(fmt.Sprintf("invalid policy ID: %v", err))
 	}
+	etag := a.revisionETag(ctx)
+	if revisionMatches(ctx, etag) {
+		return nil, ctx.NoContent(http.StatusNotModified)
+	}
 
 	pol, err := a.eng.GetPolicy(ctx.Context(), polID)
 	if err != nil {
 		return nil, mapStoreError(err)
 	}
+	setValidators(ctx, etag, pol.UpdatedAt)
 
 	resp := toPolicyResponse(pol)
 	return resp, ctx.JSON(http.StatusOK, resp)
 }
 
-func (a *API) listPolicies(ctx forge.Context, req *ListPoliciesRequest) ([]*PolicyResponse, error) {
+func (a *API) getEffectivePolicy(ctx forge.Context, _ *GetEffectivePolicyRequest) (*EffectivePolicyResponse, error) {
+	polID, err := id.ParsePolicyID(ctx.Param("policyId"))
+	if err != nil {
+		return nil, forge.BadRequest(fmt.Sprintf("invalid policy ID: %v", err))
+	}
+
+	eff, err := a.eng.EffectivePolicy(ctx.Context(), polID)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toEffectivePolicyResponse(eff)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) listPolicies(ctx forge.Context, req *ListPoliciesRequest) (*struct{}, error) {
+	bounds, err := parseTimeBounds(req.CreatedAfter, req.CreatedBefore, req.UpdatedAfter)
+	if err != nil {
+		return nil, err
+	}
+	etag := a.revisionETag(ctx)
+	if revisionMatches(ctx, etag) {
+		return nil, ctx.NoContent(http.StatusNotModified)
+	}
+
 	policies, err := a.eng.ListPolicies(ctx.Context(), &policy.ListFilter{
-		Limit:  defaultLimit(req.Limit),
-		Offset: req.Offset,
+		AppID:         req.AppID,
+		CreatedAfter:  bounds.createdAfter,
+		CreatedBefore: bounds.createdBefore,
+		UpdatedAfter:  bounds.updatedAfter,
+		IncludeInline: req.IncludeInline,
+		Limit:         a.pageLimit(ctx, req.Limit, keysmith.DefaultPageSize),
+		Offset:        req.Offset,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("list policies: %w", err)
 	}
+	setValidators(ctx, etag, lastModified(policies, func(p *policy.Policy) time.Time { return p.UpdatedAt }))
 
 	resp := make([]*PolicyResponse, len(policies))
 	for i, p := range policies {
 		resp[i] = toPolicyResponse(p)
 	}
-	return resp, ctx.JSON(http.StatusOK, resp)
+	return nil, ctx.JSON(http.StatusOK, resp)
 }
 
 func (a *API) updatePolicy(ctx forge.Context, req *UpdatePolicyRequest) (*PolicyResponse, error) {
@@ -81,19 +124,28 @@ func (a *API) updatePolicy(ctx forge.Context, req *UpdatePolicyRequest) (*Policy
 		return nil, mapStoreError(err)
 	}
 
+	basePolicyID, err := parseBasePolicyID(req.BasePolicyID)
+	if err != nil {
+		return nil, err
+	}
+
 	pol.Name = req.Name
 	pol.Description = req.Description
+	pol.BasePolicyID = basePolicyID
 	pol.RateLimit = req.RateLimit
-	pol.RateLimitWindow = parseDuration(req.RateLimitWindow)
+	pol.RateLimitWindow = time.Duration(req.RateLimitWindow)
 	pol.BurstLimit = req.BurstLimit
 	pol.AllowedScopes = req.AllowedScopes
 	pol.AllowedIPs = req.AllowedIPs
 	pol.AllowedOrigins = req.AllowedOrigins
-	pol.MaxKeyLifetime = parseDuration(req.MaxKeyLifetime)
-	pol.RotationPeriod = parseDuration(req.RotationPeriod)
-	pol.GracePeriod = parseDuration(req.GracePeriod)
+	pol.MaxKeyLifetime = time.Duration(req.MaxKeyLifetime)
+	pol.RotationPeriod = time.Duration(req.RotationPeriod)
+	pol.GracePeriod = time.Duration(req.GracePeriod)
 	pol.DailyQuota = req.DailyQuota
 	pol.MonthlyQuota = req.MonthlyQuota
+	pol.QuotaTimezone = req.QuotaTimezone
+	pol.MinTLSVersion = req.MinTLSVersion
+	pol.RequireMTLS = req.RequireMTLS
 	pol.UpdatedAt = time.Now()
 
 	if err := a.eng.UpdatePolicy(ctx.Context(), pol); err != nil {
@@ -116,3 +168,59 @@ func (a *API) deletePolicy(ctx forge.Context, _ *DeletePolicyRequest) (*struct{}
 
 	return nil, ctx.NoContent(http.StatusNoContent)
 }
+
+func policyFromRequest(req *CreatePolicyRequest) (*policy.Policy, error) {
+	basePolicyID, err := parseBasePolicyID(req.BasePolicyID)
+	if err != nil {
+		return nil, err
+	}
+	return &policy.Policy{
+		Name:            req.Name,
+		Description:     req.Description,
+		BasePolicyID:    basePolicyID,
+		RateLimit:       req.RateLimit,
+		RateLimitWindow: time.Duration(req.RateLimitWindow),
+		BurstLimit:      req.BurstLimit,
+		AllowedScopes:   req.AllowedScopes,
+		AllowedIPs:      req.AllowedIPs,
+		AllowedOrigins:  req.AllowedOrigins,
+		MaxKeyLifetime:  time.Duration(req.MaxKeyLifetime),
+		RotationPeriod:  time.Duration(req.RotationPeriod),
+		GracePeriod:     time.Duration(req.GracePeriod),
+		DailyQuota:      req.DailyQuota,
+		MonthlyQuota:    req.MonthlyQuota,
+		QuotaTimezone:   req.QuotaTimezone,
+		MinTLSVersion:   req.MinTLSVersion,
+		RequireMTLS:     req.RequireMTLS,
+	}, nil
+}
+
+// inlinePolicyFromRequest returns the inline policy req describes, or nil
+// for none. The engine validates it like any other policy.
+func inlinePolicyFromRequest(req *InlinePolicyRequest) *policy.Policy {
+	if req == nil {
+		return nil
+	}
+	return &policy.Policy{
+		RateLimit:       req.RateLimit,
+		RateLimitWindow: time.Duration(req.RateLimitWindow),
+		BurstLimit:      req.BurstLimit,
+		AllowedIPs:      req.AllowedIPs,
+		AllowedOrigins:  req.AllowedOrigins,
+		DailyQuota:      req.DailyQuota,
+		MonthlyQuota:    req.MonthlyQuota,
+		QuotaTimezone:   req.QuotaTimezone,
+	}
+}
+
+// parseBasePolicyID parses an optional base policy ID; empty means none.
+func parseBasePolicyID(s string) (*id.PolicyID, error) {
+	if s == "" {
+		return nil, nil
+	}
+	polID, err := id.ParsePolicyID(s)
+	if err != nil {
+		return nil, forge.BadRequest(fmt.Sprintf("invalid base policy ID: %v", err))
+	}
+	return &polID, nil
+}
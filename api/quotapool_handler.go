@@ -0,0 +1,164 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/xraph/forge"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/quotapool"
+)
+
+func (a *API) createQuotaPool(ctx forge.Context, req *CreateQuotaPoolRequest) (*QuotaPoolResponse, error) {
+	p := &quotapool.Pool{
+		Name:          req.Name,
+		Description:   req.Description,
+		DailyLimit:    req.DailyLimit,
+		MonthlyLimit:  req.MonthlyLimit,
+		QuotaTimezone: req.QuotaTimezone,
+	}
+
+	if err := a.eng.CreateQuotaPool(ctx.Context(), p); err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toQuotaPoolResponse(p)
+	return resp, ctx.JSON(http.StatusCreated, resp)
+}
+
+func (a *API) listQuotaPools(ctx forge.Context, req *ListQuotaPoolsRequest) (*struct{}, error) {
+	pools, err := a.eng.ListQuotaPools(ctx.Context(), &quotapool.ListFilter{
+		AppID:  req.AppID,
+		Limit:  a.pageLimit(ctx, req.Limit, keysmith.DefaultPageSize),
+		Offset: req.Offset,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list quota pools: %w", err)
+	}
+
+	resp := make([]*QuotaPoolResponse, len(pools))
+	for i, p := range pools {
+		resp[i] = toQuotaPoolResponse(p)
+	}
+	return nil, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) getQuotaPool(ctx forge.Context, _ *GetQuotaPoolRequest) (*QuotaPoolResponse, error) {
+	poolID, err := id.ParseQuotaPoolID(ctx.Param("poolId"))
+	if err != nil {
+		return nil, forge.BadRequest(fmt.Sprintf("invalid quota pool ID: %v", err))
+	}
+
+	p, err := a.eng.GetQuotaPool(ctx.Context(), poolID)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toQuotaPoolResponse(p)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) updateQuotaPool(ctx forge.Context, req *UpdateQuotaPoolRequest) (*QuotaPoolResponse, error) {
+	poolID, err := id.ParseQuotaPoolID(ctx.Param("poolId"))
+	if err != nil {
+		return nil, forge.BadRequest(fmt.Sprintf("invalid quota pool ID: %v", err))
+	}
+
+	p, err := a.eng.GetQuotaPool(ctx.Context(), poolID)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+	if req.Name != nil {
+		p.Name = *req.Name
+	}
+	if req.Description != nil {
+		p.Description = *req.Description
+	}
+	if req.DailyLimit != nil {
+		p.DailyLimit = *req.DailyLimit
+	}
+	if req.MonthlyLimit != nil {
+		p.MonthlyLimit = *req.MonthlyLimit
+	}
+	if req.QuotaTimezone != nil {
+		p.QuotaTimezone = *req.QuotaTimezone
+	}
+	if err := a.eng.UpdateQuotaPool(ctx.Context(), p); err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toQuotaPoolResponse(p)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) deleteQuotaPool(ctx forge.Context, _ *DeleteQuotaPoolRequest) (*struct{}, error) {
+	poolID, err := id.ParseQuotaPoolID(ctx.Param("poolId"))
+	if err != nil {
+		return nil, forge.BadRequest(fmt.Sprintf("invalid quota pool ID: %v", err))
+	}
+
+	if err := a.eng.DeleteQuotaPool(ctx.Context(), poolID); err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	return nil, ctx.NoContent(http.StatusNoContent)
+}
+
+func (a *API) addQuotaPoolKeys(ctx forge.Context, req *AddQuotaPoolKeysRequest) (*struct{}, error) {
+	poolID, keyIDs, err := parseQuotaPoolKeys(ctx, req.KeyIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.eng.AddKeysToQuotaPool(ctx.Context(), poolID, keyIDs); err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	return nil, ctx.NoContent(http.StatusNoContent)
+}
+
+func (a *API) removeQuotaPoolKeys(ctx forge.Context, req *RemoveQuotaPoolKeysRequest) (*struct{}, error) {
+	poolID, keyIDs, err := parseQuotaPoolKeys(ctx, req.KeyIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.eng.RemoveKeysFromQuotaPool(ctx.Context(), poolID, keyIDs); err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	return nil, ctx.NoContent(http.StatusNoContent)
+}
+
+// parseQuotaPoolKeys parses the poolId path parameter and a membership
+// request's key IDs.
+func parseQuotaPoolKeys(ctx forge.Context, raw []string) (id.QuotaPoolID, []id.KeyID, error) {
+	poolID, err := id.ParseQuotaPoolID(ctx.Param("poolId"))
+	if err != nil {
+		return id.QuotaPoolID{}, nil, forge.BadRequest(fmt.Sprintf("invalid quota pool ID: %v", err))
+	}
+	keyIDs := make([]id.KeyID, len(raw))
+	for i, s := range raw {
+		if keyIDs[i], err = id.ParseKeyID(s); err != nil {
+			return id.QuotaPoolID{}, nil, forge.BadRequest(fmt.Sprintf("invalid key ID %q: %v", s, err))
+		}
+	}
+	return poolID, keyIDs, nil
+}
+
+func (a *API) getQuotaPoolUsage(ctx forge.Context, _ *GetQuotaPoolUsageRequest) (*QuotaPoolUsageResponse, error) {
+	poolID, err := id.ParseQuotaPoolID(ctx.Param("poolId"))
+	if err != nil {
+		return nil, forge.BadRequest(fmt.Sprintf("invalid quota pool ID: %v", err))
+	}
+
+	c, err := a.eng.QuotaPoolUsage(ctx.Context(), poolID)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toQuotaPoolUsageResponse(c)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
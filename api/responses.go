@@ -4,126 +4,24 @@ import (
 	"time"
 
 	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/capture"
+	"github.com/xraph/keysmith/group"
+	"github.com/xraph/keysmith/id"
 	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keyevent"
 	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/quotapool"
+	"github.com/xraph/keysmith/revocation"
 	"github.com/xraph/keysmith/rotation"
 	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/slo"
+	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/tenant"
+	"github.com/xraph/keysmith/tombstone"
+	"github.com/xraph/keysmith/transfer"
 	"github.com/xraph/keysmith/usage"
 )
 
-// KeyResponse is the API representation of a key (raw key is never included).
-type KeyResponse struct {
-	ID          string         `json:"id"`
-	TenantID    string         `json:"tenant_id"`
-	AppID       string         `json:"app_id"`
-	Name        string         `json:"name"`
-	Description string         `json:"description,omitempty"`
-	Prefix      string         `json:"prefix"`
-	Hint        string         `json:"hint"`
-	Environment string         `json:"environment"`
-	State       string         `json:"state"`
-	PolicyID    string         `json:"policy_id,omitempty"`
-	Scopes      []string       `json:"scopes,omitempty"`
-	Metadata    map[string]any `json:"metadata,omitempty"`
-	CreatedBy   string         `json:"created_by,omitempty"`
-	ExpiresAt   *time.Time     `json:"expires_at,omitempty"`
-	LastUsedAt  *time.Time     `json:"last_used_at,omitempty"`
-	RotatedAt   *time.Time     `json:"rotated_at,omitempty"`
-	RevokedAt   *time.Time     `json:"revoked_at,omitempty"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-}
-
-// KeyCreateResponse includes the raw key (shown only once at creation).
-type KeyCreateResponse struct {
-	Key    *KeyResponse `json:"key"`
-	RawKey string       `json:"raw_key"`
-}
-
-// PolicyResponse is the API representation of a policy.
-type PolicyResponse struct {
-	ID              string         `json:"id"`
-	TenantID        string         `json:"tenant_id"`
-	AppID           string         `json:"app_id"`
-	Name            string         `json:"name"`
-	Description     string         `json:"description,omitempty"`
-	RateLimit       int            `json:"rate_limit"`
-	RateLimitWindow string         `json:"rate_limit_window"`
-	BurstLimit      int            `json:"burst_limit"`
-	AllowedScopes   []string       `json:"allowed_scopes,omitempty"`
-	AllowedIPs      []string       `json:"allowed_ips,omitempty"`
-	AllowedOrigins  []string       `json:"allowed_origins,omitempty"`
-	AllowedMethods  []string       `json:"allowed_methods,omitempty"`
-	AllowedPaths    []string       `json:"allowed_paths,omitempty"`
-	MaxKeyLifetime  string         `json:"max_key_lifetime,omitempty"`
-	RotationPeriod  string         `json:"rotation_period,omitempty"`
-	GracePeriod     string         `json:"grace_period"`
-	DailyQuota      int64          `json:"daily_quota"`
-	MonthlyQuota    int64          `json:"monthly_quota"`
-	Metadata        map[string]any `json:"metadata,omitempty"`
-	CreatedAt       time.Time      `json:"created_at"`
-	UpdatedAt       time.Time      `json:"updated_at"`
-}
-
-// ScopeResponse is the API representation of a scope.
-type ScopeResponse struct {
-	ID          string         `json:"id"`
-	TenantID    string         `json:"tenant_id"`
-	AppID       string         `json:"app_id"`
-	Name        string         `json:"name"`
-	Description string         `json:"description,omitempty"`
-	Parent      string         `json:"parent,omitempty"`
-	Metadata    map[string]any `json:"metadata,omitempty"`
-	CreatedAt   time.Time      `json:"created_at"`
-}
-
-// UsageResponse is the API representation of a usage record.
-type UsageResponse struct {
-	ID         string         `json:"id"`
-	KeyID      string         `json:"key_id"`
-	TenantID   string         `json:"tenant_id"`
-	Endpoint   string         `json:"endpoint"`
-	Method     string         `json:"method"`
-	StatusCode int            `json:"status_code"`
-	IPAddress  string         `json:"ip_address,omitempty"`
-	UserAgent  string         `json:"user_agent,omitempty"`
-	LatencyMs  int64          `json:"latency_ms"`
-	Metadata   map[string]any `json:"metadata,omitempty"`
-	CreatedAt  time.Time      `json:"created_at"`
-}
-
-// AggregationResponse is the API representation of aggregated usage.
-type AggregationResponse struct {
-	KeyID        string    `json:"key_id"`
-	TenantID     string    `json:"tenant_id"`
-	Period       string    `json:"period"`
-	PeriodStart  time.Time `json:"period_start"`
-	RequestCount int64     `json:"request_count"`
-	ErrorCount   int64     `json:"error_count"`
-	TotalLatency int64     `json:"total_latency_ms"`
-	P50Latency   int64     `json:"p50_latency_ms"`
-	P99Latency   int64     `json:"p99_latency_ms"`
-}
-
-// RotationResponse is the API representation of a rotation record.
-type RotationResponse struct {
-	ID        string    `json:"id"`
-	KeyID     string    `json:"key_id"`
-	TenantID  string    `json:"tenant_id"`
-	Reason    string    `json:"reason"`
-	GraceTTL  string    `json:"grace_ttl"`
-	GraceEnds time.Time `json:"grace_ends"`
-	RotatedBy string    `json:"rotated_by,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
-}
-
-// ValidationResponse is the API representation of a key validation result.
-type ValidationResponse struct {
-	Valid  bool         `json:"valid"`
-	Key    *KeyResponse `json:"key,omitempty"`
-	Scopes []string     `json:"scopes,omitempty"`
-}
-
 // ── Mapper functions ─────────────────────────────────
 
 func toKeyResponse(k *key.Key) *KeyResponse {
@@ -135,76 +33,118 @@ func toKeyResponse(k *key.Key) *KeyResponse {
 		Description: k.Description,
 		Prefix:      k.Prefix,
 		Hint:        k.Hint,
-		Environment: string(k.Environment),
-		State:       string(k.State),
+		Environment: KeyEnvironment(k.Environment),
+		State:       KeyState(k.State),
 		Scopes:      k.Scopes,
 		Metadata:    k.Metadata,
 		CreatedBy:   k.CreatedBy,
+		ExternalRef: k.ExternalRef,
 		ExpiresAt:   k.ExpiresAt,
 		LastUsedAt:  k.LastUsedAt,
 		RotatedAt:   k.RotatedAt,
 		RevokedAt:   k.RevokedAt,
 		CreatedAt:   k.CreatedAt,
 		UpdatedAt:   k.UpdatedAt,
+
+		AllowedOrigins: k.AllowedOrigins,
+
+		IntendedConsumer: k.IntendedConsumer,
+		EnforceConsumer:  k.EnforceConsumer,
+		CertFingerprint:  k.CertFingerprint,
+
+		Flags: flagStrings(k.Flags),
+
+		Contacts: k.Contacts,
+
+		Labels: k.Labels,
+
+		AcceptedTermsVersion: k.AcceptedTermsVersion,
+		AcceptedTermsAt:      k.AcceptedTermsAt,
+
+		DebugSampleRate: k.DebugSampleRate,
+		DebugUntil:      k.DebugUntil,
 	}
 	if k.PolicyID != nil {
 		r.PolicyID = k.PolicyID.String()
 	}
+	if k.PoolID != nil {
+		r.PoolID = k.PoolID.String()
+	}
 	return r
 }
 
 func toPolicyResponse(p *policy.Policy) *PolicyResponse {
-	return &PolicyResponse{
+	resp := &PolicyResponse{
 		ID:              p.ID.String(),
 		TenantID:        p.TenantID,
 		AppID:           p.AppID,
 		Name:            p.Name,
 		Description:     p.Description,
 		RateLimit:       p.RateLimit,
-		RateLimitWindow: p.RateLimitWindow.String(),
+		RateLimitWindow: Duration(p.RateLimitWindow),
 		BurstLimit:      p.BurstLimit,
 		AllowedScopes:   p.AllowedScopes,
 		AllowedIPs:      p.AllowedIPs,
 		AllowedOrigins:  p.AllowedOrigins,
 		AllowedMethods:  p.AllowedMethods,
 		AllowedPaths:    p.AllowedPaths,
-		MaxKeyLifetime:  p.MaxKeyLifetime.String(),
-		RotationPeriod:  p.RotationPeriod.String(),
-		GracePeriod:     p.GracePeriod.String(),
+		MaxKeyLifetime:  Duration(p.MaxKeyLifetime),
+		RotationPeriod:  Duration(p.RotationPeriod),
+		GracePeriod:     Duration(p.GracePeriod),
 		DailyQuota:      p.DailyQuota,
 		MonthlyQuota:    p.MonthlyQuota,
+		QuotaTimezone:   p.QuotaTimezone,
+		MinTLSVersion:   p.MinTLSVersion,
+		RequireMTLS:     p.RequireMTLS,
+		Inline:          p.Inline,
 		Metadata:        p.Metadata,
 		CreatedAt:       p.CreatedAt,
 		UpdatedAt:       p.UpdatedAt,
 	}
+	if p.BasePolicyID != nil {
+		resp.BasePolicyID = p.BasePolicyID.String()
+	}
+	return resp
+}
+
+func toEffectivePolicyResponse(eff *policy.Effective) *EffectivePolicyResponse {
+	chain := make([]string, len(eff.Chain))
+	for i, polID := range eff.Chain {
+		chain[i] = polID.String()
+	}
+	return &EffectivePolicyResponse{Policy: toPolicyResponse(eff.Policy), Chain: chain}
 }
 
 func toScopeResponse(s *scope.Scope) *ScopeResponse {
 	return &ScopeResponse{
-		ID:          s.ID.String(),
-		TenantID:    s.TenantID,
-		AppID:       s.AppID,
-		Name:        s.Name,
-		Description: s.Description,
-		Parent:      s.Parent,
-		Metadata:    s.Metadata,
-		CreatedAt:   s.CreatedAt,
+		ID:              s.ID.String(),
+		TenantID:        s.TenantID,
+		AppID:           s.AppID,
+		Name:            s.Name,
+		Description:     s.Description,
+		Parent:          s.Parent,
+		Metadata:        s.Metadata,
+		RateLimit:       s.RateLimit,
+		RateLimitWindow: Duration(s.RateLimitWindow),
+		CreatedAt:       s.CreatedAt,
 	}
 }
 
 func toUsageResponse(r *usage.Record) *UsageResponse {
 	return &UsageResponse{
-		ID:         r.ID.String(),
-		KeyID:      r.KeyID.String(),
-		TenantID:   r.TenantID,
-		Endpoint:   r.Endpoint,
-		Method:     r.Method,
-		StatusCode: r.StatusCode,
-		IPAddress:  r.IPAddress,
-		UserAgent:  r.UserAgent,
-		LatencyMs:  r.Latency.Milliseconds(),
-		Metadata:   r.Metadata,
-		CreatedAt:  r.CreatedAt,
+		ID:          r.ID.String(),
+		KeyID:       r.KeyID.String(),
+		TenantID:    r.TenantID,
+		AppID:       r.AppID,
+		Environment: string(r.Environment),
+		Endpoint:    r.Endpoint,
+		Method:      r.Method,
+		StatusCode:  r.StatusCode,
+		IPAddress:   r.IPAddress,
+		UserAgent:   r.UserAgent,
+		LatencyMs:   r.Latency.Milliseconds(),
+		Metadata:    r.Metadata,
+		CreatedAt:   r.CreatedAt,
 	}
 }
 
@@ -222,19 +162,217 @@ func toAggregationResponse(a *usage.Aggregation) *AggregationResponse {
 	}
 }
 
+func toEndpointActivityResponse(a *usage.EndpointActivity) *EndpointActivityResponse {
+	return &EndpointActivityResponse{
+		Endpoint:   a.Endpoint,
+		Method:     a.Method,
+		LastSeenAt: a.LastSeenAt,
+		Count:      a.Count,
+	}
+}
+
 func toRotationResponse(r *rotation.Record) *RotationResponse {
 	return &RotationResponse{
 		ID:        r.ID.String(),
 		KeyID:     r.KeyID.String(),
 		TenantID:  r.TenantID,
-		Reason:    string(r.Reason),
-		GraceTTL:  r.GraceTTL.String(),
+		AppID:     r.AppID,
+		OldHint:   r.OldHint,
+		NewHint:   r.NewHint,
+		Reason:    RotationReason(r.Reason),
+		GraceTTL:  Duration(r.GraceTTL),
 		GraceEnds: r.GraceEnds,
 		RotatedBy: r.RotatedBy,
 		CreatedAt: r.CreatedAt,
 	}
 }
 
+func toKeyOverviewResponse(o *key.Overview) *KeyOverviewResponse {
+	resp := &KeyOverviewResponse{
+		Total:           o.Total,
+		ByState:         make(map[KeyState]int64, len(o.ByState)),
+		ByEnvironment:   make(map[KeyEnvironment]int64, len(o.ByEnvironment)),
+		Expiring:        make([]*OverviewWindowResponse, len(o.Expiring)),
+		NeverUsed:       o.NeverUsed,
+		CreatedRecently: toOverviewWindowResponse(o.CreatedRecently),
+	}
+	for state, n := range o.ByState {
+		resp.ByState[KeyState(state)] = n
+	}
+	for env, n := range o.ByEnvironment {
+		resp.ByEnvironment[KeyEnvironment(env)] = n
+	}
+	for i, w := range o.Expiring {
+		resp.Expiring[i] = toOverviewWindowResponse(w)
+	}
+	return resp
+}
+
+func toOverviewWindowResponse(w key.WindowCount) *OverviewWindowResponse {
+	return &OverviewWindowResponse{WithinDays: int(w.Within / (24 * time.Hour)), Count: w.Count}
+}
+
+func toRotationSummaryResponse(after, before *time.Time, counts []*rotation.ReasonCount) *RotationSummaryResponse {
+	resp := &RotationSummaryResponse{
+		CreatedAfter:  after,
+		CreatedBefore: before,
+		Reasons:       make([]*ReasonCountResponse, len(counts)),
+	}
+	for i, c := range counts {
+		resp.Reasons[i] = &ReasonCountResponse{Reason: RotationReason(c.Reason), Count: c.Count}
+		resp.Total += c.Count
+	}
+	return resp
+}
+
+func toLineageResponse(keyID string, eras []*keysmith.HashEra, act *keysmith.HashActivity) *LineageResponse {
+	resp := &LineageResponse{KeyID: keyID, Eras: make([]*HashEraResponse, len(eras))}
+	for i, era := range eras {
+		resp.Eras[i] = &HashEraResponse{
+			Index:         era.Index,
+			Start:         era.Start,
+			End:           era.End,
+			GraceEnds:     era.GraceEnds,
+			Hint:          era.Hint,
+			GraceOverlaps: era.GraceOverlaps,
+		}
+		if era.Rotation != nil {
+			resp.Eras[i].Rotation = toRotationResponse(era.Rotation)
+		}
+	}
+	if act != nil {
+		resp.ActiveAt = &HashActivityResponse{At: act.At, Current: act.Current.Index}
+		for _, era := range act.Grace {
+			resp.ActiveAt.Grace = append(resp.ActiveAt.Grace, era.Index)
+		}
+	}
+	return resp
+}
+
+func toFailurePatternResponse(p *key.FailurePattern) *FailurePatternResponse {
+	return &FailurePatternResponse{
+		Fingerprint: p.Fingerprint,
+		Count:       p.Count,
+		Window:      Duration(p.Window),
+		FirstSeen:   p.FirstSeen,
+		LastSeen:    p.LastSeen,
+	}
+}
+
+func toKeySearchMatchResponse(m *key.SearchMatch) *KeySearchMatchResponse {
+	return &KeySearchMatchResponse{Key: toKeyResponse(m.Key), Fields: m.Fields, Snippet: m.Snippet, Score: m.Score}
+}
+
+func toKeyNameDuplicateResponse(d *keysmith.KeyNameDuplicate) *KeyNameDuplicateResponse {
+	ids := make([]string, len(d.KeyIDs))
+	for i, keyID := range d.KeyIDs {
+		ids[i] = keyID.String()
+	}
+	return &KeyNameDuplicateResponse{TenantID: d.TenantID, Name: d.Name, KeyIDs: ids}
+}
+
+func toKeyTransferResponse(t *transfer.Transfer) *KeyTransferResponse {
+	return &KeyTransferResponse{
+		ID:             t.ID.String(),
+		KeyID:          t.KeyID.String(),
+		AppID:          t.AppID,
+		SourceTenantID: t.SourceTenantID,
+		TargetTenantID: t.TargetTenantID,
+		State:          string(t.State),
+		RetagUsage:     t.RetagUsage,
+		InitiatedBy:    t.InitiatedBy,
+		ResolvedBy:     t.ResolvedBy,
+		UnmappedScopes: t.UnmappedScopes,
+		UnmappedPolicy: t.UnmappedPolicy,
+		ExpiresAt:      t.ExpiresAt,
+		CreatedAt:      t.CreatedAt,
+		ResolvedAt:     t.ResolvedAt,
+	}
+}
+
+func toLiveKeyStatsResponse(st *keysmith.LiveKeyStats) *LiveKeyStatsResponse {
+	resp := &LiveKeyStatsResponse{
+		KeyID:         st.KeyID.String(),
+		TenantID:      st.TenantID,
+		AppID:         st.AppID,
+		QPS:           st.QPS,
+		Rate1m:        st.Rate1m,
+		Rate5m:        st.Rate5m,
+		FailureRatio:  st.FailureRatio,
+		Validations5m: st.Validations5m,
+	}
+	if !st.TrackedSince.IsZero() {
+		resp.TrackedSince = &st.TrackedSince
+		resp.LastSeen = &st.LastSeen
+	}
+	return resp
+}
+
+func toQuotaForecastResponse(keyID string, f *key.QuotaForecast) *QuotaForecastResponse {
+	resp := &QuotaForecastResponse{
+		KeyID:               keyID,
+		Month:               f.Month,
+		AsOf:                f.AsOf,
+		Quota:               f.Quota,
+		Used:                f.Used,
+		BurnRate:            f.BurnRate,
+		ProjectedTotal:      f.ProjectedTotal,
+		ProjectedExhaustion: f.ProjectedExhaustion,
+	}
+	if f.PoolID != nil {
+		resp.PoolID = f.PoolID.String()
+	}
+	return resp
+}
+
+func toQuotaForecastSummary(f *key.QuotaForecast) *QuotaForecastSummary {
+	return &QuotaForecastSummary{
+		Used:                f.Used,
+		Quota:               f.Quota,
+		ProjectedTotal:      f.ProjectedTotal,
+		ProjectedExhaustion: f.ProjectedExhaustion,
+	}
+}
+
+func toReplayStatsResponse(st *keysmith.ReplayStats) *ReplayStatsResponse {
+	if st == nil {
+		return &ReplayStatsResponse{}
+	}
+	return &ReplayStatsResponse{
+		Enabled:   true,
+		Checked:   st.Checked,
+		Replays:   st.Replays,
+		Stale:     st.Stale,
+		HitRate:   st.HitRate(),
+		Size:      st.Size,
+		Evictions: st.Evictions,
+	}
+}
+
+func toSLOResponse(statuses []slo.Status) *SLOResponse {
+	resp := &SLOResponse{Objectives: make([]SLOStatusResponse, len(statuses))}
+	for i, st := range statuses {
+		obj := st.Objective
+		r := SLOStatusResponse{
+			Name:            obj.Name,
+			Target:          obj.Target,
+			Window:          Duration(obj.Window),
+			Good:            st.Good,
+			Bad:             st.Bad,
+			BudgetRemaining: st.BudgetRemaining,
+			Burn:            make([]SLOBurnResponse, len(st.Burn)),
+		}
+		if obj.LatencyThreshold > 0 {
+			r.LatencyThreshold = Duration(obj.LatencyThreshold)
+		}
+		for j, b := range st.Burn {
+			r.Burn[j] = SLOBurnResponse{Window: Duration(b.Window), Good: b.Good, Bad: b.Bad, Rate: b.Rate}
+		}
+		resp.Objectives[i] = r
+	}
+	return resp
+}
+
 func toValidationResponse(v *keysmith.ValidationResult) *ValidationResponse {
 	resp := &ValidationResponse{
 		Valid: v.Key != nil,
@@ -243,5 +381,422 @@ func toValidationResponse(v *keysmith.ValidationResult) *ValidationResponse {
 		resp.Key = toKeyResponse(v.Key)
 	}
 	resp.Scopes = v.Scopes
+	resp.ConsumerMismatch = v.ConsumerMismatch
+	resp.OutdatedTerms = v.OutdatedTerms
+	resp.AppliedFlags = flagStrings(v.AppliedFlags)
+	resp.UsingRotatedKey = v.UsingRotatedKey
+	if due := v.RotationDue; due != nil {
+		resp.RotationDue = &RotationDueResponse{DueAt: due.DueAt, DaysRemaining: due.DaysRemaining, Overdue: due.Overdue}
+	}
+	if d := v.KeyDeprecation; d != nil {
+		resp.KeyDeprecation = &KeyDeprecationResponse{Format: d.Format, Since: d.Since, Sunset: d.Sunset, Link: d.Link}
+	}
+	if rl := v.RateLimit; rl != nil {
+		resp.RateLimit = &RateLimitResponse{
+			Limit:           rl.Limit,
+			Remaining:       rl.Remaining,
+			TenantLimit:     rl.TenantLimit,
+			TenantRemaining: rl.TenantRemaining,
+		}
+		if rl.Window > 0 {
+			resp.RateLimit.Window = Duration(rl.Window)
+		}
+		if rl.TenantWindow > 0 {
+			resp.RateLimit.TenantWindow = Duration(rl.TenantWindow)
+		}
+		if p := rl.Penalty; p != nil {
+			resp.RateLimit.BaseLimit = p.BaseLimit
+			resp.RateLimit.ReducedUntil = &p.Until
+		}
+	}
+	if q := v.Quota; q != nil {
+		resp.Quota = &QuotaResponse{
+			DailyLimit:       q.DailyLimit,
+			DailyRemaining:   q.DailyRemaining,
+			DailyReset:       q.DailyReset,
+			MonthlyLimit:     q.MonthlyLimit,
+			MonthlyRemaining: q.MonthlyRemaining,
+			MonthlyReset:     q.MonthlyReset,
+		}
+		if q.PoolID != nil {
+			resp.Quota.PoolID = q.PoolID.String()
+		}
+	}
+	return resp
+}
+
+func toTenantSettingsResponse(ts *tenant.Settings) *TenantSettingsResponse {
+	scopes := ts.DefaultScopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	resp := &TenantSettingsResponse{
+		TenantID:        ts.TenantID,
+		AppID:           ts.AppID,
+		DefaultScopes:   scopes,
+		MetadataSchema:  ts.MetadataSchema,
+		RateLimit:       ts.RateLimit,
+		DefaultContacts: ts.DefaultContacts,
+		IPHandling:      ts.IPHandling,
+		QuotaTimezone:   ts.QuotaTimezone,
+		UniqueKeyNames:  ts.UniqueKeyNames,
+		CreatedAt:       ts.CreatedAt,
+		UpdatedAt:       ts.UpdatedAt,
+	}
+	if ts.RateLimitWindow > 0 {
+		resp.RateLimitWindow = Duration(ts.RateLimitWindow)
+	}
+	resp.Lockdown = toTenantLockdownResponse(ts.Lockdown)
+	return resp
+}
+
+func toKeyContactsResponse(keyID string, kc *keysmith.KeyContacts) *KeyContactsResponse {
+	orEmpty := func(cs key.Contacts) key.Contacts {
+		if cs == nil {
+			return key.Contacts{}
+		}
+		return cs
+	}
+	return &KeyContactsResponse{
+		KeyID:          keyID,
+		Contacts:       orEmpty(kc.Key),
+		TenantDefaults: orEmpty(kc.TenantDefaults),
+		Effective:      orEmpty(kc.Effective),
+	}
+}
+
+func toKeyEventResponse(e *keyevent.Event) *KeyEventResponse {
+	return &KeyEventResponse{
+		EventID:    e.ID(),
+		Type:       string(e.Type),
+		KeyID:      e.KeyID.String(),
+		TenantID:   e.TenantID,
+		AppID:      e.AppID,
+		State:      KeyState(e.State),
+		Reason:     e.Reason,
+		ReasonCode: e.ReasonCode,
+		ActorID:    e.ActorID,
+		At:         e.At,
+	}
+}
+
+func toRevocationFeedResponse(p *keysmith.RevocationPage) *RevocationFeedResponse {
+	entries := make([]*RevocationEntryResponse, len(p.Entries))
+	for i, e := range p.Entries {
+		entries[i] = &RevocationEntryResponse{
+			KeyID:      e.KeyID.String(),
+			TenantID:   e.TenantID,
+			HashPrefix: e.HashPrefix,
+			State:      KeyState(e.State),
+			Revoked:    e.Revoked,
+			At:         e.At,
+		}
+	}
+	return &RevocationFeedResponse{
+		Entries: entries,
+		Next:    p.Next,
+		HasMore: p.HasMore,
+	}
+}
+
+func toHashTombstonesResponse(tbs []*tombstone.Tombstone, counts []*tombstone.ReasonCount) *HashTombstonesResponse {
+	resp := &HashTombstonesResponse{
+		Tombstones: make([]*HashTombstoneResponse, len(tbs)),
+		Reasons:    make([]*TombstoneReasonCountResponse, len(counts)),
+	}
+	for i, t := range tbs {
+		resp.Tombstones[i] = &HashTombstoneResponse{
+			HashPrefix: revocation.HashPrefix(t.Hash),
+			KeyID:      t.KeyID.String(),
+			TenantID:   t.TenantID,
+			AppID:      t.AppID,
+			Reason:     t.Reason,
+			CreatedAt:  t.CreatedAt,
+		}
+	}
+	for i, c := range counts {
+		resp.Reasons[i] = &TombstoneReasonCountResponse{Reason: c.Reason, Count: c.Count}
+		resp.Total += c.Count
+	}
+	return resp
+}
+
+func toCaptureResponse(c *capture.Capture) *CaptureResponse {
+	return &CaptureResponse{
+		ID:            c.ID.String(),
+		KeyID:         c.KeyID.String(),
+		Method:        c.Method,
+		Path:          c.Path,
+		Headers:       c.Headers,
+		Body:          c.Body,
+		BodyTruncated: c.BodyTruncated,
+		CapturedAt:    c.CapturedAt,
+	}
+}
+
+func toHashReportsResponse(reports []keysmith.HashReport) *HashReportsResponse {
+	resp := &HashReportsResponse{Reports: make([]HashReportResponse, len(reports))}
+	for i, r := range reports {
+		resp.Reports[i] = HashReportResponse{Hash: r.Hash, Status: string(r.Status), State: KeyState(r.State)}
+		if !r.KeyID.IsNil() {
+			resp.Reports[i].KeyID = r.KeyID.String()
+		}
+		switch r.Status {
+		case keysmith.HashNotFound:
+			resp.NotFound++
+		case keysmith.HashFound:
+			resp.Found++
+		case keysmith.HashRevoked:
+			resp.Revoked++
+		case keysmith.HashAlreadyRevoked:
+			resp.AlreadyRevoked++
+		}
+	}
 	return resp
 }
+
+func toMaintenanceResponse(r *store.MaintenanceReport) *MaintenanceResponse {
+	tables := make([]TableStatsResponse, len(r.Tables))
+	for i, t := range r.Tables {
+		tables[i] = TableStatsResponse{
+			Name:      t.Name,
+			Rows:      t.Rows,
+			DeadRows:  t.DeadRows,
+			SizeBytes: t.SizeBytes,
+		}
+	}
+	return &MaintenanceResponse{
+		Backend:   r.Backend,
+		Actions:   r.Actions,
+		Tables:    tables,
+		Advice:    r.Advice,
+		StartedAt: r.StartedAt,
+		Duration:  Duration(r.Duration),
+	}
+}
+
+func toStorageReportResponse(r *store.StorageReport) *StorageReportResponse {
+	tenants := make([]TenantStorageResponse, len(r.Tenants))
+	for i, ts := range r.Tenants {
+		tenants[i] = toTenantStorageResponse(ts)
+	}
+	top := make([]TenantUsageResponse, len(r.TopUsage))
+	for i, u := range r.TopUsage {
+		top[i] = TenantUsageResponse{TenantID: u.TenantID, UsageRows: u.UsageRows, UsageBytes: u.UsageBytes}
+	}
+	return &StorageReportResponse{
+		Tenants:     tenants,
+		Total:       toTenantStorageResponse(&r.Total),
+		TopUsage:    top,
+		GeneratedAt: r.GeneratedAt,
+		Duration:    Duration(r.Duration),
+	}
+}
+
+func toTenantStorageResponse(ts *store.TenantStorage) TenantStorageResponse {
+	states := make(map[string]int64, len(ts.KeysByState))
+	for state, n := range ts.KeysByState {
+		states[string(state)] = n
+	}
+	return TenantStorageResponse{
+		TenantID:    ts.TenantID,
+		Keys:        ts.Keys,
+		KeysByState: states,
+		Policies:    ts.Policies,
+		Scopes:      ts.Scopes,
+		Rotations:   ts.Rotations,
+		UsageRows:   ts.UsageRows,
+		UsageBytes:  ts.UsageBytes,
+	}
+}
+
+func toRuntimeConfigResponse(c keysmith.RuntimeConfig) *RuntimeConfigResponse {
+	return &RuntimeConfigResponse{
+		ValidationCacheTTL:    Duration(c.ValidationCacheTTL),
+		EndpointFlushInterval: Duration(c.EndpointFlushInterval),
+		CapturePurgeInterval:  Duration(c.CapturePurgeInterval),
+		MaintenanceInterval:   Duration(c.MaintenanceInterval),
+		QuotaForecastInterval: Duration(c.QuotaForecastInterval),
+		FailureThreshold:      c.FailureThreshold,
+		FailureWindow:         Duration(c.FailureWindow),
+		UsageSampleRate:       c.UsageSampleRate,
+	}
+}
+
+func toUsageRecordingResponse(p usage.RecordingPolicy) *UsageRecordingResponse {
+	rules := p.Rules
+	if rules == nil {
+		rules = []usage.RecordingRule{}
+	}
+	mode := p.Default
+	if mode == "" {
+		mode = usage.RecordFull
+	}
+	return &UsageRecordingResponse{
+		Rules:      rules,
+		Default:    string(mode),
+		SampleRate: p.SampleRate,
+	}
+}
+
+func toLabelRevokeResponse(sel key.Selector, res *keysmith.BulkRevokeResult) *LabelRevokeResponse {
+	keyIDs := make([]string, len(res.KeyIDs))
+	for i, keyID := range res.KeyIDs {
+		keyIDs[i] = keyID.String()
+	}
+	return &LabelRevokeResponse{
+		LabelSelector:  sel.String(),
+		KeyIDs:         keyIDs,
+		AlreadyRevoked: res.AlreadyRevoked,
+		Failed:         res.Failed,
+		DryRun:         res.DryRun,
+	}
+}
+
+func toCreatorRevokeResponse(creator string, res *keysmith.CreatorRevokeResult) *CreatorRevokeResponse {
+	keyIDs := make([]string, len(res.KeyIDs))
+	for i, keyID := range res.KeyIDs {
+		keyIDs[i] = keyID.String()
+	}
+	return &CreatorRevokeResponse{
+		CreatedBy:      creator,
+		KeyIDs:         keyIDs,
+		AlreadyRevoked: res.AlreadyRevoked,
+		Failed:         res.Failed,
+		DryRun:         res.DryRun,
+	}
+}
+
+func toTenantLockdownResponse(l *tenant.Lockdown) *TenantLockdownResponse {
+	if l == nil {
+		return nil
+	}
+	resp := &TenantLockdownResponse{
+		Phase:     string(l.Phase),
+		Reason:    l.Reason,
+		ActorID:   l.ActorID,
+		LockedAt:  l.LockedAt,
+		Suspended: l.Suspended,
+	}
+	if r := l.Recovery; r != nil {
+		resp.Recovery = &TenantRecoveryResponse{
+			StartedAt:     r.StartedAt,
+			BatchSize:     r.BatchSize,
+			BatchInterval: Duration(r.BatchInterval),
+			NextBatchAt:   r.NextBatchAt,
+			Batches:       r.Batches,
+			Reactivated:   r.Reactivated,
+			Remaining:     r.Remaining,
+			HaltedAt:      r.HaltedAt,
+			HaltReason:    r.HaltReason,
+			CompletedAt:   r.CompletedAt,
+		}
+	}
+	return resp
+}
+
+func toTenantLockdownStatusResponse(st *keysmith.LockdownStatus) *TenantLockdownStatusResponse {
+	return &TenantLockdownStatusResponse{
+		TenantID: st.TenantID,
+		Locked:   st.Locked,
+		Lockdown: toTenantLockdownResponse(st.Lockdown),
+		Pending:  st.Pending,
+	}
+}
+
+func toTenantLockdownResultResponse(res *keysmith.LockdownResult) *TenantLockdownResultResponse {
+	keyIDs := make([]string, len(res.KeyIDs))
+	for i, keyID := range res.KeyIDs {
+		keyIDs[i] = keyID.String()
+	}
+	return &TenantLockdownResultResponse{
+		Lockdown: toTenantLockdownResponse(res.Lockdown),
+		KeyIDs:   keyIDs,
+		Failed:   res.Failed,
+		DryRun:   res.DryRun,
+	}
+}
+
+func toGroupResponse(g *group.Group) *GroupResponse {
+	return &GroupResponse{
+		ID:          g.ID.String(),
+		TenantID:    g.TenantID,
+		AppID:       g.AppID,
+		Name:        g.Name,
+		Description: g.Description,
+		CreatedAt:   g.CreatedAt,
+		UpdatedAt:   g.UpdatedAt,
+	}
+}
+
+func toQuotaPoolResponse(p *quotapool.Pool) *QuotaPoolResponse {
+	return &QuotaPoolResponse{
+		ID:            p.ID.String(),
+		TenantID:      p.TenantID,
+		AppID:         p.AppID,
+		Name:          p.Name,
+		Description:   p.Description,
+		DailyLimit:    p.DailyLimit,
+		MonthlyLimit:  p.MonthlyLimit,
+		QuotaTimezone: p.QuotaTimezone,
+		CreatedAt:     p.CreatedAt,
+		UpdatedAt:     p.UpdatedAt,
+	}
+}
+
+func toQuotaPoolUsageResponse(c *quotapool.Consumption) *QuotaPoolUsageResponse {
+	return &QuotaPoolUsageResponse{
+		PoolID: c.PoolID.String(),
+		AsOf:   c.AsOf,
+		Day:    toQuotaWindowUsageResponse(c.Day),
+		Month:  toQuotaWindowUsageResponse(c.Month),
+	}
+}
+
+func toQuotaWindowUsageResponse(w quotapool.WindowUsage) QuotaWindowUsageResponse {
+	members := make([]QuotaMemberUsageResponse, len(w.Members))
+	for i, m := range w.Members {
+		members[i] = QuotaMemberUsageResponse{KeyID: m.KeyID.String(), Used: m.Used}
+	}
+	return QuotaWindowUsageResponse{
+		Period:    string(w.Period),
+		Start:     w.Start,
+		End:       w.End,
+		Limit:     w.Limit,
+		Used:      w.Used,
+		Remaining: w.Remaining,
+		Members:   members,
+	}
+}
+
+func toGroupOperationResponse(res *keysmith.GroupResult) *GroupOperationResponse {
+	keys := make([]GroupKeyResultEntry, len(res.Keys))
+	for i, r := range res.Keys {
+		keys[i] = GroupKeyResultEntry{
+			KeyID:  r.KeyID.String(),
+			Status: string(r.Status),
+			RawKey: r.RawKey,
+			Error:  r.Error,
+		}
+		if r.Rotation != nil {
+			keys[i].Rotation = toRotationResponse(r.Rotation)
+		}
+	}
+	return &GroupOperationResponse{
+		GroupID: res.GroupID.String(),
+		Keys:    keys,
+		DryRun:  res.DryRun,
+	}
+}
+
+// groupIDStrings returns the string form of a key's group IDs.
+func groupIDStrings(ids []id.GroupID) []string {
+	if len(ids) == 0 {
+		return nil
+	}
+	out := make([]string, len(ids))
+	for i, gid := range ids {
+		out[i] = gid.String()
+	}
+	return out
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/revocation"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/slo"
+	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/tenant"
+)
+
+func TestResponses_FieldFormats(t *testing.T) {
+	slos := toSLOResponse([]slo.Status{{
+		Objective: slo.Objective{Name: "latency", LatencyThreshold: 20 * time.Millisecond, Window: 24 * time.Hour},
+		Burn:      []slo.Burn{{Window: 5 * time.Minute}},
+	}})
+	tests := []struct {
+		name  string
+		resp  any
+		field string // path of dot-separated object keys and array indexes
+		want  any
+	}{
+		{"key environment", toKeyResponse(&key.Key{ID: id.NewKeyID(), Environment: key.EnvLive}), "environment", "live"},
+		{"key state", toKeyResponse(&key.Key{ID: id.NewKeyID(), State: key.StateSuspended}), "state", "suspended"},
+		{"policy rate limit window", toPolicyResponse(&policy.Policy{ID: id.NewPolicyID(), RateLimitWindow: time.Minute}), "rate_limit_window", "PT1M"},
+		{"policy max key lifetime", toPolicyResponse(&policy.Policy{ID: id.NewPolicyID(), MaxKeyLifetime: 90 * 24 * time.Hour}), "max_key_lifetime", "P90D"},
+		{"policy rotation period", toPolicyResponse(&policy.Policy{ID: id.NewPolicyID(), RotationPeriod: 30 * 24 * time.Hour}), "rotation_period", "P30D"},
+		{"policy grace period", toPolicyResponse(&policy.Policy{ID: id.NewPolicyID()}), "grace_period", "PT0S"},
+		{"rotation reason", toRotationResponse(&rotation.Record{Reason: rotation.ReasonCompromise}), "reason", "compromise"},
+		{"legacy rotation reason", toRotationResponse(&rotation.Record{Reason: "legacy"}), "reason", "legacy"},
+		{"rotation grace ttl", toRotationResponse(&rotation.Record{GraceTTL: 36 * time.Hour}), "grace_ttl", "P1DT12H"},
+		{"rate limit window", toValidationResponse(&keysmith.ValidationResult{RateLimit: &keysmith.RateLimitInfo{Window: time.Minute}}), "rate_limit.window", "PT1M"},
+		{"tenant rate limit window", toValidationResponse(&keysmith.ValidationResult{RateLimit: &keysmith.RateLimitInfo{TenantWindow: time.Hour}}), "rate_limit.tenant_window", "PT1H"},
+		{"failure window", toFailurePatternResponse(&key.FailurePattern{Window: 5 * time.Minute}), "window", "PT5M"},
+		{"slo latency threshold", slos, "objectives.0.latency_threshold", "PT0.02S"},
+		{"slo window", slos, "objectives.0.window", "P1D"},
+		{"slo burn window", slos, "objectives.0.burn.0.window", "PT5M"},
+		{"tenant rate limit window setting", toTenantSettingsResponse(&tenant.Settings{RateLimitWindow: time.Minute}), "rate_limit_window", "PT1M"},
+		{"maintenance duration", toMaintenanceResponse(&store.MaintenanceReport{Duration: 4200 * time.Millisecond}), "duration", "PT4.2S"},
+		{"runtime cache ttl", toRuntimeConfigResponse(keysmith.RuntimeConfig{ValidationCacheTTL: 30 * time.Second}), "validation_cache_ttl", "PT30S"},
+		{"runtime feature off", toRuntimeConfigResponse(keysmith.RuntimeConfig{}), "maintenance_interval", "PT0S"},
+		{"runtime failure window", toRuntimeConfigResponse(keysmith.RuntimeConfig{FailureWindow: 5 * time.Minute}), "failure_window", "PT5M"},
+		{"overview states", toKeyOverviewResponse(&key.Overview{ByState: map[key.State]int64{key.StateActive: 3}}), "by_state.active", float64(3)},
+		{"revocation state", toRevocationFeedResponse(&keysmith.RevocationPage{Entries: []*revocation.Entry{{State: key.StateRevoked}}}), "entries.0.state", "revoked"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(tt.resp)
+			require.NoError(t, err)
+			var v any
+			require.NoError(t, json.Unmarshal(b, &v))
+			assert.Equal(t, tt.want, lookupJSON(t, v, tt.field))
+		})
+	}
+}
+
+// lookupJSON follows a dot-separated path of object keys and array
+// indexes through decoded JSON.
+func lookupJSON(t *testing.T, v any, path string) any {
+	t.Helper()
+	for _, part := range splitPath(path) {
+		switch node := v.(type) {
+		case map[string]any:
+			require.Contains(t, node, part, path)
+			v = node[part]
+		case []any:
+			i := 0
+			for _, c := range part {
+				i = i*10 + int(c-'0')
+			}
+			require.Less(t, i, len(node), path)
+			v = node[i]
+		default:
+			t.Fatalf("%s: %q is not an object or array", path, part)
+		}
+	}
+	return v
+}
+
+func splitPath(path string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i <= len(path); i++ {
+		if i == len(path) || path[i] == '.' {
+			parts = append(parts, path[start:i])
+			start = i + 1
+		}
+	}
+	return parts
+}
+
+func TestOpenAPI_DurationsAndEnums(t *testing.T) {
+	spec := newExampleRouter(t).OpenAPISpec()
+	require.NotNil(t, spec)
+
+	for name, values := range map[string][]any{
+		"KeyState":       {"active", "rotated", "expired", "revoked", "suspended"},
+		"KeyEnvironment": {"live", "test", "staging"},
+		"RotationReason": {"scheduled", "manual", "compromise", "policy", "admin", "automated_anomaly", "import"},
+	} {
+		require.Contains(t, spec.Components.Schemas, name)
+		assert.Equal(t, values, spec.Components.Schemas[name].Enum, name)
+	}
+
+	body := spec.Paths["/v1/policies"].Post.RequestBody.Content["application/json"].Schema
+	for _, field := range []string{"rate_limit_window", "max_key_lifetime", "rotation_period", "grace_period"} {
+		require.Contains(t, body.Properties, field)
+		assert.Equal(t, "string", body.Properties[field].Type, field)
+		assert.Equal(t, "duration", body.Properties[field].Format, field)
+	}
+
+	// forge inlines request fields without their enum values, so the
+	// descriptions list them.
+	create := spec.Paths["/v1/keys"].Post.RequestBody.Content["application/json"].Schema
+	assert.Equal(t, "string", create.Properties["environment"].Type)
+	assert.Contains(t, create.Properties["environment"].Description, "live, test, staging")
+
+	policy := spec.Components.Schemas["PolicyResponse"]
+	require.NotNil(t, policy)
+	assert.Equal(t, "duration", policy.Properties["grace_period"].Format)
+	key := spec.Components.Schemas["KeyResponse"]
+	require.NotNil(t, key)
+	assert.Equal(t, "#/components/schemas/KeyState", key.Properties["state"].Ref)
+}
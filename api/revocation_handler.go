@@ -0,0 +1,65 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/xraph/forge"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/revocation"
+)
+
+func (a *API) listRevocations(ctx forge.Context, req *ListRevocationsRequest) (*RevocationFeedResponse, error) {
+	var after revocation.Cursor
+	switch {
+	case req.Cursor != "":
+		c, err := revocation.ParseCursor(req.Cursor)
+		if err != nil {
+			return nil, forge.BadRequest(err.Error())
+		}
+		after = c
+	case req.Since != "":
+		since, err := time.Parse(time.RFC3339, req.Since)
+		if err != nil {
+			return nil, forge.BadRequest(fmt.Sprintf("invalid since: %v", err))
+		}
+		after = revocation.Cursor{At: since.UTC()}
+	}
+
+	page, err := a.eng.RevocationsSince(ctx.Context(), after, req.Limit)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	etag := `"` + page.Next + `"`
+	ctx.SetHeader("ETag", etag)
+	if n := len(page.Entries); n > 0 {
+		ctx.SetHeader("Last-Modified", page.Entries[n-1].At.Format(http.TimeFormat))
+	}
+	if notModified(ctx, etag, page) {
+		return nil, ctx.NoContent(http.StatusNotModified)
+	}
+
+	resp := toRevocationFeedResponse(page)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+// notModified evaluates the conditional request headers. If-None-Match takes
+// precedence over If-Modified-Since, as in RFC 9110.
+func notModified(ctx forge.Context, etag string, page *keysmith.RevocationPage) bool {
+	if inm := ctx.Header("If-None-Match"); inm != "" {
+		return inm == etag || inm == "*"
+	}
+	ims, err := http.ParseTime(ctx.Header("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	for _, e := range page.Entries {
+		if e.At.After(ims) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,168 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/api"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/revocationfeed"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+// The revocationfeed client is tested here, against the real handlers, so
+// that the root module needs no dependency on this one.
+
+// feedServer serves the keysmith API for eng and counts responses by status.
+type feedServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+}
+
+func newFeedServer(t *testing.T, eng *keysmith.Engine) *feedServer {
+	t.Helper()
+	fs := &feedServer{}
+	h := api.New(eng, nil).Handler()
+	fs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(rec, r)
+		fs.mu.Lock()
+		fs.statuses = append(fs.statuses, rec.status)
+		fs.mu.Unlock()
+	}))
+	t.Cleanup(fs.Close)
+	return fs
+}
+
+func (fs *feedServer) lastStatus() int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.statuses[len(fs.statuses)-1]
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func newEngine(t *testing.T) *keysmith.Engine {
+	t.Helper()
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()))
+	require.NoError(t, err)
+	return eng
+}
+
+func createKey(t *testing.T, eng *keysmith.Engine) *key.Key {
+	t.Helper()
+	result, err := eng.CreateKey(context.Background(), &keysmith.CreateKeyInput{
+		Name:        "Partner Key",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+		TenantID:    "tenant_test",
+	})
+	require.NoError(t, err)
+	return result.Key
+}
+
+func TestClient_IncrementalPolling(t *testing.T) {
+	eng := newEngine(t)
+	srv := newFeedServer(t, eng)
+	a, b := createKey(t, eng), createKey(t, eng)
+	feed := revocationfeed.New(srv.URL)
+
+	require.NoError(t, eng.RevokeKey(context.Background(), a.ID, "leaked"))
+	n, err := feed.Poll(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.True(t, feed.IsRevoked(a.ID.String()))
+	assert.True(t, feed.IsRevokedHash(a.KeyHash))
+	assert.False(t, feed.IsRevoked(b.ID.String()))
+
+	require.NoError(t, eng.SuspendKey(context.Background(), b.ID))
+	n, err = feed.Poll(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n, "only the new entry is fetched")
+	assert.True(t, feed.IsRevoked(a.ID.String()))
+	assert.True(t, feed.IsRevoked(b.ID.String()))
+}
+
+func TestClient_EmptyDeltaNotModified(t *testing.T) {
+	eng := newEngine(t)
+	srv := newFeedServer(t, eng)
+	a := createKey(t, eng)
+	feed := revocationfeed.New(srv.URL)
+
+	require.NoError(t, eng.RevokeKey(context.Background(), a.ID, "leaked"))
+	_, err := feed.Poll(context.Background())
+	require.NoError(t, err)
+	cursor := feed.Cursor()
+
+	n, err := feed.Poll(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	assert.Equal(t, http.StatusNotModified, srv.lastStatus())
+	assert.Equal(t, cursor, feed.Cursor())
+	assert.True(t, feed.IsRevoked(a.ID.String()))
+}
+
+func TestClient_ReactivationUnrevokes(t *testing.T) {
+	eng := newEngine(t)
+	srv := newFeedServer(t, eng)
+	a := createKey(t, eng)
+	feed := revocationfeed.New(srv.URL)
+
+	require.NoError(t, eng.SuspendKey(context.Background(), a.ID))
+	_, err := feed.Poll(context.Background())
+	require.NoError(t, err)
+	require.True(t, feed.IsRevoked(a.ID.String()))
+
+	require.NoError(t, eng.ReactivateKey(context.Background(), a.ID))
+	_, err = feed.Poll(context.Background())
+	require.NoError(t, err)
+	assert.False(t, feed.IsRevoked(a.ID.String()))
+	assert.False(t, feed.IsRevokedHash(a.KeyHash))
+}
+
+func TestClient_Pagination(t *testing.T) {
+	eng := newEngine(t)
+	srv := newFeedServer(t, eng)
+	for range keysmith.MaxRevocationPageSize + 5 {
+		k := createKey(t, eng)
+		require.NoError(t, eng.RevokeKey(context.Background(), k.ID, "cleanup"))
+	}
+
+	n, err := revocationfeed.New(srv.URL).Poll(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, keysmith.MaxRevocationPageSize+5, n)
+}
+
+func TestClient_ServerError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	_, err := revocationfeed.New(srv.URL).Poll(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+}
@@ -6,11 +6,12 @@ import (
 
 	"github.com/xraph/forge"
 
+	"github.com/xraph/keysmith"
 	"github.com/xraph/keysmith/id"
 	"github.com/xraph/keysmith/rotation"
 )
 
-func (a *API) listRotations(ctx forge.Context, req *ListRotationsRequest) ([]*RotationResponse, error) {
+func (a *API) listRotations(ctx forge.Context, req *ListRotationsRequest) (*struct{}, error) {
 	keyID, err := id.ParseKeyID(ctx.Param("keyId"))
 	if err != nil {
 		return nil, forge.BadRequest(fmt.Sprintf("invalid key ID: %v", err))
@@ -18,7 +19,7 @@ func (a *API) listRotations(ctx forge.Context, req *ListRotationsRequest) ([]*Ro
 
 	records, err := a.eng.ListRotations(ctx.Context(), &rotation.ListFilter{
 		KeyID:  &keyID,
-		Limit:  defaultLimit(req.Limit),
+		Limit:  a.pageLimit(ctx, req.Limit, keysmith.DefaultPageSize),
 		Offset: req.Offset,
 	})
 	if err != nil {
@@ -29,5 +30,91 @@ func (a *API) listRotations(ctx forge.Context, req *ListRotationsRequest) ([]*Ro
 	for i, r := range records {
 		resp[i] = toRotationResponse(r)
 	}
+	return nil, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) listTenantRotations(ctx forge.Context, req *ListTenantRotationsRequest) (*RotationListResponse, error) {
+	if err := validateEnum("reason", req.Reason); err != nil {
+		return nil, err
+	}
+	filter, err := tenantRotationFilter(req.TenantID, req.AppID, req.CreatedAfter, req.CreatedBefore)
+	if err != nil {
+		return nil, err
+	}
+	filter.Reason = rotation.Reason(req.Reason)
+	filter.Limit = a.pageLimit(ctx, req.Limit, keysmith.DefaultPageSize)
+	filter.Offset = req.Offset
+
+	records, err := a.eng.ListRotations(ctx.Context(), filter)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := &RotationListResponse{Rotations: make([]*RotationResponse, len(records)), Limit: filter.Limit}
+	for i, r := range records {
+		resp.Rotations[i] = toRotationResponse(r)
+	}
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) rotationSummary(ctx forge.Context, req *RotationSummaryRequest) (*RotationSummaryResponse, error) {
+	filter, err := tenantRotationFilter(req.TenantID, req.AppID, req.CreatedAfter, req.CreatedBefore)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := a.eng.CountRotationsByReason(ctx.Context(), filter)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toRotationSummaryResponse(filter.CreatedAfter, filter.CreatedBefore, counts)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+// tenantRotationFilter builds the filter shared by the tenant-wide rotation
+// routes. The engine narrows the tenant and app to the caller's scope.
+func tenantRotationFilter(tenantID, appID, createdAfter, createdBefore string) (*rotation.ListFilter, error) {
+	after, err := parseTimeParam("created_after", createdAfter)
+	if err != nil {
+		return nil, err
+	}
+	before, err := parseTimeParam("created_before", createdBefore)
+	if err != nil {
+		return nil, err
+	}
+	if after != nil && before != nil && !after.Before(*before) {
+		return nil, forge.BadRequest("created_after must be before created_before")
+	}
+	return &rotation.ListFilter{
+		TenantID:      tenantID,
+		AppID:         appID,
+		CreatedAfter:  after,
+		CreatedBefore: before,
+	}, nil
+}
+
+func (a *API) getLineage(ctx forge.Context, req *GetLineageRequest) (*LineageResponse, error) {
+	keyID, err := id.ParseKeyID(ctx.Param("keyId"))
+	if err != nil {
+		return nil, forge.BadRequest(fmt.Sprintf("invalid key ID: %v", err))
+	}
+	at, err := parseTimeParam("at", req.At)
+	if err != nil {
+		return nil, err
+	}
+
+	eras, err := a.eng.RotationChain(ctx.Context(), keyID)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+	var act *keysmith.HashActivity
+	if at != nil {
+		if act, err = keysmith.ActivityAt(eras, *at); err != nil {
+			return nil, mapStoreError(err)
+		}
+	}
+
+	resp := toLineageResponse(keyID.String(), eras, act)
 	return resp, ctx.JSON(http.StatusOK, resp)
 }
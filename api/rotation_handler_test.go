@@ -0,0 +1,48 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith/rotation"
+)
+
+func TestTenantRotationFilter(t *testing.T) {
+	f, err := tenantRotationFilter("tenant_123", "app_1", "2024-01-01T00:00:00Z", "2024-04-01T00:00:00Z")
+	require.NoError(t, err)
+	assert.Equal(t, "tenant_123", f.TenantID)
+	assert.Equal(t, "app_1", f.AppID)
+	require.NotNil(t, f.CreatedAfter)
+	require.NotNil(t, f.CreatedBefore)
+	assert.True(t, f.CreatedAfter.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, f.CreatedBefore.Equal(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)))
+
+	f, err = tenantRotationFilter("", "", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, &rotation.ListFilter{}, f, "no bounds by default")
+
+	for name, bounds := range map[string][2]string{
+		"malformed after":  {"yesterday", ""},
+		"malformed before": {"", "2024-13-01T00:00:00Z"},
+		"inverted":         {"2024-04-01T00:00:00Z", "2024-01-01T00:00:00Z"},
+		"empty range":      {"2024-01-01T00:00:00Z", "2024-01-01T00:00:00Z"},
+	} {
+		_, err := tenantRotationFilter("", "", bounds[0], bounds[1])
+		assert.Error(t, err, name)
+	}
+}
+
+func TestToRotationSummaryResponse(t *testing.T) {
+	resp := toRotationSummaryResponse(nil, nil, []*rotation.ReasonCount{
+		{Reason: rotation.ReasonScheduled, Count: 12},
+		{Reason: "legacy", Count: 2},
+	})
+	assert.Equal(t, int64(14), resp.Total)
+	assert.Equal(t, []*ReasonCountResponse{{Reason: "scheduled", Count: 12}, {Reason: "legacy", Count: 2}}, resp.Reasons)
+
+	empty := toRotationSummaryResponse(nil, nil, nil)
+	assert.NotNil(t, empty.Reasons, "an empty summary encodes reasons as []")
+}
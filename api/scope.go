@@ -0,0 +1,23 @@
+package api
+
+import (
+	"context"
+
+	"github.com/xraph/forge"
+
+	"github.com/xraph/keysmith"
+)
+
+// Requests scoped with forge.Scope select their app and tenant from it, in
+// the engine as well as in these handlers.
+func init() {
+	keysmith.RegisterScopeResolver(forgeScope)
+}
+
+func forgeScope(ctx context.Context) (appID, tenantID string, ok bool) {
+	s, ok := forge.ScopeFrom(ctx)
+	if !ok {
+		return "", "", false
+	}
+	return s.AppID(), s.OrgID(), true
+}
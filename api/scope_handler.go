@@ -7,17 +7,20 @@ import (
 
 	"github.com/xraph/forge"
 
+	"github.com/xraph/keysmith"
 	"github.com/xraph/keysmith/id"
 	"github.com/xraph/keysmith/scope"
 )
 
 func (a *API) createScope(ctx forge.Context, req *CreateScopeRequest) (*ScopeResponse, error) {
 	sc := &scope.Scope{
-		ID:          id.NewScopeID(),
-		Name:        req.Name,
-		Description: req.Description,
-		Parent:      req.Parent,
-		CreatedAt:   time.Now(),
+		ID:              id.NewScopeID(),
+		Name:            req.Name,
+		Description:     req.Description,
+		Parent:          req.Parent,
+		RateLimit:       req.RateLimit,
+		RateLimitWindow: time.Duration(req.RateLimitWindow),
+		CreatedAt:       time.Now(),
 	}
 
 	if err := a.eng.CreateScope(ctx.Context(), sc); err != nil {
@@ -28,11 +31,18 @@ func (a *API) createScope(ctx forge.Context, req *CreateScopeRequest) (*ScopeRes
 	return resp, ctx.JSON(http.StatusCreated, resp)
 }
 
-func (a *API) listScopes(ctx forge.Context, req *ListScopesRequest) ([]*ScopeResponse, error) {
+func (a *API) listScopes(ctx forge.Context, req *ListScopesRequest) (*struct{}, error) {
+	createdAfter, err := parseTimeParam("created_after", req.CreatedAfter)
+	if err != nil {
+		return nil, err
+	}
+
 	scopes, err := a.eng.ListScopes(ctx.Context(), &scope.ListFilter{
-		Parent: req.Parent,
-		Limit:  defaultLimit(req.Limit),
-		Offset: req.Offset,
+		AppID:        req.AppID,
+		Parent:       req.Parent,
+		CreatedAfter: createdAfter,
+		Limit:        a.pageLimit(ctx, req.Limit, keysmith.DefaultPageSize),
+		Offset:       req.Offset,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("list scopes: %w", err)
@@ -42,7 +52,7 @@ func (a *API) listScopes(ctx forge.Context, req *ListScopesRequest) ([]*ScopeRes
 	for i, s := range scopes {
 		resp[i] = toScopeResponse(s)
 	}
-	return resp, ctx.JSON(http.StatusOK, resp)
+	return nil, ctx.JSON(http.StatusOK, resp)
 }
 
 func (a *API) deleteScope(ctx forge.Context, _ *DeleteScopeRequest) (*struct{}, error) {
@@ -0,0 +1,20 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xraph/forge"
+
+	"github.com/xraph/keysmith"
+)
+
+func TestForgeScope(t *testing.T) {
+	ctx := forge.WithScope(context.Background(), forge.NewOrgScope("app_forge", "org_forge"))
+	assert.Equal(t, "app_forge", keysmith.AppIDFromContext(ctx))
+	assert.Equal(t, "org_forge", keysmith.TenantIDFromContext(ctx))
+
+	ctx = keysmith.WithTenant(context.Background(), "app_1", "tenant_1")
+	assert.Equal(t, "tenant_1", keysmith.TenantIDFromContext(ctx), "standalone scope without forge.Scope")
+}
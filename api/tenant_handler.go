@@ -0,0 +1,63 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/xraph/forge"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/metaschema"
+	"github.com/xraph/keysmith/tenant"
+)
+
+func (a *API) getTenantSettings(ctx forge.Context, _ *GetTenantSettingsRequest) (*TenantSettingsResponse, error) {
+	ts, err := a.eng.GetTenantSettings(ctx.Context(), ctx.Param("tenantId"))
+	if err != nil {
+		return nil, fmt.Errorf("get tenant settings: %w", err)
+	}
+
+	resp := toTenantSettingsResponse(ts)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) updateTenantSettings(ctx forge.Context, req *UpdateTenantSettingsRequest) (*TenantSettingsResponse, error) {
+	cur, err := a.eng.GetTenantSettings(ctx.Context(), ctx.Param("tenantId"))
+	if err != nil {
+		return nil, fmt.Errorf("get tenant settings: %w", err)
+	}
+	ts := &tenant.Settings{
+		TenantID:        ctx.Param("tenantId"),
+		DefaultScopes:   req.DefaultScopes,
+		MetadataSchema:  cur.MetadataSchema,
+		RateLimit:       req.RateLimit,
+		RateLimitWindow: time.Duration(req.RateLimitWindow),
+		DefaultContacts: req.DefaultContacts,
+		IPHandling:      req.IPHandling,
+		QuotaTimezone:   req.QuotaTimezone,
+		UniqueKeyNames:  req.UniqueKeyNames,
+	}
+
+	if err := a.eng.SetTenantSettings(ctx.Context(), ts); err != nil {
+		if errors.Is(err, keysmith.ErrScopeNotFound) || errors.Is(err, keysmith.ErrInvalidTenantSettings) || errors.Is(err, keysmith.ErrInvalidContact) {
+			return nil, forge.BadRequest(err.Error())
+		}
+		return nil, fmt.Errorf("update tenant settings: %w", err)
+	}
+
+	resp := toTenantSettingsResponse(ts)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) putMetadataSchema(ctx forge.Context, req *PutMetadataSchemaRequest) (*TenantSettingsResponse, error) {
+	schema := &metaschema.Schema{Fields: req.Fields, AllowUnknown: req.AllowUnknown}
+	ts, err := a.eng.SetMetadataSchema(ctx.Context(), ctx.Param("tenantId"), schema)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toTenantSettingsResponse(ts)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
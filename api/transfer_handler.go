@@ -0,0 +1,99 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/xraph/forge"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/transfer"
+)
+
+func (a *API) initiateKeyTransfer(ctx forge.Context, req *InitiateKeyTransferRequest) (*KeyTransferResponse, error) {
+	keyID, err := id.ParseKeyID(ctx.Param("keyId"))
+	if err != nil {
+		return nil, forge.BadRequest(fmt.Sprintf("invalid key ID: %v", err))
+	}
+
+	t, err := a.eng.InitiateKeyTransfer(ctx.Context(), keyID, req.TargetTenantID)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toKeyTransferResponse(t)
+	return resp, ctx.JSON(http.StatusCreated, resp)
+}
+
+func (a *API) getKeyTransfer(ctx forge.Context, _ *GetKeyTransferRequest) (*KeyTransferResponse, error) {
+	keyID, err := id.ParseKeyID(ctx.Param("keyId"))
+	if err != nil {
+		return nil, forge.BadRequest(fmt.Sprintf("invalid key ID: %v", err))
+	}
+
+	t, err := a.eng.GetKeyTransfer(ctx.Context(), keyID)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toKeyTransferResponse(t)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) acceptKeyTransfer(ctx forge.Context, _ *AcceptKeyTransferRequest) (*KeyTransferResponse, error) {
+	keyID, err := id.ParseKeyID(ctx.Param("keyId"))
+	if err != nil {
+		return nil, forge.BadRequest(fmt.Sprintf("invalid key ID: %v", err))
+	}
+
+	t, err := a.eng.AcceptKeyTransfer(ctx.Context(), keyID)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toKeyTransferResponse(t)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) cancelKeyTransfer(ctx forge.Context, _ *CancelKeyTransferRequest) (*KeyTransferResponse, error) {
+	keyID, err := id.ParseKeyID(ctx.Param("keyId"))
+	if err != nil {
+		return nil, forge.BadRequest(fmt.Sprintf("invalid key ID: %v", err))
+	}
+
+	t, err := a.eng.CancelKeyTransfer(ctx.Context(), keyID)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toKeyTransferResponse(t)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) listKeyTransfers(ctx forge.Context, req *ListKeyTransfersRequest) (*struct{}, error) {
+	filter := &transfer.ListFilter{
+		State:  transfer.State(strings.ToLower(strings.TrimSpace(req.State))),
+		Limit:  a.pageLimit(ctx, req.Limit, keysmith.DefaultPageSize),
+		Offset: req.Offset,
+	}
+	if req.KeyID != "" {
+		keyID, err := id.ParseKeyID(req.KeyID)
+		if err != nil {
+			return nil, forge.BadRequest(fmt.Sprintf("invalid key ID: %v", err))
+		}
+		filter.KeyID = &keyID
+	}
+
+	ts, err := a.eng.ListKeyTransfers(ctx.Context(), filter)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := make([]*KeyTransferResponse, len(ts))
+	for i, t := range ts {
+		resp[i] = toKeyTransferResponse(t)
+	}
+	return nil, ctx.JSON(http.StatusOK, resp)
+}
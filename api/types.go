@@ -0,0 +1,179 @@
+package api
+
+import "github.com/xraph/keysmith/apitypes"
+
+// The request and response types live in apitypes, which the Go client
+// shares; these aliases keep their names in this package.
+type (
+	CreateKeyRequest                  = apitypes.CreateKeyRequest
+	InlinePolicyRequest               = apitypes.InlinePolicyRequest
+	ListKeysRequest                   = apitypes.ListKeysRequest
+	KeyOverviewRequest                = apitypes.KeyOverviewRequest
+	GetKeyRequest                     = apitypes.GetKeyRequest
+	UpdateKeyRequest                  = apitypes.UpdateKeyRequest
+	RevokeByLabelsRequest             = apitypes.RevokeByLabelsRequest
+	DeleteKeyRequest                  = apitypes.DeleteKeyRequest
+	RotateKeyRequest                  = apitypes.RotateKeyRequest
+	RevokeKeyRequest                  = apitypes.RevokeKeyRequest
+	ValidateKeyRequest                = apitypes.ValidateKeyRequest
+	ValidateKeyHeaderRequest          = apitypes.ValidateKeyHeaderRequest
+	ListSuspiciousFingerprintsRequest = apitypes.ListSuspiciousFingerprintsRequest
+	GetIntegrationGuideRequest        = apitypes.GetIntegrationGuideRequest
+	GetSLORequest                     = apitypes.GetSLORequest
+	GetReplayStatsRequest             = apitypes.GetReplayStatsRequest
+	SuspendKeyRequest                 = apitypes.SuspendKeyRequest
+	ReactivateKeyRequest              = apitypes.ReactivateKeyRequest
+	ReportCompromiseRequest           = apitypes.ReportCompromiseRequest
+	CreatePolicyRequest               = apitypes.CreatePolicyRequest
+	CreatePolicyFromTemplateRequest   = apitypes.CreatePolicyFromTemplateRequest
+	UpdatePolicyRequest               = apitypes.UpdatePolicyRequest
+	ListPoliciesRequest               = apitypes.ListPoliciesRequest
+	GetPolicyRequest                  = apitypes.GetPolicyRequest
+	GetEffectivePolicyRequest         = apitypes.GetEffectivePolicyRequest
+	DeletePolicyRequest               = apitypes.DeletePolicyRequest
+	CreateScopeRequest                = apitypes.CreateScopeRequest
+	ListScopesRequest                 = apitypes.ListScopesRequest
+	DeleteScopeRequest                = apitypes.DeleteScopeRequest
+	AssignScopesRequest               = apitypes.AssignScopesRequest
+	GetKeyContactsRequest             = apitypes.GetKeyContactsRequest
+	GetLiveKeyStatsRequest            = apitypes.GetLiveKeyStatsRequest
+	ListDuplicateKeyNamesRequest      = apitypes.ListDuplicateKeyNamesRequest
+	SearchRequest                     = apitypes.SearchRequest
+	ListLiveTopKeysRequest            = apitypes.ListLiveTopKeysRequest
+	PutKeyContactsRequest             = apitypes.PutKeyContactsRequest
+	InitiateKeyTransferRequest        = apitypes.InitiateKeyTransferRequest
+	GetKeyTransferRequest             = apitypes.GetKeyTransferRequest
+	AcceptKeyTransferRequest          = apitypes.AcceptKeyTransferRequest
+	CancelKeyTransferRequest          = apitypes.CancelKeyTransferRequest
+	ListKeyTransfersRequest           = apitypes.ListKeyTransfersRequest
+	RemoveScopesRequest               = apitypes.RemoveScopesRequest
+	CreateGroupRequest                = apitypes.CreateGroupRequest
+	ListGroupsRequest                 = apitypes.ListGroupsRequest
+	GetGroupRequest                   = apitypes.GetGroupRequest
+	UpdateGroupRequest                = apitypes.UpdateGroupRequest
+	DeleteGroupRequest                = apitypes.DeleteGroupRequest
+	AddGroupKeysRequest               = apitypes.AddGroupKeysRequest
+	RemoveGroupKeysRequest            = apitypes.RemoveGroupKeysRequest
+	RotateGroupRequest                = apitypes.RotateGroupRequest
+	RevokeGroupRequest                = apitypes.RevokeGroupRequest
+	CreateQuotaPoolRequest            = apitypes.CreateQuotaPoolRequest
+	ListQuotaPoolsRequest             = apitypes.ListQuotaPoolsRequest
+	GetQuotaPoolRequest               = apitypes.GetQuotaPoolRequest
+	UpdateQuotaPoolRequest            = apitypes.UpdateQuotaPoolRequest
+	DeleteQuotaPoolRequest            = apitypes.DeleteQuotaPoolRequest
+	AddQuotaPoolKeysRequest           = apitypes.AddQuotaPoolKeysRequest
+	RemoveQuotaPoolKeysRequest        = apitypes.RemoveQuotaPoolKeysRequest
+	GetQuotaPoolUsageRequest          = apitypes.GetQuotaPoolUsageRequest
+	GetKeyUsageRequest                = apitypes.GetKeyUsageRequest
+	GetKeyUsageAggregateRequest       = apitypes.GetKeyUsageAggregateRequest
+	ListEndpointActivityRequest       = apitypes.ListEndpointActivityRequest
+	GetQuotaForecastRequest           = apitypes.GetQuotaForecastRequest
+	ListUsageRequest                  = apitypes.ListUsageRequest
+	ListRotationsRequest              = apitypes.ListRotationsRequest
+	ListTenantRotationsRequest        = apitypes.ListTenantRotationsRequest
+	RotationSummaryRequest            = apitypes.RotationSummaryRequest
+	GetLineageRequest                 = apitypes.GetLineageRequest
+	ListRevocationsRequest            = apitypes.ListRevocationsRequest
+	StreamKeyEventsRequest            = apitypes.StreamKeyEventsRequest
+	SetKeyDebugRequest                = apitypes.SetKeyDebugRequest
+	ListCapturesRequest               = apitypes.ListCapturesRequest
+	GetTenantSettingsRequest          = apitypes.GetTenantSettingsRequest
+	UpdateTenantSettingsRequest       = apitypes.UpdateTenantSettingsRequest
+	PutMetadataSchemaRequest          = apitypes.PutMetadataSchemaRequest
+	MaintainStoreRequest              = apitypes.MaintainStoreRequest
+	GetStorageReportRequest           = apitypes.GetStorageReportRequest
+	RevokeByHashRequest               = apitypes.RevokeByHashRequest
+	ListKeysByCreatorRequest          = apitypes.ListKeysByCreatorRequest
+	RevokeByCreatorRequest            = apitypes.RevokeByCreatorRequest
+	ValidateHashesRequest             = apitypes.ValidateHashesRequest
+	ListHashTombstonesRequest         = apitypes.ListHashTombstonesRequest
+	LockdownTenantRequest             = apitypes.LockdownTenantRequest
+	GetTenantLockdownRequest          = apitypes.GetTenantLockdownRequest
+	RecoverTenantRequest              = apitypes.RecoverTenantRequest
+	GetUsageRecordingRequest          = apitypes.GetUsageRecordingRequest
+	SetUsageRecordingRequest          = apitypes.SetUsageRecordingRequest
+	GetRuntimeConfigRequest           = apitypes.GetRuntimeConfigRequest
+	UpdateRuntimeConfigRequest        = apitypes.UpdateRuntimeConfigRequest
+	SetReadOnlyRequest                = apitypes.SetReadOnlyRequest
+	KeyResponse                       = apitypes.KeyResponse
+	QuotaForecastSummary              = apitypes.QuotaForecastSummary
+	QuotaForecastResponse             = apitypes.QuotaForecastResponse
+	KeyOverviewResponse               = apitypes.KeyOverviewResponse
+	OverviewWindowResponse            = apitypes.OverviewWindowResponse
+	KeyCreateResponse                 = apitypes.KeyCreateResponse
+	CompromiseResponse                = apitypes.CompromiseResponse
+	PolicyResponse                    = apitypes.PolicyResponse
+	EffectivePolicyResponse           = apitypes.EffectivePolicyResponse
+	ScopeResponse                     = apitypes.ScopeResponse
+	GroupResponse                     = apitypes.GroupResponse
+	GroupOperationResponse            = apitypes.GroupOperationResponse
+	GroupKeyResultEntry               = apitypes.GroupKeyResultEntry
+	QuotaPoolResponse                 = apitypes.QuotaPoolResponse
+	QuotaPoolUsageResponse            = apitypes.QuotaPoolUsageResponse
+	QuotaWindowUsageResponse          = apitypes.QuotaWindowUsageResponse
+	QuotaMemberUsageResponse          = apitypes.QuotaMemberUsageResponse
+	UsageResponse                     = apitypes.UsageResponse
+	AggregationResponse               = apitypes.AggregationResponse
+	EndpointActivityResponse          = apitypes.EndpointActivityResponse
+	RotationResponse                  = apitypes.RotationResponse
+	RotationListResponse              = apitypes.RotationListResponse
+	RotationSummaryResponse           = apitypes.RotationSummaryResponse
+	ReasonCountResponse               = apitypes.ReasonCountResponse
+	HashEraResponse                   = apitypes.HashEraResponse
+	LineageResponse                   = apitypes.LineageResponse
+	HashActivityResponse              = apitypes.HashActivityResponse
+	ValidationResponse                = apitypes.ValidationResponse
+	RateLimitResponse                 = apitypes.RateLimitResponse
+	QuotaResponse                     = apitypes.QuotaResponse
+	RotationDueResponse               = apitypes.RotationDueResponse
+	KeyDeprecationResponse            = apitypes.KeyDeprecationResponse
+	FailurePatternResponse            = apitypes.FailurePatternResponse
+	SLOResponse                       = apitypes.SLOResponse
+	SLOStatusResponse                 = apitypes.SLOStatusResponse
+	SLOBurnResponse                   = apitypes.SLOBurnResponse
+	ReplayStatsResponse               = apitypes.ReplayStatsResponse
+	TenantSettingsResponse            = apitypes.TenantSettingsResponse
+	TenantLockdownResponse            = apitypes.TenantLockdownResponse
+	TenantRecoveryResponse            = apitypes.TenantRecoveryResponse
+	TenantLockdownStatusResponse      = apitypes.TenantLockdownStatusResponse
+	TenantLockdownResultResponse      = apitypes.TenantLockdownResultResponse
+	KeyContactsResponse               = apitypes.KeyContactsResponse
+	LiveKeyStatsResponse              = apitypes.LiveKeyStatsResponse
+	KeyTransferResponse               = apitypes.KeyTransferResponse
+	KeyNameDuplicateResponse          = apitypes.KeyNameDuplicateResponse
+	SearchResponse                    = apitypes.SearchResponse
+	KeySearchMatchResponse            = apitypes.KeySearchMatchResponse
+	RevocationEntryResponse           = apitypes.RevocationEntryResponse
+	RevocationFeedResponse            = apitypes.RevocationFeedResponse
+	KeyEventResponse                  = apitypes.KeyEventResponse
+	CaptureResponse                   = apitypes.CaptureResponse
+	HashReportsResponse               = apitypes.HashReportsResponse
+	HashTombstonesResponse            = apitypes.HashTombstonesResponse
+	HashTombstoneResponse             = apitypes.HashTombstoneResponse
+	TombstoneReasonCountResponse      = apitypes.TombstoneReasonCountResponse
+	KeysByCreatorResponse             = apitypes.KeysByCreatorResponse
+	CreatorRevokeResponse             = apitypes.CreatorRevokeResponse
+	LabelRevokeResponse               = apitypes.LabelRevokeResponse
+	HashReportResponse                = apitypes.HashReportResponse
+	MaintenanceResponse               = apitypes.MaintenanceResponse
+	StorageReportResponse             = apitypes.StorageReportResponse
+	TenantStorageResponse             = apitypes.TenantStorageResponse
+	TenantUsageResponse               = apitypes.TenantUsageResponse
+	TableStatsResponse                = apitypes.TableStatsResponse
+	UsageRecordingResponse            = apitypes.UsageRecordingResponse
+	RuntimeConfigResponse             = apitypes.RuntimeConfigResponse
+	ReadOnlyResponse                  = apitypes.ReadOnlyResponse
+	IntegrationGuideResponse          = apitypes.IntegrationGuideResponse
+	IntegrationEndpointResponse       = apitypes.IntegrationEndpointResponse
+	IntegrationKeyResponse            = apitypes.IntegrationKeyResponse
+	IntegrationSnippetResponse        = apitypes.IntegrationSnippetResponse
+	IntegrationScopeResponse          = apitypes.IntegrationScopeResponse
+)
+
+// Field types shared by the requests and responses.
+type (
+	Duration       = apitypes.Duration
+	KeyState       = apitypes.KeyState
+	KeyEnvironment = apitypes.KeyEnvironment
+	RotationReason = apitypes.RotationReason
+)
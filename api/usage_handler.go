@@ -6,21 +6,42 @@ import (
 
 	"github.com/xraph/forge"
 
+	"github.com/xraph/keysmith"
 	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
 	"github.com/xraph/keysmith/usage"
 )
 
-func (a *API) getKeyUsage(ctx forge.Context, req *GetKeyUsageRequest) ([]*UsageResponse, error) {
+func (a *API) getQuotaForecast(ctx forge.Context, _ *GetQuotaForecastRequest) (*QuotaForecastResponse, error) {
 	keyID, err := id.ParseKeyID(ctx.Param("keyId"))
 	if err != nil {
 		return nil, forge.BadRequest(fmt.Sprintf("invalid key ID: %v", err))
 	}
 
+	f, err := a.eng.QuotaForecast(ctx.Context(), keyID)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	resp := toQuotaForecastResponse(keyID.String(), f)
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) getKeyUsage(ctx forge.Context, req *GetKeyUsageRequest) (*struct{}, error) {
+	keyID, err := id.ParseKeyID(ctx.Param("keyId"))
+	if err != nil {
+		return nil, forge.BadRequest(fmt.Sprintf("invalid key ID: %v", err))
+	}
+	sel, err := parseFields(req.Fields, usageFields)
+	if err != nil {
+		return nil, err
+	}
+
 	records, err := a.eng.QueryUsage(ctx.Context(), &usage.QueryFilter{
 		KeyID:  &keyID,
 		After:  parseTime(req.After),
 		Before: parseTime(req.Before),
-		Limit:  defaultLimit(req.Limit),
+		Limit:  a.pageLimit(ctx, req.Limit, keysmith.DefaultUsagePageSize),
 		Offset: req.Offset,
 	})
 	if err != nil {
@@ -31,20 +52,26 @@ func (a *API) getKeyUsage(ctx forge.Context, req *GetKeyUsageRequest) ([]*UsageR
 	for i, r := range records {
 		resp[i] = toUsageResponse(r)
 	}
-	return resp, ctx.JSON(http.StatusOK, resp)
+	return nil, writeSelectedList(ctx, sel, resp)
 }
 
-func (a *API) getKeyUsageAggregate(ctx forge.Context, req *GetKeyUsageAggregateRequest) ([]*AggregationResponse, error) {
+func (a *API) getKeyUsageAggregate(ctx forge.Context, req *GetKeyUsageAggregateRequest) (*struct{}, error) {
 	keyID, err := id.ParseKeyID(ctx.Param("keyId"))
 	if err != nil {
 		return nil, forge.BadRequest(fmt.Sprintf("invalid key ID: %v", err))
 	}
+	sel, err := parseFields(req.Fields, aggregationFields)
+	if err != nil {
+		return nil, err
+	}
 
 	aggs, err := a.eng.AggregateUsage(ctx.Context(), &usage.QueryFilter{
 		KeyID:  &keyID,
 		Period: req.Period,
 		After:  parseTime(req.After),
 		Before: parseTime(req.Before),
+		Limit:  a.pageLimit(ctx, req.Limit, keysmith.DefaultUsagePageSize),
+		Offset: req.Offset,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("aggregate usage: %w", err)
@@ -54,14 +81,44 @@ func (a *API) getKeyUsageAggregate(ctx forge.Context, req *GetKeyUsageAggregateR
 	for i, a := range aggs {
 		resp[i] = toAggregationResponse(a)
 	}
-	return resp, ctx.JSON(http.StatusOK, resp)
+	return nil, writeSelectedList(ctx, sel, resp)
 }
 
-func (a *API) listUsage(ctx forge.Context, req *ListUsageRequest) ([]*AggregationResponse, error) {
+func (a *API) listEndpointActivity(ctx forge.Context, _ *ListEndpointActivityRequest) (*struct{}, error) {
+	keyID, err := id.ParseKeyID(ctx.Param("keyId"))
+	if err != nil {
+		return nil, forge.BadRequest(fmt.Sprintf("invalid key ID: %v", err))
+	}
+
+	acts, err := a.eng.ListEndpointActivity(ctx.Context(), keyID)
+	if err != nil {
+		return nil, fmt.Errorf("list endpoint activity: %w", err)
+	}
+
+	resp := make([]*EndpointActivityResponse, len(acts))
+	for i, act := range acts {
+		resp[i] = toEndpointActivityResponse(act)
+	}
+	return nil, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) listUsage(ctx forge.Context, req *ListUsageRequest) (*struct{}, error) {
+	if err := validateEnum("environment", req.Environment); err != nil {
+		return nil, err
+	}
+	sel, err := parseFields(req.Fields, aggregationFields)
+	if err != nil {
+		return nil, err
+	}
+
 	aggs, err := a.eng.AggregateUsage(ctx.Context(), &usage.QueryFilter{
-		Period: req.Period,
-		After:  parseTime(req.After),
-		Before: parseTime(req.Before),
+		AppID:       req.AppID,
+		Environment: key.Environment(req.Environment),
+		Period:      req.Period,
+		After:       parseTime(req.After),
+		Before:      parseTime(req.Before),
+		Limit:       a.pageLimit(ctx, req.Limit, keysmith.DefaultUsagePageSize),
+		Offset:      req.Offset,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("list usage: %w", err)
@@ -71,5 +128,5 @@ func (a *API) listUsage(ctx forge.Context, req *ListUsageRequest) ([]*Aggregatio
 	for i, ag := range aggs {
 		resp[i] = toAggregationResponse(ag)
 	}
-	return resp, ctx.JSON(http.StatusOK, resp)
+	return nil, writeSelectedList(ctx, sel, resp)
 }
@@ -1,13 +1,56 @@
 package api
 
 import (
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/xraph/forge"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/apitypes"
+	"github.com/xraph/keysmith/middleware"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/usage"
+)
+
+// Headers set on a successful GET or HEAD /v1/keys/validate, for edge
+// platforms to forward to the origin.
+const (
+	KeyIDHeader    = apitypes.KeyIDHeader
+	TenantIDHeader = apitypes.TenantIDHeader
+	ScopesHeader   = apitypes.ScopesHeader
 )
 
 func (a *API) validateKey(ctx forge.Context, req *ValidateKeyRequest) (*ValidationResponse, error) {
-	result, err := a.eng.ValidateKey(ctx.Context(), req.RawKey)
+	var ts time.Time
+	if t := parseTime(req.Timestamp); t != nil {
+		ts = *t
+	}
+	if err := a.eng.CheckReplay(ctx.Context(), req.RawKey, req.Nonce, ts); err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	vreq := keysmith.ValidationRequest{
+		Origin:                req.Origin,
+		ConsumerService:       req.ConsumerService,
+		ClientCertFingerprint: req.ClientCertFingerprint,
+		Method:                req.Method,
+		Path:                  req.Path,
+		RemoteAddr:            req.RemoteIP,
+		Attributes:            req.Attributes,
+	}
+	if req.TLSVersion != "" {
+		v, err := policy.ParseTLSVersion(req.TLSVersion)
+		if err != nil {
+			return nil, forge.BadRequest("tls_version: " + err.Error())
+		}
+		vreq.TLSVersion = v
+	}
+	vctx := keysmith.WithValidationRequest(ctx.Context(), vreq)
+	result, err := a.eng.ValidateKey(vctx, req.RawKey)
 	if err != nil {
 		return nil, mapStoreError(err)
 	}
@@ -15,3 +58,84 @@ func (a *API) validateKey(ctx forge.Context, req *ValidateKeyRequest) (*Validati
 	resp := toValidationResponse(result)
 	return resp, ctx.JSON(http.StatusOK, resp)
 }
+
+// validateKeyHeader validates the key in the request headers, read as
+// APIKeyAuth reads them, for edge subrequest auth. The key is never logged
+// or echoed back.
+func (a *API) validateKeyHeader(ctx forge.Context, _ *ValidateKeyHeaderRequest) (*struct{}, error) {
+	start := time.Now()
+	r := ctx.Request()
+	// Failures are never cacheable; a success may relax this below.
+	ctx.SetHeader("Cache-Control", "no-store")
+
+	rawKey := middleware.ExtractKey(r)
+	if rawKey == "" {
+		return nil, forge.Unauthorized("missing API key")
+	}
+	// Headers carry no nonce, so with replay protection on this refuses.
+	if err := a.eng.CheckReplay(ctx.Context(), rawKey, "", time.Time{}); err != nil {
+		return nil, mapStoreError(err)
+	}
+	result, err := a.eng.ValidateKey(middleware.ValidationContext(ctx.Context(), r, a.middlewareOpts...), rawKey)
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+
+	h := ctx.Response().Header()
+	h.Set(KeyIDHeader, result.Key.ID.String())
+	h.Set(TenantIDHeader, result.Key.TenantID)
+	if len(result.Scopes) > 0 {
+		h.Set(ScopesHeader, strings.Join(result.Scopes, ","))
+	}
+	middleware.SetRateLimitHeaders(h, result.RateLimit)
+	middleware.SetQuotaHeaders(h, result.Quota)
+	middleware.SetRotationDueHeaders(h, result.RotationDue)
+	middleware.SetKeyDeprecationHeaders(h, result.KeyDeprecation)
+	if a.edgeCacheTTL > 0 {
+		h.Set("Cache-Control", "private, max-age="+strconv.Itoa(int(a.edgeCacheTTL/time.Second)))
+		h.Set("Vary", "Authorization, X-API-Key")
+	}
+
+	// Subrequests are counted without a usage record each, unless a
+	// recording rule for the route says otherwise. Recording is best
+	// effort and never fails the validation.
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	_ = a.eng.RecordUsage(ctx.Context(), &usage.Record{
+		KeyID:       result.Key.ID,
+		TenantID:    result.Key.TenantID,
+		AppID:       result.Key.AppID,
+		Environment: result.Key.Environment,
+		Endpoint:    r.URL.Path,
+		Method:      r.Method,
+		StatusCode:  http.StatusNoContent,
+		IPAddress:   ip,
+		UserAgent:   r.UserAgent(),
+		Latency:     time.Since(start),
+		DefaultMode: usage.RecordCounterOnly,
+	})
+
+	return nil, ctx.NoContent(http.StatusNoContent)
+}
+
+func (a *API) listSuspiciousFingerprints(ctx forge.Context, req *ListSuspiciousFingerprintsRequest) (*struct{}, error) {
+	patterns := a.eng.SuspiciousFingerprints(a.pageLimit(ctx, req.Limit, keysmith.DefaultPageSize))
+
+	resp := make([]*FailurePatternResponse, len(patterns))
+	for i, p := range patterns {
+		resp[i] = toFailurePatternResponse(p)
+	}
+	return nil, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) getReplayStats(ctx forge.Context, _ *GetReplayStatsRequest) (*ReplayStatsResponse, error) {
+	resp := toReplayStatsResponse(a.eng.ReplayStats())
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
+
+func (a *API) getSLO(ctx forge.Context, _ *GetSLORequest) (*SLOResponse, error) {
+	resp := toSLOResponse(a.eng.SLOStatus())
+	return resp, ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/store/memory"
+	"github.com/xraph/keysmith/usage"
+)
+
+// headerFixture is an API handler and the raw keys of an active, a revoked,
+// and a rate-limited key.
+type headerFixture struct {
+	eng                      *keysmith.Engine
+	ctx                      context.Context
+	handler                  http.Handler
+	active, revoked, limited string
+	activeKey                *key.Key
+}
+
+func newHeaderFixture(t *testing.T, opts ...Option) *headerFixture {
+	t.Helper()
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithRateLimiter(&fixedLimiter{counts: make(map[string]int)}),
+	)
+	require.NoError(t, err)
+	f := &headerFixture{eng: eng, ctx: keysmith.WithTenant(context.Background(), "app_1", "tenant_acme")}
+	for _, name := range []string{"read:orders", "write:orders"} {
+		require.NoError(t, eng.CreateScope(f.ctx, &scope.Scope{Name: name}))
+	}
+
+	pol := &policy.Policy{Name: "Edge", RateLimit: 100, RateLimitWindow: time.Minute}
+	require.NoError(t, eng.CreatePolicy(f.ctx, pol))
+	tight := &policy.Policy{Name: "Tight", RateLimit: 1, RateLimitWindow: time.Minute}
+	require.NoError(t, eng.CreatePolicy(f.ctx, tight))
+	create := func(polID *policy.Policy) *key.CreateResult {
+		created, err := eng.CreateKey(f.ctx, &keysmith.CreateKeyInput{
+			Name: "edge", Prefix: "sk", Environment: key.EnvTest, PolicyID: &polID.ID,
+			Scopes: []string{"read:orders", "write:orders"},
+		})
+		require.NoError(t, err)
+		return created
+	}
+	active := create(pol)
+	f.active, f.activeKey = active.RawKey, active.Key
+	revoked := create(pol)
+	require.NoError(t, eng.RevokeKey(f.ctx, revoked.Key.ID, "retired"))
+	f.revoked = revoked.RawKey
+	f.limited = create(tight).RawKey
+
+	f.handler = New(eng, nil, opts...).Handler()
+	return f
+}
+
+// fixedLimiter allows limit requests per bucket and never resets.
+type fixedLimiter struct{ counts map[string]int }
+
+func (l *fixedLimiter) Allow(_ context.Context, bucket string, limit int, _ time.Duration) (bool, error) {
+	if l.counts[bucket] >= limit {
+		return false, nil
+	}
+	l.counts[bucket]++
+	return true, nil
+}
+
+func (l *fixedLimiter) Remaining(_ context.Context, bucket string, limit int, _ time.Duration) (int, error) {
+	return limit - l.counts[bucket], nil
+}
+
+func (f *headerFixture) do(t *testing.T, method string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(method, "/v1/keys/validate", nil)
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	f.handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestValidateKeyHeader_Extractors(t *testing.T) {
+	f := newHeaderFixture(t)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    int
+	}{
+		{"bearer", map[string]string{"Authorization": "Bearer " + f.active}, http.StatusNoContent},
+		{"x-api-key", map[string]string{"X-API-Key": f.active}, http.StatusNoContent},
+		{"bearer wins", map[string]string{"Authorization": "Bearer " + f.active, "X-API-Key": "sk_test_bogus"}, http.StatusNoContent},
+		{"bearer wins when wrong", map[string]string{"Authorization": "Bearer sk_test_bogus", "X-API-Key": f.active}, http.StatusUnauthorized},
+		{"other schemes are skipped", map[string]string{"Authorization": "Basic " + f.active, "X-API-Key": f.active}, http.StatusNoContent},
+		{"empty bearer is skipped", map[string]string{"Authorization": "Bearer ", "X-API-Key": f.active}, http.StatusNoContent},
+		{"missing", nil, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, f.do(t, http.MethodGet, tt.headers).Code)
+		})
+	}
+}
+
+func TestValidateKeyHeader_Status(t *testing.T) {
+	f := newHeaderFixture(t)
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		t.Run(method, func(t *testing.T) {
+			for raw, want := range map[string]int{
+				f.active:                     http.StatusNoContent,
+				"sk_test_doesnotexist000000": http.StatusUnauthorized,
+				f.revoked:                    http.StatusForbidden,
+			} {
+				w := f.do(t, method, map[string]string{"X-API-Key": raw})
+				assert.Equal(t, want, w.Code)
+				assert.NotContains(t, w.Body.String(), raw, "the key is never echoed")
+			}
+		})
+	}
+
+	require.Equal(t, http.StatusNoContent, f.do(t, http.MethodGet, map[string]string{"X-API-Key": f.limited}).Code)
+	w := f.do(t, http.MethodGet, map[string]string{"X-API-Key": f.limited})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotContains(t, w.Body.String(), f.limited)
+}
+
+func TestValidateKeyHeader_Headers(t *testing.T) {
+	f := newHeaderFixture(t)
+
+	w := f.do(t, http.MethodGet, map[string]string{"Authorization": "Bearer " + f.active})
+	require.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, f.activeKey.ID.String(), w.Header().Get(KeyIDHeader))
+	assert.Equal(t, "tenant_acme", w.Header().Get(TenantIDHeader))
+	assert.ElementsMatch(t, []string{"read:orders", "write:orders"}, strings.Split(w.Header().Get(ScopesHeader), ","))
+	assert.Equal(t, "100", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "99", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"), "not cacheable by default")
+
+	w = f.do(t, http.MethodGet, map[string]string{"X-API-Key": f.revoked})
+	assert.Empty(t, w.Header().Get(KeyIDHeader))
+	assert.Empty(t, w.Header().Get(ScopesHeader))
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+}
+
+func TestValidateKeyHeader_CacheControl(t *testing.T) {
+	tests := []struct {
+		ttl  time.Duration
+		want string
+	}{
+		{0, "no-store"},
+		{-time.Second, "no-store"},
+		{10 * time.Second, "private, max-age=10"},
+		{time.Hour, "private, max-age=30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ttl.String(), func(t *testing.T) {
+			f := newHeaderFixture(t, WithEdgeCacheTTL(tt.ttl))
+			w := f.do(t, http.MethodHead, map[string]string{"X-API-Key": f.active})
+			require.Equal(t, http.StatusNoContent, w.Code)
+			assert.Equal(t, tt.want, w.Header().Get("Cache-Control"))
+
+			// Failures are never cacheable.
+			w = f.do(t, http.MethodHead, map[string]string{"X-API-Key": f.revoked})
+			assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+		})
+	}
+
+	f := newHeaderFixture(t, WithEdgeCacheTTL(10*time.Second))
+	w := f.do(t, http.MethodGet, map[string]string{"X-API-Key": f.active})
+	assert.Equal(t, "Authorization, X-API-Key", w.Header().Get("Vary"), "caches key on the credential headers")
+}
+
+func TestValidateKeyHeader_CountsUsageOnly(t *testing.T) {
+	f := newHeaderFixture(t)
+	require.Equal(t, http.StatusNoContent, f.do(t, http.MethodGet, map[string]string{"X-API-Key": f.active}).Code)
+
+	records, err := f.eng.QueryUsage(f.ctx, &usage.QueryFilter{KeyID: &f.activeKey.ID})
+	require.NoError(t, err)
+	assert.Empty(t, records, "no usage record by default")
+	activity, err := f.eng.ListEndpointActivity(f.ctx, f.activeKey.ID)
+	require.NoError(t, err)
+	require.Len(t, activity, 1)
+	assert.Equal(t, http.MethodGet, activity[0].Method)
+
+	// A recording rule for the route overrides the default.
+	require.NoError(t, f.eng.SetUsageRecording(usage.RecordingPolicy{
+		Rules: []usage.RecordingRule{{Pattern: "/v1/keys/validate", Mode: usage.RecordFull}},
+	}))
+	require.Equal(t, http.StatusNoContent, f.do(t, http.MethodGet, map[string]string{"X-API-Key": f.active}).Code)
+	records, err = f.eng.QueryUsage(f.ctx, &usage.QueryFilter{KeyID: &f.activeKey.ID})
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+}
@@ -0,0 +1,22 @@
+// Package apitypes holds the request and response types of the keysmith
+// REST API. The api package serves them and the client package sends them,
+// so a route's wire format is defined once; neither depends on the other,
+// and this package does not depend on forge.
+//
+// Struct tags say where each request field travels: path:"name" fills the
+// :name segment of the route, query:"name" is a query parameter,
+// header:"Name" is a request header, and json-tagged fields form the body.
+package apitypes
+
+// Headers set on a successful GET or HEAD /v1/keys/validate, for edge
+// platforms to forward to the origin.
+const (
+	KeyIDHeader    = "X-Keysmith-Key-ID"
+	TenantIDHeader = "X-Keysmith-Tenant-ID"
+	ScopesHeader   = "X-Keysmith-Scopes"
+)
+
+// PageLimitHeader is set on list responses to the limit the list was served
+// with: the requested limit, the route's default when none was given, or
+// the server's maximum page size when more was asked for.
+const PageLimitHeader = "X-Keysmith-Page-Limit"
@@ -0,0 +1,263 @@
+package apitypes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration is a length of time in a request or response. It is written as
+// an ISO 8601 duration, such as "PT30M" or "P7D", and read from that, from
+// a number of seconds, or from the Go duration strings ("24h0m0s") earlier
+// versions wrote. Days are 24 hours and weeks 7 days; ISO 8601 years and
+// months have no fixed length and are rejected, as are negative durations.
+type Duration time.Duration
+
+const (
+	day  = 24 * time.Hour
+	week = 7 * day
+)
+
+// ParseDuration parses s in any of the forms Duration reads. An empty s is
+// zero.
+func ParseDuration(s string) (Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if strings.HasPrefix(s, "-") {
+		return 0, fmt.Errorf("invalid duration %q: durations must not be negative", s)
+	}
+	var d time.Duration
+	var err error
+	switch {
+	case s[0] == 'P' || s[0] == 'p':
+		d, err = parseISODuration(strings.ToUpper(s[1:]))
+	case isDecimal(s):
+		d, err = parseSeconds(s)
+	default:
+		d, err = parseGoDuration(s)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+	}
+	return Duration(d), nil
+}
+
+// String returns d as an ISO 8601 duration, in days, hours, minutes, and
+// seconds: "P1DT2H", "PT1.5S", or "PT0S" for zero.
+func (d Duration) String() string {
+	v := time.Duration(d)
+	if v == 0 {
+		return "PT0S"
+	}
+	var b strings.Builder
+	if v < 0 {
+		b.WriteByte('-')
+		v = -v
+	}
+	b.WriteByte('P')
+	if days := v / day; days > 0 {
+		fmt.Fprintf(&b, "%dD", days)
+		v -= days * day
+	}
+	if v == 0 {
+		return b.String()
+	}
+	b.WriteByte('T')
+	if h := v / time.Hour; h > 0 {
+		fmt.Fprintf(&b, "%dH", h)
+		v -= h * time.Hour
+	}
+	if m := v / time.Minute; m > 0 {
+		fmt.Fprintf(&b, "%dM", m)
+		v -= m * time.Minute
+	}
+	if v > 0 {
+		secs, frac := v/time.Second, v%time.Second
+		b.WriteString(strconv.FormatInt(int64(secs), 10))
+		if frac > 0 {
+			b.WriteByte('.')
+			b.WriteString(strings.TrimRight(fmt.Sprintf("%09d", frac), "0"))
+		}
+		b.WriteByte('S')
+	}
+	return b.String()
+}
+
+// SchemaFormat marks durations as format "duration" in the OpenAPI
+// document, the JSON Schema name for ISO 8601 durations.
+func (Duration) SchemaFormat() string { return "duration" }
+
+// MarshalText writes d as an ISO 8601 duration.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText parses a duration in any form ParseDuration accepts.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// UnmarshalJSON accepts a string in any form ParseDuration accepts or a
+// number of seconds. null leaves d unchanged.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		return d.UnmarshalText([]byte(s))
+	}
+	return d.UnmarshalText(data)
+}
+
+// parseISODuration parses the part of an ISO 8601 duration after its P:
+// weeks and days, then optionally T and hours, minutes, and seconds, each
+// at most once and in that order. Only seconds may have a fraction.
+func parseISODuration(s string) (time.Duration, error) {
+	units := []struct {
+		designator byte
+		unit       time.Duration
+		timePart   bool
+	}{
+		{'W', week, false},
+		{'D', day, false},
+		{'H', time.Hour, true},
+		{'M', time.Minute, true},
+		{'S', time.Second, true},
+	}
+
+	var total time.Duration
+	next, components, inTime := 0, 0, false
+	for s != "" {
+		if s[0] == 'T' {
+			if inTime {
+				return 0, errors.New("T appears twice")
+			}
+			inTime, next, components = true, 2, 0
+			s = s[1:]
+			continue
+		}
+		n := 0
+		for n < len(s) && (s[n] >= '0' && s[n] <= '9' || s[n] == '.' || s[n] == ',') {
+			n++
+		}
+		if n == 0 || n == len(s) {
+			return 0, errors.New("each component is a number followed by a designator such as D or H")
+		}
+		num, designator := strings.ReplaceAll(s[:n], ",", "."), s[n]
+		s = s[n+1:]
+
+		i := next
+		for i < len(units) && (units[i].designator != designator || units[i].timePart != inTime) {
+			i++
+		}
+		if i == len(units) {
+			if !inTime && (designator == 'Y' || designator == 'M') {
+				return 0, errors.New("years and months have no fixed length; use days")
+			}
+			return 0, fmt.Errorf("unexpected %c: components are W, D, then T, H, M, S, in that order", designator)
+		}
+		next = i + 1
+		components++
+
+		var v time.Duration
+		var err error
+		if units[i].designator == 'S' {
+			v, err = parseSeconds(num)
+		} else {
+			v, err = scaleInt(num, units[i].unit)
+		}
+		if err != nil {
+			return 0, err
+		}
+		if total > math.MaxInt64-v {
+			return 0, errors.New("too long")
+		}
+		total += v
+	}
+	if components == 0 {
+		return 0, errors.New("no components")
+	}
+	return total, nil
+}
+
+// parseSeconds parses a decimal number of seconds with up to nanosecond
+// precision.
+func parseSeconds(s string) (time.Duration, error) {
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if whole == "" || hasFrac && frac == "" {
+		return 0, fmt.Errorf("%q is not a number of seconds", s)
+	}
+	d, err := scaleInt(whole, time.Second)
+	if err != nil {
+		return 0, err
+	}
+	if len(frac) > 9 {
+		return 0, errors.New("seconds have at most nine decimal places")
+	}
+	if frac != "" {
+		ns, err := strconv.ParseInt(frac+strings.Repeat("0", 9-len(frac)), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a number of seconds", s)
+		}
+		if d > math.MaxInt64-time.Duration(ns) {
+			return 0, errors.New("too long")
+		}
+		d += time.Duration(ns)
+	}
+	return d, nil
+}
+
+// parseGoDuration parses a Go duration string, or the "30d" and "2w" day
+// and week shorthands the API has always accepted.
+func parseGoDuration(s string) (time.Duration, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, nil
+	}
+	if n := len(s) - 1; n > 0 && isDecimal(s[:n]) && !strings.Contains(s[:n], ".") {
+		switch s[n] {
+		case 'd':
+			return scaleInt(s[:n], day)
+		case 'w':
+			return scaleInt(s[:n], week)
+		}
+	}
+	return 0, errors.New("use an ISO 8601 duration such as PT30M or P7D, a number of seconds, or a Go duration such as 30m")
+}
+
+// scaleInt parses s as a whole number of unit.
+func scaleInt(s string, unit time.Duration) (time.Duration, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a whole number", s)
+	}
+	if n > int64(math.MaxInt64/unit) {
+		return 0, errors.New("too long")
+	}
+	return time.Duration(n) * unit, nil
+}
+
+func isDecimal(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if (s[i] < '0' || s[i] > '9') && s[i] != '.' {
+			return false
+		}
+	}
+	return true
+}
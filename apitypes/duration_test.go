@@ -0,0 +1,216 @@
+package apitypes
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"", 0},
+
+		// ISO 8601.
+		{"PT0S", 0},
+		{"PT30M", 30 * time.Minute},
+		{"PT1H30M", 90 * time.Minute},
+		{"P1D", 24 * time.Hour},
+		{"P30D", 30 * 24 * time.Hour},
+		{"P2W", 14 * 24 * time.Hour},
+		{"P1DT12H", 36 * time.Hour},
+		{"PT1.5S", 1500 * time.Millisecond},
+		{"PT0,25S", 250 * time.Millisecond},
+		{"pt5m", 5 * time.Minute},
+
+		// Seconds.
+		{"0", 0},
+		{"3600", time.Hour},
+		{"0.02", 20 * time.Millisecond},
+
+		// Go durations, as earlier versions wrote them, and the day and
+		// week shorthands.
+		{"24h0m0s", 24 * time.Hour},
+		{"1m0s", time.Minute},
+		{"20ms", 20 * time.Millisecond},
+		{"1.5h", 90 * time.Minute},
+		{"90d", 90 * 24 * time.Hour},
+		{"2w", 14 * 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseDuration(tt.in)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, time.Duration(got))
+		})
+	}
+}
+
+func TestParseDuration_Invalid(t *testing.T) {
+	tests := []struct {
+		in, msg string
+	}{
+		{"soon", "use an ISO 8601 duration"},
+		{"-5m", "must not be negative"},
+		{"-PT5M", "must not be negative"},
+		{"P", "no components"},
+		{"PT", "no components"},
+		{"P1Y", "years and months"},
+		{"P1M", "years and months"},
+		{"PT1D", "unexpected D"},
+		{"PT5M1H", "unexpected H"},
+		{"P1DT2HT3M", "T appears twice"},
+		{"PT1.5M", "not a whole number"},
+		{"P5", "number followed by a designator"},
+		{"PT0.0000000001S", "nine decimal places"},
+		{"P999999999999D", "too long"},
+		{"1.", "not a number of seconds"},
+		{"1.5d", "use an ISO 8601 duration"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			_, err := ParseDuration(tt.in)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.msg)
+			assert.Contains(t, err.Error(), tt.in, "the error names the value")
+		})
+	}
+}
+
+func TestDuration_String(t *testing.T) {
+	tests := []struct {
+		in   time.Duration
+		want string
+	}{
+		{0, "PT0S"},
+		{time.Second, "PT1S"},
+		{1500 * time.Millisecond, "PT1.5S"},
+		{20 * time.Millisecond, "PT0.02S"},
+		{time.Nanosecond, "PT0.000000001S"},
+		{time.Minute, "PT1M"},
+		{90 * time.Minute, "PT1H30M"},
+		{24 * time.Hour, "P1D"},
+		{36 * time.Hour, "P1DT12H"},
+		{90 * 24 * time.Hour, "P90D"},
+		{24*time.Hour + time.Second, "P1DT1S"},
+		{-time.Minute, "-PT1M"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, Duration(tt.in).String())
+			if tt.in < 0 {
+				return
+			}
+			back, err := ParseDuration(tt.want)
+			require.NoError(t, err)
+			assert.Equal(t, tt.in, time.Duration(back), "String output parses back")
+		})
+	}
+}
+
+func TestDuration_JSON(t *testing.T) {
+	b, err := json.Marshal(struct {
+		D Duration  `json:"d"`
+		P *Duration `json:"p,omitempty"`
+	}{D: Duration(24 * time.Hour)})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"d": "P1D"}`, string(b))
+
+	tests := []struct {
+		body string
+		want time.Duration
+	}{
+		{`{"d": "PT1M"}`, time.Minute},
+		{`{"d": 60}`, time.Minute},
+		{`{"d": 0.5}`, 500 * time.Millisecond},
+		{`{"d": "60"}`, time.Minute},
+		{`{"d": "1m0s"}`, time.Minute},
+		{`{"d": ""}`, 0},
+		{`{"d": null}`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			var v struct {
+				D Duration `json:"d"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(tt.body), &v))
+			assert.Equal(t, tt.want, time.Duration(v.D))
+		})
+	}
+
+	for _, body := range []string{`{"d": "soon"}`, `{"d": -1}`, `{"d": true}`, `{"d": 1e3}`} {
+		var v struct {
+			D Duration `json:"d"`
+		}
+		assert.Error(t, json.Unmarshal([]byte(body), &v), body)
+	}
+}
+
+func TestDuration_RequestFields(t *testing.T) {
+	// Every request field holding a duration reads all three forms.
+	forms := map[string]string{"iso": `"PT1H"`, "seconds": `3600`, "go": `"1h0m0s"`}
+	tests := []struct {
+		field string
+		got   func(body []byte) (time.Duration, error)
+	}{
+		{"rate_limit_window", policyField(func(r *CreatePolicyRequest) Duration { return r.RateLimitWindow })},
+		{"max_key_lifetime", policyField(func(r *CreatePolicyRequest) Duration { return r.MaxKeyLifetime })},
+		{"rotation_period", policyField(func(r *CreatePolicyRequest) Duration { return r.RotationPeriod })},
+		{"grace_period", policyField(func(r *CreatePolicyRequest) Duration { return r.GracePeriod })},
+		{"duration", func(body []byte) (time.Duration, error) {
+			var r SetKeyDebugRequest
+			err := json.Unmarshal(body, &r)
+			return time.Duration(r.Duration), err
+		}},
+		{"rate_limit_window", func(body []byte) (time.Duration, error) {
+			var r UpdateTenantSettingsRequest
+			err := json.Unmarshal(body, &r)
+			return time.Duration(r.RateLimitWindow), err
+		}},
+		{"validation_cache_ttl", runtimeField(func(r *UpdateRuntimeConfigRequest) *Duration { return r.ValidationCacheTTL })},
+		{"endpoint_flush_interval", runtimeField(func(r *UpdateRuntimeConfigRequest) *Duration { return r.EndpointFlushInterval })},
+		{"capture_purge_interval", runtimeField(func(r *UpdateRuntimeConfigRequest) *Duration { return r.CapturePurgeInterval })},
+		{"maintenance_interval", runtimeField(func(r *UpdateRuntimeConfigRequest) *Duration { return r.MaintenanceInterval })},
+		{"quota_forecast_interval", runtimeField(func(r *UpdateRuntimeConfigRequest) *Duration { return r.QuotaForecastInterval })},
+		{"failure_window", runtimeField(func(r *UpdateRuntimeConfigRequest) *Duration { return r.FailureWindow })},
+	}
+	for _, tt := range tests {
+		for form, v := range forms {
+			t.Run(tt.field+"/"+form, func(t *testing.T) {
+				got, err := tt.got([]byte(`{"` + tt.field + `": ` + v + `}`))
+				require.NoError(t, err)
+				assert.Equal(t, time.Hour, got)
+			})
+		}
+		t.Run(tt.field+"/invalid", func(t *testing.T) {
+			_, err := tt.got([]byte(`{"` + tt.field + `": "an hour"}`))
+			assert.ErrorContains(t, err, `invalid duration "an hour"`)
+		})
+	}
+}
+
+func policyField(get func(*CreatePolicyRequest) Duration) func([]byte) (time.Duration, error) {
+	return func(body []byte) (time.Duration, error) {
+		var r CreatePolicyRequest
+		err := json.Unmarshal(body, &r)
+		return time.Duration(get(&r)), err
+	}
+}
+
+func runtimeField(get func(*UpdateRuntimeConfigRequest) *Duration) func([]byte) (time.Duration, error) {
+	return func(body []byte) (time.Duration, error) {
+		var r UpdateRuntimeConfigRequest
+		if err := json.Unmarshal(body, &r); err != nil {
+			return 0, err
+		}
+		if get(&r) == nil {
+			return 0, nil
+		}
+		return time.Duration(*get(&r)), nil
+	}
+}
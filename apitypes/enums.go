@@ -0,0 +1,177 @@
+package apitypes
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/rotation"
+)
+
+// KeyState is a key's lifecycle state in a request or response. Its values
+// are those of key.State.
+type KeyState string
+
+// Key states.
+const (
+	KeyStateActive    = KeyState(key.StateActive)
+	KeyStateRotated   = KeyState(key.StateRotated)
+	KeyStateExpired   = KeyState(key.StateExpired)
+	KeyStateRevoked   = KeyState(key.StateRevoked)
+	KeyStateSuspended = KeyState(key.StateSuspended)
+)
+
+// KeyEnvironment is a key's environment in a request or response. Its
+// values are those of key.Environment.
+type KeyEnvironment string
+
+// Key environments.
+const (
+	KeyEnvironmentLive    = KeyEnvironment(key.EnvLive)
+	KeyEnvironmentTest    = KeyEnvironment(key.EnvTest)
+	KeyEnvironmentStaging = KeyEnvironment(key.EnvStaging)
+)
+
+// RotationReason is why a key was rotated, in a request or response. Its
+// values are those of rotation.Reason. Responses return the reason as it
+// was stored, which for rotations recorded before reasons were validated
+// may be none of them.
+type RotationReason string
+
+// Rotation reasons.
+const (
+	RotationReasonScheduled  = RotationReason(rotation.ReasonScheduled)
+	RotationReasonManual     = RotationReason(rotation.ReasonManual)
+	RotationReasonCompromise = RotationReason(rotation.ReasonCompromise)
+	RotationReasonPolicy     = RotationReason(rotation.ReasonPolicy)
+	RotationReasonAdmin      = RotationReason(rotation.ReasonAdmin)
+	RotationReasonAnomaly    = RotationReason(rotation.ReasonAnomaly)
+	RotationReasonImport     = RotationReason(rotation.ReasonImport)
+)
+
+// EnumError reports a request value that is none of its enum's values.
+type EnumError struct {
+	// Enum names the enum, such as "key state".
+	Enum string
+
+	// Value is the rejected value, after normalization.
+	Value string
+
+	// Allowed lists the enum's values.
+	Allowed []string
+}
+
+// Error names the value and the values allowed instead.
+func (e *EnumError) Error() string {
+	return fmt.Sprintf("%q is not a %s; allowed values are %s", e.Value, e.Enum, strings.Join(e.Allowed, ", "))
+}
+
+// Enum values are read case-insensitively: UnmarshalText trims and
+// lowercases them, so "Live" and " LIVE" both decode to live. Decoding
+// never fails; Validate reports a value that is still unknown, so the server
+// can answer with the allowed values rather than a generic decoding error.
+
+// KeyStates returns every key state.
+func KeyStates() []KeyState {
+	states := key.States()
+	out := make([]KeyState, len(states))
+	for i, s := range states {
+		out[i] = KeyState(s)
+	}
+	return out
+}
+
+// EnumValues lists the key states for the OpenAPI document.
+func (KeyState) EnumValues() []any { return enumValues(KeyStates()) }
+
+// Validate reports whether s is empty or a key state.
+func (s KeyState) Validate() error { return validateEnum("key state", s, KeyStates()) }
+
+// MarshalText writes s as it is.
+func (s KeyState) MarshalText() ([]byte, error) { return []byte(s), nil }
+
+// UnmarshalText reads a state in any case.
+func (s *KeyState) UnmarshalText(text []byte) error {
+	*s = KeyState(normalizeEnum(text))
+	return nil
+}
+
+// KeyEnvironments returns every key environment.
+func KeyEnvironments() []KeyEnvironment {
+	envs := key.Environments()
+	out := make([]KeyEnvironment, len(envs))
+	for i, e := range envs {
+		out[i] = KeyEnvironment(e)
+	}
+	return out
+}
+
+// EnumValues lists the key environments for the OpenAPI document.
+func (KeyEnvironment) EnumValues() []any { return enumValues(KeyEnvironments()) }
+
+// Validate reports whether e is empty or a key environment.
+func (e KeyEnvironment) Validate() error {
+	return validateEnum("key environment", e, KeyEnvironments())
+}
+
+// MarshalText writes e as it is.
+func (e KeyEnvironment) MarshalText() ([]byte, error) { return []byte(e), nil }
+
+// UnmarshalText reads an environment in any case.
+func (e *KeyEnvironment) UnmarshalText(text []byte) error {
+	*e = KeyEnvironment(normalizeEnum(text))
+	return nil
+}
+
+// RotationReasons returns every rotation reason.
+func RotationReasons() []RotationReason {
+	reasons := rotation.Reasons()
+	out := make([]RotationReason, len(reasons))
+	for i, r := range reasons {
+		out[i] = RotationReason(r)
+	}
+	return out
+}
+
+// EnumValues lists the rotation reasons for the OpenAPI document.
+func (RotationReason) EnumValues() []any { return enumValues(RotationReasons()) }
+
+// Validate reports whether r is empty or a rotation reason.
+func (r RotationReason) Validate() error {
+	return validateEnum("rotation reason", r, RotationReasons())
+}
+
+// MarshalText writes r as it is.
+func (r RotationReason) MarshalText() ([]byte, error) { return []byte(r), nil }
+
+// UnmarshalText reads a reason in any case.
+func (r *RotationReason) UnmarshalText(text []byte) error {
+	*r = RotationReason(normalizeEnum(text))
+	return nil
+}
+
+func normalizeEnum(text []byte) string {
+	return strings.ToLower(strings.TrimSpace(string(text)))
+}
+
+func enumValues[T ~string](values []T) []any {
+	out := make([]any, len(values))
+	for i, v := range values {
+		out[i] = string(v)
+	}
+	return out
+}
+
+func validateEnum[T ~string](enum string, v T, allowed []T) error {
+	if v == "" {
+		return nil
+	}
+	names := make([]string, len(allowed))
+	for i, a := range allowed {
+		if a == v {
+			return nil
+		}
+		names[i] = string(a)
+	}
+	return &EnumError{Enum: enum, Value: string(v), Allowed: names}
+}
@@ -0,0 +1,106 @@
+package apitypes
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/rotation"
+)
+
+func TestEnums_MatchDomain(t *testing.T) {
+	for _, s := range key.States() {
+		assert.NoError(t, KeyState(s).Validate(), s)
+	}
+	for _, e := range key.Environments() {
+		assert.NoError(t, KeyEnvironment(e).Validate(), e)
+	}
+	for _, r := range rotation.Reasons() {
+		assert.NoError(t, RotationReason(r).Validate(), r)
+	}
+	assert.Len(t, KeyStateActive.EnumValues(), len(key.States()))
+	assert.Len(t, KeyEnvironmentLive.EnumValues(), len(key.Environments()))
+	assert.Len(t, RotationReasonManual.EnumValues(), len(rotation.Reasons()))
+}
+
+func TestEnums_Decode(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		decode  func([]byte) (string, error)
+		want    string
+		invalid string
+	}{
+		{"environment", `"Live"`, decodeEnum[KeyEnvironment], "live", ""},
+		{"environment upper", `"LIVE"`, decodeEnum[KeyEnvironment], "live", ""},
+		{"environment padded", `" staging "`, decodeEnum[KeyEnvironment], "staging", ""},
+		{"environment unknown", `"Prod"`, decodeEnum[KeyEnvironment], "prod", `"prod" is not a key environment; allowed values are live, test, staging`},
+		{"state", `"SUSPENDED"`, decodeEnum[KeyState], "suspended", ""},
+		{"state unknown", `"pending"`, decodeEnum[KeyState], "pending", `"pending" is not a key state; allowed values are active, rotated, expired, revoked, suspended`},
+		{"reason", `"Automated_Anomaly"`, decodeEnum[RotationReason], "automated_anomaly", ""},
+		{"reason unknown", `"leaked"`, decodeEnum[RotationReason], "leaked", `"leaked" is not a rotation reason; allowed values are scheduled, manual, compromise, policy, admin, automated_anomaly, import`},
+		{"empty", `""`, decodeEnum[KeyState], "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.decode([]byte(tt.body))
+			assert.Equal(t, tt.want, got, "values decode normalized")
+			if tt.invalid == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var enumErr *EnumError
+			require.True(t, errors.As(err, &enumErr))
+			assert.Equal(t, tt.invalid, enumErr.Error())
+			assert.Equal(t, tt.want, enumErr.Value)
+		})
+	}
+}
+
+// decodeEnum decodes body as T and returns the value and its Validate
+// error.
+func decodeEnum[T interface {
+	~string
+	Validate() error
+}](body []byte) (string, error) {
+	var v T
+	if err := json.Unmarshal(body, &v); err != nil {
+		return "", err
+	}
+	return string(v), v.Validate()
+}
+
+func TestEnums_RequestFields(t *testing.T) {
+	var create CreateKeyRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"environment": "TEST"}`), &create))
+	assert.Equal(t, KeyEnvironmentTest, create.Environment)
+
+	var revoke RevokeByLabelsRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"environment": "Staging"}`), &revoke))
+	assert.Equal(t, KeyEnvironmentStaging, revoke.Environment)
+
+	var rotate RotateKeyRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"reason": "Compromise"}`), &rotate))
+	assert.Equal(t, RotationReasonCompromise, rotate.Reason)
+
+	// Query parameters are read through UnmarshalText.
+	var state KeyState
+	require.NoError(t, state.UnmarshalText([]byte("Revoked")))
+	assert.Equal(t, KeyStateRevoked, state)
+}
+
+func TestEnums_MapKeys(t *testing.T) {
+	b, err := json.Marshal(KeyOverviewResponse{
+		ByState:       map[KeyState]int64{KeyStateActive: 2},
+		ByEnvironment: map[KeyEnvironment]int64{KeyEnvironmentLive: 2},
+	})
+	require.NoError(t, err)
+	var got KeyOverviewResponse
+	require.NoError(t, json.Unmarshal(b, &got))
+	assert.Equal(t, int64(2), got.ByState[KeyStateActive])
+	assert.Equal(t, int64(2), got.ByEnvironment[KeyEnvironmentLive])
+}
@@ -0,0 +1,753 @@
+package apitypes
+
+import (
+	"time"
+
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/metaschema"
+	"github.com/xraph/keysmith/usage"
+)
+
+// ── Key DTOs ──────────────────────────────────────
+
+// CreateKeyRequest is the request for creating an API key.
+type CreateKeyRequest struct {
+	Name        string         `json:"name" description:"Human-readable key name"`
+	Description string         `json:"description" optional:"true" description:"Optional description"`
+	Prefix      string         `json:"prefix" description:"Key prefix (e.g., sk, pk)"`
+	Environment KeyEnvironment `json:"environment" description:"Environment (live, test, staging), in any case"`
+	PolicyID    string         `json:"policy_id" optional:"true" description:"Optional policy ID to attach"`
+	PoolID      string         `json:"pool_id" optional:"true" description:"Optional quota pool to join; its quotas replace the policy's"`
+	Scopes      []string       `json:"scopes" description:"Permission scopes to assign"`
+	Metadata    map[string]any `json:"metadata" description:"Arbitrary metadata"`
+	ExpiresAt   *time.Time     `json:"expires_at" description:"Optional expiration time"`
+
+	AllowedOrigins []string `json:"allowed_origins" description:"Browser origins the key may be used from, e.g. https://*.example.com; overrides the policy's list"`
+
+	IntendedConsumer string `json:"intended_consumer" optional:"true" description:"Service the key is issued to, e.g. billing-worker"`
+	EnforceConsumer  bool   `json:"enforce_consumer" description:"Reject validations from other services instead of flagging them"`
+
+	CertFingerprint string `json:"cert_fingerprint,omitempty" description:"SHA-256 fingerprint, in hex, of the only client certificate the key may be presented with"`
+
+	Flags []string `json:"flags" description:"Per-key validation exceptions: skip_origin_check, skip_ip_check, extended_grace_eligible; skip flags need an admin context"`
+
+	Contacts key.Contacts `json:"contacts" description:"Where lifecycle notifications about the key go, each with a type (email, webhook, slack) and target; replaces the tenant's default contacts"`
+
+	Labels key.Labels `json:"labels" description:"Indexed name=value pairs for selecting keys, e.g. {\"team\": \"payments\"}"`
+
+	AcceptedTermsVersion string `json:"accepted_terms_version" optional:"true" description:"Terms of service version the key's holder accepted; required to match when the server tracks terms"`
+
+	SkipDefaultScopes bool `json:"skip_default_scopes" description:"Do not grant the tenant's default scopes"`
+
+	ExternalRef string `json:"external_ref" optional:"true" description:"Your own identifier for the key, unique in the tenant; creating a key with a reference already used returns the existing key"`
+
+	InlinePolicy *InlinePolicyRequest `json:"inline_policy,omitempty" description:"Limits of the key's own policy, instead of policy_id; it is not listed with the shared policies and is deleted with the key"`
+}
+
+// InlinePolicyRequest holds the fields a key's inline policy may set.
+type InlinePolicyRequest struct {
+	RateLimit       int      `json:"rate_limit" description:"Max requests per window"`
+	RateLimitWindow Duration `json:"rate_limit_window" optional:"true" description:"Window duration (e.g., PT1M, PT1H)"`
+	BurstLimit      int      `json:"burst_limit" description:"Burst allowance"`
+	AllowedIPs      []string `json:"allowed_ips" description:"IP allowlist (CIDR)"`
+	AllowedOrigins  []string `json:"allowed_origins" description:"Origin allowlist"`
+	DailyQuota      int64    `json:"daily_quota" description:"Max requests per day (0 = unlimited)"`
+	MonthlyQuota    int64    `json:"monthly_quota" description:"Max requests per month (0 = unlimited)"`
+	QuotaTimezone   string   `json:"quota_timezone,omitempty" description:"IANA time zone quota days and months are counted in (e.g., America/New_York); empty for the tenant's, else UTC"`
+}
+
+// ListKeysRequest is the request for listing keys.
+type ListKeysRequest struct {
+	AppID         string         `query:"app_id" optional:"true" description:"Filter by app; ignored when the caller is scoped to an app"`
+	Environment   KeyEnvironment `query:"environment" optional:"true" description:"Filter by environment (live, test, staging)"`
+	State         KeyState       `query:"state" optional:"true" description:"Filter by state (active, rotated, expired, revoked, suspended)"`
+	PolicyID      string         `query:"policy_id" optional:"true" description:"Filter by policy ID"`
+	CreatedBy     string         `query:"created_by" optional:"true" description:"Filter by the user or service that created the key"`
+	ExternalRef   string         `query:"external_ref" optional:"true" description:"Filter by external reference"`
+	CreatedAfter  string         `query:"created_after" optional:"true" description:"Only keys created after this RFC 3339 time"`
+	CreatedBefore string         `query:"created_before" optional:"true" description:"Only keys created before this RFC 3339 time"`
+	UpdatedAfter  string         `query:"updated_after" optional:"true" description:"Only keys changed after this RFC 3339 time, for incremental syncs"`
+	OutdatedTerms string         `query:"outdated_terms" optional:"true" description:"Only keys that did not accept this terms version"`
+	LabelSelector string         `query:"label_selector" optional:"true" description:"Only keys whose labels match, e.g. team=payments,env in (staging,prod)"`
+	GroupID       string         `query:"group_id" optional:"true" description:"Only keys in this group"`
+	PoolID        string         `query:"pool_id" optional:"true" description:"Only keys in this quota pool"`
+	Limit         int            `query:"limit" optional:"true" description:"Max results (default: 50, max: 1000)"`
+	Offset        int            `query:"offset" optional:"true" description:"Number of results to skip"`
+	Fields        string         `query:"fields" optional:"true" description:"Comma-separated fields to return, e.g. id,name,metadata.plan (default: all)"`
+}
+
+// KeyOverviewRequest is the request for a tenant's key counts by lifecycle.
+type KeyOverviewRequest struct {
+	TenantID           string `query:"tenant_id" optional:"true" description:"Restrict to one tenant; ignored when the caller is scoped to a tenant"`
+	AppID              string `query:"app_id" optional:"true" description:"Filter by app; ignored when the caller is scoped to an app"`
+	ExpiringWithinDays string `query:"expiring_within_days" optional:"true" description:"Comma-separated windows, in ascending days, to count active keys expiring within (default: 7,30,90)"`
+	CreatedWithinDays  int    `query:"created_within_days" optional:"true" description:"Window, in days, to count recently created keys over (default: 30)"`
+}
+
+// SearchRequest is the request for a full-text search of the caller's
+// resources.
+type SearchRequest struct {
+	Query    string `query:"q" description:"Words and double-quoted phrases, all of which must match; words match whole and ignoring case"`
+	TenantID string `query:"tenant_id" optional:"true" description:"Tenant to search; ignored when the caller is scoped to a tenant, and required when it is not"`
+	AppID    string `query:"app_id" optional:"true" description:"Filter by app; ignored when the caller is scoped to an app"`
+	Limit    int    `query:"limit" optional:"true" description:"Maximum number of matches per resource type (default: 20)"`
+}
+
+// ListDuplicateKeyNamesRequest is the request for the key names shared by
+// more than one key.
+type ListDuplicateKeyNamesRequest struct {
+	TenantID string `query:"tenant_id" optional:"true" description:"Restrict to one tenant; ignored when the caller is scoped to a tenant"`
+}
+
+// GetKeyRequest is the request for fetching a single key.
+type GetKeyRequest struct {
+	KeyID  string `path:"keyId" example:"akey_01m4wms908fh99berht8ytte6h" description:"Key ID"`
+	Fields string `query:"fields" optional:"true" description:"Comma-separated fields to return, e.g. id,name,metadata.plan (default: all)"`
+}
+
+// UpdateKeyRequest is the request for updating a key. Omitted fields are
+// left unchanged.
+type UpdateKeyRequest struct {
+	KeyID          string         `path:"keyId" example:"akey_01m4wms908fh99berht8ytte6h" description:"Key ID"`
+	Name           *string        `json:"name,omitempty" description:"Human-readable key name"`
+	Description    *string        `json:"description,omitempty" description:"Description"`
+	Metadata       map[string]any `json:"metadata,omitempty" description:"Replacement metadata"`
+	AllowedOrigins []string       `json:"allowed_origins,omitempty" description:"Replacement origin allowlist; an empty list falls back to the policy's"`
+	DryRun         bool           `query:"dry_run" optional:"true" description:"Validate and report the outcome without making changes"`
+
+	IntendedConsumer *string `json:"intended_consumer,omitempty" description:"Replacement intended consumer service; empty clears it"`
+	EnforceConsumer  *bool   `json:"enforce_consumer,omitempty" description:"Reject validations from other services instead of flagging them"`
+	CertFingerprint  *string `json:"cert_fingerprint,omitempty" description:"Replacement pinned client certificate fingerprint; empty unpins the key"`
+
+	Flags []string `json:"flags,omitempty" description:"Replacement flags; an empty list clears them"`
+
+	Labels key.Labels `json:"labels,omitempty" description:"Replacement labels; an empty object clears them"`
+
+	InlinePolicy *InlinePolicyRequest `json:"inline_policy,omitempty" description:"Replacement limits of the key's inline policy; keys with a shared policy cannot set it"`
+}
+
+// RevokeByLabelsRequest is the request for revoking every key whose labels
+// match a selector.
+type RevokeByLabelsRequest struct {
+	LabelSelector string         `json:"label_selector" description:"Label selector the keys must match, e.g. team=payments; required"`
+	Environment   KeyEnvironment `json:"environment,omitempty" description:"Restrict to one environment (live, test, staging)"`
+	AppID         string         `json:"app_id,omitempty" description:"Restrict to one app; ignored when the caller is scoped to an app"`
+	Reason        string         `json:"reason" description:"Revocation reason recorded on each revoked key"`
+	DryRun        bool           `query:"dry_run" optional:"true" description:"Validate and report the outcome without making changes"`
+}
+
+// DeleteKeyRequest is the request for deleting a key.
+type DeleteKeyRequest struct {
+	KeyID  string `path:"keyId" example:"akey_01m4wms908fh99berht8ytte6h" description:"Key ID"`
+	DryRun bool   `query:"dry_run" optional:"true" description:"Validate and report the outcome without making changes"`
+}
+
+// RotateKeyRequest is the request for rotating a key.
+type RotateKeyRequest struct {
+	KeyID  string         `path:"keyId" example:"akey_01m4wms908fh99berht8ytte6h" description:"Key ID to rotate"`
+	Reason RotationReason `json:"reason" optional:"true" description:"Rotation reason (scheduled, manual, compromise, policy, admin, automated_anomaly, import; default: manual)"`
+	DryRun bool           `query:"dry_run" optional:"true" description:"Validate and report the outcome without making changes"`
+}
+
+// RevokeKeyRequest is the request for revoking a key.
+type RevokeKeyRequest struct {
+	KeyID  string `path:"keyId" example:"akey_01m4wms908fh99berht8ytte6h" description:"Key ID to revoke"`
+	Reason string `json:"reason" description:"Revocation reason"`
+	DryRun bool   `query:"dry_run" optional:"true" description:"Validate and report the outcome without making changes"`
+}
+
+// ValidateKeyRequest is the request for validating a raw key.
+type ValidateKeyRequest struct {
+	RawKey string `json:"raw_key" description:"The raw API key to validate"`
+	Origin string `json:"origin,omitempty" description:"Origin the key is presented from, checked against its allowed origins"`
+
+	ConsumerService string `json:"consumer_service,omitempty" description:"Service presenting the key, checked against its intended consumer"`
+
+	TLSVersion            string `json:"tls_version,omitempty" description:"TLS version the key was presented over (e.g., 1.3); empty for plain HTTP"`
+	ClientCertFingerprint string `json:"client_cert_fingerprint,omitempty" description:"SHA-256 fingerprint, in hex, of the client certificate the key was presented with"`
+
+	Method     string            `json:"method,omitempty" description:"HTTP method of the request the key was presented with, passed to the external authorizer"`
+	Path       string            `json:"path,omitempty" description:"Path of the request the key was presented with, passed to the external authorizer"`
+	RemoteIP   string            `json:"remote_ip,omitempty" description:"Client IP address of the request, passed to the external authorizer"`
+	Attributes map[string]string `json:"attributes,omitempty" description:"Caller facts about the request, such as country or risk score, passed to the external authorizer"`
+
+	Nonce     string `json:"nonce,omitempty" description:"Unique value for this request, required when replay protection is enabled"`
+	Timestamp string `json:"timestamp,omitempty" description:"RFC 3339 time the request was made, required when replay protection is enabled"`
+}
+
+// ValidateKeyHeaderRequest is the request for validating the key a request
+// carries in its headers. One of the two headers is required; the
+// Authorization header wins when both are set.
+type ValidateKeyHeaderRequest struct {
+	Authorization string `header:"Authorization" optional:"true" description:"Bearer followed by the raw API key"`
+	APIKey        string `header:"X-API-Key" optional:"true" description:"The raw API key"`
+}
+
+// ListSuspiciousFingerprintsRequest is the request for listing the top
+// validation-failure fingerprints.
+type ListSuspiciousFingerprintsRequest struct {
+	Limit int `query:"limit" optional:"true" description:"Max results (default: 50, max: 1000)"`
+}
+
+// GetIntegrationGuideRequest is the request for the caller tenant's
+// integration guide.
+type GetIntegrationGuideRequest struct {
+	Format string `query:"format" optional:"true" description:"Response format: json (default) or markdown"`
+}
+
+// GetSLORequest is the request for the validation SLO status.
+type GetSLORequest struct{}
+
+// GetReplayStatsRequest is the request for the replay protection counters.
+type GetReplayStatsRequest struct{}
+
+// SuspendKeyRequest is the request for suspending a key.
+type SuspendKeyRequest struct {
+	KeyID  string `path:"keyId" example:"akey_01m4wms908fh99berht8ytte6h" description:"Key ID"`
+	DryRun bool   `query:"dry_run" optional:"true" description:"Validate and report the outcome without making changes"`
+}
+
+// ReactivateKeyRequest is the request for reactivating a key.
+type ReactivateKeyRequest struct {
+	KeyID string `path:"keyId" example:"akey_01m4wms908fh99berht8ytte6h" description:"Key ID"`
+}
+
+// ReportCompromiseRequest is the request for reporting a leaked key.
+type ReportCompromiseRequest struct {
+	KeyID   string `path:"keyId" example:"akey_01m4wms908fh99berht8ytte6h" description:"Key ID"`
+	Source  string `json:"source" description:"Where the leak was discovered (e.g., github-secret-scanning, customer)"`
+	Details string `json:"details" optional:"true" description:"Free-form details about the leak"`
+	Action  string `json:"action" description:"Remediation: revoke, or rotate (no grace period)"`
+	DryRun  bool   `query:"dry_run" optional:"true" description:"Validate and report the outcome without making changes"`
+}
+
+// ── Policy DTOs ───────────────────────────────────
+
+// CreatePolicyRequest is the request for creating a policy.
+type CreatePolicyRequest struct {
+	Name            string   `json:"name" description:"Policy name"`
+	Description     string   `json:"description" optional:"true" description:"Optional description"`
+	BasePolicyID    string   `json:"base_policy_id" optional:"true" description:"Optional policy to inherit limits and restrictions from"`
+	RateLimit       int      `json:"rate_limit" description:"Max requests per window"`
+	RateLimitWindow Duration `json:"rate_limit_window" optional:"true" description:"Window duration (e.g., PT1M, PT1H)"`
+	BurstLimit      int      `json:"burst_limit" description:"Burst allowance"`
+	AllowedScopes   []string `json:"allowed_scopes" description:"Scopes this policy grants"`
+	AllowedIPs      []string `json:"allowed_ips" description:"IP allowlist (CIDR)"`
+	AllowedOrigins  []string `json:"allowed_origins" description:"Origin allowlist"`
+	MaxKeyLifetime  Duration `json:"max_key_lifetime" optional:"true" description:"Max key lifetime (e.g., P90D)"`
+	RotationPeriod  Duration `json:"rotation_period" optional:"true" description:"Suggested rotation period (e.g., P30D)"`
+	GracePeriod     Duration `json:"grace_period" optional:"true" description:"Rotated key grace period (e.g., P1D)"`
+	DailyQuota      int64    `json:"daily_quota" description:"Max requests per day (0 = unlimited)"`
+	MonthlyQuota    int64    `json:"monthly_quota" description:"Max requests per month (0 = unlimited)"`
+	QuotaTimezone   string   `json:"quota_timezone,omitempty" description:"IANA time zone quota days and months are counted in (e.g., America/New_York); empty for the tenant's, else UTC"`
+	MinTLSVersion   string   `json:"min_tls_version,omitempty" description:"Lowest TLS version keys may be presented over (1.0 to 1.3); empty for no minimum"`
+	RequireMTLS     bool     `json:"require_mtls,omitempty" description:"Require a client certificate on every validation"`
+}
+
+// CreatePolicyFromTemplateRequest is the request for creating a policy from
+// a named template.
+type CreatePolicyFromTemplateRequest struct {
+	Template  string               `json:"template" description:"Template name (e.g., unlimited, standard, strict)"`
+	Overrides *CreatePolicyRequest `json:"overrides,omitempty" description:"Fields to override; zero values keep the template value"`
+	Clear     []string             `json:"clear,omitempty" description:"Fields to reset to zero (e.g., daily_quota)"`
+}
+
+// UpdatePolicyRequest is the request for updating a policy. The json tag
+// on the embedded request makes the binder decode the body; without one it
+// sees only the path parameter.
+type UpdatePolicyRequest struct {
+	PolicyID            string `path:"policyId" example:"kpol_01m4wms908fha80h1h4fmq1kpn" description:"Policy ID"`
+	CreatePolicyRequest `json:",inline"`
+}
+
+// ListPoliciesRequest is the request for listing policies.
+type ListPoliciesRequest struct {
+	AppID         string `query:"app_id" optional:"true" description:"Filter by app; ignored when the caller is scoped to an app"`
+	CreatedAfter  string `query:"created_after" optional:"true" description:"Only policies created after this RFC 3339 time"`
+	CreatedBefore string `query:"created_before" optional:"true" description:"Only policies created before this RFC 3339 time"`
+	UpdatedAfter  string `query:"updated_after" optional:"true" description:"Only policies changed after this RFC 3339 time, for incremental syncs"`
+	IncludeInline bool   `query:"include_inline" optional:"true" description:"Also return the inline policies of single keys"`
+	Limit         int    `query:"limit" optional:"true" description:"Max results (default: 50, max: 1000)"`
+	Offset        int    `query:"offset" optional:"true" description:"Number of results to skip"`
+}
+
+// GetPolicyRequest is the request for fetching a single policy.
+type GetPolicyRequest struct {
+	PolicyID string `path:"policyId" example:"kpol_01m4wms908fha80h1h4fmq1kpn" description:"Policy ID"`
+}
+
+// GetEffectivePolicyRequest is the request for fetching a policy merged with
+// the policies it inherits from.
+type GetEffectivePolicyRequest struct {
+	PolicyID string `path:"policyId" example:"kpol_01m4wms908fha80h1h4fmq1kpn" description:"Policy ID"`
+}
+
+// DeletePolicyRequest is the request for deleting a policy.
+type DeletePolicyRequest struct {
+	PolicyID string `path:"policyId" example:"kpol_01m4wms908fha80h1h4fmq1kpn" description:"Policy ID"`
+}
+
+// ── Scope DTOs ────────────────────────────────────
+
+// CreateScopeRequest is the request for creating a scope.
+type CreateScopeRequest struct {
+	Name            string   `json:"name" description:"Scope name (e.g., read:users)"`
+	Description     string   `json:"description" optional:"true" description:"Optional description"`
+	Parent          string   `json:"parent" optional:"true" description:"Parent scope (e.g., read)"`
+	RateLimit       int      `json:"rate_limit" optional:"true" description:"Max requests per window each key may make under this scope, on top of its own limit (0 = no scope limit)"`
+	RateLimitWindow Duration `json:"rate_limit_window" optional:"true" description:"Scope limit window (e.g., PT1M); required with rate_limit"`
+}
+
+// ListScopesRequest is the request for listing scopes.
+type ListScopesRequest struct {
+	AppID        string `query:"app_id" optional:"true" description:"Filter by app; ignored when the caller is scoped to an app"`
+	Parent       string `query:"parent" optional:"true" description:"Filter by parent scope"`
+	CreatedAfter string `query:"created_after" optional:"true" description:"Only scopes created after this RFC 3339 time"`
+	Limit        int    `query:"limit" optional:"true" description:"Max results (default: 50, max: 1000)"`
+	Offset       int    `query:"offset" optional:"true" description:"Number of results to skip"`
+}
+
+// DeleteScopeRequest is the request for deleting a scope.
+type DeleteScopeRequest struct {
+	ScopeID string `path:"scopeId" example:"kscp_01m4wms908fhar5rgjb8jtryba" description:"Scope ID"`
+}
+
+// AssignScopesRequest is the request for assigning scopes to a key.
+type AssignScopesRequest struct {
+	KeyID  string   `path:"keyId" example:"akey_01m4wms908fh99berht8ytte6h" description:"Key ID"`
+	Scopes []string `json:"scopes" description:"Scope names to assign"`
+}
+
+// GetKeyContactsRequest is the request for fetching a key's contacts.
+type GetKeyContactsRequest struct {
+	KeyID string `path:"keyId" example:"akey_01m4wms908fh99berht8ytte6h" description:"Key ID"`
+}
+
+// GetLiveKeyStatsRequest is the request for a key's live validation rates.
+type GetLiveKeyStatsRequest struct {
+	KeyID string `path:"keyId" example:"akey_01m4wms908fh99berht8ytte6h" description:"Key ID"`
+}
+
+// ListLiveTopKeysRequest is the request for the busiest keys by live
+// validation rate.
+type ListLiveTopKeysRequest struct {
+	N int `query:"n" optional:"true" description:"Number of keys (default: 20, max: 100)"`
+}
+
+// PutKeyContactsRequest is the request for replacing a key's contacts.
+type PutKeyContactsRequest struct {
+	KeyID    string       `path:"keyId" example:"akey_01m4wms908fh99berht8ytte6h" description:"Key ID"`
+	Contacts key.Contacts `json:"contacts" description:"Replacement contacts, each with a type (email, webhook, slack) and target; an empty list falls back to the tenant's defaults"`
+}
+
+// RemoveScopesRequest is the request for removing scopes from a key.
+type RemoveScopesRequest struct {
+	KeyID  string   `path:"keyId" example:"akey_01m4wms908fh99berht8ytte6h" description:"Key ID"`
+	Scopes []string `json:"scopes" description:"Scope names to remove"`
+}
+
+// ── Key transfer DTOs ─────────────────────────────
+
+// InitiateKeyTransferRequest is the request for starting the transfer of a
+// key to another tenant.
+type InitiateKeyTransferRequest struct {
+	KeyID          string `path:"keyId" example:"akey_01m4wms908fh99berht8ytte6h" description:"Key ID"`
+	TargetTenantID string `json:"target_tenant_id" description:"Tenant to transfer the key to, which must accept it"`
+}
+
+// GetKeyTransferRequest is the request for fetching a key's pending
+// transfer.
+type GetKeyTransferRequest struct {
+	KeyID string `path:"keyId" example:"akey_01m4wms908fh99berht8ytte6h" description:"Key ID"`
+}
+
+// AcceptKeyTransferRequest is the request for accepting a key's pending
+// transfer.
+type AcceptKeyTransferRequest struct {
+	KeyID string `path:"keyId" example:"akey_01m4wms908fh99berht8ytte6h" description:"Key ID"`
+}
+
+// CancelKeyTransferRequest is the request for cancelling a key's pending
+// transfer.
+type CancelKeyTransferRequest struct {
+	KeyID string `path:"keyId" example:"akey_01m4wms908fh99berht8ytte6h" description:"Key ID"`
+}
+
+// ListKeyTransfersRequest is the request for listing key transfers.
+type ListKeyTransfersRequest struct {
+	KeyID  string `query:"key_id" optional:"true" description:"Filter by key"`
+	State  string `query:"state" optional:"true" description:"Filter by state (pending, accepted, cancelled, expired)"`
+	Limit  int    `query:"limit" optional:"true" description:"Max results (default: 50, max: 1000)"`
+	Offset int    `query:"offset" optional:"true" description:"Number of results to skip"`
+}
+
+// ── Group DTOs ────────────────────────────────────
+
+// CreateGroupRequest is the request for creating a key group.
+type CreateGroupRequest struct {
+	Name        string `json:"name" description:"Group name (e.g., Mobile apps)"`
+	Description string `json:"description" optional:"true" description:"Optional description"`
+}
+
+// ListGroupsRequest is the request for listing key groups.
+type ListGroupsRequest struct {
+	AppID  string `query:"app_id" optional:"true" description:"Filter by app; ignored when the caller is scoped to an app"`
+	Limit  int    `query:"limit" optional:"true" description:"Max results (default: 50, max: 1000)"`
+	Offset int    `query:"offset" optional:"true" description:"Number of results to skip"`
+}
+
+// GetGroupRequest is the request for fetching a single key group.
+type GetGroupRequest struct {
+	GroupID string `path:"groupId" example:"kgrp_01m4wms908fhd3k2a6y1x9q7zr" description:"Group ID"`
+}
+
+// UpdateGroupRequest is the request for updating a key group. Omitted
+// fields are left unchanged.
+type UpdateGroupRequest struct {
+	GroupID     string  `path:"groupId" example:"kgrp_01m4wms908fhd3k2a6y1x9q7zr" description:"Group ID"`
+	Name        *string `json:"name,omitempty" description:"Group name"`
+	Description *string `json:"description,omitempty" description:"Description"`
+}
+
+// DeleteGroupRequest is the request for deleting a key group.
+type DeleteGroupRequest struct {
+	GroupID string `path:"groupId" example:"kgrp_01m4wms908fhd3k2a6y1x9q7zr" description:"Group ID"`
+}
+
+// AddGroupKeysRequest is the request for adding keys to a group.
+type AddGroupKeysRequest struct {
+	GroupID string   `path:"groupId" example:"kgrp_01m4wms908fhd3k2a6y1x9q7zr" description:"Group ID"`
+	KeyIDs  []string `json:"key_ids" description:"Keys to add, in the group's tenant and app"`
+}
+
+// RemoveGroupKeysRequest is the request for removing keys from a group.
+type RemoveGroupKeysRequest struct {
+	GroupID string   `path:"groupId" example:"kgrp_01m4wms908fhd3k2a6y1x9q7zr" description:"Group ID"`
+	KeyIDs  []string `json:"key_ids" description:"Keys to remove"`
+}
+
+// RotateGroupRequest is the request for rotating every key in a group.
+type RotateGroupRequest struct {
+	GroupID string         `path:"groupId" example:"kgrp_01m4wms908fhd3k2a6y1x9q7zr" description:"Group ID"`
+	Reason  RotationReason `json:"reason" optional:"true" description:"Rotation reason (scheduled, manual, compromise, policy, admin, automated_anomaly, import; default: manual)"`
+	DryRun  bool           `query:"dry_run" optional:"true" description:"Validate and report the outcome without making changes"`
+}
+
+// RevokeGroupRequest is the request for revoking every key in a group.
+type RevokeGroupRequest struct {
+	GroupID string `path:"groupId" example:"kgrp_01m4wms908fhd3k2a6y1x9q7zr" description:"Group ID"`
+	Reason  string `json:"reason" description:"Revocation reason recorded on each revoked key"`
+	DryRun  bool   `query:"dry_run" optional:"true" description:"Validate and report the outcome without making changes"`
+}
+
+// ── Quota pool DTOs ───────────────────────────────
+
+// CreateQuotaPoolRequest is the request for creating a quota pool.
+type CreateQuotaPoolRequest struct {
+	Name          string `json:"name" description:"Pool name (e.g., Acme Pro plan)"`
+	Description   string `json:"description" optional:"true" description:"Optional description"`
+	DailyLimit    int64  `json:"daily_limit" optional:"true" description:"Max validations per day across the pool's keys (0 = unlimited)"`
+	MonthlyLimit  int64  `json:"monthly_limit" optional:"true" description:"Max validations per month across the pool's keys (0 = unlimited)"`
+	QuotaTimezone string `json:"quota_timezone,omitempty" description:"IANA time zone the pool's days and months are counted in (e.g., America/New_York); empty for the tenant's, else UTC"`
+}
+
+// ListQuotaPoolsRequest is the request for listing quota pools.
+type ListQuotaPoolsRequest struct {
+	AppID  string `query:"app_id" optional:"true" description:"Filter by app; ignored when the caller is scoped to an app"`
+	Limit  int    `query:"limit" optional:"true" description:"Max results (default: 50, max: 1000)"`
+	Offset int    `query:"offset" optional:"true" description:"Number of results to skip"`
+}
+
+// GetQuotaPoolRequest is the request for fetching a single quota pool.
+type GetQuotaPoolRequest struct {
+	PoolID string `path:"poolId" example:"kqpl_01m4wms908fhe6yd8cv0hpsdzw" description:"Quota pool ID"`
+}
+
+// UpdateQuotaPoolRequest is the request for updating a quota pool. Omitted
+// fields are left unchanged.
+type UpdateQuotaPoolRequest struct {
+	PoolID        string  `path:"poolId" example:"kqpl_01m4wms908fhe6yd8cv0hpsdzw" description:"Quota pool ID"`
+	Name          *string `json:"name,omitempty" description:"Pool name"`
+	Description   *string `json:"description,omitempty" description:"Description"`
+	DailyLimit    *int64  `json:"daily_limit,omitempty" description:"Max validations per day (0 = unlimited)"`
+	MonthlyLimit  *int64  `json:"monthly_limit,omitempty" description:"Max validations per month (0 = unlimited)"`
+	QuotaTimezone *string `json:"quota_timezone,omitempty" description:"IANA time zone; empty for the tenant's, else UTC"`
+}
+
+// DeleteQuotaPoolRequest is the request for deleting a quota pool.
+type DeleteQuotaPoolRequest struct {
+	PoolID string `path:"poolId" example:"kqpl_01m4wms908fhe6yd8cv0hpsdzw" description:"Quota pool ID"`
+}
+
+// AddQuotaPoolKeysRequest is the request for adding keys to a quota pool.
+type AddQuotaPoolKeysRequest struct {
+	PoolID string   `path:"poolId" example:"kqpl_01m4wms908fhe6yd8cv0hpsdzw" description:"Quota pool ID"`
+	KeyIDs []string `json:"key_ids" description:"Keys to add, in the pool's tenant and app; keys in another pool move to this one"`
+}
+
+// RemoveQuotaPoolKeysRequest is the request for removing keys from a quota
+// pool.
+type RemoveQuotaPoolKeysRequest struct {
+	PoolID string   `path:"poolId" example:"kqpl_01m4wms908fhe6yd8cv0hpsdzw" description:"Quota pool ID"`
+	KeyIDs []string `json:"key_ids" description:"Keys to remove"`
+}
+
+// GetQuotaPoolUsageRequest is the request for a quota pool's consumption.
+type GetQuotaPoolUsageRequest struct {
+	PoolID string `path:"poolId" example:"kqpl_01m4wms908fhe6yd8cv0hpsdzw" description:"Quota pool ID"`
+}
+
+// ── Usage DTOs ────────────────────────────────────
+
+// GetKeyUsageRequest is the request for fetching key usage.
+type GetKeyUsageRequest struct {
+	KeyID  string `path:"keyId" example:"akey_01m4wms908fh99berht8ytte6h" description:"Key ID"`
+	After  string `query:"after" optional:"true" description:"After timestamp (ISO 8601)"`
+	Before string `query:"before" optional:"true" description:"Before timestamp (ISO 8601)"`
+	Limit  int    `query:"limit" optional:"true" description:"Max results (default: 100, max: 1000)"`
+	Offset int    `query:"offset" optional:"true" description:"Number of results to skip"`
+	Fields string `query:"fields" optional:"true" description:"Comma-separated fields to return (default: all)"`
+}
+
+// GetKeyUsageAggregateRequest is the request for aggregated usage.
+type GetKeyUsageAggregateRequest struct {
+	KeyID  string `path:"keyId" example:"akey_01m4wms908fh99berht8ytte6h" description:"Key ID"`
+	Period string `query:"period" optional:"true" description:"Aggregation period (hour, day, month)"`
+	After  string `query:"after" optional:"true" description:"After timestamp (ISO 8601)"`
+	Before string `query:"before" optional:"true" description:"Before timestamp (ISO 8601)"`
+	Limit  int    `query:"limit" optional:"true" description:"Max results (default: 100, max: 1000)"`
+	Offset int    `query:"offset" optional:"true" description:"Number of results to skip"`
+	Fields string `query:"fields" optional:"true" description:"Comma-separated fields to return (default: all)"`
+}
+
+// ListEndpointActivityRequest is the request for a key's per-endpoint activity.
+type ListEndpointActivityRequest struct {
+	KeyID string `path:"keyId" example:"akey_01m4wms908fh99berht8ytte6h" description:"Key ID"`
+}
+
+// GetQuotaForecastRequest is the request for a key's monthly quota forecast.
+type GetQuotaForecastRequest struct {
+	KeyID string `path:"keyId" example:"akey_01m4wms908fh99berht8ytte6h" description:"Key ID"`
+}
+
+// ListUsageRequest is the request for listing tenant-wide usage.
+type ListUsageRequest struct {
+	AppID       string         `query:"app_id" optional:"true" description:"Filter by app; ignored when the caller is scoped to an app"`
+	Environment KeyEnvironment `query:"environment" optional:"true" description:"Filter by key environment (live, test, staging), such as to keep sandbox traffic out of production figures"`
+	Period      string         `query:"period" optional:"true" description:"Aggregation period (hour, day, month)"`
+	After       string         `query:"after" optional:"true" description:"After timestamp (ISO 8601)"`
+	Before      string         `query:"before" optional:"true" description:"Before timestamp (ISO 8601)"`
+	Limit       int            `query:"limit" optional:"true" description:"Max results (default: 100, max: 1000)"`
+	Offset      int            `query:"offset" optional:"true" description:"Number of results to skip"`
+	Fields      string         `query:"fields" optional:"true" description:"Comma-separated fields to return (default: all)"`
+}
+
+// ── Rotation DTOs ─────────────────────────────────
+
+// ListRotationsRequest is the request for listing rotations.
+type ListRotationsRequest struct {
+	KeyID  string `path:"keyId" example:"akey_01m4wms908fh99berht8ytte6h" description:"Key ID"`
+	Limit  int    `query:"limit" optional:"true" description:"Max results (default: 50, max: 1000)"`
+	Offset int    `query:"offset" optional:"true" description:"Number of results to skip"`
+}
+
+// ListTenantRotationsRequest is the request for listing rotations across
+// the keys the caller may see.
+type ListTenantRotationsRequest struct {
+	TenantID      string         `query:"tenant_id" optional:"true" description:"Restrict to one tenant; ignored when the caller is scoped to a tenant"`
+	AppID         string         `query:"app_id" optional:"true" description:"Filter by app; ignored when the caller is scoped to an app"`
+	Reason        RotationReason `query:"reason" optional:"true" description:"Filter by rotation reason (scheduled, manual, compromise, policy, admin, automated_anomaly, import)"`
+	CreatedAfter  string         `query:"created_after" optional:"true" description:"Only rotations made after this RFC 3339 time"`
+	CreatedBefore string         `query:"created_before" optional:"true" description:"Only rotations made before this RFC 3339 time"`
+	Limit         int            `query:"limit" optional:"true" description:"Max results (default: 50, max: 1000)"`
+	Offset        int            `query:"offset" optional:"true" description:"Number of results to skip"`
+}
+
+// RotationSummaryRequest is the request for counting rotations per reason.
+type RotationSummaryRequest struct {
+	TenantID      string `query:"tenant_id" optional:"true" description:"Restrict to one tenant; ignored when the caller is scoped to a tenant"`
+	AppID         string `query:"app_id" optional:"true" description:"Filter by app; ignored when the caller is scoped to an app"`
+	CreatedAfter  string `query:"created_after" optional:"true" description:"Only rotations made after this RFC 3339 time"`
+	CreatedBefore string `query:"created_before" optional:"true" description:"Only rotations made before this RFC 3339 time"`
+}
+
+// GetLineageRequest is the request for a key's rotation lineage.
+type GetLineageRequest struct {
+	KeyID string `path:"keyId" example:"akey_01m4wms908fh99berht8ytte6h" description:"Key ID"`
+	At    string `query:"at" optional:"true" description:"RFC 3339 time; when set, the response reports which hash eras were live then"`
+}
+
+// ── Revocation DTOs ───────────────────────────────
+
+// ListRevocationsRequest is the request for reading the revocation feed.
+type ListRevocationsRequest struct {
+	Since  string `query:"since" optional:"true" description:"Return entries recorded after this time (RFC 3339)"`
+	Cursor string `query:"cursor" optional:"true" description:"Resume after the next cursor of a previous page; takes precedence over since"`
+	Limit  int    `query:"limit" optional:"true" description:"Max entries (default: 500, max: 1000)"`
+}
+
+// ── Key event DTOs ────────────────────────────────
+
+// StreamKeyEventsRequest is the request for streaming key events.
+type StreamKeyEventsRequest struct {
+	Types       string `query:"types" optional:"true" description:"Comma-separated event types to stream (key.created, key.state_changed, key.rotated, key.revoked, key.expired); default all"`
+	KeyID       string `query:"key_id" optional:"true" example:"akey_01m4wms908fh99berht8ytte6h" description:"Stream only this key's events"`
+	LastEventID string `header:"Last-Event-ID" optional:"true" description:"ID of the last event received; the events recorded after it are replayed first"`
+	After       string `query:"last_event_id" optional:"true" description:"Same as Last-Event-ID, for clients that cannot set headers; the header takes precedence"`
+}
+
+// ── Debug capture DTOs ────────────────────────────
+
+// SetKeyDebugRequest is the request for turning debug capture on or off.
+type SetKeyDebugRequest struct {
+	KeyID      string   `path:"keyId" example:"akey_01m4wms908fh99berht8ytte6h" description:"Key ID"`
+	SampleRate float64  `json:"sample_rate" description:"Fraction of requests to capture, from 0 to 1; 0 turns capture off"`
+	Duration   Duration `json:"duration" optional:"true" description:"How long capture stays on (e.g., PT30M, PT2H; max P1D)"`
+}
+
+// ListCapturesRequest is the request for listing a key's debug captures.
+type ListCapturesRequest struct {
+	KeyID string `path:"keyId" example:"akey_01m4wms908fh99berht8ytte6h" description:"Key ID"`
+}
+
+// ── Tenant DTOs ───────────────────────────────────
+
+// GetTenantSettingsRequest is the request for fetching tenant settings.
+type GetTenantSettingsRequest struct {
+	TenantID string `path:"tenantId" example:"tenant_123" description:"Tenant ID"`
+}
+
+// UpdateTenantSettingsRequest is the request for replacing tenant settings.
+type UpdateTenantSettingsRequest struct {
+	TenantID        string   `path:"tenantId" example:"tenant_123" description:"Tenant ID"`
+	DefaultScopes   []string `json:"default_scopes" description:"Scopes granted to every new key in the tenant"`
+	RateLimit       int      `json:"rate_limit,omitempty" description:"Validations allowed across all of the tenant's keys per window, on top of each key's own limit; 0 for no ceiling"`
+	RateLimitWindow Duration `json:"rate_limit_window,omitempty" description:"Tenant rate limit window (e.g., PT1M, PT1H); required with rate_limit"`
+
+	IPHandling usage.IPHandling `json:"ip_handling,omitempty" description:"How client IPs are kept in usage records: store (default), truncate, hash, or drop"`
+
+	QuotaTimezone string `json:"quota_timezone,omitempty" description:"IANA time zone the tenant's quota days and months are counted in (e.g., Asia/Tokyo); empty for UTC"`
+
+	UniqueKeyNames bool `json:"unique_key_names,omitempty" description:"Reject creating or renaming a key to the name of another of the tenant's keys, ignoring case; keys that already share a name keep it"`
+
+	DefaultContacts key.Contacts `json:"default_contacts,omitempty" description:"Where lifecycle notifications go for keys without contacts of their own"`
+}
+
+// PutMetadataSchemaRequest is the request for replacing a tenant's metadata schema.
+type PutMetadataSchemaRequest struct {
+	TenantID     string                      `path:"tenantId" example:"tenant_123" description:"Tenant ID"`
+	Fields       map[string]metaschema.Field `json:"fields" description:"Allowed metadata entries by name, with type (string, number, bool), required flag, and enum values"`
+	AllowUnknown bool                        `json:"allow_unknown,omitempty" description:"Accept entries not listed in fields"`
+}
+
+// ── Admin DTOs ────────────────────────────────────
+
+// MaintainStoreRequest is the request for running store maintenance.
+type MaintainStoreRequest struct {
+	StatsOnly bool `json:"stats_only,omitempty" description:"Report table statistics without running maintenance"`
+	Full      bool `json:"full,omitempty" description:"Run the thorough variant (VACUUM FULL, full VACUUM, or compact), which may lock tables"`
+}
+
+// GetStorageReportRequest is the request for the per-tenant storage report.
+type GetStorageReportRequest struct {
+	TenantID string `query:"tenant_id" optional:"true" description:"Report one tenant only (default: every tenant)"`
+	Top      int    `query:"top" optional:"true" description:"Tenants to rank by usage volume (default: 10)"`
+}
+
+// RevokeByHashRequest is the request for revoking keys by hash.
+type RevokeByHashRequest struct {
+	Hashes []string `json:"hashes" description:"Leaked key_hash values, at most 1000"`
+	Reason string   `json:"reason" description:"Revocation reason recorded on each revoked key"`
+	DryRun bool     `query:"dry_run" optional:"true" description:"Validate and report the outcome without making changes"`
+}
+
+// ListKeysByCreatorRequest is the request for listing the keys a user or
+// service created, across tenants.
+type ListKeysByCreatorRequest struct {
+	CreatedBy string   `query:"created_by" description:"User or service that created the keys"`
+	TenantID  string   `query:"tenant_id" optional:"true" description:"Restrict to one tenant (default: every tenant the caller may see)"`
+	AppID     string   `query:"app_id" optional:"true" description:"Restrict to one app; ignored when the caller is scoped to an app"`
+	State     KeyState `query:"state" optional:"true" description:"Filter by state (active, rotated, expired, revoked, suspended)"`
+	Limit     int      `query:"limit" optional:"true" description:"Max results (default: 50, max: 1000)"`
+	Offset    int      `query:"offset" optional:"true" description:"Number of results to skip"`
+}
+
+// RevokeByCreatorRequest is the request for revoking every key a user or
+// service created.
+type RevokeByCreatorRequest struct {
+	CreatedBy string `json:"created_by" description:"User or service that created the keys"`
+	TenantID  string `json:"tenant_id,omitempty" description:"Restrict to one tenant (default: every tenant the caller may see)"`
+	AppID     string `json:"app_id,omitempty" description:"Restrict to one app; ignored when the caller is scoped to an app"`
+	Reason    string `json:"reason" description:"Revocation reason recorded on each revoked key"`
+	DryRun    bool   `query:"dry_run" optional:"true" description:"Validate and report the outcome without making changes"`
+}
+
+// ValidateHashesRequest is the request for reporting which keys a set of
+// hashes belongs to.
+type ValidateHashesRequest struct {
+	Hashes []string `json:"hashes" description:"key_hash values to look up, at most 1000"`
+}
+
+// ListHashTombstonesRequest is the request for listing hash tombstones.
+type ListHashTombstonesRequest struct {
+	TenantID string `query:"tenant_id" optional:"true" description:"Restrict to one tenant; ignored when the caller is scoped to a tenant"`
+	AppID    string `query:"app_id" optional:"true" description:"Restrict to one app; ignored when the caller is scoped to an app"`
+	Reason   string `query:"reason" optional:"true" description:"Filter by reason (compromised, leaked_hash, or revoked)"`
+	Limit    int    `query:"limit" optional:"true" description:"Max results (default: 50, max: 1000)"`
+	Offset   int    `query:"offset" optional:"true" description:"Number of results to skip"`
+}
+
+// LockdownTenantRequest is the request for locking a tenant down.
+type LockdownTenantRequest struct {
+	TenantID string `path:"tenantId" example:"tenant_123" description:"Tenant ID"`
+	Reason   string `json:"reason" description:"Why the tenant is locked down, recorded on the lockdown and in the audit trail"`
+	DryRun   bool   `query:"dry_run" optional:"true" description:"Validate and report the outcome without making changes"`
+}
+
+// GetTenantLockdownRequest is the request for a tenant's lockdown status.
+type GetTenantLockdownRequest struct {
+	TenantID string `path:"tenantId" example:"tenant_123" description:"Tenant ID"`
+}
+
+// RecoverTenantRequest is the request for starting or resuming the staged
+// recovery of a locked down tenant.
+type RecoverTenantRequest struct {
+	TenantID      string   `path:"tenantId" example:"tenant_123" description:"Tenant ID"`
+	BatchSize     int      `json:"batch_size,omitempty" description:"Keys reactivated per batch (default: 50, or the running recovery's)"`
+	BatchInterval Duration `json:"batch_interval,omitempty" description:"Wait between batches (e.g., PT5M; default: PT5M, or the running recovery's)"`
+	DryRun        bool     `query:"dry_run" optional:"true" description:"Report the lockdown status without starting the recovery"`
+}
+
+// GetUsageRecordingRequest is the request for the usage recording policy.
+type GetUsageRecordingRequest struct{}
+
+// SetUsageRecordingRequest is the request for replacing the usage recording
+// policy.
+type SetUsageRecordingRequest struct {
+	Rules      []usage.RecordingRule `json:"rules" description:"Path rules evaluated in order, first match wins; each has a glob pattern, a mode (full, sampled, counter_only, off), and an optional sample_rate"`
+	Default    string                `json:"default,omitempty" description:"Mode for requests matching no rule; full when empty"`
+	SampleRate int                   `json:"sample_rate,omitempty" description:"1-in-N rate for the default mode and for sampled rules without their own"`
+}
+
+// GetRuntimeConfigRequest is the request for the engine's runtime
+// configuration.
+type GetRuntimeConfigRequest struct{}
+
+// UpdateRuntimeConfigRequest is the request for changing runtime settings.
+// Omitted fields are left as they are.
+type UpdateRuntimeConfigRequest struct {
+	ValidationCacheTTL    *Duration `json:"validation_cache_ttl,omitempty" description:"Validation cache TTL for new entries (e.g., PT30S)"`
+	EndpointFlushInterval *Duration `json:"endpoint_flush_interval,omitempty" description:"How often endpoint activity is flushed (e.g., PT10S)"`
+	CapturePurgeInterval  *Duration `json:"capture_purge_interval,omitempty" description:"How often expired debug captures are deleted (e.g., PT1H)"`
+	MaintenanceInterval   *Duration `json:"maintenance_interval,omitempty" description:"How often store maintenance runs (e.g., P1D)"`
+	QuotaForecastInterval *Duration `json:"quota_forecast_interval,omitempty" description:"How often quota forecasts are evaluated (e.g., PT1H)"`
+	FailureThreshold      *int      `json:"failure_threshold,omitempty" description:"Validation failures per fingerprint that fire SuspiciousValidationPattern"`
+	FailureWindow         *Duration `json:"failure_window,omitempty" description:"Window the failure threshold is counted over (e.g., PT5M)"`
+	UsageSampleRate       *int      `json:"usage_sample_rate,omitempty" description:"1-in-N rate of sampled usage recording modes without their own"`
+	DryRun                bool      `json:"dry_run,omitempty" description:"Validate and return the resulting configuration without applying it"`
+}
+
+// SetReadOnlyRequest is the request for turning read-only mode on or off.
+type SetReadOnlyRequest struct {
+	Enabled bool `json:"enabled" description:"Refuse store writes while validation keeps working"`
+}
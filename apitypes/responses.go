@@ -0,0 +1,835 @@
+package apitypes
+
+import (
+	"time"
+
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/metaschema"
+	"github.com/xraph/keysmith/middleware"
+	"github.com/xraph/keysmith/usage"
+)
+
+// KeyResponse is the API representation of a key (raw key is never included).
+type KeyResponse struct {
+	ID          string         `json:"id"`
+	TenantID    string         `json:"tenant_id"`
+	AppID       string         `json:"app_id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Prefix      string         `json:"prefix"`
+	Hint        string         `json:"hint"`
+	Environment KeyEnvironment `json:"environment"`
+	State       KeyState       `json:"state"`
+	PolicyID    string         `json:"policy_id,omitempty"`
+	PoolID      string         `json:"pool_id,omitempty"`
+	Scopes      []string       `json:"scopes,omitempty"`
+	Metadata    map[string]any `json:"metadata,omitempty"`
+	CreatedBy   string         `json:"created_by,omitempty"`
+	ExternalRef string         `json:"external_ref,omitempty"`
+
+	AllowedOrigins []string `json:"allowed_origins,omitempty"`
+
+	IntendedConsumer string `json:"intended_consumer,omitempty"`
+	EnforceConsumer  bool   `json:"enforce_consumer,omitempty"`
+	CertFingerprint  string `json:"cert_fingerprint,omitempty"`
+
+	Flags []string `json:"flags,omitempty"`
+
+	Contacts key.Contacts `json:"contacts,omitempty"`
+
+	Labels key.Labels `json:"labels,omitempty"`
+
+	// Groups are the IDs of the groups the key belongs to.
+	Groups []string `json:"groups,omitempty"`
+
+	AcceptedTermsVersion string     `json:"accepted_terms_version,omitempty"`
+	AcceptedTermsAt      *time.Time `json:"accepted_terms_at,omitempty"`
+
+	DebugSampleRate float64    `json:"debug_sample_rate,omitempty"`
+	DebugUntil      *time.Time `json:"debug_until,omitempty"`
+
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	// QuotaForecast is set by the key-details endpoint for a key whose
+	// policy sets a monthly quota.
+	QuotaForecast *QuotaForecastSummary `json:"quota_forecast,omitempty"`
+}
+
+// QuotaForecastSummary is the compact quota forecast in key details.
+type QuotaForecastSummary struct {
+	Used                int64      `json:"used"`
+	Quota               int64      `json:"quota"`
+	ProjectedTotal      int64      `json:"projected_total"`
+	ProjectedExhaustion *time.Time `json:"projected_exhaustion,omitempty"`
+}
+
+// QuotaForecastResponse is the API representation of a key's monthly quota
+// forecast. PoolID is set when the forecast is of the key's quota pool.
+type QuotaForecastResponse struct {
+	KeyID               string     `json:"key_id"`
+	PoolID              string     `json:"pool_id,omitempty"`
+	Month               time.Time  `json:"month"`
+	AsOf                time.Time  `json:"as_of"`
+	Quota               int64      `json:"quota"`
+	Used                int64      `json:"used"`
+	BurnRate            float64    `json:"burn_rate"`
+	ProjectedTotal      int64      `json:"projected_total"`
+	ProjectedExhaustion *time.Time `json:"projected_exhaustion,omitempty"`
+}
+
+// KeyOverviewResponse counts a tenant's keys by state, environment, and
+// lifecycle window.
+type KeyOverviewResponse struct {
+	Total           int64                     `json:"total"`
+	ByState         map[KeyState]int64        `json:"by_state"`
+	ByEnvironment   map[KeyEnvironment]int64  `json:"by_environment"`
+	Expiring        []*OverviewWindowResponse `json:"expiring"`
+	NeverUsed       int64                     `json:"never_used"`
+	CreatedRecently *OverviewWindowResponse   `json:"created_recently"`
+}
+
+// OverviewWindowResponse is how many keys fell within a window of days.
+type OverviewWindowResponse struct {
+	WithinDays int   `json:"within_days"`
+	Count      int64 `json:"count"`
+}
+
+// KeyCreateResponse includes the raw key (shown only once at creation).
+type KeyCreateResponse struct {
+	Key    *KeyResponse `json:"key"`
+	RawKey string       `json:"raw_key"`
+
+	// Scopes is the effective scope list, including tenant defaults.
+	Scopes []string `json:"scopes"`
+
+	// DuplicateOfExisting is set, with an empty RawKey, when the request's
+	// external_ref belongs to an existing key, which Key describes.
+	DuplicateOfExisting bool `json:"duplicate_of_existing,omitempty"`
+}
+
+// CompromiseResponse is the outcome of a compromise report. RawKey is set
+// only when the key was rotated.
+type CompromiseResponse struct {
+	Action string       `json:"action"`
+	Key    *KeyResponse `json:"key"`
+	RawKey string       `json:"raw_key,omitempty"`
+}
+
+// PolicyResponse is the API representation of a policy.
+type PolicyResponse struct {
+	ID              string         `json:"id"`
+	TenantID        string         `json:"tenant_id"`
+	AppID           string         `json:"app_id"`
+	Name            string         `json:"name"`
+	Description     string         `json:"description,omitempty"`
+	BasePolicyID    string         `json:"base_policy_id,omitempty"`
+	RateLimit       int            `json:"rate_limit"`
+	RateLimitWindow Duration       `json:"rate_limit_window"`
+	BurstLimit      int            `json:"burst_limit"`
+	AllowedScopes   []string       `json:"allowed_scopes,omitempty"`
+	AllowedIPs      []string       `json:"allowed_ips,omitempty"`
+	AllowedOrigins  []string       `json:"allowed_origins,omitempty"`
+	AllowedMethods  []string       `json:"allowed_methods,omitempty"`
+	AllowedPaths    []string       `json:"allowed_paths,omitempty"`
+	MaxKeyLifetime  Duration       `json:"max_key_lifetime,omitempty"`
+	RotationPeriod  Duration       `json:"rotation_period,omitempty"`
+	GracePeriod     Duration       `json:"grace_period"`
+	DailyQuota      int64          `json:"daily_quota"`
+	MonthlyQuota    int64          `json:"monthly_quota"`
+	QuotaTimezone   string         `json:"quota_timezone,omitempty"`
+	MinTLSVersion   string         `json:"min_tls_version,omitempty"`
+	RequireMTLS     bool           `json:"require_mtls,omitempty"`
+	Inline          bool           `json:"inline,omitempty"`
+	Metadata        map[string]any `json:"metadata,omitempty"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+}
+
+// EffectivePolicyResponse is a policy merged with the policies it inherits
+// from, as applied when validating its keys.
+type EffectivePolicyResponse struct {
+	Policy *PolicyResponse `json:"policy"`
+	Chain  []string        `json:"chain"`
+}
+
+// ScopeResponse is the API representation of a scope.
+type ScopeResponse struct {
+	ID              string         `json:"id"`
+	TenantID        string         `json:"tenant_id"`
+	AppID           string         `json:"app_id"`
+	Name            string         `json:"name"`
+	Description     string         `json:"description,omitempty"`
+	Parent          string         `json:"parent,omitempty"`
+	Metadata        map[string]any `json:"metadata,omitempty"`
+	RateLimit       int            `json:"rate_limit,omitempty"`
+	RateLimitWindow Duration       `json:"rate_limit_window,omitempty"`
+	CreatedAt       time.Time      `json:"created_at"`
+}
+
+// GroupResponse is the API representation of a key group.
+type GroupResponse struct {
+	ID          string    `json:"id"`
+	TenantID    string    `json:"tenant_id"`
+	AppID       string    `json:"app_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// QuotaPoolResponse is the API representation of a quota pool.
+type QuotaPoolResponse struct {
+	ID            string    `json:"id"`
+	TenantID      string    `json:"tenant_id"`
+	AppID         string    `json:"app_id"`
+	Name          string    `json:"name"`
+	Description   string    `json:"description,omitempty"`
+	DailyLimit    int64     `json:"daily_limit,omitempty"`
+	MonthlyLimit  int64     `json:"monthly_limit,omitempty"`
+	QuotaTimezone string    `json:"quota_timezone,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// QuotaPoolUsageResponse is a quota pool's use of its current day and
+// month.
+type QuotaPoolUsageResponse struct {
+	PoolID string                   `json:"pool_id"`
+	AsOf   time.Time                `json:"as_of"`
+	Day    QuotaWindowUsageResponse `json:"day"`
+	Month  QuotaWindowUsageResponse `json:"month"`
+}
+
+// QuotaWindowUsageResponse is a quota pool's use of one window, with each
+// key's share, most used first. Limit is zero when the window is unlimited.
+type QuotaWindowUsageResponse struct {
+	Period    string                     `json:"period"`
+	Start     time.Time                  `json:"start"`
+	End       time.Time                  `json:"end"`
+	Limit     int64                      `json:"limit,omitempty"`
+	Used      int64                      `json:"used"`
+	Remaining int64                      `json:"remaining"`
+	Members   []QuotaMemberUsageResponse `json:"members"`
+}
+
+// QuotaMemberUsageResponse is one key's share of a quota window.
+type QuotaMemberUsageResponse struct {
+	KeyID string `json:"key_id"`
+	Used  int64  `json:"used"`
+}
+
+// GroupOperationResponse reports a rotation or revocation of a group's keys,
+// with one entry per member.
+type GroupOperationResponse struct {
+	GroupID string                `json:"group_id"`
+	Keys    []GroupKeyResultEntry `json:"keys"`
+	DryRun  bool                  `json:"dry_run"`
+}
+
+// GroupKeyResultEntry is the outcome of a group operation for one key:
+// rotated, revoked, already_revoked, or failed.
+type GroupKeyResultEntry struct {
+	KeyID    string            `json:"key_id"`
+	Status   string            `json:"status"`
+	RawKey   string            `json:"raw_key,omitempty"`
+	Rotation *RotationResponse `json:"rotation,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// UsageResponse is the API representation of a usage record.
+type UsageResponse struct {
+	ID          string         `json:"id"`
+	KeyID       string         `json:"key_id"`
+	TenantID    string         `json:"tenant_id"`
+	AppID       string         `json:"app_id,omitempty"`
+	Environment string         `json:"environment,omitempty"`
+	Endpoint    string         `json:"endpoint"`
+	Method      string         `json:"method"`
+	StatusCode  int            `json:"status_code"`
+	IPAddress   string         `json:"ip_address,omitempty"`
+	UserAgent   string         `json:"user_agent,omitempty"`
+	LatencyMs   int64          `json:"latency_ms"`
+	Metadata    map[string]any `json:"metadata,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+// AggregationResponse is the API representation of aggregated usage.
+type AggregationResponse struct {
+	KeyID        string    `json:"key_id"`
+	TenantID     string    `json:"tenant_id"`
+	Period       string    `json:"period"`
+	PeriodStart  time.Time `json:"period_start"`
+	RequestCount int64     `json:"request_count"`
+	ErrorCount   int64     `json:"error_count"`
+	TotalLatency int64     `json:"total_latency_ms"`
+	P50Latency   int64     `json:"p50_latency_ms"`
+	P99Latency   int64     `json:"p99_latency_ms"`
+}
+
+// EndpointActivityResponse is the API representation of a key's last-seen
+// activity on one endpoint.
+type EndpointActivityResponse struct {
+	Endpoint   string    `json:"endpoint"`
+	Method     string    `json:"method"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Count      int64     `json:"count"`
+}
+
+// RotationResponse is the API representation of a rotation record.
+type RotationResponse struct {
+	ID        string         `json:"id"`
+	KeyID     string         `json:"key_id"`
+	TenantID  string         `json:"tenant_id"`
+	AppID     string         `json:"app_id,omitempty"`
+	OldHint   string         `json:"old_hint,omitempty"`
+	NewHint   string         `json:"new_hint,omitempty"`
+	Reason    RotationReason `json:"reason"`
+	GraceTTL  Duration       `json:"grace_ttl"`
+	GraceEnds time.Time      `json:"grace_ends"`
+	RotatedBy string         `json:"rotated_by,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// RotationListResponse is the response for listing rotations across keys.
+type RotationListResponse struct {
+	Rotations []*RotationResponse `json:"rotations"`
+	Limit     int                 `json:"limit"`
+}
+
+// RotationSummaryResponse counts rotations per reason over a time range.
+type RotationSummaryResponse struct {
+	CreatedAfter  *time.Time             `json:"created_after,omitempty"`
+	CreatedBefore *time.Time             `json:"created_before,omitempty"`
+	Total         int64                  `json:"total"`
+	Reasons       []*ReasonCountResponse `json:"reasons"`
+}
+
+// ReasonCountResponse is how many rotations had one reason.
+type ReasonCountResponse struct {
+	Reason RotationReason `json:"reason"`
+	Count  int64          `json:"count"`
+}
+
+// HashEraResponse is the API representation of a period during which one
+// hash of a key was current.
+type HashEraResponse struct {
+	Index         int               `json:"index"`
+	Start         time.Time         `json:"start"`
+	End           *time.Time        `json:"end,omitempty"`
+	GraceEnds     *time.Time        `json:"grace_ends,omitempty"`
+	Hint          string            `json:"hint,omitempty"`
+	Rotation      *RotationResponse `json:"rotation,omitempty"`
+	GraceOverlaps []int             `json:"grace_overlaps,omitempty"`
+}
+
+// LineageResponse is the API representation of a key's rotation lineage.
+type LineageResponse struct {
+	KeyID string             `json:"key_id"`
+	Eras  []*HashEraResponse `json:"eras"`
+
+	// ActiveAt is set when the request passed at.
+	ActiveAt *HashActivityResponse `json:"active_at,omitempty"`
+}
+
+// HashActivityResponse reports the hash eras live at a point in time, by
+// index into LineageResponse.Eras.
+type HashActivityResponse struct {
+	At      time.Time `json:"at"`
+	Current int       `json:"current"`
+	Grace   []int     `json:"grace,omitempty"`
+}
+
+// ValidationResponse is the API representation of a key validation result.
+type ValidationResponse struct {
+	Valid  bool         `json:"valid"`
+	Key    *KeyResponse `json:"key,omitempty"`
+	Scopes []string     `json:"scopes,omitempty"`
+
+	RateLimit *RateLimitResponse `json:"rate_limit,omitempty"`
+	Quota     *QuotaResponse     `json:"quota,omitempty"`
+
+	ConsumerMismatch bool `json:"consumer_mismatch,omitempty"`
+
+	// OutdatedTerms warns that the key accepted terms other than the
+	// current version.
+	OutdatedTerms bool `json:"outdated_terms,omitempty"`
+
+	// AppliedFlags lists the key flags that changed this validation's
+	// outcome, such as a skipped origin check.
+	AppliedFlags []string `json:"applied_flags,omitempty"`
+
+	// RotationDue reminds the caller that the key has used most of its
+	// policy's rotation period.
+	RotationDue *RotationDueResponse `json:"rotation_due,omitempty"`
+
+	// UsingRotatedKey tells the caller that the key was rotated and this
+	// secret is the one replaced, accepted until the grace period ends.
+	UsingRotatedKey bool `json:"using_rotated_key,omitempty"`
+
+	// KeyDeprecation tells the caller that the key is in a deprecated key
+	// format and should be replaced before the format's sunset.
+	KeyDeprecation *KeyDeprecationResponse `json:"key_deprecation,omitempty"`
+}
+
+// KeyDeprecationResponse is the API representation of a key format
+// deprecation notice. Since and Sunset are omitted when not announced.
+type KeyDeprecationResponse struct {
+	Format string    `json:"format"`
+	Since  time.Time `json:"since,omitzero"`
+	Sunset time.Time `json:"sunset,omitzero"`
+	Link   string    `json:"link,omitempty"`
+}
+
+// RotationDueResponse is the API representation of a key's rotation
+// reminder. DaysRemaining is negative once the key is a day or more overdue.
+type RotationDueResponse struct {
+	DueAt         time.Time `json:"due_at"`
+	DaysRemaining int       `json:"days_remaining"`
+	Overdue       bool      `json:"overdue"`
+}
+
+// RateLimitResponse is the API representation of the key and tenant rate
+// limit budgets left after a validation. Limits of zero did not apply.
+type RateLimitResponse struct {
+	Limit           int      `json:"limit,omitempty"`
+	Remaining       int      `json:"remaining"`
+	Window          Duration `json:"window,omitempty"`
+	TenantLimit     int      `json:"tenant_limit,omitempty"`
+	TenantRemaining int      `json:"tenant_remaining"`
+	TenantWindow    Duration `json:"tenant_window,omitempty"`
+
+	// BaseLimit and ReducedUntil are set while adaptive limiting has
+	// reduced Limit from the key's normal limit.
+	BaseLimit    int        `json:"base_limit,omitempty"`
+	ReducedUntil *time.Time `json:"reduced_until,omitempty"`
+}
+
+// QuotaResponse is the API representation of the daily and monthly quota
+// left after a validation. Limits of zero did not apply; a reset is when
+// its period's window ends.
+type QuotaResponse struct {
+	PoolID           string    `json:"pool_id,omitempty"`
+	DailyLimit       int64     `json:"daily_limit,omitempty"`
+	DailyRemaining   int64     `json:"daily_remaining"`
+	DailyReset       time.Time `json:"daily_reset,omitzero"`
+	MonthlyLimit     int64     `json:"monthly_limit,omitempty"`
+	MonthlyRemaining int64     `json:"monthly_remaining"`
+	MonthlyReset     time.Time `json:"monthly_reset,omitzero"`
+}
+
+// FailurePatternResponse is the API representation of a validation-failure
+// fingerprint and its count in the current window.
+type FailurePatternResponse struct {
+	Fingerprint string    `json:"fingerprint"`
+	Count       int       `json:"count"`
+	Window      Duration  `json:"window"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// SLOResponse is the API representation of the validation SLO status.
+type SLOResponse struct {
+	Objectives []SLOStatusResponse `json:"objectives"`
+}
+
+// SLOStatusResponse describes one objective: its error budget over the
+// objective window and its burn rate over each burn window.
+type SLOStatusResponse struct {
+	Name             string            `json:"name"`
+	Target           float64           `json:"target"`
+	LatencyThreshold Duration          `json:"latency_threshold,omitempty"`
+	Window           Duration          `json:"window"`
+	Good             uint64            `json:"good"`
+	Bad              uint64            `json:"bad"`
+	BudgetRemaining  float64           `json:"budget_remaining"`
+	Burn             []SLOBurnResponse `json:"burn"`
+}
+
+// SLOBurnResponse is an objective's burn rate over one window.
+type SLOBurnResponse struct {
+	Window Duration `json:"window"`
+	Good   uint64   `json:"good"`
+	Bad    uint64   `json:"bad"`
+	Rate   float64  `json:"rate"`
+}
+
+// SearchResponse holds the matches of a search grouped by resource type,
+// best match first.
+type SearchResponse struct {
+	Keys []*KeySearchMatchResponse `json:"keys"`
+}
+
+// KeySearchMatchResponse is a key matching a search, with the fields a
+// term matched in ("name", "description", or "metadata.<entry>") and the
+// text around the first match.
+type KeySearchMatchResponse struct {
+	Key     *KeyResponse `json:"key"`
+	Fields  []string     `json:"fields"`
+	Snippet string       `json:"snippet"`
+	Score   int          `json:"score"`
+}
+
+// KeyNameDuplicateResponse is the API representation of a name shared by
+// more than one key of a tenant.
+type KeyNameDuplicateResponse struct {
+	TenantID string   `json:"tenant_id"`
+	Name     string   `json:"name"`
+	KeyIDs   []string `json:"key_ids"`
+}
+
+// LiveKeyStatsResponse is the API representation of a key's live validation
+// rates. TrackedSince and LastSeen are omitted for a key with no recent
+// traffic.
+type LiveKeyStatsResponse struct {
+	KeyID         string     `json:"key_id"`
+	TenantID      string     `json:"tenant_id"`
+	AppID         string     `json:"app_id,omitempty"`
+	QPS           float64    `json:"qps"`
+	Rate1m        float64    `json:"rate_1m"`
+	Rate5m        float64    `json:"rate_5m"`
+	FailureRatio  float64    `json:"failure_ratio"`
+	Validations5m int64      `json:"validations_5m"`
+	TrackedSince  *time.Time `json:"tracked_since,omitempty"`
+	LastSeen      *time.Time `json:"last_seen,omitempty"`
+}
+
+// KeyTransferResponse is the API representation of a key transfer.
+type KeyTransferResponse struct {
+	ID             string     `json:"id"`
+	KeyID          string     `json:"key_id"`
+	AppID          string     `json:"app_id,omitempty"`
+	SourceTenantID string     `json:"source_tenant_id"`
+	TargetTenantID string     `json:"target_tenant_id"`
+	State          string     `json:"state"`
+	RetagUsage     bool       `json:"retag_usage"`
+	InitiatedBy    string     `json:"initiated_by,omitempty"`
+	ResolvedBy     string     `json:"resolved_by,omitempty"`
+	UnmappedScopes []string   `json:"unmapped_scopes,omitempty"`
+	UnmappedPolicy string     `json:"unmapped_policy,omitempty"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+}
+
+// ReplayStatsResponse is the API representation of the replay protection
+// counters.
+type ReplayStatsResponse struct {
+	Enabled   bool    `json:"enabled"`
+	Checked   int64   `json:"checked"`
+	Replays   int64   `json:"replays"`
+	Stale     int64   `json:"stale"`
+	HitRate   float64 `json:"hit_rate"`
+	Size      int     `json:"size"`
+	Evictions int64   `json:"evictions"`
+}
+
+// TenantSettingsResponse is the API representation of tenant settings.
+type TenantSettingsResponse struct {
+	TenantID        string             `json:"tenant_id"`
+	AppID           string             `json:"app_id,omitempty"`
+	DefaultScopes   []string           `json:"default_scopes"`
+	MetadataSchema  *metaschema.Schema `json:"metadata_schema,omitempty"`
+	RateLimit       int                `json:"rate_limit,omitempty"`
+	RateLimitWindow Duration           `json:"rate_limit_window,omitempty"`
+	DefaultContacts key.Contacts       `json:"default_contacts,omitempty"`
+	IPHandling      usage.IPHandling   `json:"ip_handling,omitempty"`
+	QuotaTimezone   string             `json:"quota_timezone,omitempty"`
+	UniqueKeyNames  bool               `json:"unique_key_names,omitempty"`
+	CreatedAt       time.Time          `json:"created_at,omitzero"`
+	UpdatedAt       time.Time          `json:"updated_at,omitzero"`
+
+	// Lockdown is set while the tenant is locked down.
+	Lockdown *TenantLockdownResponse `json:"lockdown,omitempty"`
+}
+
+// TenantLockdownResponse is the API representation of a tenant's lockdown.
+type TenantLockdownResponse struct {
+	Phase     string                  `json:"phase"`
+	Reason    string                  `json:"reason"`
+	ActorID   string                  `json:"actor_id,omitempty"`
+	LockedAt  time.Time               `json:"locked_at"`
+	Suspended int                     `json:"suspended"`
+	Recovery  *TenantRecoveryResponse `json:"recovery,omitempty"`
+}
+
+// TenantRecoveryResponse is the progress of a staged recovery.
+type TenantRecoveryResponse struct {
+	StartedAt     time.Time  `json:"started_at"`
+	BatchSize     int        `json:"batch_size"`
+	BatchInterval Duration   `json:"batch_interval"`
+	NextBatchAt   time.Time  `json:"next_batch_at"`
+	Batches       int        `json:"batches"`
+	Reactivated   int        `json:"reactivated"`
+	Remaining     int        `json:"remaining"`
+	HaltedAt      *time.Time `json:"halted_at,omitempty"`
+	HaltReason    string     `json:"halt_reason,omitempty"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+}
+
+// TenantLockdownStatusResponse is a tenant's lockdown status.
+type TenantLockdownStatusResponse struct {
+	TenantID string                  `json:"tenant_id"`
+	Locked   bool                    `json:"locked"`
+	Lockdown *TenantLockdownResponse `json:"lockdown,omitempty"`
+	Pending  int                     `json:"pending"`
+}
+
+// TenantLockdownResultResponse reports a tenant lockdown and the keys it
+// suspended.
+type TenantLockdownResultResponse struct {
+	Lockdown *TenantLockdownResponse `json:"lockdown"`
+	KeyIDs   []string                `json:"key_ids"`
+	Failed   int                     `json:"failed"`
+	DryRun   bool                    `json:"dry_run"`
+}
+
+// KeyContactsResponse is the API representation of where a key's
+// notifications go.
+type KeyContactsResponse struct {
+	KeyID          string       `json:"key_id"`
+	Contacts       key.Contacts `json:"contacts"`
+	TenantDefaults key.Contacts `json:"tenant_defaults"`
+	Effective      key.Contacts `json:"effective"`
+}
+
+// RevocationEntryResponse is the API representation of a revocation feed
+// entry. Revoked is false when the key validates again.
+type RevocationEntryResponse struct {
+	KeyID      string    `json:"key_id"`
+	TenantID   string    `json:"tenant_id"`
+	HashPrefix string    `json:"hash_prefix"`
+	State      KeyState  `json:"state"`
+	Revoked    bool      `json:"revoked"`
+	At         time.Time `json:"at"`
+}
+
+// RevocationFeedResponse is one page of the revocation feed.
+type RevocationFeedResponse struct {
+	Entries []*RevocationEntryResponse `json:"entries"`
+	Next    string                     `json:"next"`
+	HasMore bool                       `json:"has_more"`
+}
+
+// KeyEventResponse is the data of a key event on the event stream. EventID
+// repeats the event's SSE id, to resume from with Last-Event-ID.
+type KeyEventResponse struct {
+	EventID    string    `json:"event_id"`
+	Type       string    `json:"type"`
+	KeyID      string    `json:"key_id"`
+	TenantID   string    `json:"tenant_id"`
+	AppID      string    `json:"app_id"`
+	State      KeyState  `json:"state"`
+	Reason     string    `json:"reason,omitempty"`
+	ReasonCode string    `json:"reason_code,omitempty"`
+	ActorID    string    `json:"actor_id,omitempty"`
+	At         time.Time `json:"at"`
+}
+
+// CaptureResponse is the API representation of a debug capture. Secrets
+// are redacted before captures are stored.
+type CaptureResponse struct {
+	ID            string            `json:"id"`
+	KeyID         string            `json:"key_id"`
+	Method        string            `json:"method"`
+	Path          string            `json:"path"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	Body          string            `json:"body,omitempty"`
+	BodyTruncated bool              `json:"body_truncated,omitempty"`
+	CapturedAt    time.Time         `json:"captured_at"`
+}
+
+// HashReportsResponse is the API representation of a revoke-by-hash or
+// validate-hashes call: one report per submitted hash, in order, and counts
+// per status.
+type HashReportsResponse struct {
+	Reports        []HashReportResponse `json:"reports"`
+	Found          int                  `json:"found,omitempty"`
+	Revoked        int                  `json:"revoked,omitempty"`
+	AlreadyRevoked int                  `json:"already_revoked,omitempty"`
+	NotFound       int                  `json:"not_found,omitempty"`
+}
+
+// HashTombstonesResponse is one page of hash tombstones, with counts per
+// reason over every tombstone the filter matches.
+type HashTombstonesResponse struct {
+	Tombstones []*HashTombstoneResponse        `json:"tombstones"`
+	Total      int64                           `json:"total"`
+	Reasons    []*TombstoneReasonCountResponse `json:"reasons"`
+	Limit      int                             `json:"limit"`
+}
+
+// HashTombstoneResponse is the API representation of a hash tombstone. As
+// in the revocation feed, only the first characters of the hash are shown.
+type HashTombstoneResponse struct {
+	HashPrefix string    `json:"hash_prefix"`
+	KeyID      string    `json:"key_id"`
+	TenantID   string    `json:"tenant_id"`
+	AppID      string    `json:"app_id,omitempty"`
+	Reason     string    `json:"reason"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TombstoneReasonCountResponse is how many hash tombstones had one reason.
+type TombstoneReasonCountResponse struct {
+	Reason string `json:"reason"`
+	Count  int64  `json:"count"`
+}
+
+// KeysByCreatorResponse is the response for listing keys by creator.
+type KeysByCreatorResponse struct {
+	CreatedBy string         `json:"created_by"`
+	Keys      []*KeyResponse `json:"keys"`
+	Limit     int            `json:"limit"`
+}
+
+// CreatorRevokeResponse is the outcome of revoking keys by creator.
+type CreatorRevokeResponse struct {
+	CreatedBy      string   `json:"created_by"`
+	KeyIDs         []string `json:"key_ids"`
+	AlreadyRevoked int      `json:"already_revoked"`
+	Failed         int      `json:"failed"`
+	DryRun         bool     `json:"dry_run"`
+}
+
+// LabelRevokeResponse reports a revocation by label selector.
+type LabelRevokeResponse struct {
+	LabelSelector  string   `json:"label_selector"`
+	KeyIDs         []string `json:"key_ids"`
+	AlreadyRevoked int      `json:"already_revoked"`
+	Failed         int      `json:"failed"`
+	DryRun         bool     `json:"dry_run"`
+}
+
+// HashReportResponse is the outcome for one submitted hash.
+type HashReportResponse struct {
+	Hash   string   `json:"hash"`
+	Status string   `json:"status"`
+	KeyID  string   `json:"key_id,omitempty"`
+	State  KeyState `json:"state,omitempty"`
+}
+
+// MaintenanceResponse is the API representation of a store maintenance run.
+type MaintenanceResponse struct {
+	Backend   string               `json:"backend"`
+	Actions   []string             `json:"actions"`
+	Tables    []TableStatsResponse `json:"tables"`
+	Advice    []string             `json:"advice,omitempty"`
+	StartedAt time.Time            `json:"started_at"`
+	Duration  Duration             `json:"duration"`
+}
+
+// TableStatsResponse describes one table or collection in a maintenance
+// report. Dead rows are only reported by PostgreSQL.
+type TableStatsResponse struct {
+	Name      string `json:"name"`
+	Rows      int64  `json:"rows"`
+	DeadRows  int64  `json:"dead_rows,omitempty"`
+	SizeBytes int64  `json:"size_bytes"`
+}
+
+// StorageReportResponse is the API representation of a per-tenant storage
+// report.
+type StorageReportResponse struct {
+	Tenants     []TenantStorageResponse `json:"tenants"`
+	Total       TenantStorageResponse   `json:"total"`
+	TopUsage    []TenantUsageResponse   `json:"top_usage"`
+	GeneratedAt time.Time               `json:"generated_at"`
+	Duration    Duration                `json:"duration"`
+}
+
+// TenantStorageResponse counts the rows one tenant holds in a storage
+// report, or every tenant's for its total. UsageBytes is an estimate that
+// leaves out indexes.
+type TenantStorageResponse struct {
+	TenantID    string           `json:"tenant_id,omitempty"`
+	Keys        int64            `json:"keys"`
+	KeysByState map[string]int64 `json:"keys_by_state"`
+	Policies    int64            `json:"policies"`
+	Scopes      int64            `json:"scopes"`
+	Rotations   int64            `json:"rotations"`
+	UsageRows   int64            `json:"usage_rows"`
+	UsageBytes  int64            `json:"usage_bytes"`
+}
+
+// TenantUsageResponse is one tenant's usage volume in a storage report.
+type TenantUsageResponse struct {
+	TenantID   string `json:"tenant_id"`
+	UsageRows  int64  `json:"usage_rows"`
+	UsageBytes int64  `json:"usage_bytes"`
+}
+
+// UsageRecordingResponse is the API representation of the usage recording
+// policy.
+type UsageRecordingResponse struct {
+	Rules      []usage.RecordingRule `json:"rules"`
+	Default    string                `json:"default"`
+	SampleRate int                   `json:"sample_rate,omitempty"`
+}
+
+// RuntimeConfigResponse is the API representation of the engine's runtime
+// configuration. Durations of features that are off are "PT0S".
+type RuntimeConfigResponse struct {
+	ValidationCacheTTL    Duration `json:"validation_cache_ttl"`
+	EndpointFlushInterval Duration `json:"endpoint_flush_interval"`
+	CapturePurgeInterval  Duration `json:"capture_purge_interval"`
+	MaintenanceInterval   Duration `json:"maintenance_interval"`
+	QuotaForecastInterval Duration `json:"quota_forecast_interval"`
+	FailureThreshold      int      `json:"failure_threshold"`
+	FailureWindow         Duration `json:"failure_window"`
+	UsageSampleRate       int      `json:"usage_sample_rate"`
+}
+
+// ReadOnlyResponse is the API representation of read-only mode.
+type ReadOnlyResponse struct {
+	ReadOnly       bool `json:"read_only"`
+	UsageRecording bool `json:"usage_recording"`
+}
+
+// IntegrationGuideResponse tells a tenant how to call the API with its
+// keys. It is generated from live configuration and never holds key
+// material: example keys are fake and keys are only counted.
+type IntegrationGuideResponse struct {
+	TenantID   string                       `json:"tenant_id"`
+	AppID      string                       `json:"app_id,omitempty"`
+	BaseURL    string                       `json:"base_url"`
+	Validation IntegrationEndpointResponse  `json:"validation"`
+	Keys       []IntegrationKeyResponse     `json:"keys"`
+	Headers    []middleware.Header          `json:"headers"`
+	Snippets   []IntegrationSnippetResponse `json:"snippets"`
+	Scopes     []IntegrationScopeResponse   `json:"scopes"`
+}
+
+// IntegrationEndpointResponse is an endpoint and an example JSON body for it.
+type IntegrationEndpointResponse struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body"`
+}
+
+// IntegrationKeyResponse is one prefix and environment in use by the
+// tenant's active keys, with a fake key in the same shape.
+type IntegrationKeyResponse struct {
+	Prefix      string         `json:"prefix"`
+	Environment KeyEnvironment `json:"environment"`
+	ActiveKeys  int            `json:"active_keys"`
+	ExampleKey  string         `json:"example_key"`
+}
+
+// IntegrationSnippetResponse is example code calling the validation
+// endpoint.
+type IntegrationSnippetResponse struct {
+	Language string `json:"language"`
+	Code     string `json:"code"`
+}
+
+// IntegrationScopeResponse is one scope in the tenant's catalog.
+type IntegrationScopeResponse struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parent      string `json:"parent,omitempty"`
+}
@@ -5,32 +5,58 @@ package audithook
 import (
 	"context"
 	"fmt"
+	"slices"
+	"sync"
+	"time"
 
 	log "github.com/xraph/go-utils/log"
 
+	"github.com/xraph/keysmith/group"
 	"github.com/xraph/keysmith/id"
 	"github.com/xraph/keysmith/key"
 	"github.com/xraph/keysmith/plugin"
 	"github.com/xraph/keysmith/policy"
 	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/tenant"
+	"github.com/xraph/keysmith/transfer"
+	"github.com/xraph/keysmith/usage"
 )
 
 // Compile-time interface checks.
 var (
-	_ plugin.Plugin              = (*Extension)(nil)
-	_ plugin.KeyCreated          = (*Extension)(nil)
-	_ plugin.KeyCreateFailed     = (*Extension)(nil)
-	_ plugin.KeyValidated        = (*Extension)(nil)
-	_ plugin.KeyValidationFailed = (*Extension)(nil)
-	_ plugin.KeyRotated          = (*Extension)(nil)
-	_ plugin.KeyRevoked          = (*Extension)(nil)
-	_ plugin.KeySuspended        = (*Extension)(nil)
-	_ plugin.KeyReactivated      = (*Extension)(nil)
-	_ plugin.KeyExpired          = (*Extension)(nil)
-	_ plugin.KeyRateLimited      = (*Extension)(nil)
-	_ plugin.PolicyCreated       = (*Extension)(nil)
-	_ plugin.PolicyUpdated       = (*Extension)(nil)
-	_ plugin.PolicyDeleted       = (*Extension)(nil)
+	_ plugin.Plugin                        = (*Extension)(nil)
+	_ plugin.KeyCreatedV2                  = (*Extension)(nil)
+	_ plugin.KeyCreateFailedV2             = (*Extension)(nil)
+	_ plugin.KeyValidatedV2                = (*Extension)(nil)
+	_ plugin.KeyValidationFailedV2         = (*Extension)(nil)
+	_ plugin.KeyRotatedV2                  = (*Extension)(nil)
+	_ plugin.KeyRevokedV2                  = (*Extension)(nil)
+	_ plugin.KeySuspendedV2                = (*Extension)(nil)
+	_ plugin.KeyReactivatedV2              = (*Extension)(nil)
+	_ plugin.KeyExpiredV2                  = (*Extension)(nil)
+	_ plugin.KeyRateLimitedV2              = (*Extension)(nil)
+	_ plugin.TenantRateLimitedV2           = (*Extension)(nil)
+	_ plugin.KeyAdaptivelyLimitedV2        = (*Extension)(nil)
+	_ plugin.KeyQuotaExceededV2            = (*Extension)(nil)
+	_ plugin.KeyQuotaForecastWarningV2     = (*Extension)(nil)
+	_ plugin.KeyConsumerMismatchV2         = (*Extension)(nil)
+	_ plugin.KeyRotationOverdueV2          = (*Extension)(nil)
+	_ plugin.KeyFormatDeprecatedV2         = (*Extension)(nil)
+	_ plugin.KeyFlagsChangedV2             = (*Extension)(nil)
+	_ plugin.SuspiciousValidationPatternV2 = (*Extension)(nil)
+	_ plugin.KeyCompromisedV2              = (*Extension)(nil)
+	_ plugin.KeyDebugEnabledV2             = (*Extension)(nil)
+	_ plugin.KeyExportedV2                 = (*Extension)(nil)
+	_ plugin.KeyImportedV2                 = (*Extension)(nil)
+	_ plugin.UsageIdentifiersErasedV2      = (*Extension)(nil)
+	_ plugin.RuntimeConfigChangedV2        = (*Extension)(nil)
+	_ plugin.PolicyCreatedV2               = (*Extension)(nil)
+	_ plugin.PolicyUpdatedV2               = (*Extension)(nil)
+	_ plugin.PolicyDeletedV2               = (*Extension)(nil)
+	_ plugin.GroupMembershipChangedV2      = (*Extension)(nil)
+	_ plugin.KeyTransferChangedV2          = (*Extension)(nil)
+	_ plugin.TenantLockdownChangedV2       = (*Extension)(nil)
+	_ plugin.Shutdown                      = (*Extension)(nil)
 )
 
 // Recorder is the interface that audit backends must implement.
@@ -38,8 +64,12 @@ type Recorder interface {
 	Record(ctx context.Context, event *AuditEvent) error
 }
 
-// AuditEvent is a local representation of an audit event.
+// AuditEvent is a local representation of an audit event. ID is unique per
+// event and unchanged when a spooled event is replayed, so backends can
+// deduplicate.
 type AuditEvent struct {
+	ID         string         `json:"id"`
+	Timestamp  time.Time      `json:"timestamp"`
 	Action     string         `json:"action"`
 	Resource   string         `json:"resource"`
 	Category   string         `json:"category"`
@@ -73,25 +103,52 @@ const (
 
 // Action constants.
 const (
-	ActionKeyCreated          = "keysmith.key.created"
-	ActionKeyCreateFailed     = "keysmith.key.create_failed"
-	ActionKeyValidated        = "keysmith.key.validated"
-	ActionKeyValidationFailed = "keysmith.key.validation_failed"
-	ActionKeyRotated          = "keysmith.key.rotated"
-	ActionKeyRevoked          = "keysmith.key.revoked"
-	ActionKeySuspended        = "keysmith.key.suspended"
-	ActionKeyReactivated      = "keysmith.key.reactivated"
-	ActionKeyExpired          = "keysmith.key.expired"
-	ActionKeyRateLimited      = "keysmith.key.rate_limited"
-	ActionPolicyCreated       = "keysmith.policy.created"
-	ActionPolicyUpdated       = "keysmith.policy.updated"
-	ActionPolicyDeleted       = "keysmith.policy.deleted"
+	ActionKeyCreated           = "keysmith.key.created"
+	ActionKeyCreateFailed      = "keysmith.key.create_failed"
+	ActionKeyValidated         = "keysmith.key.validated"
+	ActionKeyValidationFailed  = "keysmith.key.validation_failed"
+	ActionKeyRotated           = "keysmith.key.rotated"
+	ActionKeyRevoked           = "keysmith.key.revoked"
+	ActionKeySuspended         = "keysmith.key.suspended"
+	ActionKeyReactivated       = "keysmith.key.reactivated"
+	ActionKeyExpired           = "keysmith.key.expired"
+	ActionKeyRateLimited       = "keysmith.key.rate_limited"
+	ActionTenantRateLimited    = "keysmith.tenant.rate_limited"
+	ActionKeyAdaptivelyLimited = "keysmith.key.adaptively_limited"
+	ActionKeyQuotaExceeded     = "keysmith.key.quota_exceeded"
+	ActionKeyQuotaForecast     = "keysmith.key.quota_forecast_warning"
+	ActionKeyConsumerMismatch  = "keysmith.key.consumer_mismatch"
+	ActionKeyRotationOverdue   = "keysmith.key.rotation_overdue"
+	ActionKeyFormatDeprecated  = "keysmith.key.format_deprecated"
+	ActionKeyFlagsChanged      = "keysmith.key.flags_changed"
+	ActionSuspiciousValidation = "keysmith.key.suspicious_validation"
+	ActionKeyCompromised       = "keysmith.key.compromised"
+	ActionKeyDebugEnabled      = "keysmith.key.debug_enabled"
+	ActionKeyExported          = "keysmith.key.exported"
+	ActionKeyImported          = "keysmith.key.imported"
+	ActionUsageErased          = "keysmith.usage.identifiers_erased"
+	ActionConfigChanged        = "keysmith.engine.config_changed"
+	ActionPolicyCreated        = "keysmith.policy.created"
+	ActionPolicyUpdated        = "keysmith.policy.updated"
+	ActionPolicyDeleted        = "keysmith.policy.deleted"
+	ActionGroupMembership      = "keysmith.group.membership_changed"
+	ActionKeyTransferInitiated = "keysmith.key.transfer_initiated"
+	ActionKeyTransferAccepted  = "keysmith.key.transfer_accepted"
+	ActionKeyTransferCancelled = "keysmith.key.transfer_cancelled"
+	ActionKeyTransferExpired   = "keysmith.key.transfer_expired"
+	ActionTenantLockedDown     = "keysmith.tenant.locked_down"
+	ActionTenantRecovering     = "keysmith.tenant.recovery_progress"
+	ActionTenantRecoveryHalted = "keysmith.tenant.recovery_halted"
+	ActionTenantRecovered      = "keysmith.tenant.recovered"
 )
 
 // Resource constants.
 const (
 	ResourceKey    = "key"
 	ResourcePolicy = "policy"
+	ResourceGroup  = "group"
+	ResourceTenant = "tenant"
+	ResourceEngine = "engine"
 )
 
 // Category constants.
@@ -100,6 +157,8 @@ const (
 	CategoryKeyValidation   = "key_validation"
 	CategoryKeySecurity     = "key_security"
 	CategoryPolicyLifecycle = "policy_lifecycle"
+	CategoryDataPrivacy     = "data_privacy"
+	CategoryConfiguration   = "configuration"
 )
 
 // Extension bridges Keysmith lifecycle events to an audit trail backend.
@@ -107,13 +166,26 @@ type Extension struct {
 	recorder Recorder
 	enabled  map[string]bool
 	logger   log.Logger
+
+	// spool holds events the recorder rejected. Nil without
+	// WithFallbackStore. spoolMu orders recording against replay; replayMu
+	// keeps replays from overlapping.
+	spool          Spool
+	spoolMu        sync.Mutex
+	replayMu       sync.Mutex
+	replayInterval time.Duration
+
+	loopMu  sync.Mutex
+	stop    context.CancelFunc
+	stopped chan struct{}
 }
 
 // New creates an Extension that emits audit events.
 func New(r Recorder, opts ...Option) *Extension {
 	e := &Extension{
-		recorder: r,
-		logger:   log.NewNoopLogger(),
+		recorder:       r,
+		logger:         log.NewNoopLogger(),
+		replayInterval: DefaultReplayInterval,
 	}
 	for _, opt := range opts {
 		opt(e)
@@ -124,105 +196,524 @@ func New(r Recorder, opts ...Option) *Extension {
 // Name implements plugin.Plugin.
 func (e *Extension) Name() string { return "audit-hook" }
 
-// OnKeyCreated implements plugin.KeyCreated.
-func (e *Extension) OnKeyCreated(ctx context.Context, k *key.Key) error {
-	return e.record(ctx, ActionKeyCreated, SeverityInfo, OutcomeSuccess,
+// OnKeyCreatedV2 implements plugin.KeyCreatedV2.
+// Keys delivered to a secrets manager also record the destination path, and
+// keys created with accepted terms record the version and time.
+func (e *Extension) OnKeyCreatedV2(ctx context.Context, k *key.Key, meta plugin.EventMeta) error {
+	return e.record(ctx, meta, ActionKeyCreated, SeverityInfo, OutcomeSuccess,
 		ResourceKey, k.ID.String(), CategoryKeyLifecycle, nil,
-		"key_name", k.Name, "environment", string(k.Environment),
+		slices.Concat([]any{"key_name", k.Name, "environment", string(k.Environment), "created_by", k.CreatedBy}, deliveryPairs(k), termsPairs(k))...,
 	)
 }
 
-// OnKeyCreateFailed implements plugin.KeyCreateFailed.
-func (e *Extension) OnKeyCreateFailed(ctx context.Context, k *key.Key, createErr error) error {
-	return e.record(ctx, ActionKeyCreateFailed, SeverityWarning, OutcomeFailure,
+// OnKeyCreated implements plugin.KeyCreated for callers without event meta.
+func (e *Extension) OnKeyCreated(ctx context.Context, k *key.Key) error {
+	return e.OnKeyCreatedV2(ctx, k, plugin.EventMeta{})
+}
+
+// OnKeyCreateFailedV2 implements plugin.KeyCreateFailedV2.
+func (e *Extension) OnKeyCreateFailedV2(ctx context.Context, k *key.Key, createErr error, meta plugin.EventMeta) error {
+	return e.record(ctx, meta, ActionKeyCreateFailed, SeverityWarning, OutcomeFailure,
 		ResourceKey, k.ID.String(), CategoryKeyLifecycle, createErr,
+		deliveryPairs(k)...,
 	)
 }
 
-// OnKeyValidated implements plugin.KeyValidated.
-func (e *Extension) OnKeyValidated(ctx context.Context, k *key.Key) error {
-	return e.record(ctx, ActionKeyValidated, SeverityInfo, OutcomeSuccess,
+// OnKeyCreateFailed implements plugin.KeyCreateFailed for callers without event meta.
+func (e *Extension) OnKeyCreateFailed(ctx context.Context, k *key.Key, createErr error) error {
+	return e.OnKeyCreateFailedV2(ctx, k, createErr, plugin.EventMeta{})
+}
+
+// deliveryPairs returns the delivered_to entry for keys created with a
+// secrets-manager destination.
+func deliveryPairs(k *key.Key) []any {
+	if path, ok := k.Metadata[key.MetadataDeliveredTo].(string); ok && path != "" {
+		return []any{"delivered_to", path}
+	}
+	return nil
+}
+
+// termsPairs returns the accepted terms entries for keys that accepted terms.
+func termsPairs(k *key.Key) []any {
+	if k.AcceptedTermsVersion == "" || k.AcceptedTermsAt == nil {
+		return nil
+	}
+	return []any{"accepted_terms_version", k.AcceptedTermsVersion, "accepted_terms_at", k.AcceptedTermsAt.UTC().Format(time.RFC3339)}
+}
+
+// OnKeyValidatedV2 implements plugin.KeyValidatedV2.
+func (e *Extension) OnKeyValidatedV2(ctx context.Context, k *key.Key, meta plugin.EventMeta) error {
+	return e.record(ctx, meta, ActionKeyValidated, SeverityInfo, OutcomeSuccess,
 		ResourceKey, k.ID.String(), CategoryKeyValidation, nil,
 	)
 }
 
-// OnKeyValidationFailed implements plugin.KeyValidationFailed.
-func (e *Extension) OnKeyValidationFailed(ctx context.Context, _ string, validationErr error) error {
-	return e.record(ctx, ActionKeyValidationFailed, SeverityWarning, OutcomeFailure,
+// OnKeyValidated implements plugin.KeyValidated for callers without event meta.
+func (e *Extension) OnKeyValidated(ctx context.Context, k *key.Key) error {
+	return e.OnKeyValidatedV2(ctx, k, plugin.EventMeta{})
+}
+
+// OnKeyValidationFailedV2 implements plugin.KeyValidationFailedV2.
+func (e *Extension) OnKeyValidationFailedV2(ctx context.Context, fingerprint string, validationErr error, meta plugin.EventMeta) error {
+	return e.record(ctx, meta, ActionKeyValidationFailed, SeverityWarning, OutcomeFailure,
 		ResourceKey, "", CategoryKeyValidation, validationErr,
+		"fingerprint", fingerprint,
 	)
 }
 
-// OnKeyRotated implements plugin.KeyRotated.
-func (e *Extension) OnKeyRotated(ctx context.Context, k *key.Key, rec *rotation.Record) error {
-	return e.record(ctx, ActionKeyRotated, SeverityCritical, OutcomeSuccess,
+// OnKeyValidationFailed implements plugin.KeyValidationFailed for callers without event meta.
+func (e *Extension) OnKeyValidationFailed(ctx context.Context, _ string, validationErr error) error {
+	return e.OnKeyValidationFailedV2(ctx, "", validationErr, plugin.EventMeta{})
+}
+
+// OnKeyRotatedV2 implements plugin.KeyRotatedV2.
+func (e *Extension) OnKeyRotatedV2(ctx context.Context, k *key.Key, rec *rotation.Record, meta plugin.EventMeta) error {
+	return e.record(ctx, meta, ActionKeyRotated, SeverityCritical, OutcomeSuccess,
 		ResourceKey, k.ID.String(), CategoryKeySecurity, nil,
 		"reason", string(rec.Reason), "grace_ttl", rec.GraceTTL.String(),
 	)
 }
 
-// OnKeyRevoked implements plugin.KeyRevoked.
-func (e *Extension) OnKeyRevoked(ctx context.Context, k *key.Key, reason string) error {
-	return e.record(ctx, ActionKeyRevoked, SeverityCritical, OutcomeSuccess,
+// OnKeyRotated implements plugin.KeyRotated for callers without event meta.
+func (e *Extension) OnKeyRotated(ctx context.Context, k *key.Key, rec *rotation.Record) error {
+	return e.OnKeyRotatedV2(ctx, k, rec, plugin.EventMeta{})
+}
+
+// OnKeyRevokedV2 implements plugin.KeyRevokedV2.
+func (e *Extension) OnKeyRevokedV2(ctx context.Context, k *key.Key, reason string, meta plugin.EventMeta) error {
+	return e.record(ctx, meta, ActionKeyRevoked, SeverityCritical, OutcomeSuccess,
 		ResourceKey, k.ID.String(), CategoryKeySecurity, nil,
 		"reason", reason,
 	)
 }
 
-// OnKeySuspended implements plugin.KeySuspended.
+// OnKeyRevoked implements plugin.KeyRevoked for callers without event meta.
+func (e *Extension) OnKeyRevoked(ctx context.Context, k *key.Key, reason string) error {
+	return e.OnKeyRevokedV2(ctx, k, reason, plugin.EventMeta{})
+}
+
+// OnKeySuspendedV2 implements plugin.KeySuspendedV2.
+func (e *Extension) OnKeySuspendedV2(ctx context.Context, k *key.Key, meta plugin.EventMeta) error {
+	return e.record(ctx, meta, ActionKeySuspended, SeverityWarning, OutcomeSuccess,
+		ResourceKey, k.ID.String(), CategoryKeySecurity, nil,
+	)
+}
+
+// OnKeySuspended implements plugin.KeySuspended for callers without event meta.
 func (e *Extension) OnKeySuspended(ctx context.Context, k *key.Key) error {
-	return e.record(ctx, ActionKeySuspended, SeverityWarning, OutcomeSuccess,
+	return e.OnKeySuspendedV2(ctx, k, plugin.EventMeta{})
+}
+
+// OnKeyReactivatedV2 implements plugin.KeyReactivatedV2.
+func (e *Extension) OnKeyReactivatedV2(ctx context.Context, k *key.Key, meta plugin.EventMeta) error {
+	return e.record(ctx, meta, ActionKeyReactivated, SeverityInfo, OutcomeSuccess,
 		ResourceKey, k.ID.String(), CategoryKeySecurity, nil,
 	)
 }
 
-// OnKeyReactivated implements plugin.KeyReactivated.
+// OnKeyReactivated implements plugin.KeyReactivated for callers without event meta.
 func (e *Extension) OnKeyReactivated(ctx context.Context, k *key.Key) error {
-	return e.record(ctx, ActionKeyReactivated, SeverityInfo, OutcomeSuccess,
-		ResourceKey, k.ID.String(), CategoryKeySecurity, nil,
+	return e.OnKeyReactivatedV2(ctx, k, plugin.EventMeta{})
+}
+
+// OnKeyExpiredV2 implements plugin.KeyExpiredV2.
+func (e *Extension) OnKeyExpiredV2(ctx context.Context, k *key.Key, meta plugin.EventMeta) error {
+	return e.record(ctx, meta, ActionKeyExpired, SeverityWarning, OutcomeSuccess,
+		ResourceKey, k.ID.String(), CategoryKeyLifecycle, nil,
 	)
 }
 
-// OnKeyExpired implements plugin.KeyExpired.
+// OnKeyExpired implements plugin.KeyExpired for callers without event meta.
 func (e *Extension) OnKeyExpired(ctx context.Context, k *key.Key) error {
-	return e.record(ctx, ActionKeyExpired, SeverityWarning, OutcomeSuccess,
-		ResourceKey, k.ID.String(), CategoryKeyLifecycle, nil,
+	return e.OnKeyExpiredV2(ctx, k, plugin.EventMeta{})
+}
+
+// OnKeyRateLimitedV2 implements plugin.KeyRateLimitedV2.
+func (e *Extension) OnKeyRateLimitedV2(ctx context.Context, k *key.Key, meta plugin.EventMeta) error {
+	return e.record(ctx, meta, ActionKeyRateLimited, SeverityWarning, OutcomeFailure,
+		ResourceKey, k.ID.String(), CategoryKeySecurity, nil,
 	)
 }
 
-// OnKeyRateLimited implements plugin.KeyRateLimited.
+// OnKeyRateLimited implements plugin.KeyRateLimited for callers without event meta.
 func (e *Extension) OnKeyRateLimited(ctx context.Context, k *key.Key) error {
-	return e.record(ctx, ActionKeyRateLimited, SeverityWarning, OutcomeFailure,
+	return e.OnKeyRateLimitedV2(ctx, k, plugin.EventMeta{})
+}
+
+// OnTenantRateLimitedV2 implements plugin.TenantRateLimitedV2. The event is
+// recorded against the tenant, with the key that hit the ceiling.
+func (e *Extension) OnTenantRateLimitedV2(ctx context.Context, k *key.Key, meta plugin.EventMeta) error {
+	return e.record(ctx, meta, ActionTenantRateLimited, SeverityWarning, OutcomeFailure,
+		ResourceTenant, k.TenantID, CategoryKeySecurity, nil,
+		"key_id", k.ID.String(),
+	)
+}
+
+// OnTenantRateLimited implements plugin.TenantRateLimited for callers without event meta.
+func (e *Extension) OnTenantRateLimited(ctx context.Context, k *key.Key) error {
+	return e.OnTenantRateLimitedV2(ctx, k, plugin.EventMeta{})
+}
+
+// OnKeyAdaptivelyLimitedV2 implements plugin.KeyAdaptivelyLimitedV2.
+func (e *Extension) OnKeyAdaptivelyLimitedV2(ctx context.Context, k *key.Key, p *key.AdaptivePenalty, meta plugin.EventMeta) error {
+	return e.record(ctx, meta, ActionKeyAdaptivelyLimited, SeverityWarning, OutcomeSuccess,
 		ResourceKey, k.ID.String(), CategoryKeySecurity, nil,
+		"error_ratio", p.ErrorRatio, "requests", p.Requests,
+		"base_limit", p.BaseLimit, "limit", p.Limit, "until", p.Until.Format(time.RFC3339),
 	)
 }
 
-// OnPolicyCreated implements plugin.PolicyCreated.
-func (e *Extension) OnPolicyCreated(ctx context.Context, pol *policy.Policy) error {
-	return e.record(ctx, ActionPolicyCreated, SeverityInfo, OutcomeSuccess,
+// OnKeyAdaptivelyLimited implements plugin.KeyAdaptivelyLimited for callers without event meta.
+func (e *Extension) OnKeyAdaptivelyLimited(ctx context.Context, k *key.Key, p *key.AdaptivePenalty) error {
+	return e.OnKeyAdaptivelyLimitedV2(ctx, k, p, plugin.EventMeta{})
+}
+
+// OnKeyQuotaExceededV2 implements plugin.KeyQuotaExceededV2.
+func (e *Extension) OnKeyQuotaExceededV2(ctx context.Context, k *key.Key, q *key.QuotaExceeded, meta plugin.EventMeta) error {
+	pairs := []any{"period", q.Period, "limit", q.Limit, "used", q.Used, "resets_at", q.WindowEnd.UTC().Format(time.RFC3339)}
+	if q.PoolID != nil {
+		pairs = append(pairs, "pool_id", q.PoolID.String())
+	}
+	return e.record(ctx, meta, ActionKeyQuotaExceeded, SeverityWarning, OutcomeFailure,
+		ResourceKey, k.ID.String(), CategoryKeySecurity, nil, pairs...,
+	)
+}
+
+// OnKeyQuotaExceeded implements plugin.KeyQuotaExceeded for callers without event meta.
+func (e *Extension) OnKeyQuotaExceeded(ctx context.Context, k *key.Key, q *key.QuotaExceeded) error {
+	return e.OnKeyQuotaExceededV2(ctx, k, q, plugin.EventMeta{})
+}
+
+// OnKeyQuotaForecastWarningV2 implements plugin.KeyQuotaForecastWarningV2.
+func (e *Extension) OnKeyQuotaForecastWarningV2(ctx context.Context, k *key.Key, f *key.QuotaForecast, meta plugin.EventMeta) error {
+	pairs := []any{"quota", f.Quota, "used", f.Used, "burn_rate", f.BurnRate, "projected_total", f.ProjectedTotal}
+	if f.ProjectedExhaustion != nil {
+		pairs = append(pairs, "projected_exhaustion", f.ProjectedExhaustion.UTC().Format(time.RFC3339))
+	}
+	return e.record(ctx, meta, ActionKeyQuotaForecast, SeverityWarning, OutcomeSuccess,
+		ResourceKey, k.ID.String(), CategoryKeyLifecycle, nil, pairs...,
+	)
+}
+
+// OnKeyQuotaForecastWarning implements plugin.KeyQuotaForecastWarning for callers without event meta.
+func (e *Extension) OnKeyQuotaForecastWarning(ctx context.Context, k *key.Key, f *key.QuotaForecast) error {
+	return e.OnKeyQuotaForecastWarningV2(ctx, k, f, plugin.EventMeta{})
+}
+
+// OnKeyConsumerMismatchV2 implements plugin.KeyConsumerMismatchV2.
+func (e *Extension) OnKeyConsumerMismatchV2(ctx context.Context, k *key.Key, service string, meta plugin.EventMeta) error {
+	outcome := OutcomeSuccess
+	if k.EnforceConsumer {
+		outcome = OutcomeFailure
+	}
+	return e.record(ctx, meta, ActionKeyConsumerMismatch, SeverityWarning, outcome,
+		ResourceKey, k.ID.String(), CategoryKeySecurity, nil,
+		"intended_consumer", k.IntendedConsumer, "consumer_service", service,
+	)
+}
+
+// OnKeyConsumerMismatch implements plugin.KeyConsumerMismatch for callers without event meta.
+func (e *Extension) OnKeyConsumerMismatch(ctx context.Context, k *key.Key, service string) error {
+	return e.OnKeyConsumerMismatchV2(ctx, k, service, plugin.EventMeta{})
+}
+
+// OnKeyRotationOverdueV2 implements plugin.KeyRotationOverdueV2.
+func (e *Extension) OnKeyRotationOverdueV2(ctx context.Context, k *key.Key, due *key.RotationDue, meta plugin.EventMeta) error {
+	return e.record(ctx, meta, ActionKeyRotationOverdue, SeverityWarning, OutcomeSuccess,
+		ResourceKey, k.ID.String(), CategoryKeySecurity, nil,
+		"due_at", due.DueAt.UTC().Format(time.RFC3339), "days_remaining", due.DaysRemaining,
+	)
+}
+
+// OnKeyRotationOverdue implements plugin.KeyRotationOverdue for callers without event meta.
+func (e *Extension) OnKeyRotationOverdue(ctx context.Context, k *key.Key, due *key.RotationDue) error {
+	return e.OnKeyRotationOverdueV2(ctx, k, due, plugin.EventMeta{})
+}
+
+// OnKeyFormatDeprecatedV2 implements plugin.KeyFormatDeprecatedV2.
+func (e *Extension) OnKeyFormatDeprecatedV2(ctx context.Context, k *key.Key, d *key.FormatDeprecation, meta plugin.EventMeta) error {
+	kv := []any{"format", d.Format}
+	if !d.Sunset.IsZero() {
+		kv = append(kv, "sunset", d.Sunset.UTC().Format(time.RFC3339))
+	}
+	return e.record(ctx, meta, ActionKeyFormatDeprecated, SeverityWarning, OutcomeSuccess,
+		ResourceKey, k.ID.String(), CategoryKeySecurity, nil, kv...,
+	)
+}
+
+// OnKeyFormatDeprecated implements plugin.KeyFormatDeprecated for callers without event meta.
+func (e *Extension) OnKeyFormatDeprecated(ctx context.Context, k *key.Key, d *key.FormatDeprecation) error {
+	return e.OnKeyFormatDeprecatedV2(ctx, k, d, plugin.EventMeta{})
+}
+
+// OnKeyFlagsChangedV2 implements plugin.KeyFlagsChangedV2. Adding a flag that
+// skips a validation check is recorded as a warning.
+func (e *Extension) OnKeyFlagsChangedV2(ctx context.Context, k *key.Key, added, removed key.Flags, meta plugin.EventMeta) error {
+	severity := SeverityInfo
+	for _, f := range added {
+		if f.SkipsCheck() {
+			severity = SeverityWarning
+		}
+	}
+	return e.record(ctx, meta, ActionKeyFlagsChanged, severity, OutcomeSuccess,
+		ResourceKey, k.ID.String(), CategoryKeySecurity, nil,
+		"added", added, "removed", removed,
+	)
+}
+
+// OnKeyFlagsChanged implements plugin.KeyFlagsChanged for callers without event meta.
+func (e *Extension) OnKeyFlagsChanged(ctx context.Context, k *key.Key, added, removed key.Flags) error {
+	return e.OnKeyFlagsChangedV2(ctx, k, added, removed, plugin.EventMeta{})
+}
+
+// OnKeyCompromisedV2 implements plugin.KeyCompromisedV2.
+func (e *Extension) OnKeyCompromisedV2(ctx context.Context, k *key.Key, report *key.CompromiseReport, meta plugin.EventMeta) error {
+	return e.record(ctx, meta, ActionKeyCompromised, SeverityCritical, OutcomeSuccess,
+		ResourceKey, k.ID.String(), CategoryKeySecurity, nil,
+		"source", report.Source, "details", report.Details, "action", string(report.Action),
+	)
+}
+
+// OnKeyCompromised implements plugin.KeyCompromised for callers without event meta.
+func (e *Extension) OnKeyCompromised(ctx context.Context, k *key.Key, report *key.CompromiseReport) error {
+	return e.OnKeyCompromisedV2(ctx, k, report, plugin.EventMeta{})
+}
+
+// OnSuspiciousValidationPatternV2 implements plugin.SuspiciousValidationPatternV2.
+func (e *Extension) OnSuspiciousValidationPatternV2(ctx context.Context, p *key.FailurePattern, meta plugin.EventMeta) error {
+	return e.record(ctx, meta, ActionSuspiciousValidation, SeverityWarning, OutcomeFailure,
+		ResourceKey, "", CategoryKeySecurity, nil,
+		"fingerprint", p.Fingerprint, "count", p.Count, "window", p.Window.String(),
+	)
+}
+
+// OnSuspiciousValidationPattern implements plugin.SuspiciousValidationPattern for callers without event meta.
+func (e *Extension) OnSuspiciousValidationPattern(ctx context.Context, p *key.FailurePattern) error {
+	return e.OnSuspiciousValidationPatternV2(ctx, p, plugin.EventMeta{})
+}
+
+// OnKeyDebugEnabledV2 implements plugin.KeyDebugEnabledV2.
+func (e *Extension) OnKeyDebugEnabledV2(ctx context.Context, k *key.Key, meta plugin.EventMeta) error {
+	var until string
+	if k.DebugUntil != nil {
+		until = k.DebugUntil.Format(time.RFC3339)
+	}
+	return e.record(ctx, meta, ActionKeyDebugEnabled, SeverityWarning, OutcomeSuccess,
+		ResourceKey, k.ID.String(), CategoryKeySecurity, nil,
+		"sample_rate", k.DebugSampleRate, "until", until, "environment", string(k.Environment),
+	)
+}
+
+// OnKeyDebugEnabled implements plugin.KeyDebugEnabled for callers without event meta.
+func (e *Extension) OnKeyDebugEnabled(ctx context.Context, k *key.Key) error {
+	return e.OnKeyDebugEnabledV2(ctx, k, plugin.EventMeta{})
+}
+
+// OnKeyExportedV2 implements plugin.KeyExportedV2. Exports carry the key hash,
+// so they are recorded as critical.
+func (e *Extension) OnKeyExportedV2(ctx context.Context, k *key.Key, meta plugin.EventMeta) error {
+	return e.record(ctx, meta, ActionKeyExported, SeverityCritical, OutcomeSuccess,
+		ResourceKey, k.ID.String(), CategoryKeySecurity, nil,
+		"key_name", k.Name, "tenant_id", k.TenantID, "state", string(k.State),
+	)
+}
+
+// OnKeyExported implements plugin.KeyExported for callers without event meta.
+func (e *Extension) OnKeyExported(ctx context.Context, k *key.Key) error {
+	return e.OnKeyExportedV2(ctx, k, plugin.EventMeta{})
+}
+
+// OnKeyImportedV2 implements plugin.KeyImportedV2.
+func (e *Extension) OnKeyImportedV2(ctx context.Context, k *key.Key, meta plugin.EventMeta) error {
+	return e.record(ctx, meta, ActionKeyImported, SeverityCritical, OutcomeSuccess,
+		ResourceKey, k.ID.String(), CategoryKeySecurity, nil,
+		"key_name", k.Name, "tenant_id", k.TenantID, "state", string(k.State),
+	)
+}
+
+// OnKeyImported implements plugin.KeyImported for callers without event meta.
+func (e *Extension) OnKeyImported(ctx context.Context, k *key.Key) error {
+	return e.OnKeyImportedV2(ctx, k, plugin.EventMeta{})
+}
+
+// OnUsageIdentifiersErasedV2 implements plugin.UsageIdentifiersErasedV2.
+// The event records which kinds of identifier were matched, never their
+// values, so the audit trail does not keep what was erased.
+func (e *Extension) OnUsageIdentifiersErasedV2(ctx context.Context, er *usage.Erasure, meta plugin.EventMeta) error {
+	pairs := []any{
+		"by_ip", er.ByIP, "by_user_agent", er.ByUserAgent,
+		"records", er.Records, "batches", er.Batches, "complete", er.Complete,
+	}
+	if er.Before != nil {
+		pairs = append(pairs, "before", er.Before.UTC().Format(time.RFC3339))
+	}
+	outcome := OutcomeSuccess
+	if !er.Complete {
+		outcome = OutcomeFailure
+	}
+	return e.record(ctx, meta, ActionUsageErased, SeverityCritical, outcome,
+		ResourceTenant, er.TenantID, CategoryDataPrivacy, nil, pairs...,
+	)
+}
+
+// OnUsageIdentifiersErased implements plugin.UsageIdentifiersErased for callers without event meta.
+func (e *Extension) OnUsageIdentifiersErased(ctx context.Context, er *usage.Erasure) error {
+	return e.OnUsageIdentifiersErasedV2(ctx, er, plugin.EventMeta{})
+}
+
+// OnRuntimeConfigChangedV2 implements plugin.RuntimeConfigChangedV2. The
+// event records each changed setting with its old and new value.
+func (e *Extension) OnRuntimeConfigChangedV2(ctx context.Context, change *plugin.ConfigChange, meta plugin.EventMeta) error {
+	return e.record(ctx, meta, ActionConfigChanged, SeverityWarning, OutcomeSuccess,
+		ResourceEngine, "", CategoryConfiguration, nil,
+		"changes", change.Changes,
+	)
+}
+
+// OnRuntimeConfigChanged implements plugin.RuntimeConfigChanged for callers without event meta.
+func (e *Extension) OnRuntimeConfigChanged(ctx context.Context, change *plugin.ConfigChange) error {
+	return e.OnRuntimeConfigChangedV2(ctx, change, plugin.EventMeta{})
+}
+
+// OnPolicyCreatedV2 implements plugin.PolicyCreatedV2.
+func (e *Extension) OnPolicyCreatedV2(ctx context.Context, pol *policy.Policy, meta plugin.EventMeta) error {
+	return e.record(ctx, meta, ActionPolicyCreated, SeverityInfo, OutcomeSuccess,
 		ResourcePolicy, pol.ID.String(), CategoryPolicyLifecycle, nil,
 		"policy_name", pol.Name,
 	)
 }
 
-// OnPolicyUpdated implements plugin.PolicyUpdated.
-func (e *Extension) OnPolicyUpdated(ctx context.Context, pol *policy.Policy) error {
-	return e.record(ctx, ActionPolicyUpdated, SeverityInfo, OutcomeSuccess,
+// OnPolicyCreated implements plugin.PolicyCreated for callers without event meta.
+func (e *Extension) OnPolicyCreated(ctx context.Context, pol *policy.Policy) error {
+	return e.OnPolicyCreatedV2(ctx, pol, plugin.EventMeta{})
+}
+
+// OnPolicyUpdatedV2 implements plugin.PolicyUpdatedV2.
+func (e *Extension) OnPolicyUpdatedV2(ctx context.Context, pol *policy.Policy, meta plugin.EventMeta) error {
+	return e.record(ctx, meta, ActionPolicyUpdated, SeverityInfo, OutcomeSuccess,
 		ResourcePolicy, pol.ID.String(), CategoryPolicyLifecycle, nil,
 		"policy_name", pol.Name,
 	)
 }
 
-// OnPolicyDeleted implements plugin.PolicyDeleted.
-func (e *Extension) OnPolicyDeleted(ctx context.Context, polID id.PolicyID) error {
-	return e.record(ctx, ActionPolicyDeleted, SeverityInfo, OutcomeSuccess,
+// OnPolicyUpdated implements plugin.PolicyUpdated for callers without event meta.
+func (e *Extension) OnPolicyUpdated(ctx context.Context, pol *policy.Policy) error {
+	return e.OnPolicyUpdatedV2(ctx, pol, plugin.EventMeta{})
+}
+
+// OnPolicyDeletedV2 implements plugin.PolicyDeletedV2.
+func (e *Extension) OnPolicyDeletedV2(ctx context.Context, polID id.PolicyID, meta plugin.EventMeta) error {
+	return e.record(ctx, meta, ActionPolicyDeleted, SeverityInfo, OutcomeSuccess,
 		ResourcePolicy, polID.String(), CategoryPolicyLifecycle, nil,
 	)
 }
 
-// record builds and sends an audit event if the action is enabled.
+// OnPolicyDeleted implements plugin.PolicyDeleted for callers without event meta.
+func (e *Extension) OnPolicyDeleted(ctx context.Context, polID id.PolicyID) error {
+	return e.OnPolicyDeletedV2(ctx, polID, plugin.EventMeta{})
+}
+
+// OnGroupMembershipChangedV2 implements plugin.GroupMembershipChangedV2.
+func (e *Extension) OnGroupMembershipChangedV2(ctx context.Context, g *group.Group, added, removed []id.KeyID, meta plugin.EventMeta) error {
+	return e.record(ctx, meta, ActionGroupMembership, SeverityInfo, OutcomeSuccess,
+		ResourceGroup, g.ID.String(), CategoryKeyLifecycle, nil,
+		"group_name", g.Name, "added", keyIDStrings(added), "removed", keyIDStrings(removed),
+	)
+}
+
+// OnGroupMembershipChanged implements plugin.GroupMembershipChanged for callers without event meta.
+func (e *Extension) OnGroupMembershipChanged(ctx context.Context, g *group.Group, added, removed []id.KeyID) error {
+	return e.OnGroupMembershipChangedV2(ctx, g, added, removed, plugin.EventMeta{})
+}
+
+// transferActions maps each transfer state to the action recording it.
+var transferActions = map[transfer.State]string{
+	transfer.StatePending:   ActionKeyTransferInitiated,
+	transfer.StateAccepted:  ActionKeyTransferAccepted,
+	transfer.StateCancelled: ActionKeyTransferCancelled,
+	transfer.StateExpired:   ActionKeyTransferExpired,
+}
+
+// OnKeyTransferChangedV2 implements plugin.KeyTransferChangedV2. It records
+// the event twice, once for each tenant, with tenant_id and the tenant's
+// role in the transfer, so that both tenants' audit trails show it.
+func (e *Extension) OnKeyTransferChangedV2(ctx context.Context, t *transfer.Transfer, meta plugin.EventMeta) error {
+	action, ok := transferActions[t.State]
+	if !ok {
+		return nil
+	}
+	for _, side := range []struct{ tenantID, role string }{
+		{t.SourceTenantID, "source"},
+		{t.TargetTenantID, "target"},
+	} {
+		err := e.record(ctx, meta, action, SeverityInfo, OutcomeSuccess,
+			ResourceKey, t.KeyID.String(), CategoryKeyLifecycle, nil,
+			"transfer_id", t.ID.String(), "tenant_id", side.tenantID, "tenant_role", side.role,
+			"source_tenant_id", t.SourceTenantID, "target_tenant_id", t.TargetTenantID,
+			"state", string(t.State), "unmapped_scopes", t.UnmappedScopes,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OnKeyTransferChanged implements plugin.KeyTransferChanged for callers without event meta.
+func (e *Extension) OnKeyTransferChanged(ctx context.Context, t *transfer.Transfer) error {
+	return e.OnKeyTransferChangedV2(ctx, t, plugin.EventMeta{})
+}
+
+// lockdownActions maps each lockdown phase to its audit action.
+var lockdownActions = map[tenant.LockdownPhase]string{
+	tenant.LockdownLocked:     ActionTenantLockedDown,
+	tenant.LockdownRecovering: ActionTenantRecovering,
+	tenant.LockdownHalted:     ActionTenantRecoveryHalted,
+	tenant.LockdownRecovered:  ActionTenantRecovered,
+}
+
+// OnTenantLockdownChangedV2 implements plugin.TenantLockdownChangedV2.
+// Every phase is recorded as critical; a halted recovery is a failure.
+func (e *Extension) OnTenantLockdownChangedV2(ctx context.Context, tenantID string, l *tenant.Lockdown, meta plugin.EventMeta) error {
+	action, ok := lockdownActions[l.Phase]
+	if !ok {
+		return nil
+	}
+	outcome := OutcomeSuccess
+	pairs := []any{"tenant_id", tenantID, "phase", string(l.Phase), "reason", l.Reason,
+		"locked_at", l.LockedAt, "suspended", l.Suspended}
+	if r := l.Recovery; r != nil {
+		pairs = append(pairs, "batches", r.Batches, "reactivated", r.Reactivated, "remaining", r.Remaining)
+		if l.Phase == tenant.LockdownHalted {
+			outcome = OutcomeFailure
+			pairs = append(pairs, "halt_reason", r.HaltReason)
+		}
+	}
+	return e.record(ctx, meta, action, SeverityCritical, outcome,
+		ResourceTenant, tenantID, CategoryKeySecurity, nil, pairs...,
+	)
+}
+
+// OnTenantLockdownChanged implements plugin.TenantLockdownChanged for callers without event meta.
+func (e *Extension) OnTenantLockdownChanged(ctx context.Context, tenantID string, l *tenant.Lockdown) error {
+	return e.OnTenantLockdownChangedV2(ctx, tenantID, l, plugin.EventMeta{})
+}
+
+// keyIDStrings returns the string form of ids.
+func keyIDStrings(ids []id.KeyID) []string {
+	out := make([]string, len(ids))
+	for i, kid := range ids {
+		out[i] = kid.String()
+	}
+	return out
+}
+
+// record builds and sends an audit event if the action is enabled. The
+// event meta, when set, adds trigger, reason_code, actor_id, request_id,
+// scope, and group_id entries and supplies the timestamp.
 func (e *Extension) record(
 	ctx context.Context,
+	meta plugin.EventMeta,
 	action, severity, outcome string,
 	resource, resourceID, category string,
 	err error,
@@ -232,32 +723,60 @@ func (e *Extension) record(
 		return nil
 	}
 
-	meta := make(map[string]any, len(kvPairs)/2+1)
+	fields := make(map[string]any, len(kvPairs)/2+7)
 	for i := 0; i+1 < len(kvPairs); i += 2 {
 		k, ok := kvPairs[i].(string)
 		if !ok {
 			k = fmt.Sprintf("%v", kvPairs[i])
 		}
-		meta[k] = kvPairs[i+1]
+		fields[k] = kvPairs[i+1]
 	}
 
 	var reason string
 	if err != nil {
 		reason = err.Error()
-		meta["error"] = err.Error()
+		fields["error"] = err.Error()
+	}
+	for k, v := range map[string]string{
+		"trigger":     string(meta.Trigger),
+		"reason_code": string(meta.ReasonCode),
+		"actor_id":    meta.ActorID,
+		"request_id":  meta.RequestID,
+		"scope":       meta.Scope,
+		"group_id":    meta.GroupID,
+	} {
+		if v != "" {
+			fields[k] = v
+		}
+	}
+	at := meta.Timestamp
+	if at.IsZero() {
+		at = time.Now()
 	}
 
 	evt := &AuditEvent{
+		ID:         id.NewAuditEventID().String(),
+		Timestamp:  at.UTC(),
 		Action:     action,
 		Resource:   resource,
 		Category:   category,
 		ResourceID: resourceID,
-		Metadata:   meta,
+		Metadata:   fields,
 		Outcome:    outcome,
 		Severity:   severity,
 		Reason:     reason,
 	}
 
+	if e.spool != nil {
+		if spoolErr := e.spoolOrRecord(ctx, evt); spoolErr != nil {
+			e.logger.Warn("audit_hook: failed to spool audit event",
+				log.String("action", action),
+				log.String("resource_id", resourceID),
+				log.Any("error", spoolErr),
+			)
+		}
+		return nil
+	}
 	if recErr := e.recorder.Record(ctx, evt); recErr != nil {
 		e.logger.Warn("audit_hook: failed to record audit event",
 			log.String("action", action),
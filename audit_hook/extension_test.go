@@ -10,10 +10,15 @@ import (
 	"github.com/stretchr/testify/require"
 
 	audithook "github.com/xraph/keysmith/audit_hook"
+	"github.com/xraph/keysmith/group"
 	"github.com/xraph/keysmith/id"
 	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/plugin"
 	"github.com/xraph/keysmith/policy"
 	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/tenant"
+	"github.com/xraph/keysmith/transfer"
+	"github.com/xraph/keysmith/usage"
 )
 
 type mockRecorder struct {
@@ -111,6 +116,210 @@ func TestExtension_OnKeyRotated(t *testing.T) {
 	assert.Equal(t, "manual", evt.Metadata["reason"])
 }
 
+func TestExtension_OnKeyCompromised(t *testing.T) {
+	rec := &mockRecorder{}
+	ext := audithook.New(rec)
+
+	k := &key.Key{ID: id.NewKeyID()}
+	report := &key.CompromiseReport{
+		Source:  "github-secret-scanning",
+		Details: "found in public repo",
+		Action:  key.CompromiseRotate,
+	}
+
+	err := ext.OnKeyCompromised(context.Background(), k, report)
+	require.NoError(t, err)
+	require.Len(t, rec.events, 1)
+
+	evt := rec.events[0]
+	assert.Equal(t, audithook.ActionKeyCompromised, evt.Action)
+	assert.Equal(t, audithook.SeverityCritical, evt.Severity)
+	assert.Equal(t, "github-secret-scanning", evt.Metadata["source"])
+	assert.Equal(t, "found in public repo", evt.Metadata["details"])
+	assert.Equal(t, "rotate", evt.Metadata["action"])
+}
+
+func TestExtension_OnKeyDebugEnabled(t *testing.T) {
+	rec := &mockRecorder{}
+	ext := audithook.New(rec)
+
+	until := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	k := &key.Key{ID: id.NewKeyID(), Environment: key.EnvTest, DebugSampleRate: 0.25, DebugUntil: &until}
+
+	require.NoError(t, ext.OnKeyDebugEnabled(context.Background(), k))
+	require.Len(t, rec.events, 1)
+
+	evt := rec.events[0]
+	assert.Equal(t, audithook.ActionKeyDebugEnabled, evt.Action)
+	assert.Equal(t, audithook.CategoryKeySecurity, evt.Category)
+	assert.Equal(t, k.ID.String(), evt.ResourceID)
+	assert.Equal(t, 0.25, evt.Metadata["sample_rate"])
+	assert.Equal(t, "2024-01-15T12:00:00Z", evt.Metadata["until"])
+}
+
+func TestExtension_OnKeyExported(t *testing.T) {
+	rec := &mockRecorder{}
+	ext := audithook.New(rec)
+	k := &key.Key{ID: id.NewKeyID(), Name: "Moving", TenantID: "tenant-1", State: key.StateActive}
+
+	require.NoError(t, ext.OnKeyExported(context.Background(), k))
+	require.Len(t, rec.events, 1)
+
+	evt := rec.events[0]
+	assert.Equal(t, audithook.ActionKeyExported, evt.Action)
+	assert.Equal(t, audithook.SeverityCritical, evt.Severity)
+	assert.Equal(t, audithook.CategoryKeySecurity, evt.Category)
+	assert.Equal(t, k.ID.String(), evt.ResourceID)
+	assert.Equal(t, "tenant-1", evt.Metadata["tenant_id"])
+}
+
+func TestExtension_OnUsageIdentifiersErased(t *testing.T) {
+	rec := &mockRecorder{}
+	ext := audithook.New(rec)
+	before := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	err := ext.OnUsageIdentifiersErased(context.Background(), &usage.Erasure{
+		TenantID: "tenant-1",
+		ByIP:     true,
+		Before:   &before,
+		Records:  42,
+		Batches:  3,
+		Complete: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, rec.events, 1)
+
+	evt := rec.events[0]
+	assert.Equal(t, audithook.ActionUsageErased, evt.Action)
+	assert.Equal(t, audithook.ResourceTenant, evt.Resource)
+	assert.Equal(t, audithook.CategoryDataPrivacy, evt.Category)
+	assert.Equal(t, audithook.OutcomeSuccess, evt.Outcome)
+	assert.Equal(t, "tenant-1", evt.ResourceID)
+	assert.Equal(t, true, evt.Metadata["by_ip"])
+	assert.Equal(t, false, evt.Metadata["by_user_agent"])
+	assert.Equal(t, int64(42), evt.Metadata["records"])
+	assert.Equal(t, "2024-01-15T00:00:00Z", evt.Metadata["before"])
+}
+
+func TestExtension_OnKeyRotationOverdue(t *testing.T) {
+	rec := &mockRecorder{}
+	ext := audithook.New(rec)
+	k := &key.Key{ID: id.NewKeyID()}
+
+	err := ext.OnKeyRotationOverdue(context.Background(), k, &key.RotationDue{
+		DueAt:         time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+		DaysRemaining: -2,
+		Overdue:       true,
+	})
+	require.NoError(t, err)
+	require.Len(t, rec.events, 1)
+
+	evt := rec.events[0]
+	assert.Equal(t, audithook.ActionKeyRotationOverdue, evt.Action)
+	assert.Equal(t, audithook.SeverityWarning, evt.Severity)
+	assert.Equal(t, "2024-01-15T00:00:00Z", evt.Metadata["due_at"])
+	assert.Equal(t, -2, evt.Metadata["days_remaining"])
+}
+
+func TestExtension_OnKeyFormatDeprecated(t *testing.T) {
+	rec := &mockRecorder{}
+	ext := audithook.New(rec)
+	k := &key.Key{ID: id.NewKeyID()}
+
+	err := ext.OnKeyFormatDeprecated(context.Background(), k, &key.FormatDeprecation{
+		Format: "legacy",
+		Sunset: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	require.Len(t, rec.events, 1)
+
+	evt := rec.events[0]
+	assert.Equal(t, audithook.ActionKeyFormatDeprecated, evt.Action)
+	assert.Equal(t, audithook.SeverityWarning, evt.Severity)
+	assert.Equal(t, "legacy", evt.Metadata["format"])
+	assert.Equal(t, "2025-06-01T00:00:00Z", evt.Metadata["sunset"])
+}
+
+func TestExtension_OnRuntimeConfigChanged(t *testing.T) {
+	rec := &mockRecorder{}
+	ext := audithook.New(rec)
+	changes := []plugin.ConfigFieldChange{{Field: "validation_cache_ttl", Before: "30s", After: "1m0s"}}
+
+	err := ext.OnRuntimeConfigChanged(context.Background(), &plugin.ConfigChange{
+		Changes: changes,
+		Config:  map[string]string{"validation_cache_ttl": "1m0s"},
+	})
+	require.NoError(t, err)
+	require.Len(t, rec.events, 1)
+
+	evt := rec.events[0]
+	assert.Equal(t, audithook.ActionConfigChanged, evt.Action)
+	assert.Equal(t, audithook.ResourceEngine, evt.Resource)
+	assert.Equal(t, audithook.CategoryConfiguration, evt.Category)
+	assert.Equal(t, audithook.SeverityWarning, evt.Severity)
+	assert.Equal(t, changes, evt.Metadata["changes"])
+}
+
+func TestExtension_OnSuspiciousValidationPattern(t *testing.T) {
+	rec := &mockRecorder{}
+	ext := audithook.New(rec)
+
+	err := ext.OnSuspiciousValidationPattern(context.Background(), &key.FailurePattern{
+		Fingerprint: "sk_live:3fa9c1",
+		Count:       25,
+		Window:      5 * time.Minute,
+	})
+	require.NoError(t, err)
+	require.Len(t, rec.events, 1)
+
+	evt := rec.events[0]
+	assert.Equal(t, audithook.ActionSuspiciousValidation, evt.Action)
+	assert.Equal(t, audithook.CategoryKeySecurity, evt.Category)
+	assert.Equal(t, "sk_live:3fa9c1", evt.Metadata["fingerprint"])
+	assert.Equal(t, 25, evt.Metadata["count"])
+	assert.Equal(t, "5m0s", evt.Metadata["window"])
+}
+
+func TestExtension_RecordsEventMeta(t *testing.T) {
+	rec := &mockRecorder{}
+	ext := audithook.New(rec)
+	at := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	err := ext.OnKeyExpiredV2(context.Background(), &key.Key{ID: id.NewKeyID()}, plugin.EventMeta{
+		Trigger:    plugin.TriggerSweep,
+		ReasonCode: plugin.ReasonKeyExpired,
+		ActorID:    "scheduler",
+		RequestID:  "req_1",
+		Timestamp:  at,
+	})
+	require.NoError(t, err)
+	require.Len(t, rec.events, 1)
+
+	evt := rec.events[0]
+	assert.Equal(t, at, evt.Timestamp)
+	assert.Equal(t, "sweep", evt.Metadata["trigger"])
+	assert.Equal(t, "key_expired", evt.Metadata["reason_code"])
+	assert.Equal(t, "scheduler", evt.Metadata["actor_id"])
+	assert.Equal(t, "req_1", evt.Metadata["request_id"])
+
+	// Without meta, none of the entries are set.
+	require.NoError(t, ext.OnKeyExpired(context.Background(), &key.Key{ID: id.NewKeyID()}))
+	assert.NotContains(t, rec.events[1].Metadata, "trigger")
+	assert.False(t, rec.events[1].Timestamp.IsZero())
+}
+
+func TestExtension_OnKeyValidationFailedV2(t *testing.T) {
+	rec := &mockRecorder{}
+	ext := audithook.New(rec)
+
+	err := ext.OnKeyValidationFailedV2(context.Background(), "sk_live:3fa9c1", errors.New("keysmith: key is not active"),
+		plugin.EventMeta{Trigger: plugin.TriggerValidation, ReasonCode: plugin.ReasonKeyInactive})
+	require.NoError(t, err)
+	require.Len(t, rec.events, 1)
+	assert.Equal(t, "sk_live:3fa9c1", rec.events[0].Metadata["fingerprint"])
+	assert.Equal(t, "key_inactive", rec.events[0].Metadata["reason_code"])
+}
+
 func TestExtension_OnPolicyCreated(t *testing.T) {
 	rec := &mockRecorder{}
 	ext := audithook.New(rec)
@@ -143,6 +352,97 @@ func TestExtension_OnPolicyDeleted(t *testing.T) {
 	assert.Equal(t, polID.String(), evt.ResourceID)
 }
 
+func TestExtension_OnGroupMembershipChanged(t *testing.T) {
+	rec := &mockRecorder{}
+	ext := audithook.New(rec)
+
+	g := &group.Group{ID: id.NewGroupID(), Name: "Mobile apps"}
+	added := id.NewKeyID()
+
+	err := ext.OnGroupMembershipChangedV2(context.Background(), g, []id.KeyID{added}, nil,
+		plugin.EventMeta{GroupID: g.ID.String()})
+	require.NoError(t, err)
+	require.Len(t, rec.events, 1)
+
+	evt := rec.events[0]
+	assert.Equal(t, audithook.ActionGroupMembership, evt.Action)
+	assert.Equal(t, audithook.ResourceGroup, evt.Resource)
+	assert.Equal(t, g.ID.String(), evt.ResourceID)
+	assert.Equal(t, []string{added.String()}, evt.Metadata["added"])
+	assert.Equal(t, []string{}, evt.Metadata["removed"])
+	assert.Equal(t, g.ID.String(), evt.Metadata["group_id"])
+}
+
+func TestExtension_OnKeyTransferChanged(t *testing.T) {
+	rec := &mockRecorder{}
+	ext := audithook.New(rec)
+
+	xfer := &transfer.Transfer{
+		ID:             id.NewTransferID(),
+		KeyID:          id.NewKeyID(),
+		SourceTenantID: "tenant-a",
+		TargetTenantID: "tenant-b",
+		State:          transfer.StateAccepted,
+		UnmappedScopes: []string{"billing:write"},
+	}
+	require.NoError(t, ext.OnKeyTransferChangedV2(context.Background(), xfer, plugin.EventMeta{ActorID: "user_b"}))
+	require.Len(t, rec.events, 2)
+
+	for i, want := range []struct{ tenantID, role string }{{"tenant-a", "source"}, {"tenant-b", "target"}} {
+		evt := rec.events[i]
+		assert.Equal(t, audithook.ActionKeyTransferAccepted, evt.Action)
+		assert.Equal(t, audithook.ResourceKey, evt.Resource)
+		assert.Equal(t, xfer.KeyID.String(), evt.ResourceID)
+		assert.Equal(t, want.tenantID, evt.Metadata["tenant_id"])
+		assert.Equal(t, want.role, evt.Metadata["tenant_role"])
+		assert.Equal(t, xfer.ID.String(), evt.Metadata["transfer_id"])
+		assert.Equal(t, []string{"billing:write"}, evt.Metadata["unmapped_scopes"])
+		assert.Equal(t, "user_b", evt.Metadata["actor_id"])
+	}
+}
+
+func TestExtension_OnTenantLockdownChanged(t *testing.T) {
+	rec := &mockRecorder{}
+	ext := audithook.New(rec)
+	halted := time.Now()
+	l := &tenant.Lockdown{
+		Phase: tenant.LockdownHalted, Reason: "credential dump", Suspended: 12,
+		Recovery: &tenant.Recovery{Batches: 2, Reactivated: 8, Remaining: 4, HaltedAt: &halted, HaltReason: "key revoked: compromised"},
+	}
+	meta := plugin.EventMeta{ReasonCode: plugin.ReasonAnomalyDetected, ActorID: "oncall"}
+	require.NoError(t, ext.OnTenantLockdownChangedV2(context.Background(), "tenant-1", l, meta))
+	require.Len(t, rec.events, 1)
+
+	evt := rec.events[0]
+	assert.Equal(t, audithook.ActionTenantRecoveryHalted, evt.Action)
+	assert.Equal(t, audithook.SeverityCritical, evt.Severity)
+	assert.Equal(t, audithook.OutcomeFailure, evt.Outcome)
+	assert.Equal(t, audithook.ResourceTenant, evt.Resource)
+	assert.Equal(t, "tenant-1", evt.ResourceID)
+	assert.Equal(t, 8, evt.Metadata["reactivated"])
+	assert.Equal(t, "key revoked: compromised", evt.Metadata["halt_reason"])
+	assert.Equal(t, "anomaly_detected", evt.Metadata["reason_code"])
+
+	l.Phase = tenant.LockdownLocked
+	require.NoError(t, ext.OnTenantLockdownChanged(context.Background(), "tenant-1", l))
+	assert.Equal(t, audithook.ActionTenantLockedDown, rec.events[1].Action)
+	assert.Equal(t, audithook.OutcomeSuccess, rec.events[1].Outcome)
+	assert.Equal(t, "credential dump", rec.events[1].Metadata["reason"])
+}
+
+func TestExtension_RecordsGroupID(t *testing.T) {
+	rec := &mockRecorder{}
+	ext := audithook.New(rec)
+	groupID := id.NewGroupID()
+
+	err := ext.OnKeyRevokedV2(context.Background(), &key.Key{ID: id.NewKeyID()}, "offboarding",
+		plugin.EventMeta{ReasonCode: plugin.ReasonGroupRevoked, GroupID: groupID.String()})
+	require.NoError(t, err)
+	require.Len(t, rec.events, 1)
+	assert.Equal(t, groupID.String(), rec.events[0].Metadata["group_id"])
+	assert.Equal(t, "group_revoked", rec.events[0].Metadata["reason_code"])
+}
+
 func TestExtension_WithEnabled_FiltersActions(t *testing.T) {
 	rec := &mockRecorder{}
 	ext := audithook.New(rec, audithook.WithEnabled(audithook.ActionKeyCreated))
@@ -194,9 +494,26 @@ func TestExtension_AllHooks(t *testing.T) {
 	require.NoError(t, ext.OnKeyReactivated(ctx, k))
 	require.NoError(t, ext.OnKeyExpired(ctx, k))
 	require.NoError(t, ext.OnKeyRateLimited(ctx, k))
+	require.NoError(t, ext.OnTenantRateLimited(ctx, k))
+	require.NoError(t, ext.OnKeyAdaptivelyLimited(ctx, k, &key.AdaptivePenalty{BaseLimit: 100, Limit: 10}))
+	require.NoError(t, ext.OnKeyQuotaExceeded(ctx, k, &key.QuotaExceeded{Period: "day", Limit: 100, Used: 100}))
+	require.NoError(t, ext.OnKeyQuotaForecastWarning(ctx, k, &key.QuotaForecast{Quota: 300, Used: 200, BurnRate: 20}))
+	require.NoError(t, ext.OnKeyConsumerMismatch(ctx, k, "billing"))
+	require.NoError(t, ext.OnKeyRotationOverdue(ctx, k, &key.RotationDue{DueAt: time.Now(), Overdue: true}))
+	require.NoError(t, ext.OnKeyFormatDeprecated(ctx, k, &key.FormatDeprecation{Format: "legacy"}))
+	require.NoError(t, ext.OnKeyFlagsChanged(ctx, k, key.Flags{key.FlagSkipOriginCheck}, nil))
+	require.NoError(t, ext.OnKeyCompromised(ctx, k, &key.CompromiseReport{Source: "github", Action: key.CompromiseRevoke}))
+	require.NoError(t, ext.OnSuspiciousValidationPattern(ctx, &key.FailurePattern{Fingerprint: "sk_live:abcdef"}))
+	require.NoError(t, ext.OnKeyExported(ctx, k))
+	require.NoError(t, ext.OnKeyImported(ctx, k))
+	require.NoError(t, ext.OnUsageIdentifiersErased(ctx, &usage.Erasure{TenantID: "tenant-1", Complete: true}))
+	require.NoError(t, ext.OnRuntimeConfigChanged(ctx, &plugin.ConfigChange{}))
 	require.NoError(t, ext.OnPolicyCreated(ctx, pol))
 	require.NoError(t, ext.OnPolicyUpdated(ctx, pol))
 	require.NoError(t, ext.OnPolicyDeleted(ctx, pol.ID))
+	require.NoError(t, ext.OnGroupMembershipChanged(ctx, &group.Group{ID: id.NewGroupID()}, []id.KeyID{k.ID}, nil))
+	require.NoError(t, ext.OnKeyTransferChanged(ctx, &transfer.Transfer{KeyID: k.ID, State: transfer.StatePending}))
+	require.NoError(t, ext.OnTenantLockdownChanged(ctx, "tenant-1", &tenant.Lockdown{Phase: tenant.LockdownLocked}))
 
-	assert.Len(t, rec.events, 13)
+	assert.Len(t, rec.events, 31, "a transfer is recorded for both tenants")
 }
@@ -1,6 +1,10 @@
 package audithook
 
-import log "github.com/xraph/go-utils/log"
+import (
+	"time"
+
+	log "github.com/xraph/go-utils/log"
+)
 
 // Option configures an Extension.
 type Option func(*Extension)
@@ -22,3 +26,23 @@ func WithLogger(logger log.Logger) Option {
 		e.logger = logger
 	}
 }
+
+// WithFallbackStore spools events the Recorder rejects, or that arrive while
+// earlier events are still spooled, instead of dropping them. Replay them
+// with Replay, or periodically with Start. [NewFileSpool] provides a local
+// NDJSON spool.
+func WithFallbackStore(s Spool) Option {
+	return func(e *Extension) {
+		e.spool = s
+	}
+}
+
+// WithReplayInterval sets how often the loop started by Start replays the
+// spool. Defaults to [DefaultReplayInterval].
+func WithReplayInterval(d time.Duration) Option {
+	return func(e *Extension) {
+		if d > 0 {
+			e.replayInterval = d
+		}
+	}
+}
@@ -0,0 +1,362 @@
+package audithook
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/xraph/go-utils/log"
+)
+
+// Default replay settings.
+const (
+	// DefaultSpoolMaxEvents caps how many events a FileSpool holds.
+	DefaultSpoolMaxEvents = 100_000
+
+	// DefaultReplayInterval is how often the retry loop started by Start
+	// replays spooled events.
+	DefaultReplayInterval = 30 * time.Second
+
+	// DefaultMaxReplayBackoff caps the retry loop's backoff while the
+	// Recorder keeps failing.
+	DefaultMaxReplayBackoff = 10 * time.Minute
+
+	spoolFileName = "audit-spool.ndjson"
+)
+
+// Spool durably holds events the Recorder rejected until they are
+// replayed. Implementations must keep events in the order they were
+// appended and be safe for concurrent use.
+type Spool interface {
+	// Append adds an event after all others.
+	Append(event *AuditEvent) error
+
+	// Events returns the spooled events, oldest first.
+	Events() ([]*AuditEvent, error)
+
+	// Remove drops the n oldest events.
+	Remove(n int) error
+
+	// Len returns the number of spooled events.
+	Len() int
+}
+
+// FileSpool is a Spool backed by a newline-delimited JSON file. Events are
+// also held in memory, so reads never touch the disk. When full, the oldest
+// event is dropped to make room and counted in Dropped.
+type FileSpool struct {
+	mu        sync.Mutex
+	path      string
+	file      *os.File
+	events    []*AuditEvent
+	maxEvents int
+	dropped   int64
+}
+
+// NewFileSpool opens, or creates, the spool file in dir and loads any events
+// left there by a previous process. maxEvents caps the spool; zero means
+// DefaultSpoolMaxEvents.
+func NewFileSpool(dir string, maxEvents int) (*FileSpool, error) {
+	if maxEvents <= 0 {
+		maxEvents = DefaultSpoolMaxEvents
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("audit_hook: create spool dir: %w", err)
+	}
+	s := &FileSpool{path: filepath.Join(dir, spoolFileName), maxEvents: maxEvents}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	if len(s.events) > maxEvents {
+		s.dropped = int64(len(s.events) - maxEvents)
+		s.events = s.events[len(s.events)-maxEvents:]
+		if err := s.rewriteLocked(); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("audit_hook: open spool: %w", err)
+	}
+	s.file = f
+	return s, nil
+}
+
+func (s *FileSpool) load() error {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("audit_hook: open spool: %w", err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		evt := &AuditEvent{}
+		if err := json.Unmarshal(sc.Bytes(), evt); err != nil {
+			// A line cut short by a crash mid-write; the rest is intact.
+			continue
+		}
+		s.events = append(s.events, evt)
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("audit_hook: read spool: %w", err)
+	}
+	return nil
+}
+
+// Append implements Spool.
+func (s *FileSpool) Append(event *AuditEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("audit_hook: encode event: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, event)
+	if len(s.events) > s.maxEvents {
+		s.events = s.events[1:]
+		s.dropped++
+		return s.rewriteLocked()
+	}
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("audit_hook: write spool: %w", err)
+	}
+	return nil
+}
+
+// Events implements Spool.
+func (s *FileSpool) Events() ([]*AuditEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]*AuditEvent, len(s.events))
+	copy(out, s.events)
+	return out, nil
+}
+
+// Remove implements Spool.
+func (s *FileSpool) Remove(n int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n = min(n, len(s.events))
+	if n <= 0 {
+		return nil
+	}
+	s.events = s.events[n:]
+	return s.rewriteLocked()
+}
+
+// Len implements Spool.
+func (s *FileSpool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.events)
+}
+
+// Dropped returns how many events were discarded, oldest first, because
+// the spool was full.
+func (s *FileSpool) Dropped() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Close closes the spool file. Spooled events stay on disk for the next
+// NewFileSpool.
+func (s *FileSpool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// rewriteLocked replaces the spool file with the in-memory events. The new
+// file is renamed into place so a crash leaves either the old or the new
+// contents.
+func (s *FileSpool) rewriteLocked() error {
+	tmp := s.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("audit_hook: rewrite spool: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, evt := range s.events {
+		if err := enc.Encode(evt); err != nil {
+			f.Close()
+			return fmt.Errorf("audit_hook: rewrite spool: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("audit_hook: rewrite spool: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("audit_hook: rewrite spool: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("audit_hook: rewrite spool: %w", err)
+	}
+	if s.file == nil {
+		return nil
+	}
+	// Reopen so later appends go to the new file.
+	_ = s.file.Close()
+	s.file, err = os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("audit_hook: reopen spool: %w", err)
+	}
+	return nil
+}
+
+// spoolOrRecord sends evt to the Recorder, or to the spool when the
+// Recorder fails or earlier events are still waiting to be replayed, so
+// that events reach the Recorder in the order they occurred.
+func (e *Extension) spoolOrRecord(ctx context.Context, evt *AuditEvent) error {
+	e.spoolMu.Lock()
+	defer e.spoolMu.Unlock()
+	if e.spool.Len() == 0 {
+		err := e.recorder.Record(ctx, evt)
+		if err == nil {
+			return nil
+		}
+		e.logger.Warn("audit_hook: recorder failed, spooling event",
+			log.String("action", evt.Action),
+			log.String("event_id", evt.ID),
+			log.Any("error", err),
+		)
+	}
+	return e.spool.Append(evt)
+}
+
+// Replay re-submits spooled events to the Recorder, oldest first, removing
+// each from the spool once it is recorded. It stops at the first failure,
+// so ordering is preserved, and returns how many events were replayed.
+// Replayed events keep their original ID for downstream deduplication.
+func (e *Extension) Replay(ctx context.Context) (int, error) {
+	if e.spool == nil {
+		return 0, nil
+	}
+	e.replayMu.Lock()
+	defer e.replayMu.Unlock()
+
+	replayed := 0
+	for {
+		e.spoolMu.Lock()
+		events, err := e.spool.Events()
+		e.spoolMu.Unlock()
+		if err != nil {
+			return replayed, fmt.Errorf("audit_hook: read spool: %w", err)
+		}
+		if len(events) == 0 {
+			return replayed, nil
+		}
+
+		sent := 0
+		var recErr error
+		for _, evt := range events {
+			if recErr = e.recorder.Record(ctx, evt); recErr != nil {
+				break
+			}
+			sent++
+		}
+
+		e.spoolMu.Lock()
+		err = e.spool.Remove(sent)
+		e.spoolMu.Unlock()
+		replayed += sent
+		if err != nil {
+			return replayed, fmt.Errorf("audit_hook: trim spool: %w", err)
+		}
+		if recErr != nil {
+			return replayed, recErr
+		}
+	}
+}
+
+// Start runs Replay in the background every replay interval, backing off
+// exponentially up to DefaultMaxReplayBackoff while the Recorder fails.
+// It does nothing without a fallback spool.
+func (e *Extension) Start(ctx context.Context) error {
+	if e.spool == nil {
+		return nil
+	}
+	e.loopMu.Lock()
+	defer e.loopMu.Unlock()
+	if e.stop != nil {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	done := make(chan struct{})
+	e.stop, e.stopped = cancel, done
+
+	go func() {
+		defer close(done)
+		wait := e.replayInterval
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+			if _, err := e.Replay(ctx); err != nil {
+				wait = min(wait*2, DefaultMaxReplayBackoff)
+				e.logger.Warn("audit_hook: replay failed",
+					log.Int("pending", e.spool.Len()),
+					log.String("retry_in", wait.String()),
+					log.Any("error", err),
+				)
+			} else {
+				wait = e.replayInterval
+			}
+			timer.Reset(wait)
+		}
+	}()
+	return nil
+}
+
+// Stop ends the retry loop started by Start and waits for it to exit.
+// Events still spooled are replayed by the next Start or Replay.
+func (e *Extension) Stop() {
+	e.loopMu.Lock()
+	stop, stopped := e.stop, e.stopped
+	e.stop, e.stopped = nil, nil
+	e.loopMu.Unlock()
+	if stop == nil {
+		return
+	}
+	stop()
+	<-stopped
+}
+
+// OnShutdown implements plugin.Shutdown. It stops the retry loop and makes
+// a last replay attempt; events that still fail stay spooled.
+func (e *Extension) OnShutdown(ctx context.Context) error {
+	e.Stop()
+	if _, err := e.Replay(ctx); err != nil {
+		e.logger.Warn("audit_hook: final replay failed",
+			log.Int("pending", e.spool.Len()),
+			log.Any("error", err),
+		)
+	}
+	return nil
+}
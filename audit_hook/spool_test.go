@@ -0,0 +1,184 @@
+package audithook_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	audithook "github.com/xraph/keysmith/audit_hook"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+)
+
+var errBackendDown = errors.New("audit backend unavailable")
+
+// flakyRecorder fails while down is set.
+type flakyRecorder struct {
+	mu     sync.Mutex
+	down   bool
+	events []*audithook.AuditEvent
+}
+
+func (r *flakyRecorder) Record(_ context.Context, event *audithook.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.down {
+		return errBackendDown
+	}
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *flakyRecorder) setDown(down bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.down = down
+}
+
+func (r *flakyRecorder) names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, len(r.events))
+	for i, evt := range r.events {
+		names[i] = evt.Metadata["key_name"].(string)
+	}
+	return names
+}
+
+func createEvent(t *testing.T, ext *audithook.Extension, name string) {
+	t.Helper()
+	require.NoError(t, ext.OnKeyCreated(context.Background(), &key.Key{ID: id.NewKeyID(), Name: name}))
+}
+
+func TestSpool_NoLossAcrossOutage(t *testing.T) {
+	spool, err := audithook.NewFileSpool(t.TempDir(), 0)
+	require.NoError(t, err)
+	defer spool.Close()
+	rec := &flakyRecorder{}
+	ext := audithook.New(rec, audithook.WithFallbackStore(spool))
+
+	createEvent(t, ext, "k1")
+	rec.setDown(true)
+	createEvent(t, ext, "k2")
+	createEvent(t, ext, "k3")
+	assert.Equal(t, 2, spool.Len())
+
+	rec.setDown(false)
+	createEvent(t, ext, "k4")
+	assert.Equal(t, 3, spool.Len(), "events queue behind the spool to keep their order")
+	assert.Equal(t, []string{"k1"}, rec.names())
+
+	n, err := ext.Replay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Zero(t, spool.Len())
+
+	createEvent(t, ext, "k5")
+	assert.Equal(t, []string{"k1", "k2", "k3", "k4", "k5"}, rec.names())
+}
+
+func TestSpool_ReplayStopsAtFailure(t *testing.T) {
+	spool, err := audithook.NewFileSpool(t.TempDir(), 0)
+	require.NoError(t, err)
+	defer spool.Close()
+	calls := 0
+	var recorded []*audithook.AuditEvent
+	ext := audithook.New(audithook.RecorderFunc(func(_ context.Context, evt *audithook.AuditEvent) error {
+		calls++
+		// Fail the live write of k1, then k2 during the first replay. k2
+		// and k3 are spooled behind k1 without calling the recorder.
+		if calls == 1 || calls == 3 {
+			return errBackendDown
+		}
+		recorded = append(recorded, evt)
+		return nil
+	}), audithook.WithFallbackStore(spool))
+
+	createEvent(t, ext, "k1")
+	createEvent(t, ext, "k2")
+	createEvent(t, ext, "k3")
+	require.Equal(t, 3, spool.Len())
+
+	n, err := ext.Replay(context.Background())
+	assert.ErrorIs(t, err, errBackendDown)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 2, spool.Len())
+
+	n, err = ext.Replay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	require.Len(t, recorded, 3)
+	for i, name := range []string{"k1", "k2", "k3"} {
+		assert.Equal(t, name, recorded[i].Metadata["key_name"])
+	}
+}
+
+func TestSpool_PersistsAcrossRestart(t *testing.T) {
+	dir := t.TempDir()
+	spool, err := audithook.NewFileSpool(dir, 0)
+	require.NoError(t, err)
+	rec := &flakyRecorder{down: true}
+	ext := audithook.New(rec, audithook.WithFallbackStore(spool))
+	createEvent(t, ext, "k1")
+	createEvent(t, ext, "k2")
+	spooled, err := spool.Events()
+	require.NoError(t, err)
+	require.NoError(t, spool.Close())
+
+	reopened, err := audithook.NewFileSpool(dir, 0)
+	require.NoError(t, err)
+	defer reopened.Close()
+	require.Equal(t, 2, reopened.Len())
+
+	rec.setDown(false)
+	ext = audithook.New(rec, audithook.WithFallbackStore(reopened))
+	_, err = ext.Replay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"k1", "k2"}, rec.names())
+	assert.Equal(t, spooled[0].ID, rec.events[0].ID, "replays keep the event ID")
+	assert.NotEqual(t, rec.events[0].ID, rec.events[1].ID)
+}
+
+func TestSpool_CapDropsOldest(t *testing.T) {
+	spool, err := audithook.NewFileSpool(t.TempDir(), 2)
+	require.NoError(t, err)
+	defer spool.Close()
+	rec := &flakyRecorder{down: true}
+	ext := audithook.New(rec, audithook.WithFallbackStore(spool))
+
+	for _, name := range []string{"k1", "k2", "k3", "k4"} {
+		createEvent(t, ext, name)
+	}
+	assert.Equal(t, 2, spool.Len())
+	assert.Equal(t, int64(2), spool.Dropped())
+
+	rec.setDown(false)
+	_, err = ext.Replay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"k3", "k4"}, rec.names())
+}
+
+func TestSpool_RetryLoop(t *testing.T) {
+	spool, err := audithook.NewFileSpool(t.TempDir(), 0)
+	require.NoError(t, err)
+	defer spool.Close()
+	rec := &flakyRecorder{down: true}
+	ext := audithook.New(rec,
+		audithook.WithFallbackStore(spool),
+		audithook.WithReplayInterval(5*time.Millisecond),
+	)
+	require.NoError(t, ext.Start(context.Background()))
+	defer ext.Stop()
+
+	createEvent(t, ext, "k1")
+	createEvent(t, ext, "k2")
+	rec.setDown(false)
+
+	assert.Eventually(t, func() bool { return spool.Len() == 0 }, 2*time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"k1", "k2"}, rec.names())
+}
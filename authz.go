@@ -0,0 +1,289 @@
+package keysmith
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/policy"
+)
+
+// Default authorizer decision cache settings.
+const (
+	DefaultAuthzCacheTTL  = 5 * time.Second
+	DefaultAuthzCacheSize = 10_000
+)
+
+// Authorizer makes an external authorization decision on a validation that
+// passed every built-in check, for rules keysmith does not model itself,
+// such as time of day, geography, or risk scores. It is set with
+// [WithAuthorizer]; adapters for OPA live in authz/opa.
+type Authorizer interface {
+	// Authorize decides on input. An error fails the validation with
+	// [ErrAuthorizerUnavailable]; an adapter that fails open returns an
+	// allowing Decision instead.
+	Authorize(ctx context.Context, input *AuthzInput) (Decision, error)
+}
+
+// AuthorizerFunc is an adapter to use a plain function as an Authorizer.
+type AuthorizerFunc func(ctx context.Context, input *AuthzInput) (Decision, error)
+
+// Authorize implements Authorizer.
+func (f AuthorizerFunc) Authorize(ctx context.Context, input *AuthzInput) (Decision, error) {
+	return f(ctx, input)
+}
+
+// AuthzInput is the document an [Authorizer] decides on, sent to OPA as
+// its input. It never holds the raw key or its hash.
+type AuthzInput struct {
+	// Key holds the attributes of the validated key.
+	Key AuthzKey `json:"key"`
+
+	TenantID string `json:"tenant_id"`
+	AppID    string `json:"app_id"`
+
+	// Scopes are the scopes the validation would grant.
+	Scopes []string `json:"scopes"`
+
+	// Policy is the key's effective policy, merged with its bases, or nil
+	// when the key has none.
+	Policy *policy.Policy `json:"policy,omitempty"`
+
+	// Request describes the call presenting the key.
+	Request AuthzRequest `json:"request"`
+
+	// Time is when the validation was made, by the engine clock. It is not
+	// part of the decision cache key, so a cached decision is reused for
+	// its TTL whatever the time.
+	Time time.Time `json:"time"`
+}
+
+// AuthzKey is the part of a key an [Authorizer] sees.
+type AuthzKey struct {
+	ID               id.KeyID        `json:"id"`
+	Name             string          `json:"name"`
+	Prefix           string          `json:"prefix"`
+	Environment      key.Environment `json:"environment"`
+	State            key.State       `json:"state"`
+	PolicyID         *id.PolicyID    `json:"policy_id,omitempty"`
+	Metadata         map[string]any  `json:"metadata,omitempty"`
+	Labels           key.Labels      `json:"labels,omitempty"`
+	Flags            key.Flags       `json:"flags,omitempty"`
+	IntendedConsumer string          `json:"intended_consumer,omitempty"`
+	CreatedBy        string          `json:"created_by,omitempty"`
+	CreatedAt        time.Time       `json:"created_at"`
+	ExpiresAt        *time.Time      `json:"expires_at,omitempty"`
+}
+
+// AuthzRequest is the request context of an [AuthzInput], taken from the
+// [ValidationRequest].
+type AuthzRequest struct {
+	Origin          string `json:"origin,omitempty"`
+	ConsumerService string `json:"consumer_service,omitempty"`
+	Method          string `json:"method,omitempty"`
+	Path            string `json:"path,omitempty"`
+	RemoteIP        string `json:"remote_ip,omitempty"`
+
+	// TLSVersion is the negotiated version, such as "1.3", or "none".
+	TLSVersion            string `json:"tls_version"`
+	ClientCertFingerprint string `json:"client_cert_fingerprint,omitempty"`
+
+	// Attributes are the caller's own facts about the request, such as a
+	// country or risk score from the gateway.
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// Decision is an [Authorizer]'s answer.
+type Decision struct {
+	Allow bool `json:"allow"`
+
+	// Reason explains a denial; it is appended to ErrAuthzDenied.
+	Reason string `json:"reason,omitempty"`
+
+	// Obligations narrow what an allowed validation grants.
+	Obligations Obligations `json:"obligations"`
+}
+
+// Obligations are conditions an allowing [Decision] attaches to the
+// validation result.
+type Obligations struct {
+	// Scopes, when non-nil, limits the result's scopes to those also
+	// listed here. It can only remove scopes; an empty list removes all.
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// authzCache holds decisions by key and input hash for a short TTL.
+type authzCache struct {
+	ttl time.Duration
+	max int
+
+	mu      sync.Mutex
+	entries map[string]authzEntry
+}
+
+type authzEntry struct {
+	decision Decision
+	expires  time.Time
+}
+
+func newAuthzCache(ttl time.Duration, maxEntries int) *authzCache {
+	if ttl <= 0 {
+		ttl = DefaultAuthzCacheTTL
+	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultAuthzCacheSize
+	}
+	return &authzCache{ttl: ttl, max: maxEntries, entries: make(map[string]authzEntry)}
+}
+
+func (c *authzCache) get(k string) (Decision, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ent, ok := c.entries[k]
+	if !ok {
+		return Decision{}, false
+	}
+	if time.Now().After(ent.expires) {
+		delete(c.entries, k)
+		return Decision{}, false
+	}
+	return ent.decision, true
+}
+
+// put stores d. When the cache is full it first drops expired entries, then
+// arbitrary ones.
+func (c *authzCache) put(k string, d Decision) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[k]; !ok && len(c.entries) >= c.max {
+		now := time.Now()
+		for ek, ent := range c.entries {
+			if now.After(ent.expires) {
+				delete(c.entries, ek)
+			}
+		}
+		for ek := range c.entries {
+			if len(c.entries) < c.max {
+				break
+			}
+			delete(c.entries, ek)
+		}
+	}
+	c.entries[k] = authzEntry{decision: d, expires: time.Now().Add(c.ttl)}
+}
+
+// authzCacheKey returns the cache key of input: its key ID and a hash of
+// the document without its time.
+func authzCacheKey(input *AuthzInput) (string, error) {
+	doc := *input
+	doc.Time = time.Time{}
+	b, err := json.Marshal(&doc)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return input.Key.ID.String() + ":" + hex.EncodeToString(sum[:]), nil
+}
+
+// newAuthzInput builds the document for a validation of k.
+func newAuthzInput(ctx context.Context, k *key.Key, scopes []string, pol *policy.Policy, now time.Time) *AuthzInput {
+	vreq := ValidationRequestFromContext(ctx)
+	return &AuthzInput{
+		Key: AuthzKey{
+			ID:               k.ID,
+			Name:             k.Name,
+			Prefix:           k.Prefix,
+			Environment:      k.Environment,
+			State:            k.State,
+			PolicyID:         k.PolicyID,
+			Metadata:         k.Metadata,
+			Labels:           k.Labels,
+			Flags:            k.Flags,
+			IntendedConsumer: k.IntendedConsumer,
+			CreatedBy:        k.CreatedBy,
+			CreatedAt:        k.CreatedAt,
+			ExpiresAt:        k.ExpiresAt,
+		},
+		TenantID: k.TenantID,
+		AppID:    k.AppID,
+		Scopes:   scopes,
+		Policy:   pol,
+		Request: AuthzRequest{
+			Origin:                vreq.Origin,
+			ConsumerService:       vreq.ConsumerService,
+			Method:                vreq.Method,
+			Path:                  vreq.Path,
+			RemoteIP:              remoteIP(vreq),
+			TLSVersion:            policy.TLSVersionName(vreq.TLSVersion),
+			ClientCertFingerprint: vreq.ClientCertFingerprint,
+			Attributes:            vreq.Attributes,
+		},
+		Time: now,
+	}
+}
+
+// remoteIP returns the client IP of vreq as [requestIP] finds it, or "" when
+// it has none.
+func remoteIP(vreq ValidationRequest) string {
+	if ip, ok := requestIP(vreq); ok {
+		return ip.String()
+	}
+	return ""
+}
+
+// checkAuthorizer asks the engine's Authorizer about a validation that
+// passed the built-in checks and applies an allowing decision's
+// obligations to result.
+func (e *Engine) checkAuthorizer(ctx context.Context, result *ValidationResult, now time.Time) error {
+	if e.authorizer == nil {
+		return nil
+	}
+	input := newAuthzInput(ctx, result.Key, result.Scopes, result.Policy, now)
+
+	var cacheKey string
+	if e.authzCache != nil {
+		if ck, err := authzCacheKey(input); err == nil {
+			cacheKey = ck
+		}
+	}
+	decision, cached := Decision{}, false
+	if cacheKey != "" {
+		decision, cached = e.authzCache.get(cacheKey)
+	}
+	if !cached {
+		var err error
+		decision, err = e.authorizer.Authorize(ctx, input)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrAuthorizerUnavailable, err)
+		}
+		if cacheKey != "" {
+			e.authzCache.put(cacheKey, decision)
+		}
+	}
+
+	if !decision.Allow {
+		if decision.Reason == "" {
+			return ErrAuthzDenied
+		}
+		return fmt.Errorf("%w: %s", ErrAuthzDenied, decision.Reason)
+	}
+	if allowed := decision.Obligations.Scopes; allowed != nil {
+		// Scopes may be shared with the validation cache, so the narrowed
+		// list is a new slice.
+		narrowed := make([]string, 0, len(result.Scopes))
+		for _, s := range result.Scopes {
+			if slices.Contains(allowed, s) {
+				narrowed = append(narrowed, s)
+			}
+		}
+		result.Scopes = narrowed
+	}
+	return nil
+}
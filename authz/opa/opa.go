@@ -0,0 +1,159 @@
+// Package opa adapts Open Policy Agent to [keysmith.Authorizer]. Authorizer
+// asks an OPA server over its REST API and adds no dependencies to
+// keysmith; built with the rego tag, NewRego evaluates Rego in process.
+//
+//	authz := opa.New("http://localhost:8181/v1/data/keysmith/authz",
+//		opa.WithTimeout(200*time.Millisecond),
+//	)
+//	eng, err := keysmith.NewEngine(keysmith.WithAuthorizer(authz), ...)
+//
+// The policy gets the [keysmith.AuthzInput] as input and decides with
+// either a boolean or an object:
+//
+//	{"allow": true, "reason": "...", "obligations": {"scopes": ["read:orders"]}}
+//
+// An undefined decision denies.
+package opa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/xraph/keysmith"
+)
+
+// Compile-time interface check.
+var _ keysmith.Authorizer = (*Authorizer)(nil)
+
+// DefaultTimeout bounds a decision request.
+const DefaultTimeout = 500 * time.Millisecond
+
+// maxErrorBody bounds how much of an error response is kept in the error.
+const maxErrorBody = 512
+
+// UndefinedReason is the reason of the denial returned when the policy
+// leaves the decision undefined.
+const UndefinedReason = "opa: decision undefined"
+
+// Authorizer asks an OPA decision endpoint, such as
+// http://opa:8181/v1/data/keysmith/authz, for each decision. It is safe for
+// concurrent use.
+type Authorizer struct {
+	url      string
+	client   *http.Client
+	header   http.Header
+	timeout  time.Duration
+	failOpen bool
+}
+
+// Option configures an Authorizer.
+type Option func(*Authorizer)
+
+// WithHTTPClient sets the HTTP client. Defaults to http.DefaultClient.
+func WithHTTPClient(c *http.Client) Option {
+	return func(a *Authorizer) { a.client = c }
+}
+
+// WithHeader adds a header, typically Authorization, to every request.
+func WithHeader(key, value string) Option {
+	return func(a *Authorizer) { a.header.Add(key, value) }
+}
+
+// WithTimeout bounds each decision request. Defaults to DefaultTimeout.
+func WithTimeout(d time.Duration) Option {
+	return func(a *Authorizer) {
+		if d > 0 {
+			a.timeout = d
+		}
+	}
+}
+
+// WithFailOpen allows validations when OPA cannot be reached or answers
+// with an error, with the failure as the decision's reason. By default the
+// error is returned and the validation fails with
+// keysmith.ErrAuthorizerUnavailable.
+func WithFailOpen() Option {
+	return func(a *Authorizer) { a.failOpen = true }
+}
+
+// New creates an Authorizer for the OPA decision endpoint at url.
+func New(url string, opts ...Option) *Authorizer {
+	a := &Authorizer{
+		url:     url,
+		client:  http.DefaultClient,
+		header:  make(http.Header),
+		timeout: DefaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Authorize implements keysmith.Authorizer. It posts input as the OPA
+// input document and decodes the result.
+func (a *Authorizer) Authorize(ctx context.Context, input *keysmith.AuthzInput) (keysmith.Decision, error) {
+	d, err := a.query(ctx, input)
+	if err != nil && a.failOpen {
+		return keysmith.Decision{Allow: true, Reason: err.Error()}, nil
+	}
+	return d, err
+}
+
+func (a *Authorizer) query(ctx context.Context, input *keysmith.AuthzInput) (keysmith.Decision, error) {
+	body, err := json.Marshal(map[string]any{"input": input})
+	if err != nil {
+		return keysmith.Decision{}, fmt.Errorf("opa: encode input: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return keysmith.Decision{}, fmt.Errorf("opa: %w", err)
+	}
+	for k, vs := range a.header {
+		req.Header[k] = vs
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return keysmith.Decision{}, fmt.Errorf("opa: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return keysmith.Decision{}, fmt.Errorf("opa: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	var out struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return keysmith.Decision{}, fmt.Errorf("opa: decode response: %w", err)
+	}
+	return decode(out.Result)
+}
+
+// decode reads a policy result: a boolean, or an object in the form of
+// keysmith.Decision. An absent result is an undefined decision and denies.
+func decode(result json.RawMessage) (keysmith.Decision, error) {
+	if len(result) == 0 || string(result) == "null" {
+		return keysmith.Decision{Reason: UndefinedReason}, nil
+	}
+	var allow bool
+	if err := json.Unmarshal(result, &allow); err == nil {
+		return keysmith.Decision{Allow: allow}, nil
+	}
+	var d keysmith.Decision
+	if err := json.Unmarshal(result, &d); err != nil {
+		return keysmith.Decision{}, fmt.Errorf("opa: decision is neither a boolean nor an object: %w", err)
+	}
+	return d, nil
+}
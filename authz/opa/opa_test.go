@@ -0,0 +1,153 @@
+package opa_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/authz/opa"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+var testInput = &keysmith.AuthzInput{
+	TenantID: "tenant_test",
+	Scopes:   []string{"read:orders"},
+	Request:  keysmith.AuthzRequest{Method: "GET", Path: "/v1/orders", TLSVersion: "1.3"},
+}
+
+// newOPA serves a decision endpoint that answers with body and status
+// after delay, and records the input documents it received.
+func newOPA(t *testing.T, status int, body string, delay time.Duration) (*httptest.Server, *[]map[string]any) {
+	t.Helper()
+	var inputs []map[string]any
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input map[string]any `json:"input"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		inputs = append(inputs, req.Input)
+		select {
+		case <-time.After(delay):
+		case <-done:
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(func() {
+		close(done)
+		srv.Close()
+	})
+	return srv, &inputs
+}
+
+func TestAuthorizer_Decisions(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want keysmith.Decision
+	}{
+		{"boolean allow", `{"result": true}`, keysmith.Decision{Allow: true}},
+		{"boolean deny", `{"result": false}`, keysmith.Decision{}},
+		{
+			"object deny",
+			`{"result": {"allow": false, "reason": "outside business hours"}}`,
+			keysmith.Decision{Reason: "outside business hours"},
+		},
+		{
+			"obligations",
+			`{"result": {"allow": true, "obligations": {"scopes": ["read:orders"]}}}`,
+			keysmith.Decision{Allow: true, Obligations: keysmith.Obligations{Scopes: []string{"read:orders"}}},
+		},
+		{"undefined", `{}`, keysmith.Decision{Reason: opa.UndefinedReason}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, inputs := newOPA(t, http.StatusOK, tt.body, 0)
+			got, err := opa.New(srv.URL).Authorize(context.Background(), testInput)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			require.Len(t, *inputs, 1)
+			in := (*inputs)[0]
+			assert.Equal(t, "tenant_test", in["tenant_id"], "the input keeps its JSON field names")
+			assert.Equal(t, "/v1/orders", in["request"].(map[string]any)["path"])
+		})
+	}
+}
+
+func TestAuthorizer_Header(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`{"result": true}`))
+	}))
+	defer srv.Close()
+
+	_, err := opa.New(srv.URL, opa.WithHeader("Authorization", "Bearer opa-token")).Authorize(context.Background(), testInput)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer opa-token", got)
+}
+
+func TestAuthorizer_FailureModes(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		delay  time.Duration
+	}{
+		{"timeout", http.StatusOK, `{"result": true}`, time.Second},
+		{"server error", http.StatusInternalServerError, `{"code": "internal_error"}`, 0},
+		{"malformed result", http.StatusOK, `{"result": "yes"}`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+"/fail closed", func(t *testing.T) {
+			srv, _ := newOPA(t, tt.status, tt.body, tt.delay)
+			_, err := opa.New(srv.URL, opa.WithTimeout(50*time.Millisecond)).Authorize(context.Background(), testInput)
+			assert.Error(t, err)
+		})
+		t.Run(tt.name+"/fail open", func(t *testing.T) {
+			srv, _ := newOPA(t, tt.status, tt.body, tt.delay)
+			got, err := opa.New(srv.URL, opa.WithTimeout(50*time.Millisecond), opa.WithFailOpen()).Authorize(context.Background(), testInput)
+			require.NoError(t, err)
+			assert.True(t, got.Allow)
+			assert.NotEmpty(t, got.Reason, "the failure is kept as the reason")
+		})
+	}
+}
+
+func TestAuthorizer_Engine(t *testing.T) {
+	srv, inputs := newOPA(t, http.StatusOK, `{"result": {"allow": true, "obligations": {"scopes": ["read:orders"]}}}`, 0)
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithAuthorizer(opa.New(srv.URL)))
+	require.NoError(t, err)
+
+	ctx := keysmith.WithTenant(context.Background(), "app_test", "tenant_test")
+	for _, name := range []string{"read:orders", "write:orders"} {
+		require.NoError(t, eng.CreateScope(ctx, &scope.Scope{Name: name}))
+	}
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
+		Name: "Orders", Prefix: "sk", Environment: key.EnvTest, Scopes: []string{"read:orders", "write:orders"},
+	})
+	require.NoError(t, err)
+
+	result, err := eng.ValidateKey(ctx, created.RawKey)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read:orders"}, result.Scopes, "the obligation narrows the scopes")
+	require.Len(t, *inputs, 1)
+	assert.Equal(t, created.Key.ID.String(), (*inputs)[0]["key"].(map[string]any)["id"])
+
+	down, _ := newOPA(t, http.StatusServiceUnavailable, "", 0)
+	eng, err = keysmith.NewEngine(keysmith.WithStore(eng.Store()), keysmith.WithAuthorizer(opa.New(down.URL)))
+	require.NoError(t, err)
+	_, err = eng.ValidateKey(ctx, created.RawKey)
+	assert.ErrorIs(t, err, keysmith.ErrAuthorizerUnavailable)
+}
@@ -0,0 +1,65 @@
+//go:build rego
+
+package opa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/open-policy-agent/opa/v1/rego"
+
+	"github.com/xraph/keysmith"
+)
+
+// Compile-time interface check.
+var _ keysmith.Authorizer = (*Rego)(nil)
+
+// Rego evaluates a Rego policy in process, with no OPA server to run or
+// reach. It is compiled only with the rego build tag, which keeps OPA's
+// dependencies out of other builds. It is safe for concurrent use.
+type Rego struct {
+	query rego.PreparedEvalQuery
+}
+
+// NewRego compiles modules, Rego source by file name, and prepares query,
+// such as "data.keysmith.authz", whose value is the decision.
+func NewRego(ctx context.Context, query string, modules map[string]string) (*Rego, error) {
+	opts := []func(*rego.Rego){rego.Query(query)}
+	for name, src := range modules {
+		opts = append(opts, rego.Module(name, src))
+	}
+	pq, err := rego.New(opts...).PrepareForEval(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("opa: prepare %s: %w", query, err)
+	}
+	return &Rego{query: pq}, nil
+}
+
+// Authorize implements keysmith.Authorizer. Evaluation errors are returned;
+// there is no server to fail open for.
+func (r *Rego) Authorize(ctx context.Context, input *keysmith.AuthzInput) (keysmith.Decision, error) {
+	// Round trip through JSON so that the policy sees the documented field
+	// names.
+	b, err := json.Marshal(input)
+	if err != nil {
+		return keysmith.Decision{}, fmt.Errorf("opa: encode input: %w", err)
+	}
+	var doc any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return keysmith.Decision{}, fmt.Errorf("opa: encode input: %w", err)
+	}
+
+	rs, err := r.query.Eval(ctx, rego.EvalInput(doc))
+	if err != nil {
+		return keysmith.Decision{}, fmt.Errorf("opa: eval: %w", err)
+	}
+	if len(rs) == 0 || len(rs[0].Expressions) == 0 {
+		return decode(nil)
+	}
+	result, err := json.Marshal(rs[0].Expressions[0].Value)
+	if err != nil {
+		return keysmith.Decision{}, fmt.Errorf("opa: decode result: %w", err)
+	}
+	return decode(result)
+}
@@ -0,0 +1,75 @@
+//go:build rego
+
+package opa_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/authz/opa"
+)
+
+const testPolicy = `package keysmith.authz
+
+default allow := false
+
+allow if input.request.attributes.country == "DE"
+
+reason := "country not allowed" if not allow
+
+obligations := {"scopes": ["read:orders"]} if input.request.method == "GET"
+`
+
+func TestRego(t *testing.T) {
+	authz, err := opa.NewRego(context.Background(), "data.keysmith.authz", map[string]string{"authz.rego": testPolicy})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		request keysmith.AuthzRequest
+		want    keysmith.Decision
+	}{
+		{
+			"allow",
+			keysmith.AuthzRequest{Method: "POST", Attributes: map[string]string{"country": "DE"}},
+			keysmith.Decision{Allow: true},
+		},
+		{
+			"deny",
+			keysmith.AuthzRequest{Method: "POST", Attributes: map[string]string{"country": "US"}},
+			keysmith.Decision{Reason: "country not allowed"},
+		},
+		{
+			"obligations",
+			keysmith.AuthzRequest{Method: "GET", Attributes: map[string]string{"country": "DE"}},
+			keysmith.Decision{Allow: true, Obligations: keysmith.Obligations{Scopes: []string{"read:orders"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := authz.Authorize(context.Background(), &keysmith.AuthzInput{Request: tt.request})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRego_UndefinedDenies(t *testing.T) {
+	authz, err := opa.NewRego(context.Background(), "data.keysmith.authz.allow", map[string]string{
+		"authz.rego": "package keysmith.authz\n\nallow if input.request.method == \"GET\"\n",
+	})
+	require.NoError(t, err)
+
+	got, err := authz.Authorize(context.Background(), &keysmith.AuthzInput{Request: keysmith.AuthzRequest{Method: "POST"}})
+	require.NoError(t, err)
+	assert.Equal(t, keysmith.Decision{Reason: opa.UndefinedReason}, got)
+}
+
+func TestNewRego_CompileError(t *testing.T) {
+	_, err := opa.NewRego(context.Background(), "data.keysmith.authz", map[string]string{"authz.rego": "package"})
+	assert.Error(t, err)
+}
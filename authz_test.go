@@ -0,0 +1,211 @@
+package keysmith_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keysmithtest"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+// stubAuthorizer returns decision, or err, and keeps the inputs it saw.
+type stubAuthorizer struct {
+	decision keysmith.Decision
+	err      error
+
+	mu     sync.Mutex
+	inputs []*keysmith.AuthzInput
+}
+
+func (a *stubAuthorizer) Authorize(_ context.Context, input *keysmith.AuthzInput) (keysmith.Decision, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.inputs = append(a.inputs, input)
+	return a.decision, a.err
+}
+
+func (a *stubAuthorizer) calls() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.inputs)
+}
+
+func newAuthzEngine(t *testing.T, authz keysmith.Authorizer, opts ...keysmith.Option) (*keysmith.Engine, *key.CreateResult) {
+	t.Helper()
+	opts = append([]keysmith.Option{keysmith.WithStore(memory.New()), keysmith.WithAuthorizer(authz)}, opts...)
+	eng, err := keysmith.NewEngine(opts...)
+	require.NoError(t, err)
+	for _, name := range []string{"read:orders", "write:orders"} {
+		require.NoError(t, eng.CreateScope(testCtx(), &scope.Scope{Name: name}))
+	}
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+		Name:        "Orders",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+		Scopes:      []string{"read:orders", "write:orders"},
+		Metadata:    map[string]any{"team": "checkout"},
+	})
+	require.NoError(t, err)
+	return eng, created
+}
+
+func TestValidateKey_Authorizer(t *testing.T) {
+	tests := []struct {
+		name       string
+		authz      *stubAuthorizer
+		want       error
+		wantScopes []string
+	}{
+		{"allow", &stubAuthorizer{decision: keysmith.Decision{Allow: true}}, nil, []string{"read:orders", "write:orders"}},
+		{"deny", &stubAuthorizer{decision: keysmith.Decision{Reason: "outside business hours"}}, keysmith.ErrAuthzDenied, nil},
+		{"unavailable", &stubAuthorizer{err: errors.New("connection refused")}, keysmith.ErrAuthorizerUnavailable, nil},
+		{
+			"obligation narrows scopes",
+			&stubAuthorizer{decision: keysmith.Decision{Allow: true, Obligations: keysmith.Obligations{Scopes: []string{"read:orders", "admin"}}}},
+			nil, []string{"read:orders"},
+		},
+		{
+			"obligation removes every scope",
+			&stubAuthorizer{decision: keysmith.Decision{Allow: true, Obligations: keysmith.Obligations{Scopes: []string{}}}},
+			nil, []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eng, created := newAuthzEngine(t, tt.authz)
+			result, err := eng.ValidateKey(testCtx(), created.RawKey)
+			if tt.want != nil {
+				require.ErrorIs(t, err, tt.want)
+				assert.Nil(t, result)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantScopes, result.Scopes)
+			assert.Equal(t, 1, tt.authz.calls())
+		})
+	}
+}
+
+func TestValidateKey_AuthorizerDenyReason(t *testing.T) {
+	eng, created := newAuthzEngine(t, &stubAuthorizer{decision: keysmith.Decision{Reason: "risk score 92"}})
+	_, err := eng.ValidateKey(testCtx(), created.RawKey)
+	require.ErrorIs(t, err, keysmith.ErrAuthzDenied)
+	assert.Contains(t, err.Error(), "risk score 92")
+}
+
+func TestValidateKey_AuthorizerInput(t *testing.T) {
+	authz := &stubAuthorizer{decision: keysmith.Decision{Allow: true}}
+	eng, created := newAuthzEngine(t, authz)
+
+	ctx := keysmith.WithValidationRequest(testCtx(), keysmith.ValidationRequest{
+		Origin:     "https://shop.example.com",
+		Method:     "POST",
+		Path:       "/v1/orders",
+		RemoteAddr: "203.0.113.7:51234",
+		Attributes: map[string]string{"country": "DE"},
+	})
+	_, err := eng.ValidateKey(ctx, created.RawKey)
+	require.NoError(t, err)
+	require.Equal(t, 1, authz.calls())
+
+	in := authz.inputs[0]
+	assert.Equal(t, created.Key.ID, in.Key.ID)
+	assert.Equal(t, "tenant_test", in.TenantID)
+	assert.Equal(t, "app_test", in.AppID)
+	assert.Equal(t, "checkout", in.Key.Metadata["team"])
+	assert.ElementsMatch(t, []string{"read:orders", "write:orders"}, in.Scopes)
+	assert.Equal(t, keysmith.AuthzRequest{
+		Origin:     "https://shop.example.com",
+		Method:     "POST",
+		Path:       "/v1/orders",
+		RemoteIP:   "203.0.113.7",
+		TLSVersion: "none",
+		Attributes: map[string]string{"country": "DE"},
+	}, in.Request)
+	assert.False(t, in.Time.IsZero())
+
+	doc, err := json.Marshal(in)
+	require.NoError(t, err)
+	require.NotEmpty(t, created.Key.KeyHash)
+	assert.NotContains(t, string(doc), created.RawKey, "the input never holds the raw key")
+	assert.NotContains(t, string(doc), created.Key.KeyHash, "or its hash")
+}
+
+func TestValidateKey_AuthorizerSkippedOnBuiltInFailure(t *testing.T) {
+	authz := &stubAuthorizer{decision: keysmith.Decision{Allow: true}}
+	eng, created := newAuthzEngine(t, authz)
+	require.NoError(t, eng.SuspendKey(testCtx(), created.Key.ID))
+
+	_, err := eng.ValidateKey(testCtx(), created.RawKey)
+	require.Error(t, err)
+	assert.Zero(t, authz.calls(), "only keys that pass the built-in checks are referred")
+}
+
+func TestValidateKey_AuthorizerDenialUsesNoQuota(t *testing.T) {
+	for name, opts := range map[string][]keysmith.Option{
+		"metered": nil,
+		"strict":  {keysmith.WithRateLimiter(newCountingLimiter()), keysmith.WithStrictQuotas()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			authz := &stubAuthorizer{}
+			eng, _ := newAuthzEngine(t, authz, opts...)
+			pol := &policy.Policy{Name: "Metered", DailyQuota: 2}
+			require.NoError(t, eng.CreatePolicy(testCtx(), pol))
+			created := keysmithtest.NewKey(eng).WithPolicy(pol.ID).MustCreate(t, testCtx())
+
+			for range 3 {
+				_, err := eng.ValidateKey(testCtx(), created.RawKey)
+				require.ErrorIs(t, err, keysmith.ErrAuthzDenied)
+			}
+
+			authz.mu.Lock()
+			authz.decision = keysmith.Decision{Allow: true}
+			authz.mu.Unlock()
+			for i := range 2 {
+				res, err := eng.ValidateKey(testCtx(), created.RawKey)
+				require.NoError(t, err, "denied requests used none of the quota")
+				assert.EqualValues(t, 1-i, res.Quota.DailyRemaining)
+			}
+			_, err := eng.ValidateKey(testCtx(), created.RawKey)
+			assert.ErrorIs(t, err, keysmith.ErrQuotaExceeded)
+		})
+	}
+}
+
+func TestValidateKey_AuthorizerCache(t *testing.T) {
+	authz := &stubAuthorizer{decision: keysmith.Decision{Allow: true, Obligations: keysmith.Obligations{Scopes: []string{"read:orders"}}}}
+	eng, created := newAuthzEngine(t, authz, keysmith.WithAuthorizerCache(0, 0), keysmith.WithValidationCache(0, 0))
+
+	validate := func(path string) *keysmith.ValidationResult {
+		ctx := keysmith.WithValidationRequest(testCtx(), keysmith.ValidationRequest{Path: path})
+		result, err := eng.ValidateKey(ctx, created.RawKey)
+		require.NoError(t, err)
+		return result
+	}
+	first := validate("/v1/orders")
+	second := validate("/v1/orders")
+	assert.Equal(t, 1, authz.calls(), "the same input reuses the decision")
+	assert.Equal(t, []string{"read:orders"}, second.Scopes, "cached obligations still apply")
+	assert.Equal(t, first.Scopes, second.Scopes)
+
+	validate("/v1/refunds")
+	assert.Equal(t, 2, authz.calls(), "another input is decided afresh")
+
+	authz.err = errors.New("timeout")
+	ctx := keysmith.WithValidationRequest(testCtx(), keysmith.ValidationRequest{Path: "/v1/invoices"})
+	_, err := eng.ValidateKey(ctx, created.RawKey)
+	require.ErrorIs(t, err, keysmith.ErrAuthorizerUnavailable)
+	_, err = eng.ValidateKey(ctx, created.RawKey)
+	require.ErrorIs(t, err, keysmith.ErrAuthorizerUnavailable, "errors are not cached")
+	assert.Equal(t, 4, authz.calls())
+}
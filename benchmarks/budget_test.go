@@ -0,0 +1,102 @@
+package benchmarks_test
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "rewrite the allocation baselines")
+
+// baselinePath holds the allocations per validation of each scenario, one
+// "name allocs" pair per line.
+var baselinePath = filepath.Join("testdata", "allocs.golden")
+
+// TestAllocationBudget fails when a scenario allocates more per validation
+// than its stored baseline, or a warm one more than warmAllocBudget. It is
+// skipped under the race detector, which allocates on its own.
+func TestAllocationBudget(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocation counts are not meaningful with -race")
+	}
+
+	got := make(map[string]int)
+	var names []string
+	for _, s := range scenarios() {
+		eng, ctx, raw := setup(t, s)
+		allocs := testing.AllocsPerRun(1000, func() {
+			if _, err := eng.ValidateKey(ctx, raw); err != nil {
+				t.Fatal(err)
+			}
+		})
+		got[s.name()] = int(allocs)
+		names = append(names, s.name())
+		if s.cache {
+			assert.LessOrEqual(t, int(allocs), warmAllocBudget, "%s exceeds the warm allocation budget", s.name())
+		}
+	}
+
+	if *update {
+		var buf bytes.Buffer
+		for _, name := range names {
+			fmt.Fprintf(&buf, "%s %d\n", name, got[name])
+		}
+		require.NoError(t, os.WriteFile(baselinePath, buf.Bytes(), 0o644))
+	}
+
+	want := readBaselines(t)
+	for _, name := range names {
+		base, ok := want[name]
+		if !assert.True(t, ok, "%s has no baseline; run go test ./benchmarks -run AllocationBudget -update", name) {
+			continue
+		}
+		assert.LessOrEqual(t, got[name], base, "%s allocates more than its baseline", name)
+		if got[name] < base {
+			t.Logf("%s allocates %d, below its baseline of %d; run with -update to lower it", name, got[name], base)
+		}
+	}
+}
+
+// TestAllocationBudget_LiveStats checks that the live stats tracker adds no
+// allocation to a validation of a key it already tracks.
+func TestAllocationBudget_LiveStats(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocation counts are not meaningful with -race")
+	}
+	s := scenario{cache: true, policy: true, scopes: 5, live: true}
+	eng, ctx, raw := setup(t, s)
+	allocs := testing.AllocsPerRun(1000, func() {
+		if _, err := eng.ValidateKey(ctx, raw); err != nil {
+			t.Fatal(err)
+		}
+	})
+	assert.LessOrEqual(t, int(allocs), warmAllocBudget, "%s exceeds the warm allocation budget", s.name())
+}
+
+func readBaselines(t *testing.T) map[string]int {
+	t.Helper()
+	f, err := os.Open(baselinePath)
+	require.NoError(t, err)
+	defer f.Close()
+
+	out := make(map[string]int)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		name, n, ok := strings.Cut(sc.Text(), " ")
+		require.True(t, ok, "malformed baseline line %q", sc.Text())
+		allocs, err := strconv.Atoi(n)
+		require.NoError(t, err)
+		out[name] = allocs
+	}
+	require.NoError(t, sc.Err())
+	return out
+}
@@ -0,0 +1,13 @@
+// Package benchmarks holds the engine's hot-path benchmarks and the
+// allocation budget they are held to. It has no code of its own; run the
+// suite with
+//
+//	go test -run '^$' -bench . -benchmem -count 10 ./benchmarks/ > new.txt
+//
+// and compare two runs with benchstat. TestAllocationBudget checks the
+// allocations of each scenario against testdata/allocs.golden so that a
+// regression fails in CI. After an intended change, regenerate the file
+// with
+//
+//	go test ./benchmarks -run AllocationBudget -update
+package benchmarks
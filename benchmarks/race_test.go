@@ -0,0 +1,7 @@
+//go:build race
+
+package benchmarks_test
+
+// raceEnabled is set when the race detector is on, which changes
+// allocation counts.
+const raceEnabled = true
@@ -0,0 +1,159 @@
+package benchmarks_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+// warmAllocBudget is the allocation budget of a validation served from the
+// validation cache: the one allocation that holds the returned
+// ValidationResult with its copies of the key and policy. Hashing uses
+// pooled buffers, the cache lookup and scopes are shared without copying,
+// and last-used times are buffered for a coalesced write rather than
+// written from a goroutine per call. Cold validations are budgeted by their
+// baseline in testdata/allocs.golden, since most of their allocations are
+// the store's.
+const warmAllocBudget = 1
+
+// scenario is one ValidateKey workload.
+type scenario struct {
+	// cache enables the validation cache, so that every call after the
+	// first is served without store reads. Without it each call reads the
+	// key, its policy, and its scopes from the store.
+	cache bool
+
+	// policy attaches a policy to the key.
+	policy bool
+
+	// scopes is the number of scopes assigned to the key.
+	scopes int
+
+	// live enables the live stats tracker. No scenario in scenarios() sets
+	// it; BenchmarkValidateKey_LiveStats compares it on and off.
+	live bool
+}
+
+// name returns the scenario's benchmark name in benchstat's key=value
+// form, such as "store=warm/policy=true/scopes=5".
+func (s scenario) name() string {
+	store := "cold"
+	if s.cache {
+		store = "warm"
+	}
+	name := fmt.Sprintf("store=%s/policy=%t/scopes=%d", store, s.policy, s.scopes)
+	if s.live {
+		name += "/live=true"
+	}
+	return name
+}
+
+// scenarios covers cold and warm lookups, with and without a policy, at 0,
+// 5, and 50 scopes.
+func scenarios() []scenario {
+	var out []scenario
+	for _, cache := range []bool{false, true} {
+		for _, pol := range []bool{false, true} {
+			for _, n := range []int{0, 5, 50} {
+				out = append(out, scenario{cache: cache, policy: pol, scopes: n})
+			}
+		}
+	}
+	return out
+}
+
+// setup returns an engine and a raw key for s, validated once so that a
+// warm cache is populated.
+func setup(tb testing.TB, s scenario) (*keysmith.Engine, context.Context, string) {
+	tb.Helper()
+	opts := []keysmith.Option{keysmith.WithStore(memory.New())}
+	if s.cache {
+		opts = append(opts, keysmith.WithValidationCache(keysmith.DefaultValidationCacheTTL, 0))
+	}
+	if s.live {
+		opts = append(opts, keysmith.WithLiveStats(0))
+	}
+	eng, err := keysmith.NewEngine(opts...)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	ctx := keysmith.WithTenant(context.Background(), "app_bench", "tenant_bench")
+
+	input := &keysmith.CreateKeyInput{Name: "Bench", Prefix: "sk", Environment: key.EnvLive}
+	for i := range s.scopes {
+		name := fmt.Sprintf("bench:scope%d", i)
+		if err := eng.CreateScope(ctx, &scope.Scope{Name: name}); err != nil {
+			tb.Fatal(err)
+		}
+		input.Scopes = append(input.Scopes, name)
+	}
+	if s.policy {
+		pol := &policy.Policy{Name: "Bench", AllowedScopes: input.Scopes}
+		if err := eng.CreatePolicy(ctx, pol); err != nil {
+			tb.Fatal(err)
+		}
+		input.PolicyID = &pol.ID
+	}
+	created, err := eng.CreateKey(ctx, input)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if _, err := eng.ValidateKey(ctx, created.RawKey); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { _ = eng.Stop(context.Background()) })
+	return eng, ctx, created.RawKey
+}
+
+func BenchmarkValidateKey(b *testing.B) {
+	for _, s := range scenarios() {
+		b.Run(s.name(), func(b *testing.B) {
+			eng, ctx, raw := setup(b, s)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for b.Loop() {
+				if _, err := eng.ValidateKey(ctx, raw); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkValidateKey_Parallel(b *testing.B) {
+	eng, ctx, raw := setup(b, scenario{cache: true, policy: true, scopes: 5})
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := eng.ValidateKey(ctx, raw); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func BenchmarkValidateKey_LiveStats(b *testing.B) {
+	for _, live := range []bool{false, true} {
+		b.Run(fmt.Sprintf("live=%t", live), func(b *testing.B) {
+			eng, ctx, raw := setup(b, scenario{cache: true, policy: true, scopes: 5, live: live})
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := eng.ValidateKey(ctx, raw); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,358 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/usage"
+)
+
+// BundleSchemaVersion is the key bundle format written by ExportKey.
+// ImportKeyBundle accepts bundles up to this version and refuses newer ones
+// with ErrBundleVersionUnsupported.
+const BundleSchemaVersion = 1
+
+// DefaultExportUsageWindow is how far back ExportKey reads daily usage
+// summaries when ExportOptions.UsageWindow is zero.
+const DefaultExportUsageWindow = 30 * 24 * time.Hour
+
+// KeyBundle is a self-contained copy of one key for moving it to another
+// keysmith cluster. It carries the key hash, so a bundle is as sensitive as
+// the key itself: anyone who imports it gets a working key.
+type KeyBundle struct {
+	SchemaVersion int       `json:"schema_version"`
+	ExportedAt    time.Time `json:"exported_at"`
+
+	Key *key.Key `json:"key"`
+
+	// KeyHash is the key's hash, which key.Key leaves out of JSON.
+	KeyHash string `json:"key_hash"`
+
+	// Policy is the key's policy, matched by name on import. Nil when the
+	// key has none.
+	Policy *policy.Policy `json:"policy,omitempty"`
+
+	// Scopes are the scopes assigned to the key, matched by name on import.
+	Scopes []*scope.Scope `json:"scopes,omitempty"`
+
+	Rotations []BundleRotation `json:"rotations,omitempty"`
+
+	// Usage holds daily usage summaries for reference, such as billing
+	// reconciliation. They are not written to the target, whose usage
+	// starts from the import.
+	Usage []*usage.Aggregation `json:"usage,omitempty"`
+}
+
+// BundleRotation is a rotation record in a bundle, with the hashes that
+// rotation.Record leaves out of JSON.
+type BundleRotation struct {
+	ID         id.RotationID   `json:"id"`
+	OldKeyHash string          `json:"old_key_hash"`
+	NewKeyHash string          `json:"new_key_hash"`
+	OldHint    string          `json:"old_hint,omitempty"`
+	NewHint    string          `json:"new_hint,omitempty"`
+	Reason     rotation.Reason `json:"reason"`
+	GraceTTL   time.Duration   `json:"grace_ttl"`
+	GraceEnds  time.Time       `json:"grace_ends"`
+	RotatedBy  string          `json:"rotated_by,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// ExportOptions configures ExportKey.
+type ExportOptions struct {
+	// UsageWindow is how far back daily usage summaries go. Zero uses
+	// DefaultExportUsageWindow; a negative window leaves usage out.
+	UsageWindow time.Duration
+
+	// WithoutRotations leaves the rotation history out.
+	WithoutRotations bool
+}
+
+// ImportOptions configures ImportKeyBundle.
+type ImportOptions struct {
+	// TenantID and AppID, when set, place the key in a different tenant or
+	// app than it had on the source cluster.
+	TenantID string
+	AppID    string
+
+	// Rotations imports the bundle's rotation history.
+	Rotations bool
+}
+
+// ImportReport describes what ImportKeyBundle did.
+type ImportReport struct {
+	Key *key.Key
+
+	// Created is false when the key already existed with the same hash, in
+	// which case nothing was written.
+	Created bool
+
+	// PolicyCreated reports that no policy with the bundle policy's name
+	// existed in the tenant, so one was created from the bundle.
+	PolicyCreated bool
+
+	// ScopesCreated names the bundle scopes that did not exist in the
+	// tenant and were created.
+	ScopesCreated []string
+
+	// Rotations is the number of rotation records imported.
+	Rotations int
+}
+
+// ImportConflictError is returned by ImportKeyBundle when the bundle's key
+// ID or hash is already taken on the target by a different key: the ID
+// with a different hash or owner, or the hash under a different ID.
+// Nothing is written.
+type ImportConflictError struct {
+	KeyID id.KeyID
+
+	// Existing is the conflicting key on the target.
+	Existing *key.Key
+
+	// Reason says what differs.
+	Reason string
+}
+
+func (e *ImportConflictError) Error() string {
+	return fmt.Sprintf("%s: key %s: %s (existing key %s in tenant %q, state %s, hint %s, updated %s)",
+		ErrImportConflict, e.KeyID, e.Reason, e.Existing.ID, e.Existing.TenantID,
+		e.Existing.State, e.Existing.Hint, e.Existing.UpdatedAt.Format(time.RFC3339))
+}
+
+// Is reports whether target is ErrImportConflict.
+func (e *ImportConflictError) Is(target error) bool { return target == ErrImportConflict }
+
+// ExportKey returns a bundle holding keyID's record and hash, its policy and
+// scopes, its rotation history, and recent daily usage summaries, for
+// ImportKeyBundle on another cluster. Because the bundle lets the key work
+// elsewhere, ExportKey requires a system-scoped context and fires
+// KeyExported, which the audit extension records as critical.
+func (e *Engine) ExportKey(ctx context.Context, keyID id.KeyID, opts ExportOptions) (*KeyBundle, error) {
+	if scopeFromContext(ctx) != (tenantScope{}) {
+		return nil, fmt.Errorf("%w: export key", ErrSystemScopeRequired)
+	}
+	k, err := e.store.Keys().Get(ctx, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("get key: %w", err)
+	}
+
+	now := e.now()
+	b := &KeyBundle{
+		SchemaVersion: BundleSchemaVersion,
+		ExportedAt:    now.UTC(),
+		Key:           k,
+		KeyHash:       k.KeyHash,
+	}
+	if k.PolicyID != nil {
+		pol, err := e.store.Policies().Get(ctx, *k.PolicyID)
+		if err != nil {
+			return nil, fmt.Errorf("get policy: %w", err)
+		}
+		// The target has none of the bases, so the policy travels merged.
+		eff, err := e.effectivePolicy(ctx, pol)
+		if err != nil {
+			return nil, err
+		}
+		flat := *eff.Policy
+		flat.BasePolicyID = nil
+		b.Policy = &flat
+	}
+	if b.Scopes, err = e.store.Scopes().ListByKey(ctx, k.ID); err != nil {
+		return nil, fmt.Errorf("list scopes: %w", err)
+	}
+	if !opts.WithoutRotations {
+		recs, err := e.store.Rotations().List(ctx, &rotation.ListFilter{KeyID: &k.ID})
+		if err != nil {
+			return nil, fmt.Errorf("list rotations: %w", err)
+		}
+		for _, rec := range recs {
+			b.Rotations = append(b.Rotations, BundleRotation{
+				ID:         rec.ID,
+				OldKeyHash: rec.OldKeyHash,
+				NewKeyHash: rec.NewKeyHash,
+				OldHint:    rec.OldHint,
+				NewHint:    rec.NewHint,
+				Reason:     rec.Reason,
+				GraceTTL:   rec.GraceTTL,
+				GraceEnds:  rec.GraceEnds,
+				RotatedBy:  rec.RotatedBy,
+				CreatedAt:  rec.CreatedAt,
+			})
+		}
+	}
+	window := opts.UsageWindow
+	if window == 0 {
+		window = DefaultExportUsageWindow
+	}
+	if window > 0 {
+		after := now.Add(-window)
+		if b.Usage, err = e.store.Usages().Aggregate(ctx, &usage.QueryFilter{KeyID: &k.ID, Period: "day", After: &after}); err != nil {
+			return nil, fmt.Errorf("aggregate usage: %w", err)
+		}
+	}
+
+	_ = e.hooks.FireKeyExported(ctx, k, e.eventMeta(ctx, plugin.TriggerManual, ""))
+	return b, nil
+}
+
+// ImportKeyBundle recreates a key exported with ExportKey, keeping its ID
+// and hash so the original raw key validates on this cluster. The bundle's
+// policy and scopes are matched by name in the target tenant and created
+// when missing. Importing the same bundle again is a no-op reported with
+// Created false; a key ID or hash already used by a different key fails
+// with an [*ImportConflictError] instead of overwriting it, and a
+// tombstoned hash fails with ErrHashTombstoned. Bundles newer than
+// BundleSchemaVersion fail with ErrBundleVersionUnsupported.
+// ImportKeyBundle requires a system-scoped context and fires KeyImported.
+func (e *Engine) ImportKeyBundle(ctx context.Context, b *KeyBundle, opts ImportOptions) (*ImportReport, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	if scopeFromContext(ctx) != (tenantScope{}) {
+		return nil, fmt.Errorf("%w: import key", ErrSystemScopeRequired)
+	}
+	switch {
+	case b == nil || b.Key == nil || b.KeyHash == "" || b.SchemaVersion < 1:
+		return nil, fmt.Errorf("%w: bundle needs a schema version, a key, and a key hash", ErrInvalidBundle)
+	case b.SchemaVersion > BundleSchemaVersion:
+		return nil, fmt.Errorf("%w: version %d, this engine reads up to %d", ErrBundleVersionUnsupported, b.SchemaVersion, BundleSchemaVersion)
+	}
+
+	cp := *b.Key
+	k := &cp
+	k.KeyHash, _ = key.NormalizeHash(b.KeyHash)
+	if err := e.checkTombstones(ctx, k.KeyHash); err != nil {
+		return nil, err
+	}
+	if opts.TenantID != "" {
+		k.TenantID = opts.TenantID
+	}
+	if opts.AppID != "" {
+		k.AppID = opts.AppID
+	}
+
+	if existing, err := e.store.Keys().Get(ctx, k.ID); err == nil {
+		switch {
+		case !key.HashesEqual(existing.KeyHash, k.KeyHash):
+			return nil, &ImportConflictError{KeyID: k.ID, Existing: existing, Reason: "ID exists with a different hash"}
+		case existing.TenantID != k.TenantID || existing.AppID != k.AppID:
+			return nil, &ImportConflictError{KeyID: k.ID, Existing: existing, Reason: "ID exists in a different tenant or app"}
+		}
+		return &ImportReport{Key: existing}, nil
+	}
+	if existing, err := e.store.Keys().GetByHash(ctx, k.KeyHash); err == nil {
+		return nil, &ImportConflictError{KeyID: k.ID, Existing: existing, Reason: "hash belongs to a different key ID"}
+	}
+
+	if err := e.validateMetadataSchema(ctx, k.TenantID, k.Metadata); err != nil {
+		return nil, err
+	}
+	if err := validateLabels(k.Labels); err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{Key: k, Created: true}
+	k.PolicyID = nil
+	if b.Policy != nil {
+		pol, created, err := e.importPolicy(ctx, b.Policy, k.TenantID, k.AppID)
+		if err != nil {
+			return nil, err
+		}
+		k.PolicyID = &pol.ID
+		report.PolicyCreated = created
+	}
+	names := make([]string, 0, len(b.Scopes))
+	for _, s := range b.Scopes {
+		created, err := e.importScope(ctx, s, k.TenantID, k.AppID)
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			report.ScopesCreated = append(report.ScopesCreated, s.Name)
+		}
+		names = append(names, s.Name)
+	}
+	k.Scopes = names
+
+	if err := e.store.Keys().Create(ctx, k); err != nil {
+		return nil, fmt.Errorf("create key: %w", err)
+	}
+	e.bumpRevision(ctx, k.TenantID)
+	if len(names) > 0 {
+		if err := e.store.Scopes().AssignToKey(ctx, k.ID, names); err != nil {
+			return nil, fmt.Errorf("assign scopes: %w", err)
+		}
+	}
+	if opts.Rotations {
+		for _, r := range b.Rotations {
+			rec := &rotation.Record{
+				ID:         r.ID,
+				KeyID:      k.ID,
+				TenantID:   k.TenantID,
+				AppID:      k.AppID,
+				OldKeyHash: r.OldKeyHash,
+				NewKeyHash: r.NewKeyHash,
+				OldHint:    r.OldHint,
+				NewHint:    r.NewHint,
+				Reason:     r.Reason,
+				GraceTTL:   r.GraceTTL,
+				GraceEnds:  r.GraceEnds,
+				RotatedBy:  r.RotatedBy,
+				CreatedAt:  r.CreatedAt,
+			}
+			if err := e.store.Rotations().Create(ctx, rec); err != nil {
+				return report, fmt.Errorf("import rotation %s: %w", r.ID, err)
+			}
+			report.Rotations++
+		}
+	}
+
+	_ = e.hooks.FireKeyImported(ctx, k, e.eventMeta(ctx, plugin.TriggerManual, plugin.ReasonImported))
+	return report, nil
+}
+
+// importPolicy returns the tenant's policy named like pol, creating a copy
+// of pol with a new ID when there is none.
+func (e *Engine) importPolicy(ctx context.Context, pol *policy.Policy, tenantID, appID string) (*policy.Policy, bool, error) {
+	if existing, err := e.store.Policies().GetByName(ctx, tenantID, pol.Name); err == nil {
+		return existing, false, nil
+	}
+	cp := *pol
+	cp.ID = id.NewPolicyID()
+	cp.TenantID, cp.AppID = tenantID, appID
+	cp.BasePolicyID = nil
+	cp.AllowedScopes = slices.Clone(pol.AllowedScopes)
+	cp.CreatedAt = time.Now()
+	cp.UpdatedAt = cp.CreatedAt
+	if err := e.store.Policies().Create(ctx, &cp); err != nil {
+		return nil, false, fmt.Errorf("create policy %q: %w", pol.Name, err)
+	}
+	e.bumpRevision(ctx, tenantID)
+	_ = e.hooks.FirePolicyCreated(ctx, &cp, e.eventMeta(ctx, plugin.TriggerManual, plugin.ReasonImported))
+	return &cp, true, nil
+}
+
+// importScope creates a copy of s with a new ID unless the tenant already
+// has a scope with its name.
+func (e *Engine) importScope(ctx context.Context, s *scope.Scope, tenantID, appID string) (bool, error) {
+	if _, err := e.store.Scopes().GetByName(ctx, tenantID, s.Name); err == nil {
+		return false, nil
+	}
+	cp := *s
+	cp.ID = id.NewScopeID()
+	cp.TenantID, cp.AppID = tenantID, appID
+	cp.CreatedAt = time.Now()
+	if err := e.store.Scopes().Create(ctx, &cp); err != nil {
+		return false, fmt.Errorf("create scope %q: %w", s.Name, err)
+	}
+	e.bumpRevision(ctx, tenantID)
+	return true, nil
+}
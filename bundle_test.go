@@ -0,0 +1,154 @@
+package keysmith_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/keysmithtest"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/rotation"
+)
+
+// exportBundle creates a key with a policy, scopes, and one rotation on a
+// source engine and exports it through JSON, as a move between clusters
+// would.
+func exportBundle(t *testing.T, src *keysmith.Engine) (*keysmith.KeyBundle, string) {
+	t.Helper()
+	ctx := testCtx()
+	pol := keysmithtest.NewPolicy(src).WithName("standard").WithAllowedScopes("read", "write").MustCreate(t, ctx)
+	created := keysmithtest.NewKey(src).WithScopes("read", "write").WithPolicy(pol.ID).MustCreate(t, ctx)
+	rotated, err := src.RotateKey(ctx, created.Key.ID, rotation.ReasonManual)
+	require.NoError(t, err)
+
+	b, err := src.ExportKey(context.Background(), created.Key.ID, keysmith.ExportOptions{})
+	require.NoError(t, err)
+
+	data, err := json.Marshal(b)
+	require.NoError(t, err)
+	var decoded keysmith.KeyBundle
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	return &decoded, rotated.RawKey
+}
+
+func TestImportKeyBundle_RoundTrip(t *testing.T) {
+	rec := keysmithtest.NewRecorder()
+	src := keysmithtest.NewEngine(t, keysmith.WithExtension(rec))
+	b, rawKey := exportBundle(t, src)
+	assert.Equal(t, keysmith.BundleSchemaVersion, b.SchemaVersion)
+	assert.Equal(t, 1, rec.Count("KeyExported"))
+
+	dst := keysmithtest.NewEngine(t)
+	report, err := dst.ImportKeyBundle(context.Background(), b, keysmith.ImportOptions{Rotations: true})
+	require.NoError(t, err)
+	assert.True(t, report.Created)
+	assert.True(t, report.PolicyCreated)
+	assert.ElementsMatch(t, []string{"read", "write"}, report.ScopesCreated)
+	assert.Equal(t, 1, report.Rotations)
+
+	vr := keysmithtest.AssertValidates(t, dst, testCtx(), rawKey)
+	require.NotNil(t, vr)
+	assert.Equal(t, b.Key.ID, vr.Key.ID)
+	assert.ElementsMatch(t, []string{"read", "write"}, vr.Scopes)
+	require.NotNil(t, vr.Policy)
+	assert.Equal(t, "standard", vr.Policy.Name)
+
+	rots, err := dst.ListRotations(testCtx(), &rotation.ListFilter{KeyID: &vr.Key.ID})
+	require.NoError(t, err)
+	require.Len(t, rots, 1)
+	assert.Equal(t, b.Rotations[0].ID, rots[0].ID)
+
+	// Importing again changes nothing.
+	again, err := dst.ImportKeyBundle(context.Background(), b, keysmith.ImportOptions{Rotations: true})
+	require.NoError(t, err)
+	assert.False(t, again.Created)
+}
+
+func TestImportKeyBundle_RemapsPolicyByName(t *testing.T) {
+	b, rawKey := exportBundle(t, keysmithtest.NewEngine(t))
+
+	dst := keysmithtest.NewEngine(t)
+	existing := keysmithtest.NewPolicy(dst).WithName("standard").MustCreate(t, testCtx())
+	keysmithtest.NewKey(dst).WithScopes("read").MustCreate(t, testCtx())
+
+	report, err := dst.ImportKeyBundle(context.Background(), b, keysmith.ImportOptions{})
+	require.NoError(t, err)
+	assert.False(t, report.PolicyCreated)
+	assert.Equal(t, []string{"write"}, report.ScopesCreated)
+	assert.Zero(t, report.Rotations)
+	require.NotNil(t, report.Key.PolicyID)
+	assert.Equal(t, existing.ID, *report.Key.PolicyID)
+
+	pols, err := dst.ListPolicies(testCtx(), &policy.ListFilter{})
+	require.NoError(t, err)
+	assert.Len(t, pols, 1)
+	keysmithtest.AssertValidates(t, dst, testCtx(), rawKey)
+}
+
+func TestImportKeyBundle_MovesTenant(t *testing.T) {
+	b, rawKey := exportBundle(t, keysmithtest.NewEngine(t))
+
+	dst := keysmithtest.NewEngine(t)
+	report, err := dst.ImportKeyBundle(context.Background(), b, keysmith.ImportOptions{TenantID: "tenant_new"})
+	require.NoError(t, err)
+	assert.Equal(t, "tenant_new", report.Key.TenantID)
+
+	keysmithtest.AssertValidates(t, dst, keysmithtest.TenantContext("tenant_new"), rawKey)
+	_, err = dst.GetKey(testCtx(), b.Key.ID)
+	assert.ErrorIs(t, err, keysmith.ErrKeyNotFound)
+}
+
+func TestImportKeyBundle_Conflict(t *testing.T) {
+	b, _ := exportBundle(t, keysmithtest.NewEngine(t))
+	dst := keysmithtest.NewEngine(t)
+	_, err := dst.ImportKeyBundle(context.Background(), b, keysmith.ImportOptions{})
+	require.NoError(t, err)
+
+	changed := *b
+	changed.KeyHash = "different"
+	_, err = dst.ImportKeyBundle(context.Background(), &changed, keysmith.ImportOptions{})
+	require.ErrorIs(t, err, keysmith.ErrImportConflict)
+	var conflict *keysmith.ImportConflictError
+	require.True(t, errors.As(err, &conflict))
+	assert.Equal(t, b.Key.ID, conflict.KeyID)
+	assert.Contains(t, err.Error(), "different hash")
+
+	// The stored key is untouched.
+	k, err := dst.ExportKey(context.Background(), b.Key.ID, keysmith.ExportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, b.KeyHash, k.KeyHash)
+
+	// The same hash under another ID is refused too.
+	other := keysmithtest.NewKey(dst).MustCreate(t, testCtx())
+	moved := *b
+	moved.Key = other.Key
+	moved.KeyHash = b.KeyHash
+	_, err = dst.ImportKeyBundle(context.Background(), &moved, keysmith.ImportOptions{})
+	assert.ErrorIs(t, err, keysmith.ErrImportConflict)
+}
+
+func TestImportKeyBundle_RefusesNewerVersion(t *testing.T) {
+	b, _ := exportBundle(t, keysmithtest.NewEngine(t))
+	b.SchemaVersion = keysmith.BundleSchemaVersion + 1
+
+	_, err := keysmithtest.NewEngine(t).ImportKeyBundle(context.Background(), b, keysmith.ImportOptions{})
+	assert.ErrorIs(t, err, keysmith.ErrBundleVersionUnsupported)
+
+	_, err = keysmithtest.NewEngine(t).ImportKeyBundle(context.Background(), &keysmith.KeyBundle{SchemaVersion: 1}, keysmith.ImportOptions{})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidBundle)
+}
+
+func TestExportKey_RequiresSystemScope(t *testing.T) {
+	eng := keysmithtest.NewEngine(t)
+	created := keysmithtest.NewKey(eng).MustCreate(t, testCtx())
+
+	_, err := eng.ExportKey(testCtx(), created.Key.ID, keysmith.ExportOptions{})
+	assert.ErrorIs(t, err, keysmith.ErrSystemScopeRequired)
+	_, err = eng.ImportKeyBundle(testCtx(), &keysmith.KeyBundle{}, keysmith.ImportOptions{})
+	assert.ErrorIs(t, err, keysmith.ErrSystemScopeRequired)
+}
@@ -0,0 +1,116 @@
+// Package capture defines debug captures: sampled copies of requests made
+// with a key, kept for a few hours so support can see what an integration
+// actually sends.
+package capture
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/xraph/keysmith/id"
+)
+
+// Redacted replaces header values and body fragments that must never be
+// stored.
+const Redacted = "[redacted]"
+
+// DefaultBodyLimit is the number of request body bytes kept in a capture.
+const DefaultBodyLimit = 4 << 10
+
+// DefaultHeaders is the header allow-list used when none is configured.
+var DefaultHeaders = []string{
+	"Accept",
+	"Content-Length",
+	"Content-Type",
+	"User-Agent",
+	"X-Request-Id",
+}
+
+// Capture is one sampled request. Only allow-listed headers are kept, and
+// secrets are redacted before the capture is stored.
+type Capture struct {
+	ID            id.CaptureID      `json:"id" db:"id"`
+	KeyID         id.KeyID          `json:"key_id" db:"key_id"`
+	TenantID      string            `json:"tenant_id" db:"tenant_id"`
+	Method        string            `json:"method" db:"method"`
+	Path          string            `json:"path" db:"path"`
+	Headers       map[string]string `json:"headers,omitempty" db:"headers"`
+	Body          string            `json:"body,omitempty" db:"body"`
+	BodyTruncated bool              `json:"body_truncated,omitempty" db:"body_truncated"`
+	CapturedAt    time.Time         `json:"captured_at" db:"captured_at"`
+}
+
+// FromRequest captures r's method, path, the allow-listed headers, and up to
+// bodyLimit bytes of body, redacted of rawKey as by Redact. The body is
+// redacted before it is cut, so a key straddling the limit is not kept in
+// part. The body is restored so that r can still be served. The query
+// string is dropped, as it may carry credentials.
+func FromRequest(r *http.Request, rawKey string, headers []string, bodyLimit int) *Capture {
+	c := &Capture{
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		Headers: make(map[string]string, len(headers)),
+	}
+	for _, name := range headers {
+		if v := r.Header.Values(name); len(v) > 0 {
+			c.Headers[http.CanonicalHeaderKey(name)] = strings.Join(v, ", ")
+		}
+	}
+
+	if r.Body != nil && r.Body != http.NoBody && bodyLimit > 0 {
+		// Read far enough past the limit to see the whole of a key that
+		// starts before it.
+		buf, _ := io.ReadAll(io.LimitReader(r.Body, int64(bodyLimit+max(len(rawKey), 1))))
+		r.Body = readCloser{io.MultiReader(bytes.NewReader(buf), r.Body), r.Body}
+		c.Body = string(buf)
+	}
+	c.Redact(rawKey)
+	c.Truncate(bodyLimit)
+	return c
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// Redact removes secrets from c. The Authorization header and other
+// credential-bearing headers are always redacted, as is any header whose
+// value contains rawKey; occurrences of rawKey in the body are replaced.
+func (c *Capture) Redact(rawKey string) {
+	for name, v := range c.Headers {
+		if sensitiveHeader(name) || (rawKey != "" && strings.Contains(v, rawKey)) {
+			c.Headers[name] = Redacted
+		}
+	}
+	if rawKey != "" {
+		c.Body = strings.ReplaceAll(c.Body, rawKey, Redacted)
+	}
+}
+
+// Truncate cuts c's body to limit bytes and marks it truncated if it was
+// longer. Redact c first: a secret cut in two is no longer found.
+func (c *Capture) Truncate(limit int) {
+	if len(c.Body) > limit {
+		c.Body = c.Body[:limit]
+		c.BodyTruncated = true
+	}
+}
+
+// sensitiveHeader reports whether a header carries credentials by name.
+func sensitiveHeader(name string) bool {
+	switch http.CanonicalHeaderKey(name) {
+	case "Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key":
+		return true
+	}
+	lower := strings.ToLower(name)
+	for _, s := range []string{"token", "secret", "password", "signature"} {
+		if strings.Contains(lower, s) {
+			return true
+		}
+	}
+	return false
+}
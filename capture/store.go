@@ -0,0 +1,20 @@
+package capture
+
+import (
+	"context"
+	"time"
+
+	"github.com/xraph/keysmith/id"
+)
+
+// Store is the persistence interface for debug captures.
+type Store interface {
+	// Record stores a capture.
+	Record(ctx context.Context, c *Capture) error
+
+	// List returns up to limit captures for the key, newest first.
+	List(ctx context.Context, keyID id.KeyID, limit int) ([]*Capture, error)
+
+	// Purge deletes captures taken before the given time.
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/xraph/keysmith/apitypes"
+)
+
+// MaintainStore runs store maintenance, or reports table statistics.
+func (c *Client) MaintainStore(ctx context.Context, req *apitypes.MaintainStoreRequest) (*apitypes.MaintenanceResponse, error) {
+	return do[*apitypes.MaintenanceResponse](ctx, c, http.MethodPost, "/v1/admin/maintenance", req)
+}
+
+// GetStorageReport counts the rows of every tenant, or of one.
+func (c *Client) GetStorageReport(ctx context.Context, req *apitypes.GetStorageReportRequest) (*apitypes.StorageReportResponse, error) {
+	return do[*apitypes.StorageReportResponse](ctx, c, http.MethodGet, "/v1/admin/storage", req)
+}
+
+// RevokeByHash revokes the keys with the given hashes.
+func (c *Client) RevokeByHash(ctx context.Context, req *apitypes.RevokeByHashRequest) (*apitypes.HashReportsResponse, error) {
+	return do[*apitypes.HashReportsResponse](ctx, c, http.MethodPost, "/v1/admin/revoke-by-hash", req)
+}
+
+// ValidateHashes reports which keys the given hashes belong to.
+func (c *Client) ValidateHashes(ctx context.Context, req *apitypes.ValidateHashesRequest) (*apitypes.HashReportsResponse, error) {
+	return do[*apitypes.HashReportsResponse](ctx, c, http.MethodPost, "/v1/admin/validate-hashes", req)
+}
+
+// ListKeysByCreator returns the keys a user or service created.
+func (c *Client) ListKeysByCreator(ctx context.Context, req *apitypes.ListKeysByCreatorRequest) (*apitypes.KeysByCreatorResponse, error) {
+	return do[*apitypes.KeysByCreatorResponse](ctx, c, http.MethodGet, "/v1/admin/keys/by-creator", req)
+}
+
+// RevokeByCreator revokes every key a user or service created.
+func (c *Client) RevokeByCreator(ctx context.Context, req *apitypes.RevokeByCreatorRequest) (*apitypes.CreatorRevokeResponse, error) {
+	return do[*apitypes.CreatorRevokeResponse](ctx, c, http.MethodPost, "/v1/admin/keys/revoke-by-creator", req)
+}
+
+// ListHashTombstones lists hash tombstones with counts per reason.
+func (c *Client) ListHashTombstones(ctx context.Context, req *apitypes.ListHashTombstonesRequest) (*apitypes.HashTombstonesResponse, error) {
+	return do[*apitypes.HashTombstonesResponse](ctx, c, http.MethodGet, "/v1/admin/hash-tombstones", req)
+}
+
+// LockdownTenant locks a tenant down, suspending its active keys.
+func (c *Client) LockdownTenant(ctx context.Context, req *apitypes.LockdownTenantRequest) (*apitypes.TenantLockdownResultResponse, error) {
+	return do[*apitypes.TenantLockdownResultResponse](ctx, c, http.MethodPost, "/v1/admin/tenants/:tenantId/lockdown", req)
+}
+
+// GetTenantLockdown returns a tenant's lockdown status.
+func (c *Client) GetTenantLockdown(ctx context.Context, req *apitypes.GetTenantLockdownRequest) (*apitypes.TenantLockdownStatusResponse, error) {
+	return do[*apitypes.TenantLockdownStatusResponse](ctx, c, http.MethodGet, "/v1/admin/tenants/:tenantId/lockdown", req)
+}
+
+// RecoverTenant starts or resumes the staged recovery of a locked down
+// tenant.
+func (c *Client) RecoverTenant(ctx context.Context, req *apitypes.RecoverTenantRequest) (*apitypes.TenantLockdownStatusResponse, error) {
+	return do[*apitypes.TenantLockdownStatusResponse](ctx, c, http.MethodPost, "/v1/admin/tenants/:tenantId/recover", req)
+}
+
+// GetUsageRecording returns the usage recording policy.
+func (c *Client) GetUsageRecording(ctx context.Context) (*apitypes.UsageRecordingResponse, error) {
+	return do[*apitypes.UsageRecordingResponse](ctx, c, http.MethodGet, "/v1/admin/usage-recording", nil)
+}
+
+// SetUsageRecording replaces the usage recording policy.
+func (c *Client) SetUsageRecording(ctx context.Context, req *apitypes.SetUsageRecordingRequest) (*apitypes.UsageRecordingResponse, error) {
+	return do[*apitypes.UsageRecordingResponse](ctx, c, http.MethodPut, "/v1/admin/usage-recording", req)
+}
+
+// GetRuntimeConfig returns the engine's runtime configuration.
+func (c *Client) GetRuntimeConfig(ctx context.Context) (*apitypes.RuntimeConfigResponse, error) {
+	return do[*apitypes.RuntimeConfigResponse](ctx, c, http.MethodGet, "/v1/admin/config", nil)
+}
+
+// UpdateRuntimeConfig changes the runtime settings req sets.
+func (c *Client) UpdateRuntimeConfig(ctx context.Context, req *apitypes.UpdateRuntimeConfigRequest) (*apitypes.RuntimeConfigResponse, error) {
+	return do[*apitypes.RuntimeConfigResponse](ctx, c, http.MethodPatch, "/v1/admin/config", req)
+}
+
+// SetReadOnly turns read-only mode on or off.
+func (c *Client) SetReadOnly(ctx context.Context, req *apitypes.SetReadOnlyRequest) (*apitypes.ReadOnlyResponse, error) {
+	return do[*apitypes.ReadOnlyResponse](ctx, c, http.MethodPost, "/v1/admin/readonly", req)
+}
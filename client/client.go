@@ -0,0 +1,310 @@
+// Package client is a typed Go client for the keysmith REST API, for
+// services that manage or validate keys without embedding the engine.
+// Requests and responses are the [apitypes] types the api package serves,
+// so the two cannot drift apart.
+//
+//	c := client.New("https://keys.example.com/keysmith",
+//		client.WithBearerToken(token),
+//	)
+//	created, err := c.CreateKey(ctx, &apitypes.CreateKeyRequest{
+//		Name: "billing-worker", Prefix: "sk", Environment: "live",
+//	})
+//
+// Failed requests return an [*APIError] that wraps a sentinel per status,
+// such as [ErrNotFound], and the keysmith sentinel named by the server's
+// message when it names one, so errors.Is(err, keysmith.ErrKeyInactive)
+// works on either side of the wire. Idempotent requests are retried with
+// exponential backoff on transport errors and 502, 503, and 504 responses;
+// every request is retried on 429, after the server's Retry-After when it
+// sends one.
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xraph/keysmith/apitypes"
+)
+
+const (
+	// DefaultMaxRetries is how many times a failed request is retried.
+	DefaultMaxRetries = 3
+
+	// DefaultMinBackoff is the wait before the first retry; each later
+	// retry waits twice as long, up to DefaultMaxBackoff.
+	DefaultMinBackoff = 100 * time.Millisecond
+
+	// DefaultMaxBackoff caps the wait between retries, including one asked
+	// for by Retry-After. A longer Retry-After fails the request instead.
+	DefaultMaxBackoff = 30 * time.Second
+)
+
+// Client calls the keysmith REST API. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	http       *http.Client
+	header     http.Header
+	editors    []func(*http.Request) error
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client requests are sent with. Defaults to a
+// client with a 30-second timeout.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+// WithHeader adds a header to every request.
+func WithHeader(key, value string) Option {
+	return func(c *Client) { c.header.Add(key, value) }
+}
+
+// WithBearerToken sends token in the Authorization header of every request.
+func WithBearerToken(token string) Option {
+	return func(c *Client) { c.header.Set("Authorization", "Bearer "+token) }
+}
+
+// WithRequestEditor adds a function called on every request before it is
+// sent, including each retry, for credentials that change over time. An
+// error fails the request without sending it.
+func WithRequestEditor(fn func(*http.Request) error) Option {
+	return func(c *Client) { c.editors = append(c.editors, fn) }
+}
+
+// WithRetry sets how many times a failed request is retried and the
+// bounds of the backoff between attempts. Zero maxRetries turns retries
+// off; zero durations keep the defaults.
+func WithRetry(maxRetries int, minBackoff, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = max(maxRetries, 0)
+		if minBackoff > 0 {
+			c.minBackoff = minBackoff
+		}
+		if maxBackoff > 0 {
+			c.maxBackoff = maxBackoff
+		}
+	}
+}
+
+// New creates a Client for the keysmith API mounted at baseURL, e.g.
+// "https://keys.example.com/keysmith". Route paths such as /v1/keys are
+// appended to it.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		http:       &http.Client{Timeout: 30 * time.Second},
+		header:     make(http.Header),
+		maxRetries: DefaultMaxRetries,
+		minBackoff: DefaultMinBackoff,
+		maxBackoff: DefaultMaxBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// call sends req to route and decodes the response body into resp, unless
+// resp is nil.
+func (c *Client) call(ctx context.Context, method, route string, req, resp any) error {
+	_, body, err := c.send(ctx, method, route, req)
+	if err != nil || resp == nil {
+		return err
+	}
+	if err := decodeBody(body, resp); err != nil {
+		return fmt.Errorf("keysmith/client: decode %s %s: %w", method, route, err)
+	}
+	return nil
+}
+
+// send encodes req for route, sends it with retries, and returns the final
+// response, already read and closed, with its body. A response with an
+// error status is returned as an *APIError.
+func (c *Client) send(ctx context.Context, method, route string, req any) (*http.Response, []byte, error) {
+	enc, err := encodeRequest(route, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	target := c.baseURL + enc.path
+	if len(enc.query) > 0 {
+		target += "?" + enc.query.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, body, err := c.attempt(ctx, method, target, enc)
+		if err == nil {
+			return resp, body, nil
+		}
+		if ctx.Err() != nil || attempt >= c.maxRetries {
+			return resp, body, err
+		}
+		wait, ok := c.retryWait(method, resp, attempt)
+		if !ok {
+			return resp, body, err
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return resp, body, err
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt sends one request.
+func (c *Client) attempt(ctx context.Context, method, target string, enc *encodedRequest) (*http.Response, []byte, error) {
+	var reqBody io.Reader = http.NoBody
+	if enc.body != nil {
+		reqBody = bytes.NewReader(enc.body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reqBody)
+	if err != nil {
+		return nil, nil, fmt.Errorf("keysmith/client: build request: %w", err)
+	}
+	for k, v := range c.header {
+		req.Header[k] = v
+	}
+	for k, v := range enc.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", "application/json")
+	if enc.body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for _, edit := range c.editors {
+		if err := edit(req); err != nil {
+			return nil, nil, fmt.Errorf("keysmith/client: edit request: %w", err)
+		}
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("keysmith/client: %s %s: %w", method, req.URL.Path, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp, nil, fmt.Errorf("keysmith/client: read %s %s: %w", method, req.URL.Path, err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return resp, body, newAPIError(resp, body)
+	}
+	return resp, body, nil
+}
+
+// retryWait reports whether a failed attempt is retried and after how
+// long. resp is nil when the request failed in transport.
+func (c *Client) retryWait(method string, resp *http.Response, attempt int) (time.Duration, bool) {
+	backoff := min(c.minBackoff<<attempt, c.maxBackoff)
+	if resp == nil {
+		return backoff, idempotent(method)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		// The server refused the request without acting on it, so even a
+		// POST is safe to send again.
+		if after, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return after, after <= c.maxBackoff
+		}
+		return backoff, true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return backoff, idempotent(method)
+	default:
+		return 0, false
+	}
+}
+
+// idempotent reports whether sending a request with method twice has the
+// same effect as sending it once.
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryAfter parses a Retry-After value, either seconds or an HTTP date.
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// do calls route and decodes the response into a new T.
+func do[T any](ctx context.Context, c *Client, method, route string, req any) (T, error) {
+	var out T
+	err := c.call(ctx, method, route, req, &out)
+	return out, err
+}
+
+// listPage sends a GET for a list route and returns one page with the limit
+// the server served it with, read from apitypes.PageLimitHeader, or 0 when
+// the server did not say.
+func listPage[T any](ctx context.Context, c *Client, route string, req any) ([]T, int, error) {
+	resp, body, err := c.send(ctx, http.MethodGet, route, req)
+	if err != nil {
+		return nil, 0, err
+	}
+	var out []T
+	if err := decodeBody(body, &out); err != nil {
+		return nil, 0, fmt.Errorf("keysmith/client: decode GET %s: %w", route, err)
+	}
+	served, _ := strconv.Atoi(resp.Header.Get(apitypes.PageLimitHeader))
+	return out, served, nil
+}
+
+// maxPageSize is the largest limit the server accepts for a list route by
+// default.
+const maxPageSize = 1000
+
+// paginate calls list for successive pages of size limit starting at
+// offset, and fn for every item, until a page comes back short or fn
+// returns an error. A zero limit pages by the server's default of 50. list
+// returns the limit the server served the page with, or 0 when it is not
+// known; a server with a smaller max page size thereby serves short pages
+// that are not mistaken for the last one.
+func paginate[T any](limit, offset int, list func(limit, offset int) ([]T, int, error), fn func(T) error) error {
+	if limit <= 0 {
+		limit = 50
+	}
+	limit = min(limit, maxPageSize)
+	for {
+		page, served, err := list(limit, offset)
+		if err != nil {
+			return err
+		}
+		for _, item := range page {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		if served > 0 && served < limit {
+			limit = served
+		}
+		if len(page) < limit {
+			return nil
+		}
+		offset += len(page)
+	}
+}
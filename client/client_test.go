@@ -0,0 +1,271 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/apitypes"
+)
+
+func TestEncodeRequest_SplitsByTag(t *testing.T) {
+	enc, err := encodeRequest("/v1/keys/:keyId/revoke", &apitypes.RevokeKeyRequest{
+		KeyID: "akey_01", Reason: "leaked", DryRun: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "/v1/keys/akey_01/revoke", enc.path)
+	assert.Equal(t, "true", enc.query.Get("dry_run"))
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(enc.body, &body))
+	assert.Equal(t, map[string]any{"reason": "leaked"}, body, "path and query fields stay out of the body")
+}
+
+func TestEncodeRequest_EmbeddedBody(t *testing.T) {
+	enc, err := encodeRequest("/v1/policies/:policyId", &apitypes.UpdatePolicyRequest{
+		PolicyID:            "kpol_01",
+		CreatePolicyRequest: apitypes.CreatePolicyRequest{Name: "Standard", RateLimit: 10},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "/v1/policies/kpol_01", enc.path)
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(enc.body, &body))
+	assert.Equal(t, "Standard", body["name"])
+	assert.NotContains(t, body, "PolicyID")
+}
+
+func TestEncodeRequest_NoBody(t *testing.T) {
+	enc, err := encodeRequest("/v1/keys", &apitypes.ListKeysRequest{Limit: 10, State: "active"})
+	require.NoError(t, err)
+	assert.Nil(t, enc.body)
+	assert.Equal(t, "10", enc.query.Get("limit"))
+	assert.Equal(t, "active", enc.query.Get("state"))
+	assert.NotContains(t, enc.query, "offset", "zero query values are left out")
+
+	enc, err = encodeRequest("/v1/slo", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "/v1/slo", enc.path)
+	assert.Nil(t, enc.body)
+}
+
+func TestEncodeRequest_MissingPathParam(t *testing.T) {
+	_, err := encodeRequest("/v1/keys/:keyId", &apitypes.GetKeyRequest{})
+	assert.EqualError(t, err, "keysmith/client: keyId is required")
+}
+
+func TestEncodeRequest_Header(t *testing.T) {
+	enc, err := encodeRequest("/v1/keys/validate", &apitypes.ValidateKeyHeaderRequest{APIKey: "sk_test_abc"})
+	require.NoError(t, err)
+	assert.Equal(t, "sk_test_abc", enc.header.Get("X-API-Key"))
+	assert.Empty(t, enc.header.Get("Authorization"))
+	assert.Nil(t, enc.body)
+}
+
+// countingServer answers with statuses in turn, repeating the last, and
+// counts the requests it received.
+func countingServer(t *testing.T, header http.Header, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var n atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		i := int(n.Add(1)) - 1
+		status := statuses[min(i, len(statuses)-1)]
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if status >= http.StatusBadRequest {
+			_, _ = w.Write([]byte(`{"error":"try again","code":` + strconv.Itoa(status) + `}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &n
+}
+
+func fastRetry() Option { return WithRetry(3, time.Millisecond, 5*time.Millisecond) }
+
+func TestRetry_IdempotentOnUnavailable(t *testing.T) {
+	srv, n := countingServer(t, nil, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK)
+	c := New(srv.URL, fastRetry())
+
+	_, err := c.GetSLO(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), n.Load())
+}
+
+func TestRetry_NotPostOnUnavailable(t *testing.T) {
+	srv, n := countingServer(t, nil, http.StatusServiceUnavailable, http.StatusOK)
+	c := New(srv.URL, fastRetry())
+
+	_, err := c.ValidateKey(context.Background(), &apitypes.ValidateKeyRequest{RawKey: "sk_test_abc"})
+	require.ErrorIs(t, err, ErrUnavailable)
+	assert.Equal(t, int32(1), n.Load(), "a POST may have been acted on")
+}
+
+func TestRetry_PostOnTooManyRequests(t *testing.T) {
+	srv, n := countingServer(t, http.Header{"Retry-After": {"0"}}, http.StatusTooManyRequests, http.StatusOK)
+	c := New(srv.URL, fastRetry())
+
+	_, err := c.ValidateKey(context.Background(), &apitypes.ValidateKeyRequest{RawKey: "sk_test_abc"})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), n.Load())
+}
+
+func TestRetry_RetryAfterBeyondMax(t *testing.T) {
+	srv, n := countingServer(t, http.Header{"Retry-After": {"60"}}, http.StatusTooManyRequests)
+	c := New(srv.URL, fastRetry())
+
+	_, err := c.GetSLO(context.Background())
+	require.ErrorIs(t, err, ErrTooManyRequests)
+	assert.Equal(t, int32(1), n.Load())
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, time.Minute, apiErr.RetryAfter)
+}
+
+func TestRetry_Exhausted(t *testing.T) {
+	srv, n := countingServer(t, nil, http.StatusBadGateway)
+	c := New(srv.URL, fastRetry())
+
+	_, err := c.GetSLO(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, http.StatusBadGateway, StatusCode(err))
+	assert.Equal(t, int32(4), n.Load(), "the first attempt and three retries")
+}
+
+func TestRequestEditor(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	c := New(srv.URL+"/", WithBearerToken("old"), WithRequestEditor(func(r *http.Request) error {
+		r.Header.Set("Authorization", "Bearer new")
+		return nil
+	}))
+	_, err := c.GetSLO(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer new", got)
+
+	failing := New(srv.URL, WithRequestEditor(func(*http.Request) error { return errors.New("no token") }))
+	_, err = failing.GetSLO(context.Background())
+	assert.ErrorContains(t, err, "no token")
+}
+
+func errorResponse(status int, body string) (*http.Response, []byte) {
+	return &http.Response{StatusCode: status, Header: make(http.Header)}, []byte(body)
+}
+
+func TestNewAPIError_Envelopes(t *testing.T) {
+	e := newAPIError(errorResponse(http.StatusNotFound, `{"error":"keysmith: key not found","code":404}`))
+	assert.Equal(t, "404", e.Code)
+	assert.Equal(t, "keysmith: key not found", e.Message)
+	assert.ErrorIs(t, e, ErrNotFound)
+	assert.ErrorIs(t, e, keysmith.ErrKeyNotFound)
+	assert.Equal(t, "keysmith/client: 404 Not Found: keysmith: key not found", e.Error())
+
+	e = newAPIError(errorResponse(http.StatusInternalServerError, `{"code":"INTERNAL_SERVER_ERROR","message":"boom"}`))
+	assert.Equal(t, "INTERNAL_SERVER_ERROR", e.Code)
+	assert.Equal(t, "boom", e.Message)
+	assert.Empty(t, e.Unwrap())
+
+	e = newAPIError(errorResponse(http.StatusBadGateway, "<html>bad gateway</html>"))
+	assert.Equal(t, "<html>bad gateway</html>", e.Message)
+	assert.Empty(t, e.Code)
+}
+
+func TestNewAPIError_Details(t *testing.T) {
+	e := newAPIError(errorResponse(http.StatusBadRequest,
+		`{"error":"invalid policy","code":400,"details":"keysmith: invalid policy: rate_limit_window"}`))
+	assert.ErrorIs(t, e, ErrInvalidRequest)
+	assert.ErrorIs(t, e, keysmith.ErrInvalidPolicy)
+}
+
+func TestNamesSentinel(t *testing.T) {
+	s := keysmith.ErrInvalidPolicy
+	assert.True(t, namesSentinel("keysmith: invalid policy", s))
+	assert.True(t, namesSentinel("invalid request: keysmith: invalid policy: rate limit", s))
+	assert.True(t, namesSentinel("failed (keysmith: invalid policy)", s))
+	assert.False(t, namesSentinel("keysmith: invalid policy inheritance", s))
+	assert.True(t, namesSentinel("keysmith: invalid policy inheritance; keysmith: invalid policy", s),
+		"a later whole mention still counts")
+	assert.False(t, namesSentinel("", s))
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	d, ok := retryAfter("5", now)
+	assert.True(t, ok)
+	assert.Equal(t, 5*time.Second, d)
+
+	d, ok = retryAfter(now.Add(90*time.Second).Format(http.TimeFormat), now)
+	assert.True(t, ok)
+	assert.Equal(t, 90*time.Second, d)
+
+	d, ok = retryAfter(now.Add(-time.Hour).Format(http.TimeFormat), now)
+	assert.True(t, ok)
+	assert.Zero(t, d, "a date in the past means now")
+
+	for _, v := range []string{"", "-1", "soon"} {
+		_, ok = retryAfter(v, now)
+		assert.False(t, ok, v)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	items := make([]int, 7)
+	for i := range items {
+		items[i] = i
+	}
+	var calls [][2]int
+	list := func(limit, offset int) ([]int, int, error) {
+		calls = append(calls, [2]int{limit, offset})
+		return items[min(offset, len(items)):min(offset+limit, len(items))], limit, nil
+	}
+
+	var got []int
+	require.NoError(t, paginate(3, 0, list, func(i int) error { got = append(got, i); return nil }))
+	assert.Equal(t, items, got)
+	assert.Equal(t, [][2]int{{3, 0}, {3, 3}, {3, 6}}, calls)
+
+	calls = nil
+	require.NoError(t, paginate(0, 0, list, func(int) error { return nil }))
+	assert.Equal(t, [][2]int{{50, 0}}, calls, "a zero limit pages by the server default")
+
+	calls = nil
+	stop := errors.New("stop")
+	err := paginate(3, 0, list, func(i int) error {
+		if i == 4 {
+			return stop
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Len(t, calls, 2)
+
+	// A server with a max page size of 2 serves short pages.
+	calls, got = nil, nil
+	clamped := func(limit, offset int) ([]int, int, error) {
+		calls = append(calls, [2]int{limit, offset})
+		return items[min(offset, len(items)):min(offset+2, len(items))], 2, nil
+	}
+	require.NoError(t, paginate(5, 0, clamped, func(i int) error { got = append(got, i); return nil }))
+	assert.Equal(t, items, got, "a clamped page is not taken for the last")
+	assert.Equal(t, [][2]int{{5, 0}, {2, 2}, {2, 4}, {2, 6}}, calls)
+}
@@ -0,0 +1,18 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/xraph/keysmith/apitypes"
+)
+
+// SetKeyDebug turns debug capture on or off for a key.
+func (c *Client) SetKeyDebug(ctx context.Context, req *apitypes.SetKeyDebugRequest) (*apitypes.KeyResponse, error) {
+	return do[*apitypes.KeyResponse](ctx, c, http.MethodPost, "/v1/keys/:keyId/debug", req)
+}
+
+// ListCaptures returns a key's debug captures.
+func (c *Client) ListCaptures(ctx context.Context, req *apitypes.ListCapturesRequest) ([]*apitypes.CaptureResponse, error) {
+	return do[[]*apitypes.CaptureResponse](ctx, c, http.MethodGet, "/v1/keys/:keyId/captures", req)
+}
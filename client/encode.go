@@ -0,0 +1,125 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// encodedRequest is a request DTO split into the parts of an HTTP request.
+type encodedRequest struct {
+	path   string
+	query  url.Values
+	header http.Header
+	body   []byte // nil when the DTO has no body fields
+}
+
+// encodeRequest fills route's :name segments and builds the query, headers,
+// and JSON body of req from its path, query, header, and json tags, the
+// tags the server binds requests by. Zero query and header values are left
+// out. req may be nil for routes that take nothing.
+func encodeRequest(route string, req any) (*encodedRequest, error) {
+	enc := &encodedRequest{path: route, query: make(url.Values), header: make(http.Header)}
+	if req == nil {
+		return enc, nil
+	}
+	v := reflect.ValueOf(req)
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return enc, nil
+		}
+		v = v.Elem()
+	}
+
+	// Fields bound from the path, query, or headers still encode into the
+	// JSON under their Go names, which are removed again below.
+	var nonBody []string
+	hasBody := false
+	var walk func(v reflect.Value) error
+	walk = func(v reflect.Value) error {
+		t := v.Type()
+		for i := range t.NumField() {
+			f, fv := t.Field(i), v.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); f.Anonymous && name == "" && fv.Kind() == reflect.Struct {
+				if err := walk(fv); err != nil {
+					return err
+				}
+				continue
+			}
+			switch {
+			case f.Tag.Get("path") != "":
+				name := f.Tag.Get("path")
+				s := formatValue(fv)
+				if s == "" {
+					return fmt.Errorf("keysmith/client: %s is required", name)
+				}
+				enc.path = strings.Replace(enc.path, ":"+name, url.PathEscape(s), 1)
+				nonBody = append(nonBody, f.Name)
+			case f.Tag.Get("query") != "":
+				if !fv.IsZero() {
+					enc.query.Set(f.Tag.Get("query"), formatValue(fv))
+				}
+				nonBody = append(nonBody, f.Name)
+			case f.Tag.Get("header") != "":
+				if !fv.IsZero() {
+					enc.header.Set(f.Tag.Get("header"), formatValue(fv))
+				}
+				nonBody = append(nonBody, f.Name)
+			case f.Tag.Get("json") != "-":
+				hasBody = true
+			}
+		}
+		return nil
+	}
+	if err := walk(v); err != nil {
+		return nil, err
+	}
+	if !hasBody {
+		return enc, nil
+	}
+
+	raw, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/client: encode request: %w", err)
+	}
+	if len(nonBody) > 0 {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return nil, fmt.Errorf("keysmith/client: encode request: %w", err)
+		}
+		for _, name := range nonBody {
+			delete(fields, name)
+		}
+		if raw, err = json.Marshal(fields); err != nil {
+			return nil, fmt.Errorf("keysmith/client: encode request: %w", err)
+		}
+	}
+	enc.body = raw
+	return enc, nil
+}
+
+// formatValue renders a path, query, or header value.
+func formatValue(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// decodeBody decodes the first JSON value of body into v.
+func decodeBody(body []byte, v any) error {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	return json.NewDecoder(bytes.NewReader(body)).Decode(v)
+}
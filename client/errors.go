@@ -0,0 +1,259 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/usage"
+)
+
+// Sentinels wrapped by an *APIError according to its status code.
+var (
+	// ErrInvalidRequest is wrapped for 400 Bad Request and 422
+	// Unprocessable Entity.
+	ErrInvalidRequest = errors.New("keysmith/client: invalid request")
+
+	// ErrUnauthorized is wrapped for 401 Unauthorized.
+	ErrUnauthorized = errors.New("keysmith/client: unauthorized")
+
+	// ErrForbidden is wrapped for 403 Forbidden.
+	ErrForbidden = errors.New("keysmith/client: forbidden")
+
+	// ErrNotFound is wrapped for 404 Not Found.
+	ErrNotFound = errors.New("keysmith/client: not found")
+
+	// ErrConflict is wrapped for 409 Conflict.
+	ErrConflict = errors.New("keysmith/client: conflict")
+
+	// ErrTooManyRequests is wrapped for 429 Too Many Requests.
+	ErrTooManyRequests = errors.New("keysmith/client: too many requests")
+
+	// ErrUnavailable is wrapped for 503 Service Unavailable.
+	ErrUnavailable = errors.New("keysmith/client: service unavailable")
+)
+
+// APIError is a response with an error status. It unwraps to the status
+// sentinel and to every keysmith sentinel its message names.
+type APIError struct {
+	// StatusCode is the HTTP status of the response.
+	StatusCode int
+
+	// Code is the envelope's code: the status code for errors the api
+	// package maps, or a name such as INTERNAL_SERVER_ERROR.
+	Code string
+
+	// Message is the envelope's error message, or the start of the body
+	// when it is not an error envelope.
+	Message string
+
+	// Details is the envelope's details, when set.
+	Details string
+
+	// RetryAfter is the wait the server asked for, when it sent
+	// Retry-After.
+	RetryAfter time.Duration
+
+	wrapped []error
+}
+
+// Error returns the status and message.
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("keysmith/client: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// Unwrap returns the status sentinel and the keysmith sentinels the
+// message names.
+func (e *APIError) Unwrap() []error {
+	return e.wrapped
+}
+
+// maxErrorMessage bounds how much of a body that is not an error envelope
+// becomes the message.
+const maxErrorMessage = 512
+
+// envelope is the wire form of an error response.
+type envelope struct {
+	Code    json.RawMessage `json:"code"`
+	Error   string          `json:"error"`
+	Message string          `json:"message"`
+	Details string          `json:"details"`
+}
+
+// newAPIError builds the error for a response with an error status.
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	e := &APIError{StatusCode: resp.StatusCode}
+	var env envelope
+	if err := decodeBody(body, &env); err == nil && (env.Error != "" || env.Message != "") {
+		e.Code = strings.Trim(string(env.Code), `"`)
+		e.Message = env.Error
+		if e.Message == "" {
+			e.Message = env.Message
+		}
+		e.Details = env.Details
+	} else {
+		msg := strings.TrimSpace(string(body))
+		if len(msg) > maxErrorMessage {
+			msg = msg[:maxErrorMessage]
+		}
+		e.Message = msg
+	}
+	if after, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+		e.RetryAfter = after
+	}
+
+	if s := statusSentinel(resp.StatusCode); s != nil {
+		e.wrapped = append(e.wrapped, s)
+	}
+	for _, s := range serverSentinels {
+		if namesSentinel(e.Message, s) || namesSentinel(e.Details, s) {
+			e.wrapped = append(e.wrapped, s)
+		}
+	}
+	return e
+}
+
+func statusSentinel(status int) error {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return ErrInvalidRequest
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusConflict:
+		return ErrConflict
+	case http.StatusTooManyRequests:
+		return ErrTooManyRequests
+	case http.StatusServiceUnavailable:
+		return ErrUnavailable
+	default:
+		return nil
+	}
+}
+
+// namesSentinel reports whether msg contains the text of sentinel as a
+// whole error, not as the start of a longer one: "keysmith: invalid
+// policy" is not named by "keysmith: invalid policy inheritance".
+func namesSentinel(msg string, sentinel error) bool {
+	text := sentinel.Error()
+	for rest := msg; ; {
+		i := strings.Index(rest, text)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(text):]
+		if rest == "" || strings.ContainsAny(rest[:1], ":;,)\n") {
+			return true
+		}
+	}
+}
+
+// serverSentinels are the keysmith errors the api package maps to a
+// status, and so the ones a message can name.
+var serverSentinels = []error{
+	keysmith.ErrInvalidKeyInput,
+	keysmith.ErrKeyNotFound,
+	keysmith.ErrPolicyNotFound,
+	keysmith.ErrScopeNotFound,
+	keysmith.ErrGroupNotFound,
+	keysmith.ErrQuotaPoolNotFound,
+	keysmith.ErrRotationNotFound,
+	keysmith.ErrNoHashAt,
+	keysmith.ErrNoMonthlyQuota,
+	keysmith.ErrKeyTransferNotFound,
+	keysmith.ErrInvalidKey,
+	keysmith.ErrKeyExpired,
+	keysmith.ErrKeyRevoked,
+	keysmith.ErrKeySuspended,
+	keysmith.ErrKeyInactive,
+	keysmith.ErrRateLimited,
+	keysmith.ErrTenantRateLimited,
+	keysmith.ErrQuotaExceeded,
+	keysmith.ErrPolicyInUse,
+	keysmith.ErrExternalRefInUse,
+	keysmith.ErrDuplicateKeyName,
+	keysmith.ErrRequestReplayed,
+	keysmith.ErrInvalidStateTransition,
+	keysmith.ErrKeyTransferPending,
+	keysmith.ErrKeyTransferExpired,
+	keysmith.ErrHashTombstoned,
+	keysmith.ErrTenantLocked,
+	keysmith.ErrTenantNotLocked,
+	keysmith.ErrInvalidCompromiseAction,
+	keysmith.ErrInvalidPolicy,
+	keysmith.ErrPolicyInheritance,
+	keysmith.ErrPolicyTemplateNotFound,
+	keysmith.ErrRevocationRangeTooLarge,
+	keysmith.ErrKeyEventRangeTooLarge,
+	keysmith.ErrInvalidDebugCapture,
+	keysmith.ErrInvalidMetadataSchema,
+	keysmith.ErrInvalidTenantSettings,
+	keysmith.ErrInvalidScope,
+	keysmith.ErrInvalidGroup,
+	keysmith.ErrInvalidQuotaPool,
+	keysmith.ErrInvalidOrigin,
+	keysmith.ErrInvalidCertFingerprint,
+	keysmith.ErrInvalidKeyFlag,
+	keysmith.ErrInvalidContact,
+	keysmith.ErrInvalidLabels,
+	keysmith.ErrInvalidLabelSelector,
+	keysmith.ErrInvalidErasure,
+	keysmith.ErrInvalidRuntimeConfig,
+	keysmith.ErrInvalidRotationReason,
+	keysmith.ErrHashBatchTooLarge,
+	keysmith.ErrInvalidOverviewQuery,
+	keysmith.ErrInvalidSearchQuery,
+	keysmith.ErrCreatorRequired,
+	keysmith.ErrTermsVersionRequired,
+	keysmith.ErrNonceRequired,
+	keysmith.ErrUnknownPrefix,
+	keysmith.ErrPrefixRuleViolation,
+	keysmith.ErrRequestStale,
+	keysmith.ErrInvalidKeyTransfer,
+	keysmith.ErrInvalidLockdown,
+	usage.ErrInvalidRecordingPolicy,
+	keysmith.ErrInvalidMetadata,
+	keysmith.ErrTermsNotAccepted,
+	keysmith.ErrEngineStopping,
+	keysmith.ErrReadOnlyMode,
+	keysmith.ErrAuthorizerUnavailable,
+	keysmith.ErrMaintenanceUnsupported,
+	keysmith.ErrStorageReportUnsupported,
+	keysmith.ErrLiveStatsDisabled,
+	keysmith.ErrIPNotAllowed,
+	keysmith.ErrOriginNotAllowed,
+	keysmith.ErrMethodNotAllowed,
+	keysmith.ErrPathNotAllowed,
+	keysmith.ErrConsumerNotAllowed,
+	keysmith.ErrTransportNotAllowed,
+	keysmith.ErrClientCertMismatch,
+	keysmith.ErrAuthzDenied,
+	keysmith.ErrPrefixNotAccepted,
+	keysmith.ErrTermsOutdated,
+	keysmith.ErrKeyFlagNotAllowed,
+	keysmith.ErrScopeNotAllowed,
+	keysmith.ErrDebugCaptureNotAllowed,
+	keysmith.ErrErasureNotAllowed,
+	keysmith.ErrKeyTransferNotTarget,
+}
+
+// StatusCode returns the HTTP status of err when it is an *APIError, and 0
+// otherwise.
+func StatusCode(err error) int {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
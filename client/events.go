@@ -0,0 +1,108 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/xraph/keysmith/apitypes"
+)
+
+// maxEventLine bounds one line of the event stream.
+const maxEventLine = 1 << 20
+
+// StreamKeyEvents streams key events to fn until ctx ends, fn returns an
+// error, or the server ends the stream, which it does when the stream falls
+// behind or the server stops; it then returns that error, ctx's error, or
+// nil. To resume without missing or repeating an event, call it again with
+// req.LastEventID set to the EventID of the last event fn handled.
+//
+// The stream is not retried, and the HTTP client's timeout does not apply
+// to it.
+func (c *Client) StreamKeyEvents(ctx context.Context, req *apitypes.StreamKeyEventsRequest, fn func(*apitypes.KeyEventResponse) error) error {
+	const route = "/v1/events/stream"
+	enc, err := encodeRequest(route, req)
+	if err != nil {
+		return err
+	}
+	target := c.baseURL + enc.path
+	if len(enc.query) > 0 {
+		target += "?" + enc.query.Encode()
+	}
+
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodGet, target, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("keysmith/client: build request: %w", err)
+	}
+	for k, v := range c.header {
+		hreq.Header[k] = v
+	}
+	for k, v := range enc.header {
+		hreq.Header[k] = v
+	}
+	hreq.Header.Set("Accept", "text/event-stream")
+	for _, edit := range c.editors {
+		if err := edit(hreq); err != nil {
+			return fmt.Errorf("keysmith/client: edit request: %w", err)
+		}
+	}
+
+	hc := *c.http
+	hc.Timeout = 0
+	resp, err := hc.Do(hreq)
+	if err != nil {
+		return fmt.Errorf("keysmith/client: GET %s: %w", route, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorMessage))
+		return newAPIError(resp, body)
+	}
+
+	err = readEvents(resp.Body, func(data string) error {
+		var e apitypes.KeyEventResponse
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			return fmt.Errorf("keysmith/client: decode key event: %w", err)
+		}
+		return fn(&e)
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// readEvents reads Server-Sent Events from r and calls fn with the data of
+// each, skipping comments, until r ends.
+func readEvents(r io.Reader, fn func(data string) error) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 4096), maxEventLine)
+	var data []string
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				if err := fn(strings.Join(data, "\n")); err != nil {
+					return err
+				}
+				data = data[:0]
+			}
+		case strings.HasPrefix(line, ":"):
+			// A comment, such as a heartbeat.
+		default:
+			field, value, _ := strings.Cut(line, ":")
+			if field == "data" {
+				data = append(data, strings.TrimPrefix(value, " "))
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("keysmith/client: read key events: %w", err)
+	}
+	return nil
+}
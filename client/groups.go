@@ -0,0 +1,53 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/xraph/keysmith/apitypes"
+)
+
+// CreateGroup creates a key group.
+func (c *Client) CreateGroup(ctx context.Context, req *apitypes.CreateGroupRequest) (*apitypes.GroupResponse, error) {
+	return do[*apitypes.GroupResponse](ctx, c, http.MethodPost, "/v1/groups", req)
+}
+
+// ListGroups returns one page of key groups.
+func (c *Client) ListGroups(ctx context.Context, req *apitypes.ListGroupsRequest) ([]*apitypes.GroupResponse, error) {
+	return do[[]*apitypes.GroupResponse](ctx, c, http.MethodGet, "/v1/groups", req)
+}
+
+// GetGroup fetches a key group.
+func (c *Client) GetGroup(ctx context.Context, req *apitypes.GetGroupRequest) (*apitypes.GroupResponse, error) {
+	return do[*apitypes.GroupResponse](ctx, c, http.MethodGet, "/v1/groups/:groupId", req)
+}
+
+// UpdateGroup changes a key group's name or description.
+func (c *Client) UpdateGroup(ctx context.Context, req *apitypes.UpdateGroupRequest) (*apitypes.GroupResponse, error) {
+	return do[*apitypes.GroupResponse](ctx, c, http.MethodPatch, "/v1/groups/:groupId", req)
+}
+
+// DeleteGroup deletes a key group, leaving its keys intact.
+func (c *Client) DeleteGroup(ctx context.Context, req *apitypes.DeleteGroupRequest) error {
+	return c.call(ctx, http.MethodDelete, "/v1/groups/:groupId", req, nil)
+}
+
+// AddGroupKeys adds keys to a group.
+func (c *Client) AddGroupKeys(ctx context.Context, req *apitypes.AddGroupKeysRequest) error {
+	return c.call(ctx, http.MethodPost, "/v1/groups/:groupId/keys", req, nil)
+}
+
+// RemoveGroupKeys removes keys from a group.
+func (c *Client) RemoveGroupKeys(ctx context.Context, req *apitypes.RemoveGroupKeysRequest) error {
+	return c.call(ctx, http.MethodDelete, "/v1/groups/:groupId/keys", req, nil)
+}
+
+// RotateGroup rotates every key in a group that is not revoked.
+func (c *Client) RotateGroup(ctx context.Context, req *apitypes.RotateGroupRequest) (*apitypes.GroupOperationResponse, error) {
+	return do[*apitypes.GroupOperationResponse](ctx, c, http.MethodPost, "/v1/groups/:groupId/rotate", req)
+}
+
+// RevokeGroup revokes every key in a group.
+func (c *Client) RevokeGroup(ctx context.Context, req *apitypes.RevokeGroupRequest) (*apitypes.GroupOperationResponse, error) {
+	return do[*apitypes.GroupOperationResponse](ctx, c, http.MethodPost, "/v1/groups/:groupId/revoke", req)
+}
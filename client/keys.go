@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/xraph/keysmith/apitypes"
+)
+
+// CreateKey creates an API key. The raw key is in the response only.
+func (c *Client) CreateKey(ctx context.Context, req *apitypes.CreateKeyRequest) (*apitypes.KeyCreateResponse, error) {
+	return do[*apitypes.KeyCreateResponse](ctx, c, http.MethodPost, "/v1/keys", req)
+}
+
+// ListKeys returns one page of keys.
+func (c *Client) ListKeys(ctx context.Context, req *apitypes.ListKeysRequest) ([]*apitypes.KeyResponse, error) {
+	return do[[]*apitypes.KeyResponse](ctx, c, http.MethodGet, "/v1/keys", req)
+}
+
+// KeyOverview counts the keys the caller may see by state, environment,
+// and lifecycle window.
+func (c *Client) KeyOverview(ctx context.Context, req *apitypes.KeyOverviewRequest) (*apitypes.KeyOverviewResponse, error) {
+	return do[*apitypes.KeyOverviewResponse](ctx, c, http.MethodGet, "/v1/keys/overview", req)
+}
+
+// IterateKeys calls fn for every key matching req, fetching page after page
+// from req.Offset in pages of req.Limit, until the keys run out or fn
+// returns an error.
+func (c *Client) IterateKeys(ctx context.Context, req *apitypes.ListKeysRequest, fn func(*apitypes.KeyResponse) error) error {
+	page := apitypes.ListKeysRequest{}
+	if req != nil {
+		page = *req
+	}
+	return paginate(page.Limit, page.Offset, func(limit, offset int) ([]*apitypes.KeyResponse, int, error) {
+		page.Limit, page.Offset = limit, offset
+		return listPage[*apitypes.KeyResponse](ctx, c, "/v1/keys", &page)
+	}, fn)
+}
+
+// RevokeByLabels revokes every key whose labels match req.LabelSelector.
+func (c *Client) RevokeByLabels(ctx context.Context, req *apitypes.RevokeByLabelsRequest) (*apitypes.LabelRevokeResponse, error) {
+	return do[*apitypes.LabelRevokeResponse](ctx, c, http.MethodPost, "/v1/keys/revoke-by-labels", req)
+}
+
+// GetKey returns a key.
+func (c *Client) GetKey(ctx context.Context, req *apitypes.GetKeyRequest) (*apitypes.KeyResponse, error) {
+	return do[*apitypes.KeyResponse](ctx, c, http.MethodGet, "/v1/keys/:keyId", req)
+}
+
+// UpdateKey changes the fields of a key that req sets.
+func (c *Client) UpdateKey(ctx context.Context, req *apitypes.UpdateKeyRequest) (*apitypes.KeyResponse, error) {
+	return do[*apitypes.KeyResponse](ctx, c, http.MethodPatch, "/v1/keys/:keyId", req)
+}
+
+// DeleteKey deletes a key.
+func (c *Client) DeleteKey(ctx context.Context, req *apitypes.DeleteKeyRequest) error {
+	return c.call(ctx, http.MethodDelete, "/v1/keys/:keyId", req, nil)
+}
+
+// RotateKey replaces a key's secret. The new raw key is in the response
+// only.
+func (c *Client) RotateKey(ctx context.Context, req *apitypes.RotateKeyRequest) (*apitypes.KeyCreateResponse, error) {
+	return do[*apitypes.KeyCreateResponse](ctx, c, http.MethodPost, "/v1/keys/:keyId/rotate", req)
+}
+
+// RevokeKey permanently revokes a key.
+func (c *Client) RevokeKey(ctx context.Context, req *apitypes.RevokeKeyRequest) error {
+	return c.call(ctx, http.MethodPost, "/v1/keys/:keyId/revoke", req, nil)
+}
+
+// ReportCompromise reports a leaked key and revokes or rotates it.
+func (c *Client) ReportCompromise(ctx context.Context, req *apitypes.ReportCompromiseRequest) (*apitypes.CompromiseResponse, error) {
+	return do[*apitypes.CompromiseResponse](ctx, c, http.MethodPost, "/v1/keys/:keyId/compromise", req)
+}
+
+// SuspendKey suspends a key until it is reactivated.
+func (c *Client) SuspendKey(ctx context.Context, req *apitypes.SuspendKeyRequest) error {
+	return c.call(ctx, http.MethodPost, "/v1/keys/:keyId/suspend", req, nil)
+}
+
+// ReactivateKey reactivates a suspended key.
+func (c *Client) ReactivateKey(ctx context.Context, req *apitypes.ReactivateKeyRequest) error {
+	return c.call(ctx, http.MethodPost, "/v1/keys/:keyId/reactivate", req, nil)
+}
+
+// GetKeyContacts returns where a key's lifecycle notifications go.
+func (c *Client) GetKeyContacts(ctx context.Context, req *apitypes.GetKeyContactsRequest) (*apitypes.KeyContactsResponse, error) {
+	return do[*apitypes.KeyContactsResponse](ctx, c, http.MethodGet, "/v1/keys/:keyId/contacts", req)
+}
+
+// Search finds the caller's keys mentioning every term of req.Query.
+func (c *Client) Search(ctx context.Context, req *apitypes.SearchRequest) (*apitypes.SearchResponse, error) {
+	return do[*apitypes.SearchResponse](ctx, c, http.MethodGet, "/v1/search", req)
+}
+
+// ListDuplicateKeyNames returns the names shared by more than one key.
+func (c *Client) ListDuplicateKeyNames(ctx context.Context, req *apitypes.ListDuplicateKeyNamesRequest) ([]*apitypes.KeyNameDuplicateResponse, error) {
+	return do[[]*apitypes.KeyNameDuplicateResponse](ctx, c, http.MethodGet, "/v1/keys/duplicate-names", req)
+}
+
+// GetLiveKeyStats returns a key's live validation rates.
+func (c *Client) GetLiveKeyStats(ctx context.Context, req *apitypes.GetLiveKeyStatsRequest) (*apitypes.LiveKeyStatsResponse, error) {
+	return do[*apitypes.LiveKeyStatsResponse](ctx, c, http.MethodGet, "/v1/keys/:keyId/live", req)
+}
+
+// ListLiveTopKeys returns the busiest keys by live validation rate.
+func (c *Client) ListLiveTopKeys(ctx context.Context, req *apitypes.ListLiveTopKeysRequest) ([]*apitypes.LiveKeyStatsResponse, error) {
+	return do[[]*apitypes.LiveKeyStatsResponse](ctx, c, http.MethodGet, "/v1/keys/live/top", req)
+}
+
+// PutKeyContacts replaces a key's contacts.
+func (c *Client) PutKeyContacts(ctx context.Context, req *apitypes.PutKeyContactsRequest) (*apitypes.KeyContactsResponse, error) {
+	return do[*apitypes.KeyContactsResponse](ctx, c, http.MethodPut, "/v1/keys/:keyId/contacts", req)
+}
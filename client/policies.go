@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/xraph/keysmith/apitypes"
+)
+
+// CreatePolicy creates a policy.
+func (c *Client) CreatePolicy(ctx context.Context, req *apitypes.CreatePolicyRequest) (*apitypes.PolicyResponse, error) {
+	return do[*apitypes.PolicyResponse](ctx, c, http.MethodPost, "/v1/policies", req)
+}
+
+// CreatePolicyFromTemplate creates a policy from a named template.
+func (c *Client) CreatePolicyFromTemplate(ctx context.Context, req *apitypes.CreatePolicyFromTemplateRequest) (*apitypes.PolicyResponse, error) {
+	return do[*apitypes.PolicyResponse](ctx, c, http.MethodPost, "/v1/policies/from-template", req)
+}
+
+// ListPolicies returns one page of policies.
+func (c *Client) ListPolicies(ctx context.Context, req *apitypes.ListPoliciesRequest) ([]*apitypes.PolicyResponse, error) {
+	return do[[]*apitypes.PolicyResponse](ctx, c, http.MethodGet, "/v1/policies", req)
+}
+
+// IteratePolicies calls fn for every policy matching req, page after page,
+// until the policies run out or fn returns an error.
+func (c *Client) IteratePolicies(ctx context.Context, req *apitypes.ListPoliciesRequest, fn func(*apitypes.PolicyResponse) error) error {
+	page := apitypes.ListPoliciesRequest{}
+	if req != nil {
+		page = *req
+	}
+	return paginate(page.Limit, page.Offset, func(limit, offset int) ([]*apitypes.PolicyResponse, int, error) {
+		page.Limit, page.Offset = limit, offset
+		return listPage[*apitypes.PolicyResponse](ctx, c, "/v1/policies", &page)
+	}, fn)
+}
+
+// GetPolicy returns a policy.
+func (c *Client) GetPolicy(ctx context.Context, req *apitypes.GetPolicyRequest) (*apitypes.PolicyResponse, error) {
+	return do[*apitypes.PolicyResponse](ctx, c, http.MethodGet, "/v1/policies/:policyId", req)
+}
+
+// GetEffectivePolicy returns a policy merged with the policies it inherits
+// from.
+func (c *Client) GetEffectivePolicy(ctx context.Context, req *apitypes.GetEffectivePolicyRequest) (*apitypes.EffectivePolicyResponse, error) {
+	return do[*apitypes.EffectivePolicyResponse](ctx, c, http.MethodGet, "/v1/policies/:policyId/effective", req)
+}
+
+// UpdatePolicy replaces a policy.
+func (c *Client) UpdatePolicy(ctx context.Context, req *apitypes.UpdatePolicyRequest) (*apitypes.PolicyResponse, error) {
+	return do[*apitypes.PolicyResponse](ctx, c, http.MethodPut, "/v1/policies/:policyId", req)
+}
+
+// DeletePolicy deletes a policy no active key is assigned.
+func (c *Client) DeletePolicy(ctx context.Context, req *apitypes.DeletePolicyRequest) error {
+	return c.call(ctx, http.MethodDelete, "/v1/policies/:policyId", req, nil)
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/xraph/keysmith/apitypes"
+)
+
+// CreateQuotaPool creates a quota pool shared by its member keys.
+func (c *Client) CreateQuotaPool(ctx context.Context, req *apitypes.CreateQuotaPoolRequest) (*apitypes.QuotaPoolResponse, error) {
+	return do[*apitypes.QuotaPoolResponse](ctx, c, http.MethodPost, "/v1/quota-pools", req)
+}
+
+// ListQuotaPools returns one page of quota pools.
+func (c *Client) ListQuotaPools(ctx context.Context, req *apitypes.ListQuotaPoolsRequest) ([]*apitypes.QuotaPoolResponse, error) {
+	return do[[]*apitypes.QuotaPoolResponse](ctx, c, http.MethodGet, "/v1/quota-pools", req)
+}
+
+// GetQuotaPool fetches a quota pool.
+func (c *Client) GetQuotaPool(ctx context.Context, req *apitypes.GetQuotaPoolRequest) (*apitypes.QuotaPoolResponse, error) {
+	return do[*apitypes.QuotaPoolResponse](ctx, c, http.MethodGet, "/v1/quota-pools/:poolId", req)
+}
+
+// UpdateQuotaPool changes a quota pool's name, description, limits, or time
+// zone.
+func (c *Client) UpdateQuotaPool(ctx context.Context, req *apitypes.UpdateQuotaPoolRequest) (*apitypes.QuotaPoolResponse, error) {
+	return do[*apitypes.QuotaPoolResponse](ctx, c, http.MethodPatch, "/v1/quota-pools/:poolId", req)
+}
+
+// DeleteQuotaPool deletes a quota pool; its keys fall back to their
+// policies' quotas.
+func (c *Client) DeleteQuotaPool(ctx context.Context, req *apitypes.DeleteQuotaPoolRequest) error {
+	return c.call(ctx, http.MethodDelete, "/v1/quota-pools/:poolId", req, nil)
+}
+
+// AddQuotaPoolKeys adds keys to a quota pool.
+func (c *Client) AddQuotaPoolKeys(ctx context.Context, req *apitypes.AddQuotaPoolKeysRequest) error {
+	return c.call(ctx, http.MethodPost, "/v1/quota-pools/:poolId/keys", req, nil)
+}
+
+// RemoveQuotaPoolKeys removes keys from a quota pool.
+func (c *Client) RemoveQuotaPoolKeys(ctx context.Context, req *apitypes.RemoveQuotaPoolKeysRequest) error {
+	return c.call(ctx, http.MethodDelete, "/v1/quota-pools/:poolId/keys", req, nil)
+}
+
+// GetQuotaPoolUsage reports a quota pool's use of its current day and
+// month, with each key's share.
+func (c *Client) GetQuotaPoolUsage(ctx context.Context, req *apitypes.GetQuotaPoolUsageRequest) (*apitypes.QuotaPoolUsageResponse, error) {
+	return do[*apitypes.QuotaPoolUsageResponse](ctx, c, http.MethodGet, "/v1/quota-pools/:poolId/usage", req)
+}
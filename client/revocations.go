@@ -0,0 +1,14 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/xraph/keysmith/apitypes"
+)
+
+// ListRevocations returns one page of the revocation feed. The
+// revocationfeed package polls the feed and keeps the revoked set.
+func (c *Client) ListRevocations(ctx context.Context, req *apitypes.ListRevocationsRequest) (*apitypes.RevocationFeedResponse, error) {
+	return do[*apitypes.RevocationFeedResponse](ctx, c, http.MethodGet, "/v1/revocations", req)
+}
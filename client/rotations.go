@@ -0,0 +1,28 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/xraph/keysmith/apitypes"
+)
+
+// ListRotations returns a key's rotation history.
+func (c *Client) ListRotations(ctx context.Context, req *apitypes.ListRotationsRequest) ([]*apitypes.RotationResponse, error) {
+	return do[[]*apitypes.RotationResponse](ctx, c, http.MethodGet, "/v1/keys/:keyId/rotations", req)
+}
+
+// ListTenantRotations returns rotations across the keys the caller may see.
+func (c *Client) ListTenantRotations(ctx context.Context, req *apitypes.ListTenantRotationsRequest) (*apitypes.RotationListResponse, error) {
+	return do[*apitypes.RotationListResponse](ctx, c, http.MethodGet, "/v1/rotations", req)
+}
+
+// RotationSummary counts rotations per reason.
+func (c *Client) RotationSummary(ctx context.Context, req *apitypes.RotationSummaryRequest) (*apitypes.RotationSummaryResponse, error) {
+	return do[*apitypes.RotationSummaryResponse](ctx, c, http.MethodGet, "/v1/rotations/summary", req)
+}
+
+// GetLineage returns the hashes a key has had across its rotations.
+func (c *Client) GetLineage(ctx context.Context, req *apitypes.GetLineageRequest) (*apitypes.LineageResponse, error) {
+	return do[*apitypes.LineageResponse](ctx, c, http.MethodGet, "/v1/keys/:keyId/lineage", req)
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/xraph/keysmith/apitypes"
+)
+
+// CreateScope creates a scope.
+func (c *Client) CreateScope(ctx context.Context, req *apitypes.CreateScopeRequest) (*apitypes.ScopeResponse, error) {
+	return do[*apitypes.ScopeResponse](ctx, c, http.MethodPost, "/v1/scopes", req)
+}
+
+// ListScopes returns one page of scopes.
+func (c *Client) ListScopes(ctx context.Context, req *apitypes.ListScopesRequest) ([]*apitypes.ScopeResponse, error) {
+	return do[[]*apitypes.ScopeResponse](ctx, c, http.MethodGet, "/v1/scopes", req)
+}
+
+// IterateScopes calls fn for every scope matching req, page after page,
+// until the scopes run out or fn returns an error.
+func (c *Client) IterateScopes(ctx context.Context, req *apitypes.ListScopesRequest, fn func(*apitypes.ScopeResponse) error) error {
+	page := apitypes.ListScopesRequest{}
+	if req != nil {
+		page = *req
+	}
+	return paginate(page.Limit, page.Offset, func(limit, offset int) ([]*apitypes.ScopeResponse, int, error) {
+		page.Limit, page.Offset = limit, offset
+		return listPage[*apitypes.ScopeResponse](ctx, c, "/v1/scopes", &page)
+	}, fn)
+}
+
+// DeleteScope deletes a scope.
+func (c *Client) DeleteScope(ctx context.Context, req *apitypes.DeleteScopeRequest) error {
+	return c.call(ctx, http.MethodDelete, "/v1/scopes/:scopeId", req, nil)
+}
+
+// AssignScopes grants scopes to a key.
+func (c *Client) AssignScopes(ctx context.Context, req *apitypes.AssignScopesRequest) error {
+	return c.call(ctx, http.MethodPost, "/v1/keys/:keyId/scopes", req, nil)
+}
+
+// RemoveScopes takes scopes away from a key.
+func (c *Client) RemoveScopes(ctx context.Context, req *apitypes.RemoveScopesRequest) error {
+	return c.call(ctx, http.MethodDelete, "/v1/keys/:keyId/scopes", req, nil)
+}
@@ -0,0 +1,23 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/xraph/keysmith/apitypes"
+)
+
+// GetTenantSettings returns a tenant's settings.
+func (c *Client) GetTenantSettings(ctx context.Context, req *apitypes.GetTenantSettingsRequest) (*apitypes.TenantSettingsResponse, error) {
+	return do[*apitypes.TenantSettingsResponse](ctx, c, http.MethodGet, "/v1/tenants/:tenantId/settings", req)
+}
+
+// UpdateTenantSettings replaces a tenant's settings.
+func (c *Client) UpdateTenantSettings(ctx context.Context, req *apitypes.UpdateTenantSettingsRequest) (*apitypes.TenantSettingsResponse, error) {
+	return do[*apitypes.TenantSettingsResponse](ctx, c, http.MethodPut, "/v1/tenants/:tenantId/settings", req)
+}
+
+// PutMetadataSchema replaces a tenant's metadata schema.
+func (c *Client) PutMetadataSchema(ctx context.Context, req *apitypes.PutMetadataSchemaRequest) (*apitypes.TenantSettingsResponse, error) {
+	return do[*apitypes.TenantSettingsResponse](ctx, c, http.MethodPut, "/v1/tenants/:tenantId/metadata-schema", req)
+}
@@ -0,0 +1,34 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/xraph/keysmith/apitypes"
+)
+
+// InitiateKeyTransfer starts the transfer of a key to another tenant.
+func (c *Client) InitiateKeyTransfer(ctx context.Context, req *apitypes.InitiateKeyTransferRequest) (*apitypes.KeyTransferResponse, error) {
+	return do[*apitypes.KeyTransferResponse](ctx, c, http.MethodPost, "/v1/keys/:keyId/transfer", req)
+}
+
+// GetKeyTransfer fetches a key's pending transfer.
+func (c *Client) GetKeyTransfer(ctx context.Context, req *apitypes.GetKeyTransferRequest) (*apitypes.KeyTransferResponse, error) {
+	return do[*apitypes.KeyTransferResponse](ctx, c, http.MethodGet, "/v1/keys/:keyId/transfer", req)
+}
+
+// AcceptKeyTransfer accepts a key's pending transfer, as the target tenant.
+func (c *Client) AcceptKeyTransfer(ctx context.Context, req *apitypes.AcceptKeyTransferRequest) (*apitypes.KeyTransferResponse, error) {
+	return do[*apitypes.KeyTransferResponse](ctx, c, http.MethodPost, "/v1/keys/:keyId/transfer/accept", req)
+}
+
+// CancelKeyTransfer cancels a key's pending transfer.
+func (c *Client) CancelKeyTransfer(ctx context.Context, req *apitypes.CancelKeyTransferRequest) (*apitypes.KeyTransferResponse, error) {
+	return do[*apitypes.KeyTransferResponse](ctx, c, http.MethodDelete, "/v1/keys/:keyId/transfer", req)
+}
+
+// ListKeyTransfers returns one page of the transfers from or to the
+// caller's tenant.
+func (c *Client) ListKeyTransfers(ctx context.Context, req *apitypes.ListKeyTransfersRequest) ([]*apitypes.KeyTransferResponse, error) {
+	return do[[]*apitypes.KeyTransferResponse](ctx, c, http.MethodGet, "/v1/transfers", req)
+}
@@ -0,0 +1,34 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/xraph/keysmith/apitypes"
+)
+
+// GetKeyUsage returns a key's usage records.
+func (c *Client) GetKeyUsage(ctx context.Context, req *apitypes.GetKeyUsageRequest) ([]*apitypes.UsageResponse, error) {
+	return do[[]*apitypes.UsageResponse](ctx, c, http.MethodGet, "/v1/keys/:keyId/usage", req)
+}
+
+// GetKeyUsageAggregate returns a key's usage counted per period.
+func (c *Client) GetKeyUsageAggregate(ctx context.Context, req *apitypes.GetKeyUsageAggregateRequest) ([]*apitypes.AggregationResponse, error) {
+	return do[[]*apitypes.AggregationResponse](ctx, c, http.MethodGet, "/v1/keys/:keyId/usage/aggregate", req)
+}
+
+// GetQuotaForecast returns when a key is projected to reach its monthly
+// quota.
+func (c *Client) GetQuotaForecast(ctx context.Context, req *apitypes.GetQuotaForecastRequest) (*apitypes.QuotaForecastResponse, error) {
+	return do[*apitypes.QuotaForecastResponse](ctx, c, http.MethodGet, "/v1/keys/:keyId/quota-forecast", req)
+}
+
+// ListEndpointActivity returns a key's activity per endpoint.
+func (c *Client) ListEndpointActivity(ctx context.Context, req *apitypes.ListEndpointActivityRequest) ([]*apitypes.EndpointActivityResponse, error) {
+	return do[[]*apitypes.EndpointActivityResponse](ctx, c, http.MethodGet, "/v1/keys/:keyId/endpoints", req)
+}
+
+// ListUsage returns tenant-wide usage counted per period.
+func (c *Client) ListUsage(ctx context.Context, req *apitypes.ListUsageRequest) ([]*apitypes.AggregationResponse, error) {
+	return do[[]*apitypes.AggregationResponse](ctx, c, http.MethodGet, "/v1/usage", req)
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/xraph/keysmith/apitypes"
+)
+
+// ValidateKey validates a raw key and returns its key, scopes, and rate
+// limit state.
+func (c *Client) ValidateKey(ctx context.Context, req *apitypes.ValidateKeyRequest) (*apitypes.ValidationResponse, error) {
+	return do[*apitypes.ValidationResponse](ctx, c, http.MethodPost, "/v1/keys/validate", req)
+}
+
+// HeaderValidation is the outcome of a successful ValidateKeyHeader, read
+// from the response headers.
+type HeaderValidation struct {
+	KeyID    string
+	TenantID string
+	Scopes   []string
+
+	// Header holds every response header, including the X-RateLimit ones.
+	Header http.Header
+}
+
+// ValidateKeyHeader validates the key in req's Authorization or X-API-Key
+// header through GET /v1/keys/validate, the route edge platforms call.
+func (c *Client) ValidateKeyHeader(ctx context.Context, req *apitypes.ValidateKeyHeaderRequest) (*HeaderValidation, error) {
+	resp, _, err := c.send(ctx, http.MethodGet, "/v1/keys/validate", req)
+	if err != nil {
+		return nil, err
+	}
+	v := &HeaderValidation{
+		KeyID:    resp.Header.Get(apitypes.KeyIDHeader),
+		TenantID: resp.Header.Get(apitypes.TenantIDHeader),
+		Header:   resp.Header,
+	}
+	if s := resp.Header.Get(apitypes.ScopesHeader); s != "" {
+		v.Scopes = strings.Split(s, ",")
+	}
+	return v, nil
+}
+
+// ListSuspiciousFingerprints returns the fingerprints with the most
+// validation failures.
+func (c *Client) ListSuspiciousFingerprints(ctx context.Context, req *apitypes.ListSuspiciousFingerprintsRequest) ([]*apitypes.FailurePatternResponse, error) {
+	return do[[]*apitypes.FailurePatternResponse](ctx, c, http.MethodGet, "/v1/validation/suspicious", req)
+}
+
+// GetIntegrationGuide returns the caller tenant's integration guide. It
+// decodes the JSON form, so req.Format must be empty or "json".
+func (c *Client) GetIntegrationGuide(ctx context.Context, req *apitypes.GetIntegrationGuideRequest) (*apitypes.IntegrationGuideResponse, error) {
+	return do[*apitypes.IntegrationGuideResponse](ctx, c, http.MethodGet, "/v1/integration-guide", req)
+}
+
+// GetSLO returns the validation SLO status.
+func (c *Client) GetSLO(ctx context.Context) (*apitypes.SLOResponse, error) {
+	return do[*apitypes.SLOResponse](ctx, c, http.MethodGet, "/v1/slo", nil)
+}
+
+// GetReplayStats returns the replay protection counters.
+func (c *Client) GetReplayStats(ctx context.Context) (*apitypes.ReplayStatsResponse, error) {
+	return do[*apitypes.ReplayStatsResponse](ctx, c, http.MethodGet, "/v1/validation/replay", nil)
+}
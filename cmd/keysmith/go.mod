@@ -0,0 +1,58 @@
+module github.com/xraph/keysmith/cmd/keysmith
+
+go 1.25.7
+
+require (
+	github.com/stretchr/testify v1.11.1
+	github.com/xraph/grove v1.5.2
+	github.com/xraph/grove/drivers/mongodriver v1.5.2
+	github.com/xraph/grove/drivers/sqlitedriver v1.5.2
+	github.com/xraph/keysmith v0.0.0-00010101000000-000000000000
+	github.com/xraph/keysmith/store/mongo v0.0.0-00010101000000-000000000000
+	github.com/xraph/keysmith/store/postgres v0.0.0-00010101000000-000000000000
+	github.com/xraph/keysmith/store/sqlite v0.0.0-00010101000000-000000000000
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc // indirect
+	github.com/dustin/go-humanize v1.0.1 // indirect
+	github.com/gofrs/uuid/v5 v5.3.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
+	github.com/jackc/pgx/v5 v5.8.0 // indirect
+	github.com/jackc/puddle/v2 v2.2.2 // indirect
+	github.com/klauspost/compress v1.18.0 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/ncruces/go-strftime v1.0.0 // indirect
+	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
+	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
+	github.com/xdg-go/pbkdf2 v1.0.0 // indirect
+	github.com/xdg-go/scram v1.2.0 // indirect
+	github.com/xdg-go/stringprep v1.0.4 // indirect
+	github.com/xraph/go-utils v1.1.1 // indirect
+	github.com/xraph/grove/drivers/pgdriver v1.5.2 // indirect
+	github.com/youmark/pkcs8 v0.0.0-20240726163527-a2c0da244d78 // indirect
+	go.jetify.com/typeid/v2 v2.0.0-alpha.3 // indirect
+	go.mongodb.org/mongo-driver/v2 v2.5.0 // indirect
+	go.uber.org/multierr v1.11.0 // indirect
+	go.uber.org/zap v1.27.1 // indirect
+	golang.org/x/crypto v0.47.0 // indirect
+	golang.org/x/exp v0.0.0-20260218203240-3dfff04db8fa // indirect
+	golang.org/x/sync v0.19.0 // indirect
+	golang.org/x/sys v0.41.0 // indirect
+	golang.org/x/text v0.34.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+	modernc.org/libc v1.68.0 // indirect
+	modernc.org/mathutil v1.7.1 // indirect
+	modernc.org/memory v1.11.0 // indirect
+	modernc.org/sqlite v1.46.1 // indirect
+)
+
+replace github.com/xraph/keysmith => ../..
+
+replace github.com/xraph/keysmith/store/mongo => ../../store/mongo
+
+replace github.com/xraph/keysmith/store/postgres => ../../store/postgres
+
+replace github.com/xraph/keysmith/store/sqlite => ../../store/sqlite
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/keysmithtest"
+)
+
+func runCLI(t *testing.T, args ...string) (string, string, error) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	err := run(context.Background(), args, &stdout, &stderr)
+	return stdout.String(), stderr.String(), err
+}
+
+func TestRun_Usage(t *testing.T) {
+	_, stderr, err := runCLI(t)
+	assert.ErrorIs(t, err, errUsage)
+	assert.Contains(t, stderr, "usage: keysmith")
+
+	_, stderr, err = runCLI(t, "dump")
+	assert.ErrorIs(t, err, errUsage)
+	assert.Contains(t, stderr, `unknown command "dump"`)
+
+	_, stderr, err = runCLI(t, "backup", "-driver", "sqlite", "-dsn", "x.db")
+	assert.ErrorIs(t, err, errUsage)
+	assert.Contains(t, stderr, "-out is required")
+
+	_, _, err = runCLI(t, "restore", "-h")
+	assert.ErrorIs(t, err, flag.ErrHelp)
+}
+
+func TestRun_UnknownDriver(t *testing.T) {
+	_, _, err := runCLI(t, "backup", "-driver", "redis", "-dsn", "x", "-out", filepath.Join(t.TempDir(), "s"))
+	assert.ErrorContains(t, err, `unknown driver "redis"`)
+
+	_, _, err = runCLI(t, "backup", "-driver", "mongo", "-dsn", "mongodb://localhost:27017", "-out", "-")
+	assert.ErrorContains(t, err, "names no database")
+}
+
+func TestVerify(t *testing.T) {
+	src := keysmithtest.NewEngine(t)
+	keysmithtest.NewKey(src).WithScopes("read").MustCreate(t, keysmithtest.Context())
+
+	path := filepath.Join(t.TempDir(), "keysmith.snap")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	_, err = src.Snapshot(context.Background(), f, keysmith.SnapshotOptions{})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	stdout, _, err := runCLI(t, "verify", "-in", path)
+	require.NoError(t, err)
+	assert.Equal(t, "snapshot holds 0 policies, 1 scopes, 1 keys, 0 rotations, 0 usage records, 0 tenant settings\n", stdout)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data[:len(data)-1], 0o600))
+	_, _, err = runCLI(t, "verify", "-in", path)
+	assert.ErrorIs(t, err, keysmith.ErrInvalidSnapshot)
+}
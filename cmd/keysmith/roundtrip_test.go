@@ -0,0 +1,122 @@
+//go:build integration
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/keysmithtest"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/tenant"
+)
+
+// seed fills eng with a policy, scopes, a rotated key with usage, a plain
+// key, and tenant settings, and returns the keys' raw keys.
+func seed(t *testing.T, eng *keysmith.Engine) []string {
+	t.Helper()
+	ctx := keysmithtest.Context()
+	pol := keysmithtest.NewPolicy(eng).WithName("standard").WithAllowedScopes("read", "write").MustCreate(t, ctx)
+	created := keysmithtest.NewKey(eng).WithScopes("read", "write").WithPolicy(pol.ID).MustCreate(t, ctx)
+	rotated, err := eng.RotateKey(ctx, created.Key.ID, rotation.ReasonManual)
+	require.NoError(t, err)
+	plain := keysmithtest.NewKey(eng).WithName("plain").WithScopes("read").MustCreate(t, ctx)
+
+	now := time.Now()
+	keysmithtest.NewUsage(eng, created.Key.ID).Count(3).Between(now.Add(-time.Hour), now).MustCreate(t, ctx)
+	require.NoError(t, eng.SetTenantSettings(ctx, &tenant.Settings{DefaultScopes: []string{"read"}}))
+	return []string{rotated.RawKey, plain.RawKey}
+}
+
+func openTestEngine(t *testing.T, driver, dsn string) *keysmith.Engine {
+	t.Helper()
+	eng, closeStore, err := openEngine(context.Background(), driver, dsn, true)
+	require.NoError(t, err)
+	t.Cleanup(closeStore)
+	return eng
+}
+
+func sqliteDSN(t *testing.T) string {
+	return filepath.Join(t.TempDir(), "keysmith.db")
+}
+
+// restoreInto snapshots src, restores the snapshot into dst, and checks
+// that the raw keys validate on dst and that dst snapshots to the same
+// counts.
+func restoreInto(t *testing.T, src, dst *keysmith.Engine, rawKeys []string) {
+	t.Helper()
+	var buf bytes.Buffer
+	counts, err := src.Snapshot(context.Background(), &buf, keysmith.SnapshotOptions{Usage: true})
+	require.NoError(t, err)
+	assert.Equal(t, keysmith.SnapshotCounts{Policies: 1, Scopes: 2, Keys: 2, Rotations: 1, Usage: 3, Tenants: 1}, *counts)
+
+	report, err := dst.Restore(context.Background(), &buf, keysmith.RestoreOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, *counts, report.Restored)
+
+	for _, raw := range rawKeys {
+		vr := keysmithtest.AssertValidates(t, dst, keysmithtest.Context(), raw)
+		require.NotNil(t, vr)
+		want := keysmithtest.AssertValidates(t, src, keysmithtest.Context(), raw)
+		require.NotNil(t, want)
+		assert.Equal(t, want.Key.ID, vr.Key.ID)
+		assert.Equal(t, want.Key.Name, vr.Key.Name)
+		assert.ElementsMatch(t, want.Scopes, vr.Scopes)
+	}
+
+	again, err := dst.Snapshot(context.Background(), &bytes.Buffer{}, keysmith.SnapshotOptions{Usage: true})
+	require.NoError(t, err)
+	assert.Equal(t, counts, again)
+}
+
+func TestRoundTrip_MemoryToSQLite(t *testing.T) {
+	src := keysmithtest.NewEngine(t)
+	rawKeys := seed(t, src)
+	restoreInto(t, src, openTestEngine(t, "sqlite", sqliteDSN(t)), rawKeys)
+}
+
+func TestRoundTrip_SQLiteToPostgres(t *testing.T) {
+	dsn := os.Getenv("KEYSMITH_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("KEYSMITH_TEST_POSTGRES_DSN is not set")
+	}
+	src := openTestEngine(t, "sqlite", sqliteDSN(t))
+	rawKeys := seed(t, src)
+	restoreInto(t, src, openTestEngine(t, "postgres", dsn), rawKeys)
+}
+
+func TestCLI_BackupRestore(t *testing.T) {
+	srcDSN, dstDSN := sqliteDSN(t), sqliteDSN(t)
+	rawKeys := seed(t, openTestEngine(t, "sqlite", srcDSN))
+	snap := filepath.Join(t.TempDir(), "keysmith.snap")
+
+	stdout, _, err := runCLI(t, "backup", "-driver", "sqlite", "-dsn", srcDSN, "-out", snap, "-usage")
+	require.NoError(t, err)
+	assert.Equal(t, "backed up 1 policies, 2 scopes, 2 keys, 1 rotations, 3 usage records, 1 tenant settings\n", stdout)
+
+	stdout, stderr, err := runCLI(t, "restore", "-driver", "sqlite", "-dsn", dstDSN, "-in", snap)
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "restored 1 policies, 2 scopes, 2 keys")
+	assert.Contains(t, stderr, "keys: 2")
+
+	dst := openTestEngine(t, "sqlite", dstDSN)
+	for _, raw := range rawKeys {
+		keysmithtest.AssertValidates(t, dst, keysmithtest.Context(), raw)
+	}
+
+	// A second restore finds the store full.
+	_, _, err = runCLI(t, "restore", "-driver", "sqlite", "-dsn", dstDSN, "-in", snap)
+	assert.ErrorIs(t, err, keysmith.ErrRestoreTargetNotEmpty)
+	stdout, _, err = runCLI(t, "restore", "-driver", "sqlite", "-dsn", dstDSN, "-in", snap, "-conflict", "skip", "-dry-run")
+	require.NoError(t, err)
+	assert.Equal(t, "would restore 0 policies, 0 scopes, 0 keys, 0 rotations, 0 usage records, 0 tenant settings\n"+
+		"skipped 10 entities the store already has\n", stdout)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/xraph/grove"
+	"github.com/xraph/grove/drivers/mongodriver"
+	"github.com/xraph/grove/drivers/sqlitedriver"
+	_ "github.com/xraph/grove/drivers/sqlitedriver/sqlitemigrate"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/store/mongo"
+	"github.com/xraph/keysmith/store/postgres"
+	"github.com/xraph/keysmith/store/sqlite"
+)
+
+const driverNames = "sqlite, postgres, or mongo"
+
+// openStore connects to the store of driver at dsn. A mongo DSN names its
+// database in the URI path, as in mongodb://localhost:27017/keysmith.
+func openStore(ctx context.Context, driver, dsn string) (store.Store, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("-dsn is required")
+	}
+	switch driver {
+	case "sqlite":
+		d := sqlitedriver.New()
+		if err := d.Open(ctx, dsn); err != nil {
+			return nil, fmt.Errorf("open sqlite: %w", err)
+		}
+		db, err := grove.Open(d)
+		if err != nil {
+			return nil, fmt.Errorf("open sqlite: %w", err)
+		}
+		return sqlite.New(db), nil
+	case "postgres":
+		return postgres.NewFromDSN(ctx, dsn)
+	case "mongo":
+		u, err := url.Parse(dsn)
+		if err != nil || strings.Trim(u.Path, "/") == "" {
+			return nil, fmt.Errorf("mongo DSN %q names no database", dsn)
+		}
+		d := mongodriver.New()
+		if err := d.Open(ctx, dsn); err != nil {
+			return nil, fmt.Errorf("open mongo: %w", err)
+		}
+		db, err := grove.Open(d)
+		if err != nil {
+			return nil, fmt.Errorf("open mongo: %w", err)
+		}
+		return mongo.New(db), nil
+	case "":
+		return nil, fmt.Errorf("-driver is required: %s", driverNames)
+	default:
+		return nil, fmt.Errorf("unknown driver %q: %s", driver, driverNames)
+	}
+}
+
+// openEngine opens the store and an engine on it, running the store's
+// migrations when migrate is set. The returned func closes the store.
+func openEngine(ctx context.Context, driver, dsn string, migrate bool) (*keysmith.Engine, func(), error) {
+	s, err := openStore(ctx, driver, dsn)
+	if err != nil {
+		return nil, nil, err
+	}
+	closeStore := func() { _ = s.Close() }
+	if migrate {
+		if err := s.Migrate(ctx); err != nil {
+			closeStore()
+			return nil, nil, fmt.Errorf("migrate: %w", err)
+		}
+	}
+	eng, err := keysmith.NewEngine(keysmith.WithStore(s))
+	if err != nil {
+		closeStore()
+		return nil, nil, err
+	}
+	return eng, closeStore, nil
+}
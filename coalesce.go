@@ -0,0 +1,201 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/quotapool"
+	"github.com/xraph/keysmith/rotation"
+)
+
+// detachedTimeout bounds work detached from a caller's cancellation by
+// detachedContext.
+const detachedTimeout = 10 * time.Second
+
+// validationSnapshot is the store-backed state needed to validate a key.
+// A snapshot may be cached and shared between coalesced callers, so it is
+// read-only; ValidateKey copies the key and policy before handing them out
+// and shares scopes, which callers must not modify.
+type validationSnapshot struct {
+	key      *key.Key
+	policy   *policy.Policy
+	scopes   []string
+	rotation *rotation.Record
+
+	// previousHash is set when the hash looked up is the one the key's
+	// latest rotation, in rotation, replaced.
+	previousHash bool
+
+	// pool is the quota pool the key is in, if it loaded.
+	pool *quotapool.Pool
+
+	// scopeLimits holds the rate limits of the key's scopes that have one,
+	// by scope name. It is nil when none do.
+	scopeLimits map[string]scopeLimit
+
+	// policyErr is set when the key's policy inheritance cannot be
+	// resolved; policy is then nil and validation fails.
+	policyErr error
+}
+
+// validationAlloc holds a ValidationResult together with the key and policy
+// copies it points to, so that a validation makes one allocation for all
+// three. Results are owned by callers once returned, so they are not pooled.
+type validationAlloc struct {
+	result ValidationResult
+	key    key.Key
+	policy policy.Policy
+}
+
+// newValidationResult returns a result holding copies of the snapshot's key
+// and policy, safe to mutate and return to a single caller.
+func newValidationResult(snap *validationSnapshot) *ValidationResult {
+	a := &validationAlloc{key: *snap.key}
+	a.result.Key = &a.key
+	a.result.Scopes = snap.scopes
+	a.result.scopeLimits = snap.scopeLimits
+	if snap.policy != nil {
+		a.policy = *snap.policy
+		a.result.Policy = &a.policy
+	}
+	return &a.result
+}
+
+// loadSnapshot loads the validation snapshot for a key hash. When coalescing
+// is enabled, concurrent loads of the same hash share a single store round
+// trip. The shared load is detached from the leader's cancellation, so that
+// one caller giving up does not fail every waiter, but keeps its deadline;
+// each caller still returns when its own context is done. A cache hit does
+// not allocate. A request with [ValidationRequest.BypassCache] skips the
+// cache lookup, and its load replaces the cached entry.
+func (e *Engine) loadSnapshot(ctx context.Context, hash []byte) (*validationSnapshot, error) {
+	if e.cache != nil && !ValidationRequestFromContext(ctx).BypassCache {
+		if snap, ok := e.cache.get(hash); ok {
+			return snap, nil
+		}
+	}
+	h := string(hash)
+	if e.flights == nil {
+		return e.fetchSnapshot(ctx, h)
+	}
+	if ctx.Done() == nil {
+		// A context that is never done has no cancellation to detach
+		// from and no deadline to return by.
+		v, err, _ := e.flights.Do(h, func() (any, error) {
+			return e.fetchSnapshot(ctx, h)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return v.(*validationSnapshot), nil
+	}
+	ch := e.flights.DoChan(h, func() (any, error) {
+		lctx, cancel := detachedContext(ctx, true)
+		defer cancel()
+		return e.fetchSnapshot(lctx, h)
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*validationSnapshot), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// detachedContext returns a context with ctx's values that is not canceled
+// with it, for work that must finish after its caller gives up. The work is
+// bounded by detachedTimeout or, with keepDeadline, by ctx's deadline when
+// it has one.
+func detachedContext(ctx context.Context, keepDeadline bool) (context.Context, context.CancelFunc) {
+	detached := context.WithoutCancel(ctx)
+	if dl, ok := ctx.Deadline(); ok && keepDeadline {
+		return context.WithDeadline(detached, dl)
+	}
+	return context.WithTimeout(detached, detachedTimeout)
+}
+
+// fetchSnapshot performs the store reads for a single validation and caches
+// the result when the validation cache is enabled.
+func (e *Engine) fetchSnapshot(ctx context.Context, hash string) (*validationSnapshot, error) {
+	var gen uint64
+	if e.cache != nil {
+		gen = e.cache.generation()
+	}
+
+	k, err := e.store.Keys().GetByHash(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("get key by hash: %w", err)
+	}
+
+	snap := e.snapshotFor(ctx, k, nil)
+	if hash != k.KeyHash && snap.rotation == nil && !key.HashesEqual(hash, k.KeyHash) {
+		// A hash other than the current one is either another version of
+		// the current secret or the secret a rotation replaced.
+		if rec, rotErr := e.store.Rotations().LatestForKey(ctx, k.ID); rotErr == nil && key.HashesEqual(rec.OldKeyHash, hash) {
+			snap.rotation = rec
+			snap.previousHash = true
+		}
+	}
+	if e.cache != nil {
+		e.cache.put(hash, snap, gen, e.runtimeConfig().ValidationCacheTTL)
+	}
+	return snap, nil
+}
+
+// snapshotFor loads the policy, quota pool, scopes, and rotation state that
+// validating k needs. When policies is non-nil it memoizes policy reads across calls.
+func (e *Engine) snapshotFor(ctx context.Context, k *key.Key, policies map[id.PolicyID]*policy.Policy) *validationSnapshot {
+	snap := &validationSnapshot{key: k}
+	if k.State != key.StateActive && k.State != key.StateRotated {
+		// Nothing else is needed to reject an inactive key.
+		return snap
+	}
+
+	if k.State == key.StateRotated {
+		if rec, rotErr := e.store.Rotations().LatestForKey(ctx, k.ID); rotErr == nil {
+			snap.rotation = rec
+		}
+	}
+
+	if k.PolicyID != nil {
+		pol, seen := policies[*k.PolicyID]
+		if !seen {
+			pol, _ = e.store.Policies().Get(ctx, *k.PolicyID)
+			if policies != nil {
+				policies[*k.PolicyID] = pol
+			}
+		}
+		if pol != nil {
+			if eff, err := e.effectivePolicy(ctx, pol); err != nil {
+				snap.policyErr = err
+			} else {
+				merged := *eff.Policy
+				snap.policy = &merged
+			}
+		}
+	}
+
+	if k.PoolID != nil {
+		snap.pool, _ = e.store.QuotaPools().Get(ctx, *k.PoolID)
+	}
+
+	scopes, _ := e.store.Scopes().ListByKey(ctx, k.ID)
+	snap.scopes = make([]string, len(scopes))
+	for i, s := range scopes {
+		snap.scopes[i] = s.Name
+		if s.RateLimit > 0 {
+			if snap.scopeLimits == nil {
+				snap.scopeLimits = make(map[string]scopeLimit)
+			}
+			snap.scopeLimits[s.Name] = scopeLimit{limit: s.RateLimit, window: s.RateLimitWindow}
+		}
+	}
+	return snap
+}
@@ -0,0 +1,172 @@
+package keysmith_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+// countingStore wraps the memory store and counts GetByHash calls. When gate
+// is non-nil, every GetByHash blocks until the gate is closed; when delay is
+// set, each call sleeps to simulate a remote backend.
+type countingStore struct {
+	*memory.Store
+	calls atomic.Int64
+	gate  chan struct{}
+	delay time.Duration
+}
+
+func (s *countingStore) Keys() key.Store { return &countingKeyStore{Store: s.Store.Keys(), parent: s} }
+
+type countingKeyStore struct {
+	key.Store
+	parent *countingStore
+}
+
+func (s *countingKeyStore) GetByHash(ctx context.Context, hash string) (*key.Key, error) {
+	s.parent.calls.Add(1)
+	if s.parent.gate != nil {
+		<-s.parent.gate
+	}
+	if s.parent.delay > 0 {
+		time.Sleep(s.parent.delay)
+	}
+	return s.Store.GetByHash(ctx, hash)
+}
+
+func newCountingEngine(tb testing.TB, cs *countingStore, opts ...keysmith.Option) (*keysmith.Engine, string) {
+	tb.Helper()
+	eng, err := keysmith.NewEngine(append([]keysmith.Option{keysmith.WithStore(cs)}, opts...)...)
+	require.NoError(tb, err)
+
+	result, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+		Name:        "Burst Key",
+		Prefix:      "sk",
+		Environment: key.EnvLive,
+	})
+	require.NoError(tb, err)
+	return eng, result.RawKey
+}
+
+func TestValidateKey_CoalescesConcurrentLoads(t *testing.T) {
+	cs := &countingStore{Store: memory.New()}
+	eng, raw := newCountingEngine(t, cs)
+
+	cs.gate = make(chan struct{})
+	const n = 32
+
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	results := make(chan *keysmith.ValidationResult, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			vr, err := eng.ValidateKey(testCtx(), raw)
+			errs <- err
+			results <- vr
+		}()
+	}
+
+	// Give every caller time to join the in-flight load before releasing it.
+	time.Sleep(100 * time.Millisecond)
+	close(cs.gate)
+	wg.Wait()
+	close(errs)
+	close(results)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int64(1), cs.calls.Load())
+
+	// Each caller receives its own copy of the key.
+	seen := make(map[*key.Key]bool, n)
+	for vr := range results {
+		assert.False(t, seen[vr.Key], "validation results share a key pointer")
+		seen[vr.Key] = true
+	}
+}
+
+func TestValidateKey_WithoutCoalescing(t *testing.T) {
+	cs := &countingStore{Store: memory.New()}
+	eng, raw := newCountingEngine(t, cs, keysmith.WithoutValidationCoalescing())
+
+	const n = 8
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := eng.ValidateKey(testCtx(), raw)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(n), cs.calls.Load())
+}
+
+func TestValidateKey_CoalescedLoadHonorsCallerDeadline(t *testing.T) {
+	cs := &countingStore{Store: memory.New()}
+	eng, raw := newCountingEngine(t, cs)
+	cs.gate = make(chan struct{})
+	defer close(cs.gate)
+
+	const n = 4
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(testCtx(), 50*time.Millisecond)
+			defer cancel()
+			_, err := eng.ValidateKey(ctx, raw)
+			assert.ErrorIs(t, err, keysmith.ErrInvalidKey)
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("validations waited on the hung store past their deadline")
+	}
+	assert.Equal(t, int64(1), cs.calls.Load(), "the callers still share one load")
+}
+
+func BenchmarkValidateKey_Burst(b *testing.B) {
+	run := func(b *testing.B, opts ...keysmith.Option) {
+		cs := &countingStore{Store: memory.New()}
+		eng, raw := newCountingEngine(b, cs, opts...)
+		cs.delay = time.Millisecond
+		cs.calls.Store(0)
+
+		b.SetParallelism(16)
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			ctx := testCtx()
+			for pb.Next() {
+				_, _ = eng.ValidateKey(ctx, raw)
+			}
+		})
+		b.StopTimer()
+		b.ReportMetric(float64(cs.calls.Load())/float64(b.N), "store-calls/op")
+	}
+
+	b.Run("coalesced", func(b *testing.B) { run(b) })
+	b.Run("uncoalesced", func(b *testing.B) { run(b, keysmith.WithoutValidationCoalescing()) })
+}
@@ -0,0 +1,76 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/rotation"
+)
+
+// MetadataCompromise is the key metadata entry holding the most recent
+// compromise report. Keys have no separate notes store, so the report is kept
+// alongside the key it describes.
+const MetadataCompromise = "keysmith.compromise"
+
+// ReportCompromise remediates a leaked key in a single step. Depending on
+// report.Action the key is revoked, or rotated with no grace period so the
+// leaked secret stops validating immediately. The report is written to the
+// key's metadata and the KeyCompromised hook fires after the KeyRevoked or
+// KeyRotated hook, so audit and alerting plugins see the full sequence. The
+// leaked hash is tombstoned either way, and can never be written again.
+func (e *Engine) ReportCompromise(ctx context.Context, keyID id.KeyID, report *key.CompromiseReport) (*CompromiseResult, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	if report.Action != key.CompromiseRevoke && report.Action != key.CompromiseRotate {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidCompromiseAction, report.Action)
+	}
+
+	k, err := e.getKey(ctx, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("get key: %w", err)
+	}
+	if k.State == key.StateRevoked {
+		return nil, ErrInvalidStateTransition
+	}
+
+	if report.ReportedAt.IsZero() {
+		report.ReportedAt = time.Now()
+	}
+	k.Metadata = setInternalMetadata(k.Metadata, MetadataCompromise, map[string]any{
+		"source":      report.Source,
+		"details":     report.Details,
+		"action":      string(report.Action),
+		"reported_at": report.ReportedAt.UTC().Format(time.RFC3339),
+	})
+
+	result := &CompromiseResult{Key: k, Action: report.Action}
+
+	switch report.Action {
+	case key.CompromiseRevoke:
+		if err := e.revokeKey(ctx, k, "compromised: "+report.Source, plugin.ReasonCompromised); err != nil {
+			return nil, err
+		}
+	case key.CompromiseRotate:
+		created, rec, err := e.rotateKey(ctx, k, rotation.ReasonCompromise, 0)
+		if err != nil {
+			return nil, err
+		}
+		result.Key = created.Key
+		result.RawKey = created.RawKey
+		result.Rotation = rec
+		if !IsDryRun(ctx) {
+			e.tombstoneHashes(ctx, k, plugin.ReasonCompromised, rec.OldKeyHash)
+		}
+	}
+
+	if !IsDryRun(ctx) {
+		_ = e.hooks.FireKeyCompromised(ctx, result.Key, report, e.eventMeta(ctx, plugin.TriggerManual, plugin.ReasonCompromised))
+	}
+
+	return result, nil
+}
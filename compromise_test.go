@@ -0,0 +1,132 @@
+package keysmith_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+// hookRecorder records the order in which remediation hooks fire.
+type hookRecorder struct {
+	events []string
+	reason string
+	report *key.CompromiseReport
+}
+
+func (r *hookRecorder) Name() string { return "hook-recorder" }
+
+func (r *hookRecorder) OnKeyRevoked(_ context.Context, _ *key.Key, reason string) error {
+	r.events = append(r.events, "revoked")
+	r.reason = reason
+	return nil
+}
+
+func (r *hookRecorder) OnKeyRotated(_ context.Context, _ *key.Key, _ *rotation.Record) error {
+	r.events = append(r.events, "rotated")
+	return nil
+}
+
+func (r *hookRecorder) OnKeyCompromised(_ context.Context, _ *key.Key, report *key.CompromiseReport) error {
+	r.events = append(r.events, "compromised")
+	r.report = report
+	return nil
+}
+
+func newCompromiseEngine(t *testing.T) (*keysmith.Engine, *hookRecorder, *key.CreateResult) {
+	t.Helper()
+	rec := &hookRecorder{}
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithExtension(rec))
+	require.NoError(t, err)
+
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+		Name:        "Leaked Key",
+		Prefix:      "sk",
+		Environment: key.EnvLive,
+	})
+	require.NoError(t, err)
+	return eng, rec, created
+}
+
+func TestReportCompromise_Revoke(t *testing.T) {
+	eng, rec, created := newCompromiseEngine(t)
+	ctx := testCtx()
+
+	result, err := eng.ReportCompromise(ctx, created.Key.ID, &key.CompromiseReport{
+		Source:  "github-secret-scanning",
+		Details: "found in public repo",
+		Action:  key.CompromiseRevoke,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, key.CompromiseRevoke, result.Action)
+	assert.Empty(t, result.RawKey)
+	assert.Nil(t, result.Rotation)
+
+	k, err := eng.GetKey(ctx, created.Key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, key.StateRevoked, k.State)
+	assert.NotNil(t, k.RevokedAt)
+
+	note, ok := k.Metadata[keysmith.MetadataCompromise].(map[string]any)
+	require.True(t, ok, "compromise note missing from metadata")
+	assert.Equal(t, "github-secret-scanning", note["source"])
+	assert.Equal(t, "found in public repo", note["details"])
+
+	_, err = eng.ValidateKey(ctx, created.RawKey)
+	assert.ErrorIs(t, err, keysmith.ErrKeyInactive)
+
+	assert.Equal(t, []string{"revoked", "compromised"}, rec.events)
+	assert.Equal(t, "compromised: github-secret-scanning", rec.reason)
+	require.NotNil(t, rec.report)
+	assert.False(t, rec.report.ReportedAt.IsZero())
+}
+
+func TestReportCompromise_RotateWithoutGrace(t *testing.T) {
+	eng, rec, created := newCompromiseEngine(t)
+	ctx := testCtx()
+
+	result, err := eng.ReportCompromise(ctx, created.Key.ID, &key.CompromiseReport{
+		Source: "customer",
+		Action: key.CompromiseRotate,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, result.RawKey)
+	require.NotNil(t, result.Rotation)
+	assert.Equal(t, rotation.ReasonCompromise, result.Rotation.Reason)
+	assert.Zero(t, result.Rotation.GraceTTL)
+
+	_, err = eng.ValidateKey(ctx, result.RawKey)
+	require.NoError(t, err)
+
+	_, err = eng.ValidateKey(ctx, created.RawKey)
+	assert.Error(t, err, "leaked key must stop validating immediately")
+
+	assert.Equal(t, []string{"rotated", "compromised"}, rec.events)
+}
+
+func TestReportCompromise_InvalidAction(t *testing.T) {
+	eng, rec, created := newCompromiseEngine(t)
+
+	_, err := eng.ReportCompromise(testCtx(), created.Key.ID, &key.CompromiseReport{
+		Source: "customer",
+		Action: "delete",
+	})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidCompromiseAction)
+	assert.Empty(t, rec.events)
+}
+
+func TestReportCompromise_AlreadyRevoked(t *testing.T) {
+	eng, _, created := newCompromiseEngine(t)
+	ctx := testCtx()
+
+	require.NoError(t, eng.RevokeKey(ctx, created.Key.ID, "manual"))
+
+	_, err := eng.ReportCompromise(ctx, created.Key.ID, &key.CompromiseReport{Action: key.CompromiseRevoke})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidStateTransition)
+}
@@ -0,0 +1,92 @@
+package keysmith
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/plugin"
+)
+
+const (
+	// consumerMismatchInterval is the minimum time between two
+	// KeyConsumerMismatch hooks for the same key.
+	consumerMismatchInterval = time.Hour
+
+	// maxTrackedReports bounds a tracker; keys beyond it are reported
+	// without deduplication until older entries expire.
+	maxTrackedReports = 10_000
+)
+
+// reportTracker remembers when an event was last reported for each key, so
+// a misconfigured caller produces one hook per interval rather than one per
+// request.
+type reportTracker struct {
+	interval time.Duration
+
+	mu       sync.Mutex
+	reported map[id.KeyID]time.Time
+}
+
+func newReportTracker(interval time.Duration) *reportTracker {
+	return &reportTracker{interval: interval, reported: make(map[id.KeyID]time.Time)}
+}
+
+// shouldReport reports whether an event for keyID at now is the first in
+// the current interval, and records it if so.
+func (t *reportTracker) shouldReport(keyID id.KeyID, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if last, ok := t.reported[keyID]; ok && now.Sub(last) < t.interval {
+		return false
+	}
+	if len(t.reported) >= maxTrackedReports {
+		t.pruneLocked(now)
+		if len(t.reported) >= maxTrackedReports {
+			return true
+		}
+	}
+	t.reported[keyID] = now
+	return true
+}
+
+func (t *reportTracker) pruneLocked(now time.Time) {
+	for keyID, last := range t.reported {
+		if now.Sub(last) >= t.interval {
+			delete(t.reported, keyID)
+		}
+	}
+}
+
+// consumerMismatch reports whether the validation request names a consumer
+// service other than the key's intended consumer. Either side being empty
+// is not a mismatch. Names are compared case-insensitively.
+func consumerMismatch(ctx context.Context, k *key.Key) (string, bool) {
+	service := strings.TrimSpace(ValidationRequestFromContext(ctx).ConsumerService)
+	intended := strings.TrimSpace(k.IntendedConsumer)
+	if service == "" || intended == "" || strings.EqualFold(service, intended) {
+		return service, false
+	}
+	return service, true
+}
+
+// checkConsumer compares the calling service with k's intended consumer. A
+// mismatch fires KeyConsumerMismatch at most once per key per hour and, when
+// the key enforces its consumer, fails with ErrConsumerNotAllowed.
+func (e *Engine) checkConsumer(ctx context.Context, k *key.Key) (bool, error) {
+	service, mismatch := consumerMismatch(ctx, k)
+	if !mismatch {
+		return false, nil
+	}
+	if e.consumers.shouldReport(k.ID, e.now()) {
+		_ = e.hooks.FireKeyConsumerMismatch(ctx, k, service, e.eventMeta(ctx, plugin.TriggerValidation, plugin.ReasonConsumerMismatch))
+	}
+	if k.EnforceConsumer {
+		return true, ErrConsumerNotAllowed
+	}
+	return true, nil
+}
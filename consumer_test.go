@@ -0,0 +1,160 @@
+package keysmith_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+type mismatchRecorder struct {
+	mu       sync.Mutex
+	services []string
+}
+
+func (r *mismatchRecorder) Name() string { return "mismatch-recorder" }
+
+func (r *mismatchRecorder) OnKeyConsumerMismatch(_ context.Context, _ *key.Key, service string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.services = append(r.services, service)
+	return nil
+}
+
+func (r *mismatchRecorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.services)
+}
+
+func newConsumerEngine(t *testing.T) (*keysmith.Engine, *mismatchRecorder, *fakeClock) {
+	t.Helper()
+	rec := &mismatchRecorder{}
+	clock := &fakeClock{t: time.Now()}
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithExtension(rec),
+		keysmith.WithClock(clock.Now),
+	)
+	require.NoError(t, err)
+	return eng, rec, clock
+}
+
+func createConsumerKey(t *testing.T, eng *keysmith.Engine, consumer string, enforce bool) *key.CreateResult {
+	t.Helper()
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+		Name:             "Billing Key",
+		Prefix:           "sk",
+		Environment:      key.EnvTest,
+		IntendedConsumer: consumer,
+		EnforceConsumer:  enforce,
+	})
+	require.NoError(t, err)
+	return created
+}
+
+func consumerCtx(service string) context.Context {
+	return keysmith.WithValidationRequest(testCtx(), keysmith.ValidationRequest{ConsumerService: service})
+}
+
+func TestConsumer_MismatchWarns(t *testing.T) {
+	eng, rec, _ := newConsumerEngine(t)
+	created := createConsumerKey(t, eng, "billing-worker", false)
+	assert.Equal(t, "billing-worker", created.Key.IntendedConsumer)
+
+	result, err := eng.ValidateKey(consumerCtx("billing-worker"), created.RawKey)
+	require.NoError(t, err)
+	assert.False(t, result.ConsumerMismatch)
+
+	result, err = eng.ValidateKey(consumerCtx("BILLING-WORKER"), created.RawKey)
+	require.NoError(t, err)
+	assert.False(t, result.ConsumerMismatch, "names compare case-insensitively")
+
+	result, err = eng.ValidateKey(consumerCtx("reporting"), created.RawKey)
+	require.NoError(t, err, "a mismatch does not fail validation")
+	assert.True(t, result.ConsumerMismatch)
+	assert.Equal(t, []string{"reporting"}, rec.services)
+
+	result, err = eng.ValidateKey(testCtx(), created.RawKey)
+	require.NoError(t, err)
+	assert.False(t, result.ConsumerMismatch, "requests without a consumer service are not checked")
+}
+
+func TestConsumer_EnforceRejects(t *testing.T) {
+	eng, rec, _ := newConsumerEngine(t)
+	created := createConsumerKey(t, eng, "billing-worker", true)
+
+	_, err := eng.ValidateKey(consumerCtx("billing-worker"), created.RawKey)
+	require.NoError(t, err)
+
+	_, err = eng.ValidateKey(consumerCtx("reporting"), created.RawKey)
+	assert.ErrorIs(t, err, keysmith.ErrConsumerNotAllowed)
+	assert.Equal(t, 1, rec.count(), "enforced mismatches are reported too")
+}
+
+func TestConsumer_NoIntendedConsumer(t *testing.T) {
+	eng, rec, _ := newConsumerEngine(t)
+	created := createConsumerKey(t, eng, "", true)
+
+	result, err := eng.ValidateKey(consumerCtx("anything"), created.RawKey)
+	require.NoError(t, err)
+	assert.False(t, result.ConsumerMismatch)
+	assert.Zero(t, rec.count())
+}
+
+func TestConsumer_HookOncePerHour(t *testing.T) {
+	eng, rec, clock := newConsumerEngine(t)
+	created := createConsumerKey(t, eng, "billing-worker", false)
+	other := createConsumerKey(t, eng, "billing-worker", false)
+
+	for range 5 {
+		result, err := eng.ValidateKey(consumerCtx("reporting"), created.RawKey)
+		require.NoError(t, err)
+		assert.True(t, result.ConsumerMismatch, "every mismatched result is flagged")
+	}
+	assert.Equal(t, 1, rec.count())
+
+	_, err := eng.ValidateKey(consumerCtx("reporting"), other.RawKey)
+	require.NoError(t, err)
+	assert.Equal(t, 2, rec.count(), "deduplication is per key")
+
+	clock.Set(clock.Now().Add(59 * time.Minute))
+	_, err = eng.ValidateKey(consumerCtx("search"), created.RawKey)
+	require.NoError(t, err)
+	assert.Equal(t, 2, rec.count())
+
+	clock.Set(clock.Now().Add(time.Minute))
+	_, err = eng.ValidateKey(consumerCtx("search"), created.RawKey)
+	require.NoError(t, err)
+	assert.Equal(t, 3, rec.count())
+	assert.Equal(t, []string{"reporting", "reporting", "search"}, rec.services)
+}
+
+func TestUpdateKey_Consumer(t *testing.T) {
+	eng, _, _ := newConsumerEngine(t)
+	created := createConsumerKey(t, eng, "billing-worker", false)
+
+	enforce := true
+	updated, err := eng.UpdateKey(testCtx(), created.Key.ID, &keysmith.UpdateKeyInput{EnforceConsumer: &enforce})
+	require.NoError(t, err)
+	assert.True(t, updated.EnforceConsumer)
+	assert.Equal(t, "billing-worker", updated.IntendedConsumer, "unset fields are unchanged")
+
+	_, err = eng.ValidateKey(consumerCtx("reporting"), created.RawKey)
+	assert.ErrorIs(t, err, keysmith.ErrConsumerNotAllowed)
+
+	cleared := ""
+	updated, err = eng.UpdateKey(testCtx(), created.Key.ID, &keysmith.UpdateKeyInput{IntendedConsumer: &cleared})
+	require.NoError(t, err)
+	assert.Empty(t, updated.IntendedConsumer)
+
+	_, err = eng.ValidateKey(consumerCtx("reporting"), created.RawKey)
+	require.NoError(t, err, "clearing the intended consumer disables the check")
+}
@@ -0,0 +1,58 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+)
+
+// KeyContacts describes where notifications about a key go.
+type KeyContacts struct {
+	// Key holds the key's own contacts.
+	Key key.Contacts `json:"key"`
+
+	// TenantDefaults holds the tenant's default contacts.
+	TenantDefaults key.Contacts `json:"tenant_defaults"`
+
+	// Effective holds the contacts notifications are sent to: the key's own
+	// when it has any, and the tenant defaults otherwise.
+	Effective key.Contacts `json:"effective"`
+}
+
+// validateContacts checks contacts at write time, so a typo in an address
+// is rejected instead of silently dropping every notification.
+func validateContacts(cs key.Contacts) error {
+	if err := cs.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidContact, err)
+	}
+	return nil
+}
+
+// KeyContacts returns a key's contacts, its tenant's defaults, and the
+// contacts that apply.
+func (e *Engine) KeyContacts(ctx context.Context, keyID id.KeyID) (*KeyContacts, error) {
+	k, err := e.getKey(ctx, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("get key: %w", err)
+	}
+	defaults := e.tenantSettings(ctx, k.TenantID).DefaultContacts
+	return &KeyContacts{
+		Key:            k.Contacts,
+		TenantDefaults: defaults,
+		Effective:      key.EffectiveContacts(k, defaults),
+	}, nil
+}
+
+// SetKeyContacts replaces a key's contacts. An empty list clears them, so
+// the tenant's defaults apply again.
+func (e *Engine) SetKeyContacts(ctx context.Context, keyID id.KeyID, contacts key.Contacts) (*KeyContacts, error) {
+	if contacts == nil {
+		contacts = key.Contacts{}
+	}
+	if _, err := e.UpdateKey(ctx, keyID, &UpdateKeyInput{Contacts: contacts}); err != nil {
+		return nil, err
+	}
+	return e.KeyContacts(ctx, keyID)
+}
@@ -0,0 +1,113 @@
+package keysmith_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store/memory"
+	"github.com/xraph/keysmith/tenant"
+)
+
+func newContactsEngine(t *testing.T) *keysmith.Engine {
+	t.Helper()
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()))
+	require.NoError(t, err)
+	return eng
+}
+
+func TestCreateKey_Contacts(t *testing.T) {
+	eng := newContactsEngine(t)
+	ctx := testCtx()
+
+	res, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
+		Name:        "billing",
+		Environment: key.EnvTest,
+		Contacts: key.Contacts{
+			{Type: key.ContactEmail, Target: " team@example.com "},
+			{Type: key.ContactEmail, Target: "team@example.com"},
+			{Type: key.ContactWebhook, Target: "https://hooks.example.com/keys"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, key.Contacts{
+		{Type: key.ContactEmail, Target: "team@example.com"},
+		{Type: key.ContactWebhook, Target: "https://hooks.example.com/keys"},
+	}, res.Key.Contacts, "trimmed and deduplicated")
+
+	for _, c := range []key.Contact{
+		{Type: key.ContactEmail, Target: "Team <team@example.com>"},
+		{Type: key.ContactEmail, Target: "not-an-address"},
+		{Type: key.ContactWebhook, Target: "ftp://hooks.example.com"},
+		{Type: key.ContactWebhook, Target: "https://"},
+		{Type: key.ContactSlack, Target: "#billing oncall"},
+		{Type: "pager", Target: "billing"},
+		{Type: key.ContactEmail, Target: " "},
+	} {
+		_, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
+			Name:        "billing",
+			Environment: key.EnvTest,
+			Contacts:    key.Contacts{c},
+		})
+		assert.ErrorIs(t, err, keysmith.ErrInvalidContact, "%+v", c)
+	}
+}
+
+func TestKeyContacts_Precedence(t *testing.T) {
+	eng := newContactsEngine(t)
+	ctx := testCtx()
+	defaults := key.Contacts{{Type: key.ContactEmail, Target: "security@example.com"}}
+	require.NoError(t, eng.SetTenantSettings(ctx, &tenant.Settings{DefaultContacts: defaults}))
+
+	res, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "billing", Environment: key.EnvTest})
+	require.NoError(t, err)
+
+	kc, err := eng.KeyContacts(ctx, res.Key.ID)
+	require.NoError(t, err)
+	assert.Empty(t, kc.Key)
+	assert.Equal(t, defaults, kc.TenantDefaults)
+	assert.Equal(t, defaults, kc.Effective, "tenant defaults apply to a key without contacts")
+
+	own := key.Contacts{{Type: key.ContactSlack, Target: "#billing-oncall"}}
+	kc, err = eng.SetKeyContacts(ctx, res.Key.ID, own)
+	require.NoError(t, err)
+	assert.Equal(t, own, kc.Key)
+	assert.Equal(t, own, kc.Effective, "key contacts replace the defaults")
+
+	kc, err = eng.SetKeyContacts(ctx, res.Key.ID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, kc.Key)
+	assert.Equal(t, defaults, kc.Effective, "clearing falls back to the defaults")
+}
+
+func TestUpdateKey_Contacts(t *testing.T) {
+	eng := newContactsEngine(t)
+	ctx := testCtx()
+	res, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
+		Name:        "billing",
+		Environment: key.EnvTest,
+		Contacts:    key.Contacts{{Type: key.ContactEmail, Target: "team@example.com"}},
+	})
+	require.NoError(t, err)
+
+	name := "billing-v2"
+	k, err := eng.UpdateKey(ctx, res.Key.ID, &keysmith.UpdateKeyInput{Name: &name})
+	require.NoError(t, err)
+	assert.Len(t, k.Contacts, 1, "omitted contacts are unchanged")
+
+	_, err = eng.UpdateKey(ctx, res.Key.ID, &keysmith.UpdateKeyInput{
+		Contacts: key.Contacts{{Type: key.ContactWebhook, Target: "hooks.example.com"}},
+	})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidContact)
+}
+
+func TestSetTenantSettings_InvalidContact(t *testing.T) {
+	eng := newContactsEngine(t)
+	err := eng.SetTenantSettings(testCtx(), &tenant.Settings{
+		DefaultContacts: key.Contacts{{Type: key.ContactEmail, Target: "security"}},
+	})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidContact)
+}
@@ -0,0 +1,128 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	log "github.com/xraph/go-utils/log"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/plugin"
+)
+
+// CreatorOptions narrows ListKeysByCreator and BulkRevokeByCreator. TenantID
+// and AppID only narrow what the context may already see: a tenant-scoped
+// context is held to its tenant, while an app-scoped or unscoped context
+// searches every tenant unless TenantID is set.
+type CreatorOptions struct {
+	TenantID string
+	AppID    string
+	State    key.State
+
+	// Limit and Offset page ListKeysByCreator, with Limit defaulting to
+	// DefaultPageSize and clamped by [Engine.PageLimit];
+	// BulkRevokeByCreator ignores them and visits every matching key.
+	Limit  int
+	Offset int
+}
+
+// BulkRevokeResult reports a bulk revocation, such as BulkRevokeByCreator
+// or BulkRevokeByLabels.
+type BulkRevokeResult struct {
+	// KeyIDs are the keys revoked, or that would be in a dry run.
+	KeyIDs []id.KeyID `json:"key_ids"`
+
+	// AlreadyRevoked counts matching keys that were revoked before the call.
+	AlreadyRevoked int `json:"already_revoked"`
+
+	Failed int  `json:"failed"`
+	DryRun bool `json:"dry_run"`
+}
+
+// CreatorRevokeResult reports a BulkRevokeByCreator run.
+type CreatorRevokeResult = BulkRevokeResult
+
+// ListKeysByCreator returns the keys whose CreatedBy is creator, for
+// offboarding reviews such as "every key user U created in app A". From an
+// app-scoped or unscoped context the search spans tenants.
+func (e *Engine) ListKeysByCreator(ctx context.Context, creator string, opts CreatorOptions) ([]*key.Key, error) {
+	filter, err := creatorFilter(ctx, creator, opts)
+	if err != nil {
+		return nil, err
+	}
+	filter.Limit = e.PageLimit(filter.Limit, DefaultPageSize)
+	return e.store.Keys().List(ctx, filter)
+}
+
+// BulkRevokeByCreator revokes every key ListKeysByCreator would return for
+// creator, ignoring paging. Each revocation fires KeyRevoked with reason
+// and the creator_revoked reason code. With [WithDryRun] it reports the keys
+// it would revoke. A key the store fails to update is logged and counted in
+// Failed, and the run continues.
+func (e *Engine) BulkRevokeByCreator(ctx context.Context, creator, reason string, opts CreatorOptions) (*CreatorRevokeResult, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	filter, err := creatorFilter(ctx, creator, opts)
+	if err != nil {
+		return nil, err
+	}
+	res, err := e.bulkRevoke(ctx, filter, reason, plugin.ReasonCreatorRevoked)
+	if err != nil {
+		return res, fmt.Errorf("iterate keys by creator: %w", err)
+	}
+	return res, nil
+}
+
+// bulkRevoke revokes every key matching filter, ignoring paging, with reason
+// and code. A key the store fails to update is logged and counted in Failed,
+// and the run continues; the error is from iterating.
+func (e *Engine) bulkRevoke(ctx context.Context, filter *key.ListFilter, reason string, code plugin.ReasonCode) (*BulkRevokeResult, error) {
+	filter.Limit, filter.Offset = 0, 0
+
+	res := &BulkRevokeResult{DryRun: IsDryRun(ctx)}
+	err := e.store.Keys().Iterate(ctx, filter, func(k *key.Key) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if k.State == key.StateRevoked {
+			res.AlreadyRevoked++
+			return nil
+		}
+		if err := e.revokeKey(ctx, k, reason, code); err != nil {
+			e.logger.Warn("failed to revoke key", log.String("key_id", k.ID.String()), log.Any("error", err))
+			res.Failed++
+			return nil
+		}
+		res.KeyIDs = append(res.KeyIDs, k.ID)
+		return nil
+	})
+	return res, err
+}
+
+// createdBy returns the CreatedBy to store for a new key: the caller's value,
+// or the context's actor when it is empty.
+func createdBy(ctx context.Context, raw string) string {
+	if v := strings.TrimSpace(raw); v != "" {
+		return v
+	}
+	return ActorFromContext(ctx)
+}
+
+// creatorFilter builds the key filter for a creator search.
+func creatorFilter(ctx context.Context, creator string, opts CreatorOptions) (*key.ListFilter, error) {
+	creator = strings.TrimSpace(creator)
+	if creator == "" {
+		return nil, ErrCreatorRequired
+	}
+	return keyFilter(ctx, &key.ListFilter{
+		TenantID:  opts.TenantID,
+		AppID:     opts.AppID,
+		State:     opts.State,
+		CreatedBy: creator,
+		Limit:     opts.Limit,
+		Offset:    opts.Offset,
+	}), nil
+}
@@ -0,0 +1,147 @@
+package keysmith_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	audithook "github.com/xraph/keysmith/audit_hook"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keysmithtest"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+func createByCreator(t *testing.T, eng *keysmith.Engine, ctx context.Context, creator string) *key.Key {
+	t.Helper()
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Offboard", Prefix: "sk", Environment: key.EnvTest, CreatedBy: creator})
+	require.NoError(t, err)
+	return created.Key
+}
+
+func TestCreateKey_CreatedByDefaultsToActor(t *testing.T) {
+	audit := &auditCapture{}
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithExtension(audithook.New(audit)))
+	require.NoError(t, err)
+	ctx := keysmith.WithActor(testCtx(), "user_42")
+
+	defaulted := createByCreator(t, eng, ctx, "")
+	assert.Equal(t, "user_42", defaulted.CreatedBy)
+
+	explicit := createByCreator(t, eng, ctx, " ci-bot ")
+	assert.Equal(t, "ci-bot", explicit.CreatedBy, "the caller's value wins and is trimmed")
+
+	anonymous := createByCreator(t, eng, testCtx(), "")
+	assert.Empty(t, anonymous.CreatedBy)
+
+	require.Len(t, audit.recorded(), 3)
+	assert.Equal(t, "user_42", audit.recorded()[0].Metadata["created_by"])
+	assert.Equal(t, "user_42", audit.recorded()[0].Metadata["actor_id"])
+	assert.Equal(t, "ci-bot", audit.recorded()[1].Metadata["created_by"])
+	assert.Equal(t, "user_42", audit.recorded()[1].Metadata["actor_id"], "a creator other than the actor is visible")
+}
+
+func TestListKeysByCreator_Scopes(t *testing.T) {
+	eng := newTestEngine(t)
+	acmeBilling := keysmith.WithTenant(context.Background(), "app_billing", "tenant_acme")
+	globexBilling := keysmith.WithTenant(context.Background(), "app_billing", "tenant_globex")
+	acmeSearch := keysmith.WithTenant(context.Background(), "app_search", "tenant_acme")
+
+	a := createByCreator(t, eng, acmeBilling, "user_42")
+	b := createByCreator(t, eng, globexBilling, "user_42")
+	c := createByCreator(t, eng, acmeSearch, "user_42")
+	createByCreator(t, eng, acmeBilling, "user_7")
+
+	// Tenant-scoped: held to the tenant even if another is asked for.
+	keys, err := eng.ListKeysByCreator(acmeBilling, "user_42", keysmith.CreatorOptions{TenantID: "tenant_globex"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{a.ID.String()}, keyIDs(keys))
+
+	// App-scoped: every tenant in the app.
+	appCtx := keysmith.WithTenant(context.Background(), "app_billing", "")
+	keys, err = eng.ListKeysByCreator(appCtx, "user_42", keysmith.CreatorOptions{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{a.ID.String(), b.ID.String()}, keyIDs(keys))
+
+	// Unscoped: every tenant and app, narrowed by the options.
+	keys, err = eng.ListKeysByCreator(context.Background(), "user_42", keysmith.CreatorOptions{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{a.ID.String(), b.ID.String(), c.ID.String()}, keyIDs(keys))
+
+	keys, err = eng.ListKeysByCreator(context.Background(), "user_42", keysmith.CreatorOptions{TenantID: "tenant_acme"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{a.ID.String(), c.ID.String()}, keyIDs(keys))
+
+	keys, err = eng.ListKeysByCreator(context.Background(), "user_42", keysmith.CreatorOptions{Limit: 2})
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+
+	_, err = eng.ListKeysByCreator(context.Background(), " ", keysmith.CreatorOptions{})
+	assert.ErrorIs(t, err, keysmith.ErrCreatorRequired)
+}
+
+func TestListKeys_CreatedByFilter(t *testing.T) {
+	eng := newTestEngine(t)
+	mine := createByCreator(t, eng, testCtx(), "user_42")
+	createByCreator(t, eng, testCtx(), "user_7")
+
+	keys, err := eng.ListKeys(testCtx(), &key.ListFilter{CreatedBy: "user_42"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{mine.ID.String()}, keyIDs(keys))
+}
+
+func TestBulkRevokeByCreator(t *testing.T) {
+	rec := keysmithtest.NewRecorder()
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithExtension(rec))
+	require.NoError(t, err)
+	acme := keysmith.WithTenant(context.Background(), "app_billing", "tenant_acme")
+	globex := keysmith.WithTenant(context.Background(), "app_billing", "tenant_globex")
+
+	a := createByCreator(t, eng, acme, "user_42")
+	b := createByCreator(t, eng, globex, "user_42")
+	already := createByCreator(t, eng, acme, "user_42")
+	require.NoError(t, eng.RevokeKey(acme, already.ID, "earlier"))
+	other := createByCreator(t, eng, acme, "user_7")
+	appCtx := keysmith.WithTenant(context.Background(), "app_billing", "")
+	rec.Reset()
+
+	// A dry run reports the keys without revoking them.
+	res, err := eng.BulkRevokeByCreator(keysmith.WithDryRun(appCtx), "user_42", "offboarded", keysmith.CreatorOptions{})
+	require.NoError(t, err)
+	assert.True(t, res.DryRun)
+	assert.ElementsMatch(t, []id.KeyID{a.ID, b.ID}, res.KeyIDs)
+	assert.Equal(t, 1, res.AlreadyRevoked)
+	assert.Zero(t, rec.Count("KeyRevoked"))
+	k, err := eng.GetKey(acme, a.ID)
+	require.NoError(t, err)
+	assert.Equal(t, key.StateActive, k.State)
+
+	res, err = eng.BulkRevokeByCreator(appCtx, "user_42", "offboarded", keysmith.CreatorOptions{Limit: 1})
+	require.NoError(t, err)
+	assert.False(t, res.DryRun)
+	assert.ElementsMatch(t, []id.KeyID{a.ID, b.ID}, res.KeyIDs, "paging is ignored")
+	assert.Zero(t, res.Failed)
+
+	revoked := rec.Filter("KeyRevoked")
+	require.Len(t, revoked, 2)
+	for _, evt := range revoked {
+		assert.Equal(t, "offboarded", evt.Reason)
+		assert.Equal(t, plugin.ReasonCreatorRevoked, evt.Meta.ReasonCode)
+	}
+	k, err = eng.GetKey(globex, b.ID)
+	require.NoError(t, err)
+	assert.Equal(t, key.StateRevoked, k.State)
+	k, err = eng.GetKey(acme, other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, key.StateActive, k.State, "other creators' keys are untouched")
+
+	// Re-running reports the keys as already revoked.
+	res, err = eng.BulkRevokeByCreator(appCtx, "user_42", "offboarded", keysmith.CreatorOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, res.KeyIDs)
+	assert.Equal(t, 3, res.AlreadyRevoked)
+}
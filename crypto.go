@@ -0,0 +1,111 @@
+package keysmith
+
+import (
+	"crypto/fips140"
+	"fmt"
+	"slices"
+)
+
+// Algorithm names reported by [CryptoProfile].
+const (
+	AlgSHA256     = "SHA-256"
+	AlgHMACSHA256 = "HMAC-SHA256"
+	AlgCryptoRand = "crypto/rand"
+	AlgPKCS11     = "PKCS#11"
+)
+
+// AlgorithmReporter is implemented by a [Hasher] or [KeyGenerator] that
+// names the primitive it uses, such as [AlgSHA256]. Built with the fips tag,
+// NewEngine refuses a hasher or generator that does not implement it, since
+// its algorithm cannot be vouched for.
+type AlgorithmReporter interface {
+	Algorithm() string
+}
+
+// EntropyReporter is implemented by a [KeyGenerator] that states how many
+// bits of entropy the random part of each key carries, as
+// [DefaultKeyGenerator] does.
+type EntropyReporter interface {
+	EntropyBits() int
+}
+
+// Algorithms approved for each role when built with the fips tag. A PKCS#11
+// entropy source is trusted to be a validated module.
+var (
+	fipsHashers    = []string{AlgSHA256, AlgHMACSHA256}
+	fipsGenerators = []string{AlgCryptoRand, AlgPKCS11}
+)
+
+// CryptoProfile lists the primitives an engine uses, for compliance evidence.
+// Algorithms that do not report themselves are listed as "unknown".
+type CryptoProfile struct {
+	// FIPSMode is true when keysmith was built with the fips tag.
+	FIPSMode bool `json:"fips_mode"`
+
+	// FIPS140Module is true when the Go FIPS 140-3 module is enabled, as
+	// with GODEBUG=fips140=on.
+	FIPS140Module bool `json:"fips140_module"`
+
+	// KeyHash hashes raw keys for storage and lookup.
+	KeyHash string `json:"key_hash"`
+
+	// KeyGeneration produces the random part of new keys.
+	KeyGeneration string `json:"key_generation"`
+
+	// KeyEntropyBits is the entropy of the random part of each new key, or
+	// zero when the generator does not implement [EntropyReporter].
+	KeyEntropyBits int `json:"key_entropy_bits"`
+
+	// FailureFingerprint hashes rejected keys for failure tracking and the
+	// KeyValidationFailed hook.
+	FailureFingerprint string `json:"failure_fingerprint"`
+}
+
+// CryptoProfile reports the primitives in use by e.
+func (e *Engine) CryptoProfile() CryptoProfile {
+	return CryptoProfile{
+		FIPSMode:           FIPSMode,
+		FIPS140Module:      fips140.Enabled(),
+		KeyHash:            algorithmOf(e.hasher),
+		KeyGeneration:      algorithmOf(e.generator),
+		KeyEntropyBits:     entropyBitsOf(e.generator),
+		FailureFingerprint: AlgSHA256,
+	}
+}
+
+func algorithmOf(v any) string {
+	if r, ok := v.(AlgorithmReporter); ok {
+		return r.Algorithm()
+	}
+	return "unknown"
+}
+
+func entropyBitsOf(v any) int {
+	if r, ok := v.(EntropyReporter); ok {
+		return r.EntropyBits()
+	}
+	return 0
+}
+
+// checkCrypto refuses, in FIPS mode, a hasher or key generator whose
+// algorithm is not approved.
+func (e *Engine) checkCrypto() error {
+	if !FIPSMode {
+		return nil
+	}
+	if err := checkApproved("hasher", e.hasher, fipsHashers); err != nil {
+		return err
+	}
+	return checkApproved("key generator", e.generator, fipsGenerators)
+}
+
+func checkApproved(role string, v any, approved []string) error {
+	r, ok := v.(AlgorithmReporter)
+	if !ok {
+		return fmt.Errorf("%w: %s %T does not report its algorithm", ErrNotAvailableInFIPSMode, role, v)
+	}
+	if alg := r.Algorithm(); !slices.Contains(approved, alg) {
+		return fmt.Errorf("%w: %s algorithm %q is not approved; use one of %v", ErrNotAvailableInFIPSMode, role, alg, approved)
+	}
+	return nil
+}
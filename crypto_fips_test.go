@@ -0,0 +1,39 @@
+//go:build fips
+
+package keysmith_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+func TestFIPS_RefusesUnapprovedCrypto(t *testing.T) {
+	for name, opt := range map[string]keysmith.Option{
+		"argon2 hasher":     keysmith.WithHasher(namedHasher{alg: "argon2id"}),
+		"bcrypt hasher":     keysmith.WithHasher(namedHasher{alg: "bcrypt"}),
+		"unreported hasher": keysmith.WithHasher(plainHasher{}),
+		"unreported keygen": keysmith.WithKeyGenerator(fixedGenerator{}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), opt)
+			assert.ErrorIs(t, err, keysmith.ErrNotAvailableInFIPSMode)
+		})
+	}
+}
+
+func TestFIPS_ErrorNamesAlgorithm(t *testing.T) {
+	_, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithHasher(namedHasher{alg: "argon2id"}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `"argon2id"`)
+	assert.Contains(t, err.Error(), keysmith.AlgSHA256)
+}
+
+func TestFIPS_Profile(t *testing.T) {
+	assert.True(t, keysmith.FIPSMode)
+	assert.True(t, newTestEngine(t).CryptoProfile().FIPSMode)
+}
@@ -0,0 +1,33 @@
+//go:build !fips
+
+package keysmith_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+func TestCryptoProfile_AnyAlgorithmOutsideFIPS(t *testing.T) {
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithHasher(namedHasher{alg: "argon2id"}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "argon2id", eng.CryptoProfile().KeyHash)
+
+	eng, err = keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithHasher(plainHasher{}),
+		keysmith.WithKeyGenerator(fixedGenerator{}),
+	)
+	require.NoError(t, err)
+	p := eng.CryptoProfile()
+	assert.False(t, p.FIPSMode)
+	assert.Equal(t, "unknown", p.KeyHash)
+	assert.Equal(t, "unknown", p.KeyGeneration)
+}
@@ -0,0 +1,67 @@
+package keysmith_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+// plainHasher is a custom hasher that does not report its algorithm.
+type plainHasher struct{}
+
+func (plainHasher) Hash(rawKey string) (string, error) { return "plain:" + rawKey, nil }
+
+func (plainHasher) Verify(rawKey, hash string) (bool, error) { return hash == "plain:"+rawKey, nil }
+
+// namedHasher is a custom hasher reporting alg.
+type namedHasher struct {
+	plainHasher
+	alg string
+}
+
+func (h namedHasher) Algorithm() string { return h.alg }
+
+// fixedGenerator is a custom generator that does not report its algorithm.
+type fixedGenerator struct{}
+
+func (fixedGenerator) Generate(prefix string, env key.Environment) (string, error) {
+	return prefix + "_" + string(env) + "_fixed", nil
+}
+
+func TestCryptoProfile_Default(t *testing.T) {
+	eng := newTestEngine(t)
+	p := eng.CryptoProfile()
+	assert.Equal(t, keysmith.FIPSMode, p.FIPSMode)
+	assert.Equal(t, keysmith.AlgSHA256, p.KeyHash)
+	assert.Equal(t, keysmith.AlgCryptoRand, p.KeyGeneration)
+	assert.Equal(t, keysmith.AlgSHA256, p.FailureFingerprint)
+	assert.Equal(t, 256, p.KeyEntropyBits)
+	assert.Equal(t, p, eng.HealthReport(testCtx()).Crypto)
+}
+
+func TestCryptoProfile_HMAC(t *testing.T) {
+	h, err := keysmith.NewHMACHasher([]byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithHasher(h))
+	require.NoError(t, err)
+	assert.Equal(t, keysmith.AlgHMACSHA256, eng.CryptoProfile().KeyHash)
+
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "hmac", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	_, err = eng.ValidateKey(testCtx(), created.RawKey)
+	assert.NoError(t, err)
+}
+
+func TestCryptoProfile_ApprovedCustomHasher(t *testing.T) {
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithHasher(namedHasher{alg: keysmith.AlgSHA256}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, keysmith.AlgSHA256, eng.CryptoProfile().KeyHash)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"time"
 
 	"github.com/a-h/templ"
 
@@ -103,6 +104,8 @@ func (c *Contributor) RenderWidget(ctx context.Context, widgetID string) (templ.
 	switch widgetID {
 	case "keysmith-stats":
 		return c.renderStatsWidget(ctx)
+	case "keysmith-key-overview":
+		return c.renderKeyOverviewWidget(ctx)
 	case "keysmith-recent-keys":
 		return c.renderRecentKeysWidget(ctx)
 	case "keysmith-usage-summary":
@@ -408,6 +411,27 @@ func (c *Contributor) renderStatsWidget(ctx context.Context) (templ.Component, e
 	}), nil
 }
 
+func (c *Contributor) renderKeyOverviewWidget(ctx context.Context) (templ.Component, error) {
+	o, err := fetchKeyOverview(ctx, c.engine)
+	if err != nil {
+		return nil, err
+	}
+	return templ.ComponentFunc(func(_ context.Context, w io.Writer) error {
+		_, _ = io.WriteString(w, `<div class="grid grid-cols-2 gap-4 p-4">`)
+		for _, e := range o.Expiring {
+			_, _ = fmt.Fprintf(w,
+				`<div class="space-y-1"><span class="text-sm text-muted-foreground">Expiring in %dd</span><p class="text-2xl font-bold">%d</p></div>`,
+				int(e.Within/(24*time.Hour)), e.Count)
+		}
+		_, err := fmt.Fprintf(w,
+			`<div class="space-y-1"><span class="text-sm text-muted-foreground">Never Used</span><p class="text-2xl font-bold">%d</p></div>`+
+				`<div class="space-y-1"><span class="text-sm text-muted-foreground">Created in %dd</span><p class="text-2xl font-bold">%d</p></div>`+
+				`</div>`,
+			o.NeverUsed, int(o.CreatedRecently.Within/(24*time.Hour)), o.CreatedRecently.Count)
+		return err
+	}), nil
+}
+
 func (c *Contributor) renderRecentKeysWidget(ctx context.Context) (templ.Component, error) {
 	keys, _ := fetchRecentKeys(ctx, c.engine, 5)
 	return templ.ComponentFunc(func(_ context.Context, w io.Writer) error {
@@ -24,28 +24,27 @@ type KeyStats struct {
 
 // fetchKeyStats returns aggregated key counts for the overview.
 func fetchKeyStats(ctx context.Context, engine *keysmith.Engine) KeyStats {
-	var stats KeyStats
-	total, err := engine.Store().Keys().Count(ctx, &key.ListFilter{})
-	if err == nil {
-		stats.Total = total
-	}
-	active, err := engine.Store().Keys().Count(ctx, &key.ListFilter{State: key.StateActive})
-	if err == nil {
-		stats.Active = active
-	}
-	revoked, err := engine.Store().Keys().Count(ctx, &key.ListFilter{State: key.StateRevoked})
-	if err == nil {
-		stats.Revoked = revoked
+	o, err := fetchKeyOverview(ctx, engine)
+	if err != nil {
+		return KeyStats{}
 	}
-	suspended, err := engine.Store().Keys().Count(ctx, &key.ListFilter{State: key.StateSuspended})
-	if err == nil {
-		stats.Suspended = suspended
+	return KeyStats{
+		Total:     o.Total,
+		Active:    o.ByState[key.StateActive],
+		Revoked:   o.ByState[key.StateRevoked],
+		Suspended: o.ByState[key.StateSuspended],
+		Expired:   o.ByState[key.StateExpired],
 	}
-	expired, err := engine.Store().Keys().Count(ctx, &key.ListFilter{State: key.StateExpired})
-	if err == nil {
-		stats.Expired = expired
+}
+
+// fetchKeyOverview returns key counts by state, environment, and lifecycle
+// window, with the engine's default windows.
+func fetchKeyOverview(ctx context.Context, engine *keysmith.Engine) (*key.Overview, error) {
+	o, err := engine.TenantKeyOverview(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("dashboard: fetch key overview: %w", err)
 	}
-	return stats
+	return o, nil
 }
 
 // fetchRecentKeys returns the most recently created keys.
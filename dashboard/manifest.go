@@ -105,6 +105,14 @@ func baseWidgets() []contributor.WidgetDescriptor {
 			RefreshSec:  60,
 			Group:       "Keysmith",
 		},
+		{
+			ID:          "keysmith-key-overview",
+			Title:       "Key Lifecycle",
+			Description: "Keys expiring soon, never used, and recently created",
+			Size:        "md",
+			RefreshSec:  300,
+			Group:       "Keysmith",
+		},
 		{
 			ID:          "keysmith-recent-keys",
 			Title:       "Recent Keys",
@@ -0,0 +1,153 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	log "github.com/xraph/go-utils/log"
+
+	"github.com/xraph/keysmith/capture"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/plugin"
+)
+
+// Debug capture settings.
+const (
+	// DefaultCaptureRetention is how long debug captures are kept.
+	DefaultCaptureRetention = 24 * time.Hour
+
+	// DefaultCapturePurgeInterval is how often the background worker
+	// started by Start purges captures past retention.
+	DefaultCapturePurgeInterval = 10 * time.Minute
+
+	// MaxDebugDuration bounds how long debug capture can stay on for a key.
+	MaxDebugDuration = 24 * time.Hour
+
+	// MaxCapturesListed caps the captures returned by ListCaptures.
+	MaxCapturesListed = 100
+)
+
+// SetKeyDebug turns on debug capture for a key: the middleware captures the
+// given fraction of its requests, from 0 to 1, until d has passed. A rate of
+// 0 turns capture off. Keys in the live environment are refused with
+// ErrDebugCaptureNotAllowed unless WithLiveDebugCapture is set. Enabling
+// capture fires the KeyDebugEnabled hook so that the change is audited.
+func (e *Engine) SetKeyDebug(ctx context.Context, keyID id.KeyID, rate float64, d time.Duration) (*key.Key, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	if rate < 0 || rate > 1 {
+		return nil, fmt.Errorf("%w: sample rate must be between 0 and 1", ErrInvalidDebugCapture)
+	}
+	if rate > 0 && (d <= 0 || d > MaxDebugDuration) {
+		return nil, fmt.Errorf("%w: duration must be positive and at most %s", ErrInvalidDebugCapture, MaxDebugDuration)
+	}
+
+	k, err := e.getKey(ctx, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("get key: %w", err)
+	}
+	if rate > 0 && !e.captureAllowed(k) {
+		return nil, ErrDebugCaptureNotAllowed
+	}
+
+	now := e.now()
+	if rate > 0 {
+		until := now.Add(d).UTC()
+		k.DebugSampleRate = rate
+		k.DebugUntil = &until
+	} else {
+		k.DebugSampleRate = 0
+		k.DebugUntil = nil
+	}
+	k.UpdatedAt = now
+
+	if IsDryRun(ctx) {
+		return k, nil
+	}
+	if err := e.store.Keys().Update(ctx, k); err != nil {
+		return nil, fmt.Errorf("update key: %w", err)
+	}
+	e.invalidateKey(k.ID)
+	e.bumpRevision(ctx, k.TenantID)
+
+	if rate > 0 {
+		_ = e.hooks.FireKeyDebugEnabled(ctx, k, e.eventMeta(ctx, plugin.TriggerManual, ""))
+	}
+	return k, nil
+}
+
+// SampleCapture reports whether a request made with k should be captured.
+// It is false once the key's debug window has passed.
+func (e *Engine) SampleCapture(k *key.Key) bool {
+	rate := k.DebugRate(e.now())
+	if rate <= 0 || !e.captureAllowed(k) {
+		return false
+	}
+	return rate >= 1 || rand.Float64() < rate
+}
+
+// RecordCapture stores a capture of a request made with k. Secrets are
+// redacted first, including any header or body text containing rawKey, and
+// the body is then cut to capture.DefaultBodyLimit. A failed write is logged and
+// returned; callers serving the request can ignore it.
+func (e *Engine) RecordCapture(ctx context.Context, k *key.Key, rawKey string, c *capture.Capture) error {
+	if !e.captureAllowed(k) {
+		return ErrDebugCaptureNotAllowed
+	}
+	if err := e.checkUsageWritable(); err != nil {
+		return err
+	}
+
+	c.ID = id.NewCaptureID()
+	c.KeyID = k.ID
+	c.TenantID = k.TenantID
+	c.CapturedAt = e.now().UTC()
+	c.Redact(rawKey)
+	c.Truncate(capture.DefaultBodyLimit)
+
+	if err := e.store.Captures().Record(context.WithoutCancel(ctx), c); err != nil {
+		e.logger.Warn("failed to record debug capture",
+			log.String("key_id", k.ID.String()),
+			log.Any("error", err),
+		)
+		return fmt.Errorf("record capture: %w", err)
+	}
+	return nil
+}
+
+// ListCaptures returns the most recent debug captures for a key, newest
+// first, up to MaxCapturesListed.
+func (e *Engine) ListCaptures(ctx context.Context, keyID id.KeyID) ([]*capture.Capture, error) {
+	if err := e.checkKey(ctx, keyID); err != nil {
+		return nil, err
+	}
+	return e.store.Captures().List(ctx, keyID, MaxCapturesListed)
+}
+
+// PurgeCaptures deletes captures older than the capture retention. It runs
+// on a timer after Start; call it directly when running without Start.
+func (e *Engine) PurgeCaptures(ctx context.Context) (int64, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return 0, err
+	}
+	return e.store.Captures().Purge(ctx, e.now().Add(-e.captureRetention))
+}
+
+// captureAllowed reports whether k's environment permits debug capture.
+func (e *Engine) captureAllowed(k *key.Key) bool {
+	return k.Environment != key.EnvLive || e.liveCapture
+}
+
+// purgeCaptures is the body of the background capture purger.
+func (e *Engine) purgeCaptures(ctx context.Context) {
+	if e.ReadOnly() {
+		return
+	}
+	if _, err := e.PurgeCaptures(ctx); err != nil {
+		e.logger.Warn("failed to purge debug captures", log.Any("error", err))
+	}
+}
@@ -0,0 +1,225 @@
+package keysmith_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/capture"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+// debugRecorder collects KeyDebugEnabled hook calls.
+type debugRecorder struct {
+	mu      sync.Mutex
+	enabled []*key.Key
+}
+
+func (r *debugRecorder) Name() string { return "debug-recorder" }
+
+func (r *debugRecorder) OnKeyDebugEnabled(_ context.Context, k *key.Key) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cp := *k
+	r.enabled = append(r.enabled, &cp)
+	return nil
+}
+
+func newDebugEngine(t *testing.T, opts ...keysmith.Option) (*keysmith.Engine, *fakeClock, *debugRecorder) {
+	t.Helper()
+	clock := &fakeClock{t: time.Now()}
+	rec := &debugRecorder{}
+	opts = append([]keysmith.Option{
+		keysmith.WithStore(memory.New()),
+		keysmith.WithClock(clock.Now),
+		keysmith.WithExtension(rec),
+	}, opts...)
+	eng, err := keysmith.NewEngine(opts...)
+	require.NoError(t, err)
+	return eng, clock, rec
+}
+
+func createDebugKey(t *testing.T, eng *keysmith.Engine, env key.Environment) *key.CreateResult {
+	t.Helper()
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+		Name:        "Debug Key",
+		Prefix:      "sk",
+		Environment: env,
+	})
+	require.NoError(t, err)
+	return created
+}
+
+func TestSetKeyDebug(t *testing.T) {
+	eng, clock, rec := newDebugEngine(t)
+	created := createDebugKey(t, eng, key.EnvTest)
+
+	k, err := eng.SetKeyDebug(testCtx(), created.Key.ID, 0.5, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 0.5, k.DebugSampleRate)
+	require.NotNil(t, k.DebugUntil)
+	assert.WithinDuration(t, clock.Now().Add(time.Hour), *k.DebugUntil, time.Second)
+
+	stored, err := eng.GetKey(testCtx(), created.Key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0.5, stored.DebugRate(clock.Now()))
+
+	require.Len(t, rec.enabled, 1, "enabling capture is audited")
+	assert.Equal(t, created.Key.ID, rec.enabled[0].ID)
+
+	k, err = eng.SetKeyDebug(testCtx(), created.Key.ID, 0, 0)
+	require.NoError(t, err)
+	assert.Zero(t, k.DebugSampleRate)
+	assert.Nil(t, k.DebugUntil)
+	assert.Len(t, rec.enabled, 1, "turning capture off fires no hook")
+}
+
+func TestSetKeyDebug_Invalid(t *testing.T) {
+	eng, _, rec := newDebugEngine(t)
+	created := createDebugKey(t, eng, key.EnvTest)
+
+	for _, tt := range []struct {
+		rate float64
+		d    time.Duration
+	}{
+		{-0.1, time.Hour},
+		{1.5, time.Hour},
+		{0.5, 0},
+		{0.5, keysmith.MaxDebugDuration + time.Minute},
+	} {
+		_, err := eng.SetKeyDebug(testCtx(), created.Key.ID, tt.rate, tt.d)
+		assert.ErrorIs(t, err, keysmith.ErrInvalidDebugCapture, "rate %v for %s", tt.rate, tt.d)
+	}
+	assert.Empty(t, rec.enabled)
+}
+
+func TestSetKeyDebug_LiveKeys(t *testing.T) {
+	eng, _, rec := newDebugEngine(t)
+	live := createDebugKey(t, eng, key.EnvLive)
+
+	_, err := eng.SetKeyDebug(testCtx(), live.Key.ID, 1, time.Hour)
+	require.ErrorIs(t, err, keysmith.ErrDebugCaptureNotAllowed)
+	assert.Empty(t, rec.enabled)
+
+	permissive, _, _ := newDebugEngine(t, keysmith.WithLiveDebugCapture())
+	live = createDebugKey(t, permissive, key.EnvLive)
+	_, err = permissive.SetKeyDebug(testCtx(), live.Key.ID, 1, time.Hour)
+	assert.NoError(t, err)
+}
+
+func TestSampleCapture(t *testing.T) {
+	eng, clock, _ := newDebugEngine(t)
+	created := createDebugKey(t, eng, key.EnvTest)
+
+	k, err := eng.GetKey(testCtx(), created.Key.ID)
+	require.NoError(t, err)
+	assert.False(t, eng.SampleCapture(k), "capture is off by default")
+
+	k, err = eng.SetKeyDebug(testCtx(), created.Key.ID, 1, time.Hour)
+	require.NoError(t, err)
+	for range 20 {
+		require.True(t, eng.SampleCapture(k))
+	}
+
+	k, err = eng.SetKeyDebug(testCtx(), created.Key.ID, 0.5, time.Hour)
+	require.NoError(t, err)
+	sampled := 0
+	for range 2000 {
+		if eng.SampleCapture(k) {
+			sampled++
+		}
+	}
+	assert.InDelta(t, 1000, sampled, 200)
+
+	// The flag expires on its own.
+	clock.Set(clock.Now().Add(time.Hour))
+	assert.False(t, eng.SampleCapture(k))
+	assert.Zero(t, k.DebugRate(clock.Now()))
+}
+
+func TestRecordCapture_RedactsAndTruncates(t *testing.T) {
+	eng, _, _ := newDebugEngine(t)
+	created := createDebugKey(t, eng, key.EnvTest)
+	raw := created.RawKey
+
+	c := &capture.Capture{
+		Method: "POST",
+		Path:   "/v1/charges",
+		Headers: map[string]string{
+			"Authorization": "Bearer " + raw,
+			"X-Forwarded":   "key=" + raw,
+			"Content-Type":  "application/json",
+		},
+		Body: `{"api_key":"` + raw + `","pad":"` + string(make([]byte, capture.DefaultBodyLimit)) + `"}`,
+	}
+	require.NoError(t, eng.RecordCapture(testCtx(), created.Key, raw, c))
+
+	list, err := eng.ListCaptures(testCtx(), created.Key.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	got := list[0]
+	assert.Equal(t, created.Key.ID, got.KeyID)
+	assert.Equal(t, "tenant_test", got.TenantID)
+	assert.Equal(t, capture.Redacted, got.Headers["Authorization"])
+	assert.Equal(t, capture.Redacted, got.Headers["X-Forwarded"])
+	assert.Equal(t, "application/json", got.Headers["Content-Type"])
+	assert.NotContains(t, got.Body, raw)
+	assert.True(t, got.BodyTruncated)
+	assert.LessOrEqual(t, len(got.Body), capture.DefaultBodyLimit+len(capture.Redacted))
+}
+
+func TestRecordCapture_RedactsKeyAcrossLimit(t *testing.T) {
+	eng, _, _ := newDebugEngine(t)
+	created := createDebugKey(t, eng, key.EnvTest)
+	raw := created.RawKey
+
+	pad := strings.Repeat("x", capture.DefaultBodyLimit-len(raw)/2)
+	c := &capture.Capture{Method: "POST", Path: "/v1/charges", Body: pad + raw + strings.Repeat("y", 64)}
+	require.NoError(t, eng.RecordCapture(testCtx(), created.Key, raw, c))
+
+	list, err := eng.ListCaptures(testCtx(), created.Key.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	got := list[0]
+	assert.True(t, got.BodyTruncated)
+	assert.Len(t, got.Body, capture.DefaultBodyLimit)
+	assert.True(t, strings.HasPrefix(got.Body, pad+capture.Redacted), "no part of the key is kept")
+}
+
+func TestRecordCapture_RefusesLiveKeys(t *testing.T) {
+	eng, _, _ := newDebugEngine(t)
+	live := createDebugKey(t, eng, key.EnvLive)
+
+	err := eng.RecordCapture(testCtx(), live.Key, live.RawKey, &capture.Capture{Method: "GET", Path: "/"})
+	require.ErrorIs(t, err, keysmith.ErrDebugCaptureNotAllowed)
+
+	list, err := eng.ListCaptures(testCtx(), live.Key.ID)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func TestPurgeCaptures(t *testing.T) {
+	eng, clock, _ := newDebugEngine(t, keysmith.WithCaptureRetention(2*time.Hour))
+	created := createDebugKey(t, eng, key.EnvTest)
+
+	require.NoError(t, eng.RecordCapture(testCtx(), created.Key, created.RawKey, &capture.Capture{Method: "GET", Path: "/old"}))
+	clock.Set(clock.Now().Add(90 * time.Minute))
+	require.NoError(t, eng.RecordCapture(testCtx(), created.Key, created.RawKey, &capture.Capture{Method: "GET", Path: "/new"}))
+
+	clock.Set(clock.Now().Add(time.Hour))
+	n, err := eng.PurgeCaptures(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	list, err := eng.ListCaptures(testCtx(), created.Key.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "/new", list[0].Path)
+}
@@ -0,0 +1,83 @@
+package keysmith
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/plugin"
+)
+
+// SecretSink stores a secret at a path in an external secrets manager.
+// Implementations for HashiCorp Vault and AWS Secrets Manager live under
+// delivery/.
+type SecretSink interface {
+	Put(ctx context.Context, path string, secret string) error
+}
+
+// SecretSinkFunc is an adapter to use a plain function as a SecretSink.
+type SecretSinkFunc func(ctx context.Context, path string, secret string) error
+
+// Put implements SecretSink.
+func (f SecretSinkFunc) Put(ctx context.Context, path string, secret string) error {
+	return f(ctx, path, secret)
+}
+
+// SecretDestination names where CreateKey delivers a raw key. When set on
+// [CreateKeyInput], the raw key is written to Sink at Path and the returned
+// CreateResult carries Path in place of the secret.
+type SecretDestination struct {
+	Sink SecretSink
+	Path string
+}
+
+// DeliveryFailureMode selects what CreateKey does with a key whose raw value
+// could not be delivered.
+type DeliveryFailureMode int
+
+const (
+	// DeliveryRollback deletes the key, or revokes it if it cannot be
+	// deleted, and returns an error. This is the default: no working key
+	// exists that nobody holds.
+	DeliveryRollback DeliveryFailureMode = iota
+
+	// DeliverySuspend keeps the key in the suspended state and returns the
+	// CreateResult, raw key included, together with an error wrapping
+	// ErrDeliveryFailed. An operator can deliver the key by hand and then
+	// reactivate it. A key that cannot be suspended is rolled back.
+	DeliverySuspend
+)
+
+// deliverKey writes the raw key to dest. On failure it applies the engine's
+// DeliveryFailureMode and returns the error to hand back from CreateKey. A
+// key that cannot be suspended is rolled back as with DeliveryRollback.
+func (e *Engine) deliverKey(ctx context.Context, k *key.Key, rawKey string, dest *SecretDestination) error {
+	err := dest.Sink.Put(ctx, dest.Path, rawKey)
+	if err == nil {
+		return nil
+	}
+	err = fmt.Errorf("%w: %s: %w", ErrDeliveryFailed, dest.Path, err)
+
+	if e.deliveryFailure == DeliverySuspend {
+		// The sink often fails because ctx is done, so the suspension
+		// must not depend on it.
+		sctx, cancel := detachedContext(ctx, false)
+		stErr := e.store.Keys().UpdateState(sctx, k.ID, key.StateSuspended)
+		cancel()
+		if stErr == nil {
+			k.State = key.StateSuspended
+			e.invalidateKey(k.ID)
+			e.recordRevocation(ctx, k, key.StateSuspended, true)
+			return err
+		}
+		// A key that cannot be suspended is rolled back instead, so that
+		// it never validates.
+		err = errors.Join(err, fmt.Errorf("suspend key: %w", stErr))
+	}
+
+	e.rollbackCreate(ctx, k)
+	e.bumpRevision(ctx, k.TenantID)
+	_ = e.hooks.FireKeyCreateFailed(ctx, k, err, e.eventMeta(ctx, plugin.TriggerManual, plugin.ReasonDeliveryFailed))
+	return err
+}
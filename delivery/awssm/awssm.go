@@ -0,0 +1,83 @@
+// Package awssm delivers raw API keys to AWS Secrets Manager.
+// It defines a local Client interface so the package does not import the AWS
+// SDK directly; adapt *secretsmanager.Client from aws-sdk-go-v2 in a few
+// lines:
+//
+//	type smClient struct{ c *secretsmanager.Client }
+//
+//	func (a smClient) CreateSecret(ctx context.Context, name, secret string) error {
+//		_, err := a.c.CreateSecret(ctx, &secretsmanager.CreateSecretInput{
+//			Name: &name, SecretString: &secret,
+//		})
+//		var exists *types.ResourceExistsException
+//		if errors.As(err, &exists) {
+//			return awssm.ErrSecretExists
+//		}
+//		return err
+//	}
+//
+//	func (a smClient) PutSecretValue(ctx context.Context, secretID, secret string) error {
+//		_, err := a.c.PutSecretValue(ctx, &secretsmanager.PutSecretValueInput{
+//			SecretId: &secretID, SecretString: &secret,
+//		})
+//		return err
+//	}
+package awssm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/xraph/keysmith"
+)
+
+// Compile-time interface check.
+var _ keysmith.SecretSink = (*Sink)(nil)
+
+// ErrSecretExists is returned by Client.CreateSecret when a secret with the
+// name already exists.
+var ErrSecretExists = errors.New("keysmith/awssm: secret already exists")
+
+// Client is the subset of the Secrets Manager API the sink uses.
+type Client interface {
+	CreateSecret(ctx context.Context, name, secret string) error
+	PutSecretValue(ctx context.Context, secretID, secret string) error
+}
+
+// Sink writes secrets to AWS Secrets Manager.
+type Sink struct {
+	client Client
+	prefix string
+}
+
+// Option configures a Sink.
+type Option func(*Sink)
+
+// WithNamePrefix prepends prefix to every secret name, e.g. "prod/keysmith/".
+func WithNamePrefix(prefix string) Option {
+	return func(s *Sink) { s.prefix = prefix }
+}
+
+// New creates a Sink backed by c.
+func New(c Client, opts ...Option) *Sink {
+	s := &Sink{client: c}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Put implements keysmith.SecretSink. It creates the secret, or stores a new
+// version when a secret with the name already exists.
+func (s *Sink) Put(ctx context.Context, path string, secret string) error {
+	name := s.prefix + path
+	err := s.client.CreateSecret(ctx, name, secret)
+	if errors.Is(err, ErrSecretExists) {
+		err = s.client.PutSecretValue(ctx, name, secret)
+	}
+	if err != nil {
+		return fmt.Errorf("keysmith/awssm: put %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package awssm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/delivery/awssm"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+// fakeClient is an in-memory Secrets Manager. Each secret keeps its
+// versions in write order. createErr and putErr, when set, fail the
+// matching call.
+type fakeClient struct {
+	secrets   map[string][]string
+	creates   int
+	puts      int
+	createErr error
+	putErr    error
+}
+
+func (c *fakeClient) CreateSecret(_ context.Context, name, secret string) error {
+	c.creates++
+	if c.createErr != nil {
+		return c.createErr
+	}
+	if _, ok := c.secrets[name]; ok {
+		return awssm.ErrSecretExists
+	}
+	if c.secrets == nil {
+		c.secrets = make(map[string][]string)
+	}
+	c.secrets[name] = []string{secret}
+	return nil
+}
+
+func (c *fakeClient) PutSecretValue(_ context.Context, secretID, secret string) error {
+	c.puts++
+	if c.putErr != nil {
+		return c.putErr
+	}
+	if _, ok := c.secrets[secretID]; !ok {
+		return errors.New("ResourceNotFoundException")
+	}
+	c.secrets[secretID] = append(c.secrets[secretID], secret)
+	return nil
+}
+
+func TestSink_PutCreatesSecret(t *testing.T) {
+	client := &fakeClient{}
+	sink := awssm.New(client, awssm.WithNamePrefix("prod/keysmith/"))
+
+	require.NoError(t, sink.Put(context.Background(), "payments/api-key", "sk_live_secret"))
+	assert.Equal(t, []string{"sk_live_secret"}, client.secrets["prod/keysmith/payments/api-key"])
+	assert.Zero(t, client.puts, "a new secret is created, not updated")
+}
+
+func TestSink_PutExistingSecretAddsVersion(t *testing.T) {
+	client := &fakeClient{}
+	sink := awssm.New(client)
+
+	require.NoError(t, sink.Put(context.Background(), "payments/api-key", "sk_live_first"))
+	require.NoError(t, sink.Put(context.Background(), "payments/api-key", "sk_live_second"))
+	assert.Equal(t, []string{"sk_live_first", "sk_live_second"}, client.secrets["payments/api-key"])
+	assert.Equal(t, 1, client.puts)
+}
+
+func TestSink_PutError(t *testing.T) {
+	denied := errors.New("AccessDeniedException")
+	tests := map[string]*fakeClient{
+		"create": {createErr: denied},
+		"put":    {secrets: map[string][]string{"payments/api-key": {"sk_live_old"}}, putErr: denied},
+	}
+	for name, client := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := awssm.New(client).Put(context.Background(), "payments/api-key", "sk_live_secret")
+			require.ErrorIs(t, err, denied)
+			assert.Contains(t, err.Error(), "payments/api-key")
+			assert.NotContains(t, err.Error(), "sk_live_secret")
+		})
+	}
+}
+
+func TestSink_EngineDelivery(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()))
+	require.NoError(t, err)
+	ctx := keysmith.WithTenant(context.Background(), "app_test", "tenant_test")
+	client := &fakeClient{}
+
+	result, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
+		Name:        "Delivered Key",
+		Prefix:      "sk",
+		Environment: key.EnvLive,
+		DeliverTo:   &keysmith.SecretDestination{Sink: awssm.New(client), Path: "payments/api-key"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "payments/api-key", result.DeliveredTo)
+
+	versions := client.secrets["payments/api-key"]
+	require.Len(t, versions, 1)
+	_, err = eng.ValidateKey(ctx, versions[0])
+	assert.NoError(t, err, "the delivered secret is the working key")
+}
+
+func TestSink_EngineRollsBackOnFailure(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()))
+	require.NoError(t, err)
+	ctx := keysmith.WithTenant(context.Background(), "app_test", "tenant_test")
+	denied := errors.New("AccessDeniedException")
+
+	result, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
+		Name:        "Delivered Key",
+		Prefix:      "sk",
+		Environment: key.EnvLive,
+		DeliverTo:   &keysmith.SecretDestination{Sink: awssm.New(&fakeClient{createErr: denied}), Path: "payments/api-key"},
+	})
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, keysmith.ErrDeliveryFailed)
+	assert.ErrorIs(t, err, denied)
+
+	keys, err := eng.ListKeys(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, keys, "the undelivered key is rolled back")
+}
@@ -0,0 +1,111 @@
+// Package vault delivers raw API keys to a HashiCorp Vault KV version 2
+// secrets engine. It talks to Vault's HTTP API directly, so it adds no
+// dependencies to keysmith.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/xraph/keysmith"
+)
+
+// Compile-time interface check.
+var _ keysmith.SecretSink = (*Sink)(nil)
+
+const (
+	defaultMount = "secret"
+	defaultField = "api_key"
+)
+
+// Sink writes secrets to a Vault KV v2 mount.
+type Sink struct {
+	address   string
+	token     string
+	mount     string
+	field     string
+	namespace string
+	client    *http.Client
+}
+
+// Option configures a Sink.
+type Option func(*Sink)
+
+// WithMount sets the KV v2 mount path. Defaults to "secret".
+func WithMount(mount string) Option {
+	return func(s *Sink) { s.mount = strings.Trim(mount, "/") }
+}
+
+// WithField sets the field of the secret's data map that holds the raw key.
+// Defaults to "api_key".
+func WithField(field string) Option {
+	return func(s *Sink) { s.field = field }
+}
+
+// WithNamespace sets the Vault Enterprise namespace.
+func WithNamespace(ns string) Option {
+	return func(s *Sink) { s.namespace = ns }
+}
+
+// WithHTTPClient sets the HTTP client. Defaults to http.DefaultClient.
+func WithHTTPClient(c *http.Client) Option {
+	return func(s *Sink) { s.client = c }
+}
+
+// New creates a Sink for the Vault server at address, authenticating with
+// token.
+func New(address, token string, opts ...Option) *Sink {
+	s := &Sink{
+		address: strings.TrimRight(address, "/"),
+		token:   token,
+		mount:   defaultMount,
+		field:   defaultField,
+		client:  http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Put implements keysmith.SecretSink. It writes a new version of the secret
+// at path, storing the raw key under the configured field.
+func (s *Sink) Put(ctx context.Context, path string, secret string) error {
+	body, err := json.Marshal(map[string]any{
+		"data": map[string]string{s.field: secret},
+	})
+	if err != nil {
+		return fmt.Errorf("keysmith/vault: encode secret: %w", err)
+	}
+
+	url := s.address + "/v1/" + s.mount + "/data/" + strings.TrimLeft(path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("keysmith/vault: build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", s.token)
+	if s.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.namespace)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("keysmith/vault: write %s: %w", path, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var vErr struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&vErr)
+		return fmt.Errorf("keysmith/vault: write %s: status %d: %s", path, resp.StatusCode, strings.Join(vErr.Errors, "; "))
+	}
+	return nil
+}
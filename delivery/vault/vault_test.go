@@ -0,0 +1,46 @@
+package vault_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith/delivery/vault"
+)
+
+func TestSink_Put(t *testing.T) {
+	var gotPath, gotToken string
+	var gotBody map[string]map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotToken = r.Header.Get("X-Vault-Token")
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	sink := vault.New(srv.URL, "s.token", vault.WithMount("kv"), vault.WithField("key"))
+	require.NoError(t, sink.Put(context.Background(), "payments/api-key", "sk_live_secret"))
+
+	assert.Equal(t, "/v1/kv/data/payments/api-key", gotPath)
+	assert.Equal(t, "s.token", gotToken)
+	assert.Equal(t, "sk_live_secret", gotBody["data"]["key"])
+}
+
+func TestSink_PutError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+	}))
+	defer srv.Close()
+
+	err := vault.New(srv.URL, "bad").Put(context.Background(), "payments/api-key", "sk_live_secret")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "permission denied")
+	assert.NotContains(t, err.Error(), "sk_live_secret")
+}
@@ -0,0 +1,206 @@
+package keysmith_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	audithook "github.com/xraph/keysmith/audit_hook"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store/chaos"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+// fakeSink records delivered secrets, or fails every write when err is set.
+type fakeSink struct {
+	secrets map[string]string
+	err     error
+}
+
+func (s *fakeSink) Put(_ context.Context, path string, secret string) error {
+	if s.err != nil {
+		return s.err
+	}
+	if s.secrets == nil {
+		s.secrets = make(map[string]string)
+	}
+	s.secrets[path] = secret
+	return nil
+}
+
+// auditCapture keeps the audit events it is given. Hooks may record from
+// other goroutines, so events is guarded by mu.
+type auditCapture struct {
+	mu     sync.Mutex
+	events []*audithook.AuditEvent
+}
+
+func (r *auditCapture) Record(_ context.Context, evt *audithook.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, evt)
+	return nil
+}
+
+func (r *auditCapture) recorded() []*audithook.AuditEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.events)
+}
+
+func (r *auditCapture) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = nil
+}
+
+func newDeliveryEngine(t *testing.T, opts ...keysmith.Option) (*keysmith.Engine, *auditCapture) {
+	t.Helper()
+	audit := &auditCapture{}
+	opts = append([]keysmith.Option{
+		keysmith.WithStore(memory.New()),
+		keysmith.WithExtension(audithook.New(audit)),
+	}, opts...)
+	eng, err := keysmith.NewEngine(opts...)
+	require.NoError(t, err)
+	return eng, audit
+}
+
+func deliveryInput(sink keysmith.SecretSink) *keysmith.CreateKeyInput {
+	return &keysmith.CreateKeyInput{
+		Name:        "Delivered Key",
+		Prefix:      "sk",
+		Environment: key.EnvLive,
+		TenantID:    "tenant_test",
+		DeliverTo:   &keysmith.SecretDestination{Sink: sink, Path: "payments/api-key"},
+	}
+}
+
+func TestDelivery_RedactsRawKey(t *testing.T) {
+	eng, audit := newDeliveryEngine(t)
+	sink := &fakeSink{}
+
+	result, err := eng.CreateKey(testCtx(), deliveryInput(sink))
+	require.NoError(t, err)
+	assert.Equal(t, "payments/api-key", result.RawKey)
+	assert.Equal(t, "payments/api-key", result.DeliveredTo)
+
+	secret := sink.secrets["payments/api-key"]
+	require.NotEmpty(t, secret)
+	_, err = eng.ValidateKey(testCtx(), secret)
+	assert.NoError(t, err, "the delivered secret is the working key")
+
+	events := audit.recorded()
+	require.Len(t, events, 2) // created, validated
+	evt := events[0]
+	assert.Equal(t, audithook.ActionKeyCreated, evt.Action)
+	assert.Equal(t, "payments/api-key", evt.Metadata["delivered_to"])
+	for _, v := range evt.Metadata {
+		assert.NotEqual(t, secret, v, "audit events must not carry the secret")
+	}
+}
+
+func TestDelivery_RollbackOnFailure(t *testing.T) {
+	eng, audit := newDeliveryEngine(t)
+	sinkErr := errors.New("vault sealed")
+
+	result, err := eng.CreateKey(testCtx(), deliveryInput(&fakeSink{err: sinkErr}))
+	require.Error(t, err)
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, keysmith.ErrDeliveryFailed)
+	assert.ErrorIs(t, err, sinkErr)
+
+	keys, err := eng.ListKeys(testCtx(), &key.ListFilter{TenantID: "tenant_test"})
+	require.NoError(t, err)
+	assert.Empty(t, keys, "the undelivered key is rolled back")
+
+	events := audit.recorded()
+	require.Len(t, events, 1)
+	assert.Equal(t, audithook.ActionKeyCreateFailed, events[0].Action)
+	assert.Equal(t, "payments/api-key", events[0].Metadata["delivered_to"])
+}
+
+// timeoutSink keeps the secret it was handed, then cancels the request's
+// context and fails with its error, as a sink timing out would.
+type timeoutSink struct {
+	cancel context.CancelFunc
+	secret string
+}
+
+func (s *timeoutSink) Put(ctx context.Context, _ string, secret string) error {
+	s.secret = secret
+	s.cancel()
+	return ctx.Err()
+}
+
+func TestDelivery_FailureNeverLeavesAWorkingKey(t *testing.T) {
+	tests := map[string]struct {
+		mode   keysmith.DeliveryFailureMode
+		method string
+		state  key.State
+	}{
+		"rollback":                 {mode: keysmith.DeliveryRollback},
+		"rollback, undeletable":    {mode: keysmith.DeliveryRollback, method: "Delete", state: key.StateRevoked},
+		"suspend, unsuspendable":   {mode: keysmith.DeliverySuspend, method: "UpdateState"},
+		"suspend after a time-out": {mode: keysmith.DeliverySuspend, state: key.StateSuspended},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cs := chaos.New(memory.New())
+			if tt.method != "" {
+				require.NoError(t, cs.Set(chaos.Rule{Name: "fail", Store: chaos.StoreKeys, Method: tt.method, Error: chaos.ErrorInjected}))
+			}
+			eng, _ := newDeliveryEngine(t, keysmith.WithStore(cs), keysmith.WithDeliveryFailureMode(tt.mode))
+			ctx, cancel := context.WithCancel(testCtx())
+			defer cancel()
+			sink := &timeoutSink{cancel: cancel}
+
+			_, err := eng.CreateKey(ctx, deliveryInput(sink))
+			assert.ErrorIs(t, err, keysmith.ErrDeliveryFailed)
+			assert.ErrorIs(t, err, context.Canceled)
+
+			_, err = eng.ValidateKey(testCtx(), sink.secret)
+			assert.Error(t, err, "the undelivered key does not validate")
+			keys, err := eng.ListKeys(testCtx(), &key.ListFilter{TenantID: "tenant_test"})
+			require.NoError(t, err)
+			if tt.state == "" {
+				assert.Empty(t, keys, "the undelivered key is deleted")
+				return
+			}
+			require.Len(t, keys, 1)
+			assert.Equal(t, tt.state, keys[0].State)
+		})
+	}
+}
+
+func TestDelivery_SuspendOnFailure(t *testing.T) {
+	eng, _ := newDeliveryEngine(t, keysmith.WithDeliveryFailureMode(keysmith.DeliverySuspend))
+
+	result, err := eng.CreateKey(testCtx(), deliveryInput(&fakeSink{err: errors.New("timeout")}))
+	assert.ErrorIs(t, err, keysmith.ErrDeliveryFailed)
+	require.NotNil(t, result)
+	require.NotEmpty(t, result.RawKey)
+	assert.Empty(t, result.DeliveredTo)
+	assert.Equal(t, key.StateSuspended, result.Key.State)
+
+	_, err = eng.ValidateKey(testCtx(), result.RawKey)
+	assert.Error(t, err, "a suspended key does not validate")
+
+	require.NoError(t, eng.ReactivateKey(testCtx(), result.Key.ID))
+	_, err = eng.ValidateKey(testCtx(), result.RawKey)
+	assert.NoError(t, err)
+}
+
+func TestDelivery_InvalidDestination(t *testing.T) {
+	eng, _ := newDeliveryEngine(t)
+	input := deliveryInput(nil)
+
+	_, err := eng.CreateKey(testCtx(), input)
+	assert.ErrorIs(t, err, keysmith.ErrDeliveryFailed)
+}
@@ -0,0 +1,20 @@
+package keysmith
+
+import "context"
+
+type ctxKeyDryRun struct{}
+
+// WithDryRun marks ctx so that destructive engine operations — RevokeKey,
+// RotateKey, SuspendKey, ReportCompromise, BulkRevokeByCreator,
+// CleanupExpiredKeys, and CleanupGraceExpired — perform their reads and
+// validations and return the outcome they would produce, without writing to
+// the store or firing hooks. A dry-run rotation returns no raw key.
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxKeyDryRun{}, true)
+}
+
+// IsDryRun reports whether ctx was marked with [WithDryRun].
+func IsDryRun(ctx context.Context) bool {
+	v, _ := ctx.Value(ctxKeyDryRun{}).(bool)
+	return v
+}
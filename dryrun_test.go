@@ -0,0 +1,179 @@
+package keysmith_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/store/chaos"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+// newDryRunEngine returns an engine on a chaos store, for forbidWrites and
+// assertNoWrites to watch.
+func newDryRunEngine(t *testing.T, opts ...keysmith.Option) (*keysmith.Engine, *chaos.Store, *hookRecorder) {
+	t.Helper()
+	cs := chaos.New(memory.New())
+	rec := &hookRecorder{}
+	opts = append([]keysmith.Option{keysmith.WithStore(cs), keysmith.WithExtension(rec)}, opts...)
+	eng, err := keysmith.NewEngine(opts...)
+	require.NoError(t, err)
+	return eng, cs, rec
+}
+
+// forbidWrites makes cs fail every write, to every sub-store, and record it.
+func forbidWrites(t *testing.T, cs *chaos.Store) {
+	t.Helper()
+	cs.ResetInjections()
+	require.NoError(t, cs.Set(chaos.Rule{Name: "no-writes", Writes: true, Error: chaos.ErrorInjected}))
+}
+
+// assertNoWrites checks that nothing was written since forbidWrites, and
+// allows writes again.
+func assertNoWrites(t *testing.T, cs *chaos.Store) {
+	t.Helper()
+	assert.Empty(t, cs.Injections(), "a dry run writes nothing")
+	cs.Clear()
+}
+
+func createDryRunKey(t *testing.T, eng *keysmith.Engine, expiresAt *time.Time) *key.CreateResult {
+	t.Helper()
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+		Name:        "Dry Run Key",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+		ExpiresAt:   expiresAt,
+	})
+	require.NoError(t, err)
+	return created
+}
+
+func TestDryRun_Context(t *testing.T) {
+	assert.False(t, keysmith.IsDryRun(context.Background()))
+	assert.True(t, keysmith.IsDryRun(keysmith.WithDryRun(context.Background())))
+}
+
+func TestDryRun_RevokeKey(t *testing.T) {
+	eng, cs, rec := newDryRunEngine(t)
+	created := createDryRunKey(t, eng, nil)
+	forbidWrites(t, cs)
+
+	require.NoError(t, eng.RevokeKey(keysmith.WithDryRun(testCtx()), created.Key.ID, "cleanup"))
+	assertNoWrites(t, cs)
+	assert.Empty(t, rec.events)
+
+	k, err := eng.GetKey(testCtx(), created.Key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, key.StateActive, k.State)
+
+	// Validation still runs.
+	err = eng.RevokeKey(keysmith.WithDryRun(testCtx()), id.NewKeyID(), "cleanup")
+	assert.Error(t, err)
+}
+
+func TestDryRun_SuspendKey(t *testing.T) {
+	eng, cs, _ := newDryRunEngine(t)
+	created := createDryRunKey(t, eng, nil)
+	forbidWrites(t, cs)
+
+	require.NoError(t, eng.SuspendKey(keysmith.WithDryRun(testCtx()), created.Key.ID))
+	assertNoWrites(t, cs)
+
+	_, err := eng.ValidateKey(testCtx(), created.RawKey)
+	assert.NoError(t, err)
+
+	err = eng.SuspendKey(keysmith.WithDryRun(testCtx()), id.NewKeyID())
+	assert.Error(t, err)
+}
+
+func TestDryRun_RotateKey(t *testing.T) {
+	eng, cs, rec := newDryRunEngine(t)
+	created := createDryRunKey(t, eng, nil)
+	forbidWrites(t, cs)
+
+	result, err := eng.RotateKey(keysmith.WithDryRun(testCtx()), created.Key.ID, rotation.ReasonManual)
+	require.NoError(t, err)
+	assert.Empty(t, result.RawKey, "a dry-run rotation must not hand out a secret")
+	assert.NotNil(t, result.Key.RotatedAt)
+	assertNoWrites(t, cs)
+	assert.Empty(t, rec.events)
+
+	_, err = eng.ValidateKey(testCtx(), created.RawKey)
+	assert.NoError(t, err)
+}
+
+func TestDryRun_ReportCompromise(t *testing.T) {
+	for _, action := range []key.CompromiseAction{key.CompromiseRevoke, key.CompromiseRotate} {
+		t.Run(string(action), func(t *testing.T) {
+			eng, cs, rec := newDryRunEngine(t)
+			created := createDryRunKey(t, eng, nil)
+			forbidWrites(t, cs)
+
+			result, err := eng.ReportCompromise(keysmith.WithDryRun(testCtx()), created.Key.ID, &key.CompromiseReport{
+				Source: "scanner",
+				Action: action,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, action, result.Action)
+			assert.Empty(t, result.RawKey)
+			assertNoWrites(t, cs)
+			assert.Empty(t, rec.events)
+
+			k, err := eng.GetKey(testCtx(), created.Key.ID)
+			require.NoError(t, err)
+			assert.NotContains(t, k.Metadata, keysmith.MetadataCompromise)
+		})
+	}
+}
+
+func TestDryRun_CleanupExpiredKeys(t *testing.T) {
+	eng, cs, _ := newDryRunEngine(t)
+	past := time.Now().Add(-time.Hour)
+	expired := createDryRunKey(t, eng, &past)
+	createDryRunKey(t, eng, nil)
+	forbidWrites(t, cs)
+
+	res, err := eng.CleanupExpiredKeys(keysmith.WithDryRun(testCtx()))
+	require.NoError(t, err)
+	assert.True(t, res.DryRun)
+	assert.Equal(t, []id.KeyID{expired.Key.ID}, res.KeyIDs)
+	assertNoWrites(t, cs)
+
+	k, err := eng.GetKey(testCtx(), expired.Key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, key.StateActive, k.State)
+
+	// The real run changes exactly what the dry run reported.
+	res, err = eng.CleanupExpiredKeys(testCtx())
+	require.NoError(t, err)
+	assert.False(t, res.DryRun)
+	assert.Equal(t, []id.KeyID{expired.Key.ID}, res.KeyIDs)
+}
+
+func TestDryRun_CleanupGraceExpired(t *testing.T) {
+	clock := &fakeClock{t: time.Now()}
+	eng, cs, _ := newDryRunEngine(t, keysmith.WithClock(clock.Now))
+	created := createDryRunKey(t, eng, nil)
+	_, err := eng.RotateKey(testCtx(), created.Key.ID, rotation.ReasonManual)
+	require.NoError(t, err)
+	clock.Set(clock.Now().Add(25 * time.Hour))
+	forbidWrites(t, cs)
+
+	res, err := eng.CleanupGraceExpired(keysmith.WithDryRun(testCtx()))
+	require.NoError(t, err)
+	assert.True(t, res.DryRun)
+	assert.Equal(t, []id.KeyID{created.Key.ID}, res.KeyIDs)
+	assertNoWrites(t, cs)
+
+	// The real run retires what the dry run reported.
+	res, err = eng.CleanupGraceExpired(testCtx())
+	require.NoError(t, err)
+	assert.Equal(t, []id.KeyID{created.Key.ID}, res.KeyIDs)
+}
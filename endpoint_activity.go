@@ -0,0 +1,263 @@
+package keysmith
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/xraph/go-utils/log"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/usage"
+)
+
+const (
+	// DefaultMaxEndpointsPerKey is the number of distinct endpoints tracked
+	// per key before further endpoints are folded into the overflow bucket.
+	DefaultMaxEndpointsPerKey = 100
+
+	// DefaultEndpointFlushInterval is how often buffered endpoint activity is
+	// written to the store.
+	DefaultEndpointFlushInterval = 10 * time.Second
+
+	// endpointFlushThreshold triggers an early flush once this many distinct
+	// (key, endpoint, method) entries are buffered.
+	endpointFlushThreshold = 1000
+)
+
+type endpointKey struct {
+	keyID    string
+	endpoint string
+	method   string
+}
+
+// endpointTracker buffers per-endpoint usage deltas in memory so recording a
+// request does not cost an extra write. Deltas are upserted in batches by
+// flush, which resolves the per-key cap against what is already stored.
+type endpointTracker struct {
+	maxPerKey int
+	interval  time.Duration
+
+	mu      sync.Mutex
+	pending map[endpointKey]*usage.EndpointActivity
+	perKey  map[string]int // keyID -> distinct non-overflow entries pending
+
+	flushMu sync.Mutex
+
+	// flusher flushes every interval while the engine runs. NewEngine sets
+	// it up.
+	flusher *periodicJob
+}
+
+func newEndpointTracker(maxPerKey int, interval time.Duration) *endpointTracker {
+	return &endpointTracker{
+		maxPerKey: maxPerKey,
+		interval:  interval,
+		pending:   make(map[endpointKey]*usage.EndpointActivity),
+		perKey:    make(map[string]int),
+	}
+}
+
+// add buffers one request and reports whether the buffer is due a flush.
+func (t *endpointTracker) add(keyID id.KeyID, endpoint, method string, at time.Time) bool {
+	kid := keyID.String()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	k := endpointKey{keyID: kid, endpoint: endpoint, method: method}
+	if _, ok := t.pending[k]; !ok && t.perKey[kid] >= t.maxPerKey {
+		k = endpointKey{keyID: kid, endpoint: usage.OverflowEndpoint, method: usage.OverflowMethod}
+	}
+
+	a, ok := t.pending[k]
+	if !ok {
+		a = &usage.EndpointActivity{KeyID: keyID, Endpoint: k.endpoint, Method: k.method}
+		t.pending[k] = a
+		if !a.IsOverflow() {
+			t.perKey[kid]++
+		}
+	}
+	a.Count++
+	if at.After(a.LastSeenAt) {
+		a.LastSeenAt = at
+	}
+	return len(t.pending) >= endpointFlushThreshold
+}
+
+// take removes and returns buffered entries, all of them when keyID is nil.
+func (t *endpointTracker) take(keyID *id.KeyID) []*usage.EndpointActivity {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var out []*usage.EndpointActivity
+	for k, a := range t.pending {
+		if keyID != nil && k.keyID != keyID.String() {
+			continue
+		}
+		out = append(out, a)
+		delete(t.pending, k)
+		delete(t.perKey, k.keyID)
+	}
+	return out
+}
+
+// restore merges entries that failed to flush back into the buffer.
+func (t *endpointTracker) restore(acts []*usage.EndpointActivity) {
+	for _, a := range acts {
+		t.mu.Lock()
+		k := endpointKey{keyID: a.KeyID.String(), endpoint: a.Endpoint, method: a.Method}
+		if cur, ok := t.pending[k]; ok {
+			cur.Count += a.Count
+			if a.LastSeenAt.After(cur.LastSeenAt) {
+				cur.LastSeenAt = a.LastSeenAt
+			}
+		} else {
+			t.pending[k] = a
+			if !a.IsOverflow() {
+				t.perKey[k.keyID]++
+			}
+		}
+		t.mu.Unlock()
+	}
+}
+
+// flush writes buffered entries to us, folding endpoints beyond the per-key
+// cap into the overflow bucket. The cap is checked against stored rows at
+// flush time, so concurrent flushers from several processes may briefly
+// exceed it by a few entries.
+func (t *endpointTracker) flush(ctx context.Context, us usage.Store, keyID *id.KeyID) error {
+	t.flushMu.Lock()
+	defer t.flushMu.Unlock()
+
+	acts := t.take(keyID)
+	if len(acts) == 0 {
+		return nil
+	}
+
+	byKey := make(map[string][]*usage.EndpointActivity)
+	for _, a := range acts {
+		byKey[a.KeyID.String()] = append(byKey[a.KeyID.String()], a)
+	}
+
+	batch := make([]*usage.EndpointActivity, 0, len(acts))
+	for _, group := range byKey {
+		capped, err := t.applyCap(ctx, us, group)
+		if err != nil {
+			t.restore(acts)
+			return err
+		}
+		batch = append(batch, capped...)
+	}
+
+	if err := us.UpsertEndpointActivity(ctx, batch); err != nil {
+		t.restore(batch)
+		return err
+	}
+	return nil
+}
+
+// applyCap admits new endpoints for one key while it is under the cap and
+// merges the rest into a single overflow entry.
+func (t *endpointTracker) applyCap(ctx context.Context, us usage.Store, group []*usage.EndpointActivity) ([]*usage.EndpointActivity, error) {
+	stored, err := us.ListEndpointActivity(ctx, group[0].KeyID)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[endpointKey]bool, len(stored))
+	distinct := 0
+	for _, a := range stored {
+		known[endpointKey{endpoint: a.Endpoint, method: a.Method}] = true
+		if !a.IsOverflow() {
+			distinct++
+		}
+	}
+
+	out := make([]*usage.EndpointActivity, 0, len(group))
+	var overflow *usage.EndpointActivity
+	for _, a := range group {
+		if !a.IsOverflow() {
+			k := endpointKey{endpoint: a.Endpoint, method: a.Method}
+			if !known[k] && distinct < t.maxPerKey {
+				known[k] = true
+				distinct++
+			}
+			if known[k] {
+				out = append(out, a)
+				continue
+			}
+		}
+		if overflow == nil {
+			overflow = &usage.EndpointActivity{KeyID: a.KeyID, Endpoint: usage.OverflowEndpoint, Method: usage.OverflowMethod}
+		}
+		overflow.Count += a.Count
+		if a.LastSeenAt.After(overflow.LastSeenAt) {
+			overflow.LastSeenAt = a.LastSeenAt
+		}
+	}
+	if overflow != nil {
+		out = append(out, overflow)
+	}
+	return out, nil
+}
+
+func (t *endpointTracker) start() {
+	if t.flusher != nil {
+		t.flusher.start()
+	}
+}
+
+func (t *endpointTracker) shutdown() {
+	if t.flusher != nil {
+		t.flusher.shutdown()
+	}
+}
+
+// trackEndpoint buffers rec's endpoint for last-seen analytics.
+func (e *Engine) trackEndpoint(ctx context.Context, rec *usage.Record) {
+	if e.endpoints == nil || rec.KeyID.IsNil() || !e.writes.add() {
+		return
+	}
+	defer e.writes.done()
+	if e.endpoints.add(rec.KeyID, usage.NormalizeEndpoint(rec), rec.Method, rec.CreatedAt) {
+		e.flushEndpointActivity(ctx, nil)
+	}
+}
+
+func (e *Engine) flushEndpointActivity(ctx context.Context, keyID *id.KeyID) {
+	if e.checkUsageWritable() != nil {
+		return // kept buffered until read-only mode ends
+	}
+	if err := e.endpoints.flush(ctx, e.store.Usages(), keyID); err != nil {
+		e.logger.Warn("failed to flush endpoint activity", log.Any("error", err))
+	}
+}
+
+// FlushEndpointActivity writes buffered endpoint activity to the store. It is
+// called on a timer after Start and once more by Stop; call it directly when
+// running without Start, e.g., in tests or short-lived jobs.
+func (e *Engine) FlushEndpointActivity(ctx context.Context) error {
+	if e.endpoints == nil {
+		return nil
+	}
+	if err := e.checkUsageWritable(); err != nil {
+		return err
+	}
+	return e.endpoints.flush(ctx, e.store.Usages(), nil)
+}
+
+// ListEndpointActivity returns the last-seen time and request count for each
+// endpoint a key has called, most recent first. Endpoints beyond the per-key
+// cap are reported as a single [usage.OverflowEndpoint] entry. Buffered
+// activity for the key is flushed first, so results are current.
+func (e *Engine) ListEndpointActivity(ctx context.Context, keyID id.KeyID) ([]*usage.EndpointActivity, error) {
+	if err := e.checkKey(ctx, keyID); err != nil {
+		return nil, err
+	}
+	if e.endpoints != nil && e.checkUsageWritable() == nil {
+		if err := e.endpoints.flush(ctx, e.store.Usages(), &keyID); err != nil {
+			return nil, err
+		}
+	}
+	return e.store.Usages().ListEndpointActivity(ctx, keyID)
+}
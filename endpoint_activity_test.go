@@ -0,0 +1,162 @@
+package keysmith_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store/memory"
+	"github.com/xraph/keysmith/usage"
+)
+
+func newEndpointEngine(t *testing.T, maxPerKey int) (*keysmith.Engine, *memory.Store, id.KeyID) {
+	t.Helper()
+	ms := memory.New()
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(ms),
+		keysmith.WithEndpointActivity(maxPerKey, 0),
+	)
+	require.NoError(t, err)
+
+	result, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+		Name:        "Endpoint Test",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+	})
+	require.NoError(t, err)
+	return eng, ms, result.Key.ID
+}
+
+func recordCall(t *testing.T, eng *keysmith.Engine, keyID id.KeyID, method, endpoint, route string) {
+	t.Helper()
+	require.NoError(t, eng.RecordUsage(testCtx(), &usage.Record{
+		KeyID:      keyID,
+		TenantID:   "tenant_test",
+		Endpoint:   endpoint,
+		Route:      route,
+		Method:     method,
+		StatusCode: 200,
+	}))
+}
+
+func findActivity(acts []*usage.EndpointActivity, method, endpoint string) *usage.EndpointActivity {
+	for _, a := range acts {
+		if a.Method == method && a.Endpoint == endpoint {
+			return a
+		}
+	}
+	return nil
+}
+
+func TestEndpointActivity_Counts(t *testing.T) {
+	eng, _, kid := newEndpointEngine(t, 10)
+
+	recordCall(t, eng, kid, "POST", "/webhooks", "")
+	recordCall(t, eng, kid, "POST", "/webhooks", "")
+	recordCall(t, eng, kid, "GET", "/webhooks", "")
+
+	acts, err := eng.ListEndpointActivity(testCtx(), kid)
+	require.NoError(t, err)
+	require.Len(t, acts, 2)
+	assert.Equal(t, int64(2), findActivity(acts, "POST", "/webhooks").Count)
+	assert.Equal(t, int64(1), findActivity(acts, "GET", "/webhooks").Count)
+
+	// Counts keep accumulating across flushes.
+	recordCall(t, eng, kid, "POST", "/webhooks", "")
+	acts, err = eng.ListEndpointActivity(testCtx(), kid)
+	require.NoError(t, err)
+	post := findActivity(acts, "POST", "/webhooks")
+	assert.Equal(t, int64(3), post.Count)
+	assert.WithinDuration(t, time.Now(), post.LastSeenAt, time.Second)
+}
+
+func TestEndpointActivity_Normalization(t *testing.T) {
+	eng, _, kid := newEndpointEngine(t, 10)
+
+	recordCall(t, eng, kid, "GET", "/users/123?expand=true", "")
+	recordCall(t, eng, kid, "GET", "/users/9b2f6c1e-3d4a-4b5c-8d9e-0f1a2b3c4d5e", "")
+	recordCall(t, eng, kid, "GET", "/keys/akey_01h2xcejqtf2nbrexx3vqjhp41/usage", "")
+	recordCall(t, eng, kid, "GET", "/orgs/acme/projects/web", "/orgs/:org/projects/:project")
+
+	acts, err := eng.ListEndpointActivity(testCtx(), kid)
+	require.NoError(t, err)
+	require.Len(t, acts, 3)
+	assert.Equal(t, int64(2), findActivity(acts, "GET", "/users/:id").Count)
+	assert.NotNil(t, findActivity(acts, "GET", "/keys/:id/usage"))
+	assert.NotNil(t, findActivity(acts, "GET", "/orgs/:org/projects/:project"))
+}
+
+func TestEndpointActivity_CapWithinBuffer(t *testing.T) {
+	eng, _, kid := newEndpointEngine(t, 2)
+
+	recordCall(t, eng, kid, "GET", "/a", "")
+	recordCall(t, eng, kid, "GET", "/b", "")
+	recordCall(t, eng, kid, "GET", "/c", "")
+	recordCall(t, eng, kid, "GET", "/d", "")
+	recordCall(t, eng, kid, "GET", "/a", "")
+
+	acts, err := eng.ListEndpointActivity(testCtx(), kid)
+	require.NoError(t, err)
+	require.Len(t, acts, 3, "two tracked endpoints plus the overflow bucket")
+	assert.Equal(t, int64(2), findActivity(acts, "GET", "/a").Count)
+	assert.NotNil(t, findActivity(acts, "GET", "/b"))
+
+	overflow := findActivity(acts, usage.OverflowMethod, usage.OverflowEndpoint)
+	require.NotNil(t, overflow)
+	assert.True(t, overflow.IsOverflow())
+	assert.Equal(t, int64(2), overflow.Count)
+}
+
+func TestEndpointActivity_CapAcrossFlushes(t *testing.T) {
+	eng, _, kid := newEndpointEngine(t, 2)
+
+	recordCall(t, eng, kid, "GET", "/a", "")
+	recordCall(t, eng, kid, "GET", "/b", "")
+	require.NoError(t, eng.FlushEndpointActivity(testCtx()))
+
+	// The cap must hold against endpoints already in the store.
+	recordCall(t, eng, kid, "GET", "/c", "")
+	recordCall(t, eng, kid, "GET", "/b", "")
+	require.NoError(t, eng.FlushEndpointActivity(testCtx()))
+
+	acts, err := eng.ListEndpointActivity(testCtx(), kid)
+	require.NoError(t, err)
+	require.Len(t, acts, 3)
+	assert.Nil(t, findActivity(acts, "GET", "/c"))
+	assert.Equal(t, int64(2), findActivity(acts, "GET", "/b").Count)
+	assert.Equal(t, int64(1), findActivity(acts, usage.OverflowMethod, usage.OverflowEndpoint).Count)
+}
+
+func TestEndpointActivity_StopFlushes(t *testing.T) {
+	eng, ms, kid := newEndpointEngine(t, 10)
+	require.NoError(t, eng.Start(testCtx()))
+
+	recordCall(t, eng, kid, "GET", "/status", "")
+
+	// Nothing is written per request.
+	stored, err := ms.Usages().ListEndpointActivity(testCtx(), kid)
+	require.NoError(t, err)
+	assert.Empty(t, stored)
+
+	require.NoError(t, eng.Stop(testCtx()))
+	stored, err = ms.Usages().ListEndpointActivity(testCtx(), kid)
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	assert.Equal(t, int64(1), stored[0].Count)
+}
+
+func TestEndpointActivity_Disabled(t *testing.T) {
+	eng, _, kid := newEndpointEngine(t, 0)
+
+	recordCall(t, eng, kid, "GET", "/status", "")
+	require.NoError(t, eng.FlushEndpointActivity(testCtx()))
+
+	acts, err := eng.ListEndpointActivity(testCtx(), kid)
+	require.NoError(t, err)
+	assert.Empty(t, acts)
+}
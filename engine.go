@@ -2,18 +2,27 @@ package keysmith
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/xraph/go-utils/log"
+	"golang.org/x/sync/singleflight"
 
 	"github.com/xraph/keysmith/id"
 	"github.com/xraph/keysmith/key"
 	"github.com/xraph/keysmith/plugin"
 	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/ratelimit"
+	"github.com/xraph/keysmith/revocation"
 	"github.com/xraph/keysmith/rotation"
 	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/slo"
 	"github.com/xraph/keysmith/store"
 	"github.com/xraph/keysmith/usage"
 )
@@ -26,6 +35,240 @@ type Engine struct {
 	ratelimiter RateLimiter
 	hooks       *plugin.Manager
 	logger      log.Logger
+
+	// defaultLimiter holds the options for the in-memory limiter installed
+	// when no RateLimiter is configured; customLimiter is set when one was,
+	// with WithRateLimiter.
+	defaultLimiter []ratelimit.Option
+	customLimiter  bool
+
+	// flights coalesces concurrent validation loads of the same key hash.
+	// Nil when coalescing is disabled.
+	flights *singleflight.Group
+
+	// cache holds recent validation snapshots. Nil when the validation
+	// cache is disabled.
+	cache *validationCache
+
+	// warmup preloads the cache in Start. Nil when no warm-up is configured.
+	warmup *warmup
+
+	settings *settingsCache
+
+	// revisions caches tenant revisions for TenantRevision.
+	revisions *revisionCache
+
+	// effective caches policies merged with their bases.
+	effective *effectivePolicyCache
+
+	// failures counts recent validation failures per fingerprint. Nil when
+	// failure fingerprinting is disabled.
+	failures *failureTracker
+
+	// endpoints buffers per-endpoint last-seen activity. Nil when endpoint
+	// tracking is disabled.
+	endpoints *endpointTracker
+
+	// lastUsed coalesces the last-used writes of validations.
+	lastUsed *lastUsedTracker
+
+	// authorizer makes external decisions after the built-in checks, with
+	// decisions cached in authzCache when set. Both are nil by default.
+	authorizer Authorizer
+	authzCache *authzCache
+
+	// consumers deduplicates KeyConsumerMismatch hooks per key.
+	consumers *reportTracker
+
+	// rotationWarning is the fraction of a policy's rotation period after
+	// which validation results carry RotationDue. rotationOverdue, when
+	// set, deduplicates the KeyRotationOverdue hooks of keys validated past
+	// their rotation period; it is nil unless WithRotationOverdueEvents.
+	rotationWarning float64
+	rotationOverdue *reportTracker
+
+	// keyFormats are the raw key formats registered with WithKeyFormat,
+	// matched after the generator's own. formatDeprecations flags formats
+	// deprecated by name, and formatNotices deduplicates their
+	// KeyFormatDeprecated hooks per key.
+	keyFormats         []KeyFormat
+	formatDeprecations map[string]KeyFormatDeprecation
+	formatNotices      *reportTracker
+
+	// Validators registered with WithCreateKeyValidator and friends, run
+	// in registration order.
+	createValidators []CreateKeyValidator
+	updateValidators []UpdateKeyValidator
+	rotateValidators []RotateKeyValidator
+
+	// now is the clock used for expiry and grace-period evaluation.
+	now func() time.Time
+
+	// extendedGrace is added to the grace period of rotated keys flagged
+	// key.FlagExtendedGraceEligible.
+	extendedGrace time.Duration
+
+	// expirySkew extends every ExpiresAt to absorb clock drift between
+	// the servers that create and validate keys.
+	expirySkew time.Duration
+
+	// metadataLimits bounds caller-supplied metadata.
+	metadataLimits MetadataLimits
+
+	// termsVersion is the terms version new keys must accept, and
+	// termsEnforcement how validation treats keys that accepted another.
+	termsVersion     string
+	termsEnforcement TermsEnforcement
+
+	// deliveryFailure selects how CreateKey handles a failed DeliverTo write.
+	deliveryFailure DeliveryFailureMode
+
+	// revocationLookback is how far back RevocationsSince can read.
+	revocationLookback time.Duration
+
+	revocationClock revocationClock
+
+	// tombstoneAll tombstones the hashes of every revoked key, not only
+	// those revoked for a security reason.
+	tombstoneAll bool
+
+	// tombstoneRetention is how long PurgeHashTombstones keeps tombstones.
+	// Zero keeps them forever.
+	tombstoneRetention time.Duration
+
+	// events records key lifecycle hooks in the key event history and
+	// fans them out to SubscribeKeyEvents subscribers.
+	events           *keyEventHub
+	keyEventBuffer   int
+	keyEventLookback time.Duration
+	keyEventClock    revocationClock
+
+	// captureRetention is how long debug captures are kept.
+	captureRetention time.Duration
+
+	// liveCapture permits debug capture for keys in the live environment.
+	liveCapture bool
+
+	// idFormat is the ID wire format set by WithIDFormat, applied
+	// process-wide by NewEngine. Nil leaves the format unchanged.
+	idFormat *id.Format
+
+	// slo tracks validations against the objectives set by WithSLO. Nil
+	// when none are configured.
+	slo           *slo.Tracker
+	sloObjectives []slo.Objective
+	sloClassify   func(error) slo.Outcome
+
+	// liveStats counts recent validations per key for LiveKeyStats. Nil
+	// unless WithLiveStats is set.
+	liveStats     *liveTracker
+	liveStatsKeys int
+
+	// recording is the usage recording policy, replaced at runtime by
+	// SetUsageRecording. Nil records every request in full.
+	recording    atomic.Pointer[recordingState]
+	recordingOpt *usage.RecordingPolicy
+
+	// adaptive tracks per-key error ratios when WithAdaptiveLimiting is
+	// set. Nil otherwise.
+	adaptive    *adaptiveTracker
+	adaptiveOpt *AdaptiveLimiting
+
+	// replay checks validate endpoint nonces. Nil when replay protection
+	// is off.
+	replay    *replayGuard
+	replayOpt *ReplayProtection
+
+	// ipHashSecret keys the hashes of tenants with IP handling "hash". Set
+	// by WithIPHashSecret, otherwise drawn at random by NewEngine.
+	ipHashSecret     []byte
+	erasureBatchSize int
+
+	purger *periodicJob
+
+	// maxPageSize caps the Limit of list calls; see PageLimit.
+	maxPageSize int
+
+	// transferTTL is how long a key transfer waits for the target tenant,
+	// and transferRetag whether accepting one moves the key's usage.
+	transferTTL   time.Duration
+	transferRetag bool
+
+	// jobLockTTL is the TTL of the store locks the purger, maintenance, and
+	// quota forecast jobs run under. Non-positive runs them unlocked.
+	jobLockTTL time.Duration
+
+	// maintenance runs store maintenance when WithStoreMaintenance is set.
+	maintenance     *periodicJob
+	maintenanceOpts store.MaintenanceOptions
+
+	// quotaForecasts runs EvaluateQuotaForecasts when
+	// WithQuotaForecastWarnings is set; quotaWarnings deduplicates its hooks.
+	quotaForecasts *periodicJob
+	quotaWarnings  *quotaWarningTracker
+
+	// storageReports runs TenantStorageReport when WithStorageReports is
+	// set.
+	storageReports *periodicJob
+
+	// telemetry sends anonymous usage payloads when WithTelemetry is set.
+	// installID caches the install ID, guarded by installMu.
+	telemetry         *periodicJob
+	telemetryEndpoint string
+	telemetryOpt      *TelemetryOptions
+	installMu         sync.Mutex
+	installID         string
+
+	// quotas counts validations toward daily and monthly quotas, and
+	// quotaFlusher writes its counts between Start and Stop.
+	quotas       *quotaMeter
+	quotaFlusher *periodicJob
+
+	// prefixRules constrains keys by prefix; strictPrefixes rejects
+	// prefixes without a rule.
+	prefixRules    map[string]PrefixRule
+	strictPrefixes bool
+
+	// envProfiles sets defaults and caps per environment. idleSweep runs
+	// SuspendIdleKeys when a profile suspends idle keys; keyEnvs resolves
+	// the environments of usage records.
+	envProfiles map[key.Environment]EnvironmentProfile
+	idleSweep   *periodicJob
+	keyEnvs     *keyEnvironmentCache
+
+	// recoveries lists the tenants whose staged recovery this engine
+	// advances, and recoveryJob advances them between Start and Stop.
+	// lockdownMu serializes lockdown changes. anomalies halts recoveries.
+	recoveries  *recoverySet
+	recoveryJob *periodicJob
+	lockdownMu  sync.Mutex
+	anomalies   AnomalyDetector
+
+	// readOnly refuses store writes while set, changed by SetReadOnly.
+	// readOnlyBlocksUsage extends it to usage recording.
+	readOnly            atomic.Bool
+	readOnlyBlocksUsage bool
+
+	// state is the EngineState, changed by Stop.
+	state atomic.Int32
+
+	// validations counts in-flight ValidateKey calls for Stop to drain.
+	validations inflight
+
+	// writes counts background writes: last-used flushes and endpoint
+	// tracking. Stop closes it before the final flush.
+	writes inflight
+
+	// refuseWhenStopping makes ValidateKey fail with ErrEngineStopping
+	// once Stop has been called.
+	refuseWhenStopping bool
+
+	shutdownTimeouts ShutdownTimeouts
+
+	// runtime is the RuntimeConfig the cache, trackers, and workers read
+	// each cycle. runtimeMu serializes UpdateRuntimeConfig.
+	runtime   atomic.Pointer[RuntimeConfig]
+	runtimeMu sync.Mutex
 }
 
 // NewEngine creates a new Keysmith engine with the given options.
@@ -35,30 +278,237 @@ func NewEngine(opts ...Option) (*Engine, error) {
 		generator: DefaultKeyGenerator(),
 		hooks:     plugin.NewManager(),
 		logger:    log.NewNoopLogger(),
+		flights:   &singleflight.Group{},
+		settings:  newSettingsCache(),
+		revisions: newRevisionCache(DefaultRevisionTTL),
+		effective: newEffectivePolicyCache(),
+		consumers: newReportTracker(consumerMismatchInterval),
+		keyEnvs:   newKeyEnvironmentCache(),
+
+		quotaWarnings: newQuotaWarningTracker(),
+		quotas:        newQuotaMeter(),
+		failures:      newFailureTracker(DefaultFailureThreshold, DefaultFailureWindow),
+		endpoints:     newEndpointTracker(DefaultMaxEndpointsPerKey, DefaultEndpointFlushInterval),
+		lastUsed:      newLastUsedTracker(DefaultLastUsedFlushInterval),
+		now:           time.Now,
+
+		sloClassify: DefaultSLOClassifier,
+
+		extendedGrace:      DefaultExtendedGracePeriod,
+		revocationLookback: DefaultRevocationLookback,
+		keyEventBuffer:     DefaultKeyEventBuffer,
+		keyEventLookback:   DefaultKeyEventLookback,
+		captureRetention:   DefaultCaptureRetention,
+		jobLockTTL:         DefaultJobLockTTL,
+		maxPageSize:        DefaultMaxPageSize,
+		transferTTL:        DefaultKeyTransferTTL,
+		rotationWarning:    DefaultRotationDueWarning,
+	}
+	e.purger = &periodicJob{
+		name:     jobCapturePurge,
+		interval: DefaultCapturePurgeInterval,
+		every:    func() time.Duration { return e.runtimeConfig().CapturePurgeInterval },
+		run:      e.purgeCaptures,
 	}
+	e.maintenance = &periodicJob{
+		name:  jobStoreMaintenance,
+		every: func() time.Duration { return e.runtimeConfig().MaintenanceInterval },
+		run:   e.runMaintenance,
+	}
+	e.quotaForecasts = &periodicJob{
+		name:  jobQuotaForecasts,
+		every: func() time.Duration { return e.runtimeConfig().QuotaForecastInterval },
+		run:   e.runQuotaForecasts,
+	}
+	e.idleSweep = &periodicJob{
+		name: jobIdleSuspension,
+		run:  e.runIdleSweep,
+	}
+	e.storageReports = &periodicJob{
+		name: jobStorageReport,
+		run:  e.runStorageReport,
+	}
+	e.telemetry = &periodicJob{
+		name: jobTelemetry,
+		run:  e.runTelemetry,
+	}
+	e.recoveries = newRecoverySet()
+	e.recoveryJob = &periodicJob{
+		interval: DefaultRecoveryCheckInterval,
+		run:      e.runRecoveries,
+	}
+	e.anomalies = historyAnomalyDetector{e}
+	e.events = newKeyEventHub(e)
+	e.hooks.Register(e.events)
 	for _, opt := range opts {
 		opt(e)
 	}
 	if e.store == nil {
 		return nil, errors.New("keysmith: store is required")
 	}
+	if err := e.checkCrypto(); err != nil {
+		return nil, err
+	}
+	if e.quotas.strict {
+		// The default token bucket refills continuously, so it would admit
+		// more than a calendar window's quota.
+		if _, bucket := e.ratelimiter.(*ratelimit.Limiter); !e.customLimiter || bucket {
+			return nil, errors.New("keysmith: strict quotas require a fixed-window rate limiter set with WithRateLimiter")
+		}
+	}
+	if e.ratelimiter == nil {
+		e.ratelimiter = ratelimit.New(append([]ratelimit.Option{ratelimit.WithClock(e.now)}, e.defaultLimiter...)...)
+	}
+	e.quotaFlusher = &periodicJob{
+		interval: e.quotas.interval,
+		run:      e.runQuotaFlush,
+	}
+	if e.idFormat != nil {
+		if err := id.SetFormat(*e.idFormat); err != nil {
+			return nil, fmt.Errorf("keysmith: %w", err)
+		}
+	}
+	if len(e.sloObjectives) > 0 {
+		tracker, err := slo.NewTracker(e.now, e.sloObjectives...)
+		if err != nil {
+			return nil, fmt.Errorf("keysmith: %w", err)
+		}
+		e.slo = tracker
+	}
+	if e.liveStatsKeys > 0 {
+		e.liveStats = newLiveTracker(e.liveStatsKeys)
+	}
+	if e.recordingOpt != nil {
+		if err := e.SetUsageRecording(*e.recordingOpt); err != nil {
+			return nil, err
+		}
+	}
+	if e.adaptiveOpt != nil {
+		if err := e.adaptiveOpt.validate(); err != nil {
+			return nil, err
+		}
+		e.adaptive = newAdaptiveTracker(*e.adaptiveOpt)
+	}
+	for prefix, rule := range e.prefixRules {
+		if err := rule.validate(prefix); err != nil {
+			return nil, err
+		}
+	}
+	for env, p := range e.envProfiles {
+		if err := p.validate(env); err != nil {
+			return nil, err
+		}
+	}
+	if err := e.initKeyFormats(); err != nil {
+		return nil, err
+	}
+	e.idleSweep.interval = e.idleSweepInterval()
+	if e.replayOpt != nil {
+		if err := e.replayOpt.validate(); err != nil {
+			return nil, err
+		}
+		e.replay = newReplayGuard(*e.replayOpt, e.now)
+	}
+	if err := e.initTelemetry(); err != nil {
+		return nil, err
+	}
+	if len(e.ipHashSecret) == 0 {
+		e.ipHashSecret = make([]byte, 32)
+		if _, err := rand.Read(e.ipHashSecret); err != nil {
+			return nil, fmt.Errorf("keysmith: ip hash secret: %w", err)
+		}
+	}
+	if e.endpoints != nil {
+		e.endpoints.flusher = &periodicJob{
+			interval: e.endpoints.interval,
+			every:    func() time.Duration { return e.runtimeConfig().EndpointFlushInterval },
+			run:      func(ctx context.Context) { e.flushEndpointActivity(ctx, nil) },
+		}
+	}
+	if locker, ok := store.As[store.Locker](e.store); ok && e.jobLockTTL > 0 {
+		locks := &jobLocks{locker: locker, ttl: e.jobLockTTL, logger: e.logger}
+		for _, j := range []*periodicJob{e.purger, e.maintenance, e.quotaForecasts, e.idleSweep, e.storageReports, e.telemetry} {
+			j.locks = locks
+		}
+	}
+	e.initRuntimeConfig()
+	e.hooks.SetLogger(e.logger)
 	return e, nil
 }
 
 // Store returns the underlying composite store.
 func (e *Engine) Store() store.Store { return e.store }
 
-// Health checks the health of the engine by pinging its store.
+// Health checks the health of the engine by pinging its store. It reports
+// ErrEngineStopping once Stop has been called, so that load balancers stop
+// routing to an instance that is shutting down.
 func (e *Engine) Health(ctx context.Context) error {
+	if e.Stopping() {
+		return ErrEngineStopping
+	}
 	return e.store.Ping(ctx)
 }
 
-// Start starts the engine and any background workers.
-func (e *Engine) Start(_ context.Context) error { return nil }
+// HealthReport is the detailed health of an engine.
+type HealthReport struct {
+	// Err is the result of Health: nil while the engine is serving.
+	Err error
+
+	// SLOs holds the state of every objective set with WithSLO. Burning
+	// budget does not make the engine unhealthy; alerting on it is left
+	// to the caller.
+	SLOs []slo.Status
+
+	// Crypto lists the primitives in use, for compliance evidence.
+	Crypto CryptoProfile
 
-// Stop gracefully shuts down the engine.
-func (e *Engine) Stop(ctx context.Context) error {
-	return e.hooks.FireShutdown(ctx)
+	// Replay holds the replay protection counters. Nil when replay
+	// protection is off.
+	Replay *ReplayStats
+
+	// ReadOnly reports whether read-only mode is on. A read-only engine is
+	// healthy: it still validates keys.
+	ReadOnly bool
+}
+
+// HealthReport runs Health and reports SLO budgets and burn rates, the
+// crypto profile, replay protection counters, and read-only mode alongside.
+func (e *Engine) HealthReport(ctx context.Context) *HealthReport {
+	return &HealthReport{
+		Err:      e.Health(ctx),
+		SLOs:     e.SLOStatus(),
+		Crypto:   e.CryptoProfile(),
+		Replay:   e.ReplayStats(),
+		ReadOnly: e.ReadOnly(),
+	}
+}
+
+// Start starts the engine and its background workers: the endpoint
+// activity and quota count flushers, the debug capture purger, and
+// scheduled store maintenance, quota forecast warnings, idle key
+// suspension, storage reports, and telemetry when configured.
+// Start first self-tests the key generator, checking its entropy source and
+// generating a batch of keys to discard, and fails with
+// ErrKeyGeneratorSelfTest if the test fails.
+// When a cache warm-up is configured, Start preloads the validation cache
+// before returning; a failed or timed-out warm-up is logged, not returned.
+func (e *Engine) Start(ctx context.Context) error {
+	if err := e.selfTestGenerator(); err != nil {
+		return err
+	}
+	e.runWarmup(ctx)
+	if e.endpoints != nil {
+		e.endpoints.start()
+	}
+	e.purger.start()
+	e.maintenance.start()
+	e.quotaForecasts.start()
+	e.quotaFlusher.start()
+	e.idleSweep.start()
+	e.storageReports.start()
+	e.telemetry.start()
+	e.recoveryJob.start()
+	return nil
 }
 
 // ──────────────────────────────────────────────────
@@ -67,23 +517,130 @@ func (e *Engine) Stop(ctx context.Context) error {
 
 // CreateKey generates a new API key, hashes it, stores the hash, and returns
 // the raw key exactly once. The raw key is never persisted.
+//
+// When input.ExternalRef is already used in the tenant, no key is created:
+// the result holds the existing key with DuplicateOfExisting set and no raw
+// key, whatever the rest of input says. Concurrent creates with the same
+// reference yield one key.
+//
+// In a tenant locked down by LockdownTenant it fails with ErrTenantLocked
+// until the tenant has recovered.
 func (e *Engine) CreateKey(ctx context.Context, input *CreateKeyInput) (*key.CreateResult, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, err
+	}
 	sc := scopeFromContext(ctx)
 	tenantID := sc.tenantID
 	appID := sc.appID
 	if tenantID == "" {
 		tenantID = input.TenantID
 	}
+	// The lockdown is read from the store, not the settings cache, so
+	// that one LockdownTenant stops key creation on every engine at once.
+	ts, err := e.storedSettings(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if l := ts.Lockdown; l != nil {
+		return nil, fmt.Errorf("%w: %s since %s: %s", ErrTenantLocked, tenantID, l.LockedAt.UTC().Format(time.RFC3339), l.Reason)
+	}
+	if input.ExternalRef != "" {
+		if res, err := e.existingExternalRef(ctx, tenantID, input.ExternalRef); res != nil || err != nil {
+			return res, err
+		}
+	}
+	rule, err := e.prefixRule(input.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPrefixEnvironment(input.Prefix, rule, input.Environment); err != nil {
+		return nil, err
+	}
+	if err := e.validateMetadata(input.Metadata, nil); err != nil {
+		return nil, err
+	}
+	if err := e.validateMetadataSchema(ctx, tenantID, input.Metadata); err != nil {
+		return nil, err
+	}
+	if err := validateOrigins(input.AllowedOrigins); err != nil {
+		return nil, err
+	}
+	certFingerprint, err := normalizeCertFingerprint(input.CertFingerprint)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFlags(ctx, nil, input.Flags); err != nil {
+		return nil, err
+	}
+	if err := validateContacts(input.Contacts); err != nil {
+		return nil, err
+	}
+	if err := validateLabels(input.Labels); err != nil {
+		return nil, err
+	}
+	if dest := input.DeliverTo; dest != nil && (dest.Sink == nil || dest.Path == "") {
+		return nil, fmt.Errorf("%w: destination needs a sink and a path", ErrDeliveryFailed)
+	}
+	termsVersion, err := e.checkAcceptedTerms(input)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.validateCreateInput(ctx, input); err != nil {
+		return nil, err
+	}
+	if err := e.checkKeyName(ctx, tenantID, input.Name, id.KeyID{}); err != nil {
+		return nil, err
+	}
+	if input.PoolID != nil {
+		if err := e.checkPoolForKey(ctx, *input.PoolID, tenantID, appID); err != nil {
+			return nil, err
+		}
+	}
+	var inline *policy.Policy
+	if input.InlinePolicy != nil {
+		if input.PolicyID != nil {
+			return nil, fmt.Errorf("%w: a key cannot have both a policy ID and an inline policy", ErrInvalidPolicy)
+		}
+		if inline, err = inlinePolicy(input.InlinePolicy); err != nil {
+			return nil, err
+		}
+	}
+
+	// The tenant's default scopes count towards those the prefix requires.
+	scopes := input.Scopes
+	if !input.SkipDefaultScopes {
+		scopes = mergeScopes(input.Scopes, e.tenantSettings(ctx, tenantID).DefaultScopes)
+	}
+	if err := checkPrefixScopes(input.Prefix, rule, scopes); err != nil {
+		return nil, err
+	}
+	policyID := input.PolicyID
+	if policyID == nil && inline == nil {
+		if policyID, err = e.prefixDefaultPolicy(ctx, input.Prefix, rule, tenantID); err != nil {
+			return nil, err
+		}
+	}
+	profile := e.envProfile(input.Environment)
+	if policyID == nil && inline == nil {
+		if policyID, err = e.profileDefaultPolicy(ctx, input.Environment, profile, tenantID); err != nil {
+			return nil, err
+		}
+	}
 
 	rawKey, err := e.generator.Generate(input.Prefix, input.Environment)
 	if err != nil {
 		return nil, fmt.Errorf("generate key: %w", err)
 	}
 
-	hash, err := e.hasher.Hash(rawKey)
+	hash, err := e.hashKey(rawKey)
 	if err != nil {
 		return nil, fmt.Errorf("hash key: %w", err)
 	}
+	// A generated key colliding with a tombstone is all but impossible;
+	// the check is cheap insurance.
+	if err := e.checkTombstones(ctx, hash); err != nil {
+		return nil, err
+	}
 
 	now := time.Now()
 	k := &key.Key{
@@ -97,147 +654,466 @@ func (e *Engine) CreateKey(ctx context.Context, input *CreateKeyInput) (*key.Cre
 		KeyHash:     hash,
 		Environment: input.Environment,
 		State:       key.StateActive,
-		PolicyID:    input.PolicyID,
+		PolicyID:    policyID,
+		PoolID:      input.PoolID,
 		Metadata:    input.Metadata,
-		CreatedBy:   input.CreatedBy,
+		Labels:      input.Labels.Clone(),
+		CreatedBy:   createdBy(ctx, input.CreatedBy),
+		ExternalRef: input.ExternalRef,
 		ExpiresAt:   input.ExpiresAt,
 		CreatedAt:   now,
 		UpdatedAt:   now,
+
+		AllowedOrigins: slices.Clone(input.AllowedOrigins),
+
+		IntendedConsumer: strings.TrimSpace(input.IntendedConsumer),
+		EnforceConsumer:  input.EnforceConsumer,
+		CertFingerprint:  certFingerprint,
+
+		AcceptedTermsVersion: termsVersion,
+		AcceptedTermsAt:      acceptedTermsAt(termsVersion, now),
+
+		Flags:    input.Flags.Normalize(),
+		Contacts: input.Contacts.Normalize(),
+	}
+	if input.DeliverTo != nil {
+		meta := make(map[string]any, len(input.Metadata)+1)
+		for mk, mv := range input.Metadata {
+			meta[mk] = mv
+		}
+		k.Metadata = setInternalMetadata(meta, key.MetadataDeliveredTo, input.DeliverTo.Path)
 	}
 
-	// Apply policy constraints if assigned.
-	if input.PolicyID != nil {
-		pol, polErr := e.store.Policies().Get(ctx, *input.PolicyID)
+	// Apply policy constraints if assigned, then the prefix's and the
+	// environment's lifetime caps. Inline policies set no lifetime.
+	if inline != nil {
+		if err := e.storeInlinePolicy(ctx, k, inline); err != nil {
+			return nil, err
+		}
+	} else if policyID != nil {
+		pol, polErr := e.getPolicy(ctx, *policyID)
 		if polErr != nil {
 			return nil, fmt.Errorf("get policy: %w", polErr)
 		}
+		eff, polErr := e.effectivePolicy(ctx, pol)
+		if polErr != nil {
+			return nil, polErr
+		}
+		pol = eff.Policy
 		if pol.MaxKeyLifetime > 0 && input.ExpiresAt == nil {
 			expiry := now.Add(pol.MaxKeyLifetime)
 			k.ExpiresAt = &expiry
 		}
 	}
+	k.ExpiresAt = clampLifetime(rule, k.ExpiresAt, now)
+	if profile != nil {
+		k.ExpiresAt = capExpiry(k.ExpiresAt, profile.MaxKeyLifetime, now)
+	}
 
 	if err := e.store.Keys().Create(ctx, k); err != nil {
-		_ = e.hooks.FireKeyCreateFailed(ctx, k, err)
+		if inline != nil {
+			_ = e.store.Policies().Delete(ctx, inline.ID)
+		}
+		if errors.Is(err, key.ErrExternalRefInUse) {
+			// Lost a race with a create of the same reference.
+			if res, err := e.existingExternalRef(ctx, tenantID, k.ExternalRef); res != nil || err != nil {
+				return res, err
+			}
+		}
+		_ = e.hooks.FireKeyCreateFailed(ctx, k, err, e.eventMeta(ctx, plugin.TriggerManual, plugin.ReasonStoreFailed))
 		return nil, fmt.Errorf("store key: %w", err)
 	}
 
-	// Assign scopes.
-	if len(input.Scopes) > 0 {
-		if err := e.store.Scopes().AssignToKey(ctx, k.ID, input.Scopes); err != nil {
-			return nil, fmt.Errorf("assign scopes: %w", err)
+	// Assign the requested scopes plus the tenant's default scopes. The
+	// key is already stored and its hash would validate, so a failed
+	// assignment deletes it again.
+	if len(scopes) > 0 {
+		if err := e.store.Scopes().AssignToKey(ctx, k.ID, scopes); err != nil {
+			err = fmt.Errorf("assign scopes: %w", err)
+			e.rollbackCreate(ctx, k)
+			_ = e.hooks.FireKeyCreateFailed(ctx, k, err, e.eventMeta(ctx, plugin.TriggerManual, plugin.ReasonStoreFailed))
+			return nil, err
+		}
+		k.Scopes = scopes
+	}
+	e.bumpRevision(ctx, k.TenantID)
+
+	if dest := input.DeliverTo; dest != nil {
+		if err := e.deliverKey(ctx, k, rawKey, dest); err != nil {
+			if k.State != key.StateSuspended {
+				return nil, err
+			}
+			_ = e.hooks.FireKeyCreated(ctx, k, e.eventMeta(ctx, plugin.TriggerManual, ""))
+			e.flagsChanged(ctx, k, nil)
+			_ = e.hooks.FireKeySuspended(ctx, k, e.eventMeta(ctx, plugin.TriggerManual, plugin.ReasonDeliveryFailed))
+			return &key.CreateResult{Key: k, RawKey: rawKey}, err
 		}
-		k.Scopes = input.Scopes
+		_ = e.hooks.FireKeyCreated(ctx, k, e.eventMeta(ctx, plugin.TriggerManual, ""))
+		e.flagsChanged(ctx, k, nil)
+		return &key.CreateResult{Key: k, RawKey: dest.Path, DeliveredTo: dest.Path}, nil
 	}
 
-	_ = e.hooks.FireKeyCreated(ctx, k)
+	_ = e.hooks.FireKeyCreated(ctx, k, e.eventMeta(ctx, plugin.TriggerManual, ""))
+	e.flagsChanged(ctx, k, nil)
 
 	return &key.CreateResult{Key: k, RawKey: rawKey}, nil
 }
 
+// rollbackCreate deletes a key CreateKey stored but could not finish, with
+// its inline policy. A key that cannot be deleted is revoked instead, so
+// that it never validates; either failure is logged. It runs detached from
+// ctx, as the step that failed often did so because ctx was done.
+func (e *Engine) rollbackCreate(ctx context.Context, k *key.Key) {
+	ctx, cancel := detachedContext(ctx, false)
+	defer cancel()
+	if err := e.deleteKey(ctx, k); err != nil {
+		e.logger.Warn("failed to delete key after failed create", log.String("key_id", k.ID.String()), log.Any("error", err))
+		if err := e.store.Keys().UpdateState(ctx, k.ID, key.StateRevoked); err != nil {
+			e.logger.Warn("failed to revoke key after failed create", log.String("key_id", k.ID.String()), log.Any("error", err))
+		}
+	}
+	e.invalidateKey(k.ID)
+}
+
+// existingExternalRef returns the duplicate result for the key in tenantID
+// with external reference ref, or nil if there is none. A key in an app the
+// context may not see is reported as ErrExternalRefInUse.
+func (e *Engine) existingExternalRef(ctx context.Context, tenantID, ref string) (*key.CreateResult, error) {
+	k, err := e.keyByExternalRef(ctx, tenantID, ref)
+	if err != nil || k == nil {
+		return nil, err
+	}
+	if !scopeFromContext(ctx).owns(k.TenantID, k.AppID) {
+		return nil, fmt.Errorf("%w: %q", ErrExternalRefInUse, ref)
+	}
+	// Scopes are filled in as on a created key.
+	assigned, err := e.store.Scopes().ListByKey(ctx, k.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list scopes: %w", err)
+	}
+	for _, s := range assigned {
+		k.Scopes = append(k.Scopes, s.Name)
+	}
+	return &key.CreateResult{Key: k, DuplicateOfExisting: true}, nil
+}
+
+// keyByExternalRef returns the key in tenantID with external reference ref,
+// or nil if there is none. It reads from the primary, so a key created
+// moments earlier by a retried request is found.
+func (e *Engine) keyByExternalRef(ctx context.Context, tenantID, ref string) (*key.Key, error) {
+	if ref == "" {
+		return nil, nil
+	}
+	// An empty TenantID filter matches every tenant, so the tenant is
+	// compared here as well.
+	keys, err := e.store.Keys().List(store.WithPrimaryReads(ctx), &key.ListFilter{TenantID: tenantID, ExternalRef: ref})
+	if err != nil {
+		return nil, fmt.Errorf("find key by external reference: %w", err)
+	}
+	for _, k := range keys {
+		if k.TenantID == tenantID {
+			return k, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetKeyByExternalRef returns the key with the given external reference in
+// the context's tenant. A key in an app the context may not see is not
+// found.
+func (e *Engine) GetKeyByExternalRef(ctx context.Context, ref string) (*key.Key, error) {
+	k, err := e.keyByExternalRef(ctx, scopeFromContext(ctx).tenantID, ref)
+	if err != nil {
+		return nil, err
+	}
+	if k == nil || !scopeFromContext(ctx).owns(k.TenantID, k.AppID) {
+		return nil, fmt.Errorf("%w: external reference %q", ErrKeyNotFound, ref)
+	}
+	return k, nil
+}
+
 // ValidateKey validates a raw API key and returns the key record if valid.
-// This is the hot path — optimized for speed.
+// This is the hot path — optimized for speed. Concurrent validations of the
+// same key share one store load (see [WithoutValidationCoalescing]); the
+// per-request checks below always run individually.
 func (e *Engine) ValidateKey(ctx context.Context, rawKey string) (*ValidationResult, error) {
-	hash, err := e.hasher.Hash(rawKey)
+	if e.slo == nil {
+		return e.validateKey(ctx, rawKey)
+	}
+	start := e.now()
+	result, err := e.validateKey(ctx, rawKey)
+	e.slo.Record(e.now().Sub(start), e.sloClassify(err))
+	return result, err
+}
+
+func (e *Engine) validateKey(ctx context.Context, rawKey string) (_ *ValidationResult, err error) {
+	if e.refuseWhenStopping && e.Stopping() {
+		return nil, ErrEngineStopping
+	}
+	e.validations.add()
+	defer e.validations.done()
+
+	if err := checkAcceptedPrefix(ctx, rawKey); err != nil {
+		e.validationFailed(ctx, rawKey, err)
+		return nil, err
+	}
+
+	buf := hashBufs.Get().(*[]byte)
+	hash, err := e.appendHash((*buf)[:0], rawKey)
 	if err != nil {
+		hashBufs.Put(buf)
 		return nil, fmt.Errorf("hash key: %w", err)
 	}
-
-	k, err := e.store.Keys().GetByHash(ctx, hash)
+	snap, err := e.loadSnapshot(ctx, hash)
+	*buf = hash
+	hashBufs.Put(buf)
 	if err != nil {
-		_ = e.hooks.FireKeyValidationFailed(ctx, rawKey, err)
+		e.validationFailed(ctx, rawKey, err)
 		return nil, ErrInvalidKey
 	}
+	result := newValidationResult(snap)
+	k := result.Key
+	if e.liveStats != nil {
+		defer func() { e.liveStats.record(k, err != nil, e.now()) }()
+	}
 
 	// Check state.
 	if k.State != key.StateActive && k.State != key.StateRotated {
-		_ = e.hooks.FireKeyValidationFailed(ctx, rawKey, ErrKeyInactive)
+		e.validationFailed(ctx, rawKey, ErrKeyInactive)
 		return nil, ErrKeyInactive
 	}
 
-	// Check expiration.
-	if k.ExpiresAt != nil && time.Now().After(*k.ExpiresAt) {
-		_ = e.store.Keys().UpdateState(ctx, k.ID, key.StateExpired)
-		_ = e.hooks.FireKeyExpired(ctx, k)
+	// Check expiration. Only the caller that wins the state transition fires
+	// the hook, so concurrent validations of a just-expired key report it once.
+	// In read-only mode the key is rejected without the transition.
+	now := e.now()
+	readOnly := e.ReadOnly()
+	if e.isExpired(k, now) {
+		if readOnly {
+			return nil, ErrKeyExpired
+		}
+		if ok, _ := e.store.Keys().UpdateStateIf(ctx, k.ID, k.State, key.StateExpired); ok {
+			e.invalidateKey(k.ID)
+			e.recordRevocation(ctx, k, key.StateExpired, true)
+			_ = e.hooks.FireKeyExpired(ctx, k, e.eventMeta(ctx, plugin.TriggerValidation, plugin.ReasonKeyExpired))
+		}
 		return nil, ErrKeyExpired
 	}
 
-	// Check grace period for rotated keys.
-	if k.State == key.StateRotated {
-		latest, rotErr := e.store.Rotations().LatestForKey(ctx, k.ID)
-		if rotErr == nil && time.Now().After(latest.GraceEnds) {
-			_ = e.store.Keys().UpdateState(ctx, k.ID, key.StateRevoked)
+	// Check grace period for rotated keys, extended for keys flagged
+	// eligible.
+	var applied []key.Flag
+	if k.State == key.StateRotated && snap.rotation != nil {
+		graceEnds, extended := e.graceEnds(k, snap.rotation.GraceEnds, now)
+		if now.After(graceEnds) {
+			if readOnly {
+				return nil, ErrKeyRevoked
+			}
+			if ok, _ := e.store.Keys().UpdateStateIf(ctx, k.ID, key.StateRotated, key.StateRevoked); ok {
+				e.invalidateKey(k.ID)
+				e.recordRevocation(ctx, k, key.StateRevoked, true)
+			}
 			return nil, ErrKeyRevoked
 		}
+		if extended {
+			applied = append(applied, key.FlagExtendedGraceEligible)
+		}
 	}
 
-	// Load policy for rate-limiting.
-	var pol *policy.Policy
-	if k.PolicyID != nil {
-		pol, _ = e.store.Policies().Get(ctx, *k.PolicyID)
+	// The secret a rotation replaced validates until the grace period
+	// ends, and is refused from then on. CleanupGraceExpired retires it.
+	if snap.previousHash && k.State == key.StateActive {
+		graceEnds, extended := e.graceEnds(k, snap.rotation.GraceEnds, now)
+		if now.After(graceEnds) {
+			return nil, ErrKeyRevoked
+		}
+		if extended {
+			applied = append(applied, key.FlagExtendedGraceEligible)
+		}
+		k.State = key.StateRotated
+		result.UsingRotatedKey = true
+	}
+
+	if snap.policyErr != nil {
+		e.validationFailed(ctx, rawKey, snap.policyErr)
+		return nil, snap.policyErr
+	}
+	pol := result.Policy
+
+	// Origin check: the key's own allowlist takes precedence over the
+	// policy's.
+	if k.Flags.Has(key.FlagSkipOriginCheck) {
+		if len(effectiveOrigins(k, pol)) > 0 {
+			applied = append(applied, key.FlagSkipOriginCheck)
+		}
+	} else if err := checkOrigin(ctx, k, pol); err != nil {
+		return nil, err
 	}
 
-	// Rate-limit check.
-	if pol != nil && e.ratelimiter != nil && pol.RateLimit > 0 {
-		allowed, rlErr := e.ratelimiter.Allow(ctx, k.ID.String(), pol.RateLimit, pol.RateLimitWindow)
-		if rlErr != nil || !allowed {
-			_ = e.hooks.FireKeyRateLimited(ctx, k)
-			return nil, ErrRateLimited
+	// IP check: the client IP must be in the policy's allowlist.
+	if k.Flags.Has(key.FlagSkipIPCheck) {
+		if pol != nil && len(pol.AllowedIPs) > 0 {
+			applied = append(applied, key.FlagSkipIPCheck)
 		}
+	} else if err := checkIP(ctx, pol); err != nil {
+		e.validationFailed(ctx, rawKey, err)
+		return nil, err
+	}
+
+	// Route check: the request method and path must be in the policy's
+	// allowlists.
+	if err := checkMethod(ctx, pol); err != nil {
+		e.validationFailed(ctx, rawKey, err)
+		return nil, err
+	}
+	if err := checkPath(ctx, pol); err != nil {
+		e.validationFailed(ctx, rawKey, err)
+		return nil, err
+	}
+
+	// Consumer check: flag, or with EnforceConsumer reject, a calling
+	// service other than the key's intended consumer.
+	mismatch, err := e.checkConsumer(ctx, k)
+	if err != nil {
+		return nil, err
+	}
+
+	// Transport check: the policy's minimum TLS version and mTLS
+	// requirement, then the key's pinned client certificate.
+	if err := checkTransport(ctx, k, pol); err != nil {
+		return nil, err
+	}
+
+	// Terms check: flag, or with TermsStrict reject, a key that accepted
+	// other terms than the current version.
+	outdated, err := e.checkTerms(k)
+	if err != nil {
+		return nil, err
 	}
 
-	// Load scopes.
-	scopes, _ := e.store.Scopes().ListByKey(ctx, k.ID)
-	scopeNames := make([]string, len(scopes))
-	for i, s := range scopes {
-		scopeNames[i] = s.Name
+	// Rate-limit checks: the key's own limit, then its tenant's ceiling.
+	limits, err := e.checkRateLimits(ctx, k, pol)
+	if err != nil {
+		return nil, err
 	}
 
-	// Update last-used timestamp asynchronously.
-	go func() {
-		now := time.Now()
-		_ = e.store.Keys().UpdateLastUsed(context.WithoutCancel(ctx), k.ID, now)
-	}()
+	// External authorization: the authorizer's decision on a validation
+	// that passed every built-in check, with its obligations applied.
+	result.RateLimit = limits
+	if err := e.checkAuthorizer(ctx, result, now); err != nil {
+		return nil, err
+	}
 
-	_ = e.hooks.FireKeyValidated(ctx, k)
+	// Quotas: the key's pool's daily and monthly limits, else its policy's.
+	// They are counted last, so a request the authorizer denies uses none.
+	quota, err := e.checkQuotas(ctx, k, snap.pool, pol, now)
+	if err != nil {
+		return nil, err
+	}
+	result.Quota = quota
+
+	// Record last use for the coalesced background write. Stop flushes
+	// pending times and drops later ones once the engine has stopped;
+	// read-only mode skips them.
+	if !readOnly {
+		e.recordLastUsed(k.ID, time.Now())
+	}
+
+	_ = e.hooks.FireKeyValidated(ctx, k, e.eventMeta(ctx, plugin.TriggerValidation, ""))
+
+	result.ConsumerMismatch = mismatch
+	result.OutdatedTerms = outdated
+	result.AppliedFlags = applied
+	result.RotationDue = e.checkRotationDue(ctx, k, pol, now)
+	result.KeyDeprecation = e.checkKeyFormat(ctx, rawKey, k, now)
+	return result, nil
+}
 
-	return &ValidationResult{
-		Key:    k,
-		Scopes: scopeNames,
-		Policy: pol,
-	}, nil
+// isExpired reports whether k's expiry, extended by the skew tolerance, is
+// before now.
+func (e *Engine) isExpired(k *key.Key, now time.Time) bool {
+	return k.ExpiresAt != nil && now.After(k.ExpiresAt.Add(e.expirySkew))
 }
 
 // RotateKey creates a new key for the same key record, depreciates the old one
-// with a grace period, and returns the new raw key.
+// with a grace period, and returns the new raw key. The reason must be one of
+// the rotation package's constants, or empty for [rotation.ReasonManual];
+// any other reason returns [ErrInvalidRotationReason].
 func (e *Engine) RotateKey(ctx context.Context, keyID id.KeyID, reason rotation.Reason) (*key.CreateResult, error) {
-	k, err := e.store.Keys().Get(ctx, keyID)
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	if reason == "" {
+		reason = rotation.ReasonManual
+	}
+	if !reason.IsValid() {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidRotationReason, reason)
+	}
+	k, err := e.getKey(ctx, keyID)
 	if err != nil {
 		return nil, fmt.Errorf("get key: %w", err)
 	}
 
-	// Determine grace period from policy or default.
-	graceTTL := 24 * time.Hour
+	if err := e.validateRotateInput(ctx, k, reason); err != nil {
+		return nil, err
+	}
+
+	result, _, err := e.rotateKey(ctx, k, reason, e.rotationGrace(ctx, k))
+	return result, err
+}
+
+// rotationGrace returns the grace period for rotating k: its effective
+// policy's GracePeriod, or 24 hours.
+func (e *Engine) rotationGrace(ctx context.Context, k *key.Key) time.Duration {
 	if k.PolicyID != nil {
-		pol, polErr := e.store.Policies().Get(ctx, *k.PolicyID)
-		if polErr == nil && pol.GracePeriod > 0 {
-			graceTTL = pol.GracePeriod
+		if pol, polErr := e.store.Policies().Get(ctx, *k.PolicyID); polErr == nil {
+			if eff, effErr := e.effectivePolicy(ctx, pol); effErr == nil && eff.Policy.GracePeriod > 0 {
+				return eff.Policy.GracePeriod
+			}
 		}
 	}
+	return 24 * time.Hour
+}
+
+// rotateKey replaces the key's secret and records the rotation. A graceTTL of
+// zero ends the grace period immediately, so the old hash stops validating
+// as soon as the rotation is stored.
+func (e *Engine) rotateKey(ctx context.Context, k *key.Key, reason rotation.Reason, graceTTL time.Duration) (*key.CreateResult, *rotation.Record, error) {
+	if IsDryRun(ctx) {
+		// No secret is generated: a raw key that is never stored would be
+		// indistinguishable from a real one.
+		now := e.now()
+		k.RotatedAt = &now
+		k.UpdatedAt = now
+		return &key.CreateResult{Key: k}, &rotation.Record{
+			KeyID:      k.ID,
+			TenantID:   k.TenantID,
+			AppID:      k.AppID,
+			OldKeyHash: k.KeyHash,
+			OldHint:    k.Hint,
+			Reason:     reason,
+			GraceTTL:   graceTTL,
+			GraceEnds:  now.Add(graceTTL),
+			CreatedAt:  now,
+		}, nil
+	}
 
 	// Generate new key.
 	rawKey, err := e.generator.Generate(k.Prefix, k.Environment)
 	if err != nil {
-		return nil, fmt.Errorf("generate new key: %w", err)
+		return nil, nil, fmt.Errorf("generate new key: %w", err)
 	}
 
-	newHash, err := e.hasher.Hash(rawKey)
+	newHash, err := e.hashKey(rawKey)
 	if err != nil {
-		return nil, fmt.Errorf("hash new key: %w", err)
+		return nil, nil, fmt.Errorf("hash new key: %w", err)
+	}
+	if err := e.checkTombstones(ctx, newHash); err != nil {
+		return nil, nil, err
 	}
 
-	oldHash := k.KeyHash
-	now := time.Now()
+	oldHash, oldHint := k.KeyHash, k.Hint
+	now := e.now()
 
 	// Update the key record with the new hash.
 	k.KeyHash = newHash
@@ -246,65 +1122,119 @@ func (e *Engine) RotateKey(ctx context.Context, keyID id.KeyID, reason rotation.
 	k.UpdatedAt = now
 
 	if err := e.store.Keys().Update(ctx, k); err != nil {
-		return nil, fmt.Errorf("update key: %w", err)
+		return nil, nil, fmt.Errorf("update key: %w", err)
+	}
+	// Update retires the old hash; it stays valid through the grace
+	// period, after which validation retires it again.
+	if graceTTL > 0 {
+		if err := e.store.Keys().AddHash(ctx, &key.HashVersion{KeyID: k.ID, Hash: oldHash, Active: true, CreatedAt: now}); err != nil {
+			return nil, nil, fmt.Errorf("keep rotated hash: %w", err)
+		}
 	}
+	e.invalidateKey(k.ID)
+	e.bumpRevision(ctx, k.TenantID)
 
 	// Record the rotation.
 	rec := &rotation.Record{
 		ID:         id.NewRotationID(),
 		KeyID:      k.ID,
 		TenantID:   k.TenantID,
+		AppID:      k.AppID,
 		OldKeyHash: oldHash,
 		NewKeyHash: newHash,
+		OldHint:    oldHint,
+		NewHint:    k.Hint,
 		Reason:     reason,
 		GraceTTL:   graceTTL,
 		GraceEnds:  now.Add(graceTTL),
 		CreatedAt:  now,
 	}
 	if err := e.store.Rotations().Create(ctx, rec); err != nil {
-		return nil, fmt.Errorf("record rotation: %w", err)
+		// Without the record nothing would end the grace period, so the old
+		// hash stops matching now.
+		if graceTTL > 0 {
+			if dErr := e.store.Keys().DeactivateHash(ctx, k.ID, oldHash); dErr != nil {
+				e.logger.Warn("failed to retire rotated key hash", log.String("key_id", k.ID.String()), log.Any("error", dErr))
+			}
+			e.invalidateKey(k.ID)
+		}
+		return nil, nil, fmt.Errorf("record rotation: %w", err)
+	}
+	if graceTTL > 0 {
+		// A validation of the old key between the hash and the record
+		// could not tell it from the current one.
+		e.invalidateKey(k.ID)
 	}
 
-	_ = e.hooks.FireKeyRotated(ctx, k, rec)
+	_ = e.hooks.FireKeyRotated(ctx, k, rec, e.eventMeta(ctx, plugin.TriggerManual, plugin.ReasonCode(reason)))
 
-	return &key.CreateResult{Key: k, RawKey: rawKey}, nil
+	return &key.CreateResult{Key: k, RawKey: rawKey}, rec, nil
 }
 
 // RevokeKey permanently disables a key.
 func (e *Engine) RevokeKey(ctx context.Context, keyID id.KeyID, reason string) error {
-	k, err := e.store.Keys().Get(ctx, keyID)
+	if err := e.checkWritable(ctx); err != nil {
+		return err
+	}
+	k, err := e.getKey(ctx, keyID)
 	if err != nil {
 		return fmt.Errorf("get key: %w", err)
 	}
+	return e.revokeKey(ctx, k, reason, "")
+}
 
+// revokeKey marks k revoked, persists it, tombstones its hashes when code is
+// a security reason, and fires the KeyRevoked hook with code as its reason
+// code.
+func (e *Engine) revokeKey(ctx context.Context, k *key.Key, reason string, code plugin.ReasonCode) error {
 	now := time.Now()
 	k.State = key.StateRevoked
 	k.RevokedAt = &now
 	k.UpdatedAt = now
 
+	if IsDryRun(ctx) {
+		return nil
+	}
 	if err := e.store.Keys().Update(ctx, k); err != nil {
 		return fmt.Errorf("update key: %w", err)
 	}
+	e.invalidateKey(k.ID)
+	e.recordRevocation(ctx, k, key.StateRevoked, true)
+	e.tombstoneKey(ctx, k, code)
 
-	_ = e.hooks.FireKeyRevoked(ctx, k, reason)
+	_ = e.hooks.FireKeyRevoked(ctx, k, reason, e.eventMeta(ctx, plugin.TriggerManual, code))
 	return nil
 }
 
 // SuspendKey temporarily disables a key.
 func (e *Engine) SuspendKey(ctx context.Context, keyID id.KeyID) error {
+	if err := e.checkWritable(ctx); err != nil {
+		return err
+	}
+	if _, err := e.getKey(ctx, keyID); err != nil {
+		return fmt.Errorf("suspend key: %w", err)
+	}
+	if IsDryRun(ctx) {
+		return nil
+	}
 	if err := e.store.Keys().UpdateState(ctx, keyID, key.StateSuspended); err != nil {
 		return fmt.Errorf("suspend key: %w", err)
 	}
+	e.invalidateKey(keyID)
 	k, _ := e.store.Keys().Get(ctx, keyID)
 	if k != nil {
-		_ = e.hooks.FireKeySuspended(ctx, k)
+		e.recordRevocation(ctx, k, key.StateSuspended, true)
+		_ = e.hooks.FireKeySuspended(ctx, k, e.eventMeta(ctx, plugin.TriggerManual, ""))
 	}
 	return nil
 }
 
 // ReactivateKey re-enables a suspended key.
 func (e *Engine) ReactivateKey(ctx context.Context, keyID id.KeyID) error {
-	k, err := e.store.Keys().Get(ctx, keyID)
+	if err := e.checkWritable(ctx); err != nil {
+		return err
+	}
+	k, err := e.getKey(ctx, keyID)
 	if err != nil {
 		return fmt.Errorf("get key: %w", err)
 	}
@@ -314,18 +1244,129 @@ func (e *Engine) ReactivateKey(ctx context.Context, keyID id.KeyID) error {
 	if err := e.store.Keys().UpdateState(ctx, keyID, key.StateActive); err != nil {
 		return fmt.Errorf("reactivate key: %w", err)
 	}
-	_ = e.hooks.FireKeyReactivated(ctx, k)
+	e.invalidateKey(keyID)
+	e.recordRevocation(ctx, k, key.StateActive, false)
+	_ = e.hooks.FireKeyReactivated(ctx, k, e.eventMeta(ctx, plugin.TriggerManual, ""))
 	return nil
 }
 
 // GetKey returns a key by ID.
 func (e *Engine) GetKey(ctx context.Context, keyID id.KeyID) (*key.Key, error) {
-	return e.store.Keys().Get(ctx, keyID)
+	return e.getKey(ctx, keyID)
 }
 
-// ListKeys returns keys matching the filter.
+// UpdateKey changes a key's descriptive fields, metadata, origin allowlist,
+// intended consumer, pinned client certificate, flags, contacts, and labels.
+// Metadata is checked against the engine's limits and the tenant's metadata
+// schema.
+func (e *Engine) UpdateKey(ctx context.Context, keyID id.KeyID, input *UpdateKeyInput) (*key.Key, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	k, err := e.getKey(ctx, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("get key: %w", err)
+	}
+	if err := e.validateUpdateInput(ctx, k, input); err != nil {
+		return nil, err
+	}
+
+	if input.Name != nil {
+		// Keys that already share a name may keep it or change its case.
+		if !strings.EqualFold(*input.Name, k.Name) {
+			if err := e.checkKeyName(ctx, k.TenantID, *input.Name, k.ID); err != nil {
+				return nil, err
+			}
+		}
+		k.Name = *input.Name
+	}
+	if input.Description != nil {
+		k.Description = *input.Description
+	}
+	if input.Metadata != nil {
+		if err := e.validateMetadata(input.Metadata, k.Metadata); err != nil {
+			return nil, err
+		}
+		if err := e.validateMetadataSchema(ctx, k.TenantID, input.Metadata); err != nil {
+			return nil, err
+		}
+		k.Metadata = input.Metadata
+	}
+	if input.AllowedOrigins != nil {
+		if err := validateOrigins(input.AllowedOrigins); err != nil {
+			return nil, err
+		}
+		k.AllowedOrigins = slices.Clone(input.AllowedOrigins)
+		if len(k.AllowedOrigins) == 0 {
+			k.AllowedOrigins = nil
+		}
+	}
+	if input.IntendedConsumer != nil {
+		k.IntendedConsumer = strings.TrimSpace(*input.IntendedConsumer)
+	}
+	if input.EnforceConsumer != nil {
+		k.EnforceConsumer = *input.EnforceConsumer
+	}
+	if input.CertFingerprint != nil {
+		fp, err := normalizeCertFingerprint(*input.CertFingerprint)
+		if err != nil {
+			return nil, err
+		}
+		k.CertFingerprint = fp
+	}
+	prevFlags := k.Flags
+	if input.Flags != nil {
+		if err := checkFlags(ctx, k.Flags, input.Flags); err != nil {
+			return nil, err
+		}
+		k.Flags = input.Flags.Normalize()
+	}
+	if input.Contacts != nil {
+		if err := validateContacts(input.Contacts); err != nil {
+			return nil, err
+		}
+		k.Contacts = input.Contacts.Normalize()
+	}
+	if input.Labels != nil {
+		if err := validateLabels(input.Labels); err != nil {
+			return nil, err
+		}
+		k.Labels = input.Labels.Clone()
+	}
+	if input.InlinePolicy != nil {
+		if err := e.updateInlinePolicy(ctx, k, input.InlinePolicy); err != nil {
+			return nil, err
+		}
+	}
+	k.UpdatedAt = time.Now()
+
+	if IsDryRun(ctx) {
+		return k, nil
+	}
+	if err := e.store.Keys().Update(ctx, k); err != nil {
+		return nil, fmt.Errorf("update key: %w", err)
+	}
+	e.invalidateKey(k.ID)
+	e.bumpRevision(ctx, k.TenantID)
+	e.flagsChanged(ctx, k, prevFlags)
+	return k, nil
+}
+
+// ListKeys returns a page of keys matching the filter, restricted to the
+// context's tenant and app. The filter's Limit defaults to DefaultPageSize
+// and is clamped by [Engine.PageLimit]; use IterateKeys for every key.
 func (e *Engine) ListKeys(ctx context.Context, filter *key.ListFilter) ([]*key.Key, error) {
-	return e.store.Keys().List(ctx, filter)
+	f := keyFilter(ctx, filter)
+	f.Limit = e.PageLimit(f.Limit, DefaultPageSize)
+	return e.store.Keys().List(ctx, f)
+}
+
+// IterateKeys calls fn for every key matching the filter without loading the
+// full result set, which keeps memory flat for tenants with many keys. Keys
+// are visited in ascending ID order. Iteration stops at the first error from
+// fn or the store, and that error is returned.
+func (e *Engine) IterateKeys(ctx context.Context, filter *key.ListFilter, fn func(*key.Key) error) error {
+	return e.store.Keys().Iterate(ctx, keyFilter(ctx, filter), fn)
 }
 
 // ──────────────────────────────────────────────────
@@ -334,6 +1375,20 @@ func (e *Engine) ListKeys(ctx context.Context, filter *key.ListFilter) ([]*key.K
 
 // CreatePolicy creates a new key policy.
 func (e *Engine) CreatePolicy(ctx context.Context, pol *policy.Policy) error {
+	if err := e.checkWritable(ctx); err != nil {
+		return err
+	}
+	if err := pol.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+	}
+	if err := e.validateMetadata(pol.Metadata, nil); err != nil {
+		return err
+	}
+	return e.createPolicy(ctx, pol)
+}
+
+// createPolicy stores a validated policy.
+func (e *Engine) createPolicy(ctx context.Context, pol *policy.Policy) error {
 	sc := scopeFromContext(ctx)
 	pol.ID = id.NewPolicyID()
 	pol.TenantID = sc.tenantID
@@ -341,141 +1396,395 @@ func (e *Engine) CreatePolicy(ctx context.Context, pol *policy.Policy) error {
 	now := time.Now()
 	pol.CreatedAt = now
 	pol.UpdatedAt = now
+	if err := e.checkInheritance(ctx, pol); err != nil {
+		return err
+	}
 	if err := e.store.Policies().Create(ctx, pol); err != nil {
 		return fmt.Errorf("create policy: %w", err)
 	}
-	_ = e.hooks.FirePolicyCreated(ctx, pol)
+	e.bumpRevision(ctx, pol.TenantID)
+	_ = e.hooks.FirePolicyCreated(ctx, pol, e.eventMeta(ctx, plugin.TriggerManual, ""))
 	return nil
 }
 
 // GetPolicy returns a policy by ID.
 func (e *Engine) GetPolicy(ctx context.Context, polID id.PolicyID) (*policy.Policy, error) {
-	return e.store.Policies().Get(ctx, polID)
+	return e.getPolicy(ctx, polID)
 }
 
 // UpdatePolicy updates an existing policy.
 func (e *Engine) UpdatePolicy(ctx context.Context, pol *policy.Policy) error {
+	if err := e.checkWritable(ctx); err != nil {
+		return err
+	}
+	if err := pol.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+	}
+	cur, err := e.getPolicy(ctx, pol.ID)
+	if err != nil {
+		return fmt.Errorf("get policy: %w", err)
+	}
+	if len(pol.Metadata) > 0 {
+		if err := e.validateMetadata(pol.Metadata, cur.Metadata); err != nil {
+			return err
+		}
+	}
+	pol.TenantID, pol.AppID = cur.TenantID, cur.AppID
+	pol.Inline = cur.Inline
+	if err := e.checkInheritance(ctx, pol); err != nil {
+		return err
+	}
 	pol.UpdatedAt = time.Now()
 	if err := e.store.Policies().Update(ctx, pol); err != nil {
 		return fmt.Errorf("update policy: %w", err)
 	}
-	_ = e.hooks.FirePolicyUpdated(ctx, pol)
+	e.effective.invalidate()
+	e.invalidateAll()
+	e.bumpRevision(ctx, pol.TenantID)
+	_ = e.hooks.FirePolicyUpdated(ctx, pol, e.eventMeta(ctx, plugin.TriggerManual, ""))
 	return nil
 }
 
 // DeletePolicy deletes a policy by ID.
 func (e *Engine) DeletePolicy(ctx context.Context, polID id.PolicyID) error {
-	keys, err := e.store.Keys().ListByPolicy(ctx, polID)
+	if err := e.checkWritable(ctx); err != nil {
+		return err
+	}
+	pol, err := e.getPolicy(ctx, polID)
+	if err != nil {
+		return fmt.Errorf("get policy: %w", err)
+	}
+	n, err := e.store.Keys().Count(ctx, &key.ListFilter{PolicyID: &polID})
 	if err != nil {
-		return fmt.Errorf("list keys by policy: %w", err)
+		return fmt.Errorf("count keys by policy: %w", err)
 	}
-	if len(keys) > 0 {
+	if n > 0 {
 		return ErrPolicyInUse
 	}
+	child, err := e.inheritingPolicy(ctx, pol)
+	if err != nil {
+		return err
+	}
+	if child != nil {
+		return fmt.Errorf("%w: policy %s inherits from it", ErrPolicyInUse, child.ID)
+	}
 	if err := e.store.Policies().Delete(ctx, polID); err != nil {
 		return fmt.Errorf("delete policy: %w", err)
 	}
-	_ = e.hooks.FirePolicyDeleted(ctx, polID)
+	e.effective.invalidate()
+	e.bumpRevision(ctx, pol.TenantID)
+	_ = e.hooks.FirePolicyDeleted(ctx, polID, e.eventMeta(ctx, plugin.TriggerManual, ""))
 	return nil
 }
 
-// ListPolicies returns policies matching the filter.
+// ListPolicies returns a page of policies matching the filter, restricted
+// to the context's tenant and app. The filter's Limit defaults to
+// DefaultPageSize and is clamped by [Engine.PageLimit].
 func (e *Engine) ListPolicies(ctx context.Context, filter *policy.ListFilter) ([]*policy.Policy, error) {
-	return e.store.Policies().List(ctx, filter)
+	f := policyFilter(ctx, filter)
+	f.Limit = e.PageLimit(f.Limit, DefaultPageSize)
+	return e.store.Policies().List(ctx, f)
 }
 
 // ──────────────────────────────────────────────────
 // Scope Management
 // ──────────────────────────────────────────────────
 
-// CreateScope creates a permission scope.
+// CreateScope creates a permission scope. A rate limit requires a window;
+// see [Engine.CheckScopeRateLimits].
 func (e *Engine) CreateScope(ctx context.Context, s *scope.Scope) error {
+	if err := e.checkWritable(ctx); err != nil {
+		return err
+	}
+	if s.RateLimit < 0 || s.RateLimitWindow < 0 || (s.RateLimit > 0 && s.RateLimitWindow <= 0) {
+		return fmt.Errorf("%w: rate_limit must not be negative and needs a positive rate_limit_window", ErrInvalidScope)
+	}
+	if err := e.validateMetadata(s.Metadata, nil); err != nil {
+		return err
+	}
 	sc := scopeFromContext(ctx)
 	s.ID = id.NewScopeID()
 	s.TenantID = sc.tenantID
 	s.AppID = sc.appID
 	s.CreatedAt = time.Now()
-	return e.store.Scopes().Create(ctx, s)
+	if err := e.store.Scopes().Create(ctx, s); err != nil {
+		return err
+	}
+	e.bumpRevision(ctx, s.TenantID)
+	return nil
 }
 
-// ListScopes returns scopes for the tenant.
+// ListScopes returns a page of scopes for the tenant, restricted to the
+// context's app. The filter's Limit defaults to DefaultPageSize and is
+// clamped by [Engine.PageLimit]; use IterateScopes for every scope.
 func (e *Engine) ListScopes(ctx context.Context, filter *scope.ListFilter) ([]*scope.Scope, error) {
-	return e.store.Scopes().List(ctx, filter)
+	f := scopeFilter(ctx, filter)
+	f.Limit = e.PageLimit(f.Limit, DefaultPageSize)
+	return e.store.Scopes().List(ctx, f)
 }
 
 // DeleteScope deletes a scope by ID.
 func (e *Engine) DeleteScope(ctx context.Context, scopeID id.ScopeID) error {
-	return e.store.Scopes().Delete(ctx, scopeID)
+	if err := e.checkWritable(ctx); err != nil {
+		return err
+	}
+	s, err := e.store.Scopes().Get(ctx, scopeID)
+	if err != nil {
+		return err
+	}
+	if !scopeFromContext(ctx).owns(s.TenantID, s.AppID) {
+		return fmt.Errorf("%w: %s", ErrScopeNotFound, scopeID)
+	}
+	if err := e.store.Scopes().Delete(ctx, scopeID); err != nil {
+		return err
+	}
+	e.invalidateAll()
+	e.bumpRevision(ctx, s.TenantID)
+	return nil
 }
 
 // AssignScopes assigns scopes to a key by name.
 func (e *Engine) AssignScopes(ctx context.Context, keyID id.KeyID, scopeNames []string) error {
-	return e.store.Scopes().AssignToKey(ctx, keyID, scopeNames)
+	if err := e.checkWritable(ctx); err != nil {
+		return err
+	}
+	if err := e.checkKey(ctx, keyID); err != nil {
+		return err
+	}
+	if err := e.store.Scopes().AssignToKey(ctx, keyID, scopeNames); err != nil {
+		return err
+	}
+	e.invalidateKey(keyID)
+	e.bumpKeyRevision(ctx, keyID)
+	return nil
 }
 
 // RemoveScopes removes scopes from a key by name.
 func (e *Engine) RemoveScopes(ctx context.Context, keyID id.KeyID, scopeNames []string) error {
-	return e.store.Scopes().RemoveFromKey(ctx, keyID, scopeNames)
+	if err := e.checkWritable(ctx); err != nil {
+		return err
+	}
+	if err := e.checkKey(ctx, keyID); err != nil {
+		return err
+	}
+	if err := e.store.Scopes().RemoveFromKey(ctx, keyID, scopeNames); err != nil {
+		return err
+	}
+	e.invalidateKey(keyID)
+	e.bumpKeyRevision(ctx, keyID)
+	return nil
 }
 
 // ──────────────────────────────────────────────────
 // Usage & Analytics
 // ──────────────────────────────────────────────────
 
-// RecordUsage records a single usage event for a key.
+// RecordUsage records a single usage event for a key and buffers its
+// endpoint for [Engine.ListEndpointActivity]. The policy set with
+// [WithUsageRecording] may sample the record, keep only its endpoint
+// activity, or drop it, and so may the profile of the key's environment
+// for requests no rule matches; see [WithEnvironmentProfile]. The record's
+// Environment is filled from the key when unset.
 func (e *Engine) RecordUsage(ctx context.Context, rec *usage.Record) error {
+	if err := e.checkUsageWritable(); err != nil {
+		return err
+	}
+	if e.adaptive != nil {
+		e.adaptive.observe(rec.KeyID, rec.StatusCode, e.now())
+	}
+	e.applyProfileRecording(ctx, rec)
+	s := e.recording.Load()
+	if s == nil {
+		s = defaultRecording
+	}
+	row, count := s.decide(rec)
+	if !row && !count {
+		return nil
+	}
+
+	sc := scopeFromContext(ctx)
+	if rec.TenantID == "" {
+		rec.TenantID = sc.tenantID
+	}
+	if rec.AppID == "" {
+		rec.AppID = sc.appID
+	}
 	rec.ID = id.NewUsageID()
 	rec.CreatedAt = time.Now()
-	return e.store.Usages().Record(ctx, rec)
+	e.applyIPHandling(ctx, rec)
+	if row {
+		if err := e.store.Usages().Record(ctx, rec); err != nil {
+			return err
+		}
+	}
+	e.trackEndpoint(ctx, rec)
+	return nil
 }
 
-// QueryUsage queries usage records.
+// QueryUsage queries a page of usage records in the context's tenant and
+// app. The filter's Limit defaults to DefaultUsagePageSize and is clamped by
+// [Engine.PageLimit].
 func (e *Engine) QueryUsage(ctx context.Context, filter *usage.QueryFilter) ([]*usage.Record, error) {
+	filter, err := e.usageFilter(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	filter.Limit = e.PageLimit(filter.Limit, DefaultUsagePageSize)
 	return e.store.Usages().Query(ctx, filter)
 }
 
-// AggregateUsage returns aggregated usage statistics.
+// AggregateUsage returns a page of aggregated usage statistics for the
+// context's tenant and app. The filter's Limit defaults to
+// DefaultUsagePageSize and is clamped by [Engine.PageLimit].
 func (e *Engine) AggregateUsage(ctx context.Context, filter *usage.QueryFilter) ([]*usage.Aggregation, error) {
+	filter, err := e.usageFilter(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	filter.Limit = e.PageLimit(filter.Limit, DefaultUsagePageSize)
 	return e.store.Usages().Aggregate(ctx, filter)
 }
 
-// ListRotations returns rotation records matching the filter.
+// ListRotations returns a page of rotation records matching the filter,
+// restricted to the context's tenant and app. The filter's Limit defaults to
+// DefaultPageSize and is clamped by [Engine.PageLimit].
 func (e *Engine) ListRotations(ctx context.Context, filter *rotation.ListFilter) ([]*rotation.Record, error) {
+	filter, err := e.rotationFilter(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	filter.Limit = e.PageLimit(filter.Limit, DefaultPageSize)
 	return e.store.Rotations().List(ctx, filter)
 }
 
+// CountRotationsByReason counts the rotation records matching the filter per
+// reason, most frequent first, restricted to the context's tenant and app.
+// The filter's Limit and Offset are ignored. Reasons recorded before
+// validation was added are counted under the strings they were stored with.
+func (e *Engine) CountRotationsByReason(ctx context.Context, filter *rotation.ListFilter) ([]*rotation.ReasonCount, error) {
+	filter, err := e.rotationFilter(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return e.store.Rotations().CountByReason(ctx, filter)
+}
+
 // ──────────────────────────────────────────────────
 // Cleanup
 // ──────────────────────────────────────────────────
 
 // CleanupExpiredKeys finds and marks expired keys.
-func (e *Engine) CleanupExpiredKeys(ctx context.Context) error {
-	keys, err := e.store.Keys().ListExpired(ctx, time.Now())
-	if err != nil {
-		return fmt.Errorf("list expired keys: %w", err)
+func (e *Engine) CleanupExpiredKeys(ctx context.Context) (*CleanupResult, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, err
 	}
-	for _, k := range keys {
-		if err := e.store.Keys().UpdateState(ctx, k.ID, key.StateExpired); err != nil {
+	res := &CleanupResult{DryRun: IsDryRun(ctx)}
+	cutoff := e.now().Add(-e.expirySkew)
+	err := e.store.Keys().Iterate(ctx, &key.ListFilter{State: key.StateActive, ExpiresBefore: &cutoff}, func(k *key.Key) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if res.DryRun {
+			res.KeyIDs = append(res.KeyIDs, k.ID)
+			return nil
+		}
+		ok, err := e.store.Keys().UpdateStateIf(ctx, k.ID, key.StateActive, key.StateExpired)
+		if err != nil {
 			e.logger.Warn("failed to expire key", log.String("key_id", k.ID.String()), log.Any("error", err))
-			continue
+			res.Failed++
+			return nil
 		}
-		_ = e.hooks.FireKeyExpired(ctx, k)
+		if !ok {
+			// Expired concurrently, e.g., by a validation.
+			return nil
+		}
+		res.KeyIDs = append(res.KeyIDs, k.ID)
+		e.invalidateKey(k.ID)
+		e.recordRevocation(ctx, k, key.StateExpired, true)
+		_ = e.hooks.FireKeyExpired(ctx, k, e.eventMeta(ctx, plugin.TriggerSweep, plugin.ReasonKeyExpired))
+		return nil
+	})
+	if err != nil {
+		return res, fmt.Errorf("iterate expired keys: %w", err)
 	}
-	return nil
+	return res, nil
 }
 
-// CleanupGraceExpired revokes keys whose grace period has ended.
-func (e *Engine) CleanupGraceExpired(ctx context.Context) error {
-	recs, err := e.store.Rotations().ListPendingGrace(ctx, time.Now())
+// CleanupGraceExpired retires the secrets rotations replaced once their
+// grace period, including any extended grace, has ended, so that the old
+// hash no longer matches the key, e.g. in GetByHashes. Keys a rotation left
+// in StateRotated, from before rotations kept the key active, are revoked.
+// Rotations whose grace ended more than the revocation lookback ago are not
+// revisited; run it at least that often.
+func (e *Engine) CleanupGraceExpired(ctx context.Context) (*CleanupResult, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	res := &CleanupResult{DryRun: IsDryRun(ctx)}
+	now := e.now()
+	recs, err := e.store.Rotations().ListPendingGrace(ctx, now.Add(-e.revocationLookback))
 	if err != nil {
-		return fmt.Errorf("list pending grace: %w", err)
+		return nil, fmt.Errorf("list pending grace: %w", err)
 	}
 	for _, rec := range recs {
-		if time.Now().After(rec.GraceEnds) {
-			if err := e.store.Keys().UpdateState(ctx, rec.KeyID, key.StateRevoked); err != nil {
-				e.logger.Warn("failed to revoke grace-expired key", log.String("key_id", rec.KeyID.String()), log.Any("error", err))
-			}
+		if !now.After(rec.GraceEnds) {
+			continue
+		}
+		k, err := e.store.Keys().Get(ctx, rec.KeyID)
+		if err != nil {
+			// Deleted since, with its hashes.
+			continue
+		}
+		if graceEnds, _ := e.graceEnds(k, rec.GraceEnds, now); !now.After(graceEnds) {
+			continue
+		}
+		if k.State == key.StateRotated {
+			e.cleanupRotatedKey(ctx, k, res)
+			continue
+		}
+		if !e.hashMatches(ctx, k.ID, rec.OldKeyHash) {
+			// Retired already, or replaced by a later rotation.
+			continue
+		}
+		if res.DryRun {
+			res.KeyIDs = append(res.KeyIDs, rec.KeyID)
+			continue
+		}
+		if err := e.store.Keys().DeactivateHash(ctx, rec.KeyID, rec.OldKeyHash); err != nil {
+			e.logger.Warn("failed to retire rotated key hash", log.String("key_id", rec.KeyID.String()), log.Any("error", err))
+			res.Failed++
+			continue
 		}
+		e.invalidateKey(rec.KeyID)
+		e.appendRevocation(ctx, &revocation.Entry{
+			KeyID:      rec.KeyID,
+			TenantID:   rec.TenantID,
+			HashPrefix: revocation.HashPrefix(rec.OldKeyHash),
+			State:      k.State,
+			Revoked:    true,
+		})
+		res.KeyIDs = append(res.KeyIDs, rec.KeyID)
 	}
-	return nil
+	return res, nil
+}
+
+// cleanupRotatedKey revokes k, left in StateRotated by a rotation whose grace
+// has ended, and records it in res.
+func (e *Engine) cleanupRotatedKey(ctx context.Context, k *key.Key, res *CleanupResult) {
+	if res.DryRun {
+		res.KeyIDs = append(res.KeyIDs, k.ID)
+		return
+	}
+	ok, err := e.store.Keys().UpdateStateIf(ctx, k.ID, key.StateRotated, key.StateRevoked)
+	if err != nil {
+		e.logger.Warn("failed to revoke grace-expired key", log.String("key_id", k.ID.String()), log.Any("error", err))
+		res.Failed++
+		return
+	}
+	if !ok {
+		return
+	}
+	e.invalidateKey(k.ID)
+	e.recordRevocation(ctx, k, key.StateRevoked, true)
+	res.KeyIDs = append(res.KeyIDs, k.ID)
 }
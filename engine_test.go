@@ -2,6 +2,8 @@ package keysmith_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -9,10 +11,13 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
 	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keysmithtest"
 	"github.com/xraph/keysmith/policy"
 	"github.com/xraph/keysmith/rotation"
 	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/store/chaos"
 	"github.com/xraph/keysmith/store/memory"
 	"github.com/xraph/keysmith/usage"
 )
@@ -82,62 +87,39 @@ func TestValidateKey(t *testing.T) {
 }
 
 func TestRevokeKey(t *testing.T) {
-	eng := newTestEngine(t)
-	ctx := testCtx()
+	eng := keysmithtest.NewEngine(t)
+	ctx := keysmithtest.Context()
+	result := keysmithtest.NewKey(eng).WithName("Revoke Test").MustCreate(t, ctx)
 
-	result, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
-		Name:        "Revoke Test",
-		Prefix:      "sk",
-		Environment: key.EnvTest,
-	})
-	require.NoError(t, err)
-
-	err = eng.RevokeKey(ctx, result.Key.ID, "test revocation")
+	err := eng.RevokeKey(ctx, result.Key.ID, "test revocation")
 	require.NoError(t, err)
 
-	_, err = eng.ValidateKey(ctx, result.RawKey)
-	assert.ErrorIs(t, err, keysmith.ErrKeyInactive)
+	keysmithtest.AssertRejectedWith(t, eng, ctx, result.RawKey, keysmith.ErrKeyInactive)
 }
 
 func TestSuspendAndReactivateKey(t *testing.T) {
-	eng := newTestEngine(t)
-	ctx := testCtx()
-
-	result, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
-		Name:        "Suspend Test",
-		Prefix:      "sk",
-		Environment: key.EnvTest,
-	})
-	require.NoError(t, err)
-
-	// Suspend.
-	err = eng.SuspendKey(ctx, result.Key.ID)
-	require.NoError(t, err)
+	eng := keysmithtest.NewEngine(t)
+	ctx := keysmithtest.Context()
+	result := keysmithtest.NewKey(eng).WithName("Suspend Test").Suspended().MustCreate(t, ctx)
+	assert.Equal(t, key.StateSuspended, result.Key.State)
 
-	_, err = eng.ValidateKey(ctx, result.RawKey)
-	assert.ErrorIs(t, err, keysmith.ErrKeyInactive)
+	keysmithtest.AssertRejectedWith(t, eng, ctx, result.RawKey, keysmith.ErrKeyInactive)
 
 	// Reactivate.
-	err = eng.ReactivateKey(ctx, result.Key.ID)
+	err := eng.ReactivateKey(ctx, result.Key.ID)
 	require.NoError(t, err)
 
-	vr, err := eng.ValidateKey(ctx, result.RawKey)
-	require.NoError(t, err)
+	vr := keysmithtest.AssertValidates(t, eng, ctx, result.RawKey)
+	require.NotNil(t, vr)
 	assert.Equal(t, result.Key.ID.String(), vr.Key.ID.String())
 }
 
 func TestReactivateKey_InvalidState(t *testing.T) {
-	eng := newTestEngine(t)
-	ctx := testCtx()
+	eng := keysmithtest.NewEngine(t)
+	ctx := keysmithtest.Context()
+	result := keysmithtest.NewKey(eng).WithName("Active Key").MustCreate(t, ctx)
 
-	result, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
-		Name:        "Active Key",
-		Prefix:      "sk",
-		Environment: key.EnvTest,
-	})
-	require.NoError(t, err)
-
-	err = eng.ReactivateKey(ctx, result.Key.ID)
+	err := eng.ReactivateKey(ctx, result.Key.ID)
 	assert.ErrorIs(t, err, keysmith.ErrInvalidStateTransition)
 }
 
@@ -163,27 +145,116 @@ func TestRotateKey(t *testing.T) {
 	vr, err := eng.ValidateKey(ctx, rotated.RawKey)
 	require.NoError(t, err)
 	assert.Equal(t, original.Key.ID.String(), vr.Key.ID.String())
+	assert.False(t, vr.UsingRotatedKey)
+	assert.Equal(t, key.StateActive, vr.Key.State)
+
+	// Old key still validates within the grace period.
+	vr, err = eng.ValidateKey(ctx, original.RawKey)
+	require.NoError(t, err)
+	assert.Equal(t, original.Key.ID.String(), vr.Key.ID.String())
+	assert.True(t, vr.UsingRotatedKey)
+	assert.Equal(t, key.StateRotated, vr.Key.State)
 
-	// Old key should fail.
+	// The rotation record keeps both hints.
+	kid := original.Key.ID
+	recs, err := eng.ListRotations(ctx, &rotation.ListFilter{KeyID: &kid})
+	require.NoError(t, err)
+	require.Len(t, recs, 1)
+	assert.Equal(t, original.Key.Hint, recs[0].OldHint)
+	assert.Equal(t, rotated.Key.Hint, recs[0].NewHint)
+	assert.Equal(t, rotated.RawKey[len(rotated.RawKey)-4:], rotated.Key.Hint)
+}
+
+func TestRotateKey_GracePeriodEnds(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []keysmith.Option
+	}{
+		{"uncached", nil},
+		{"cached", []keysmith.Option{keysmith.WithValidationCache(time.Hour, 100)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := &fakeClock{t: time.Now()}
+			eng, err := keysmith.NewEngine(append([]keysmith.Option{keysmith.WithStore(memory.New()), keysmith.WithClock(clock.Now)}, tc.opts...)...)
+			require.NoError(t, err)
+			ctx := testCtx()
+			pol := &policy.Policy{Name: "Grace", RateLimit: 100, RateLimitWindow: time.Minute, GracePeriod: time.Hour}
+			require.NoError(t, eng.CreatePolicy(ctx, pol))
+			original, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Grace Test", Prefix: "sk", Environment: key.EnvLive, PolicyID: &pol.ID})
+			require.NoError(t, err)
+			rotated, err := eng.RotateKey(ctx, original.Key.ID, rotation.ReasonManual)
+			require.NoError(t, err)
+
+			clock.Set(clock.Now().Add(59 * time.Minute))
+			vr, err := eng.ValidateKey(ctx, original.RawKey)
+			require.NoError(t, err, "within grace")
+			assert.True(t, vr.UsingRotatedKey)
+
+			clock.Set(clock.Now().Add(2 * time.Minute))
+			for range 3 {
+				_, err = eng.ValidateKey(ctx, original.RawKey)
+				assert.ErrorIs(t, err, keysmith.ErrKeyRevoked, "after grace")
+			}
+			res, err := eng.CleanupGraceExpired(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, []id.KeyID{original.Key.ID}, res.KeyIDs)
+			for range 2 {
+				_, err = eng.ValidateKey(ctx, original.RawKey)
+				assert.ErrorIs(t, err, keysmith.ErrInvalidKey, "cleanup retires the old hash")
+			}
+
+			vr, err = eng.ValidateKey(ctx, rotated.RawKey)
+			require.NoError(t, err, "the new key is unaffected")
+			assert.False(t, vr.UsingRotatedKey)
+			got, err := eng.GetKey(ctx, original.Key.ID)
+			require.NoError(t, err)
+			assert.Equal(t, key.StateActive, got.State)
+		})
+	}
+}
+
+func TestRotateKey_RecordFailureRetiresOldHash(t *testing.T) {
+	cs := chaos.New(memory.New())
+	eng, err := keysmith.NewEngine(keysmith.WithStore(cs))
+	require.NoError(t, err)
+	ctx := testCtx()
+	original, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Unrecorded", Prefix: "sk", Environment: key.EnvLive})
+	require.NoError(t, err)
+	require.NoError(t, cs.Set(chaos.Rule{Name: "no-records", Store: chaos.StoreRotations, Method: "Create", Error: chaos.ErrorInjected}))
+
+	_, err = eng.RotateKey(ctx, original.Key.ID, rotation.ReasonManual)
+	require.ErrorIs(t, err, chaos.ErrInjected)
 	_, err = eng.ValidateKey(ctx, original.RawKey)
-	assert.ErrorIs(t, err, keysmith.ErrInvalidKey)
+	assert.ErrorIs(t, err, keysmith.ErrInvalidKey, "no record would ever end its grace")
 }
 
-func TestExpiredKey(t *testing.T) {
+func TestRotateKey_RotatingAgainRetiresOlderKey(t *testing.T) {
 	eng := newTestEngine(t)
 	ctx := testCtx()
 
-	past := time.Now().Add(-1 * time.Hour)
-	result, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
-		Name:        "Expired Key",
-		Prefix:      "sk",
-		Environment: key.EnvTest,
-		ExpiresAt:   &past,
-	})
+	first, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Twice", Prefix: "sk", Environment: key.EnvLive})
+	require.NoError(t, err)
+	second, err := eng.RotateKey(ctx, first.Key.ID, rotation.ReasonManual)
+	require.NoError(t, err)
+	third, err := eng.RotateKey(ctx, first.Key.ID, rotation.ReasonManual)
 	require.NoError(t, err)
 
-	_, err = eng.ValidateKey(ctx, result.RawKey)
-	assert.ErrorIs(t, err, keysmith.ErrKeyExpired)
+	_, err = eng.ValidateKey(ctx, first.RawKey)
+	assert.ErrorIs(t, err, keysmith.ErrInvalidKey, "only the latest rotation's old key has grace")
+	vr, err := eng.ValidateKey(ctx, second.RawKey)
+	require.NoError(t, err)
+	assert.True(t, vr.UsingRotatedKey)
+	vr, err = eng.ValidateKey(ctx, third.RawKey)
+	require.NoError(t, err)
+	assert.False(t, vr.UsingRotatedKey)
+}
+
+func TestExpiredKey(t *testing.T) {
+	eng := keysmithtest.NewEngine(t)
+	ctx := keysmithtest.Context()
+	result := keysmithtest.NewKey(eng).WithName("Expired Key").Expired().MustCreate(t, ctx)
+
+	keysmithtest.AssertRejectedWith(t, eng, ctx, result.RawKey, keysmith.ErrKeyExpired)
 }
 
 func TestListKeys(t *testing.T) {
@@ -204,6 +275,68 @@ func TestListKeys(t *testing.T) {
 	assert.Len(t, keys, 3)
 }
 
+func TestIterateKeys(t *testing.T) {
+	eng := newTestEngine(t)
+	ctx := testCtx()
+
+	for i := 0; i < 3; i++ {
+		_, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
+			Name:        "Key",
+			Prefix:      "sk",
+			Environment: key.EnvTest,
+		})
+		require.NoError(t, err)
+	}
+
+	visited := 0
+	require.NoError(t, eng.IterateKeys(ctx, &key.ListFilter{}, func(*key.Key) error {
+		visited++
+		return nil
+	}))
+	assert.Equal(t, 3, visited)
+
+	stop := errors.New("stop")
+	visited = 0
+	err := eng.IterateKeys(ctx, &key.ListFilter{}, func(*key.Key) error {
+		visited++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, visited)
+}
+
+func TestCleanupExpiredKeys(t *testing.T) {
+	eng := newTestEngine(t)
+	ctx := testCtx()
+
+	past := time.Now().Add(-1 * time.Hour)
+	expired, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
+		Name:        "Expired Key",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+		ExpiresAt:   &past,
+	})
+	require.NoError(t, err)
+	live, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
+		Name:        "Live Key",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+	})
+	require.NoError(t, err)
+
+	res, err := eng.CleanupExpiredKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []id.KeyID{expired.Key.ID}, res.KeyIDs)
+
+	k, err := eng.GetKey(ctx, expired.Key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, key.StateExpired, k.State)
+
+	k, err = eng.GetKey(ctx, live.Key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, key.StateActive, k.State)
+}
+
 func TestPolicyCRUD(t *testing.T) {
 	eng := newTestEngine(t)
 	ctx := testCtx()
@@ -274,28 +407,121 @@ func TestScopeCRUD(t *testing.T) {
 }
 
 func TestCreateKeyWithScopes(t *testing.T) {
-	eng := newTestEngine(t)
+	eng := keysmithtest.NewEngine(t)
+	ctx := keysmithtest.Context()
+	result := keysmithtest.NewKey(eng).
+		WithName("Scoped Key").
+		WithEnvironment(key.EnvLive).
+		WithScopes("read:users", "write:users").
+		MustCreate(t, ctx)
+	assert.Equal(t, []string{"read:users", "write:users"}, result.Key.Scopes)
+
+	// Validate should return scopes.
+	vr := keysmithtest.AssertValidates(t, eng, ctx, result.RawKey)
+	require.NotNil(t, vr)
+	assert.Len(t, vr.Scopes, 2)
+}
+
+func TestCreateKey_ScopeAssignmentFailureRollsBack(t *testing.T) {
+	rec := keysmithtest.NewRecorder()
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithExtension(rec))
+	require.NoError(t, err)
 	ctx := testCtx()
+	require.NoError(t, eng.CreateScope(ctx, &scope.Scope{Name: "read"}))
 
-	// Create scopes first.
-	for _, name := range []string{"read:users", "write:users"} {
-		err := eng.CreateScope(ctx, &scope.Scope{Name: name})
-		require.NoError(t, err)
-	}
+	_, err = eng.CreateKey(ctx, &keysmith.CreateKeyInput{
+		Name:         "Half Made",
+		Prefix:       "sk",
+		Environment:  key.EnvTest,
+		Scopes:       []string{"read", "missing"},
+		InlinePolicy: &policy.Policy{RateLimit: 10, RateLimitWindow: time.Minute},
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "assign scopes")
 
-	result, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
-		Name:        "Scoped Key",
+	keys, err := eng.ListKeys(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, keys, "the stored key is deleted again")
+	pols, err := eng.ListPolicies(ctx, &policy.ListFilter{IncludeInline: true})
+	require.NoError(t, err)
+	assert.Empty(t, pols, "the inline policy is deleted with it")
+	assert.Equal(t, 1, rec.Count("KeyCreateFailed"))
+	assert.Zero(t, rec.Count("KeyCreated"))
+}
+
+func TestCreateKey_ScopeAssignmentFailureRevokesUndeletableKey(t *testing.T) {
+	cs := chaos.New(memory.New())
+	eng, err := keysmith.NewEngine(keysmith.WithStore(cs))
+	require.NoError(t, err)
+	ctx := testCtx()
+	require.NoError(t, cs.Set(chaos.Rule{Name: "no-deletes", Store: chaos.StoreKeys, Method: "Delete", Error: chaos.ErrorInjected}))
+
+	_, err = eng.CreateKey(ctx, &keysmith.CreateKeyInput{
+		Name:        "Half Made",
 		Prefix:      "sk",
-		Environment: key.EnvLive,
-		Scopes:      []string{"read:users", "write:users"},
+		Environment: key.EnvTest,
+		Scopes:      []string{"missing"},
 	})
-	require.NoError(t, err)
-	assert.Equal(t, []string{"read:users", "write:users"}, result.Key.Scopes)
+	require.Error(t, err)
 
-	// Validate should return scopes.
-	vr, err := eng.ValidateKey(ctx, result.RawKey)
+	keys, err := eng.ListKeys(ctx, nil)
 	require.NoError(t, err)
-	assert.Len(t, vr.Scopes, 2)
+	require.Len(t, keys, 1, "the key could not be deleted")
+	assert.Equal(t, key.StateRevoked, keys[0].State, "so it is revoked instead")
+}
+
+// cancelingStore cancels the context of a scope assignment, as a client
+// disconnecting mid-request would, and fails it with the context's error.
+type cancelingStore struct {
+	*memory.Store
+	cancel context.CancelFunc
+}
+
+func (s *cancelingStore) Scopes() scope.Store {
+	return &cancelingScopeStore{Store: s.Store.Scopes(), cancel: s.cancel}
+}
+
+type cancelingScopeStore struct {
+	scope.Store
+	cancel context.CancelFunc
+}
+
+func (s *cancelingScopeStore) AssignToKey(ctx context.Context, _ id.KeyID, _ []string) error {
+	s.cancel()
+	return ctx.Err()
+}
+
+func TestCreateKey_CanceledScopeAssignmentRollsBack(t *testing.T) {
+	for _, undeletable := range []bool{false, true} {
+		t.Run(fmt.Sprintf("undeletable=%v", undeletable), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(testCtx())
+			defer cancel()
+			cs := chaos.New(&cancelingStore{Store: memory.New(), cancel: cancel})
+			if undeletable {
+				require.NoError(t, cs.Set(chaos.Rule{Name: "no-deletes", Store: chaos.StoreKeys, Method: "Delete", Error: chaos.ErrorInjected}))
+			}
+			eng, err := keysmith.NewEngine(keysmith.WithStore(cs))
+			require.NoError(t, err)
+			require.NoError(t, eng.CreateScope(testCtx(), &scope.Scope{Name: "read"}))
+
+			_, err = eng.CreateKey(ctx, &keysmith.CreateKeyInput{
+				Name:        "Half Made",
+				Prefix:      "sk",
+				Environment: key.EnvTest,
+				Scopes:      []string{"read"},
+			})
+			require.ErrorIs(t, err, context.Canceled)
+
+			keys, err := eng.ListKeys(testCtx(), nil)
+			require.NoError(t, err)
+			if !undeletable {
+				assert.Empty(t, keys, "the key is deleted although the request was canceled")
+				return
+			}
+			require.Len(t, keys, 1)
+			assert.Equal(t, key.StateRevoked, keys[0].State, "the key is revoked although the request was canceled")
+		})
+	}
 }
 
 func TestRecordUsage(t *testing.T) {
@@ -0,0 +1,62 @@
+package keysmith
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	log "github.com/xraph/go-utils/log"
+
+	"github.com/xraph/keysmith/key"
+)
+
+// generatorSelfTestKeys is how many keys Start generates and discards to
+// self-test the key generator.
+const generatorSelfTestKeys = 32
+
+// EntropyChecker is implemented by a [KeyGenerator] that can health-check
+// its entropy source, as [DefaultKeyGenerator] does. Start calls it before
+// the generator self-test.
+type EntropyChecker interface {
+	CheckEntropy() error
+}
+
+// selfTestGenerator checks the key generator's entropy source, then
+// generates generatorSelfTestKeys keys and checks that none repeats and
+// each has the {prefix}_{env}_ form, with the default generator's hex
+// random part.
+func (e *Engine) selfTestGenerator() error {
+	if c, ok := e.generator.(EntropyChecker); ok {
+		if err := c.CheckEntropy(); err != nil {
+			return fmt.Errorf("%w: %w", ErrKeyGeneratorSelfTest, err)
+		}
+	}
+	const prefix, env = "sk", key.EnvLive
+	head := prefix + "_" + string(env) + "_"
+	seen := make(map[string]struct{}, generatorSelfTestKeys)
+	for i := range generatorSelfTestKeys {
+		raw, err := e.generator.Generate(prefix, env)
+		if err != nil {
+			return fmt.Errorf("%w: key %d: %w", ErrKeyGeneratorSelfTest, i+1, err)
+		}
+		random, ok := strings.CutPrefix(raw, head)
+		if !ok || random == "" {
+			return fmt.Errorf("%w: key %d does not have the form %s<random>", ErrKeyGeneratorSelfTest, i+1, head)
+		}
+		if g, ok := e.generator.(*defaultGenerator); ok {
+			if b, err := hex.DecodeString(random); err != nil || len(b) != g.byteLen {
+				return fmt.Errorf("%w: key %d does not end in %d hex characters", ErrKeyGeneratorSelfTest, i+1, 2*g.byteLen)
+			}
+		}
+		if _, dup := seen[raw]; dup {
+			return fmt.Errorf("%w: key %d repeats an earlier key", ErrKeyGeneratorSelfTest, i+1)
+		}
+		seen[raw] = struct{}{}
+	}
+	e.logger.Info("key generator self-test passed",
+		log.String("algorithm", algorithmOf(e.generator)),
+		log.Int("entropy_bits", entropyBitsOf(e.generator)),
+		log.Int("keys", generatorSelfTestKeys),
+	)
+	return nil
+}
@@ -0,0 +1,18 @@
+// Package pkcs11 draws key material from a PKCS#11 token, such as an HSM,
+// for [keysmith.WithEntropySource]. It needs cgo and is built only with the
+// pkcs11 tag:
+//
+//	src, err := pkcs11.Open(pkcs11.Config{
+//		Module:     "/usr/lib/softhsm/libsofthsm2.so",
+//		TokenLabel: "keysmith",
+//		PIN:        os.Getenv("HSM_PIN"),
+//	})
+//	if err != nil { ... }
+//	defer src.Close()
+//	eng, err := keysmith.NewEngine(
+//		keysmith.WithKeyGenerator(keysmith.DefaultKeyGenerator(keysmith.WithEntropySource(src))),
+//		...
+//	)
+//
+// The source reports [keysmith.AlgPKCS11], which FIPS mode accepts.
+package pkcs11
@@ -0,0 +1,24 @@
+module github.com/xraph/keysmith/entropy/pkcs11
+
+go 1.25.7
+
+require (
+	github.com/miekg/pkcs11 v1.1.1
+	github.com/stretchr/testify v1.11.1
+	github.com/xraph/keysmith v0.0.0-00010101000000-000000000000
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc // indirect
+	github.com/gofrs/uuid/v5 v5.3.2 // indirect
+	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
+	github.com/xraph/go-utils v1.1.1 // indirect
+	go.jetify.com/typeid/v2 v2.0.0-alpha.3 // indirect
+	go.uber.org/multierr v1.11.0 // indirect
+	go.uber.org/zap v1.27.1 // indirect
+	golang.org/x/sync v0.19.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/xraph/keysmith => ../..
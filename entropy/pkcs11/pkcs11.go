@@ -0,0 +1,153 @@
+//go:build pkcs11
+
+package pkcs11
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	p11 "github.com/miekg/pkcs11"
+
+	"github.com/xraph/keysmith"
+)
+
+// maxRandomBytes bounds each C_GenerateRandom call; larger reads are split.
+const maxRandomBytes = 1024
+
+// Compile-time interface checks.
+var (
+	_ io.Reader                  = (*Source)(nil)
+	_ keysmith.AlgorithmReporter = (*Source)(nil)
+)
+
+// Config selects the token a Source draws from.
+type Config struct {
+	// Module is the path of the PKCS#11 library, such as libsofthsm2.so.
+	Module string
+
+	// TokenLabel selects the token with this label. It takes precedence
+	// over Slot.
+	TokenLabel string
+
+	// Slot selects the token in this slot when TokenLabel is empty.
+	Slot uint
+
+	// PIN logs the session in as the user. Empty skips the login, for
+	// tokens that generate random data without one.
+	PIN string
+}
+
+// Source reads random bytes from a PKCS#11 token with C_GenerateRandom. It
+// holds one session, and is safe for concurrent use.
+type Source struct {
+	mu      sync.Mutex
+	ctx     *p11.Ctx
+	session p11.SessionHandle
+	closed  bool
+}
+
+// Open loads cfg.Module, opens a session with the selected token, and logs
+// in when cfg.PIN is set.
+func Open(cfg Config) (*Source, error) {
+	if cfg.Module == "" {
+		return nil, errors.New("pkcs11: module path is required")
+	}
+	ctx := p11.New(cfg.Module)
+	if ctx == nil {
+		return nil, fmt.Errorf("pkcs11: load module %q", cfg.Module)
+	}
+	if err := ctx.Initialize(); err != nil {
+		ctx.Destroy()
+		return nil, fmt.Errorf("pkcs11: initialize: %w", err)
+	}
+	s := &Source{ctx: ctx}
+	slot, err := findSlot(ctx, cfg)
+	if err != nil {
+		s.release()
+		return nil, err
+	}
+	s.session, err = ctx.OpenSession(slot, p11.CKF_SERIAL_SESSION)
+	if err != nil {
+		s.release()
+		return nil, fmt.Errorf("pkcs11: open session on slot %d: %w", slot, err)
+	}
+	if cfg.PIN != "" {
+		if err := ctx.Login(s.session, p11.CKU_USER, cfg.PIN); err != nil {
+			_ = ctx.CloseSession(s.session)
+			s.release()
+			return nil, fmt.Errorf("pkcs11: login: %w", err)
+		}
+	}
+	return s, nil
+}
+
+func findSlot(ctx *p11.Ctx, cfg Config) (uint, error) {
+	slots, err := ctx.GetSlotList(true)
+	if err != nil {
+		return 0, fmt.Errorf("pkcs11: list slots: %w", err)
+	}
+	for _, slot := range slots {
+		if cfg.TokenLabel == "" {
+			if slot == cfg.Slot {
+				return slot, nil
+			}
+			continue
+		}
+		info, err := ctx.GetTokenInfo(slot)
+		if err != nil {
+			return 0, fmt.Errorf("pkcs11: token info of slot %d: %w", slot, err)
+		}
+		if info.Label == cfg.TokenLabel {
+			return slot, nil
+		}
+	}
+	if cfg.TokenLabel != "" {
+		return 0, fmt.Errorf("pkcs11: no token labelled %q", cfg.TokenLabel)
+	}
+	return 0, fmt.Errorf("pkcs11: no token in slot %d", cfg.Slot)
+}
+
+// Algorithm reports [keysmith.AlgPKCS11].
+func (s *Source) Algorithm() string { return keysmith.AlgPKCS11 }
+
+// Read fills p with random bytes from the token.
+func (s *Source) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return 0, errors.New("pkcs11: source is closed")
+	}
+	n := 0
+	for n < len(p) {
+		b, err := s.ctx.GenerateRandom(s.session, min(len(p)-n, maxRandomBytes))
+		if err != nil {
+			return n, fmt.Errorf("pkcs11: generate random: %w", err)
+		}
+		n += copy(p[n:], b)
+	}
+	return n, nil
+}
+
+// Close logs out, closes the session, and unloads the module.
+func (s *Source) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	_ = s.ctx.Logout(s.session)
+	err := s.ctx.CloseSession(s.session)
+	s.release()
+	if err != nil {
+		return fmt.Errorf("pkcs11: close session: %w", err)
+	}
+	return nil
+}
+
+func (s *Source) release() {
+	_ = s.ctx.Finalize()
+	s.ctx.Destroy()
+}
@@ -0,0 +1,57 @@
+//go:build pkcs11
+
+package pkcs11_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/entropy/pkcs11"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+// TestSource runs against the token labelled KEYSMITH_TEST_PKCS11_TOKEN in
+// the module at KEYSMITH_TEST_PKCS11_MODULE, such as a SoftHSM token,
+// logging in with KEYSMITH_TEST_PKCS11_PIN.
+func TestSource(t *testing.T) {
+	module := os.Getenv("KEYSMITH_TEST_PKCS11_MODULE")
+	if module == "" {
+		t.Skip("KEYSMITH_TEST_PKCS11_MODULE is not set")
+	}
+	src, err := pkcs11.Open(pkcs11.Config{
+		Module:     module,
+		TokenLabel: os.Getenv("KEYSMITH_TEST_PKCS11_TOKEN"),
+		PIN:        os.Getenv("KEYSMITH_TEST_PKCS11_PIN"),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = src.Close() })
+
+	b := make([]byte, 3000)
+	n, err := src.Read(b)
+	require.NoError(t, err)
+	assert.Equal(t, len(b), n, "reads beyond one call are split")
+
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithKeyGenerator(keysmith.DefaultKeyGenerator(keysmith.WithEntropySource(src))),
+	)
+	require.NoError(t, err)
+	require.NoError(t, eng.Start(context.Background()))
+	t.Cleanup(func() { _ = eng.Stop(context.Background()) })
+	assert.Equal(t, keysmith.AlgPKCS11, eng.CryptoProfile().KeyGeneration)
+
+	created, err := eng.CreateKey(keysmith.WithTenant(context.Background(), "app_test", "tenant_test"),
+		&keysmith.CreateKeyInput{Name: "hsm", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	assert.Len(t, created.RawKey, len("sk_test_")+64)
+
+	require.NoError(t, src.Close())
+	_, err = src.Read(b)
+	assert.Error(t, err)
+}
@@ -0,0 +1,171 @@
+package keysmith_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+var errSourceDown = errors.New("hsm: token removed")
+
+// The test sources stand in for an HSM, and the test generators for
+// crypto/rand, so that they pass NewEngine in FIPS mode.
+
+// countingSource is a deterministic entropy source returning the byte
+// sequence 0, 1, 2, ... and failing once failAfter bytes were read, when
+// failAfter is positive.
+type countingSource struct {
+	read      int
+	failAfter int
+}
+
+func (s *countingSource) Read(p []byte) (int, error) {
+	for i := range p {
+		if s.failAfter > 0 && s.read == s.failAfter {
+			return i, errSourceDown
+		}
+		p[i] = byte(s.read)
+		s.read++
+	}
+	return len(p), nil
+}
+
+func (s *countingSource) Algorithm() string { return keysmith.AlgPKCS11 }
+
+// drbgSource is a deterministic entropy source returning SHA-256 of a
+// counter, which does not repeat within a test.
+type drbgSource struct {
+	counter uint64
+	buf     []byte
+}
+
+func (s *drbgSource) Read(p []byte) (int, error) {
+	for len(s.buf) < len(p) {
+		sum := sha256.Sum256(binary.BigEndian.AppendUint64(nil, s.counter))
+		s.counter++
+		s.buf = append(s.buf, sum[:]...)
+	}
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+func (s *drbgSource) Algorithm() string { return keysmith.AlgPKCS11 }
+
+// stuckSource returns only zeros.
+type stuckSource struct{}
+
+func (stuckSource) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func (stuckSource) Algorithm() string { return keysmith.AlgPKCS11 }
+
+func TestEntropySource_ConsumesKeyLength(t *testing.T) {
+	src := &countingSource{}
+	g := keysmith.DefaultKeyGenerator(keysmith.WithEntropySource(src))
+
+	raw, err := g.Generate("sk", key.EnvLive)
+	require.NoError(t, err)
+	assert.Equal(t, 32, src.read, "one key draws 32 bytes")
+	want := make([]byte, 32)
+	for i := range want {
+		want[i] = byte(i)
+	}
+	assert.Equal(t, "sk_live_"+hex.EncodeToString(want), raw)
+
+	_, err = g.Generate("sk", key.EnvLive)
+	require.NoError(t, err)
+	assert.Equal(t, 64, src.read)
+
+	assert.Equal(t, keysmith.AlgPKCS11, g.(keysmith.AlgorithmReporter).Algorithm())
+	assert.Equal(t, 256, g.(keysmith.EntropyReporter).EntropyBits())
+}
+
+func TestEntropySource_FailsMidGeneration(t *testing.T) {
+	g := keysmith.DefaultKeyGenerator(keysmith.WithEntropySource(&countingSource{failAfter: 40}))
+
+	_, err := g.Generate("sk", key.EnvLive)
+	require.NoError(t, err)
+	_, err = g.Generate("sk", key.EnvLive)
+	assert.ErrorIs(t, err, errSourceDown, "the second key runs out after 8 bytes")
+}
+
+func TestEntropySource_Profile(t *testing.T) {
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithKeyGenerator(keysmith.DefaultKeyGenerator(keysmith.WithEntropySource(&countingSource{}))),
+	)
+	require.NoError(t, err)
+	p := eng.CryptoProfile()
+	assert.Equal(t, keysmith.AlgPKCS11, p.KeyGeneration)
+	assert.Equal(t, 256, p.KeyEntropyBits)
+
+	g := keysmith.DefaultKeyGenerator(keysmith.WithEntropySource(bytes.NewReader(make([]byte, 32))))
+	assert.Equal(t, "unknown", g.(keysmith.AlgorithmReporter).Algorithm(), "the source does not report its algorithm")
+}
+
+func TestStart_GeneratorSelfTest(t *testing.T) {
+	for name, tt := range map[string]struct {
+		gen  keysmith.KeyGenerator
+		want error
+	}{
+		"default": {gen: keysmith.DefaultKeyGenerator()},
+		"source":  {gen: keysmith.DefaultKeyGenerator(keysmith.WithEntropySource(&drbgSource{}))},
+		"source errors": {
+			gen:  keysmith.DefaultKeyGenerator(keysmith.WithEntropySource(&countingSource{failAfter: 1})),
+			want: errSourceDown,
+		},
+		"source fails mid self-test": {
+			gen:  keysmith.DefaultKeyGenerator(keysmith.WithEntropySource(&countingSource{failAfter: 2*64 + 4*32 + 5})),
+			want: errSourceDown,
+		},
+		"stuck source": {
+			gen:  keysmith.DefaultKeyGenerator(keysmith.WithEntropySource(stuckSource{})),
+			want: keysmith.ErrKeyGeneratorSelfTest,
+		},
+		"repeating keys": {gen: repeatingGenerator{}, want: keysmith.ErrKeyGeneratorSelfTest},
+		"malformed keys": {gen: malformedGenerator{}, want: keysmith.ErrKeyGeneratorSelfTest},
+	} {
+		t.Run(name, func(t *testing.T) {
+			eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithKeyGenerator(tt.gen))
+			require.NoError(t, err)
+			err = eng.Start(context.Background())
+			t.Cleanup(func() { _ = eng.Stop(context.Background()) })
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, keysmith.ErrKeyGeneratorSelfTest)
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+// repeatingGenerator returns the same key every time.
+type repeatingGenerator struct{}
+
+func (repeatingGenerator) Generate(prefix string, env key.Environment) (string, error) {
+	return prefix + "_" + string(env) + "_0123456789abcdef", nil
+}
+
+func (repeatingGenerator) Algorithm() string { return keysmith.AlgCryptoRand }
+
+// malformedGenerator ignores the prefix it is given.
+type malformedGenerator struct{ repeatingGenerator }
+
+func (malformedGenerator) Generate(string, key.Environment) (string, error) {
+	return "key_0123456789abcdef", nil
+}
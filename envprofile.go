@@ -0,0 +1,230 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	log "github.com/xraph/go-utils/log"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/usage"
+)
+
+// DefaultIdleSweepInterval is how often SuspendIdleKeys runs between Start
+// and Stop when an environment profile sets AutoSuspendAfterIdle.
+const DefaultIdleSweepInterval = 15 * time.Minute
+
+// maxCachedKeyEnvironments bounds the cache RecordUsage resolves key
+// environments from; it is emptied when full.
+const maxCachedKeyEnvironments = 10_000
+
+// EnvironmentProfile sets defaults and caps for the keys of one
+// environment, for [WithEnvironmentProfile], such as a sandbox handed out
+// to prospects. Zero fields impose nothing.
+type EnvironmentProfile struct {
+	// MaxKeyLifetime caps how long keys in the environment live: a key
+	// created without an expiry, or with a later one, expires
+	// MaxKeyLifetime after creation. It is applied after the policy's
+	// MaxKeyLifetime and the prefix rule's MaxLifetime.
+	MaxKeyLifetime time.Duration `json:"max_key_lifetime" mapstructure:"max_key_lifetime" yaml:"max_key_lifetime"`
+
+	// DefaultPolicyName names the policy, in the key's tenant, attached to
+	// keys created without a PolicyID that their prefix rule gives none.
+	DefaultPolicyName string `json:"default_policy_name" mapstructure:"default_policy_name" yaml:"default_policy_name"`
+
+	// UsageRecording is how RecordUsage keeps the requests of keys in the
+	// environment that no recording rule matches, in place of the recording
+	// policy's Default. Records whose caller set a DefaultMode keep it.
+	UsageRecording usage.RecordMode `json:"usage_recording" mapstructure:"usage_recording" yaml:"usage_recording"`
+
+	// UsageSampleRate is the 1-in-N rate of a sampled UsageRecording, and
+	// must then be positive.
+	UsageSampleRate int `json:"usage_sample_rate" mapstructure:"usage_sample_rate" yaml:"usage_sample_rate"`
+
+	// AutoSuspendAfterIdle suspends active keys in the environment that
+	// have not been used for this long, counting from creation for keys
+	// never used. See [Engine.SuspendIdleKeys].
+	AutoSuspendAfterIdle time.Duration `json:"auto_suspend_after_idle" mapstructure:"auto_suspend_after_idle" yaml:"auto_suspend_after_idle"`
+}
+
+func (p *EnvironmentProfile) validate(env key.Environment) error {
+	if !slices.Contains(key.Environments(), env) {
+		return fmt.Errorf("%w: unknown environment %q", ErrInvalidEnvironmentProfile, env)
+	}
+	if p.MaxKeyLifetime < 0 || p.AutoSuspendAfterIdle < 0 {
+		return fmt.Errorf("%w: %s: durations must not be negative", ErrInvalidEnvironmentProfile, env)
+	}
+	if p.UsageRecording == usage.RecordSampled && p.UsageSampleRate <= 0 {
+		return fmt.Errorf("%w: %s: sampled usage recording needs a positive usage_sample_rate", ErrInvalidEnvironmentProfile, env)
+	}
+	rp := usage.RecordingPolicy{Default: p.UsageRecording, SampleRate: p.UsageSampleRate}
+	if err := rp.Validate(); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrInvalidEnvironmentProfile, env, err)
+	}
+	return nil
+}
+
+// envProfile returns the profile of env, or nil when it has none.
+func (e *Engine) envProfile(env key.Environment) *EnvironmentProfile {
+	if p, ok := e.envProfiles[env]; ok {
+		return &p
+	}
+	return nil
+}
+
+// profileDefaultPolicy returns the ID of the profile's default policy in
+// tenantID, or nil when the profile names none.
+func (e *Engine) profileDefaultPolicy(ctx context.Context, env key.Environment, p *EnvironmentProfile, tenantID string) (*id.PolicyID, error) {
+	if p == nil || p.DefaultPolicyName == "" {
+		return nil, nil
+	}
+	pol, err := e.store.Policies().GetByName(ctx, tenantID, p.DefaultPolicyName)
+	if err != nil {
+		return nil, fmt.Errorf("environment %q default policy %q: %w", env, p.DefaultPolicyName, err)
+	}
+	return &pol.ID, nil
+}
+
+// idleSweepInterval returns DefaultIdleSweepInterval when a profile
+// suspends idle keys, and zero otherwise, so that the job never starts.
+func (e *Engine) idleSweepInterval() time.Duration {
+	for _, p := range e.envProfiles {
+		if p.AutoSuspendAfterIdle > 0 {
+			return DefaultIdleSweepInterval
+		}
+	}
+	return 0
+}
+
+// SuspendIdleKeys suspends the active keys, among those ctx can see, that
+// have gone unused longer than their environment profile's
+// AutoSuspendAfterIdle. Only environments whose profile sets it are swept.
+// A key never used counts as idle from its creation. Each suspension fires
+// KeySuspended with the idle reason. With [WithDryRun] it only lists the
+// keys it would suspend. It runs every DefaultIdleSweepInterval between
+// Start and Stop when a profile sets AutoSuspendAfterIdle.
+func (e *Engine) SuspendIdleKeys(ctx context.Context) (*CleanupResult, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	res := &CleanupResult{DryRun: IsDryRun(ctx)}
+	now := e.now()
+	envs := make([]key.Environment, 0, len(e.envProfiles))
+	for env, p := range e.envProfiles {
+		if p.AutoSuspendAfterIdle > 0 {
+			envs = append(envs, env)
+		}
+	}
+	slices.Sort(envs)
+
+	for _, env := range envs {
+		cutoff := now.Add(-e.envProfiles[env].AutoSuspendAfterIdle)
+		filter := keyFilter(ctx, &key.ListFilter{State: key.StateActive, Environment: env})
+		err := e.store.Keys().Iterate(ctx, filter, func(k *key.Key) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			last := k.CreatedAt
+			if k.LastUsedAt != nil && k.LastUsedAt.After(last) {
+				last = *k.LastUsedAt
+			}
+			if last.After(cutoff) {
+				return nil
+			}
+			if res.DryRun {
+				res.KeyIDs = append(res.KeyIDs, k.ID)
+				return nil
+			}
+			ok, err := e.store.Keys().UpdateStateIf(ctx, k.ID, key.StateActive, key.StateSuspended)
+			if err != nil {
+				e.logger.Warn("failed to suspend idle key", log.String("key_id", k.ID.String()), log.Any("error", err))
+				res.Failed++
+				return nil
+			}
+			if !ok {
+				// Changed concurrently, e.g., revoked.
+				return nil
+			}
+			k.State = key.StateSuspended
+			res.KeyIDs = append(res.KeyIDs, k.ID)
+			e.invalidateKey(k.ID)
+			e.recordRevocation(ctx, k, key.StateSuspended, true)
+			_ = e.hooks.FireKeySuspended(ctx, k, e.eventMeta(ctx, plugin.TriggerSweep, plugin.ReasonIdle))
+			return nil
+		})
+		if err != nil {
+			return res, fmt.Errorf("iterate %s keys: %w", env, err)
+		}
+	}
+	return res, nil
+}
+
+// runIdleSweep is the body of the scheduled idle suspension job.
+func (e *Engine) runIdleSweep(ctx context.Context) {
+	if e.ReadOnly() {
+		return
+	}
+	res, err := e.SuspendIdleKeys(ctx)
+	if err != nil {
+		e.logger.Warn("idle key sweep failed", log.Any("error", err))
+		return
+	}
+	if len(res.KeyIDs) > 0 {
+		e.logger.Info("idle keys suspended", log.Int("keys", len(res.KeyIDs)))
+	}
+}
+
+// applyProfileRecording sets rec's environment, when the caller did not,
+// and its default recording mode from the environment's profile.
+func (e *Engine) applyProfileRecording(ctx context.Context, rec *usage.Record) {
+	if rec.Environment == "" {
+		rec.Environment = e.keyEnvs.lookup(ctx, e, rec.KeyID)
+	}
+	p := e.envProfile(rec.Environment)
+	if p == nil || p.UsageRecording == "" || rec.DefaultMode != "" {
+		return
+	}
+	rec.DefaultMode = p.UsageRecording
+	rec.DefaultSampleRate = p.UsageSampleRate
+}
+
+// keyEnvironmentCache remembers the environments of recently recorded
+// keys, which never change, so that RecordUsage reads each key once.
+type keyEnvironmentCache struct {
+	mu   sync.Mutex
+	envs map[id.KeyID]key.Environment
+}
+
+func newKeyEnvironmentCache() *keyEnvironmentCache {
+	return &keyEnvironmentCache{envs: make(map[id.KeyID]key.Environment)}
+}
+
+// lookup returns keyID's environment, reading the key on a miss. A key that
+// cannot be read has none.
+func (c *keyEnvironmentCache) lookup(ctx context.Context, e *Engine, keyID id.KeyID) key.Environment {
+	if keyID.IsNil() {
+		return ""
+	}
+	c.mu.Lock()
+	env, ok := c.envs[keyID]
+	c.mu.Unlock()
+	if ok {
+		return env
+	}
+	k, err := e.store.Keys().Get(ctx, keyID)
+	if err != nil {
+		return ""
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.envs) >= maxCachedKeyEnvironments {
+		clear(c.envs)
+	}
+	c.envs[keyID] = k.Environment
+	return k.Environment
+}
@@ -0,0 +1,174 @@
+package keysmith_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/store/memory"
+	"github.com/xraph/keysmith/usage"
+)
+
+func TestEnvironmentProfile_CreationCaps(t *testing.T) {
+	eng := newPrefixEngine(t,
+		keysmith.WithEnvironmentProfile(key.EnvTest, keysmith.EnvironmentProfile{
+			MaxKeyLifetime:    7 * 24 * time.Hour,
+			DefaultPolicyName: "Sandbox",
+		}),
+		keysmith.WithPrefixRule("pk", keysmith.PrefixRule{DefaultPolicyName: "Public"}),
+	)
+	ctx := testCtx()
+	sandbox := &policy.Policy{Name: "Sandbox", MaxKeyLifetime: 30 * 24 * time.Hour}
+	require.NoError(t, eng.CreatePolicy(ctx, sandbox))
+	public := &policy.Policy{Name: "Public"}
+	require.NoError(t, eng.CreatePolicy(ctx, public))
+	limit := time.Now().Add(7 * 24 * time.Hour)
+
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Prospect", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	require.NotNil(t, created.Key.PolicyID)
+	assert.Equal(t, sandbox.ID, *created.Key.PolicyID)
+	require.NotNil(t, created.Key.ExpiresAt)
+	assert.WithinDuration(t, limit, *created.Key.ExpiresAt, time.Minute, "the policy's longer lifetime is capped")
+
+	later := time.Now().Add(90 * 24 * time.Hour)
+	created, err = eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Prospect", Prefix: "sk", Environment: key.EnvTest, ExpiresAt: &later})
+	require.NoError(t, err)
+	assert.WithinDuration(t, limit, *created.Key.ExpiresAt, time.Minute)
+
+	sooner := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	created, err = eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Prospect", Prefix: "sk", Environment: key.EnvTest, ExpiresAt: &sooner})
+	require.NoError(t, err)
+	assert.Equal(t, sooner, *created.Key.ExpiresAt, "an earlier expiry is kept")
+
+	created, err = eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Browser", Prefix: "pk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	assert.Equal(t, public.ID, *created.Key.PolicyID, "the prefix rule's default wins")
+	assert.WithinDuration(t, limit, *created.Key.ExpiresAt, time.Minute)
+
+	created, err = eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Production", Prefix: "sk", Environment: key.EnvLive})
+	require.NoError(t, err)
+	assert.Nil(t, created.Key.PolicyID, "other environments are unaffected")
+	assert.Nil(t, created.Key.ExpiresAt)
+}
+
+func TestEnvironmentProfile_Invalid(t *testing.T) {
+	for name, opt := range map[string]keysmith.Option{
+		"unknown environment": keysmith.WithEnvironmentProfile("prod", keysmith.EnvironmentProfile{}),
+		"negative lifetime":   keysmith.WithEnvironmentProfile(key.EnvTest, keysmith.EnvironmentProfile{MaxKeyLifetime: -time.Hour}),
+		"sampled without rate": keysmith.WithEnvironmentProfile(key.EnvTest, keysmith.EnvironmentProfile{
+			UsageRecording: usage.RecordSampled,
+		}),
+		"unknown mode": keysmith.WithEnvironmentProfile(key.EnvTest, keysmith.EnvironmentProfile{UsageRecording: "some"}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), opt)
+			assert.ErrorIs(t, err, keysmith.ErrInvalidEnvironmentProfile)
+		})
+	}
+}
+
+func TestSuspendIdleKeys_Scoping(t *testing.T) {
+	clock := &fakeClock{t: time.Now()}
+	eng := newPrefixEngine(t,
+		keysmith.WithClock(clock.Now),
+		keysmith.WithEnvironmentProfile(key.EnvTest, keysmith.EnvironmentProfile{AutoSuspendAfterIdle: 24 * time.Hour}),
+		keysmith.WithEnvironmentProfile(key.EnvStaging, keysmith.EnvironmentProfile{MaxKeyLifetime: time.Hour}),
+	)
+	other := keysmith.WithTenant(context.Background(), "app_test", "tenant_other")
+	idle := createEventKey(t, eng, testCtx())
+	busy := createEventKey(t, eng, testCtx())
+	theirs := createEventKey(t, eng, other)
+	live, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "Production", Prefix: "sk", Environment: key.EnvLive})
+	require.NoError(t, err)
+	staging, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "Staging", Prefix: "sk", Environment: key.EnvStaging})
+	require.NoError(t, err)
+
+	clock.Set(clock.Now().Add(25 * time.Hour))
+	require.NoError(t, eng.Store().Keys().UpdateLastUsed(testCtx(), busy.ID, clock.Now().Add(-time.Hour)))
+
+	dry, err := eng.SuspendIdleKeys(keysmith.WithDryRun(testCtx()))
+	require.NoError(t, err)
+	assert.True(t, dry.DryRun)
+	assert.Len(t, dry.KeyIDs, 1)
+
+	res, err := eng.SuspendIdleKeys(testCtx())
+	require.NoError(t, err)
+	require.Len(t, res.KeyIDs, 1, "only the caller's tenant is swept")
+	assert.Equal(t, idle.ID, res.KeyIDs[0])
+
+	states := func() map[string]key.State {
+		out := make(map[string]key.State)
+		for name, k := range map[string]*key.Key{"idle": idle, "busy": busy, "live": live.Key, "staging": staging.Key} {
+			got, err := eng.GetKey(testCtx(), k.ID)
+			require.NoError(t, err)
+			out[name] = got.State
+		}
+		got, err := eng.GetKey(other, theirs.ID)
+		require.NoError(t, err)
+		out["theirs"] = got.State
+		return out
+	}
+	assert.Equal(t, map[string]key.State{
+		"idle":    key.StateSuspended,
+		"busy":    key.StateActive,
+		"live":    key.StateActive,
+		"staging": key.StateActive,
+		"theirs":  key.StateActive,
+	}, states(), "environments without AutoSuspendAfterIdle are not swept")
+
+	res, err = eng.SuspendIdleKeys(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, res.KeyIDs, 1, "the system scope sweeps every tenant")
+	assert.Equal(t, key.StateSuspended, states()["theirs"])
+}
+
+func TestEnvironmentProfile_UsageRecording(t *testing.T) {
+	ms := memory.New()
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(ms),
+		keysmith.WithEnvironmentProfile(key.EnvTest, keysmith.EnvironmentProfile{
+			UsageRecording:  usage.RecordSampled,
+			UsageSampleRate: 5,
+		}),
+	)
+	require.NoError(t, err)
+	sandbox := createEventKey(t, eng, testCtx())
+	live, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "Production", Prefix: "sk", Environment: key.EnvLive})
+	require.NoError(t, err)
+
+	for range 10 {
+		recordCall(t, eng, sandbox.ID, "GET", "/v1/users", "")
+		recordCall(t, eng, live.Key.ID, "GET", "/v1/users", "")
+	}
+
+	recs, err := eng.QueryUsage(testCtx(), &usage.QueryFilter{Environment: key.EnvTest})
+	require.NoError(t, err)
+	require.Len(t, recs, 2, "sandbox usage is sampled 1 in 5")
+	for _, rec := range recs {
+		assert.Equal(t, sandbox.ID, rec.KeyID)
+		assert.Equal(t, key.EnvTest, rec.Environment, "the recording path fills the environment")
+	}
+
+	recs, err = eng.QueryUsage(testCtx(), &usage.QueryFilter{Environment: key.EnvLive})
+	require.NoError(t, err)
+	assert.Len(t, recs, 10, "production usage is recorded in full")
+
+	acts, err := eng.ListEndpointActivity(testCtx(), sandbox.ID)
+	require.NoError(t, err)
+	require.Len(t, acts, 1)
+	assert.EqualValues(t, 10, acts[0].Count, "sampled requests are still counted")
+
+	// A caller's own default mode is kept.
+	rec := &usage.Record{KeyID: sandbox.ID, Method: "GET", Endpoint: "/edge", StatusCode: 204, DefaultMode: usage.RecordFull}
+	require.NoError(t, eng.RecordUsage(testCtx(), rec))
+	n, err := ms.Usages().Count(testCtx(), &usage.QueryFilter{Environment: key.EnvTest})
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, n)
+}
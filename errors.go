@@ -9,6 +9,11 @@ var (
 	// ErrKeyInactive is returned when the key is not in an active state.
 	ErrKeyInactive = errors.New("keysmith: key is not active")
 
+	// ErrEngineStopping is returned by ValidateKey after Stop has been
+	// called, when the engine is configured to refuse validations while
+	// stopping, and by Health during shutdown.
+	ErrEngineStopping = errors.New("keysmith: engine is stopping")
+
 	// ErrKeyExpired is returned when the key has expired.
 	ErrKeyExpired = errors.New("keysmith: key has expired")
 
@@ -21,7 +26,16 @@ var (
 	// ErrRateLimited is returned when the key exceeds its rate limit.
 	ErrRateLimited = errors.New("keysmith: rate limit exceeded")
 
-	// ErrQuotaExceeded is returned when the key exceeds its usage quota.
+	// ErrTenantRateLimited is returned when the key is within its own rate
+	// limit but its tenant has exceeded the tenant-wide ceiling.
+	ErrTenantRateLimited = errors.New("keysmith: tenant rate limit exceeded")
+
+	// ErrScopeRateLimited is returned when the key exceeds the rate limit
+	// of a scope the request exercises. The error names the scope.
+	ErrScopeRateLimited = errors.New("keysmith: scope rate limit exceeded")
+
+	// ErrQuotaExceeded is returned when the key exceeds the daily or monthly
+	// quota of its quota pool or policy.
 	ErrQuotaExceeded = errors.New("keysmith: usage quota exceeded")
 
 	// ErrInvalidStateTransition is returned for illegal key state changes.
@@ -30,15 +44,45 @@ var (
 	// ErrPolicyInUse is returned when deleting a policy assigned to active keys.
 	ErrPolicyInUse = errors.New("keysmith: policy is assigned to active keys")
 
+	// ErrExternalRefInUse is returned by CreateKey when the external
+	// reference belongs to a key in another app of the tenant, which the
+	// caller may not be given.
+	ErrExternalRefInUse = errors.New("keysmith: external reference belongs to a key in another app")
+
+	// ErrDuplicateKeyName is returned by CreateKey and UpdateKey when the
+	// tenant requires unique key names and another of its keys has the
+	// name. Use errors.As with a [*DuplicateKeyNameError] for the key.
+	ErrDuplicateKeyName = errors.New("keysmith: key name is already in use in the tenant")
+
 	// ErrPolicyNotFound is returned when a policy cannot be found.
 	ErrPolicyNotFound = errors.New("keysmith: policy not found")
 
+	// ErrPolicyTemplateNotFound is returned when no policy template has the
+	// requested name.
+	ErrPolicyTemplateNotFound = errors.New("keysmith: policy template not found")
+
+	// ErrInvalidPolicy is returned when a policy fails validation.
+	ErrInvalidPolicy = errors.New("keysmith: invalid policy")
+
+	// ErrPolicyInheritance is returned when a policy's BasePolicyID chain
+	// cannot be resolved: it has a cycle, is too deep, reaches a missing or
+	// foreign policy, or restricts a list to nothing. Validating a key whose
+	// policy fails to resolve returns it too, so a broken baseline never
+	// widens access.
+	ErrPolicyInheritance = errors.New("keysmith: invalid policy inheritance")
+
 	// ErrKeyNotFound is returned when a key cannot be found.
 	ErrKeyNotFound = errors.New("keysmith: key not found")
 
 	// ErrScopeNotFound is returned when a scope cannot be found.
 	ErrScopeNotFound = errors.New("keysmith: scope not found")
 
+	// ErrGroupNotFound is returned when a key group cannot be found.
+	ErrGroupNotFound = errors.New("keysmith: group not found")
+
+	// ErrQuotaPoolNotFound is returned when a quota pool cannot be found.
+	ErrQuotaPoolNotFound = errors.New("keysmith: quota pool not found")
+
 	// ErrScopeNotAllowed is returned when a scope is not permitted by the policy.
 	ErrScopeNotAllowed = errors.New("keysmith: scope not allowed by policy")
 
@@ -48,6 +92,336 @@ var (
 	// ErrOriginNotAllowed is returned when the origin is not in the allowlist.
 	ErrOriginNotAllowed = errors.New("keysmith: origin not allowed")
 
+	// ErrMethodNotAllowed is returned when the request method is not in the
+	// policy's allowed methods.
+	ErrMethodNotAllowed = errors.New("keysmith: method not allowed")
+
+	// ErrPathNotAllowed is returned when the request path matches none of
+	// the policy's allowed paths.
+	ErrPathNotAllowed = errors.New("keysmith: path not allowed")
+
+	// ErrInvalidOrigin is returned when an origin allowlist entry is not an
+	// origin, a wildcard subdomain, or "*".
+	ErrInvalidOrigin = errors.New("keysmith: invalid allowed origin")
+
+	// ErrConsumerNotAllowed is returned when a key that enforces its
+	// intended consumer is presented by a different service.
+	ErrConsumerNotAllowed = errors.New("keysmith: consumer service not allowed")
+
+	// ErrTransportNotAllowed is returned when a key is presented over a
+	// connection weaker than its policy requires: a TLS version below
+	// MinTLSVersion, or no client certificate with RequireMTLS.
+	ErrTransportNotAllowed = errors.New("keysmith: transport not allowed")
+
+	// ErrClientCertMismatch is returned when a key pinned to a client
+	// certificate is presented without that certificate.
+	ErrClientCertMismatch = errors.New("keysmith: client certificate mismatch")
+
+	// ErrInvalidCertFingerprint is returned when a key is written with a
+	// cert fingerprint that is not a SHA-256 digest.
+	ErrInvalidCertFingerprint = errors.New("keysmith: invalid cert fingerprint")
+
+	// ErrAuthzDenied is returned by ValidateKey when the external
+	// [Authorizer] denies a key that passed every built-in check. The
+	// decision's reason follows the message.
+	ErrAuthzDenied = errors.New("keysmith: denied by authorizer")
+
+	// ErrAuthorizerUnavailable is returned by ValidateKey when the external
+	// [Authorizer] fails to decide. Adapters that fail open allow instead.
+	ErrAuthorizerUnavailable = errors.New("keysmith: authorizer unavailable")
+
+	// ErrInvalidKeyFlag is returned when a key is written with an unknown
+	// flag.
+	ErrInvalidKeyFlag = errors.New("keysmith: invalid key flag")
+
+	// ErrKeyFlagNotAllowed is returned when a flag that skips a validation
+	// check is added from a context scoped to an app. Only tenant-admin and
+	// system contexts may set such flags.
+	ErrKeyFlagNotAllowed = errors.New("keysmith: key flag requires an admin context")
+
+	// ErrInvalidKeyInput is returned when a registered CreateKey, UpdateKey,
+	// or RotateKey validator rejects its input. Unwrap an [*InputError] for
+	// the individual errors.
+	ErrInvalidKeyInput = errors.New("keysmith: invalid key input")
+
 	// ErrRotationNotFound is returned when a rotation record cannot be found.
 	ErrRotationNotFound = errors.New("keysmith: rotation record not found")
+
+	// ErrInvalidCompromiseAction is returned when a compromise report names an
+	// unsupported remediation.
+	ErrInvalidCompromiseAction = errors.New("keysmith: invalid compromise action")
+
+	// ErrInvalidMetadata is returned when metadata exceeds the configured
+	// limits or sets a reserved entry. See [MetadataError].
+	ErrInvalidMetadata = errors.New("keysmith: invalid metadata")
+
+	// ErrInvalidMetadataSchema is returned when a tenant metadata schema
+	// names an unknown type or has enum values of the wrong type.
+	ErrInvalidMetadataSchema = errors.New("keysmith: invalid metadata schema")
+
+	// ErrInvalidTenantSettings is returned when tenant settings fail
+	// validation, such as a rate limit without a window.
+	ErrInvalidTenantSettings = errors.New("keysmith: invalid tenant settings")
+
+	// ErrInvalidScope is returned when a scope fails validation, such as a
+	// rate limit without a window.
+	ErrInvalidScope = errors.New("keysmith: invalid scope")
+
+	// ErrInvalidGroup is returned when a key group fails validation, such
+	// as an empty name.
+	ErrInvalidGroup = errors.New("keysmith: invalid group")
+
+	// ErrInvalidQuotaPool is returned when a quota pool fails validation,
+	// such as a daily limit above its monthly limit, or when a key is added
+	// to a pool of another tenant or app.
+	ErrInvalidQuotaPool = errors.New("keysmith: invalid quota pool")
+
+	// ErrDeliveryFailed is returned when a raw key could not be written to
+	// its secrets-manager destination.
+	ErrDeliveryFailed = errors.New("keysmith: raw key delivery failed")
+
+	// ErrRevocationRangeTooLarge is returned when a revocation feed cursor
+	// is older than the lookback window.
+	ErrRevocationRangeTooLarge = errors.New("keysmith: revocation range exceeds the lookback window")
+
+	// ErrKeyEventRangeTooLarge is returned when a key event cursor is older
+	// than the lookback window.
+	ErrKeyEventRangeTooLarge = errors.New("keysmith: key event range exceeds the lookback window")
+
+	// ErrLiveStatsDisabled is returned by LiveKeyStats and LiveTopKeys when
+	// the engine was built without WithLiveStats.
+	ErrLiveStatsDisabled = errors.New("keysmith: live key stats are not enabled")
+
+	// ErrInvalidDebugCapture is returned when a debug capture sample rate
+	// or duration is out of range.
+	ErrInvalidDebugCapture = errors.New("keysmith: invalid debug capture settings")
+
+	// ErrDebugCaptureNotAllowed is returned when debug capture is requested
+	// for a live key without WithLiveDebugCapture.
+	ErrDebugCaptureNotAllowed = errors.New("keysmith: debug capture is not allowed for live keys")
+
+	// ErrHashBatchTooLarge is returned when RevokeByHashes or
+	// BulkValidateHashes is given more than MaxHashBatch hashes.
+	ErrHashBatchTooLarge = errors.New("keysmith: too many hashes in one batch")
+
+	// ErrMaintenanceUnsupported is returned by MaintainStore when the store
+	// does not implement store.Maintainer.
+	ErrMaintenanceUnsupported = errors.New("keysmith: store does not support maintenance")
+
+	// ErrStorageReportUnsupported is returned by TenantStorageReport and
+	// TenantStorage when the store does not implement store.StorageReporter.
+	ErrStorageReportUnsupported = errors.New("keysmith: store does not support storage reports")
+
+	// ErrSystemScopeRequired is returned by operations that span tenants or
+	// expose key hashes, such as ExportKey, when the context is scoped to a
+	// tenant or app.
+	ErrSystemScopeRequired = errors.New("keysmith: operation requires a system-scoped context")
+
+	// ErrInvalidBundle is returned by ImportKeyBundle for a bundle without a
+	// schema version, key, or key hash.
+	ErrInvalidBundle = errors.New("keysmith: invalid key bundle")
+
+	// ErrBundleVersionUnsupported is returned by ImportKeyBundle for a
+	// bundle written by a newer keysmith than this one.
+	ErrBundleVersionUnsupported = errors.New("keysmith: key bundle schema version is not supported")
+
+	// ErrImportConflict is returned by ImportKeyBundle when the bundle's key
+	// ID or hash belongs to a different key on the target. Use errors.As
+	// with an [*ImportConflictError] for details.
+	ErrImportConflict = errors.New("keysmith: key import conflicts with an existing key")
+
+	// ErrIDMigrationUnsupported is returned by MigrateIDs when the store
+	// does not implement store.IDMigrator.
+	ErrIDMigrationUnsupported = errors.New("keysmith: store does not support ID migration")
+
+	// ErrNotAvailableInFIPSMode is returned by NewEngine, when built with the
+	// fips tag, for a hasher or key generator whose algorithm is not
+	// FIPS-approved or not reported.
+	ErrNotAvailableInFIPSMode = errors.New("keysmith: not available in FIPS mode")
+
+	// ErrKeyGeneratorSelfTest is returned by Start when the key generator's
+	// entropy source errors or looks broken, or when the keys it generates
+	// repeat or are malformed.
+	ErrKeyGeneratorSelfTest = errors.New("keysmith: key generator self-test failed")
+
+	// ErrInvalidAdaptiveLimiting is returned by NewEngine for an
+	// AdaptiveLimiting with a negative duration or count, or a ratio or
+	// penalty out of range.
+	ErrInvalidAdaptiveLimiting = errors.New("keysmith: invalid adaptive limiting")
+
+	// ErrInvalidHMACKey is returned by NewHMACHasher for a secret shorter
+	// than MinHMACKeySize.
+	ErrInvalidHMACKey = errors.New("keysmith: invalid HMAC key")
+
+	// ErrCreatorRequired is returned by ListKeysByCreator and
+	// BulkRevokeByCreator for an empty creator, which would otherwise match
+	// every key created without one.
+	ErrCreatorRequired = errors.New("keysmith: creator is required")
+
+	// ErrNoHashAt is returned by HashActiveAt for a time before the key was
+	// created.
+	ErrNoHashAt = errors.New("keysmith: key had no hash at that time")
+
+	// ErrTermsNotAccepted is returned by CreateKey, under WithTermsVersion,
+	// when the input did not accept the current terms version.
+	ErrTermsNotAccepted = errors.New("keysmith: current terms not accepted")
+
+	// ErrTermsOutdated is returned by ValidateKey, under TermsStrict, for a
+	// key that accepted other terms than the current version.
+	ErrTermsOutdated = errors.New("keysmith: key accepted outdated terms")
+
+	// ErrTermsVersionRequired is returned by ListKeysWithOutdatedTerms when
+	// no version is given and none is configured.
+	ErrTermsVersionRequired = errors.New("keysmith: terms version is required")
+
+	// ErrNoMonthlyQuota is returned by QuotaForecast for a key whose
+	// effective policy sets no monthly quota.
+	ErrNoMonthlyQuota = errors.New("keysmith: key has no monthly quota")
+
+	// ErrNonceRequired is returned by CheckReplay, under
+	// WithReplayProtection, for a request without a nonce and timestamp or
+	// with an overlong nonce.
+	ErrNonceRequired = errors.New("keysmith: request nonce and timestamp are required")
+
+	// ErrRequestReplayed is returned by CheckReplay for a nonce already used
+	// with the key within the replay window.
+	ErrRequestReplayed = errors.New("keysmith: request replayed")
+
+	// ErrRequestStale is returned by CheckReplay for a request timestamp
+	// outside the replay window.
+	ErrRequestStale = errors.New("keysmith: request timestamp outside the replay window")
+
+	// ErrInvalidReplayProtection is returned by NewEngine for a
+	// ReplayProtection with a negative window or size.
+	ErrInvalidReplayProtection = errors.New("keysmith: invalid replay protection")
+
+	// ErrInvalidTelemetry is returned by NewEngine for a WithTelemetry
+	// endpoint that is not an absolute http or https URL, outside dry-run
+	// mode, or a negative interval.
+	ErrInvalidTelemetry = errors.New("keysmith: invalid telemetry configuration")
+
+	// ErrReadOnlyMode is returned by engine methods that write to the store
+	// while read-only mode is on.
+	ErrReadOnlyMode = errors.New("keysmith: engine is in read-only mode")
+
+	// ErrUnknownPrefix is returned by CreateKey, under WithStrictPrefixes,
+	// for a prefix without a rule.
+	ErrUnknownPrefix = errors.New("keysmith: key prefix has no rule")
+
+	// ErrPrefixRuleViolation is returned by CreateKey for a key its prefix
+	// rule does not allow: in another environment or without a required
+	// scope.
+	ErrPrefixRuleViolation = errors.New("keysmith: key violates its prefix rule")
+
+	// ErrPrefixNotAccepted is returned by ValidateKey for a key whose prefix
+	// is not among the ValidationRequest's AcceptPrefixes.
+	ErrPrefixNotAccepted = errors.New("keysmith: key prefix not accepted")
+
+	// ErrInvalidPrefixRule is returned by NewEngine for a prefix rule with an
+	// empty prefix, a negative lifetime, or an unknown environment.
+	ErrInvalidPrefixRule = errors.New("keysmith: invalid prefix rule")
+
+	// ErrInvalidKeyFormat is returned by NewEngine for a key format without
+	// a name or matcher, two formats with the same name, or a deprecation
+	// of a format neither the generator nor WithKeyFormat registered.
+	ErrInvalidKeyFormat = errors.New("keysmith: invalid key format")
+
+	// ErrInvalidEnvironmentProfile is returned by NewEngine for an
+	// environment profile of an unknown environment, with a negative
+	// duration, or with an invalid usage recording mode.
+	ErrInvalidEnvironmentProfile = errors.New("keysmith: invalid environment profile")
+
+	// ErrInvalidContact is returned for a key or tenant default contact of
+	// unknown type or with a malformed target.
+	ErrInvalidContact = errors.New("keysmith: invalid contact")
+
+	// ErrInvalidErasure is returned by EraseUsageIdentifiers for a matcher
+	// without an identifier or a call without a tenant.
+	ErrInvalidErasure = errors.New("keysmith: invalid usage erasure")
+
+	// ErrErasureNotAllowed is returned by EraseUsageIdentifiers for a
+	// context scoped to an app or to another tenant.
+	ErrErasureNotAllowed = errors.New("keysmith: usage erasure not allowed in this scope")
+
+	// ErrInvalidRuntimeConfig is returned by UpdateRuntimeConfig for a value
+	// out of range or a setting whose feature is off.
+	ErrInvalidRuntimeConfig = errors.New("keysmith: invalid runtime config")
+
+	// ErrInvalidRotationReason is returned by RotateKey for a reason that is
+	// not one of the rotation package's constants.
+	ErrInvalidRotationReason = errors.New("keysmith: invalid rotation reason")
+
+	// ErrInvalidLabels is returned for key labels with a malformed name or
+	// value, or more than key.MaxLabels of them.
+	ErrInvalidLabels = errors.New("keysmith: invalid labels")
+
+	// ErrInvalidLabelSelector is returned for a malformed label selector,
+	// and by BulkRevokeByLabels for a filter without one.
+	ErrInvalidLabelSelector = errors.New("keysmith: invalid label selector")
+
+	// ErrInvalidSnapshot is returned by Restore for a stream that is not a
+	// snapshot, is truncated, or fails its section counts or checksum.
+	ErrInvalidSnapshot = errors.New("keysmith: invalid snapshot")
+
+	// ErrSnapshotVersionUnsupported is returned by Restore for a snapshot
+	// written by a newer keysmith than this one.
+	ErrSnapshotVersionUnsupported = errors.New("keysmith: snapshot format version is not supported")
+
+	// ErrRestoreTargetNotEmpty is returned by Restore when the store already
+	// holds keys, policies, or scopes and RestoreOptions.Conflict is unset.
+	ErrRestoreTargetNotEmpty = errors.New("keysmith: restore target store is not empty")
+
+	// ErrInvalidRestoreOptions is returned by Restore for an unknown
+	// RestoreOptions.Conflict.
+	ErrInvalidRestoreOptions = errors.New("keysmith: invalid restore options")
+
+	// ErrInvalidOverviewQuery is returned by TenantKeyOverview for windows
+	// that are not positive and ascending, or more than MaxOverviewWindows
+	// of them.
+	ErrInvalidOverviewQuery = errors.New("keysmith: invalid key overview query")
+
+	// ErrInvalidSearchQuery is returned by SearchKeys for a query without
+	// words or with more than MaxSearchTerms terms, and for a search
+	// without a tenant.
+	ErrInvalidSearchQuery = errors.New("keysmith: invalid key search query")
+
+	// ErrKeyTransferNotFound is returned for a key without a pending
+	// transfer the context may see.
+	ErrKeyTransferNotFound = errors.New("keysmith: key transfer not found")
+
+	// ErrKeyTransferPending is returned by InitiateKeyTransfer for a key
+	// that already has a pending transfer.
+	ErrKeyTransferPending = errors.New("keysmith: key already has a pending transfer")
+
+	// ErrKeyTransferExpired is returned by AcceptKeyTransfer for a transfer
+	// past its expiry, which it marks expired.
+	ErrKeyTransferExpired = errors.New("keysmith: key transfer has expired")
+
+	// ErrKeyTransferNotTarget is returned by AcceptKeyTransfer for a
+	// context not scoped to the transfer's target tenant.
+	ErrKeyTransferNotTarget = errors.New("keysmith: only the target tenant can accept a key transfer")
+
+	// ErrInvalidKeyTransfer is returned by InitiateKeyTransfer for a key
+	// that is revoked or expired, and for a target tenant that is empty or
+	// the key's own.
+	ErrInvalidKeyTransfer = errors.New("keysmith: invalid key transfer")
+
+	// ErrHashTombstoned is returned when a key would be written with a hash
+	// that was tombstoned when its key was revoked, so that a revoked
+	// credential cannot be brought back by an import or restore.
+	ErrHashTombstoned = errors.New("keysmith: key hash is tombstoned")
+
+	// ErrTenantLocked is returned by CreateKey in a tenant locked down by
+	// LockdownTenant, until RecoverTenant has brought it back.
+	ErrTenantLocked = errors.New("keysmith: tenant is locked down")
+
+	// ErrTenantNotLocked is returned by RecoverTenant for a tenant that is
+	// not locked down.
+	ErrTenantNotLocked = errors.New("keysmith: tenant is not locked down")
+
+	// ErrInvalidLockdown is returned by the lockdown calls without a
+	// tenant, for a tenant outside the caller's scope or an app-scoped
+	// caller, by LockdownTenant without a reason, and by RecoverTenant for
+	// negative options.
+	ErrInvalidLockdown = errors.New("keysmith: invalid tenant lockdown")
 )
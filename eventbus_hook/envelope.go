@@ -0,0 +1,81 @@
+package eventbushook
+
+import (
+	"time"
+
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/rotation"
+)
+
+// SchemaVersion is the version of the [Envelope] schema. It is raised when a
+// field changes meaning or is removed; new fields are added without raising
+// it, so consumers should ignore fields they do not know.
+const SchemaVersion = 1
+
+// Event type constants, used as [Envelope.Type] and to route with [WithTopic].
+const (
+	EventKeyCreated      = "keysmith.key.created"
+	EventKeyRotated      = "keysmith.key.rotated"
+	EventKeyRevoked      = "keysmith.key.revoked"
+	EventKeySuspended    = "keysmith.key.suspended"
+	EventKeyReactivated  = "keysmith.key.reactivated"
+	EventKeyExpired      = "keysmith.key.expired"
+	EventKeyFlagsChanged = "keysmith.key.flags_changed"
+	EventKeyCompromised  = "keysmith.key.compromised"
+	EventKeyDebugEnabled = "keysmith.key.debug_enabled"
+	EventKeyExported     = "keysmith.key.exported"
+	EventKeyImported     = "keysmith.key.imported"
+	EventPolicyCreated   = "keysmith.policy.created"
+	EventPolicyUpdated   = "keysmith.policy.updated"
+	EventPolicyDeleted   = "keysmith.policy.deleted"
+)
+
+// Envelope is the JSON document published for each event. Entity snapshots
+// are taken when the hook fires. They never carry raw keys or key hashes:
+// [key.Key] and [rotation.Record] leave hashes out of their JSON.
+type Envelope struct {
+	SchemaVersion int       `json:"schema_version"`
+	ID            string    `json:"id"`
+	Type          string    `json:"type"`
+	Time          time.Time `json:"time"`
+	TenantID      string    `json:"tenant_id,omitempty"`
+	AppID         string    `json:"app_id,omitempty"`
+	Meta          Meta      `json:"meta"`
+
+	// Key is set for key events.
+	Key *key.Key `json:"key,omitempty"`
+
+	// Policy is set for policy events but deletes, which carry only PolicyID.
+	Policy   *policy.Policy `json:"policy,omitempty"`
+	PolicyID string         `json:"policy_id,omitempty"`
+
+	// Rotation is set for key.rotated.
+	Rotation *rotation.Record `json:"rotation,omitempty"`
+
+	// Data holds event-specific details, such as a revoke reason or the
+	// flags added and removed.
+	Data map[string]any `json:"data,omitempty"`
+}
+
+// Meta is the JSON form of [plugin.EventMeta].
+type Meta struct {
+	Trigger    string `json:"trigger,omitempty"`
+	ReasonCode string `json:"reason_code,omitempty"`
+	ActorID    string `json:"actor_id,omitempty"`
+	RequestID  string `json:"request_id,omitempty"`
+	Scope      string `json:"scope,omitempty"`
+	GroupID    string `json:"group_id,omitempty"`
+}
+
+func metaOf(m plugin.EventMeta) Meta {
+	return Meta{
+		Trigger:    string(m.Trigger),
+		ReasonCode: string(m.ReasonCode),
+		ActorID:    m.ActorID,
+		RequestID:  m.RequestID,
+		Scope:      m.Scope,
+		GroupID:    m.GroupID,
+	}
+}
@@ -0,0 +1,278 @@
+// Package eventbushook publishes Keysmith lifecycle events to an event bus
+// such as Kafka or NATS JetStream. It defines a small Publisher interface so
+// the package does not import a broker client; the kafka and nats
+// subpackages adapt one.
+//
+// Each event is published as a JSON [Envelope] keyed by the key ID (or the
+// policy ID for policy events), so brokers that partition by key keep each
+// key's events in order. Events the Publisher rejects are held in a bounded
+// in-memory queue and retried by Drain, periodically by Start, and once
+// more at shutdown.
+package eventbushook
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	log "github.com/xraph/go-utils/log"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/rotation"
+)
+
+// Compile-time interface checks.
+var (
+	_ plugin.Plugin            = (*Extension)(nil)
+	_ plugin.KeyCreatedV2      = (*Extension)(nil)
+	_ plugin.KeyRotatedV2      = (*Extension)(nil)
+	_ plugin.KeyRevokedV2      = (*Extension)(nil)
+	_ plugin.KeySuspendedV2    = (*Extension)(nil)
+	_ plugin.KeyReactivatedV2  = (*Extension)(nil)
+	_ plugin.KeyExpiredV2      = (*Extension)(nil)
+	_ plugin.KeyFlagsChangedV2 = (*Extension)(nil)
+	_ plugin.KeyCompromisedV2  = (*Extension)(nil)
+	_ plugin.KeyDebugEnabledV2 = (*Extension)(nil)
+	_ plugin.KeyExportedV2     = (*Extension)(nil)
+	_ plugin.KeyImportedV2     = (*Extension)(nil)
+	_ plugin.PolicyCreatedV2   = (*Extension)(nil)
+	_ plugin.PolicyUpdatedV2   = (*Extension)(nil)
+	_ plugin.PolicyDeletedV2   = (*Extension)(nil)
+	_ plugin.Shutdown          = (*Extension)(nil)
+)
+
+// Defaults.
+const (
+	// DefaultTopic receives event types without a WithTopic route.
+	DefaultTopic = "keysmith.events"
+
+	// DefaultMaxPending caps the retry queue.
+	DefaultMaxPending = 10_000
+
+	// DefaultDrainInterval is how often the loop started by Start drains
+	// the retry queue.
+	DefaultDrainInterval = 5 * time.Second
+
+	// DefaultMaxDrainBackoff caps the drain loop's backoff while the
+	// Publisher keeps failing.
+	DefaultMaxDrainBackoff = 5 * time.Minute
+)
+
+// Publisher is the interface that event bus backends must implement.
+// key is the partition key; events with the same key must be delivered in
+// the order they are published.
+type Publisher interface {
+	Publish(ctx context.Context, topic string, key []byte, payload []byte) error
+}
+
+// PublisherFunc is an adapter to use a plain function as a Publisher.
+type PublisherFunc func(ctx context.Context, topic string, key []byte, payload []byte) error
+
+// Publish implements Publisher.
+func (f PublisherFunc) Publish(ctx context.Context, topic string, key []byte, payload []byte) error {
+	return f(ctx, topic, key, payload)
+}
+
+// Extension publishes Keysmith lifecycle events to an event bus.
+type Extension struct {
+	publisher    Publisher
+	topics       map[string]string
+	defaultTopic string
+	enabled      map[string]bool
+	logger       log.Logger
+
+	// mu is held while publishing, so events reach the Publisher in the
+	// order their hooks fired. pending holds rejected events, oldest first.
+	mu         sync.Mutex
+	pending    []*message
+	maxPending int
+	dropped    int
+
+	drainInterval time.Duration
+	loopMu        sync.Mutex
+	stop          context.CancelFunc
+	stopped       chan struct{}
+}
+
+// message is an encoded event waiting to be published.
+type message struct {
+	topic   string
+	key     string
+	payload []byte
+}
+
+// New creates an Extension that publishes events with p.
+func New(p Publisher, opts ...Option) *Extension {
+	e := &Extension{
+		publisher:     p,
+		topics:        make(map[string]string),
+		defaultTopic:  DefaultTopic,
+		logger:        log.NewNoopLogger(),
+		maxPending:    DefaultMaxPending,
+		drainInterval: DefaultDrainInterval,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Name implements plugin.Plugin.
+func (e *Extension) Name() string { return "eventbus-hook" }
+
+// OnKeyCreatedV2 implements plugin.KeyCreatedV2.
+func (e *Extension) OnKeyCreatedV2(ctx context.Context, k *key.Key, meta plugin.EventMeta) error {
+	return e.emitKey(ctx, EventKeyCreated, k, meta, nil)
+}
+
+// OnKeyRotatedV2 implements plugin.KeyRotatedV2.
+func (e *Extension) OnKeyRotatedV2(ctx context.Context, k *key.Key, rec *rotation.Record, meta plugin.EventMeta) error {
+	env := keyEnvelope(EventKeyRotated, k, meta)
+	env.Rotation = rec
+	return e.publish(ctx, env, k.ID.String())
+}
+
+// OnKeyRevokedV2 implements plugin.KeyRevokedV2.
+func (e *Extension) OnKeyRevokedV2(ctx context.Context, k *key.Key, reason string, meta plugin.EventMeta) error {
+	return e.emitKey(ctx, EventKeyRevoked, k, meta, map[string]any{"reason": reason})
+}
+
+// OnKeySuspendedV2 implements plugin.KeySuspendedV2.
+func (e *Extension) OnKeySuspendedV2(ctx context.Context, k *key.Key, meta plugin.EventMeta) error {
+	return e.emitKey(ctx, EventKeySuspended, k, meta, nil)
+}
+
+// OnKeyReactivatedV2 implements plugin.KeyReactivatedV2.
+func (e *Extension) OnKeyReactivatedV2(ctx context.Context, k *key.Key, meta plugin.EventMeta) error {
+	return e.emitKey(ctx, EventKeyReactivated, k, meta, nil)
+}
+
+// OnKeyExpiredV2 implements plugin.KeyExpiredV2.
+func (e *Extension) OnKeyExpiredV2(ctx context.Context, k *key.Key, meta plugin.EventMeta) error {
+	return e.emitKey(ctx, EventKeyExpired, k, meta, nil)
+}
+
+// OnKeyFlagsChangedV2 implements plugin.KeyFlagsChangedV2.
+func (e *Extension) OnKeyFlagsChangedV2(ctx context.Context, k *key.Key, added, removed key.Flags, meta plugin.EventMeta) error {
+	return e.emitKey(ctx, EventKeyFlagsChanged, k, meta, map[string]any{"added": added, "removed": removed})
+}
+
+// OnKeyCompromisedV2 implements plugin.KeyCompromisedV2.
+func (e *Extension) OnKeyCompromisedV2(ctx context.Context, k *key.Key, report *key.CompromiseReport, meta plugin.EventMeta) error {
+	return e.emitKey(ctx, EventKeyCompromised, k, meta, map[string]any{"report": report})
+}
+
+// OnKeyDebugEnabledV2 implements plugin.KeyDebugEnabledV2.
+func (e *Extension) OnKeyDebugEnabledV2(ctx context.Context, k *key.Key, meta plugin.EventMeta) error {
+	return e.emitKey(ctx, EventKeyDebugEnabled, k, meta, nil)
+}
+
+// OnKeyExportedV2 implements plugin.KeyExportedV2. The published snapshot
+// leaves out the exported hash, like every other key snapshot.
+func (e *Extension) OnKeyExportedV2(ctx context.Context, k *key.Key, meta plugin.EventMeta) error {
+	return e.emitKey(ctx, EventKeyExported, k, meta, nil)
+}
+
+// OnKeyImportedV2 implements plugin.KeyImportedV2.
+func (e *Extension) OnKeyImportedV2(ctx context.Context, k *key.Key, meta plugin.EventMeta) error {
+	return e.emitKey(ctx, EventKeyImported, k, meta, nil)
+}
+
+// OnPolicyCreatedV2 implements plugin.PolicyCreatedV2.
+func (e *Extension) OnPolicyCreatedV2(ctx context.Context, pol *policy.Policy, meta plugin.EventMeta) error {
+	return e.emitPolicy(ctx, EventPolicyCreated, pol, meta)
+}
+
+// OnPolicyUpdatedV2 implements plugin.PolicyUpdatedV2.
+func (e *Extension) OnPolicyUpdatedV2(ctx context.Context, pol *policy.Policy, meta plugin.EventMeta) error {
+	return e.emitPolicy(ctx, EventPolicyUpdated, pol, meta)
+}
+
+// OnPolicyDeletedV2 implements plugin.PolicyDeletedV2.
+func (e *Extension) OnPolicyDeletedV2(ctx context.Context, polID id.PolicyID, meta plugin.EventMeta) error {
+	env := newEnvelope(EventPolicyDeleted, meta)
+	env.PolicyID = polID.String()
+	return e.publish(ctx, env, polID.String())
+}
+
+func (e *Extension) emitKey(ctx context.Context, eventType string, k *key.Key, meta plugin.EventMeta, data map[string]any) error {
+	env := keyEnvelope(eventType, k, meta)
+	env.Data = data
+	return e.publish(ctx, env, k.ID.String())
+}
+
+func (e *Extension) emitPolicy(ctx context.Context, eventType string, pol *policy.Policy, meta plugin.EventMeta) error {
+	env := newEnvelope(eventType, meta)
+	env.TenantID, env.AppID = pol.TenantID, pol.AppID
+	env.Policy, env.PolicyID = pol, pol.ID.String()
+	return e.publish(ctx, env, pol.ID.String())
+}
+
+func keyEnvelope(eventType string, k *key.Key, meta plugin.EventMeta) *Envelope {
+	env := newEnvelope(eventType, meta)
+	env.TenantID, env.AppID, env.Key = k.TenantID, k.AppID, k
+	return env
+}
+
+func newEnvelope(eventType string, meta plugin.EventMeta) *Envelope {
+	ts := meta.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	return &Envelope{
+		SchemaVersion: SchemaVersion,
+		ID:            id.NewEventID().String(),
+		Type:          eventType,
+		Time:          ts.UTC(),
+		Meta:          metaOf(meta),
+	}
+}
+
+// publish encodes env and publishes it with partition key pk, queueing it
+// when the Publisher fails or earlier events for pk are still queued.
+// Failures are logged, not returned: the event is retried by Drain.
+func (e *Extension) publish(ctx context.Context, env *Envelope, pk string) error {
+	if e.enabled != nil && !e.enabled[env.Type] {
+		return nil
+	}
+	payload, err := json.Marshal(env)
+	if err != nil {
+		e.logger.Warn("eventbus_hook: failed to encode event",
+			log.String("type", env.Type),
+			log.Any("error", err),
+		)
+		return nil
+	}
+	msg := &message{topic: e.topicFor(env.Type), key: pk, payload: payload}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.queuedLocked(pk) {
+		e.enqueueLocked(msg)
+		return nil
+	}
+	if err := e.send(ctx, msg); err != nil {
+		e.logger.Warn("eventbus_hook: failed to publish event, queued for retry",
+			log.String("type", env.Type),
+			log.String("topic", msg.topic),
+			log.Any("error", err),
+		)
+		e.enqueueLocked(msg)
+	}
+	return nil
+}
+
+func (e *Extension) topicFor(eventType string) string {
+	if t, ok := e.topics[eventType]; ok {
+		return t
+	}
+	return e.defaultTopic
+}
+
+func (e *Extension) send(ctx context.Context, msg *message) error {
+	return e.publisher.Publish(ctx, msg.topic, []byte(msg.key), msg.payload)
+}
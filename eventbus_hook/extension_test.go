@@ -0,0 +1,229 @@
+package eventbushook_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	eventbushook "github.com/xraph/keysmith/eventbus_hook"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+func testCtx() context.Context {
+	ctx := keysmith.WithTenant(context.Background(), "app1", "tenant1")
+	ctx = keysmith.WithActor(ctx, "user-42")
+	return keysmith.WithRequestID(ctx, "req-7")
+}
+
+func newEngine(t *testing.T, ext *eventbushook.Extension) *keysmith.Engine {
+	t.Helper()
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithExtension(ext))
+	require.NoError(t, err)
+	return eng
+}
+
+func envelopes(t *testing.T, pub *eventbushook.MemoryPublisher) []*eventbushook.Envelope {
+	t.Helper()
+	msgs := pub.Messages()
+	out := make([]*eventbushook.Envelope, len(msgs))
+	for i, m := range msgs {
+		env, err := m.Envelope()
+		require.NoError(t, err)
+		out[i] = env
+	}
+	return out
+}
+
+func TestExtension_Name(t *testing.T) {
+	assert.Equal(t, "eventbus-hook", eventbushook.New(eventbushook.NewMemoryPublisher()).Name())
+}
+
+func TestExtension_EnvelopeSchema(t *testing.T) {
+	pub := eventbushook.NewMemoryPublisher()
+	eng := newEngine(t, eventbushook.New(pub))
+
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "Orders", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	require.NoError(t, eng.RevokeKey(testCtx(), created.Key.ID, "no longer used"))
+
+	msgs := pub.Messages()
+	require.Len(t, msgs, 2)
+	for _, m := range msgs {
+		assert.Equal(t, created.Key.ID.String(), m.Key, "keyed by key ID")
+		assert.NotContains(t, string(m.Payload), created.RawKey)
+		assert.NotContains(t, string(m.Payload), created.Key.KeyHash)
+		assert.NotContains(t, string(m.Payload), "key_hash")
+	}
+
+	envs := envelopes(t, pub)
+	env := envs[0]
+	assert.Equal(t, eventbushook.SchemaVersion, env.SchemaVersion)
+	assert.Equal(t, eventbushook.EventKeyCreated, env.Type)
+	_, err = id.ParseEventID(env.ID)
+	assert.NoError(t, err)
+	assert.False(t, env.Time.IsZero())
+	assert.Equal(t, "tenant1", env.TenantID)
+	assert.Equal(t, "app1", env.AppID)
+	assert.Equal(t, eventbushook.Meta{Trigger: string(plugin.TriggerManual), ActorID: "user-42", RequestID: "req-7"}, env.Meta)
+	require.NotNil(t, env.Key)
+	assert.Equal(t, created.Key.ID, env.Key.ID)
+	assert.Equal(t, "Orders", env.Key.Name)
+	assert.Empty(t, env.Key.KeyHash)
+
+	assert.Equal(t, eventbushook.EventKeyRevoked, envs[1].Type)
+	assert.Equal(t, key.StateRevoked, envs[1].Key.State)
+	assert.Equal(t, "no longer used", envs[1].Data["reason"])
+	assert.NotEqual(t, env.ID, envs[1].ID)
+}
+
+func TestExtension_TopicRouting(t *testing.T) {
+	pub := eventbushook.NewMemoryPublisher()
+	ext := eventbushook.New(pub,
+		eventbushook.WithDefaultTopic("keysmith"),
+		eventbushook.WithTopic(eventbushook.EventKeyRevoked, "keysmith.security"),
+		eventbushook.WithTopic(eventbushook.EventPolicyCreated, "keysmith.policies"),
+		eventbushook.WithTopic(eventbushook.EventPolicyDeleted, "keysmith.policies"),
+	)
+	eng := newEngine(t, ext)
+
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "Routed", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	require.NoError(t, eng.RevokeKey(testCtx(), created.Key.ID, "rotated out"))
+	pol := &policy.Policy{Name: "Standard"}
+	require.NoError(t, eng.CreatePolicy(testCtx(), pol))
+	require.NoError(t, eng.DeletePolicy(testCtx(), pol.ID))
+
+	topics := make([]string, 0, 4)
+	for _, m := range pub.Messages() {
+		topics = append(topics, m.Topic)
+	}
+	assert.Equal(t, []string{"keysmith", "keysmith.security", "keysmith.policies", "keysmith.policies"}, topics)
+
+	envs := envelopes(t, pub)
+	assert.Equal(t, pol.ID.String(), pub.Messages()[2].Key, "policy events are keyed by policy ID")
+	assert.Equal(t, "Standard", envs[2].Policy.Name)
+	assert.Nil(t, envs[3].Policy)
+	assert.Equal(t, pol.ID.String(), envs[3].PolicyID)
+}
+
+func TestExtension_WithEvents(t *testing.T) {
+	pub := eventbushook.NewMemoryPublisher()
+	eng := newEngine(t, eventbushook.New(pub, eventbushook.WithEvents(eventbushook.EventKeyRevoked)))
+
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "Filtered", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	require.NoError(t, eng.RevokeKey(testCtx(), created.Key.ID, "done"))
+
+	envs := envelopes(t, pub)
+	require.Len(t, envs, 1)
+	assert.Equal(t, eventbushook.EventKeyRevoked, envs[0].Type)
+}
+
+func TestExtension_OrderingPerKey(t *testing.T) {
+	ctx := context.Background()
+	pub := eventbushook.NewMemoryPublisher()
+	ext := eventbushook.New(pub)
+	a := &key.Key{ID: id.NewKeyID(), Name: "a"}
+	b := &key.Key{ID: id.NewKeyID(), Name: "b"}
+	meta := plugin.EventMeta{Trigger: plugin.TriggerManual}
+
+	// Key a's first event fails; b's events are unaffected.
+	pub.FailWhen(func(_, k string) bool { return k == a.ID.String() })
+	require.NoError(t, ext.OnKeyCreatedV2(ctx, a, meta), "publish failures are queued, not returned")
+	require.NoError(t, ext.OnKeyCreatedV2(ctx, b, meta))
+	pub.FailWhen(nil)
+
+	// a's later events queue behind the failed one even though the
+	// publisher has recovered.
+	require.NoError(t, ext.OnKeySuspendedV2(ctx, a, meta))
+	require.NoError(t, ext.OnKeySuspendedV2(ctx, b, meta))
+	require.NoError(t, ext.OnKeyReactivatedV2(ctx, a, meta))
+	assert.Equal(t, 3, ext.Pending())
+
+	n, err := ext.Drain(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Zero(t, ext.Pending())
+
+	perKey := make(map[string][]string)
+	for _, env := range envelopes(t, pub) {
+		perKey[env.Key.Name] = append(perKey[env.Key.Name], env.Type)
+	}
+	assert.Equal(t, []string{eventbushook.EventKeyCreated, eventbushook.EventKeySuspended, eventbushook.EventKeyReactivated}, perKey["a"])
+	assert.Equal(t, []string{eventbushook.EventKeyCreated, eventbushook.EventKeySuspended}, perKey["b"])
+}
+
+func TestExtension_DrainSkipsBlockedKeys(t *testing.T) {
+	ctx := context.Background()
+	pub := eventbushook.NewMemoryPublisher()
+	ext := eventbushook.New(pub)
+	a := &key.Key{ID: id.NewKeyID(), Name: "a"}
+	b := &key.Key{ID: id.NewKeyID(), Name: "b"}
+
+	pub.SetDown(true)
+	require.NoError(t, ext.OnKeyCreatedV2(ctx, a, plugin.EventMeta{}))
+	require.NoError(t, ext.OnKeyCreatedV2(ctx, b, plugin.EventMeta{}))
+	require.NoError(t, ext.OnKeySuspendedV2(ctx, a, plugin.EventMeta{}))
+
+	// a is still failing; b drains.
+	pub.FailWhen(func(_, k string) bool { return k == a.ID.String() })
+	n, err := ext.Drain(ctx)
+	require.ErrorIs(t, err, eventbushook.ErrPublisherDown)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 2, ext.Pending())
+	require.Len(t, pub.Messages(), 1)
+	assert.Equal(t, b.ID.String(), pub.Messages()[0].Key)
+}
+
+func TestExtension_MaxPending(t *testing.T) {
+	ctx := context.Background()
+	pub := eventbushook.NewMemoryPublisher()
+	ext := eventbushook.New(pub, eventbushook.WithMaxPending(2))
+	pub.SetDown(true)
+
+	keys := make([]*key.Key, 3)
+	for i := range keys {
+		keys[i] = &key.Key{ID: id.NewKeyID()}
+		require.NoError(t, ext.OnKeyCreatedV2(ctx, keys[i], plugin.EventMeta{}))
+	}
+	assert.Equal(t, 2, ext.Pending())
+	assert.Equal(t, 1, ext.Dropped())
+
+	pub.SetDown(false)
+	_, err := ext.Drain(ctx)
+	require.NoError(t, err)
+	msgs := pub.Messages()
+	require.Len(t, msgs, 2)
+	assert.Equal(t, keys[1].ID.String(), msgs[0].Key, "the oldest event was dropped")
+	assert.Equal(t, keys[2].ID.String(), msgs[1].Key)
+}
+
+func TestExtension_DrainOnShutdown(t *testing.T) {
+	pub := eventbushook.NewMemoryPublisher()
+	ext := eventbushook.New(pub)
+	eng := newEngine(t, ext)
+	require.NoError(t, ext.Start(context.Background()))
+
+	pub.SetDown(true)
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "Late", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	require.NoError(t, eng.SuspendKey(testCtx(), created.Key.ID))
+	assert.Equal(t, 2, ext.Pending())
+	assert.Empty(t, pub.Messages())
+
+	pub.SetDown(false)
+	require.NoError(t, eng.Stop(context.Background()))
+	assert.Zero(t, ext.Pending(), "the engine's shutdown drains the queue")
+
+	envs := envelopes(t, pub)
+	require.Len(t, envs, 2)
+	assert.Equal(t, eventbushook.EventKeyCreated, envs[0].Type)
+	assert.Equal(t, eventbushook.EventKeySuspended, envs[1].Type)
+}
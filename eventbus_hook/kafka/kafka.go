@@ -0,0 +1,82 @@
+// Package kafka publishes eventbus_hook events to Kafka.
+// It defines a local Client interface so the package does not import a
+// Kafka client directly; adapt *kgo.Client from franz-go in a few lines:
+//
+//	type kgoClient struct{ c *kgo.Client }
+//
+//	func (a kgoClient) Produce(ctx context.Context, r *kafka.Record) error {
+//		rec := &kgo.Record{Topic: r.Topic, Key: r.Key, Value: r.Value}
+//		for _, h := range r.Headers {
+//			rec.Headers = append(rec.Headers, kgo.RecordHeader{Key: h.Key, Value: []byte(h.Value)})
+//		}
+//		return a.c.ProduceSync(ctx, rec).FirstErr()
+//	}
+//
+// Records are keyed by key ID, so the default partitioner keeps each key's
+// events on one partition and in order.
+package kafka
+
+import (
+	"context"
+
+	eventbushook "github.com/xraph/keysmith/eventbus_hook"
+)
+
+// Compile-time interface check.
+var _ eventbushook.Publisher = (*Publisher)(nil)
+
+// HeaderContentType is set on every record.
+const HeaderContentType = "content-type"
+
+// Header is a Kafka record header.
+type Header struct {
+	Key   string
+	Value string
+}
+
+// Record is a Kafka record to produce.
+type Record struct {
+	Topic   string
+	Key     []byte
+	Value   []byte
+	Headers []Header
+}
+
+// Client is the subset of a Kafka producer the publisher uses. Produce must
+// return once the record is acknowledged, so a failure can be retried.
+type Client interface {
+	Produce(ctx context.Context, r *Record) error
+}
+
+// Publisher produces events to Kafka.
+type Publisher struct {
+	client Client
+	prefix string
+}
+
+// Option configures a Publisher.
+type Option func(*Publisher)
+
+// WithTopicPrefix prepends prefix to every topic, e.g. "prod.".
+func WithTopicPrefix(prefix string) Option {
+	return func(p *Publisher) { p.prefix = prefix }
+}
+
+// New returns a Publisher that produces with c.
+func New(c Client, opts ...Option) *Publisher {
+	p := &Publisher{client: c}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Publish implements eventbushook.Publisher.
+func (p *Publisher) Publish(ctx context.Context, topic string, key, payload []byte) error {
+	return p.client.Produce(ctx, &Record{
+		Topic:   p.prefix + topic,
+		Key:     key,
+		Value:   payload,
+		Headers: []Header{{Key: HeaderContentType, Value: "application/json"}},
+	})
+}
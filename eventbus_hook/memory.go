@@ -0,0 +1,75 @@
+package eventbushook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// ErrPublisherDown is returned by a [MemoryPublisher] set to fail.
+var ErrPublisherDown = errors.New("eventbus_hook: publisher down")
+
+// Message is an event received by a [MemoryPublisher].
+type Message struct {
+	Topic   string
+	Key     string
+	Payload []byte
+}
+
+// Envelope decodes the message payload.
+func (m Message) Envelope() (*Envelope, error) {
+	var env Envelope
+	if err := json.Unmarshal(m.Payload, &env); err != nil {
+		return nil, err
+	}
+	return &env, nil
+}
+
+// MemoryPublisher is an in-memory Publisher for tests. It records every
+// message it accepts, in order.
+type MemoryPublisher struct {
+	mu       sync.Mutex
+	messages []Message
+	fail     func(topic, key string) bool
+}
+
+// NewMemoryPublisher returns an empty MemoryPublisher.
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+// Publish implements Publisher.
+func (p *MemoryPublisher) Publish(_ context.Context, topic string, key, payload []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.fail != nil && p.fail(topic, string(key)) {
+		return ErrPublisherDown
+	}
+	p.messages = append(p.messages, Message{Topic: topic, Key: string(key), Payload: append([]byte(nil), payload...)})
+	return nil
+}
+
+// SetDown makes every publish fail with ErrPublisherDown, or succeed again.
+func (p *MemoryPublisher) SetDown(down bool) {
+	if !down {
+		p.FailWhen(nil)
+		return
+	}
+	p.FailWhen(func(string, string) bool { return true })
+}
+
+// FailWhen makes publishes for which fn returns true fail with
+// ErrPublisherDown. A nil fn clears it.
+func (p *MemoryPublisher) FailWhen(fn func(topic, key string) bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.fail = fn
+}
+
+// Messages returns a copy of the accepted messages, oldest first.
+func (p *MemoryPublisher) Messages() []Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Message(nil), p.messages...)
+}
@@ -0,0 +1,94 @@
+// Package nats publishes eventbus_hook events to NATS JetStream.
+// It defines a local Client interface so the package does not import the
+// NATS client directly; adapt jetstream.JetStream from nats.go in a few
+// lines:
+//
+//	type jsClient struct{ js jetstream.JetStream }
+//
+//	func (a jsClient) Publish(ctx context.Context, m *nats.Msg) error {
+//		msg := natsgo.NewMsg(m.Subject)
+//		msg.Data = m.Data
+//		for k, v := range m.Header {
+//			msg.Header.Set(k, v)
+//		}
+//		_, err := a.js.PublishMsg(ctx, msg)
+//		return err
+//	}
+//
+// Each message carries its envelope ID as Nats-Msg-Id, so JetStream drops
+// the duplicate when a publish that timed out is retried.
+package nats
+
+import (
+	"context"
+	"encoding/json"
+
+	eventbushook "github.com/xraph/keysmith/eventbus_hook"
+)
+
+// Compile-time interface check.
+var _ eventbushook.Publisher = (*Publisher)(nil)
+
+// Header names set on every message.
+const (
+	HeaderMsgID        = "Nats-Msg-Id"
+	HeaderPartitionKey = "Keysmith-Partition-Key"
+	HeaderContentType  = "Content-Type"
+)
+
+// Msg is a message to publish to a JetStream stream.
+type Msg struct {
+	Subject string
+	Data    []byte
+	Header  map[string]string
+}
+
+// Client is the subset of JetStream the publisher uses. Publish must return
+// once the stream acknowledges the message, so a failure can be retried.
+type Client interface {
+	Publish(ctx context.Context, m *Msg) error
+}
+
+// Publisher publishes events to JetStream subjects named after the topics.
+type Publisher struct {
+	client       Client
+	keyInSubject bool
+}
+
+// Option configures a Publisher.
+type Option func(*Publisher)
+
+// WithKeyInSubject appends the partition key to the subject as a last
+// token, e.g. "keysmith.events.akey_01h...", so consumers can filter on or
+// order by one key with a subject wildcard.
+func WithKeyInSubject() Option {
+	return func(p *Publisher) { p.keyInSubject = true }
+}
+
+// New returns a Publisher that publishes with c.
+func New(c Client, opts ...Option) *Publisher {
+	p := &Publisher{client: c}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Publish implements eventbushook.Publisher.
+func (p *Publisher) Publish(ctx context.Context, topic string, key, payload []byte) error {
+	subject := topic
+	if p.keyInSubject && len(key) > 0 {
+		subject += "." + string(key)
+	}
+	header := map[string]string{
+		HeaderPartitionKey: string(key),
+		HeaderContentType:  "application/json",
+	}
+	var env struct {
+		ID string `json:"id"`
+	}
+	if json.Unmarshal(payload, &env) == nil && env.ID != "" {
+		header[HeaderMsgID] = env.ID
+	}
+	return p.client.Publish(ctx, &Msg{Subject: subject, Data: payload, Header: header})
+}
@@ -0,0 +1,66 @@
+package eventbushook
+
+import (
+	"time"
+
+	log "github.com/xraph/go-utils/log"
+)
+
+// Option configures an Extension.
+type Option func(*Extension)
+
+// WithTopic publishes events of the given type, such as [EventKeyRevoked],
+// to topic instead of the default topic.
+func WithTopic(eventType, topic string) Option {
+	return func(e *Extension) {
+		e.topics[eventType] = topic
+	}
+}
+
+// WithDefaultTopic sets the topic for event types without a [WithTopic]
+// route. Defaults to [DefaultTopic].
+func WithDefaultTopic(topic string) Option {
+	return func(e *Extension) {
+		if topic != "" {
+			e.defaultTopic = topic
+		}
+	}
+}
+
+// WithEvents restricts publishing to the specified event types only.
+// If not called, all event types are published.
+func WithEvents(eventTypes ...string) Option {
+	return func(e *Extension) {
+		e.enabled = make(map[string]bool, len(eventTypes))
+		for _, t := range eventTypes {
+			e.enabled[t] = true
+		}
+	}
+}
+
+// WithLogger sets the logger for publish errors.
+func WithLogger(logger log.Logger) Option {
+	return func(e *Extension) {
+		e.logger = logger
+	}
+}
+
+// WithMaxPending caps the retry queue. When it is full the oldest event is
+// dropped and counted by Dropped. Defaults to [DefaultMaxPending].
+func WithMaxPending(n int) Option {
+	return func(e *Extension) {
+		if n > 0 {
+			e.maxPending = n
+		}
+	}
+}
+
+// WithDrainInterval sets how often the loop started by Start drains the
+// retry queue. Defaults to [DefaultDrainInterval].
+func WithDrainInterval(d time.Duration) Option {
+	return func(e *Extension) {
+		if d > 0 {
+			e.drainInterval = d
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package eventbushook
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/xraph/go-utils/log"
+)
+
+// queuedLocked reports whether an event with partition key pk is waiting in
+// the retry queue. e.mu must be held.
+func (e *Extension) queuedLocked(pk string) bool {
+	for _, m := range e.pending {
+		if m.key == pk {
+			return true
+		}
+	}
+	return false
+}
+
+// enqueueLocked adds msg to the retry queue, dropping the oldest event when
+// the queue is full. e.mu must be held.
+func (e *Extension) enqueueLocked(msg *message) {
+	if len(e.pending) >= e.maxPending {
+		e.logger.Warn("eventbus_hook: retry queue full, dropping oldest event",
+			log.Int("max_pending", e.maxPending),
+			log.String("topic", e.pending[0].topic),
+		)
+		e.pending[0] = nil
+		e.pending = e.pending[1:]
+		e.dropped++
+	}
+	e.pending = append(e.pending, msg)
+}
+
+// Pending returns the number of events waiting in the retry queue.
+func (e *Extension) Pending() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.pending)
+}
+
+// Dropped returns the number of events dropped because the retry queue was
+// full.
+func (e *Extension) Dropped() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.dropped
+}
+
+// Drain publishes queued events, oldest first, and returns how many were
+// published. When an event fails, later events with the same partition key
+// stay queued behind it so each key's events keep their order; events for
+// other keys are still attempted. It returns the first publish error.
+func (e *Extension) Drain(ctx context.Context) (int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var (
+		published int
+		firstErr  error
+		kept      []*message
+		blocked   map[string]bool
+	)
+	for i, m := range e.pending {
+		if err := ctx.Err(); err != nil {
+			kept = append(kept, e.pending[i:]...)
+			if firstErr == nil {
+				firstErr = err
+			}
+			break
+		}
+		if blocked[m.key] {
+			kept = append(kept, m)
+			continue
+		}
+		if err := e.send(ctx, m); err != nil {
+			if blocked == nil {
+				blocked = make(map[string]bool)
+			}
+			blocked[m.key] = true
+			kept = append(kept, m)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("eventbus_hook: publish to %s: %w", m.topic, err)
+			}
+			continue
+		}
+		published++
+	}
+	e.pending = kept
+	return published, firstErr
+}
+
+// Start runs Drain in the background every drain interval, backing off
+// exponentially up to DefaultMaxDrainBackoff while the Publisher fails.
+func (e *Extension) Start(ctx context.Context) error {
+	e.loopMu.Lock()
+	defer e.loopMu.Unlock()
+	if e.stop != nil {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	done := make(chan struct{})
+	e.stop, e.stopped = cancel, done
+
+	go func() {
+		defer close(done)
+		wait := e.drainInterval
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+			if _, err := e.Drain(ctx); err != nil {
+				wait = min(wait*2, DefaultMaxDrainBackoff)
+				e.logger.Warn("eventbus_hook: drain failed",
+					log.Int("pending", e.Pending()),
+					log.String("retry_in", wait.String()),
+					log.Any("error", err),
+				)
+			} else {
+				wait = e.drainInterval
+			}
+			timer.Reset(wait)
+		}
+	}()
+	return nil
+}
+
+// Stop ends the drain loop started by Start and waits for it to exit.
+// Queued events stay queued for the next Start or Drain.
+func (e *Extension) Stop() {
+	e.loopMu.Lock()
+	stop, stopped := e.stop, e.stopped
+	e.stop, e.stopped = nil, nil
+	e.loopMu.Unlock()
+	if stop == nil {
+		return
+	}
+	stop()
+	<-stopped
+}
+
+// OnShutdown implements plugin.Shutdown. It stops the drain loop and makes a
+// last attempt to publish queued events. The queue is in memory, so events
+// that still fail are lost; they are logged with the count.
+func (e *Extension) OnShutdown(ctx context.Context) error {
+	e.Stop()
+	if _, err := e.Drain(ctx); err != nil {
+		e.logger.Warn("eventbus_hook: final drain failed, queued events lost",
+			log.Int("pending", e.Pending()),
+			log.Any("error", err),
+		)
+	}
+	return nil
+}
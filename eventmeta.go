@@ -0,0 +1,50 @@
+package keysmith
+
+import (
+	"context"
+	"errors"
+
+	"github.com/xraph/keysmith/plugin"
+)
+
+// eventMeta builds the meta passed to V2 hooks for an event caused by
+// trigger, with the actor, request ID, and group read from ctx.
+func (e *Engine) eventMeta(ctx context.Context, trigger plugin.Trigger, code plugin.ReasonCode) plugin.EventMeta {
+	return plugin.EventMeta{
+		Trigger:    trigger,
+		ReasonCode: code,
+		ActorID:    ActorFromContext(ctx),
+		RequestID:  RequestIDFromContext(ctx),
+		GroupID:    groupEventFromContext(ctx),
+		Timestamp:  e.now(),
+	}
+}
+
+// validationReasons maps validation errors to reason codes, first match
+// wins.
+var validationReasons = []struct {
+	err  error
+	code plugin.ReasonCode
+}{
+	{ErrKeyInactive, plugin.ReasonKeyInactive},
+	{ErrKeyExpired, plugin.ReasonKeyExpired},
+	{ErrRateLimited, plugin.ReasonRateLimited},
+	{ErrTenantRateLimited, plugin.ReasonTenantRateLimited},
+	{ErrConsumerNotAllowed, plugin.ReasonConsumerMismatch},
+	{ErrPrefixNotAccepted, plugin.ReasonPrefixNotAccepted},
+	{ErrIPNotAllowed, plugin.ReasonIPNotAllowed},
+	{ErrMethodNotAllowed, plugin.ReasonRouteNotAllowed},
+	{ErrPathNotAllowed, plugin.ReasonRouteNotAllowed},
+}
+
+// validationReason classifies a validation failure. Anything not listed,
+// including a failed lookup, is reported as ReasonInvalidKey, matching the
+// ErrInvalidKey the caller sees.
+func validationReason(err error) plugin.ReasonCode {
+	for _, r := range validationReasons {
+		if errors.Is(err, r.err) {
+			return r.code
+		}
+	}
+	return plugin.ReasonInvalidKey
+}
@@ -0,0 +1,132 @@
+package keysmith_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keysmithtest"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/rotation"
+)
+
+func newMetaEngine(t *testing.T) (*keysmith.Engine, *fakeClock, *keysmithtest.Recorder) {
+	t.Helper()
+	clock := &fakeClock{t: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)}
+	rec := keysmithtest.NewRecorder()
+	eng := keysmithtest.NewEngine(t, keysmith.WithClock(clock.Now), keysmith.WithExtension(rec))
+	return eng, clock, rec
+}
+
+// onlyEvent returns the single recorded event of hook.
+func onlyEvent(t *testing.T, rec *keysmithtest.Recorder, hook string) keysmithtest.Event {
+	t.Helper()
+	evts := rec.Filter(hook)
+	require.Len(t, evts, 1, hook)
+	return evts[0]
+}
+
+func TestEventMeta_Manual(t *testing.T) {
+	eng, clock, rec := newMetaEngine(t)
+	ctx := keysmith.WithRequestID(keysmith.WithActor(testCtx(), "user_42"), "req_7")
+	created := keysmithtest.NewKey(eng).MustCreate(t, ctx)
+
+	require.NoError(t, eng.SuspendKey(ctx, created.Key.ID))
+	_, err := eng.RotateKey(ctx, created.Key.ID, rotation.ReasonScheduled)
+	require.NoError(t, err)
+
+	want := plugin.EventMeta{
+		Trigger:   plugin.TriggerManual,
+		ActorID:   "user_42",
+		RequestID: "req_7",
+		Timestamp: clock.Now(),
+	}
+	assert.Equal(t, want, onlyEvent(t, rec, "KeyCreated").Meta)
+	assert.Equal(t, want, onlyEvent(t, rec, "KeySuspended").Meta)
+
+	want.ReasonCode = plugin.ReasonCode(rotation.ReasonScheduled)
+	assert.Equal(t, want, onlyEvent(t, rec, "KeyRotated").Meta)
+}
+
+func TestEventMeta_RevocationReasons(t *testing.T) {
+	eng, _, rec := newMetaEngine(t)
+	ctx := testCtx()
+
+	manual := keysmithtest.NewKey(eng).MustCreate(t, ctx)
+	require.NoError(t, eng.RevokeKey(ctx, manual.Key.ID, "offboarding"))
+
+	leaked := keysmithtest.NewKey(eng).MustCreate(t, ctx)
+	_, err := eng.RevokeByHashes(ctx, []string{leaked.Key.KeyHash}, "found on pastebin")
+	require.NoError(t, err)
+
+	compromised := keysmithtest.NewKey(eng).MustCreate(t, ctx)
+	_, err = eng.ReportCompromise(ctx, compromised.Key.ID, &key.CompromiseReport{Source: "github", Action: key.CompromiseRevoke})
+	require.NoError(t, err)
+
+	codes := make(map[string]plugin.ReasonCode)
+	for _, evt := range rec.Filter("KeyRevoked") {
+		assert.Equal(t, plugin.TriggerManual, evt.Meta.Trigger)
+		codes[evt.Reason] = evt.Meta.ReasonCode
+	}
+	assert.Equal(t, map[string]plugin.ReasonCode{
+		"offboarding":         "",
+		"found on pastebin":   plugin.ReasonLeakedHash,
+		"compromised: github": plugin.ReasonCompromised,
+	}, codes)
+	assert.Equal(t, plugin.ReasonCompromised, onlyEvent(t, rec, "KeyCompromised").Meta.ReasonCode)
+}
+
+func TestEventMeta_ExpiryTrigger(t *testing.T) {
+	eng, clock, rec := newMetaEngine(t)
+	ctx := testCtx()
+	expiry := clock.Now().Add(time.Hour)
+	byValidation := keysmithtest.NewKey(eng).WithExpiry(expiry).MustCreate(t, ctx)
+	bySweep := keysmithtest.NewKey(eng).WithExpiry(expiry).MustCreate(t, ctx)
+
+	clock.Set(expiry.Add(time.Minute))
+	keysmithtest.AssertRejectedWith(t, eng, ctx, byValidation.RawKey, keysmith.ErrKeyExpired)
+	_, err := eng.CleanupExpiredKeys(ctx)
+	require.NoError(t, err)
+
+	triggers := make(map[string]plugin.EventMeta)
+	for _, evt := range rec.Filter("KeyExpired") {
+		triggers[evt.Key.ID.String()] = evt.Meta
+	}
+	require.Len(t, triggers, 2)
+
+	v := triggers[byValidation.Key.ID.String()]
+	assert.Equal(t, plugin.TriggerValidation, v.Trigger)
+	assert.Equal(t, plugin.ReasonKeyExpired, v.ReasonCode)
+	assert.Equal(t, clock.Now(), v.Timestamp)
+
+	s := triggers[bySweep.Key.ID.String()]
+	assert.Equal(t, plugin.TriggerSweep, s.Trigger)
+	assert.Equal(t, plugin.ReasonKeyExpired, s.ReasonCode)
+}
+
+func TestEventMeta_ValidationFailed(t *testing.T) {
+	eng, _, rec := newMetaEngine(t)
+	ctx := testCtx()
+
+	const unknown = "sk_test_doesnotexist"
+	keysmithtest.AssertRejectedWith(t, eng, ctx, unknown, keysmith.ErrInvalidKey)
+
+	suspended := keysmithtest.NewKey(eng).Suspended().MustCreate(t, ctx)
+	keysmithtest.AssertRejectedWith(t, eng, ctx, suspended.RawKey, keysmith.ErrKeyInactive)
+
+	evts := rec.Filter("KeyValidationFailed")
+	require.Len(t, evts, 2)
+
+	assert.Equal(t, plugin.TriggerValidation, evts[0].Meta.Trigger)
+	assert.Equal(t, plugin.ReasonInvalidKey, evts[0].Meta.ReasonCode)
+	assert.True(t, strings.HasPrefix(evts[0].Fingerprint, "sk_test:"), evts[0].Fingerprint)
+	assert.NotContains(t, evts[0].Fingerprint, "doesnotexist", "the raw key is never passed on")
+
+	assert.Equal(t, plugin.ReasonKeyInactive, evts[1].Meta.ReasonCode)
+	assert.ErrorIs(t, evts[1].Err, keysmith.ErrKeyInactive)
+}
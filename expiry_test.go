@@ -0,0 +1,162 @@
+package keysmith_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+// fakeClock is a manually advanced clock for expiry tests.
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = t
+}
+
+// expiryRecorder counts KeyExpired hook calls.
+type expiryRecorder struct {
+	expired atomic.Int64
+}
+
+func (r *expiryRecorder) Name() string { return "expiry-recorder" }
+
+func (r *expiryRecorder) OnKeyExpired(_ context.Context, _ *key.Key) error {
+	r.expired.Add(1)
+	return nil
+}
+
+func newExpiryEngine(t *testing.T, opts ...keysmith.Option) (*keysmith.Engine, *fakeClock, *expiryRecorder) {
+	t.Helper()
+	clock := &fakeClock{t: time.Now()}
+	rec := &expiryRecorder{}
+	opts = append([]keysmith.Option{
+		keysmith.WithStore(memory.New()),
+		keysmith.WithClock(clock.Now),
+		keysmith.WithExtension(rec),
+	}, opts...)
+	eng, err := keysmith.NewEngine(opts...)
+	require.NoError(t, err)
+	return eng, clock, rec
+}
+
+func createExpiringKey(t *testing.T, eng *keysmith.Engine, expiresAt time.Time) *key.CreateResult {
+	t.Helper()
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+		Name:        "Expiring Key",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+		ExpiresAt:   &expiresAt,
+	})
+	require.NoError(t, err)
+	return created
+}
+
+func TestExpiry_Boundary(t *testing.T) {
+	eng, clock, rec := newExpiryEngine(t)
+	expiresAt := clock.Now().Add(time.Hour)
+	created := createExpiringKey(t, eng, expiresAt)
+
+	clock.Set(expiresAt)
+	_, err := eng.ValidateKey(testCtx(), created.RawKey)
+	require.NoError(t, err, "a key is valid at exactly its expiry instant")
+
+	clock.Set(expiresAt.Add(time.Nanosecond))
+	_, err = eng.ValidateKey(testCtx(), created.RawKey)
+	assert.ErrorIs(t, err, keysmith.ErrKeyExpired)
+	assert.Equal(t, int64(1), rec.expired.Load())
+
+	k, err := eng.GetKey(testCtx(), created.Key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, key.StateExpired, k.State)
+}
+
+func TestExpiry_SkewTolerance(t *testing.T) {
+	eng, clock, _ := newExpiryEngine(t, keysmith.WithExpirySkewTolerance(5*time.Second))
+	expiresAt := clock.Now().Add(time.Hour)
+	created := createExpiringKey(t, eng, expiresAt)
+
+	clock.Set(expiresAt.Add(5 * time.Second))
+	_, err := eng.ValidateKey(testCtx(), created.RawKey)
+	require.NoError(t, err)
+
+	// Cleanup honours the same tolerance.
+	res, err := eng.CleanupExpiredKeys(testCtx())
+	require.NoError(t, err)
+	assert.Empty(t, res.KeyIDs)
+
+	clock.Set(expiresAt.Add(5*time.Second + time.Nanosecond))
+	_, err = eng.ValidateKey(testCtx(), created.RawKey)
+	assert.ErrorIs(t, err, keysmith.ErrKeyExpired)
+}
+
+func TestExpiry_ConcurrentValidationTransitionsOnce(t *testing.T) {
+	eng, clock, rec := newExpiryEngine(t, keysmith.WithoutValidationCoalescing())
+	expiresAt := clock.Now().Add(time.Minute)
+	created := createExpiringKey(t, eng, expiresAt)
+	clock.Set(expiresAt.Add(time.Millisecond))
+
+	const workers = 32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make([]error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			_, errs[i] = eng.ValidateKey(testCtx(), created.RawKey)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	for _, err := range errs {
+		// Validations that load the key after the transition see it as
+		// inactive rather than expired.
+		if !assert.Error(t, err) {
+			continue
+		}
+		assert.True(t, errors.Is(err, keysmith.ErrKeyExpired) || errors.Is(err, keysmith.ErrKeyInactive), "unexpected error: %v", err)
+	}
+	assert.Equal(t, int64(1), rec.expired.Load(), "exactly one KeyExpired hook")
+
+	// A later cleanup finds nothing left to expire.
+	res, err := eng.CleanupExpiredKeys(testCtx())
+	require.NoError(t, err)
+	assert.Empty(t, res.KeyIDs)
+	assert.Equal(t, int64(1), rec.expired.Load())
+}
+
+func TestExpiry_StoredInUTC(t *testing.T) {
+	eng, clock, _ := newExpiryEngine(t)
+	zone := time.FixedZone("UTC+9", 9*60*60)
+	expiresAt := clock.Now().Add(time.Hour).In(zone)
+	created := createExpiringKey(t, eng, expiresAt)
+
+	k, err := eng.GetKey(testCtx(), created.Key.ID)
+	require.NoError(t, err)
+	require.NotNil(t, k.ExpiresAt)
+	assert.Equal(t, time.UTC, k.ExpiresAt.Location())
+	assert.True(t, k.ExpiresAt.Equal(expiresAt))
+}
@@ -1,5 +1,14 @@
 package extension
 
+import (
+	"time"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/api"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/usage"
+)
+
 // Config holds the Keysmith extension configuration.
 // Fields can be set programmatically via Option functions or loaded from
 // YAML configuration files (under "extensions.keysmith" or "keysmith" keys).
@@ -19,11 +28,66 @@ type Config struct {
 	// When empty and WithGroveDatabase was called, the default (unnamed) DB is used.
 	GroveDatabase string `json:"grove_database" mapstructure:"grove_database" yaml:"grove_database"`
 
+	// UsageRecording sets how usage is recorded per route: path glob rules
+	// mapping to full, sampled, counter_only, or off, with a default mode.
+	// Nil records every request in full. It can be changed at runtime with
+	// the admin usage-recording endpoint.
+	UsageRecording *usage.RecordingPolicy `json:"usage_recording" mapstructure:"usage_recording" yaml:"usage_recording"`
+
+	// ReplayProtection, when set, requires validate endpoint requests to
+	// carry a nonce and timestamp and rejects replays; see
+	// keysmith.WithReplayProtection. Nil leaves the endpoint as it is.
+	ReplayProtection *keysmith.ReplayProtection `json:"replay_protection" mapstructure:"replay_protection" yaml:"replay_protection"`
+
+	// PrefixRules constrains keys by prefix, such as the environments "pk"
+	// keys may be created in; see keysmith.WithPrefixRule.
+	PrefixRules map[string]keysmith.PrefixRule `json:"prefix_rules" mapstructure:"prefix_rules" yaml:"prefix_rules"`
+
+	// StrictPrefixes rejects keys whose prefix has no rule in PrefixRules.
+	StrictPrefixes bool `json:"strict_prefixes" mapstructure:"strict_prefixes" yaml:"strict_prefixes"`
+
+	// EnvironmentProfiles sets defaults and caps per key environment, such
+	// as short-lived, sampled, idle-suspended "test" keys for a sandbox;
+	// see keysmith.WithEnvironmentProfile.
+	EnvironmentProfiles map[key.Environment]keysmith.EnvironmentProfile `json:"environment_profiles" mapstructure:"environment_profiles" yaml:"environment_profiles"`
+
+	// EdgeCacheTTL lets edge caches reuse a successful GET or HEAD on the
+	// validate endpoint for this long, capped at api.MaxEdgeCacheTTL. Zero
+	// keeps the responses uncacheable.
+	EdgeCacheTTL time.Duration `json:"edge_cache_ttl" mapstructure:"edge_cache_ttl" yaml:"edge_cache_ttl"`
+
+	// DeprecatedRoutes flags API routes deprecated, with the dates their
+	// Deprecation and Sunset headers announce; see
+	// api.WithRouteDeprecations.
+	DeprecatedRoutes []api.RouteDeprecation `json:"deprecated_routes" mapstructure:"deprecated_routes" yaml:"deprecated_routes"`
+
+	// DeprecatedKeyFormats flags key formats deprecated by name, such as
+	// keysmith.KeyFormatDefault or a format registered with
+	// keysmith.WithKeyFormat; see keysmith.WithKeyFormatDeprecation.
+	DeprecatedKeyFormats map[string]keysmith.KeyFormatDeprecation `json:"deprecated_key_formats" mapstructure:"deprecated_key_formats" yaml:"deprecated_key_formats"`
+
+	// Telemetry, when set, sends anonymous usage telemetry; see
+	// keysmith.WithTelemetry. Nil, the default, sends nothing.
+	Telemetry *TelemetryConfig `json:"telemetry" mapstructure:"telemetry" yaml:"telemetry"`
+
 	// RequireConfig requires config to be present in YAML files.
 	// If true and no config is found, Register returns an error.
 	RequireConfig bool `json:"-" yaml:"-"`
 }
 
+// TelemetryConfig configures keysmith.WithTelemetry.
+type TelemetryConfig struct {
+	// Endpoint receives the payloads. It may be empty in dry-run mode.
+	Endpoint string `json:"endpoint" mapstructure:"endpoint" yaml:"endpoint"`
+
+	// Interval is how often a payload is sent. Zero uses
+	// keysmith.DefaultTelemetryInterval.
+	Interval time.Duration `json:"interval" mapstructure:"interval" yaml:"interval"`
+
+	// DryRun logs each payload instead of sending it.
+	DryRun bool `json:"dry_run" mapstructure:"dry_run" yaml:"dry_run"`
+}
+
 // DefaultConfig returns a Config with sensible defaults.
 func DefaultConfig() Config {
 	return Config{}
@@ -12,17 +12,15 @@ import (
 	"github.com/xraph/forge"
 	"github.com/xraph/forge/extensions/dashboard/contributor"
 	"github.com/xraph/grove"
-	"github.com/xraph/grove/drivers/pgdriver"
 	"github.com/xraph/vessel"
 
 	"github.com/xraph/keysmith"
 	"github.com/xraph/keysmith/api"
 	ksdash "github.com/xraph/keysmith/dashboard"
+	"github.com/xraph/keysmith/middleware"
 	"github.com/xraph/keysmith/plugin"
 	"github.com/xraph/keysmith/store"
-	mongostore "github.com/xraph/keysmith/store/mongo"
-	pgstore "github.com/xraph/keysmith/store/postgres"
-	sqlitestore "github.com/xraph/keysmith/store/sqlite"
+	"github.com/xraph/keysmith/usage"
 )
 
 // ExtensionName is the name registered with Forge.
@@ -48,6 +46,10 @@ type Extension struct {
 	keysmithOpts []keysmith.Option
 	exts         []plugin.Plugin
 	useGrove     bool
+
+	// middlewareOpts configure Middleware and are described by the
+	// integration guide.
+	middlewareOpts []middleware.Option
 }
 
 // New creates a Keysmith Forge extension with the given options.
@@ -67,6 +69,14 @@ func (e *Extension) Engine() *keysmith.Engine { return e.eng }
 // API returns the API handler.
 func (e *Extension) API() *api.API { return e.apiHandler }
 
+// Middleware returns API key middleware for the engine, built with the
+// options set by WithMiddlewareOptions. Mounting it rather than calling
+// middleware.APIKeyAuth directly keeps the integration guide's headers in
+// step with what is enforced. It must be called after Register.
+func (e *Extension) Middleware() func(http.Handler) http.Handler {
+	return middleware.APIKeyAuth(e.eng, e.middlewareOpts...)
+}
+
 // Register implements [forge.Extension].
 func (e *Extension) Register(fapp forge.App) error {
 	if err := e.BaseExtension.Register(fapp); err != nil {
@@ -86,6 +96,13 @@ func (e *Extension) Register(fapp forge.App) error {
 	}); err != nil {
 		return fmt.Errorf("keysmith: register engine in container: %w", err)
 	}
+	// Services that only call the core can depend on the interface and be
+	// tested against a mock.
+	if err := vessel.Provide(fapp.Container(), func() (keysmith.KeyService, error) {
+		return e.eng, nil
+	}); err != nil {
+		return fmt.Errorf("keysmith: register key service in container: %w", err)
+	}
 
 	return nil
 }
@@ -113,19 +130,47 @@ func (e *Extension) init(fapp forge.App) error {
 		e.keysmithOpts = append(e.keysmithOpts, keysmith.WithStore(s))
 	} else if db, err := vessel.Inject[*grove.DB](fapp.Container()); err == nil {
 		// Auto-discover default grove.DB from container (matches authsome/cortex pattern).
+		// A driver whose store module is not imported is skipped.
 		s, err := e.buildStoreFromGroveDB(db)
-		if err != nil {
+		switch {
+		case errors.Is(err, store.ErrUnknownDriver):
+			e.Logger().Debug("keysmith: no store for discovered grove.DB",
+				forge.F("driver", db.Driver().Name()),
+			)
+		case err != nil:
 			return err
+		default:
+			e.keysmithOpts = append(e.keysmithOpts, keysmith.WithStore(s))
+			e.Logger().Info("keysmith: auto-discovered grove.DB from container",
+				forge.F("driver", db.Driver().Name()),
+			)
 		}
-		e.keysmithOpts = append(e.keysmithOpts, keysmith.WithStore(s))
-		e.Logger().Info("keysmith: auto-discovered grove.DB from container",
-			forge.F("driver", db.Driver().Name()),
-		)
 	}
 
-	opts := make([]keysmith.Option, 0, len(e.keysmithOpts)+1)
+	opts := make([]keysmith.Option, 0, len(e.keysmithOpts)+2)
 	opts = append(opts, e.keysmithOpts...)
 	opts = append(opts, keysmith.WithLogger(logger))
+	if e.config.UsageRecording != nil {
+		opts = append(opts, keysmith.WithUsageRecording(*e.config.UsageRecording))
+	}
+	if e.config.ReplayProtection != nil {
+		opts = append(opts, keysmith.WithReplayProtection(*e.config.ReplayProtection))
+	}
+	for prefix, rule := range e.config.PrefixRules {
+		opts = append(opts, keysmith.WithPrefixRule(prefix, rule))
+	}
+	if e.config.StrictPrefixes {
+		opts = append(opts, keysmith.WithStrictPrefixes())
+	}
+	for env, p := range e.config.EnvironmentProfiles {
+		opts = append(opts, keysmith.WithEnvironmentProfile(env, p))
+	}
+	for format, d := range e.config.DeprecatedKeyFormats {
+		opts = append(opts, keysmith.WithKeyFormatDeprecation(format, d))
+	}
+	if t := e.config.Telemetry; t != nil {
+		opts = append(opts, keysmith.WithTelemetry(t.Endpoint, keysmith.TelemetryOptions{Interval: t.Interval, DryRun: t.DryRun}))
+	}
 
 	for _, hookExt := range e.exts {
 		opts = append(opts, keysmith.WithExtension(hookExt))
@@ -137,13 +182,18 @@ func (e *Extension) init(fapp forge.App) error {
 	}
 	e.eng = eng
 
-	e.apiHandler = api.New(e.eng, fapp.Router())
+	basePath := e.config.BasePath
+	if basePath == "" {
+		basePath = "/keysmith"
+	}
+	e.apiHandler = api.New(e.eng, fapp.Router(),
+		api.WithBasePath(basePath),
+		api.WithMiddlewareOptions(e.middlewareOpts...),
+		api.WithEdgeCacheTTL(e.config.EdgeCacheTTL),
+		api.WithRouteDeprecations(e.config.DeprecatedRoutes...),
+	)
 
 	if !e.config.DisableRoutes {
-		basePath := e.config.BasePath
-		if basePath == "" {
-			basePath = "/keysmith"
-		}
 		e.apiHandler.RegisterRoutes(fapp.Router().Group(basePath))
 	}
 
@@ -183,7 +233,7 @@ func (e *Extension) Health(ctx context.Context) error {
 	if e.eng == nil {
 		return errors.New("keysmith: extension not initialized")
 	}
-	return e.eng.Store().Ping(ctx)
+	return e.eng.Health(ctx)
 }
 
 // Handler returns the HTTP handler for standalone use outside Forge.
@@ -237,11 +287,28 @@ func (e *Extension) loadConfiguration() error {
 		forge.F("disable_migrate", e.config.DisableMigrate),
 		forge.F("base_path", e.config.BasePath),
 		forge.F("grove_database", e.config.GroveDatabase),
+		forge.F("usage_recording_rules", usageRecordingRules(e.config.UsageRecording)),
+		forge.F("replay_protection", e.config.ReplayProtection != nil),
+		forge.F("prefix_rules", len(e.config.PrefixRules)),
+		forge.F("strict_prefixes", e.config.StrictPrefixes),
+		forge.F("environment_profiles", len(e.config.EnvironmentProfiles)),
+		forge.F("edge_cache_ttl", e.config.EdgeCacheTTL),
+		forge.F("deprecated_routes", len(e.config.DeprecatedRoutes)),
+		forge.F("deprecated_key_formats", len(e.config.DeprecatedKeyFormats)),
+		forge.F("telemetry", e.config.Telemetry != nil),
 	)
 
 	return nil
 }
 
+// usageRecordingRules returns the number of usage recording rules, for logging.
+func usageRecordingRules(p *usage.RecordingPolicy) int {
+	if p == nil {
+		return 0
+	}
+	return len(p.Rules)
+}
+
 // tryLoadFromConfigFile attempts to load config from YAML files.
 func (e *Extension) tryLoadFromConfigFile() (Config, bool) {
 	cm := e.App().Config()
@@ -292,6 +359,9 @@ func (e *Extension) mergeConfigurations(yamlConfig, programmaticConfig Config) C
 	if programmaticConfig.DisableMigrate {
 		yamlConfig.DisableMigrate = true
 	}
+	if programmaticConfig.StrictPrefixes {
+		yamlConfig.StrictPrefixes = true
+	}
 
 	// String fields: YAML takes precedence.
 	if yamlConfig.BasePath == "" && programmaticConfig.BasePath != "" {
@@ -300,6 +370,30 @@ func (e *Extension) mergeConfigurations(yamlConfig, programmaticConfig Config) C
 	if yamlConfig.GroveDatabase == "" && programmaticConfig.GroveDatabase != "" {
 		yamlConfig.GroveDatabase = programmaticConfig.GroveDatabase
 	}
+	if yamlConfig.UsageRecording == nil {
+		yamlConfig.UsageRecording = programmaticConfig.UsageRecording
+	}
+	if yamlConfig.ReplayProtection == nil {
+		yamlConfig.ReplayProtection = programmaticConfig.ReplayProtection
+	}
+	if yamlConfig.PrefixRules == nil {
+		yamlConfig.PrefixRules = programmaticConfig.PrefixRules
+	}
+	if yamlConfig.EnvironmentProfiles == nil {
+		yamlConfig.EnvironmentProfiles = programmaticConfig.EnvironmentProfiles
+	}
+	if yamlConfig.EdgeCacheTTL == 0 {
+		yamlConfig.EdgeCacheTTL = programmaticConfig.EdgeCacheTTL
+	}
+	if yamlConfig.DeprecatedRoutes == nil {
+		yamlConfig.DeprecatedRoutes = programmaticConfig.DeprecatedRoutes
+	}
+	if yamlConfig.DeprecatedKeyFormats == nil {
+		yamlConfig.DeprecatedKeyFormats = programmaticConfig.DeprecatedKeyFormats
+	}
+	if yamlConfig.Telemetry == nil {
+		yamlConfig.Telemetry = programmaticConfig.Telemetry
+	}
 
 	// Fill remaining zeros with defaults.
 	return e.mergeWithDefaults(yamlConfig)
@@ -322,18 +416,17 @@ func (e *Extension) resolveGroveDB(fapp forge.App) (*grove.DB, error) {
 	return db, nil
 }
 
-// buildStoreFromGroveDB constructs the appropriate store backend
-// based on the grove driver type (pg, sqlite, mongo).
+// buildStoreFromGroveDB constructs the store backend registered for the
+// grove driver type (pg, sqlite, mongo). Backends register when their
+// module is imported, e.g. _ "github.com/xraph/keysmith/store/postgres".
 func (e *Extension) buildStoreFromGroveDB(db *grove.DB) (store.Store, error) {
 	driverName := db.Driver().Name()
-	switch driverName {
-	case "pg":
-		return pgstore.New(pgdriver.Unwrap(db)), nil
-	case "sqlite":
-		return sqlitestore.New(db), nil
-	case "mongo":
-		return mongostore.New(db), nil
-	default:
-		return nil, fmt.Errorf("keysmith: unsupported grove driver %q", driverName)
+	s, err := store.Open(driverName, db)
+	if errors.Is(err, store.ErrUnknownDriver) {
+		return nil, fmt.Errorf("keysmith: unsupported grove driver %q; import its store package, such as github.com/xraph/keysmith/store/postgres: %w", driverName, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("keysmith: open %s store: %w", driverName, err)
 	}
+	return s, nil
 }
@@ -1,10 +1,16 @@
 package extension
 
 import (
+	"time"
+
 	log "github.com/xraph/go-utils/log"
 
 	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/api"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/middleware"
 	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/usage"
 )
 
 // ExtOption is a functional option for the Forge extension.
@@ -21,6 +27,12 @@ func WithEngineOptions(opts ...keysmith.Option) ExtOption {
 	return func(e *Extension) { e.keysmithOpts = append(e.keysmithOpts, opts...) }
 }
 
+// WithMiddlewareOptions sets the options Extension.Middleware builds the API
+// key middleware with. The integration guide lists the headers they read.
+func WithMiddlewareOptions(opts ...middleware.Option) ExtOption {
+	return func(e *Extension) { e.middlewareOpts = append(e.middlewareOpts, opts...) }
+}
+
 // WithHookExtension registers a lifecycle plugin with the engine.
 func WithHookExtension(x plugin.Plugin) ExtOption {
 	return func(e *Extension) { e.exts = append(e.exts, x) }
@@ -41,6 +53,80 @@ func WithBasePath(path string) ExtOption {
 	return func(e *Extension) { e.config.BasePath = path }
 }
 
+// WithUsageRecording sets the per-route usage recording policy. A
+// usage_recording block in the YAML config takes precedence.
+func WithUsageRecording(p usage.RecordingPolicy) ExtOption {
+	return func(e *Extension) { e.config.UsageRecording = &p }
+}
+
+// WithReplayProtection enables replay protection on the validate endpoint.
+// A replay_protection block in the YAML config takes precedence.
+func WithReplayProtection(cfg keysmith.ReplayProtection) ExtOption {
+	return func(e *Extension) { e.config.ReplayProtection = &cfg }
+}
+
+// WithTelemetry opts in to anonymous usage telemetry; see
+// keysmith.WithTelemetry. A telemetry block in the YAML config takes
+// precedence.
+func WithTelemetry(cfg TelemetryConfig) ExtOption {
+	return func(e *Extension) { e.config.Telemetry = &cfg }
+}
+
+// WithPrefixRule constrains the keys created with prefix; see
+// keysmith.WithPrefixRule. A prefix_rules block in the YAML config takes
+// precedence.
+func WithPrefixRule(prefix string, rule keysmith.PrefixRule) ExtOption {
+	return func(e *Extension) {
+		if e.config.PrefixRules == nil {
+			e.config.PrefixRules = make(map[string]keysmith.PrefixRule)
+		}
+		e.config.PrefixRules[prefix] = rule
+	}
+}
+
+// WithEnvironmentProfile sets defaults and caps for the keys of env; see
+// keysmith.WithEnvironmentProfile. An environment_profiles block in the YAML
+// config takes precedence.
+func WithEnvironmentProfile(env key.Environment, p keysmith.EnvironmentProfile) ExtOption {
+	return func(e *Extension) {
+		if e.config.EnvironmentProfiles == nil {
+			e.config.EnvironmentProfiles = make(map[key.Environment]keysmith.EnvironmentProfile)
+		}
+		e.config.EnvironmentProfiles[env] = p
+	}
+}
+
+// WithStrictPrefixes rejects keys whose prefix has no rule.
+func WithStrictPrefixes() ExtOption {
+	return func(e *Extension) { e.config.StrictPrefixes = true }
+}
+
+// WithRouteDeprecations flags API routes deprecated; see
+// api.WithRouteDeprecations. A deprecated_routes list in the YAML config
+// takes precedence.
+func WithRouteDeprecations(deps ...api.RouteDeprecation) ExtOption {
+	return func(e *Extension) { e.config.DeprecatedRoutes = append(e.config.DeprecatedRoutes, deps...) }
+}
+
+// WithKeyFormatDeprecation flags the key format named format deprecated;
+// see keysmith.WithKeyFormatDeprecation. A deprecated_key_formats block in
+// the YAML config takes precedence.
+func WithKeyFormatDeprecation(format string, d keysmith.KeyFormatDeprecation) ExtOption {
+	return func(e *Extension) {
+		if e.config.DeprecatedKeyFormats == nil {
+			e.config.DeprecatedKeyFormats = make(map[string]keysmith.KeyFormatDeprecation)
+		}
+		e.config.DeprecatedKeyFormats[format] = d
+	}
+}
+
+// WithEdgeCacheTTL lets edge caches reuse successful header-based
+// validations for ttl; see api.WithEdgeCacheTTL. An edge_cache_ttl in the
+// YAML config takes precedence.
+func WithEdgeCacheTTL(ttl time.Duration) ExtOption {
+	return func(e *Extension) { e.config.EdgeCacheTTL = ttl }
+}
+
 // WithRequireConfig requires config to be present in YAML files.
 // If true and no config is found, Register returns an error.
 func WithRequireConfig(require bool) ExtOption {
@@ -0,0 +1,122 @@
+package keysmith_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keysmithtest"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+func externalRefInput(ref string) *keysmith.CreateKeyInput {
+	return &keysmith.CreateKeyInput{Name: "Provisioned", Prefix: "sk", Environment: key.EnvTest, ExternalRef: ref}
+}
+
+func TestCreateKey_ExternalRefReturnsExisting(t *testing.T) {
+	rec := keysmithtest.NewRecorder()
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithExtension(rec))
+	require.NoError(t, err)
+	ctx := testCtx()
+
+	first, err := eng.CreateKey(ctx, externalRefInput("order-1001"))
+	require.NoError(t, err)
+	assert.False(t, first.DuplicateOfExisting)
+	assert.NotEmpty(t, first.RawKey)
+	assert.Equal(t, "order-1001", first.Key.ExternalRef)
+
+	// A retry after the caller lost the first response mints nothing.
+	retry := externalRefInput("order-1001")
+	retry.Name = "Renamed on retry"
+	again, err := eng.CreateKey(ctx, retry)
+	require.NoError(t, err)
+	assert.True(t, again.DuplicateOfExisting)
+	assert.Empty(t, again.RawKey, "the secret is not returned again")
+	assert.Equal(t, first.Key.ID, again.Key.ID)
+	assert.Equal(t, "Provisioned", again.Key.Name, "the rest of the input is ignored")
+	assert.Equal(t, 1, rec.Count("KeyCreated"))
+
+	keys, err := eng.ListKeys(ctx, nil)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	got, err := eng.GetKeyByExternalRef(ctx, "order-1001")
+	require.NoError(t, err)
+	assert.Equal(t, first.Key.ID, got.ID)
+	_, err = eng.GetKeyByExternalRef(ctx, "order-9999")
+	require.ErrorIs(t, err, keysmith.ErrKeyNotFound)
+	_, err = eng.GetKeyByExternalRef(ctx, "")
+	require.ErrorIs(t, err, keysmith.ErrKeyNotFound)
+
+	keys, err = eng.ListKeys(ctx, &key.ListFilter{ExternalRef: "order-1001"})
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, first.Key.ID, keys[0].ID)
+
+	// Keys without a reference never collide.
+	for range 2 {
+		res, err := eng.CreateKey(ctx, externalRefInput(""))
+		require.NoError(t, err)
+		assert.False(t, res.DuplicateOfExisting)
+	}
+}
+
+func TestCreateKey_ExternalRefPerTenant(t *testing.T) {
+	eng := newTestEngine(t)
+	acme := keysmith.WithTenant(context.Background(), "app_billing", "tenant_acme")
+	globex := keysmith.WithTenant(context.Background(), "app_billing", "tenant_globex")
+
+	a, err := eng.CreateKey(acme, externalRefInput("customer-7"))
+	require.NoError(t, err)
+	g, err := eng.CreateKey(globex, externalRefInput("customer-7"))
+	require.NoError(t, err)
+	assert.False(t, g.DuplicateOfExisting, "another tenant may reuse the reference")
+	assert.NotEqual(t, a.Key.ID, g.Key.ID)
+
+	got, err := eng.GetKeyByExternalRef(globex, "customer-7")
+	require.NoError(t, err)
+	assert.Equal(t, g.Key.ID, got.ID)
+
+	// The reference is unique across the tenant's apps, but a key in
+	// another app is not handed out.
+	search := keysmith.WithTenant(context.Background(), "app_search", "tenant_acme")
+	_, err = eng.CreateKey(search, externalRefInput("customer-7"))
+	require.ErrorIs(t, err, keysmith.ErrExternalRefInUse)
+	_, err = eng.GetKeyByExternalRef(search, "customer-7")
+	require.ErrorIs(t, err, keysmith.ErrKeyNotFound)
+}
+
+func TestCreateKey_ExternalRefConcurrent(t *testing.T) {
+	eng := newTestEngine(t)
+	ctx := testCtx()
+
+	const n = 8
+	results := make([]*key.CreateResult, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Go(func() {
+			res, err := eng.CreateKey(ctx, externalRefInput("order-2002"))
+			assert.NoError(t, err)
+			results[i] = res
+		})
+	}
+	wg.Wait()
+
+	created := 0
+	for _, res := range results {
+		require.NotNil(t, res)
+		if !res.DuplicateOfExisting {
+			created++
+		}
+		assert.Equal(t, results[0].Key.ID, res.Key.ID)
+	}
+	assert.Equal(t, 1, created, "exactly one create mints a key")
+	keys, err := eng.ListKeys(ctx, nil)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+}
@@ -0,0 +1,174 @@
+package keysmith
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/plugin"
+)
+
+const (
+	// DefaultFailureThreshold is the number of validation failures sharing a
+	// fingerprint, within DefaultFailureWindow, that triggers the
+	// SuspiciousValidationPattern hook.
+	DefaultFailureThreshold = 20
+
+	// DefaultFailureWindow is the window over which failures are counted.
+	DefaultFailureWindow = 5 * time.Minute
+
+	// maxTrackedFingerprints bounds the tracker so a flood of distinct
+	// random keys cannot grow it without limit.
+	maxTrackedFingerprints = 10_000
+
+	// maxFingerprintPrefix bounds the caller-controlled prefix portion.
+	maxFingerprintPrefix = 32
+)
+
+// failureFingerprint derives a safe identifier for a raw key that failed
+// validation: the key's prefix (everything before the last underscore) and
+// the first six hex characters of its SHA-256. The raw key cannot be
+// recovered from it.
+func failureFingerprint(rawKey string) string {
+	prefix := ""
+	if i := strings.LastIndexByte(rawKey, '_'); i > 0 {
+		prefix = rawKey[:i]
+	}
+	if len(prefix) > maxFingerprintPrefix {
+		prefix = prefix[:maxFingerprintPrefix]
+	}
+	sum := sha256.Sum256([]byte(rawKey))
+	return prefix + ":" + hex.EncodeToString(sum[:3])
+}
+
+type failureEntry struct {
+	count   int
+	first   time.Time
+	last    time.Time
+	alerted bool
+}
+
+// failureTracker keeps short-lived, in-memory failure counts per fingerprint
+// using fixed windows that start at a fingerprint's first failure.
+type failureTracker struct {
+	threshold int
+	window    time.Duration
+	now       func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*failureEntry
+}
+
+func newFailureTracker(threshold int, window time.Duration) *failureTracker {
+	return &failureTracker{
+		threshold: threshold,
+		window:    window,
+		now:       time.Now,
+		entries:   make(map[string]*failureEntry),
+	}
+}
+
+// record counts a failure for fp. It returns a pattern the first time the
+// fingerprint reaches threshold within window, and nil otherwise. The limits
+// are passed per call, since UpdateRuntimeConfig can change them.
+func (t *failureTracker) record(fp string, threshold int, window time.Duration) *key.FailurePattern {
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ent, ok := t.entries[fp]
+	if !ok || now.Sub(ent.first) > window {
+		if !ok && len(t.entries) >= maxTrackedFingerprints {
+			t.pruneLocked(now, window)
+			if len(t.entries) >= maxTrackedFingerprints {
+				return nil
+			}
+		}
+		ent = &failureEntry{first: now}
+		t.entries[fp] = ent
+	}
+	ent.count++
+	ent.last = now
+
+	if ent.alerted || ent.count < threshold {
+		return nil
+	}
+	ent.alerted = true
+	return t.pattern(fp, ent, window)
+}
+
+// top returns up to n fingerprints with the most failures in their current
+// window, highest first.
+func (t *failureTracker) top(n int, window time.Duration) []*key.FailurePattern {
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make([]*key.FailurePattern, 0, len(t.entries))
+	for fp, ent := range t.entries {
+		if now.Sub(ent.first) > window {
+			continue
+		}
+		out = append(out, t.pattern(fp, ent, window))
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Fingerprint < out[j].Fingerprint
+	})
+	if n > 0 && len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+func (t *failureTracker) pattern(fp string, ent *failureEntry, window time.Duration) *key.FailurePattern {
+	return &key.FailurePattern{
+		Fingerprint: fp,
+		Count:       ent.count,
+		Window:      window,
+		FirstSeen:   ent.first,
+		LastSeen:    ent.last,
+	}
+}
+
+func (t *failureTracker) pruneLocked(now time.Time, window time.Duration) {
+	for fp, ent := range t.entries {
+		if now.Sub(ent.first) > window {
+			delete(t.entries, fp)
+		}
+	}
+}
+
+// validationFailed fires the KeyValidationFailed hook and counts the failure
+// against the raw key's fingerprint, firing SuspiciousValidationPattern when
+// the threshold is crossed.
+func (e *Engine) validationFailed(ctx context.Context, rawKey string, err error) {
+	fp := failureFingerprint(rawKey)
+	_ = e.hooks.FireKeyValidationFailed(ctx, rawKey, fp, err, e.eventMeta(ctx, plugin.TriggerValidation, validationReason(err)))
+	if e.failures == nil {
+		return
+	}
+	cfg := e.runtimeConfig()
+	if p := e.failures.record(fp, cfg.FailureThreshold, cfg.FailureWindow); p != nil {
+		_ = e.hooks.FireSuspiciousValidationPattern(ctx, p, e.eventMeta(ctx, plugin.TriggerValidation, plugin.ReasonFailureThreshold))
+	}
+}
+
+// SuspiciousFingerprints returns up to limit failure fingerprints with the
+// most validation failures in the current window, for incident response.
+// It returns nil when failure fingerprinting is disabled.
+func (e *Engine) SuspiciousFingerprints(limit int) []*key.FailurePattern {
+	if e.failures == nil {
+		return nil
+	}
+	return e.failures.top(limit, e.runtimeConfig().FailureWindow)
+}
@@ -0,0 +1,7 @@
+//go:build !fips
+
+package keysmith
+
+// FIPSMode reports whether keysmith was built with the fips build tag, which
+// restricts the engine to FIPS-approved primitives. See [CryptoProfile].
+const FIPSMode = false
@@ -0,0 +1,70 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/plugin"
+)
+
+// DefaultExtendedGracePeriod is how long a rotated key with
+// [key.FlagExtendedGraceEligible] stays valid after its normal grace period.
+const DefaultExtendedGracePeriod = 7 * 24 * time.Hour
+
+// checkFlags rejects unknown flags and, outside admin contexts, adding a
+// flag that skips a check. Admin contexts are those without an app: the
+// tenant-admin view and unscoped system callers. Removing flags is always
+// allowed.
+func checkFlags(ctx context.Context, before, after key.Flags) error {
+	for _, f := range after {
+		if !f.Valid() {
+			return fmt.Errorf("%w: %q", ErrInvalidKeyFlag, f)
+		}
+	}
+	added, _ := diffFlags(before, after)
+	if scopeFromContext(ctx).appID == "" {
+		return nil
+	}
+	for _, f := range added {
+		if f.SkipsCheck() {
+			return fmt.Errorf("%w: %q", ErrKeyFlagNotAllowed, f)
+		}
+	}
+	return nil
+}
+
+// diffFlags returns the flags in after but not before, and the reverse.
+func diffFlags(before, after key.Flags) (added, removed key.Flags) {
+	for _, f := range after {
+		if !before.Has(f) {
+			added = append(added, f)
+		}
+	}
+	for _, f := range before {
+		if !after.Has(f) {
+			removed = append(removed, f)
+		}
+	}
+	return added, removed
+}
+
+// flagsChanged fires KeyFlagsChanged when k's flags differ from before.
+func (e *Engine) flagsChanged(ctx context.Context, k *key.Key, before key.Flags) {
+	added, removed := diffFlags(before, k.Flags)
+	if len(added) == 0 && len(removed) == 0 {
+		return
+	}
+	_ = e.hooks.FireKeyFlagsChanged(ctx, k, added, removed, e.eventMeta(ctx, plugin.TriggerManual, ""))
+}
+
+// graceEnds returns when k's rotation grace period ends, extended for keys
+// eligible for extended grace, and whether the extension is what keeps the
+// key valid at now.
+func (e *Engine) graceEnds(k *key.Key, normal, now time.Time) (time.Time, bool) {
+	if !k.Flags.Has(key.FlagExtendedGraceEligible) || e.extendedGrace <= 0 {
+		return normal, false
+	}
+	return normal.Add(e.extendedGrace), now.After(normal)
+}
@@ -0,0 +1,208 @@
+package keysmith_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+type flagsRecorder struct {
+	mu      sync.Mutex
+	added   []key.Flags
+	removed []key.Flags
+}
+
+func (r *flagsRecorder) Name() string { return "flags-recorder" }
+
+func (r *flagsRecorder) OnKeyFlagsChanged(_ context.Context, _ *key.Key, added, removed key.Flags) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.added = append(r.added, added)
+	r.removed = append(r.removed, removed)
+	return nil
+}
+
+// adminCtx is the tenant-admin view of the test tenant.
+func adminCtx() context.Context {
+	return keysmith.WithTenant(context.Background(), "", "tenant_test")
+}
+
+func newFlagsEngine(t *testing.T) (*keysmith.Engine, *memory.Store, *flagsRecorder, *fakeClock) {
+	t.Helper()
+	st := memory.New()
+	rec := &flagsRecorder{}
+	clock := &fakeClock{t: time.Now()}
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(st),
+		keysmith.WithExtension(rec),
+		keysmith.WithClock(clock.Now),
+	)
+	require.NoError(t, err)
+	return eng, st, rec, clock
+}
+
+func createFlaggedKey(t *testing.T, eng *keysmith.Engine, flags ...key.Flag) *key.CreateResult {
+	t.Helper()
+	created, err := eng.CreateKey(adminCtx(), &keysmith.CreateKeyInput{
+		Name:           "Legacy Integration",
+		Prefix:         "sk",
+		Environment:    key.EnvTest,
+		AllowedOrigins: []string{"https://app.example.com"},
+		Flags:          flags,
+	})
+	require.NoError(t, err)
+	return created
+}
+
+func TestFlags_SkipOriginCheck(t *testing.T) {
+	eng, _, _, _ := newFlagsEngine(t)
+	plain := createFlaggedKey(t, eng)
+	flagged := createFlaggedKey(t, eng, key.FlagSkipOriginCheck)
+	ctx := keysmith.WithValidationRequest(testCtx(), keysmith.ValidationRequest{Origin: "https://evil.example"})
+
+	_, err := eng.ValidateKey(ctx, plain.RawKey)
+	assert.ErrorIs(t, err, keysmith.ErrOriginNotAllowed)
+
+	result, err := eng.ValidateKey(ctx, flagged.RawKey)
+	require.NoError(t, err)
+	assert.Equal(t, []key.Flag{key.FlagSkipOriginCheck}, result.AppliedFlags)
+}
+
+func TestFlags_SkipOnlyItsCheck(t *testing.T) {
+	eng, _, _, _ := newFlagsEngine(t)
+	created, err := eng.CreateKey(adminCtx(), &keysmith.CreateKeyInput{
+		Name:             "Legacy Integration",
+		Prefix:           "sk",
+		Environment:      key.EnvTest,
+		AllowedOrigins:   []string{"https://app.example.com"},
+		IntendedConsumer: "billing-worker",
+		EnforceConsumer:  true,
+		Flags:            key.Flags{key.FlagSkipIPCheck, key.FlagExtendedGraceEligible},
+	})
+	require.NoError(t, err)
+
+	_, err = eng.ValidateKey(testCtx(), created.RawKey)
+	assert.ErrorIs(t, err, keysmith.ErrOriginNotAllowed, "other flags leave the origin check on")
+
+	ctx := keysmith.WithValidationRequest(testCtx(), keysmith.ValidationRequest{
+		Origin:          "https://app.example.com",
+		ConsumerService: "reporting",
+	})
+	_, err = eng.ValidateKey(ctx, created.RawKey)
+	assert.ErrorIs(t, err, keysmith.ErrConsumerNotAllowed, "flags never skip the consumer check")
+}
+
+func TestFlags_NotAppliedWithoutAllowlist(t *testing.T) {
+	eng, _, _, _ := newFlagsEngine(t)
+	created, err := eng.CreateKey(adminCtx(), &keysmith.CreateKeyInput{
+		Name:        "Server Key",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+		Flags:       key.Flags{key.FlagSkipOriginCheck},
+	})
+	require.NoError(t, err)
+
+	result, err := eng.ValidateKey(testCtx(), created.RawKey)
+	require.NoError(t, err)
+	assert.Empty(t, result.AppliedFlags, "nothing was skipped")
+}
+
+func TestFlags_ExtendedGrace(t *testing.T) {
+	eng, st, _, clock := newFlagsEngine(t)
+	plain := createFlaggedKey(t, eng)
+	flagged := createFlaggedKey(t, eng, key.FlagExtendedGraceEligible)
+
+	// Put both keys in their grace period, which ended an hour ago.
+	graceEnds := clock.Now().Add(-time.Hour)
+	for _, k := range []*key.Key{plain.Key, flagged.Key} {
+		require.NoError(t, st.Keys().UpdateState(context.Background(), k.ID, key.StateRotated))
+		require.NoError(t, st.Rotations().Create(context.Background(), &rotation.Record{
+			ID:        id.NewRotationID(),
+			KeyID:     k.ID,
+			TenantID:  k.TenantID,
+			Reason:    rotation.ReasonManual,
+			GraceTTL:  time.Hour,
+			GraceEnds: graceEnds,
+			CreatedAt: graceEnds.Add(-time.Hour),
+		}))
+	}
+	ctx := keysmith.WithValidationRequest(testCtx(), keysmith.ValidationRequest{Origin: "https://app.example.com"})
+
+	_, err := eng.ValidateKey(ctx, plain.RawKey)
+	assert.ErrorIs(t, err, keysmith.ErrKeyRevoked)
+
+	result, err := eng.ValidateKey(ctx, flagged.RawKey)
+	require.NoError(t, err)
+	assert.Equal(t, []key.Flag{key.FlagExtendedGraceEligible}, result.AppliedFlags)
+
+	clock.Set(graceEnds.Add(keysmith.DefaultExtendedGracePeriod + time.Second))
+	_, err = eng.ValidateKey(ctx, flagged.RawKey)
+	assert.ErrorIs(t, err, keysmith.ErrKeyRevoked, "the extension ends too")
+}
+
+func TestFlags_SkipFlagsRequireAdminContext(t *testing.T) {
+	eng, _, rec, _ := newFlagsEngine(t)
+
+	_, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+		Name:        "App Key",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+		Flags:       key.Flags{key.FlagSkipOriginCheck},
+	})
+	assert.ErrorIs(t, err, keysmith.ErrKeyFlagNotAllowed)
+
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+		Name:        "App Key",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+		Flags:       key.Flags{key.FlagExtendedGraceEligible},
+	})
+	require.NoError(t, err, "flags that skip no check can be set by the app")
+
+	_, err = eng.UpdateKey(testCtx(), created.Key.ID, &keysmith.UpdateKeyInput{
+		Flags: key.Flags{key.FlagExtendedGraceEligible, key.FlagSkipIPCheck},
+	})
+	assert.ErrorIs(t, err, keysmith.ErrKeyFlagNotAllowed)
+
+	updated, err := eng.UpdateKey(adminCtx(), created.Key.ID, &keysmith.UpdateKeyInput{
+		Flags: key.Flags{key.FlagSkipIPCheck, key.FlagExtendedGraceEligible, key.FlagSkipIPCheck},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, key.Flags{key.FlagExtendedGraceEligible, key.FlagSkipIPCheck}, updated.Flags, "flags are normalized")
+
+	updated, err = eng.UpdateKey(testCtx(), created.Key.ID, &keysmith.UpdateKeyInput{Flags: key.Flags{}})
+	require.NoError(t, err, "the app may clear skip flags")
+	assert.Empty(t, updated.Flags)
+
+	require.Len(t, rec.added, 3)
+	assert.Equal(t, key.Flags{key.FlagExtendedGraceEligible}, rec.added[0])
+	assert.Equal(t, key.Flags{key.FlagSkipIPCheck}, rec.added[1])
+	assert.Equal(t, key.Flags{key.FlagExtendedGraceEligible, key.FlagSkipIPCheck}, rec.removed[2])
+}
+
+func TestFlags_UnknownFlag(t *testing.T) {
+	eng, _, rec, _ := newFlagsEngine(t)
+	_, err := eng.CreateKey(adminCtx(), &keysmith.CreateKeyInput{
+		Name:        "Key",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+		Flags:       key.Flags{"skip_everything"},
+	})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidKeyFlag)
+
+	created := createFlaggedKey(t, eng)
+	desc := "no flag change"
+	_, err = eng.UpdateKey(adminCtx(), created.Key.ID, &keysmith.UpdateKeyInput{Description: &desc})
+	require.NoError(t, err)
+	assert.Empty(t, rec.added, "the hook fires only when flags change")
+}
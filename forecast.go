@@ -0,0 +1,267 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	log "github.com/xraph/go-utils/log"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/quotapool"
+	"github.com/xraph/keysmith/usage"
+)
+
+const (
+	// quotaWarningInterval is the minimum time between two
+	// KeyQuotaForecastWarning hooks for the same key.
+	quotaWarningInterval = 7 * 24 * time.Hour
+
+	// maxTrackedQuotaWarnings bounds the warning tracker; keys beyond it
+	// are warned about without deduplication until older entries expire.
+	maxTrackedQuotaWarnings = 10_000
+
+	// minForecastElapsed is the shortest span a burn rate is measured over,
+	// so that the first requests of a month do not project absurd totals.
+	minForecastElapsed = time.Hour
+)
+
+// QuotaForecast projects the key's usage this month against its policy's
+// monthly quota, from the daily usage counts recorded so far. The
+// projection is linear: the average daily count over the elapsed part of
+// the month, carried to month end. Days and months are those of the quota
+// time zone: the policy's, else the tenant's, else UTC. For a key in a
+// quota pool it projects the pool's validations against the pool's monthly
+// limit, in the pool's time zone. It fails with ErrNoMonthlyQuota for a
+// key whose pool, or else effective policy, sets none.
+func (e *Engine) QuotaForecast(ctx context.Context, keyID id.KeyID) (*key.QuotaForecast, error) {
+	k, err := e.getKey(ctx, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("get key: %w", err)
+	}
+	if k.PoolID != nil {
+		pool, err := e.store.QuotaPools().Get(ctx, *k.PoolID)
+		if err != nil {
+			return nil, fmt.Errorf("get quota pool: %w", err)
+		}
+		return e.forecastPool(ctx, pool, e.now())
+	}
+	pol, err := e.quotaPolicy(ctx, k, nil)
+	if err != nil {
+		return nil, err
+	}
+	if pol == nil || pol.MonthlyQuota <= 0 {
+		return nil, ErrNoMonthlyQuota
+	}
+	return e.forecastQuota(ctx, k.ID, pol.MonthlyQuota, e.now(), e.quotaLocation(ctx, k.TenantID, pol))
+}
+
+// quotaPolicy returns k's effective policy, or nil for a key without one.
+// When policies is non-nil it memoizes policy reads across calls.
+func (e *Engine) quotaPolicy(ctx context.Context, k *key.Key, policies map[id.PolicyID]*policy.Policy) (*policy.Policy, error) {
+	if k.PolicyID == nil {
+		return nil, nil
+	}
+	pol, seen := policies[*k.PolicyID]
+	if !seen {
+		var err error
+		if pol, err = e.store.Policies().Get(ctx, *k.PolicyID); err != nil {
+			return nil, fmt.Errorf("get policy: %w", err)
+		}
+		if policies != nil {
+			policies[*k.PolicyID] = pol
+		}
+	}
+	eff, err := e.effectivePolicy(ctx, pol)
+	if err != nil {
+		return nil, err
+	}
+	return eff.Policy, nil
+}
+
+// quotaLocation returns the time zone pol's quotas count days and months
+// in: the policy's QuotaTimezone, else the tenant's, else UTC. Both are
+// validated when set, so a name that no longer loads falls back to UTC.
+func (e *Engine) quotaLocation(ctx context.Context, tenantID string, pol *policy.Policy) *time.Location {
+	name := ""
+	if pol != nil {
+		name = pol.QuotaTimezone
+	}
+	return e.quotaZone(ctx, tenantID, name)
+}
+
+// forecastQuota sums the key's daily counts from the start of now's month
+// in loc.
+func (e *Engine) forecastQuota(ctx context.Context, keyID id.KeyID, quota int64, now time.Time, loc *time.Location) (*key.QuotaForecast, error) {
+	return forecastMonth(quota, now, loc, func(day usage.Window) (int64, error) {
+		return e.store.Usages().DailyCount(ctx, keyID, day)
+	})
+}
+
+// forecastPool projects the pool's daily validation counts from the start
+// of now's month in the pool's time zone. Counts this engine has not
+// written yet are left out.
+func (e *Engine) forecastPool(ctx context.Context, pool *quotapool.Pool, now time.Time) (*key.QuotaForecast, error) {
+	if pool.MonthlyLimit <= 0 {
+		return nil, ErrNoMonthlyQuota
+	}
+	loc := e.quotaZone(ctx, pool.TenantID, pool.QuotaTimezone)
+	month := usage.MonthWindow(now.In(loc), loc)
+	counts, err := e.store.QuotaPools().ListUsage(ctx, &quotapool.UsageFilter{
+		PoolID: pool.ID, Period: quotapool.PeriodDay, From: month.Start, To: month.End,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list quota counts: %w", err)
+	}
+	daily := make(map[int64]int64)
+	for _, c := range counts {
+		daily[c.WindowStart.Unix()] += c.Count
+	}
+	f, err := forecastMonth(pool.MonthlyLimit, now, loc, func(day usage.Window) (int64, error) {
+		return daily[day.Start.Unix()], nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	poolID := pool.ID
+	f.PoolID = &poolID
+	return f, nil
+}
+
+// forecastMonth sums the daily counts from the start of now's month in loc
+// and projects them to month end.
+func forecastMonth(quota int64, now time.Time, loc *time.Location, dailyCount func(usage.Window) (int64, error)) (*key.QuotaForecast, error) {
+	now = now.In(loc)
+	month := usage.MonthWindow(now, loc)
+	monthStart, monthEnd := month.Start, month.End
+	f := &key.QuotaForecast{Month: monthStart, AsOf: now, Quota: quota}
+
+	for day := usage.DayWindow(monthStart, loc); day.Start.Before(now); day = usage.DayWindow(day.End, loc) {
+		n, err := dailyCount(day)
+		if err != nil {
+			return nil, fmt.Errorf("daily count: %w", err)
+		}
+		f.Used += n
+		if f.Used >= quota && f.ProjectedExhaustion == nil {
+			exhausted := day.Start
+			f.ProjectedExhaustion = &exhausted
+		}
+	}
+
+	elapsed := max(now.Sub(monthStart), minForecastElapsed)
+	f.BurnRate = float64(f.Used) / elapsed.Hours() * 24
+	f.ProjectedTotal = f.Used + int64(math.Round(f.BurnRate*monthEnd.Sub(now).Hours()/24))
+	if f.ProjectedExhaustion == nil && f.BurnRate > 0 {
+		days := float64(quota-f.Used) / f.BurnRate
+		if at := now.Add(time.Duration(days * float64(24*time.Hour))); at.Before(monthEnd) {
+			f.ProjectedExhaustion = &at
+		}
+	}
+	return f, nil
+}
+
+// EvaluateQuotaForecasts forecasts every active key with a monthly quota
+// and fires KeyQuotaForecastWarning for those projected to exhaust it
+// before month end, at most once per key per week. Each quota pool is
+// forecast once, and every active member of a pool heading over its limit
+// is warned about. It returns the number of warnings fired.
+// [WithQuotaForecastWarnings] runs it on an interval.
+func (e *Engine) EvaluateQuotaForecasts(ctx context.Context) (int, error) {
+	now := e.now()
+	policies := make(map[id.PolicyID]*policy.Policy)
+	pools := make(map[id.QuotaPoolID]*key.QuotaForecast)
+	fired := 0
+	err := e.store.Keys().Iterate(ctx, &key.ListFilter{State: key.StateActive}, func(k *key.Key) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		f, err := e.sweepForecast(ctx, k, now, policies, pools)
+		if err != nil {
+			return err
+		}
+		if f == nil || f.ProjectedExhaustion == nil || !e.quotaWarnings.shouldWarn(k.ID, now) {
+			return nil
+		}
+		fired++
+		_ = e.hooks.FireKeyQuotaForecastWarning(ctx, k, f, e.eventMeta(ctx, plugin.TriggerSweep, plugin.ReasonQuotaForecast))
+		return nil
+	})
+	if err != nil {
+		return fired, fmt.Errorf("evaluate quota forecasts: %w", err)
+	}
+	return fired, nil
+}
+
+// sweepForecast forecasts k for EvaluateQuotaForecasts, memoizing policy
+// reads and pool forecasts. It returns nil for a key without a monthly
+// quota, or whose policy or pool cannot be read, so that the sweep goes on.
+func (e *Engine) sweepForecast(ctx context.Context, k *key.Key, now time.Time, policies map[id.PolicyID]*policy.Policy, pools map[id.QuotaPoolID]*key.QuotaForecast) (*key.QuotaForecast, error) {
+	if k.PoolID != nil {
+		f, seen := pools[*k.PoolID]
+		if !seen {
+			if pool, err := e.store.QuotaPools().Get(ctx, *k.PoolID); err == nil && pool.MonthlyLimit > 0 {
+				if f, err = e.forecastPool(ctx, pool, now); err != nil {
+					return nil, err
+				}
+			}
+			pools[*k.PoolID] = f
+		}
+		return f, nil
+	}
+	pol, err := e.quotaPolicy(ctx, k, policies)
+	if err != nil || pol == nil || pol.MonthlyQuota <= 0 {
+		return nil, nil
+	}
+	return e.forecastQuota(ctx, k.ID, pol.MonthlyQuota, now, e.quotaLocation(ctx, k.TenantID, pol))
+}
+
+func (e *Engine) runQuotaForecasts(ctx context.Context) {
+	fired, err := e.EvaluateQuotaForecasts(ctx)
+	if err != nil {
+		e.logger.Warn("quota forecast sweep failed", log.Any("error", err))
+		return
+	}
+	if fired > 0 {
+		e.logger.Info("quota forecast warnings fired", log.Int("keys", fired))
+	}
+}
+
+// quotaWarningTracker remembers when each key was last warned about, so
+// that a key heading over its quota is reported once a week rather than on
+// every sweep.
+type quotaWarningTracker struct {
+	mu     sync.Mutex
+	warned map[id.KeyID]time.Time
+}
+
+func newQuotaWarningTracker() *quotaWarningTracker {
+	return &quotaWarningTracker{warned: make(map[id.KeyID]time.Time)}
+}
+
+// shouldWarn reports whether a warning for keyID at now is the first in the
+// current interval, and records it if so.
+func (t *quotaWarningTracker) shouldWarn(keyID id.KeyID, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if last, ok := t.warned[keyID]; ok && now.Sub(last) < quotaWarningInterval {
+		return false
+	}
+	if len(t.warned) >= maxTrackedQuotaWarnings {
+		for kid, last := range t.warned {
+			if now.Sub(last) >= quotaWarningInterval {
+				delete(t.warned, kid)
+			}
+		}
+		if len(t.warned) >= maxTrackedQuotaWarnings {
+			return true
+		}
+	}
+	t.warned[keyID] = now
+	return true
+}
@@ -0,0 +1,248 @@
+package keysmith_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keysmithtest"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/store/memory"
+	"github.com/xraph/keysmith/tenant"
+	"github.com/xraph/keysmith/usage"
+)
+
+// forecastStart is ten days into a 31-day month.
+var forecastStart = time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC)
+
+func newForecastEngine(t *testing.T, opts ...keysmith.Option) (*keysmith.Engine, *fakeClock, *keysmithtest.Recorder) {
+	t.Helper()
+	clock := &fakeClock{t: forecastStart}
+	rec := keysmithtest.NewRecorder()
+	eng, err := keysmith.NewEngine(append([]keysmith.Option{
+		keysmith.WithStore(memory.New()),
+		keysmith.WithClock(clock.Now),
+		keysmith.WithExtension(rec),
+	}, opts...)...)
+	require.NoError(t, err)
+	return eng, clock, rec
+}
+
+// quotaKey creates a key under a new policy with the given monthly quota
+// and records perDay requests on each of the first days of the month.
+func quotaKey(t *testing.T, eng *keysmith.Engine, quota int64, days, perDay int) id.KeyID {
+	t.Helper()
+	ctx := testCtx()
+	pol := &policy.Policy{Name: "Quota", MonthlyQuota: quota}
+	require.NoError(t, eng.CreatePolicy(ctx, pol))
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Job", Prefix: "sk", Environment: key.EnvTest, PolicyID: &pol.ID})
+	require.NoError(t, err)
+
+	kid := created.Key.ID
+	for d := range days {
+		day := time.Date(2026, 3, 1+d, 9, 0, 0, 0, time.UTC)
+		for range perDay {
+			require.NoError(t, eng.Store().Usages().Record(context.Background(), &usage.Record{
+				ID:         id.NewUsageID(),
+				KeyID:      kid,
+				TenantID:   "tenant_test",
+				AppID:      "app_test",
+				Endpoint:   "/v1/orders",
+				Method:     "GET",
+				StatusCode: 200,
+				CreatedAt:  day,
+			}))
+		}
+	}
+	return kid
+}
+
+func TestQuotaForecast_ProjectsExhaustion(t *testing.T) {
+	eng, _, _ := newForecastEngine(t)
+	kid := quotaKey(t, eng, 300, 10, 20)
+
+	f, err := eng.QuotaForecast(testCtx(), kid)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), f.Month)
+	assert.Equal(t, forecastStart, f.AsOf)
+	assert.EqualValues(t, 300, f.Quota)
+	assert.EqualValues(t, 200, f.Used)
+	assert.InDelta(t, 20, f.BurnRate, 1e-9)
+	assert.EqualValues(t, 200+20*21, f.ProjectedTotal)
+	require.NotNil(t, f.ProjectedExhaustion)
+	assert.Equal(t, time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC), *f.ProjectedExhaustion, "100 left at 20 a day")
+}
+
+func TestQuotaForecast_WithinQuota(t *testing.T) {
+	eng, _, _ := newForecastEngine(t)
+	kid := quotaKey(t, eng, 1000, 10, 20)
+
+	f, err := eng.QuotaForecast(testCtx(), kid)
+	require.NoError(t, err)
+	assert.EqualValues(t, 620, f.ProjectedTotal)
+	assert.Nil(t, f.ProjectedExhaustion)
+}
+
+func TestQuotaForecast_AlreadyExhausted(t *testing.T) {
+	eng, _, _ := newForecastEngine(t)
+	kid := quotaKey(t, eng, 150, 10, 20)
+
+	f, err := eng.QuotaForecast(testCtx(), kid)
+	require.NoError(t, err)
+	require.NotNil(t, f.ProjectedExhaustion)
+	assert.Equal(t, time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC), *f.ProjectedExhaustion, "the day the count reached 160")
+}
+
+func TestQuotaForecast_InheritedQuota(t *testing.T) {
+	eng, _, _ := newForecastEngine(t)
+	ctx := testCtx()
+	base := &policy.Policy{Name: "Base", MonthlyQuota: 300}
+	require.NoError(t, eng.CreatePolicy(ctx, base))
+	child := &policy.Policy{Name: "Child", BasePolicyID: &base.ID}
+	require.NoError(t, eng.CreatePolicy(ctx, child))
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Job", Prefix: "sk", Environment: key.EnvTest, PolicyID: &child.ID})
+	require.NoError(t, err)
+
+	f, err := eng.QuotaForecast(ctx, created.Key.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 300, f.Quota)
+	assert.Zero(t, f.Used)
+	assert.Nil(t, f.ProjectedExhaustion)
+}
+
+func TestQuotaForecast_NoQuota(t *testing.T) {
+	eng, _, _ := newForecastEngine(t)
+	ctx := testCtx()
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Job", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	_, err = eng.QuotaForecast(ctx, created.Key.ID)
+	assert.ErrorIs(t, err, keysmith.ErrNoMonthlyQuota)
+
+	kid := quotaKey(t, eng, 0, 1, 1)
+	_, err = eng.QuotaForecast(ctx, kid)
+	assert.ErrorIs(t, err, keysmith.ErrNoMonthlyQuota)
+}
+
+func TestQuotaForecast_TenantScoped(t *testing.T) {
+	eng, _, _ := newForecastEngine(t)
+	kid := quotaKey(t, eng, 300, 10, 20)
+
+	other := keysmith.WithTenant(context.Background(), "app_test", "tenant_other")
+	_, err := eng.QuotaForecast(other, kid)
+	assert.ErrorIs(t, err, keysmith.ErrKeyNotFound)
+}
+
+func TestEvaluateQuotaForecasts_WarnsOncePerWeek(t *testing.T) {
+	eng, clock, rec := newForecastEngine(t)
+	ctx := testCtx()
+	over := quotaKey(t, eng, 300, 10, 20)
+	quotaKey(t, eng, 1000, 10, 20)
+	_, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "No Policy", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+
+	fired, err := eng.EvaluateQuotaForecasts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, fired)
+
+	events := rec.Filter("KeyQuotaForecastWarning")
+	require.Len(t, events, 1)
+	assert.Equal(t, over.String(), events[0].Key.ID.String())
+	assert.Equal(t, plugin.TriggerSweep, events[0].Meta.Trigger)
+	assert.Equal(t, plugin.ReasonQuotaForecast, events[0].Meta.ReasonCode)
+	require.NotNil(t, events[0].Forecast)
+	assert.EqualValues(t, 200, events[0].Forecast.Used)
+
+	fired, err = eng.EvaluateQuotaForecasts(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, fired, "a second sweep the same day is quiet")
+
+	clock.Set(forecastStart.Add(6 * 24 * time.Hour))
+	fired, err = eng.EvaluateQuotaForecasts(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, fired, "still within the week")
+
+	// No further usage: 200 over 17 days still reaches 300 before April.
+	clock.Set(forecastStart.Add(7 * 24 * time.Hour))
+	fired, err = eng.EvaluateQuotaForecasts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, fired)
+	assert.Equal(t, 2, rec.Count("KeyQuotaForecastWarning"))
+}
+
+func TestWithQuotaForecastWarnings_RunsInBackground(t *testing.T) {
+	eng, _, rec := newForecastEngine(t, keysmith.WithQuotaForecastWarnings(10*time.Millisecond))
+	quotaKey(t, eng, 300, 10, 20)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, eng.Start(ctx))
+	defer func() { require.NoError(t, eng.Stop(context.Background())) }()
+
+	require.Eventually(t, func() bool {
+		return rec.Count("KeyQuotaForecastWarning") == 1
+	}, time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 1, rec.Count("KeyQuotaForecastWarning"), "repeat sweeps are deduplicated")
+}
+
+func TestQuotaForecast_QuotaTimezone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// 2026-03-01 03:00 UTC is noon on March 1 in Tokyo, and 22:00 on
+	// February 28 in New York.
+	now := time.Date(2026, 3, 1, 3, 0, 0, 0, time.UTC)
+	eng, clock, _ := newForecastEngine(t)
+	clock.t = now
+	ctx := testCtx()
+	require.NoError(t, eng.SetTenantSettings(ctx, &tenant.Settings{QuotaTimezone: "Asia/Tokyo"}))
+
+	pol := &policy.Policy{Name: "Quota", MonthlyQuota: 1000}
+	require.NoError(t, eng.CreatePolicy(ctx, pol))
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Job", Prefix: "sk", Environment: key.EnvTest, PolicyID: &pol.ID})
+	require.NoError(t, err)
+	kid := created.Key.ID
+	// March 1 08:30 in Tokyo, still February in UTC and New York.
+	at := time.Date(2026, 2, 28, 23, 30, 0, 0, time.UTC)
+	for range 3 {
+		require.NoError(t, eng.Store().Usages().Record(context.Background(), &usage.Record{
+			ID: id.NewUsageID(), KeyID: kid, TenantID: "tenant_test", AppID: "app_test", CreatedAt: at,
+		}))
+	}
+
+	f, err := eng.QuotaForecast(ctx, kid)
+	require.NoError(t, err)
+	assert.True(t, f.Month.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, tokyo)), "the tenant's month")
+	assert.EqualValues(t, 3, f.Used)
+
+	pol.QuotaTimezone = "America/New_York"
+	require.NoError(t, eng.UpdatePolicy(ctx, pol))
+	f, err = eng.QuotaForecast(ctx, kid)
+	require.NoError(t, err)
+	assert.True(t, f.Month.Equal(time.Date(2026, 2, 1, 0, 0, 0, 0, ny)), "the policy's zone wins")
+	assert.EqualValues(t, 3, f.Used)
+	assert.True(t, f.AsOf.Equal(now))
+}
+
+func TestQuotaTimezone_Validation(t *testing.T) {
+	eng, _, _ := newForecastEngine(t)
+	ctx := testCtx()
+
+	for _, name := range []string{"Mars/Olympus", "Local"} {
+		err := eng.SetTenantSettings(ctx, &tenant.Settings{QuotaTimezone: name})
+		assert.ErrorIs(t, err, keysmith.ErrInvalidTenantSettings, name)
+
+		err = eng.CreatePolicy(ctx, &policy.Policy{Name: "Zoned " + name, QuotaTimezone: name})
+		assert.ErrorIs(t, err, keysmith.ErrInvalidPolicy, name)
+	}
+	require.NoError(t, eng.CreatePolicy(ctx, &policy.Policy{Name: "Zoned", QuotaTimezone: "Europe/Berlin"}))
+}
@@ -1,31 +1,97 @@
 package keysmith
 
 import (
+	"bytes"
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"io"
 
 	"github.com/xraph/keysmith/key"
 )
 
+// entropyCheckBytes is the size of each block CheckEntropy draws.
+const entropyCheckBytes = 64
+
 // KeyGenerator generates raw API key strings.
 type KeyGenerator interface {
 	// Generate produces a raw API key string with the given prefix and environment.
 	Generate(prefix string, env key.Environment) (string, error)
 }
 
+// GeneratorOption configures [DefaultKeyGenerator].
+type GeneratorOption func(*defaultGenerator)
+
+// WithEntropySource draws the random part of keys from r, such as an HSM or
+// a vetted DRBG, instead of crypto/rand. r must be safe for concurrent use.
+// The generator reports r's algorithm when r implements
+// [AlgorithmReporter], and "unknown" otherwise, so a source that does not
+// report one is refused in FIPS mode.
+func WithEntropySource(r io.Reader) GeneratorOption {
+	return func(g *defaultGenerator) { g.source = r }
+}
+
 // DefaultKeyGenerator returns a generator producing keys in the format:
-// {prefix}_{env}_{64 random hex chars} (e.g., "sk_live_a3f8b2c9...").
-func DefaultKeyGenerator() KeyGenerator { return &defaultGenerator{byteLen: 32} }
+// {prefix}_{env}_{64 random hex chars} (e.g., "sk_live_a3f8b2c9..."). The
+// random part is 32 bytes read from the entropy source, 256 bits of
+// entropy; the prefix and environment add none.
+func DefaultKeyGenerator(opts ...GeneratorOption) KeyGenerator {
+	g := &defaultGenerator{byteLen: 32}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
 
 type defaultGenerator struct {
 	byteLen int
+	// source supplies the random bytes. Nil reads crypto/rand.
+	source io.Reader
+}
+
+func (g *defaultGenerator) Algorithm() string {
+	if g.source == nil {
+		return AlgCryptoRand
+	}
+	return algorithmOf(g.source)
 }
 
+func (g *defaultGenerator) EntropyBits() int { return g.byteLen * 8 }
+
 func (g *defaultGenerator) Generate(prefix string, env key.Environment) (string, error) {
 	b := make([]byte, g.byteLen)
-	if _, err := rand.Read(b); err != nil {
+	if err := g.read(b); err != nil {
 		return "", fmt.Errorf("generate random bytes: %w", err)
 	}
 	return fmt.Sprintf("%s_%s_%s", prefix, env, hex.EncodeToString(b)), nil
 }
+
+// CheckEntropy draws two blocks from the entropy source and discards them.
+// It fails when the source errors, returns a block of one repeated byte, or
+// returns the same block twice.
+func (g *defaultGenerator) CheckEntropy() error {
+	var blocks [2][]byte
+	for i := range blocks {
+		b := make([]byte, entropyCheckBytes)
+		if err := g.read(b); err != nil {
+			return fmt.Errorf("read entropy: %w", err)
+		}
+		if bytes.Count(b, b[:1]) == len(b) {
+			return fmt.Errorf("entropy source returned %d bytes of 0x%02x", len(b), b[0])
+		}
+		blocks[i] = b
+	}
+	if bytes.Equal(blocks[0], blocks[1]) {
+		return fmt.Errorf("entropy source repeated a %d-byte block", entropyCheckBytes)
+	}
+	return nil
+}
+
+func (g *defaultGenerator) read(b []byte) error {
+	if g.source == nil {
+		_, err := rand.Read(b)
+		return err
+	}
+	_, err := io.ReadFull(g.source, b)
+	return err
+}
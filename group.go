@@ -0,0 +1,342 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	log "github.com/xraph/go-utils/log"
+
+	"github.com/xraph/keysmith/group"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/rotation"
+)
+
+type ctxKeyGroup struct{}
+
+// withGroupEvent marks ctx so that events fired under it carry groupID in
+// their meta.
+func withGroupEvent(ctx context.Context, groupID id.GroupID) context.Context {
+	return context.WithValue(ctx, ctxKeyGroup{}, groupID.String())
+}
+
+// groupEventFromContext returns the group ID set by withGroupEvent.
+func groupEventFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(ctxKeyGroup{}).(string)
+	return v
+}
+
+// GroupKeyStatus is the outcome of a group operation for one member key.
+type GroupKeyStatus string
+
+const (
+	// GroupKeyRotated is a member RotateGroup rotated.
+	GroupKeyRotated GroupKeyStatus = "rotated"
+
+	// GroupKeyRevoked is a member RevokeGroup revoked.
+	GroupKeyRevoked GroupKeyStatus = "revoked"
+
+	// GroupKeyAlreadyRevoked is a member that was revoked before the call.
+	GroupKeyAlreadyRevoked GroupKeyStatus = "already_revoked"
+
+	// GroupKeyFailed is a member the operation failed for; see Error.
+	GroupKeyFailed GroupKeyStatus = "failed"
+)
+
+// GroupKeyResult reports a group operation for one member key.
+type GroupKeyResult struct {
+	KeyID  id.KeyID       `json:"key_id"`
+	Status GroupKeyStatus `json:"status"`
+
+	// RawKey is the new secret of a rotated key. It is empty in a dry run.
+	RawKey string `json:"raw_key,omitempty"`
+
+	// Rotation is the record of a rotated key.
+	Rotation *rotation.Record `json:"rotation,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// GroupResult reports RotateGroup or RevokeGroup, with one entry per member
+// in ascending key ID order.
+type GroupResult struct {
+	GroupID id.GroupID       `json:"group_id"`
+	Keys    []GroupKeyResult `json:"keys"`
+	DryRun  bool             `json:"dry_run"`
+}
+
+// validateGroup checks a group's fields at write time.
+func validateGroup(g *group.Group) error {
+	g.Name = strings.TrimSpace(g.Name)
+	if g.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidGroup)
+	}
+	return nil
+}
+
+// CreateGroup creates a key group in the context's tenant and app.
+func (e *Engine) CreateGroup(ctx context.Context, g *group.Group) error {
+	if err := e.checkWritable(ctx); err != nil {
+		return err
+	}
+	if err := validateGroup(g); err != nil {
+		return err
+	}
+	sc := scopeFromContext(ctx)
+	g.ID = id.NewGroupID()
+	g.TenantID = sc.tenantID
+	g.AppID = sc.appID
+	now := time.Now()
+	g.CreatedAt = now
+	g.UpdatedAt = now
+	if err := e.store.Groups().Create(ctx, g); err != nil {
+		return fmt.Errorf("create group: %w", err)
+	}
+	return nil
+}
+
+// GetGroup returns a key group by ID.
+func (e *Engine) GetGroup(ctx context.Context, groupID id.GroupID) (*group.Group, error) {
+	return e.getGroup(ctx, groupID)
+}
+
+// UpdateGroup updates a key group's name and description.
+func (e *Engine) UpdateGroup(ctx context.Context, g *group.Group) error {
+	if err := e.checkWritable(ctx); err != nil {
+		return err
+	}
+	if err := validateGroup(g); err != nil {
+		return err
+	}
+	cur, err := e.getGroup(ctx, g.ID)
+	if err != nil {
+		return fmt.Errorf("get group: %w", err)
+	}
+	g.TenantID, g.AppID, g.CreatedAt = cur.TenantID, cur.AppID, cur.CreatedAt
+	g.UpdatedAt = time.Now()
+	if err := e.store.Groups().Update(ctx, g); err != nil {
+		return fmt.Errorf("update group: %w", err)
+	}
+	return nil
+}
+
+// DeleteGroup deletes a key group. Its keys are left intact; their
+// memberships are removed and reported as a GroupMembershipChanged event.
+func (e *Engine) DeleteGroup(ctx context.Context, groupID id.GroupID) error {
+	if err := e.checkWritable(ctx); err != nil {
+		return err
+	}
+	g, err := e.getGroup(ctx, groupID)
+	if err != nil {
+		return fmt.Errorf("get group: %w", err)
+	}
+	var members []id.KeyID
+	err = e.store.Keys().Iterate(ctx, &key.ListFilter{GroupID: &groupID}, func(k *key.Key) error {
+		members = append(members, k.ID)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("list group members: %w", err)
+	}
+	if err := e.store.Groups().Delete(ctx, groupID); err != nil {
+		return fmt.Errorf("delete group: %w", err)
+	}
+	if len(members) > 0 {
+		e.membershipChanged(ctx, g, nil, members)
+	}
+	return nil
+}
+
+// ListGroups returns a page of key groups ordered by name, restricted to the
+// context's tenant and app. The filter's Limit defaults to DefaultPageSize
+// and is clamped by [Engine.PageLimit].
+func (e *Engine) ListGroups(ctx context.Context, filter *group.ListFilter) ([]*group.Group, error) {
+	f := groupFilter(ctx, filter)
+	f.Limit = e.PageLimit(f.Limit, DefaultPageSize)
+	return e.store.Groups().List(ctx, f)
+}
+
+// AddKeysToGroup adds keys to a group. Every key must be in the group's
+// tenant and app. Keys that are already members are left as they are and
+// not reported in the GroupMembershipChanged event.
+func (e *Engine) AddKeysToGroup(ctx context.Context, groupID id.GroupID, keyIDs []id.KeyID) error {
+	g, current, err := e.prepareMembership(ctx, groupID, keyIDs)
+	if err != nil {
+		return err
+	}
+	var added []id.KeyID
+	for _, kid := range keyIDs {
+		if !slices.Contains(current[kid], groupID) && !slices.Contains(added, kid) {
+			added = append(added, kid)
+		}
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	if err := e.store.Groups().AddKeys(ctx, groupID, added); err != nil {
+		return fmt.Errorf("add group members: %w", err)
+	}
+	e.membershipChanged(ctx, g, added, nil)
+	return nil
+}
+
+// RemoveKeysFromGroup removes keys from a group. Keys that are not members
+// are ignored.
+func (e *Engine) RemoveKeysFromGroup(ctx context.Context, groupID id.GroupID, keyIDs []id.KeyID) error {
+	g, current, err := e.prepareMembership(ctx, groupID, keyIDs)
+	if err != nil {
+		return err
+	}
+	var removed []id.KeyID
+	for _, kid := range keyIDs {
+		if slices.Contains(current[kid], groupID) && !slices.Contains(removed, kid) {
+			removed = append(removed, kid)
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+	if err := e.store.Groups().RemoveKeys(ctx, groupID, removed); err != nil {
+		return fmt.Errorf("remove group members: %w", err)
+	}
+	e.membershipChanged(ctx, g, nil, removed)
+	return nil
+}
+
+// prepareMembership loads the group and checks that each key is visible and
+// in the group's tenant and app. It returns the keys' current groups.
+func (e *Engine) prepareMembership(ctx context.Context, groupID id.GroupID, keyIDs []id.KeyID) (*group.Group, map[id.KeyID][]id.GroupID, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, nil, err
+	}
+	g, err := e.getGroup(ctx, groupID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get group: %w", err)
+	}
+	for _, kid := range keyIDs {
+		k, err := e.getKey(ctx, kid)
+		if err != nil {
+			return nil, nil, fmt.Errorf("get key: %w", err)
+		}
+		if k.TenantID != g.TenantID || k.AppID != g.AppID {
+			return nil, nil, fmt.Errorf("%w: key %s is not in the group's tenant and app", ErrInvalidGroup, kid)
+		}
+	}
+	current, err := e.store.Groups().ListByKeys(ctx, keyIDs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list key groups: %w", err)
+	}
+	return g, current, nil
+}
+
+// KeyGroups returns the IDs of the groups each of keys belongs to. Keys the
+// context may not see, and keys in no group, are absent from the map.
+func (e *Engine) KeyGroups(ctx context.Context, keys []*key.Key) (map[id.KeyID][]id.GroupID, error) {
+	sc := scopeFromContext(ctx)
+	keyIDs := make([]id.KeyID, 0, len(keys))
+	for _, k := range keys {
+		if sc.owns(k.TenantID, k.AppID) {
+			keyIDs = append(keyIDs, k.ID)
+		}
+	}
+	return e.store.Groups().ListByKeys(ctx, keyIDs)
+}
+
+// RotateGroup rotates every member of a group that is not revoked, each with
+// its policy's grace period as RotateKey does. The reason is checked like
+// RotateKey's. Each rotation fires KeyRotated with the group's ID in its
+// meta. A member that fails to rotate is logged and reported as failed, and
+// the run continues. With [WithDryRun] it reports the rotations it would
+// make, without raw keys.
+func (e *Engine) RotateGroup(ctx context.Context, groupID id.GroupID, reason rotation.Reason) (*GroupResult, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	if reason == "" {
+		reason = rotation.ReasonManual
+	}
+	if !reason.IsValid() {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidRotationReason, reason)
+	}
+	return e.groupBulk(ctx, groupID, "rotate", func(ctx context.Context, k *key.Key) (GroupKeyResult, error) {
+		if k.State == key.StateRevoked {
+			return GroupKeyResult{Status: GroupKeyAlreadyRevoked}, nil
+		}
+		if err := e.validateRotateInput(ctx, k, reason); err != nil {
+			return GroupKeyResult{}, err
+		}
+		result, rec, err := e.rotateKey(ctx, k, reason, e.rotationGrace(ctx, k))
+		if err != nil {
+			return GroupKeyResult{}, err
+		}
+		return GroupKeyResult{Status: GroupKeyRotated, RawKey: result.RawKey, Rotation: rec}, nil
+	})
+}
+
+// RevokeGroup revokes every member of a group. Each revocation fires
+// KeyRevoked with reason, the group_revoked reason code, and the group's ID
+// in its meta. A member that fails to revoke is logged and reported as
+// failed, and the run continues. With [WithDryRun] it reports the keys it
+// would revoke.
+func (e *Engine) RevokeGroup(ctx context.Context, groupID id.GroupID, reason string) (*GroupResult, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	return e.groupBulk(ctx, groupID, "revoke", func(ctx context.Context, k *key.Key) (GroupKeyResult, error) {
+		if k.State == key.StateRevoked {
+			return GroupKeyResult{Status: GroupKeyAlreadyRevoked}, nil
+		}
+		if err := e.revokeKey(ctx, k, reason, plugin.ReasonGroupRevoked); err != nil {
+			return GroupKeyResult{}, err
+		}
+		return GroupKeyResult{Status: GroupKeyRevoked}, nil
+	})
+}
+
+// groupBulk applies fn to every member of the group, ignoring paging, under
+// a context whose events carry the group's ID. An error from fn is logged
+// and reported as a failed member; the returned error is from loading the
+// group or iterating.
+func (e *Engine) groupBulk(ctx context.Context, groupID id.GroupID, op string, fn func(context.Context, *key.Key) (GroupKeyResult, error)) (*GroupResult, error) {
+	g, err := e.getGroup(ctx, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("get group: %w", err)
+	}
+	ctx = withGroupEvent(ctx, g.ID)
+
+	res := &GroupResult{GroupID: g.ID, Keys: []GroupKeyResult{}, DryRun: IsDryRun(ctx)}
+	filter := &key.ListFilter{TenantID: g.TenantID, AppID: g.AppID, GroupID: &g.ID}
+	err = e.store.Keys().Iterate(ctx, filter, func(k *key.Key) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		r, err := fn(ctx, k)
+		if err != nil {
+			e.logger.Warn("failed to "+op+" group member",
+				log.String("group_id", g.ID.String()),
+				log.String("key_id", k.ID.String()),
+				log.Any("error", err),
+			)
+			r = GroupKeyResult{Status: GroupKeyFailed, Error: err.Error()}
+		}
+		r.KeyID = k.ID
+		res.Keys = append(res.Keys, r)
+		return nil
+	})
+	if err != nil {
+		return res, fmt.Errorf("iterate group members: %w", err)
+	}
+	return res, nil
+}
+
+// membershipChanged bumps the tenant's revision, since key responses list
+// their groups, and fires GroupMembershipChanged.
+func (e *Engine) membershipChanged(ctx context.Context, g *group.Group, added, removed []id.KeyID) {
+	e.bumpRevision(ctx, g.TenantID)
+	meta := e.eventMeta(withGroupEvent(ctx, g.ID), plugin.TriggerManual, "")
+	_ = e.hooks.FireGroupMembershipChanged(ctx, g, added, removed, meta)
+}
@@ -0,0 +1,30 @@
+// Package group defines key groups: named sets of keys, such as "Mobile
+// apps" or "Partner X", that are listed and operated on together.
+package group
+
+import (
+	"time"
+
+	"github.com/xraph/keysmith/id"
+)
+
+// Group is a named set of keys in one tenant and app. A key may belong to
+// any number of groups; deleting a group leaves its keys intact.
+type Group struct {
+	ID          id.GroupID `json:"id" db:"id"`
+	TenantID    string     `json:"tenant_id" db:"tenant_id"`
+	AppID       string     `json:"app_id" db:"app_id"`
+	Name        string     `json:"name" db:"name"`
+	Description string     `json:"description,omitempty" db:"description"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// ListFilter contains filters for listing groups.
+type ListFilter struct {
+	TenantID string `json:"tenant_id,omitempty"`
+	AppID    string `json:"app_id,omitempty"`
+
+	Limit  int `json:"limit,omitempty"`
+	Offset int `json:"offset,omitempty"`
+}
@@ -0,0 +1,30 @@
+package group
+
+import (
+	"context"
+
+	"github.com/xraph/keysmith/id"
+)
+
+// Store is the persistence interface for key groups and their members.
+// Members are listed through key.ListFilter.GroupID.
+type Store interface {
+	Create(ctx context.Context, g *Group) error
+	Get(ctx context.Context, groupID id.GroupID) (*Group, error)
+	Update(ctx context.Context, g *Group) error
+
+	// Delete deletes the group and its memberships, not its keys.
+	Delete(ctx context.Context, groupID id.GroupID) error
+
+	// List returns groups ordered by name.
+	List(ctx context.Context, filter *ListFilter) ([]*Group, error)
+
+	// AddKeys and RemoveKeys change the group's members. Adding a member
+	// twice, or removing a key that is not one, is a no-op.
+	AddKeys(ctx context.Context, groupID id.GroupID, keyIDs []id.KeyID) error
+	RemoveKeys(ctx context.Context, groupID id.GroupID, keyIDs []id.KeyID) error
+
+	// ListByKeys returns the IDs of the groups each of keyIDs belongs to.
+	// Keys in no group are absent from the map.
+	ListByKeys(ctx context.Context, keyIDs []id.KeyID) (map[id.KeyID][]id.GroupID, error)
+}
@@ -0,0 +1,198 @@
+package keysmith_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/group"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keysmithtest"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+func createGroupKeys(t *testing.T, eng *keysmith.Engine, ctx context.Context, n int) []id.KeyID {
+	t.Helper()
+	ids := make([]id.KeyID, n)
+	for i := range ids {
+		created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Member", Prefix: "sk", Environment: key.EnvTest})
+		require.NoError(t, err)
+		ids[i] = created.Key.ID
+	}
+	return ids
+}
+
+func TestGroups_CRUD(t *testing.T) {
+	eng := newTestEngine(t)
+	ctx := testCtx()
+
+	require.ErrorIs(t, eng.CreateGroup(ctx, &group.Group{Name: "  "}), keysmith.ErrInvalidGroup)
+
+	g := &group.Group{Name: "Mobile apps"}
+	require.NoError(t, eng.CreateGroup(ctx, g))
+	assert.Equal(t, id.PrefixGroup, g.ID.Prefix())
+	assert.Equal(t, "tenant_test", g.TenantID)
+	require.NoError(t, eng.CreateGroup(ctx, &group.Group{Name: "Analytics"}))
+
+	groups, err := eng.ListGroups(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	assert.Equal(t, "Analytics", groups[0].Name, "groups are ordered by name")
+
+	g.Description = "iOS and Android"
+	require.NoError(t, eng.UpdateGroup(ctx, g))
+	got, err := eng.GetGroup(ctx, g.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "iOS and Android", got.Description)
+
+	other := keysmith.WithTenant(context.Background(), "app_test", "tenant_other")
+	_, err = eng.GetGroup(other, g.ID)
+	require.ErrorIs(t, err, keysmith.ErrGroupNotFound, "other tenants cannot see the group")
+	groups, err = eng.ListGroups(other, nil)
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+}
+
+func TestGroups_Membership(t *testing.T) {
+	rec := keysmithtest.NewRecorder()
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithExtension(rec))
+	require.NoError(t, err)
+	ctx := testCtx()
+	ids := createGroupKeys(t, eng, ctx, 3)
+
+	g := &group.Group{Name: "Partner X"}
+	require.NoError(t, eng.CreateGroup(ctx, g))
+	rec.Reset()
+
+	require.NoError(t, eng.AddKeysToGroup(ctx, g.ID, []id.KeyID{ids[0], ids[1]}))
+	require.NoError(t, eng.AddKeysToGroup(ctx, g.ID, []id.KeyID{ids[1]}), "adding a member again is a no-op")
+	changes := rec.Filter("GroupMembershipChanged")
+	require.Len(t, changes, 1, "a no-op fires no event")
+	assert.Equal(t, []id.KeyID{ids[0], ids[1]}, changes[0].AddedKeys)
+	assert.Equal(t, g.ID.String(), changes[0].Meta.GroupID)
+
+	members, err := eng.ListKeys(ctx, &key.ListFilter{GroupID: &g.ID})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{ids[0].String(), ids[1].String()}, keyIDs(members))
+
+	byKey, err := eng.KeyGroups(ctx, members)
+	require.NoError(t, err)
+	assert.Equal(t, []id.GroupID{g.ID}, byKey[ids[0]])
+
+	require.NoError(t, eng.RemoveKeysFromGroup(ctx, g.ID, []id.KeyID{ids[0], ids[2]}))
+	changes = rec.Filter("GroupMembershipChanged")
+	require.Len(t, changes, 2)
+	assert.Equal(t, []id.KeyID{ids[0]}, changes[1].RemovedKeys, "only members are reported")
+
+	// Keys outside the group's tenant cannot join it.
+	other := keysmith.WithTenant(context.Background(), "app_test", "tenant_other")
+	outsider := createGroupKeys(t, eng, other, 1)[0]
+	require.ErrorIs(t, eng.AddKeysToGroup(ctx, g.ID, []id.KeyID{outsider}), keysmith.ErrKeyNotFound)
+
+	// Deleting the group keeps its keys and reports them as removed.
+	require.NoError(t, eng.DeleteGroup(ctx, g.ID))
+	changes = rec.Filter("GroupMembershipChanged")
+	require.Len(t, changes, 3)
+	assert.Equal(t, []id.KeyID{ids[1]}, changes[2].RemovedKeys)
+	_, err = eng.GetKey(ctx, ids[1])
+	require.NoError(t, err)
+	byKey, err = eng.KeyGroups(ctx, members)
+	require.NoError(t, err)
+	assert.Empty(t, byKey)
+}
+
+func TestRotateGroup_RecordsEachMember(t *testing.T) {
+	rec := keysmithtest.NewRecorder()
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithExtension(rec))
+	require.NoError(t, err)
+	ctx := testCtx()
+	ids := createGroupKeys(t, eng, ctx, 4)
+
+	g := &group.Group{Name: "Mobile apps"}
+	require.NoError(t, eng.CreateGroup(ctx, g))
+	require.NoError(t, eng.AddKeysToGroup(ctx, g.ID, ids[:3]))
+	require.NoError(t, eng.RevokeKey(ctx, ids[2], "retired"))
+	rec.Reset()
+
+	_, err = eng.RotateGroup(ctx, g.ID, "bogus")
+	require.ErrorIs(t, err, keysmith.ErrInvalidRotationReason)
+
+	// A dry run reports the rotations without making them.
+	res, err := eng.RotateGroup(keysmith.WithDryRun(ctx), g.ID, rotation.ReasonScheduled)
+	require.NoError(t, err)
+	assert.True(t, res.DryRun)
+	assert.Zero(t, rec.Count("KeyRotated"))
+
+	res, err = eng.RotateGroup(ctx, g.ID, rotation.ReasonScheduled)
+	require.NoError(t, err)
+	assert.Equal(t, g.ID, res.GroupID)
+	require.Len(t, res.Keys, 3)
+	statuses := make(map[id.KeyID]keysmith.GroupKeyStatus)
+	for _, r := range res.Keys {
+		statuses[r.KeyID] = r.Status
+		if r.Status == keysmith.GroupKeyRotated {
+			assert.NotEmpty(t, r.RawKey)
+		}
+	}
+	assert.Equal(t, map[id.KeyID]keysmith.GroupKeyStatus{
+		ids[0]: keysmith.GroupKeyRotated,
+		ids[1]: keysmith.GroupKeyRotated,
+		ids[2]: keysmith.GroupKeyAlreadyRevoked,
+	}, statuses)
+
+	for _, kid := range ids[:2] {
+		recs, err := eng.ListRotations(ctx, &rotation.ListFilter{KeyID: &kid})
+		require.NoError(t, err)
+		require.Len(t, recs, 1, "one rotation record per member")
+		assert.Equal(t, rotation.ReasonScheduled, recs[0].Reason)
+	}
+	recs, err := eng.ListRotations(ctx, &rotation.ListFilter{KeyID: &ids[3]})
+	require.NoError(t, err)
+	assert.Empty(t, recs, "keys outside the group are not rotated")
+
+	rotated := rec.Filter("KeyRotated")
+	require.Len(t, rotated, 2)
+	for _, evt := range rotated {
+		assert.Equal(t, g.ID.String(), evt.Meta.GroupID)
+	}
+}
+
+func TestRevokeGroup(t *testing.T) {
+	rec := keysmithtest.NewRecorder()
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithExtension(rec))
+	require.NoError(t, err)
+	ctx := testCtx()
+	ids := createGroupKeys(t, eng, ctx, 3)
+
+	g := &group.Group{Name: "Partner X"}
+	require.NoError(t, eng.CreateGroup(ctx, g))
+	require.NoError(t, eng.AddKeysToGroup(ctx, g.ID, ids[:2]))
+	rec.Reset()
+
+	res, err := eng.RevokeGroup(ctx, g.ID, "partner offboarded")
+	require.NoError(t, err)
+	require.Len(t, res.Keys, 2)
+	for _, r := range res.Keys {
+		assert.Equal(t, keysmith.GroupKeyRevoked, r.Status)
+	}
+
+	revoked := rec.Filter("KeyRevoked")
+	require.Len(t, revoked, 2)
+	for _, evt := range revoked {
+		assert.Equal(t, plugin.ReasonGroupRevoked, evt.Meta.ReasonCode)
+		assert.Equal(t, g.ID.String(), evt.Meta.GroupID)
+	}
+	k, err := eng.GetKey(ctx, ids[2])
+	require.NoError(t, err)
+	assert.Equal(t, key.StateActive, k.State, "keys outside the group are untouched")
+
+	res, err = eng.RevokeGroup(ctx, g.ID, "again")
+	require.NoError(t, err)
+	assert.Equal(t, keysmith.GroupKeyAlreadyRevoked, res.Keys[0].Status)
+}
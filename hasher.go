@@ -1,28 +1,79 @@
 package keysmith
 
 import (
+	"crypto/hmac"
 	"crypto/sha256"
-	"crypto/subtle"
-	"encoding/hex"
+	"fmt"
+	"hash"
+	"slices"
+	"sync"
+
+	"github.com/xraph/keysmith/key"
 )
 
 // Hasher hashes raw API keys for secure storage.
 type Hasher interface {
-	// Hash produces a deterministic hash of the raw key.
+	// Hash produces a deterministic hash of the raw key. It should be in the
+	// canonical form built by [key.FormatHash]; the engine normalizes other
+	// encodings with [key.NormalizeHash] before storing or looking them up.
 	Hash(rawKey string) (string, error)
 
 	// Verify checks whether a raw key matches a stored hash.
 	Verify(rawKey, hash string) (bool, error)
 }
 
+// BufferHasher is implemented by a [Hasher] that can hash into a caller's
+// buffer, which lets ValidateKey hash a key without allocating. Both
+// built-in hashers implement it.
+type BufferHasher interface {
+	Hasher
+
+	// HashInto appends the hash of rawKey to dst and returns the extended
+	// buffer. The hash must be what Hash returns and in the canonical form
+	// of [key.AppendHash], since the engine looks it up as is.
+	HashInto(dst []byte, rawKey string) ([]byte, error)
+}
+
+// MinHMACKeySize is the smallest key, in bytes, NewHMACHasher accepts.
+const MinHMACKeySize = 32
+
 // DefaultHasher returns a SHA-256 hasher.
 func DefaultHasher() Hasher { return &sha256Hasher{} }
 
+// NewHMACHasher returns a hasher that keys SHA-256 with secret, so stored
+// hashes cannot be checked against guessed keys without it. Hashes differ
+// from DefaultHasher's, so switching an existing store needs every key
+// rehashed. A secret shorter than [MinHMACKeySize] returns
+// [ErrInvalidHMACKey].
+func NewHMACHasher(secret []byte) (Hasher, error) {
+	if len(secret) < MinHMACKeySize {
+		return nil, fmt.Errorf("%w: got %d bytes, need at least %d", ErrInvalidHMACKey, len(secret), MinHMACKeySize)
+	}
+	h := &hmacHasher{secret: slices.Clone(secret)}
+	h.macs.New = func() any { return hmac.New(sha256.New, h.secret) }
+	return h, nil
+}
+
+// scratchBufs holds the buffers hashers copy raw keys into, so that hashing
+// a string does not allocate. Buffers are cleared before they are returned.
+var scratchBufs = sync.Pool{New: func() any { return new([]byte) }}
+
 type sha256Hasher struct{}
 
+func (h *sha256Hasher) Algorithm() string { return AlgSHA256 }
+
 func (h *sha256Hasher) Hash(rawKey string) (string, error) {
-	sum := sha256.Sum256([]byte(rawKey))
-	return hex.EncodeToString(sum[:]), nil
+	b, err := h.HashInto(nil, rawKey)
+	return string(b), err
+}
+
+func (h *sha256Hasher) HashInto(dst []byte, rawKey string) ([]byte, error) {
+	buf := scratchBufs.Get().(*[]byte)
+	*buf = append((*buf)[:0], rawKey...)
+	sum := sha256.Sum256(*buf)
+	clear(*buf)
+	scratchBufs.Put(buf)
+	return key.AppendHash(dst, key.HashAlgSHA256, sum[:]), nil
 }
 
 func (h *sha256Hasher) Verify(rawKey, hash string) (bool, error) {
@@ -30,5 +81,94 @@ func (h *sha256Hasher) Verify(rawKey, hash string) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	return subtle.ConstantTimeCompare([]byte(computed), []byte(hash)) == 1, nil
+	return key.HashesEqual(computed, hash), nil
+}
+
+type hmacHasher struct {
+	secret []byte
+
+	// macs holds keyed MACs for reuse, since keying one allocates.
+	macs sync.Pool
+}
+
+func (h *hmacHasher) Algorithm() string { return AlgHMACSHA256 }
+
+func (h *hmacHasher) Hash(rawKey string) (string, error) {
+	b, err := h.HashInto(nil, rawKey)
+	return string(b), err
+}
+
+func (h *hmacHasher) HashInto(dst []byte, rawKey string) ([]byte, error) {
+	mac := h.macs.Get().(hash.Hash)
+	buf := scratchBufs.Get().(*[]byte)
+	*buf = append((*buf)[:0], rawKey...)
+	mac.Write(*buf)
+	clear(*buf)
+	*buf = mac.Sum((*buf)[:0])
+	mac.Reset()
+	h.macs.Put(mac)
+	dst = key.AppendHash(dst, key.HashAlgHMACSHA256, *buf)
+	clear(*buf)
+	scratchBufs.Put(buf)
+	return dst, nil
+}
+
+func (h *hmacHasher) Verify(rawKey, hash string) (bool, error) {
+	computed, err := h.Hash(rawKey)
+	if err != nil {
+		return false, err
+	}
+	return key.HashesEqual(computed, hash), nil
+}
+
+// hashKey hashes rawKey with the engine's hasher and normalizes the result,
+// so a custom hasher's encoding does not cause lookup misses.
+func (e *Engine) hashKey(rawKey string) (string, error) {
+	sum, err := e.hasher.Hash(rawKey)
+	if err != nil {
+		return "", err
+	}
+	sum, _ = key.NormalizeHash(sum)
+	return sum, nil
+}
+
+// hashBufs holds the buffers ValidateKey hashes keys into.
+var hashBufs = sync.Pool{New: func() any { return new([]byte) }}
+
+// appendHash appends rawKey's normalized hash to dst, without allocating
+// when the hasher is a [BufferHasher].
+func (e *Engine) appendHash(dst []byte, rawKey string) ([]byte, error) {
+	if h, ok := e.hasher.(BufferHasher); ok {
+		return h.HashInto(dst, rawKey)
+	}
+	norm, err := e.hashKey(rawKey)
+	if err != nil {
+		return dst, err
+	}
+	return append(dst, norm...), nil
+}
+
+// storedHashAlg returns the canonical hash prefix of the engine's hasher, or
+// "" for a custom hasher.
+func (e *Engine) storedHashAlg() string {
+	switch algorithmOf(e.hasher) {
+	case AlgSHA256:
+		return key.HashAlgSHA256
+	case AlgHMACSHA256:
+		return key.HashAlgHMACSHA256
+	}
+	return ""
+}
+
+// canonicalHash returns a stored hash in canonical form. A bare legacy
+// digest gets the prefix of the engine's hasher, which is assumed to have
+// made it.
+func (e *Engine) canonicalHash(stored string) string {
+	norm, ok := key.NormalizeHash(stored)
+	if alg, digest := key.SplitHash(norm); ok && alg == "" {
+		if prefix := e.storedHashAlg(); prefix != "" {
+			return prefix + ":" + digest
+		}
+	}
+	return norm
 }
@@ -1,12 +1,19 @@
 package keysmith_test
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store/memory"
 )
 
 func TestHasher_Deterministic(t *testing.T) {
@@ -61,6 +68,211 @@ func TestHasher_HashFormat(t *testing.T) {
 	h := keysmith.DefaultHasher()
 	hash, err := h.Hash("test")
 	require.NoError(t, err)
-	// SHA-256 produces a 64-character hex string.
-	assert.Len(t, hash, 64)
+	// Canonical form: the algorithm, then SHA-256 as 64 lowercase hex characters.
+	assert.Equal(t, "sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", hash)
+	assert.True(t, key.IsCanonicalHash(hash))
+}
+
+func TestHasher_VerifyLegacyHash(t *testing.T) {
+	h := keysmith.DefaultHasher()
+	hash, err := h.Hash("test")
+	require.NoError(t, err)
+	_, digest := key.SplitHash(hash)
+
+	for _, stored := range []string{digest, strings.ToUpper(digest), "SHA256:" + digest} {
+		ok, err := h.Verify("test", stored)
+		require.NoError(t, err)
+		assert.True(t, ok, stored)
+	}
+	ok, err := h.Verify("test", "hmac-sha256:"+digest)
+	require.NoError(t, err)
+	assert.False(t, ok, "a different algorithm does not verify")
+}
+
+func TestNormalizeHash(t *testing.T) {
+	const digest = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	raw, err := hex.DecodeString(digest)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+		ok   bool
+	}{
+		{"canonical", "sha256:" + digest, "sha256:" + digest, true},
+		{"uppercase", "SHA256:" + strings.ToUpper(digest), "sha256:" + digest, true},
+		{"bare legacy hex", digest, digest, true},
+		{"base64", "sha256:" + base64.StdEncoding.EncodeToString(raw), "sha256:" + digest, true},
+		{"raw url base64", base64.RawURLEncoding.EncodeToString(raw), digest, true},
+		{"whitespace", " sha256:" + digest + "\n", "sha256:" + digest, true},
+		{"opaque", "$argon2id$v=19$m=65536", "$argon2id$v=19$m=65536", false},
+		{"empty algorithm", ":" + digest, ":" + digest, false},
+		{"empty", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := key.NormalizeHash(tt.in)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.ok, ok)
+		})
+	}
+}
+
+func FuzzNormalizeHash(f *testing.F) {
+	for _, seed := range []string{
+		"sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		"9F86D081884C7D659A2FEAA0C55AD015A3BF4F1B2B0B822CD15D6C15B0F00A08",
+		"hmac-sha256:n4bQgYhMfWWaL+qgxVrQFaO/TxsrC4Is0V1sFbDwCgg=",
+		"", ":", "sha256:", "a:b:c", "$2a$10$abc",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, in string) {
+		norm, ok := key.NormalizeHash(in)
+		again, okAgain := key.NormalizeHash(norm)
+		if again != norm || okAgain != ok {
+			t.Fatalf("not idempotent: %q -> %q (%v) -> %q (%v)", in, norm, ok, again, okAgain)
+		}
+		if ok && !key.HashesEqual(in, norm) {
+			t.Fatalf("%q does not equal its normal form %q", in, norm)
+		}
+		forms := key.LookupHashes(in)
+		if len(forms) == 0 || forms[0] != in || !slices.Contains(forms, norm) {
+			t.Fatalf("lookup forms of %q miss it or %q: %q", in, norm, forms)
+		}
+	})
+}
+
+func TestHMACHasher(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	h, err := keysmith.NewHMACHasher(secret)
+	require.NoError(t, err)
+
+	hash, err := h.Hash("sk_live_abc123def456")
+	require.NoError(t, err)
+	plain, err := keysmith.DefaultHasher().Hash("sk_live_abc123def456")
+	require.NoError(t, err)
+	assert.NotEqual(t, plain, hash)
+
+	ok, err := h.Verify("sk_live_abc123def456", hash)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	other, err := keysmith.NewHMACHasher([]byte("fedcba9876543210fedcba9876543210"))
+	require.NoError(t, err)
+	ok, err = other.Verify("sk_live_abc123def456", hash)
+	require.NoError(t, err)
+	assert.False(t, ok, "a different secret does not verify")
+}
+
+func TestHasher_HashInto(t *testing.T) {
+	rawKey := "sk_live_abc123def456"
+	hmacHasher, err := keysmith.NewHMACHasher([]byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+
+	for name, h := range map[string]keysmith.Hasher{"sha256": keysmith.DefaultHasher(), "hmac": hmacHasher} {
+		t.Run(name, func(t *testing.T) {
+			bh, ok := h.(keysmith.BufferHasher)
+			require.True(t, ok)
+
+			want, err := h.Hash(rawKey)
+			require.NoError(t, err)
+			got, err := bh.HashInto([]byte("prefix|"), rawKey)
+			require.NoError(t, err)
+			assert.Equal(t, "prefix|"+want, string(got), "the hash is appended as Hash returns it")
+
+			again, err := bh.HashInto(got[:0], rawKey)
+			require.NoError(t, err)
+			assert.Equal(t, want, string(again), "pooled state does not leak between calls")
+		})
+	}
+}
+
+func TestHMACHasher_ShortSecret(t *testing.T) {
+	_, err := keysmith.NewHMACHasher([]byte("too short"))
+	assert.ErrorIs(t, err, keysmith.ErrInvalidHMACKey)
+}
+
+// storeLegacyHash rewrites k's stored hash the way a hasher from before
+// algorithm prefixes would have written it.
+func storeLegacyHash(t *testing.T, ms *memory.Store, k *key.Key, legacy func(digest string) string) string {
+	t.Helper()
+	stored, err := ms.Keys().Get(testCtx(), k.ID)
+	require.NoError(t, err)
+	_, digest := key.SplitHash(stored.KeyHash)
+	stored.KeyHash = legacy(digest)
+	require.NoError(t, ms.Keys().Update(testCtx(), stored))
+	return stored.KeyHash
+}
+
+func TestLegacyHashLookups(t *testing.T) {
+	ms := memory.New()
+	eng, err := keysmith.NewEngine(keysmith.WithStore(ms))
+	require.NoError(t, err)
+	ctx := testCtx()
+
+	bare := createHashKey(t, eng, ctx)
+	upper := createHashKey(t, eng, ctx)
+	bareHash := storeLegacyHash(t, ms, bare.Key, func(d string) string { return d })
+	storeLegacyHash(t, ms, upper.Key, strings.ToUpper)
+
+	// Validation hashes into canonical form and still finds both.
+	for _, created := range []*key.CreateResult{bare, upper} {
+		res, err := eng.ValidateKey(ctx, created.RawKey)
+		require.NoError(t, err)
+		assert.Equal(t, created.Key.ID, res.Key.ID)
+	}
+
+	// Hashes submitted in either form match either stored form.
+	canonical, err := keysmith.DefaultHasher().Hash(upper.RawKey)
+	require.NoError(t, err)
+	reports, err := eng.BulkValidateHashes(context.Background(), []string{bareHash, "SHA256:" + strings.ToUpper(bareHash), canonical})
+	require.NoError(t, err)
+	require.Len(t, reports, 3)
+	assert.Equal(t, bare.Key.ID, reports[0].KeyID)
+	assert.Equal(t, bare.Key.ID, reports[1].KeyID)
+	assert.Equal(t, upper.Key.ID, reports[2].KeyID)
+
+	// The backfill rewrites both into canonical form; a dry run only counts.
+	report, err := eng.CanonicalizeKeyHashes(keysmith.WithDryRun(context.Background()))
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Rewritten)
+	k, err := ms.Keys().Get(ctx, bare.Key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, bareHash, k.KeyHash)
+
+	report, err = eng.CanonicalizeKeyHashes(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, keysmith.HashBackfillReport{Scanned: 2, Rewritten: 2}, *report)
+	for _, created := range []*key.CreateResult{bare, upper} {
+		k, err := ms.Keys().Get(ctx, created.Key.ID)
+		require.NoError(t, err)
+		assert.True(t, key.IsCanonicalHash(k.KeyHash), k.KeyHash)
+		assert.True(t, k.UpdatedAt.Equal(created.Key.UpdatedAt), "the backfill is not a change")
+		_, err = eng.ValidateKey(ctx, created.RawKey)
+		require.NoError(t, err)
+	}
+
+	report, err = eng.CanonicalizeKeyHashes(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, report.Rewritten, "re-running is a no-op")
+}
+
+func TestCanonicalizeKeyHashes_HMAC(t *testing.T) {
+	ms := memory.New()
+	h, err := keysmith.NewHMACHasher([]byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	eng, err := keysmith.NewEngine(keysmith.WithStore(ms), keysmith.WithHasher(h))
+	require.NoError(t, err)
+
+	created := createHashKey(t, eng, testCtx())
+	assert.True(t, strings.HasPrefix(created.Key.KeyHash, key.HashAlgHMACSHA256+":"))
+	storeLegacyHash(t, ms, created.Key, func(d string) string { return d })
+
+	_, err = eng.CanonicalizeKeyHashes(context.Background())
+	require.NoError(t, err)
+	k, err := ms.Keys().Get(testCtx(), created.Key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, created.Key.KeyHash, k.KeyHash, "the bare digest gets the engine hasher's prefix")
 }
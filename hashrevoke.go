@@ -0,0 +1,171 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/plugin"
+)
+
+// MaxHashBatch is the most hashes RevokeByHashes and BulkValidateHashes
+// accept in one call.
+const MaxHashBatch = 1000
+
+// HashStatus is the outcome for one hash in a [HashReport].
+type HashStatus string
+
+const (
+	// HashNotFound: no key visible from the context has the hash.
+	HashNotFound HashStatus = "not_found"
+
+	// HashFound: a key has the hash and is not revoked. Only
+	// BulkValidateHashes reports it.
+	HashFound HashStatus = "found"
+
+	// HashRevoked: RevokeByHashes revoked the key.
+	HashRevoked HashStatus = "revoked"
+
+	// HashAlreadyRevoked: the key was revoked before the call.
+	HashAlreadyRevoked HashStatus = "already_revoked"
+)
+
+// HashReport is the outcome for one submitted hash. KeyID and State are
+// zero when the hash was not found; State is the key's state before the
+// call.
+type HashReport struct {
+	Hash   string     `json:"hash"`
+	Status HashStatus `json:"status"`
+	KeyID  id.KeyID   `json:"key_id,omitzero"`
+	State  key.State  `json:"state,omitempty"`
+}
+
+// RevokeByHashes revokes the keys whose current key_hash is in hashes, for
+// responding to a leaked database dump. It resolves every hash in one
+// store lookup and returns a report per submitted hash in the same order.
+// Keys outside the context's tenant and app are reported as not found, and
+// re-running a batch reports the keys as already revoked. Each revocation
+// fires KeyRevoked with reason, so audit trails reference key IDs rather
+// than the submitted hashes. At most MaxHashBatch hashes are accepted.
+func (e *Engine) RevokeByHashes(ctx context.Context, hashes []string, reason string) ([]HashReport, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	reports, keys, err := e.resolveHashes(ctx, hashes)
+	if err != nil {
+		return nil, err
+	}
+	for i := range reports {
+		r := &reports[i]
+		if r.Status != HashFound {
+			continue
+		}
+		// A hash submitted twice is revoked once; revokeKey has already
+		// marked the shared key revoked.
+		k := keys[r.Hash]
+		if k.State != key.StateRevoked {
+			if err := e.revokeKey(ctx, k, reason, plugin.ReasonLeakedHash); err != nil {
+				return reports[:i], fmt.Errorf("revoke key %s: %w", k.ID, err)
+			}
+		}
+		r.Status = HashRevoked
+	}
+	return reports, nil
+}
+
+// BulkValidateHashes reports, without changing anything, what
+// RevokeByHashes would find for hashes, so the blast radius of a leak can be
+// scoped first. Keys that are not revoked are reported as HashFound.
+func (e *Engine) BulkValidateHashes(ctx context.Context, hashes []string) ([]HashReport, error) {
+	reports, _, err := e.resolveHashes(ctx, hashes)
+	return reports, err
+}
+
+// resolveHashes looks up hashes and builds the initial reports: found,
+// already revoked, or not found.
+func (e *Engine) resolveHashes(ctx context.Context, hashes []string) ([]HashReport, map[string]*key.Key, error) {
+	if len(hashes) > MaxHashBatch {
+		return nil, nil, fmt.Errorf("%w: %d hashes, at most %d", ErrHashBatchTooLarge, len(hashes), MaxHashBatch)
+	}
+	found, err := e.store.Keys().GetByHashes(ctx, hashes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get keys by hash: %w", err)
+	}
+	sc := scopeFromContext(ctx)
+	byDigest := make(map[string]*key.Key, len(found))
+	for _, k := range found {
+		if sc.owns(k.TenantID, k.AppID) {
+			byDigest[hashDigest(k.KeyHash)] = k
+		}
+	}
+	previous := e.previousHashes(ctx, found, byDigest, hashes)
+
+	// Submitted and stored hashes may differ in encoding, so match them by
+	// normalized digest.
+	keys := make(map[string]*key.Key, len(found))
+	reports := make([]HashReport, len(hashes))
+	for i, hash := range hashes {
+		reports[i] = HashReport{Hash: hash, Status: HashNotFound}
+		k, ok := byDigest[hashDigest(hash)]
+		if !ok || !key.HashesEqual(k.KeyHash, hash) {
+			if k, ok = previous[hash]; !ok {
+				continue
+			}
+		}
+		keys[hash] = k
+		reports[i].KeyID = k.ID
+		reports[i].State = k.State
+		reports[i].Status = HashFound
+		if k.State == key.StateRevoked {
+			reports[i].Status = HashAlreadyRevoked
+		}
+	}
+	return reports, keys, nil
+}
+
+// previousHashes maps those of hashes that are the secret a rotation
+// replaced, still matching its key during the grace period, to the key.
+// Only the keys of found no hash matched by their current hash are read.
+func (e *Engine) previousHashes(ctx context.Context, found []*key.Key, byDigest map[string]*key.Key, hashes []string) map[string]*key.Key {
+	submitted := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		submitted[hashDigest(hash)] = true
+	}
+	sc := scopeFromContext(ctx)
+	var previous map[string]*key.Key
+	for _, k := range found {
+		if submitted[hashDigest(k.KeyHash)] || !sc.owns(k.TenantID, k.AppID) {
+			continue
+		}
+		rec, err := e.store.Rotations().LatestForKey(ctx, k.ID)
+		if err != nil {
+			continue
+		}
+		for _, hash := range hashes {
+			if _, current := byDigest[hashDigest(hash)]; current || !key.HashesEqual(rec.OldKeyHash, hash) {
+				continue
+			}
+			if e.hashMatches(ctx, k.ID, hash) {
+				if previous == nil {
+					previous = make(map[string]*key.Key)
+				}
+				previous[hash] = k
+			}
+		}
+	}
+	return previous
+}
+
+// hashMatches reports whether hash still matches the key keyID.
+func (e *Engine) hashMatches(ctx context.Context, keyID id.KeyID, hash string) bool {
+	k, err := e.store.Keys().GetByHash(ctx, hash)
+	return err == nil && k.ID == keyID
+}
+
+// hashDigest returns the normalized digest of hash, without its algorithm.
+func hashDigest(hash string) string {
+	norm, _ := key.NormalizeHash(hash)
+	_, digest := key.SplitHash(norm)
+	return digest
+}
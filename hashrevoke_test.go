@@ -0,0 +1,123 @@
+package keysmith_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	audithook "github.com/xraph/keysmith/audit_hook"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+func newHashEngine(t *testing.T) (*keysmith.Engine, *auditCapture) {
+	t.Helper()
+	audit := &auditCapture{}
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithExtension(audithook.New(audit)),
+	)
+	require.NoError(t, err)
+	return eng, audit
+}
+
+func createHashKey(t *testing.T, eng *keysmith.Engine, ctx context.Context) *key.CreateResult {
+	t.Helper()
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Leaked", Prefix: "sk", Environment: key.EnvLive})
+	require.NoError(t, err)
+	return created
+}
+
+func TestRevokeByHashes_MixedBatch(t *testing.T) {
+	eng, audit := newHashEngine(t)
+	a := createHashKey(t, eng, testCtx())
+	b := createHashKey(t, eng, testCtx())
+	revoked := createHashKey(t, eng, testCtx())
+	untouched := createHashKey(t, eng, testCtx())
+	require.NoError(t, eng.RevokeKey(testCtx(), revoked.Key.ID, "rotated out"))
+	audit.reset()
+
+	hashes := []string{a.Key.KeyHash, "not-a-known-hash", revoked.Key.KeyHash, b.Key.KeyHash}
+	reports, err := eng.RevokeByHashes(context.Background(), hashes, "backup leak")
+	require.NoError(t, err)
+	require.Len(t, reports, 4)
+	assert.Equal(t, keysmith.HashRevoked, reports[0].Status)
+	assert.Equal(t, a.Key.ID, reports[0].KeyID)
+	assert.Equal(t, key.StateActive, reports[0].State)
+	assert.Equal(t, keysmith.HashNotFound, reports[1].Status)
+	assert.True(t, reports[1].KeyID.IsNil())
+	assert.Equal(t, keysmith.HashAlreadyRevoked, reports[2].Status)
+	assert.Equal(t, keysmith.HashRevoked, reports[3].Status)
+
+	// Audit events name the keys, never the submitted hashes.
+	require.Len(t, audit.recorded(), 2)
+	for _, evt := range audit.recorded() {
+		assert.Equal(t, audithook.ActionKeyRevoked, evt.Action)
+		encoded, err := json.Marshal(evt)
+		require.NoError(t, err)
+		for _, h := range hashes {
+			assert.False(t, strings.Contains(string(encoded), h))
+		}
+	}
+	assert.Equal(t, a.Key.ID.String(), audit.recorded()[0].ResourceID)
+
+	for _, created := range []*key.CreateResult{a, b} {
+		_, err := eng.ValidateKey(testCtx(), created.RawKey)
+		assert.ErrorIs(t, err, keysmith.ErrKeyInactive)
+	}
+	_, err = eng.ValidateKey(testCtx(), untouched.RawKey)
+	require.NoError(t, err)
+}
+
+func TestRevokeByHashes_Idempotent(t *testing.T) {
+	eng, audit := newHashEngine(t)
+	a := createHashKey(t, eng, testCtx())
+	hashes := []string{a.Key.KeyHash, a.Key.KeyHash}
+
+	reports, err := eng.RevokeByHashes(context.Background(), hashes, "backup leak")
+	require.NoError(t, err)
+	assert.Equal(t, keysmith.HashRevoked, reports[0].Status)
+	assert.Equal(t, keysmith.HashRevoked, reports[1].Status, "a repeated hash reports the same outcome")
+	before := len(audit.recorded())
+
+	reports, err = eng.RevokeByHashes(context.Background(), hashes, "backup leak")
+	require.NoError(t, err)
+	assert.Equal(t, keysmith.HashAlreadyRevoked, reports[0].Status)
+	assert.Equal(t, keysmith.HashAlreadyRevoked, reports[1].Status)
+	assert.Len(t, audit.recorded(), before, "re-running revokes nothing")
+}
+
+func TestBulkValidateHashes_DoesNotMutate(t *testing.T) {
+	eng, audit := newHashEngine(t)
+	a := createHashKey(t, eng, testCtx())
+	audit.reset()
+
+	reports, err := eng.BulkValidateHashes(context.Background(), []string{a.Key.KeyHash, "missing"})
+	require.NoError(t, err)
+	assert.Equal(t, keysmith.HashFound, reports[0].Status)
+	assert.Equal(t, keysmith.HashNotFound, reports[1].Status)
+	assert.Empty(t, audit.recorded())
+
+	_, err = eng.ValidateKey(testCtx(), a.RawKey)
+	require.NoError(t, err)
+}
+
+func TestRevokeByHashes_Scoped(t *testing.T) {
+	eng, _ := newHashEngine(t)
+	other := createHashKey(t, eng, keysmith.WithTenant(context.Background(), "app_test", "tenant_other"))
+
+	reports, err := eng.RevokeByHashes(testCtx(), []string{other.Key.KeyHash}, "backup leak")
+	require.NoError(t, err)
+	assert.Equal(t, keysmith.HashNotFound, reports[0].Status, "keys in other tenants are not visible")
+}
+
+func TestRevokeByHashes_BatchCap(t *testing.T) {
+	eng, _ := newHashEngine(t)
+	_, err := eng.RevokeByHashes(context.Background(), make([]string, keysmith.MaxHashBatch+1), "leak")
+	assert.ErrorIs(t, err, keysmith.ErrHashBatchTooLarge)
+}
@@ -0,0 +1,129 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/xraph/go-utils/log"
+
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/tombstone"
+)
+
+// tombstoneReasonRevoked is the tombstone reason of a key revoked without a
+// reason code, tombstoned under WithTombstoneEveryRevocation.
+const tombstoneReasonRevoked = "revoked"
+
+// securityRevocation reports whether a revocation with code is for a
+// security reason, whose key's hashes are always tombstoned.
+func securityRevocation(code plugin.ReasonCode) bool {
+	return code == plugin.ReasonCompromised || code == plugin.ReasonLeakedHash
+}
+
+// tombstoneKey tombstones the current hash and every hash version of the
+// revoked key k, when code is a security reason or WithTombstoneEveryRevocation
+// is set.
+func (e *Engine) tombstoneKey(ctx context.Context, k *key.Key, code plugin.ReasonCode) {
+	if !securityRevocation(code) && !e.tombstoneAll {
+		return
+	}
+	hashes := []string{k.KeyHash}
+	versions, err := e.store.Keys().ListHashes(ctx, k.ID)
+	if err != nil {
+		e.logger.Warn("failed to list hash versions to tombstone",
+			log.String("key_id", k.ID.String()),
+			log.Any("error", err),
+		)
+	}
+	for _, v := range versions {
+		hashes = append(hashes, v.Hash)
+	}
+	e.tombstoneHashes(ctx, k, code, hashes...)
+}
+
+// tombstoneHashes writes a tombstone for each of hashes of k. The key
+// change has already been stored, so a failed write is logged rather than
+// returned.
+func (e *Engine) tombstoneHashes(ctx context.Context, k *key.Key, code plugin.ReasonCode, hashes ...string) {
+	reason := string(code)
+	if reason == "" {
+		reason = tombstoneReasonRevoked
+	}
+	now := e.now()
+	seen := make(map[string]bool, len(hashes))
+	for _, h := range hashes {
+		h, _ = key.NormalizeHash(h)
+		if h == "" || seen[h] {
+			continue
+		}
+		seen[h] = true
+		err := e.store.Tombstones().Create(context.WithoutCancel(ctx), &tombstone.Tombstone{
+			Hash:      h,
+			KeyID:     k.ID,
+			TenantID:  k.TenantID,
+			AppID:     k.AppID,
+			Reason:    reason,
+			CreatedAt: now,
+		})
+		if err != nil {
+			e.logger.Warn("failed to tombstone key hash",
+				log.String("key_id", k.ID.String()),
+				log.Any("error", err),
+			)
+		}
+	}
+}
+
+// checkTombstones returns ErrHashTombstoned if any of hashes is tombstoned.
+func (e *Engine) checkTombstones(ctx context.Context, hashes ...string) error {
+	normalized := make([]string, 0, len(hashes))
+	for _, h := range hashes {
+		if h, _ = key.NormalizeHash(h); h != "" {
+			normalized = append(normalized, h)
+		}
+	}
+	found, err := e.store.Tombstones().Find(ctx, normalized)
+	if err != nil {
+		return fmt.Errorf("find tombstones: %w", err)
+	}
+	if len(found) > 0 {
+		return fmt.Errorf("%w: the hash of key %s was tombstoned (%s)", ErrHashTombstoned, found[0].KeyID, found[0].Reason)
+	}
+	return nil
+}
+
+// ListHashTombstones returns the hash tombstones matching the filter, newest
+// first, restricted to the context's tenant and app. Tombstones are written
+// when a key is revoked for a security reason, or for any reason under
+// WithTombstoneEveryRevocation, and make every later write of the hash fail
+// with ErrHashTombstoned.
+func (e *Engine) ListHashTombstones(ctx context.Context, filter *tombstone.ListFilter) ([]*tombstone.Tombstone, error) {
+	filter = tombstoneFilter(ctx, filter)
+	filter.Limit = e.PageLimit(filter.Limit, DefaultPageSize)
+	return e.store.Tombstones().List(ctx, filter)
+}
+
+// CountHashTombstonesByReason counts the hash tombstones matching the filter
+// per reason, most frequent first, restricted to the context's tenant and
+// app. The filter's Limit and Offset are ignored.
+func (e *Engine) CountHashTombstonesByReason(ctx context.Context, filter *tombstone.ListFilter) ([]*tombstone.ReasonCount, error) {
+	return e.store.Tombstones().CountByReason(ctx, tombstoneFilter(ctx, filter))
+}
+
+// PurgeHashTombstones deletes hash tombstones older than the retention set
+// with WithHashTombstoneRetention. Without a retention tombstones are kept
+// forever and it deletes nothing.
+func (e *Engine) PurgeHashTombstones(ctx context.Context) (int64, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return 0, err
+	}
+	if e.tombstoneRetention <= 0 {
+		return 0, nil
+	}
+	n, err := e.store.Tombstones().Purge(ctx, e.now().Add(-e.tombstoneRetention))
+	if err != nil {
+		return 0, fmt.Errorf("purge hash tombstones: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,157 @@
+package keysmith_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keysmithtest"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/store/memory"
+	"github.com/xraph/keysmith/tombstone"
+)
+
+func compromise(t *testing.T, eng *keysmith.Engine, keyID id.KeyID, action key.CompromiseAction) {
+	t.Helper()
+	_, err := eng.ReportCompromise(testCtx(), keyID, &key.CompromiseReport{Source: "github", Action: action})
+	require.NoError(t, err)
+}
+
+func tombstoneReasons(t *testing.T, eng *keysmith.Engine) []string {
+	t.Helper()
+	tbs, err := eng.ListHashTombstones(testCtx(), nil)
+	require.NoError(t, err)
+	reasons := make([]string, 0, len(tbs))
+	for _, tb := range tbs {
+		reasons = append(reasons, tb.Reason)
+	}
+	return reasons
+}
+
+func TestHashTombstone_SecurityRevocations(t *testing.T) {
+	eng := newTestEngine(t)
+	revoked := keysmithtest.NewKey(eng).MustCreate(t, testCtx())
+	rotated := keysmithtest.NewKey(eng).MustCreate(t, testCtx())
+	leaked := keysmithtest.NewKey(eng).MustCreate(t, testCtx())
+	oldHash := rotated.Key.KeyHash
+
+	compromise(t, eng, revoked.Key.ID, key.CompromiseRevoke)
+	compromise(t, eng, rotated.Key.ID, key.CompromiseRotate)
+	_, err := eng.RevokeByHashes(context.Background(), []string{leaked.Key.KeyHash}, "dump")
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"compromised", "compromised", "leaked_hash"}, tombstoneReasons(t, eng))
+	found, err := eng.Store().Tombstones().Find(testCtx(), []string{oldHash})
+	require.NoError(t, err)
+	assert.Len(t, found, 1, "a compromise rotation tombstones the leaked hash")
+
+	counts, err := eng.CountHashTombstonesByReason(testCtx(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []*tombstone.ReasonCount{{Reason: "compromised", Count: 2}, {Reason: "leaked_hash", Count: 1}}, counts)
+
+	other, err := eng.ListHashTombstones(keysmith.WithTenant(context.Background(), "app_test", "tenant_other"), nil)
+	require.NoError(t, err)
+	assert.Empty(t, other, "tombstones are listed per tenant")
+}
+
+func TestHashTombstone_NormalRevocations(t *testing.T) {
+	eng := newTestEngine(t)
+	created := keysmithtest.NewKey(eng).MustCreate(t, testCtx())
+	require.NoError(t, eng.RevokeKey(testCtx(), created.Key.ID, "no longer needed"))
+	assert.Empty(t, tombstoneReasons(t, eng))
+
+	all, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithTombstoneEveryRevocation())
+	require.NoError(t, err)
+	created = keysmithtest.NewKey(all).MustCreate(t, testCtx())
+	require.NoError(t, all.RevokeKey(testCtx(), created.Key.ID, "no longer needed"))
+	assert.Equal(t, []string{"revoked"}, tombstoneReasons(t, all))
+}
+
+func TestHashTombstone_DryRun(t *testing.T) {
+	eng := newTestEngine(t)
+	created := keysmithtest.NewKey(eng).MustCreate(t, testCtx())
+	_, err := eng.ReportCompromise(keysmith.WithDryRun(testCtx()), created.Key.ID,
+		&key.CompromiseReport{Source: "github", Action: key.CompromiseRevoke})
+	require.NoError(t, err)
+	assert.Empty(t, tombstoneReasons(t, eng))
+}
+
+func TestHashTombstone_RefusesCreateAndRotate(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithKeyGenerator(repeatingGenerator{}))
+	require.NoError(t, err)
+	created := keysmithtest.NewKey(eng).MustCreate(t, testCtx())
+	compromise(t, eng, created.Key.ID, key.CompromiseRevoke)
+
+	_, err = eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "Again", Prefix: "sk", Environment: key.EnvTest})
+	assert.ErrorIs(t, err, keysmith.ErrHashTombstoned)
+
+	other, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "Live", Prefix: "sk", Environment: key.EnvLive})
+	require.NoError(t, err)
+	compromise(t, eng, other.Key.ID, key.CompromiseRotate)
+	_, err = eng.RotateKey(testCtx(), other.Key.ID, rotation.ReasonManual)
+	assert.ErrorIs(t, err, keysmith.ErrHashTombstoned, "the rotation would bring back the leaked secret")
+}
+
+func TestHashTombstone_RefusesImport(t *testing.T) {
+	eng := newTestEngine(t)
+	created := keysmithtest.NewKey(eng).MustCreate(t, testCtx())
+	b, err := eng.ExportKey(context.Background(), created.Key.ID, keysmith.ExportOptions{})
+	require.NoError(t, err)
+	compromise(t, eng, created.Key.ID, key.CompromiseRevoke)
+
+	_, err = eng.ImportKeyBundle(context.Background(), b, keysmith.ImportOptions{})
+	assert.ErrorIs(t, err, keysmith.ErrHashTombstoned)
+
+	require.NoError(t, eng.Store().Keys().Delete(context.Background(), created.Key.ID))
+	_, err = eng.ImportKeyBundle(context.Background(), b, keysmith.ImportOptions{})
+	assert.ErrorIs(t, err, keysmith.ErrHashTombstoned, "a deleted key cannot be imported back")
+	_, err = eng.GetKey(testCtx(), created.Key.ID)
+	assert.Error(t, err)
+}
+
+func TestHashTombstone_RefusesRestore(t *testing.T) {
+	eng := newTestEngine(t)
+	created := keysmithtest.NewKey(eng).MustCreate(t, testCtx())
+	before := snapshotBytes(t, eng, keysmith.SnapshotOptions{})
+	compromise(t, eng, created.Key.ID, key.CompromiseRevoke)
+	after := snapshotBytes(t, eng, keysmith.SnapshotOptions{})
+
+	overwrite := keysmith.RestoreOptions{Conflict: keysmith.RestoreOverwrite}
+	_, err := eng.Restore(context.Background(), bytes.NewReader(before), overwrite)
+	assert.ErrorIs(t, err, keysmith.ErrHashTombstoned)
+	_, err = eng.Restore(keysmith.WithDryRun(context.Background()), bytes.NewReader(before), overwrite)
+	assert.ErrorIs(t, err, keysmith.ErrHashTombstoned, "a dry run reports the refusal")
+	keysmithtest.AssertRejectedWith(t, eng, testCtx(), created.RawKey, keysmith.ErrKeyInactive)
+
+	_, err = eng.Restore(context.Background(), bytes.NewReader(after), overwrite)
+	require.NoError(t, err, "a revoked key restores")
+}
+
+func TestHashTombstone_Retention(t *testing.T) {
+	clock := &fakeClock{t: time.Now()}
+	forever, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithClock(clock.Now))
+	require.NoError(t, err)
+	limited, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithClock(clock.Now),
+		keysmith.WithHashTombstoneRetention(24*time.Hour))
+	require.NoError(t, err)
+	for _, eng := range []*keysmith.Engine{forever, limited} {
+		created := keysmithtest.NewKey(eng).MustCreate(t, testCtx())
+		compromise(t, eng, created.Key.ID, key.CompromiseRevoke)
+	}
+
+	clock.Set(clock.Now().Add(48 * time.Hour))
+	n, err := forever.PurgeHashTombstones(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n, "tombstones are kept forever by default")
+	n, err = limited.PurgeHashTombstones(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	assert.Empty(t, tombstoneReasons(t, limited))
+}
@@ -0,0 +1,83 @@
+package keysmith_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+// panickingHook panics on every key lifecycle event it handles.
+type panickingHook struct{}
+
+func (panickingHook) Name() string { return "panicking-hook" }
+
+func (panickingHook) OnKeyCreated(_ context.Context, _ *key.Key) error { panic("key created") }
+
+func (panickingHook) OnKeyValidated(_ context.Context, _ *key.Key) error { panic("key validated") }
+
+// blockingHook blocks key creation until its context is cancelled.
+type blockingHook struct{}
+
+func (blockingHook) Name() string { return "blocking-hook" }
+
+func (blockingHook) OnKeyCreated(ctx context.Context, _ *key.Key) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// countingHook counts the KeyCreated and KeyValidated calls it sees.
+type countingHook struct {
+	created   atomic.Int32
+	validated atomic.Int32
+}
+
+func (*countingHook) Name() string { return "counting-hook" }
+
+func (h *countingHook) OnKeyCreated(_ context.Context, _ *key.Key) error {
+	h.created.Add(1)
+	return nil
+}
+
+func (h *countingHook) OnKeyValidated(_ context.Context, _ *key.Key) error {
+	h.validated.Add(1)
+	return nil
+}
+
+func TestHooks_MisbehavingPluginsDoNotBreakEngineCalls(t *testing.T) {
+	counter := &countingHook{}
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithHookTimeout(50*time.Millisecond),
+		keysmith.WithExtension(panickingHook{}),
+		keysmith.WithExtension(blockingHook{}),
+		keysmith.WithExtension(counter),
+	)
+	require.NoError(t, err)
+
+	start := time.Now()
+	var created *key.CreateResult
+	require.NotPanics(t, func() {
+		created, err = eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+			Name:        "Guarded",
+			Prefix:      "sk",
+			Environment: key.EnvTest,
+		})
+	})
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), time.Second, "the blocking hook is abandoned at the timeout")
+	assert.Equal(t, int32(1), counter.created.Load(), "plugins after the failing ones still run")
+
+	require.NotPanics(t, func() {
+		_, err = eng.ValidateKey(testCtx(), created.RawKey)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), counter.validated.Load())
+}
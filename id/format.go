@@ -0,0 +1,203 @@
+package id
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"go.jetify.com/typeid/v2"
+)
+
+// Format is a wire format for IDs. It controls how String, MarshalText,
+// MarshalBSONValue, and Value render an ID; parsing accepts every format
+// regardless of the one configured. The internal representation is always
+// a TypeID, so the entity prefix is kept even when a format omits it.
+type Format uint8
+
+// Supported ID formats.
+const (
+	// FormatTypeID renders "akey_01h2xcejqtf2nbrexx3vqjhp41". This is the
+	// default.
+	FormatTypeID Format = iota
+
+	// FormatUUID renders the bare UUID, "01890a5d-ac96-774b-bcce-b302099a8057",
+	// for columns and joins that expect UUIDs. The prefix is dropped on
+	// output and inferred from the entity type on input.
+	FormatUUID
+
+	// FormatPrefixedUUID renders the prefix and the UUID,
+	// "akey_01890a5d-ac96-774b-bcce-b302099a8057".
+	FormatPrefixedUUID
+)
+
+// uuidLen is the length of a canonical hyphenated UUID.
+const uuidLen = 36
+
+var current atomic.Uint32
+
+// SetFormat sets the format IDs are rendered in, process-wide. Set it once
+// at startup, before IDs are stored or used as map keys: String changes
+// with it. keysmith.WithIDFormat calls it.
+func SetFormat(f Format) error {
+	if !f.Valid() {
+		return fmt.Errorf("id: unknown format %d", f)
+	}
+	current.Store(uint32(f))
+	return nil
+}
+
+// CurrentFormat returns the format set by SetFormat.
+func CurrentFormat() Format { return Format(current.Load()) } //nolint:gosec // only valid formats are stored
+
+// ParseFormat parses a format name as returned by Format.String.
+func ParseFormat(s string) (Format, error) {
+	for _, f := range []Format{FormatTypeID, FormatUUID, FormatPrefixedUUID} {
+		if f.String() == s {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("id: unknown format %q", s)
+}
+
+// Valid reports whether f is a supported format.
+func (f Format) Valid() bool { return f <= FormatPrefixedUUID }
+
+// String returns the format's name: "typeid", "uuid", or "prefixed_uuid".
+func (f Format) String() string {
+	switch f {
+	case FormatTypeID:
+		return "typeid"
+	case FormatUUID:
+		return "uuid"
+	case FormatPrefixedUUID:
+		return "prefixed_uuid"
+	default:
+		return fmt.Sprintf("Format(%d)", f)
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler using the format's name.
+func (f Format) MarshalText() ([]byte, error) {
+	if !f.Valid() {
+		return nil, fmt.Errorf("id: unknown format %d", f)
+	}
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (f *Format) UnmarshalText(data []byte) error {
+	parsed, err := ParseFormat(string(data))
+	if err != nil {
+		return err
+	}
+	*f = parsed
+	return nil
+}
+
+// Encode renders i in format f. It returns an empty string for the Nil ID.
+func (f Format) Encode(i ID) string {
+	if !i.valid {
+		return ""
+	}
+	switch f {
+	case FormatUUID:
+		return i.inner.UUID()
+	case FormatPrefixedUUID:
+		if i.inner.Prefix() == "" {
+			return i.inner.UUID()
+		}
+		return i.inner.Prefix() + "_" + i.inner.UUID()
+	default:
+		return i.inner.String()
+	}
+}
+
+// Decode parses s in format f only. A bare UUID takes expected as its
+// prefix; for the other formats expected is checked when non-empty.
+func (f Format) Decode(s string, expected Prefix) (ID, error) {
+	var (
+		tid typeid.TypeID
+		err error
+	)
+	switch f {
+	case FormatUUID:
+		if !isUUID(s) {
+			return Nil, fmt.Errorf("id: parse %q: not a UUID", s)
+		}
+		tid, err = typeid.FromUUID(string(expected), s)
+	case FormatPrefixedUUID:
+		sep := strings.LastIndexByte(s, '_')
+		if sep <= 0 || !isUUID(s[sep+1:]) {
+			return Nil, fmt.Errorf("id: parse %q: not a prefixed UUID", s)
+		}
+		tid, err = typeid.FromUUID(s[:sep], s[sep+1:])
+	case FormatTypeID:
+		tid, err = typeid.Parse(s)
+	default:
+		return Nil, fmt.Errorf("id: unknown format %d", f)
+	}
+	if err != nil {
+		return Nil, fmt.Errorf("id: parse %q: %w", s, err)
+	}
+	if expected != "" && Prefix(tid.Prefix()) != expected {
+		return Nil, fmt.Errorf("id: expected prefix %q, got %q", expected, tid.Prefix())
+	}
+	return ID{inner: tid, valid: true}, nil
+}
+
+// decode parses s in the configured format, falling back to the others so
+// that values written before a format change still parse.
+func decode(s string, expected Prefix) (ID, error) {
+	if s == "" {
+		return Nil, fmt.Errorf("id: parse %q: empty string", s)
+	}
+	f := detect(s)
+	if f == FormatUUID && expected == "" && CurrentFormat() != FormatUUID {
+		return Nil, fmt.Errorf("id: parse %q: bare UUID without an entity prefix", s)
+	}
+	return f.Decode(s, expected)
+}
+
+// detect reports which format s is written in. The formats do not overlap:
+// a TypeID suffix is 26 characters and never contains a hyphen.
+func detect(s string) Format {
+	if isUUID(s) {
+		return FormatUUID
+	}
+	if sep := strings.LastIndexByte(s, '_'); sep > 0 && isUUID(s[sep+1:]) {
+		return FormatPrefixedUUID
+	}
+	return FormatTypeID
+}
+
+// isUUID reports whether s is a canonical hyphenated UUID.
+func isUUID(s string) bool {
+	if len(s) != uuidLen {
+		return false
+	}
+	for i := range len(s) {
+		c := s[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Reformat rewrites s, an ID of the entity with prefix p written in any
+// format, into format to. It is used to migrate stored IDs between
+// formats; see store.IDMigrator.
+func Reformat(s string, p Prefix, to Format) (string, error) {
+	parsed, err := ParseWithPrefix(s, p)
+	if err != nil {
+		return "", err
+	}
+	return to.Encode(parsed), nil
+}
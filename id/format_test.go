@@ -0,0 +1,257 @@
+package id_test
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/xraph/keysmith/id"
+)
+
+var (
+	formats = []id.Format{id.FormatTypeID, id.FormatUUID, id.FormatPrefixedUUID}
+
+	uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+	entities = []struct {
+		prefix  id.Prefix
+		newFn   func() id.ID
+		parseFn func(string) (id.ID, error)
+	}{
+		{id.PrefixKey, untypedNew(id.NewKeyID), untypedParse(id.ParseKeyID)},
+		{id.PrefixPolicy, untypedNew(id.NewPolicyID), untypedParse(id.ParsePolicyID)},
+		{id.PrefixUsage, untypedNew(id.NewUsageID), untypedParse(id.ParseUsageID)},
+		{id.PrefixRotation, untypedNew(id.NewRotationID), untypedParse(id.ParseRotationID)},
+		{id.PrefixScope, untypedNew(id.NewScopeID), untypedParse(id.ParseScopeID)},
+		{id.PrefixCapture, untypedNew(id.NewCaptureID), untypedParse(id.ParseCaptureID)},
+		{id.PrefixGroup, untypedNew(id.NewGroupID), untypedParse(id.ParseGroupID)},
+		{id.PrefixAudit, untypedNew(id.NewAuditEventID), untypedParse(id.ParseAuditEventID)},
+		{id.PrefixEvent, untypedNew(id.NewEventID), untypedParse(id.ParseEventID)},
+		{id.PrefixTransfer, untypedNew(id.NewTransferID), untypedParse(id.ParseTransferID)},
+		{id.PrefixQuotaPool, untypedNew(id.NewQuotaPoolID), untypedParse(id.ParseQuotaPoolID)},
+	}
+)
+
+// useFormat sets the ID format for the rest of the test.
+func useFormat(t *testing.T, f id.Format) {
+	t.Helper()
+	if err := id.SetFormat(f); err != nil {
+		t.Fatalf("SetFormat(%s): %v", f, err)
+	}
+	t.Cleanup(func() { _ = id.SetFormat(id.FormatTypeID) })
+}
+
+func sameID(t *testing.T, got, want id.ID) {
+	t.Helper()
+	if got.Prefix() != want.Prefix() || got.UUID() != want.UUID() {
+		t.Errorf("got %s/%s, want %s/%s", got.Prefix(), got.UUID(), want.Prefix(), want.UUID())
+	}
+}
+
+func TestFormatEncode(t *testing.T) {
+	for _, e := range entities {
+		i := e.newFn()
+		p := string(e.prefix)
+
+		typeID := id.FormatTypeID.Encode(i)
+		if !strings.HasPrefix(typeID, p+"_") || len(typeID) != len(p)+27 {
+			t.Errorf("typeid %q", typeID)
+		}
+		if got := id.FormatUUID.Encode(i); !uuidPattern.MatchString(got) || got != i.UUID() {
+			t.Errorf("uuid %q", got)
+		}
+		prefixed := id.FormatPrefixedUUID.Encode(i)
+		rest, ok := strings.CutPrefix(prefixed, p+"_")
+		if !ok || !uuidPattern.MatchString(rest) {
+			t.Errorf("prefixed uuid %q", prefixed)
+		}
+		for _, f := range formats {
+			if f.Encode(id.Nil) != "" {
+				t.Errorf("%s: Nil should encode empty", f)
+			}
+		}
+	}
+}
+
+// TestFormatRoundTrip writes every entity's ID in each format and parses it
+// back with the typed parser under every configured format, as happens while
+// stored values are migrated from one format to another.
+func TestFormatRoundTrip(t *testing.T) {
+	for _, written := range formats {
+		for _, configured := range formats {
+			t.Run(written.String()+"/"+configured.String(), func(t *testing.T) {
+				useFormat(t, configured)
+				for _, e := range entities {
+					original := e.newFn()
+					parsed, err := e.parseFn(written.Encode(original))
+					if err != nil {
+						t.Fatalf("%s: %v", e.prefix, err)
+					}
+					sameID(t, parsed, original)
+					if parsed.String() != configured.Encode(original) {
+						t.Errorf("%s: String() = %q", e.prefix, parsed.String())
+					}
+
+					decoded, err := written.Decode(written.Encode(original), e.prefix)
+					if err != nil {
+						t.Fatalf("%s: Decode: %v", e.prefix, err)
+					}
+					sameID(t, decoded, original)
+				}
+			})
+		}
+	}
+}
+
+func TestFormatPrefixValidation(t *testing.T) {
+	for _, configured := range formats {
+		useFormat(t, configured)
+		for _, written := range []id.Format{id.FormatTypeID, id.FormatPrefixedUUID} {
+			s := written.Encode(id.NewPolicyID().ID)
+			if _, err := id.ParseKeyID(s); err == nil {
+				t.Errorf("%s/%s: ParseKeyID accepted %q", configured, written, s)
+			}
+			if _, err := written.Decode(s, id.PrefixKey); err == nil {
+				t.Errorf("%s/%s: Decode accepted %q", configured, written, s)
+			}
+		}
+	}
+}
+
+func TestFormatBareUUIDInfersPrefix(t *testing.T) {
+	original := id.NewPolicyID()
+	parsed, err := id.ParseKeyID(original.UUID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Prefix() != id.PrefixKey {
+		t.Errorf("prefix %q, want the parser's", parsed.Prefix())
+	}
+
+	if _, err := id.Parse(original.UUID()); err == nil {
+		t.Error("Parse should reject a bare UUID outside FormatUUID")
+	}
+	useFormat(t, id.FormatUUID)
+	untyped, err := id.Parse(original.UUID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if untyped.Prefix() != "" || untyped.String() != original.String() {
+		t.Errorf("got %q with prefix %q", untyped.String(), untyped.Prefix())
+	}
+}
+
+func TestFormatRejectsMalformed(t *testing.T) {
+	for _, s := range []string{
+		"akey_01890a5d-ac96-774b-bcce-b302099a805",  // short UUID
+		"akey_01890a5dxac96-774b-bcce-b302099a8057", // bad separator
+		"01890a5d-ac96-774b-bcce-b302099a805g",      // non-hex
+		"_01890a5d-ac96-774b-bcce-b302099a8057",     // empty prefix
+		"AKEY_01890a5d-ac96-774b-bcce-b302099a8057", // invalid prefix
+	} {
+		if _, err := id.ParseKeyID(s); err == nil {
+			t.Errorf("ParseKeyID accepted %q", s)
+		}
+	}
+}
+
+func TestFormatEncodings(t *testing.T) {
+	type doc struct {
+		ID id.ID `json:"id"`
+	}
+	for _, configured := range formats {
+		t.Run(configured.String(), func(t *testing.T) {
+			useFormat(t, configured)
+			original := id.NewKeyID()
+			want := configured.Encode(original.ID)
+
+			data, err := json.Marshal(doc{ID: original.ID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != `{"id":"`+want+`"}` {
+				t.Errorf("json %s", data)
+			}
+			var restored doc
+			if err := json.Unmarshal(data, &restored); err != nil {
+				t.Fatal(err)
+			}
+			if restored.ID.String() != want {
+				t.Errorf("json round-trip %q", restored.ID.String())
+			}
+
+			val, err := original.Value()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if val != want {
+				t.Errorf("Value() = %v", val)
+			}
+			for _, written := range formats {
+				var typed id.KeyID
+				if err := typed.Scan(written.Encode(original.ID)); err != nil {
+					t.Fatalf("Scan %s into KeyID: %v", written, err)
+				}
+				if typed != original {
+					t.Errorf("Scan %s into KeyID: %q", written, typed.UUID())
+				}
+				var scanned id.ID
+				if err := scanned.Scan(written.Encode(original.ID)); err != nil {
+					if written == id.FormatUUID && configured != id.FormatUUID {
+						continue // a bare UUID only scans into an untyped ID under FormatUUID
+					}
+					t.Fatalf("Scan %s: %v", written, err)
+				}
+				if scanned.UUID() != original.UUID() {
+					t.Errorf("Scan %s: %q", written, scanned.UUID())
+				}
+			}
+
+			bsonType, raw, err := original.MarshalBSONValue()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fromBSON id.ID
+			if err := fromBSON.UnmarshalBSONValue(bsonType, raw); err != nil {
+				t.Fatal(err)
+			}
+			if fromBSON.String() != want {
+				t.Errorf("bson round-trip %q", fromBSON.String())
+			}
+		})
+	}
+}
+
+func TestReformat(t *testing.T) {
+	original := id.NewScopeID().ID
+	for _, from := range formats {
+		for _, to := range formats {
+			got, err := id.Reformat(from.Encode(original), id.PrefixScope, to)
+			if err != nil {
+				t.Fatalf("%s -> %s: %v", from, to, err)
+			}
+			if got != to.Encode(original) {
+				t.Errorf("%s -> %s: %q", from, to, got)
+			}
+		}
+	}
+	if _, err := id.Reformat(id.NewKeyID().String(), id.PrefixScope, id.FormatUUID); err == nil {
+		t.Error("Reformat should validate the prefix")
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, f := range formats {
+		got, err := id.ParseFormat(f.String())
+		if err != nil || got != f {
+			t.Errorf("ParseFormat(%q) = %v, %v", f.String(), got, err)
+		}
+	}
+	if _, err := id.ParseFormat("ulid"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+	if err := id.SetFormat(id.Format(9)); err == nil {
+		t.Error("SetFormat should reject unknown formats")
+	}
+}
@@ -1,8 +1,14 @@
 // Package id defines TypeID-based identity types for all Keysmith entities.
 //
-// Every entity in Keysmith uses a single ID struct with a prefix that identifies
-// the entity type. IDs are K-sortable (UUIDv7-based), globally unique,
+// Every entity in Keysmith has its own ID type, such as KeyID or PolicyID,
+// wrapping a single ID struct with a prefix that identifies the entity type.
+// The types are distinct, so passing a PolicyID where a KeyID is expected
+// does not compile. IDs are K-sortable (UUIDv7-based), globally unique,
 // and URL-safe in the format "prefix_suffix".
+//
+// For compatibility with infrastructure that stores UUIDs, IDs can instead
+// be rendered as bare or prefixed UUIDs with SetFormat. Parsing accepts
+// every format, so stored values keep working while they are migrated.
 package id
 
 import (
@@ -24,11 +30,17 @@ type Prefix string
 
 // Prefix constants for all Keysmith entity types.
 const (
-	PrefixKey      Prefix = "akey"
-	PrefixPolicy   Prefix = "kpol"
-	PrefixUsage    Prefix = "kusg"
-	PrefixRotation Prefix = "krot"
-	PrefixScope    Prefix = "kscp"
+	PrefixKey       Prefix = "akey"
+	PrefixPolicy    Prefix = "kpol"
+	PrefixUsage     Prefix = "kusg"
+	PrefixRotation  Prefix = "krot"
+	PrefixScope     Prefix = "kscp"
+	PrefixCapture   Prefix = "kcap"
+	PrefixGroup     Prefix = "kgrp"
+	PrefixAudit     Prefix = "kaud"
+	PrefixEvent     Prefix = "kevt"
+	PrefixTransfer  Prefix = "kxfr"
+	PrefixQuotaPool Prefix = "kqpl"
 )
 
 // ID is the primary identifier type for all Keysmith entities.
@@ -56,33 +68,18 @@ func New(prefix Prefix) ID {
 }
 
 // Parse parses a TypeID string (e.g., "akey_01h2xcejqtf2nbrexx3vqjhp41")
-// into an ID. Returns an error if the string is not valid.
+// or a prefixed UUID into an ID. Returns an error if the string is not
+// valid. A bare UUID carries no prefix, so Parse accepts one only under
+// FormatUUID, returning an ID without a prefix; use ParseWithPrefix or
+// the typed parsers to infer it.
 func Parse(s string) (ID, error) {
-	if s == "" {
-		return Nil, fmt.Errorf("id: parse %q: empty string", s)
-	}
-
-	tid, err := typeid.Parse(s)
-	if err != nil {
-		return Nil, fmt.Errorf("id: parse %q: %w", s, err)
-	}
-
-	return ID{inner: tid, valid: true}, nil
+	return decode(s, "")
 }
 
-// ParseWithPrefix parses a TypeID string and validates that its prefix
-// matches the expected value.
+// ParseWithPrefix parses an ID in any format and validates that its prefix
+// matches the expected value. A bare UUID is given the expected prefix.
 func ParseWithPrefix(s string, expected Prefix) (ID, error) {
-	parsed, err := Parse(s)
-	if err != nil {
-		return Nil, err
-	}
-
-	if parsed.Prefix() != expected {
-		return Nil, fmt.Errorf("id: expected prefix %q, got %q", expected, parsed.Prefix())
-	}
-
-	return parsed, nil
+	return decode(s, expected)
 }
 
 // MustParse is like Parse but panics on error. Use for hardcoded ID values.
@@ -105,81 +102,102 @@ func MustParseWithPrefix(s string, expected Prefix) ID {
 	return parsed
 }
 
-// ──────────────────────────────────────────────────
-// Type aliases for backward compatibility
-// ──────────────────────────────────────────────────
-
-// KeyID is a type-safe identifier for API keys (prefix: "akey").
-type KeyID = ID
-
-// PolicyID is a type-safe identifier for key policies (prefix: "kpol").
-type PolicyID = ID
-
-// UsageID is a type-safe identifier for usage records (prefix: "kusg").
-type UsageID = ID
-
-// RotationID is a type-safe identifier for rotation records (prefix: "krot").
-type RotationID = ID
-
-// ScopeID is a type-safe identifier for key scopes (prefix: "kscp").
-type ScopeID = ID
-
-// AnyID is a type alias that accepts any valid prefix.
-type AnyID = ID
-
 // ──────────────────────────────────────────────────
 // Convenience constructors
 // ──────────────────────────────────────────────────
 
 // NewKeyID generates a new unique API key ID.
-func NewKeyID() ID { return New(PrefixKey) }
+func NewKeyID() KeyID { return KeyID{New(PrefixKey)} }
 
 // NewPolicyID generates a new unique policy ID.
-func NewPolicyID() ID { return New(PrefixPolicy) }
+func NewPolicyID() PolicyID { return PolicyID{New(PrefixPolicy)} }
 
 // NewUsageID generates a new unique usage ID.
-func NewUsageID() ID { return New(PrefixUsage) }
+func NewUsageID() UsageID { return UsageID{New(PrefixUsage)} }
 
 // NewRotationID generates a new unique rotation ID.
-func NewRotationID() ID { return New(PrefixRotation) }
+func NewRotationID() RotationID { return RotationID{New(PrefixRotation)} }
 
 // NewScopeID generates a new unique scope ID.
-func NewScopeID() ID { return New(PrefixScope) }
+func NewScopeID() ScopeID { return ScopeID{New(PrefixScope)} }
+
+// NewCaptureID generates a new unique debug capture ID.
+func NewCaptureID() CaptureID { return CaptureID{New(PrefixCapture)} }
+
+// NewGroupID generates a new unique key group ID.
+func NewGroupID() GroupID { return GroupID{New(PrefixGroup)} }
+
+// NewAuditEventID generates a new unique audit event ID.
+func NewAuditEventID() AuditEventID { return AuditEventID{New(PrefixAudit)} }
+
+// NewEventID generates a new unique lifecycle event ID.
+func NewEventID() EventID { return EventID{New(PrefixEvent)} }
+
+// NewTransferID generates a new unique key transfer ID.
+func NewTransferID() TransferID { return TransferID{New(PrefixTransfer)} }
+
+// NewQuotaPoolID generates a new unique quota pool ID.
+func NewQuotaPoolID() QuotaPoolID { return QuotaPoolID{New(PrefixQuotaPool)} }
 
 // ──────────────────────────────────────────────────
 // Convenience parsers
 // ──────────────────────────────────────────────────
 
 // ParseKeyID parses a string and validates the "akey" prefix.
-func ParseKeyID(s string) (ID, error) { return ParseWithPrefix(s, PrefixKey) }
+func ParseKeyID(s string) (KeyID, error) { return parseAs[KeyID](s) }
 
 // ParsePolicyID parses a string and validates the "kpol" prefix.
-func ParsePolicyID(s string) (ID, error) { return ParseWithPrefix(s, PrefixPolicy) }
+func ParsePolicyID(s string) (PolicyID, error) { return parseAs[PolicyID](s) }
 
 // ParseUsageID parses a string and validates the "kusg" prefix.
-func ParseUsageID(s string) (ID, error) { return ParseWithPrefix(s, PrefixUsage) }
+func ParseUsageID(s string) (UsageID, error) { return parseAs[UsageID](s) }
 
 // ParseRotationID parses a string and validates the "krot" prefix.
-func ParseRotationID(s string) (ID, error) { return ParseWithPrefix(s, PrefixRotation) }
+func ParseRotationID(s string) (RotationID, error) { return parseAs[RotationID](s) }
 
 // ParseScopeID parses a string and validates the "kscp" prefix.
-func ParseScopeID(s string) (ID, error) { return ParseWithPrefix(s, PrefixScope) }
+func ParseScopeID(s string) (ScopeID, error) { return parseAs[ScopeID](s) }
+
+// ParseCaptureID parses a string and validates the "kcap" prefix.
+func ParseCaptureID(s string) (CaptureID, error) { return parseAs[CaptureID](s) }
+
+// ParseGroupID parses a string and validates the "kgrp" prefix.
+func ParseGroupID(s string) (GroupID, error) { return parseAs[GroupID](s) }
+
+// ParseAuditEventID parses a string and validates the "kaud" prefix.
+func ParseAuditEventID(s string) (AuditEventID, error) { return parseAs[AuditEventID](s) }
+
+// ParseEventID parses a string and validates the "kevt" prefix.
+func ParseEventID(s string) (EventID, error) { return parseAs[EventID](s) }
+
+// ParseTransferID parses a string and validates the "kxfr" prefix.
+func ParseTransferID(s string) (TransferID, error) { return parseAs[TransferID](s) }
+
+// ParseQuotaPoolID parses a string and validates the "kqpl" prefix.
+func ParseQuotaPoolID(s string) (QuotaPoolID, error) { return parseAs[QuotaPoolID](s) }
 
 // ParseAny parses a string into an ID without type checking the prefix.
-func ParseAny(s string) (ID, error) { return Parse(s) }
+func ParseAny(s string) (AnyID, error) { return Parse(s) }
 
 // ──────────────────────────────────────────────────
 // ID methods
 // ──────────────────────────────────────────────────
 
-// String returns the full TypeID string representation (prefix_suffix).
+// String returns the ID in the configured format, by default the full
+// TypeID string representation (prefix_suffix).
 // Returns an empty string for the Nil ID.
 func (i ID) String() string {
+	return CurrentFormat().Encode(i)
+}
+
+// UUID returns the ID's UUID in canonical hyphenated form, regardless of
+// the configured format. Returns an empty string for the Nil ID.
+func (i ID) UUID() string {
 	if !i.valid {
 		return ""
 	}
 
-	return i.inner.String()
+	return i.inner.UUID()
 }
 
 // Prefix returns the prefix component of this ID.
@@ -202,18 +220,24 @@ func (i ID) MarshalText() ([]byte, error) {
 		return []byte{}, nil
 	}
 
-	return []byte(i.inner.String()), nil
+	return []byte(i.String()), nil
 }
 
 // UnmarshalText implements encoding.TextUnmarshaler.
 func (i *ID) UnmarshalText(data []byte) error {
+	return i.unmarshalText(data, "")
+}
+
+// unmarshalText is UnmarshalText checking the prefix against expected when
+// it is non-empty.
+func (i *ID) unmarshalText(data []byte, expected Prefix) error {
 	if len(data) == 0 {
 		*i = Nil
 
 		return nil
 	}
 
-	parsed, err := Parse(string(data))
+	parsed, err := decode(string(data), expected)
 	if err != nil {
 		return err
 	}
@@ -231,11 +255,11 @@ func (i ID) MarshalBSONValue() (bsonType byte, data []byte, err error) {
 		return bsonTypeNull, nil, nil
 	}
 
-	s := i.inner.String()
+	s := i.String()
 	l := len(s) + 1 // length includes null terminator
 
 	buf := make([]byte, 4+len(s)+1)
-	binary.LittleEndian.PutUint32(buf, uint32(l)) //nolint:gosec // TypeID strings are <64 bytes; no overflow
+	binary.LittleEndian.PutUint32(buf, uint32(l)) //nolint:gosec // ID strings are <100 bytes; no overflow
 	copy(buf[4:], s)
 	// trailing 0x00 is already zero from make
 
@@ -244,6 +268,10 @@ func (i ID) MarshalBSONValue() (bsonType byte, data []byte, err error) {
 
 // UnmarshalBSONValue satisfies bson.ValueUnmarshaler (mongo-driver v2).
 func (i *ID) UnmarshalBSONValue(t byte, data []byte) error {
+	return i.unmarshalBSONValue(t, data, "")
+}
+
+func (i *ID) unmarshalBSONValue(t byte, data []byte, expected Prefix) error {
 	if t == bsonTypeNull {
 		*i = Nil
 
@@ -269,7 +297,7 @@ func (i *ID) UnmarshalBSONValue(t byte, data []byte) error {
 
 	s := string(data[4 : 4+l-1]) // exclude null terminator
 
-	return i.UnmarshalText([]byte(s))
+	return i.unmarshalText([]byte(s), expected)
 }
 
 // Value implements driver.Valuer for database storage.
@@ -279,11 +307,15 @@ func (i ID) Value() (driver.Value, error) {
 		return nil, nil //nolint:nilnil // nil is the canonical NULL for driver.Valuer
 	}
 
-	return i.inner.String(), nil
+	return i.String(), nil
 }
 
 // Scan implements sql.Scanner for database retrieval.
 func (i *ID) Scan(src any) error {
+	return i.scan(src, "")
+}
+
+func (i *ID) scan(src any, expected Prefix) error {
 	if src == nil {
 		*i = Nil
 
@@ -298,7 +330,7 @@ func (i *ID) Scan(src any) error {
 			return nil
 		}
 
-		return i.UnmarshalText([]byte(v))
+		return i.unmarshalText([]byte(v), expected)
 	case []byte:
 		if len(v) == 0 {
 			*i = Nil
@@ -306,7 +338,7 @@ func (i *ID) Scan(src any) error {
 			return nil
 		}
 
-		return i.UnmarshalText(v)
+		return i.unmarshalText(v, expected)
 	default:
 		return fmt.Errorf("id: cannot scan %T into ID", src)
 	}
@@ -13,11 +13,16 @@ func TestConstructors(t *testing.T) {
 		newFn  func() id.ID
 		prefix string
 	}{
-		{"KeyID", id.NewKeyID, "akey_"},
-		{"PolicyID", id.NewPolicyID, "kpol_"},
-		{"UsageID", id.NewUsageID, "kusg_"},
-		{"RotationID", id.NewRotationID, "krot_"},
-		{"ScopeID", id.NewScopeID, "kscp_"},
+		{"KeyID", untypedNew(id.NewKeyID), "akey_"},
+		{"PolicyID", untypedNew(id.NewPolicyID), "kpol_"},
+		{"UsageID", untypedNew(id.NewUsageID), "kusg_"},
+		{"RotationID", untypedNew(id.NewRotationID), "krot_"},
+		{"ScopeID", untypedNew(id.NewScopeID), "kscp_"},
+		{"CaptureID", untypedNew(id.NewCaptureID), "kcap_"},
+		{"GroupID", untypedNew(id.NewGroupID), "kgrp_"},
+		{"AuditEventID", untypedNew(id.NewAuditEventID), "kaud_"},
+		{"TransferID", untypedNew(id.NewTransferID), "kxfr_"},
+		{"QuotaPoolID", untypedNew(id.NewQuotaPoolID), "kqpl_"},
 	}
 
 	for _, tt := range tests {
@@ -46,11 +51,11 @@ func TestParseRoundTrip(t *testing.T) {
 		newFn   func() id.ID
 		parseFn func(string) (id.ID, error)
 	}{
-		{"KeyID", id.NewKeyID, id.ParseKeyID},
-		{"PolicyID", id.NewPolicyID, id.ParsePolicyID},
-		{"UsageID", id.NewUsageID, id.ParseUsageID},
-		{"RotationID", id.NewRotationID, id.ParseRotationID},
-		{"ScopeID", id.NewScopeID, id.ParseScopeID},
+		{"KeyID", untypedNew(id.NewKeyID), untypedParse(id.ParseKeyID)},
+		{"PolicyID", untypedNew(id.NewPolicyID), untypedParse(id.ParsePolicyID)},
+		{"UsageID", untypedNew(id.NewUsageID), untypedParse(id.ParseUsageID)},
+		{"RotationID", untypedNew(id.NewRotationID), untypedParse(id.ParseRotationID)},
+		{"ScopeID", untypedNew(id.NewScopeID), untypedParse(id.ParseScopeID)},
 	}
 
 	for _, tt := range tests {
@@ -73,11 +78,11 @@ func TestCrossTypeRejection(t *testing.T) {
 		input   string
 		parseFn func(string) (id.ID, error)
 	}{
-		{"ParseKeyID rejects kpol_", id.NewPolicyID().String(), id.ParseKeyID},
-		{"ParsePolicyID rejects kusg_", id.NewUsageID().String(), id.ParsePolicyID},
-		{"ParseUsageID rejects krot_", id.NewRotationID().String(), id.ParseUsageID},
-		{"ParseRotationID rejects kscp_", id.NewScopeID().String(), id.ParseRotationID},
-		{"ParseScopeID rejects akey_", id.NewKeyID().String(), id.ParseScopeID},
+		{"ParseKeyID rejects kpol_", id.NewPolicyID().String(), untypedParse(id.ParseKeyID)},
+		{"ParsePolicyID rejects kusg_", id.NewUsageID().String(), untypedParse(id.ParsePolicyID)},
+		{"ParseUsageID rejects krot_", id.NewRotationID().String(), untypedParse(id.ParseUsageID)},
+		{"ParseRotationID rejects kscp_", id.NewScopeID().String(), untypedParse(id.ParseRotationID)},
+		{"ParseScopeID rejects akey_", id.NewKeyID().String(), untypedParse(id.ParseScopeID)},
 	}
 
 	for _, tt := range tests {
@@ -92,11 +97,11 @@ func TestCrossTypeRejection(t *testing.T) {
 
 func TestParseAny(t *testing.T) {
 	ids := []id.ID{
-		id.NewKeyID(),
-		id.NewPolicyID(),
-		id.NewUsageID(),
-		id.NewRotationID(),
-		id.NewScopeID(),
+		id.NewKeyID().ID,
+		id.NewPolicyID().ID,
+		id.NewUsageID().ID,
+		id.NewRotationID().ID,
+		id.NewScopeID().ID,
 	}
 
 	for _, i := range ids {
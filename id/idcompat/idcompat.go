@@ -0,0 +1,190 @@
+// Package idcompat keeps the untyped entity IDs of earlier releases, in
+// which id.KeyID, id.PolicyID, and the others were aliases of id.ID, for
+// code that has not moved to the typed IDs yet. Change the import from id
+// to idcompat to keep such code compiling, then convert where it calls
+// keysmith with id.Convert:
+//
+//	keyID, err := id.Convert[id.KeyID](legacyID)
+//
+// Deprecated: use the typed IDs in package id. This package will be removed
+// in the next release.
+package idcompat
+
+import "github.com/xraph/keysmith/id"
+
+// ──────────────────────────────────────────────────
+// Untyped aliases
+// ──────────────────────────────────────────────────
+
+// KeyID is an untyped identifier for API keys.
+//
+// Deprecated: use id.KeyID.
+type KeyID = id.ID
+
+// PolicyID is an untyped identifier for key policies.
+//
+// Deprecated: use id.PolicyID.
+type PolicyID = id.ID
+
+// UsageID is an untyped identifier for usage records.
+//
+// Deprecated: use id.UsageID.
+type UsageID = id.ID
+
+// RotationID is an untyped identifier for rotation records.
+//
+// Deprecated: use id.RotationID.
+type RotationID = id.ID
+
+// ScopeID is an untyped identifier for key scopes.
+//
+// Deprecated: use id.ScopeID.
+type ScopeID = id.ID
+
+// CaptureID is an untyped identifier for debug captures.
+//
+// Deprecated: use id.CaptureID.
+type CaptureID = id.ID
+
+// GroupID is an untyped identifier for key groups.
+//
+// Deprecated: use id.GroupID.
+type GroupID = id.ID
+
+// AuditEventID is an untyped identifier for audit events.
+//
+// Deprecated: use id.AuditEventID.
+type AuditEventID = id.ID
+
+// EventID is an untyped identifier for published lifecycle events.
+//
+// Deprecated: use id.EventID.
+type EventID = id.ID
+
+// TransferID is an untyped identifier for key transfers.
+//
+// Deprecated: use id.TransferID.
+type TransferID = id.ID
+
+// QuotaPoolID is an untyped identifier for quota pools.
+//
+// Deprecated: use id.QuotaPoolID.
+type QuotaPoolID = id.ID
+
+// ──────────────────────────────────────────────────
+// Untyped constructors
+// ──────────────────────────────────────────────────
+
+// NewKeyID generates a new unique ID for API keys.
+//
+// Deprecated: use id.NewKeyID.
+func NewKeyID() id.ID { return id.New(id.PrefixKey) }
+
+// NewPolicyID generates a new unique ID for key policies.
+//
+// Deprecated: use id.NewPolicyID.
+func NewPolicyID() id.ID { return id.New(id.PrefixPolicy) }
+
+// NewUsageID generates a new unique ID for usage records.
+//
+// Deprecated: use id.NewUsageID.
+func NewUsageID() id.ID { return id.New(id.PrefixUsage) }
+
+// NewRotationID generates a new unique ID for rotation records.
+//
+// Deprecated: use id.NewRotationID.
+func NewRotationID() id.ID { return id.New(id.PrefixRotation) }
+
+// NewScopeID generates a new unique ID for key scopes.
+//
+// Deprecated: use id.NewScopeID.
+func NewScopeID() id.ID { return id.New(id.PrefixScope) }
+
+// NewCaptureID generates a new unique ID for debug captures.
+//
+// Deprecated: use id.NewCaptureID.
+func NewCaptureID() id.ID { return id.New(id.PrefixCapture) }
+
+// NewGroupID generates a new unique ID for key groups.
+//
+// Deprecated: use id.NewGroupID.
+func NewGroupID() id.ID { return id.New(id.PrefixGroup) }
+
+// NewAuditEventID generates a new unique ID for audit events.
+//
+// Deprecated: use id.NewAuditEventID.
+func NewAuditEventID() id.ID { return id.New(id.PrefixAudit) }
+
+// NewEventID generates a new unique ID for published lifecycle events.
+//
+// Deprecated: use id.NewEventID.
+func NewEventID() id.ID { return id.New(id.PrefixEvent) }
+
+// NewTransferID generates a new unique ID for key transfers.
+//
+// Deprecated: use id.NewTransferID.
+func NewTransferID() id.ID { return id.New(id.PrefixTransfer) }
+
+// NewQuotaPoolID generates a new unique ID for quota pools.
+//
+// Deprecated: use id.NewQuotaPoolID.
+func NewQuotaPoolID() id.ID { return id.New(id.PrefixQuotaPool) }
+
+// ──────────────────────────────────────────────────
+// Untyped parsers
+// ──────────────────────────────────────────────────
+
+// ParseKeyID parses a string and validates the prefix of API keys.
+//
+// Deprecated: use id.ParseKeyID.
+func ParseKeyID(s string) (id.ID, error) { return id.ParseWithPrefix(s, id.PrefixKey) }
+
+// ParsePolicyID parses a string and validates the prefix of key policies.
+//
+// Deprecated: use id.ParsePolicyID.
+func ParsePolicyID(s string) (id.ID, error) { return id.ParseWithPrefix(s, id.PrefixPolicy) }
+
+// ParseUsageID parses a string and validates the prefix of usage records.
+//
+// Deprecated: use id.ParseUsageID.
+func ParseUsageID(s string) (id.ID, error) { return id.ParseWithPrefix(s, id.PrefixUsage) }
+
+// ParseRotationID parses a string and validates the prefix of rotation records.
+//
+// Deprecated: use id.ParseRotationID.
+func ParseRotationID(s string) (id.ID, error) { return id.ParseWithPrefix(s, id.PrefixRotation) }
+
+// ParseScopeID parses a string and validates the prefix of key scopes.
+//
+// Deprecated: use id.ParseScopeID.
+func ParseScopeID(s string) (id.ID, error) { return id.ParseWithPrefix(s, id.PrefixScope) }
+
+// ParseCaptureID parses a string and validates the prefix of debug captures.
+//
+// Deprecated: use id.ParseCaptureID.
+func ParseCaptureID(s string) (id.ID, error) { return id.ParseWithPrefix(s, id.PrefixCapture) }
+
+// ParseGroupID parses a string and validates the prefix of key groups.
+//
+// Deprecated: use id.ParseGroupID.
+func ParseGroupID(s string) (id.ID, error) { return id.ParseWithPrefix(s, id.PrefixGroup) }
+
+// ParseAuditEventID parses a string and validates the prefix of audit events.
+//
+// Deprecated: use id.ParseAuditEventID.
+func ParseAuditEventID(s string) (id.ID, error) { return id.ParseWithPrefix(s, id.PrefixAudit) }
+
+// ParseEventID parses a string and validates the prefix of published lifecycle events.
+//
+// Deprecated: use id.ParseEventID.
+func ParseEventID(s string) (id.ID, error) { return id.ParseWithPrefix(s, id.PrefixEvent) }
+
+// ParseTransferID parses a string and validates the prefix of key transfers.
+//
+// Deprecated: use id.ParseTransferID.
+func ParseTransferID(s string) (id.ID, error) { return id.ParseWithPrefix(s, id.PrefixTransfer) }
+
+// ParseQuotaPoolID parses a string and validates the prefix of quota pools.
+//
+// Deprecated: use id.ParseQuotaPoolID.
+func ParseQuotaPoolID(s string) (id.ID, error) { return id.ParseWithPrefix(s, id.PrefixQuotaPool) }
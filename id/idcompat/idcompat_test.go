@@ -0,0 +1,27 @@
+package idcompat_test
+
+import (
+	"testing"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/id/idcompat"
+)
+
+func TestUntypedIDsConvert(t *testing.T) {
+	var legacy idcompat.KeyID = idcompat.NewKeyID()
+	parsed, err := idcompat.ParseKeyID(legacy.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := idcompat.ParsePolicyID(legacy.String()); err == nil {
+		t.Error("ParsePolicyID accepted a key ID")
+	}
+
+	keyID, err := id.Convert[id.KeyID](parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyID.ID != legacy {
+		t.Errorf("converted %s, want %s", keyID, legacy)
+	}
+}
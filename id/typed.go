@@ -0,0 +1,261 @@
+package id
+
+import "fmt"
+
+// ──────────────────────────────────────────────────
+// Entity ID types
+// ──────────────────────────────────────────────────
+//
+// Each entity ID type embeds ID, so it has ID's methods, and its embedded
+// ID field is the untyped ID. The types are distinct: assigning one to
+// another does not compile, and converting between them, as in
+// PolicyID(keyID), has to be spelled out. Decoding from text, BSON, or SQL
+// rejects another entity's ID.
+
+// Entity is the set of entity ID types, for code generic over them.
+type Entity interface {
+	KeyID | PolicyID | UsageID | RotationID | ScopeID | CaptureID | GroupID |
+		AuditEventID | EventID | TransferID | QuotaPoolID
+
+	entityPrefix() Prefix
+}
+
+// AnyID is an ID of any entity, as returned by ParseAny.
+type AnyID = ID
+
+// Convert returns i as the entity ID type T. It fails when i has another
+// entity's prefix; an ID without a prefix, parsed from a bare UUID, is given
+// T's. The Nil ID converts to T's zero value.
+func Convert[T Entity](i ID) (T, error) {
+	var zero T
+	if i.IsNil() {
+		return zero, nil
+	}
+	want := zero.entityPrefix()
+	switch i.Prefix() {
+	case want:
+		return T{i}, nil
+	case "":
+		tagged, err := FormatUUID.Decode(i.UUID(), want)
+		if err != nil {
+			return zero, err
+		}
+		return T{tagged}, nil
+	default:
+		return zero, fmt.Errorf("id: expected prefix %q, got %q", want, i.Prefix())
+	}
+}
+
+// parseAs parses s as an ID of the entity type T.
+func parseAs[T Entity](s string) (T, error) {
+	var zero T
+	parsed, err := ParseWithPrefix(s, zero.entityPrefix())
+	if err != nil {
+		return zero, err
+	}
+	return T{parsed}, nil
+}
+
+// KeyID is a type-safe identifier for API keys (prefix: "akey").
+//
+//nolint:recvcheck // Value receivers promoted from ID, pointer receivers for decoding.
+type KeyID struct{ ID }
+
+func (KeyID) entityPrefix() Prefix { return PrefixKey }
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (i *KeyID) UnmarshalText(data []byte) error { return i.ID.unmarshalText(data, PrefixKey) }
+
+// UnmarshalBSONValue satisfies bson.ValueUnmarshaler (mongo-driver v2).
+func (i *KeyID) UnmarshalBSONValue(t byte, data []byte) error {
+	return i.ID.unmarshalBSONValue(t, data, PrefixKey)
+}
+
+// Scan implements sql.Scanner for database retrieval.
+func (i *KeyID) Scan(src any) error { return i.ID.scan(src, PrefixKey) }
+
+// PolicyID is a type-safe identifier for key policies (prefix: "kpol").
+//
+//nolint:recvcheck // Value receivers promoted from ID, pointer receivers for decoding.
+type PolicyID struct{ ID }
+
+func (PolicyID) entityPrefix() Prefix { return PrefixPolicy }
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (i *PolicyID) UnmarshalText(data []byte) error { return i.ID.unmarshalText(data, PrefixPolicy) }
+
+// UnmarshalBSONValue satisfies bson.ValueUnmarshaler (mongo-driver v2).
+func (i *PolicyID) UnmarshalBSONValue(t byte, data []byte) error {
+	return i.ID.unmarshalBSONValue(t, data, PrefixPolicy)
+}
+
+// Scan implements sql.Scanner for database retrieval.
+func (i *PolicyID) Scan(src any) error { return i.ID.scan(src, PrefixPolicy) }
+
+// UsageID is a type-safe identifier for usage records (prefix: "kusg").
+//
+//nolint:recvcheck // Value receivers promoted from ID, pointer receivers for decoding.
+type UsageID struct{ ID }
+
+func (UsageID) entityPrefix() Prefix { return PrefixUsage }
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (i *UsageID) UnmarshalText(data []byte) error { return i.ID.unmarshalText(data, PrefixUsage) }
+
+// UnmarshalBSONValue satisfies bson.ValueUnmarshaler (mongo-driver v2).
+func (i *UsageID) UnmarshalBSONValue(t byte, data []byte) error {
+	return i.ID.unmarshalBSONValue(t, data, PrefixUsage)
+}
+
+// Scan implements sql.Scanner for database retrieval.
+func (i *UsageID) Scan(src any) error { return i.ID.scan(src, PrefixUsage) }
+
+// RotationID is a type-safe identifier for rotation records (prefix: "krot").
+//
+//nolint:recvcheck // Value receivers promoted from ID, pointer receivers for decoding.
+type RotationID struct{ ID }
+
+func (RotationID) entityPrefix() Prefix { return PrefixRotation }
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (i *RotationID) UnmarshalText(data []byte) error {
+	return i.ID.unmarshalText(data, PrefixRotation)
+}
+
+// UnmarshalBSONValue satisfies bson.ValueUnmarshaler (mongo-driver v2).
+func (i *RotationID) UnmarshalBSONValue(t byte, data []byte) error {
+	return i.ID.unmarshalBSONValue(t, data, PrefixRotation)
+}
+
+// Scan implements sql.Scanner for database retrieval.
+func (i *RotationID) Scan(src any) error { return i.ID.scan(src, PrefixRotation) }
+
+// ScopeID is a type-safe identifier for key scopes (prefix: "kscp").
+//
+//nolint:recvcheck // Value receivers promoted from ID, pointer receivers for decoding.
+type ScopeID struct{ ID }
+
+func (ScopeID) entityPrefix() Prefix { return PrefixScope }
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (i *ScopeID) UnmarshalText(data []byte) error { return i.ID.unmarshalText(data, PrefixScope) }
+
+// UnmarshalBSONValue satisfies bson.ValueUnmarshaler (mongo-driver v2).
+func (i *ScopeID) UnmarshalBSONValue(t byte, data []byte) error {
+	return i.ID.unmarshalBSONValue(t, data, PrefixScope)
+}
+
+// Scan implements sql.Scanner for database retrieval.
+func (i *ScopeID) Scan(src any) error { return i.ID.scan(src, PrefixScope) }
+
+// CaptureID is a type-safe identifier for debug captures (prefix: "kcap").
+//
+//nolint:recvcheck // Value receivers promoted from ID, pointer receivers for decoding.
+type CaptureID struct{ ID }
+
+func (CaptureID) entityPrefix() Prefix { return PrefixCapture }
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (i *CaptureID) UnmarshalText(data []byte) error { return i.ID.unmarshalText(data, PrefixCapture) }
+
+// UnmarshalBSONValue satisfies bson.ValueUnmarshaler (mongo-driver v2).
+func (i *CaptureID) UnmarshalBSONValue(t byte, data []byte) error {
+	return i.ID.unmarshalBSONValue(t, data, PrefixCapture)
+}
+
+// Scan implements sql.Scanner for database retrieval.
+func (i *CaptureID) Scan(src any) error { return i.ID.scan(src, PrefixCapture) }
+
+// GroupID is a type-safe identifier for key groups (prefix: "kgrp").
+//
+//nolint:recvcheck // Value receivers promoted from ID, pointer receivers for decoding.
+type GroupID struct{ ID }
+
+func (GroupID) entityPrefix() Prefix { return PrefixGroup }
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (i *GroupID) UnmarshalText(data []byte) error { return i.ID.unmarshalText(data, PrefixGroup) }
+
+// UnmarshalBSONValue satisfies bson.ValueUnmarshaler (mongo-driver v2).
+func (i *GroupID) UnmarshalBSONValue(t byte, data []byte) error {
+	return i.ID.unmarshalBSONValue(t, data, PrefixGroup)
+}
+
+// Scan implements sql.Scanner for database retrieval.
+func (i *GroupID) Scan(src any) error { return i.ID.scan(src, PrefixGroup) }
+
+// AuditEventID is a type-safe identifier for audit events (prefix: "kaud").
+//
+//nolint:recvcheck // Value receivers promoted from ID, pointer receivers for decoding.
+type AuditEventID struct{ ID }
+
+func (AuditEventID) entityPrefix() Prefix { return PrefixAudit }
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (i *AuditEventID) UnmarshalText(data []byte) error { return i.ID.unmarshalText(data, PrefixAudit) }
+
+// UnmarshalBSONValue satisfies bson.ValueUnmarshaler (mongo-driver v2).
+func (i *AuditEventID) UnmarshalBSONValue(t byte, data []byte) error {
+	return i.ID.unmarshalBSONValue(t, data, PrefixAudit)
+}
+
+// Scan implements sql.Scanner for database retrieval.
+func (i *AuditEventID) Scan(src any) error { return i.ID.scan(src, PrefixAudit) }
+
+// EventID is a type-safe identifier for published lifecycle events (prefix: "kevt").
+//
+//nolint:recvcheck // Value receivers promoted from ID, pointer receivers for decoding.
+type EventID struct{ ID }
+
+func (EventID) entityPrefix() Prefix { return PrefixEvent }
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (i *EventID) UnmarshalText(data []byte) error { return i.ID.unmarshalText(data, PrefixEvent) }
+
+// UnmarshalBSONValue satisfies bson.ValueUnmarshaler (mongo-driver v2).
+func (i *EventID) UnmarshalBSONValue(t byte, data []byte) error {
+	return i.ID.unmarshalBSONValue(t, data, PrefixEvent)
+}
+
+// Scan implements sql.Scanner for database retrieval.
+func (i *EventID) Scan(src any) error { return i.ID.scan(src, PrefixEvent) }
+
+// TransferID is a type-safe identifier for key transfers (prefix: "kxfr").
+//
+//nolint:recvcheck // Value receivers promoted from ID, pointer receivers for decoding.
+type TransferID struct{ ID }
+
+func (TransferID) entityPrefix() Prefix { return PrefixTransfer }
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (i *TransferID) UnmarshalText(data []byte) error {
+	return i.ID.unmarshalText(data, PrefixTransfer)
+}
+
+// UnmarshalBSONValue satisfies bson.ValueUnmarshaler (mongo-driver v2).
+func (i *TransferID) UnmarshalBSONValue(t byte, data []byte) error {
+	return i.ID.unmarshalBSONValue(t, data, PrefixTransfer)
+}
+
+// Scan implements sql.Scanner for database retrieval.
+func (i *TransferID) Scan(src any) error { return i.ID.scan(src, PrefixTransfer) }
+
+// QuotaPoolID is a type-safe identifier for quota pools (prefix: "kqpl").
+//
+//nolint:recvcheck // Value receivers promoted from ID, pointer receivers for decoding.
+type QuotaPoolID struct{ ID }
+
+func (QuotaPoolID) entityPrefix() Prefix { return PrefixQuotaPool }
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (i *QuotaPoolID) UnmarshalText(data []byte) error {
+	return i.ID.unmarshalText(data, PrefixQuotaPool)
+}
+
+// UnmarshalBSONValue satisfies bson.ValueUnmarshaler (mongo-driver v2).
+func (i *QuotaPoolID) UnmarshalBSONValue(t byte, data []byte) error {
+	return i.ID.unmarshalBSONValue(t, data, PrefixQuotaPool)
+}
+
+// Scan implements sql.Scanner for database retrieval.
+func (i *QuotaPoolID) Scan(src any) error { return i.ID.scan(src, PrefixQuotaPool) }
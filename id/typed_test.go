@@ -0,0 +1,136 @@
+package id_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xraph/keysmith/id"
+)
+
+// untypedNew adapts a typed constructor to return the untyped ID, so that
+// one table can hold every entity.
+func untypedNew[T id.Entity](fn func() T) func() id.ID {
+	return func() id.ID { return struct{ id.ID }(fn()).ID }
+}
+
+// untypedParse adapts a typed parser as untypedNew does.
+func untypedParse[T id.Entity](fn func(string) (T, error)) func(string) (id.ID, error) {
+	return func(s string) (id.ID, error) {
+		parsed, err := fn(s)
+		return struct{ id.ID }(parsed).ID, err
+	}
+}
+
+var entityTypes = []reflect.Type{
+	reflect.TypeFor[id.KeyID](),
+	reflect.TypeFor[id.PolicyID](),
+	reflect.TypeFor[id.UsageID](),
+	reflect.TypeFor[id.RotationID](),
+	reflect.TypeFor[id.ScopeID](),
+	reflect.TypeFor[id.CaptureID](),
+	reflect.TypeFor[id.GroupID](),
+	reflect.TypeFor[id.AuditEventID](),
+	reflect.TypeFor[id.EventID](),
+	reflect.TypeFor[id.TransferID](),
+	reflect.TypeFor[id.QuotaPoolID](),
+}
+
+// TestEntityTypesNotAssignable holds the compiler's assignability rule
+// against every pair of entity ID types, so that a return to aliases, which
+// would let a ScopeID be passed as a KeyID, fails here.
+func TestEntityTypesNotAssignable(t *testing.T) {
+	untyped := reflect.TypeFor[id.ID]()
+	for _, a := range entityTypes {
+		if a.AssignableTo(untyped) || untyped.AssignableTo(a) {
+			t.Errorf("%s and id.ID are assignable to each other", a)
+		}
+		for _, b := range entityTypes {
+			if a != b && a.AssignableTo(b) {
+				t.Errorf("%s is assignable to %s", a, b)
+			}
+		}
+	}
+}
+
+func TestTypedDecodingRejectsOtherEntities(t *testing.T) {
+	policyID := id.NewPolicyID()
+
+	var k id.KeyID
+	if err := k.UnmarshalText([]byte(policyID.String())); err == nil {
+		t.Error("UnmarshalText accepted a policy ID as a key ID")
+	}
+	if err := k.Scan(policyID.String()); err == nil {
+		t.Error("Scan accepted a policy ID as a key ID")
+	}
+	typ, data, err := policyID.MarshalBSONValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := k.UnmarshalBSONValue(typ, data); err == nil {
+		t.Error("UnmarshalBSONValue accepted a policy ID as a key ID")
+	}
+
+	var doc struct {
+		KeyID id.KeyID `json:"key_id"`
+	}
+	err = json.Unmarshal([]byte(`{"key_id":"`+policyID.String()+`"}`), &doc)
+	if err == nil || !strings.Contains(err.Error(), "expected prefix") {
+		t.Errorf("json.Unmarshal: got %v, want a prefix error", err)
+	}
+
+	keyID := id.NewKeyID()
+	if err := json.Unmarshal([]byte(`{"key_id":"`+keyID.String()+`"}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.KeyID != keyID {
+		t.Errorf("decoded %s, want %s", doc.KeyID, keyID)
+	}
+	if err := json.Unmarshal([]byte(`{"key_id":""}`), &doc); err != nil || !doc.KeyID.IsNil() {
+		t.Errorf("empty key ID: got %v, %v", doc.KeyID, err)
+	}
+}
+
+func TestTypedEncodingMatchesUntyped(t *testing.T) {
+	k := id.NewKeyID()
+	data, err := json.Marshal(map[string]any{"typed": k, "untyped": k.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"typed":"` + k.ID.String() + `","untyped":"` + k.ID.String() + `"}`
+	if string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+	if v, err := k.Value(); err != nil || v != k.ID.String() {
+		t.Errorf("Value: got %v, %v", v, err)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	k := id.NewKeyID()
+
+	got, err := id.Convert[id.KeyID](k.ID)
+	if err != nil || got != k {
+		t.Errorf("Convert: got %v, %v", got, err)
+	}
+	if _, err := id.Convert[id.PolicyID](k.ID); err == nil {
+		t.Error("Convert accepted a key ID as a policy ID")
+	}
+	if got, err := id.Convert[id.KeyID](id.Nil); err != nil || !got.IsNil() {
+		t.Errorf("Convert(Nil): got %v, %v", got, err)
+	}
+
+	useFormat(t, id.FormatUUID)
+	bare, err := id.ParseAny(k.UUID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = id.Convert[id.KeyID](bare)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Prefix() != id.PrefixKey || got.UUID() != k.UUID() {
+		t.Errorf("got %s/%s, want the key's", got.Prefix(), got.UUID())
+	}
+}
@@ -0,0 +1,51 @@
+package keysmith_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+var uuidRE = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+func TestWithIDFormat_UUID(t *testing.T) {
+	t.Cleanup(func() { _ = id.SetFormat(id.FormatTypeID) })
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithIDFormat(id.FormatUUID))
+	require.NoError(t, err)
+	assert.Equal(t, id.FormatUUID, id.CurrentFormat())
+
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "UUID", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	assert.Regexp(t, uuidRE, created.Key.ID.String())
+	assert.Equal(t, id.PrefixKey, created.Key.ID.Prefix(), "the prefix is kept internally")
+
+	kid, err := id.ParseKeyID(created.Key.ID.String())
+	require.NoError(t, err)
+	got, err := eng.GetKey(testCtx(), kid)
+	require.NoError(t, err)
+	assert.Equal(t, created.Key.ID.String(), got.ID.String())
+
+	result, err := eng.ValidateKey(testCtx(), created.RawKey)
+	require.NoError(t, err)
+	assert.Regexp(t, uuidRE, result.Key.ID.String())
+}
+
+func TestWithIDFormat_Invalid(t *testing.T) {
+	_, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithIDFormat(id.Format(9)))
+	assert.Error(t, err)
+	assert.Equal(t, id.FormatTypeID, id.CurrentFormat())
+}
+
+func TestMigrateIDs_Unsupported(t *testing.T) {
+	eng := newTestEngine(t)
+	_, err := eng.MigrateIDs(testCtx(), store.IDMigrationOptions{To: id.FormatUUID})
+	assert.ErrorIs(t, err, keysmith.ErrIDMigrationUnsupported)
+}
@@ -0,0 +1,64 @@
+package keysmith
+
+import (
+	"context"
+	"net/netip"
+
+	"github.com/xraph/keysmith/policy"
+)
+
+// requestIP returns the address a validation's request came from: its
+// ClientIP when set, otherwise the host of its RemoteAddr. It reports false
+// when neither holds an address.
+func requestIP(vreq ValidationRequest) (netip.Addr, bool) {
+	addr := vreq.ClientIP
+	if addr == "" {
+		addr = vreq.RemoteAddr
+	}
+	if ap, err := netip.ParseAddrPort(addr); err == nil {
+		return ap.Addr().Unmap().WithZone(""), true
+	}
+	if a, err := netip.ParseAddr(addr); err == nil {
+		return a.Unmap().WithZone(""), true
+	}
+	return netip.Addr{}, false
+}
+
+// checkIP rejects a validation whose client IP is not in the policy's
+// AllowedIPs. A request without a client IP is rejected when a list is set,
+// since the engine cannot tell where it came from.
+func checkIP(ctx context.Context, pol *policy.Policy) error {
+	if pol == nil || len(pol.AllowedIPs) == 0 {
+		return nil
+	}
+	ip, ok := requestIP(ValidationRequestFromContext(ctx))
+	if !ok || !ipAllowed(pol.AllowedIPs, ip) {
+		return ErrIPNotAllowed
+	}
+	return nil
+}
+
+// ipAllowed reports whether ip matches an entry of allowed, each a single
+// address such as "203.0.113.7" or a CIDR range such as "10.0.0.0/8" or
+// "2001:db8::/32". IPv4-mapped IPv6 addresses match as IPv4. An entry that
+// does not parse matches nothing; policy validation rejects them at write
+// time.
+func ipAllowed(allowed []string, ip netip.Addr) bool {
+	for _, entry := range allowed {
+		if a, err := netip.ParseAddr(entry); err == nil {
+			if a.Unmap() == ip {
+				return true
+			}
+			continue
+		}
+		if p, err := netip.ParsePrefix(entry); err == nil {
+			if p.Addr().Is4In6() {
+				p = netip.PrefixFrom(p.Addr().Unmap(), p.Bits()-96)
+			}
+			if p.Contains(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
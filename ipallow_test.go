@@ -0,0 +1,138 @@
+package keysmith_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keysmithtest"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+func ipCtx(remoteAddr string) context.Context {
+	return keysmith.WithValidationRequest(testCtx(), keysmith.ValidationRequest{RemoteAddr: remoteAddr})
+}
+
+// createIPKey returns a key under a new policy allowing ips.
+func createIPKey(t *testing.T, eng *keysmith.Engine, ips []string, flags ...key.Flag) *key.CreateResult {
+	t.Helper()
+	pol := &policy.Policy{Name: "Office " + t.Name(), AllowedIPs: ips}
+	require.NoError(t, eng.CreatePolicy(testCtx(), pol))
+	created, err := eng.CreateKey(adminCtx(), &keysmith.CreateKeyInput{
+		Name:        "Office Key",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+		PolicyID:    &pol.ID,
+		Flags:       flags,
+	})
+	require.NoError(t, err)
+	return created
+}
+
+func TestAllowedIPs(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		allowed []string
+		ok      []string
+		refused []string
+	}{
+		{
+			name:    "IPv4",
+			allowed: []string{"10.0.0.0/8", "203.0.113.7"},
+			ok:      []string{"10.1.2.3:443", "10.255.255.255", "203.0.113.7:51234", "[::ffff:10.0.0.1]:80"},
+			refused: []string{"11.0.0.1:443", "203.0.113.8:51234", "[2001:db8::1]:443"},
+		},
+		{
+			name:    "IPv6",
+			allowed: []string{"2001:db8::/32", "fe80::1"},
+			ok:      []string{"[2001:db8:1::42]:443", "2001:db8::1", "[fe80::1%eth0]:8080"},
+			refused: []string{"[2001:db9::1]:443", "[fe80::2]:8080", "10.0.0.1:443"},
+		},
+		{
+			name:    "mixed",
+			allowed: []string{"192.168.0.0/16", "2001:db8:abcd::/48", "198.51.100.10", "::ffff:172.16.0.0/108"},
+			ok:      []string{"192.168.4.20:443", "[2001:db8:abcd:1::5]:443", "198.51.100.10:1", "172.16.3.4:443"},
+			refused: []string{"192.169.0.1:443", "[2001:db8:abce::1]:443", "198.51.100.11:1", "172.32.0.1:443"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			eng := newTestEngine(t)
+			created := createIPKey(t, eng, tc.allowed)
+			for _, addr := range tc.ok {
+				_, err := eng.ValidateKey(ipCtx(addr), created.RawKey)
+				assert.NoError(t, err, addr)
+			}
+			for _, addr := range tc.refused {
+				_, err := eng.ValidateKey(ipCtx(addr), created.RawKey)
+				assert.ErrorIs(t, err, keysmith.ErrIPNotAllowed, addr)
+			}
+		})
+	}
+}
+
+func TestAllowedIPs_ClientIPAndMissingAddress(t *testing.T) {
+	eng := newTestEngine(t)
+	created := createIPKey(t, eng, []string{"203.0.113.0/24"})
+
+	ctx := keysmith.WithValidationRequest(testCtx(), keysmith.ValidationRequest{
+		RemoteAddr: "10.0.0.5:443",
+		ClientIP:   "203.0.113.9",
+	})
+	_, err := eng.ValidateKey(ctx, created.RawKey)
+	require.NoError(t, err, "the client IP takes precedence over the proxy's address")
+
+	_, err = eng.ValidateKey(testCtx(), created.RawKey)
+	assert.ErrorIs(t, err, keysmith.ErrIPNotAllowed, "a request without an address is refused when a list applies")
+	_, err = eng.ValidateKey(ipCtx("not-an-address"), created.RawKey)
+	assert.ErrorIs(t, err, keysmith.ErrIPNotAllowed)
+
+	open := createIPKey(t, eng, nil)
+	_, err = eng.ValidateKey(testCtx(), open.RawKey)
+	assert.NoError(t, err, "no list allows any address")
+}
+
+func TestAllowedIPs_FiresValidationFailed(t *testing.T) {
+	rec := keysmithtest.NewRecorder()
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithExtension(rec))
+	require.NoError(t, err)
+	created := createIPKey(t, eng, []string{"10.0.0.0/8"})
+
+	_, err = eng.ValidateKey(ipCtx("10.1.2.3:443"), created.RawKey)
+	require.NoError(t, err)
+	_, err = eng.ValidateKey(ipCtx("192.0.2.1:443"), created.RawKey)
+	require.ErrorIs(t, err, keysmith.ErrIPNotAllowed)
+
+	evts := rec.Filter("KeyValidationFailed")
+	require.Len(t, evts, 1)
+	assert.ErrorIs(t, evts[0].Err, keysmith.ErrIPNotAllowed)
+	assert.Equal(t, plugin.ReasonIPNotAllowed, evts[0].Meta.ReasonCode)
+	assert.NotEmpty(t, evts[0].Fingerprint)
+}
+
+func TestAllowedIPs_SkipFlag(t *testing.T) {
+	eng := newTestEngine(t)
+	created := createIPKey(t, eng, []string{"10.0.0.0/8"}, key.FlagSkipIPCheck)
+
+	result, err := eng.ValidateKey(ipCtx("192.0.2.1:443"), created.RawKey)
+	require.NoError(t, err)
+	assert.Equal(t, []key.Flag{key.FlagSkipIPCheck}, result.AppliedFlags)
+}
+
+func TestAllowedIPs_InvalidEntriesRejected(t *testing.T) {
+	eng := newTestEngine(t)
+	err := eng.CreatePolicy(testCtx(), &policy.Policy{Name: "Bad", AllowedIPs: []string{"10.0.0.0/33"}})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidPolicy)
+
+	pol := &policy.Policy{Name: "Office", AllowedIPs: []string{"10.0.0.0/8"}}
+	require.NoError(t, eng.CreatePolicy(testCtx(), pol))
+	pol.AllowedIPs = []string{"2001:db8::/32", "office-router"}
+	err = eng.UpdatePolicy(testCtx(), pol)
+	assert.ErrorIs(t, err, keysmith.ErrInvalidPolicy)
+	assert.ErrorContains(t, err, "office-router")
+}
@@ -0,0 +1,203 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xraph/keysmith/group"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/quotapool"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/tombstone"
+	"github.com/xraph/keysmith/transfer"
+	"github.com/xraph/keysmith/usage"
+)
+
+// Tenant and app isolation.
+//
+// Engine calls are scoped to the tenant and app on the context. List calls
+// only see resources in that scope, and a resource fetched by ID from
+// another tenant or app is reported as not found. A context without an app
+// is the tenant-admin view: it sees every app in its tenant, and list
+// filters may narrow it to one app with AppID. A context with neither is
+// unscoped, which is what background jobs and single-tenant deployments use.
+
+// owns reports whether a resource in tenantID and appID is visible from sc.
+func (sc tenantScope) owns(tenantID, appID string) bool {
+	if sc.tenantID != "" && sc.tenantID != tenantID {
+		return false
+	}
+	return sc.appID == "" || sc.appID == appID
+}
+
+// narrow returns the tenant and app a filter must be restricted to: the
+// context's values when set, otherwise the filter's own.
+func (sc tenantScope) narrow(tenantID, appID string) (string, string) {
+	if sc.tenantID != "" {
+		tenantID = sc.tenantID
+	}
+	if sc.appID != "" {
+		appID = sc.appID
+	}
+	return tenantID, appID
+}
+
+// getKey loads a key and checks that the context may see it.
+func (e *Engine) getKey(ctx context.Context, keyID id.KeyID) (*key.Key, error) {
+	k, err := e.store.Keys().Get(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if !scopeFromContext(ctx).owns(k.TenantID, k.AppID) {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+	}
+	return k, nil
+}
+
+// checkKey reports ErrKeyNotFound, or the store error, unless the key exists
+// and the context may see it. Unscoped contexts skip the lookup.
+func (e *Engine) checkKey(ctx context.Context, keyID id.KeyID) error {
+	if scopeFromContext(ctx) == (tenantScope{}) {
+		return nil
+	}
+	_, err := e.getKey(ctx, keyID)
+	return err
+}
+
+// getPolicy loads a policy and checks that the context may see it.
+func (e *Engine) getPolicy(ctx context.Context, polID id.PolicyID) (*policy.Policy, error) {
+	pol, err := e.store.Policies().Get(ctx, polID)
+	if err != nil {
+		return nil, err
+	}
+	if !scopeFromContext(ctx).owns(pol.TenantID, pol.AppID) {
+		return nil, fmt.Errorf("%w: %s", ErrPolicyNotFound, polID)
+	}
+	return pol, nil
+}
+
+// getGroup loads a key group and checks that the context may see it.
+func (e *Engine) getGroup(ctx context.Context, groupID id.GroupID) (*group.Group, error) {
+	g, err := e.store.Groups().Get(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if !scopeFromContext(ctx).owns(g.TenantID, g.AppID) {
+		return nil, fmt.Errorf("%w: %s", ErrGroupNotFound, groupID)
+	}
+	return g, nil
+}
+
+// getQuotaPool loads a quota pool and checks that the context may see it.
+func (e *Engine) getQuotaPool(ctx context.Context, poolID id.QuotaPoolID) (*quotapool.Pool, error) {
+	p, err := e.store.QuotaPools().Get(ctx, poolID)
+	if err != nil {
+		return nil, err
+	}
+	if !scopeFromContext(ctx).owns(p.TenantID, p.AppID) {
+		return nil, fmt.Errorf("%w: %s", ErrQuotaPoolNotFound, poolID)
+	}
+	return p, nil
+}
+
+// keyFilter returns a copy of f restricted to the context's scope.
+func keyFilter(ctx context.Context, f *key.ListFilter) *key.ListFilter {
+	out := key.ListFilter{}
+	if f != nil {
+		out = *f
+	}
+	out.TenantID, out.AppID = scopeFromContext(ctx).narrow(out.TenantID, out.AppID)
+	return &out
+}
+
+// policyFilter returns a copy of f restricted to the context's scope.
+func policyFilter(ctx context.Context, f *policy.ListFilter) *policy.ListFilter {
+	out := policy.ListFilter{}
+	if f != nil {
+		out = *f
+	}
+	out.TenantID, out.AppID = scopeFromContext(ctx).narrow(out.TenantID, out.AppID)
+	return &out
+}
+
+// scopeFilter returns a copy of f restricted to the context's scope.
+func scopeFilter(ctx context.Context, f *scope.ListFilter) *scope.ListFilter {
+	out := scope.ListFilter{}
+	if f != nil {
+		out = *f
+	}
+	out.TenantID, out.AppID = scopeFromContext(ctx).narrow(out.TenantID, out.AppID)
+	return &out
+}
+
+// groupFilter returns a copy of f restricted to the context's scope.
+func groupFilter(ctx context.Context, f *group.ListFilter) *group.ListFilter {
+	out := group.ListFilter{}
+	if f != nil {
+		out = *f
+	}
+	out.TenantID, out.AppID = scopeFromContext(ctx).narrow(out.TenantID, out.AppID)
+	return &out
+}
+
+// quotaPoolFilter returns a copy of f restricted to the context's scope.
+func quotaPoolFilter(ctx context.Context, f *quotapool.ListFilter) *quotapool.ListFilter {
+	out := quotapool.ListFilter{}
+	if f != nil {
+		out = *f
+	}
+	out.TenantID, out.AppID = scopeFromContext(ctx).narrow(out.TenantID, out.AppID)
+	return &out
+}
+
+// usageFilter returns a copy of f restricted to the context's scope. A
+// filter on one key is checked against the key's owner instead, so records
+// written before usage carried an app stay visible with their key.
+func (e *Engine) usageFilter(ctx context.Context, f *usage.QueryFilter) (*usage.QueryFilter, error) {
+	out := usage.QueryFilter{}
+	if f != nil {
+		out = *f
+	}
+	if out.KeyID != nil {
+		return &out, e.checkKey(ctx, *out.KeyID)
+	}
+	out.TenantID, out.AppID = scopeFromContext(ctx).narrow(out.TenantID, out.AppID)
+	return &out, nil
+}
+
+// rotationFilter returns a copy of f restricted to the context's scope. As
+// with usage, a filter on one key is checked against the key's owner.
+func (e *Engine) rotationFilter(ctx context.Context, f *rotation.ListFilter) (*rotation.ListFilter, error) {
+	out := rotation.ListFilter{}
+	if f != nil {
+		out = *f
+	}
+	if out.KeyID != nil {
+		return &out, e.checkKey(ctx, *out.KeyID)
+	}
+	out.TenantID, out.AppID = scopeFromContext(ctx).narrow(out.TenantID, out.AppID)
+	return &out, nil
+}
+
+// transferFilter returns a copy of f restricted to the context's scope.
+func transferFilter(ctx context.Context, f *transfer.ListFilter) *transfer.ListFilter {
+	out := transfer.ListFilter{}
+	if f != nil {
+		out = *f
+	}
+	out.TenantID, out.AppID = scopeFromContext(ctx).narrow(out.TenantID, out.AppID)
+	return &out
+}
+
+// tombstoneFilter returns a copy of f restricted to the context's scope.
+func tombstoneFilter(ctx context.Context, f *tombstone.ListFilter) *tombstone.ListFilter {
+	out := tombstone.ListFilter{}
+	if f != nil {
+		out = *f
+	}
+	out.TenantID, out.AppID = scopeFromContext(ctx).narrow(out.TenantID, out.AppID)
+	return &out
+}
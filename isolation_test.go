@@ -0,0 +1,179 @@
+package keysmith_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/usage"
+)
+
+func appCtx(appID string) context.Context {
+	return keysmith.WithTenant(context.Background(), appID, "tenant_shared")
+}
+
+// tenantAdminCtx is scoped to the tenant but to no app.
+func tenantAdminCtx() context.Context {
+	return appCtx("")
+}
+
+func createAppKey(t *testing.T, eng *keysmith.Engine, appID string) *key.CreateResult {
+	t.Helper()
+	created, err := eng.CreateKey(appCtx(appID), &keysmith.CreateKeyInput{
+		Name:        appID + " key",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+	})
+	require.NoError(t, err)
+	require.Equal(t, appID, created.Key.AppID)
+	return created
+}
+
+func keyIDs(keys []*key.Key) []string {
+	out := make([]string, len(keys))
+	for i, k := range keys {
+		out[i] = k.ID.String()
+	}
+	return out
+}
+
+func TestAppIsolation_ListKeys(t *testing.T) {
+	eng := newTestEngine(t)
+	a := createAppKey(t, eng, "app_a")
+	b := createAppKey(t, eng, "app_b")
+
+	keys, err := eng.ListKeys(appCtx("app_a"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{a.Key.ID.String()}, keyIDs(keys))
+
+	keys, err = eng.ListKeys(appCtx("app_b"), &key.ListFilter{AppID: "app_a"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{b.Key.ID.String()}, keyIDs(keys), "the context's app wins over the filter")
+
+	keys, err = eng.ListKeys(tenantAdminCtx(), nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{a.Key.ID.String(), b.Key.ID.String()}, keyIDs(keys))
+
+	keys, err = eng.ListKeys(tenantAdminCtx(), &key.ListFilter{AppID: "app_b"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{b.Key.ID.String()}, keyIDs(keys), "a tenant admin can narrow to one app")
+
+	keys, err = eng.ListKeys(keysmith.WithTenant(context.Background(), "", "tenant_other"), nil)
+	require.NoError(t, err)
+	assert.Empty(t, keys, "other tenants see nothing")
+}
+
+func TestAppIsolation_GetAndRevoke(t *testing.T) {
+	eng := newTestEngine(t)
+	a := createAppKey(t, eng, "app_a")
+
+	_, err := eng.GetKey(appCtx("app_b"), a.Key.ID)
+	require.ErrorIs(t, err, keysmith.ErrKeyNotFound)
+
+	require.ErrorIs(t, eng.RevokeKey(appCtx("app_b"), a.Key.ID, "cross-app"), keysmith.ErrKeyNotFound)
+	require.ErrorIs(t, eng.SuspendKey(appCtx("app_b"), a.Key.ID), keysmith.ErrKeyNotFound)
+	_, err = eng.RotateKey(appCtx("app_b"), a.Key.ID, rotation.ReasonManual)
+	require.ErrorIs(t, err, keysmith.ErrKeyNotFound)
+
+	k, err := eng.GetKey(appCtx("app_a"), a.Key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, key.StateActive, k.State, "cross-app calls changed nothing")
+
+	k, err = eng.GetKey(tenantAdminCtx(), a.Key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "app_a", k.AppID)
+
+	require.NoError(t, eng.RevokeKey(tenantAdminCtx(), a.Key.ID, "tenant admin"))
+	k, err = eng.GetKey(appCtx("app_a"), a.Key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, key.StateRevoked, k.State)
+}
+
+func TestAppIsolation_PoliciesAndScopes(t *testing.T) {
+	eng := newTestEngine(t)
+
+	polA := &policy.Policy{Name: "a-policy"}
+	require.NoError(t, eng.CreatePolicy(appCtx("app_a"), polA))
+	polB := &policy.Policy{Name: "b-policy"}
+	require.NoError(t, eng.CreatePolicy(appCtx("app_b"), polB))
+
+	pols, err := eng.ListPolicies(appCtx("app_a"), nil)
+	require.NoError(t, err)
+	require.Len(t, pols, 1)
+	assert.Equal(t, polA.ID, pols[0].ID)
+
+	pols, err = eng.ListPolicies(tenantAdminCtx(), nil)
+	require.NoError(t, err)
+	assert.Len(t, pols, 2)
+
+	_, err = eng.GetPolicy(appCtx("app_b"), polA.ID)
+	require.ErrorIs(t, err, keysmith.ErrPolicyNotFound)
+	require.ErrorIs(t, eng.DeletePolicy(appCtx("app_b"), polA.ID), keysmith.ErrPolicyNotFound)
+
+	_, err = eng.CreateKey(appCtx("app_b"), &keysmith.CreateKeyInput{
+		Name:     "borrowed policy",
+		Prefix:   "sk",
+		PolicyID: &polA.ID,
+	})
+	require.ErrorIs(t, err, keysmith.ErrPolicyNotFound, "keys cannot use another app's policy")
+
+	require.NoError(t, eng.CreateScope(appCtx("app_a"), &scope.Scope{Name: "read:a"}))
+	require.NoError(t, eng.CreateScope(appCtx("app_b"), &scope.Scope{Name: "read:b"}))
+
+	scopes, err := eng.ListScopes(appCtx("app_b"), nil)
+	require.NoError(t, err)
+	require.Len(t, scopes, 1)
+	assert.Equal(t, "read:b", scopes[0].Name)
+
+	scopes, err = eng.ListScopes(tenantAdminCtx(), &scope.ListFilter{AppID: "app_a"})
+	require.NoError(t, err)
+	require.Len(t, scopes, 1)
+	assert.Equal(t, "read:a", scopes[0].Name)
+	require.ErrorIs(t, eng.DeleteScope(appCtx("app_b"), scopes[0].ID), keysmith.ErrScopeNotFound)
+}
+
+func TestAppIsolation_UsageAndRotations(t *testing.T) {
+	eng := newTestEngine(t)
+	a := createAppKey(t, eng, "app_a")
+	b := createAppKey(t, eng, "app_b")
+
+	require.NoError(t, eng.RecordUsage(appCtx("app_a"), &usage.Record{KeyID: a.Key.ID, Endpoint: "/a", Method: "GET"}))
+	require.NoError(t, eng.RecordUsage(appCtx("app_b"), &usage.Record{KeyID: b.Key.ID, Endpoint: "/b", Method: "GET"}))
+
+	recs, err := eng.QueryUsage(appCtx("app_a"), nil)
+	require.NoError(t, err)
+	require.Len(t, recs, 1)
+	assert.Equal(t, "app_a", recs[0].AppID)
+
+	recs, err = eng.QueryUsage(tenantAdminCtx(), nil)
+	require.NoError(t, err)
+	assert.Len(t, recs, 2)
+
+	_, err = eng.QueryUsage(appCtx("app_a"), &usage.QueryFilter{KeyID: &b.Key.ID})
+	require.ErrorIs(t, err, keysmith.ErrKeyNotFound)
+
+	_, err = eng.RotateKey(appCtx("app_a"), a.Key.ID, rotation.ReasonManual)
+	require.NoError(t, err)
+	_, err = eng.RotateKey(appCtx("app_b"), b.Key.ID, rotation.ReasonManual)
+	require.NoError(t, err)
+
+	rots, err := eng.ListRotations(appCtx("app_b"), nil)
+	require.NoError(t, err)
+	require.Len(t, rots, 1)
+	assert.Equal(t, b.Key.ID, rots[0].KeyID)
+	assert.Equal(t, "app_b", rots[0].AppID)
+
+	_, err = eng.ListRotations(appCtx("app_b"), &rotation.ListFilter{KeyID: &a.Key.ID})
+	require.ErrorIs(t, err, keysmith.ErrKeyNotFound)
+
+	rots, err = eng.ListRotations(tenantAdminCtx(), nil)
+	require.NoError(t, err)
+	assert.Len(t, rots, 2)
+}
@@ -0,0 +1,123 @@
+package keysmith
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	log "github.com/xraph/go-utils/log"
+
+	"github.com/xraph/keysmith/store"
+)
+
+// DefaultJobLockTTL is how long a background job's store lock lasts without
+// renewal. A running job renews it every third of the TTL.
+const DefaultJobLockTTL = time.Minute
+
+// Store lock names of the background jobs. Each is prefixed with
+// "keysmith:" in the store.
+const (
+	jobCapturePurge     = "capture_purge"
+	jobStoreMaintenance = "store_maintenance"
+	jobQuotaForecasts   = "quota_forecasts"
+	jobIdleSuspension   = "idle_suspension"
+	jobStorageReport    = "storage_report"
+	jobTelemetry        = "telemetry"
+)
+
+// jobLocks runs periodic jobs under store locks, so that of the engines
+// sharing a store, one runs each job per period rather than every engine
+// running it. A run renews its lock while it lasts and then keeps it until
+// the next run is due, so engines whose timers fire later in the same
+// period skip. The engine that ran a job renews its kept lock at its next
+// run, so the job tends to stay with one engine; when that engine stops, it
+// releases the lock, and when it crashes, the lock frees once the TTL
+// passes.
+type jobLocks struct {
+	locker store.Locker
+	ttl    time.Duration
+	logger log.Logger
+}
+
+// run runs j once if its lock is free or already ours, and skips it when
+// another engine holds it.
+func (l *jobLocks) run(j *periodicJob) {
+	ctx := context.Background()
+	start := time.Now()
+	period := j.period()
+
+	lock := j.held
+	j.held = nil
+	if lock == nil || lock.Renew(ctx, l.ttl) != nil {
+		var err error
+		lock, err = l.locker.AcquireLock(ctx, "keysmith:"+j.name, l.ttl)
+		if errors.Is(err, store.ErrLockHeld) {
+			l.logger.Info("background job skipped: lock held elsewhere", log.String("job", j.name))
+			return
+		}
+		if err != nil {
+			l.logger.Warn("background job skipped: failed to acquire lock", log.String("job", j.name), log.Any("error", err))
+			return
+		}
+	}
+	l.logger.Info("background job lock acquired", log.String("job", j.name))
+
+	runCtx, cancel := context.WithCancel(ctx)
+	lost := l.keepAlive(runCtx, cancel, lock, j.name)
+	j.run(runCtx)
+	cancel()
+	if <-lost {
+		return
+	}
+
+	if rest := period - time.Since(start); rest > 0 && lock.Renew(ctx, rest) == nil {
+		j.held = lock
+		return
+	}
+	if err := lock.Release(ctx); err != nil {
+		l.logger.Warn("failed to release background job lock", log.String("job", j.name), log.Any("error", err))
+	}
+}
+
+// keepAlive renews lock every third of the TTL until ctx is done. When a
+// renewal finds the lock lost, it cancels the run. The returned channel
+// yields whether the lock was lost once renewal has stopped.
+func (l *jobLocks) keepAlive(ctx context.Context, cancel context.CancelFunc, lock store.Lock, job string) <-chan bool {
+	lost := make(chan bool, 1)
+	go func() {
+		ticker := time.NewTicker(l.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				lost <- false
+				return
+			case <-ticker.C:
+				err := lock.Renew(ctx, l.ttl)
+				if errors.Is(err, store.ErrLockLost) {
+					l.logger.Warn("background job lock lost; stopping the run", log.String("job", job))
+					cancel()
+					lost <- true
+					return
+				}
+				if err != nil && ctx.Err() == nil {
+					l.logger.Warn("failed to renew background job lock", log.String("job", job), log.Any("error", err))
+				}
+			}
+		}
+	}()
+	return lost
+}
+
+// releaseKept frees the lock j kept after its last run. shutdown calls it
+// once j's goroutine has exited.
+func (l *jobLocks) releaseKept(j *periodicJob) {
+	if j.held == nil {
+		return
+	}
+	lock := j.held
+	j.held = nil
+	if err := lock.Release(context.Background()); err != nil {
+		l.logger.Warn("failed to release background job lock", log.String("job", j.name), log.Any("error", err))
+	}
+}
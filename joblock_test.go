@@ -0,0 +1,127 @@
+package keysmith_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+// slowMaintainStore counts Maintain calls, holds each for delay, and
+// records the most that ran at once.
+type slowMaintainStore struct {
+	*memory.Store
+	delay   time.Duration
+	calls   atomic.Int64
+	running atomic.Int64
+	most    atomic.Int64
+}
+
+func (s *slowMaintainStore) Maintain(ctx context.Context, opts store.MaintenanceOptions) (*store.MaintenanceReport, error) {
+	s.calls.Add(1)
+	n := s.running.Add(1)
+	defer s.running.Add(-1)
+	for {
+		most := s.most.Load()
+		if n <= most || s.most.CompareAndSwap(most, n) {
+			break
+		}
+	}
+	select {
+	case <-time.After(s.delay):
+	case <-ctx.Done():
+	}
+	return s.Store.Maintain(ctx, opts)
+}
+
+// startMaintenanceEngines starts n engines over st that run store
+// maintenance every interval.
+func startMaintenanceEngines(t *testing.T, st store.Store, n int, interval time.Duration, opts ...keysmith.Option) []*keysmith.Engine {
+	t.Helper()
+	engines := make([]*keysmith.Engine, n)
+	for i := range engines {
+		eng, err := keysmith.NewEngine(append([]keysmith.Option{
+			keysmith.WithStore(st),
+			keysmith.WithStoreMaintenance(interval, store.MaintenanceOptions{}),
+		}, opts...)...)
+		require.NoError(t, err)
+		require.NoError(t, eng.Start(context.Background()))
+		t.Cleanup(func() { _ = eng.Stop(context.Background()) })
+		engines[i] = eng
+	}
+	return engines
+}
+
+func TestJobLocks_OneRunPerInterval(t *testing.T) {
+	ms := &slowMaintainStore{Store: memory.New()}
+	const interval = 100 * time.Millisecond
+	start := time.Now()
+	engines := startMaintenanceEngines(t, ms, 3, interval)
+
+	require.Eventually(t, func() bool { return ms.calls.Load() >= 4 }, 5*time.Second, 5*time.Millisecond)
+	for _, eng := range engines {
+		require.NoError(t, eng.Stop(context.Background()))
+	}
+	periods := int64(time.Since(start)/interval) + 1
+	assert.LessOrEqual(t, ms.calls.Load(), periods, "three engines run the job once per interval between them")
+}
+
+func TestJobLocks_UnlockedRunsOnEveryEngine(t *testing.T) {
+	ms := &slowMaintainStore{Store: memory.New()}
+	const interval = 100 * time.Millisecond
+	start := time.Now()
+	engines := startMaintenanceEngines(t, ms, 3, interval, keysmith.WithJobLockTTL(-1))
+
+	require.Eventually(t, func() bool { return ms.calls.Load() >= 6 }, 5*time.Second, 5*time.Millisecond)
+	for _, eng := range engines {
+		require.NoError(t, eng.Stop(context.Background()))
+	}
+	periods := int64(time.Since(start)/interval) + 1
+	assert.Greater(t, ms.calls.Load(), periods, "without locks every engine runs the job")
+}
+
+func TestJobLocks_RenewedWhileRunning(t *testing.T) {
+	// Each run outlasts the lock TTL several times over, so only renewal
+	// keeps the other engine out.
+	ms := &slowMaintainStore{Store: memory.New(), delay: 200 * time.Millisecond}
+	startMaintenanceEngines(t, ms, 2, 10*time.Millisecond, keysmith.WithJobLockTTL(60*time.Millisecond))
+
+	require.Eventually(t, func() bool { return ms.calls.Load() >= 3 }, 5*time.Second, 5*time.Millisecond)
+	assert.Equal(t, int64(1), ms.most.Load(), "runs never overlap")
+}
+
+func TestJobLocks_TakeoverAfterCrash(t *testing.T) {
+	ms := &slowMaintainStore{Store: memory.New()}
+
+	// A crashed engine leaves its lock behind, unreleased.
+	_, err := ms.AcquireLock(context.Background(), "keysmith:store_maintenance", 300*time.Millisecond)
+	require.NoError(t, err)
+	startMaintenanceEngines(t, ms, 1, 10*time.Millisecond)
+
+	time.Sleep(150 * time.Millisecond)
+	assert.Zero(t, ms.calls.Load(), "the job is skipped while the crashed engine's lock lasts")
+	assert.Eventually(t, func() bool { return ms.calls.Load() >= 1 }, 5*time.Second, 5*time.Millisecond,
+		"the job runs once the lock's TTL passes")
+}
+
+func TestJobLocks_ReleasedOnStop(t *testing.T) {
+	ms := &slowMaintainStore{Store: memory.New()}
+	eng := startMaintenanceEngines(t, ms, 1, 300*time.Millisecond)[0]
+
+	require.Eventually(t, func() bool { return ms.calls.Load() >= 1 }, 5*time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	_, err := ms.AcquireLock(context.Background(), "keysmith:store_maintenance", time.Minute)
+	require.ErrorIs(t, err, store.ErrLockHeld, "the lock is kept until the next run is due")
+
+	require.NoError(t, eng.Stop(context.Background()))
+	lock, err := ms.AcquireLock(context.Background(), "keysmith:store_maintenance", time.Minute)
+	require.NoError(t, err, "a stopped engine releases its lock")
+	require.NoError(t, lock.Release(context.Background()))
+}
@@ -0,0 +1,109 @@
+package keysmith
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/xraph/keysmith/store"
+)
+
+// periodicJob runs a function on an interval between Start and Stop. A job
+// with a non-positive interval never starts.
+type periodicJob struct {
+	// name identifies the job in logs and names its store lock.
+	name     string
+	interval time.Duration
+	run      func(context.Context)
+
+	// every, when set, is read before each wait for the interval to wait.
+	// A non-positive result falls back to interval.
+	every func() time.Duration
+
+	// locks, when set, runs each cycle under the job's store lock. held is
+	// the lock kept from the last run, touched only by the job's goroutine
+	// and, after it exits, by shutdown.
+	locks *jobLocks
+	held  store.Lock
+
+	mu    sync.Mutex
+	stop  chan struct{}
+	done  chan struct{}
+	rearm chan struct{}
+}
+
+func (j *periodicJob) start() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.stop != nil || j.interval <= 0 || j.run == nil {
+		return
+	}
+	j.stop = make(chan struct{})
+	j.done = make(chan struct{})
+	j.rearm = make(chan struct{}, 1)
+
+	go func(stop, rearm <-chan struct{}, done chan<- struct{}) {
+		defer close(done)
+		timer := time.NewTimer(j.period())
+		defer timer.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-rearm:
+				timer.Reset(j.period())
+			case <-timer.C:
+				j.cycle()
+				timer.Reset(j.period())
+			}
+		}
+	}(j.stop, j.rearm, j.done)
+}
+
+// reschedule restarts a running job's wait with its current period, so a
+// shorter interval takes effect without waiting out the old one.
+func (j *periodicJob) reschedule() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.rearm == nil {
+		return
+	}
+	select {
+	case j.rearm <- struct{}{}:
+	default:
+	}
+}
+
+func (j *periodicJob) shutdown() {
+	j.mu.Lock()
+	stop, done := j.stop, j.done
+	j.stop, j.done, j.rearm = nil, nil, nil
+	j.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+	if j.locks != nil {
+		j.locks.releaseKept(j)
+	}
+}
+
+// cycle runs the job once, under its lock when it has one.
+func (j *periodicJob) cycle() {
+	if j.locks == nil {
+		j.run(context.Background())
+		return
+	}
+	j.locks.run(j)
+}
+
+// period returns the interval to wait before the next run.
+func (j *periodicJob) period() time.Duration {
+	if j.every != nil {
+		if d := j.every(); d > 0 {
+			return d
+		}
+	}
+	return j.interval
+}
@@ -0,0 +1,39 @@
+package key
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// CertFingerprint returns the SHA-256 fingerprint of a DER-encoded
+// certificate, such as x509.Certificate.Raw, in lowercase hex.
+func CertFingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+// NormalizeCertFingerprint returns a SHA-256 fingerprint in the form
+// [CertFingerprint] returns. It accepts hex of either case, with the bytes
+// optionally separated by colons, and an optional "sha256:" prefix or the
+// "sha256 Fingerprint=" label that openssl x509 -fingerprint prints. An
+// empty fingerprint stays empty.
+func NormalizeCertFingerprint(fp string) (string, error) {
+	given := fp
+	fp = strings.TrimSpace(fp)
+	if fp == "" {
+		return "", nil
+	}
+	if alg, digest, ok := strings.Cut(fp, "="); ok && strings.EqualFold(strings.TrimSpace(alg), "sha256 fingerprint") {
+		fp = strings.TrimSpace(digest)
+	}
+	if len(fp) > 7 && strings.EqualFold(fp[:7], "sha256:") {
+		fp = fp[7:]
+	}
+	fp = strings.ToLower(strings.ReplaceAll(fp, ":", ""))
+	if _, err := hex.DecodeString(fp); err != nil || len(fp) != 2*sha256.Size {
+		return "", fmt.Errorf("%q is not a SHA-256 digest in hex", given)
+	}
+	return fp, nil
+}
@@ -0,0 +1,23 @@
+package key
+
+import "time"
+
+// CompromiseAction is the remediation applied to a key reported as leaked.
+type CompromiseAction string
+
+const (
+	// CompromiseRevoke permanently revokes the key.
+	CompromiseRevoke CompromiseAction = "revoke"
+
+	// CompromiseRotate rotates the key with no grace period, so the leaked
+	// secret stops working immediately.
+	CompromiseRotate CompromiseAction = "rotate"
+)
+
+// CompromiseReport describes a reported key leak.
+type CompromiseReport struct {
+	Source     string           `json:"source"`
+	Details    string           `json:"details,omitempty"`
+	Action     CompromiseAction `json:"action"`
+	ReportedAt time.Time        `json:"reported_at"`
+}
@@ -0,0 +1,105 @@
+package key
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// ContactType is the kind of address a Contact holds.
+type ContactType string
+
+const (
+	// ContactEmail is an email address.
+	ContactEmail ContactType = "email"
+
+	// ContactWebhook is an http or https URL notifications are posted to.
+	ContactWebhook ContactType = "webhook"
+
+	// ContactSlack is a Slack channel reference, such as "#payments-oncall"
+	// or a channel ID.
+	ContactSlack ContactType = "slack"
+)
+
+// Valid reports whether t is a known contact type.
+func (t ContactType) Valid() bool {
+	switch t {
+	case ContactEmail, ContactWebhook, ContactSlack:
+		return true
+	}
+	return false
+}
+
+// Contact is where notifications about a key's lifecycle go, such as
+// expiry or compromise.
+type Contact struct {
+	Type   ContactType `json:"type"`
+	Target string      `json:"target"`
+}
+
+// Validate reports a contact of unknown type or with a malformed target.
+func (c Contact) Validate() error {
+	target := strings.TrimSpace(c.Target)
+	if target == "" {
+		return fmt.Errorf("%s contact has no target", c.Type)
+	}
+	switch c.Type {
+	case ContactEmail:
+		if a, err := mail.ParseAddress(target); err != nil || a.Name != "" {
+			return fmt.Errorf("invalid email address %q", c.Target)
+		}
+	case ContactWebhook:
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid webhook URL %q", c.Target)
+		}
+	case ContactSlack:
+		if strings.ContainsAny(target, " \t\n") {
+			return fmt.Errorf("invalid slack channel %q", c.Target)
+		}
+	default:
+		return fmt.Errorf("unknown contact type %q", c.Type)
+	}
+	return nil
+}
+
+// Contacts is a list of contacts.
+type Contacts []Contact
+
+// Validate checks every contact.
+func (cs Contacts) Validate() error {
+	var errs []error
+	for _, c := range cs {
+		if err := c.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Normalize returns the contacts with targets trimmed and duplicates
+// removed, in their original order, or nil when there are none.
+func (cs Contacts) Normalize() Contacts {
+	var out Contacts
+	for _, c := range cs {
+		c.Target = strings.TrimSpace(c.Target)
+		if !slices.Contains(out, c) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// EffectiveContacts returns the contacts notifications about k go to: the
+// key's own when it has any, and the tenant's defaults otherwise. Key
+// contacts replace the defaults rather than adding to them, so a team that
+// owns a key is not joined by the tenant admin.
+func EffectiveContacts(k *Key, tenantDefaults Contacts) Contacts {
+	if len(k.Contacts) > 0 {
+		return k.Contacts
+	}
+	return tenantDefaults
+}
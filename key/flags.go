@@ -0,0 +1,52 @@
+package key
+
+import "slices"
+
+// Flag is a per-key switch that changes how validation treats the key. Flags
+// are for exceptions, such as one legacy integration that cannot send an
+// Origin header; policies remain the way to configure groups of keys.
+type Flag string
+
+const (
+	// FlagSkipOriginCheck disables the allowed-origins check.
+	FlagSkipOriginCheck Flag = "skip_origin_check"
+
+	// FlagSkipIPCheck disables the allowed-IPs check.
+	FlagSkipIPCheck Flag = "skip_ip_check"
+
+	// FlagExtendedGraceEligible keeps a rotated key valid for the engine's
+	// extended grace period after its normal grace period ends.
+	FlagExtendedGraceEligible Flag = "extended_grace_eligible"
+)
+
+// Valid reports whether f is a known flag.
+func (f Flag) Valid() bool {
+	switch f {
+	case FlagSkipOriginCheck, FlagSkipIPCheck, FlagExtendedGraceEligible:
+		return true
+	}
+	return false
+}
+
+// SkipsCheck reports whether f turns a validation check off. Setting such a
+// flag is restricted to admin contexts.
+func (f Flag) SkipsCheck() bool {
+	return f == FlagSkipOriginCheck || f == FlagSkipIPCheck
+}
+
+// Flags is a set of flags. The zero value is empty.
+type Flags []Flag
+
+// Has reports whether f is set.
+func (fs Flags) Has(f Flag) bool { return slices.Contains(fs, f) }
+
+// Normalize returns the flags sorted with duplicates removed, or nil when
+// there are none.
+func (fs Flags) Normalize() Flags {
+	if len(fs) == 0 {
+		return nil
+	}
+	out := slices.Clone(fs)
+	slices.Sort(out)
+	return slices.Compact(out)
+}
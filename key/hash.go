@@ -0,0 +1,137 @@
+package key
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// Hash algorithm names used as the prefix of a canonical stored hash.
+const (
+	HashAlgSHA256     = "sha256"
+	HashAlgHMACSHA256 = "hmac-sha256"
+)
+
+// hashAlgs are the algorithms a bare legacy digest may have been made with.
+var hashAlgs = []string{HashAlgSHA256, HashAlgHMACSHA256}
+
+// FormatHash returns the canonical stored form of a digest made with alg:
+// "<alg>:<lowercase hex>", such as "sha256:9f86d0...".
+func FormatHash(alg string, digest []byte) string {
+	return string(AppendHash(nil, alg, digest))
+}
+
+// AppendHash appends the canonical stored form of a digest made with alg to
+// dst, as [FormatHash] returns it, and returns the extended buffer.
+func AppendHash(dst []byte, alg string, digest []byte) []byte {
+	dst = append(dst, alg...)
+	dst = append(dst, ':')
+	return hex.AppendEncode(dst, digest)
+}
+
+// NormalizeHash returns hash in canonical form. The algorithm prefix is
+// lowercased, and a digest in hex of either case or in base64 (standard or
+// URL alphabet, with or without padding) becomes lowercase hex. A bare
+// digest, the format stored before algorithm prefixes, stays bare. A digest
+// that is neither hex nor base64 is returned as given with ok false, so
+// hashes from custom hashers still match exactly.
+func NormalizeHash(hash string) (normalized string, ok bool) {
+	hash = strings.TrimSpace(hash)
+	alg, digest, prefixed := strings.Cut(hash, ":")
+	if !prefixed {
+		alg, digest = "", hash
+	}
+	if alg == "" && prefixed {
+		return hash, false
+	}
+	norm, ok := normalizeDigest(digest)
+	if !ok {
+		return hash, false
+	}
+	if !prefixed {
+		return norm, true
+	}
+	return strings.ToLower(alg) + ":" + norm, true
+}
+
+// SplitHash returns the algorithm and digest of a hash as given. alg is
+// empty for a bare digest.
+func SplitHash(hash string) (alg, digest string) {
+	if alg, digest, ok := strings.Cut(hash, ":"); ok {
+		return alg, digest
+	}
+	return "", hash
+}
+
+// IsCanonicalHash reports whether hash carries an algorithm prefix and is
+// already normalized.
+func IsCanonicalHash(hash string) bool {
+	norm, ok := NormalizeHash(hash)
+	alg, _ := SplitHash(norm)
+	return ok && alg != "" && norm == hash
+}
+
+// LookupHashes returns the stored values a lookup for hashes must match
+// while bare legacy digests remain in the store. For each hash these are the
+// hash as given, its canonical form, and its digest bare in lowercase and
+// uppercase hex; a bare hash also matches its canonical form under each
+// built-in algorithm. The result has no duplicates.
+func LookupHashes(hashes ...string) []string {
+	forms := make([]string, 0, 4*len(hashes))
+	seen := make(map[string]struct{}, cap(forms))
+	add := func(v string) {
+		if _, ok := seen[v]; !ok {
+			seen[v] = struct{}{}
+			forms = append(forms, v)
+		}
+	}
+	for _, hash := range hashes {
+		add(hash)
+		norm, ok := NormalizeHash(hash)
+		add(norm)
+		if !ok {
+			continue
+		}
+		alg, digest := SplitHash(norm)
+		add(digest)
+		add(strings.ToUpper(digest))
+		if alg == "" {
+			for _, a := range hashAlgs {
+				add(a + ":" + digest)
+			}
+		}
+	}
+	return forms
+}
+
+// HashesEqual reports whether a and b are the same hash in any encoding,
+// comparing in constant time. A bare digest equals a prefixed hash with the
+// same digest; two prefixed hashes must also agree on the algorithm.
+func HashesEqual(a, b string) bool {
+	na, _ := NormalizeHash(a)
+	nb, _ := NormalizeHash(b)
+	algA, digestA := SplitHash(na)
+	algB, digestB := SplitHash(nb)
+	if algA != "" && algB != "" && algA != algB {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(digestA), []byte(digestB)) == 1
+}
+
+// normalizeDigest returns digest as lowercase hex, decoding it from hex or
+// base64. Hex is tried first, so a value valid in both is read as hex.
+func normalizeDigest(digest string) (string, bool) {
+	if digest == "" {
+		return "", false
+	}
+	if b, err := hex.DecodeString(digest); err == nil {
+		return hex.EncodeToString(b), true
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if b, err := enc.DecodeString(digest); err == nil && len(b) > 0 {
+			return hex.EncodeToString(b), true
+		}
+	}
+	return "", false
+}
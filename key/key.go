@@ -2,6 +2,7 @@
 package key
 
 import (
+	"errors"
 	"time"
 
 	"github.com/xraph/keysmith/id"
@@ -27,6 +28,11 @@ const (
 	StateSuspended State = "suspended"
 )
 
+// States returns every state.
+func States() []State {
+	return []State{StateActive, StateRotated, StateExpired, StateRevoked, StateSuspended}
+}
+
 // Environment represents the key environment.
 type Environment string
 
@@ -41,6 +47,11 @@ const (
 	EnvStaging Environment = "staging"
 )
 
+// Environments returns every environment.
+func Environments() []Environment {
+	return []Environment{EnvLive, EnvTest, EnvStaging}
+}
+
 // Key is the core API key entity. The raw key value is never persisted;
 // only the hash is stored. The raw key is returned exactly once at creation.
 type Key struct {
@@ -58,27 +69,137 @@ type Key struct {
 	Scopes      []string       `json:"scopes,omitempty" db:"-"`
 	Metadata    map[string]any `json:"metadata,omitempty" db:"metadata"`
 	CreatedBy   string         `json:"created_by,omitempty" db:"created_by"`
-	ExpiresAt   *time.Time     `json:"expires_at,omitempty" db:"expires_at"`
-	LastUsedAt  *time.Time     `json:"last_used_at,omitempty" db:"last_used_at"`
-	RotatedAt   *time.Time     `json:"rotated_at,omitempty" db:"rotated_at"`
-	RevokedAt   *time.Time     `json:"revoked_at,omitempty" db:"revoked_at"`
-	CreatedAt   time.Time      `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at" db:"updated_at"`
+
+	// PoolID names the quota pool the key draws from. While set, the
+	// pool's quotas replace those of the key's policy.
+	PoolID *id.QuotaPoolID `json:"pool_id,omitempty" db:"pool_id"`
+
+	// ExternalRef is the caller's own identifier for the key, such as the
+	// ID of the record it was provisioned for. It is unique within the
+	// tenant and cannot be changed once set.
+	ExternalRef string `json:"external_ref,omitempty" db:"external_ref"`
+
+	// Labels are indexed key=value pairs for organizing and selecting keys.
+	// See [Labels] and [Selector].
+	Labels Labels `json:"labels,omitempty" db:"labels"`
+
+	// AllowedOrigins restricts the browser origins the key validates from.
+	// When non-empty it replaces the policy's AllowedOrigins.
+	AllowedOrigins []string `json:"allowed_origins,omitempty" db:"allowed_origins"`
+
+	// IntendedConsumer names the service the key is issued to, e.g.
+	// "billing-worker". Validations that report a different consumer service
+	// are flagged as mismatches. Empty disables the check.
+	IntendedConsumer string `json:"intended_consumer,omitempty" db:"intended_consumer"`
+
+	// EnforceConsumer rejects mismatched validations instead of flagging
+	// them.
+	EnforceConsumer bool `json:"enforce_consumer,omitempty" db:"enforce_consumer"`
+
+	// CertFingerprint pins the key to one client certificate: the SHA-256
+	// fingerprint of its DER encoding, as [CertFingerprint] returns it.
+	// Validations over a connection without that certificate fail. Empty
+	// disables the check.
+	CertFingerprint string `json:"cert_fingerprint,omitempty" db:"cert_fingerprint"`
+
+	// AcceptedTermsVersion and AcceptedTermsAt record the terms of service
+	// version accepted for the key and when, as proof of consent. They are
+	// set at creation and never rewritten when the current version changes.
+	AcceptedTermsVersion string     `json:"accepted_terms_version,omitempty" db:"accepted_terms_version"`
+	AcceptedTermsAt      *time.Time `json:"accepted_terms_at,omitempty" db:"accepted_terms_at"`
+
+	// Flags holds per-key exceptions to validation; see [Flag].
+	Flags Flags `json:"flags,omitempty" db:"flags"`
+
+	// Contacts are where notifications about the key go. When empty, the
+	// tenant's DefaultContacts are used; see [EffectiveContacts].
+	Contacts Contacts `json:"contacts,omitempty" db:"contacts"`
+
+	// DebugSampleRate is the fraction of requests, from 0 to 1, captured
+	// for debugging until DebugUntil. Zero disables capture.
+	DebugSampleRate float64    `json:"debug_sample_rate,omitempty" db:"debug_sample_rate"`
+	DebugUntil      *time.Time `json:"debug_until,omitempty" db:"debug_until"`
+
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty" db:"rotated_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// DebugRate returns the debug capture sample rate in effect at now. It is
+// zero once DebugUntil has passed, so capture switches itself off.
+func (k *Key) DebugRate(now time.Time) float64 {
+	if k.DebugUntil == nil || !now.Before(*k.DebugUntil) {
+		return 0
+	}
+	return k.DebugSampleRate
 }
 
 // CreateResult is returned from key creation. The RawKey is shown exactly once.
 type CreateResult struct {
 	Key    *Key   `json:"key"`
 	RawKey string `json:"raw_key"`
+
+	// DeliveredTo is the secrets-manager path the raw key was written to.
+	// When set, RawKey holds the same path instead of the secret.
+	DeliveredTo string `json:"delivered_to,omitempty"`
+
+	// DuplicateOfExisting is set when the create named an ExternalRef that
+	// a key in the tenant already has. Key is that key and RawKey is empty;
+	// rotate the key to get a new secret.
+	DuplicateOfExisting bool `json:"duplicate_of_existing,omitempty"`
 }
 
+// ErrExternalRefInUse is returned by Store.Create for a key whose
+// ExternalRef another key in its tenant already has.
+var ErrExternalRefInUse = errors.New("key: external reference belongs to another key")
+
+// MetadataDeliveredTo is the key metadata entry recording the secrets-manager
+// path a key's raw value was delivered to. The secret itself is never stored.
+const MetadataDeliveredTo = "keysmith.delivered_to"
+
 // ListFilter contains filters for listing keys.
 type ListFilter struct {
 	TenantID    string       `json:"tenant_id,omitempty"`
+	AppID       string       `json:"app_id,omitempty"`
 	Environment Environment  `json:"environment,omitempty"`
 	State       State        `json:"state,omitempty"`
 	PolicyID    *id.PolicyID `json:"policy_id,omitempty"`
 	CreatedBy   string       `json:"created_by,omitempty"`
-	Limit       int          `json:"limit,omitempty"`
-	Offset      int          `json:"offset,omitempty"`
+	ExternalRef string       `json:"external_ref,omitempty"`
+
+	// Name restricts the results to keys with this name, compared
+	// case-insensitively.
+	Name string `json:"name,omitempty"`
+
+	// ExpiresBefore restricts the results to keys with an expiry earlier
+	// than this time.
+	ExpiresBefore *time.Time `json:"expires_before,omitempty"`
+
+	// CreatedAfter, CreatedBefore, and UpdatedAfter restrict the results by
+	// creation and last modification time, for incremental syncs. All bounds
+	// are exclusive. UpdatedAt changes on every write to a key except
+	// UpdateLastUsed.
+	CreatedAfter  *time.Time `json:"created_after,omitempty"`
+	CreatedBefore *time.Time `json:"created_before,omitempty"`
+	UpdatedAfter  *time.Time `json:"updated_after,omitempty"`
+
+	// OutdatedTerms restricts the results to keys whose
+	// AcceptedTermsVersion differs from this version, including keys that
+	// accepted none.
+	OutdatedTerms string `json:"outdated_terms,omitempty"`
+
+	// LabelSelector restricts the results to keys whose labels satisfy it.
+	LabelSelector Selector `json:"label_selector,omitempty"`
+
+	// GroupID restricts the results to members of the group.
+	GroupID *id.GroupID `json:"group_id,omitempty"`
+
+	// PoolID restricts the results to members of the quota pool.
+	PoolID *id.QuotaPoolID `json:"pool_id,omitempty"`
+
+	Limit  int `json:"limit,omitempty"`
+	Offset int `json:"offset,omitempty"`
 }
@@ -0,0 +1,130 @@
+package key
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Label limits, following Kubernetes label syntax.
+const (
+	// MaxLabels is the most labels a key may carry.
+	MaxLabels = 64
+
+	// MaxLabelNameLength bounds a label name, not counting its prefix.
+	MaxLabelNameLength = 63
+
+	// MaxLabelPrefixLength bounds the DNS subdomain before a name's "/".
+	MaxLabelPrefixLength = 253
+
+	// MaxLabelValueLength bounds a label value.
+	MaxLabelValueLength = 63
+)
+
+// Labels are short key=value pairs for organizing keys, such as
+// team=payments. Unlike Metadata they are indexed by the stores and can be
+// filtered on with a [Selector].
+//
+// A name is up to 63 letters, digits, '-', '_', and '.', starting and ending
+// with a letter or digit, optionally behind a DNS subdomain prefix and a
+// slash, as in example.com/team. A value follows the same rules without the
+// prefix and may be empty.
+type Labels map[string]string
+
+// Validate reports labels that break the naming rules or exceed MaxLabels.
+func (l Labels) Validate() error {
+	if len(l) > MaxLabels {
+		return fmt.Errorf("%d labels exceed the limit of %d", len(l), MaxLabels)
+	}
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(l)) {
+		if err := ValidateLabelName(name); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := ValidateLabelValue(l[name]); err != nil {
+			errs = append(errs, fmt.Errorf("label %q: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Clone returns a copy of l, or nil when l is empty.
+func (l Labels) Clone() Labels {
+	if len(l) == 0 {
+		return nil
+	}
+	return maps.Clone(l)
+}
+
+// ValidateLabelName reports a name that is not a valid label name.
+func ValidateLabelName(name string) error {
+	prefix, rest, hasPrefix := strings.Cut(name, "/")
+	if hasPrefix {
+		if prefix == "" || len(prefix) > MaxLabelPrefixLength || !isDNSSubdomain(prefix) {
+			return fmt.Errorf("invalid label name %q: prefix must be a DNS subdomain", name)
+		}
+	} else {
+		rest = name
+	}
+	if rest == "" {
+		return fmt.Errorf("invalid label name %q: name is empty", name)
+	}
+	if len(rest) > MaxLabelNameLength {
+		return fmt.Errorf("invalid label name %q: longer than %d characters", name, MaxLabelNameLength)
+	}
+	if !isLabelToken(rest) {
+		return fmt.Errorf("invalid label name %q: use letters, digits, '-', '_', and '.', starting and ending with a letter or digit", name)
+	}
+	return nil
+}
+
+// ValidateLabelValue reports a value that is not a valid label value.
+func ValidateLabelValue(value string) error {
+	if value == "" {
+		return nil
+	}
+	if len(value) > MaxLabelValueLength {
+		return fmt.Errorf("invalid label value %q: longer than %d characters", value, MaxLabelValueLength)
+	}
+	if !isLabelToken(value) {
+		return fmt.Errorf("invalid label value %q: use letters, digits, '-', '_', and '.', starting and ending with a letter or digit", value)
+	}
+	return nil
+}
+
+// isLabelToken reports whether s is alphanumeric at both ends with only
+// alphanumerics, '-', '_', and '.' between.
+func isLabelToken(s string) bool {
+	if s == "" || !isAlnum(s[0]) || !isAlnum(s[len(s)-1]) {
+		return false
+	}
+	for i := 1; i < len(s)-1; i++ {
+		if c := s[i]; !isAlnum(c) && c != '-' && c != '_' && c != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// isDNSSubdomain reports whether s is dot-separated lowercase labels of
+// alphanumerics and inner hyphens.
+func isDNSSubdomain(s string) bool {
+	for part := range strings.SplitSeq(s, ".") {
+		if part == "" || len(part) > 63 || part[0] == '-' || part[len(part)-1] == '-' {
+			return false
+		}
+		for i := range len(part) {
+			if c := part[i]; (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isAlnum(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
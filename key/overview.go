@@ -0,0 +1,66 @@
+package key
+
+import "time"
+
+// OverviewQuery selects the keys [Store.Overview] counts and the windows it
+// counts them over. Empty TenantID and AppID count every tenant and app.
+type OverviewQuery struct {
+	TenantID string `json:"tenant_id,omitempty"`
+	AppID    string `json:"app_id,omitempty"`
+
+	// Now is the time the windows are measured from.
+	Now time.Time `json:"now"`
+
+	// ExpiringWithin lists windows after Now, shortest first. Each counts
+	// the active keys whose ExpiresAt falls after Now and no later than
+	// Now plus the window, so the windows overlap: a key expiring tomorrow
+	// is counted in every one.
+	ExpiringWithin []time.Duration `json:"expiring_within"`
+
+	// CreatedWithin is the window before Now that CreatedRecently counts
+	// keys created in, regardless of their state.
+	CreatedWithin time.Duration `json:"created_within"`
+}
+
+// Overview counts keys by lifecycle, as [Store.Overview] computes it for an
+// [OverviewQuery].
+type Overview struct {
+	Total int64 `json:"total"`
+
+	// ByState and ByEnvironment count every key by its state and
+	// environment. States and environments without keys are absent.
+	ByState       map[State]int64       `json:"by_state"`
+	ByEnvironment map[Environment]int64 `json:"by_environment"`
+
+	// Expiring holds one count per OverviewQuery.ExpiringWithin window, in
+	// the same order.
+	Expiring []WindowCount `json:"expiring"`
+
+	// NeverUsed counts the active keys that have never been validated.
+	NeverUsed int64 `json:"never_used"`
+
+	// CreatedRecently counts the keys created within
+	// OverviewQuery.CreatedWithin.
+	CreatedRecently WindowCount `json:"created_recently"`
+}
+
+// WindowCount is how many keys fell within one window of an overview.
+type WindowCount struct {
+	Within time.Duration `json:"within"`
+	Count  int64         `json:"count"`
+}
+
+// NewOverview returns an empty overview for q, with a zero count for each
+// of its windows.
+func NewOverview(q *OverviewQuery) *Overview {
+	o := &Overview{
+		ByState:         make(map[State]int64),
+		ByEnvironment:   make(map[Environment]int64),
+		Expiring:        make([]WindowCount, len(q.ExpiringWithin)),
+		CreatedRecently: WindowCount{Within: q.CreatedWithin},
+	}
+	for i, w := range q.ExpiringWithin {
+		o.Expiring[i].Within = w
+	}
+	return o
+}
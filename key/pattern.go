@@ -0,0 +1,115 @@
+package key
+
+import (
+	"time"
+
+	"github.com/xraph/keysmith/id"
+)
+
+// FailurePattern describes a burst of validation failures that share a
+// fingerprint, which may indicate someone probing with a leaked or guessed
+// key. It never carries the raw key: Fingerprint is the key's prefix plus
+// the first characters of the SHA-256 of the raw value.
+type FailurePattern struct {
+	Fingerprint string        `json:"fingerprint"`
+	Count       int           `json:"count"`
+	Window      time.Duration `json:"window"`
+	FirstSeen   time.Time     `json:"first_seen"`
+	LastSeen    time.Time     `json:"last_seen"`
+}
+
+// AdaptivePenalty describes a temporary rate-limit reduction applied to a key
+// whose requests mostly fail, such as a misconfigured client retrying a
+// request that is always refused.
+type AdaptivePenalty struct {
+	// ErrorRatio is the fraction of the key's recent requests that failed,
+	// and Requests how many requests it was measured over.
+	ErrorRatio float64 `json:"error_ratio"`
+	Requests   int     `json:"requests"`
+
+	// BaseLimit is the key's normal rate limit and Limit the reduced one.
+	BaseLimit int `json:"base_limit"`
+	Limit     int `json:"limit"`
+
+	// Since is when the penalty started. Until is when it lifts unless the
+	// error ratio is still over the threshold then.
+	Since time.Time `json:"since"`
+	Until time.Time `json:"until"`
+}
+
+// QuotaForecast projects a key's usage over the current calendar month, in
+// UTC, against its policy's monthly quota, or for a key in a quota pool the
+// pool's usage against the pool's monthly limit.
+type QuotaForecast struct {
+	// PoolID is set when the forecast is of the key's quota pool.
+	PoolID *id.QuotaPoolID `json:"pool_id,omitempty"`
+
+	// Month is the start of the month forecast, and AsOf the time the
+	// forecast was made.
+	Month time.Time `json:"month"`
+	AsOf  time.Time `json:"as_of"`
+
+	// Quota is the effective monthly quota and Used the requests recorded
+	// so far this month.
+	Quota int64 `json:"quota"`
+	Used  int64 `json:"used"`
+
+	// BurnRate is the average number of requests per day so far this month,
+	// and ProjectedTotal the month-end count if that rate holds.
+	BurnRate       float64 `json:"burn_rate"`
+	ProjectedTotal int64   `json:"projected_total"`
+
+	// ProjectedExhaustion is when Used reaches Quota at the current rate,
+	// or the day it did for a quota already exhausted. It is nil when the
+	// projection stays under the quota through the month.
+	ProjectedExhaustion *time.Time `json:"projected_exhaustion,omitempty"`
+}
+
+// QuotaExceeded describes the quota window a validation was refused for:
+// the key's daily or monthly policy quota, or its quota pool's limit.
+type QuotaExceeded struct {
+	// PoolID is set when the quota is the key's quota pool's.
+	PoolID *id.QuotaPoolID `json:"pool_id,omitempty"`
+
+	// Period is "day" or "month". The window runs from WindowStart to
+	// WindowEnd, when the quota resets.
+	Period      string    `json:"period"`
+	WindowStart time.Time `json:"window_start"`
+	WindowEnd   time.Time `json:"window_end"`
+
+	// Limit is the window's quota and Used the requests counted toward it.
+	Limit int64 `json:"limit"`
+	Used  int64 `json:"used"`
+}
+
+// RotationDue reports that a key's policy rotation period is almost over.
+// The period runs from the key's last rotation, or its creation when it was
+// never rotated.
+type RotationDue struct {
+	// DueAt is when the rotation period ends.
+	DueAt time.Time `json:"due_at"`
+
+	// DaysRemaining is the number of whole days until DueAt, negative once
+	// the key is overdue by a day or more.
+	DaysRemaining int `json:"days_remaining"`
+
+	// Overdue is set once DueAt has passed.
+	Overdue bool `json:"overdue"`
+}
+
+// FormatDeprecation reports that a key is in a raw key format flagged
+// deprecated. The key keeps validating; the notice tells its holder to
+// replace it before Sunset.
+type FormatDeprecation struct {
+	// Format names the key's format.
+	Format string `json:"format"`
+
+	// Since is when the format was deprecated, and Sunset when keys in it
+	// are planned to stop validating. Either may be zero when not
+	// announced.
+	Since  time.Time `json:"since,omitzero"`
+	Sunset time.Time `json:"sunset,omitzero"`
+
+	// Link points to migration documentation, when configured.
+	Link string `json:"link,omitempty"`
+}
@@ -0,0 +1,297 @@
+package key
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Search fields are the names MatchSearch reports a term matched in. A
+// metadata value is reported as SearchFieldMetadata plus its entry's name,
+// such as "metadata.customer".
+const (
+	SearchFieldName        = "name"
+	SearchFieldDescription = "description"
+	SearchFieldMetadata    = "metadata."
+)
+
+// Field weights rank a match in a key's name over one in its description,
+// and both over one in a metadata value.
+const (
+	nameWeight        = 3
+	descriptionWeight = 2
+	metadataWeight    = 1
+)
+
+// snippetWords is how many words of context a snippet keeps on each side
+// of the first match.
+const snippetWords = 6
+
+// SearchTerm is one term or quoted phrase of a search query, as the words
+// a field must contain, consecutively and in order, to match it.
+type SearchTerm []string
+
+// String returns the term's words separated by spaces, which is how the
+// backends query their full-text indexes for it.
+func (t SearchTerm) String() string { return strings.Join(t, " ") }
+
+// ParseSearchTerms splits a search query into terms and double-quoted
+// phrases. Both are reduced to their words with SearchWords, so a term with
+// punctuation such as "acme-mobile" is matched as the phrase "acme mobile".
+// An unterminated quote runs to the end of the query, and terms without
+// words are dropped.
+func ParseSearchTerms(q string) []SearchTerm {
+	var terms []SearchTerm
+	add := func(s string) {
+		if words := SearchWords(s); len(words) > 0 {
+			terms = append(terms, words)
+		}
+	}
+	for q != "" {
+		q = strings.TrimLeftFunc(q, unicode.IsSpace)
+		if strings.HasPrefix(q, `"`) {
+			phrase, rest, _ := strings.Cut(q[1:], `"`)
+			add(phrase)
+			q = rest
+			continue
+		}
+		end := strings.IndexFunc(q, func(r rune) bool { return unicode.IsSpace(r) || r == '"' })
+		if end < 0 {
+			end = len(q)
+		}
+		add(q[:end])
+		q = q[end:]
+	}
+	return terms
+}
+
+// SearchWords splits s into lowercase words: runs of letters and digits.
+// Everything else separates words. Search matches whole words only, which
+// every backend's full-text index can answer the same way.
+func SearchWords(s string) []string {
+	spans := wordSpans(s)
+	words := make([]string, len(spans))
+	for i, sp := range spans {
+		words[i] = sp.word
+	}
+	return words
+}
+
+// SearchText returns the words of every searchable field of k, separated
+// by spaces. Backends store it with the key and index it for full-text
+// search; the index only narrows the candidates, which MatchSearch then
+// checks field by field.
+func SearchText(k *Key) string {
+	var words []string
+	for _, f := range searchFields(k) {
+		words = append(words, SearchWords(f.text)...)
+	}
+	return strings.Join(words, " ")
+}
+
+// SearchQuery selects the keys [Store.Search] returns. Empty TenantID and
+// AppID search every tenant and app.
+type SearchQuery struct {
+	TenantID string `json:"tenant_id,omitempty"`
+	AppID    string `json:"app_id,omitempty"`
+
+	// Terms must each match a field of a key for it to be returned.
+	Terms []SearchTerm `json:"terms"`
+
+	// Limit caps the matches returned; zero returns every match.
+	Limit int `json:"limit,omitempty"`
+}
+
+// SearchMatch is a key found by [Store.Search].
+type SearchMatch struct {
+	Key *Key `json:"key"`
+
+	// Fields lists the fields a term matched in, in the order name,
+	// description, then metadata entries by name.
+	Fields []string `json:"fields"`
+
+	// Snippet is the text around the first match in the highest ranked
+	// field, with an ellipsis where it was cut.
+	Snippet string `json:"snippet"`
+
+	// Score ranks the match: each term scores 3 if it matches the name, 2
+	// if it matches the description, and 1 for each metadata value it
+	// matches.
+	Score int `json:"score"`
+}
+
+// MatchSearch returns the match of k for terms, or nil unless every term
+// matches at least one field of k.
+func MatchSearch(k *Key, terms []SearchTerm) *SearchMatch {
+	if len(terms) == 0 {
+		return nil
+	}
+	fields := searchFields(k)
+	matched := make([]bool, len(terms))
+	m := &SearchMatch{Key: k}
+	snippetWeight := 0
+	for _, f := range fields {
+		spans := wordSpans(f.text)
+		first := -1
+		for i, t := range terms {
+			at := findTerm(spans, t)
+			if at < 0 {
+				continue
+			}
+			matched[i] = true
+			m.Score += f.weight
+			if first < 0 || at < first {
+				first = at
+			}
+		}
+		if first < 0 {
+			continue
+		}
+		m.Fields = append(m.Fields, f.name)
+		if f.weight > snippetWeight {
+			snippetWeight = f.weight
+			m.Snippet = snippet(f.text, spans, first)
+		}
+	}
+	if slices.Contains(matched, false) {
+		return nil
+	}
+	return m
+}
+
+// RankSearch sorts matches by score, highest first, then by key creation,
+// newest first, then by key ID, and returns at most limit of them. A limit
+// of zero or less keeps every match. Backends call it on the keys their
+// index found, so every backend ranks the same way.
+func RankSearch(matches []*SearchMatch, limit int) []*SearchMatch {
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if !a.Key.CreatedAt.Equal(b.Key.CreatedAt) {
+			return a.Key.CreatedAt.After(b.Key.CreatedAt)
+		}
+		return a.Key.ID.String() < b.Key.ID.String()
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+type searchField struct {
+	name   string
+	text   string
+	weight int
+}
+
+// searchFields returns the searchable fields of k in reporting order.
+func searchFields(k *Key) []searchField {
+	fields := []searchField{
+		{SearchFieldName, k.Name, nameWeight},
+		{SearchFieldDescription, k.Description, descriptionWeight},
+	}
+	names := make([]string, 0, len(k.Metadata))
+	for name := range k.Metadata {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fields = append(fields, searchField{SearchFieldMetadata + name, metadataText(k.Metadata[name]), metadataWeight})
+	}
+	return fields
+}
+
+// metadataText returns the searchable text of a metadata value: strings
+// as they are, other scalars formatted, and the elements of lists. Nested
+// objects are not searched, since backends decode them into different
+// types.
+func metadataText(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String()
+	case reflect.Slice, reflect.Array:
+	default:
+		return ""
+	}
+	parts := make([]string, rv.Len())
+	for i := range parts {
+		parts[i] = metadataText(rv.Index(i).Interface())
+	}
+	return strings.Join(parts, " ")
+}
+
+// wordSpan is a word of a text with its byte offsets.
+type wordSpan struct {
+	word       string
+	start, end int
+}
+
+func wordSpans(s string) []wordSpan {
+	var spans []wordSpan
+	start := -1
+	for i, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			spans = append(spans, wordSpan{strings.ToLower(s[start:i]), start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, wordSpan{strings.ToLower(s[start:]), start, len(s)})
+	}
+	return spans
+}
+
+// findTerm returns the index of the first word of the first occurrence of
+// t in spans, or -1.
+func findTerm(spans []wordSpan, t SearchTerm) int {
+outer:
+	for i := 0; i+len(t) <= len(spans); i++ {
+		for j, w := range t {
+			if spans[i+j].word != w {
+				continue outer
+			}
+		}
+		return i
+	}
+	return -1
+}
+
+// snippet returns the text of a field around the word at index at.
+func snippet(text string, spans []wordSpan, at int) string {
+	from, to := max(at-snippetWords, 0), min(at+snippetWords, len(spans)-1)
+	start, end := spans[from].start, spans[to].end
+	if from == 0 {
+		start = 0
+	}
+	if to == len(spans)-1 {
+		end = len(text)
+	}
+	out := strings.TrimSpace(text[start:end])
+	if start > 0 {
+		out = "…" + out
+	}
+	if end < len(text) {
+		out += "…"
+	}
+	return out
+}
@@ -0,0 +1,259 @@
+package key
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// MaxSelectorRequirements bounds the requirements in one Selector, so that a
+// selector stays a cheap query.
+const MaxSelectorRequirements = 20
+
+// ErrInvalidSelector is returned by ParseSelector for malformed input.
+var ErrInvalidSelector = errors.New("key: invalid label selector")
+
+// SelectorOp is the operator of a Requirement.
+type SelectorOp string
+
+const (
+	// SelectorEquals matches keys whose label has the value.
+	SelectorEquals SelectorOp = "="
+
+	// SelectorNotEquals matches keys whose label has another value or is
+	// absent.
+	SelectorNotEquals SelectorOp = "!="
+
+	// SelectorIn matches keys whose label has one of the values.
+	SelectorIn SelectorOp = "in"
+
+	// SelectorNotIn matches keys whose label has none of the values or is
+	// absent.
+	SelectorNotIn SelectorOp = "notin"
+
+	// SelectorExists matches keys that have the label.
+	SelectorExists SelectorOp = "exists"
+
+	// SelectorDoesNotExist matches keys without the label.
+	SelectorDoesNotExist SelectorOp = "!"
+)
+
+// Requirement is one condition of a Selector on the label Name. Values has
+// one entry for SelectorEquals and SelectorNotEquals, one or more for
+// SelectorIn and SelectorNotIn, and none otherwise.
+type Requirement struct {
+	Name   string
+	Op     SelectorOp
+	Values []string
+}
+
+// Matches reports whether labels satisfy r.
+func (r Requirement) Matches(labels map[string]string) bool {
+	v, ok := labels[r.Name]
+	switch r.Op {
+	case SelectorEquals, SelectorIn:
+		return ok && slices.Contains(r.Values, v)
+	case SelectorNotEquals, SelectorNotIn:
+		return !ok || !slices.Contains(r.Values, v)
+	case SelectorExists:
+		return ok
+	case SelectorDoesNotExist:
+		return !ok
+	}
+	return false
+}
+
+// String returns r in selector syntax.
+func (r Requirement) String() string {
+	switch r.Op {
+	case SelectorEquals, SelectorNotEquals:
+		return r.Name + string(r.Op) + strings.Join(r.Values, "")
+	case SelectorIn, SelectorNotIn:
+		return r.Name + " " + string(r.Op) + " (" + strings.Join(r.Values, ",") + ")"
+	case SelectorDoesNotExist:
+		return "!" + r.Name
+	}
+	return r.Name
+}
+
+// Selector filters keys by label, in the Kubernetes label selector syntax: a
+// comma-separated list of requirements that must all hold.
+//
+//	team=payments              label equals a value ("==" also works)
+//	team!=payments             label differs or is absent
+//	env in (staging,prod)      label is one of the values
+//	env notin (dev)            label is none of the values or is absent
+//	owner                      label is present
+//	!deprecated                label is absent
+//
+// An empty Selector matches every key.
+type Selector []Requirement
+
+// ParseSelector parses s. An empty or blank s gives an empty Selector.
+// Values of in and notin are sorted and deduplicated.
+func ParseSelector(s string) (Selector, error) {
+	parts, err := splitRequirements(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) > MaxSelectorRequirements {
+		return nil, fmt.Errorf("%w: %d requirements exceed the limit of %d", ErrInvalidSelector, len(parts), MaxSelectorRequirements)
+	}
+	var sel Selector
+	for _, part := range parts {
+		r, err := parseRequirement(part)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %w", ErrInvalidSelector, part, err)
+		}
+		sel = append(sel, r)
+	}
+	return sel, nil
+}
+
+// Matches reports whether labels satisfy every requirement of s.
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s {
+		if !r.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns s in selector syntax; ParseSelector reads it back.
+func (s Selector) String() string {
+	parts := make([]string, len(s))
+	for i, r := range s {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Selector) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Selector) UnmarshalText(b []byte) error {
+	sel, err := ParseSelector(string(b))
+	if err != nil {
+		return err
+	}
+	*s = sel
+	return nil
+}
+
+// splitRequirements splits s at the commas outside parentheses.
+func splitRequirements(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var parts []string
+	depth, start := 0, 0
+	for i := range len(s) {
+		switch s[i] {
+		case '(':
+			depth++
+			if depth > 1 {
+				return nil, fmt.Errorf("%w: nested parentheses", ErrInvalidSelector)
+			}
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("%w: unbalanced parentheses", ErrInvalidSelector)
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("%w: unbalanced parentheses", ErrInvalidSelector)
+	}
+	parts = append(parts, strings.TrimSpace(s[start:]))
+	for _, p := range parts {
+		if p == "" {
+			return nil, fmt.Errorf("%w: empty requirement", ErrInvalidSelector)
+		}
+	}
+	return parts, nil
+}
+
+// parseRequirement parses one trimmed, non-empty requirement.
+func parseRequirement(s string) (Requirement, error) {
+	if name, ok := strings.CutPrefix(s, "!"); ok && !strings.HasPrefix(name, "=") {
+		name = strings.TrimSpace(name)
+		if err := ValidateLabelName(name); err != nil {
+			return Requirement{}, err
+		}
+		return Requirement{Name: name, Op: SelectorDoesNotExist}, nil
+	}
+
+	end := strings.IndexAny(s, " \t=!(")
+	if end < 0 {
+		end = len(s)
+	}
+	name, rest := s[:end], strings.TrimSpace(s[end:])
+	if err := ValidateLabelName(name); err != nil {
+		return Requirement{}, err
+	}
+
+	var op SelectorOp
+	switch {
+	case rest == "":
+		return Requirement{Name: name, Op: SelectorExists}, nil
+	case strings.HasPrefix(rest, "!="):
+		op, rest = SelectorNotEquals, rest[2:]
+	case strings.HasPrefix(rest, "=="):
+		op, rest = SelectorEquals, rest[2:]
+	case strings.HasPrefix(rest, "="):
+		op, rest = SelectorEquals, rest[1:]
+	default:
+		word, list, _ := strings.Cut(rest, "(")
+		switch strings.TrimSpace(word) {
+		case string(SelectorIn):
+			op = SelectorIn
+		case string(SelectorNotIn):
+			op = SelectorNotIn
+		default:
+			return Requirement{}, errors.New("expected =, ==, !=, in, or notin")
+		}
+		values, err := parseValueList(rest[len(word):], list)
+		if err != nil {
+			return Requirement{}, err
+		}
+		return Requirement{Name: name, Op: op, Values: values}, nil
+	}
+
+	value := strings.TrimSpace(rest)
+	if err := ValidateLabelValue(value); err != nil {
+		return Requirement{}, err
+	}
+	return Requirement{Name: name, Op: op, Values: []string{value}}, nil
+}
+
+// parseValueList parses the "(a,b)" of an in or notin requirement; group is
+// the text from the opening parenthesis and list what follows it.
+func parseValueList(group, list string) ([]string, error) {
+	if !strings.HasPrefix(group, "(") || !strings.HasSuffix(list, ")") {
+		return nil, errors.New("expected a parenthesized list of values")
+	}
+	var values []string
+	for v := range strings.SplitSeq(strings.TrimSuffix(list, ")"), ",") {
+		v = strings.TrimSpace(v)
+		if err := ValidateLabelValue(v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	slices.Sort(values)
+	values = slices.Compact(values)
+	if len(values) == 1 && values[0] == "" && strings.TrimSpace(strings.TrimSuffix(list, ")")) == "" {
+		return nil, errors.New("the list of values is empty")
+	}
+	return values, nil
+}
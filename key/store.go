@@ -9,17 +9,83 @@ import (
 
 // Store is the persistence interface for API keys.
 type Store interface {
+	// Create stores the key and an active hash version for its KeyHash. A
+	// non-empty ExternalRef that another key in the tenant has fails with
+	// ErrExternalRefInUse, atomically with the insert.
 	Create(ctx context.Context, key *Key) error
 	Get(ctx context.Context, keyID id.KeyID) (*Key, error)
+
+	// GetByHash returns the key with an active hash version matching hash.
 	GetByHash(ctx context.Context, hash string) (*Key, error)
-	GetByPrefix(ctx context.Context, prefix, hint string) (*Key, error)
+
+	// GetByHashes returns the keys with an active hash version matching one
+	// of hashes, in a single round trip and in no particular order. Hashes
+	// without a key are skipped, so the result may be shorter than hashes.
+	GetByHashes(ctx context.Context, hashes []string) ([]*Key, error)
+
+	// AddHash records an additional hash version for h.KeyID, or reactivates
+	// it if the key already has it. An empty Scheme is taken from the hash.
+	// A missing key is not found, and a hash of another key fails with
+	// ErrHashInUse.
+	AddHash(ctx context.Context, h *HashVersion) error
+
+	// ListHashes returns the key's hash versions, active or not, oldest
+	// first. A missing key returns an empty slice.
+	ListHashes(ctx context.Context, keyID id.KeyID) ([]*HashVersion, error)
+
+	// DeactivateHash stops hash from matching the key. The key's current
+	// KeyHash always stays active; change it with Update instead. A hash
+	// the key does not have is ignored.
+	DeactivateHash(ctx context.Context, keyID id.KeyID, hash string) error
+
+	// ListByPrefixHint returns every key with the given prefix and hint,
+	// oldest first. Hints are only the last four characters of the raw key,
+	// so distinct keys can share a prefix and hint; callers must not assume
+	// a single match. No match returns an empty slice.
+	ListByPrefixHint(ctx context.Context, prefix, hint string) ([]*Key, error)
+
+	// Update replaces the stored key. When KeyHash changes, the new hash
+	// gets an active version and every other version of the key is
+	// deactivated, since they belong to the secret being replaced.
 	Update(ctx context.Context, key *Key) error
 	UpdateState(ctx context.Context, keyID id.KeyID, state State) error
+
+	// UpdateStateIf moves the key to state to only if it is currently in
+	// state from, and reports whether it did. Concurrent callers racing on
+	// the same transition see exactly one success. A missing key reports
+	// false.
+	UpdateStateIf(ctx context.Context, keyID id.KeyID, from, to State) (bool, error)
+
+	// UpdateLastUsed records a successful validation. It deliberately leaves
+	// UpdatedAt alone, so hot keys do not flood UpdatedAfter queries; every
+	// other write bumps it.
 	UpdateLastUsed(ctx context.Context, keyID id.KeyID, at time.Time) error
 	Delete(ctx context.Context, keyID id.KeyID) error
 	List(ctx context.Context, filter *ListFilter) ([]*Key, error)
 	Count(ctx context.Context, filter *ListFilter) (int64, error)
+
+	// Iterate calls fn for every key matching filter, in ascending ID order,
+	// without loading the full result set into memory. Iteration stops at the
+	// first error returned by fn or by the backend, and that error is
+	// returned. filter.Limit caps the number of keys visited.
+	Iterate(ctx context.Context, filter *ListFilter, fn func(*Key) error) error
+
+	// Overview counts the keys matching q by state, environment, and the
+	// windows q sets, in a single pass over them.
+	Overview(ctx context.Context, q *OverviewQuery) (*Overview, error)
+
+	// Search returns the keys matching every term of q, as MatchSearch
+	// decides, ranked with RankSearch. Backends may use a full-text index
+	// to find candidates, but the result must be the same as checking
+	// every key in q's tenant and app.
+	Search(ctx context.Context, q *SearchQuery) ([]*SearchMatch, error)
+
 	ListExpired(ctx context.Context, before time.Time) ([]*Key, error)
+
+	// ListRecentlyUsed returns up to limit keys that have been used, most
+	// recently used first. Keys that were never used are not returned.
+	ListRecentlyUsed(ctx context.Context, limit int) ([]*Key, error)
+
 	ListByPolicy(ctx context.Context, policyID id.PolicyID) ([]*Key, error)
 	DeleteByTenant(ctx context.Context, tenantID string) error
 }
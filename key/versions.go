@@ -0,0 +1,33 @@
+package key
+
+import (
+	"errors"
+	"time"
+
+	"github.com/xraph/keysmith/id"
+)
+
+// ErrHashInUse is returned by Store.AddHash for a hash recorded for another
+// key.
+var ErrHashInUse = errors.New("key: hash belongs to another key")
+
+// HashVersion is one of the hashes a key is looked up by. Every key has a
+// version for its current KeyHash; more can be added, so that a key is found
+// under several hashing schemes, such as while stored hashes are migrated to
+// a new hasher. Inactive versions are kept as history and no longer match.
+type HashVersion struct {
+	KeyID  id.KeyID `json:"key_id"`
+	Hash   string   `json:"hash"`
+	Scheme string   `json:"scheme,omitempty"`
+	Active bool     `json:"active"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// HashScheme returns the algorithm prefix of hash, such as "sha256", or ""
+// for a bare legacy digest.
+func HashScheme(hash string) string {
+	norm, _ := NormalizeHash(hash)
+	alg, _ := SplitHash(norm)
+	return alg
+}
@@ -0,0 +1,113 @@
+// Package keyevent defines the key event history: an append-only log of key
+// lifecycle changes. Event subscribers that reconnect read it to catch up on
+// the changes they missed.
+package keyevent
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+)
+
+// Type is the kind of change an event records.
+type Type string
+
+const (
+	// TypeCreated is a key being created.
+	TypeCreated Type = "key.created"
+
+	// TypeStateChanged is a key being suspended or reactivated.
+	TypeStateChanged Type = "key.state_changed"
+
+	// TypeRotated is a key being rotated.
+	TypeRotated Type = "key.rotated"
+
+	// TypeRevoked is a key being revoked.
+	TypeRevoked Type = "key.revoked"
+
+	// TypeExpired is a key moved to expired, by validation or a sweep.
+	TypeExpired Type = "key.expired"
+)
+
+// Types lists every event type.
+var Types = []Type{TypeCreated, TypeStateChanged, TypeRotated, TypeRevoked, TypeExpired}
+
+// Valid reports whether t is one of [Types].
+func (t Type) Valid() bool { return slices.Contains(Types, t) }
+
+// Event records a change to a key. It never carries the key's hash or raw
+// value.
+type Event struct {
+	Type     Type      `json:"type" db:"type"`
+	KeyID    id.KeyID  `json:"key_id" db:"key_id"`
+	TenantID string    `json:"tenant_id" db:"tenant_id"`
+	AppID    string    `json:"app_id" db:"app_id"`
+	State    key.State `json:"state" db:"state"`
+
+	// Reason is the revocation reason or the rotation reason, and
+	// ReasonCode the event's machine-readable reason code, if any.
+	Reason     string `json:"reason,omitempty" db:"reason"`
+	ReasonCode string `json:"reason_code,omitempty" db:"reason_code"`
+
+	// ActorID is the actor the change was made on behalf of, if any.
+	ActorID string `json:"actor_id,omitempty" db:"actor_id"`
+
+	At time.Time `json:"at" db:"at"`
+}
+
+// ID returns the event's ID: its cursor, encoded.
+func (e *Event) ID() string { return CursorOf(e).String() }
+
+// Cursor is a position in the history. Events are ordered by (At, KeyID), so
+// a cursor taken from an event resumes exactly after it.
+type Cursor struct {
+	At    time.Time
+	KeyID string
+}
+
+// CursorOf returns the cursor positioned at e.
+func CursorOf(e *Event) Cursor {
+	return Cursor{At: e.At, KeyID: e.KeyID.String()}
+}
+
+// String encodes the cursor as "<unix nanos>.<key id>".
+func (c Cursor) String() string {
+	return strconv.FormatInt(c.At.UnixNano(), 10) + "." + c.KeyID
+}
+
+// ParseCursor decodes a cursor produced by Cursor.String.
+func ParseCursor(s string) (Cursor, error) {
+	nanos, keyID, _ := strings.Cut(s, ".")
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("keyevent: invalid cursor %q", s)
+	}
+	return Cursor{At: time.Unix(0, n).UTC(), KeyID: keyID}, nil
+}
+
+// Filter selects events by type and key. The zero Filter matches every
+// event.
+type Filter struct {
+	// Types limits events to the given types.
+	Types []Type
+
+	// KeyID limits events to one key.
+	KeyID *id.KeyID
+}
+
+// Matches reports whether e passes the filter. A nil filter matches every
+// event.
+func (f *Filter) Matches(e *Event) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.Types) > 0 && !slices.Contains(f.Types, e.Type) {
+		return false
+	}
+	return f.KeyID == nil || *f.KeyID == e.KeyID
+}
@@ -0,0 +1,42 @@
+package keyevent
+
+import (
+	"context"
+	"time"
+
+	"github.com/xraph/keysmith/id"
+)
+
+// ListFilter selects the events returned by Store.ListAfter.
+type ListFilter struct {
+	// TenantID and AppID limit events to a tenant and app. Empty matches
+	// all.
+	TenantID string
+	AppID    string
+
+	// KeyID limits events to one key.
+	KeyID *id.KeyID
+
+	// Types limits events to the given types. Empty matches every type.
+	Types []Type
+
+	// After is the cursor the events follow.
+	After Cursor
+
+	// Limit caps the number of events. Zero returns all.
+	Limit int
+}
+
+// Store is the persistence interface for the key event history.
+type Store interface {
+	// Append adds an event to the history. An event at the same (At,
+	// KeyID) as a stored one is ignored.
+	Append(ctx context.Context, e *Event) error
+
+	// ListAfter returns the events matching the filter positioned after
+	// filter.After, in (At, KeyID) order.
+	ListAfter(ctx context.Context, filter *ListFilter) ([]*Event, error)
+
+	// Purge deletes events recorded before the given time.
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,271 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/xraph/go-utils/log"
+
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keyevent"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/rotation"
+)
+
+// Key event defaults.
+const (
+	// DefaultKeyEventBuffer is how many events a subscriber may fall behind
+	// before it is dropped. See [WithKeyEventBuffer].
+	DefaultKeyEventBuffer = 256
+
+	// DefaultKeyEventPageSize is the number of events KeyEventsSince
+	// returns when the caller does not ask for a limit.
+	DefaultKeyEventPageSize = 500
+
+	// MaxKeyEventPageSize caps the number of events in one page.
+	MaxKeyEventPageSize = 1000
+
+	// DefaultKeyEventLookback is how far back the key event history can be
+	// read. See [WithKeyEventLookback].
+	DefaultKeyEventLookback = 7 * 24 * time.Hour
+)
+
+// KeyEventPage is one page of the key event history.
+type KeyEventPage struct {
+	// Events are ordered oldest first.
+	Events []*keyevent.Event `json:"events"`
+
+	// Next is the cursor to resume from. It equals the requested cursor
+	// when there is nothing new.
+	Next string `json:"next"`
+
+	// HasMore reports whether more events follow Next.
+	HasMore bool `json:"has_more"`
+}
+
+// SubscribeKeyEvents streams the key lifecycle events — created, suspended
+// or reactivated, rotated, revoked, and expired — of the keys the context
+// may see, from now on. A nil filter receives every type.
+//
+// Each subscriber has a buffer of [WithKeyEventBuffer] events. A subscriber
+// that falls further behind is dropped: its channel is closed rather than
+// letting it hold up the engine or silently lose events, and it catches up
+// from KeyEventsSince with the cursor of the last event it handled. The
+// channel is also closed when ctx ends, cancel is called, or the engine
+// stops. Events only reach subscribers of the engine that made the change;
+// other instances' changes are read from the history.
+func (e *Engine) SubscribeKeyEvents(ctx context.Context, filter *keyevent.Filter) (<-chan *keyevent.Event, func()) {
+	sub := &keyEventSub{
+		scope: scopeFromContext(ctx),
+		ch:    make(chan *keyevent.Event, e.keyEventBuffer),
+	}
+	if filter != nil {
+		sub.filter = *filter
+	}
+	e.events.add(sub)
+
+	stop := context.AfterFunc(ctx, func() { e.events.remove(sub) })
+	return sub.ch, func() {
+		stop()
+		e.events.remove(sub)
+	}
+}
+
+// KeyEventsSince returns the events recorded after the cursor for the keys
+// the context may see. A zero cursor starts at the oldest readable event.
+// Cursors older than the lookback window (see [WithKeyEventLookback])
+// return ErrKeyEventRangeTooLarge.
+func (e *Engine) KeyEventsSince(ctx context.Context, after keyevent.Cursor, filter *keyevent.Filter, limit int) (*KeyEventPage, error) {
+	oldest := e.now().Add(-e.keyEventLookback)
+	switch {
+	case after.At.IsZero():
+		after = keyevent.Cursor{At: oldest}
+	case after.At.Before(oldest):
+		return nil, fmt.Errorf("%w: %s is older than %s", ErrKeyEventRangeTooLarge, after.At.Format(time.RFC3339), e.keyEventLookback)
+	}
+
+	if limit <= 0 {
+		limit = DefaultKeyEventPageSize
+	}
+	limit = min(limit, MaxKeyEventPageSize)
+
+	sc := scopeFromContext(ctx)
+	f := &keyevent.ListFilter{TenantID: sc.tenantID, AppID: sc.appID, After: after, Limit: limit + 1}
+	if filter != nil {
+		f.Types, f.KeyID = filter.Types, filter.KeyID
+	}
+	// Fetch one extra event to learn whether another page follows.
+	events, err := e.store.KeyEvents().ListAfter(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("list key events: %w", err)
+	}
+
+	page := &KeyEventPage{Events: events}
+	if len(events) > limit {
+		page.Events = events[:limit]
+		page.HasMore = true
+	}
+	if n := len(page.Events); n > 0 {
+		after = keyevent.CursorOf(page.Events[n-1])
+	}
+	page.Next = after.String()
+	return page, nil
+}
+
+// PurgeKeyEvents deletes key events older than the lookback window.
+func (e *Engine) PurgeKeyEvents(ctx context.Context) (int64, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return 0, err
+	}
+	n, err := e.store.KeyEvents().Purge(ctx, e.now().Add(-e.keyEventLookback))
+	if err != nil {
+		return 0, fmt.Errorf("purge key events: %w", err)
+	}
+	return n, nil
+}
+
+// keyEventSub is one SubscribeKeyEvents subscription.
+type keyEventSub struct {
+	scope  tenantScope
+	filter keyevent.Filter
+	ch     chan *keyevent.Event
+}
+
+// keyEventHub is the plugin that turns key lifecycle hooks into key events:
+// it records each in the history and fans it out to the subscribers that
+// may see it. NewEngine registers it ahead of any extension, so events are
+// recorded even when a later hook fails.
+type keyEventHub struct {
+	e *Engine
+
+	mu     sync.Mutex
+	subs   map[*keyEventSub]struct{}
+	closed bool // set by closeAll; later subscriptions start closed
+}
+
+var (
+	_ plugin.KeyCreatedV2     = (*keyEventHub)(nil)
+	_ plugin.KeyRotatedV2     = (*keyEventHub)(nil)
+	_ plugin.KeyRevokedV2     = (*keyEventHub)(nil)
+	_ plugin.KeySuspendedV2   = (*keyEventHub)(nil)
+	_ plugin.KeyReactivatedV2 = (*keyEventHub)(nil)
+	_ plugin.KeyExpiredV2     = (*keyEventHub)(nil)
+)
+
+func newKeyEventHub(e *Engine) *keyEventHub {
+	return &keyEventHub{e: e, subs: make(map[*keyEventSub]struct{})}
+}
+
+func (h *keyEventHub) Name() string { return "keysmith-key-events" }
+
+func (h *keyEventHub) OnKeyCreatedV2(ctx context.Context, k *key.Key, meta plugin.EventMeta) error {
+	h.record(ctx, keyevent.TypeCreated, k, k.State, "", meta)
+	return nil
+}
+
+func (h *keyEventHub) OnKeyRotatedV2(ctx context.Context, k *key.Key, rec *rotation.Record, meta plugin.EventMeta) error {
+	h.record(ctx, keyevent.TypeRotated, k, k.State, string(rec.Reason), meta)
+	return nil
+}
+
+func (h *keyEventHub) OnKeyRevokedV2(ctx context.Context, k *key.Key, reason string, meta plugin.EventMeta) error {
+	h.record(ctx, keyevent.TypeRevoked, k, key.StateRevoked, reason, meta)
+	return nil
+}
+
+func (h *keyEventHub) OnKeySuspendedV2(ctx context.Context, k *key.Key, meta plugin.EventMeta) error {
+	h.record(ctx, keyevent.TypeStateChanged, k, key.StateSuspended, "", meta)
+	return nil
+}
+
+func (h *keyEventHub) OnKeyReactivatedV2(ctx context.Context, k *key.Key, meta plugin.EventMeta) error {
+	h.record(ctx, keyevent.TypeStateChanged, k, key.StateActive, "", meta)
+	return nil
+}
+
+func (h *keyEventHub) OnKeyExpiredV2(ctx context.Context, k *key.Key, meta plugin.EventMeta) error {
+	h.record(ctx, keyevent.TypeExpired, k, key.StateExpired, "", meta)
+	return nil
+}
+
+// record appends an event for k, now in state, to the history and publishes
+// it. Some hooks receive the key as it was before the change, so the state
+// is passed in. The change has already been stored, so a failed append is
+// logged rather than returned, and subscribers still receive the event.
+func (h *keyEventHub) record(ctx context.Context, typ keyevent.Type, k *key.Key, state key.State, reason string, meta plugin.EventMeta) {
+	evt := &keyevent.Event{
+		Type:       typ,
+		KeyID:      k.ID,
+		TenantID:   k.TenantID,
+		AppID:      k.AppID,
+		State:      state,
+		Reason:     reason,
+		ReasonCode: string(meta.ReasonCode),
+		ActorID:    meta.ActorID,
+		At:         h.e.keyEventClock.next(h.e.now()),
+	}
+	if err := h.e.store.KeyEvents().Append(context.WithoutCancel(ctx), evt); err != nil {
+		h.e.logger.Warn("failed to record key event",
+			log.String("key_id", evt.KeyID.String()),
+			log.String("type", string(evt.Type)),
+			log.Any("error", err),
+		)
+	}
+	h.publish(evt)
+}
+
+// publish hands evt to every subscriber that may see it without blocking,
+// dropping subscribers whose buffer is full.
+func (h *keyEventHub) publish(evt *keyevent.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		if !sub.scope.owns(evt.TenantID, evt.AppID) || !sub.filter.Matches(evt) {
+			continue
+		}
+		cp := *evt
+		select {
+		case sub.ch <- &cp:
+		default:
+			h.e.logger.Warn("dropping slow key event subscriber",
+				log.String("tenant_id", sub.scope.tenantID),
+				log.Int("buffer", cap(sub.ch)),
+			)
+			delete(h.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+func (h *keyEventHub) add(sub *keyEventSub) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(sub.ch)
+		return
+	}
+	h.subs[sub] = struct{}{}
+}
+
+// remove ends a subscription. Removing one already ended does nothing.
+func (h *keyEventHub) remove(sub *keyEventSub) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// closeAll ends every subscription and refuses new ones.
+func (h *keyEventHub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for sub := range h.subs {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
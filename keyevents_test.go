@@ -0,0 +1,216 @@
+package keysmith_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keyevent"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+func newKeyEventEngine(t *testing.T, opts ...keysmith.Option) *keysmith.Engine {
+	t.Helper()
+	eng, err := keysmith.NewEngine(append([]keysmith.Option{keysmith.WithStore(memory.New())}, opts...)...)
+	require.NoError(t, err)
+	return eng
+}
+
+func createEventKey(t *testing.T, eng *keysmith.Engine, ctx context.Context) *key.Key {
+	t.Helper()
+	result, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
+		Name:        "Event Key",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+	})
+	require.NoError(t, err)
+	return result.Key
+}
+
+// drain returns the events waiting on ch without blocking.
+func drain(ch <-chan *keyevent.Event) []*keyevent.Event {
+	var out []*keyevent.Event
+	for {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				return out
+			}
+			out = append(out, e)
+		default:
+			return out
+		}
+	}
+}
+
+func TestSubscribeKeyEvents_Lifecycle(t *testing.T) {
+	eng := newKeyEventEngine(t)
+	events, cancel := eng.SubscribeKeyEvents(testCtx(), nil)
+	defer cancel()
+
+	k := createEventKey(t, eng, testCtx())
+	require.NoError(t, eng.SuspendKey(testCtx(), k.ID))
+	require.NoError(t, eng.ReactivateKey(testCtx(), k.ID))
+	_, err := eng.RotateKey(testCtx(), k.ID, rotation.ReasonManual)
+	require.NoError(t, err)
+	require.NoError(t, eng.RevokeKey(testCtx(), k.ID, "retired"))
+
+	got := drain(events)
+	require.Len(t, got, 5)
+	types := make([]keyevent.Type, len(got))
+	for i, e := range got {
+		types[i] = e.Type
+		assert.Equal(t, k.ID, e.KeyID)
+		assert.Equal(t, "tenant_test", e.TenantID)
+		if i > 0 {
+			assert.True(t, e.At.After(got[i-1].At), "events are strictly ordered")
+		}
+	}
+	assert.Equal(t, []keyevent.Type{
+		keyevent.TypeCreated, keyevent.TypeStateChanged, keyevent.TypeStateChanged,
+		keyevent.TypeRotated, keyevent.TypeRevoked,
+	}, types)
+	assert.Equal(t, key.StateSuspended, got[1].State)
+	assert.Equal(t, key.StateActive, got[2].State)
+	assert.Equal(t, string(rotation.ReasonManual), got[3].Reason)
+	assert.Equal(t, "retired", got[4].Reason)
+}
+
+func TestSubscribeKeyEvents_Filter(t *testing.T) {
+	eng := newKeyEventEngine(t)
+	a := createEventKey(t, eng, testCtx())
+	b := createEventKey(t, eng, testCtx())
+
+	revoked, cancel := eng.SubscribeKeyEvents(testCtx(), &keyevent.Filter{Types: []keyevent.Type{keyevent.TypeRevoked}})
+	defer cancel()
+	onlyB, cancelB := eng.SubscribeKeyEvents(testCtx(), &keyevent.Filter{KeyID: &b.ID})
+	defer cancelB()
+
+	require.NoError(t, eng.SuspendKey(testCtx(), a.ID))
+	require.NoError(t, eng.RevokeKey(testCtx(), a.ID, "leaked"))
+	require.NoError(t, eng.SuspendKey(testCtx(), b.ID))
+
+	got := drain(revoked)
+	require.Len(t, got, 1)
+	assert.Equal(t, keyevent.TypeRevoked, got[0].Type)
+	assert.Equal(t, a.ID, got[0].KeyID)
+
+	got = drain(onlyB)
+	require.Len(t, got, 1)
+	assert.Equal(t, b.ID, got[0].KeyID)
+}
+
+func TestSubscribeKeyEvents_TenantScoped(t *testing.T) {
+	eng := newKeyEventEngine(t)
+	other := keysmith.WithTenant(context.Background(), "app_test", "tenant_other")
+
+	mine, cancel := eng.SubscribeKeyEvents(testCtx(), nil)
+	defer cancel()
+	system, cancelSystem := eng.SubscribeKeyEvents(context.Background(), nil)
+	defer cancelSystem()
+
+	createEventKey(t, eng, other)
+	k := createEventKey(t, eng, testCtx())
+
+	got := drain(mine)
+	require.Len(t, got, 1, "other tenants' events are not delivered")
+	assert.Equal(t, k.ID, got[0].KeyID)
+	assert.Len(t, drain(system), 2, "the system scope sees every tenant")
+}
+
+func TestSubscribeKeyEvents_DropsStalledConsumer(t *testing.T) {
+	eng := newKeyEventEngine(t, keysmith.WithKeyEventBuffer(2))
+	stalled, cancel := eng.SubscribeKeyEvents(testCtx(), nil)
+	defer cancel()
+	live, cancelLive := eng.SubscribeKeyEvents(testCtx(), nil)
+	defer cancelLive()
+
+	var keys []id.KeyID
+	for range 3 {
+		k := createEventKey(t, eng, testCtx())
+		keys = append(keys, k.ID)
+		require.Len(t, drain(live), 1, "a consumer that keeps up is unaffected")
+	}
+
+	// The third event did not fit: the buffered two are delivered, then the
+	// channel is closed.
+	var got []*keyevent.Event
+	for e := range stalled {
+		got = append(got, e)
+	}
+	require.Len(t, got, 2)
+	assert.Equal(t, keys[:2], []id.KeyID{got[0].KeyID, got[1].KeyID})
+
+	// The dropped consumer catches up from the history.
+	page, err := eng.KeyEventsSince(testCtx(), keyevent.CursorOf(got[1]), nil, 0)
+	require.NoError(t, err)
+	require.Len(t, page.Events, 1)
+	assert.Equal(t, keys[2], page.Events[0].KeyID)
+}
+
+func TestSubscribeKeyEvents_EndsWithContext(t *testing.T) {
+	eng := newKeyEventEngine(t)
+	ctx, cancel := context.WithCancel(testCtx())
+	events, stop := eng.SubscribeKeyEvents(ctx, nil)
+	defer stop()
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("subscription outlived its context")
+	}
+	stop() // Ending it again is a no-op.
+}
+
+func TestSubscribeKeyEvents_ClosedOnShutdown(t *testing.T) {
+	eng := newKeyEventEngine(t)
+	events, cancel := eng.SubscribeKeyEvents(testCtx(), nil)
+	defer cancel()
+
+	require.NoError(t, eng.Stop(context.Background()))
+	_, ok := <-events
+	assert.False(t, ok)
+}
+
+func TestKeyEventsSince_Resume(t *testing.T) {
+	eng := newKeyEventEngine(t)
+	a := createEventKey(t, eng, testCtx())
+	b := createEventKey(t, eng, testCtx())
+	require.NoError(t, eng.RevokeKey(testCtx(), a.ID, "leaked"))
+	createEventKey(t, eng, keysmith.WithTenant(context.Background(), "app_test", "tenant_other"))
+
+	first, err := eng.KeyEventsSince(testCtx(), keyevent.Cursor{}, nil, 2)
+	require.NoError(t, err)
+	require.Len(t, first.Events, 2)
+	assert.True(t, first.HasMore)
+	assert.Equal(t, a.ID, first.Events[0].KeyID)
+	assert.Equal(t, b.ID, first.Events[1].KeyID)
+	assert.Equal(t, first.Events[1].ID(), first.Next)
+
+	cursor, err := keyevent.ParseCursor(first.Next)
+	require.NoError(t, err)
+	rest, err := eng.KeyEventsSince(testCtx(), cursor, nil, 0)
+	require.NoError(t, err)
+	require.Len(t, rest.Events, 1, "only the tenant's events after the cursor")
+	assert.False(t, rest.HasMore)
+	assert.Equal(t, keyevent.TypeRevoked, rest.Events[0].Type)
+
+	created, err := eng.KeyEventsSince(testCtx(), keyevent.Cursor{}, &keyevent.Filter{Types: []keyevent.Type{keyevent.TypeCreated}}, 0)
+	require.NoError(t, err)
+	assert.Len(t, created.Events, 2)
+}
+
+func TestKeyEventsSince_OutsideLookback(t *testing.T) {
+	eng := newKeyEventEngine(t, keysmith.WithKeyEventLookback(time.Hour))
+	_, err := eng.KeyEventsSince(testCtx(), keyevent.Cursor{At: time.Now().Add(-2 * time.Hour)}, nil, 0)
+	assert.ErrorIs(t, err, keysmith.ErrKeyEventRangeTooLarge)
+}
@@ -0,0 +1,133 @@
+package keysmith
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/plugin"
+)
+
+// KeyFormatDefault names the format of [DefaultKeyGenerator]'s keys:
+// {prefix}_{env}_{64 hex chars}.
+const KeyFormatDefault = "default"
+
+// keyFormatDeprecatedInterval is the minimum time between two
+// KeyFormatDeprecated hooks for the same key.
+const keyFormatDeprecatedInterval = 7 * 24 * time.Hour
+
+// KeyFormat is a named raw key format, such as that of one generator
+// version. Match reports whether a raw key is in the format; it is only
+// called for keys that already validated, and must be safe for concurrent
+// use.
+type KeyFormat struct {
+	Name  string
+	Match func(rawKey string) bool
+}
+
+// KeyFormatReporter is implemented by a [KeyGenerator] that names the raw
+// key formats it issues and recognises, current and past, as
+// [DefaultKeyGenerator] does. A generator that does not implement it has
+// no formats of its own; register them with WithKeyFormat.
+type KeyFormatReporter interface {
+	KeyFormats() []KeyFormat
+}
+
+// KeyFormatDeprecation announces the retirement of a key format, for
+// [WithKeyFormatDeprecation]. Zero fields are left out of the notice.
+type KeyFormatDeprecation struct {
+	// Since is when the format was deprecated.
+	Since time.Time `json:"since" mapstructure:"since" yaml:"since"`
+
+	// Sunset is when keys in the format are planned to stop validating.
+	// It is announced to their holders, not enforced.
+	Sunset time.Time `json:"sunset" mapstructure:"sunset" yaml:"sunset"`
+
+	// Link points to migration documentation.
+	Link string `json:"link" mapstructure:"link" yaml:"link"`
+}
+
+// KeyFormats returns the default format: keys ending in the hex encoding
+// of the generator's random bytes.
+func (g *defaultGenerator) KeyFormats() []KeyFormat {
+	return []KeyFormat{{Name: KeyFormatDefault, Match: g.matches}}
+}
+
+func (g *defaultGenerator) matches(rawKey string) bool {
+	i := strings.LastIndexByte(rawKey, '_')
+	if i < 0 {
+		return false
+	}
+	random := rawKey[i+1:]
+	if len(random) != 2*g.byteLen {
+		return false
+	}
+	_, err := hex.DecodeString(random)
+	return err == nil
+}
+
+// initKeyFormats puts the generator's formats ahead of those registered
+// with WithKeyFormat and checks them and the deprecations against each
+// other.
+func (e *Engine) initKeyFormats() error {
+	var formats []KeyFormat
+	if r, ok := e.generator.(KeyFormatReporter); ok {
+		formats = r.KeyFormats()
+	}
+	formats = append(formats, e.keyFormats...)
+	names := make(map[string]bool, len(formats))
+	for _, f := range formats {
+		if f.Name == "" {
+			return fmt.Errorf("%w: name is empty", ErrInvalidKeyFormat)
+		}
+		if f.Match == nil {
+			return fmt.Errorf("%w: %q: match is nil", ErrInvalidKeyFormat, f.Name)
+		}
+		if names[f.Name] {
+			return fmt.Errorf("%w: %q is registered twice", ErrInvalidKeyFormat, f.Name)
+		}
+		names[f.Name] = true
+	}
+	for name := range e.formatDeprecations {
+		if !names[name] {
+			return fmt.Errorf("%w: deprecation of unknown format %q", ErrInvalidKeyFormat, name)
+		}
+	}
+	e.keyFormats = formats
+	if len(e.formatDeprecations) > 0 {
+		e.formatNotices = newReportTracker(keyFormatDeprecatedInterval)
+	}
+	return nil
+}
+
+// keyFormat returns the name of the first format rawKey matches, or "".
+func (e *Engine) keyFormat(rawKey string) string {
+	for _, f := range e.keyFormats {
+		if f.Match(rawKey) {
+			return f.Name
+		}
+	}
+	return ""
+}
+
+// checkKeyFormat returns the deprecation notice for a key in a deprecated
+// format and fires KeyFormatDeprecated at most once per key per week. It
+// returns nil without matching when no format is deprecated.
+func (e *Engine) checkKeyFormat(ctx context.Context, rawKey string, k *key.Key, now time.Time) *key.FormatDeprecation {
+	if len(e.formatDeprecations) == 0 {
+		return nil
+	}
+	name := e.keyFormat(rawKey)
+	d, ok := e.formatDeprecations[name]
+	if !ok {
+		return nil
+	}
+	notice := &key.FormatDeprecation{Format: name, Since: d.Since, Sunset: d.Sunset, Link: d.Link}
+	if e.formatNotices.shouldReport(k.ID, now) {
+		_ = e.hooks.FireKeyFormatDeprecated(ctx, k, notice, e.eventMeta(ctx, plugin.TriggerValidation, plugin.ReasonKeyFormatDeprecated))
+	}
+	return notice
+}
@@ -0,0 +1,126 @@
+package keysmith_test
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keysmithtest"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+// legacyGenerator issues keys with 16 random bytes, as an older generator
+// might have. Like the test generators of entropy_test.go, it stands in for
+// crypto/rand so that it passes NewEngine in FIPS mode.
+type legacyGenerator struct{ n int }
+
+func (g *legacyGenerator) Generate(prefix string, env key.Environment) (string, error) {
+	g.n++
+	return fmt.Sprintf("%s_%s_%032x", prefix, env, g.n), nil
+}
+
+func (*legacyGenerator) Algorithm() string { return keysmith.AlgCryptoRand }
+
+// legacyFormat matches the keys of legacyGenerator.
+var legacyFormat = keysmith.KeyFormat{
+	Name: "v0",
+	Match: func(rawKey string) bool {
+		random := rawKey[strings.LastIndexByte(rawKey, '_')+1:]
+		_, err := hex.DecodeString(random)
+		return len(random) == 32 && err == nil
+	},
+}
+
+func TestKeyFormat_DeprecatedFormatDetected(t *testing.T) {
+	s := memory.New()
+	legacy, err := keysmith.NewEngine(keysmith.WithStore(s), keysmith.WithKeyGenerator(&legacyGenerator{}))
+	require.NoError(t, err)
+	old, err := legacy.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "Old", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+
+	sunset := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	eng, err := keysmith.NewEngine(keysmith.WithStore(s),
+		keysmith.WithKeyFormat(legacyFormat),
+		keysmith.WithKeyFormatDeprecation("v0", keysmith.KeyFormatDeprecation{Sunset: sunset, Link: "https://example.com/migrate"}),
+	)
+	require.NoError(t, err)
+	current, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "New", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+
+	vr, err := eng.ValidateKey(testCtx(), old.RawKey)
+	require.NoError(t, err)
+	require.NotNil(t, vr.KeyDeprecation)
+	assert.Equal(t, "v0", vr.KeyDeprecation.Format)
+	assert.Equal(t, sunset, vr.KeyDeprecation.Sunset)
+	assert.Equal(t, "https://example.com/migrate", vr.KeyDeprecation.Link)
+
+	vr, err = eng.ValidateKey(testCtx(), current.RawKey)
+	require.NoError(t, err)
+	assert.Nil(t, vr.KeyDeprecation, "keys in the default format are not deprecated")
+}
+
+func TestKeyFormat_DeprecatedEventOncePerWeek(t *testing.T) {
+	clock := &fakeClock{t: time.Now()}
+	rec := keysmithtest.NewRecorder()
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithClock(clock.Now),
+		keysmith.WithExtension(rec),
+		keysmith.WithKeyFormatDeprecation(keysmith.KeyFormatDefault, keysmith.KeyFormatDeprecation{}),
+	)
+	require.NoError(t, err)
+	created := keysmithtest.NewKey(eng).MustCreate(t, testCtx())
+	start := clock.Now()
+
+	validate := func(at time.Time) {
+		t.Helper()
+		clock.Set(at)
+		vr, err := eng.ValidateKey(testCtx(), created.RawKey)
+		require.NoError(t, err)
+		require.NotNil(t, vr.KeyDeprecation, "every validation carries the notice")
+	}
+
+	validate(start)
+	validate(start.Add(6 * day))
+	assert.Equal(t, 1, rec.Count("KeyFormatDeprecated"), "once per key per week")
+
+	validate(start.Add(7*day + time.Minute))
+	assert.Equal(t, 2, rec.Count("KeyFormatDeprecated"))
+
+	evt := rec.Filter("KeyFormatDeprecated")[0]
+	assert.Equal(t, keysmith.KeyFormatDefault, evt.Deprecation.Format)
+	assert.Equal(t, plugin.ReasonKeyFormatDeprecated, evt.Meta.ReasonCode)
+}
+
+func TestKeyFormat_NoDeprecationsNoNotice(t *testing.T) {
+	rec := keysmithtest.NewRecorder()
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithExtension(rec))
+	require.NoError(t, err)
+	created := keysmithtest.NewKey(eng).MustCreate(t, testCtx())
+
+	vr, err := eng.ValidateKey(testCtx(), created.RawKey)
+	require.NoError(t, err)
+	assert.Nil(t, vr.KeyDeprecation)
+	assert.Zero(t, rec.Count("KeyFormatDeprecated"))
+}
+
+func TestKeyFormat_InvalidFormats(t *testing.T) {
+	match := func(string) bool { return true }
+	for name, opts := range map[string][]keysmith.Option{
+		"empty name":     {keysmith.WithKeyFormat(keysmith.KeyFormat{Match: match})},
+		"nil match":      {keysmith.WithKeyFormat(keysmith.KeyFormat{Name: "v0"})},
+		"duplicate name": {keysmith.WithKeyFormat(keysmith.KeyFormat{Name: keysmith.KeyFormatDefault, Match: match})},
+		"unknown format": {keysmith.WithKeyFormatDeprecation("v0", keysmith.KeyFormatDeprecation{})},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := keysmith.NewEngine(append(opts, keysmith.WithStore(memory.New()))...)
+			assert.ErrorIs(t, err, keysmith.ErrInvalidKeyFormat)
+		})
+	}
+}
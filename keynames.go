@@ -0,0 +1,110 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store"
+)
+
+// DuplicateKeyNameError is returned by CreateKey and UpdateKey when the
+// tenant has [tenant.Settings.UniqueKeyNames] set and another of its keys
+// has the name, compared case-insensitively. Nothing is written.
+type DuplicateKeyNameError struct {
+	Name string
+
+	// Existing is the key that has the name, or nil when it is in an app
+	// the caller may not see.
+	Existing *key.Key
+}
+
+func (e *DuplicateKeyNameError) Error() string {
+	if e.Existing == nil {
+		return fmt.Sprintf("%s: %q", ErrDuplicateKeyName, e.Name)
+	}
+	return fmt.Sprintf("%s: %q (existing key %s, hint %s)", ErrDuplicateKeyName, e.Name, e.Existing.ID, e.Existing.Hint)
+}
+
+// Is reports whether target is ErrDuplicateKeyName.
+func (e *DuplicateKeyNameError) Is(target error) bool { return target == ErrDuplicateKeyName }
+
+// KeyNameDuplicate is a name shared by more than one key of a tenant.
+type KeyNameDuplicate struct {
+	TenantID string `json:"tenant_id"`
+
+	// Name is the name as the oldest of the keys has it; the others may
+	// differ in case.
+	Name string `json:"name"`
+
+	// KeyIDs are the keys with the name, oldest first.
+	KeyIDs []id.KeyID `json:"key_ids"`
+}
+
+// checkKeyName returns a [*DuplicateKeyNameError] when tenantID requires
+// unique key names and a key other than self has name. The check reads
+// before the write, so concurrent writes of the same name can both pass.
+func (e *Engine) checkKeyName(ctx context.Context, tenantID, name string, self id.KeyID) error {
+	if tenantID == "" || name == "" || !e.tenantSettings(ctx, tenantID).UniqueKeyNames {
+		return nil
+	}
+	// Two keys are enough to find one that is not self. An empty TenantID
+	// filter matches every tenant, so the tenant is compared here as well.
+	keys, err := e.store.Keys().List(store.WithPrimaryReads(ctx), &key.ListFilter{TenantID: tenantID, Name: name, Limit: 2})
+	if err != nil {
+		return fmt.Errorf("find key by name: %w", err)
+	}
+	for _, k := range keys {
+		if k.TenantID != tenantID || k.ID == self {
+			continue
+		}
+		dup := &DuplicateKeyNameError{Name: name}
+		if scopeFromContext(ctx).owns(k.TenantID, k.AppID) {
+			dup.Existing = k
+		}
+		return dup
+	}
+	return nil
+}
+
+// DuplicateKeyNames lists the names shared by more than one key the context
+// may see, compared case-insensitively, so a tenant can rename keys before
+// setting UniqueKeyNames. A non-empty tenantID restricts it to that tenant.
+// Duplicates are sorted by tenant and name.
+func (e *Engine) DuplicateKeyNames(ctx context.Context, tenantID string) ([]*KeyNameDuplicate, error) {
+	type nameKey struct{ tenantID, fold string }
+	byName := make(map[nameKey]*KeyNameDuplicate)
+	err := e.IterateKeys(ctx, &key.ListFilter{TenantID: tenantID}, func(k *key.Key) error {
+		if k.Name == "" {
+			return nil
+		}
+		nk := nameKey{k.TenantID, strings.ToLower(k.Name)}
+		d, ok := byName[nk]
+		if !ok {
+			d = &KeyNameDuplicate{TenantID: k.TenantID, Name: k.Name}
+			byName[nk] = d
+		}
+		d.KeyIDs = append(d.KeyIDs, k.ID)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("iterate keys: %w", err)
+	}
+
+	var out []*KeyNameDuplicate
+	for _, d := range byName {
+		if len(d.KeyIDs) > 1 {
+			out = append(out, d)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].TenantID != out[j].TenantID {
+			return out[i].TenantID < out[j].TenantID
+		}
+		return strings.ToLower(out[i].Name) < strings.ToLower(out[j].Name)
+	})
+	return out, nil
+}
@@ -0,0 +1,124 @@
+package keysmith_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/tenant"
+)
+
+func createNamedKey(t *testing.T, eng *keysmith.Engine, ctx context.Context, name string) (*key.Key, error) {
+	t.Helper()
+	result, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: name, Prefix: "sk", Environment: key.EnvTest})
+	if err != nil {
+		return nil, err
+	}
+	return result.Key, nil
+}
+
+func setUniqueKeyNames(t *testing.T, eng *keysmith.Engine, on bool) {
+	t.Helper()
+	require.NoError(t, eng.SetTenantSettings(testCtx(), &tenant.Settings{UniqueKeyNames: on}))
+}
+
+func TestUniqueKeyNames_CaseInsensitive(t *testing.T) {
+	eng := newTestEngine(t)
+	setUniqueKeyNames(t, eng, true)
+
+	first, err := createNamedKey(t, eng, testCtx(), "Production Key")
+	require.NoError(t, err)
+
+	_, err = createNamedKey(t, eng, testCtx(), "production KEY")
+	require.ErrorIs(t, err, keysmith.ErrDuplicateKeyName)
+	var dup *keysmith.DuplicateKeyNameError
+	require.ErrorAs(t, err, &dup)
+	require.NotNil(t, dup.Existing)
+	assert.Equal(t, first.ID, dup.Existing.ID)
+	assert.Contains(t, err.Error(), first.ID.String())
+	assert.Contains(t, err.Error(), first.Hint)
+
+	other, err := createNamedKey(t, eng, testCtx(), "Staging Key")
+	require.NoError(t, err)
+	name := "PRODUCTION key"
+	_, err = eng.UpdateKey(testCtx(), other.ID, &keysmith.UpdateKeyInput{Name: &name})
+	require.ErrorIs(t, err, keysmith.ErrDuplicateKeyName, "renames are checked too")
+
+	// A key may change the case of its own name.
+	_, err = eng.UpdateKey(testCtx(), first.ID, &keysmith.UpdateKeyInput{Name: &name})
+	require.NoError(t, err)
+
+	_, err = createNamedKey(t, eng, keysmith.WithTenant(context.Background(), "app_test", "tenant_other"), "Production Key")
+	require.NoError(t, err, "names are unique per tenant")
+}
+
+func TestUniqueKeyNames_Grandfathered(t *testing.T) {
+	eng := newTestEngine(t)
+	a, err := createNamedKey(t, eng, testCtx(), "Shared")
+	require.NoError(t, err)
+	b, err := createNamedKey(t, eng, testCtx(), "shared")
+	require.NoError(t, err)
+	_, err = createNamedKey(t, eng, testCtx(), "Unique")
+	require.NoError(t, err)
+
+	dups, err := eng.DuplicateKeyNames(testCtx(), "")
+	require.NoError(t, err)
+	require.Len(t, dups, 1)
+	assert.Equal(t, "Shared", dups[0].Name)
+	assert.Equal(t, "tenant_test", dups[0].TenantID)
+	assert.Equal(t, []id.KeyID{a.ID, b.ID}, dups[0].KeyIDs)
+
+	setUniqueKeyNames(t, eng, true)
+	desc := "still here"
+	_, err = eng.UpdateKey(testCtx(), b.ID, &keysmith.UpdateKeyInput{Description: &desc})
+	require.NoError(t, err, "existing duplicates may still be updated")
+	same := "SHARED"
+	_, err = eng.UpdateKey(testCtx(), b.ID, &keysmith.UpdateKeyInput{Name: &same})
+	require.NoError(t, err, "or change the case of their name")
+	_, err = createNamedKey(t, eng, testCtx(), "Shared")
+	require.ErrorIs(t, err, keysmith.ErrDuplicateKeyName, "but no new key joins them")
+
+	renamed := "Shared (old)"
+	_, err = eng.UpdateKey(testCtx(), a.ID, &keysmith.UpdateKeyInput{Name: &renamed})
+	require.NoError(t, err)
+	dups, err = eng.DuplicateKeyNames(testCtx(), "")
+	require.NoError(t, err)
+	assert.Empty(t, dups)
+}
+
+func TestUniqueKeyNames_Toggle(t *testing.T) {
+	eng := newTestEngine(t)
+	_, err := createNamedKey(t, eng, testCtx(), "Billing")
+	require.NoError(t, err)
+	_, err = createNamedKey(t, eng, testCtx(), "Billing")
+	require.NoError(t, err, "names may repeat by default")
+
+	setUniqueKeyNames(t, eng, true)
+	_, err = createNamedKey(t, eng, testCtx(), "billing")
+	require.ErrorIs(t, err, keysmith.ErrDuplicateKeyName)
+
+	setUniqueKeyNames(t, eng, false)
+	_, err = createNamedKey(t, eng, testCtx(), "billing")
+	require.NoError(t, err)
+
+	ts, err := eng.GetTenantSettings(testCtx(), "")
+	require.NoError(t, err)
+	assert.False(t, ts.UniqueKeyNames)
+}
+
+func TestUniqueKeyNames_OtherApp(t *testing.T) {
+	eng := newTestEngine(t)
+	setUniqueKeyNames(t, eng, true)
+	_, err := createNamedKey(t, eng, testCtx(), "Gateway")
+	require.NoError(t, err)
+
+	_, err = createNamedKey(t, eng, keysmith.WithTenant(context.Background(), "app_other", "tenant_test"), "Gateway")
+	var dup *keysmith.DuplicateKeyNameError
+	require.ErrorAs(t, err, &dup, "the tenant's names are unique across its apps")
+	assert.Nil(t, dup.Existing, "a key in another app is not disclosed")
+}
@@ -0,0 +1,40 @@
+package keysmithtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/xraph/keysmith"
+)
+
+// AssertValidates checks that rawKey passes Engine.ValidateKey in ctx and
+// returns the result. On failure it reports the error with tb.Errorf and
+// returns nil.
+func AssertValidates(tb testing.TB, eng keysmith.KeyValidator, ctx context.Context, rawKey string) *keysmith.ValidationResult {
+	tb.Helper()
+	result, err := eng.ValidateKey(ctx, rawKey)
+	if err != nil {
+		tb.Errorf("keysmithtest: expected key to validate, got %v", err)
+		return nil
+	}
+	return result
+}
+
+// AssertRejectedWith checks that Engine.ValidateKey rejects rawKey in ctx
+// with an error matching want under errors.Is, such as
+// keysmith.ErrKeyExpired. It reports a mismatch with tb.Errorf and returns
+// whether the check passed.
+func AssertRejectedWith(tb testing.TB, eng keysmith.KeyValidator, ctx context.Context, rawKey string, want error) bool {
+	tb.Helper()
+	_, err := eng.ValidateKey(ctx, rawKey)
+	switch {
+	case err == nil:
+		tb.Errorf("keysmithtest: expected key to be rejected with %v, but it validated", want)
+		return false
+	case !errors.Is(err, want):
+		tb.Errorf("keysmithtest: expected key to be rejected with %v, got %v", want, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,173 @@
+package keysmithtest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/scope"
+)
+
+// KeyBuilder creates a key through Engine.CreateKey. The zero options give
+// an active "sk" test-environment key named "test key".
+type KeyBuilder struct {
+	eng   keysmith.KeyService
+	input keysmith.CreateKeyInput
+
+	// then is the state the key is moved to after creation.
+	then         key.State
+	revokeReason string
+}
+
+// NewKey starts building a key for eng.
+func NewKey(eng keysmith.KeyService) *KeyBuilder {
+	return &KeyBuilder{eng: eng, input: keysmith.CreateKeyInput{
+		Name:        "test key",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+	}}
+}
+
+// WithName sets the key's name.
+func (b *KeyBuilder) WithName(name string) *KeyBuilder {
+	b.input.Name = name
+	return b
+}
+
+// WithPrefix sets the prefix of the raw key.
+func (b *KeyBuilder) WithPrefix(prefix string) *KeyBuilder {
+	b.input.Prefix = prefix
+	return b
+}
+
+// WithEnvironment sets the key's environment.
+func (b *KeyBuilder) WithEnvironment(env key.Environment) *KeyBuilder {
+	b.input.Environment = env
+	return b
+}
+
+// WithScopes assigns scopes to the key. Scopes that do not exist in the
+// context's tenant are created first.
+func (b *KeyBuilder) WithScopes(names ...string) *KeyBuilder {
+	b.input.Scopes = append(b.input.Scopes, names...)
+	return b
+}
+
+// WithPolicy attaches a policy, e.g. one made with NewPolicy.
+func (b *KeyBuilder) WithPolicy(policyID id.PolicyID) *KeyBuilder {
+	b.input.PolicyID = &policyID
+	return b
+}
+
+// WithExpiry sets when the key expires.
+func (b *KeyBuilder) WithExpiry(at time.Time) *KeyBuilder {
+	b.input.ExpiresAt = &at
+	return b
+}
+
+// WithMetadata sets the key's metadata.
+func (b *KeyBuilder) WithMetadata(meta map[string]any) *KeyBuilder {
+	b.input.Metadata = meta
+	return b
+}
+
+// WithInput adjusts the CreateKeyInput directly, for fields without a
+// builder method.
+func (b *KeyBuilder) WithInput(fn func(*keysmith.CreateKeyInput)) *KeyBuilder {
+	fn(&b.input)
+	return b
+}
+
+// Expired makes the key expire an hour before it is created, so
+// validation rejects it with ErrKeyExpired.
+func (b *KeyBuilder) Expired() *KeyBuilder {
+	return b.WithExpiry(time.Now().Add(-time.Hour))
+}
+
+// Revoked revokes the key after creating it.
+func (b *KeyBuilder) Revoked() *KeyBuilder {
+	b.then, b.revokeReason = key.StateRevoked, "keysmithtest"
+	return b
+}
+
+// Suspended suspends the key after creating it.
+func (b *KeyBuilder) Suspended() *KeyBuilder {
+	b.then = key.StateSuspended
+	return b
+}
+
+// Create creates the scopes and then the key in ctx's tenant. The result
+// holds the stored key, in its final state, and the raw key.
+func (b *KeyBuilder) Create(ctx context.Context) (*key.CreateResult, error) {
+	if err := ensureScopes(ctx, b.eng, b.input.Scopes); err != nil {
+		return nil, err
+	}
+	input := b.input
+	result, err := b.eng.CreateKey(ctx, &input)
+	if err != nil {
+		return nil, fmt.Errorf("keysmithtest: create key: %w", err)
+	}
+
+	switch b.then {
+	case key.StateRevoked:
+		err = b.eng.RevokeKey(ctx, result.Key.ID, b.revokeReason)
+	case key.StateSuspended:
+		err = b.eng.SuspendKey(ctx, result.Key.ID)
+	default:
+		return result, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("keysmithtest: set key state %s: %w", b.then, err)
+	}
+	k, err := b.eng.GetKey(ctx, result.Key.ID)
+	if err != nil {
+		return nil, fmt.Errorf("keysmithtest: reload key: %w", err)
+	}
+	k.Scopes = result.Key.Scopes
+	result.Key = k
+	return result, nil
+}
+
+// MustCreate is Create, failing tb on error.
+func (b *KeyBuilder) MustCreate(tb testing.TB, ctx context.Context) *key.CreateResult {
+	tb.Helper()
+	result, err := b.Create(ctx)
+	if err != nil {
+		tb.Fatalf("%v", err)
+	}
+	return result
+}
+
+// ensureScopes creates the scopes in names that ctx's tenant lacks.
+func ensureScopes(ctx context.Context, eng keysmith.ScopeManager, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	have := make(map[string]bool)
+	for offset := 0; ; offset += keysmith.DefaultMaxPageSize {
+		page, err := eng.ListScopes(ctx, &scope.ListFilter{Limit: keysmith.DefaultMaxPageSize, Offset: offset})
+		if err != nil {
+			return fmt.Errorf("keysmithtest: list scopes: %w", err)
+		}
+		for _, s := range page {
+			have[s.Name] = true
+		}
+		if len(page) < keysmith.DefaultMaxPageSize {
+			break
+		}
+	}
+	for _, name := range names {
+		if have[name] {
+			continue
+		}
+		if err := eng.CreateScope(ctx, &scope.Scope{Name: name}); err != nil {
+			return fmt.Errorf("keysmithtest: create scope %q: %w", name, err)
+		}
+		have[name] = true
+	}
+	return nil
+}
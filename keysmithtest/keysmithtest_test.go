@@ -0,0 +1,156 @@
+package keysmithtest_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keysmithtest"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/usage"
+)
+
+func TestKeyBuilder(t *testing.T) {
+	rec := keysmithtest.NewRecorder()
+	eng := keysmithtest.NewEngine(t, keysmith.WithExtension(rec))
+	ctx := keysmithtest.Context()
+
+	pol := keysmithtest.NewPolicy(eng).WithAllowedScopes("read", "write").MustCreate(t, ctx)
+	created := keysmithtest.NewKey(eng).WithScopes("read").WithPolicy(pol.ID).MustCreate(t, ctx)
+	if created.RawKey == "" || created.Key.TenantID != keysmithtest.TenantID {
+		t.Fatalf("unexpected key %+v", created.Key)
+	}
+	if vr := keysmithtest.AssertValidates(t, eng, ctx, created.RawKey); vr != nil && !slices.Equal(vr.Scopes, []string{"read"}) {
+		t.Errorf("scopes %v", vr.Scopes)
+	}
+
+	revoked := keysmithtest.NewKey(eng).Revoked().MustCreate(t, ctx)
+	if revoked.Key.State != key.StateRevoked {
+		t.Errorf("state %s", revoked.Key.State)
+	}
+	keysmithtest.AssertRejectedWith(t, eng, ctx, revoked.RawKey, keysmith.ErrKeyInactive)
+	expired := keysmithtest.NewKey(eng).Expired().MustCreate(t, ctx)
+	keysmithtest.AssertRejectedWith(t, eng, ctx, expired.RawKey, keysmith.ErrKeyExpired)
+
+	want := []string{"PolicyCreated", "KeyCreated", "KeyValidated", "KeyCreated", "KeyRevoked", "KeyValidationFailed", "KeyCreated", "KeyExpired"}
+	if got := rec.Hooks(); !slices.Equal(got, want) {
+		t.Errorf("hooks %v, want %v", got, want)
+	}
+	if evt := rec.Filter("KeyRevoked"); len(evt) != 1 || evt[0].Key.ID != revoked.Key.ID {
+		t.Errorf("revoked events %+v", evt)
+	}
+	rec.Reset()
+	if len(rec.Events()) != 0 {
+		t.Error("Reset should discard events")
+	}
+}
+
+func TestKeyBuilder_ReusesScopes(t *testing.T) {
+	eng := keysmithtest.NewEngine(t)
+	ctx := keysmithtest.Context()
+	keysmithtest.NewKey(eng).WithScopes("read").MustCreate(t, ctx)
+	keysmithtest.NewKey(eng).WithScopes("read", "write").MustCreate(t, ctx)
+
+	scopes, err := eng.ListScopes(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scopes) != 2 {
+		t.Errorf("got %d scopes, want 2", len(scopes))
+	}
+}
+
+func TestUsageBuilder(t *testing.T) {
+	eng := keysmithtest.NewEngine(t)
+	ctx := keysmithtest.Context()
+	created := keysmithtest.NewKey(eng).MustCreate(t, ctx)
+	kid := created.Key.ID
+
+	to := time.Now().Truncate(time.Hour)
+	from := to.Add(-10 * time.Hour)
+	recs := keysmithtest.NewUsage(eng, kid).Count(10).Between(from, to).WithStatus(500).MustCreate(t, ctx)
+	if !recs[0].CreatedAt.Equal(from) || !recs[9].CreatedAt.Equal(to.Add(-time.Hour)) {
+		t.Errorf("records span %s to %s", recs[0].CreatedAt, recs[9].CreatedAt)
+	}
+	keysmithtest.NewUsage(eng, kid).Count(2).MustCreate(t, ctx)
+
+	got, err := eng.QueryUsage(ctx, &usage.QueryFilter{KeyID: &kid})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 12 {
+		t.Errorf("got %d records, want 12", len(got))
+	}
+	old, err := eng.QueryUsage(ctx, &usage.QueryFilter{KeyID: &kid, Before: &to})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(old) != 10 {
+		t.Errorf("got %d backdated records, want 10", len(old))
+	}
+
+	if _, err := keysmithtest.NewUsage(eng, kid).Count(0).Create(ctx); err == nil {
+		t.Error("expected an error for a zero count")
+	}
+}
+
+// fakeTB captures failures reported by the assertion helpers.
+type fakeTB struct {
+	testing.TB
+	failed bool
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(string, ...any) { f.failed = true }
+
+func TestAssertions_ReportFailures(t *testing.T) {
+	eng := keysmithtest.NewEngine(t)
+	ctx := keysmithtest.Context()
+	created := keysmithtest.NewKey(eng).MustCreate(t, ctx)
+
+	tb := &fakeTB{TB: t}
+	if keysmithtest.AssertRejectedWith(tb, eng, ctx, created.RawKey, keysmith.ErrKeyInactive) || !tb.failed {
+		t.Error("a valid key should fail AssertRejectedWith")
+	}
+	tb = &fakeTB{TB: t}
+	if keysmithtest.AssertRejectedWith(tb, eng, ctx, "sk_test_unknown", keysmith.ErrKeyExpired) || !tb.failed {
+		t.Error("the wrong error should fail AssertRejectedWith")
+	}
+	tb = &fakeTB{TB: t}
+	if keysmithtest.AssertValidates(tb, eng, ctx, "sk_test_unknown") != nil || !tb.failed {
+		t.Error("an unknown key should fail AssertValidates")
+	}
+}
+
+func TestMockService(t *testing.T) {
+	var svc keysmith.KeyService = &keysmithtest.MockService{
+		RevokeKeyFunc: func(context.Context, id.KeyID, string) error { return nil },
+	}
+	mock := svc.(*keysmithtest.MockService)
+	ctx := keysmithtest.Context()
+
+	if err := svc.RevokeKey(ctx, id.NewKeyID(), "rotated"); err != nil {
+		t.Fatalf("mocked RevokeKey: %v", err)
+	}
+	if _, err := svc.ValidateKey(ctx, "sk_test_unknown"); !errors.Is(err, keysmithtest.ErrNotMocked) {
+		t.Errorf("unmocked ValidateKey returned %v, want ErrNotMocked", err)
+	}
+	if got := mock.Calls("RevokeKey"); got != 1 {
+		t.Errorf("RevokeKey calls = %d, want 1", got)
+	}
+	if got := mock.Calls("ValidateKey"); got != 1 {
+		t.Errorf("ValidateKey calls = %d, want 1", got)
+	}
+
+	// Builders accept the interfaces, so they run against a mock too.
+	mock.CreatePolicyFunc = func(context.Context, *policy.Policy) error { return nil }
+	if pol := keysmithtest.NewPolicy(mock).WithName("mocked").MustCreate(t, ctx); pol.Name != "mocked" {
+		t.Errorf("policy name = %q, want mocked", pol.Name)
+	}
+}
@@ -0,0 +1,265 @@
+package keysmithtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/usage"
+)
+
+// Compile-time interface check.
+var _ keysmith.KeyService = (*MockService)(nil)
+
+// ErrNotMocked is returned by a MockService method whose function is not
+// set.
+var ErrNotMocked = errors.New("keysmithtest: method not mocked")
+
+// MockService is a keysmith.KeyService for unit tests of code that depends
+// on the service interfaces rather than on an engine. Each method calls the
+// field of the same name with a Func suffix, such as ValidateKeyFunc; a
+// method whose field is nil returns an error wrapping ErrNotMocked. Calls
+// are counted per method. It is safe for concurrent use if the functions
+// are.
+//
+//	mock := &keysmithtest.MockService{
+//		ValidateKeyFunc: func(ctx context.Context, raw string) (*keysmith.ValidationResult, error) {
+//			return nil, keysmith.ErrKeyRevoked
+//		},
+//	}
+//	handler := middleware.APIKeyAuth(mock)(next)
+type MockService struct {
+	ValidateKeyFunc   func(ctx context.Context, rawKey string) (*keysmith.ValidationResult, error)
+	CreateKeyFunc     func(ctx context.Context, input *keysmith.CreateKeyInput) (*key.CreateResult, error)
+	GetKeyFunc        func(ctx context.Context, keyID id.KeyID) (*key.Key, error)
+	ListKeysFunc      func(ctx context.Context, filter *key.ListFilter) ([]*key.Key, error)
+	UpdateKeyFunc     func(ctx context.Context, keyID id.KeyID, input *keysmith.UpdateKeyInput) (*key.Key, error)
+	RotateKeyFunc     func(ctx context.Context, keyID id.KeyID, reason rotation.Reason) (*key.CreateResult, error)
+	RevokeKeyFunc     func(ctx context.Context, keyID id.KeyID, reason string) error
+	SuspendKeyFunc    func(ctx context.Context, keyID id.KeyID) error
+	ReactivateKeyFunc func(ctx context.Context, keyID id.KeyID) error
+
+	CreatePolicyFunc func(ctx context.Context, pol *policy.Policy) error
+	GetPolicyFunc    func(ctx context.Context, polID id.PolicyID) (*policy.Policy, error)
+	UpdatePolicyFunc func(ctx context.Context, pol *policy.Policy) error
+	DeletePolicyFunc func(ctx context.Context, polID id.PolicyID) error
+	ListPoliciesFunc func(ctx context.Context, filter *policy.ListFilter) ([]*policy.Policy, error)
+
+	CreateScopeFunc  func(ctx context.Context, s *scope.Scope) error
+	ListScopesFunc   func(ctx context.Context, filter *scope.ListFilter) ([]*scope.Scope, error)
+	DeleteScopeFunc  func(ctx context.Context, scopeID id.ScopeID) error
+	AssignScopesFunc func(ctx context.Context, keyID id.KeyID, scopeNames []string) error
+	RemoveScopesFunc func(ctx context.Context, keyID id.KeyID, scopeNames []string) error
+
+	RecordUsageFunc func(ctx context.Context, rec *usage.Record) error
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+// Calls returns how many times method, such as "ValidateKey", was called.
+func (m *MockService) Calls(method string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls[method]
+}
+
+func (m *MockService) called(method string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.calls == nil {
+		m.calls = make(map[string]int)
+	}
+	m.calls[method]++
+}
+
+func notMocked(method string) error {
+	return fmt.Errorf("%w: %s", ErrNotMocked, method)
+}
+
+// ValidateKey implements keysmith.KeyService.
+func (m *MockService) ValidateKey(ctx context.Context, rawKey string) (*keysmith.ValidationResult, error) {
+	m.called("ValidateKey")
+	if m.ValidateKeyFunc == nil {
+		return nil, notMocked("ValidateKey")
+	}
+	return m.ValidateKeyFunc(ctx, rawKey)
+}
+
+// CreateKey implements keysmith.KeyService.
+func (m *MockService) CreateKey(ctx context.Context, input *keysmith.CreateKeyInput) (*key.CreateResult, error) {
+	m.called("CreateKey")
+	if m.CreateKeyFunc == nil {
+		return nil, notMocked("CreateKey")
+	}
+	return m.CreateKeyFunc(ctx, input)
+}
+
+// GetKey implements keysmith.KeyService.
+func (m *MockService) GetKey(ctx context.Context, keyID id.KeyID) (*key.Key, error) {
+	m.called("GetKey")
+	if m.GetKeyFunc == nil {
+		return nil, notMocked("GetKey")
+	}
+	return m.GetKeyFunc(ctx, keyID)
+}
+
+// ListKeys implements keysmith.KeyService.
+func (m *MockService) ListKeys(ctx context.Context, filter *key.ListFilter) ([]*key.Key, error) {
+	m.called("ListKeys")
+	if m.ListKeysFunc == nil {
+		return nil, notMocked("ListKeys")
+	}
+	return m.ListKeysFunc(ctx, filter)
+}
+
+// UpdateKey implements keysmith.KeyService.
+func (m *MockService) UpdateKey(ctx context.Context, keyID id.KeyID, input *keysmith.UpdateKeyInput) (*key.Key, error) {
+	m.called("UpdateKey")
+	if m.UpdateKeyFunc == nil {
+		return nil, notMocked("UpdateKey")
+	}
+	return m.UpdateKeyFunc(ctx, keyID, input)
+}
+
+// RotateKey implements keysmith.KeyService.
+func (m *MockService) RotateKey(ctx context.Context, keyID id.KeyID, reason rotation.Reason) (*key.CreateResult, error) {
+	m.called("RotateKey")
+	if m.RotateKeyFunc == nil {
+		return nil, notMocked("RotateKey")
+	}
+	return m.RotateKeyFunc(ctx, keyID, reason)
+}
+
+// RevokeKey implements keysmith.KeyService.
+func (m *MockService) RevokeKey(ctx context.Context, keyID id.KeyID, reason string) error {
+	m.called("RevokeKey")
+	if m.RevokeKeyFunc == nil {
+		return notMocked("RevokeKey")
+	}
+	return m.RevokeKeyFunc(ctx, keyID, reason)
+}
+
+// SuspendKey implements keysmith.KeyService.
+func (m *MockService) SuspendKey(ctx context.Context, keyID id.KeyID) error {
+	m.called("SuspendKey")
+	if m.SuspendKeyFunc == nil {
+		return notMocked("SuspendKey")
+	}
+	return m.SuspendKeyFunc(ctx, keyID)
+}
+
+// ReactivateKey implements keysmith.KeyService.
+func (m *MockService) ReactivateKey(ctx context.Context, keyID id.KeyID) error {
+	m.called("ReactivateKey")
+	if m.ReactivateKeyFunc == nil {
+		return notMocked("ReactivateKey")
+	}
+	return m.ReactivateKeyFunc(ctx, keyID)
+}
+
+// CreatePolicy implements keysmith.KeyService.
+func (m *MockService) CreatePolicy(ctx context.Context, pol *policy.Policy) error {
+	m.called("CreatePolicy")
+	if m.CreatePolicyFunc == nil {
+		return notMocked("CreatePolicy")
+	}
+	return m.CreatePolicyFunc(ctx, pol)
+}
+
+// GetPolicy implements keysmith.KeyService.
+func (m *MockService) GetPolicy(ctx context.Context, polID id.PolicyID) (*policy.Policy, error) {
+	m.called("GetPolicy")
+	if m.GetPolicyFunc == nil {
+		return nil, notMocked("GetPolicy")
+	}
+	return m.GetPolicyFunc(ctx, polID)
+}
+
+// UpdatePolicy implements keysmith.KeyService.
+func (m *MockService) UpdatePolicy(ctx context.Context, pol *policy.Policy) error {
+	m.called("UpdatePolicy")
+	if m.UpdatePolicyFunc == nil {
+		return notMocked("UpdatePolicy")
+	}
+	return m.UpdatePolicyFunc(ctx, pol)
+}
+
+// DeletePolicy implements keysmith.KeyService.
+func (m *MockService) DeletePolicy(ctx context.Context, polID id.PolicyID) error {
+	m.called("DeletePolicy")
+	if m.DeletePolicyFunc == nil {
+		return notMocked("DeletePolicy")
+	}
+	return m.DeletePolicyFunc(ctx, polID)
+}
+
+// ListPolicies implements keysmith.KeyService.
+func (m *MockService) ListPolicies(ctx context.Context, filter *policy.ListFilter) ([]*policy.Policy, error) {
+	m.called("ListPolicies")
+	if m.ListPoliciesFunc == nil {
+		return nil, notMocked("ListPolicies")
+	}
+	return m.ListPoliciesFunc(ctx, filter)
+}
+
+// CreateScope implements keysmith.KeyService.
+func (m *MockService) CreateScope(ctx context.Context, s *scope.Scope) error {
+	m.called("CreateScope")
+	if m.CreateScopeFunc == nil {
+		return notMocked("CreateScope")
+	}
+	return m.CreateScopeFunc(ctx, s)
+}
+
+// ListScopes implements keysmith.KeyService.
+func (m *MockService) ListScopes(ctx context.Context, filter *scope.ListFilter) ([]*scope.Scope, error) {
+	m.called("ListScopes")
+	if m.ListScopesFunc == nil {
+		return nil, notMocked("ListScopes")
+	}
+	return m.ListScopesFunc(ctx, filter)
+}
+
+// DeleteScope implements keysmith.KeyService.
+func (m *MockService) DeleteScope(ctx context.Context, scopeID id.ScopeID) error {
+	m.called("DeleteScope")
+	if m.DeleteScopeFunc == nil {
+		return notMocked("DeleteScope")
+	}
+	return m.DeleteScopeFunc(ctx, scopeID)
+}
+
+// AssignScopes implements keysmith.KeyService.
+func (m *MockService) AssignScopes(ctx context.Context, keyID id.KeyID, scopeNames []string) error {
+	m.called("AssignScopes")
+	if m.AssignScopesFunc == nil {
+		return notMocked("AssignScopes")
+	}
+	return m.AssignScopesFunc(ctx, keyID, scopeNames)
+}
+
+// RemoveScopes implements keysmith.KeyService.
+func (m *MockService) RemoveScopes(ctx context.Context, keyID id.KeyID, scopeNames []string) error {
+	m.called("RemoveScopes")
+	if m.RemoveScopesFunc == nil {
+		return notMocked("RemoveScopes")
+	}
+	return m.RemoveScopesFunc(ctx, keyID, scopeNames)
+}
+
+// RecordUsage implements keysmith.KeyService.
+func (m *MockService) RecordUsage(ctx context.Context, rec *usage.Record) error {
+	m.called("RecordUsage")
+	if m.RecordUsageFunc == nil {
+		return notMocked("RecordUsage")
+	}
+	return m.RecordUsageFunc(ctx, rec)
+}
@@ -0,0 +1,85 @@
+package keysmithtest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/policy"
+)
+
+// PolicyBuilder creates a policy through Engine.CreatePolicy. The zero
+// options give an unrestricted policy named "test policy".
+type PolicyBuilder struct {
+	eng keysmith.PolicyManager
+	pol policy.Policy
+}
+
+// NewPolicy starts building a policy for eng.
+func NewPolicy(eng keysmith.PolicyManager) *PolicyBuilder {
+	return &PolicyBuilder{eng: eng, pol: policy.Policy{Name: "test policy"}}
+}
+
+// WithName sets the policy's name.
+func (b *PolicyBuilder) WithName(name string) *PolicyBuilder {
+	b.pol.Name = name
+	return b
+}
+
+// WithRateLimit allows limit validations per window.
+func (b *PolicyBuilder) WithRateLimit(limit int, window time.Duration) *PolicyBuilder {
+	b.pol.RateLimit, b.pol.RateLimitWindow = limit, window
+	return b
+}
+
+// WithAllowedScopes restricts the scopes keys under the policy may hold.
+func (b *PolicyBuilder) WithAllowedScopes(names ...string) *PolicyBuilder {
+	b.pol.AllowedScopes = append(b.pol.AllowedScopes, names...)
+	return b
+}
+
+// WithAllowedOrigins restricts the browser origins keys validate from.
+func (b *PolicyBuilder) WithAllowedOrigins(origins ...string) *PolicyBuilder {
+	b.pol.AllowedOrigins = append(b.pol.AllowedOrigins, origins...)
+	return b
+}
+
+// WithMaxKeyLifetime sets the expiry given to keys created without one.
+func (b *PolicyBuilder) WithMaxKeyLifetime(d time.Duration) *PolicyBuilder {
+	b.pol.MaxKeyLifetime = d
+	return b
+}
+
+// WithGracePeriod sets how long a rotated key stays valid.
+func (b *PolicyBuilder) WithGracePeriod(d time.Duration) *PolicyBuilder {
+	b.pol.GracePeriod = d
+	return b
+}
+
+// WithPolicy adjusts the policy directly, for fields without a builder
+// method.
+func (b *PolicyBuilder) WithPolicy(fn func(*policy.Policy)) *PolicyBuilder {
+	fn(&b.pol)
+	return b
+}
+
+// Create creates the policy in ctx's tenant and returns it as stored.
+func (b *PolicyBuilder) Create(ctx context.Context) (*policy.Policy, error) {
+	pol := b.pol
+	if err := b.eng.CreatePolicy(ctx, &pol); err != nil {
+		return nil, fmt.Errorf("keysmithtest: create policy: %w", err)
+	}
+	return &pol, nil
+}
+
+// MustCreate is Create, failing tb on error.
+func (b *PolicyBuilder) MustCreate(tb testing.TB, ctx context.Context) *policy.Policy {
+	tb.Helper()
+	pol, err := b.Create(ctx)
+	if err != nil {
+		tb.Fatalf("%v", err)
+	}
+	return pol
+}
@@ -0,0 +1,349 @@
+package keysmithtest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/xraph/keysmith/group"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/tenant"
+	"github.com/xraph/keysmith/transfer"
+	"github.com/xraph/keysmith/usage"
+)
+
+// Compile-time interface checks.
+var (
+	_ plugin.Plugin                        = (*Recorder)(nil)
+	_ plugin.KeyCreatedV2                  = (*Recorder)(nil)
+	_ plugin.KeyCreateFailedV2             = (*Recorder)(nil)
+	_ plugin.KeyValidatedV2                = (*Recorder)(nil)
+	_ plugin.KeyValidationFailedV2         = (*Recorder)(nil)
+	_ plugin.KeyRotatedV2                  = (*Recorder)(nil)
+	_ plugin.KeyRevokedV2                  = (*Recorder)(nil)
+	_ plugin.KeySuspendedV2                = (*Recorder)(nil)
+	_ plugin.KeyReactivatedV2              = (*Recorder)(nil)
+	_ plugin.KeyExpiredV2                  = (*Recorder)(nil)
+	_ plugin.KeyRateLimitedV2              = (*Recorder)(nil)
+	_ plugin.TenantRateLimitedV2           = (*Recorder)(nil)
+	_ plugin.KeyAdaptivelyLimitedV2        = (*Recorder)(nil)
+	_ plugin.KeyQuotaExceededV2            = (*Recorder)(nil)
+	_ plugin.KeyQuotaForecastWarningV2     = (*Recorder)(nil)
+	_ plugin.KeyConsumerMismatchV2         = (*Recorder)(nil)
+	_ plugin.KeyRotationOverdueV2          = (*Recorder)(nil)
+	_ plugin.KeyFormatDeprecatedV2         = (*Recorder)(nil)
+	_ plugin.KeyFlagsChangedV2             = (*Recorder)(nil)
+	_ plugin.KeyCompromisedV2              = (*Recorder)(nil)
+	_ plugin.SuspiciousValidationPatternV2 = (*Recorder)(nil)
+	_ plugin.KeyDebugEnabledV2             = (*Recorder)(nil)
+	_ plugin.KeyExportedV2                 = (*Recorder)(nil)
+	_ plugin.KeyImportedV2                 = (*Recorder)(nil)
+	_ plugin.UsageIdentifiersErasedV2      = (*Recorder)(nil)
+	_ plugin.RuntimeConfigChangedV2        = (*Recorder)(nil)
+	_ plugin.StorageReportGeneratedV2      = (*Recorder)(nil)
+	_ plugin.PolicyCreatedV2               = (*Recorder)(nil)
+	_ plugin.PolicyUpdatedV2               = (*Recorder)(nil)
+	_ plugin.PolicyDeletedV2               = (*Recorder)(nil)
+	_ plugin.GroupMembershipChangedV2      = (*Recorder)(nil)
+	_ plugin.KeyTransferChangedV2          = (*Recorder)(nil)
+	_ plugin.TenantLockdownChangedV2       = (*Recorder)(nil)
+	_ plugin.PluginPanickedV2              = (*Recorder)(nil)
+	_ plugin.ShutdownV2                    = (*Recorder)(nil)
+)
+
+// Event is one hook call seen by a Recorder. Hook is the name of the
+// plugin interface, such as "KeyCreated"; the other fields hold the hook's
+// arguments and are zero when the hook has no such argument. Key is a copy
+// taken when the hook fired.
+type Event struct {
+	Hook string
+
+	Key      *key.Key
+	Policy   *policy.Policy
+	PolicyID id.PolicyID
+	Rotation *rotation.Record
+
+	// Reason is the revocation reason for KeyRevoked and the presenting
+	// service for KeyConsumerMismatch.
+	Reason string
+
+	// Err is the error passed to KeyCreateFailed, KeyValidationFailed, and
+	// PluginPanicked.
+	Err error
+
+	Added, Removed key.Flags
+	Compromise     *key.CompromiseReport
+	Pattern        *key.FailurePattern
+	Penalty        *key.AdaptivePenalty
+	Quota          *key.QuotaExceeded
+	Forecast       *key.QuotaForecast
+	RotationDue    *key.RotationDue
+	Deprecation    *key.FormatDeprecation
+	Erasure        *usage.Erasure
+	ConfigChange   *plugin.ConfigChange
+
+	// Group, AddedKeys, and RemovedKeys are the arguments of
+	// GroupMembershipChanged.
+	Group                  *group.Group
+	AddedKeys, RemovedKeys []id.KeyID
+
+	// Transfer is a copy of KeyTransferChanged's transfer.
+	Transfer *transfer.Transfer
+
+	// TenantID and Lockdown are the tenant and a copy of the lockdown of
+	// TenantLockdownChanged.
+	TenantID string
+	Lockdown *tenant.Lockdown
+
+	// StorageReport is the report of StorageReportGenerated.
+	StorageReport *store.StorageReport
+
+	// Fingerprint identifies the key that failed KeyValidationFailed.
+	Fingerprint string
+
+	// Meta is the event meta the hook received.
+	Meta plugin.EventMeta
+}
+
+// Recorder is a plugin that records every hook the engine fires, for
+// assertions on lifecycle events. Register it with keysmith.WithExtension.
+// It is safe for concurrent use.
+type Recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder { return &Recorder{} }
+
+// Events returns the recorded events, oldest first.
+func (r *Recorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]Event, len(r.events))
+	copy(out, r.events)
+	return out
+}
+
+// Filter returns the recorded events of hook, oldest first.
+func (r *Recorder) Filter(hook string) []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []Event
+	for _, evt := range r.events {
+		if evt.Hook == hook {
+			out = append(out, evt)
+		}
+	}
+	return out
+}
+
+// Count returns how many times hook fired.
+func (r *Recorder) Count(hook string) int { return len(r.Filter(hook)) }
+
+// Hooks returns the names of the recorded hooks in the order they fired.
+func (r *Recorder) Hooks() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]string, len(r.events))
+	for i, evt := range r.events {
+		out[i] = evt.Hook
+	}
+	return out
+}
+
+// Reset discards the recorded events.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = nil
+}
+
+func (r *Recorder) record(evt Event) error {
+	if evt.Key != nil {
+		cp := *evt.Key
+		evt.Key = &cp
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, evt)
+	return nil
+}
+
+// Name implements plugin.Plugin.
+func (r *Recorder) Name() string { return "keysmithtest-recorder" }
+
+// OnKeyCreatedV2 implements plugin.KeyCreatedV2.
+func (r *Recorder) OnKeyCreatedV2(_ context.Context, k *key.Key, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "KeyCreated", Key: k, Meta: meta})
+}
+
+// OnKeyCreateFailedV2 implements plugin.KeyCreateFailedV2.
+func (r *Recorder) OnKeyCreateFailedV2(_ context.Context, k *key.Key, err error, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "KeyCreateFailed", Key: k, Err: err, Meta: meta})
+}
+
+// OnKeyValidatedV2 implements plugin.KeyValidatedV2.
+func (r *Recorder) OnKeyValidatedV2(_ context.Context, k *key.Key, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "KeyValidated", Key: k, Meta: meta})
+}
+
+// OnKeyValidationFailedV2 implements plugin.KeyValidationFailedV2.
+func (r *Recorder) OnKeyValidationFailedV2(_ context.Context, fingerprint string, err error, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "KeyValidationFailed", Fingerprint: fingerprint, Err: err, Meta: meta})
+}
+
+// OnKeyRotatedV2 implements plugin.KeyRotatedV2.
+func (r *Recorder) OnKeyRotatedV2(_ context.Context, k *key.Key, rec *rotation.Record, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "KeyRotated", Key: k, Rotation: rec, Meta: meta})
+}
+
+// OnKeyRevokedV2 implements plugin.KeyRevokedV2.
+func (r *Recorder) OnKeyRevokedV2(_ context.Context, k *key.Key, reason string, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "KeyRevoked", Key: k, Reason: reason, Meta: meta})
+}
+
+// OnKeySuspendedV2 implements plugin.KeySuspendedV2.
+func (r *Recorder) OnKeySuspendedV2(_ context.Context, k *key.Key, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "KeySuspended", Key: k, Meta: meta})
+}
+
+// OnKeyReactivatedV2 implements plugin.KeyReactivatedV2.
+func (r *Recorder) OnKeyReactivatedV2(_ context.Context, k *key.Key, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "KeyReactivated", Key: k, Meta: meta})
+}
+
+// OnKeyExpiredV2 implements plugin.KeyExpiredV2.
+func (r *Recorder) OnKeyExpiredV2(_ context.Context, k *key.Key, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "KeyExpired", Key: k, Meta: meta})
+}
+
+// OnKeyRateLimitedV2 implements plugin.KeyRateLimitedV2.
+func (r *Recorder) OnKeyRateLimitedV2(_ context.Context, k *key.Key, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "KeyRateLimited", Key: k, Meta: meta})
+}
+
+// OnTenantRateLimitedV2 implements plugin.TenantRateLimitedV2.
+func (r *Recorder) OnTenantRateLimitedV2(_ context.Context, k *key.Key, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "TenantRateLimited", Key: k, Meta: meta})
+}
+
+// OnKeyAdaptivelyLimitedV2 implements plugin.KeyAdaptivelyLimitedV2.
+func (r *Recorder) OnKeyAdaptivelyLimitedV2(_ context.Context, k *key.Key, p *key.AdaptivePenalty, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "KeyAdaptivelyLimited", Key: k, Penalty: p, Meta: meta})
+}
+
+// OnKeyQuotaExceededV2 implements plugin.KeyQuotaExceededV2.
+func (r *Recorder) OnKeyQuotaExceededV2(_ context.Context, k *key.Key, q *key.QuotaExceeded, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "KeyQuotaExceeded", Key: k, Quota: q, Meta: meta})
+}
+
+// OnKeyQuotaForecastWarningV2 implements plugin.KeyQuotaForecastWarningV2.
+func (r *Recorder) OnKeyQuotaForecastWarningV2(_ context.Context, k *key.Key, f *key.QuotaForecast, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "KeyQuotaForecastWarning", Key: k, Forecast: f, Meta: meta})
+}
+
+// OnKeyConsumerMismatchV2 implements plugin.KeyConsumerMismatchV2.
+func (r *Recorder) OnKeyConsumerMismatchV2(_ context.Context, k *key.Key, service string, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "KeyConsumerMismatch", Key: k, Reason: service, Meta: meta})
+}
+
+// OnKeyRotationOverdueV2 implements plugin.KeyRotationOverdueV2.
+func (r *Recorder) OnKeyRotationOverdueV2(_ context.Context, k *key.Key, due *key.RotationDue, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "KeyRotationOverdue", Key: k, RotationDue: due, Meta: meta})
+}
+
+// OnKeyFormatDeprecatedV2 implements plugin.KeyFormatDeprecatedV2.
+func (r *Recorder) OnKeyFormatDeprecatedV2(_ context.Context, k *key.Key, d *key.FormatDeprecation, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "KeyFormatDeprecated", Key: k, Deprecation: d, Meta: meta})
+}
+
+// OnKeyFlagsChangedV2 implements plugin.KeyFlagsChangedV2.
+func (r *Recorder) OnKeyFlagsChangedV2(_ context.Context, k *key.Key, added, removed key.Flags, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "KeyFlagsChanged", Key: k, Added: added, Removed: removed, Meta: meta})
+}
+
+// OnKeyCompromisedV2 implements plugin.KeyCompromisedV2.
+func (r *Recorder) OnKeyCompromisedV2(_ context.Context, k *key.Key, report *key.CompromiseReport, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "KeyCompromised", Key: k, Compromise: report, Meta: meta})
+}
+
+// OnSuspiciousValidationPatternV2 implements plugin.SuspiciousValidationPatternV2.
+func (r *Recorder) OnSuspiciousValidationPatternV2(_ context.Context, p *key.FailurePattern, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "SuspiciousValidationPattern", Pattern: p, Meta: meta})
+}
+
+// OnKeyDebugEnabledV2 implements plugin.KeyDebugEnabledV2.
+func (r *Recorder) OnKeyDebugEnabledV2(_ context.Context, k *key.Key, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "KeyDebugEnabled", Key: k, Meta: meta})
+}
+
+// OnKeyExportedV2 implements plugin.KeyExportedV2.
+func (r *Recorder) OnKeyExportedV2(_ context.Context, k *key.Key, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "KeyExported", Key: k, Meta: meta})
+}
+
+// OnKeyImportedV2 implements plugin.KeyImportedV2.
+func (r *Recorder) OnKeyImportedV2(_ context.Context, k *key.Key, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "KeyImported", Key: k, Meta: meta})
+}
+
+// OnUsageIdentifiersErasedV2 implements plugin.UsageIdentifiersErasedV2.
+func (r *Recorder) OnUsageIdentifiersErasedV2(_ context.Context, er *usage.Erasure, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "UsageIdentifiersErased", Erasure: er, Meta: meta})
+}
+
+// OnRuntimeConfigChangedV2 implements plugin.RuntimeConfigChangedV2.
+func (r *Recorder) OnRuntimeConfigChangedV2(_ context.Context, change *plugin.ConfigChange, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "RuntimeConfigChanged", ConfigChange: change, Meta: meta})
+}
+
+// OnStorageReportGeneratedV2 implements plugin.StorageReportGeneratedV2.
+func (r *Recorder) OnStorageReportGeneratedV2(_ context.Context, report *store.StorageReport, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "StorageReportGenerated", StorageReport: report, Meta: meta})
+}
+
+// OnPolicyCreatedV2 implements plugin.PolicyCreatedV2.
+func (r *Recorder) OnPolicyCreatedV2(_ context.Context, pol *policy.Policy, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "PolicyCreated", Policy: pol, Meta: meta})
+}
+
+// OnPolicyUpdatedV2 implements plugin.PolicyUpdatedV2.
+func (r *Recorder) OnPolicyUpdatedV2(_ context.Context, pol *policy.Policy, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "PolicyUpdated", Policy: pol, Meta: meta})
+}
+
+// OnPolicyDeletedV2 implements plugin.PolicyDeletedV2.
+func (r *Recorder) OnPolicyDeletedV2(_ context.Context, polID id.PolicyID, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "PolicyDeleted", PolicyID: polID, Meta: meta})
+}
+
+// OnGroupMembershipChangedV2 implements plugin.GroupMembershipChangedV2.
+func (r *Recorder) OnGroupMembershipChangedV2(_ context.Context, g *group.Group, added, removed []id.KeyID, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "GroupMembershipChanged", Group: g, AddedKeys: added, RemovedKeys: removed, Meta: meta})
+}
+
+// OnKeyTransferChangedV2 implements plugin.KeyTransferChangedV2.
+func (r *Recorder) OnKeyTransferChangedV2(_ context.Context, t *transfer.Transfer, meta plugin.EventMeta) error {
+	cp := *t
+	return r.record(Event{Hook: "KeyTransferChanged", Transfer: &cp, Meta: meta})
+}
+
+// OnTenantLockdownChangedV2 implements plugin.TenantLockdownChangedV2.
+func (r *Recorder) OnTenantLockdownChangedV2(_ context.Context, tenantID string, l *tenant.Lockdown, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "TenantLockdownChanged", TenantID: tenantID, Lockdown: l.Clone(), Meta: meta})
+}
+
+// OnPluginPanickedV2 implements plugin.PluginPanickedV2.
+func (r *Recorder) OnPluginPanickedV2(_ context.Context, err *plugin.HookError, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "PluginPanicked", Err: err, Meta: meta})
+}
+
+// OnShutdownV2 implements plugin.ShutdownV2.
+func (r *Recorder) OnShutdownV2(_ context.Context, meta plugin.EventMeta) error {
+	return r.record(Event{Hook: "Shutdown", Meta: meta})
+}
@@ -0,0 +1,114 @@
+package keysmithtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/usage"
+)
+
+// UsageBuilder seeds usage records for one key. The zero options give a
+// single successful GET /v1/test.
+type UsageBuilder struct {
+	eng      *keysmith.Engine
+	rec      usage.Record
+	count    int
+	from, to time.Time
+}
+
+// NewUsage starts building usage records for keyID.
+func NewUsage(eng *keysmith.Engine, keyID id.KeyID) *UsageBuilder {
+	return &UsageBuilder{eng: eng, count: 1, rec: usage.Record{
+		KeyID:      keyID,
+		Method:     "GET",
+		Endpoint:   "/v1/test",
+		StatusCode: 200,
+		IPAddress:  "192.0.2.1",
+	}}
+}
+
+// Count sets how many records are created.
+func (b *UsageBuilder) Count(n int) *UsageBuilder {
+	b.count = n
+	return b
+}
+
+// Between spreads the records evenly from from up to, but not including,
+// to. Without it, records are stamped with the current time.
+func (b *UsageBuilder) Between(from, to time.Time) *UsageBuilder {
+	b.from, b.to = from, to
+	return b
+}
+
+// WithEndpoint sets the request method and path.
+func (b *UsageBuilder) WithEndpoint(method, path string) *UsageBuilder {
+	b.rec.Method, b.rec.Endpoint = method, path
+	return b
+}
+
+// WithStatus sets the response status code.
+func (b *UsageBuilder) WithStatus(code int) *UsageBuilder {
+	b.rec.StatusCode = code
+	return b
+}
+
+// WithLatency sets the request latency.
+func (b *UsageBuilder) WithLatency(d time.Duration) *UsageBuilder {
+	b.rec.Latency = d
+	return b
+}
+
+// Create records the usage in ctx's tenant and app and returns the
+// records. Records are written with Engine.RecordUsage, which stamps the
+// current time; with Between they go to the engine's usage store in one
+// batch instead, since RecordUsage cannot backdate them, and the endpoint
+// activity buffer does not see them.
+func (b *UsageBuilder) Create(ctx context.Context) ([]*usage.Record, error) {
+	if b.count <= 0 {
+		return nil, errors.New("keysmithtest: usage count must be positive")
+	}
+	recs := make([]*usage.Record, b.count)
+	for i := range recs {
+		rec := b.rec
+		recs[i] = &rec
+	}
+
+	if b.from.IsZero() {
+		for _, rec := range recs {
+			if err := b.eng.RecordUsage(ctx, rec); err != nil {
+				return nil, fmt.Errorf("keysmithtest: record usage: %w", err)
+			}
+		}
+		return recs, nil
+	}
+
+	if !b.to.After(b.from) {
+		return nil, errors.New("keysmithtest: usage range must end after it starts")
+	}
+	step := b.to.Sub(b.from) / time.Duration(b.count)
+	for i, rec := range recs {
+		rec.ID = id.NewUsageID()
+		rec.TenantID = keysmith.TenantIDFromContext(ctx)
+		rec.AppID = keysmith.AppIDFromContext(ctx)
+		rec.CreatedAt = b.from.Add(time.Duration(i) * step)
+	}
+	if err := b.eng.Store().Usages().RecordBatch(ctx, recs); err != nil {
+		return nil, fmt.Errorf("keysmithtest: record usage: %w", err)
+	}
+	return recs, nil
+}
+
+// MustCreate is Create, failing tb on error.
+func (b *UsageBuilder) MustCreate(tb testing.TB, ctx context.Context) []*usage.Record {
+	tb.Helper()
+	recs, err := b.Create(ctx)
+	if err != nil {
+		tb.Fatalf("%v", err)
+	}
+	return recs
+}
@@ -0,0 +1,300 @@
+package keysmith
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/xraph/go-utils/log"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/transfer"
+)
+
+// DefaultKeyTransferTTL is how long a key transfer waits for the target
+// tenant when WithKeyTransferTTL is not set.
+const DefaultKeyTransferTTL = 72 * time.Hour
+
+// Key transfers.
+//
+// A key moves between tenants of its app in two steps: a context scoped to
+// the source tenant initiates the transfer, and one scoped to the target
+// tenant accepts it before it expires. The key keeps its ID and secret, so
+// it validates throughout. Acceptance maps its scopes and policy to the
+// target tenant's by name; the ones without a match are dropped and listed
+// on the transfer.
+
+// InitiateKeyTransfer starts the transfer of a key to targetTenantID, which
+// must accept it within the engine's key transfer TTL. A revoked or expired
+// key cannot be transferred, and, when the target tenant requires unique key
+// names or has a key with the same external reference, neither can a key
+// that would clash there. A key has at most one pending transfer.
+func (e *Engine) InitiateKeyTransfer(ctx context.Context, keyID id.KeyID, targetTenantID string) (*transfer.Transfer, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	targetTenantID = strings.TrimSpace(targetTenantID)
+	k, err := e.getKey(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case targetTenantID == "":
+		return nil, fmt.Errorf("%w: target tenant is required", ErrInvalidKeyTransfer)
+	case targetTenantID == k.TenantID:
+		return nil, fmt.Errorf("%w: key already belongs to tenant %q", ErrInvalidKeyTransfer, targetTenantID)
+	case k.State == key.StateRevoked || k.State == key.StateExpired:
+		return nil, fmt.Errorf("%w: key is %s", ErrInvalidKeyTransfer, k.State)
+	}
+	if err := e.checkTransferTarget(ctx, k, targetTenantID); err != nil {
+		return nil, err
+	}
+
+	now := e.now()
+	if prev, err := e.store.Transfers().GetPending(ctx, keyID); err == nil && !now.Before(prev.ExpiresAt) {
+		// A lapsed transfer the sweep has not reached yet.
+		e.expireTransfer(ctx, prev, plugin.TriggerManual)
+	}
+	t := &transfer.Transfer{
+		ID:             id.NewTransferID(),
+		KeyID:          k.ID,
+		AppID:          k.AppID,
+		SourceTenantID: k.TenantID,
+		TargetTenantID: targetTenantID,
+		State:          transfer.StatePending,
+		RetagUsage:     e.transferRetag,
+		InitiatedBy:    ActorFromContext(ctx),
+		ExpiresAt:      now.Add(e.transferTTL),
+		CreatedAt:      now,
+	}
+	if IsDryRun(ctx) {
+		return t, nil
+	}
+	if err := e.store.Transfers().Create(ctx, t); err != nil {
+		if errors.Is(err, transfer.ErrPendingTransfer) {
+			return nil, fmt.Errorf("%w: %s", ErrKeyTransferPending, keyID)
+		}
+		return nil, fmt.Errorf("create key transfer: %w", err)
+	}
+	_ = e.hooks.FireKeyTransferChanged(ctx, t, e.eventMeta(ctx, plugin.TriggerManual, ""))
+	return t, nil
+}
+
+// AcceptKeyTransfer completes the pending transfer of a key. The context
+// must be scoped to the transfer's target tenant. The key moves to that
+// tenant with the scopes and policy of the same names there; the returned
+// transfer lists those that had no match. The key leaves its groups, which
+// belong to the source tenant. A transfer past its expiry is marked expired
+// and fails with ErrKeyTransferExpired.
+func (e *Engine) AcceptKeyTransfer(ctx context.Context, keyID id.KeyID) (*transfer.Transfer, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	t, err := e.pendingTransfer(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if scopeFromContext(ctx).tenantID != t.TargetTenantID {
+		return nil, ErrKeyTransferNotTarget
+	}
+	now := e.now()
+	if !now.Before(t.ExpiresAt) {
+		e.expireTransfer(ctx, t, plugin.TriggerManual)
+		return nil, fmt.Errorf("%w: %s", ErrKeyTransferExpired, t.ID)
+	}
+	k, err := e.store.Keys().Get(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.checkTransferTarget(ctx, k, t.TargetTenantID); err != nil {
+		return nil, err
+	}
+	remap, err := e.transferRemap(ctx, k, t)
+	if err != nil {
+		return nil, err
+	}
+	t.State = transfer.StateAccepted
+	t.ResolvedBy = ActorFromContext(ctx)
+	t.ResolvedAt = &now
+	if IsDryRun(ctx) {
+		return t, nil
+	}
+	ok, err := e.store.Transfers().Accept(ctx, t, remap)
+	if err != nil {
+		return nil, fmt.Errorf("accept key transfer: %w", err)
+	}
+	if !ok {
+		// Cancelled or expired since it was read.
+		return nil, fmt.Errorf("%w: %s", ErrKeyTransferNotFound, keyID)
+	}
+	e.invalidateKey(keyID)
+	e.bumpRevision(ctx, t.SourceTenantID)
+	e.bumpRevision(ctx, t.TargetTenantID)
+	_ = e.hooks.FireKeyTransferChanged(ctx, t, e.eventMeta(ctx, plugin.TriggerManual, ""))
+	return t, nil
+}
+
+// CancelKeyTransfer calls off the pending transfer of a key. Either tenant
+// may cancel it.
+func (e *Engine) CancelKeyTransfer(ctx context.Context, keyID id.KeyID) (*transfer.Transfer, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	t, err := e.pendingTransfer(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	now := e.now()
+	t.State = transfer.StateCancelled
+	t.ResolvedBy = ActorFromContext(ctx)
+	t.ResolvedAt = &now
+	if IsDryRun(ctx) {
+		return t, nil
+	}
+	ok, err := e.store.Transfers().Resolve(ctx, t.ID, transfer.StateCancelled, t.ResolvedBy, now)
+	if err != nil {
+		return nil, fmt.Errorf("cancel key transfer: %w", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrKeyTransferNotFound, keyID)
+	}
+	_ = e.hooks.FireKeyTransferChanged(ctx, t, e.eventMeta(ctx, plugin.TriggerManual, ""))
+	return t, nil
+}
+
+// GetKeyTransfer returns the pending transfer of a key, for either tenant.
+func (e *Engine) GetKeyTransfer(ctx context.Context, keyID id.KeyID) (*transfer.Transfer, error) {
+	return e.pendingTransfer(ctx, keyID)
+}
+
+// ListKeyTransfers lists the transfers from or to the tenants the context
+// may see, newest first. The limit defaults to DefaultPageSize and is
+// clamped by [Engine.PageLimit].
+func (e *Engine) ListKeyTransfers(ctx context.Context, filter *transfer.ListFilter) ([]*transfer.Transfer, error) {
+	f := transferFilter(ctx, filter)
+	f.Limit = e.PageLimit(f.Limit, DefaultPageSize)
+	return e.store.Transfers().List(ctx, f)
+}
+
+// CleanupExpiredKeyTransfers marks pending transfers past their expiry
+// expired. Their keys stay with the source tenant.
+func (e *Engine) CleanupExpiredKeyTransfers(ctx context.Context) (*CleanupResult, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	res := &CleanupResult{DryRun: IsDryRun(ctx)}
+	now := e.now()
+	ts, err := e.store.Transfers().List(ctx, &transfer.ListFilter{State: transfer.StatePending, ExpiresBefore: &now})
+	if err != nil {
+		return nil, fmt.Errorf("list expired key transfers: %w", err)
+	}
+	for _, t := range ts {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		if res.DryRun {
+			res.KeyIDs = append(res.KeyIDs, t.KeyID)
+			continue
+		}
+		ok, err := e.store.Transfers().Resolve(ctx, t.ID, transfer.StateExpired, "", now)
+		if err != nil {
+			e.logger.Warn("failed to expire key transfer", log.String("transfer_id", t.ID.String()), log.Any("error", err))
+			res.Failed++
+			continue
+		}
+		if !ok {
+			// Accepted or cancelled concurrently.
+			continue
+		}
+		res.KeyIDs = append(res.KeyIDs, t.KeyID)
+		t.State = transfer.StateExpired
+		t.ResolvedAt = &now
+		_ = e.hooks.FireKeyTransferChanged(ctx, t, e.eventMeta(ctx, plugin.TriggerSweep, ""))
+	}
+	return res, nil
+}
+
+// pendingTransfer returns the pending transfer of a key when the context
+// may see its source or target tenant.
+func (e *Engine) pendingTransfer(ctx context.Context, keyID id.KeyID) (*transfer.Transfer, error) {
+	t, err := e.store.Transfers().GetPending(ctx, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrKeyTransferNotFound, keyID)
+	}
+	sc := scopeFromContext(ctx)
+	if !sc.owns(t.SourceTenantID, t.AppID) && !sc.owns(t.TargetTenantID, t.AppID) {
+		return nil, fmt.Errorf("%w: %s", ErrKeyTransferNotFound, keyID)
+	}
+	return t, nil
+}
+
+// expireTransfer marks a lapsed pending transfer expired. Failures are
+// logged and left to CleanupExpiredKeyTransfers.
+func (e *Engine) expireTransfer(ctx context.Context, t *transfer.Transfer, trigger plugin.Trigger) {
+	if IsDryRun(ctx) {
+		return
+	}
+	now := e.now()
+	ok, err := e.store.Transfers().Resolve(ctx, t.ID, transfer.StateExpired, "", now)
+	if err != nil {
+		e.logger.Warn("failed to expire key transfer", log.String("transfer_id", t.ID.String()), log.Any("error", err))
+		return
+	}
+	if !ok {
+		return
+	}
+	t.State = transfer.StateExpired
+	t.ResolvedAt = &now
+	_ = e.hooks.FireKeyTransferChanged(ctx, t, e.eventMeta(ctx, trigger, ""))
+}
+
+// checkTransferTarget reports whether k would clash with a key of the
+// target tenant: one with the same external reference, or the same name
+// when the target requires unique names.
+func (e *Engine) checkTransferTarget(ctx context.Context, k *key.Key, targetTenantID string) error {
+	if k.ExternalRef != "" {
+		other, err := e.keyByExternalRef(ctx, targetTenantID, k.ExternalRef)
+		if err != nil {
+			return err
+		}
+		if other != nil {
+			return fmt.Errorf("%w: %q", ErrExternalRefInUse, k.ExternalRef)
+		}
+	}
+	return e.checkKeyName(ctx, targetTenantID, k.Name, k.ID)
+}
+
+// transferRemap maps the key's scopes and policy to the target tenant's of
+// the same names, recording those without one on t.
+func (e *Engine) transferRemap(ctx context.Context, k *key.Key, t *transfer.Transfer) (*transfer.Remap, error) {
+	remap := &transfer.Remap{}
+	assigned, err := e.store.Scopes().ListByKey(ctx, k.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list scopes: %w", err)
+	}
+	t.UnmappedScopes = nil
+	for _, s := range assigned {
+		if _, err := e.store.Scopes().GetByName(ctx, t.TargetTenantID, s.Name); err == nil {
+			remap.Scopes = append(remap.Scopes, s.Name)
+		} else {
+			t.UnmappedScopes = append(t.UnmappedScopes, s.Name)
+		}
+	}
+	t.UnmappedPolicy = ""
+	if k.PolicyID != nil {
+		pol, err := e.store.Policies().Get(ctx, *k.PolicyID)
+		if err != nil {
+			return nil, fmt.Errorf("get policy: %w", err)
+		}
+		if target, err := e.store.Policies().GetByName(ctx, t.TargetTenantID, pol.Name); err == nil {
+			remap.PolicyID = &target.ID
+		} else {
+			t.UnmappedPolicy = pol.Name
+		}
+	}
+	return remap, nil
+}
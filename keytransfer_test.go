@@ -0,0 +1,225 @@
+package keysmith_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keysmithtest"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/tenant"
+	"github.com/xraph/keysmith/transfer"
+)
+
+func targetCtx() context.Context {
+	return keysmith.WithTenant(context.Background(), "app_test", "tenant_other")
+}
+
+func newTransferEngine(t *testing.T, opts ...keysmith.Option) (*keysmith.Engine, *fakeClock, *keysmithtest.Recorder) {
+	t.Helper()
+	clock := &fakeClock{t: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)}
+	rec := keysmithtest.NewRecorder()
+	opts = append([]keysmith.Option{keysmith.WithClock(clock.Now), keysmith.WithExtension(rec)}, opts...)
+	return keysmithtest.NewEngine(t, opts...), clock, rec
+}
+
+// transferStates returns the states of the recorded transfer events.
+func transferStates(rec *keysmithtest.Recorder) []transfer.State {
+	var out []transfer.State
+	for _, evt := range rec.Filter("KeyTransferChanged") {
+		out = append(out, evt.Transfer.State)
+	}
+	return out
+}
+
+func TestKeyTransfer_Accept(t *testing.T) {
+	eng, _, rec := newTransferEngine(t)
+	for _, ctx := range []context.Context{testCtx(), targetCtx()} {
+		require.NoError(t, eng.CreateScope(ctx, &scope.Scope{Name: "read"}))
+		require.NoError(t, eng.CreatePolicy(ctx, &policy.Policy{Name: "Pro"}))
+	}
+	pols, err := eng.ListPolicies(testCtx(), nil)
+	require.NoError(t, err)
+	require.Len(t, pols, 1)
+	created, err := eng.CreateKey(keysmith.WithActor(testCtx(), "alice"), &keysmith.CreateKeyInput{
+		Name: "Billing", Prefix: "sk", Environment: key.EnvTest, Scopes: []string{"read"}, PolicyID: &pols[0].ID,
+	})
+	require.NoError(t, err)
+	kid := created.Key.ID
+
+	tr, err := eng.InitiateKeyTransfer(keysmith.WithActor(testCtx(), "alice"), kid, "tenant_other")
+	require.NoError(t, err)
+	assert.Equal(t, transfer.StatePending, tr.State)
+	assert.Equal(t, "alice", tr.InitiatedBy)
+	assert.Equal(t, time.Date(2024, 1, 18, 0, 0, 0, 0, time.UTC), tr.ExpiresAt, "DefaultKeyTransferTTL")
+
+	_, err = eng.InitiateKeyTransfer(testCtx(), kid, "tenant_third")
+	assert.ErrorIs(t, err, keysmith.ErrKeyTransferPending)
+	_, err = eng.AcceptKeyTransfer(testCtx(), kid)
+	assert.ErrorIs(t, err, keysmith.ErrKeyTransferNotTarget, "the source cannot accept its own transfer")
+	_, err = eng.AcceptKeyTransfer(keysmith.WithTenant(context.Background(), "app_test", "tenant_third"), kid)
+	assert.ErrorIs(t, err, keysmith.ErrKeyTransferNotFound, "other tenants do not see the transfer")
+
+	pending, err := eng.GetKeyTransfer(targetCtx(), kid)
+	require.NoError(t, err)
+	assert.Equal(t, tr.ID, pending.ID)
+	_, err = eng.GetKey(targetCtx(), kid)
+	assert.ErrorIs(t, err, keysmith.ErrKeyNotFound, "the key is not the target's until accepted")
+	_, err = eng.ValidateKey(context.Background(), created.RawKey)
+	require.NoError(t, err, "a pending transfer does not interrupt validation")
+
+	accepted, err := eng.AcceptKeyTransfer(keysmith.WithActor(targetCtx(), "bob"), kid)
+	require.NoError(t, err)
+	assert.Equal(t, transfer.StateAccepted, accepted.State)
+	assert.Equal(t, "bob", accepted.ResolvedBy)
+	assert.Empty(t, accepted.UnmappedScopes)
+	assert.Empty(t, accepted.UnmappedPolicy)
+
+	got, err := eng.GetKey(targetCtx(), kid)
+	require.NoError(t, err)
+	assert.Equal(t, "tenant_other", got.TenantID)
+	targetPols, err := eng.ListPolicies(targetCtx(), nil)
+	require.NoError(t, err)
+	require.NotNil(t, got.PolicyID)
+	assert.Equal(t, targetPols[0].ID, *got.PolicyID, "the policy maps to the target's of the same name")
+	_, err = eng.GetKey(testCtx(), kid)
+	assert.ErrorIs(t, err, keysmith.ErrKeyNotFound)
+
+	res, err := eng.ValidateKey(context.Background(), created.RawKey)
+	require.NoError(t, err, "the key keeps its secret")
+	assert.Equal(t, "tenant_other", res.Key.TenantID)
+	assert.Equal(t, []string{"read"}, res.Scopes)
+
+	_, err = eng.GetKeyTransfer(targetCtx(), kid)
+	assert.ErrorIs(t, err, keysmith.ErrKeyTransferNotFound)
+	for _, ctx := range []context.Context{testCtx(), targetCtx()} {
+		list, err := eng.ListKeyTransfers(ctx, nil)
+		require.NoError(t, err)
+		require.Len(t, list, 1, "both tenants list the transfer")
+		assert.Equal(t, transfer.StateAccepted, list[0].State)
+	}
+	assert.Equal(t, []transfer.State{transfer.StatePending, transfer.StateAccepted}, transferStates(rec))
+}
+
+func TestKeyTransfer_ScopeRemapGap(t *testing.T) {
+	eng, _, _ := newTransferEngine(t)
+	require.NoError(t, eng.CreateScope(testCtx(), &scope.Scope{Name: "read"}))
+	require.NoError(t, eng.CreateScope(testCtx(), &scope.Scope{Name: "write"}))
+	require.NoError(t, eng.CreateScope(targetCtx(), &scope.Scope{Name: "read"}))
+	require.NoError(t, eng.CreatePolicy(testCtx(), &policy.Policy{Name: "Enterprise"}))
+	pols, err := eng.ListPolicies(testCtx(), nil)
+	require.NoError(t, err)
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+		Name: "Billing", Prefix: "sk", Environment: key.EnvTest, Scopes: []string{"read", "write"}, PolicyID: &pols[0].ID,
+	})
+	require.NoError(t, err)
+
+	_, err = eng.InitiateKeyTransfer(testCtx(), created.Key.ID, "tenant_other")
+	require.NoError(t, err)
+	accepted, err := eng.AcceptKeyTransfer(targetCtx(), created.Key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"write"}, accepted.UnmappedScopes)
+	assert.Equal(t, "Enterprise", accepted.UnmappedPolicy)
+
+	got, err := eng.GetKey(targetCtx(), created.Key.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got.PolicyID, "the target has no policy of that name")
+	res, err := eng.ValidateKey(context.Background(), created.RawKey)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read"}, res.Scopes)
+}
+
+func TestKeyTransfer_Cancel(t *testing.T) {
+	eng, _, rec := newTransferEngine(t)
+	created := keysmithtest.NewKey(eng).MustCreate(t, testCtx())
+	kid := created.Key.ID
+
+	for _, ctx := range []context.Context{testCtx(), targetCtx()} {
+		_, err := eng.InitiateKeyTransfer(testCtx(), kid, "tenant_other")
+		require.NoError(t, err)
+		cancelled, err := eng.CancelKeyTransfer(keysmith.WithActor(ctx, "carol"), kid)
+		require.NoError(t, err, "either tenant may cancel")
+		assert.Equal(t, transfer.StateCancelled, cancelled.State)
+		assert.Equal(t, "carol", cancelled.ResolvedBy)
+	}
+	_, err := eng.CancelKeyTransfer(testCtx(), kid)
+	assert.ErrorIs(t, err, keysmith.ErrKeyTransferNotFound)
+	_, err = eng.AcceptKeyTransfer(targetCtx(), kid)
+	assert.ErrorIs(t, err, keysmith.ErrKeyTransferNotFound)
+
+	got, err := eng.GetKey(testCtx(), kid)
+	require.NoError(t, err)
+	assert.Equal(t, "tenant_test", got.TenantID)
+	assert.Equal(t, []transfer.State{
+		transfer.StatePending, transfer.StateCancelled, transfer.StatePending, transfer.StateCancelled,
+	}, transferStates(rec))
+}
+
+func TestKeyTransfer_Expire(t *testing.T) {
+	eng, clock, rec := newTransferEngine(t, keysmith.WithKeyTransferTTL(time.Hour))
+	start := clock.Now()
+	first := keysmithtest.NewKey(eng).MustCreate(t, testCtx())
+	second := keysmithtest.NewKey(eng).MustCreate(t, testCtx())
+	_, err := eng.InitiateKeyTransfer(testCtx(), first.Key.ID, "tenant_other")
+	require.NoError(t, err)
+	_, err = eng.InitiateKeyTransfer(testCtx(), second.Key.ID, "tenant_other")
+	require.NoError(t, err)
+
+	clock.Set(start.Add(time.Hour))
+	_, err = eng.AcceptKeyTransfer(targetCtx(), first.Key.ID)
+	assert.ErrorIs(t, err, keysmith.ErrKeyTransferExpired)
+	_, err = eng.AcceptKeyTransfer(targetCtx(), first.Key.ID)
+	assert.ErrorIs(t, err, keysmith.ErrKeyTransferNotFound, "the failed accept marked it expired")
+
+	clock.Set(start.Add(2 * time.Hour))
+	res, err := eng.CleanupExpiredKeyTransfers(keysmith.WithDryRun(context.Background()))
+	require.NoError(t, err)
+	assert.True(t, res.DryRun)
+	assert.Len(t, res.KeyIDs, 1)
+	res, err = eng.CleanupExpiredKeyTransfers(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, res.KeyIDs, 1)
+	assert.Equal(t, second.Key.ID, res.KeyIDs[0])
+
+	for _, c := range []*key.CreateResult{first, second} {
+		got, err := eng.GetKey(testCtx(), c.Key.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "tenant_test", got.TenantID, "expired transfers leave the key with its tenant")
+	}
+	expired := rec.Filter("KeyTransferChanged")
+	require.Len(t, expired, 4)
+	assert.Equal(t, transfer.StateExpired, expired[3].Transfer.State)
+	assert.Equal(t, plugin.TriggerSweep, expired[3].Meta.Trigger)
+
+	_, err = eng.InitiateKeyTransfer(testCtx(), first.Key.ID, "tenant_other")
+	assert.NoError(t, err, "an expired transfer does not block a new one")
+}
+
+func TestKeyTransfer_Invalid(t *testing.T) {
+	eng, _, _ := newTransferEngine(t)
+	created := keysmithtest.NewKey(eng).MustCreate(t, testCtx())
+
+	_, err := eng.InitiateKeyTransfer(testCtx(), created.Key.ID, " ")
+	assert.ErrorIs(t, err, keysmith.ErrInvalidKeyTransfer)
+	_, err = eng.InitiateKeyTransfer(testCtx(), created.Key.ID, "tenant_test")
+	assert.ErrorIs(t, err, keysmith.ErrInvalidKeyTransfer)
+	_, err = eng.InitiateKeyTransfer(targetCtx(), created.Key.ID, "tenant_other")
+	assert.ErrorIs(t, err, keysmith.ErrKeyNotFound)
+
+	require.NoError(t, eng.SetTenantSettings(targetCtx(), &tenant.Settings{UniqueKeyNames: true}))
+	_, err = eng.CreateKey(targetCtx(), &keysmith.CreateKeyInput{Name: created.Key.Name, Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	_, err = eng.InitiateKeyTransfer(testCtx(), created.Key.ID, "tenant_other")
+	assert.ErrorIs(t, err, keysmith.ErrDuplicateKeyName, "the name would clash in the target")
+
+	require.NoError(t, eng.RevokeKey(testCtx(), created.Key.ID, "done"))
+	_, err = eng.InitiateKeyTransfer(testCtx(), created.Key.ID, "tenant_third")
+	assert.ErrorIs(t, err, keysmith.ErrInvalidKeyTransfer)
+}
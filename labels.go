@@ -0,0 +1,48 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/plugin"
+)
+
+// validateLabels checks labels at write time.
+func validateLabels(l key.Labels) error {
+	if err := l.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidLabels, err)
+	}
+	return nil
+}
+
+// ParseLabelSelector parses a label selector such as
+// "team=payments,env in (staging,prod)". See [key.Selector] for the syntax.
+func ParseLabelSelector(s string) (key.Selector, error) {
+	sel, err := key.ParseSelector(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidLabelSelector, err)
+	}
+	return sel, nil
+}
+
+// BulkRevokeByLabels revokes every key matching filter, which must have a
+// LabelSelector, ignoring paging. The filter's other fields narrow the
+// match, and it is restricted to the context's tenant and app like
+// ListKeys. Each revocation fires KeyRevoked with reason and the
+// label_revoked reason code. With [WithDryRun] it reports the keys it would
+// revoke. A key the store fails to update is logged and counted in Failed,
+// and the run continues.
+func (e *Engine) BulkRevokeByLabels(ctx context.Context, filter *key.ListFilter, reason string) (*BulkRevokeResult, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	if filter == nil || len(filter.LabelSelector) == 0 {
+		return nil, fmt.Errorf("%w: a bulk revocation needs a selector", ErrInvalidLabelSelector)
+	}
+	res, err := e.bulkRevoke(ctx, keyFilter(ctx, filter), reason, plugin.ReasonLabelRevoked)
+	if err != nil {
+		return res, fmt.Errorf("iterate keys by labels: %w", err)
+	}
+	return res, nil
+}
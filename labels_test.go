@@ -0,0 +1,122 @@
+package keysmith_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keysmithtest"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+func createLabeled(t *testing.T, eng *keysmith.Engine, ctx context.Context, labels key.Labels) *key.Key {
+	t.Helper()
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Labeled", Prefix: "sk", Environment: key.EnvTest, Labels: labels})
+	require.NoError(t, err)
+	return created.Key
+}
+
+func TestCreateKey_Labels(t *testing.T) {
+	eng := newTestEngine(t)
+	ctx := testCtx()
+
+	k := createLabeled(t, eng, ctx, key.Labels{"team": "payments"})
+	assert.Equal(t, key.Labels{"team": "payments"}, k.Labels)
+
+	_, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
+		Name: "Bad", Prefix: "sk", Environment: key.EnvTest, Labels: key.Labels{"team": "not valid"},
+	})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidLabels)
+}
+
+func TestUpdateKey_Labels(t *testing.T) {
+	eng := newTestEngine(t)
+	ctx := testCtx()
+	k := createLabeled(t, eng, ctx, key.Labels{"team": "payments"})
+
+	name := "Renamed"
+	updated, err := eng.UpdateKey(ctx, k.ID, &keysmith.UpdateKeyInput{Name: &name})
+	require.NoError(t, err)
+	assert.Equal(t, key.Labels{"team": "payments"}, updated.Labels, "nil leaves labels alone")
+
+	updated, err = eng.UpdateKey(ctx, k.ID, &keysmith.UpdateKeyInput{Labels: key.Labels{"team": "billing", "env": "prod"}})
+	require.NoError(t, err)
+	assert.Equal(t, key.Labels{"team": "billing", "env": "prod"}, updated.Labels)
+
+	_, err = eng.UpdateKey(ctx, k.ID, &keysmith.UpdateKeyInput{Labels: key.Labels{"bad/name/x": "y"}})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidLabels)
+
+	updated, err = eng.UpdateKey(ctx, k.ID, &keysmith.UpdateKeyInput{Labels: key.Labels{}})
+	require.NoError(t, err)
+	assert.Empty(t, updated.Labels, "an empty map clears them")
+}
+
+func TestListKeys_LabelSelector(t *testing.T) {
+	eng := newTestEngine(t)
+	ctx := testCtx()
+	a := createLabeled(t, eng, ctx, key.Labels{"team": "payments", "env": "prod"})
+	b := createLabeled(t, eng, ctx, key.Labels{"team": "payments", "env": "staging"})
+	createLabeled(t, eng, ctx, nil)
+	createLabeled(t, eng, keysmith.WithTenant(context.Background(), "app_test", "tenant_other"), key.Labels{"team": "payments"})
+
+	sel, err := keysmith.ParseLabelSelector("team=payments")
+	require.NoError(t, err)
+	keys, err := eng.ListKeys(ctx, &key.ListFilter{LabelSelector: sel})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{a.ID.String(), b.ID.String()}, keyIDs(keys))
+
+	sel, err = keysmith.ParseLabelSelector("team=payments,env notin (staging)")
+	require.NoError(t, err)
+	keys, err = eng.ListKeys(ctx, &key.ListFilter{LabelSelector: sel})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{a.ID.String()}, keyIDs(keys))
+}
+
+func TestBulkRevokeByLabels(t *testing.T) {
+	rec := keysmithtest.NewRecorder()
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithExtension(rec))
+	require.NoError(t, err)
+	ctx := testCtx()
+
+	a := createLabeled(t, eng, ctx, key.Labels{"team": "payments", "env": "prod"})
+	b := createLabeled(t, eng, ctx, key.Labels{"team": "payments", "env": "staging"})
+	already := createLabeled(t, eng, ctx, key.Labels{"team": "payments"})
+	require.NoError(t, eng.RevokeKey(ctx, already.ID, "earlier"))
+	other := createLabeled(t, eng, ctx, key.Labels{"team": "search"})
+	rec.Reset()
+
+	_, err = eng.BulkRevokeByLabels(ctx, &key.ListFilter{}, "team disbanded")
+	require.ErrorIs(t, err, keysmith.ErrInvalidLabelSelector, "an empty selector would revoke everything")
+
+	sel, err := keysmith.ParseLabelSelector("team=payments")
+	require.NoError(t, err)
+
+	// A dry run reports the keys without revoking them.
+	res, err := eng.BulkRevokeByLabels(keysmith.WithDryRun(ctx), &key.ListFilter{LabelSelector: sel}, "team disbanded")
+	require.NoError(t, err)
+	assert.True(t, res.DryRun)
+	assert.ElementsMatch(t, []id.KeyID{a.ID, b.ID}, res.KeyIDs)
+	assert.Equal(t, 1, res.AlreadyRevoked)
+	assert.Zero(t, rec.Count("KeyRevoked"))
+
+	res, err = eng.BulkRevokeByLabels(ctx, &key.ListFilter{LabelSelector: sel, Environment: key.EnvTest, Limit: 1}, "team disbanded")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []id.KeyID{a.ID, b.ID}, res.KeyIDs, "paging is ignored")
+	assert.Zero(t, res.Failed)
+
+	revoked := rec.Filter("KeyRevoked")
+	require.Len(t, revoked, 2)
+	for _, evt := range revoked {
+		assert.Equal(t, "team disbanded", evt.Reason)
+		assert.Equal(t, plugin.ReasonLabelRevoked, evt.Meta.ReasonCode)
+	}
+	k, err := eng.GetKey(ctx, other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, key.StateActive, k.State, "unmatched keys are untouched")
+}
@@ -0,0 +1,119 @@
+package keysmith
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/xraph/keysmith/id"
+)
+
+// DefaultLastUsedFlushInterval is how long last-used times are buffered
+// before they are written, so that a busy key costs one write per interval
+// rather than one per validation.
+const DefaultLastUsedFlushInterval = 100 * time.Millisecond
+
+// lastUsedTracker coalesces the last-used writes of successful validations.
+// record keeps the latest time per key, and a single background flush,
+// started by the first record while none is running, writes them in
+// batches one interval apart until nothing is pending.
+type lastUsedTracker struct {
+	interval time.Duration
+
+	mu       sync.Mutex
+	pending  map[id.KeyID]time.Time
+	flushing bool
+
+	// hurry is closed by Stop so that the running flush stops waiting.
+	hurry     chan struct{}
+	hurryOnce sync.Once
+}
+
+func newLastUsedTracker(interval time.Duration) *lastUsedTracker {
+	return &lastUsedTracker{
+		interval: interval,
+		pending:  make(map[id.KeyID]time.Time),
+		hurry:    make(chan struct{}),
+	}
+}
+
+// record buffers at as keyID's last use and reports whether the caller must
+// start a flush.
+func (t *lastUsedTracker) record(keyID id.KeyID, at time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if cur, ok := t.pending[keyID]; !ok || at.After(cur) {
+		t.pending[keyID] = at
+	}
+	if t.flushing {
+		return false
+	}
+	t.flushing = true
+	return true
+}
+
+// take removes and returns the pending times. When nothing is pending it
+// ends the running flush and returns nil.
+func (t *lastUsedTracker) take() map[id.KeyID]time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.pending) == 0 {
+		t.flushing = false
+		return nil
+	}
+	batch := t.pending
+	t.pending = make(map[id.KeyID]time.Time, len(batch))
+	return batch
+}
+
+// drop discards the pending times of a flush that could not start.
+func (t *lastUsedTracker) drop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	clear(t.pending)
+	t.flushing = false
+}
+
+// shutdown makes the running flush, and any later one, write without
+// waiting for the interval.
+func (t *lastUsedTracker) shutdown() {
+	t.hurryOnce.Do(func() { close(t.hurry) })
+}
+
+// recordLastUsed buffers keyID's last use and starts the background flush
+// when none is running. Once Stop has closed the write group the time is
+// dropped.
+func (e *Engine) recordLastUsed(keyID id.KeyID, at time.Time) {
+	if !e.lastUsed.record(keyID, at) {
+		return
+	}
+	if !e.writes.add() {
+		e.lastUsed.drop()
+		return
+	}
+	go e.flushLastUsed()
+}
+
+// flushLastUsed writes buffered last-used times an interval at a time until
+// a wait leaves nothing pending. Failed writes are dropped, as a later
+// validation records the key again.
+func (e *Engine) flushLastUsed() {
+	defer e.writes.done()
+	t := e.lastUsed
+	timer := time.NewTimer(t.interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+		case <-t.hurry:
+		}
+		batch := t.take()
+		if batch == nil {
+			return
+		}
+		for keyID, at := range batch {
+			_ = e.store.Keys().UpdateLastUsed(context.Background(), keyID, at)
+		}
+		timer.Reset(t.interval)
+	}
+}
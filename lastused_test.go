@@ -0,0 +1,36 @@
+package keysmith_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store/chaos"
+)
+
+func TestValidateKey_CoalescesLastUsed(t *testing.T) {
+	eng, cs, _ := newDryRunEngine(t, keysmith.WithLastUsedFlushInterval(time.Hour))
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "Busy", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+
+	forbidWrites(t, cs)
+	for range 20 {
+		_, err := eng.ValidateKey(testCtx(), created.RawKey)
+		require.NoError(t, err)
+	}
+	assert.Empty(t, cs.Injections(), "last use is buffered")
+
+	// A rule only adding latency records each key write without failing it.
+	require.NoError(t, cs.Set(chaos.Rule{Name: "no-writes", Store: chaos.StoreKeys, Writes: true, Latency: chaos.Latency{Min: time.Nanosecond}}))
+	require.NoError(t, eng.Stop(context.Background()))
+	require.Len(t, cs.Injections(), 1, "Stop writes the buffered time once")
+	assert.Equal(t, "UpdateLastUsed", cs.Injections()[0].Method)
+	got, err := eng.GetKey(testCtx(), created.Key.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, got.LastUsedAt)
+}
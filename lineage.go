@@ -0,0 +1,137 @@
+package keysmith
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/rotation"
+)
+
+// HashEra is a period during which one hash of a key was current. Eras
+// carry hints and rotation records, never hashes.
+type HashEra struct {
+	// Index is the era's position in the chain, 0 for the hash the key was
+	// created with.
+	Index int `json:"index"`
+
+	// Start is when the hash became current: the key's creation, or the
+	// rotation that introduced it.
+	Start time.Time `json:"start"`
+
+	// End is when the next rotation replaced the hash, and GraceEnds is when
+	// that rotation's grace period let it stop validating. Both are nil for
+	// the current era. GraceEnds equals End for a zero-grace rotation.
+	End       *time.Time `json:"end,omitempty"`
+	GraceEnds *time.Time `json:"grace_ends,omitempty"`
+
+	// Hint is the last four characters of the era's raw key.
+	Hint string `json:"hint,omitempty"`
+
+	// Rotation is the rotation that introduced the hash, nil for era 0.
+	Rotation *rotation.Record `json:"rotation,omitempty"`
+
+	// GraceOverlaps lists the later eras that were current while this era's
+	// hash was still in its grace period. Rotating again within a grace
+	// period makes it list more than the next era.
+	GraceOverlaps []int `json:"grace_overlaps,omitempty"`
+}
+
+// contains reports whether the era's hash was current at t.
+func (h *HashEra) contains(t time.Time) bool {
+	return !t.Before(h.Start) && (h.End == nil || t.Before(*h.End))
+}
+
+// inGrace reports whether the era's hash was replaced but still in its grace
+// period at t. A zero-grace era has none.
+func (h *HashEra) inGrace(t time.Time) bool {
+	return h.End != nil && h.GraceEnds.After(*h.End) && !t.Before(*h.End) && !t.After(*h.GraceEnds)
+}
+
+// HashActivity reports which hashes of a key validated at a point in time.
+type HashActivity struct {
+	At time.Time `json:"at"`
+
+	// Current is the era whose hash was current at At.
+	Current *HashEra `json:"current"`
+
+	// Grace lists the earlier eras whose hash was still in its grace period
+	// at At, most recent first.
+	Grace []*HashEra `json:"grace,omitempty"`
+}
+
+// RotationChain returns the key's hash eras, oldest first, reconstructed
+// from its creation and its rotation records. Records are ordered by
+// CreatedAt, so records stored out of order still chain correctly.
+func (e *Engine) RotationChain(ctx context.Context, keyID id.KeyID) ([]*HashEra, error) {
+	k, err := e.getKey(ctx, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("get key: %w", err)
+	}
+	recs, err := e.store.Rotations().List(ctx, &rotation.ListFilter{KeyID: &keyID})
+	if err != nil {
+		return nil, fmt.Errorf("list rotations: %w", err)
+	}
+	slices.SortStableFunc(recs, func(a, b *rotation.Record) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID.String(), b.ID.String()))
+	})
+
+	eras := make([]*HashEra, 0, len(recs)+1)
+	eras = append(eras, &HashEra{Start: k.CreatedAt, Hint: k.Hint})
+	if len(recs) > 0 {
+		eras[0].Hint = recs[0].OldHint
+	}
+	for i, rec := range recs {
+		prev := eras[i]
+		end, graceEnds := rec.CreatedAt, rec.GraceEnds
+		if graceEnds.Before(end) {
+			graceEnds = end
+		}
+		prev.End, prev.GraceEnds = &end, &graceEnds
+		eras = append(eras, &HashEra{Index: i + 1, Start: rec.CreatedAt, Hint: rec.NewHint, Rotation: rec})
+	}
+	for _, era := range eras {
+		if era.End == nil || !era.GraceEnds.After(*era.End) {
+			continue
+		}
+		for _, later := range eras[era.Index+1:] {
+			if later.Start.After(*era.GraceEnds) {
+				break
+			}
+			era.GraceOverlaps = append(era.GraceOverlaps, later.Index)
+		}
+	}
+	return eras, nil
+}
+
+// HashActiveAt reports which of the key's hashes were current and in grace
+// at at. A time before the key was created returns ErrNoHashAt.
+func (e *Engine) HashActiveAt(ctx context.Context, keyID id.KeyID, at time.Time) (*HashActivity, error) {
+	eras, err := e.RotationChain(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	return ActivityAt(eras, at)
+}
+
+// ActivityAt is HashActiveAt over a chain already returned by
+// RotationChain.
+func ActivityAt(chain []*HashEra, at time.Time) (*HashActivity, error) {
+	act := &HashActivity{At: at}
+	for i := len(chain) - 1; i >= 0; i-- {
+		era := chain[i]
+		switch {
+		case act.Current == nil && era.contains(at):
+			act.Current = era
+		case act.Current != nil && era.inGrace(at):
+			act.Grace = append(act.Grace, era)
+		}
+	}
+	if act.Current == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoHashAt, at.Format(time.RFC3339))
+	}
+	return act, nil
+}
@@ -0,0 +1,139 @@
+package keysmith_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+func eraIndexes(eras []*keysmith.HashEra) []int {
+	out := make([]int, len(eras))
+	for i, era := range eras {
+		out[i] = era.Index
+	}
+	return out
+}
+
+func TestRotationChain_OverlappingGrace(t *testing.T) {
+	eng, clock, _ := newExpiryEngine(t)
+	ctx := testCtx()
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Lineage", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	base := created.Key.CreatedAt
+	hints := []string{created.Key.Hint}
+
+	// Rotations at +1h and +2h fall in each other's 24h grace; +48h does not.
+	for _, at := range []time.Duration{time.Hour, 2 * time.Hour, 48 * time.Hour} {
+		clock.Set(base.Add(at))
+		res, err := eng.RotateKey(ctx, created.Key.ID, rotation.ReasonManual)
+		require.NoError(t, err)
+		hints = append(hints, res.Key.Hint)
+	}
+
+	eras, err := eng.RotationChain(ctx, created.Key.ID)
+	require.NoError(t, err)
+	require.Len(t, eras, 4)
+
+	starts := []time.Duration{0, time.Hour, 2 * time.Hour, 48 * time.Hour}
+	for i, era := range eras {
+		assert.Equal(t, i, era.Index)
+		assert.True(t, era.Start.Equal(base.Add(starts[i])), "era %d start", i)
+		assert.Equal(t, hints[i], era.Hint, "era %d hint", i)
+		if i == 0 {
+			assert.Nil(t, era.Rotation)
+		} else {
+			require.NotNil(t, era.Rotation)
+			assert.Equal(t, hints[i-1], era.Rotation.OldHint)
+		}
+		if i == len(eras)-1 {
+			assert.Nil(t, era.End, "the current era is open")
+			assert.Nil(t, era.GraceEnds)
+			continue
+		}
+		require.NotNil(t, era.End)
+		assert.True(t, era.End.Equal(base.Add(starts[i+1])), "era %d end", i)
+		assert.True(t, era.GraceEnds.Equal(base.Add(starts[i+1]+24*time.Hour)), "era %d grace end", i)
+	}
+	assert.Equal(t, []int{1, 2}, eras[0].GraceOverlaps)
+	assert.Equal(t, []int{2}, eras[1].GraceOverlaps)
+	assert.Equal(t, []int{3}, eras[2].GraceOverlaps)
+	assert.Empty(t, eras[3].GraceOverlaps)
+
+	tests := []struct {
+		at      time.Duration
+		current int
+		grace   []int
+	}{
+		{0, 0, []int{}},
+		{time.Hour, 1, []int{0}},
+		{3 * time.Hour, 2, []int{1, 0}},
+		{30 * time.Hour, 2, []int{}},
+		{49 * time.Hour, 3, []int{2}},
+		{100 * 24 * time.Hour, 3, []int{}},
+	}
+	for _, tt := range tests {
+		act, err := eng.HashActiveAt(ctx, created.Key.ID, base.Add(tt.at))
+		require.NoError(t, err)
+		assert.Equal(t, tt.current, act.Current.Index, "current at +%s", tt.at)
+		assert.Equal(t, tt.grace, eraIndexes(act.Grace), "grace at +%s", tt.at)
+	}
+
+	_, err = eng.HashActiveAt(ctx, created.Key.ID, base.Add(-time.Second))
+	assert.ErrorIs(t, err, keysmith.ErrNoHashAt)
+}
+
+func TestRotationChain_ZeroGraceOutOfOrder(t *testing.T) {
+	ms := memory.New()
+	eng, err := keysmith.NewEngine(keysmith.WithStore(ms))
+	require.NoError(t, err)
+	ctx := testCtx()
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Lineage", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	base := created.Key.CreatedAt
+
+	record := func(at time.Duration, oldHint, newHint string, grace time.Duration) {
+		require.NoError(t, ms.Rotations().Create(ctx, &rotation.Record{
+			ID:        id.NewRotationID(),
+			KeyID:     created.Key.ID,
+			TenantID:  created.Key.TenantID,
+			AppID:     created.Key.AppID,
+			OldHint:   oldHint,
+			NewHint:   newHint,
+			Reason:    rotation.ReasonCompromise,
+			GraceTTL:  grace,
+			GraceEnds: base.Add(at + grace),
+			CreatedAt: base.Add(at),
+		}))
+	}
+	// Stored newest first.
+	record(3*time.Hour, "bbbb", "cccc", 0)
+	record(time.Hour, "aaaa", "bbbb", time.Hour)
+
+	eras, err := eng.RotationChain(ctx, created.Key.ID)
+	require.NoError(t, err)
+	require.Len(t, eras, 3)
+	assert.Equal(t, []string{"aaaa", "bbbb", "cccc"}, []string{eras[0].Hint, eras[1].Hint, eras[2].Hint})
+
+	assert.True(t, eras[0].GraceEnds.Equal(base.Add(2*time.Hour)))
+	assert.Equal(t, []int{1}, eras[0].GraceOverlaps)
+	assert.True(t, eras[1].GraceEnds.Equal(*eras[1].End), "a zero-grace rotation ends the hash at once")
+	assert.Empty(t, eras[1].GraceOverlaps)
+
+	act, err := eng.HashActiveAt(ctx, created.Key.ID, base.Add(3*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 2, act.Current.Index)
+	assert.Empty(t, act.Grace, "a zero-grace hash is not in grace at the rotation")
+
+	// Another tenant cannot inspect the key.
+	other := keysmith.WithTenant(ctx, "app_test", "tenant_other")
+	_, err = eng.RotationChain(other, created.Key.ID)
+	assert.ErrorIs(t, err, keysmith.ErrKeyNotFound)
+}
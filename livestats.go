@@ -0,0 +1,265 @@
+package keysmith
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"hash/maphash"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+)
+
+// Live stats defaults.
+const (
+	// DefaultLiveStatsKeys is how many keys WithLiveStats tracks when not
+	// told otherwise.
+	DefaultLiveStatsKeys = 1000
+
+	// DefaultLiveTopKeys is the number of keys LiveTopKeys returns when the
+	// caller does not ask for a count.
+	DefaultLiveTopKeys = 20
+
+	// MaxLiveTopKeys caps the number of keys LiveTopKeys returns.
+	MaxLiveTopKeys = 100
+)
+
+const (
+	// liveWindow is the number of per-second counters kept per key, and so
+	// the longest window a rate is reported over.
+	liveWindow = 300
+
+	// liveQPSWindow is the window in seconds QPS is averaged over, long
+	// enough to smooth bursts within a second.
+	liveQPSWindow = 10
+
+	// liveShards is the most shards the tracker is split into.
+	liveShards = 16
+)
+
+// LiveKeyStats is a key's recent validation traffic, as seen by this engine
+// since it started. Rates are validations per second; windows end at the
+// current second.
+type LiveKeyStats struct {
+	KeyID    id.KeyID `json:"key_id"`
+	TenantID string   `json:"tenant_id"`
+	AppID    string   `json:"app_id"`
+
+	// QPS is the rate over the last 10 seconds.
+	QPS float64 `json:"qps"`
+
+	// Rate1m and Rate5m are the rates over the last 1 and 5 minutes.
+	Rate1m float64 `json:"rate_1m"`
+	Rate5m float64 `json:"rate_5m"`
+
+	// FailureRatio is the fraction of the last minute's validations that
+	// failed after the key was identified: inactive, expired, rate-limited,
+	// or refused by a policy check.
+	FailureRatio float64 `json:"failure_ratio"`
+
+	// Validations5m is the number of validations in the last 5 minutes.
+	Validations5m int64 `json:"validations_5m"`
+
+	// TrackedSince is when the tracker started counting the key, and
+	// LastSeen its latest validation. Both are zero for a key not tracked.
+	TrackedSince time.Time `json:"tracked_since"`
+	LastSeen     time.Time `json:"last_seen"`
+}
+
+// LiveKeyStats returns a key's recent validation rates from the in-memory
+// tracker enabled by [WithLiveStats]. The counts are this engine's alone and
+// start over when it restarts. A key the tracker holds no traffic for, such
+// as an idle key or one evicted by busier keys, reports zero rates. Returns
+// ErrLiveStatsDisabled when the tracker is off.
+func (e *Engine) LiveKeyStats(ctx context.Context, keyID id.KeyID) (*LiveKeyStats, error) {
+	if e.liveStats == nil {
+		return nil, ErrLiveStatsDisabled
+	}
+	if st := e.liveStats.stats(keyID, e.now()); st != nil {
+		if !scopeFromContext(ctx).owns(st.TenantID, st.AppID) {
+			return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+		}
+		return st, nil
+	}
+	k, err := e.getKey(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	return &LiveKeyStats{KeyID: k.ID, TenantID: k.TenantID, AppID: k.AppID}, nil
+}
+
+// LiveTopKeys returns up to n of the tracked keys the context may see,
+// busiest first by QPS and then by 1-minute rate. n defaults to
+// DefaultLiveTopKeys and is capped at MaxLiveTopKeys. Returns
+// ErrLiveStatsDisabled when the tracker is off.
+func (e *Engine) LiveTopKeys(ctx context.Context, n int) ([]*LiveKeyStats, error) {
+	if e.liveStats == nil {
+		return nil, ErrLiveStatsDisabled
+	}
+	if n <= 0 {
+		n = DefaultLiveTopKeys
+	}
+	n = min(n, MaxLiveTopKeys)
+
+	all := e.liveStats.all(scopeFromContext(ctx), e.now())
+	sort.Slice(all, func(i, j int) bool {
+		a, b := all[i], all[j]
+		if a.QPS != b.QPS {
+			return a.QPS > b.QPS
+		}
+		if a.Rate1m != b.Rate1m {
+			return a.Rate1m > b.Rate1m
+		}
+		return a.KeyID.String() < b.KeyID.String()
+	})
+	return all[:min(n, len(all))], nil
+}
+
+// liveCounter is one key's validations in per-second slots. Slot i counts
+// the second secs[i], the latest second congruent to i modulo liveWindow.
+type liveCounter struct {
+	keyID    id.KeyID
+	tenantID string
+	appID    string
+	since    int64
+	last     int64
+	secs     [liveWindow]int64
+	total    [liveWindow]uint32
+	failed   [liveWindow]uint32
+}
+
+// liveShard is one shard of the tracker: its counters and their recency,
+// most recently validated first.
+type liveShard struct {
+	mu       sync.Mutex
+	counters map[id.KeyID]*list.Element
+	lru      list.List
+}
+
+// liveTracker counts validations per key for the keys validated most
+// recently, evicting the least recently validated once full. Keys are
+// spread over shards, each with its own lock, so concurrent validations of
+// different keys rarely contend.
+type liveTracker struct {
+	seed     maphash.Seed
+	shards   []liveShard
+	perShard int
+}
+
+func newLiveTracker(maxKeys int) *liveTracker {
+	n := min(liveShards, maxKeys)
+	t := &liveTracker{
+		seed:     maphash.MakeSeed(),
+		shards:   make([]liveShard, n),
+		perShard: (maxKeys + n - 1) / n,
+	}
+	for i := range t.shards {
+		t.shards[i].counters = make(map[id.KeyID]*list.Element)
+	}
+	return t
+}
+
+func (t *liveTracker) shard(keyID id.KeyID) *liveShard {
+	return &t.shards[maphash.Comparable(t.seed, keyID)%uint64(len(t.shards))]
+}
+
+// record counts one validation of k at now.
+func (t *liveTracker) record(k *key.Key, failed bool, now time.Time) {
+	sec := now.Unix()
+	sh := t.shard(k.ID)
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	var c *liveCounter
+	if el, ok := sh.counters[k.ID]; ok {
+		sh.lru.MoveToFront(el)
+		c = el.Value.(*liveCounter)
+	} else {
+		if sh.lru.Len() >= t.perShard {
+			oldest := sh.lru.Back()
+			sh.lru.Remove(oldest)
+			delete(sh.counters, oldest.Value.(*liveCounter).keyID)
+		}
+		c = &liveCounter{keyID: k.ID, tenantID: k.TenantID, appID: k.AppID, since: sec}
+		sh.counters[k.ID] = sh.lru.PushFront(c)
+	}
+
+	i := sec % liveWindow
+	if c.secs[i] != sec {
+		c.secs[i], c.total[i], c.failed[i] = sec, 0, 0
+	}
+	c.total[i]++
+	if failed {
+		c.failed[i]++
+	}
+	c.last = max(c.last, sec)
+}
+
+// stats returns keyID's stats, or nil when it is not tracked.
+func (t *liveTracker) stats(keyID id.KeyID, now time.Time) *LiveKeyStats {
+	sh := t.shard(keyID)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	el, ok := sh.counters[keyID]
+	if !ok {
+		return nil
+	}
+	return el.Value.(*liveCounter).stats(now.Unix())
+}
+
+// all returns the stats of every tracked key sc may see.
+func (t *liveTracker) all(sc tenantScope, now time.Time) []*LiveKeyStats {
+	sec := now.Unix()
+	var out []*LiveKeyStats
+	for i := range t.shards {
+		sh := &t.shards[i]
+		sh.mu.Lock()
+		for _, el := range sh.counters {
+			c := el.Value.(*liveCounter)
+			if sc.owns(c.tenantID, c.appID) {
+				out = append(out, c.stats(sec))
+			}
+		}
+		sh.mu.Unlock()
+	}
+	return out
+}
+
+// stats sums the slots within each window ending at now.
+func (c *liveCounter) stats(now int64) *LiveKeyStats {
+	var qps, total1m, failed1m, total5m int64
+	for i := range c.secs {
+		age := now - c.secs[i]
+		if c.total[i] == 0 || age < 0 || age >= liveWindow {
+			continue
+		}
+		n := int64(c.total[i])
+		total5m += n
+		if age < 60 {
+			total1m += n
+			failed1m += int64(c.failed[i])
+		}
+		if age < liveQPSWindow {
+			qps += n
+		}
+	}
+	st := &LiveKeyStats{
+		KeyID:         c.keyID,
+		TenantID:      c.tenantID,
+		AppID:         c.appID,
+		QPS:           float64(qps) / liveQPSWindow,
+		Rate1m:        float64(total1m) / 60,
+		Rate5m:        float64(total5m) / liveWindow,
+		Validations5m: total5m,
+		TrackedSince:  time.Unix(c.since, 0).UTC(),
+		LastSeen:      time.Unix(c.last, 0).UTC(),
+	}
+	if total1m > 0 {
+		st.FailureRatio = float64(failed1m) / float64(total1m)
+	}
+	return st
+}
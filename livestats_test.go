@@ -0,0 +1,178 @@
+package keysmith_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+func newLiveStatsEngine(t *testing.T, maxKeys int) (*keysmith.Engine, *fakeClock) {
+	t.Helper()
+	clock := &fakeClock{t: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)}
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithClock(clock.Now),
+		keysmith.WithLiveStats(maxKeys),
+	)
+	require.NoError(t, err)
+	return eng, clock
+}
+
+func createLiveKey(t *testing.T, eng *keysmith.Engine, ctx context.Context, name string) *key.CreateResult {
+	t.Helper()
+	result, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: name, Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	return result
+}
+
+// sendValidations validates raw perSec times in each of the next secs seconds,
+// spread over each second, leaving the clock at the last validation.
+func sendValidations(t *testing.T, eng *keysmith.Engine, clock *fakeClock, raw string, secs, perSec int) {
+	t.Helper()
+	start := clock.Now().Truncate(time.Second)
+	for s := range secs {
+		for i := range perSec {
+			clock.Set(start.Add(time.Duration(s)*time.Second + time.Duration(i)*time.Second/time.Duration(perSec)))
+			_, err := eng.ValidateKey(testCtx(), raw)
+			require.NoError(t, err)
+		}
+	}
+}
+
+func TestLiveKeyStats_Rates(t *testing.T) {
+	eng, clock := newLiveStatsEngine(t, 0)
+	k := createLiveKey(t, eng, testCtx(), "busy")
+
+	// Four minutes at 2/s, then a minute at 5/s.
+	sendValidations(t, eng, clock, k.RawKey, 240, 2)
+	clock.Set(clock.Now().Truncate(time.Second).Add(time.Second))
+	sendValidations(t, eng, clock, k.RawKey, 60, 5)
+
+	st, err := eng.LiveKeyStats(testCtx(), k.Key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, k.Key.ID, st.KeyID)
+	assert.Equal(t, "tenant_test", st.TenantID)
+	assert.InDelta(t, 5.0, st.QPS, 0.1)
+	assert.InDelta(t, 5.0, st.Rate1m, 0.1)
+	assert.InDelta(t, 2.6, st.Rate5m, 0.1, "(240*2 + 60*5) / 300")
+	assert.Equal(t, int64(780), st.Validations5m)
+	assert.Zero(t, st.FailureRatio)
+	assert.Equal(t, clock.Now().Truncate(time.Second), st.LastSeen)
+
+	// Idle for a minute: the 10s and 1m windows empty, the 5m one does not.
+	clock.Set(clock.Now().Add(time.Minute))
+	st, err = eng.LiveKeyStats(testCtx(), k.Key.ID)
+	require.NoError(t, err)
+	assert.Zero(t, st.QPS)
+	assert.Zero(t, st.Rate1m)
+	assert.InDelta(t, 2.2, st.Rate5m, 0.1, "(180*2 + 60*5) / 300")
+
+	clock.Set(clock.Now().Add(5 * time.Minute))
+	st, err = eng.LiveKeyStats(testCtx(), k.Key.ID)
+	require.NoError(t, err)
+	assert.Zero(t, st.Validations5m)
+	assert.False(t, st.TrackedSince.IsZero(), "an idle key stays tracked until evicted")
+}
+
+func TestLiveKeyStats_FailureRatio(t *testing.T) {
+	eng, clock := newLiveStatsEngine(t, 0)
+	k := createLiveKey(t, eng, testCtx(), "flaky")
+
+	sendValidations(t, eng, clock, k.RawKey, 3, 1)
+	require.NoError(t, eng.SuspendKey(testCtx(), k.Key.ID))
+	_, err := eng.ValidateKey(testCtx(), k.RawKey)
+	require.Error(t, err)
+
+	st, err := eng.LiveKeyStats(testCtx(), k.Key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), st.Validations5m)
+	assert.InDelta(t, 0.25, st.FailureRatio, 1e-9)
+
+	_, err = eng.ValidateKey(testCtx(), "sk_test_unknown")
+	require.Error(t, err)
+	st, err = eng.LiveKeyStats(testCtx(), k.Key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), st.Validations5m, "unknown keys are not counted")
+}
+
+func TestLiveKeyStats_Untracked(t *testing.T) {
+	eng, _ := newLiveStatsEngine(t, 1)
+	a := createLiveKey(t, eng, testCtx(), "a")
+	b := createLiveKey(t, eng, testCtx(), "b")
+
+	_, err := eng.ValidateKey(testCtx(), a.RawKey)
+	require.NoError(t, err)
+	_, err = eng.ValidateKey(testCtx(), b.RawKey)
+	require.NoError(t, err)
+
+	st, err := eng.LiveKeyStats(testCtx(), a.Key.ID)
+	require.NoError(t, err)
+	assert.Zero(t, st.Validations5m, "a was evicted by b")
+	assert.True(t, st.TrackedSince.IsZero())
+	assert.Equal(t, a.Key.ID, st.KeyID)
+
+	st, err = eng.LiveKeyStats(testCtx(), b.Key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), st.Validations5m)
+
+	_, err = eng.LiveKeyStats(testCtx(), id.NewKeyID())
+	assert.Error(t, err, "an unknown key is not found")
+}
+
+func TestLiveKeyStats_TenantScoped(t *testing.T) {
+	eng, clock := newLiveStatsEngine(t, 0)
+	other := keysmith.WithTenant(context.Background(), "app_test", "tenant_other")
+	mine := createLiveKey(t, eng, testCtx(), "mine")
+	theirs := createLiveKey(t, eng, other, "theirs")
+
+	sendValidations(t, eng, clock, mine.RawKey, 1, 3)
+	_, err := eng.ValidateKey(other, theirs.RawKey)
+	require.NoError(t, err)
+
+	_, err = eng.LiveKeyStats(other, mine.Key.ID)
+	assert.ErrorIs(t, err, keysmith.ErrKeyNotFound)
+
+	top, err := eng.LiveTopKeys(other, 0)
+	require.NoError(t, err)
+	require.Len(t, top, 1)
+	assert.Equal(t, theirs.Key.ID, top[0].KeyID)
+
+	all, err := eng.LiveTopKeys(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Len(t, all, 2, "the system scope sees every tenant")
+}
+
+func TestLiveTopKeys_Order(t *testing.T) {
+	eng, clock := newLiveStatsEngine(t, 0)
+	slow := createLiveKey(t, eng, testCtx(), "slow")
+	fast := createLiveKey(t, eng, testCtx(), "fast")
+	mid := createLiveKey(t, eng, testCtx(), "mid")
+
+	sendValidations(t, eng, clock, slow.RawKey, 1, 1)
+	sendValidations(t, eng, clock, fast.RawKey, 1, 9)
+	sendValidations(t, eng, clock, mid.RawKey, 1, 4)
+
+	top, err := eng.LiveTopKeys(testCtx(), 2)
+	require.NoError(t, err)
+	require.Len(t, top, 2)
+	assert.Equal(t, fast.Key.ID, top[0].KeyID)
+	assert.Equal(t, mid.Key.ID, top[1].KeyID)
+	assert.InDelta(t, 0.9, top[0].QPS, 1e-9)
+}
+
+func TestLiveKeyStats_Disabled(t *testing.T) {
+	eng := newTestEngine(t)
+	k := createLiveKey(t, eng, testCtx(), "k")
+	_, err := eng.LiveKeyStats(testCtx(), k.Key.ID)
+	assert.ErrorIs(t, err, keysmith.ErrLiveStatsDisabled)
+	_, err = eng.LiveTopKeys(testCtx(), 0)
+	assert.ErrorIs(t, err, keysmith.ErrLiveStatsDisabled)
+}
@@ -0,0 +1,539 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	log "github.com/xraph/go-utils/log"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keyevent"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/tenant"
+)
+
+const (
+	// DefaultRecoveryBatchSize is how many keys each batch of a staged
+	// recovery reactivates when RecoveryOptions.BatchSize is zero.
+	DefaultRecoveryBatchSize = 50
+
+	// DefaultRecoveryBatchInterval is the wait between the batches of a
+	// staged recovery when RecoveryOptions.BatchInterval is zero.
+	DefaultRecoveryBatchInterval = 5 * time.Minute
+
+	// DefaultRecoveryCheckInterval is how often, between Start and Stop,
+	// the engine runs the due batches of the recoveries it is advancing.
+	DefaultRecoveryCheckInterval = 30 * time.Second
+)
+
+// RecoveryOptions shapes a staged recovery started by RecoverTenant.
+type RecoveryOptions struct {
+	// BatchSize is how many keys each batch reactivates. Zero is
+	// DefaultRecoveryBatchSize.
+	BatchSize int
+
+	// BatchInterval is the wait between batches. Zero is
+	// DefaultRecoveryBatchInterval.
+	BatchInterval time.Duration
+}
+
+// AnomalyDetector tells a staged recovery whether trouble is back in the
+// tenant. Set it with [WithAnomalyDetector].
+type AnomalyDetector interface {
+	// TenantAnomaly describes trouble seen in the tenant since the given
+	// time, or returns "" when there was none. An error postpones the
+	// batch to the next check.
+	TenantAnomaly(ctx context.Context, tenantID string, since time.Time) (string, error)
+}
+
+// LockdownResult reports a LockdownTenant call.
+type LockdownResult struct {
+	Lockdown *tenant.Lockdown `json:"lockdown"`
+
+	// KeyIDs are the keys suspended, or that would be under WithDryRun.
+	// Failed counts keys whose suspension failed.
+	KeyIDs []id.KeyID `json:"key_ids"`
+	Failed int        `json:"failed"`
+	DryRun bool       `json:"dry_run"`
+}
+
+// LockdownStatus is a tenant's lockdown state.
+type LockdownStatus struct {
+	TenantID string `json:"tenant_id"`
+	Locked   bool   `json:"locked"`
+
+	// Lockdown is the tenant's lockdown. It is nil for a tenant that is not
+	// locked down, except in the result of the RecoverTenant call whose
+	// batch completed the recovery, where its phase is recovered.
+	Lockdown *tenant.Lockdown `json:"lockdown,omitempty"`
+
+	// Pending is how many of the keys the lockdown suspended are still
+	// suspended and waiting for the recovery.
+	Pending int `json:"pending"`
+}
+
+// LockdownTenant locks a tenant down for an incident in one call. It marks
+// the tenant locked, so that CreateKey refuses to create keys in it with
+// ErrTenantLocked, then suspends every active key of the tenant, in every
+// app, with plugin.ReasonTenantLockdown as the reason code. RecoverTenant
+// later reactivates exactly those keys.
+//
+// Locking down a locked tenant suspends the keys active again since, for
+// example, after a recovery is halted; it keeps the original LockedAt and
+// stops the recovery. It fires TenantLockdownChanged in the locked phase.
+// With [WithDryRun] it only lists the keys it would suspend. tenantID may
+// be empty for a tenant-scoped context; a lockdown needs a tenant- or
+// system-scoped caller and a reason.
+func (e *Engine) LockdownTenant(ctx context.Context, tenantID, reason string) (*LockdownResult, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	tenantID, err := lockdownTenantID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if reason == "" {
+		return nil, fmt.Errorf("%w: a lockdown needs a reason", ErrInvalidLockdown)
+	}
+
+	e.lockdownMu.Lock()
+	defer e.lockdownMu.Unlock()
+	ts, err := e.storedSettings(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	l := ts.Lockdown.Clone()
+	if l == nil {
+		l = &tenant.Lockdown{LockedAt: e.now()}
+	}
+	l.Phase, l.Reason, l.ActorID, l.Recovery = tenant.LockdownLocked, reason, ActorFromContext(ctx), nil
+	res := &LockdownResult{Lockdown: l, DryRun: IsDryRun(ctx)}
+
+	// The marker is saved first, so no key is created while the tenant's
+	// keys are being suspended.
+	if !res.DryRun {
+		e.recoveries.remove(tenantID)
+		if err := e.saveLockdown(ctx, ts, l); err != nil {
+			return nil, err
+		}
+	}
+
+	meta := e.eventMeta(ctx, plugin.TriggerManual, plugin.ReasonTenantLockdown)
+	filter := &key.ListFilter{TenantID: tenantID, State: key.StateActive}
+	err = e.store.Keys().Iterate(ctx, filter, func(k *key.Key) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if res.DryRun {
+			res.KeyIDs = append(res.KeyIDs, k.ID)
+			return nil
+		}
+		ok, err := e.store.Keys().UpdateStateIf(ctx, k.ID, key.StateActive, key.StateSuspended)
+		if err != nil {
+			e.logger.Warn("failed to suspend key for tenant lockdown", log.String("key_id", k.ID.String()), log.Any("error", err))
+			res.Failed++
+			return nil
+		}
+		if !ok {
+			// Changed concurrently, e.g., revoked.
+			return nil
+		}
+		k.State = key.StateSuspended
+		res.KeyIDs = append(res.KeyIDs, k.ID)
+		e.invalidateKey(k.ID)
+		e.recordRevocation(ctx, k, key.StateSuspended, true)
+		_ = e.hooks.FireKeySuspended(ctx, k, meta)
+		return nil
+	})
+	if err != nil {
+		return res, fmt.Errorf("iterate %s keys: %w", tenantID, err)
+	}
+	if res.DryRun {
+		return res, nil
+	}
+
+	l.Suspended += len(res.KeyIDs)
+	if err := e.saveLockdown(ctx, ts, l); err != nil {
+		return res, err
+	}
+	_ = e.hooks.FireTenantLockdownChanged(ctx, tenantID, l.Clone(), meta)
+	return res, nil
+}
+
+// LockdownStatus returns a tenant's lockdown and how many of its keys are
+// waiting for the recovery. tenantID may be empty for a tenant-scoped
+// context.
+func (e *Engine) LockdownStatus(ctx context.Context, tenantID string) (*LockdownStatus, error) {
+	tenantID, err := lockdownTenantID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	ts, err := e.storedSettings(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return e.lockdownStatus(ctx, tenantID, ts.Lockdown)
+}
+
+// RecoverTenant starts the staged recovery of a locked-down tenant, or
+// resumes one that was halted. The keys the lockdown suspended, as recorded
+// in the key event history, are reactivated opts.BatchSize at a time, one
+// batch every opts.BatchInterval. A key reactivated, revoked, or suspended
+// again by other means since the lockdown is left alone. The first batch
+// runs now and the rest from the engine's recovery job between Start and
+// Stop; calling RecoverTenant again runs a batch that is due.
+//
+// Before each batch the anomaly detector (see [WithAnomalyDetector]) is
+// asked about the tenant since the recovery started or was resumed. If it
+// reports trouble the recovery halts, the tenant stays locked, and the keys
+// already reactivated stay active: LockdownTenant suspends them again, and
+// RecoverTenant resumes. Once every key is back the lockdown is cleared
+// and CreateKey works again.
+//
+// Every batch, halt, and completion fires TenantLockdownChanged, and every
+// reactivation KeyReactivated with plugin.ReasonTenantRecovery. Options
+// given for a recovery in progress replace its own. With [WithDryRun] it
+// only reports the status. The recovery's history has to be readable: a
+// lockdown older than the key event lookback fails with
+// ErrKeyEventRangeTooLarge.
+func (e *Engine) RecoverTenant(ctx context.Context, tenantID string, opts RecoveryOptions) (*LockdownStatus, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	tenantID, err := lockdownTenantID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if opts.BatchSize < 0 || opts.BatchInterval < 0 {
+		return nil, fmt.Errorf("%w: batch_size and batch_interval must not be negative", ErrInvalidLockdown)
+	}
+
+	e.lockdownMu.Lock()
+	defer e.lockdownMu.Unlock()
+	ts, err := e.storedSettings(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if ts.Lockdown == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTenantNotLocked, tenantID)
+	}
+	if IsDryRun(ctx) {
+		return e.lockdownStatus(ctx, tenantID, ts.Lockdown)
+	}
+
+	l := ts.Lockdown.Clone()
+	now := e.now()
+	r := l.Recovery
+	if r == nil {
+		r = &tenant.Recovery{StartedAt: now, BatchSize: DefaultRecoveryBatchSize, BatchInterval: DefaultRecoveryBatchInterval}
+		l.Recovery = r
+	}
+	if opts.BatchSize > 0 {
+		r.BatchSize = opts.BatchSize
+	}
+	if opts.BatchInterval > 0 {
+		r.BatchInterval = opts.BatchInterval
+	}
+	if l.Phase != tenant.LockdownRecovering {
+		l.Phase = tenant.LockdownRecovering
+		r.WatchSince, r.NextBatchAt = now, now
+		r.HaltedAt, r.HaltReason = nil, ""
+	}
+	if err := e.saveLockdown(ctx, ts, l); err != nil {
+		return nil, err
+	}
+	e.recoveries.add(tenantID)
+	return e.advanceRecovery(ctx, tenantID, plugin.TriggerManual)
+}
+
+// runRecoveries is the body of the recovery job.
+func (e *Engine) runRecoveries(ctx context.Context) {
+	if e.ReadOnly() {
+		return
+	}
+	for _, tenantID := range e.recoveries.list() {
+		e.lockdownMu.Lock()
+		_, err := e.advanceRecovery(ctx, tenantID, plugin.TriggerSweep)
+		e.lockdownMu.Unlock()
+		if err != nil {
+			e.logger.Warn("tenant recovery batch failed", log.String("tenant_id", tenantID), log.Any("error", err))
+		}
+	}
+}
+
+// advanceRecovery runs the tenant's next recovery batch if it is due. The
+// caller holds lockdownMu.
+func (e *Engine) advanceRecovery(ctx context.Context, tenantID string, trigger plugin.Trigger) (*LockdownStatus, error) {
+	ts, err := e.storedSettings(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	l := ts.Lockdown.Clone()
+	if l == nil || l.Phase != tenant.LockdownRecovering || l.Recovery == nil {
+		// Locked down again, or recovered by another engine.
+		e.recoveries.remove(tenantID)
+		return e.lockdownStatus(ctx, tenantID, l)
+	}
+	r := l.Recovery
+	now := e.now()
+	if now.Before(r.NextBatchAt) {
+		return e.lockdownStatus(ctx, tenantID, l)
+	}
+
+	trouble, err := e.anomalies.TenantAnomaly(ctx, tenantID, r.WatchSince)
+	if err != nil {
+		return nil, fmt.Errorf("check tenant for anomalies: %w", err)
+	}
+	if trouble != "" {
+		l.Phase = tenant.LockdownHalted
+		r.HaltedAt, r.HaltReason = &now, trouble
+		if err := e.saveLockdown(ctx, ts, l); err != nil {
+			return nil, err
+		}
+		e.recoveries.remove(tenantID)
+		e.logger.Warn("tenant recovery halted", log.String("tenant_id", tenantID), log.String("reason", trouble))
+		_ = e.hooks.FireTenantLockdownChanged(ctx, tenantID, l.Clone(), e.eventMeta(ctx, trigger, plugin.ReasonAnomalyDetected))
+		return e.lockdownStatus(ctx, tenantID, l)
+	}
+
+	pending, err := e.lockdownKeys(ctx, tenantID, l)
+	if err != nil {
+		return nil, err
+	}
+	meta := e.eventMeta(ctx, trigger, plugin.ReasonTenantRecovery)
+	reactivated, gone := 0, 0
+	for _, k := range pending[:min(len(pending), r.BatchSize)] {
+		ok, err := e.store.Keys().UpdateStateIf(ctx, k.ID, key.StateSuspended, key.StateActive)
+		if err != nil {
+			e.logger.Warn("failed to reactivate key for tenant recovery", log.String("key_id", k.ID.String()), log.Any("error", err))
+			continue
+		}
+		if !ok {
+			// Changed concurrently, e.g., revoked: no longer waiting.
+			gone++
+			continue
+		}
+		k.State = key.StateActive
+		reactivated++
+		e.invalidateKey(k.ID)
+		e.recordRevocation(ctx, k, key.StateActive, false)
+		_ = e.hooks.FireKeyReactivated(ctx, k, meta)
+	}
+
+	r.Batches++
+	r.Reactivated += reactivated
+	r.Remaining = len(pending) - reactivated - gone
+	r.NextBatchAt = now.Add(r.BatchInterval)
+	if r.Remaining == 0 {
+		l.Phase = tenant.LockdownRecovered
+		r.CompletedAt = &now
+		if err := e.saveLockdown(ctx, ts, nil); err != nil {
+			return nil, err
+		}
+		e.recoveries.remove(tenantID)
+	} else if err := e.saveLockdown(ctx, ts, l); err != nil {
+		return nil, err
+	}
+	_ = e.hooks.FireTenantLockdownChanged(ctx, tenantID, l.Clone(), meta)
+	return &LockdownStatus{TenantID: tenantID, Locked: r.Remaining > 0, Lockdown: l, Pending: r.Remaining}, nil
+}
+
+// lockdownKeys returns the keys the tenant's lockdown suspended that are
+// still suspended, in the order they were suspended. A key counts when its
+// latest change since the lockdown began is a suspension with
+// plugin.ReasonTenantLockdown.
+func (e *Engine) lockdownKeys(ctx context.Context, tenantID string, l *tenant.Lockdown) ([]*key.Key, error) {
+	if oldest := e.now().Add(-e.keyEventLookback); l.LockedAt.Before(oldest) {
+		return nil, fmt.Errorf("%w: the lockdown of %s began %s, before the key event lookback of %s",
+			ErrKeyEventRangeTooLarge, tenantID, l.LockedAt.UTC().Format(time.RFC3339), e.keyEventLookback)
+	}
+	latest := make(map[id.KeyID]*keyevent.Event)
+	var order []id.KeyID
+	f := &keyevent.ListFilter{
+		TenantID: tenantID,
+		Types:    []keyevent.Type{keyevent.TypeStateChanged, keyevent.TypeRotated, keyevent.TypeRevoked, keyevent.TypeExpired},
+		// Event times are kept to the microsecond, so the first suspension
+		// can sort at or just before LockedAt; start a microsecond early.
+		After: keyevent.Cursor{At: l.LockedAt.Truncate(time.Microsecond).Add(-time.Microsecond)},
+		Limit: MaxKeyEventPageSize,
+	}
+	for {
+		events, err := e.store.KeyEvents().ListAfter(ctx, f)
+		if err != nil {
+			return nil, fmt.Errorf("list key events: %w", err)
+		}
+		for _, ev := range events {
+			if _, seen := latest[ev.KeyID]; !seen {
+				order = append(order, ev.KeyID)
+			}
+			latest[ev.KeyID] = ev
+		}
+		if len(events) < f.Limit {
+			break
+		}
+		f.After = keyevent.CursorOf(events[len(events)-1])
+	}
+
+	var keys []*key.Key
+	for _, keyID := range order {
+		ev := latest[keyID]
+		if ev.Type != keyevent.TypeStateChanged || ev.State != key.StateSuspended || ev.ReasonCode != string(plugin.ReasonTenantLockdown) {
+			continue
+		}
+		k, err := e.store.Keys().Get(ctx, keyID)
+		if err != nil {
+			return nil, fmt.Errorf("get key %s: %w", keyID, err)
+		}
+		if k.State == key.StateSuspended && k.TenantID == tenantID {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+// lockdownStatus returns the status of the tenant under l.
+func (e *Engine) lockdownStatus(ctx context.Context, tenantID string, l *tenant.Lockdown) (*LockdownStatus, error) {
+	st := &LockdownStatus{TenantID: tenantID, Locked: l != nil, Lockdown: l.Clone()}
+	if l == nil {
+		return st, nil
+	}
+	pending, err := e.lockdownKeys(ctx, tenantID, l)
+	if err != nil {
+		return nil, err
+	}
+	st.Pending = len(pending)
+	return st, nil
+}
+
+// storedSettings reads the tenant's settings from the store rather than
+// the cache, so that lockdown changes start from what another engine may
+// have written. Stores do not tell a missing tenant from a failed read, so
+// on an error it falls back to the cached settings, which are empty for a
+// tenant without any.
+func (e *Engine) storedSettings(ctx context.Context, tenantID string) (*tenant.Settings, error) {
+	ts, err := e.store.Tenants().GetSettings(ctx, tenantID)
+	if err != nil {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return cloneSettings(e.tenantSettings(ctx, tenantID)), nil
+	}
+	return ts, nil
+}
+
+// saveLockdown stores ts with its lockdown replaced by l.
+func (e *Engine) saveLockdown(ctx context.Context, ts *tenant.Settings, l *tenant.Lockdown) error {
+	now := time.Now()
+	if ts.CreatedAt.IsZero() {
+		ts.CreatedAt = now
+	}
+	ts.UpdatedAt = now
+	ts.Lockdown = l.Clone()
+	if err := e.store.Tenants().UpsertSettings(ctx, ts); err != nil {
+		return fmt.Errorf("save tenant lockdown: %w", err)
+	}
+	e.settings.invalidate(ts.TenantID)
+	e.bumpRevision(ctx, ts.TenantID)
+	return nil
+}
+
+// lockdownTenantID returns the tenant a lockdown call applies to. A
+// lockdown covers every app of its tenant, so app-scoped callers are
+// refused.
+func lockdownTenantID(ctx context.Context, tenantID string) (string, error) {
+	sc := scopeFromContext(ctx)
+	switch {
+	case sc.appID != "":
+		return "", fmt.Errorf("%w: a lockdown covers every app of a tenant and needs a tenant- or system-scoped caller", ErrInvalidLockdown)
+	case sc.tenantID != "" && tenantID != "" && tenantID != sc.tenantID:
+		return "", fmt.Errorf("%w: tenant %s is outside the caller's scope", ErrInvalidLockdown, tenantID)
+	case tenantID == "":
+		tenantID = sc.tenantID
+	}
+	if tenantID == "" {
+		return "", fmt.Errorf("%w: no tenant given", ErrInvalidLockdown)
+	}
+	return tenantID, nil
+}
+
+// recoverySet is the set of tenants whose recovery an engine advances. It
+// is kept in memory: after a restart, RecoverTenant picks a recovery up
+// again.
+type recoverySet struct {
+	mu      sync.Mutex
+	tenants map[string]bool
+}
+
+func newRecoverySet() *recoverySet {
+	return &recoverySet{tenants: make(map[string]bool)}
+}
+
+func (s *recoverySet) add(tenantID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tenants[tenantID] = true
+}
+
+func (s *recoverySet) remove(tenantID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tenants, tenantID)
+}
+
+// list returns the tenants in the set, sorted.
+func (s *recoverySet) list() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]string, 0, len(s.tenants))
+	for tenantID := range s.tenants {
+		out = append(out, tenantID)
+	}
+	slices.Sort(out)
+	return out
+}
+
+// troubleCodes are the reason codes the default anomaly detector treats as
+// renewed trouble: a key revoked as compromised or leaked, or rotated for
+// a compromise or an anomaly.
+var troubleCodes = []string{
+	string(plugin.ReasonCompromised),
+	string(plugin.ReasonLeakedHash),
+	string(rotation.ReasonCompromise),
+	string(rotation.ReasonAnomaly),
+}
+
+// historyAnomalyDetector is the default AnomalyDetector. It reports the
+// first key event in the tenant since the given time with one of
+// troubleCodes.
+type historyAnomalyDetector struct {
+	e *Engine
+}
+
+func (d historyAnomalyDetector) TenantAnomaly(ctx context.Context, tenantID string, since time.Time) (string, error) {
+	f := &keyevent.ListFilter{
+		TenantID: tenantID,
+		Types:    []keyevent.Type{keyevent.TypeStateChanged, keyevent.TypeRotated, keyevent.TypeRevoked},
+		After:    keyevent.Cursor{At: since},
+		Limit:    MaxKeyEventPageSize,
+	}
+	for {
+		events, err := d.e.store.KeyEvents().ListAfter(ctx, f)
+		if err != nil {
+			return "", fmt.Errorf("list key events: %w", err)
+		}
+		for _, ev := range events {
+			if slices.Contains(troubleCodes, ev.ReasonCode) {
+				return fmt.Sprintf("%s for key %s with reason code %s", ev.Type, ev.KeyID, ev.ReasonCode), nil
+			}
+		}
+		if len(events) < f.Limit {
+			return "", nil
+		}
+		f.After = keyevent.CursorOf(events[len(events)-1])
+	}
+}
@@ -0,0 +1,258 @@
+package keysmith_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keysmithtest"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/store/memory"
+	"github.com/xraph/keysmith/tenant"
+)
+
+// lockdownEngine returns an engine on a fake clock with a hook recorder
+// and the given number of active keys in the test tenant.
+func lockdownEngine(t *testing.T, keys int, opts ...keysmith.Option) (*keysmith.Engine, *fakeClock, *keysmithtest.Recorder, []*key.CreateResult) {
+	t.Helper()
+	clock := &fakeClock{t: time.Now()}
+	rec := keysmithtest.NewRecorder()
+	opts = append([]keysmith.Option{keysmith.WithStore(memory.New()), keysmith.WithClock(clock.Now), keysmith.WithExtension(rec)}, opts...)
+	eng, err := keysmith.NewEngine(opts...)
+	require.NoError(t, err)
+	created := make([]*key.CreateResult, keys)
+	for i := range created {
+		created[i] = keysmithtest.NewKey(eng).MustCreate(t, testCtx())
+	}
+	clock.Set(clock.Now().Add(time.Minute))
+	return eng, clock, rec, created
+}
+
+func keyState(t *testing.T, eng *keysmith.Engine, keyID id.KeyID) key.State {
+	t.Helper()
+	k, err := eng.GetKey(testCtx(), keyID)
+	require.NoError(t, err)
+	return k.State
+}
+
+func lockdownPhases(rec *keysmithtest.Recorder) []tenant.LockdownPhase {
+	var phases []tenant.LockdownPhase
+	for _, ev := range rec.Filter("TenantLockdownChanged") {
+		phases = append(phases, ev.Lockdown.Phase)
+	}
+	return phases
+}
+
+func TestLockdown_StagedRecovery(t *testing.T) {
+	eng, clock, rec, created := lockdownEngine(t, 6)
+	ctx := context.Background()
+	require.NoError(t, eng.SuspendKey(testCtx(), created[5].Key.ID))
+
+	res, err := eng.LockdownTenant(keysmith.WithActor(ctx, "oncall"), "tenant_test", "credential dump")
+	require.NoError(t, err)
+	assert.Len(t, res.KeyIDs, 5, "the key already suspended is not the lockdown's")
+	assert.Equal(t, 5, res.Lockdown.Suspended)
+	assert.Equal(t, "oncall", res.Lockdown.ActorID)
+	keysmithtest.AssertRejectedWith(t, eng, testCtx(), created[0].RawKey, keysmith.ErrKeyInactive)
+
+	_, err = eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "New", Prefix: "sk", Environment: key.EnvTest})
+	assert.ErrorIs(t, err, keysmith.ErrTenantLocked)
+	other := keysmith.WithTenant(ctx, "app_test", "tenant_other")
+	_, err = eng.CreateKey(other, &keysmith.CreateKeyInput{Name: "New", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err, "other tenants are not locked")
+
+	// Keys handled by hand during the incident are left alone.
+	clock.Set(clock.Now().Add(time.Minute))
+	require.NoError(t, eng.RevokeKey(testCtx(), created[3].Key.ID, "rotated out"))
+	require.NoError(t, eng.ReactivateKey(testCtx(), created[4].Key.ID))
+	require.NoError(t, eng.SuspendKey(testCtx(), created[4].Key.ID))
+
+	status, err := eng.LockdownStatus(ctx, "tenant_test")
+	require.NoError(t, err)
+	assert.True(t, status.Locked)
+	assert.Equal(t, 3, status.Pending)
+
+	clock.Set(clock.Now().Add(time.Minute))
+	opts := keysmith.RecoveryOptions{BatchSize: 2, BatchInterval: 10 * time.Minute}
+	status, err = eng.RecoverTenant(ctx, "tenant_test", opts)
+	require.NoError(t, err)
+	assert.Equal(t, tenant.LockdownRecovering, status.Lockdown.Phase)
+	assert.Equal(t, 1, status.Pending)
+	assert.Equal(t, key.StateActive, keyState(t, eng, created[0].Key.ID))
+	assert.Equal(t, key.StateActive, keyState(t, eng, created[1].Key.ID))
+	assert.Equal(t, key.StateSuspended, keyState(t, eng, created[2].Key.ID))
+
+	clock.Set(clock.Now().Add(5 * time.Minute))
+	status, err = eng.RecoverTenant(ctx, "tenant_test", keysmith.RecoveryOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, status.Lockdown.Recovery.Batches, "the next batch is not due yet")
+	_, err = eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "New", Prefix: "sk", Environment: key.EnvTest})
+	assert.ErrorIs(t, err, keysmith.ErrTenantLocked, "creation stays blocked during the recovery")
+
+	clock.Set(clock.Now().Add(5 * time.Minute))
+	status, err = eng.RecoverTenant(ctx, "tenant_test", keysmith.RecoveryOptions{})
+	require.NoError(t, err)
+	assert.False(t, status.Locked)
+	assert.Equal(t, tenant.LockdownRecovered, status.Lockdown.Phase)
+	assert.Equal(t, 3, status.Lockdown.Recovery.Reactivated)
+
+	assert.Equal(t, key.StateActive, keyState(t, eng, created[2].Key.ID))
+	assert.Equal(t, key.StateRevoked, keyState(t, eng, created[3].Key.ID))
+	assert.Equal(t, key.StateSuspended, keyState(t, eng, created[4].Key.ID), "suspended again by hand")
+	assert.Equal(t, key.StateSuspended, keyState(t, eng, created[5].Key.ID), "suspended before the lockdown")
+	keysmithtest.NewKey(eng).MustCreate(t, testCtx())
+
+	status, err = eng.LockdownStatus(ctx, "tenant_test")
+	require.NoError(t, err)
+	assert.False(t, status.Locked)
+	assert.Nil(t, status.Lockdown)
+
+	assert.Equal(t, []tenant.LockdownPhase{tenant.LockdownLocked, tenant.LockdownRecovering, tenant.LockdownRecovered}, lockdownPhases(rec))
+	for _, ev := range rec.Filter("KeyReactivated") {
+		if ev.Key.ID != created[4].Key.ID {
+			assert.Equal(t, plugin.ReasonTenantRecovery, ev.Meta.ReasonCode)
+		}
+	}
+}
+
+func TestLockdown_HaltsOnRenewedTrouble(t *testing.T) {
+	eng, clock, rec, created := lockdownEngine(t, 3)
+	ctx := context.Background()
+	_, err := eng.LockdownTenant(ctx, "tenant_test", "credential dump")
+	require.NoError(t, err)
+
+	clock.Set(clock.Now().Add(time.Minute))
+	opts := keysmith.RecoveryOptions{BatchSize: 1, BatchInterval: time.Minute}
+	_, err = eng.RecoverTenant(ctx, "tenant_test", opts)
+	require.NoError(t, err)
+	for _, c := range created {
+		if keyState(t, eng, c.Key.ID) == key.StateActive {
+			compromise(t, eng, c.Key.ID, key.CompromiseRevoke)
+		}
+	}
+
+	clock.Set(clock.Now().Add(time.Minute))
+	status, err := eng.RecoverTenant(ctx, "tenant_test", keysmith.RecoveryOptions{})
+	require.NoError(t, err)
+	assert.True(t, status.Locked)
+	assert.Equal(t, tenant.LockdownHalted, status.Lockdown.Phase)
+	assert.Contains(t, status.Lockdown.Recovery.HaltReason, "compromised")
+	assert.Equal(t, 2, status.Pending, "no key was reactivated after the trouble")
+	_, err = eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "New", Prefix: "sk", Environment: key.EnvTest})
+	assert.ErrorIs(t, err, keysmith.ErrTenantLocked)
+
+	// Resuming watches for trouble from then on.
+	clock.Set(clock.Now().Add(time.Minute))
+	status, err = eng.RecoverTenant(ctx, "tenant_test", keysmith.RecoveryOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, tenant.LockdownRecovering, status.Lockdown.Phase)
+	assert.Equal(t, 1, status.Pending)
+
+	// Locking down again suspends the keys reactivated since.
+	res, err := eng.LockdownTenant(ctx, "tenant_test", "still leaking")
+	require.NoError(t, err)
+	assert.Len(t, res.KeyIDs, 1)
+	assert.Nil(t, res.Lockdown.Recovery)
+	status, err = eng.LockdownStatus(ctx, "tenant_test")
+	require.NoError(t, err)
+	assert.Equal(t, 2, status.Pending)
+
+	assert.Equal(t, []tenant.LockdownPhase{
+		tenant.LockdownLocked, tenant.LockdownRecovering, tenant.LockdownHalted, tenant.LockdownRecovering, tenant.LockdownLocked,
+	}, lockdownPhases(rec))
+	halted := rec.Filter("TenantLockdownChanged")[2]
+	assert.Equal(t, plugin.ReasonAnomalyDetected, halted.Meta.ReasonCode)
+}
+
+type anomalyFunc func(tenantID string, since time.Time) string
+
+func (f anomalyFunc) TenantAnomaly(_ context.Context, tenantID string, since time.Time) (string, error) {
+	return f(tenantID, since), nil
+}
+
+func TestLockdown_CustomAnomalyDetector(t *testing.T) {
+	var asked time.Time
+	detector := anomalyFunc(func(tenantID string, since time.Time) string {
+		asked = since
+		return "error rate at 40% for " + tenantID
+	})
+	eng, clock, _, created := lockdownEngine(t, 2, keysmith.WithAnomalyDetector(detector))
+	ctx := context.Background()
+	_, err := eng.LockdownTenant(ctx, "tenant_test", "credential dump")
+	require.NoError(t, err)
+
+	clock.Set(clock.Now().Add(time.Minute))
+	status, err := eng.RecoverTenant(ctx, "tenant_test", keysmith.RecoveryOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, tenant.LockdownHalted, status.Lockdown.Phase)
+	assert.Equal(t, "error rate at 40% for tenant_test", status.Lockdown.Recovery.HaltReason)
+	assert.Equal(t, clock.Now(), asked)
+	assert.Equal(t, key.StateSuspended, keyState(t, eng, created[0].Key.ID))
+}
+
+func TestLockdown_Invalid(t *testing.T) {
+	eng, _, _, _ := lockdownEngine(t, 1)
+	ctx := context.Background()
+
+	_, err := eng.RecoverTenant(ctx, "tenant_test", keysmith.RecoveryOptions{})
+	assert.ErrorIs(t, err, keysmith.ErrTenantNotLocked)
+	_, err = eng.LockdownTenant(ctx, "", "incident")
+	assert.ErrorIs(t, err, keysmith.ErrInvalidLockdown)
+	_, err = eng.LockdownTenant(ctx, "tenant_test", "")
+	assert.ErrorIs(t, err, keysmith.ErrInvalidLockdown)
+	_, err = eng.LockdownTenant(testCtx(), "", "incident")
+	assert.ErrorIs(t, err, keysmith.ErrInvalidLockdown, "an app-scoped caller cannot lock the whole tenant")
+	tenantCtx := keysmith.WithTenant(ctx, "", "tenant_other")
+	_, err = eng.LockdownTenant(tenantCtx, "tenant_test", "incident")
+	assert.ErrorIs(t, err, keysmith.ErrInvalidLockdown)
+
+	_, err = eng.LockdownTenant(ctx, "tenant_test", "incident")
+	require.NoError(t, err)
+	_, err = eng.RecoverTenant(ctx, "tenant_test", keysmith.RecoveryOptions{BatchSize: -1})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidLockdown)
+}
+
+func TestLockdown_DryRunAndSettings(t *testing.T) {
+	eng, _, _, created := lockdownEngine(t, 2)
+	ctx := context.Background()
+
+	res, err := eng.LockdownTenant(keysmith.WithDryRun(ctx), "tenant_test", "drill")
+	require.NoError(t, err)
+	assert.True(t, res.DryRun)
+	assert.Len(t, res.KeyIDs, 2)
+	assert.Equal(t, key.StateActive, keyState(t, eng, created[0].Key.ID))
+	status, err := eng.LockdownStatus(ctx, "tenant_test")
+	require.NoError(t, err)
+	assert.False(t, status.Locked)
+
+	_, err = eng.LockdownTenant(ctx, "tenant_test", "incident")
+	require.NoError(t, err)
+	require.NoError(t, eng.SetTenantSettings(ctx, &tenant.Settings{TenantID: "tenant_test", UniqueKeyNames: true}))
+	ts, err := eng.GetTenantSettings(ctx, "tenant_test")
+	require.NoError(t, err)
+	require.NotNil(t, ts.Lockdown, "replacing the settings keeps the lockdown")
+	assert.Equal(t, "incident", ts.Lockdown.Reason)
+	assert.True(t, ts.UniqueKeyNames)
+}
+
+func TestLockdown_StopsCreateOnOtherEngines(t *testing.T) {
+	shared := memory.New()
+	engA, err := keysmith.NewEngine(keysmith.WithStore(shared))
+	require.NoError(t, err)
+	engB, err := keysmith.NewEngine(keysmith.WithStore(shared))
+	require.NoError(t, err)
+	input := &keysmith.CreateKeyInput{Name: "New", Prefix: "sk", Environment: key.EnvTest}
+	_, err = engB.CreateKey(testCtx(), input)
+	require.NoError(t, err, "engine B has the tenant's settings cached")
+
+	_, err = engA.LockdownTenant(context.Background(), "tenant_test", "credential dump")
+	require.NoError(t, err)
+	_, err = engB.CreateKey(testCtx(), input)
+	assert.ErrorIs(t, err, keysmith.ErrTenantLocked, "engine B refuses at once")
+}
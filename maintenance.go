@@ -0,0 +1,125 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/xraph/go-utils/log"
+
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store"
+)
+
+// MaintainStore runs the store's maintenance with the options set by
+// [WithStoreMaintenance], or the defaults when none are set, and returns the
+// report. It returns ErrMaintenanceUnsupported when the store does not
+// implement store.Maintainer.
+func (e *Engine) MaintainStore(ctx context.Context) (*store.MaintenanceReport, error) {
+	return e.MaintainStoreWith(ctx, e.maintenanceOpts)
+}
+
+// MaintainStoreWith is MaintainStore with explicit options. In read-only
+// mode only a StatsOnly run is allowed.
+func (e *Engine) MaintainStoreWith(ctx context.Context, opts store.MaintenanceOptions) (*store.MaintenanceReport, error) {
+	if !opts.StatsOnly {
+		if err := e.checkWritable(ctx); err != nil {
+			return nil, err
+		}
+	}
+	m, ok := store.As[store.Maintainer](e.store)
+	if !ok {
+		return nil, ErrMaintenanceUnsupported
+	}
+	return m.Maintain(ctx, opts)
+}
+
+// MigrateIDs rewrites the IDs held by the store into opts.To, for moving an
+// existing deployment to a different [WithIDFormat]. Run it before serving
+// traffic in the new format: stores look rows up by comparing ID strings,
+// so a row still in the old format is not found by its ID until it is
+// rewritten, although rows read by other means, such as validation by hash,
+// parse in either format. It returns ErrIDMigrationUnsupported when the store does not implement
+// store.IDMigrator.
+func (e *Engine) MigrateIDs(ctx context.Context, opts store.IDMigrationOptions) (*store.IDMigrationReport, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	m, ok := store.As[store.IDMigrator](e.store)
+	if !ok {
+		return nil, ErrIDMigrationUnsupported
+	}
+	return m.MigrateIDs(ctx, opts)
+}
+
+// HashBackfillReport reports a CanonicalizeKeyHashes run.
+type HashBackfillReport struct {
+	Scanned   int  `json:"scanned"`
+	Rewritten int  `json:"rewritten"`
+	Failed    int  `json:"failed"`
+	DryRun    bool `json:"dry_run"`
+}
+
+// CanonicalizeKeyHashes rewrites the stored hashes of the keys ctx can see
+// into the canonical "<alg>:<hex>" form of [key.FormatHash]. A bare legacy
+// digest gets the prefix of the engine's hasher, so run it with the hasher
+// that made the hashes. Lookups match legacy forms until then, so it can run
+// while serving traffic. UpdatedAt is left alone: the key has not changed.
+// With [WithDryRun] it only counts the keys it would rewrite. A key the
+// store fails to update is logged and counted in Failed.
+func (e *Engine) CanonicalizeKeyHashes(ctx context.Context) (*HashBackfillReport, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	report := &HashBackfillReport{DryRun: IsDryRun(ctx)}
+	err := e.store.Keys().Iterate(ctx, keyFilter(ctx, &key.ListFilter{}), func(k *key.Key) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		report.Scanned++
+		canonical := e.canonicalHash(k.KeyHash)
+		if canonical == k.KeyHash {
+			return nil
+		}
+		if !report.DryRun {
+			k.KeyHash = canonical
+			if err := e.store.Keys().Update(ctx, k); err != nil {
+				e.logger.Warn("failed to rewrite key hash", log.String("key_id", k.ID.String()), log.Any("error", err))
+				report.Failed++
+				return nil
+			}
+		}
+		report.Rewritten++
+		return nil
+	})
+	if err != nil {
+		return report, fmt.Errorf("iterate keys: %w", err)
+	}
+	return report, nil
+}
+
+// runMaintenance is the body of the scheduled maintenance job.
+func (e *Engine) runMaintenance(ctx context.Context) {
+	if e.ReadOnly() {
+		return
+	}
+	report, err := e.MaintainStore(ctx)
+	if err != nil {
+		e.logger.Warn("store maintenance failed", log.Any("error", err))
+		return
+	}
+	var rows, dead, size int64
+	for _, t := range report.Tables {
+		rows += t.Rows
+		dead += t.DeadRows
+		size += t.SizeBytes
+	}
+	e.logger.Info("store maintenance completed",
+		log.String("backend", report.Backend),
+		log.Int("actions", len(report.Actions)),
+		log.Int("tables", len(report.Tables)),
+		log.Int64("rows", rows),
+		log.Int64("dead_rows", dead),
+		log.Int64("size_bytes", size),
+		log.Duration("duration", report.Duration),
+	)
+}
@@ -0,0 +1,74 @@
+package keysmith_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+// plainStore hides the memory store's Maintain method.
+type plainStore struct{ store.Store }
+
+// maintainCountingStore counts Maintain calls and records their options.
+type maintainCountingStore struct {
+	*memory.Store
+	calls atomic.Int64
+	full  atomic.Bool
+}
+
+func (s *maintainCountingStore) Maintain(ctx context.Context, opts store.MaintenanceOptions) (*store.MaintenanceReport, error) {
+	s.calls.Add(1)
+	s.full.Store(opts.Full)
+	return s.Store.Maintain(ctx, opts)
+}
+
+func TestMaintainStore_Memory(t *testing.T) {
+	eng := newTestEngine(t)
+	_, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "K", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+
+	report, err := eng.MaintainStore(testCtx())
+	require.NoError(t, err)
+	assert.Equal(t, "memory", report.Backend)
+	assert.NotEmpty(t, report.Tables)
+	for _, tbl := range report.Tables {
+		if tbl.Name == "keysmith_keys" {
+			assert.Equal(t, int64(1), tbl.Rows)
+		}
+	}
+}
+
+func TestMaintainStore_Unsupported(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(plainStore{memory.New()}))
+	require.NoError(t, err)
+
+	_, err = eng.MaintainStore(testCtx())
+	assert.ErrorIs(t, err, keysmith.ErrMaintenanceUnsupported)
+}
+
+func TestWithStoreMaintenance_RunsOnSchedule(t *testing.T) {
+	ms := &maintainCountingStore{Store: memory.New()}
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(ms),
+		keysmith.WithStoreMaintenance(10*time.Millisecond, store.MaintenanceOptions{Full: true}),
+	)
+	require.NoError(t, err)
+	require.NoError(t, eng.Start(context.Background()))
+
+	assert.Eventually(t, func() bool { return ms.calls.Load() >= 2 }, time.Second, 5*time.Millisecond)
+	assert.True(t, ms.full.Load(), "the configured options are used")
+
+	require.NoError(t, eng.Stop(context.Background()))
+	n := ms.calls.Load()
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, n, ms.calls.Load(), "the job stops with the engine")
+}
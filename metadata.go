@@ -0,0 +1,198 @@
+package keysmith
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ReservedMetadataPrefix marks metadata entries written by keysmith itself,
+// such as [MetadataCompromise]. Callers cannot set entries with this prefix.
+const ReservedMetadataPrefix = "keysmith."
+
+// Default metadata limits.
+const (
+	DefaultMetadataMaxBytes     = 16 << 10
+	DefaultMetadataMaxKeys      = 64
+	DefaultMetadataMaxKeyLength = 128
+)
+
+// MetadataLimits bounds the metadata accepted on keys, policies, and scopes.
+// Metadata is loaded with every key on the validation path, so large blobs
+// slow down every request made with the key. Zero fields use the defaults.
+type MetadataLimits struct {
+	// MaxBytes is the largest JSON-encoded size of the whole map.
+	MaxBytes int
+
+	// MaxKeys is the largest number of entries.
+	MaxKeys int
+
+	// MaxKeyLength is the longest entry name, in bytes.
+	MaxKeyLength int
+}
+
+func (l MetadataLimits) withDefaults() MetadataLimits {
+	if l.MaxBytes <= 0 {
+		l.MaxBytes = DefaultMetadataMaxBytes
+	}
+	if l.MaxKeys <= 0 {
+		l.MaxKeys = DefaultMetadataMaxKeys
+	}
+	if l.MaxKeyLength <= 0 {
+		l.MaxKeyLength = DefaultMetadataMaxKeyLength
+	}
+	return l
+}
+
+// MetadataViolation describes one rejected metadata entry. Key is empty for
+// violations of the map as a whole.
+type MetadataViolation struct {
+	Key    string `json:"key,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// MetadataError is returned when metadata breaks the engine's
+// [MetadataLimits], sets a reserved entry, or does not match the tenant's
+// metadata schema. It matches ErrInvalidMetadata with errors.Is.
+type MetadataError struct {
+	Violations []MetadataViolation
+}
+
+func (e *MetadataError) Error() string {
+	parts := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		if v.Key == "" {
+			parts = append(parts, v.Reason)
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%q: %s", v.Key, v.Reason))
+	}
+	return ErrInvalidMetadata.Error() + ": " + strings.Join(parts, "; ")
+}
+
+// Is reports whether target is ErrInvalidMetadata.
+func (e *MetadataError) Is(target error) bool { return target == ErrInvalidMetadata }
+
+// Keys returns the names of the offending entries.
+func (e *MetadataError) Keys() []string {
+	var keys []string
+	for _, v := range e.Violations {
+		if v.Key != "" {
+			keys = append(keys, v.Key)
+		}
+	}
+	return keys
+}
+
+// validateMetadata checks caller-supplied metadata against the engine's
+// limits. Engine-internal entries are added after validation with
+// setInternalMetadata. Reserved entries already present in stored, the
+// record being updated, are accepted when they are unchanged.
+func (e *Engine) validateMetadata(meta, stored map[string]any) error {
+	if len(meta) == 0 {
+		return nil
+	}
+	limits := e.metadataLimits.withDefaults()
+
+	names := make([]string, 0, len(meta))
+	for k := range meta {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var violations []MetadataViolation
+	if len(meta) > limits.MaxKeys {
+		violations = append(violations, MetadataViolation{
+			Reason: fmt.Sprintf("has %d entries, more than the limit of %d", len(meta), limits.MaxKeys),
+		})
+	}
+	for _, k := range names {
+		switch {
+		case strings.HasPrefix(k, ReservedMetadataPrefix) && !sameMetadataValue(meta[k], stored, k):
+			violations = append(violations, MetadataViolation{Key: k, Reason: "uses the reserved " + ReservedMetadataPrefix + " prefix"})
+		case len(k) > limits.MaxKeyLength:
+			violations = append(violations, MetadataViolation{Key: k, Reason: fmt.Sprintf("name is longer than %d bytes", limits.MaxKeyLength)})
+		}
+		if _, err := json.Marshal(meta[k]); err != nil {
+			violations = append(violations, MetadataViolation{Key: k, Reason: "value is not JSON-serializable: " + err.Error()})
+		}
+	}
+	if len(violations) > 0 {
+		return &MetadataError{Violations: violations}
+	}
+
+	buf, err := json.Marshal(meta)
+	if err != nil {
+		return &MetadataError{Violations: []MetadataViolation{{Reason: err.Error()}}}
+	}
+	if len(buf) > limits.MaxBytes {
+		return &MetadataError{Violations: []MetadataViolation{{
+			Reason: fmt.Sprintf("encodes to %d bytes, more than the limit of %d", len(buf), limits.MaxBytes),
+		}}}
+	}
+	return nil
+}
+
+// ValidateMetadata checks key metadata against the engine's limits and the
+// tenant's metadata schema without writing anything, so a UI can report
+// field errors before submitting a key. An empty tenantID uses the tenant on
+// the context. It returns a [MetadataError] listing every offending entry.
+func (e *Engine) ValidateMetadata(ctx context.Context, tenantID string, meta map[string]any) error {
+	if tenantID == "" {
+		tenantID = scopeFromContext(ctx).tenantID
+	}
+	if err := e.validateMetadata(meta, nil); err != nil {
+		return err
+	}
+	return e.validateMetadataSchema(ctx, tenantID, meta)
+}
+
+// validateMetadataSchema checks key metadata against the tenant's metadata
+// schema, if it has one. Reserved entries belong to the engine and are not
+// subject to the schema.
+func (e *Engine) validateMetadataSchema(ctx context.Context, tenantID string, meta map[string]any) error {
+	schema := e.tenantSettings(ctx, tenantID).MetadataSchema
+	if schema == nil {
+		return nil
+	}
+	caller := make(map[string]any, len(meta))
+	for k, v := range meta {
+		if !strings.HasPrefix(k, ReservedMetadataPrefix) {
+			caller[k] = v
+		}
+	}
+	found := schema.Validate(caller)
+	if len(found) == 0 {
+		return nil
+	}
+	violations := make([]MetadataViolation, len(found))
+	for i, v := range found {
+		violations[i] = MetadataViolation{Key: v.Field, Reason: v.Reason}
+	}
+	return &MetadataError{Violations: violations}
+}
+
+// sameMetadataValue reports whether stored holds name with a value that
+// encodes the same as v.
+func sameMetadataValue(v any, stored map[string]any, name string) bool {
+	sv, ok := stored[name]
+	if !ok {
+		return false
+	}
+	a, errA := json.Marshal(v)
+	b, errB := json.Marshal(sv)
+	return errA == nil && errB == nil && bytes.Equal(a, b)
+}
+
+// setInternalMetadata writes an engine-owned entry, bypassing the reserved
+// prefix check applied to caller input.
+func setInternalMetadata(meta map[string]any, name string, value any) map[string]any {
+	if meta == nil {
+		meta = make(map[string]any)
+	}
+	meta[name] = value
+	return meta
+}
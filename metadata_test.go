@@ -0,0 +1,200 @@
+package keysmith_test
+
+import (
+	"errors"
+	"maps"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/metaschema"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+func newMetadataEngine(t *testing.T, limits keysmith.MetadataLimits) *keysmith.Engine {
+	t.Helper()
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithMetadataLimits(limits),
+	)
+	require.NoError(t, err)
+	return eng
+}
+
+func createWithMetadata(eng *keysmith.Engine, meta map[string]any) error {
+	_, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+		Name:        "Metadata Key",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+		Metadata:    meta,
+	})
+	return err
+}
+
+func TestMetadata_SizeBoundary(t *testing.T) {
+	eng := newMetadataEngine(t, keysmith.MetadataLimits{MaxBytes: 1024})
+
+	// {"blob":"…"} encodes to 11 bytes plus the value.
+	require.NoError(t, createWithMetadata(eng, map[string]any{"blob": strings.Repeat("a", 1024-11)}))
+
+	err := createWithMetadata(eng, map[string]any{"blob": strings.Repeat("a", 1024-10)})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidMetadata)
+	assert.Contains(t, err.Error(), "1025 bytes")
+}
+
+func TestMetadata_ReservedPrefix(t *testing.T) {
+	eng := newMetadataEngine(t, keysmith.MetadataLimits{})
+
+	err := createWithMetadata(eng, map[string]any{
+		"team":                         "payments",
+		keysmith.MetadataCompromise:    "spoofed",
+		"keysmith.cloned_from":         "akey_x",
+		"keysmith_not_reserved_either": true,
+	})
+	require.ErrorIs(t, err, keysmith.ErrInvalidMetadata)
+
+	var metaErr *keysmith.MetadataError
+	require.True(t, errors.As(err, &metaErr))
+	assert.Equal(t, []string{"keysmith.cloned_from", keysmith.MetadataCompromise}, metaErr.Keys())
+}
+
+func TestMetadata_KeyLimits(t *testing.T) {
+	eng := newMetadataEngine(t, keysmith.MetadataLimits{MaxKeys: 2, MaxKeyLength: 8})
+
+	err := createWithMetadata(eng, map[string]any{"a": 1, "b": 2, "c": 3})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidMetadata)
+
+	err = createWithMetadata(eng, map[string]any{"much-too-long": 1})
+	var metaErr *keysmith.MetadataError
+	require.True(t, errors.As(err, &metaErr))
+	assert.Equal(t, []string{"much-too-long"}, metaErr.Keys())
+}
+
+func TestMetadata_NonSerializable(t *testing.T) {
+	eng := newMetadataEngine(t, keysmith.MetadataLimits{})
+
+	err := createWithMetadata(eng, map[string]any{"ratio": math.NaN(), "ok": "yes"})
+	var metaErr *keysmith.MetadataError
+	require.True(t, errors.As(err, &metaErr))
+	assert.Equal(t, []string{"ratio"}, metaErr.Keys())
+}
+
+func TestMetadata_Policy(t *testing.T) {
+	eng := newMetadataEngine(t, keysmith.MetadataLimits{})
+
+	pol := &policy.Policy{Name: "Tagged", Metadata: map[string]any{"keysmith.owner": "me"}}
+	assert.ErrorIs(t, eng.CreatePolicy(testCtx(), pol), keysmith.ErrInvalidMetadata)
+
+	pol.Metadata = map[string]any{"owner": "me"}
+	require.NoError(t, eng.CreatePolicy(testCtx(), pol))
+
+	upd := updatedPolicy(pol, "keysmith.owner", "me")
+	assert.ErrorIs(t, eng.UpdatePolicy(testCtx(), upd), keysmith.ErrInvalidMetadata)
+}
+
+// updatedPolicy returns a copy of pol with one metadata entry set, leaving
+// the stored record untouched.
+func updatedPolicy(pol *policy.Policy, name string, value any) *policy.Policy {
+	upd := *pol
+	upd.Metadata = maps.Clone(pol.Metadata)
+	upd.Metadata[name] = value
+	return &upd
+}
+
+func TestMetadata_UpdateKeepsReservedEntries(t *testing.T) {
+	eng := newMetadataEngine(t, keysmith.MetadataLimits{})
+
+	pol, err := eng.CreatePolicyFromTemplate(testCtx(), keysmith.PolicyTemplateStandard, &policy.Policy{Name: "From template"})
+	require.NoError(t, err)
+
+	// Round-tripping the stored reserved entry is fine; changing it is not.
+	require.NoError(t, eng.UpdatePolicy(testCtx(), updatedPolicy(pol, "owner", "me")))
+
+	upd := updatedPolicy(pol, keysmith.MetadataPolicyTemplate, "strict")
+	assert.ErrorIs(t, eng.UpdatePolicy(testCtx(), upd), keysmith.ErrInvalidMetadata)
+}
+
+func TestMetadata_InternalWritesBypassReservedPrefix(t *testing.T) {
+	eng := newMetadataEngine(t, keysmith.MetadataLimits{})
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+		Name:        "Metadata Key",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+	})
+	require.NoError(t, err)
+
+	_, err = eng.ReportCompromise(testCtx(), created.Key.ID, &key.CompromiseReport{
+		Source: "scanner",
+		Action: key.CompromiseRevoke,
+	})
+	require.NoError(t, err)
+
+	k, err := eng.GetKey(testCtx(), created.Key.ID)
+	require.NoError(t, err)
+	assert.Contains(t, k.Metadata, keysmith.MetadataCompromise)
+}
+
+func TestMetadata_TenantSchema(t *testing.T) {
+	eng := newMetadataEngine(t, keysmith.MetadataLimits{})
+	old, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+		Name:        "Before schema",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+		Metadata:    map[string]any{"planName": "Pro"},
+	})
+	require.NoError(t, err)
+
+	_, err = eng.SetMetadataSchema(testCtx(), "", &metaschema.Schema{Fields: map[string]metaschema.Field{
+		"plan":  {Type: metaschema.TypeString, Required: true, Enum: []any{"free", "pro"}},
+		"seats": {Type: metaschema.TypeNumber},
+	}})
+	require.NoError(t, err)
+
+	err = createWithMetadata(eng, map[string]any{"planName": "Pro", "seats": "ten"})
+	var merr *keysmith.MetadataError
+	require.ErrorAs(t, err, &merr)
+	assert.ErrorIs(t, err, keysmith.ErrInvalidMetadata)
+	assert.Equal(t, []string{"plan", "planName", "seats"}, merr.Keys())
+
+	require.NoError(t, createWithMetadata(eng, map[string]any{"plan": "pro", "seats": 10}))
+	assert.NoError(t, eng.ValidateMetadata(testCtx(), "", map[string]any{"plan": "free"}))
+	assert.ErrorIs(t, eng.ValidateMetadata(testCtx(), "", map[string]any{"plan": "gold"}), keysmith.ErrInvalidMetadata)
+	assert.NoError(t, eng.ValidateMetadata(testCtx(), "other_tenant", map[string]any{"planName": "Pro"}),
+		"tenants without a schema accept any metadata")
+
+	// Keys written before the schema keep working.
+	_, err = eng.ValidateKey(testCtx(), old.RawKey)
+	require.NoError(t, err)
+	_, err = eng.RotateKey(testCtx(), old.Key.ID, "manual")
+	require.NoError(t, err)
+}
+
+func TestMetadata_TenantSchemaAllowUnknown(t *testing.T) {
+	eng := newMetadataEngine(t, keysmith.MetadataLimits{})
+	_, err := eng.SetMetadataSchema(testCtx(), "", &metaschema.Schema{
+		Fields:       map[string]metaschema.Field{"plan": {Type: metaschema.TypeString}},
+		AllowUnknown: true,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, createWithMetadata(eng, map[string]any{"team": "growth"}))
+	assert.ErrorIs(t, createWithMetadata(eng, map[string]any{"plan": 3}), keysmith.ErrInvalidMetadata)
+}
+
+func TestMetadata_TenantSchemaRejectsInvalidSchema(t *testing.T) {
+	eng := newMetadataEngine(t, keysmith.MetadataLimits{})
+	_, err := eng.SetMetadataSchema(testCtx(), "", &metaschema.Schema{Fields: map[string]metaschema.Field{
+		"plan": {Type: "enum"},
+	}})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidMetadataSchema)
+
+	ts, err := eng.GetTenantSettings(testCtx(), "")
+	require.NoError(t, err)
+	assert.Nil(t, ts.MetadataSchema)
+}
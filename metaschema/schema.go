@@ -0,0 +1,213 @@
+// Package metaschema validates metadata maps against a small typed schema.
+//
+// A [Schema] is a subset of JSON Schema sized for key metadata: a flat set of
+// named fields, each with a type (string, number, or bool), an optional
+// required flag, and an optional list of allowed values. Entries the schema
+// does not name are rejected unless the schema allows unknown fields.
+//
+// Example:
+//
+//	s := &metaschema.Schema{Fields: map[string]metaschema.Field{
+//	    "plan": {Type: metaschema.TypeString, Required: true, Enum: []any{"free", "pro"}},
+//	    "seats": {Type: metaschema.TypeNumber},
+//	}}
+//	violations := s.Validate(map[string]any{"plan": "enterprise"})
+package metaschema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Type is the value type of a schema field.
+type Type string
+
+// Field types.
+const (
+	TypeString Type = "string"
+	TypeNumber Type = "number"
+	TypeBool   Type = "bool"
+)
+
+// Field describes one metadata entry.
+type Field struct {
+	Type     Type  `json:"type"`
+	Required bool  `json:"required,omitempty"`
+	Enum     []any `json:"enum,omitempty"`
+}
+
+// Schema describes the metadata a map may hold.
+type Schema struct {
+	Fields map[string]Field `json:"fields"`
+
+	// AllowUnknown accepts entries that are not in Fields. When false they
+	// are reported as violations.
+	AllowUnknown bool `json:"allow_unknown,omitempty"`
+}
+
+// Violation describes one entry that does not match the schema.
+type Violation struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// Check reports problems with the schema itself: empty field names,
+// unknown types, and enum values that do not match their field's type.
+func (s *Schema) Check() error {
+	var problems []string
+	for _, name := range sortedNames(s.Fields) {
+		f := s.Fields[name]
+		if strings.TrimSpace(name) == "" {
+			problems = append(problems, "field name is empty")
+			continue
+		}
+		switch f.Type {
+		case TypeString, TypeNumber, TypeBool:
+		default:
+			problems = append(problems, fmt.Sprintf("%q: unknown type %q", name, f.Type))
+			continue
+		}
+		for _, v := range f.Enum {
+			if !f.Type.matches(v) {
+				problems = append(problems, fmt.Sprintf("%q: enum value %v is not a %s", name, v, f.Type))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("metaschema: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Validate returns every entry of meta that does not match the schema,
+// ordered by field name. A nil schema accepts anything.
+func (s *Schema) Validate(meta map[string]any) []Violation {
+	if s == nil {
+		return nil
+	}
+
+	var violations []Violation
+	for _, name := range sortedNames(s.Fields) {
+		f := s.Fields[name]
+		v, ok := meta[name]
+		switch {
+		case !ok || v == nil:
+			if f.Required {
+				violations = append(violations, Violation{Field: name, Reason: "is required"})
+			}
+		case !f.Type.matches(v):
+			violations = append(violations, Violation{Field: name, Reason: fmt.Sprintf("must be a %s", f.Type)})
+		case len(f.Enum) > 0 && !f.allows(v):
+			violations = append(violations, Violation{Field: name, Reason: "must be one of " + f.enumList()})
+		}
+	}
+	if !s.AllowUnknown {
+		for _, name := range sortedNames(meta) {
+			if _, ok := s.Fields[name]; !ok {
+				violations = append(violations, Violation{Field: name, Reason: "is not in the schema"})
+			}
+		}
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool { return violations[i].Field < violations[j].Field })
+	return violations
+}
+
+// Clone returns a deep copy of the schema.
+func (s *Schema) Clone() *Schema {
+	if s == nil {
+		return nil
+	}
+	cp := &Schema{AllowUnknown: s.AllowUnknown, Fields: make(map[string]Field, len(s.Fields))}
+	for name, f := range s.Fields {
+		f.Enum = append([]any(nil), f.Enum...)
+		cp.Fields[name] = f
+	}
+	return cp
+}
+
+// matches reports whether v has type t. Numbers decoded from JSON arrive
+// as float64, but any Go integer or float type is accepted.
+func (t Type) matches(v any) bool {
+	switch t {
+	case TypeString:
+		_, ok := v.(string)
+		return ok
+	case TypeNumber:
+		_, ok := toFloat(v)
+		return ok
+	case TypeBool:
+		_, ok := v.(bool)
+		return ok
+	}
+	return false
+}
+
+func (f Field) allows(v any) bool {
+	for _, allowed := range f.Enum {
+		if f.Type == TypeNumber {
+			a, _ := toFloat(allowed)
+			b, _ := toFloat(v)
+			if a == b {
+				return true
+			}
+			continue
+		}
+		if allowed == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (f Field) enumList() string {
+	parts := make([]string, len(f.Enum))
+	for i, v := range f.Enum {
+		if s, ok := v.(string); ok {
+			parts[i] = fmt.Sprintf("%q", s)
+			continue
+		}
+		parts[i] = fmt.Sprint(v)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,153 @@
+package metaschema_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith/metaschema"
+)
+
+func planSchema() *metaschema.Schema {
+	return &metaschema.Schema{Fields: map[string]metaschema.Field{
+		"plan":   {Type: metaschema.TypeString, Required: true, Enum: []any{"free", "pro"}},
+		"seats":  {Type: metaschema.TypeNumber, Enum: []any{1, 5, 10}},
+		"trial":  {Type: metaschema.TypeBool},
+		"region": {Type: metaschema.TypeString},
+	}}
+}
+
+func TestSchema_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema *metaschema.Schema
+		meta   map[string]any
+		want   []metaschema.Violation
+	}{
+		{
+			name:   "valid",
+			schema: planSchema(),
+			meta:   map[string]any{"plan": "pro", "seats": 5, "trial": true},
+		},
+		{
+			name:   "nil schema accepts anything",
+			schema: nil,
+			meta:   map[string]any{"anything": []int{1}},
+		},
+		{
+			name:   "missing required",
+			schema: planSchema(),
+			meta:   map[string]any{"seats": 1},
+			want:   []metaschema.Violation{{Field: "plan", Reason: "is required"}},
+		},
+		{
+			name:   "null required",
+			schema: planSchema(),
+			meta:   map[string]any{"plan": nil},
+			want:   []metaschema.Violation{{Field: "plan", Reason: "is required"}},
+		},
+		{
+			name:   "wrong types",
+			schema: planSchema(),
+			meta:   map[string]any{"plan": "free", "seats": "five", "trial": "yes"},
+			want: []metaschema.Violation{
+				{Field: "seats", Reason: "must be a number"},
+				{Field: "trial", Reason: "must be a bool"},
+			},
+		},
+		{
+			name:   "enum mismatch",
+			schema: planSchema(),
+			meta:   map[string]any{"plan": "enterprise", "seats": 3},
+			want: []metaschema.Violation{
+				{Field: "plan", Reason: `must be one of "free", "pro"`},
+				{Field: "seats", Reason: "must be one of 1, 5, 10"},
+			},
+		},
+		{
+			name:   "unknown field rejected",
+			schema: planSchema(),
+			meta:   map[string]any{"plan": "free", "planName": "Free"},
+			want:   []metaschema.Violation{{Field: "planName", Reason: "is not in the schema"}},
+		},
+		{
+			name:   "unknown field allowed",
+			schema: &metaschema.Schema{Fields: planSchema().Fields, AllowUnknown: true},
+			meta:   map[string]any{"plan": "free", "planName": "Free"},
+		},
+		{
+			name:   "violations sorted by field",
+			schema: planSchema(),
+			meta:   map[string]any{"zone": "x", "trial": 1, "alpha": true},
+			want: []metaschema.Violation{
+				{Field: "alpha", Reason: "is not in the schema"},
+				{Field: "plan", Reason: "is required"},
+				{Field: "trial", Reason: "must be a bool"},
+				{Field: "zone", Reason: "is not in the schema"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.schema.Validate(tt.meta))
+		})
+	}
+}
+
+func TestSchema_ValidateDecodedJSON(t *testing.T) {
+	var meta map[string]any
+	require.NoError(t, json.Unmarshal([]byte(`{"plan":"pro","seats":10,"trial":false}`), &meta))
+	assert.Empty(t, planSchema().Validate(meta), "JSON numbers decode as float64")
+}
+
+func TestSchema_Check(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  map[string]metaschema.Field
+		wantErr string
+	}{
+		{name: "valid", fields: planSchema().Fields},
+		{name: "empty", fields: nil},
+		{
+			name:    "unknown type",
+			fields:  map[string]metaschema.Field{"plan": {Type: "object"}},
+			wantErr: `"plan": unknown type "object"`,
+		},
+		{
+			name:    "enum type mismatch",
+			fields:  map[string]metaschema.Field{"seats": {Type: metaschema.TypeNumber, Enum: []any{1, "two"}}},
+			wantErr: `"seats": enum value two is not a number`,
+		},
+		{
+			name:    "empty name",
+			fields:  map[string]metaschema.Field{" ": {Type: metaschema.TypeString}},
+			wantErr: "field name is empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&metaschema.Schema{Fields: tt.fields}).Check()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestSchema_Clone(t *testing.T) {
+	s := planSchema()
+	cp := s.Clone()
+	cp.Fields["plan"].Enum[0] = "changed"
+	cp.Fields["extra"] = metaschema.Field{Type: metaschema.TypeBool}
+
+	assert.Equal(t, "free", s.Fields["plan"].Enum[0])
+	assert.NotContains(t, s.Fields, "extra")
+	assert.Nil(t, (*metaschema.Schema)(nil).Clone())
+}
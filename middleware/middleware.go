@@ -6,12 +6,50 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/capture"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/policy"
 )
 
-type contextKey struct{}
+type (
+	contextKey     struct{}
+	scopeLimitsKey struct{}
+)
+
+// capturer, readOnlyReporter, and scopeRateLimiter are the optional engine
+// capabilities APIKeyAuth uses beyond validation.
+type capturer interface {
+	SampleCapture(k *key.Key) bool
+	RecordCapture(ctx context.Context, k *key.Key, rawKey string, c *capture.Capture) error
+}
+
+type readOnlyReporter interface {
+	ReadOnly() bool
+}
+
+type scopeRateLimiter interface {
+	CheckScopeRateLimits(ctx context.Context, result *keysmith.ValidationResult, scopes ...string) ([]keysmith.ScopeRateLimitInfo, error)
+}
+
+var (
+	_ capturer         = (*keysmith.Engine)(nil)
+	_ readOnlyReporter = (*keysmith.Engine)(nil)
+	_ scopeRateLimiter = (*keysmith.Engine)(nil)
+)
+
+// scopeLimits is what APIKeyAuth passes RequireScopes to apply per-scope
+// rate limits with.
+type scopeLimits struct {
+	limiter scopeRateLimiter
+	headers bool
+}
 
 // ResultFromContext extracts the ValidationResult from the context.
 func ResultFromContext(ctx context.Context) (*keysmith.ValidationResult, bool) {
@@ -19,40 +57,284 @@ func ResultFromContext(ctx context.Context) (*keysmith.ValidationResult, bool) {
 	return v, ok
 }
 
+// Option configures APIKeyAuth.
+type Option func(*options)
+
+type options struct {
+	captureHeaders  []string
+	consumerHeader  string
+	requestIDHeader string
+	acceptPrefixes  []string
+
+	scopeRateLimitHeaders bool
+
+	// tlsVersionHeader and certHeader, when either is set, replace the
+	// connection state as the source of transport details.
+	tlsVersionHeader string
+	certHeader       string
+
+	// trustedProxies, when set, are the peers whose X-Forwarded-For is
+	// believed.
+	trustedProxies []netip.Prefix
+}
+
+// ScopeRateLimitHeader is the header RequireScopes reports per-scope rate
+// limits in when APIKeyAuth is built with WithScopeRateLimitHeaders. It has
+// one value per limited scope, such as "export; limit=10; remaining=4".
+const ScopeRateLimitHeader = "X-RateLimit-Scope"
+
+// ForwardedForHeader is the header APIKeyAuth reads the client IP from when
+// the request comes through a proxy trusted with WithTrustedProxies.
+const ForwardedForHeader = "X-Forwarded-For"
+
+// DefaultRequestIDHeader is the header APIKeyAuth reads the request ID from.
+const DefaultRequestIDHeader = "X-Request-ID"
+
+// ReadOnlyHeader is set to "true" on every response APIKeyAuth handles while
+// the engine is in read-only mode, so that clients can tell a refused write
+// from a failure.
+const ReadOnlyHeader = "X-Keysmith-Read-Only"
+
+// RotationDueHeader holds the RFC 3339 time a key's policy rotation period
+// ends, set by APIKeyAuth once the key is due for rotation; see
+// [keysmith.WithRotationDueWarning]. RotationDaysRemainingHeader holds the
+// whole days left, and RotationOverdueHeader is "true" after the due time.
+const (
+	RotationDueHeader           = "X-Keysmith-Rotation-Due"
+	RotationDaysRemainingHeader = "X-Keysmith-Rotation-Days-Remaining"
+	RotationOverdueHeader       = "X-Keysmith-Rotation-Overdue"
+)
+
+// KeyDeprecatedHeader names the deprecated format of the key, set by
+// APIKeyAuth for a key in a format flagged by
+// [keysmith.WithKeyFormatDeprecation]. KeySunsetHeader holds the format's
+// sunset as an HTTP date and KeyDeprecationLinkHeader its migration link,
+// when configured. They describe the key, not the resource, so they are
+// kept apart from the Deprecation and Sunset headers of deprecated routes.
+const (
+	KeyDeprecatedHeader      = "X-Keysmith-Key-Deprecated"
+	KeySunsetHeader          = "X-Keysmith-Key-Sunset"
+	KeyDeprecationLinkHeader = "X-Keysmith-Key-Deprecation-Link"
+)
+
+// keyHeaders are the headers APIKeyAuth reads the key from, in order, with
+// the scheme that precedes the key in the header value.
+var keyHeaders = []struct{ name, scheme string }{
+	{name: "Authorization", scheme: "Bearer "},
+	{name: "X-API-Key"},
+}
+
+// Header describes a request header APIKeyAuth reads.
+type Header struct {
+	// Name is the header name.
+	Name string `json:"name"`
+
+	// Value is the header value, with "{key}" or "{value}" standing for
+	// the caller's part.
+	Value string `json:"value"`
+
+	// Purpose is "key" for a header carrying the API key, of which a request
+	// needs one, "consumer" for the consumer service header, "tls_version"
+	// or "client_cert" for trusted transport headers, "client_ip" for
+	// X-Forwarded-For from trusted proxies, or "request_id".
+	Purpose string `json:"purpose"`
+}
+
+// Headers returns the headers APIKeyAuth reads when built with opts, key
+// headers first in the order they are tried. It is generated from the same
+// configuration APIKeyAuth uses, for documentation that cannot drift.
+func Headers(opts ...Option) []Header {
+	o := newOptions(opts)
+	out := make([]Header, 0, len(keyHeaders)+5)
+	for _, h := range keyHeaders {
+		out = append(out, Header{Name: h.name, Value: h.scheme + "{key}", Purpose: "key"})
+	}
+	if o.consumerHeader != "" {
+		out = append(out, Header{Name: o.consumerHeader, Value: "{value}", Purpose: "consumer"})
+	}
+	if o.tlsVersionHeader != "" {
+		out = append(out, Header{Name: o.tlsVersionHeader, Value: "{value}", Purpose: "tls_version"})
+	}
+	if o.certHeader != "" {
+		out = append(out, Header{Name: o.certHeader, Value: "{value}", Purpose: "client_cert"})
+	}
+	if len(o.trustedProxies) > 0 {
+		out = append(out, Header{Name: ForwardedForHeader, Value: "{value}", Purpose: "client_ip"})
+	}
+	if o.requestIDHeader != "" {
+		out = append(out, Header{Name: o.requestIDHeader, Value: "{value}", Purpose: "request_id"})
+	}
+	return out
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{captureHeaders: capture.DefaultHeaders, requestIDHeader: DefaultRequestIDHeader}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithCaptureHeaders sets the headers kept in debug captures. Defaults to
+// capture.DefaultHeaders. Credential headers are redacted even when listed.
+func WithCaptureHeaders(names ...string) Option {
+	return func(o *options) { o.captureHeaders = names }
+}
+
+// WithConsumerHeader names the request header, such as "X-Service-Name",
+// that identifies the calling service. Its value is checked against the
+// key's intended consumer; see [keysmith.ValidationRequest.ConsumerService].
+// Only set it for internal traffic, where the header cannot be forged by
+// outside clients. By default no header is read.
+func WithConsumerHeader(name string) Option {
+	return func(o *options) { o.consumerHeader = name }
+}
+
+// WithRequestIDHeader names the request header holding the request ID,
+// which reaches hooks as plugin.EventMeta.RequestID via [keysmith.WithRequestID].
+// Defaults to DefaultRequestIDHeader; an empty name reads no header.
+func WithRequestIDHeader(name string) Option {
+	return func(o *options) { o.requestIDHeader = name }
+}
+
+// WithAcceptPrefixes limits the key prefixes the middleware accepts, such as
+// only "sk" in front of server-side routes so that a publishable "pk" key is
+// refused. Keys with another prefix get 403 before they are looked up; see
+// [keysmith.ValidationRequest.AcceptPrefixes]. By default any prefix is
+// accepted.
+func WithAcceptPrefixes(prefixes ...string) Option {
+	return func(o *options) { o.acceptPrefixes = prefixes }
+}
+
+// WithScopeRateLimitHeaders has RequireScopes report the budget left under
+// each rate-limited scope it checks in [ScopeRateLimitHeader]. By default
+// scope limits are enforced without headers.
+func WithScopeRateLimitHeaders() Option {
+	return func(o *options) { o.scopeRateLimitHeaders = true }
+}
+
+// WithTrustedTransportHeaders reads the TLS version and client certificate
+// fingerprint from request headers set by an edge proxy that terminates TLS,
+// such as "X-Forwarded-TLS-Version" and "X-Client-Cert-Fingerprint", instead
+// of from the connection, which then only describes the hop from the proxy.
+// The version header holds "1.3" or "TLSv1.3"; the fingerprint header holds
+// the SHA-256 fingerprint of the client certificate in hex. An empty name
+// reports that detail as absent. Unparseable values are ignored.
+//
+// Only set it when every request comes through the edge and the edge
+// overwrites these headers, since a client reaching the service directly
+// could claim any transport. By default the connection state is used; see
+// [keysmith.ValidationRequest.TLSVersion].
+func WithTrustedTransportHeaders(versionHeader, certHeader string) Option {
+	return func(o *options) {
+		o.tlsVersionHeader = versionHeader
+		o.certHeader = certHeader
+	}
+}
+
+// WithTrustedProxies names the proxies, as CIDR ranges or single addresses
+// in /32 or /128 form, whose [ForwardedForHeader] gives the client IP that
+// policy AllowedIPs are checked against. For a request from a trusted
+// proxy, the header is read from the right, skipping trusted addresses, and
+// the first address that is not trusted is the client. A request from any
+// other peer is checked by its own address and the header is ignored, as a
+// client could set it to anything. By default the header is never read;
+// see [keysmith.ValidationRequest.ClientIP].
+func WithTrustedProxies(proxies ...netip.Prefix) Option {
+	return func(o *options) { o.trustedProxies = proxies }
+}
+
 // APIKeyAuth returns middleware that validates API keys from the
-// Authorization header (Bearer token) or X-API-Key header.
-func APIKeyAuth(eng *keysmith.Engine) func(http.Handler) http.Handler {
+// Authorization header (Bearer token) or X-API-Key header. Requests made
+// with a key that has debug capture turned on are sampled and recorded; see
+// [keysmith.Engine.SetKeyDebug]. When rate limits apply, the remaining key
+// and tenant budgets are set as X-RateLimit-Limit, X-RateLimit-Remaining,
+// X-RateLimit-Tenant-Limit, and X-RateLimit-Tenant-Remaining headers, and
+// X-RateLimit-Reduced-Until is set while adaptive limiting has reduced the
+// key's limit. When quotas apply, the quota headers of [SetQuotaHeaders] are
+// set. [ReadOnlyHeader] is set while the engine is read-only. The
+// connection's TLS version and client certificate are passed on for the
+// policy's and key's transport requirements. [RequireScopes] further down
+// the chain applies the rate limits of the scopes it requires through eng.
+//
+// eng is usually a [*keysmith.Engine]. Any other validator works too, such
+// as a mock in tests; debug capture, the read-only header, and scope rate
+// limits then apply only if it also has the engine's SampleCapture and
+// RecordCapture, ReadOnly, or CheckScopeRateLimits methods.
+func APIKeyAuth(eng keysmith.KeyValidator, opts ...Option) func(http.Handler) http.Handler {
+	o := newOptions(opts)
+	capt, _ := eng.(capturer)
+	ro, _ := eng.(readOnlyReporter)
+	var sl *scopeLimits
+	if limiter, ok := eng.(scopeRateLimiter); ok {
+		sl = &scopeLimits{limiter: limiter, headers: o.scopeRateLimitHeaders}
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			rawKey := extractKey(r)
+			if ro != nil && ro.ReadOnly() {
+				w.Header().Set(ReadOnlyHeader, "true")
+			}
+
+			rawKey := ExtractKey(r)
 			if rawKey == "" {
 				http.Error(w, `{"error":"missing API key"}`, http.StatusUnauthorized)
 				return
 			}
 
-			result, err := eng.ValidateKey(r.Context(), rawKey)
+			vctx := o.validationContext(r.Context(), r)
+			result, err := eng.ValidateKey(vctx, rawKey)
 			if err != nil {
 				code := http.StatusUnauthorized
 				switch {
-				case errors.Is(err, keysmith.ErrRateLimited):
+				case errors.Is(err, keysmith.ErrRateLimited),
+					errors.Is(err, keysmith.ErrTenantRateLimited),
+					errors.Is(err, keysmith.ErrQuotaExceeded):
 					code = http.StatusTooManyRequests
+				case errors.Is(err, keysmith.ErrEngineStopping),
+					errors.Is(err, keysmith.ErrAuthorizerUnavailable):
+					code = http.StatusServiceUnavailable
 				case errors.Is(err, keysmith.ErrKeyExpired),
 					errors.Is(err, keysmith.ErrKeyRevoked),
-					errors.Is(err, keysmith.ErrKeySuspended):
+					errors.Is(err, keysmith.ErrKeySuspended),
+					errors.Is(err, keysmith.ErrOriginNotAllowed),
+					errors.Is(err, keysmith.ErrIPNotAllowed),
+					errors.Is(err, keysmith.ErrMethodNotAllowed),
+					errors.Is(err, keysmith.ErrPathNotAllowed),
+					errors.Is(err, keysmith.ErrConsumerNotAllowed),
+					errors.Is(err, keysmith.ErrTransportNotAllowed),
+					errors.Is(err, keysmith.ErrClientCertMismatch),
+					errors.Is(err, keysmith.ErrAuthzDenied),
+					errors.Is(err, keysmith.ErrPrefixNotAccepted):
 					code = http.StatusForbidden
 				}
 				http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), code)
 				return
 			}
 
+			SetRateLimitHeaders(w.Header(), result.RateLimit)
+			SetQuotaHeaders(w.Header(), result.Quota)
+			SetRotationDueHeaders(w.Header(), result.RotationDue)
+			SetKeyDeprecationHeaders(w.Header(), result.KeyDeprecation)
+
+			if capt != nil && capt.SampleCapture(result.Key) {
+				c := capture.FromRequest(r, rawKey, o.captureHeaders, capture.DefaultBodyLimit)
+				_ = capt.RecordCapture(r.Context(), result.Key, rawKey, c)
+			}
+
 			ctx := context.WithValue(r.Context(), contextKey{}, result)
+			if sl != nil {
+				ctx = context.WithValue(ctx, scopeLimitsKey{}, sl)
+			}
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
 // RequireScopes returns middleware that checks the validated key has all
-// of the specified scopes.
+// of the specified scopes, and then that the key is within the rate limit
+// of each scope that has one; see [keysmith.Engine.CheckScopeRateLimits]. A
+// key over a scope's limit gets 429 with an error naming the scope.
 func RequireScopes(scopes ...string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -74,16 +356,222 @@ func RequireScopes(scopes ...string) func(http.Handler) http.Handler {
 				}
 			}
 
+			if sl, ok := r.Context().Value(scopeLimitsKey{}).(*scopeLimits); ok {
+				infos, err := sl.limiter.CheckScopeRateLimits(r.Context(), result, scopes...)
+				if err != nil {
+					code := http.StatusInternalServerError
+					if errors.Is(err, keysmith.ErrScopeRateLimited) {
+						code = http.StatusTooManyRequests
+					}
+					http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), code)
+					return
+				}
+				if sl.headers {
+					SetScopeRateLimitHeaders(w.Header(), infos)
+				}
+			}
+
 			next.ServeHTTP(w, r)
 		})
 	}
 }
 
-// extractKey extracts the API key from Authorization header or X-API-Key header.
-func extractKey(r *http.Request) string {
-	auth := r.Header.Get("Authorization")
-	if strings.HasPrefix(auth, "Bearer ") {
-		return strings.TrimPrefix(auth, "Bearer ")
+// SetRateLimitHeaders sets the X-RateLimit headers APIKeyAuth reports the
+// budgets left after a validation with. Headers for a limit that did not
+// apply are omitted.
+func SetRateLimitHeaders(h http.Header, rl *keysmith.RateLimitInfo) {
+	if rl == nil {
+		return
+	}
+	if rl.Limit > 0 {
+		h.Set("X-RateLimit-Limit", strconv.Itoa(rl.Limit))
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(rl.Remaining))
+	}
+	if rl.Penalty != nil {
+		h.Set("X-RateLimit-Reduced-Until", rl.Penalty.Until.UTC().Format(time.RFC3339))
+	}
+	if rl.TenantLimit > 0 {
+		h.Set("X-RateLimit-Tenant-Limit", strconv.Itoa(rl.TenantLimit))
+		h.Set("X-RateLimit-Tenant-Remaining", strconv.Itoa(rl.TenantRemaining))
+	}
+}
+
+// SetQuotaHeaders sets the X-Quota headers APIKeyAuth reports the quota
+// left after a validation with: X-Quota-Daily-Limit,
+// X-Quota-Daily-Remaining, X-Quota-Monthly-Limit, and
+// X-Quota-Monthly-Remaining for each quota that applied, and
+// X-Quota-Remaining with the smaller of the two remainders. It sets nothing
+// when q is nil.
+func SetQuotaHeaders(h http.Header, q *keysmith.QuotaInfo) {
+	if q == nil {
+		return
+	}
+	remaining := int64(-1)
+	if q.DailyLimit > 0 {
+		h.Set("X-Quota-Daily-Limit", strconv.FormatInt(q.DailyLimit, 10))
+		h.Set("X-Quota-Daily-Remaining", strconv.FormatInt(q.DailyRemaining, 10))
+		remaining = q.DailyRemaining
+	}
+	if q.MonthlyLimit > 0 {
+		h.Set("X-Quota-Monthly-Limit", strconv.FormatInt(q.MonthlyLimit, 10))
+		h.Set("X-Quota-Monthly-Remaining", strconv.FormatInt(q.MonthlyRemaining, 10))
+		if remaining < 0 || q.MonthlyRemaining < remaining {
+			remaining = q.MonthlyRemaining
+		}
+	}
+	if remaining >= 0 {
+		h.Set("X-Quota-Remaining", strconv.FormatInt(remaining, 10))
+	}
+}
+
+// SetRotationDueHeaders sets the rotation headers APIKeyAuth reminds the
+// caller of a key due for rotation with. It sets nothing when due is nil.
+func SetRotationDueHeaders(h http.Header, due *key.RotationDue) {
+	if due == nil {
+		return
+	}
+	h.Set(RotationDueHeader, due.DueAt.UTC().Format(time.RFC3339))
+	h.Set(RotationDaysRemainingHeader, strconv.Itoa(due.DaysRemaining))
+	if due.Overdue {
+		h.Set(RotationOverdueHeader, "true")
+	}
+}
+
+// SetKeyDeprecationHeaders sets the headers APIKeyAuth tells the caller
+// their key's format is deprecated with. It sets nothing when d is nil.
+func SetKeyDeprecationHeaders(h http.Header, d *key.FormatDeprecation) {
+	if d == nil {
+		return
+	}
+	h.Set(KeyDeprecatedHeader, d.Format)
+	if !d.Sunset.IsZero() {
+		h.Set(KeySunsetHeader, d.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if d.Link != "" {
+		h.Set(KeyDeprecationLinkHeader, d.Link)
+	}
+}
+
+// SetScopeRateLimitHeaders adds a [ScopeRateLimitHeader] value for each
+// scope limit RequireScopes applied.
+func SetScopeRateLimitHeaders(h http.Header, infos []keysmith.ScopeRateLimitInfo) {
+	for _, info := range infos {
+		h.Add(ScopeRateLimitHeader, fmt.Sprintf("%s; limit=%d; remaining=%d", info.Scope, info.Limit, info.Remaining))
+	}
+}
+
+// ExtractKey returns the API key from the first header APIKeyAuth reads that
+// holds one: the Authorization header (Bearer token) or the X-API-Key
+// header. It returns "" when r carries no key.
+func ExtractKey(r *http.Request) string {
+	for _, h := range keyHeaders {
+		if v, ok := strings.CutPrefix(r.Header.Get(h.name), h.scheme); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// ValidationContext returns ctx with the [keysmith.ValidationRequest] and
+// request ID that APIKeyAuth built with opts would validate r with, for
+// handlers that validate a key from r themselves.
+func ValidationContext(ctx context.Context, r *http.Request, opts ...Option) context.Context {
+	return newOptions(opts).validationContext(ctx, r)
+}
+
+func (o *options) validationContext(ctx context.Context, r *http.Request) context.Context {
+	vreq := keysmith.ValidationRequest{
+		Origin:         requestOrigin(r),
+		AcceptPrefixes: o.acceptPrefixes,
+		Method:         r.Method,
+		Path:           r.URL.Path,
+		RemoteAddr:     r.RemoteAddr,
+		ClientIP:       o.forwardedClientIP(r),
+	}
+	vreq.TLSVersion, vreq.ClientCertFingerprint = o.requestTransport(r)
+	if o.consumerHeader != "" {
+		vreq.ConsumerService = r.Header.Get(o.consumerHeader)
+	}
+	ctx = keysmith.WithValidationRequest(ctx, vreq)
+	if o.requestIDHeader != "" {
+		if reqID := r.Header.Get(o.requestIDHeader); reqID != "" {
+			ctx = keysmith.WithRequestID(ctx, reqID)
+		}
+	}
+	return ctx
+}
+
+// forwardedClientIP returns the client IP that [ForwardedForHeader] gives
+// for r when r comes from a trusted proxy, or "" to have the engine use
+// RemoteAddr. Hops are read from the right, since each proxy appends the
+// peer it saw; an unparseable hop ends the walk at the last trusted one.
+func (o *options) forwardedClientIP(r *http.Request) string {
+	if len(o.trustedProxies) == 0 {
+		return ""
+	}
+	ap, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil || !o.trusted(ap.Addr().Unmap()) {
+		return ""
+	}
+	var hops []string
+	for _, v := range r.Header.Values(ForwardedForHeader) {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+	client := ap.Addr().Unmap()
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		client = hop.Unmap()
+		if !o.trusted(client) {
+			break
+		}
+	}
+	return client.String()
+}
+
+// trusted reports whether ip is one of the trusted proxies.
+func (o *options) trusted(ip netip.Addr) bool {
+	for _, p := range o.trustedProxies {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestTransport returns the TLS version and client certificate
+// fingerprint of r: from the trusted transport headers when configured, and
+// from the connection state otherwise.
+func (o *options) requestTransport(r *http.Request) (version uint16, fingerprint string) {
+	if o.tlsVersionHeader != "" || o.certHeader != "" {
+		if o.tlsVersionHeader != "" {
+			version, _ = policy.ParseTLSVersion(r.Header.Get(o.tlsVersionHeader))
+		}
+		if o.certHeader != "" {
+			fingerprint, _ = key.NormalizeCertFingerprint(r.Header.Get(o.certHeader))
+		}
+		return version, fingerprint
+	}
+	if r.TLS == nil {
+		return 0, ""
+	}
+	if len(r.TLS.PeerCertificates) > 0 {
+		fingerprint = key.CertFingerprint(r.TLS.PeerCertificates[0].Raw)
+	}
+	return r.TLS.Version, fingerprint
+}
+
+// requestOrigin returns the Origin header, falling back to the scheme and
+// host of the Referer for browsers that omit Origin on same-origin GETs.
+func requestOrigin(r *http.Request) string {
+	if o := r.Header.Get("Origin"); o != "" && o != "null" {
+		return o
+	}
+	u, err := url.Parse(r.Header.Get("Referer"))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
 	}
-	return r.Header.Get("X-API-Key")
+	return u.Scheme + "://" + u.Host
 }
@@ -0,0 +1,814 @@
+package middleware_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/capture"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keysmithtest"
+	"github.com/xraph/keysmith/middleware"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/store/memory"
+	"github.com/xraph/keysmith/tenant"
+)
+
+func newKey(t *testing.T, eng *keysmith.Engine, rate float64) *key.CreateResult {
+	t.Helper()
+	ctx := keysmith.WithTenant(context.Background(), "app_test", "tenant_test")
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
+		Name:        "Integration",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+	})
+	require.NoError(t, err)
+	if rate > 0 {
+		_, err = eng.SetKeyDebug(ctx, created.Key.ID, rate, time.Hour)
+		require.NoError(t, err)
+	}
+	return created
+}
+
+func TestAPIKeyAuth_CapturesSampledRequests(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()))
+	require.NoError(t, err)
+	created := newKey(t, eng, 1)
+
+	var served string
+	h := middleware.APIKeyAuth(eng, middleware.WithCaptureHeaders("Content-Type", "Authorization", "X-Echo"))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			served = string(b)
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+
+	body := `{"amount":100,"echo":"` + created.RawKey + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/charges?api_key="+created.RawKey, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+created.RawKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Echo", created.RawKey)
+	req.Header.Set("X-Not-Listed", "dropped")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, body, served, "the handler still sees the full body")
+
+	captures, err := eng.ListCaptures(context.Background(), created.Key.ID)
+	require.NoError(t, err)
+	require.Len(t, captures, 1)
+	c := captures[0]
+	assert.Equal(t, http.MethodPost, c.Method)
+	assert.Equal(t, "/v1/charges", c.Path, "the query string is not captured")
+	assert.Equal(t, capture.Redacted, c.Headers["Authorization"])
+	assert.Equal(t, capture.Redacted, c.Headers["X-Echo"])
+	assert.Equal(t, "application/json", c.Headers["Content-Type"])
+	assert.NotContains(t, c.Headers, "X-Not-Listed")
+	assert.NotContains(t, c.Body, created.RawKey)
+}
+
+func TestAPIKeyAuth_CaptureRedactsKeyAcrossLimit(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()))
+	require.NoError(t, err)
+	created := newKey(t, eng, 1)
+
+	var served string
+	h := middleware.APIKeyAuth(eng)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		served = string(b)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	pad := strings.Repeat("x", capture.DefaultBodyLimit-len(created.RawKey)/2)
+	body := pad + created.RawKey + strings.Repeat("y", 64)
+	req := httptest.NewRequest(http.MethodPost, "/v1/charges", strings.NewReader(body))
+	req.Header.Set("X-API-Key", created.RawKey)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, body, served, "the handler still sees the full body")
+
+	captures, err := eng.ListCaptures(context.Background(), created.Key.ID)
+	require.NoError(t, err)
+	require.Len(t, captures, 1)
+	c := captures[0]
+	assert.True(t, c.BodyTruncated)
+	assert.Len(t, c.Body, capture.DefaultBodyLimit)
+	assert.True(t, strings.HasPrefix(c.Body, pad+capture.Redacted), "no part of the key is kept")
+}
+
+func TestAPIKeyAuth_SkipsKeysWithoutDebug(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()))
+	require.NoError(t, err)
+	created := newKey(t, eng, 0)
+
+	h := middleware.APIKeyAuth(eng)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/v1/things", nil)
+	req.Header.Set("X-API-Key", created.RawKey)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	captures, err := eng.ListCaptures(context.Background(), created.Key.ID)
+	require.NoError(t, err)
+	assert.Empty(t, captures)
+}
+
+func TestAPIKeyAuth_AllowedOrigins(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()))
+	require.NoError(t, err)
+	ctx := keysmith.WithTenant(context.Background(), "app_test", "tenant_test")
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
+		Name:           "Browser",
+		Prefix:         "pk",
+		Environment:    key.EnvTest,
+		AllowedOrigins: []string{"https://*.example.com"},
+	})
+	require.NoError(t, err)
+
+	h := middleware.APIKeyAuth(eng)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    int
+	}{
+		{"origin allowed", map[string]string{"Origin": "https://app.example.com"}, http.StatusOK},
+		{"referer fallback", map[string]string{"Referer": "https://app.example.com/page?x=1"}, http.StatusOK},
+		{"origin not allowed", map[string]string{"Origin": "https://evil.io"}, http.StatusForbidden},
+		{"no origin", nil, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/status", nil)
+			req.Header.Set("X-API-Key", created.RawKey)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
+
+func TestAPIKeyAuth_AllowedIPs(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()))
+	require.NoError(t, err)
+	ctx := keysmith.WithTenant(context.Background(), "app_test", "tenant_test")
+	pol := &policy.Policy{Name: "Office", AllowedIPs: []string{"203.0.113.0/24", "2001:db8::/32"}}
+	require.NoError(t, eng.CreatePolicy(ctx, pol))
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
+		Name:        "Office",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+		PolicyID:    &pol.ID,
+	})
+	require.NoError(t, err)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	direct := middleware.APIKeyAuth(eng)(ok)
+	proxied := middleware.APIKeyAuth(eng, middleware.WithTrustedProxies(
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("fd00::/8"),
+	))(ok)
+
+	tests := []struct {
+		name       string
+		h          http.Handler
+		remoteAddr string
+		forwarded  []string
+		want       int
+	}{
+		{"direct allowed", direct, "203.0.113.5:51234", nil, http.StatusOK},
+		{"direct IPv6 allowed", direct, "[2001:db8::7]:51234", nil, http.StatusOK},
+		{"direct refused", direct, "198.51.100.1:51234", nil, http.StatusForbidden},
+		{"header ignored by default", direct, "10.0.0.2:51234", []string{"203.0.113.5"}, http.StatusForbidden},
+		{"trusted proxy", proxied, "10.0.0.2:51234", []string{"203.0.113.5"}, http.StatusOK},
+		{"proxy chain", proxied, "10.0.0.2:51234", []string{"203.0.113.5, 10.1.1.1", "fd00::3"}, http.StatusOK},
+		{"spoofed hop left of client", proxied, "10.0.0.2:51234", []string{"203.0.113.5, 198.51.100.1"}, http.StatusForbidden},
+		{"untrusted peer", proxied, "198.51.100.1:51234", []string{"203.0.113.5"}, http.StatusForbidden},
+		{"proxy without header", proxied, "10.0.0.2:51234", nil, http.StatusForbidden},
+		{"unparseable hop", proxied, "10.0.0.2:51234", []string{"203.0.113.5, junk"}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/status", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-API-Key", created.RawKey)
+			for _, v := range tt.forwarded {
+				req.Header.Add(middleware.ForwardedForHeader, v)
+			}
+			rec := httptest.NewRecorder()
+			tt.h.ServeHTTP(rec, req)
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
+
+func TestAPIKeyAuth_AllowedRoutes(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()))
+	require.NoError(t, err)
+	ctx := keysmith.WithTenant(context.Background(), "app_test", "tenant_test")
+	pol := &policy.Policy{Name: "Readers", AllowedMethods: []string{"GET"}, AllowedPaths: []string{"/v1/users/*"}}
+	require.NoError(t, eng.CreatePolicy(ctx, pol))
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
+		Name:        "Reader",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+		PolicyID:    &pol.ID,
+	})
+	require.NoError(t, err)
+
+	h := middleware.APIKeyAuth(eng)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("X-API-Key", created.RawKey)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/v1/users/42?expand=keys").Code)
+
+	rec := serve(http.MethodDelete, "/v1/users/42")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), keysmith.ErrMethodNotAllowed.Error())
+
+	rec = serve(http.MethodGet, "/v1/admin")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), keysmith.ErrPathNotAllowed.Error())
+}
+
+func TestAPIKeyAuth_ConsumerHeader(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()))
+	require.NoError(t, err)
+	ctx := keysmith.WithTenant(context.Background(), "app_test", "tenant_test")
+	newConsumerKey := func(enforce bool) string {
+		created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
+			Name:             "Billing",
+			Prefix:           "sk",
+			Environment:      key.EnvTest,
+			IntendedConsumer: "billing-worker",
+			EnforceConsumer:  enforce,
+		})
+		require.NoError(t, err)
+		return created.RawKey
+	}
+	warnKey, enforceKey := newConsumerKey(false), newConsumerKey(true)
+
+	var mismatch bool
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		result, _ := middleware.ResultFromContext(r.Context())
+		mismatch = result.ConsumerMismatch
+		w.WriteHeader(http.StatusOK)
+	})
+	withHeader := middleware.APIKeyAuth(eng, middleware.WithConsumerHeader("X-Service-Name"))(next)
+	withoutHeader := middleware.APIKeyAuth(eng)(next)
+
+	tests := []struct {
+		name     string
+		h        http.Handler
+		rawKey   string
+		service  string
+		want     int
+		mismatch bool
+	}{
+		{"matching service", withHeader, enforceKey, "billing-worker", http.StatusOK, false},
+		{"mismatch warns", withHeader, warnKey, "reporting", http.StatusOK, true},
+		{"mismatch enforced", withHeader, enforceKey, "reporting", http.StatusForbidden, false},
+		{"header not configured", withoutHeader, enforceKey, "reporting", http.StatusOK, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mismatch = false
+			req := httptest.NewRequest(http.MethodGet, "/v1/status", nil)
+			req.Header.Set("X-API-Key", tt.rawKey)
+			req.Header.Set("X-Service-Name", tt.service)
+			rec := httptest.NewRecorder()
+			tt.h.ServeHTTP(rec, req)
+			assert.Equal(t, tt.want, rec.Code)
+			assert.Equal(t, tt.mismatch, mismatch)
+		})
+	}
+}
+
+func TestAPIKeyAuth_RequestID(t *testing.T) {
+	rec := keysmithtest.NewRecorder()
+	eng := keysmithtest.NewEngine(t, keysmith.WithExtension(rec))
+	created := keysmithtest.NewKey(eng).MustCreate(t, keysmithtest.Context())
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	serve := func(h http.Handler, header, reqID string) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/status", nil)
+		req.Header.Set("X-API-Key", created.RawKey)
+		req.Header.Set(header, reqID)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	serve(middleware.APIKeyAuth(eng)(next), "X-Request-ID", "req_default")
+	serve(middleware.APIKeyAuth(eng, middleware.WithRequestIDHeader("X-Trace"))(next), "X-Trace", "req_custom")
+	serve(middleware.APIKeyAuth(eng, middleware.WithRequestIDHeader(""))(next), "X-Request-ID", "req_ignored")
+
+	var got []string
+	for _, evt := range rec.Filter("KeyValidated") {
+		got = append(got, evt.Meta.RequestID)
+	}
+	assert.Equal(t, []string{"req_default", "req_custom", ""}, got)
+}
+
+// fixedLimiter allows limit requests per bucket and never resets.
+type fixedLimiter struct{ counts map[string]int }
+
+func (l *fixedLimiter) Allow(_ context.Context, bucket string, limit int, _ time.Duration) (bool, error) {
+	if l.counts[bucket] >= limit {
+		return false, nil
+	}
+	l.counts[bucket]++
+	return true, nil
+}
+
+func (l *fixedLimiter) Remaining(_ context.Context, bucket string, limit int, _ time.Duration) (int, error) {
+	return limit - l.counts[bucket], nil
+}
+
+func TestAPIKeyAuth_TenantRateLimit(t *testing.T) {
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithRateLimiter(&fixedLimiter{counts: make(map[string]int)}),
+	)
+	require.NoError(t, err)
+	ctx := keysmith.WithTenant(context.Background(), "app_test", "tenant_test")
+	require.NoError(t, eng.SetTenantSettings(ctx, &tenant.Settings{RateLimit: 2, RateLimitWindow: time.Minute}))
+	created := newKey(t, eng, 0)
+
+	h := middleware.APIKeyAuth(eng)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/status", nil)
+		req.Header.Set("X-API-Key", created.RawKey)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve()
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("X-RateLimit-Tenant-Limit"))
+	assert.Equal(t, "1", rec.Header().Get("X-RateLimit-Tenant-Remaining"))
+	assert.Empty(t, rec.Header().Get("X-RateLimit-Limit"), "the key has no limit of its own")
+
+	require.Equal(t, http.StatusOK, serve().Code)
+	rec = serve()
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Contains(t, rec.Body.String(), "tenant rate limit exceeded")
+}
+
+func TestAPIKeyAuth_QuotaHeaders(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()))
+	require.NoError(t, err)
+	ctx := keysmith.WithTenant(context.Background(), "app_test", "tenant_test")
+	pol := &policy.Policy{Name: "Metered", DailyQuota: 2, MonthlyQuota: 100}
+	require.NoError(t, eng.CreatePolicy(ctx, pol))
+	created := keysmithtest.NewKey(eng).WithPolicy(pol.ID).MustCreate(t, ctx)
+
+	h := middleware.APIKeyAuth(eng)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/status", nil)
+		req.Header.Set("X-API-Key", created.RawKey)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve()
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("X-Quota-Daily-Limit"))
+	assert.Equal(t, "1", rec.Header().Get("X-Quota-Daily-Remaining"))
+	assert.Equal(t, "100", rec.Header().Get("X-Quota-Monthly-Limit"))
+	assert.Equal(t, "99", rec.Header().Get("X-Quota-Monthly-Remaining"))
+	assert.Equal(t, "1", rec.Header().Get("X-Quota-Remaining"), "the tighter of the two")
+
+	rec = serve()
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get("X-Quota-Remaining"))
+	rec = serve()
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Contains(t, rec.Body.String(), "quota exceeded")
+}
+
+func TestRequireScopes_ScopeRateLimit(t *testing.T) {
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithRateLimiter(&fixedLimiter{counts: make(map[string]int)}),
+	)
+	require.NoError(t, err)
+	ctx := keysmith.WithTenant(context.Background(), "app_test", "tenant_test")
+	require.NoError(t, eng.CreateScope(ctx, &scope.Scope{Name: "export", RateLimit: 1, RateLimitWindow: time.Minute}))
+	require.NoError(t, eng.CreateScope(ctx, &scope.Scope{Name: "read"}))
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
+		Name:        "Exporter",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+		Scopes:      []string{"export", "read"},
+	})
+	require.NoError(t, err)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	mux := http.NewServeMux()
+	mux.Handle("/export", middleware.RequireScopes("read", "export")(ok))
+	mux.Handle("/read", middleware.RequireScopes("read")(ok))
+	h := middleware.APIKeyAuth(eng, middleware.WithScopeRateLimitHeaders())(mux)
+	serve := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-API-Key", created.RawKey)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("/export")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"export; limit=1; remaining=0"}, rec.Header().Values(middleware.ScopeRateLimitHeader))
+
+	rec = serve("/export")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Contains(t, rec.Body.String(), "scope rate limit exceeded: export")
+
+	rec = serve("/read")
+	assert.Equal(t, http.StatusOK, rec.Code, "routes not requiring the scope are unaffected")
+	assert.Empty(t, rec.Header().Values(middleware.ScopeRateLimitHeader))
+}
+
+func TestAPIKeyAuth_ReadOnlyHeader(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()))
+	require.NoError(t, err)
+	created := newKey(t, eng, 0)
+
+	h := middleware.APIKeyAuth(eng)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(rawKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/status", nil)
+		req.Header.Set("X-API-Key", rawKey)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Empty(t, serve(created.RawKey).Header().Get(middleware.ReadOnlyHeader))
+
+	eng.SetReadOnly(true)
+	rec := serve(created.RawKey)
+	assert.Equal(t, http.StatusOK, rec.Code, "keys still validate")
+	assert.Equal(t, "true", rec.Header().Get(middleware.ReadOnlyHeader))
+	rec = serve("sk_test_unknown")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get(middleware.ReadOnlyHeader), "rejections carry it too")
+}
+
+func TestAPIKeyAuth_RotationDueHeaders(t *testing.T) {
+	now := time.Now()
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithClock(func() time.Time { return now }))
+	require.NoError(t, err)
+	ctx := keysmith.WithTenant(context.Background(), "app_test", "tenant_test")
+	pol := &policy.Policy{Name: "Rotating", RotationPeriod: 10 * 24 * time.Hour}
+	require.NoError(t, eng.CreatePolicy(ctx, pol))
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
+		Name: "Rotating", Prefix: "sk", Environment: key.EnvTest, PolicyID: &pol.ID,
+	})
+	require.NoError(t, err)
+
+	h := middleware.APIKeyAuth(eng)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(at time.Time) http.Header {
+		now = at
+		req := httptest.NewRequest(http.MethodGet, "/v1/status", nil)
+		req.Header.Set("X-API-Key", created.RawKey)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		return rec.Header()
+	}
+
+	assert.Empty(t, serve(now).Get(middleware.RotationDueHeader))
+
+	dueAt := created.Key.CreatedAt.Add(10 * 24 * time.Hour)
+	hdr := serve(dueAt.Add(-36 * time.Hour))
+	assert.Equal(t, dueAt.UTC().Format(time.RFC3339), hdr.Get(middleware.RotationDueHeader))
+	assert.Equal(t, "1", hdr.Get(middleware.RotationDaysRemainingHeader))
+	assert.Empty(t, hdr.Get(middleware.RotationOverdueHeader))
+
+	hdr = serve(dueAt.Add(time.Hour))
+	assert.Equal(t, "0", hdr.Get(middleware.RotationDaysRemainingHeader))
+	assert.Equal(t, "true", hdr.Get(middleware.RotationOverdueHeader))
+}
+
+func TestAPIKeyAuth_KeyDeprecationHeaders(t *testing.T) {
+	sunset := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()),
+		keysmith.WithKeyFormatDeprecation(keysmith.KeyFormatDefault, keysmith.KeyFormatDeprecation{
+			Sunset: sunset, Link: "https://example.com/migrate",
+		}),
+	)
+	require.NoError(t, err)
+	ctx := keysmith.WithTenant(context.Background(), "app_test", "tenant_test")
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Old", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+
+	h := middleware.APIKeyAuth(eng)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/v1/status", nil)
+	req.Header.Set("X-API-Key", created.RawKey)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	assert.Equal(t, keysmith.KeyFormatDefault, rec.Header().Get(middleware.KeyDeprecatedHeader))
+	assert.Equal(t, "Tue, 01 Jan 2030 00:00:00 GMT", rec.Header().Get(middleware.KeySunsetHeader))
+	assert.Equal(t, "https://example.com/migrate", rec.Header().Get(middleware.KeyDeprecationLinkHeader))
+}
+
+func TestAPIKeyAuth_AcceptPrefixes(t *testing.T) {
+	eng := keysmithtest.NewEngine(t)
+	created := newKey(t, eng, 0)
+
+	serve := func(prefixes ...string) int {
+		h := middleware.APIKeyAuth(eng, middleware.WithAcceptPrefixes(prefixes...))(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/v1/status", nil)
+		req.Header.Set("X-API-Key", created.RawKey)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, serve("pk"))
+	assert.Equal(t, http.StatusOK, serve("pk", "sk"))
+	assert.Equal(t, http.StatusOK, serve())
+}
+
+func TestAPIKeyAuth_MockValidator(t *testing.T) {
+	k := &key.Key{Name: "Mocked", TenantID: "tenant_test"}
+	mock := &keysmithtest.MockService{
+		ValidateKeyFunc: func(_ context.Context, rawKey string) (*keysmith.ValidationResult, error) {
+			if rawKey != "sk_test_mocked" {
+				return nil, keysmith.ErrKeyRevoked
+			}
+			return &keysmith.ValidationResult{Key: k}, nil
+		},
+	}
+
+	var got *keysmith.ValidationResult
+	h := middleware.APIKeyAuth(mock)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = middleware.ResultFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(raw string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/status", nil)
+		req.Header.Set("X-API-Key", raw)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("sk_test_mocked")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	require.NotNil(t, got)
+	assert.Same(t, k, got.Key)
+	assert.Empty(t, rec.Header().Get(middleware.ReadOnlyHeader))
+
+	assert.Equal(t, http.StatusForbidden, serve("sk_test_other").Code)
+	assert.Equal(t, 2, mock.Calls("ValidateKey"))
+}
+
+func TestHeaders(t *testing.T) {
+	assert.Equal(t, []middleware.Header{
+		{Name: "Authorization", Value: "Bearer {key}", Purpose: "key"},
+		{Name: "X-API-Key", Value: "{key}", Purpose: "key"},
+		{Name: "X-Service-Name", Value: "{value}", Purpose: "consumer"},
+		{Name: "X-Forwarded-TLS-Version", Value: "{value}", Purpose: "tls_version"},
+		{Name: "X-Client-Cert-Fingerprint", Value: "{value}", Purpose: "client_cert"},
+		{Name: "X-Forwarded-For", Value: "{value}", Purpose: "client_ip"},
+		{Name: "X-Trace-ID", Value: "{value}", Purpose: "request_id"},
+	}, middleware.Headers(
+		middleware.WithConsumerHeader("X-Service-Name"),
+		middleware.WithTrustedTransportHeaders("X-Forwarded-TLS-Version", "X-Client-Cert-Fingerprint"),
+		middleware.WithTrustedProxies(netip.MustParsePrefix("10.0.0.0/8")),
+		middleware.WithRequestIDHeader("X-Trace-ID"),
+	))
+
+	assert.Len(t, middleware.Headers(middleware.WithRequestIDHeader("")), 2)
+}
+
+func TestHeaders_EachKeyHeaderAuthenticates(t *testing.T) {
+	eng := keysmithtest.NewEngine(t)
+	created := newKey(t, eng, 0)
+	h := middleware.APIKeyAuth(eng)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, hdr := range middleware.Headers() {
+		if hdr.Purpose != "key" {
+			continue
+		}
+		req := httptest.NewRequest(http.MethodGet, "/v1/status", nil)
+		req.Header.Set(hdr.Name, strings.ReplaceAll(hdr.Value, "{key}", created.RawKey))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code, hdr.Name)
+	}
+}
+
+// newClientCert returns a self-signed client certificate and its
+// fingerprint.
+func newClientCert(t *testing.T) (tls.Certificate, string) {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "billing-worker"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &priv.PublicKey, priv)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: priv}, key.CertFingerprint(der)
+}
+
+// newTLSServer starts a TLS server running h with cfg applied to its
+// configuration.
+func newTLSServer(t *testing.T, h http.Handler, cfg func(*tls.Config)) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(h)
+	srv.TLS = &tls.Config{}
+	if cfg != nil {
+		cfg(srv.TLS)
+	}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// getStatus sends a GET with rawKey to url using client and returns the
+// response status.
+func getStatus(t *testing.T, client *http.Client, url, rawKey string, headers map[string]string) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url+"/v1/status", nil)
+	require.NoError(t, err)
+	req.Header.Set("X-API-Key", rawKey)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestAPIKeyAuth_MinTLSVersion(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()))
+	require.NoError(t, err)
+	ctx := keysmith.WithTenant(context.Background(), "app_test", "tenant_test")
+	pol := &policy.Policy{Name: "TLS 1.3", MinTLSVersion: "1.3"}
+	require.NoError(t, eng.CreatePolicy(ctx, pol))
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Strict", Prefix: "sk", Environment: key.EnvTest, PolicyID: &pol.ID})
+	require.NoError(t, err)
+
+	h := middleware.APIKeyAuth(eng)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tls13 := newTLSServer(t, h, nil)
+	assert.Equal(t, http.StatusOK, getStatus(t, tls13.Client(), tls13.URL, created.RawKey, nil))
+
+	tls12 := newTLSServer(t, h, func(c *tls.Config) { c.MaxVersion = tls.VersionTLS12 })
+	assert.Equal(t, http.StatusForbidden, getStatus(t, tls12.Client(), tls12.URL, created.RawKey, nil))
+
+	plain := httptest.NewServer(h)
+	t.Cleanup(plain.Close)
+	assert.Equal(t, http.StatusForbidden, getStatus(t, plain.Client(), plain.URL, created.RawKey, nil))
+}
+
+func TestAPIKeyAuth_PinnedClientCert(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()))
+	require.NoError(t, err)
+	pinned, fingerprint := newClientCert(t)
+	other, _ := newClientCert(t)
+	ctx := keysmith.WithTenant(context.Background(), "app_test", "tenant_test")
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Pinned", Prefix: "sk", Environment: key.EnvTest, CertFingerprint: fingerprint})
+	require.NoError(t, err)
+
+	h := middleware.APIKeyAuth(eng)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	srv := newTLSServer(t, h, func(c *tls.Config) { c.ClientAuth = tls.RequestClientCert })
+
+	// Each client gets its own transport, so connections made with one
+	// certificate are not reused for another.
+	clientWith := func(certs ...tls.Certificate) *http.Client {
+		tr := srv.Client().Transport.(*http.Transport).Clone()
+		tr.TLSClientConfig.Certificates = certs
+		t.Cleanup(tr.CloseIdleConnections)
+		return &http.Client{Transport: tr}
+	}
+	assert.Equal(t, http.StatusOK, getStatus(t, clientWith(pinned), srv.URL, created.RawKey, nil))
+	assert.Equal(t, http.StatusForbidden, getStatus(t, clientWith(other), srv.URL, created.RawKey, nil))
+	assert.Equal(t, http.StatusForbidden, getStatus(t, clientWith(), srv.URL, created.RawKey, nil))
+}
+
+func TestAPIKeyAuth_TrustedTransportHeaders(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()))
+	require.NoError(t, err)
+	ctx := keysmith.WithTenant(context.Background(), "app_test", "tenant_test")
+	pol := &policy.Policy{Name: "mTLS", MinTLSVersion: "1.3", RequireMTLS: true}
+	require.NoError(t, eng.CreatePolicy(ctx, pol))
+	_, fingerprint := newClientCert(t)
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Edge", Prefix: "sk", Environment: key.EnvTest, PolicyID: &pol.ID, CertFingerprint: fingerprint})
+	require.NoError(t, err)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	trusted := httptest.NewServer(middleware.APIKeyAuth(eng,
+		middleware.WithTrustedTransportHeaders("X-Forwarded-TLS-Version", "X-Client-Cert-Fingerprint"))(next))
+	t.Cleanup(trusted.Close)
+	untrusted := httptest.NewServer(middleware.APIKeyAuth(eng)(next))
+	t.Cleanup(untrusted.Close)
+
+	edge := map[string]string{"X-Forwarded-TLS-Version": "TLSv1.3", "X-Client-Cert-Fingerprint": strings.ToUpper(fingerprint)}
+	assert.Equal(t, http.StatusOK, getStatus(t, trusted.Client(), trusted.URL, created.RawKey, edge))
+	assert.Equal(t, http.StatusForbidden, getStatus(t, trusted.Client(), trusted.URL, created.RawKey, map[string]string{"X-Forwarded-TLS-Version": "TLSv1.3"}))
+	assert.Equal(t, http.StatusForbidden, getStatus(t, untrusted.Client(), untrusted.URL, created.RawKey, edge), "headers are ignored unless trusted")
+}
+
+func TestAPIKeyAuth_Authorizer(t *testing.T) {
+	var got keysmith.AuthzRequest
+	var down bool
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()),
+		keysmith.WithAuthorizer(keysmith.AuthorizerFunc(func(_ context.Context, in *keysmith.AuthzInput) (keysmith.Decision, error) {
+			got = in.Request
+			if down {
+				return keysmith.Decision{}, errors.New("connection refused")
+			}
+			return keysmith.Decision{Allow: in.Request.Method == http.MethodGet}, nil
+		})))
+	require.NoError(t, err)
+	created := newKey(t, eng, 0)
+
+	srv := httptest.NewServer(middleware.APIKeyAuth(eng)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	t.Cleanup(srv.Close)
+
+	send := func(method string) int {
+		req, err := http.NewRequest(method, srv.URL+"/v1/orders", http.NoBody)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+created.RawKey)
+		resp, err := srv.Client().Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Equal(t, http.StatusOK, send(http.MethodGet))
+	assert.Equal(t, "/v1/orders", got.Path)
+	assert.Equal(t, "127.0.0.1", got.RemoteIP)
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPost))
+
+	down = true
+	assert.Equal(t, http.StatusServiceUnavailable, send(http.MethodGet))
+}
@@ -0,0 +1,60 @@
+package notifyhook
+
+import (
+	"context"
+
+	audithook "github.com/xraph/keysmith/audit_hook"
+	"github.com/xraph/keysmith/id"
+)
+
+// Audit action constants for delivery attempts.
+const (
+	ActionNotificationDelivered   = "keysmith.notification.delivered"
+	ActionNotificationFailed      = "keysmith.notification.failed"
+	ActionNotificationRateLimited = "keysmith.notification.rate_limited"
+	ActionNotificationNoSender    = "keysmith.notification.no_sender"
+)
+
+// AuditRecorder returns a DeliveryRecorder that writes each attempt to an
+// audit backend as an event on the key, so deliveries appear in the key's
+// audit history next to the event that caused them.
+func AuditRecorder(r audithook.Recorder) DeliveryRecorder {
+	return auditRecorder{r: r}
+}
+
+type auditRecorder struct {
+	r audithook.Recorder
+}
+
+func (a auditRecorder) RecordDelivery(ctx context.Context, d *Delivery) error {
+	ev := &audithook.AuditEvent{
+		ID:         id.NewEventID().String(),
+		Timestamp:  d.At,
+		Resource:   audithook.ResourceKey,
+		Category:   audithook.CategoryKeyLifecycle,
+		ResourceID: d.KeyID,
+		Outcome:    audithook.OutcomeSuccess,
+		Severity:   audithook.SeverityInfo,
+		Reason:     d.Error,
+		Metadata: map[string]any{
+			"notification_id": d.NotificationID,
+			"event":           d.Event,
+			"tenant_id":       d.TenantID,
+			"contact_type":    string(d.Contact.Type),
+			"contact_target":  d.Contact.Target,
+		},
+	}
+	switch d.Status {
+	case StatusDelivered:
+		ev.Action = ActionNotificationDelivered
+	case StatusRateLimited:
+		ev.Action = ActionNotificationRateLimited
+	case StatusNoSender:
+		ev.Action = ActionNotificationNoSender
+		ev.Outcome, ev.Severity = audithook.OutcomeFailure, audithook.SeverityWarning
+	default:
+		ev.Action = ActionNotificationFailed
+		ev.Outcome, ev.Severity = audithook.OutcomeFailure, audithook.SeverityWarning
+	}
+	return a.r.Record(ctx, ev)
+}
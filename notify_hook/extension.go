@@ -0,0 +1,405 @@
+// Package notifyhook routes Keysmith key lifecycle events to the people and
+// systems responsible for each key. A notification goes to the key's own
+// contacts, or to its tenant's default contacts when the key has none, and
+// is handed to the [Sender] registered for each contact type. SMTP and
+// generic webhook senders are provided; Slack and others plug in through
+// the interface.
+//
+// Sends are rate limited per contact, so a burst of events does not flood
+// one mailbox or channel, and every attempt is reported to an optional
+// [DeliveryRecorder], such as an audit trail.
+package notifyhook
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/xraph/go-utils/log"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/tenant"
+)
+
+// Compile-time interface checks.
+var (
+	_ plugin.Plugin                    = (*Extension)(nil)
+	_ plugin.KeyExpiredV2              = (*Extension)(nil)
+	_ plugin.KeyRotatedV2              = (*Extension)(nil)
+	_ plugin.KeyCompromisedV2          = (*Extension)(nil)
+	_ plugin.KeyQuotaForecastWarningV2 = (*Extension)(nil)
+	_ plugin.KeyAdaptivelyLimitedV2    = (*Extension)(nil)
+	_ plugin.KeyConsumerMismatchV2     = (*Extension)(nil)
+	_ plugin.KeyRotationOverdueV2      = (*Extension)(nil)
+	_ plugin.KeyFormatDeprecatedV2     = (*Extension)(nil)
+)
+
+// Event type constants, used as [Notification.Event] and with [WithEvents].
+const (
+	EventKeyExpired              = "keysmith.key.expired"
+	EventKeyRotated              = "keysmith.key.rotated"
+	EventKeyCompromised          = "keysmith.key.compromised"
+	EventKeyQuotaForecastWarning = "keysmith.key.quota_forecast_warning"
+	EventKeyAdaptivelyLimited    = "keysmith.key.adaptively_limited"
+	EventKeyConsumerMismatch     = "keysmith.key.consumer_mismatch"
+	EventKeyRotationOverdue      = "keysmith.key.rotation_overdue"
+	EventKeyFormatDeprecated     = "keysmith.key.format_deprecated"
+)
+
+// DefaultRateLimit applies to contact types without a [WithRateLimit].
+var DefaultRateLimit = RateLimit{Max: 10, Per: time.Hour}
+
+// Notification is a message about one key.
+type Notification struct {
+	ID         string         `json:"id"`
+	Event      string         `json:"event"`
+	Time       time.Time      `json:"time"`
+	TenantID   string         `json:"tenant_id,omitempty"`
+	AppID      string         `json:"app_id,omitempty"`
+	KeyID      string         `json:"key_id"`
+	KeyName    string         `json:"key_name,omitempty"`
+	KeyHint    string         `json:"key_hint,omitempty"`
+	ReasonCode string         `json:"reason_code,omitempty"`
+	Subject    string         `json:"subject"`
+	Message    string         `json:"message"`
+	Data       map[string]any `json:"data,omitempty"`
+}
+
+// Sender delivers a notification to one contact. Senders are registered
+// per contact type with [WithSender].
+type Sender interface {
+	Send(ctx context.Context, c key.Contact, n *Notification) error
+}
+
+// SenderFunc is an adapter to use a plain function as a Sender.
+type SenderFunc func(ctx context.Context, c key.Contact, n *Notification) error
+
+// Send implements Sender.
+func (f SenderFunc) Send(ctx context.Context, c key.Contact, n *Notification) error {
+	return f(ctx, c, n)
+}
+
+// SettingsSource looks up tenant settings for their default contacts.
+// [tenant.Store] implements it.
+type SettingsSource interface {
+	GetSettings(ctx context.Context, tenantID string) (*tenant.Settings, error)
+}
+
+// RateLimit allows at most Max notifications to one contact per Per. A zero
+// Max disables the limit.
+type RateLimit struct {
+	Max int
+	Per time.Duration
+}
+
+// Status is the outcome of one delivery attempt.
+type Status string
+
+// Delivery statuses.
+const (
+	StatusDelivered   Status = "delivered"
+	StatusFailed      Status = "failed"
+	StatusRateLimited Status = "rate_limited"
+	StatusNoSender    Status = "no_sender"
+)
+
+// Delivery records one attempt to notify one contact.
+type Delivery struct {
+	NotificationID string      `json:"notification_id"`
+	Event          string      `json:"event"`
+	TenantID       string      `json:"tenant_id,omitempty"`
+	KeyID          string      `json:"key_id"`
+	Contact        key.Contact `json:"contact"`
+	Status         Status      `json:"status"`
+	Error          string      `json:"error,omitempty"`
+	At             time.Time   `json:"at"`
+}
+
+// DeliveryRecorder receives every delivery attempt, so a key's notification
+// history can be shown alongside its other events. See [AuditRecorder].
+type DeliveryRecorder interface {
+	RecordDelivery(ctx context.Context, d *Delivery) error
+}
+
+// Extension sends notifications about key lifecycle events.
+type Extension struct {
+	settings SettingsSource
+	senders  map[key.ContactType]Sender
+	limits   map[key.ContactType]RateLimit
+	recorder DeliveryRecorder
+	enabled  map[string]bool
+	timeout  time.Duration
+	logger   log.Logger
+	now      func() time.Time
+
+	mu   sync.Mutex
+	sent map[key.Contact][]time.Time
+}
+
+// New creates an Extension. settings supplies tenant default contacts and
+// may be nil, in which case only keys with contacts of their own are
+// notified.
+func New(settings SettingsSource, opts ...Option) *Extension {
+	e := &Extension{
+		settings: settings,
+		senders:  make(map[key.ContactType]Sender),
+		limits:   make(map[key.ContactType]RateLimit),
+		timeout:  DefaultSendTimeout,
+		logger:   log.NewNoopLogger(),
+		now:      time.Now,
+		sent:     make(map[key.Contact][]time.Time),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Name implements plugin.Plugin.
+func (e *Extension) Name() string { return "notify-hook" }
+
+// OnKeyExpiredV2 implements plugin.KeyExpiredV2.
+func (e *Extension) OnKeyExpiredV2(ctx context.Context, k *key.Key, meta plugin.EventMeta) error {
+	n := newNotification(EventKeyExpired, k, meta)
+	n.Subject = fmt.Sprintf("API key %q has expired", keyLabel(k))
+	n.Message = fmt.Sprintf("The API key %q (%s) has expired and no longer validates. Create or rotate a key to restore access.", keyLabel(k), k.ID)
+	e.notify(ctx, k, n)
+	return nil
+}
+
+// OnKeyRotatedV2 implements plugin.KeyRotatedV2.
+func (e *Extension) OnKeyRotatedV2(ctx context.Context, k *key.Key, rec *rotation.Record, meta plugin.EventMeta) error {
+	n := newNotification(EventKeyRotated, k, meta)
+	n.Subject = fmt.Sprintf("API key %q was rotated", keyLabel(k))
+	n.Message = fmt.Sprintf("The API key %q (%s) was rotated (%s).", keyLabel(k), k.ID, rec.Reason)
+	if !rec.GraceEnds.IsZero() {
+		n.Message += fmt.Sprintf(" The previous key stops validating at %s.", rec.GraceEnds.UTC().Format(time.RFC3339))
+	}
+	n.Data = map[string]any{"reason": rec.Reason, "grace_ends": rec.GraceEnds}
+	e.notify(ctx, k, n)
+	return nil
+}
+
+// OnKeyCompromisedV2 implements plugin.KeyCompromisedV2.
+func (e *Extension) OnKeyCompromisedV2(ctx context.Context, k *key.Key, report *key.CompromiseReport, meta plugin.EventMeta) error {
+	n := newNotification(EventKeyCompromised, k, meta)
+	n.Subject = fmt.Sprintf("API key %q was reported compromised", keyLabel(k))
+	n.Message = fmt.Sprintf("The API key %q (%s) was reported leaked by %s and was %s.", keyLabel(k), k.ID, report.Source, actionPast(report.Action))
+	n.Data = map[string]any{"source": report.Source, "action": report.Action}
+	e.notify(ctx, k, n)
+	return nil
+}
+
+// OnKeyQuotaForecastWarningV2 implements plugin.KeyQuotaForecastWarningV2.
+func (e *Extension) OnKeyQuotaForecastWarningV2(ctx context.Context, k *key.Key, f *key.QuotaForecast, meta plugin.EventMeta) error {
+	n := newNotification(EventKeyQuotaForecastWarning, k, meta)
+	n.Subject = fmt.Sprintf("API key %q is projected to exceed its monthly quota", keyLabel(k))
+	n.Message = fmt.Sprintf("The API key %q (%s) has used %d of its %d monthly requests and is projected to reach %d.", keyLabel(k), k.ID, f.Used, f.Quota, f.ProjectedTotal)
+	if f.ProjectedExhaustion != nil {
+		n.Message += fmt.Sprintf(" At the current rate the quota runs out at %s.", f.ProjectedExhaustion.UTC().Format(time.RFC3339))
+	}
+	n.Data = map[string]any{"quota": f.Quota, "used": f.Used, "projected_total": f.ProjectedTotal}
+	e.notify(ctx, k, n)
+	return nil
+}
+
+// OnKeyAdaptivelyLimitedV2 implements plugin.KeyAdaptivelyLimitedV2.
+func (e *Extension) OnKeyAdaptivelyLimitedV2(ctx context.Context, k *key.Key, p *key.AdaptivePenalty, meta plugin.EventMeta) error {
+	n := newNotification(EventKeyAdaptivelyLimited, k, meta)
+	n.Subject = fmt.Sprintf("API key %q is being throttled", keyLabel(k))
+	n.Message = fmt.Sprintf("%.0f%% of recent requests with the API key %q (%s) failed, so its rate limit was lowered from %d to %d until %s.",
+		p.ErrorRatio*100, keyLabel(k), k.ID, p.BaseLimit, p.Limit, p.Until.UTC().Format(time.RFC3339))
+	n.Data = map[string]any{"error_ratio": p.ErrorRatio, "base_limit": p.BaseLimit, "limit": p.Limit}
+	e.notify(ctx, k, n)
+	return nil
+}
+
+// OnKeyConsumerMismatchV2 implements plugin.KeyConsumerMismatchV2.
+func (e *Extension) OnKeyConsumerMismatchV2(ctx context.Context, k *key.Key, service string, meta plugin.EventMeta) error {
+	n := newNotification(EventKeyConsumerMismatch, k, meta)
+	n.Subject = fmt.Sprintf("API key %q was used by an unexpected service", keyLabel(k))
+	n.Message = fmt.Sprintf("The API key %q (%s), issued to %s, was presented by %s.", keyLabel(k), k.ID, k.IntendedConsumer, service)
+	n.Data = map[string]any{"intended_consumer": k.IntendedConsumer, "consumer_service": service}
+	e.notify(ctx, k, n)
+	return nil
+}
+
+// OnKeyRotationOverdueV2 implements plugin.KeyRotationOverdueV2.
+func (e *Extension) OnKeyRotationOverdueV2(ctx context.Context, k *key.Key, due *key.RotationDue, meta plugin.EventMeta) error {
+	n := newNotification(EventKeyRotationOverdue, k, meta)
+	n.Subject = fmt.Sprintf("API key %q is overdue for rotation", keyLabel(k))
+	n.Message = fmt.Sprintf("The API key %q (%s) was due for rotation at %s and is still in use.", keyLabel(k), k.ID, due.DueAt.UTC().Format(time.RFC3339))
+	n.Data = map[string]any{"due_at": due.DueAt, "days_remaining": due.DaysRemaining}
+	e.notify(ctx, k, n)
+	return nil
+}
+
+// OnKeyFormatDeprecatedV2 implements plugin.KeyFormatDeprecatedV2.
+func (e *Extension) OnKeyFormatDeprecatedV2(ctx context.Context, k *key.Key, d *key.FormatDeprecation, meta plugin.EventMeta) error {
+	n := newNotification(EventKeyFormatDeprecated, k, meta)
+	n.Subject = fmt.Sprintf("API key %q uses a deprecated key format", keyLabel(k))
+	n.Message = fmt.Sprintf("The API key %q (%s) is in the deprecated %s key format and should be replaced.", keyLabel(k), k.ID, d.Format)
+	if !d.Sunset.IsZero() {
+		n.Message += fmt.Sprintf(" Keys in this format are planned to stop working at %s.", d.Sunset.UTC().Format(time.RFC3339))
+	}
+	n.Data = map[string]any{"format": d.Format, "since": d.Since, "sunset": d.Sunset, "link": d.Link}
+	e.notify(ctx, k, n)
+	return nil
+}
+
+// Notify sends n to the contacts of k, for events the engine does not
+// raise a hook for. Event, Subject, and Message should be set; ID, Time,
+// and the key fields are filled in when empty. It returns one delivery per
+// contact.
+func (e *Extension) Notify(ctx context.Context, k *key.Key, n *Notification) []*Delivery {
+	fillNotification(n, k, e.now())
+	return e.deliver(ctx, k, n)
+}
+
+// Contacts returns the contacts notifications about k go to.
+func (e *Extension) Contacts(ctx context.Context, k *key.Key) key.Contacts {
+	if len(k.Contacts) > 0 || e.settings == nil {
+		return k.Contacts
+	}
+	ts, err := e.settings.GetSettings(ctx, k.TenantID)
+	if err != nil {
+		// Stores report missing settings, the common case, as an error
+		// without a shared sentinel, so no error is treated as special.
+		return nil
+	}
+	return key.EffectiveContacts(k, ts.DefaultContacts)
+}
+
+func (e *Extension) notify(ctx context.Context, k *key.Key, n *Notification) {
+	if e.enabled != nil && !e.enabled[n.Event] {
+		return
+	}
+	e.deliver(ctx, k, n)
+}
+
+func (e *Extension) deliver(ctx context.Context, k *key.Key, n *Notification) []*Delivery {
+	contacts := e.Contacts(ctx, k)
+	deliveries := make([]*Delivery, 0, len(contacts))
+	for _, c := range contacts {
+		d := e.send(ctx, c, n)
+		if e.recorder != nil {
+			if err := e.recorder.RecordDelivery(ctx, d); err != nil {
+				e.logger.Warn("notify_hook: failed to record delivery",
+					log.String("notification_id", n.ID),
+					log.Any("error", err),
+				)
+			}
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries
+}
+
+func (e *Extension) send(ctx context.Context, c key.Contact, n *Notification) *Delivery {
+	d := &Delivery{
+		NotificationID: n.ID,
+		Event:          n.Event,
+		TenantID:       n.TenantID,
+		KeyID:          n.KeyID,
+		Contact:        c,
+		At:             e.now(),
+	}
+	sender, ok := e.senders[c.Type]
+	if !ok {
+		d.Status = StatusNoSender
+		return d
+	}
+	if !e.allow(c, d.At) {
+		d.Status = StatusRateLimited
+		return d
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+	if err := sender.Send(sendCtx, c, n); err != nil {
+		e.logger.Warn("notify_hook: failed to send notification",
+			log.String("event", n.Event),
+			log.String("contact_type", string(c.Type)),
+			log.Any("error", err),
+		)
+		d.Status, d.Error = StatusFailed, err.Error()
+		return d
+	}
+	d.Status = StatusDelivered
+	return d
+}
+
+// allow reports whether c may be sent another notification at now, and
+// counts it if so. Attempts that fail still count, so an unreachable
+// endpoint is not retried on every event.
+func (e *Extension) allow(c key.Contact, now time.Time) bool {
+	limit, ok := e.limits[c.Type]
+	if !ok {
+		limit = DefaultRateLimit
+	}
+	if limit.Max <= 0 || limit.Per <= 0 {
+		return true
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	cutoff := now.Add(-limit.Per)
+	recent := e.sent[c][:0]
+	for _, t := range e.sent[c] {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= limit.Max {
+		e.sent[c] = recent
+		return false
+	}
+	e.sent[c] = append(recent, now)
+	return true
+}
+
+func newNotification(event string, k *key.Key, meta plugin.EventMeta) *Notification {
+	n := &Notification{Event: event, Time: meta.Timestamp, ReasonCode: string(meta.ReasonCode)}
+	fillNotification(n, k, time.Now())
+	return n
+}
+
+func fillNotification(n *Notification, k *key.Key, now time.Time) {
+	if n.ID == "" {
+		n.ID = id.NewEventID().String()
+	}
+	if n.Time.IsZero() {
+		n.Time = now
+	}
+	n.Time = n.Time.UTC()
+	if n.KeyID == "" {
+		n.KeyID = k.ID.String()
+	}
+	if n.TenantID == "" {
+		n.TenantID, n.AppID = k.TenantID, k.AppID
+	}
+	if n.KeyName == "" {
+		n.KeyName = k.Name
+	}
+	if n.KeyHint == "" {
+		n.KeyHint = k.Hint
+	}
+}
+
+func keyLabel(k *key.Key) string {
+	if k.Name != "" {
+		return k.Name
+	}
+	return k.Prefix + "..." + k.Hint
+}
+
+func actionPast(a key.CompromiseAction) string {
+	if a == key.CompromiseRotate {
+		return "rotated"
+	}
+	return "revoked"
+}
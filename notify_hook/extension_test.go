@@ -0,0 +1,319 @@
+package notifyhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	audithook "github.com/xraph/keysmith/audit_hook"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	notifyhook "github.com/xraph/keysmith/notify_hook"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/tenant"
+)
+
+// fakeSender records what it was asked to send and fails when err is set.
+type fakeSender struct {
+	mu   sync.Mutex
+	sent []sent
+	err  error
+}
+
+type sent struct {
+	contact key.Contact
+	n       *notifyhook.Notification
+}
+
+func (s *fakeSender) Send(_ context.Context, c key.Contact, n *notifyhook.Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, sent{contact: c, n: n})
+	return s.err
+}
+
+func (s *fakeSender) targets() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]string, len(s.sent))
+	for i, x := range s.sent {
+		out[i] = x.contact.Target
+	}
+	return out
+}
+
+// fakeSettings serves tenant settings from a map.
+type fakeSettings map[string]*tenant.Settings
+
+func (f fakeSettings) GetSettings(_ context.Context, tenantID string) (*tenant.Settings, error) {
+	ts, ok := f[tenantID]
+	if !ok {
+		return nil, errors.New("tenant settings not found")
+	}
+	return ts, nil
+}
+
+type deliveryLog struct {
+	deliveries []*notifyhook.Delivery
+}
+
+func (l *deliveryLog) RecordDelivery(_ context.Context, d *notifyhook.Delivery) error {
+	l.deliveries = append(l.deliveries, d)
+	return nil
+}
+
+func testKey(contacts ...key.Contact) *key.Key {
+	return &key.Key{
+		ID:       id.NewKeyID(),
+		TenantID: "tenant-1",
+		AppID:    "app-1",
+		Name:     "billing",
+		Prefix:   "sk",
+		Hint:     "abcd",
+		Contacts: contacts,
+	}
+}
+
+var tenantDefaults = fakeSettings{"tenant-1": {
+	TenantID: "tenant-1",
+	DefaultContacts: key.Contacts{
+		{Type: key.ContactEmail, Target: "security@example.com"},
+	},
+}}
+
+func TestExtension_Name(t *testing.T) {
+	assert.Equal(t, "notify-hook", notifyhook.New(nil).Name())
+}
+
+func TestExtension_KeyContactsOverrideTenantDefaults(t *testing.T) {
+	email := &fakeSender{}
+	slack := &fakeSender{}
+	ext := notifyhook.New(tenantDefaults,
+		notifyhook.WithSender(key.ContactEmail, email),
+		notifyhook.WithSender(key.ContactSlack, slack),
+	)
+	k := testKey(
+		key.Contact{Type: key.ContactEmail, Target: "team@example.com"},
+		key.Contact{Type: key.ContactSlack, Target: "#billing-oncall"},
+	)
+
+	require.NoError(t, ext.OnKeyExpiredV2(context.Background(), k, plugin.EventMeta{}))
+
+	assert.Equal(t, []string{"team@example.com"}, email.targets(), "tenant default not added")
+	assert.Equal(t, []string{"#billing-oncall"}, slack.targets())
+}
+
+func TestExtension_TenantDefaultsWhenKeyHasNone(t *testing.T) {
+	email := &fakeSender{}
+	ext := notifyhook.New(tenantDefaults, notifyhook.WithSender(key.ContactEmail, email))
+
+	require.NoError(t, ext.OnKeyExpiredV2(context.Background(), testKey(), plugin.EventMeta{}))
+
+	assert.Equal(t, []string{"security@example.com"}, email.targets())
+}
+
+func TestExtension_NoSettings(t *testing.T) {
+	email := &fakeSender{}
+	ext := notifyhook.New(nil, notifyhook.WithSender(key.ContactEmail, email))
+	k := testKey()
+	k.TenantID = "unknown"
+
+	require.NoError(t, ext.OnKeyExpiredV2(context.Background(), k, plugin.EventMeta{}))
+	assert.Empty(t, email.targets())
+
+	withLookup := notifyhook.New(tenantDefaults, notifyhook.WithSender(key.ContactEmail, email))
+	assert.Empty(t, withLookup.Contacts(context.Background(), k), "missing settings mean no defaults")
+}
+
+func TestExtension_Notification(t *testing.T) {
+	email := &fakeSender{}
+	ext := notifyhook.New(tenantDefaults, notifyhook.WithSender(key.ContactEmail, email))
+	k := testKey()
+	at := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	rec := &rotation.Record{Reason: rotation.ReasonScheduled, GraceEnds: at.Add(time.Hour)}
+	require.NoError(t, ext.OnKeyRotatedV2(context.Background(), k, rec, plugin.EventMeta{
+		Timestamp:  at,
+		ReasonCode: plugin.ReasonCode("scheduled"),
+	}))
+
+	require.Len(t, email.sent, 1)
+	n := email.sent[0].n
+	assert.NotEmpty(t, n.ID)
+	assert.Equal(t, notifyhook.EventKeyRotated, n.Event)
+	assert.Equal(t, at, n.Time)
+	assert.Equal(t, k.ID.String(), n.KeyID)
+	assert.Equal(t, "tenant-1", n.TenantID)
+	assert.Equal(t, "billing", n.KeyName)
+	assert.Equal(t, "scheduled", n.ReasonCode)
+	assert.Contains(t, n.Subject, "billing")
+	assert.Contains(t, n.Message, "2024-01-15T11:00:00Z")
+}
+
+func TestExtension_RateLimitPerContactType(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	email := &fakeSender{}
+	webhook := &fakeSender{}
+	log := &deliveryLog{}
+	ext := notifyhook.New(nil,
+		notifyhook.WithSender(key.ContactEmail, email),
+		notifyhook.WithSender(key.ContactWebhook, webhook),
+		notifyhook.WithRateLimit(key.ContactEmail, 2, time.Hour),
+		notifyhook.WithRateLimit(key.ContactWebhook, 0, 0),
+		notifyhook.WithDeliveryRecorder(log),
+		notifyhook.WithClock(func() time.Time { return now }),
+	)
+	k := testKey(
+		key.Contact{Type: key.ContactEmail, Target: "team@example.com"},
+		key.Contact{Type: key.ContactWebhook, Target: "https://hooks.example.com/keys"},
+	)
+	ctx := context.Background()
+
+	for range 3 {
+		require.NoError(t, ext.OnKeyExpiredV2(ctx, k, plugin.EventMeta{}))
+	}
+	assert.Len(t, email.targets(), 2, "email capped at two an hour")
+	assert.Len(t, webhook.targets(), 3, "webhook limit disabled")
+
+	statuses := map[notifyhook.Status]int{}
+	for _, d := range log.deliveries {
+		statuses[d.Status]++
+	}
+	assert.Equal(t, map[notifyhook.Status]int{
+		notifyhook.StatusDelivered:   5,
+		notifyhook.StatusRateLimited: 1,
+	}, statuses)
+
+	// The limit is per contact: another key's address is unaffected.
+	other := testKey(key.Contact{Type: key.ContactEmail, Target: "other@example.com"})
+	require.NoError(t, ext.OnKeyExpiredV2(ctx, other, plugin.EventMeta{}))
+	assert.Len(t, email.targets(), 3)
+
+	// And the window slides.
+	now = now.Add(time.Hour + time.Second)
+	require.NoError(t, ext.OnKeyExpiredV2(ctx, k, plugin.EventMeta{}))
+	assert.Len(t, email.targets(), 4)
+}
+
+func TestExtension_DefaultRateLimit(t *testing.T) {
+	email := &fakeSender{}
+	ext := notifyhook.New(nil, notifyhook.WithSender(key.ContactEmail, email))
+	k := testKey(key.Contact{Type: key.ContactEmail, Target: "team@example.com"})
+
+	for range notifyhook.DefaultRateLimit.Max + 5 {
+		require.NoError(t, ext.OnKeyExpiredV2(context.Background(), k, plugin.EventMeta{}))
+	}
+	assert.Len(t, email.targets(), notifyhook.DefaultRateLimit.Max)
+}
+
+func TestExtension_DeliveryResults(t *testing.T) {
+	failing := &fakeSender{err: errors.New("connection refused")}
+	ext := notifyhook.New(nil, notifyhook.WithSender(key.ContactWebhook, failing))
+	k := testKey(
+		key.Contact{Type: key.ContactWebhook, Target: "https://hooks.example.com/keys"},
+		key.Contact{Type: key.ContactSlack, Target: "#billing-oncall"},
+	)
+
+	deliveries := ext.Notify(context.Background(), k, &notifyhook.Notification{
+		Event:   "custom.key.review_due",
+		Subject: "Review billing",
+		Message: "The billing key is due for review.",
+	})
+
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, notifyhook.StatusFailed, deliveries[0].Status)
+	assert.Equal(t, "connection refused", deliveries[0].Error)
+	assert.Equal(t, notifyhook.StatusNoSender, deliveries[1].Status)
+	assert.Equal(t, k.ID.String(), deliveries[0].KeyID)
+	assert.Equal(t, "custom.key.review_due", deliveries[0].Event)
+	assert.Equal(t, deliveries[0].NotificationID, deliveries[1].NotificationID)
+}
+
+func TestExtension_WithEvents(t *testing.T) {
+	email := &fakeSender{}
+	ext := notifyhook.New(tenantDefaults,
+		notifyhook.WithSender(key.ContactEmail, email),
+		notifyhook.WithEvents(notifyhook.EventKeyCompromised),
+	)
+	k := testKey()
+	ctx := context.Background()
+
+	require.NoError(t, ext.OnKeyExpiredV2(ctx, k, plugin.EventMeta{}))
+	assert.Empty(t, email.targets())
+
+	report := &key.CompromiseReport{Source: "github-secret-scanning", Action: key.CompromiseRevoke}
+	require.NoError(t, ext.OnKeyCompromisedV2(ctx, k, report, plugin.EventMeta{}))
+	require.Len(t, email.sent, 1)
+	assert.Contains(t, email.sent[0].n.Message, "github-secret-scanning")
+}
+
+func TestWebhookSender(t *testing.T) {
+	var got notifyhook.Notification
+	var secret string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret = r.Header.Get("X-Secret")
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	s := notifyhook.NewWebhookSender(srv.Client()).WithHeader("X-Secret", "s3cret")
+	n := &notifyhook.Notification{ID: "evt_1", Event: notifyhook.EventKeyExpired, KeyID: "akey_1", Subject: "expired"}
+	require.NoError(t, s.Send(context.Background(), key.Contact{Type: key.ContactWebhook, Target: srv.URL}, n))
+	assert.Equal(t, "s3cret", secret)
+	assert.Equal(t, "evt_1", got.ID)
+	assert.Equal(t, notifyhook.EventKeyExpired, got.Event)
+
+	err := s.Send(context.Background(), key.Contact{Type: key.ContactEmail, Target: "a@example.com"}, n)
+	assert.Error(t, err)
+}
+
+func TestWebhookSender_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	s := notifyhook.NewWebhookSender(nil)
+	err := s.Send(context.Background(), key.Contact{Type: key.ContactWebhook, Target: srv.URL}, &notifyhook.Notification{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "502")
+}
+
+func TestAuditRecorder(t *testing.T) {
+	var events []*audithook.AuditEvent
+	rec := notifyhook.AuditRecorder(audithook.RecorderFunc(func(_ context.Context, ev *audithook.AuditEvent) error {
+		events = append(events, ev)
+		return nil
+	}))
+	ext := notifyhook.New(nil,
+		notifyhook.WithSender(key.ContactEmail, &fakeSender{}),
+		notifyhook.WithSender(key.ContactWebhook, &fakeSender{err: errors.New("timeout")}),
+		notifyhook.WithDeliveryRecorder(rec),
+	)
+	k := testKey(
+		key.Contact{Type: key.ContactEmail, Target: "team@example.com"},
+		key.Contact{Type: key.ContactWebhook, Target: "https://hooks.example.com/keys"},
+	)
+
+	require.NoError(t, ext.OnKeyExpiredV2(context.Background(), k, plugin.EventMeta{}))
+
+	require.Len(t, events, 2)
+	assert.Equal(t, notifyhook.ActionNotificationDelivered, events[0].Action)
+	assert.Equal(t, audithook.OutcomeSuccess, events[0].Outcome)
+	assert.Equal(t, audithook.ResourceKey, events[0].Resource)
+	assert.Equal(t, k.ID.String(), events[0].ResourceID)
+	assert.Equal(t, notifyhook.EventKeyExpired, events[0].Metadata["event"])
+	assert.Equal(t, notifyhook.ActionNotificationFailed, events[1].Action)
+	assert.Equal(t, audithook.OutcomeFailure, events[1].Outcome)
+	assert.Equal(t, "timeout", events[1].Reason)
+}
@@ -0,0 +1,77 @@
+package notifyhook
+
+import (
+	"time"
+
+	log "github.com/xraph/go-utils/log"
+
+	"github.com/xraph/keysmith/key"
+)
+
+// DefaultSendTimeout bounds each call to a Sender.
+const DefaultSendTimeout = 10 * time.Second
+
+// Option configures an Extension.
+type Option func(*Extension)
+
+// WithSender registers the Sender for contacts of type t. Contacts of a
+// type without a sender are recorded with [StatusNoSender].
+func WithSender(t key.ContactType, s Sender) Option {
+	return func(e *Extension) {
+		e.senders[t] = s
+	}
+}
+
+// WithRateLimit allows at most max notifications to any one contact of
+// type t per window. A max of 0 removes the limit. Types without a
+// WithRateLimit use [DefaultRateLimit].
+func WithRateLimit(t key.ContactType, max int, per time.Duration) Option {
+	return func(e *Extension) {
+		e.limits[t] = RateLimit{Max: max, Per: per}
+	}
+}
+
+// WithDeliveryRecorder reports every delivery attempt to r.
+func WithDeliveryRecorder(r DeliveryRecorder) Option {
+	return func(e *Extension) {
+		e.recorder = r
+	}
+}
+
+// WithEvents restricts notifications to the specified event types only.
+// If not called, all event types are sent.
+func WithEvents(eventTypes ...string) Option {
+	return func(e *Extension) {
+		e.enabled = make(map[string]bool, len(eventTypes))
+		for _, t := range eventTypes {
+			e.enabled[t] = true
+		}
+	}
+}
+
+// WithSendTimeout bounds each call to a Sender. Defaults to
+// [DefaultSendTimeout].
+func WithSendTimeout(d time.Duration) Option {
+	return func(e *Extension) {
+		if d > 0 {
+			e.timeout = d
+		}
+	}
+}
+
+// WithLogger sets the logger for send and recording errors.
+func WithLogger(logger log.Logger) Option {
+	return func(e *Extension) {
+		e.logger = logger
+	}
+}
+
+// WithClock sets the time source for rate limiting and delivery times.
+// Tests use it to move time forward.
+func WithClock(now func() time.Time) Option {
+	return func(e *Extension) {
+		if now != nil {
+			e.now = now
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package notifyhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/smtp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/webhooksig"
+)
+
+// Compile-time interface checks.
+var (
+	_ Sender = (*SMTPSender)(nil)
+	_ Sender = (*WebhookSender)(nil)
+)
+
+// SMTPSender sends [key.ContactEmail] notifications as plain-text mail.
+type SMTPSender struct {
+	addr string
+	from string
+	auth smtp.Auth
+
+	// sendMail is smtp.SendMail, replaced in tests.
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewSMTPSender creates an SMTPSender that relays through the server at
+// addr (host:port) as from. auth may be nil for servers that need none.
+func NewSMTPSender(addr, from string, auth smtp.Auth) *SMTPSender {
+	return &SMTPSender{addr: addr, from: from, auth: auth, sendMail: smtp.SendMail}
+}
+
+// Send implements Sender. The standard SMTP client cannot be cancelled, so
+// ctx is only checked before connecting.
+func (s *SMTPSender) Send(ctx context.Context, c key.Contact, n *Notification) error {
+	if c.Type != key.ContactEmail {
+		return fmt.Errorf("notify_hook: smtp sender cannot send to %s contacts", c.Type)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.sendMail(s.addr, s.auth, s.from, []string{c.Target}, s.message(c.Target, n))
+}
+
+func (s *SMTPSender) message(to string, n *Notification) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.from)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", headerValue(n.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", n.Time.Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: <%s@keysmith>\r\n", n.ID)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(n.Message)
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
+
+// headerValue strips line breaks, so a key name cannot inject headers.
+func headerValue(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
+
+// WebhookSender posts [key.ContactWebhook] notifications as JSON to the
+// contact's URL. A response other than 2xx is an error. With signing
+// secrets, each request is signed as described in package webhooksig.
+type WebhookSender struct {
+	client  *http.Client
+	headers http.Header
+
+	mu      sync.RWMutex
+	secrets []webhooksig.Secret
+	scheme  webhooksig.Scheme
+	schemes map[string]webhooksig.Scheme
+
+	// now is time.Now, replaced in tests.
+	now func() time.Time
+}
+
+// NewWebhookSender creates a WebhookSender. A nil client uses
+// [http.DefaultClient]; requests are bounded by the Extension's send
+// timeout either way.
+func NewWebhookSender(client *http.Client) *WebhookSender {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &WebhookSender{
+		client:  client,
+		headers: make(http.Header),
+		scheme:  webhooksig.SchemeHMAC,
+		schemes: make(map[string]webhooksig.Scheme),
+		now:     time.Now,
+	}
+}
+
+// WithHeader adds a header to every request, such as a shared secret the
+// receiver checks. It returns s for chaining.
+func (s *WebhookSender) WithHeader(name, value string) *WebhookSender {
+	s.headers.Add(name, value)
+	return s
+}
+
+// WithSigningSecrets signs every request with secrets, newest first; see
+// SetSigningSecrets. An invalid secret fails every Send. It returns s for
+// chaining.
+func (s *WebhookSender) WithSigningSecrets(secrets ...webhooksig.Secret) *WebhookSender {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.secrets = slices.Clone(secrets)
+	return s
+}
+
+// WithScheme sets the signature scheme of endpoints without a
+// WithEndpointScheme. Defaults to [webhooksig.SchemeHMAC]. It returns s for
+// chaining.
+func (s *WebhookSender) WithScheme(scheme webhooksig.Scheme) *WebhookSender {
+	s.scheme = scheme
+	return s
+}
+
+// WithEndpointScheme sets the signature scheme of requests to the webhook
+// URL target, for receivers that expect another format. It returns s for
+// chaining.
+func (s *WebhookSender) WithEndpointScheme(target string, scheme webhooksig.Scheme) *WebhookSender {
+	s.schemes[target] = scheme
+	return s
+}
+
+// SetSigningSecrets replaces the signing secrets, newest first, such as
+// when the configuration is reloaded. Requests are signed with each secret
+// that has not expired, so a retiring secret given with an Expires keeps
+// signing alongside the new one until then. It fails without changing the
+// secrets if one is invalid.
+func (s *WebhookSender) SetSigningSecrets(secrets ...webhooksig.Secret) error {
+	for _, sec := range secrets {
+		if err := sec.Validate(); err != nil {
+			return fmt.Errorf("notify_hook: %w", err)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.secrets = slices.Clone(secrets)
+	return nil
+}
+
+// RotateSigningSecret makes next the newest signing secret. The current
+// secrets keep signing for overlap, so receivers can add next before the
+// old secrets stop; a secret that expires sooner keeps its expiry. Expired
+// secrets are dropped. A zero overlap retires the current secrets at once.
+func (s *WebhookSender) RotateSigningSecret(next webhooksig.Secret, overlap time.Duration) error {
+	if err := next.Validate(); err != nil {
+		return fmt.Errorf("notify_hook: %w", err)
+	}
+	now := s.now()
+	retire := now.Add(overlap)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	secrets := []webhooksig.Secret{next}
+	for _, sec := range s.secrets {
+		if sec.ID == next.ID || overlap <= 0 || (!sec.Expires.IsZero() && !now.Before(sec.Expires)) {
+			continue
+		}
+		if sec.Expires.IsZero() || sec.Expires.After(retire) {
+			sec.Expires = retire
+		}
+		secrets = append(secrets, sec)
+	}
+	s.secrets = secrets
+	return nil
+}
+
+// SigningSecrets returns the current signing secrets, newest first.
+func (s *WebhookSender) SigningSecrets() []webhooksig.Secret {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.secrets)
+}
+
+// Send implements Sender.
+func (s *WebhookSender) Send(ctx context.Context, c key.Contact, n *Notification) error {
+	if c.Type != key.ContactWebhook {
+		return fmt.Errorf("notify_hook: webhook sender cannot send to %s contacts", c.Type)
+	}
+	body, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("notify_hook: encode notification: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("notify_hook: build webhook request: %w", err)
+	}
+	for name, values := range s.headers {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := s.sign(req.Header, c.Target, body); err != nil {
+		return err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("notify_hook: post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("notify_hook: webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+// sign sets the signature headers of the request to target, if s has
+// signing secrets.
+func (s *WebhookSender) sign(h http.Header, target string, body []byte) error {
+	s.mu.RLock()
+	secrets := s.secrets
+	s.mu.RUnlock()
+	if len(secrets) == 0 {
+		return nil
+	}
+	scheme, ok := s.schemes[target]
+	if !ok {
+		scheme = s.scheme
+	}
+	if err := webhooksig.Sign(h, scheme, body, secrets, s.now()); err != nil {
+		return fmt.Errorf("notify_hook: sign webhook: %w", err)
+	}
+	return nil
+}
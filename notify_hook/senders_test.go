@@ -0,0 +1,118 @@
+package notifyhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/webhooksig"
+)
+
+func TestSMTPSender(t *testing.T) {
+	var gotAddr, gotFrom string
+	var gotTo []string
+	var gotMsg []byte
+	s := NewSMTPSender("smtp.example.com:587", "keysmith@example.com", nil)
+	s.sendMail = func(addr string, _ smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotFrom, gotTo, gotMsg = addr, from, to, msg
+		return nil
+	}
+
+	n := &Notification{
+		ID:      "evt_1",
+		Time:    time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC),
+		Subject: "API key \"billing\r\nBcc: x@example.com\" has expired",
+		Message: "The key has expired.",
+	}
+	require.NoError(t, s.Send(context.Background(), key.Contact{Type: key.ContactEmail, Target: "team@example.com"}, n))
+
+	assert.Equal(t, "smtp.example.com:587", gotAddr)
+	assert.Equal(t, "keysmith@example.com", gotFrom)
+	assert.Equal(t, []string{"team@example.com"}, gotTo)
+	msg := string(gotMsg)
+	assert.Contains(t, msg, "To: team@example.com\r\n")
+	assert.Contains(t, msg, "Subject: API key \"billing  Bcc: x@example.com\" has expired\r\n", "line breaks stripped from headers")
+	assert.NotContains(t, msg, "\r\nBcc:")
+	assert.Contains(t, msg, "\r\n\r\nThe key has expired.\r\n")
+
+	err := s.Send(context.Background(), key.Contact{Type: key.ContactSlack, Target: "#ops"}, n)
+	assert.Error(t, err)
+}
+
+func TestWebhookSender_SigningRotation(t *testing.T) {
+	var got http.Header
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	now := time.Unix(1700000000, 0)
+	old := webhooksig.Secret{ID: "2024-01", Key: []byte("whsec_old")}
+	next := webhooksig.Secret{ID: "2024-06", Key: []byte("whsec_new")}
+	s := NewWebhookSender(srv.Client()).WithSigningSecrets(old)
+	s.now = func() time.Time { return now }
+	send := func() {
+		t.Helper()
+		require.NoError(t, s.Send(context.Background(), key.Contact{Type: key.ContactWebhook, Target: srv.URL}, &Notification{ID: "evt_1"}))
+	}
+	verify := func(secrets ...webhooksig.Secret) error {
+		v := &webhooksig.Verifier{Secrets: secrets, Now: func() time.Time { return now }}
+		return v.Verify(got, body)
+	}
+
+	send()
+	require.NoError(t, verify(old))
+
+	require.NoError(t, s.RotateSigningSecret(next, time.Hour))
+	send()
+	assert.NoError(t, verify(old), "the old secret signs during the overlap")
+	assert.NoError(t, verify(next))
+	assert.Regexp(t, `^2024-06=[0-9a-f]{64}, 2024-01=[0-9a-f]{64}$`, got.Get(webhooksig.SignatureHeader), "the newest signs first")
+
+	now = now.Add(time.Hour)
+	send()
+	assert.ErrorIs(t, verify(old), webhooksig.ErrMismatch, "the overlap is over")
+	assert.NoError(t, verify(next))
+	require.NoError(t, s.RotateSigningSecret(webhooksig.Secret{ID: "2024-12", Key: []byte("whsec_next")}, 0))
+	assert.Len(t, s.SigningSecrets(), 1, "expired and retired secrets are dropped")
+
+	assert.Error(t, s.RotateSigningSecret(webhooksig.Secret{ID: "bad id", Key: []byte("k")}, time.Hour))
+	assert.Error(t, s.SetSigningSecrets(webhooksig.Secret{ID: "2025-01"}))
+	require.NoError(t, s.SetSigningSecrets(next, old))
+	assert.Equal(t, []webhooksig.Secret{next, old}, s.SigningSecrets())
+}
+
+func TestWebhookSender_EndpointScheme(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	secret := webhooksig.Secret{ID: "2024-06", Key: []byte("whsec_new")}
+	plain := srv.URL + "/plain"
+	stripe := srv.URL + "/stripe"
+	s := NewWebhookSender(srv.Client()).WithSigningSecrets(secret).WithEndpointScheme(stripe, webhooksig.SchemeStripe)
+
+	require.NoError(t, s.Send(context.Background(), key.Contact{Type: key.ContactWebhook, Target: plain}, &Notification{}))
+	assert.NotEmpty(t, got.Get(webhooksig.TimestampHeader))
+	assert.Regexp(t, `^2024-06=[0-9a-f]{64}$`, got.Get(webhooksig.SignatureHeader))
+
+	require.NoError(t, s.Send(context.Background(), key.Contact{Type: key.ContactWebhook, Target: stripe}, &Notification{}))
+	assert.Empty(t, got.Get(webhooksig.TimestampHeader))
+	assert.Regexp(t, `^t=\d+,v1=[0-9a-f]{64}$`, got.Get(webhooksig.SignatureHeader))
+
+	unsigned := NewWebhookSender(srv.Client())
+	require.NoError(t, unsigned.Send(context.Background(), key.Contact{Type: key.ContactWebhook, Target: plain}, &Notification{}))
+	assert.Empty(t, got.Get(webhooksig.SignatureHeader), "requests are unsigned without secrets")
+}
@@ -3,6 +3,7 @@ package observability
 
 import (
 	"context"
+	"sync"
 
 	gu "github.com/xraph/go-utils/metrics"
 
@@ -15,24 +16,39 @@ import (
 
 // Compile-time interface checks.
 var (
-	_ plugin.Plugin              = (*MetricsExtension)(nil)
-	_ plugin.KeyCreated          = (*MetricsExtension)(nil)
-	_ plugin.KeyCreateFailed     = (*MetricsExtension)(nil)
-	_ plugin.KeyValidated        = (*MetricsExtension)(nil)
-	_ plugin.KeyValidationFailed = (*MetricsExtension)(nil)
-	_ plugin.KeyRotated          = (*MetricsExtension)(nil)
-	_ plugin.KeyRevoked          = (*MetricsExtension)(nil)
-	_ plugin.KeySuspended        = (*MetricsExtension)(nil)
-	_ plugin.KeyReactivated      = (*MetricsExtension)(nil)
-	_ plugin.KeyExpired          = (*MetricsExtension)(nil)
-	_ plugin.KeyRateLimited      = (*MetricsExtension)(nil)
-	_ plugin.PolicyCreated       = (*MetricsExtension)(nil)
-	_ plugin.PolicyUpdated       = (*MetricsExtension)(nil)
-	_ plugin.PolicyDeleted       = (*MetricsExtension)(nil)
+	_ plugin.Plugin                  = (*MetricsExtension)(nil)
+	_ plugin.KeyCreated              = (*MetricsExtension)(nil)
+	_ plugin.KeyCreateFailed         = (*MetricsExtension)(nil)
+	_ plugin.KeyValidated            = (*MetricsExtension)(nil)
+	_ plugin.KeyValidationFailedV2   = (*MetricsExtension)(nil)
+	_ plugin.KeyRotated              = (*MetricsExtension)(nil)
+	_ plugin.KeyRevoked              = (*MetricsExtension)(nil)
+	_ plugin.KeySuspended            = (*MetricsExtension)(nil)
+	_ plugin.KeyReactivated          = (*MetricsExtension)(nil)
+	_ plugin.KeyExpiredV2            = (*MetricsExtension)(nil)
+	_ plugin.KeyRateLimited          = (*MetricsExtension)(nil)
+	_ plugin.TenantRateLimited       = (*MetricsExtension)(nil)
+	_ plugin.KeyConsumerMismatch     = (*MetricsExtension)(nil)
+	_ plugin.KeyQuotaExceeded        = (*MetricsExtension)(nil)
+	_ plugin.KeyQuotaForecastWarning = (*MetricsExtension)(nil)
+	_ plugin.KeyFlagsChanged         = (*MetricsExtension)(nil)
+	_ plugin.PolicyCreated           = (*MetricsExtension)(nil)
+	_ plugin.PolicyUpdated           = (*MetricsExtension)(nil)
+	_ plugin.PolicyDeleted           = (*MetricsExtension)(nil)
+	_ plugin.PluginPanicked          = (*MetricsExtension)(nil)
 )
 
 // MetricsExtension records Keysmith lifecycle metrics via go-utils MetricFactory.
+//
+// Validation failures are also counted per reason code, as
+// keysmith.key.validation_failed.<reason_code>, and expiries per trigger, as
+// keysmith.key.expired.<trigger>.
 type MetricsExtension struct {
+	factory gu.MetricFactory
+
+	mu       sync.Mutex
+	counters map[string]gu.Counter
+
 	keyCreated          gu.Counter
 	keyCreateFailed     gu.Counter
 	keyValidated        gu.Counter
@@ -43,9 +59,15 @@ type MetricsExtension struct {
 	keyReactivated      gu.Counter
 	keyExpired          gu.Counter
 	keyRateLimited      gu.Counter
+	tenantRateLimited   gu.Counter
+	keyConsumerMismatch gu.Counter
+	keyQuotaExceeded    gu.Counter
+	keyQuotaForecast    gu.Counter
+	keyFlagsChanged     gu.Counter
 	policyCreated       gu.Counter
 	policyUpdated       gu.Counter
 	policyDeleted       gu.Counter
+	pluginPanicked      gu.Counter
 }
 
 // NewMetricsExtension creates a MetricsExtension using a default collector.
@@ -56,6 +78,9 @@ func NewMetricsExtension() *MetricsExtension {
 // NewMetricsExtensionWithFactory creates a MetricsExtension with the provided factory.
 func NewMetricsExtensionWithFactory(factory gu.MetricFactory) *MetricsExtension {
 	return &MetricsExtension{
+		factory:  factory,
+		counters: make(map[string]gu.Counter),
+
 		keyCreated:          factory.Counter("keysmith.key.created"),
 		keyCreateFailed:     factory.Counter("keysmith.key.create_failed"),
 		keyValidated:        factory.Counter("keysmith.key.validated"),
@@ -66,9 +91,15 @@ func NewMetricsExtensionWithFactory(factory gu.MetricFactory) *MetricsExtension
 		keyReactivated:      factory.Counter("keysmith.key.reactivated"),
 		keyExpired:          factory.Counter("keysmith.key.expired"),
 		keyRateLimited:      factory.Counter("keysmith.key.rate_limited"),
+		tenantRateLimited:   factory.Counter("keysmith.tenant.rate_limited"),
+		keyConsumerMismatch: factory.Counter("keysmith.key.consumer_mismatch"),
+		keyQuotaExceeded:    factory.Counter("keysmith.key.quota_exceeded"),
+		keyQuotaForecast:    factory.Counter("keysmith.key.quota_forecast_warning"),
+		keyFlagsChanged:     factory.Counter("keysmith.key.flags_changed"),
 		policyCreated:       factory.Counter("keysmith.policy.created"),
 		policyUpdated:       factory.Counter("keysmith.policy.updated"),
 		policyDeleted:       factory.Counter("keysmith.policy.deleted"),
+		pluginPanicked:      factory.Counter("keysmith.plugin.panicked"),
 	}
 }
 
@@ -93,9 +124,12 @@ func (m *MetricsExtension) OnKeyValidated(_ context.Context, _ *key.Key) error {
 	return nil
 }
 
-// OnKeyValidationFailed implements plugin.KeyValidationFailed.
-func (m *MetricsExtension) OnKeyValidationFailed(_ context.Context, _ string, _ error) error {
+// OnKeyValidationFailedV2 implements plugin.KeyValidationFailedV2.
+func (m *MetricsExtension) OnKeyValidationFailedV2(_ context.Context, _ string, _ error, meta plugin.EventMeta) error {
 	m.keyValidationFailed.Inc()
+	if meta.ReasonCode != "" {
+		m.counter("keysmith.key.validation_failed." + string(meta.ReasonCode)).Inc()
+	}
 	return nil
 }
 
@@ -123,9 +157,12 @@ func (m *MetricsExtension) OnKeyReactivated(_ context.Context, _ *key.Key) error
 	return nil
 }
 
-// OnKeyExpired implements plugin.KeyExpired.
-func (m *MetricsExtension) OnKeyExpired(_ context.Context, _ *key.Key) error {
+// OnKeyExpiredV2 implements plugin.KeyExpiredV2.
+func (m *MetricsExtension) OnKeyExpiredV2(_ context.Context, _ *key.Key, meta plugin.EventMeta) error {
 	m.keyExpired.Inc()
+	if meta.Trigger != "" {
+		m.counter("keysmith.key.expired." + string(meta.Trigger)).Inc()
+	}
 	return nil
 }
 
@@ -135,6 +172,36 @@ func (m *MetricsExtension) OnKeyRateLimited(_ context.Context, _ *key.Key) error
 	return nil
 }
 
+// OnTenantRateLimited implements plugin.TenantRateLimited.
+func (m *MetricsExtension) OnTenantRateLimited(_ context.Context, _ *key.Key) error {
+	m.tenantRateLimited.Inc()
+	return nil
+}
+
+// OnKeyConsumerMismatch implements plugin.KeyConsumerMismatch.
+func (m *MetricsExtension) OnKeyConsumerMismatch(_ context.Context, _ *key.Key, _ string) error {
+	m.keyConsumerMismatch.Inc()
+	return nil
+}
+
+// OnKeyQuotaExceeded implements plugin.KeyQuotaExceeded.
+func (m *MetricsExtension) OnKeyQuotaExceeded(_ context.Context, _ *key.Key, _ *key.QuotaExceeded) error {
+	m.keyQuotaExceeded.Inc()
+	return nil
+}
+
+// OnKeyQuotaForecastWarning implements plugin.KeyQuotaForecastWarning.
+func (m *MetricsExtension) OnKeyQuotaForecastWarning(_ context.Context, _ *key.Key, _ *key.QuotaForecast) error {
+	m.keyQuotaForecast.Inc()
+	return nil
+}
+
+// OnKeyFlagsChanged implements plugin.KeyFlagsChanged.
+func (m *MetricsExtension) OnKeyFlagsChanged(_ context.Context, _ *key.Key, _, _ key.Flags) error {
+	m.keyFlagsChanged.Inc()
+	return nil
+}
+
 // OnPolicyCreated implements plugin.PolicyCreated.
 func (m *MetricsExtension) OnPolicyCreated(_ context.Context, _ *policy.Policy) error {
 	m.policyCreated.Inc()
@@ -152,3 +219,21 @@ func (m *MetricsExtension) OnPolicyDeleted(_ context.Context, _ id.PolicyID) err
 	m.policyDeleted.Inc()
 	return nil
 }
+
+// OnPluginPanicked implements plugin.PluginPanicked.
+func (m *MetricsExtension) OnPluginPanicked(_ context.Context, _ *plugin.HookError) error {
+	m.pluginPanicked.Inc()
+	return nil
+}
+
+// counter returns the counter named name, creating it on first use.
+func (m *MetricsExtension) counter(name string) gu.Counter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.counters[name]
+	if !ok {
+		c = m.factory.Counter(name)
+		m.counters[name] = c
+	}
+	return c
+}
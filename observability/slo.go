@@ -0,0 +1,76 @@
+package observability
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	gu "github.com/xraph/go-utils/metrics"
+
+	"github.com/xraph/keysmith/slo"
+)
+
+// SLOGauges exports SLO error budgets and burn rates as gauges named
+// keysmith.slo.<objective>.budget_remaining and
+// keysmith.slo.<objective>.burn_rate.<window>, such as
+// keysmith.slo.validation.burn_rate.5m.
+type SLOGauges struct {
+	factory gu.MetricFactory
+
+	mu     sync.Mutex
+	gauges map[string]gu.Gauge
+}
+
+// NewSLOGauges creates SLOGauges using the provided factory.
+func NewSLOGauges(factory gu.MetricFactory) *SLOGauges {
+	return &SLOGauges{factory: factory, gauges: make(map[string]gu.Gauge)}
+}
+
+// Update sets the gauges from statuses, as returned by Engine.SLOStatus.
+func (g *SLOGauges) Update(statuses []slo.Status) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, st := range statuses {
+		prefix := "keysmith.slo." + st.Objective.Name
+		g.gauge(prefix + ".budget_remaining").Set(st.BudgetRemaining)
+		for _, b := range st.Burn {
+			g.gauge(prefix + ".burn_rate." + windowName(b.Window)).Set(b.Rate)
+		}
+	}
+}
+
+// Run calls Update with status() every interval until ctx is done.
+func (g *SLOGauges) Run(ctx context.Context, interval time.Duration, status func() []slo.Status) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		g.Update(status())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (g *SLOGauges) gauge(name string) gu.Gauge {
+	gauge, ok := g.gauges[name]
+	if !ok {
+		gauge = g.factory.Gauge(name)
+		g.gauges[name] = gauge
+	}
+	return gauge
+}
+
+// windowName formats d without zero trailing units: 5m rather than 5m0s.
+func windowName(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
@@ -1,10 +1,18 @@
 package keysmith
 
 import (
+	"strings"
+	"time"
+
 	log "github.com/xraph/go-utils/log"
 
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
 	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/ratelimit"
+	"github.com/xraph/keysmith/slo"
 	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/usage"
 )
 
 // Option is a functional option for Engine.
@@ -13,17 +21,627 @@ type Option func(*Engine)
 // WithStore sets the composite store.
 func WithStore(s store.Store) Option { return func(e *Engine) { e.store = s } }
 
-// WithHasher sets the key hasher.
+// WithHasher sets the key hasher. Built with the fips tag, the hasher must
+// report an approved algorithm through [AlgorithmReporter].
 func WithHasher(h Hasher) Option { return func(e *Engine) { e.hasher = h } }
 
-// WithKeyGenerator sets the key generator.
+// WithKeyGenerator sets the key generator. Built with the fips tag, the
+// generator must report an approved algorithm through [AlgorithmReporter].
 func WithKeyGenerator(g KeyGenerator) Option { return func(e *Engine) { e.generator = g } }
 
-// WithRateLimiter sets the rate limiter.
-func WithRateLimiter(r RateLimiter) Option { return func(e *Engine) { e.ratelimiter = r } }
+// WithRateLimiter sets the rate limiter. Without one the engine uses the
+// in-memory default; see [WithDefaultRateLimiter].
+func WithRateLimiter(r RateLimiter) Option {
+	return func(e *Engine) {
+		e.ratelimiter = r
+		e.customLimiter = r != nil
+	}
+}
+
+// WithDefaultRateLimiter configures the in-memory token-bucket limiter,
+// [ratelimit.Limiter], that enforces policy, tenant, and scope rate limits
+// when no [WithRateLimiter] is given. The engine installs it without this
+// option too; use it to pass limiter options, such as the sweep interval.
+// Buckets are per process, so each engine instance applies the full limit.
+// A later WithRateLimiter takes precedence. Strict quotas require a
+// fixed-window limiter; see [WithStrictQuotas].
+func WithDefaultRateLimiter(opts ...ratelimit.Option) Option {
+	return func(e *Engine) {
+		e.ratelimiter = nil
+		e.customLimiter = false
+		e.defaultLimiter = opts
+	}
+}
 
 // WithExtension registers a lifecycle plugin with the engine.
 func WithExtension(x plugin.Plugin) Option { return func(e *Engine) { e.hooks.Register(x) } }
 
 // WithLogger sets the logger.
 func WithLogger(l log.Logger) Option { return func(e *Engine) { e.logger = l } }
+
+// WithHookTimeout sets how long a single plugin hook may run before the
+// dispatch gives up on it and moves to the next plugin. The default is
+// plugin.DefaultHookTimeout; zero or less disables the timeout.
+func WithHookTimeout(d time.Duration) Option { return func(e *Engine) { e.hooks.SetTimeout(d) } }
+
+// WithRefuseValidationsWhenStopping makes ValidateKey fail with
+// ErrEngineStopping once Stop has been called. By default, validations are
+// served until the process exits; enable this when a load balancer retries
+// refused requests on another instance.
+func WithRefuseValidationsWhenStopping() Option {
+	return func(e *Engine) { e.refuseWhenStopping = true }
+}
+
+// WithShutdownTimeouts bounds the phases of Stop. Zero fields keep their
+// defaults.
+func WithShutdownTimeouts(t ShutdownTimeouts) Option {
+	return func(e *Engine) { e.shutdownTimeouts = t }
+}
+
+// WithoutValidationCoalescing disables request coalescing in ValidateKey.
+// By default, concurrent validations of the same key share a single store
+// load; rate limiting, state checks, and hooks still run per request.
+func WithoutValidationCoalescing() Option { return func(e *Engine) { e.flights = nil } }
+
+// WithFailureFingerprinting sets how many validation failures sharing a
+// fingerprint, within window, fire the SuspiciousValidationPattern hook. The
+// hook fires at most once per fingerprint per window. A threshold of zero or
+// less disables failure tracking. Defaults to [DefaultFailureThreshold]
+// failures per [DefaultFailureWindow].
+func WithFailureFingerprinting(threshold int, window time.Duration) Option {
+	return func(e *Engine) {
+		if threshold <= 0 || window <= 0 {
+			e.failures = nil
+			return
+		}
+		e.failures = newFailureTracker(threshold, window)
+	}
+}
+
+// WithEndpointActivity sets the number of distinct endpoints tracked per key
+// for [Engine.ListEndpointActivity] and how often buffered activity is
+// flushed to the store. Endpoints beyond maxPerKey are counted in a single
+// overflow bucket. A maxPerKey of zero or less disables tracking; a
+// flushInterval of zero or less disables the background flush, leaving
+// flushes to a full buffer, Stop, and [Engine.FlushEndpointActivity].
+// Defaults to [DefaultMaxEndpointsPerKey] and [DefaultEndpointFlushInterval].
+func WithEndpointActivity(maxPerKey int, flushInterval time.Duration) Option {
+	return func(e *Engine) {
+		if maxPerKey <= 0 {
+			e.endpoints = nil
+			return
+		}
+		e.endpoints = newEndpointTracker(maxPerKey, flushInterval)
+	}
+}
+
+// WithLastUsedFlushInterval sets how long last-used times are buffered
+// before they are written. Validations within one interval cost a single
+// write per key; a shorter interval makes LastUsedAt fresher at the cost of
+// more writes. Zero or less writes as soon as the previous write finishes.
+// Defaults to [DefaultLastUsedFlushInterval].
+func WithLastUsedFlushInterval(d time.Duration) Option {
+	return func(e *Engine) { e.lastUsed = newLastUsedTracker(d) }
+}
+
+// WithAuthorizer sets an external [Authorizer] that ValidateKey consults
+// after every built-in check passes. Decisions are not cached unless
+// [WithAuthorizerCache] is set.
+func WithAuthorizer(a Authorizer) Option { return func(e *Engine) { e.authorizer = a } }
+
+// WithAuthorizerCache caches authorizer decisions for ttl per key and input
+// document, so repeat validations of a key with the same request context
+// skip the authorizer. Errors are not cached. Zero values use
+// [DefaultAuthzCacheTTL] and [DefaultAuthzCacheSize].
+func WithAuthorizerCache(ttl time.Duration, maxEntries int) Option {
+	return func(e *Engine) { e.authzCache = newAuthzCache(ttl, maxEntries) }
+}
+
+// WithExpirySkewTolerance treats keys as valid for d past their ExpiresAt, so
+// that small clock differences between the servers that create and validate
+// keys do not make a key flap between valid and expired at the boundary.
+// CleanupExpiredKeys applies the same tolerance. Defaults to zero.
+func WithExpirySkewTolerance(d time.Duration) Option {
+	return func(e *Engine) {
+		if d > 0 {
+			e.expirySkew = d
+		}
+	}
+}
+
+// WithClock sets the clock used to evaluate key expiry and grace periods
+// and to timestamp rotations. Defaults to time.Now; tests can supply a fake
+// clock.
+func WithClock(now func() time.Time) Option {
+	return func(e *Engine) {
+		if now != nil {
+			e.now = now
+		}
+	}
+}
+
+// WithExtendedGracePeriod sets how long rotated keys flagged
+// key.FlagExtendedGraceEligible stay valid after their normal grace period.
+// Zero disables the extension. Defaults to [DefaultExtendedGracePeriod].
+func WithExtendedGracePeriod(d time.Duration) Option {
+	return func(e *Engine) {
+		if d >= 0 {
+			e.extendedGrace = d
+		}
+	}
+}
+
+// WithIDFormat sets the wire format of entity IDs: how they are returned by
+// the API, written to stores, and rendered by String. The setting is
+// process-wide, since IDs format themselves, so engines in one process
+// share it. Parsing accepts every format, which lets stored IDs be
+// rewritten with [Engine.MigrateIDs] after the switch. Defaults to
+// id.FormatTypeID.
+func WithIDFormat(f id.Format) Option { return func(e *Engine) { e.idFormat = &f } }
+
+// WithDeliveryFailureMode sets what CreateKey does when writing a raw key to
+// its [SecretDestination] fails. Defaults to [DeliveryRollback].
+func WithDeliveryFailureMode(mode DeliveryFailureMode) Option {
+	return func(e *Engine) { e.deliveryFailure = mode }
+}
+
+// WithTermsVersion makes CreateKey require CreateKeyInput.AcceptedTermsVersion
+// to equal v, failing with ErrTermsNotAccepted otherwise. The accepted
+// version and time are stored on the key. Changing v later leaves existing
+// keys as they are; find them with [Engine.ListKeysWithOutdatedTerms].
+func WithTermsVersion(v string) Option {
+	return func(e *Engine) { e.termsVersion = strings.TrimSpace(v) }
+}
+
+// WithTermsEnforcement sets how ValidateKey treats keys whose accepted terms
+// are not the version set with [WithTermsVersion]. Defaults to
+// [TermsIgnore].
+func WithTermsEnforcement(mode TermsEnforcement) Option {
+	return func(e *Engine) { e.termsEnforcement = mode }
+}
+
+// WithMetadataLimits bounds the metadata callers may attach to keys,
+// policies, and scopes. Zero fields keep the defaults: 16 KiB encoded, 64
+// entries, and 128-byte entry names.
+func WithMetadataLimits(limits MetadataLimits) Option {
+	return func(e *Engine) { e.metadataLimits = limits }
+}
+
+// WithCreateKeyValidator adds a check run on every CreateKey input before the
+// key is generated. It may be given more than once; validators run in the
+// order they were added, all of them run, and their errors are returned
+// together as an [*InputError].
+func WithCreateKeyValidator(v CreateKeyValidator) Option {
+	return func(e *Engine) { e.createValidators = append(e.createValidators, v) }
+}
+
+// WithUpdateKeyValidator adds a check run on every UpdateKey input. It
+// behaves like [WithCreateKeyValidator].
+func WithUpdateKeyValidator(v UpdateKeyValidator) Option {
+	return func(e *Engine) { e.updateValidators = append(e.updateValidators, v) }
+}
+
+// WithRotateKeyValidator adds a check run on every RotateKey call. It
+// behaves like [WithCreateKeyValidator].
+func WithRotateKeyValidator(v RotateKeyValidator) Option {
+	return func(e *Engine) { e.rotateValidators = append(e.rotateValidators, v) }
+}
+
+// WithRevocationLookback sets how far back the revocation feed can be read
+// and how long PurgeRevocations keeps entries. Validators whose cursor falls
+// outside the window must rebuild their cache. Defaults to 30 days.
+func WithRevocationLookback(d time.Duration) Option {
+	return func(e *Engine) {
+		if d > 0 {
+			e.revocationLookback = d
+		}
+	}
+}
+
+// WithTombstoneEveryRevocation tombstones the hashes of every revoked key.
+// By default only keys revoked for a security reason, by ReportCompromise or
+// RevokeByHashes, are tombstoned.
+func WithTombstoneEveryRevocation() Option {
+	return func(e *Engine) { e.tombstoneAll = true }
+}
+
+// WithRotationDueWarning sets the fraction of a policy's rotation period,
+// counted from the key's last rotation or its creation, after which
+// ValidationResult.RotationDue reminds the caller to rotate the key.
+// fraction must be in (0, 1]; other values keep DefaultRotationDueWarning.
+func WithRotationDueWarning(fraction float64) Option {
+	return func(e *Engine) {
+		if fraction > 0 && fraction <= 1 {
+			e.rotationWarning = fraction
+		}
+	}
+}
+
+// WithRotationOverdueEvents fires the KeyRotationOverdue hook, at most once
+// per key per day, when a key is validated after its policy's rotation
+// period ended. The audit extension records it as a warning. Off by default.
+func WithRotationOverdueEvents() Option {
+	return func(e *Engine) { e.rotationOverdue = newReportTracker(rotationOverdueInterval) }
+}
+
+// WithKeyFormat registers a raw key format the generator no longer
+// reports, such as that of keys issued before a format change, so
+// WithKeyFormatDeprecation can flag it. Formats are checked by NewEngine.
+func WithKeyFormat(f KeyFormat) Option {
+	return func(e *Engine) { e.keyFormats = append(e.keyFormats, f) }
+}
+
+// WithKeyFormatDeprecation flags the key format named format deprecated.
+// Validations of keys in it carry ValidationResult.KeyDeprecation and fire
+// the KeyFormatDeprecated hook at most once per key per week, so the
+// holders still to migrate can be found before the sunset. The sunset is
+// announced, not enforced. It may be repeated; a later deprecation of the
+// same format replaces the earlier one.
+func WithKeyFormatDeprecation(format string, d KeyFormatDeprecation) Option {
+	return func(e *Engine) {
+		if e.formatDeprecations == nil {
+			e.formatDeprecations = make(map[string]KeyFormatDeprecation)
+		}
+		e.formatDeprecations[format] = d
+	}
+}
+
+// WithHashTombstoneRetention sets how long PurgeHashTombstones keeps hash
+// tombstones, for deployments that cannot let them grow without bound. A
+// purged hash can be imported again. Defaults to 0, which keeps tombstones
+// forever.
+func WithHashTombstoneRetention(d time.Duration) Option {
+	return func(e *Engine) {
+		if d > 0 {
+			e.tombstoneRetention = d
+		}
+	}
+}
+
+// WithAnomalyDetector sets the detector a staged tenant recovery consults
+// before each batch; the recovery halts when it reports trouble. The
+// default watches the key event history for keys revoked or rotated as
+// compromised or leaked.
+func WithAnomalyDetector(d AnomalyDetector) Option {
+	return func(e *Engine) {
+		if d != nil {
+			e.anomalies = d
+		}
+	}
+}
+
+// WithKeyEventLookback sets how far back the key event history can be read
+// and how long PurgeKeyEvents keeps events. Subscribers resuming from an
+// older cursor must reload their view. Defaults to 7 days.
+func WithKeyEventLookback(d time.Duration) Option {
+	return func(e *Engine) {
+		if d > 0 {
+			e.keyEventLookback = d
+		}
+	}
+}
+
+// WithKeyEventBuffer sets how many events a SubscribeKeyEvents subscriber
+// may fall behind before it is dropped. Defaults to
+// [DefaultKeyEventBuffer].
+func WithKeyEventBuffer(n int) Option {
+	return func(e *Engine) {
+		if n > 0 {
+			e.keyEventBuffer = n
+		}
+	}
+}
+
+// WithValidationCache caches the store data needed to validate a key for
+// ttl, holding at most maxEntries keys. Revocation, suspension, rotation,
+// and scope or policy changes made through the engine take effect
+// immediately; changes made by other engine instances take up to ttl, or
+// none for a call with [ValidationRequest.BypassCache]. A full cache evicts
+// the least recently used key. Zero values use DefaultValidationCacheTTL and
+// DefaultValidationCacheSize.
+func WithValidationCache(ttl time.Duration, maxEntries int) Option {
+	return func(e *Engine) { e.cache = newValidationCache(ttl, maxEntries) }
+}
+
+// WithRevisionTTL sets how long [Engine.TenantRevision] reuses a revision
+// read from the store, which bounds how long mutations made by other engine
+// instances go unnoticed. Zero uses DefaultRevisionTTL; a negative ttl reads
+// the store on every call.
+func WithRevisionTTL(ttl time.Duration) Option {
+	return func(e *Engine) {
+		if ttl == 0 {
+			ttl = DefaultRevisionTTL
+		}
+		e.revisions = newRevisionCache(ttl)
+	}
+}
+
+// WithCacheWarmup preloads the validation cache in Start with the keys
+// selected by strategy, so that the first requests after a deploy do not
+// all go to the store. The warm-up is bounded by timeout (zero uses
+// DefaultWarmupTimeout) and never fails Start; see [Engine.WarmupReport].
+// It requires WithValidationCache.
+func WithCacheWarmup(strategy WarmupStrategy, timeout time.Duration) Option {
+	return func(e *Engine) {
+		if strategy == nil {
+			return
+		}
+		if timeout <= 0 {
+			timeout = DefaultWarmupTimeout
+		}
+		e.warmup = &warmup{strategy: strategy, timeout: timeout}
+	}
+}
+
+// WithCaptureRetention sets how long debug captures are kept before the
+// background purger deletes them. Defaults to 24 hours.
+func WithCaptureRetention(d time.Duration) Option {
+	return func(e *Engine) {
+		if d > 0 {
+			e.captureRetention = d
+		}
+	}
+}
+
+// WithLiveDebugCapture permits debug capture for keys in the live
+// environment. Without it, SetKeyDebug refuses live keys with
+// ErrDebugCaptureNotAllowed.
+func WithLiveDebugCapture() Option { return func(e *Engine) { e.liveCapture = true } }
+
+// WithStoreMaintenance runs [Engine.MaintainStore] with opts every interval
+// between Start and Stop, logging a summary of each run. opts.Full locks
+// tables on most backends, so leave it unset on busy stores. A non-positive
+// interval disables the job.
+func WithStoreMaintenance(interval time.Duration, opts store.MaintenanceOptions) Option {
+	return func(e *Engine) {
+		e.maintenance.interval = interval
+		e.maintenanceOpts = opts
+	}
+}
+
+// WithQuotaForecastWarnings runs [Engine.EvaluateQuotaForecasts] every
+// interval between Start and Stop, firing KeyQuotaForecastWarning for keys
+// projected to exhaust their monthly quota before month end. Each key is
+// warned about at most once a week. A non-positive interval disables the
+// job, which is the default.
+func WithQuotaForecastWarnings(interval time.Duration) Option {
+	return func(e *Engine) { e.quotaForecasts.interval = interval }
+}
+
+// WithStorageReports runs [Engine.TenantStorageReport] every interval
+// between Start and Stop and fires StorageReportGenerated with each report,
+// for a billing plugin to charge for storage or usage; use 30*24*time.Hour
+// for a roughly monthly report. Of the engines sharing a store, one runs
+// each report. A non-positive interval disables the job, which is the
+// default.
+func WithStorageReports(interval time.Duration) Option {
+	return func(e *Engine) { e.storageReports.interval = interval }
+}
+
+// WithTelemetry sends anonymous usage telemetry to endpoint every
+// opts.Interval between Start and Stop: the keysmith version, the store
+// driver, which options are in use, and entity counts as orders of
+// magnitude, under an install ID drawn at random and kept in the store; see
+// [TelemetryPayload]. Nothing names a tenant, app, key, or endpoint. Of the
+// engines sharing a store, one sends each payload. Setting the
+// [TelemetryDisableEnv] or DO_NOT_TRACK environment variable turns it off
+// regardless. opts is checked by NewEngine. Off by default.
+func WithTelemetry(endpoint string, opts TelemetryOptions) Option {
+	return func(e *Engine) {
+		e.telemetryEndpoint = endpoint
+		e.telemetryOpt = &opts
+	}
+}
+
+// WithQuotaSync sets how often the engine writes the quota counts of its
+// validations and reads back those of other engines, and how many counts
+// of one window it holds before syncing that window early. Between syncs
+// each engine enforces daily and monthly quotas from its own view, so a
+// window shared by several engines may overshoot its limit by up to the
+// number of engines times batch. Defaults to DefaultQuotaSyncInterval and
+// DefaultQuotaSyncBatch; non-positive values keep the defaults.
+func WithQuotaSync(interval time.Duration, batch int) Option {
+	return func(e *Engine) {
+		if interval > 0 {
+			e.quotas.interval = interval
+		}
+		if batch > 0 {
+			e.quotas.batch = int64(batch)
+		}
+	}
+}
+
+// WithStrictQuotas enforces daily and monthly quotas with the rate limiter
+// instead of the engine's synced counts, in buckets named
+// "quota:pool:<pool ID>:<period>:<window start>" or
+// "quota:key:<key ID>:...". A limiter shared by every engine, such as one
+// backed by Redis, then holds each window to its limit exactly. Counts are
+// still recorded for [Engine.QuotaPoolUsage] and forecasts. A validation
+// refused by its daily quota has already spent one request of its monthly
+// bucket, and the buckets only count validations made since strict mode was
+// turned on. The limiter must count each bucket over a fixed window:
+// NewEngine fails without one set with [WithRateLimiter], and with a
+// [ratelimit.Limiter], whose buckets refill during the window.
+func WithStrictQuotas() Option {
+	return func(e *Engine) { e.quotas.strict = true }
+}
+
+// WithMaxPageSize sets the largest Limit the engine's list methods honor;
+// larger limits are clamped to n, and a zero or negative Limit gets the
+// method's default page size, itself at most n. Defaults to
+// DefaultMaxPageSize. A non-positive n keeps the default. See
+// [Engine.PageLimit].
+func WithMaxPageSize(n int) Option {
+	return func(e *Engine) {
+		if n > 0 {
+			e.maxPageSize = n
+		}
+	}
+}
+
+// WithJobLockTTL sets the TTL of the store locks that the capture purge,
+// store maintenance, and quota forecast jobs run under when the store
+// implements store.Locker, so that engines sharing a store run each job once
+// per interval between them. A running job renews its lock every third of
+// ttl; a crashed engine's lock frees once ttl passes. Zero uses
+// DefaultJobLockTTL; a negative ttl runs the jobs unlocked on every engine.
+func WithJobLockTTL(ttl time.Duration) Option {
+	return func(e *Engine) {
+		if ttl == 0 {
+			ttl = DefaultJobLockTTL
+		}
+		e.jobLockTTL = ttl
+	}
+}
+
+// WithPrefixRule constrains the keys created with prefix, such as "pk" for
+// publishable keys: the environments they may be created in, the policy
+// attached when none is given, the scopes they must carry, and their
+// longest lifetime. It may be repeated; a later rule for the same prefix
+// replaces the earlier one. Rules are checked by NewEngine.
+func WithPrefixRule(prefix string, rule PrefixRule) Option {
+	return func(e *Engine) {
+		if e.prefixRules == nil {
+			e.prefixRules = make(map[string]PrefixRule)
+		}
+		e.prefixRules[prefix] = rule
+	}
+}
+
+// WithEnvironmentProfile sets defaults and caps for the keys of env, such
+// as a sandbox given to prospects: their longest lifetime and the policy
+// attached when none is given, applied by CreateKey; how their usage is
+// recorded; and how long they may go unused before SuspendIdleKeys
+// suspends them. It may be repeated; a later profile for the same
+// environment replaces the earlier one. Profiles are checked by NewEngine.
+func WithEnvironmentProfile(env key.Environment, p EnvironmentProfile) Option {
+	return func(e *Engine) {
+		if e.envProfiles == nil {
+			e.envProfiles = make(map[key.Environment]EnvironmentProfile)
+		}
+		e.envProfiles[env] = p
+	}
+}
+
+// WithStrictPrefixes makes CreateKey reject prefixes that have no
+// [WithPrefixRule] with ErrUnknownPrefix. By default they are allowed
+// unconstrained.
+func WithStrictPrefixes() Option {
+	return func(e *Engine) { e.strictPrefixes = true }
+}
+
+// WithReadOnly starts the engine in read-only mode, refusing store writes
+// until [Engine.SetReadOnly] turns it off.
+func WithReadOnly() Option {
+	return func(e *Engine) { e.readOnly.Store(true) }
+}
+
+// WithReadOnlyUsageRecording sets whether usage records, debug captures, and
+// endpoint activity are still written in read-only mode. Defaults to true,
+// since they are append-only; pass false when the maintenance takes the
+// usage tables offline too, and RecordUsage fails with ErrReadOnlyMode.
+func WithReadOnlyUsageRecording(allowed bool) Option {
+	return func(e *Engine) { e.readOnlyBlocksUsage = !allowed }
+}
+
+// WithUsageRecording sets how [Engine.RecordUsage] records each request by
+// path: in full, sampled 1-in-N, as endpoint activity counts only, or not at
+// all. It can be changed later with [Engine.SetUsageRecording]. The policy is
+// checked by NewEngine, which fails on an invalid one. Defaults to recording
+// every request in full.
+func WithUsageRecording(p usage.RecordingPolicy) Option {
+	return func(e *Engine) { e.recordingOpt = &p }
+}
+
+// WithAdaptiveLimiting temporarily reduces the rate limit of a key whose
+// requests mostly fail, such as a client retrying with the wrong scopes.
+// Outcomes come from the status codes passed to [Engine.RecordUsage]; when
+// a key's error ratio crosses the threshold, ValidateKey applies the reduced
+// limit, fires the KeyAdaptivelyLimited hook, and reports the penalty in
+// [RateLimitInfo]. The penalty lifts once the cooldown has passed and the
+// ratio is back under the threshold. Only keys whose policy sets a rate
+// limit are affected. cfg is checked by NewEngine. Off by default.
+func WithAdaptiveLimiting(cfg AdaptiveLimiting) Option {
+	return func(e *Engine) { e.adaptiveOpt = &cfg }
+}
+
+// WithReplayProtection requires validate endpoint requests to carry a
+// nonce and a timestamp, and rejects a request whose nonce was already used
+// with the same key or whose timestamp is outside the window; see
+// [Engine.CheckReplay]. Nonces are kept in a bounded in-memory cache unless
+// cfg.Store is set. cfg is checked by NewEngine. Off by default.
+func WithReplayProtection(cfg ReplayProtection) Option {
+	return func(e *Engine) { e.replayOpt = &cfg }
+}
+
+// WithIPHashSecret sets the secret that keys client IP hashes for tenants
+// whose IP handling is [usage.IPHash]. Engines sharing a store should share
+// the secret, so a client hashes the same on each of them within a day.
+// Defaults to a random secret per engine.
+func WithIPHashSecret(secret []byte) Option {
+	return func(e *Engine) { e.ipHashSecret = append([]byte(nil), secret...) }
+}
+
+// WithErasureBatchSize sets how many usage records
+// [Engine.EraseUsageIdentifiers] rewrites per store call. Defaults to
+// [DefaultErasureBatchSize].
+func WithErasureBatchSize(n int) Option {
+	return func(e *Engine) {
+		if n > 0 {
+			e.erasureBatchSize = n
+		}
+	}
+}
+
+// WithSLO tracks key validation against objectives, for [Engine.SLOStatus],
+// the health report, and the SLO endpoint. Each ValidateKey call is timed
+// with the engine clock and classified by [WithSLOClassifier]. Objectives
+// are checked by NewEngine, which fails on an invalid one.
+func WithSLO(objectives ...slo.Objective) Option {
+	return func(e *Engine) { e.sloObjectives = append(e.sloObjectives, objectives...) }
+}
+
+// WithLiveStats counts each key's validations in memory for
+// [Engine.LiveKeyStats] and [Engine.LiveTopKeys], keeping the maxKeys keys
+// validated most recently; non-positive maxKeys keep DefaultLiveStatsKeys.
+// Each tracked key takes about 5 KiB. The counts are not persisted or
+// shared between engines. Off by default.
+func WithLiveStats(maxKeys int) Option {
+	return func(e *Engine) {
+		if maxKeys <= 0 {
+			maxKeys = DefaultLiveStatsKeys
+		}
+		e.liveStatsKeys = maxKeys
+	}
+}
+
+// WithSLOClassifier sets how a ValidateKey result counts against SLO error
+// budgets. fn receives the error ValidateKey returned, nil on success.
+// Defaults to [DefaultSLOClassifier].
+func WithSLOClassifier(fn func(err error) slo.Outcome) Option {
+	return func(e *Engine) {
+		if fn != nil {
+			e.sloClassify = fn
+		}
+	}
+}
+
+// WithKeyTransferTTL sets how long a key transfer waits for the target
+// tenant to accept it before it expires. Non-positive values keep
+// DefaultKeyTransferTTL.
+func WithKeyTransferTTL(d time.Duration) Option {
+	return func(e *Engine) {
+		if d > 0 {
+			e.transferTTL = d
+		}
+	}
+}
+
+// WithKeyTransferUsageRetag moves a transferred key's usage records to the
+// target tenant when the transfer is accepted, so that the key's history
+// goes with it. Off by default: the records stay with the source tenant,
+// which served the requests. The setting is captured when a transfer is
+// initiated.
+func WithKeyTransferUsageRetag(retag bool) Option {
+	return func(e *Engine) { e.transferRetag = retag }
+}
@@ -0,0 +1,167 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/policy"
+)
+
+type ctxKeyValidationRequest struct{}
+
+// ValidationRequest describes the request a key is presented with, for
+// checks that depend on the caller rather than the key alone. The
+// middleware fills it from the incoming *http.Request.
+type ValidationRequest struct {
+	// Origin is the request's Origin header, or the scheme and host of its
+	// Referer when Origin is absent.
+	Origin string
+
+	// ConsumerService names the internal service presenting the key,
+	// compared with the key's IntendedConsumer. The middleware reads it
+	// from the header set with middleware.WithConsumerHeader.
+	ConsumerService string
+
+	// AcceptPrefixes, when set, lists the key prefixes the caller accepts,
+	// such as only "sk" on a server-side endpoint. A key with another
+	// prefix fails with ErrPrefixNotAccepted before it is looked up.
+	AcceptPrefixes []string
+
+	// TLSVersion is the negotiated TLS version, a crypto/tls constant such
+	// as tls.VersionTLS13, or zero for plain HTTP. It is checked against
+	// the policy's MinTLSVersion.
+	TLSVersion uint16
+
+	// ClientCertFingerprint is the SHA-256 fingerprint of the client
+	// certificate presented on the connection, as key.CertFingerprint
+	// returns it, or empty when there was none. It is checked against the
+	// key's CertFingerprint and the policy's RequireMTLS.
+	ClientCertFingerprint string
+
+	// Method, Path, and RemoteAddr describe the HTTP request, as in
+	// http.Request. RemoteAddr is "host:port" or a bare IP. The engine
+	// checks Method and Path against the policy's AllowedMethods and
+	// AllowedPaths, and RemoteAddr against its AllowedIPs when ClientIP is
+	// empty. They and Attributes are also passed to the [Authorizer].
+	Method     string
+	Path       string
+	RemoteAddr string
+
+	// ClientIP is the address of the client behind any trusted proxies,
+	// checked against the policy's AllowedIPs in place of RemoteAddr. The
+	// middleware sets it from X-Forwarded-For when built with
+	// middleware.WithTrustedProxies.
+	ClientIP string
+
+	// Attributes carry the caller's own facts about the request, such as a
+	// country or risk score from the gateway, to the Authorizer.
+	Attributes map[string]string
+
+	// BypassCache reads the key from the store rather than the validation
+	// cache, for a check that must see changes made by other engine
+	// instances at once, such as before a payout. The fresh read replaces
+	// the cached entry. See [WithValidationCache].
+	BypassCache bool
+}
+
+// WithValidationRequest returns a copy of ctx that carries r for
+// ValidateKey.
+func WithValidationRequest(ctx context.Context, r ValidationRequest) context.Context {
+	return context.WithValue(ctx, ctxKeyValidationRequest{}, r)
+}
+
+// ValidationRequestFromContext returns the request set with
+// [WithValidationRequest], or a zero value.
+func ValidationRequestFromContext(ctx context.Context) ValidationRequest {
+	r, _ := ctx.Value(ctxKeyValidationRequest{}).(ValidationRequest)
+	return r
+}
+
+// effectiveOrigins returns the origin allowlist for k: its own list when
+// set, otherwise its policy's.
+func effectiveOrigins(k *key.Key, pol *policy.Policy) []string {
+	if len(k.AllowedOrigins) > 0 {
+		return k.AllowedOrigins
+	}
+	if pol != nil {
+		return pol.AllowedOrigins
+	}
+	return nil
+}
+
+// checkOrigin rejects a validation whose request origin is not in the key's
+// effective allowlist. A request without an origin is rejected when a list
+// is set, since the key is then meant for browsers only.
+func checkOrigin(ctx context.Context, k *key.Key, pol *policy.Policy) error {
+	allowed := effectiveOrigins(k, pol)
+	if len(allowed) == 0 {
+		return nil
+	}
+	origin := ValidationRequestFromContext(ctx).Origin
+	if origin == "" || !originAllowed(allowed, origin) {
+		return ErrOriginNotAllowed
+	}
+	return nil
+}
+
+// originAllowed reports whether origin matches an entry of allowed. An entry
+// is "*", a full origin such as "https://app.example.com", or a wildcard
+// subdomain such as "https://*.example.com", which matches any subdomain of
+// example.com but not example.com itself. Entries without a scheme match
+// either scheme. Matching ignores case and a trailing slash.
+func originAllowed(allowed []string, origin string) bool {
+	scheme, host := splitOrigin(origin)
+	if host == "" {
+		return false
+	}
+	for _, entry := range allowed {
+		if entry == "*" {
+			return true
+		}
+		es, eh := splitOrigin(entry)
+		if es != "" && es != scheme {
+			continue
+		}
+		if suffix, ok := strings.CutPrefix(eh, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if eh == host {
+			return true
+		}
+	}
+	return false
+}
+
+// splitOrigin lowercases an origin and splits it into scheme and host. The
+// scheme is empty when the origin has none.
+func splitOrigin(origin string) (scheme, host string) {
+	origin = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(origin)), "/")
+	if s, h, ok := strings.Cut(origin, "://"); ok {
+		return s, h
+	}
+	return "", origin
+}
+
+// validateOrigins checks an allowlist at write time, so a malformed entry
+// is rejected instead of silently never matching.
+func validateOrigins(origins []string) error {
+	for _, entry := range origins {
+		if entry == "*" {
+			continue
+		}
+		_, host := splitOrigin(entry)
+		wildcard := strings.HasPrefix(host, "*.")
+		if wildcard {
+			host = host[2:]
+		}
+		if host == "" || strings.ContainsAny(host, "*/?#@ ") {
+			return fmt.Errorf("%w: %q", ErrInvalidOrigin, entry)
+		}
+	}
+	return nil
+}
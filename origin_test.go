@@ -0,0 +1,162 @@
+package keysmith_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/policy"
+)
+
+func originCtx(origin string) context.Context {
+	return keysmith.WithValidationRequest(testCtx(), keysmith.ValidationRequest{Origin: origin})
+}
+
+func createOriginKey(t *testing.T, eng *keysmith.Engine, polID *id.PolicyID, origins []string) *key.CreateResult {
+	t.Helper()
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+		Name:           "Browser Key",
+		Prefix:         "pk",
+		Environment:    key.EnvTest,
+		PolicyID:       polID,
+		AllowedOrigins: origins,
+	})
+	require.NoError(t, err)
+	return created
+}
+
+func TestAllowedOrigins_KeyOverridesPolicy(t *testing.T) {
+	eng := newTestEngine(t)
+	pol := &policy.Policy{Name: "Web", AllowedOrigins: []string{"https://policy.example.com"}}
+	require.NoError(t, eng.CreatePolicy(testCtx(), pol))
+
+	created := createOriginKey(t, eng, &pol.ID, []string{"https://key.example.com"})
+	assert.Equal(t, []string{"https://key.example.com"}, created.Key.AllowedOrigins)
+
+	_, err := eng.ValidateKey(originCtx("https://key.example.com"), created.RawKey)
+	require.NoError(t, err)
+
+	_, err = eng.ValidateKey(originCtx("https://policy.example.com"), created.RawKey)
+	assert.ErrorIs(t, err, keysmith.ErrOriginNotAllowed, "the key's list replaces the policy's")
+}
+
+func TestAllowedOrigins_EmptyFallsBackToPolicy(t *testing.T) {
+	eng := newTestEngine(t)
+	pol := &policy.Policy{Name: "Web", AllowedOrigins: []string{"https://policy.example.com"}}
+	require.NoError(t, eng.CreatePolicy(testCtx(), pol))
+
+	created := createOriginKey(t, eng, &pol.ID, nil)
+
+	_, err := eng.ValidateKey(originCtx("https://policy.example.com"), created.RawKey)
+	require.NoError(t, err)
+
+	_, err = eng.ValidateKey(originCtx("https://other.example.com"), created.RawKey)
+	assert.ErrorIs(t, err, keysmith.ErrOriginNotAllowed)
+
+	_, err = eng.ValidateKey(testCtx(), created.RawKey)
+	assert.ErrorIs(t, err, keysmith.ErrOriginNotAllowed, "a missing origin is rejected when a list applies")
+}
+
+func TestAllowedOrigins_NoListAllowsAnyOrigin(t *testing.T) {
+	eng := newTestEngine(t)
+	created := createOriginKey(t, eng, nil, nil)
+
+	_, err := eng.ValidateKey(originCtx("https://anywhere.example.org"), created.RawKey)
+	require.NoError(t, err)
+	_, err = eng.ValidateKey(testCtx(), created.RawKey)
+	require.NoError(t, err)
+}
+
+func TestAllowedOrigins_Matching(t *testing.T) {
+	eng := newTestEngine(t)
+	created := createOriginKey(t, eng, nil, []string{
+		"https://*.example.com",
+		"app.example.org",
+	})
+
+	tests := []struct {
+		origin string
+		ok     bool
+	}{
+		{"https://a.example.com", true},
+		{"https://deep.a.example.com", true},
+		{"HTTPS://A.Example.com/", true},
+		{"https://example.com", false},
+		{"http://a.example.com", false},
+		{"https://a.example.com.evil.io", false},
+		{"https://badexample.com", false},
+		{"https://app.example.org", true},
+		{"http://app.example.org", true},
+		{"https://x.app.example.org", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			_, err := eng.ValidateKey(originCtx(tt.origin), created.RawKey)
+			if tt.ok {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, keysmith.ErrOriginNotAllowed)
+		})
+	}
+}
+
+func TestAllowedOrigins_RejectsInvalidEntries(t *testing.T) {
+	eng := newTestEngine(t)
+	for _, entry := range []string{"", "https://", "https://a.*.example.com", "https://example.com/path"} {
+		_, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+			Name:           "Bad Origins",
+			Prefix:         "pk",
+			Environment:    key.EnvTest,
+			AllowedOrigins: []string{entry},
+		})
+		assert.ErrorIs(t, err, keysmith.ErrInvalidOrigin, entry)
+	}
+}
+
+func TestUpdateKey_AllowedOrigins(t *testing.T) {
+	eng := newTestEngine(t)
+	pol := &policy.Policy{Name: "Web", AllowedOrigins: []string{"https://policy.example.com"}}
+	require.NoError(t, eng.CreatePolicy(testCtx(), pol))
+	created := createOriginKey(t, eng, &pol.ID, nil)
+
+	updated, err := eng.UpdateKey(testCtx(), created.Key.ID, &keysmith.UpdateKeyInput{
+		AllowedOrigins: []string{"https://*.example.com"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://*.example.com"}, updated.AllowedOrigins)
+	assert.Equal(t, "Browser Key", updated.Name, "unset fields are unchanged")
+
+	_, err = eng.ValidateKey(originCtx("https://a.example.com"), created.RawKey)
+	require.NoError(t, err, "the update takes effect immediately")
+
+	// An empty list clears the override.
+	updated, err = eng.UpdateKey(testCtx(), created.Key.ID, &keysmith.UpdateKeyInput{AllowedOrigins: []string{}})
+	require.NoError(t, err)
+	assert.Empty(t, updated.AllowedOrigins)
+
+	_, err = eng.ValidateKey(originCtx("https://a.example.com"), created.RawKey)
+	assert.ErrorIs(t, err, keysmith.ErrOriginNotAllowed)
+
+	_, err = eng.UpdateKey(testCtx(), created.Key.ID, &keysmith.UpdateKeyInput{AllowedOrigins: []string{"*.*"}})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidOrigin)
+}
+
+func TestUpdateKey_DryRun(t *testing.T) {
+	eng := newTestEngine(t)
+	created := createOriginKey(t, eng, nil, nil)
+
+	name := "Renamed"
+	updated, err := eng.UpdateKey(keysmith.WithDryRun(testCtx()), created.Key.ID, &keysmith.UpdateKeyInput{Name: &name})
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", updated.Name)
+
+	fetched, err := eng.GetKey(testCtx(), created.Key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Browser Key", fetched.Name)
+}
@@ -0,0 +1,51 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xraph/keysmith/key"
+)
+
+// MaxOverviewWindows is the most expiring windows one TenantKeyOverview
+// call may count over.
+const MaxOverviewWindows = 8
+
+// TenantKeyOverview counts the keys visible from the context by state,
+// environment, and lifecycle window, in one store query. The tenant and app
+// of q are narrowed to the context's scope like a ListKeys filter.
+//
+// A nil q, or one with its windows unset, counts active keys expiring
+// within 7, 30, and 90 days and keys created within the last 30. A zero
+// q.Now is the engine's clock.
+func (e *Engine) TenantKeyOverview(ctx context.Context, q *key.OverviewQuery) (*key.Overview, error) {
+	out := key.OverviewQuery{}
+	if q != nil {
+		out = *q
+	}
+	out.TenantID, out.AppID = scopeFromContext(ctx).narrow(out.TenantID, out.AppID)
+	if out.Now.IsZero() {
+		out.Now = e.now()
+	}
+	if out.ExpiringWithin == nil {
+		out.ExpiringWithin = []time.Duration{7 * 24 * time.Hour, 30 * 24 * time.Hour, 90 * 24 * time.Hour}
+	}
+	if out.CreatedWithin == 0 {
+		out.CreatedWithin = 30 * 24 * time.Hour
+	}
+
+	if len(out.ExpiringWithin) > MaxOverviewWindows {
+		return nil, fmt.Errorf("%w: %d expiring windows, at most %d are allowed",
+			ErrInvalidOverviewQuery, len(out.ExpiringWithin), MaxOverviewWindows)
+	}
+	for i, w := range out.ExpiringWithin {
+		if w <= 0 || (i > 0 && w <= out.ExpiringWithin[i-1]) {
+			return nil, fmt.Errorf("%w: expiring windows must be positive and ascending", ErrInvalidOverviewQuery)
+		}
+	}
+	if out.CreatedWithin < 0 {
+		return nil, fmt.Errorf("%w: created window must be positive", ErrInvalidOverviewQuery)
+	}
+	return e.store.Keys().Overview(ctx, &out)
+}
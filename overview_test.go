@@ -0,0 +1,68 @@
+package keysmith_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keysmithtest"
+)
+
+func TestTenantKeyOverview(t *testing.T) {
+	eng := keysmithtest.NewEngine(t)
+	ctx := keysmithtest.Context()
+	const day = 24 * time.Hour
+	now := time.Now()
+
+	soon := keysmithtest.NewKey(eng).WithExpiry(now.Add(10*day)).MustCreate(t, ctx)
+	keysmithtest.NewKey(eng).WithExpiry(now.Add(60*day)).WithEnvironment(key.EnvLive).MustCreate(t, ctx)
+	keysmithtest.NewKey(eng).WithExpiry(now.Add(day)).Revoked().MustCreate(t, ctx)
+	keysmithtest.NewKey(eng).WithExpiry(now.Add(day)).MustCreate(t, keysmithtest.TenantContext("tenant_other"))
+	require.NoError(t, eng.Store().Keys().UpdateLastUsed(ctx, soon.Key.ID, now))
+
+	o, err := eng.TenantKeyOverview(ctx, nil)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, o.Total, "only the context's tenant is counted")
+	assert.Equal(t, map[key.State]int64{key.StateActive: 2, key.StateRevoked: 1}, o.ByState)
+	assert.Equal(t, []key.WindowCount{
+		{Within: 7 * day, Count: 0},
+		{Within: 30 * day, Count: 1},
+		{Within: 90 * day, Count: 2},
+	}, o.Expiring)
+	assert.EqualValues(t, 1, o.NeverUsed)
+	assert.Equal(t, key.WindowCount{Within: 30 * day, Count: 3}, o.CreatedRecently)
+
+	// A tenant-scoped caller cannot widen the query to another tenant.
+	o, err = eng.TenantKeyOverview(ctx, &key.OverviewQuery{TenantID: "tenant_other"})
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, o.Total)
+
+	o, err = eng.TenantKeyOverview(context.Background(), &key.OverviewQuery{
+		TenantID:       "tenant_other",
+		ExpiringWithin: []time.Duration{2 * day},
+		CreatedWithin:  time.Minute,
+	})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, o.Total)
+	assert.Equal(t, []key.WindowCount{{Within: 2 * day, Count: 1}}, o.Expiring)
+	assert.Equal(t, key.WindowCount{Within: time.Minute, Count: 1}, o.CreatedRecently)
+}
+
+func TestTenantKeyOverview_InvalidQuery(t *testing.T) {
+	eng := keysmithtest.NewEngine(t)
+	for name, q := range map[string]*key.OverviewQuery{
+		"negative window":  {ExpiringWithin: []time.Duration{-time.Hour}},
+		"descending":       {ExpiringWithin: []time.Duration{48 * time.Hour, 24 * time.Hour}},
+		"duplicate":        {ExpiringWithin: []time.Duration{time.Hour, time.Hour}},
+		"too many windows": {ExpiringWithin: make([]time.Duration, keysmith.MaxOverviewWindows+1)},
+		"negative created": {CreatedWithin: -time.Hour},
+	} {
+		_, err := eng.TenantKeyOverview(keysmithtest.Context(), q)
+		assert.ErrorIs(t, err, keysmith.ErrInvalidOverviewQuery, name)
+	}
+}
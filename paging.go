@@ -0,0 +1,59 @@
+package keysmith
+
+import (
+	"context"
+
+	"github.com/xraph/keysmith/scope"
+)
+
+// Page sizes of the engine's list methods. A list call never returns more
+// than the max page size, so that a caller forgetting a limit cannot pull a
+// whole table; IterateKeys and IterateScopes visit every match instead.
+const (
+	// DefaultPageSize is the Limit ListKeys, ListPolicies, ListScopes,
+	// ListRotations, and ListKeysByCreator use for a zero or negative
+	// filter Limit.
+	DefaultPageSize = 50
+
+	// DefaultUsagePageSize is the Limit QueryUsage and AggregateUsage use
+	// for a zero or negative filter Limit.
+	DefaultUsagePageSize = 100
+
+	// DefaultMaxPageSize is the largest Limit a list method honors, unless
+	// WithMaxPageSize sets another. Larger limits are clamped to it.
+	DefaultMaxPageSize = 1000
+)
+
+// PageLimit returns the Limit a list method uses when asked for limit: def
+// for a zero or negative limit, and at most the max page size. The REST API
+// reports it, so that a client can tell its limit was clamped.
+func (e *Engine) PageLimit(limit, def int) int {
+	if limit <= 0 {
+		limit = def
+	}
+	return min(limit, e.maxPageSize)
+}
+
+// IterateScopes calls fn for every scope matching the filter, restricted to
+// the context's tenant and app, reading them a page of the max page size at
+// a time. The filter's Limit and Offset are ignored. Iteration stops at the
+// first error from fn or the store, and that error is returned.
+func (e *Engine) IterateScopes(ctx context.Context, filter *scope.ListFilter, fn func(*scope.Scope) error) error {
+	f := scopeFilter(ctx, filter)
+	f.Limit, f.Offset = e.maxPageSize, 0
+	for {
+		page, err := e.store.Scopes().List(ctx, f)
+		if err != nil {
+			return err
+		}
+		for _, s := range page {
+			if err := fn(s); err != nil {
+				return err
+			}
+		}
+		if len(page) < f.Limit {
+			return nil
+		}
+		f.Offset += len(page)
+	}
+}
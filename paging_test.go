@@ -0,0 +1,86 @@
+package keysmith_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+func TestPageLimit(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()))
+	require.NoError(t, err)
+
+	assert.Equal(t, keysmith.DefaultPageSize, eng.PageLimit(0, keysmith.DefaultPageSize))
+	assert.Equal(t, keysmith.DefaultUsagePageSize, eng.PageLimit(-1, keysmith.DefaultUsagePageSize))
+	assert.Equal(t, 7, eng.PageLimit(7, keysmith.DefaultPageSize))
+	assert.Equal(t, keysmith.DefaultMaxPageSize, eng.PageLimit(keysmith.DefaultMaxPageSize, keysmith.DefaultPageSize))
+	assert.Equal(t, keysmith.DefaultMaxPageSize, eng.PageLimit(keysmith.DefaultMaxPageSize+1, keysmith.DefaultPageSize))
+}
+
+// newPagedEngine returns an engine with a max page size of 3 and five keys
+// and scopes in testCtx's tenant.
+func newPagedEngine(t *testing.T) *keysmith.Engine {
+	t.Helper()
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithMaxPageSize(3))
+	require.NoError(t, err)
+	for i := range 5 {
+		_, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "Paged", Prefix: "sk", Environment: key.EnvTest})
+		require.NoError(t, err)
+		require.NoError(t, eng.CreateScope(testCtx(), &scope.Scope{Name: fmt.Sprintf("read:%d", i)}))
+	}
+	return eng
+}
+
+func TestListLimits_ClampedToMax(t *testing.T) {
+	eng := newPagedEngine(t)
+
+	for limit, want := range map[int]int{-1: 3, 0: 3, 2: 2, 3: 3, 4: 3} {
+		keys, err := eng.ListKeys(testCtx(), &key.ListFilter{Limit: limit})
+		require.NoError(t, err)
+		assert.Len(t, keys, want, "keys with limit %d", limit)
+
+		scopes, err := eng.ListScopes(testCtx(), &scope.ListFilter{Limit: limit})
+		require.NoError(t, err)
+		assert.Len(t, scopes, want, "scopes with limit %d", limit)
+	}
+
+	keys, err := eng.ListKeys(testCtx(), nil)
+	require.NoError(t, err)
+	assert.Len(t, keys, 3, "a nil filter gets the default, clamped")
+}
+
+func TestListLimits_FilterNotModified(t *testing.T) {
+	eng := newPagedEngine(t)
+
+	filter := &scope.ListFilter{Limit: 500}
+	_, err := eng.ListScopes(testCtx(), filter)
+	require.NoError(t, err)
+	assert.Equal(t, 500, filter.Limit)
+}
+
+func TestIterateScopes_PagesPastMax(t *testing.T) {
+	eng := newPagedEngine(t)
+
+	var names []string
+	require.NoError(t, eng.IterateScopes(testCtx(), &scope.ListFilter{Limit: 1}, func(s *scope.Scope) error {
+		names = append(names, s.Name)
+		return nil
+	}))
+	assert.ElementsMatch(t, []string{"read:0", "read:1", "read:2", "read:3", "read:4"}, names)
+
+	stop := fmt.Errorf("stop")
+	var visited int
+	err := eng.IterateScopes(testCtx(), nil, func(*scope.Scope) error {
+		visited++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, visited)
+}
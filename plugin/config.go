@@ -0,0 +1,20 @@
+package plugin
+
+// ConfigChange describes a change to the engine's runtime configuration.
+// Values are strings: durations as [time.Duration.String] formats them and
+// counts in decimal. A setting that is off reads "0".
+type ConfigChange struct {
+	// Changes lists the settings whose values changed.
+	Changes []ConfigFieldChange `json:"changes"`
+
+	// Config holds every runtime setting after the change, by field name.
+	Config map[string]string `json:"config"`
+}
+
+// ConfigFieldChange is one runtime setting's value before and after a
+// change.
+type ConfigFieldChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
@@ -2,196 +2,625 @@ package plugin
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
 
+	log "github.com/xraph/go-utils/log"
+
+	"github.com/xraph/keysmith/group"
 	"github.com/xraph/keysmith/id"
 	"github.com/xraph/keysmith/key"
 	"github.com/xraph/keysmith/policy"
 	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/tenant"
+	"github.com/xraph/keysmith/transfer"
+	"github.com/xraph/keysmith/usage"
+)
+
+// DefaultHookTimeout bounds how long a single hook call may run before the
+// dispatch moves on to the next plugin.
+const DefaultHookTimeout = 5 * time.Second
+
+var (
+	// ErrHookPanicked is wrapped by the HookError returned when a hook panics.
+	ErrHookPanicked = errors.New("keysmith/plugin: hook panicked")
+
+	// ErrHookTimeout is wrapped by the HookError returned when a hook runs
+	// past the manager's hook timeout.
+	ErrHookTimeout = errors.New("keysmith/plugin: hook timed out")
 )
 
+// HookError reports a hook call that panicked or timed out, tagged with the
+// plugin and hook it came from.
+type HookError struct {
+	Plugin string
+	Hook   string
+	Err    error
+}
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("keysmith/plugin: %s.%s: %v", e.Plugin, e.Hook, e.Err)
+}
+
+func (e *HookError) Unwrap() error { return e.Err }
+
 // Manager holds registered plugins and dispatches lifecycle events.
+//
+// Every hook call is guarded: a panic is recovered and a call that runs past
+// the hook timeout is abandoned. Either way the failure is logged, returned
+// as a *HookError once the remaining plugins have run, and never takes down
+// the caller. An error returned by a hook still stops the dispatch.
 type Manager struct {
 	plugins []Plugin
+
+	// validated counts the plugins with a KeyValidated hook, so that
+	// validations skip the dispatch when there are none.
+	validated int
+
+	mu      sync.RWMutex
+	timeout time.Duration
+	logger  log.Logger
 }
 
-// NewManager creates a new plugin manager.
+// NewManager creates a new plugin manager with DefaultHookTimeout.
 func NewManager() *Manager {
-	return &Manager{}
+	return &Manager{
+		timeout: DefaultHookTimeout,
+		logger:  log.NewNoopLogger(),
+	}
 }
 
 // Register adds a plugin.
-func (m *Manager) Register(p Plugin) { m.plugins = append(m.plugins, p) }
+func (m *Manager) Register(p Plugin) {
+	m.plugins = append(m.plugins, p)
+	_, v1 := p.(KeyValidated)
+	_, v2 := p.(KeyValidatedV2)
+	if v1 || v2 {
+		m.validated++
+	}
+}
 
-// ── Key lifecycle dispatch ────────────────────────
+// SetTimeout sets the per-hook timeout. Zero or less disables it, and hooks
+// then run on the caller's goroutine with no deadline.
+//
+// A timed-out hook cannot be stopped: it keeps running in the background
+// with a cancelled context, and its result is discarded.
+func (m *Manager) SetTimeout(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.timeout = d
+}
 
-// FireKeyCreated dispatches to all plugins that implement KeyCreated.
-func (m *Manager) FireKeyCreated(ctx context.Context, k *key.Key) error {
-	for _, p := range m.plugins {
-		if h, ok := p.(KeyCreated); ok {
-			if err := h.OnKeyCreated(ctx, k); err != nil {
-				return err
-			}
-		}
-	}
-	return nil
+// SetLogger sets the logger used to report panicking and timed-out hooks.
+func (m *Manager) SetLogger(l log.Logger) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logger = l
 }
 
-// FireKeyCreateFailed dispatches to all plugins that implement KeyCreateFailed.
-func (m *Manager) FireKeyCreateFailed(ctx context.Context, k *key.Key, createErr error) error {
-	for _, p := range m.plugins {
-		if h, ok := p.(KeyCreateFailed); ok {
-			if err := h.OnKeyCreateFailed(ctx, k, createErr); err != nil {
-				return err
-			}
-		}
-	}
-	return nil
+func (m *Manager) config() (time.Duration, log.Logger) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.timeout, m.logger
 }
 
-// FireKeyValidated dispatches to all plugins that implement KeyValidated.
-func (m *Manager) FireKeyValidated(ctx context.Context, k *key.Key) error {
-	for _, p := range m.plugins {
-		if h, ok := p.(KeyValidated); ok {
-			if err := h.OnKeyValidated(ctx, k); err != nil {
-				return err
-			}
-		}
+// dispatch calls the hook for every plugin, in registration order: v2 for
+// plugins implementing V2, otherwise v1 for those implementing V1. A zero
+// meta.Timestamp is set to the current time. A hook error stops the dispatch
+// and is returned as is; panics and timeouts are logged and joined into the
+// result after the remaining plugins run.
+func dispatch[V1, V2 any](
+	ctx context.Context, m *Manager, hook string, meta EventMeta,
+	v1 func(context.Context, V1) error,
+	v2 func(context.Context, V2, EventMeta) error,
+) error {
+	timeout, logger := m.config()
+	if meta.Timestamp.IsZero() {
+		meta.Timestamp = time.Now()
 	}
-	return nil
-}
 
-// FireKeyValidationFailed dispatches to all plugins that implement KeyValidationFailed.
-func (m *Manager) FireKeyValidationFailed(ctx context.Context, rawKey string, validationErr error) error {
+	var contained error
 	for _, p := range m.plugins {
-		if h, ok := p.(KeyValidationFailed); ok {
-			if err := h.OnKeyValidationFailed(ctx, rawKey, validationErr); err != nil {
-				return err
-			}
+		var (
+			name = hook
+			fn   func(context.Context) error
+		)
+		if h, ok := p.(V2); ok {
+			name += "V2"
+			fn = func(ctx context.Context) error { return v2(ctx, h, meta) }
+		} else if h, ok := p.(V1); ok {
+			fn = func(ctx context.Context) error { return v1(ctx, h) }
+		} else {
+			continue
 		}
-	}
-	return nil
-}
 
-// FireKeyRotated dispatches to all plugins that implement KeyRotated.
-func (m *Manager) FireKeyRotated(ctx context.Context, k *key.Key, rec *rotation.Record) error {
-	for _, p := range m.plugins {
-		if h, ok := p.(KeyRotated); ok {
-			if err := h.OnKeyRotated(ctx, k, rec); err != nil {
-				return err
-			}
+		err := m.call(ctx, p, name, timeout, fn)
+		if err == nil {
+			continue
+		}
+		var he *HookError
+		if !errors.As(err, &he) {
+			return err
 		}
-	}
-	return nil
-}
 
-// FireKeyRevoked dispatches to all plugins that implement KeyRevoked.
-func (m *Manager) FireKeyRevoked(ctx context.Context, k *key.Key, reason string) error {
-	for _, p := range m.plugins {
-		if h, ok := p.(KeyRevoked); ok {
-			if err := h.OnKeyRevoked(ctx, k, reason); err != nil {
-				return err
-			}
+		logger.Error("keysmith plugin hook failed",
+			log.String("plugin", he.Plugin),
+			log.String("hook", he.Hook),
+			log.Any("error", he.Err),
+		)
+		contained = errors.Join(contained, err)
+
+		if errors.Is(err, ErrHookPanicked) && hook != "OnPluginPanicked" {
+			panicMeta := meta
+			panicMeta.ReasonCode = ReasonHookPanicked
+			_ = m.FirePluginPanicked(ctx, he, panicMeta)
 		}
 	}
-	return nil
+	return contained
 }
 
-// FireKeySuspended dispatches to all plugins that implement KeySuspended.
-func (m *Manager) FireKeySuspended(ctx context.Context, k *key.Key) error {
-	for _, p := range m.plugins {
-		if h, ok := p.(KeySuspended); ok {
-			if err := h.OnKeySuspended(ctx, k); err != nil {
-				return err
-			}
-		}
+// call runs fn for plugin p, recovering panics and enforcing timeout.
+func (m *Manager) call(ctx context.Context, p Plugin, hook string, timeout time.Duration, fn func(context.Context) error) error {
+	if timeout <= 0 {
+		return invoke(ctx, p, hook, fn)
 	}
-	return nil
-}
 
-// FireKeyReactivated dispatches to all plugins that implement KeyReactivated.
-func (m *Manager) FireKeyReactivated(ctx context.Context, k *key.Key) error {
-	for _, p := range m.plugins {
-		if h, ok := p.(KeyReactivated); ok {
-			if err := h.OnKeyReactivated(ctx, k); err != nil {
-				return err
-			}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- invoke(ctx, p, hook, fn) }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		err := ctx.Err()
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("%w after %s", ErrHookTimeout, timeout)
 		}
+		return &HookError{Plugin: p.Name(), Hook: hook, Err: err}
 	}
-	return nil
 }
 
-// FireKeyExpired dispatches to all plugins that implement KeyExpired.
-func (m *Manager) FireKeyExpired(ctx context.Context, k *key.Key) error {
-	for _, p := range m.plugins {
-		if h, ok := p.(KeyExpired); ok {
-			if err := h.OnKeyExpired(ctx, k); err != nil {
-				return err
+// invoke calls fn, converting a panic into a *HookError.
+func invoke(ctx context.Context, p Plugin, hook string, fn func(context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &HookError{
+				Plugin: p.Name(),
+				Hook:   hook,
+				Err:    fmt.Errorf("%w: %v\n%s", ErrHookPanicked, r, debug.Stack()),
 			}
 		}
-	}
-	return nil
+	}()
+	return fn(ctx)
 }
 
-// FireKeyRateLimited dispatches to all plugins that implement KeyRateLimited.
-func (m *Manager) FireKeyRateLimited(ctx context.Context, k *key.Key) error {
-	for _, p := range m.plugins {
-		if h, ok := p.(KeyRateLimited); ok {
-			if err := h.OnKeyRateLimited(ctx, k); err != nil {
-				return err
-			}
-		}
+// ── Key lifecycle dispatch ────────────────────────
+
+// FireKeyCreated dispatches to all plugins that implement KeyCreated or KeyCreatedV2.
+func (m *Manager) FireKeyCreated(ctx context.Context, k *key.Key, meta EventMeta) error {
+	return dispatch(ctx, m, "OnKeyCreated", meta,
+		func(ctx context.Context, h KeyCreated) error {
+			return h.OnKeyCreated(ctx, k)
+		},
+		func(ctx context.Context, h KeyCreatedV2, meta EventMeta) error {
+			return h.OnKeyCreatedV2(ctx, k, meta)
+		},
+	)
+}
+
+// FireKeyCreateFailed dispatches to all plugins that implement KeyCreateFailed or KeyCreateFailedV2.
+func (m *Manager) FireKeyCreateFailed(ctx context.Context, k *key.Key, createErr error, meta EventMeta) error {
+	return dispatch(ctx, m, "OnKeyCreateFailed", meta,
+		func(ctx context.Context, h KeyCreateFailed) error {
+			return h.OnKeyCreateFailed(ctx, k, createErr)
+		},
+		func(ctx context.Context, h KeyCreateFailedV2, meta EventMeta) error {
+			return h.OnKeyCreateFailedV2(ctx, k, createErr, meta)
+		},
+	)
+}
+
+// FireKeyValidated dispatches to all plugins that implement KeyValidated or KeyValidatedV2.
+func (m *Manager) FireKeyValidated(ctx context.Context, k *key.Key, meta EventMeta) error {
+	if m.validated == 0 {
+		return nil // spares the hot path the dispatch closures
 	}
-	return nil
+	return dispatch(ctx, m, "OnKeyValidated", meta,
+		func(ctx context.Context, h KeyValidated) error {
+			return h.OnKeyValidated(ctx, k)
+		},
+		func(ctx context.Context, h KeyValidatedV2, meta EventMeta) error {
+			return h.OnKeyValidatedV2(ctx, k, meta)
+		},
+	)
+}
+
+// FireKeyValidationFailed dispatches to all plugins that implement KeyValidationFailed or KeyValidationFailedV2.
+// Only KeyValidationFailed plugins receive rawKey; KeyValidationFailedV2
+// plugins receive fingerprint.
+func (m *Manager) FireKeyValidationFailed(ctx context.Context, rawKey, fingerprint string, validationErr error, meta EventMeta) error {
+	return dispatch(ctx, m, "OnKeyValidationFailed", meta,
+		func(ctx context.Context, h KeyValidationFailed) error {
+			return h.OnKeyValidationFailed(ctx, rawKey, validationErr)
+		},
+		func(ctx context.Context, h KeyValidationFailedV2, meta EventMeta) error {
+			return h.OnKeyValidationFailedV2(ctx, fingerprint, validationErr, meta)
+		},
+	)
+}
+
+// FireKeyRotated dispatches to all plugins that implement KeyRotated or KeyRotatedV2.
+func (m *Manager) FireKeyRotated(ctx context.Context, k *key.Key, rec *rotation.Record, meta EventMeta) error {
+	return dispatch(ctx, m, "OnKeyRotated", meta,
+		func(ctx context.Context, h KeyRotated) error {
+			return h.OnKeyRotated(ctx, k, rec)
+		},
+		func(ctx context.Context, h KeyRotatedV2, meta EventMeta) error {
+			return h.OnKeyRotatedV2(ctx, k, rec, meta)
+		},
+	)
+}
+
+// FireKeyRevoked dispatches to all plugins that implement KeyRevoked or KeyRevokedV2.
+func (m *Manager) FireKeyRevoked(ctx context.Context, k *key.Key, reason string, meta EventMeta) error {
+	return dispatch(ctx, m, "OnKeyRevoked", meta,
+		func(ctx context.Context, h KeyRevoked) error {
+			return h.OnKeyRevoked(ctx, k, reason)
+		},
+		func(ctx context.Context, h KeyRevokedV2, meta EventMeta) error {
+			return h.OnKeyRevokedV2(ctx, k, reason, meta)
+		},
+	)
+}
+
+// FireKeySuspended dispatches to all plugins that implement KeySuspended or KeySuspendedV2.
+func (m *Manager) FireKeySuspended(ctx context.Context, k *key.Key, meta EventMeta) error {
+	return dispatch(ctx, m, "OnKeySuspended", meta,
+		func(ctx context.Context, h KeySuspended) error {
+			return h.OnKeySuspended(ctx, k)
+		},
+		func(ctx context.Context, h KeySuspendedV2, meta EventMeta) error {
+			return h.OnKeySuspendedV2(ctx, k, meta)
+		},
+	)
+}
+
+// FireKeyReactivated dispatches to all plugins that implement KeyReactivated or KeyReactivatedV2.
+func (m *Manager) FireKeyReactivated(ctx context.Context, k *key.Key, meta EventMeta) error {
+	return dispatch(ctx, m, "OnKeyReactivated", meta,
+		func(ctx context.Context, h KeyReactivated) error {
+			return h.OnKeyReactivated(ctx, k)
+		},
+		func(ctx context.Context, h KeyReactivatedV2, meta EventMeta) error {
+			return h.OnKeyReactivatedV2(ctx, k, meta)
+		},
+	)
+}
+
+// FireKeyExpired dispatches to all plugins that implement KeyExpired or KeyExpiredV2.
+func (m *Manager) FireKeyExpired(ctx context.Context, k *key.Key, meta EventMeta) error {
+	return dispatch(ctx, m, "OnKeyExpired", meta,
+		func(ctx context.Context, h KeyExpired) error {
+			return h.OnKeyExpired(ctx, k)
+		},
+		func(ctx context.Context, h KeyExpiredV2, meta EventMeta) error {
+			return h.OnKeyExpiredV2(ctx, k, meta)
+		},
+	)
+}
+
+// FireKeyRateLimited dispatches to all plugins that implement KeyRateLimited or KeyRateLimitedV2.
+func (m *Manager) FireKeyRateLimited(ctx context.Context, k *key.Key, meta EventMeta) error {
+	return dispatch(ctx, m, "OnKeyRateLimited", meta,
+		func(ctx context.Context, h KeyRateLimited) error {
+			return h.OnKeyRateLimited(ctx, k)
+		},
+		func(ctx context.Context, h KeyRateLimitedV2, meta EventMeta) error {
+			return h.OnKeyRateLimitedV2(ctx, k, meta)
+		},
+	)
+}
+
+// FireTenantRateLimited dispatches to all plugins that implement TenantRateLimited or TenantRateLimitedV2.
+func (m *Manager) FireTenantRateLimited(ctx context.Context, k *key.Key, meta EventMeta) error {
+	return dispatch(ctx, m, "OnTenantRateLimited", meta,
+		func(ctx context.Context, h TenantRateLimited) error {
+			return h.OnTenantRateLimited(ctx, k)
+		},
+		func(ctx context.Context, h TenantRateLimitedV2, meta EventMeta) error {
+			return h.OnTenantRateLimitedV2(ctx, k, meta)
+		},
+	)
+}
+
+// FireKeyAdaptivelyLimited dispatches to all plugins that implement KeyAdaptivelyLimited or KeyAdaptivelyLimitedV2.
+func (m *Manager) FireKeyAdaptivelyLimited(ctx context.Context, k *key.Key, p *key.AdaptivePenalty, meta EventMeta) error {
+	return dispatch(ctx, m, "OnKeyAdaptivelyLimited", meta,
+		func(ctx context.Context, h KeyAdaptivelyLimited) error {
+			return h.OnKeyAdaptivelyLimited(ctx, k, p)
+		},
+		func(ctx context.Context, h KeyAdaptivelyLimitedV2, meta EventMeta) error {
+			return h.OnKeyAdaptivelyLimitedV2(ctx, k, p, meta)
+		},
+	)
+}
+
+// FireKeyQuotaExceeded dispatches to all plugins that implement KeyQuotaExceeded or KeyQuotaExceededV2.
+func (m *Manager) FireKeyQuotaExceeded(ctx context.Context, k *key.Key, q *key.QuotaExceeded, meta EventMeta) error {
+	return dispatch(ctx, m, "OnKeyQuotaExceeded", meta,
+		func(ctx context.Context, h KeyQuotaExceeded) error {
+			return h.OnKeyQuotaExceeded(ctx, k, q)
+		},
+		func(ctx context.Context, h KeyQuotaExceededV2, meta EventMeta) error {
+			return h.OnKeyQuotaExceededV2(ctx, k, q, meta)
+		},
+	)
+}
+
+// FireKeyQuotaForecastWarning dispatches to all plugins that implement KeyQuotaForecastWarning or KeyQuotaForecastWarningV2.
+func (m *Manager) FireKeyQuotaForecastWarning(ctx context.Context, k *key.Key, f *key.QuotaForecast, meta EventMeta) error {
+	return dispatch(ctx, m, "OnKeyQuotaForecastWarning", meta,
+		func(ctx context.Context, h KeyQuotaForecastWarning) error {
+			return h.OnKeyQuotaForecastWarning(ctx, k, f)
+		},
+		func(ctx context.Context, h KeyQuotaForecastWarningV2, meta EventMeta) error {
+			return h.OnKeyQuotaForecastWarningV2(ctx, k, f, meta)
+		},
+	)
+}
+
+// FireKeyRotationOverdue dispatches to all plugins that implement KeyRotationOverdue or KeyRotationOverdueV2.
+func (m *Manager) FireKeyRotationOverdue(ctx context.Context, k *key.Key, due *key.RotationDue, meta EventMeta) error {
+	return dispatch(ctx, m, "OnKeyRotationOverdue", meta,
+		func(ctx context.Context, h KeyRotationOverdue) error {
+			return h.OnKeyRotationOverdue(ctx, k, due)
+		},
+		func(ctx context.Context, h KeyRotationOverdueV2, meta EventMeta) error {
+			return h.OnKeyRotationOverdueV2(ctx, k, due, meta)
+		},
+	)
+}
+
+// FireKeyFormatDeprecated dispatches to all plugins that implement KeyFormatDeprecated or KeyFormatDeprecatedV2.
+func (m *Manager) FireKeyFormatDeprecated(ctx context.Context, k *key.Key, d *key.FormatDeprecation, meta EventMeta) error {
+	return dispatch(ctx, m, "OnKeyFormatDeprecated", meta,
+		func(ctx context.Context, h KeyFormatDeprecated) error {
+			return h.OnKeyFormatDeprecated(ctx, k, d)
+		},
+		func(ctx context.Context, h KeyFormatDeprecatedV2, meta EventMeta) error {
+			return h.OnKeyFormatDeprecatedV2(ctx, k, d, meta)
+		},
+	)
+}
+
+// FireKeyConsumerMismatch dispatches to all plugins that implement KeyConsumerMismatch or KeyConsumerMismatchV2.
+func (m *Manager) FireKeyConsumerMismatch(ctx context.Context, k *key.Key, service string, meta EventMeta) error {
+	return dispatch(ctx, m, "OnKeyConsumerMismatch", meta,
+		func(ctx context.Context, h KeyConsumerMismatch) error {
+			return h.OnKeyConsumerMismatch(ctx, k, service)
+		},
+		func(ctx context.Context, h KeyConsumerMismatchV2, meta EventMeta) error {
+			return h.OnKeyConsumerMismatchV2(ctx, k, service, meta)
+		},
+	)
+}
+
+// FireKeyFlagsChanged dispatches to all plugins that implement KeyFlagsChanged or KeyFlagsChangedV2.
+func (m *Manager) FireKeyFlagsChanged(ctx context.Context, k *key.Key, added, removed key.Flags, meta EventMeta) error {
+	return dispatch(ctx, m, "OnKeyFlagsChanged", meta,
+		func(ctx context.Context, h KeyFlagsChanged) error {
+			return h.OnKeyFlagsChanged(ctx, k, added, removed)
+		},
+		func(ctx context.Context, h KeyFlagsChangedV2, meta EventMeta) error {
+			return h.OnKeyFlagsChangedV2(ctx, k, added, removed, meta)
+		},
+	)
+}
+
+// FireKeyCompromised dispatches to all plugins that implement KeyCompromised or KeyCompromisedV2.
+func (m *Manager) FireKeyCompromised(ctx context.Context, k *key.Key, report *key.CompromiseReport, meta EventMeta) error {
+	return dispatch(ctx, m, "OnKeyCompromised", meta,
+		func(ctx context.Context, h KeyCompromised) error {
+			return h.OnKeyCompromised(ctx, k, report)
+		},
+		func(ctx context.Context, h KeyCompromisedV2, meta EventMeta) error {
+			return h.OnKeyCompromisedV2(ctx, k, report, meta)
+		},
+	)
+}
+
+// FireSuspiciousValidationPattern dispatches to all plugins that implement SuspiciousValidationPattern or SuspiciousValidationPatternV2.
+func (m *Manager) FireSuspiciousValidationPattern(ctx context.Context, p *key.FailurePattern, meta EventMeta) error {
+	return dispatch(ctx, m, "OnSuspiciousValidationPattern", meta,
+		func(ctx context.Context, h SuspiciousValidationPattern) error {
+			return h.OnSuspiciousValidationPattern(ctx, p)
+		},
+		func(ctx context.Context, h SuspiciousValidationPatternV2, meta EventMeta) error {
+			return h.OnSuspiciousValidationPatternV2(ctx, p, meta)
+		},
+	)
+}
+
+// FireKeyDebugEnabled dispatches to all plugins that implement KeyDebugEnabled or KeyDebugEnabledV2.
+func (m *Manager) FireKeyDebugEnabled(ctx context.Context, k *key.Key, meta EventMeta) error {
+	return dispatch(ctx, m, "OnKeyDebugEnabled", meta,
+		func(ctx context.Context, h KeyDebugEnabled) error {
+			return h.OnKeyDebugEnabled(ctx, k)
+		},
+		func(ctx context.Context, h KeyDebugEnabledV2, meta EventMeta) error {
+			return h.OnKeyDebugEnabledV2(ctx, k, meta)
+		},
+	)
+}
+
+// FireKeyExported dispatches to all plugins that implement KeyExported or KeyExportedV2.
+func (m *Manager) FireKeyExported(ctx context.Context, k *key.Key, meta EventMeta) error {
+	return dispatch(ctx, m, "OnKeyExported", meta,
+		func(ctx context.Context, h KeyExported) error {
+			return h.OnKeyExported(ctx, k)
+		},
+		func(ctx context.Context, h KeyExportedV2, meta EventMeta) error {
+			return h.OnKeyExportedV2(ctx, k, meta)
+		},
+	)
+}
+
+// FireKeyImported dispatches to all plugins that implement KeyImported or KeyImportedV2.
+func (m *Manager) FireKeyImported(ctx context.Context, k *key.Key, meta EventMeta) error {
+	return dispatch(ctx, m, "OnKeyImported", meta,
+		func(ctx context.Context, h KeyImported) error {
+			return h.OnKeyImported(ctx, k)
+		},
+		func(ctx context.Context, h KeyImportedV2, meta EventMeta) error {
+			return h.OnKeyImportedV2(ctx, k, meta)
+		},
+	)
+}
+
+// FireUsageIdentifiersErased dispatches to all plugins that implement UsageIdentifiersErased or UsageIdentifiersErasedV2.
+func (m *Manager) FireUsageIdentifiersErased(ctx context.Context, e *usage.Erasure, meta EventMeta) error {
+	return dispatch(ctx, m, "OnUsageIdentifiersErased", meta,
+		func(ctx context.Context, h UsageIdentifiersErased) error {
+			return h.OnUsageIdentifiersErased(ctx, e)
+		},
+		func(ctx context.Context, h UsageIdentifiersErasedV2, meta EventMeta) error {
+			return h.OnUsageIdentifiersErasedV2(ctx, e, meta)
+		},
+	)
+}
+
+// FireTenantLockdownChanged dispatches to all plugins that implement TenantLockdownChanged or TenantLockdownChangedV2.
+func (m *Manager) FireTenantLockdownChanged(ctx context.Context, tenantID string, l *tenant.Lockdown, meta EventMeta) error {
+	return dispatch(ctx, m, "OnTenantLockdownChanged", meta,
+		func(ctx context.Context, h TenantLockdownChanged) error {
+			return h.OnTenantLockdownChanged(ctx, tenantID, l)
+		},
+		func(ctx context.Context, h TenantLockdownChangedV2, meta EventMeta) error {
+			return h.OnTenantLockdownChangedV2(ctx, tenantID, l, meta)
+		},
+	)
+}
+
+// FireRuntimeConfigChanged dispatches to all plugins that implement RuntimeConfigChanged or RuntimeConfigChangedV2.
+func (m *Manager) FireRuntimeConfigChanged(ctx context.Context, change *ConfigChange, meta EventMeta) error {
+	return dispatch(ctx, m, "OnRuntimeConfigChanged", meta,
+		func(ctx context.Context, h RuntimeConfigChanged) error {
+			return h.OnRuntimeConfigChanged(ctx, change)
+		},
+		func(ctx context.Context, h RuntimeConfigChangedV2, meta EventMeta) error {
+			return h.OnRuntimeConfigChangedV2(ctx, change, meta)
+		},
+	)
+}
+
+// FireStorageReportGenerated dispatches to all plugins that implement StorageReportGenerated or StorageReportGeneratedV2.
+func (m *Manager) FireStorageReportGenerated(ctx context.Context, report *store.StorageReport, meta EventMeta) error {
+	return dispatch(ctx, m, "OnStorageReportGenerated", meta,
+		func(ctx context.Context, h StorageReportGenerated) error {
+			return h.OnStorageReportGenerated(ctx, report)
+		},
+		func(ctx context.Context, h StorageReportGeneratedV2, meta EventMeta) error {
+			return h.OnStorageReportGeneratedV2(ctx, report, meta)
+		},
+	)
+}
+
+// FirePluginPanicked dispatches to all plugins that implement PluginPanicked or PluginPanickedV2.
+// The manager calls it after recovering a hook panic.
+func (m *Manager) FirePluginPanicked(ctx context.Context, he *HookError, meta EventMeta) error {
+	return dispatch(ctx, m, "OnPluginPanicked", meta,
+		func(ctx context.Context, h PluginPanicked) error {
+			return h.OnPluginPanicked(ctx, he)
+		},
+		func(ctx context.Context, h PluginPanickedV2, meta EventMeta) error {
+			return h.OnPluginPanickedV2(ctx, he, meta)
+		},
+	)
 }
 
 // ── Policy lifecycle dispatch ─────────────────────
 
-// FirePolicyCreated dispatches to all plugins that implement PolicyCreated.
-func (m *Manager) FirePolicyCreated(ctx context.Context, pol *policy.Policy) error {
-	for _, p := range m.plugins {
-		if h, ok := p.(PolicyCreated); ok {
-			if err := h.OnPolicyCreated(ctx, pol); err != nil {
-				return err
-			}
-		}
-	}
-	return nil
+// FirePolicyCreated dispatches to all plugins that implement PolicyCreated or PolicyCreatedV2.
+func (m *Manager) FirePolicyCreated(ctx context.Context, pol *policy.Policy, meta EventMeta) error {
+	return dispatch(ctx, m, "OnPolicyCreated", meta,
+		func(ctx context.Context, h PolicyCreated) error {
+			return h.OnPolicyCreated(ctx, pol)
+		},
+		func(ctx context.Context, h PolicyCreatedV2, meta EventMeta) error {
+			return h.OnPolicyCreatedV2(ctx, pol, meta)
+		},
+	)
 }
 
-// FirePolicyUpdated dispatches to all plugins that implement PolicyUpdated.
-func (m *Manager) FirePolicyUpdated(ctx context.Context, pol *policy.Policy) error {
-	for _, p := range m.plugins {
-		if h, ok := p.(PolicyUpdated); ok {
-			if err := h.OnPolicyUpdated(ctx, pol); err != nil {
-				return err
-			}
-		}
-	}
-	return nil
+// FirePolicyUpdated dispatches to all plugins that implement PolicyUpdated or PolicyUpdatedV2.
+func (m *Manager) FirePolicyUpdated(ctx context.Context, pol *policy.Policy, meta EventMeta) error {
+	return dispatch(ctx, m, "OnPolicyUpdated", meta,
+		func(ctx context.Context, h PolicyUpdated) error {
+			return h.OnPolicyUpdated(ctx, pol)
+		},
+		func(ctx context.Context, h PolicyUpdatedV2, meta EventMeta) error {
+			return h.OnPolicyUpdatedV2(ctx, pol, meta)
+		},
+	)
 }
 
-// FirePolicyDeleted dispatches to all plugins that implement PolicyDeleted.
-func (m *Manager) FirePolicyDeleted(ctx context.Context, polID id.PolicyID) error {
-	for _, p := range m.plugins {
-		if h, ok := p.(PolicyDeleted); ok {
-			if err := h.OnPolicyDeleted(ctx, polID); err != nil {
-				return err
-			}
-		}
-	}
-	return nil
+// FirePolicyDeleted dispatches to all plugins that implement PolicyDeleted or PolicyDeletedV2.
+func (m *Manager) FirePolicyDeleted(ctx context.Context, polID id.PolicyID, meta EventMeta) error {
+	return dispatch(ctx, m, "OnPolicyDeleted", meta,
+		func(ctx context.Context, h PolicyDeleted) error {
+			return h.OnPolicyDeleted(ctx, polID)
+		},
+		func(ctx context.Context, h PolicyDeletedV2, meta EventMeta) error {
+			return h.OnPolicyDeletedV2(ctx, polID, meta)
+		},
+	)
+}
+
+// ── Key group dispatch ────────────────────────────
+
+// FireGroupMembershipChanged dispatches to all plugins that implement GroupMembershipChanged or GroupMembershipChangedV2.
+func (m *Manager) FireGroupMembershipChanged(ctx context.Context, g *group.Group, added, removed []id.KeyID, meta EventMeta) error {
+	return dispatch(ctx, m, "OnGroupMembershipChanged", meta,
+		func(ctx context.Context, h GroupMembershipChanged) error {
+			return h.OnGroupMembershipChanged(ctx, g, added, removed)
+		},
+		func(ctx context.Context, h GroupMembershipChangedV2, meta EventMeta) error {
+			return h.OnGroupMembershipChangedV2(ctx, g, added, removed, meta)
+		},
+	)
+}
+
+// ── Key transfer dispatch ─────────────────────────
+
+// FireKeyTransferChanged dispatches to all plugins that implement KeyTransferChanged or KeyTransferChangedV2.
+func (m *Manager) FireKeyTransferChanged(ctx context.Context, t *transfer.Transfer, meta EventMeta) error {
+	return dispatch(ctx, m, "OnKeyTransferChanged", meta,
+		func(ctx context.Context, h KeyTransferChanged) error {
+			return h.OnKeyTransferChanged(ctx, t)
+		},
+		func(ctx context.Context, h KeyTransferChangedV2, meta EventMeta) error {
+			return h.OnKeyTransferChangedV2(ctx, t, meta)
+		},
+	)
 }
 
 // ── Shutdown dispatch ─────────────────────────────
 
-// FireShutdown dispatches to all plugins that implement Shutdown.
-func (m *Manager) FireShutdown(ctx context.Context) error {
-	for _, p := range m.plugins {
-		if h, ok := p.(Shutdown); ok {
-			if err := h.OnShutdown(ctx); err != nil {
-				return err
-			}
-		}
-	}
-	return nil
+// FireShutdown dispatches to all plugins that implement Shutdown or ShutdownV2.
+func (m *Manager) FireShutdown(ctx context.Context, meta EventMeta) error {
+	return dispatch(ctx, m, "OnShutdown", meta,
+		func(ctx context.Context, h Shutdown) error {
+			return h.OnShutdown(ctx)
+		},
+		func(ctx context.Context, h ShutdownV2, meta EventMeta) error {
+			return h.OnShutdownV2(ctx, meta)
+		},
+	)
 }
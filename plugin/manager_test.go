@@ -4,15 +4,21 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/xraph/keysmith/group"
 	"github.com/xraph/keysmith/id"
 	"github.com/xraph/keysmith/key"
 	"github.com/xraph/keysmith/plugin"
 	"github.com/xraph/keysmith/policy"
 	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/tenant"
+	"github.com/xraph/keysmith/transfer"
+	"github.com/xraph/keysmith/usage"
 )
 
 // testPlugin implements all lifecycle hooks for testing.
@@ -78,6 +84,91 @@ func (p *testPlugin) OnKeyRateLimited(_ context.Context, _ *key.Key) error {
 	return p.err
 }
 
+func (p *testPlugin) OnTenantRateLimited(_ context.Context, _ *key.Key) error {
+	p.called["TenantRateLimited"]++
+	return p.err
+}
+
+func (p *testPlugin) OnKeyAdaptivelyLimited(_ context.Context, _ *key.Key, _ *key.AdaptivePenalty) error {
+	p.called["KeyAdaptivelyLimited"]++
+	return p.err
+}
+
+func (p *testPlugin) OnKeyConsumerMismatch(_ context.Context, _ *key.Key, _ string) error {
+	p.called["KeyConsumerMismatch"]++
+	return p.err
+}
+
+func (p *testPlugin) OnKeyRotationOverdue(_ context.Context, _ *key.Key, _ *key.RotationDue) error {
+	p.called["KeyRotationOverdue"]++
+	return p.err
+}
+
+func (p *testPlugin) OnKeyFormatDeprecated(_ context.Context, _ *key.Key, _ *key.FormatDeprecation) error {
+	p.called["KeyFormatDeprecated"]++
+	return p.err
+}
+
+func (p *testPlugin) OnKeyFlagsChanged(_ context.Context, _ *key.Key, _, _ key.Flags) error {
+	p.called["KeyFlagsChanged"]++
+	return p.err
+}
+
+func (p *testPlugin) OnKeyCompromised(_ context.Context, _ *key.Key, _ *key.CompromiseReport) error {
+	p.called["KeyCompromised"]++
+	return p.err
+}
+
+func (p *testPlugin) OnKeyDebugEnabled(_ context.Context, _ *key.Key) error {
+	p.called["KeyDebugEnabled"]++
+	return p.err
+}
+
+func (p *testPlugin) OnKeyExported(_ context.Context, _ *key.Key) error {
+	p.called["KeyExported"]++
+	return p.err
+}
+
+func (p *testPlugin) OnKeyImported(_ context.Context, _ *key.Key) error {
+	p.called["KeyImported"]++
+	return p.err
+}
+
+func (p *testPlugin) OnGroupMembershipChanged(_ context.Context, _ *group.Group, _, _ []id.KeyID) error {
+	p.called["GroupMembershipChanged"]++
+	return p.err
+}
+
+func (p *testPlugin) OnKeyTransferChanged(_ context.Context, _ *transfer.Transfer) error {
+	p.called["KeyTransferChanged"]++
+	return p.err
+}
+
+func (p *testPlugin) OnTenantLockdownChanged(_ context.Context, _ string, _ *tenant.Lockdown) error {
+	p.called["TenantLockdownChanged"]++
+	return p.err
+}
+
+func (p *testPlugin) OnUsageIdentifiersErased(_ context.Context, _ *usage.Erasure) error {
+	p.called["UsageIdentifiersErased"]++
+	return p.err
+}
+
+func (p *testPlugin) OnRuntimeConfigChanged(_ context.Context, _ *plugin.ConfigChange) error {
+	p.called["RuntimeConfigChanged"]++
+	return p.err
+}
+
+func (p *testPlugin) OnStorageReportGenerated(_ context.Context, _ *store.StorageReport) error {
+	p.called["StorageReportGenerated"]++
+	return p.err
+}
+
+func (p *testPlugin) OnSuspiciousValidationPattern(_ context.Context, _ *key.FailurePattern) error {
+	p.called["SuspiciousValidationPattern"]++
+	return p.err
+}
+
 func (p *testPlugin) OnPolicyCreated(_ context.Context, _ *policy.Policy) error {
 	p.called["PolicyCreated"]++
 	return p.err
@@ -103,7 +194,7 @@ func TestManager_FireKeyCreated(t *testing.T) {
 	p := newTestPlugin("test")
 	m.Register(p)
 
-	err := m.FireKeyCreated(context.Background(), &key.Key{})
+	err := m.FireKeyCreated(context.Background(), &key.Key{}, plugin.EventMeta{})
 	require.NoError(t, err)
 	assert.Equal(t, 1, p.called["KeyCreated"])
 }
@@ -114,7 +205,7 @@ func TestManager_FireKeyCreated_Error(t *testing.T) {
 	p.err = errors.New("hook error")
 	m.Register(p)
 
-	err := m.FireKeyCreated(context.Background(), &key.Key{})
+	err := m.FireKeyCreated(context.Background(), &key.Key{}, plugin.EventMeta{})
 	assert.Error(t, err)
 	assert.Equal(t, "hook error", err.Error())
 }
@@ -126,7 +217,7 @@ func TestManager_MultiplePlugins(t *testing.T) {
 	m.Register(p1)
 	m.Register(p2)
 
-	err := m.FireKeyCreated(context.Background(), &key.Key{})
+	err := m.FireKeyCreated(context.Background(), &key.Key{}, plugin.EventMeta{})
 	require.NoError(t, err)
 	assert.Equal(t, 1, p1.called["KeyCreated"])
 	assert.Equal(t, 1, p2.called["KeyCreated"])
@@ -140,7 +231,7 @@ func TestManager_ErrorStopsDispatch(t *testing.T) {
 	m.Register(p1)
 	m.Register(p2)
 
-	err := m.FireKeyCreated(context.Background(), &key.Key{})
+	err := m.FireKeyCreated(context.Background(), &key.Key{}, plugin.EventMeta{})
 	assert.Error(t, err)
 	assert.Equal(t, 1, p1.called["KeyCreated"])
 	assert.Equal(t, 0, p2.called["KeyCreated"])
@@ -153,21 +244,39 @@ func TestManager_FireAllHooks(t *testing.T) {
 	ctx := context.Background()
 	k := &key.Key{}
 	pol := &policy.Policy{}
-
-	require.NoError(t, m.FireKeyCreated(ctx, k))
-	require.NoError(t, m.FireKeyCreateFailed(ctx, k, errors.New("fail")))
-	require.NoError(t, m.FireKeyValidated(ctx, k))
-	require.NoError(t, m.FireKeyValidationFailed(ctx, "raw", errors.New("fail")))
-	require.NoError(t, m.FireKeyRotated(ctx, k, &rotation.Record{}))
-	require.NoError(t, m.FireKeyRevoked(ctx, k, "reason"))
-	require.NoError(t, m.FireKeySuspended(ctx, k))
-	require.NoError(t, m.FireKeyReactivated(ctx, k))
-	require.NoError(t, m.FireKeyExpired(ctx, k))
-	require.NoError(t, m.FireKeyRateLimited(ctx, k))
-	require.NoError(t, m.FirePolicyCreated(ctx, pol))
-	require.NoError(t, m.FirePolicyUpdated(ctx, pol))
-	require.NoError(t, m.FirePolicyDeleted(ctx, id.NewPolicyID()))
-	require.NoError(t, m.FireShutdown(ctx))
+	var meta plugin.EventMeta
+
+	require.NoError(t, m.FireKeyCreated(ctx, k, meta))
+	require.NoError(t, m.FireKeyCreateFailed(ctx, k, errors.New("fail"), meta))
+	require.NoError(t, m.FireKeyValidated(ctx, k, meta))
+	require.NoError(t, m.FireKeyValidationFailed(ctx, "raw", "fp", errors.New("fail"), meta))
+	require.NoError(t, m.FireKeyRotated(ctx, k, &rotation.Record{}, meta))
+	require.NoError(t, m.FireKeyRevoked(ctx, k, "reason", meta))
+	require.NoError(t, m.FireKeySuspended(ctx, k, meta))
+	require.NoError(t, m.FireKeyReactivated(ctx, k, meta))
+	require.NoError(t, m.FireKeyExpired(ctx, k, meta))
+	require.NoError(t, m.FireKeyRateLimited(ctx, k, meta))
+	require.NoError(t, m.FireTenantRateLimited(ctx, k, meta))
+	require.NoError(t, m.FireKeyAdaptivelyLimited(ctx, k, &key.AdaptivePenalty{}, meta))
+	require.NoError(t, m.FireKeyConsumerMismatch(ctx, k, "billing", meta))
+	require.NoError(t, m.FireKeyRotationOverdue(ctx, k, &key.RotationDue{}, meta))
+	require.NoError(t, m.FireKeyFormatDeprecated(ctx, k, &key.FormatDeprecation{}, meta))
+	require.NoError(t, m.FireKeyFlagsChanged(ctx, k, key.Flags{key.FlagSkipOriginCheck}, nil, meta))
+	require.NoError(t, m.FireKeyCompromised(ctx, k, &key.CompromiseReport{}, meta))
+	require.NoError(t, m.FireSuspiciousValidationPattern(ctx, &key.FailurePattern{}, meta))
+	require.NoError(t, m.FireKeyDebugEnabled(ctx, k, meta))
+	require.NoError(t, m.FireKeyExported(ctx, k, meta))
+	require.NoError(t, m.FireKeyImported(ctx, k, meta))
+	require.NoError(t, m.FireUsageIdentifiersErased(ctx, &usage.Erasure{}, meta))
+	require.NoError(t, m.FireRuntimeConfigChanged(ctx, &plugin.ConfigChange{}, meta))
+	require.NoError(t, m.FireStorageReportGenerated(ctx, &store.StorageReport{}, meta))
+	require.NoError(t, m.FirePolicyCreated(ctx, pol, meta))
+	require.NoError(t, m.FirePolicyUpdated(ctx, pol, meta))
+	require.NoError(t, m.FirePolicyDeleted(ctx, id.NewPolicyID(), meta))
+	require.NoError(t, m.FireGroupMembershipChanged(ctx, &group.Group{}, []id.KeyID{id.NewKeyID()}, nil, meta))
+	require.NoError(t, m.FireKeyTransferChanged(ctx, &transfer.Transfer{}, meta))
+	require.NoError(t, m.FireTenantLockdownChanged(ctx, "tenant-1", &tenant.Lockdown{}, meta))
+	require.NoError(t, m.FireShutdown(ctx, meta))
 
 	assert.Equal(t, 1, p.called["KeyCreated"])
 	assert.Equal(t, 1, p.called["KeyCreateFailed"])
@@ -179,9 +288,26 @@ func TestManager_FireAllHooks(t *testing.T) {
 	assert.Equal(t, 1, p.called["KeyReactivated"])
 	assert.Equal(t, 1, p.called["KeyExpired"])
 	assert.Equal(t, 1, p.called["KeyRateLimited"])
+	assert.Equal(t, 1, p.called["TenantRateLimited"])
+	assert.Equal(t, 1, p.called["KeyAdaptivelyLimited"])
+	assert.Equal(t, 1, p.called["KeyConsumerMismatch"])
+	assert.Equal(t, 1, p.called["KeyRotationOverdue"])
+	assert.Equal(t, 1, p.called["KeyFormatDeprecated"])
+	assert.Equal(t, 1, p.called["KeyFlagsChanged"])
+	assert.Equal(t, 1, p.called["KeyCompromised"])
+	assert.Equal(t, 1, p.called["SuspiciousValidationPattern"])
+	assert.Equal(t, 1, p.called["KeyDebugEnabled"])
+	assert.Equal(t, 1, p.called["KeyExported"])
+	assert.Equal(t, 1, p.called["KeyImported"])
+	assert.Equal(t, 1, p.called["UsageIdentifiersErased"])
+	assert.Equal(t, 1, p.called["RuntimeConfigChanged"])
+	assert.Equal(t, 1, p.called["StorageReportGenerated"])
 	assert.Equal(t, 1, p.called["PolicyCreated"])
 	assert.Equal(t, 1, p.called["PolicyUpdated"])
 	assert.Equal(t, 1, p.called["PolicyDeleted"])
+	assert.Equal(t, 1, p.called["GroupMembershipChanged"])
+	assert.Equal(t, 1, p.called["KeyTransferChanged"])
+	assert.Equal(t, 1, p.called["TenantLockdownChanged"])
 	assert.Equal(t, 1, p.called["Shutdown"])
 }
 
@@ -203,11 +329,193 @@ func TestManager_SkipUnimplementedHooks(t *testing.T) {
 	ctx := context.Background()
 
 	// This hook is implemented.
-	require.NoError(t, m.FireKeyCreated(ctx, &key.Key{}))
+	require.NoError(t, m.FireKeyCreated(ctx, &key.Key{}, plugin.EventMeta{}))
 	assert.Equal(t, 1, pp.called)
 
 	// These hooks are not implemented — should not error.
-	require.NoError(t, m.FireKeyRevoked(ctx, &key.Key{}, "reason"))
-	require.NoError(t, m.FirePolicyCreated(ctx, &policy.Policy{}))
-	require.NoError(t, m.FireShutdown(ctx))
+	require.NoError(t, m.FireKeyRevoked(ctx, &key.Key{}, "reason", plugin.EventMeta{}))
+	require.NoError(t, m.FirePolicyCreated(ctx, &policy.Policy{}, plugin.EventMeta{}))
+	require.NoError(t, m.FireShutdown(ctx, plugin.EventMeta{}))
+}
+
+// panicPlugin panics in OnKeyCreated.
+type panicPlugin struct{}
+
+func (panicPlugin) Name() string { return "panicky" }
+func (panicPlugin) OnKeyCreated(_ context.Context, _ *key.Key) error {
+	panic("boom")
+}
+
+// slowPlugin blocks in OnKeyCreated until its context is done.
+type slowPlugin struct{}
+
+func (slowPlugin) Name() string { return "slow" }
+func (slowPlugin) OnKeyCreated(ctx context.Context, _ *key.Key) error {
+	<-ctx.Done()
+	time.Sleep(time.Second)
+	return nil
+}
+
+// panicRecorder records PluginPanicked calls.
+type panicRecorder struct {
+	got []*plugin.HookError
+}
+
+func (r *panicRecorder) Name() string { return "panic-recorder" }
+func (r *panicRecorder) OnPluginPanicked(_ context.Context, err *plugin.HookError) error {
+	r.got = append(r.got, err)
+	return nil
+}
+
+func TestManager_RecoversPanics(t *testing.T) {
+	m := plugin.NewManager()
+	rec := &panicRecorder{}
+	after := newTestPlugin("after")
+	m.Register(panicPlugin{})
+	m.Register(after)
+	m.Register(rec)
+
+	var err error
+	require.NotPanics(t, func() { err = m.FireKeyCreated(context.Background(), &key.Key{}, plugin.EventMeta{}) })
+	require.ErrorIs(t, err, plugin.ErrHookPanicked)
+
+	var he *plugin.HookError
+	require.ErrorAs(t, err, &he)
+	assert.Equal(t, "panicky", he.Plugin)
+	assert.Equal(t, "OnKeyCreated", he.Hook)
+	assert.Contains(t, err.Error(), "boom")
+
+	assert.Equal(t, 1, after.called["KeyCreated"], "later plugins still run")
+	require.Len(t, rec.got, 1)
+	assert.Equal(t, "panicky", rec.got[0].Plugin)
+}
+
+func TestManager_RecoversPanicsWithoutTimeout(t *testing.T) {
+	m := plugin.NewManager()
+	m.SetTimeout(0)
+	after := newTestPlugin("after")
+	m.Register(panicPlugin{})
+	m.Register(after)
+
+	err := m.FireKeyCreated(context.Background(), &key.Key{}, plugin.EventMeta{})
+	require.ErrorIs(t, err, plugin.ErrHookPanicked)
+	assert.Equal(t, 1, after.called["KeyCreated"])
+}
+
+func TestManager_HookTimeout(t *testing.T) {
+	m := plugin.NewManager()
+	m.SetTimeout(20 * time.Millisecond)
+	after := newTestPlugin("after")
+	m.Register(slowPlugin{})
+	m.Register(after)
+
+	start := time.Now()
+	err := m.FireKeyCreated(context.Background(), &key.Key{}, plugin.EventMeta{})
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "the dispatch does not wait for the slow hook")
+	require.ErrorIs(t, err, plugin.ErrHookTimeout)
+
+	var he *plugin.HookError
+	require.ErrorAs(t, err, &he)
+	assert.Equal(t, "slow", he.Plugin)
+	assert.Equal(t, 1, after.called["KeyCreated"], "later plugins still run")
+}
+
+// metaPlugin implements both versions of KeyCreated and KeyValidationFailed,
+// and PluginPanickedV2.
+type metaPlugin struct {
+	v1, v2      int
+	meta        []plugin.EventMeta
+	fingerprint string
+}
+
+func (p *metaPlugin) Name() string { return "meta" }
+func (p *metaPlugin) OnKeyCreated(_ context.Context, _ *key.Key) error {
+	p.v1++
+	return nil
+}
+func (p *metaPlugin) OnKeyCreatedV2(_ context.Context, _ *key.Key, meta plugin.EventMeta) error {
+	p.v2++
+	p.meta = append(p.meta, meta)
+	return nil
+}
+func (p *metaPlugin) OnKeyValidationFailed(_ context.Context, _ string, _ error) error {
+	p.v1++
+	return nil
+}
+func (p *metaPlugin) OnKeyValidationFailedV2(_ context.Context, fingerprint string, _ error, meta plugin.EventMeta) error {
+	p.v2++
+	p.fingerprint = fingerprint
+	p.meta = append(p.meta, meta)
+	return nil
+}
+func (p *metaPlugin) OnPluginPanickedV2(_ context.Context, _ *plugin.HookError, meta plugin.EventMeta) error {
+	p.meta = append(p.meta, meta)
+	return nil
+}
+
+// rawKeyPlugin implements only the original KeyValidationFailed.
+type rawKeyPlugin struct{ rawKey string }
+
+func (p *rawKeyPlugin) Name() string { return "raw" }
+func (p *rawKeyPlugin) OnKeyValidationFailed(_ context.Context, rawKey string, _ error) error {
+	p.rawKey = rawKey
+	return nil
+}
+
+func TestManager_PrefersV2(t *testing.T) {
+	m := plugin.NewManager()
+	mp := &metaPlugin{}
+	old := newTestPlugin("old")
+	m.Register(mp)
+	m.Register(old)
+
+	at := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	meta := plugin.EventMeta{
+		Trigger:   plugin.TriggerManual,
+		ActorID:   "user_1",
+		RequestID: "req_1",
+		Timestamp: at,
+	}
+	require.NoError(t, m.FireKeyCreated(context.Background(), &key.Key{}, meta))
+
+	assert.Zero(t, mp.v1, "a plugin implementing both is called once")
+	assert.Equal(t, 1, mp.v2)
+	assert.Equal(t, []plugin.EventMeta{meta}, mp.meta)
+	assert.Equal(t, 1, old.called["KeyCreated"], "V1-only plugins are adapted")
+}
+
+func TestManager_ValidationFailedFingerprint(t *testing.T) {
+	m := plugin.NewManager()
+	mp := &metaPlugin{}
+	raw := &rawKeyPlugin{}
+	m.Register(mp)
+	m.Register(raw)
+
+	meta := plugin.EventMeta{Trigger: plugin.TriggerValidation, ReasonCode: plugin.ReasonInvalidKey}
+	require.NoError(t, m.FireKeyValidationFailed(context.Background(), "sk_live_secret", "sk_live:abc123", errors.New("fail"), meta))
+
+	assert.Equal(t, "sk_live:abc123", mp.fingerprint)
+	require.Len(t, mp.meta, 1)
+	assert.Equal(t, plugin.ReasonInvalidKey, mp.meta[0].ReasonCode)
+	assert.False(t, mp.meta[0].Timestamp.IsZero(), "a zero timestamp is filled in")
+	assert.Equal(t, "sk_live_secret", raw.rawKey)
+}
+
+func TestManager_PanicCarriesMeta(t *testing.T) {
+	m := plugin.NewManager()
+	mp := &metaPlugin{}
+	m.Register(panicPlugin{})
+	m.Register(mp)
+
+	meta := plugin.EventMeta{Trigger: plugin.TriggerManual, ActorID: "user_1"}
+	err := m.FireKeyCreated(context.Background(), &key.Key{}, meta)
+	require.ErrorIs(t, err, plugin.ErrHookPanicked)
+
+	// PluginPanicked fires as soon as the panic is recovered, before the
+	// remaining plugins' KeyCreated.
+	require.Len(t, mp.meta, 2)
+	got := mp.meta[0]
+	assert.Equal(t, plugin.TriggerManual, got.Trigger)
+	assert.Equal(t, "user_1", got.ActorID)
+	assert.Equal(t, plugin.ReasonHookPanicked, got.ReasonCode)
 }
@@ -0,0 +1,172 @@
+package plugin
+
+import "time"
+
+// Trigger says what caused a hook to fire.
+type Trigger string
+
+const (
+	// TriggerManual is an engine call made on someone's behalf, such as
+	// RevokeKey or CreatePolicy.
+	TriggerManual Trigger = "manual"
+
+	// TriggerValidation is an event noticed while validating a key, such as
+	// a key found expired or over its rate limit.
+	TriggerValidation Trigger = "validation"
+
+	// TriggerSweep is a background or scheduled sweep, such as
+	// CleanupExpiredKeys.
+	TriggerSweep Trigger = "sweep"
+)
+
+// ReasonCode classifies why an event happened. It is stable and meant for
+// machines; human-readable detail stays in the hook's own arguments.
+type ReasonCode string
+
+// Reason codes set by the engine. KeyRotated carries the rotation's
+// [rotation.Reason] as its code instead.
+const (
+	// ReasonInvalidKey is a validation failure for a key that does not
+	// resolve to a stored key.
+	ReasonInvalidKey ReasonCode = "invalid_key"
+
+	// ReasonKeyInactive is a validation failure for a suspended, revoked, or
+	// expired key.
+	ReasonKeyInactive ReasonCode = "key_inactive"
+
+	// ReasonKeyExpired is a key moved to expired, by validation or a sweep.
+	ReasonKeyExpired ReasonCode = "key_expired"
+
+	// ReasonRateLimited is a key over its own rate limit.
+	ReasonRateLimited ReasonCode = "rate_limited"
+
+	// ReasonTenantRateLimited is a key over its tenant's rate limit.
+	ReasonTenantRateLimited ReasonCode = "tenant_rate_limited"
+
+	// ReasonScopeRateLimited is a key over the rate limit of a scope it
+	// exercised; [EventMeta.Scope] names the scope.
+	ReasonScopeRateLimited ReasonCode = "scope_rate_limited"
+
+	// ReasonQuotaExceeded is a key over a daily or monthly quota, its quota
+	// pool's or else its policy's.
+	ReasonQuotaExceeded ReasonCode = "quota_exceeded"
+
+	// ReasonErrorRate is a key whose rate limit was reduced because most of
+	// its recent requests failed.
+	ReasonErrorRate ReasonCode = "error_rate"
+
+	// ReasonConsumerMismatch is a key presented by another service than its
+	// intended consumer.
+	ReasonConsumerMismatch ReasonCode = "consumer_mismatch"
+
+	// ReasonRotationOverdue is a key validated after its policy's rotation
+	// period ended.
+	ReasonRotationOverdue ReasonCode = "rotation_overdue"
+
+	// ReasonKeyFormatDeprecated is a key validated in a raw key format
+	// flagged deprecated.
+	ReasonKeyFormatDeprecated ReasonCode = "key_format_deprecated"
+
+	// ReasonPrefixNotAccepted is a validation failure for a key whose prefix
+	// the caller does not accept.
+	ReasonPrefixNotAccepted ReasonCode = "prefix_not_accepted"
+
+	// ReasonIPNotAllowed is a validation failure for a request from a
+	// client IP the key's policy does not allow.
+	ReasonIPNotAllowed ReasonCode = "ip_not_allowed"
+
+	// ReasonRouteNotAllowed is a validation failure for a request whose
+	// method or path the key's policy does not allow.
+	ReasonRouteNotAllowed ReasonCode = "route_not_allowed"
+
+	// ReasonFailureThreshold is a failure fingerprint crossing the
+	// suspicious-pattern threshold.
+	ReasonFailureThreshold ReasonCode = "failure_threshold"
+
+	// ReasonCompromised is a key revoked or rotated by ReportCompromise.
+	ReasonCompromised ReasonCode = "compromised"
+
+	// ReasonLeakedHash is a key revoked by RevokeByHashes.
+	ReasonLeakedHash ReasonCode = "leaked_hash"
+
+	// ReasonCreatorRevoked is a key revoked by BulkRevokeByCreator.
+	ReasonCreatorRevoked ReasonCode = "creator_revoked"
+
+	// ReasonLabelRevoked is a key revoked by BulkRevokeByLabels.
+	ReasonLabelRevoked ReasonCode = "label_revoked"
+
+	// ReasonGroupRevoked is a key revoked by RevokeGroup.
+	ReasonGroupRevoked ReasonCode = "group_revoked"
+
+	// ReasonDeliveryFailed is a key whose delivery to a secrets manager
+	// failed, so it was rolled back or suspended.
+	ReasonDeliveryFailed ReasonCode = "delivery_failed"
+
+	// ReasonStoreFailed is a key the store refused to create.
+	ReasonStoreFailed ReasonCode = "store_failed"
+
+	// ReasonImported is a key or policy created by ImportKeyBundle.
+	ReasonImported ReasonCode = "imported"
+
+	// ReasonQuotaForecast is a key projected to exhaust its monthly quota
+	// before the month ends.
+	ReasonQuotaForecast ReasonCode = "quota_forecast"
+
+	// ReasonIdle is a key suspended by SuspendIdleKeys for going unused
+	// longer than its environment profile allows.
+	ReasonIdle ReasonCode = "idle"
+
+	// ReasonErasure is an erasure of client identifiers from usage records,
+	// such as for a data-subject request.
+	ReasonErasure ReasonCode = "erasure"
+
+	// ReasonConfigChange is a change to the engine's runtime configuration.
+	ReasonConfigChange ReasonCode = "config_change"
+
+	// ReasonTenantLockdown is a key suspended by LockdownTenant, and a
+	// TenantLockdownChanged for the lockdown itself.
+	ReasonTenantLockdown ReasonCode = "tenant_lockdown"
+
+	// ReasonTenantRecovery is a key reactivated by a staged recovery from
+	// a lockdown, and a TenantLockdownChanged for the recovery's progress.
+	ReasonTenantRecovery ReasonCode = "tenant_recovery"
+
+	// ReasonAnomalyDetected is a staged recovery halted because the
+	// anomaly detector reported renewed trouble in the tenant.
+	ReasonAnomalyDetected ReasonCode = "anomaly_detected"
+
+	// ReasonStorageReport is a scheduled per-tenant storage report.
+	ReasonStorageReport ReasonCode = "storage_report"
+
+	// ReasonHookPanicked is set on PluginPanicked, which otherwise carries
+	// the meta of the event whose hook panicked.
+	ReasonHookPanicked ReasonCode = "hook_panicked"
+)
+
+// EventMeta describes why and on whose behalf a hook fired. It is passed to
+// the V2 hook interfaces, such as [KeyCreatedV2].
+type EventMeta struct {
+	// Trigger says what caused the event.
+	Trigger Trigger
+
+	// ReasonCode classifies the event. It is empty when the trigger alone
+	// explains it, as for most manual calls.
+	ReasonCode ReasonCode
+
+	// ActorID is the actor set with keysmith.WithActor, if any.
+	ActorID string
+
+	// RequestID is the request ID set with keysmith.WithRequestID, if any.
+	RequestID string
+
+	// Scope is the scope whose rate limit was reached, for
+	// ReasonScopeRateLimited.
+	Scope string
+
+	// GroupID is the key group the event concerns, for group membership
+	// changes and the per-key events of RotateGroup and RevokeGroup.
+	GroupID string
+
+	// Timestamp is when the event happened, by the engine's clock.
+	Timestamp time.Time
+}
@@ -15,23 +15,66 @@
 //   - [KeyReactivated] — fired when a suspended key is reactivated
 //   - [KeyExpired] — fired when a key is found expired during validation
 //   - [KeyRateLimited] — fired when a key exceeds its rate limit
+//   - [TenantRateLimited] — fired when a key's tenant exceeds its tenant-wide rate limit
+//   - [KeyAdaptivelyLimited] — fired when a key's rate limit is reduced for a high error rate
+//   - [KeyConsumerMismatch] — fired when a key is presented by a service other than its intended consumer
+//   - [KeyQuotaExceeded] — fired when a validation is refused because a daily or monthly quota is used up
+//   - [KeyQuotaForecastWarning] — fired when a key is projected to exhaust its monthly quota before month end
+//   - [KeyRotationOverdue] — fired when a key is validated past its policy's rotation period
+//   - [KeyFormatDeprecated] — fired when a key in a deprecated raw key format is validated
+//   - [KeyFlagsChanged] — fired when a key's flags are set or cleared
+//   - [KeyCompromised] — fired after a reported key leak has been remediated
+//   - [SuspiciousValidationPattern] — fired when failures for one fingerprint cross a threshold
+//   - [KeyDebugEnabled] — fired when debug capture is turned on for a key
+//   - [KeyExported] — fired when a key is exported in a bundle, hash included
+//   - [KeyImported] — fired when a key is imported from a bundle
+//
+// Available usage hooks:
+//   - [UsageIdentifiersErased] — fired after client identifiers are erased from usage records
 //
 // Available policy lifecycle hooks:
 //   - [PolicyCreated] — fired after a policy is created
 //   - [PolicyUpdated] — fired after a policy is updated
 //   - [PolicyDeleted] — fired after a policy is deleted
 //
+// Key group hook:
+//   - [GroupMembershipChanged] — fired when keys are added to or removed from a group
+//
+// Key transfer hook:
+//   - [KeyTransferChanged] — fired when a key transfer is initiated, accepted, cancelled, or expires
+//
+// Tenant lockdown hook:
+//   - [TenantLockdownChanged] — fired when a tenant is locked down and as its staged recovery progresses, halts, or completes
+//
+// Engine configuration hook:
+//   - [RuntimeConfigChanged] — fired after the engine's runtime configuration changes
+//
+// Storage report hook:
+//   - [StorageReportGenerated] — fired after a scheduled per-tenant storage report
+//
+// Plugin health hook:
+//   - [PluginPanicked] — fired after the manager recovers a panicking hook
+//
 // Shutdown hook:
 //   - [Shutdown] — fired during graceful engine shutdown
 //
+// Every hook has a V2 variant, such as [KeyCreatedV2], that also receives an
+// [EventMeta] saying what triggered the event, why, and on whose behalf. The
+// manager calls the V2 method of plugins that implement it and the original
+// method of those that do not, so a plugin implementing both is called once.
+//
+// Hooks run synchronously: the caller waits for each plugin in turn.
+// The [Manager] recovers panics and abandons hooks that run past its hook
+// timeout, so a misbehaving plugin cannot crash or hang the engine call.
+//
 // Example plugin:
 //
 //	type myPlugin struct{}
 //
 //	func (p *myPlugin) Name() string { return "my-plugin" }
 //
-//	func (p *myPlugin) OnKeyCreated(ctx context.Context, k *key.Key) error {
-//	    log.Println("key created:", k.ID)
+//	func (p *myPlugin) OnKeyCreatedV2(ctx context.Context, k *key.Key, meta plugin.EventMeta) error {
+//	    log.Println("key created:", k.ID, "by", meta.ActorID)
 //	    return nil
 //	}
 package plugin
@@ -39,10 +82,15 @@ package plugin
 import (
 	"context"
 
+	"github.com/xraph/keysmith/group"
 	"github.com/xraph/keysmith/id"
 	"github.com/xraph/keysmith/key"
 	"github.com/xraph/keysmith/policy"
 	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/tenant"
+	"github.com/xraph/keysmith/transfer"
+	"github.com/xraph/keysmith/usage"
 )
 
 // ──────────────────────────────────────────────────
@@ -63,51 +111,276 @@ type KeyCreated interface {
 	OnKeyCreated(ctx context.Context, k *key.Key) error
 }
 
+// KeyCreatedV2 is [KeyCreated] with the event's [EventMeta].
+type KeyCreatedV2 interface {
+	OnKeyCreatedV2(ctx context.Context, k *key.Key, meta EventMeta) error
+}
+
 // KeyCreateFailed is called when key creation fails.
 type KeyCreateFailed interface {
 	OnKeyCreateFailed(ctx context.Context, k *key.Key, err error) error
 }
 
+// KeyCreateFailedV2 is [KeyCreateFailed] with the event's [EventMeta].
+type KeyCreateFailedV2 interface {
+	OnKeyCreateFailedV2(ctx context.Context, k *key.Key, err error, meta EventMeta) error
+}
+
 // KeyValidated is called when a key passes validation.
 type KeyValidated interface {
 	OnKeyValidated(ctx context.Context, k *key.Key) error
 }
 
+// KeyValidatedV2 is [KeyValidated] with the event's [EventMeta].
+type KeyValidatedV2 interface {
+	OnKeyValidatedV2(ctx context.Context, k *key.Key, meta EventMeta) error
+}
+
 // KeyValidationFailed is called when key validation fails.
+//
+// Deprecated: the raw key is a secret that plugins should not see; use
+// [KeyValidationFailedV2], which receives a fingerprint instead.
 type KeyValidationFailed interface {
 	OnKeyValidationFailed(ctx context.Context, rawKey string, err error) error
 }
 
+// KeyValidationFailedV2 is called when key validation fails. Unlike
+// [KeyValidationFailed] it never receives the raw key: fingerprint is the
+// key's prefix and a short hash, as in [key.FailurePattern], and
+// meta.ReasonCode classifies the failure.
+type KeyValidationFailedV2 interface {
+	OnKeyValidationFailedV2(ctx context.Context, fingerprint string, err error, meta EventMeta) error
+}
+
 // KeyRotated is called when a key is rotated.
 type KeyRotated interface {
 	OnKeyRotated(ctx context.Context, k *key.Key, rec *rotation.Record) error
 }
 
+// KeyRotatedV2 is [KeyRotated] with the event's [EventMeta].
+type KeyRotatedV2 interface {
+	OnKeyRotatedV2(ctx context.Context, k *key.Key, rec *rotation.Record, meta EventMeta) error
+}
+
 // KeyRevoked is called when a key is revoked.
 type KeyRevoked interface {
 	OnKeyRevoked(ctx context.Context, k *key.Key, reason string) error
 }
 
+// KeyRevokedV2 is [KeyRevoked] with the event's [EventMeta].
+type KeyRevokedV2 interface {
+	OnKeyRevokedV2(ctx context.Context, k *key.Key, reason string, meta EventMeta) error
+}
+
 // KeySuspended is called when a key is suspended.
 type KeySuspended interface {
 	OnKeySuspended(ctx context.Context, k *key.Key) error
 }
 
+// KeySuspendedV2 is [KeySuspended] with the event's [EventMeta].
+type KeySuspendedV2 interface {
+	OnKeySuspendedV2(ctx context.Context, k *key.Key, meta EventMeta) error
+}
+
 // KeyReactivated is called when a suspended key is reactivated.
 type KeyReactivated interface {
 	OnKeyReactivated(ctx context.Context, k *key.Key) error
 }
 
+// KeyReactivatedV2 is [KeyReactivated] with the event's [EventMeta].
+type KeyReactivatedV2 interface {
+	OnKeyReactivatedV2(ctx context.Context, k *key.Key, meta EventMeta) error
+}
+
 // KeyExpired is called when a key is found to be expired during validation.
 type KeyExpired interface {
 	OnKeyExpired(ctx context.Context, k *key.Key) error
 }
 
+// KeyExpiredV2 is [KeyExpired] with the event's [EventMeta].
+type KeyExpiredV2 interface {
+	OnKeyExpiredV2(ctx context.Context, k *key.Key, meta EventMeta) error
+}
+
 // KeyRateLimited is called when a key exceeds its rate limit.
 type KeyRateLimited interface {
 	OnKeyRateLimited(ctx context.Context, k *key.Key) error
 }
 
+// KeyRateLimitedV2 is [KeyRateLimited] with the event's [EventMeta].
+type KeyRateLimitedV2 interface {
+	OnKeyRateLimitedV2(ctx context.Context, k *key.Key, meta EventMeta) error
+}
+
+// TenantRateLimited is called when a validation is refused because the
+// key's tenant exceeded its tenant-wide rate limit, although the key itself
+// was within its own. k.TenantID names the tenant.
+type TenantRateLimited interface {
+	OnTenantRateLimited(ctx context.Context, k *key.Key) error
+}
+
+// TenantRateLimitedV2 is [TenantRateLimited] with the event's [EventMeta].
+type TenantRateLimitedV2 interface {
+	OnTenantRateLimitedV2(ctx context.Context, k *key.Key, meta EventMeta) error
+}
+
+// KeyAdaptivelyLimited is called when adaptive limiting reduces a key's rate
+// limit because most of its recent requests failed. It fires once when the
+// penalty starts, not on every validation while it lasts.
+type KeyAdaptivelyLimited interface {
+	OnKeyAdaptivelyLimited(ctx context.Context, k *key.Key, p *key.AdaptivePenalty) error
+}
+
+// KeyAdaptivelyLimitedV2 is [KeyAdaptivelyLimited] with the event's [EventMeta].
+type KeyAdaptivelyLimitedV2 interface {
+	OnKeyAdaptivelyLimitedV2(ctx context.Context, k *key.Key, p *key.AdaptivePenalty, meta EventMeta) error
+}
+
+// KeyQuotaExceeded is called when a validation is refused with
+// ErrQuotaExceeded because the key's daily or monthly quota, or its quota
+// pool's, is used up. q describes the window.
+type KeyQuotaExceeded interface {
+	OnKeyQuotaExceeded(ctx context.Context, k *key.Key, q *key.QuotaExceeded) error
+}
+
+// KeyQuotaExceededV2 is [KeyQuotaExceeded] with the event's [EventMeta].
+type KeyQuotaExceededV2 interface {
+	OnKeyQuotaExceededV2(ctx context.Context, k *key.Key, q *key.QuotaExceeded, meta EventMeta) error
+}
+
+// KeyQuotaForecastWarning is called by the quota forecast sweep when a key
+// is projected to exhaust its monthly quota before the month ends. It fires
+// at most once per key per week.
+type KeyQuotaForecastWarning interface {
+	OnKeyQuotaForecastWarning(ctx context.Context, k *key.Key, f *key.QuotaForecast) error
+}
+
+// KeyQuotaForecastWarningV2 is [KeyQuotaForecastWarning] with the event's [EventMeta].
+type KeyQuotaForecastWarningV2 interface {
+	OnKeyQuotaForecastWarningV2(ctx context.Context, k *key.Key, f *key.QuotaForecast, meta EventMeta) error
+}
+
+// KeyRotationOverdue is called when a key is validated after its policy's
+// rotation period ended, under WithRotationOverdueEvents. It fires at most
+// once per key per day.
+type KeyRotationOverdue interface {
+	OnKeyRotationOverdue(ctx context.Context, k *key.Key, due *key.RotationDue) error
+}
+
+// KeyRotationOverdueV2 is [KeyRotationOverdue] with the event's [EventMeta].
+type KeyRotationOverdueV2 interface {
+	OnKeyRotationOverdueV2(ctx context.Context, k *key.Key, due *key.RotationDue, meta EventMeta) error
+}
+
+// KeyFormatDeprecated is called when a key in a raw key format flagged by
+// WithKeyFormatDeprecation is validated. It fires at most once per key per
+// week.
+type KeyFormatDeprecated interface {
+	OnKeyFormatDeprecated(ctx context.Context, k *key.Key, d *key.FormatDeprecation) error
+}
+
+// KeyFormatDeprecatedV2 is [KeyFormatDeprecated] with the event's [EventMeta].
+type KeyFormatDeprecatedV2 interface {
+	OnKeyFormatDeprecatedV2(ctx context.Context, k *key.Key, d *key.FormatDeprecation, meta EventMeta) error
+}
+
+// KeyConsumerMismatch is called when a key is presented by a consumer
+// service other than its IntendedConsumer. It fires at most once per key per
+// hour, whether or not the key enforces its consumer.
+type KeyConsumerMismatch interface {
+	OnKeyConsumerMismatch(ctx context.Context, k *key.Key, service string) error
+}
+
+// KeyConsumerMismatchV2 is [KeyConsumerMismatch] with the event's [EventMeta].
+type KeyConsumerMismatchV2 interface {
+	OnKeyConsumerMismatchV2(ctx context.Context, k *key.Key, service string, meta EventMeta) error
+}
+
+// KeyFlagsChanged is called after a key is created with flags or an update
+// changes them. added and removed hold the flags that were set and cleared.
+type KeyFlagsChanged interface {
+	OnKeyFlagsChanged(ctx context.Context, k *key.Key, added, removed key.Flags) error
+}
+
+// KeyFlagsChangedV2 is [KeyFlagsChanged] with the event's [EventMeta].
+type KeyFlagsChangedV2 interface {
+	OnKeyFlagsChangedV2(ctx context.Context, k *key.Key, added, removed key.Flags, meta EventMeta) error
+}
+
+// KeyCompromised is called after a key reported as compromised has been
+// revoked or rotated. It fires after the corresponding KeyRevoked or
+// KeyRotated hook.
+type KeyCompromised interface {
+	OnKeyCompromised(ctx context.Context, k *key.Key, report *key.CompromiseReport) error
+}
+
+// KeyCompromisedV2 is [KeyCompromised] with the event's [EventMeta].
+type KeyCompromisedV2 interface {
+	OnKeyCompromisedV2(ctx context.Context, k *key.Key, report *key.CompromiseReport, meta EventMeta) error
+}
+
+// SuspiciousValidationPattern is called when validation failures sharing a
+// fingerprint exceed the configured threshold within a window. It fires at
+// most once per fingerprint per window and never receives the raw key.
+type SuspiciousValidationPattern interface {
+	OnSuspiciousValidationPattern(ctx context.Context, p *key.FailurePattern) error
+}
+
+// SuspiciousValidationPatternV2 is [SuspiciousValidationPattern] with the event's [EventMeta].
+type SuspiciousValidationPatternV2 interface {
+	OnSuspiciousValidationPatternV2(ctx context.Context, p *key.FailurePattern, meta EventMeta) error
+}
+
+// KeyDebugEnabled is called when debug capture is turned on for a key. The
+// key carries the sample rate and the time capture switches off.
+type KeyDebugEnabled interface {
+	OnKeyDebugEnabled(ctx context.Context, k *key.Key) error
+}
+
+// KeyDebugEnabledV2 is [KeyDebugEnabled] with the event's [EventMeta].
+type KeyDebugEnabledV2 interface {
+	OnKeyDebugEnabledV2(ctx context.Context, k *key.Key, meta EventMeta) error
+}
+
+// KeyExported is called when a key is exported for a move to another
+// cluster. The bundle carries the key's hash, which is enough to keep the
+// key working elsewhere.
+type KeyExported interface {
+	OnKeyExported(ctx context.Context, k *key.Key) error
+}
+
+// KeyExportedV2 is [KeyExported] with the event's [EventMeta].
+type KeyExportedV2 interface {
+	OnKeyExportedV2(ctx context.Context, k *key.Key, meta EventMeta) error
+}
+
+// KeyImported is called when a key is recreated from an exported bundle.
+type KeyImported interface {
+	OnKeyImported(ctx context.Context, k *key.Key) error
+}
+
+// KeyImportedV2 is [KeyImported] with the event's [EventMeta].
+type KeyImportedV2 interface {
+	OnKeyImportedV2(ctx context.Context, k *key.Key, meta EventMeta) error
+}
+
+// ──────────────────────────────────────────────────
+// Usage hooks
+// ──────────────────────────────────────────────────
+
+// UsageIdentifiersErased is called after an erasure run rewrites client
+// identifiers in a tenant's usage records, including a run that stopped
+// early. The summary says what kind of identifier was matched, never its
+// value.
+type UsageIdentifiersErased interface {
+	OnUsageIdentifiersErased(ctx context.Context, e *usage.Erasure) error
+}
+
+// UsageIdentifiersErasedV2 is [UsageIdentifiersErased] with the event's [EventMeta].
+type UsageIdentifiersErasedV2 interface {
+	OnUsageIdentifiersErasedV2(ctx context.Context, e *usage.Erasure, meta EventMeta) error
+}
+
 // ──────────────────────────────────────────────────
 // Policy lifecycle hooks
 // ──────────────────────────────────────────────────
@@ -117,16 +390,132 @@ type PolicyCreated interface {
 	OnPolicyCreated(ctx context.Context, pol *policy.Policy) error
 }
 
+// PolicyCreatedV2 is [PolicyCreated] with the event's [EventMeta].
+type PolicyCreatedV2 interface {
+	OnPolicyCreatedV2(ctx context.Context, pol *policy.Policy, meta EventMeta) error
+}
+
 // PolicyUpdated is called when a policy is updated.
 type PolicyUpdated interface {
 	OnPolicyUpdated(ctx context.Context, pol *policy.Policy) error
 }
 
+// PolicyUpdatedV2 is [PolicyUpdated] with the event's [EventMeta].
+type PolicyUpdatedV2 interface {
+	OnPolicyUpdatedV2(ctx context.Context, pol *policy.Policy, meta EventMeta) error
+}
+
 // PolicyDeleted is called when a policy is deleted.
 type PolicyDeleted interface {
 	OnPolicyDeleted(ctx context.Context, polID id.PolicyID) error
 }
 
+// PolicyDeletedV2 is [PolicyDeleted] with the event's [EventMeta].
+type PolicyDeletedV2 interface {
+	OnPolicyDeletedV2(ctx context.Context, polID id.PolicyID, meta EventMeta) error
+}
+
+// ──────────────────────────────────────────────────
+// Key group hooks
+// ──────────────────────────────────────────────────
+
+// GroupMembershipChanged is called after keys are added to or removed from
+// a group, with the keys that were. Deleting a group reports its members as
+// removed.
+type GroupMembershipChanged interface {
+	OnGroupMembershipChanged(ctx context.Context, g *group.Group, added, removed []id.KeyID) error
+}
+
+// GroupMembershipChangedV2 is [GroupMembershipChanged] with the event's [EventMeta].
+type GroupMembershipChangedV2 interface {
+	OnGroupMembershipChangedV2(ctx context.Context, g *group.Group, added, removed []id.KeyID, meta EventMeta) error
+}
+
+// ──────────────────────────────────────────────────
+// Key transfer hooks
+// ──────────────────────────────────────────────────
+
+// KeyTransferChanged is called after a key transfer between tenants is
+// initiated, accepted, cancelled, or expires; t.State says which. An
+// accepted transfer's key already belongs to t.TargetTenantID.
+type KeyTransferChanged interface {
+	OnKeyTransferChanged(ctx context.Context, t *transfer.Transfer) error
+}
+
+// KeyTransferChangedV2 is [KeyTransferChanged] with the event's [EventMeta].
+type KeyTransferChangedV2 interface {
+	OnKeyTransferChangedV2(ctx context.Context, t *transfer.Transfer, meta EventMeta) error
+}
+
+// ──────────────────────────────────────────────────
+// Tenant lockdown hooks
+// ──────────────────────────────────────────────────
+
+// TenantLockdownChanged is called after a tenant is locked down, after
+// each batch of its staged recovery, and when the recovery halts or
+// completes; l.Phase says which. The meta's ReasonCode is
+// ReasonTenantLockdown, ReasonTenantRecovery, or, for a halt,
+// ReasonAnomalyDetected.
+type TenantLockdownChanged interface {
+	OnTenantLockdownChanged(ctx context.Context, tenantID string, l *tenant.Lockdown) error
+}
+
+// TenantLockdownChangedV2 is [TenantLockdownChanged] with the event's [EventMeta].
+type TenantLockdownChangedV2 interface {
+	OnTenantLockdownChangedV2(ctx context.Context, tenantID string, l *tenant.Lockdown, meta EventMeta) error
+}
+
+// ──────────────────────────────────────────────────
+// Engine configuration hooks
+// ──────────────────────────────────────────────────
+
+// RuntimeConfigChanged is called after the engine's runtime configuration
+// changes. The change is lost on restart, so a plugin that should keep it
+// can persist change.Config and apply it again at startup.
+type RuntimeConfigChanged interface {
+	OnRuntimeConfigChanged(ctx context.Context, change *ConfigChange) error
+}
+
+// RuntimeConfigChangedV2 is [RuntimeConfigChanged] with the event's [EventMeta].
+type RuntimeConfigChangedV2 interface {
+	OnRuntimeConfigChangedV2(ctx context.Context, change *ConfigChange, meta EventMeta) error
+}
+
+// ──────────────────────────────────────────────────
+// Storage report hooks
+// ──────────────────────────────────────────────────
+
+// StorageReportGenerated is called after each run of the storage report
+// scheduled by WithStorageReports, with the row counts and usage volume of
+// every tenant, for billing by storage or usage. Of the engines sharing a
+// store, one runs each report.
+type StorageReportGenerated interface {
+	OnStorageReportGenerated(ctx context.Context, report *store.StorageReport) error
+}
+
+// StorageReportGeneratedV2 is [StorageReportGenerated] with the event's [EventMeta].
+type StorageReportGeneratedV2 interface {
+	OnStorageReportGeneratedV2(ctx context.Context, report *store.StorageReport, meta EventMeta) error
+}
+
+// ──────────────────────────────────────────────────
+// Plugin health hooks
+// ──────────────────────────────────────────────────
+
+// PluginPanicked is called after the manager recovers a panic in another
+// plugin's hook. The error names the plugin and hook and wraps
+// ErrHookPanicked. A panic inside OnPluginPanicked itself is recovered but
+// not reported again.
+type PluginPanicked interface {
+	OnPluginPanicked(ctx context.Context, err *HookError) error
+}
+
+// PluginPanickedV2 is [PluginPanicked] with the meta of the event whose hook
+// panicked, its ReasonCode set to [ReasonHookPanicked].
+type PluginPanickedV2 interface {
+	OnPluginPanickedV2(ctx context.Context, err *HookError, meta EventMeta) error
+}
+
 // ──────────────────────────────────────────────────
 // Shutdown hook
 // ──────────────────────────────────────────────────
@@ -135,3 +524,8 @@ type PolicyDeleted interface {
 type Shutdown interface {
 	OnShutdown(ctx context.Context) error
 }
+
+// ShutdownV2 is [Shutdown] with the event's [EventMeta].
+type ShutdownV2 interface {
+	OnShutdownV2(ctx context.Context, meta EventMeta) error
+}
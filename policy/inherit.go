@@ -0,0 +1,279 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/netip"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/xraph/keysmith/id"
+)
+
+// MaxInheritanceDepth is the most base policies a policy may inherit from,
+// directly and through its bases.
+const MaxInheritanceDepth = 5
+
+// ErrNoOverlap is returned by [Merge] when a base and child policy both
+// restrict a list, such as AllowedIPs, and have no entry in common. An empty
+// list means "no restriction", so the merged policy could not express "allow
+// nothing".
+var ErrNoOverlap = errors.New("policy: base and child policies have no allowed values in common")
+
+// Effective is a policy merged with the base policies it inherits from.
+type Effective struct {
+	// Policy is the merged policy. It keeps the identity of the policy it
+	// was resolved for: ID, name, tenant, and timestamps.
+	Policy *Policy `json:"policy"`
+
+	// Chain lists the policy and then its bases, nearest first.
+	Chain []id.PolicyID `json:"chain"`
+}
+
+// Merge returns the policy child would have if it inherited from base. Both
+// are left unchanged. Fields are merged as follows:
+//
+//   - Identity (ID, tenant, app, name, description, BasePolicyID, and
+//     timestamps) is the child's.
+//   - RateLimit and RateLimitWindow are taken as a pair from the policy with
+//     the lower rate, comparing requests per second; the child wins a tie.
+//     A zero rate limit means unlimited, so the other policy's pair is used.
+//   - BurstLimit, MaxKeyLifetime, RotationPeriod, and GracePeriod take the
+//     smaller non-zero value: the most restrictive wins.
+//   - DailyQuota and MonthlyQuota are the child's when non-zero, and the
+//     base's otherwise. QuotaTimezone is the child's when set.
+//   - MinTLSVersion is the higher of the two, and RequireMTLS is set when
+//     either policy sets it.
+//   - AllowedScopes, AllowedIPs, AllowedOrigins, AllowedMethods, and
+//     AllowedPaths are inherited when the child's is empty, and intersected
+//     otherwise. Methods compare case-insensitively. IP entries intersect as
+//     ranges, so a child's 10.1.0.0/16 narrows a base's 10.0.0.0/8, and
+//     path patterns by containment, so a child's "/v1/users/*" narrows a
+//     base's "/v1/**". An empty intersection is [ErrNoOverlap].
+//   - Metadata is the base's overlaid with the child's.
+func Merge(base, child *Policy) (*Policy, error) {
+	out := *child
+
+	out.RateLimit, out.RateLimitWindow = stricterRate(base.RateLimit, base.RateLimitWindow, child.RateLimit, child.RateLimitWindow)
+	out.BurstLimit = minNonZero(base.BurstLimit, child.BurstLimit)
+	out.MaxKeyLifetime = minNonZero(base.MaxKeyLifetime, child.MaxKeyLifetime)
+	out.RotationPeriod = minNonZero(base.RotationPeriod, child.RotationPeriod)
+	out.GracePeriod = minNonZero(base.GracePeriod, child.GracePeriod)
+
+	if out.DailyQuota == 0 {
+		out.DailyQuota = base.DailyQuota
+	}
+	if out.MonthlyQuota == 0 {
+		out.MonthlyQuota = base.MonthlyQuota
+	}
+	if out.QuotaTimezone == "" {
+		out.QuotaTimezone = base.QuotaTimezone
+	}
+	out.MinTLSVersion = stricterTLSVersion(base.MinTLSVersion, child.MinTLSVersion)
+	out.RequireMTLS = base.RequireMTLS || child.RequireMTLS
+
+	var errs []string
+	merge := func(field string, b, c []string, intersect func(b, c []string) []string) []string {
+		switch {
+		case len(c) == 0:
+			return slices.Clone(b)
+		case len(b) == 0:
+			return slices.Clone(c)
+		}
+		res := intersect(b, c)
+		if len(res) == 0 {
+			errs = append(errs, field)
+		}
+		return res
+	}
+	out.AllowedScopes = merge("allowed_scopes", base.AllowedScopes, child.AllowedScopes, intersectExact)
+	out.AllowedIPs = merge("allowed_ips", base.AllowedIPs, child.AllowedIPs, intersectIPs)
+	out.AllowedOrigins = merge("allowed_origins", base.AllowedOrigins, child.AllowedOrigins, intersectExact)
+	out.AllowedMethods = merge("allowed_methods", base.AllowedMethods, child.AllowedMethods, intersectFold)
+	out.AllowedPaths = merge("allowed_paths", base.AllowedPaths, child.AllowedPaths, intersectPaths)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoOverlap, strings.Join(errs, ", "))
+	}
+
+	if len(base.Metadata) > 0 {
+		out.Metadata = maps.Clone(base.Metadata)
+		maps.Copy(out.Metadata, child.Metadata)
+	}
+	return &out, nil
+}
+
+// stricterRate returns the limit and window with the lower rate. A zero
+// limit is unlimited.
+func stricterRate(bLimit int, bWindow time.Duration, cLimit int, cWindow time.Duration) (int, time.Duration) {
+	switch {
+	case bLimit <= 0 || bWindow <= 0:
+		return cLimit, cWindow
+	case cLimit <= 0 || cWindow <= 0:
+		return bLimit, bWindow
+	}
+	// Compare bLimit/bWindow with cLimit/cWindow without dividing.
+	if float64(bLimit)*float64(cWindow) < float64(cLimit)*float64(bWindow) {
+		return bLimit, bWindow
+	}
+	return cLimit, cWindow
+}
+
+// stricterTLSVersion returns the higher of two minimum TLS versions. An
+// empty or unknown version is no minimum.
+func stricterTLSVersion(b, c string) string {
+	bv, _ := ParseTLSVersion(b)
+	cv, _ := ParseTLSVersion(c)
+	if bv > cv {
+		return b
+	}
+	if cv == 0 {
+		return ""
+	}
+	return c
+}
+
+func minNonZero[T int | time.Duration](b, c T) T {
+	switch {
+	case b == 0:
+		return c
+	case c == 0:
+		return b
+	}
+	return min(b, c)
+}
+
+// intersectExact returns the entries of c also in b, in c's order.
+func intersectExact(b, c []string) []string {
+	out := make([]string, 0, len(c))
+	for _, v := range c {
+		if slices.Contains(b, v) && !slices.Contains(out, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// intersectFold is intersectExact ignoring case.
+func intersectFold(b, c []string) []string {
+	out := make([]string, 0, len(c))
+	for _, v := range c {
+		inBase := slices.ContainsFunc(b, func(s string) bool { return strings.EqualFold(s, v) })
+		seen := slices.ContainsFunc(out, func(s string) bool { return strings.EqualFold(s, v) })
+		if inBase && !seen {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// intersectIPs returns the addresses allowed by both b and c. Two prefixes
+// either nest or are disjoint, so each overlapping pair contributes the
+// narrower of the two. Entries that do not parse are dropped; Validate
+// rejects them on write.
+func intersectIPs(b, c []string) []string {
+	bp, cp := parsePrefixes(b), parsePrefixes(c)
+	var out []string
+	for _, x := range cp {
+		for _, y := range bp {
+			if !x.prefix.Overlaps(y.prefix) {
+				continue
+			}
+			narrow := x
+			if y.prefix.Bits() > x.prefix.Bits() {
+				narrow = y
+			}
+			if !slices.Contains(out, narrow.text) {
+				out = append(out, narrow.text)
+			}
+		}
+	}
+	return out
+}
+
+// intersectPaths returns the path patterns allowed by both b and c: each
+// pattern of one that a pattern of the other covers, in c's order. Patterns
+// that only partly overlap, such as "/v1/*/keys" and "/v1/users/*", are
+// dropped, since no single pattern expresses their intersection.
+func intersectPaths(b, c []string) []string {
+	var out []string
+	add := func(p string) {
+		if !slices.Contains(out, p) {
+			out = append(out, p)
+		}
+	}
+	for _, x := range c {
+		for _, y := range b {
+			switch {
+			case pathCovers(y, x):
+				add(x)
+			case pathCovers(x, y):
+				add(y)
+			}
+		}
+	}
+	return out
+}
+
+// pathCovers reports whether every path the pattern narrow matches is also
+// matched by wide. The check is conservative: a segment with wildcards is
+// only covered by an identical segment or by "*".
+func pathCovers(wide, narrow string) bool {
+	wBase, wSubtree := strings.CutSuffix(wide, "/**")
+	nBase, nSubtree := strings.CutSuffix(narrow, "/**")
+	if wSubtree && wBase == "" {
+		return true
+	}
+	if nSubtree && !wSubtree {
+		return false
+	}
+	ws, ns := pathSegments(wBase), pathSegments(nBase)
+	if len(ns) < len(ws) || (!wSubtree && len(ns) != len(ws)) {
+		return false
+	}
+	for i, w := range ws {
+		if !segmentCovers(w, ns[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func pathSegments(p string) []string {
+	if p == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimPrefix(p, "/"), "/")
+}
+
+func segmentCovers(wide, narrow string) bool {
+	if wide == narrow || wide == "*" {
+		return true
+	}
+	if strings.ContainsAny(narrow, `*?[\`) {
+		return false
+	}
+	ok, _ := path.Match(wide, narrow)
+	return ok
+}
+
+type ipEntry struct {
+	text   string
+	prefix netip.Prefix
+}
+
+func parsePrefixes(entries []string) []ipEntry {
+	out := make([]ipEntry, 0, len(entries))
+	for _, s := range entries {
+		if addr, err := netip.ParseAddr(s); err == nil {
+			out = append(out, ipEntry{text: s, prefix: netip.PrefixFrom(addr, addr.BitLen())})
+			continue
+		}
+		if p, err := netip.ParsePrefix(s); err == nil {
+			out = append(out, ipEntry{text: s, prefix: p.Masked()})
+		}
+	}
+	return out
+}
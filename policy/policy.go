@@ -8,13 +8,20 @@ import (
 )
 
 // Policy defines the rules attached to one or more API keys.
-// Policies are tenant-scoped and reusable across keys.
+// Policies are tenant-scoped and reusable across keys. A policy with a
+// BasePolicyID inherits from that policy, which it refines as described by
+// [Merge].
+//
+// An Inline policy belongs to the one key it was created with, by
+// CreateKeyInput.InlinePolicy. It is left out of listings unless
+// ListFilter.IncludeInline is set, and is deleted with its key.
 type Policy struct {
 	ID              id.PolicyID    `json:"id" db:"id"`
 	TenantID        string         `json:"tenant_id" db:"tenant_id"`
 	AppID           string         `json:"app_id" db:"app_id"`
 	Name            string         `json:"name" db:"name"`
 	Description     string         `json:"description,omitempty" db:"description"`
+	BasePolicyID    *id.PolicyID   `json:"base_policy_id,omitempty" db:"base_policy_id"`
 	RateLimit       int            `json:"rate_limit" db:"rate_limit"`
 	RateLimitWindow time.Duration  `json:"rate_limit_window" db:"rate_limit_window"`
 	BurstLimit      int            `json:"burst_limit" db:"burst_limit"`
@@ -28,6 +35,10 @@ type Policy struct {
 	GracePeriod     time.Duration  `json:"grace_period" db:"grace_period"`
 	DailyQuota      int64          `json:"daily_quota,omitempty" db:"daily_quota"`
 	MonthlyQuota    int64          `json:"monthly_quota,omitempty" db:"monthly_quota"`
+	QuotaTimezone   string         `json:"quota_timezone,omitempty" db:"quota_timezone"`
+	MinTLSVersion   string         `json:"min_tls_version,omitempty" db:"min_tls_version"`
+	RequireMTLS     bool           `json:"require_mtls,omitempty" db:"require_mtls"`
+	Inline          bool           `json:"inline,omitempty" db:"inline"`
 	Metadata        map[string]any `json:"metadata,omitempty" db:"metadata"`
 	CreatedAt       time.Time      `json:"created_at" db:"created_at"`
 	UpdatedAt       time.Time      `json:"updated_at" db:"updated_at"`
@@ -36,6 +47,18 @@ type Policy struct {
 // ListFilter contains filters for listing policies.
 type ListFilter struct {
 	TenantID string `json:"tenant_id,omitempty"`
-	Limit    int    `json:"limit,omitempty"`
-	Offset   int    `json:"offset,omitempty"`
+	AppID    string `json:"app_id,omitempty"`
+
+	// CreatedAfter, CreatedBefore, and UpdatedAfter restrict the results by
+	// creation and last modification time. All bounds are exclusive.
+	CreatedAfter  *time.Time `json:"created_after,omitempty"`
+	CreatedBefore *time.Time `json:"created_before,omitempty"`
+	UpdatedAfter  *time.Time `json:"updated_after,omitempty"`
+
+	// IncludeInline also returns inline policies, which are otherwise
+	// omitted.
+	IncludeInline bool `json:"include_inline,omitempty"`
+
+	Limit  int `json:"limit,omitempty"`
+	Offset int `json:"offset,omitempty"`
 }
@@ -0,0 +1,46 @@
+package policy
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+)
+
+// tlsVersions maps the names MinTLSVersion accepts to crypto/tls versions.
+var tlsVersions = []struct {
+	name    string
+	version uint16
+}{
+	{"1.0", tls.VersionTLS10},
+	{"1.1", tls.VersionTLS11},
+	{"1.2", tls.VersionTLS12},
+	{"1.3", tls.VersionTLS13},
+}
+
+// ParseTLSVersion returns the crypto/tls version named by s: "1.0" to "1.3",
+// optionally prefixed with "TLS" or "TLSv" as edge proxies report it, such
+// as "TLSv1.3". Case is ignored.
+func ParseTLSVersion(s string) (uint16, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "tls"), "v")
+	for _, v := range tlsVersions {
+		if v.name == name {
+			return v.version, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown TLS version %q", s)
+}
+
+// TLSVersionName returns the name of a crypto/tls version, such as "1.3",
+// or "none" for zero.
+func TLSVersionName(version uint16) string {
+	if version == 0 {
+		return "none"
+	}
+	for _, v := range tlsVersions {
+		if v.version == version {
+			return v.name
+		}
+	}
+	return fmt.Sprintf("0x%04x", version)
+}
@@ -0,0 +1,103 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/xraph/keysmith/usage"
+)
+
+// Validate reports whether the policy's fields are internally consistent.
+// It checks shape only; it does not look up scopes or other stored state.
+func (p *Policy) Validate() error {
+	var problems []string
+
+	if strings.TrimSpace(p.Name) == "" {
+		problems = append(problems, "name is required")
+	}
+	if p.RateLimit < 0 {
+		problems = append(problems, "rate_limit must not be negative")
+	}
+	if p.RateLimit > 0 && p.RateLimitWindow <= 0 {
+		problems = append(problems, "rate_limit_window is required when rate_limit is set")
+	}
+	if p.BurstLimit < 0 {
+		problems = append(problems, "burst_limit must not be negative")
+	}
+	if p.MaxKeyLifetime < 0 || p.RotationPeriod < 0 || p.GracePeriod < 0 || p.RateLimitWindow < 0 {
+		problems = append(problems, "durations must not be negative")
+	}
+	if p.DailyQuota < 0 || p.MonthlyQuota < 0 {
+		problems = append(problems, "quotas must not be negative")
+	}
+	if p.DailyQuota > 0 && p.MonthlyQuota > 0 && p.DailyQuota > p.MonthlyQuota {
+		problems = append(problems, "daily_quota must not exceed monthly_quota")
+	}
+	if _, err := usage.LoadQuotaLocation(p.QuotaTimezone); err != nil {
+		problems = append(problems, "quota_timezone: "+err.Error())
+	}
+	if p.MinTLSVersion != "" {
+		if _, err := ParseTLSVersion(p.MinTLSVersion); err != nil {
+			problems = append(problems, "min_tls_version: "+err.Error())
+		}
+	}
+	if p.BasePolicyID != nil && p.BasePolicyID.String() == p.ID.String() {
+		problems = append(problems, "base_policy_id must not be the policy itself")
+	}
+	for _, ip := range p.AllowedIPs {
+		if !validIPOrCIDR(ip) {
+			problems = append(problems, fmt.Sprintf("allowed_ips: %q is not an IP address or CIDR", ip))
+		}
+	}
+	for _, m := range p.AllowedMethods {
+		if !validMethod(m) {
+			problems = append(problems, fmt.Sprintf("allowed_methods: %q is not an HTTP method", m))
+		}
+	}
+	for _, pattern := range p.AllowedPaths {
+		if !validPathPattern(pattern) {
+			problems = append(problems, fmt.Sprintf("allowed_paths: %q is not an absolute path pattern", pattern))
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(problems, "; "))
+}
+
+// validMethod reports whether s is an HTTP method token, such as "GET".
+func validMethod(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c > unicode.MaxASCII || !(unicode.IsLetter(c) || unicode.IsDigit(c) || strings.ContainsRune("!#$%&'*+-.^_`|~", c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// validPathPattern reports whether s is a path.Match pattern starting with
+// "/", optionally ending in "/**".
+func validPathPattern(s string) bool {
+	if !strings.HasPrefix(s, "/") {
+		return false
+	}
+	base, _ := strings.CutSuffix(s, "/**")
+	_, err := path.Match(base, "")
+	return err == nil
+}
+
+func validIPOrCIDR(s string) bool {
+	if _, err := netip.ParseAddr(s); err == nil {
+		return true
+	}
+	_, err := netip.ParsePrefix(s)
+	return err == nil
+}
@@ -0,0 +1,213 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/policy"
+)
+
+const (
+	// effectivePolicyTTL is how long a merged policy is cached. Changes made
+	// through this engine invalidate it at once; other engines sharing the
+	// store see them within the TTL.
+	effectivePolicyTTL = 30 * time.Second
+
+	// maxCachedEffectivePolicies bounds the effective policy cache.
+	maxCachedEffectivePolicies = 10_000
+)
+
+// EffectivePolicy returns the policy merged with the base policies it
+// inherits from, as applied when validating its keys. A policy without a
+// base is returned as is.
+func (e *Engine) EffectivePolicy(ctx context.Context, polID id.PolicyID) (*policy.Effective, error) {
+	pol, err := e.getPolicy(ctx, polID)
+	if err != nil {
+		return nil, fmt.Errorf("get policy: %w", err)
+	}
+	eff, err := e.effectivePolicy(ctx, pol)
+	if err != nil {
+		return nil, err
+	}
+	merged := *eff.Policy
+	return &policy.Effective{Policy: &merged, Chain: slices.Clone(eff.Chain)}, nil
+}
+
+// effectivePolicy resolves pol's inheritance chain from the store, using the
+// effective policy cache.
+func (e *Engine) effectivePolicy(ctx context.Context, pol *policy.Policy) (*policy.Effective, error) {
+	if pol.BasePolicyID == nil {
+		return &policy.Effective{Policy: pol, Chain: []id.PolicyID{pol.ID}}, nil
+	}
+	if eff, ok := e.effective.get(pol); ok {
+		return eff, nil
+	}
+	gen := e.effective.generation()
+	eff, err := resolvePolicy(pol, func(polID id.PolicyID) (*policy.Policy, error) {
+		return e.store.Policies().Get(ctx, polID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	e.effective.put(pol, eff, gen)
+	return eff, nil
+}
+
+// resolvePolicy walks pol's bases with get and merges them, root first.
+// Bases must belong to pol's tenant and app. A cycle, a chain deeper than
+// policy.MaxInheritanceDepth, a missing base, or a disjoint list is an
+// ErrPolicyInheritance error; the chain walked so far is always returned.
+func resolvePolicy(pol *policy.Policy, get func(id.PolicyID) (*policy.Policy, error)) (*policy.Effective, error) {
+	chain := []*policy.Policy{pol}
+	ids := []id.PolicyID{pol.ID}
+	for cur := pol; cur.BasePolicyID != nil; {
+		baseID := *cur.BasePolicyID
+		if slices.ContainsFunc(ids, func(v id.PolicyID) bool { return v.String() == baseID.String() }) {
+			return &policy.Effective{Chain: ids}, fmt.Errorf("%w: policy %s: base policy %s is part of a cycle", ErrPolicyInheritance, pol.ID, baseID)
+		}
+		if len(ids) > policy.MaxInheritanceDepth {
+			return &policy.Effective{Chain: ids}, fmt.Errorf("%w: policy %s inherits from more than %d policies", ErrPolicyInheritance, pol.ID, policy.MaxInheritanceDepth)
+		}
+		base, err := get(baseID)
+		if err != nil {
+			return &policy.Effective{Chain: ids}, fmt.Errorf("%w: policy %s: get base policy %s: %v", ErrPolicyInheritance, pol.ID, baseID, err)
+		}
+		if base.TenantID != pol.TenantID || base.AppID != pol.AppID {
+			return &policy.Effective{Chain: ids}, fmt.Errorf("%w: policy %s: base policy %s belongs to another tenant or app", ErrPolicyInheritance, pol.ID, baseID)
+		}
+		chain = append(chain, base)
+		ids = append(ids, base.ID)
+		cur = base
+	}
+
+	merged := chain[len(chain)-1]
+	for i := len(chain) - 2; i >= 0; i-- {
+		var err error
+		if merged, err = policy.Merge(merged, chain[i]); err != nil {
+			return &policy.Effective{Chain: ids}, fmt.Errorf("%w: policy %s: %v", ErrPolicyInheritance, pol.ID, err)
+		}
+	}
+	return &policy.Effective{Policy: merged, Chain: ids}, nil
+}
+
+// checkInheritance reports whether storing pol keeps every inheritance
+// chain through it resolvable: its own, and those of the tenant's policies
+// that inherit from it.
+func (e *Engine) checkInheritance(ctx context.Context, pol *policy.Policy) error {
+	if pol.BasePolicyID != nil {
+		base, err := e.getPolicy(ctx, *pol.BasePolicyID)
+		if err != nil {
+			return fmt.Errorf("%w: base policy: %v", ErrPolicyInheritance, err)
+		}
+		if base.Inline {
+			return fmt.Errorf("%w: base policy %s is an inline policy", ErrPolicyInheritance, base.ID)
+		}
+	}
+	all, err := e.store.Policies().List(ctx, &policy.ListFilter{TenantID: pol.TenantID, AppID: pol.AppID})
+	if err != nil {
+		return fmt.Errorf("list policies: %w", err)
+	}
+	byID := make(map[string]*policy.Policy, len(all)+1)
+	for _, p := range all {
+		byID[p.ID.String()] = p
+	}
+	byID[pol.ID.String()] = pol
+	get := func(polID id.PolicyID) (*policy.Policy, error) {
+		if p, ok := byID[polID.String()]; ok {
+			return p, nil
+		}
+		return nil, fmt.Errorf("%w: %s", ErrPolicyNotFound, polID)
+	}
+
+	if _, err := resolvePolicy(pol, get); err != nil {
+		return err
+	}
+	for _, p := range byID {
+		if p.BasePolicyID == nil || p.ID.String() == pol.ID.String() {
+			continue
+		}
+		eff, err := resolvePolicy(p, get)
+		if err != nil && slices.ContainsFunc(eff.Chain, func(v id.PolicyID) bool { return v.String() == pol.ID.String() }) {
+			return err
+		}
+	}
+	return nil
+}
+
+// inheritingPolicy returns a policy of pol's tenant whose base is pol, or
+// nil if there is none.
+func (e *Engine) inheritingPolicy(ctx context.Context, pol *policy.Policy) (*policy.Policy, error) {
+	all, err := e.store.Policies().List(ctx, &policy.ListFilter{TenantID: pol.TenantID, AppID: pol.AppID})
+	if err != nil {
+		return nil, fmt.Errorf("list policies: %w", err)
+	}
+	for _, p := range all {
+		if p.BasePolicyID != nil && p.BasePolicyID.String() == pol.ID.String() {
+			return p, nil
+		}
+	}
+	return nil, nil
+}
+
+// effectivePolicyCache holds merged policies by policy ID. An entry is used
+// only while the policy it was merged for is unchanged.
+type effectivePolicyCache struct {
+	mu      sync.Mutex
+	entries map[string]effectiveEntry
+
+	// gen counts invalidations, like validationCache.gen.
+	gen uint64
+}
+
+type effectiveEntry struct {
+	eff       *policy.Effective
+	updatedAt time.Time
+	expires   time.Time
+}
+
+func newEffectivePolicyCache() *effectivePolicyCache {
+	return &effectivePolicyCache{entries: make(map[string]effectiveEntry)}
+}
+
+func (c *effectivePolicyCache) get(pol *policy.Policy) (*policy.Effective, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ent, ok := c.entries[pol.ID.String()]
+	if !ok || !ent.updatedAt.Equal(pol.UpdatedAt) || time.Now().After(ent.expires) {
+		return nil, false
+	}
+	return ent.eff, true
+}
+
+func (c *effectivePolicyCache) generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+// put caches eff for pol unless an invalidation happened since gen was
+// read. When the cache is full it is emptied first.
+func (c *effectivePolicyCache) put(pol *policy.Policy, eff *policy.Effective, gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		return
+	}
+	if len(c.entries) >= maxCachedEffectivePolicies {
+		clear(c.entries)
+	}
+	c.entries[pol.ID.String()] = effectiveEntry{eff: eff, updatedAt: pol.UpdatedAt, expires: time.Now().Add(effectivePolicyTTL)}
+}
+
+// invalidate drops every entry: a change to one policy can change the
+// merged policies of all that inherit from it.
+func (c *effectivePolicyCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+	c.gen++
+}
@@ -0,0 +1,346 @@
+package keysmith_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+func TestPolicyMerge(t *testing.T) {
+	tests := []struct {
+		name    string
+		base    policy.Policy
+		child   policy.Policy
+		want    func(t *testing.T, got *policy.Policy)
+		wantErr error
+	}{
+		{
+			name:  "identity is the child's",
+			base:  policy.Policy{Name: "Base", Description: "tenant default", TenantID: "t1"},
+			child: policy.Policy{Name: "Child", TenantID: "t1"},
+			want: func(t *testing.T, got *policy.Policy) {
+				assert.Equal(t, "Child", got.Name)
+				assert.Empty(t, got.Description)
+			},
+		},
+		{
+			name:  "lower rate wins as a pair",
+			base:  policy.Policy{RateLimit: 100, RateLimitWindow: time.Minute},
+			child: policy.Policy{RateLimit: 10, RateLimitWindow: time.Second},
+			want: func(t *testing.T, got *policy.Policy) {
+				assert.Equal(t, 100, got.RateLimit, "100/min is lower than 10/s")
+				assert.Equal(t, time.Minute, got.RateLimitWindow)
+			},
+		},
+		{
+			name:  "child wins an equal rate",
+			base:  policy.Policy{RateLimit: 60, RateLimitWindow: time.Minute},
+			child: policy.Policy{RateLimit: 1, RateLimitWindow: time.Second},
+			want: func(t *testing.T, got *policy.Policy) {
+				assert.Equal(t, 1, got.RateLimit)
+				assert.Equal(t, time.Second, got.RateLimitWindow)
+			},
+		},
+		{
+			name:  "zero rate is unlimited",
+			base:  policy.Policy{RateLimit: 50, RateLimitWindow: time.Minute},
+			child: policy.Policy{},
+			want: func(t *testing.T, got *policy.Policy) {
+				assert.Equal(t, 50, got.RateLimit)
+				assert.Equal(t, time.Minute, got.RateLimitWindow)
+			},
+		},
+		{
+			name:  "smallest non-zero limits win",
+			base:  policy.Policy{BurstLimit: 20, MaxKeyLifetime: 90 * 24 * time.Hour, GracePeriod: time.Hour},
+			child: policy.Policy{BurstLimit: 50, MaxKeyLifetime: 30 * 24 * time.Hour, RotationPeriod: 7 * 24 * time.Hour},
+			want: func(t *testing.T, got *policy.Policy) {
+				assert.Equal(t, 20, got.BurstLimit)
+				assert.Equal(t, 30*24*time.Hour, got.MaxKeyLifetime)
+				assert.Equal(t, 7*24*time.Hour, got.RotationPeriod)
+				assert.Equal(t, time.Hour, got.GracePeriod)
+			},
+		},
+		{
+			name:  "quotas are the child's when set",
+			base:  policy.Policy{DailyQuota: 1000, MonthlyQuota: 20000},
+			child: policy.Policy{DailyQuota: 5000},
+			want: func(t *testing.T, got *policy.Policy) {
+				assert.Equal(t, int64(5000), got.DailyQuota, "the child may raise a quota")
+				assert.Equal(t, int64(20000), got.MonthlyQuota)
+			},
+		},
+		{
+			name:  "stricter transport wins",
+			base:  policy.Policy{MinTLSVersion: "1.3"},
+			child: policy.Policy{MinTLSVersion: "1.2", RequireMTLS: true},
+			want: func(t *testing.T, got *policy.Policy) {
+				assert.Equal(t, "1.3", got.MinTLSVersion, "the child may not lower the minimum")
+				assert.True(t, got.RequireMTLS)
+			},
+		},
+		{
+			name:  "empty lists are inherited",
+			base:  policy.Policy{AllowedScopes: []string{"read"}, AllowedOrigins: []string{"https://app.example.com"}},
+			child: policy.Policy{AllowedPaths: []string{"/v1/orders"}},
+			want: func(t *testing.T, got *policy.Policy) {
+				assert.Equal(t, []string{"read"}, got.AllowedScopes)
+				assert.Equal(t, []string{"https://app.example.com"}, got.AllowedOrigins)
+				assert.Equal(t, []string{"/v1/orders"}, got.AllowedPaths)
+			},
+		},
+		{
+			name:  "lists intersect",
+			base:  policy.Policy{AllowedScopes: []string{"read", "write", "admin"}},
+			child: policy.Policy{AllowedScopes: []string{"write", "read", "billing"}},
+			want: func(t *testing.T, got *policy.Policy) {
+				assert.Equal(t, []string{"write", "read"}, got.AllowedScopes, "in the child's order")
+			},
+		},
+		{
+			name:  "methods intersect ignoring case",
+			base:  policy.Policy{AllowedMethods: []string{"GET", "POST"}},
+			child: policy.Policy{AllowedMethods: []string{"get", "delete"}},
+			want: func(t *testing.T, got *policy.Policy) {
+				assert.Equal(t, []string{"get"}, got.AllowedMethods)
+			},
+		},
+		{
+			name:  "IP ranges keep the narrower entry",
+			base:  policy.Policy{AllowedIPs: []string{"10.0.0.0/8", "192.168.1.10"}},
+			child: policy.Policy{AllowedIPs: []string{"10.1.0.0/16", "192.168.0.0/16", "172.16.0.0/12"}},
+			want: func(t *testing.T, got *policy.Policy) {
+				assert.Equal(t, []string{"10.1.0.0/16", "192.168.1.10"}, got.AllowedIPs)
+			},
+		},
+		{
+			name:  "path patterns intersect by containment",
+			base:  policy.Policy{AllowedPaths: []string{"/v1/**", "/status"}},
+			child: policy.Policy{AllowedPaths: []string{"/v1/users/*", "/v2/**", "/*"}},
+			want: func(t *testing.T, got *policy.Policy) {
+				assert.Equal(t, []string{"/v1/users/*", "/status"}, got.AllowedPaths,
+					"the child's narrower pattern and the base's narrower entry")
+			},
+		},
+		{
+			name:    "disjoint path patterns",
+			base:    policy.Policy{AllowedPaths: []string{"/v1/*/keys"}},
+			child:   policy.Policy{AllowedPaths: []string{"/v1/users/*", "/v2/**"}},
+			wantErr: policy.ErrNoOverlap,
+		},
+		{
+			name:    "disjoint lists",
+			base:    policy.Policy{AllowedIPs: []string{"10.0.0.0/8"}, AllowedScopes: []string{"read"}},
+			child:   policy.Policy{AllowedIPs: []string{"192.168.0.0/16"}, AllowedScopes: []string{"read"}},
+			wantErr: policy.ErrNoOverlap,
+		},
+		{
+			name:  "metadata overlays the base",
+			base:  policy.Policy{Metadata: map[string]any{"tier": "free", "region": "eu"}},
+			child: policy.Policy{Metadata: map[string]any{"tier": "pro"}},
+			want: func(t *testing.T, got *policy.Policy) {
+				assert.Equal(t, map[string]any{"tier": "pro", "region": "eu"}, got.Metadata)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, child := tt.base, tt.child
+			got, err := policy.Merge(&base, &child)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			tt.want(t, got)
+		})
+	}
+}
+
+func TestPolicyMerge_LeavesInputsUnchanged(t *testing.T) {
+	base := &policy.Policy{AllowedScopes: []string{"read", "write"}, Metadata: map[string]any{"a": 1}}
+	child := &policy.Policy{AllowedScopes: []string{"read"}, Metadata: map[string]any{"b": 2}}
+
+	got, err := policy.Merge(base, child)
+	require.NoError(t, err)
+	got.Metadata["c"] = 3
+
+	assert.Equal(t, []string{"read", "write"}, base.AllowedScopes)
+	assert.Equal(t, map[string]any{"a": 1}, base.Metadata)
+	assert.Equal(t, map[string]any{"b": 2}, child.Metadata)
+}
+
+func createBasedPolicy(t *testing.T, eng *keysmith.Engine, name string, base *policy.Policy, pol *policy.Policy) *policy.Policy {
+	t.Helper()
+	pol.Name = name
+	if base != nil {
+		pol.BasePolicyID = &base.ID
+	}
+	require.NoError(t, eng.CreatePolicy(testCtx(), pol))
+	return pol
+}
+
+func TestPolicyInheritance_ValidationUsesEffectivePolicy(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithRateLimiter(newCountingLimiter()))
+	require.NoError(t, err)
+	base := createBasedPolicy(t, eng, "Tenant Base", nil, &policy.Policy{
+		RateLimit:       1,
+		RateLimitWindow: time.Minute,
+		AllowedOrigins:  []string{"https://app.example.com"},
+	})
+	child := createBasedPolicy(t, eng, "Orders", base, &policy.Policy{RateLimit: 100, RateLimitWindow: time.Minute})
+
+	raw := createLimitedKey(t, testCtx(), eng, child)
+
+	_, err = eng.ValidateKey(originCtx("https://other.example.com"), raw)
+	require.ErrorIs(t, err, keysmith.ErrOriginNotAllowed, "the base's origins apply")
+
+	vr, err := eng.ValidateKey(originCtx("https://app.example.com"), raw)
+	require.NoError(t, err)
+	assert.Equal(t, child.ID, vr.Policy.ID)
+	assert.Equal(t, 1, vr.Policy.RateLimit, "the snapshot carries the merged policy")
+	assert.Equal(t, []string{"https://app.example.com"}, vr.Policy.AllowedOrigins)
+
+	_, err = eng.ValidateKey(originCtx("https://app.example.com"), raw)
+	assert.ErrorIs(t, err, keysmith.ErrRateLimited, "the base's lower rate limit applies")
+}
+
+func TestPolicyInheritance_BaseUpdateInvalidates(t *testing.T) {
+	eng := newTestEngine(t)
+	base := createBasedPolicy(t, eng, "Tenant Base", nil, &policy.Policy{AllowedOrigins: []string{"https://app.example.com"}})
+	child := createBasedPolicy(t, eng, "Orders", base, &policy.Policy{})
+	created := createOriginKey(t, eng, &child.ID, nil)
+
+	_, err := eng.ValidateKey(originCtx("https://app.example.com"), created.RawKey)
+	require.NoError(t, err)
+
+	base.AllowedOrigins = []string{"https://admin.example.com"}
+	require.NoError(t, eng.UpdatePolicy(testCtx(), base))
+
+	_, err = eng.ValidateKey(originCtx("https://app.example.com"), created.RawKey)
+	assert.ErrorIs(t, err, keysmith.ErrOriginNotAllowed)
+	_, err = eng.ValidateKey(originCtx("https://admin.example.com"), created.RawKey)
+	assert.NoError(t, err)
+
+	eff, err := eng.EffectivePolicy(testCtx(), child.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://admin.example.com"}, eff.Policy.AllowedOrigins)
+}
+
+func TestEffectivePolicy_Chain(t *testing.T) {
+	eng := newTestEngine(t)
+	root := createBasedPolicy(t, eng, "Root", nil, &policy.Policy{DailyQuota: 1000, AllowedScopes: []string{"read", "write"}})
+	mid := createBasedPolicy(t, eng, "Mid", root, &policy.Policy{BurstLimit: 10})
+	leaf := createBasedPolicy(t, eng, "Leaf", mid, &policy.Policy{AllowedScopes: []string{"read"}})
+
+	eff, err := eng.EffectivePolicy(testCtx(), leaf.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []id.PolicyID{leaf.ID, mid.ID, root.ID}, eff.Chain)
+	assert.Equal(t, "Leaf", eff.Policy.Name)
+	assert.Equal(t, int64(1000), eff.Policy.DailyQuota)
+	assert.Equal(t, 10, eff.Policy.BurstLimit)
+	assert.Equal(t, []string{"read"}, eff.Policy.AllowedScopes)
+
+	eff, err = eng.EffectivePolicy(testCtx(), root.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []id.PolicyID{root.ID}, eff.Chain)
+	assert.Equal(t, "Root", eff.Policy.Name)
+}
+
+func TestPolicyInheritance_RejectsCycle(t *testing.T) {
+	eng := newTestEngine(t)
+	a := createBasedPolicy(t, eng, "A", nil, &policy.Policy{})
+	b := createBasedPolicy(t, eng, "B", a, &policy.Policy{})
+
+	a.BasePolicyID = &b.ID
+	err := eng.UpdatePolicy(testCtx(), a)
+	assert.ErrorIs(t, err, keysmith.ErrPolicyInheritance)
+
+	a.BasePolicyID = &a.ID
+	err = eng.UpdatePolicy(testCtx(), a)
+	assert.ErrorIs(t, err, keysmith.ErrInvalidPolicy, "a policy cannot inherit from itself")
+
+	stored, err := eng.GetPolicy(testCtx(), a.ID)
+	require.NoError(t, err)
+	assert.Nil(t, stored.BasePolicyID)
+}
+
+func TestPolicyInheritance_MaxDepth(t *testing.T) {
+	eng := newTestEngine(t)
+	prev := createBasedPolicy(t, eng, "Root", nil, &policy.Policy{})
+	for range policy.MaxInheritanceDepth {
+		prev = createBasedPolicy(t, eng, "Level", prev, &policy.Policy{})
+	}
+
+	err := eng.CreatePolicy(testCtx(), &policy.Policy{Name: "Too Deep", BasePolicyID: &prev.ID})
+	assert.ErrorIs(t, err, keysmith.ErrPolicyInheritance)
+}
+
+func TestPolicyInheritance_PathPatterns(t *testing.T) {
+	eng := newTestEngine(t)
+	base := createBasedPolicy(t, eng, "API", nil, &policy.Policy{AllowedPaths: []string{"/v1/**"}})
+	child := createBasedPolicy(t, eng, "Users", base, &policy.Policy{AllowedPaths: []string{"/v1/users/*"}})
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "Users", Prefix: "sk", Environment: key.EnvTest, PolicyID: &child.ID})
+	require.NoError(t, err)
+
+	_, err = eng.ValidateKey(routeCtx("GET", "/v1/users/42"), created.RawKey)
+	require.NoError(t, err)
+	_, err = eng.ValidateKey(routeCtx("GET", "/v1/orders/7"), created.RawKey)
+	assert.ErrorIs(t, err, keysmith.ErrPathNotAllowed, "the child narrows the base")
+}
+
+func TestPolicyInheritance_RejectsDisjointChild(t *testing.T) {
+	eng := newTestEngine(t)
+	base := createBasedPolicy(t, eng, "Base", nil, &policy.Policy{AllowedIPs: []string{"10.0.0.0/8"}})
+
+	err := eng.CreatePolicy(testCtx(), &policy.Policy{Name: "Elsewhere", BasePolicyID: &base.ID, AllowedIPs: []string{"192.168.0.0/16"}})
+	assert.ErrorIs(t, err, keysmith.ErrPolicyInheritance)
+	assert.ErrorContains(t, err, "allowed_ips")
+
+	// Narrowing a base so an existing child no longer overlaps is refused
+	// too.
+	child := createBasedPolicy(t, eng, "Office", base, &policy.Policy{AllowedIPs: []string{"10.1.0.0/16"}})
+	base.AllowedIPs = []string{"172.16.0.0/12"}
+	err = eng.UpdatePolicy(testCtx(), base)
+	assert.ErrorIs(t, err, keysmith.ErrPolicyInheritance)
+	assert.ErrorContains(t, err, child.ID.String())
+}
+
+func TestPolicyInheritance_MissingBase(t *testing.T) {
+	eng := newTestEngine(t)
+	missing := id.NewPolicyID()
+
+	err := eng.CreatePolicy(testCtx(), &policy.Policy{Name: "Orphan", BasePolicyID: &missing})
+	assert.ErrorIs(t, err, keysmith.ErrPolicyInheritance)
+}
+
+func TestDeletePolicy_RefusesBaseInUse(t *testing.T) {
+	eng := newTestEngine(t)
+	base := createBasedPolicy(t, eng, "Base", nil, &policy.Policy{})
+	child := createBasedPolicy(t, eng, "Child", base, &policy.Policy{})
+
+	err := eng.DeletePolicy(testCtx(), base.ID)
+	assert.ErrorIs(t, err, keysmith.ErrPolicyInUse)
+
+	require.NoError(t, eng.DeletePolicy(testCtx(), child.ID))
+	assert.NoError(t, eng.DeletePolicy(testCtx(), base.ID))
+}
+
+func TestPolicyInheritance_KeyLifetimeFromBase(t *testing.T) {
+	eng := newTestEngine(t)
+	base := createBasedPolicy(t, eng, "Base", nil, &policy.Policy{MaxKeyLifetime: 24 * time.Hour})
+	child := createBasedPolicy(t, eng, "Child", base, &policy.Policy{})
+
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "Short", Prefix: "sk", Environment: key.EnvTest, PolicyID: &child.ID})
+	require.NoError(t, err)
+	require.NotNil(t, created.Key.ExpiresAt)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), *created.Key.ExpiresAt, time.Minute)
+}
@@ -0,0 +1,182 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/policy"
+)
+
+// InlinePolicyPrefix starts the generated name of an inline policy, which is
+// followed by its key's ID.
+const InlinePolicyPrefix = "inline-"
+
+// inlinePolicy returns a validated copy of the fields of in that an inline
+// policy may set: the rate limit, its window and burst, the quotas and their
+// time zone, and the allowed IPs and origins. Setting any other field is
+// ErrInvalidPolicy; the ID, name, owner, and timestamps are ignored.
+func inlinePolicy(in *policy.Policy) (*policy.Policy, error) {
+	var extra []string
+	for name, set := range map[string]bool{
+		"description":      in.Description != "",
+		"base_policy_id":   in.BasePolicyID != nil,
+		"allowed_scopes":   len(in.AllowedScopes) > 0,
+		"allowed_methods":  len(in.AllowedMethods) > 0,
+		"allowed_paths":    len(in.AllowedPaths) > 0,
+		"max_key_lifetime": in.MaxKeyLifetime != 0,
+		"rotation_period":  in.RotationPeriod != 0,
+		"grace_period":     in.GracePeriod != 0,
+		"min_tls_version":  in.MinTLSVersion != "",
+		"require_mtls":     in.RequireMTLS,
+		"metadata":         len(in.Metadata) > 0,
+	} {
+		if set {
+			extra = append(extra, name)
+		}
+	}
+	if len(extra) > 0 {
+		slices.Sort(extra)
+		return nil, fmt.Errorf("%w: an inline policy cannot set %s", ErrInvalidPolicy, strings.Join(extra, ", "))
+	}
+	pol := &policy.Policy{
+		Name:            InlinePolicyPrefix,
+		RateLimit:       in.RateLimit,
+		RateLimitWindow: in.RateLimitWindow,
+		BurstLimit:      in.BurstLimit,
+		DailyQuota:      in.DailyQuota,
+		MonthlyQuota:    in.MonthlyQuota,
+		QuotaTimezone:   in.QuotaTimezone,
+		AllowedIPs:      slices.Clone(in.AllowedIPs),
+		AllowedOrigins:  slices.Clone(in.AllowedOrigins),
+		Inline:          true,
+	}
+	if err := validateOrigins(pol.AllowedOrigins); err != nil {
+		return nil, err
+	}
+	if err := pol.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+	}
+	return pol, nil
+}
+
+// storeInlinePolicy stores pol as the inline policy of k and points k at it.
+func (e *Engine) storeInlinePolicy(ctx context.Context, k *key.Key, pol *policy.Policy) error {
+	pol.ID = id.NewPolicyID()
+	pol.TenantID, pol.AppID = k.TenantID, k.AppID
+	pol.Name = InlinePolicyPrefix + k.ID.String()
+	pol.CreatedAt, pol.UpdatedAt = k.CreatedAt, k.CreatedAt
+	if err := e.store.Policies().Create(ctx, pol); err != nil {
+		return fmt.Errorf("store inline policy: %w", err)
+	}
+	k.PolicyID = &pol.ID
+	return nil
+}
+
+// keyInlinePolicy returns k's inline policy, or nil if k has a shared
+// policy or none.
+func (e *Engine) keyInlinePolicy(ctx context.Context, k *key.Key) (*policy.Policy, error) {
+	if k.PolicyID == nil {
+		return nil, nil
+	}
+	pol, err := e.store.Policies().Get(ctx, *k.PolicyID)
+	if err != nil {
+		return nil, fmt.Errorf("get policy: %w", err)
+	}
+	if !pol.Inline {
+		return nil, nil
+	}
+	return pol, nil
+}
+
+// updateInlinePolicy replaces the inline values of k's inline policy with
+// those of in. A key without an inline policy is ErrInvalidPolicy.
+func (e *Engine) updateInlinePolicy(ctx context.Context, k *key.Key, in *policy.Policy) error {
+	cur, err := e.keyInlinePolicy(ctx, k)
+	if err != nil {
+		return err
+	}
+	if cur == nil {
+		return fmt.Errorf("%w: key %s has no inline policy", ErrInvalidPolicy, k.ID)
+	}
+	pol, err := inlinePolicy(in)
+	if err != nil {
+		return err
+	}
+	if IsDryRun(ctx) {
+		return nil
+	}
+	pol.ID, pol.TenantID, pol.AppID, pol.Name = cur.ID, cur.TenantID, cur.AppID, cur.Name
+	pol.CreatedAt, pol.UpdatedAt = cur.CreatedAt, time.Now()
+	if err := e.store.Policies().Update(ctx, pol); err != nil {
+		return fmt.Errorf("update inline policy: %w", err)
+	}
+	e.effective.invalidate()
+	return nil
+}
+
+// deleteKey removes k from the store together with its inline policy.
+func (e *Engine) deleteKey(ctx context.Context, k *key.Key) error {
+	if err := e.store.Keys().Delete(ctx, k.ID); err != nil {
+		return err
+	}
+	return e.dropInlinePolicy(ctx, k)
+}
+
+// dropInlinePolicy deletes k's inline policy, if it has one.
+func (e *Engine) dropInlinePolicy(ctx context.Context, k *key.Key) error {
+	pol, err := e.keyInlinePolicy(ctx, k)
+	if err != nil || pol == nil {
+		return err
+	}
+	if err := e.store.Policies().Delete(ctx, pol.ID); err != nil {
+		return fmt.Errorf("delete inline policy: %w", err)
+	}
+	e.effective.invalidate()
+	return nil
+}
+
+// PromoteInlinePolicy turns the inline policy of the key into a shared
+// policy named name, which is listed and can be assigned to other keys
+// like any other. The key keeps the policy. A key without an inline policy,
+// or a name another policy of the tenant has, is ErrInvalidPolicy.
+func (e *Engine) PromoteInlinePolicy(ctx context.Context, keyID id.KeyID, name string) (*policy.Policy, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	k, err := e.getKey(ctx, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("get key: %w", err)
+	}
+	pol, err := e.keyInlinePolicy(ctx, k)
+	if err != nil {
+		return nil, err
+	}
+	if pol == nil {
+		return nil, fmt.Errorf("%w: key %s has no inline policy", ErrInvalidPolicy, k.ID)
+	}
+	pol.Name = strings.TrimSpace(name)
+	if err := pol.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+	}
+	if _, err := e.store.Policies().GetByName(ctx, pol.TenantID, pol.Name); err == nil {
+		return nil, fmt.Errorf("%w: a policy named %q exists", ErrInvalidPolicy, pol.Name)
+	}
+	pol.Inline = false
+	pol.UpdatedAt = time.Now()
+	if IsDryRun(ctx) {
+		return pol, nil
+	}
+	if err := e.store.Policies().Update(ctx, pol); err != nil {
+		return nil, fmt.Errorf("update policy: %w", err)
+	}
+	e.effective.invalidate()
+	e.bumpRevision(ctx, pol.TenantID)
+	_ = e.hooks.FirePolicyCreated(ctx, pol, e.eventMeta(ctx, plugin.TriggerManual, ""))
+	return pol, nil
+}
@@ -0,0 +1,191 @@
+package keysmith_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keysmithtest"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+func inlineLimits() *policy.Policy {
+	return &policy.Policy{
+		RateLimit:       1,
+		RateLimitWindow: time.Minute,
+		DailyQuota:      100,
+		AllowedOrigins:  []string{"https://app.example.com"},
+	}
+}
+
+func createInlineKey(t *testing.T, eng *keysmith.Engine, pol *policy.Policy) *key.CreateResult {
+	t.Helper()
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+		Name:         "One-off",
+		Prefix:       "sk",
+		Environment:  key.EnvTest,
+		InlinePolicy: pol,
+	})
+	require.NoError(t, err)
+	return created
+}
+
+func TestInlinePolicy_Create(t *testing.T) {
+	eng := newTestEngine(t)
+	created := createInlineKey(t, eng, inlineLimits())
+	require.NotNil(t, created.Key.PolicyID)
+
+	pol, err := eng.GetPolicy(testCtx(), *created.Key.PolicyID)
+	require.NoError(t, err)
+	assert.True(t, pol.Inline)
+	assert.Equal(t, keysmith.InlinePolicyPrefix+created.Key.ID.String(), pol.Name)
+	assert.Equal(t, 1, pol.RateLimit)
+	assert.Equal(t, int64(100), pol.DailyQuota)
+
+	pols, err := eng.ListPolicies(testCtx(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, pols, "inline policies are not listed")
+	pols, err = eng.ListPolicies(testCtx(), &policy.ListFilter{IncludeInline: true})
+	require.NoError(t, err)
+	assert.Len(t, pols, 1)
+}
+
+func TestInlinePolicy_Invalid(t *testing.T) {
+	eng := newTestEngine(t)
+	named := &policy.Policy{Name: "Standard"}
+	require.NoError(t, eng.CreatePolicy(testCtx(), named))
+
+	for name, input := range map[string]*keysmith.CreateKeyInput{
+		"with policy ID":    {PolicyID: &named.ID, InlinePolicy: &policy.Policy{RateLimit: 1, RateLimitWindow: time.Minute}},
+		"field not inline":  {InlinePolicy: &policy.Policy{AllowedScopes: []string{"read"}}},
+		"no window":         {InlinePolicy: &policy.Policy{RateLimit: 10}},
+		"invalid IP":        {InlinePolicy: &policy.Policy{AllowedIPs: []string{"not-an-ip"}}},
+		"negative quota":    {InlinePolicy: &policy.Policy{DailyQuota: -1}},
+		"unknown time zone": {InlinePolicy: &policy.Policy{QuotaTimezone: "Mars/Olympus"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			input.Name, input.Prefix, input.Environment = "One-off", "sk", key.EnvTest
+			_, err := eng.CreateKey(testCtx(), input)
+			assert.ErrorIs(t, err, keysmith.ErrInvalidPolicy)
+		})
+	}
+	_, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+		Name: "One-off", Prefix: "sk", Environment: key.EnvTest,
+		InlinePolicy: &policy.Policy{AllowedOrigins: []string{"app.example.com/path"}},
+	})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidOrigin)
+
+	keys, err := eng.ListKeys(testCtx(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+	pols, err := eng.ListPolicies(testCtx(), &policy.ListFilter{IncludeInline: true})
+	require.NoError(t, err)
+	assert.Len(t, pols, 1, "no inline policy is left behind")
+}
+
+func TestInlinePolicy_EnforcedLikeNamedPolicy(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithRateLimiter(newCountingLimiter()))
+	require.NoError(t, err)
+	named := inlineLimits()
+	named.Name = "Shared"
+	require.NoError(t, eng.CreatePolicy(testCtx(), named))
+
+	for name, raw := range map[string]string{
+		"named":  createLimitedKey(t, testCtx(), eng, named),
+		"inline": createInlineKey(t, eng, inlineLimits()).RawKey,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := eng.ValidateKey(originCtx("https://other.example.com"), raw)
+			require.ErrorIs(t, err, keysmith.ErrOriginNotAllowed)
+
+			vr, err := eng.ValidateKey(originCtx("https://app.example.com"), raw)
+			require.NoError(t, err)
+			assert.Equal(t, int64(100), vr.Policy.DailyQuota)
+
+			_, err = eng.ValidateKey(originCtx("https://app.example.com"), raw)
+			assert.ErrorIs(t, err, keysmith.ErrRateLimited)
+		})
+	}
+}
+
+func TestInlinePolicy_Update(t *testing.T) {
+	eng := newTestEngine(t)
+	created := createInlineKey(t, eng, inlineLimits())
+
+	_, err := eng.UpdateKey(testCtx(), created.Key.ID, &keysmith.UpdateKeyInput{
+		InlinePolicy: &policy.Policy{AllowedOrigins: []string{"https://admin.example.com"}},
+	})
+	require.NoError(t, err)
+	vr, err := eng.ValidateKey(originCtx("https://admin.example.com"), created.RawKey)
+	require.NoError(t, err)
+	assert.Zero(t, vr.Policy.RateLimit, "the inline values are replaced")
+	_, err = eng.ValidateKey(originCtx("https://app.example.com"), created.RawKey)
+	assert.ErrorIs(t, err, keysmith.ErrOriginNotAllowed)
+
+	_, err = eng.UpdateKey(testCtx(), created.Key.ID, &keysmith.UpdateKeyInput{
+		InlinePolicy: &policy.Policy{MaxKeyLifetime: time.Hour},
+	})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidPolicy)
+
+	plain := keysmithtest.NewKey(eng).MustCreate(t, testCtx())
+	_, err = eng.UpdateKey(testCtx(), plain.Key.ID, &keysmith.UpdateKeyInput{InlinePolicy: inlineLimits()})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidPolicy, "the key has no inline policy")
+}
+
+func TestInlinePolicy_DeletedWithKey(t *testing.T) {
+	eng, _ := newDeliveryEngine(t)
+	input := deliveryInput(&fakeSink{err: errors.New("vault sealed")})
+	input.InlinePolicy = inlineLimits()
+
+	_, err := eng.CreateKey(testCtx(), input)
+	require.ErrorIs(t, err, keysmith.ErrDeliveryFailed)
+
+	pols, err := eng.Store().Policies().List(testCtx(), &policy.ListFilter{IncludeInline: true})
+	require.NoError(t, err)
+	assert.Empty(t, pols, "the rolled back key's inline policy is deleted")
+}
+
+func TestInlinePolicy_Promote(t *testing.T) {
+	eng := newTestEngine(t)
+	created := createInlineKey(t, eng, inlineLimits())
+	require.NoError(t, eng.CreatePolicy(testCtx(), &policy.Policy{Name: "Taken"}))
+
+	_, err := eng.PromoteInlinePolicy(testCtx(), created.Key.ID, "Taken")
+	assert.ErrorIs(t, err, keysmith.ErrInvalidPolicy)
+	_, err = eng.PromoteInlinePolicy(testCtx(), created.Key.ID, " ")
+	assert.ErrorIs(t, err, keysmith.ErrInvalidPolicy)
+
+	pol, err := eng.PromoteInlinePolicy(testCtx(), created.Key.ID, "One-off Limits")
+	require.NoError(t, err)
+	assert.False(t, pol.Inline)
+	assert.Equal(t, *created.Key.PolicyID, pol.ID, "the key keeps the policy")
+
+	pols, err := eng.ListPolicies(testCtx(), nil)
+	require.NoError(t, err)
+	assert.Len(t, pols, 2, "the promoted policy is listed")
+
+	other, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+		Name: "Second", Prefix: "sk", Environment: key.EnvTest, PolicyID: &pol.ID,
+	})
+	require.NoError(t, err, "the promoted policy can be shared")
+	assert.Equal(t, pol.ID, *other.Key.PolicyID)
+
+	_, err = eng.PromoteInlinePolicy(testCtx(), created.Key.ID, "Again")
+	assert.ErrorIs(t, err, keysmith.ErrInvalidPolicy, "the policy is no longer inline")
+	_, err = eng.UpdateKey(testCtx(), created.Key.ID, &keysmith.UpdateKeyInput{InlinePolicy: inlineLimits()})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidPolicy)
+}
+
+func TestInlinePolicy_NotABase(t *testing.T) {
+	eng := newTestEngine(t)
+	created := createInlineKey(t, eng, inlineLimits())
+
+	err := eng.CreatePolicy(testCtx(), &policy.Policy{Name: "Child", BasePolicyID: created.Key.PolicyID})
+	assert.ErrorIs(t, err, keysmith.ErrPolicyInheritance)
+}
@@ -0,0 +1,253 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/policy"
+)
+
+// MetadataPolicyTemplate is the policy metadata entry recording the template
+// a policy was created from.
+const MetadataPolicyTemplate = "keysmith.template"
+
+// Built-in policy template names.
+const (
+	PolicyTemplateUnlimited = "unlimited"
+	PolicyTemplateStandard  = "standard"
+	PolicyTemplateStrict    = "strict"
+)
+
+var (
+	policyTemplatesMu sync.RWMutex
+	policyTemplates   = map[string]policy.Policy{
+		PolicyTemplateUnlimited: {
+			Name:        "Unlimited",
+			Description: "No rate limits or quotas.",
+			GracePeriod: 24 * time.Hour,
+		},
+		PolicyTemplateStandard: {
+			Name:            "Standard",
+			Description:     "General-purpose limits for production traffic.",
+			RateLimit:       1000,
+			RateLimitWindow: time.Minute,
+			BurstLimit:      100,
+			RotationPeriod:  90 * 24 * time.Hour,
+			GracePeriod:     24 * time.Hour,
+		},
+		PolicyTemplateStrict: {
+			Name:            "Strict",
+			Description:     "Tight limits, short-lived keys, and fast rotation.",
+			RateLimit:       100,
+			RateLimitWindow: time.Minute,
+			BurstLimit:      10,
+			MaxKeyLifetime:  90 * 24 * time.Hour,
+			RotationPeriod:  30 * 24 * time.Hour,
+			GracePeriod:     time.Hour,
+			DailyQuota:      10_000,
+			MonthlyQuota:    250_000,
+		},
+	}
+)
+
+// RegisterPolicyTemplate adds or replaces a named policy preset. Templates
+// live in code rather than the store, so they are versioned with the binary.
+// Identity and timestamp fields on tmpl are ignored. It is typically called
+// from an init function.
+func RegisterPolicyTemplate(name string, tmpl policy.Policy) {
+	if name == "" {
+		panic("keysmith: policy template name is required")
+	}
+	policyTemplatesMu.Lock()
+	defer policyTemplatesMu.Unlock()
+	policyTemplates[name] = clonePolicy(tmpl)
+}
+
+// PolicyTemplate returns a copy of the named template.
+func PolicyTemplate(name string) (policy.Policy, bool) {
+	policyTemplatesMu.RLock()
+	defer policyTemplatesMu.RUnlock()
+	tmpl, ok := policyTemplates[name]
+	if !ok {
+		return policy.Policy{}, false
+	}
+	return clonePolicy(tmpl), true
+}
+
+// PolicyTemplateNames returns the registered template names in sorted order.
+func PolicyTemplateNames() []string {
+	policyTemplatesMu.RLock()
+	defer policyTemplatesMu.RUnlock()
+	return slices.Sorted(maps.Keys(policyTemplates))
+}
+
+// CreatePolicyFromTemplate creates a policy from a named template with
+// overrides applied field by field. A zero value in overrides leaves the
+// template's value in place; to explicitly reset a field to its zero value,
+// name it in clearFields using its JSON field name (e.g., "daily_quota"). The
+// merged policy must pass [policy.Policy.Validate].
+func (e *Engine) CreatePolicyFromTemplate(ctx context.Context, templateName string, overrides *policy.Policy, clearFields ...string) (*policy.Policy, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	tmpl, ok := PolicyTemplate(templateName)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrPolicyTemplateNotFound, templateName)
+	}
+
+	pol := &tmpl
+	for _, field := range clearFields {
+		if !clearPolicyField(pol, field) {
+			return nil, fmt.Errorf("%w: cannot clear unknown field %q", ErrInvalidPolicy, field)
+		}
+	}
+	if overrides != nil {
+		mergePolicy(pol, overrides)
+	}
+
+	if err := pol.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+	}
+	if err := e.validateMetadata(pol.Metadata, nil); err != nil {
+		return nil, err
+	}
+	pol.Metadata = setInternalMetadata(pol.Metadata, MetadataPolicyTemplate, templateName)
+
+	if err := e.createPolicy(ctx, pol); err != nil {
+		return nil, err
+	}
+	return pol, nil
+}
+
+// mergePolicy copies every non-zero configurable field of src onto dst.
+// Metadata is merged key by key.
+func mergePolicy(dst, src *policy.Policy) {
+	if src.Name != "" {
+		dst.Name = src.Name
+	}
+	if src.Description != "" {
+		dst.Description = src.Description
+	}
+	if src.BasePolicyID != nil {
+		dst.BasePolicyID = src.BasePolicyID
+	}
+	if src.RateLimit != 0 {
+		dst.RateLimit = src.RateLimit
+	}
+	if src.RateLimitWindow != 0 {
+		dst.RateLimitWindow = src.RateLimitWindow
+	}
+	if src.BurstLimit != 0 {
+		dst.BurstLimit = src.BurstLimit
+	}
+	if len(src.AllowedScopes) > 0 {
+		dst.AllowedScopes = slices.Clone(src.AllowedScopes)
+	}
+	if len(src.AllowedIPs) > 0 {
+		dst.AllowedIPs = slices.Clone(src.AllowedIPs)
+	}
+	if len(src.AllowedOrigins) > 0 {
+		dst.AllowedOrigins = slices.Clone(src.AllowedOrigins)
+	}
+	if len(src.AllowedMethods) > 0 {
+		dst.AllowedMethods = slices.Clone(src.AllowedMethods)
+	}
+	if len(src.AllowedPaths) > 0 {
+		dst.AllowedPaths = slices.Clone(src.AllowedPaths)
+	}
+	if src.MaxKeyLifetime != 0 {
+		dst.MaxKeyLifetime = src.MaxKeyLifetime
+	}
+	if src.RotationPeriod != 0 {
+		dst.RotationPeriod = src.RotationPeriod
+	}
+	if src.GracePeriod != 0 {
+		dst.GracePeriod = src.GracePeriod
+	}
+	if src.DailyQuota != 0 {
+		dst.DailyQuota = src.DailyQuota
+	}
+	if src.MonthlyQuota != 0 {
+		dst.MonthlyQuota = src.MonthlyQuota
+	}
+	if src.QuotaTimezone != "" {
+		dst.QuotaTimezone = src.QuotaTimezone
+	}
+	if src.MinTLSVersion != "" {
+		dst.MinTLSVersion = src.MinTLSVersion
+	}
+	if src.RequireMTLS {
+		dst.RequireMTLS = true
+	}
+	if len(src.Metadata) > 0 {
+		if dst.Metadata == nil {
+			dst.Metadata = make(map[string]any, len(src.Metadata))
+		}
+		maps.Copy(dst.Metadata, src.Metadata)
+	}
+}
+
+// clearPolicyField zeroes the field with the given JSON name and reports
+// whether the name was recognized.
+func clearPolicyField(p *policy.Policy, field string) bool {
+	switch field {
+	case "description":
+		p.Description = ""
+	case "rate_limit":
+		p.RateLimit = 0
+	case "rate_limit_window":
+		p.RateLimitWindow = 0
+	case "burst_limit":
+		p.BurstLimit = 0
+	case "allowed_scopes":
+		p.AllowedScopes = nil
+	case "allowed_ips":
+		p.AllowedIPs = nil
+	case "allowed_origins":
+		p.AllowedOrigins = nil
+	case "allowed_methods":
+		p.AllowedMethods = nil
+	case "allowed_paths":
+		p.AllowedPaths = nil
+	case "max_key_lifetime":
+		p.MaxKeyLifetime = 0
+	case "rotation_period":
+		p.RotationPeriod = 0
+	case "grace_period":
+		p.GracePeriod = 0
+	case "daily_quota":
+		p.DailyQuota = 0
+	case "monthly_quota":
+		p.MonthlyQuota = 0
+	case "quota_timezone":
+		p.QuotaTimezone = ""
+	case "min_tls_version":
+		p.MinTLSVersion = ""
+	case "require_mtls":
+		p.RequireMTLS = false
+	default:
+		return false
+	}
+	return true
+}
+
+// clonePolicy returns a deep copy of the configurable parts of p with identity
+// and timestamps reset.
+func clonePolicy(p policy.Policy) policy.Policy {
+	cp := p
+	cp.ID = id.PolicyID{}
+	cp.TenantID, cp.AppID = "", ""
+	cp.CreatedAt, cp.UpdatedAt = time.Time{}, time.Time{}
+	cp.AllowedScopes = slices.Clone(p.AllowedScopes)
+	cp.AllowedIPs = slices.Clone(p.AllowedIPs)
+	cp.AllowedOrigins = slices.Clone(p.AllowedOrigins)
+	cp.AllowedMethods = slices.Clone(p.AllowedMethods)
+	cp.AllowedPaths = slices.Clone(p.AllowedPaths)
+	cp.Metadata = maps.Clone(p.Metadata)
+	return cp
+}
@@ -0,0 +1,131 @@
+package keysmith_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/policy"
+)
+
+func TestPolicyTemplates_BuiltinsAreValid(t *testing.T) {
+	for _, name := range []string{
+		keysmith.PolicyTemplateUnlimited,
+		keysmith.PolicyTemplateStandard,
+		keysmith.PolicyTemplateStrict,
+	} {
+		t.Run(name, func(t *testing.T) {
+			tmpl, ok := keysmith.PolicyTemplate(name)
+			require.True(t, ok)
+			assert.NoError(t, tmpl.Validate())
+		})
+	}
+}
+
+func TestCreatePolicyFromTemplate_Merge(t *testing.T) {
+	eng := newTestEngine(t)
+	ctx := testCtx()
+
+	pol, err := eng.CreatePolicyFromTemplate(ctx, keysmith.PolicyTemplateStrict, &policy.Policy{
+		Name:      "Partner API",
+		RateLimit: 250,
+		// Zero values must not override the template.
+		BurstLimit: 0,
+	})
+	require.NoError(t, err)
+
+	strict, _ := keysmith.PolicyTemplate(keysmith.PolicyTemplateStrict)
+	assert.Equal(t, "Partner API", pol.Name)
+	assert.Equal(t, 250, pol.RateLimit)
+	assert.Equal(t, strict.BurstLimit, pol.BurstLimit)
+	assert.Equal(t, strict.RateLimitWindow, pol.RateLimitWindow)
+	assert.Equal(t, strict.DailyQuota, pol.DailyQuota)
+	assert.Equal(t, "tenant_test", pol.TenantID)
+	assert.Equal(t, keysmith.PolicyTemplateStrict, pol.Metadata[keysmith.MetadataPolicyTemplate])
+
+	stored, err := eng.GetPolicy(ctx, pol.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 250, stored.RateLimit)
+	assert.NoError(t, stored.Validate())
+}
+
+func TestCreatePolicyFromTemplate_ClearFields(t *testing.T) {
+	eng := newTestEngine(t)
+
+	pol, err := eng.CreatePolicyFromTemplate(testCtx(), keysmith.PolicyTemplateStrict, nil, "daily_quota", "max_key_lifetime")
+	require.NoError(t, err)
+	assert.Zero(t, pol.DailyQuota)
+	assert.Zero(t, pol.MaxKeyLifetime)
+	assert.NotZero(t, pol.MonthlyQuota)
+}
+
+func TestCreatePolicyFromTemplate_OverrideWinsOverClear(t *testing.T) {
+	eng := newTestEngine(t)
+
+	pol, err := eng.CreatePolicyFromTemplate(testCtx(), keysmith.PolicyTemplateStandard,
+		&policy.Policy{RateLimit: 5}, "rate_limit")
+	require.NoError(t, err)
+	assert.Equal(t, 5, pol.RateLimit)
+}
+
+func TestCreatePolicyFromTemplate_UnknownTemplate(t *testing.T) {
+	eng := newTestEngine(t)
+
+	_, err := eng.CreatePolicyFromTemplate(testCtx(), "does-not-exist", nil)
+	assert.ErrorIs(t, err, keysmith.ErrPolicyTemplateNotFound)
+}
+
+func TestCreatePolicyFromTemplate_UnknownClearField(t *testing.T) {
+	eng := newTestEngine(t)
+
+	_, err := eng.CreatePolicyFromTemplate(testCtx(), keysmith.PolicyTemplateStandard, nil, "tenant_id")
+	assert.ErrorIs(t, err, keysmith.ErrInvalidPolicy)
+}
+
+func TestCreatePolicyFromTemplate_InvalidResult(t *testing.T) {
+	eng := newTestEngine(t)
+
+	// The strict template's monthly quota is below this daily quota.
+	_, err := eng.CreatePolicyFromTemplate(testCtx(), keysmith.PolicyTemplateStrict, &policy.Policy{
+		DailyQuota: 1_000_000,
+	})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidPolicy)
+
+	// Clearing the window while keeping a rate limit is rejected.
+	_, err = eng.CreatePolicyFromTemplate(testCtx(), keysmith.PolicyTemplateStandard, nil, "rate_limit_window")
+	assert.ErrorIs(t, err, keysmith.ErrInvalidPolicy)
+}
+
+func TestRegisterPolicyTemplate(t *testing.T) {
+	keysmith.RegisterPolicyTemplate("test-internal", policy.Policy{
+		Name:            "Internal",
+		RateLimit:       50,
+		RateLimitWindow: time.Second,
+		AllowedIPs:      []string{"10.0.0.0/8"},
+	})
+	assert.Contains(t, keysmith.PolicyTemplateNames(), "test-internal")
+
+	// Mutating a returned copy must not affect the registry.
+	tmpl, ok := keysmith.PolicyTemplate("test-internal")
+	require.True(t, ok)
+	tmpl.AllowedIPs[0] = "0.0.0.0/0"
+
+	eng := newTestEngine(t)
+	pol, err := eng.CreatePolicyFromTemplate(testCtx(), "test-internal", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.0/8"}, pol.AllowedIPs)
+	assert.Equal(t, 50, pol.RateLimit)
+}
+
+func TestCreatePolicy_Validation(t *testing.T) {
+	eng := newTestEngine(t)
+
+	err := eng.CreatePolicy(testCtx(), &policy.Policy{RateLimit: 10})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidPolicy)
+
+	err = eng.CreatePolicy(testCtx(), &policy.Policy{Name: "Bad IPs", AllowedIPs: []string{"not-an-ip"}})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidPolicy)
+}
@@ -0,0 +1,133 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+)
+
+// PrefixRule constrains the keys created with one prefix, for
+// [WithPrefixRule]. Zero fields impose nothing.
+type PrefixRule struct {
+	// AllowedEnvironments lists the environments keys with the prefix may
+	// be created in. Empty allows any.
+	AllowedEnvironments []key.Environment `json:"allowed_environments" mapstructure:"allowed_environments" yaml:"allowed_environments"`
+
+	// DefaultPolicyName names the policy, in the key's tenant, attached to
+	// keys created without a PolicyID.
+	DefaultPolicyName string `json:"default_policy_name" mapstructure:"default_policy_name" yaml:"default_policy_name"`
+
+	// RequireScopes lists scopes every key with the prefix must be created
+	// with, counting the tenant's default scopes.
+	RequireScopes []string `json:"require_scopes" mapstructure:"require_scopes" yaml:"require_scopes"`
+
+	// MaxLifetime caps how long keys with the prefix live: a key created
+	// without an expiry, or with a later one, expires MaxLifetime after
+	// creation. It is applied after the policy's MaxKeyLifetime.
+	MaxLifetime time.Duration `json:"max_lifetime" mapstructure:"max_lifetime" yaml:"max_lifetime"`
+}
+
+func (r *PrefixRule) validate(prefix string) error {
+	if prefix == "" {
+		return fmt.Errorf("%w: prefix is empty", ErrInvalidPrefixRule)
+	}
+	if r.MaxLifetime < 0 {
+		return fmt.Errorf("%w: %q: max lifetime must not be negative", ErrInvalidPrefixRule, prefix)
+	}
+	for _, env := range r.AllowedEnvironments {
+		if env != key.EnvLive && env != key.EnvTest {
+			return fmt.Errorf("%w: %q: unknown environment %q", ErrInvalidPrefixRule, prefix, env)
+		}
+	}
+	return nil
+}
+
+// prefixRule returns the rule for prefix. It fails with ErrUnknownPrefix
+// when [WithStrictPrefixes] is set and prefix has none.
+func (e *Engine) prefixRule(prefix string) (*PrefixRule, error) {
+	if r, ok := e.prefixRules[prefix]; ok {
+		return &r, nil
+	}
+	if e.strictPrefixes {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownPrefix, prefix)
+	}
+	return nil, nil
+}
+
+// checkPrefixEnvironment reports a create in an environment the rule does
+// not allow.
+func checkPrefixEnvironment(prefix string, r *PrefixRule, env key.Environment) error {
+	if r == nil || len(r.AllowedEnvironments) == 0 || slices.Contains(r.AllowedEnvironments, env) {
+		return nil
+	}
+	return fmt.Errorf("%w: %q keys cannot be created in the %s environment", ErrPrefixRuleViolation, prefix, env)
+}
+
+// checkPrefixScopes reports a required scope missing from scopes.
+func checkPrefixScopes(prefix string, r *PrefixRule, scopes []string) error {
+	if r == nil {
+		return nil
+	}
+	for _, s := range r.RequireScopes {
+		if !slices.Contains(scopes, s) {
+			return fmt.Errorf("%w: %q keys require scope %q", ErrPrefixRuleViolation, prefix, s)
+		}
+	}
+	return nil
+}
+
+// prefixDefaultPolicy returns the ID of the rule's default policy in
+// tenantID, or nil when the rule names none.
+func (e *Engine) prefixDefaultPolicy(ctx context.Context, prefix string, r *PrefixRule, tenantID string) (*id.PolicyID, error) {
+	if r == nil || r.DefaultPolicyName == "" {
+		return nil, nil
+	}
+	pol, err := e.store.Policies().GetByName(ctx, tenantID, r.DefaultPolicyName)
+	if err != nil {
+		return nil, fmt.Errorf("prefix %q default policy %q: %w", prefix, r.DefaultPolicyName, err)
+	}
+	return &pol.ID, nil
+}
+
+// clampLifetime caps expiresAt at the rule's MaxLifetime from now.
+func clampLifetime(r *PrefixRule, expiresAt *time.Time, now time.Time) *time.Time {
+	if r == nil {
+		return expiresAt
+	}
+	return capExpiry(expiresAt, r.MaxLifetime, now)
+}
+
+// capExpiry caps expiresAt at lifetime from now. A non-positive lifetime
+// caps nothing.
+func capExpiry(expiresAt *time.Time, lifetime time.Duration, now time.Time) *time.Time {
+	if lifetime <= 0 {
+		return expiresAt
+	}
+	limit := now.Add(lifetime)
+	if expiresAt == nil || expiresAt.After(limit) {
+		return &limit
+	}
+	return expiresAt
+}
+
+// checkAcceptedPrefix rejects rawKey, before it is hashed or looked up, when
+// the request lists the prefixes it accepts and rawKey has none of them.
+// Raw keys are taken to begin with "<prefix>_", as the default generator
+// makes them.
+func checkAcceptedPrefix(ctx context.Context, rawKey string) error {
+	accept := ValidationRequestFromContext(ctx).AcceptPrefixes
+	if len(accept) == 0 {
+		return nil
+	}
+	for _, p := range accept {
+		if strings.HasPrefix(rawKey, p+"_") {
+			return nil
+		}
+	}
+	return ErrPrefixNotAccepted
+}
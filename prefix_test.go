@@ -0,0 +1,141 @@
+package keysmith_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/store/memory"
+	"github.com/xraph/keysmith/tenant"
+)
+
+func newPrefixEngine(t *testing.T, opts ...keysmith.Option) *keysmith.Engine {
+	t.Helper()
+	eng, err := keysmith.NewEngine(append([]keysmith.Option{keysmith.WithStore(memory.New())}, opts...)...)
+	require.NoError(t, err)
+	return eng
+}
+
+func TestPrefixRule_AllowedEnvironments(t *testing.T) {
+	eng := newPrefixEngine(t, keysmith.WithPrefixRule("pk", keysmith.PrefixRule{
+		AllowedEnvironments: []key.Environment{key.EnvTest},
+	}))
+	ctx := testCtx()
+
+	_, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Browser", Prefix: "pk", Environment: key.EnvLive})
+	assert.ErrorIs(t, err, keysmith.ErrPrefixRuleViolation)
+	_, err = eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Browser", Prefix: "pk", Environment: key.EnvTest})
+	assert.NoError(t, err)
+	_, err = eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Server", Prefix: "sk", Environment: key.EnvLive})
+	assert.NoError(t, err, "other prefixes are unconstrained")
+}
+
+func TestPrefixRule_DefaultPolicy(t *testing.T) {
+	eng := newPrefixEngine(t, keysmith.WithPrefixRule("pk", keysmith.PrefixRule{DefaultPolicyName: "Public"}))
+	ctx := testCtx()
+	public := &policy.Policy{Name: "Public"}
+	require.NoError(t, eng.CreatePolicy(ctx, public))
+	other := &policy.Policy{Name: "Other"}
+	require.NoError(t, eng.CreatePolicy(ctx, other))
+
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Browser", Prefix: "pk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	require.NotNil(t, created.Key.PolicyID)
+	assert.Equal(t, public.ID, *created.Key.PolicyID)
+
+	created, err = eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Browser", Prefix: "pk", Environment: key.EnvTest, PolicyID: &other.ID})
+	require.NoError(t, err)
+	assert.Equal(t, other.ID, *created.Key.PolicyID, "an explicit policy wins")
+
+	missing := newPrefixEngine(t, keysmith.WithPrefixRule("pk", keysmith.PrefixRule{DefaultPolicyName: "Public"}))
+	_, err = missing.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Browser", Prefix: "pk", Environment: key.EnvTest})
+	assert.Error(t, err, "the named policy must exist")
+}
+
+func TestPrefixRule_RequireScopes(t *testing.T) {
+	eng := newPrefixEngine(t, keysmith.WithPrefixRule("pk", keysmith.PrefixRule{RequireScopes: []string{"public"}}))
+	ctx := testCtx()
+	require.NoError(t, eng.CreateScope(ctx, &scope.Scope{Name: "read"}))
+	require.NoError(t, eng.CreateScope(ctx, &scope.Scope{Name: "public"}))
+
+	_, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Browser", Prefix: "pk", Environment: key.EnvTest, Scopes: []string{"read"}})
+	assert.ErrorIs(t, err, keysmith.ErrPrefixRuleViolation)
+	_, err = eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Browser", Prefix: "pk", Environment: key.EnvTest, Scopes: []string{"read", "public"}})
+	assert.NoError(t, err)
+
+	require.NoError(t, eng.SetTenantSettings(ctx, &tenant.Settings{DefaultScopes: []string{"public"}}))
+	_, err = eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Browser", Prefix: "pk", Environment: key.EnvTest})
+	assert.NoError(t, err, "tenant default scopes count")
+	_, err = eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Browser", Prefix: "pk", Environment: key.EnvTest, SkipDefaultScopes: true})
+	assert.ErrorIs(t, err, keysmith.ErrPrefixRuleViolation)
+}
+
+func TestPrefixRule_MaxLifetime(t *testing.T) {
+	eng := newPrefixEngine(t, keysmith.WithPrefixRule("pk", keysmith.PrefixRule{MaxLifetime: 30 * 24 * time.Hour}))
+	ctx := testCtx()
+	limit := time.Now().Add(30 * 24 * time.Hour)
+
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Browser", Prefix: "pk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	require.NotNil(t, created.Key.ExpiresAt)
+	assert.WithinDuration(t, limit, *created.Key.ExpiresAt, time.Minute, "keys without an expiry get one")
+
+	later := time.Now().Add(90 * 24 * time.Hour)
+	created, err = eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Browser", Prefix: "pk", Environment: key.EnvTest, ExpiresAt: &later})
+	require.NoError(t, err)
+	assert.WithinDuration(t, limit, *created.Key.ExpiresAt, time.Minute, "later expiries are capped")
+
+	sooner := time.Now().Add(24 * time.Hour)
+	created, err = eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Browser", Prefix: "pk", Environment: key.EnvTest, ExpiresAt: &sooner})
+	require.NoError(t, err)
+	assert.True(t, sooner.Equal(*created.Key.ExpiresAt), "earlier expiries are kept")
+}
+
+func TestWithStrictPrefixes(t *testing.T) {
+	ctx := testCtx()
+	rule := keysmith.WithPrefixRule("sk", keysmith.PrefixRule{})
+
+	strict := newPrefixEngine(t, rule, keysmith.WithStrictPrefixes())
+	_, err := strict.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Job", Prefix: "xk", Environment: key.EnvTest})
+	assert.ErrorIs(t, err, keysmith.ErrUnknownPrefix)
+	_, err = strict.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Job", Prefix: "sk", Environment: key.EnvTest})
+	assert.NoError(t, err)
+
+	permissive := newPrefixEngine(t, rule)
+	_, err = permissive.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Job", Prefix: "xk", Environment: key.EnvTest})
+	assert.NoError(t, err)
+}
+
+func TestWithPrefixRule_Invalid(t *testing.T) {
+	for name, opt := range map[string]keysmith.Option{
+		"empty prefix": keysmith.WithPrefixRule("", keysmith.PrefixRule{}),
+		"negative":     keysmith.WithPrefixRule("pk", keysmith.PrefixRule{MaxLifetime: -time.Hour}),
+		"environment":  keysmith.WithPrefixRule("pk", keysmith.PrefixRule{AllowedEnvironments: []key.Environment{"staging"}}),
+	} {
+		_, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), opt)
+		assert.ErrorIs(t, err, keysmith.ErrInvalidPrefixRule, name)
+	}
+}
+
+func TestValidateKey_AcceptPrefixes(t *testing.T) {
+	eng := newPrefixEngine(t)
+	ctx := testCtx()
+	pk, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Browser", Prefix: "pk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	sk, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Server", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+
+	serverOnly := keysmith.WithValidationRequest(ctx, keysmith.ValidationRequest{AcceptPrefixes: []string{"sk"}})
+	_, err = eng.ValidateKey(serverOnly, pk.RawKey)
+	assert.ErrorIs(t, err, keysmith.ErrPrefixNotAccepted)
+	_, err = eng.ValidateKey(serverOnly, sk.RawKey)
+	assert.NoError(t, err)
+	_, err = eng.ValidateKey(ctx, pk.RawKey)
+	assert.NoError(t, err, "requests without a list accept any prefix")
+}
@@ -0,0 +1,113 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/usage"
+)
+
+// DefaultErasureBatchSize is how many usage records EraseUsageIdentifiers
+// rewrites per store call unless WithErasureBatchSize is set.
+const DefaultErasureBatchSize = 1000
+
+// applyIPHandling rewrites rec's client IP as the tenant's settings ask,
+// before the record is persisted.
+func (e *Engine) applyIPHandling(ctx context.Context, rec *usage.Record) {
+	if rec.IPAddress == "" || rec.TenantID == "" {
+		return
+	}
+	switch e.tenantSettings(ctx, rec.TenantID).IPHandling {
+	case usage.IPTruncate:
+		rec.IPAddress = usage.TruncateIP(rec.IPAddress)
+	case usage.IPHash:
+		rec.IPAddress = usage.HashIP(rec.IPAddress, e.ipHashSecret, rec.CreatedAt)
+	case usage.IPDrop:
+		rec.IPAddress = ""
+	}
+}
+
+// EraseUsageIdentifiers replaces the client IPs and user agents that m
+// selects in a tenant's usage records with [usage.Redacted], such as for a
+// data-subject erasure request. Only the identifiers change: the records
+// stay, so counts and aggregates are unaffected.
+//
+// Records are rewritten in batches of WithErasureBatchSize. When ctx is
+// cancelled or the store fails between batches, the partial summary is
+// returned with Complete false alongside the error; the same call resumes
+// the run, since records already rewritten no longer match. Every run that
+// rewrote records, finished or not, fires the UsageIdentifiersErased hook
+// with a summary that does not contain the matched values.
+//
+// tenantID defaults to the context's tenant, and a context scoped to an
+// app cannot erase, since usage records of other apps would match too. A
+// dry run checks its arguments and writes nothing.
+func (e *Engine) EraseUsageIdentifiers(ctx context.Context, tenantID string, m usage.IdentifierMatcher) (*usage.Erasure, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	if m.IPAddress == "" && m.UserAgent == "" {
+		return nil, fmt.Errorf("%w: ip_address or user_agent is required", ErrInvalidErasure)
+	}
+	if m.IPAddress == usage.Redacted || m.UserAgent == usage.Redacted {
+		return nil, fmt.Errorf("%w: %q is not an identifier", ErrInvalidErasure, usage.Redacted)
+	}
+	sc := scopeFromContext(ctx)
+	if sc.appID != "" {
+		return nil, ErrErasureNotAllowed
+	}
+	if tenantID == "" {
+		tenantID = sc.tenantID
+	}
+	if tenantID == "" {
+		return nil, fmt.Errorf("%w: tenant is required", ErrInvalidErasure)
+	}
+	if !sc.owns(tenantID, "") {
+		return nil, ErrErasureNotAllowed
+	}
+
+	res := &usage.Erasure{
+		TenantID:    tenantID,
+		ByIP:        m.IPAddress != "",
+		ByUserAgent: m.UserAgent != "",
+		Before:      m.Before,
+		StartedAt:   e.now(),
+	}
+	if IsDryRun(ctx) {
+		res.FinishedAt = res.StartedAt
+		return res, nil
+	}
+
+	err := e.eraseBatches(ctx, tenantID, &m, res)
+	res.FinishedAt = e.now()
+	res.Complete = err == nil
+	if res.Records > 0 || res.Complete {
+		// A cancelled run is still audited, so the hook outlives ctx.
+		_ = e.hooks.FireUsageIdentifiersErased(context.WithoutCancel(ctx), res, e.eventMeta(ctx, plugin.TriggerManual, plugin.ReasonErasure))
+	}
+	return res, err
+}
+
+func (e *Engine) eraseBatches(ctx context.Context, tenantID string, m *usage.IdentifierMatcher, res *usage.Erasure) error {
+	size := e.erasureBatchSize
+	if size <= 0 {
+		size = DefaultErasureBatchSize
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, err := e.store.Usages().UpdateUsageIdentifiers(ctx, tenantID, m, size)
+		if err != nil {
+			return fmt.Errorf("erase usage identifiers: %w", err)
+		}
+		if n > 0 {
+			res.Records += n
+			res.Batches++
+		}
+		if n < int64(size) {
+			return nil
+		}
+	}
+}
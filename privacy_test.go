@@ -0,0 +1,226 @@
+package keysmith_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keysmithtest"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/store/memory"
+	"github.com/xraph/keysmith/tenant"
+	"github.com/xraph/keysmith/usage"
+)
+
+var testIPHashSecret = []byte("test-ip-hash-secret")
+
+func newPrivacyEngine(t *testing.T, s store.Store, opts ...keysmith.Option) (*keysmith.Engine, *keysmithtest.Recorder, id.KeyID) {
+	t.Helper()
+	rec := keysmithtest.NewRecorder()
+	eng, err := keysmith.NewEngine(append([]keysmith.Option{
+		keysmith.WithStore(s),
+		keysmith.WithExtension(rec),
+		keysmith.WithEndpointActivity(10, 0),
+		keysmith.WithIPHashSecret(testIPHashSecret),
+	}, opts...)...)
+	require.NoError(t, err)
+
+	result, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "Privacy", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	return eng, rec, result.Key.ID
+}
+
+func recordFrom(t *testing.T, eng *keysmith.Engine, kid id.KeyID, ip, ua string) *usage.Record {
+	t.Helper()
+	rec := &usage.Record{KeyID: kid, Method: "GET", Endpoint: "/v1/users", StatusCode: 200, IPAddress: ip, UserAgent: ua}
+	require.NoError(t, eng.RecordUsage(testCtx(), rec))
+	return rec
+}
+
+func TestRecordUsage_IPHandling(t *testing.T) {
+	tests := []struct {
+		mode usage.IPHandling
+		ip   string
+		want func(rec *usage.Record) string
+	}{
+		{"", "203.0.113.7", func(*usage.Record) string { return "203.0.113.7" }},
+		{usage.IPStore, "203.0.113.7", func(*usage.Record) string { return "203.0.113.7" }},
+		{usage.IPTruncate, "203.0.113.7", func(*usage.Record) string { return "203.0.113.0" }},
+		{usage.IPTruncate, "2001:db8:85a3:8d3:1319:8a2e:370:7348", func(*usage.Record) string { return "2001:db8:85a3::" }},
+		{usage.IPHash, "203.0.113.7", func(rec *usage.Record) string {
+			return usage.HashIP("203.0.113.7", testIPHashSecret, rec.CreatedAt)
+		}},
+		{usage.IPDrop, "203.0.113.7", func(*usage.Record) string { return "" }},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode)+"/"+tt.ip, func(t *testing.T) {
+			eng, _, kid := newPrivacyEngine(t, memory.New())
+			require.NoError(t, eng.SetTenantSettings(testCtx(), &tenant.Settings{IPHandling: tt.mode}))
+
+			rec := recordFrom(t, eng, kid, tt.ip, "curl/8")
+
+			stored, err := eng.QueryUsage(testCtx(), &usage.QueryFilter{KeyID: &kid})
+			require.NoError(t, err)
+			require.Len(t, stored, 1)
+			assert.Equal(t, tt.want(rec), stored[0].IPAddress)
+			assert.Equal(t, "curl/8", stored[0].UserAgent)
+		})
+	}
+}
+
+func TestRecordUsage_IPHashIsDailyAndKeyed(t *testing.T) {
+	day := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, usage.HashIP("203.0.113.7", testIPHashSecret, day),
+		usage.HashIP("203.0.113.7", testIPHashSecret, day.Add(12*time.Hour)))
+	assert.NotEqual(t, usage.HashIP("203.0.113.7", testIPHashSecret, day),
+		usage.HashIP("203.0.113.7", testIPHashSecret, day.Add(24*time.Hour)))
+	assert.NotEqual(t, usage.HashIP("203.0.113.7", testIPHashSecret, day),
+		usage.HashIP("203.0.113.7", []byte("other"), day))
+	assert.NotContains(t, usage.HashIP("203.0.113.7", testIPHashSecret, day), "203.0.113")
+}
+
+func TestSetTenantSettings_RejectsUnknownIPHandling(t *testing.T) {
+	eng, _, _ := newPrivacyEngine(t, memory.New())
+
+	err := eng.SetTenantSettings(testCtx(), &tenant.Settings{IPHandling: "mask"})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidTenantSettings)
+}
+
+func TestEraseUsageIdentifiers(t *testing.T) {
+	eng, rec, kid := newPrivacyEngine(t, memory.New(), keysmith.WithErasureBatchSize(2))
+	for range 5 {
+		recordFrom(t, eng, kid, "198.51.100.4", "curl/8")
+	}
+	recordFrom(t, eng, kid, "198.51.100.9", "curl/8")
+	recordFrom(t, eng, kid, "198.51.100.9", "sdk/1")
+
+	res, err := eng.EraseUsageIdentifiers(adminCtx(), "", usage.IdentifierMatcher{IPAddress: "198.51.100.4", UserAgent: "sdk/1"})
+	require.NoError(t, err)
+	assert.Equal(t, "tenant_test", res.TenantID)
+	assert.Equal(t, int64(6), res.Records)
+	assert.Equal(t, 3, res.Batches)
+	assert.True(t, res.Complete)
+	assert.True(t, res.ByIP)
+	assert.True(t, res.ByUserAgent)
+
+	stored, err := eng.QueryUsage(testCtx(), &usage.QueryFilter{KeyID: &kid})
+	require.NoError(t, err)
+	require.Len(t, stored, 7, "records are kept")
+	var redactedIPs, redactedUAs int
+	for _, r := range stored {
+		assert.NotEqual(t, "198.51.100.4", r.IPAddress)
+		assert.NotEqual(t, "sdk/1", r.UserAgent)
+		if r.IPAddress == usage.Redacted {
+			redactedIPs++
+		}
+		if r.UserAgent == usage.Redacted {
+			redactedUAs++
+		}
+	}
+	assert.Equal(t, 5, redactedIPs)
+	assert.Equal(t, 1, redactedUAs)
+
+	acts, err := eng.ListEndpointActivity(testCtx(), kid)
+	require.NoError(t, err)
+	require.Len(t, acts, 1)
+	assert.Equal(t, int64(7), acts[0].Count, "aggregates are preserved")
+
+	events := rec.Filter("UsageIdentifiersErased")
+	require.Len(t, events, 1)
+	assert.Equal(t, plugin.ReasonErasure, events[0].Meta.ReasonCode)
+	assert.Equal(t, int64(6), events[0].Erasure.Records)
+}
+
+func TestEraseUsageIdentifiers_Before(t *testing.T) {
+	eng, _, kid := newPrivacyEngine(t, memory.New())
+	recordFrom(t, eng, kid, "198.51.100.4", "curl/8")
+	cutoff := time.Now()
+	time.Sleep(time.Millisecond)
+	recordFrom(t, eng, kid, "198.51.100.4", "curl/8")
+
+	res, err := eng.EraseUsageIdentifiers(adminCtx(), "tenant_test", usage.IdentifierMatcher{IPAddress: "198.51.100.4", Before: &cutoff})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), res.Records)
+}
+
+// cancellingStore cancels a context after the first usage identifier batch.
+type cancellingStore struct {
+	store.Store
+	cancel context.CancelFunc
+}
+
+func (s *cancellingStore) Usages() usage.Store {
+	return &cancellingUsages{Store: s.Store.Usages(), cancel: s.cancel}
+}
+
+type cancellingUsages struct {
+	usage.Store
+	cancel context.CancelFunc
+}
+
+func (u *cancellingUsages) UpdateUsageIdentifiers(ctx context.Context, tenantID string, m *usage.IdentifierMatcher, limit int) (int64, error) {
+	n, err := u.Store.UpdateUsageIdentifiers(ctx, tenantID, m, limit)
+	u.cancel()
+	return n, err
+}
+
+func TestEraseUsageIdentifiers_Resumes(t *testing.T) {
+	ms := memory.New()
+	ctx, cancel := context.WithCancel(adminCtx())
+	defer cancel()
+	eng, rec, kid := newPrivacyEngine(t, &cancellingStore{Store: ms, cancel: cancel}, keysmith.WithErasureBatchSize(2))
+	for range 5 {
+		recordFrom(t, eng, kid, "198.51.100.4", "curl/8")
+	}
+	m := usage.IdentifierMatcher{IPAddress: "198.51.100.4"}
+
+	res, err := eng.EraseUsageIdentifiers(ctx, "", m)
+	require.ErrorIs(t, err, context.Canceled)
+	require.NotNil(t, res)
+	assert.False(t, res.Complete)
+	assert.Equal(t, int64(2), res.Records)
+
+	resumed, err := keysmith.NewEngine(keysmith.WithStore(ms), keysmith.WithErasureBatchSize(2))
+	require.NoError(t, err)
+	res, err = resumed.EraseUsageIdentifiers(adminCtx(), "", m)
+	require.NoError(t, err)
+	assert.True(t, res.Complete)
+	assert.Equal(t, int64(3), res.Records)
+
+	events := rec.Filter("UsageIdentifiersErased")
+	require.Len(t, events, 1)
+	assert.False(t, events[0].Erasure.Complete)
+}
+
+func TestEraseUsageIdentifiers_Validation(t *testing.T) {
+	eng, rec, _ := newPrivacyEngine(t, memory.New())
+
+	_, err := eng.EraseUsageIdentifiers(adminCtx(), "", usage.IdentifierMatcher{})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidErasure)
+
+	_, err = eng.EraseUsageIdentifiers(adminCtx(), "", usage.IdentifierMatcher{IPAddress: usage.Redacted})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidErasure)
+
+	_, err = eng.EraseUsageIdentifiers(testCtx(), "", usage.IdentifierMatcher{IPAddress: "198.51.100.4"})
+	assert.ErrorIs(t, err, keysmith.ErrErasureNotAllowed, "app-scoped contexts cannot erase")
+
+	_, err = eng.EraseUsageIdentifiers(adminCtx(), "tenant_other", usage.IdentifierMatcher{IPAddress: "198.51.100.4"})
+	assert.ErrorIs(t, err, keysmith.ErrErasureNotAllowed)
+
+	_, err = eng.EraseUsageIdentifiers(context.Background(), "", usage.IdentifierMatcher{IPAddress: "198.51.100.4"})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidErasure)
+
+	eng.SetReadOnly(true)
+	_, err = eng.EraseUsageIdentifiers(adminCtx(), "", usage.IdentifierMatcher{IPAddress: "198.51.100.4"})
+	assert.ErrorIs(t, err, keysmith.ErrReadOnlyMode)
+
+	assert.Empty(t, rec.Filter("UsageIdentifiersErased"))
+}
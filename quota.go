@@ -0,0 +1,526 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/xraph/go-utils/log"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/quotapool"
+	"github.com/xraph/keysmith/usage"
+)
+
+const (
+	// DefaultQuotaSyncInterval is how often an engine writes its quota
+	// counts and reads those of other engines. See [WithQuotaSync].
+	DefaultQuotaSyncInterval = 5 * time.Second
+
+	// DefaultQuotaSyncBatch is how many validations an engine counts
+	// toward a quota window before it syncs the window early.
+	DefaultQuotaSyncBatch = 100
+
+	// quotaCountRetention is how long quota counts are kept after their
+	// window starts, so that a year of monthly consumption stays readable.
+	quotaCountRetention = 400 * 24 * time.Hour
+
+	// quotaPurgeInterval is the minimum time between two purges of old
+	// quota counts by the same engine.
+	quotaPurgeInterval = 24 * time.Hour
+)
+
+// quotaSubject is what a validation's quota counts toward: a quota pool, or
+// with a nil pool the key's own policy quotas.
+type quotaSubject struct {
+	pool           *quotapool.Pool
+	daily, monthly int64
+	loc            *time.Location
+}
+
+// quotaSubjectFor returns the quotas validating k counts toward: its pool's
+// when it is in one that loaded, else its policy's. It reports false when
+// neither sets a quota.
+func (e *Engine) quotaSubjectFor(ctx context.Context, k *key.Key, pool *quotapool.Pool, pol *policy.Policy) (quotaSubject, bool) {
+	if pool != nil {
+		if pool.DailyLimit <= 0 && pool.MonthlyLimit <= 0 {
+			return quotaSubject{}, false
+		}
+		return quotaSubject{
+			pool:    pool,
+			daily:   pool.DailyLimit,
+			monthly: pool.MonthlyLimit,
+			loc:     e.quotaZone(ctx, pool.TenantID, pool.QuotaTimezone),
+		}, true
+	}
+	if pol == nil || (pol.DailyQuota <= 0 && pol.MonthlyQuota <= 0) {
+		return quotaSubject{}, false
+	}
+	return quotaSubject{
+		daily:   pol.DailyQuota,
+		monthly: pol.MonthlyQuota,
+		loc:     e.quotaLocation(ctx, k.TenantID, pol),
+	}, true
+}
+
+// QuotaInfo reports the quotas a validation counted toward and the
+// requests left in their current windows, for quota response headers. A
+// period's Remaining is meaningful only when its Limit is set.
+type QuotaInfo struct {
+	// PoolID is set when the quotas are the key's quota pool's.
+	PoolID *id.QuotaPoolID `json:"pool_id,omitempty"`
+
+	DailyLimit     int64     `json:"daily_limit,omitempty"`
+	DailyRemaining int64     `json:"daily_remaining"`
+	DailyReset     time.Time `json:"daily_reset,omitzero"`
+
+	MonthlyLimit     int64     `json:"monthly_limit,omitempty"`
+	MonthlyRemaining int64     `json:"monthly_remaining"`
+	MonthlyReset     time.Time `json:"monthly_reset,omitzero"`
+}
+
+// checkQuotas counts a validation of k toward its quotas and rejects it
+// with ErrQuotaExceeded when a window is already used up, so the request
+// that reaches a limit is the last one admitted. The monthly window is
+// checked before the daily one, and a rejected validation is not counted.
+// With [WithStrictQuotas] the rate limiter's buckets decide. Counts come
+// from the engine's quota meter, which reads the store at most once per
+// sync interval, so the check adds no store call to most validations; the
+// store is called without holding the windows' locks, so validations of
+// the same quota are not held up by another's sync. The returned info is
+// nil when no quota applies.
+func (e *Engine) checkQuotas(ctx context.Context, k *key.Key, pool *quotapool.Pool, pol *policy.Policy, now time.Time) (*QuotaInfo, error) {
+	sub, ok := e.quotaSubjectFor(ctx, k, pool, pol)
+	if !ok {
+		return nil, nil
+	}
+	windows := e.quotas.windowsFor(sub, k.ID, now)
+	if !e.quotas.strict {
+		for _, w := range windows {
+			e.refreshQuotaWindow(ctx, w, now)
+		}
+	}
+	info, full, err := e.countQuotas(ctx, k, sub, windows, now)
+	for _, w := range full {
+		// A window another validation is syncing is left to it.
+		if w != nil && w.sync.TryLock() {
+			e.syncQuotaWindow(ctx, w, now)
+			w.sync.Unlock()
+		}
+	}
+	return info, err
+}
+
+// countQuotas checks and counts a validation toward windows under their
+// locks, for checkQuotas. It returns the windows that have counted a full
+// batch since their last write and are due a sync.
+func (e *Engine) countQuotas(ctx context.Context, k *key.Key, sub quotaSubject, windows []*quotaWindow, now time.Time) (*QuotaInfo, [2]*quotaWindow, error) {
+	var full [2]*quotaWindow
+	for _, w := range windows {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+	}
+
+	info := &QuotaInfo{}
+	if sub.pool != nil {
+		info.PoolID = &sub.pool.ID
+	}
+	for _, w := range windows {
+		if w.limit <= 0 {
+			continue
+		}
+		var remaining int64
+		if e.quotas.strict {
+			bucket := quotaBucket(w.key)
+			window := w.end.Sub(w.start)
+			allowed, err := e.ratelimiter.Allow(ctx, bucket, int(w.limit), window)
+			if err != nil || !allowed {
+				return nil, full, e.quotaExceeded(ctx, k, sub, w, w.limit)
+			}
+			n, _ := e.ratelimiter.Remaining(ctx, bucket, int(w.limit), window)
+			remaining = int64(n)
+		} else {
+			used := w.used()
+			if used >= w.limit {
+				return nil, full, e.quotaExceeded(ctx, k, sub, w, used)
+			}
+			remaining = w.limit - used - 1
+		}
+		if w.key.period == quotapool.PeriodDay {
+			info.DailyLimit, info.DailyRemaining, info.DailyReset = w.limit, remaining, w.end
+		} else {
+			info.MonthlyLimit, info.MonthlyRemaining, info.MonthlyReset = w.limit, remaining, w.end
+		}
+	}
+
+	writable := e.checkUsageWritable() == nil
+	for i, w := range windows {
+		w.pending[k.ID]++
+		w.unsynced++
+		if writable && w.unsynced >= e.quotas.batch && !now.Before(w.backoff) {
+			full[i] = w
+		}
+	}
+	return info, full, nil
+}
+
+// refreshQuotaWindow syncs w when its total is due to be read before it is
+// checked. The first read of a window waits for one already in progress, so
+// that no validation is checked against an unread total; later reads are
+// skipped while another validation syncs w, which then checks against the
+// previous total.
+func (e *Engine) refreshQuotaWindow(ctx context.Context, w *quotaWindow, now time.Time) {
+	w.mu.Lock()
+	due, first := w.limit > 0 && w.due(now, e.quotas.interval), w.syncedAt.IsZero()
+	w.mu.Unlock()
+	if !due {
+		return
+	}
+	if first {
+		w.sync.Lock()
+	} else if !w.sync.TryLock() {
+		return
+	}
+	defer w.sync.Unlock()
+
+	w.mu.Lock()
+	due = w.due(now, e.quotas.interval)
+	w.mu.Unlock()
+	if due {
+		e.syncQuotaWindow(ctx, w, now)
+	}
+}
+
+// quotaExceeded fires KeyQuotaExceeded for a validation over w's limit,
+// with used requests already counted, and returns its error.
+func (e *Engine) quotaExceeded(ctx context.Context, k *key.Key, sub quotaSubject, w *quotaWindow, used int64) error {
+	q := &key.QuotaExceeded{
+		Period:      string(w.key.period),
+		WindowStart: w.start,
+		WindowEnd:   w.end,
+		Limit:       w.limit,
+		Used:        used,
+	}
+	if sub.pool != nil {
+		q.PoolID = &sub.pool.ID
+	}
+	_ = e.hooks.FireKeyQuotaExceeded(ctx, k, q, e.eventMeta(ctx, plugin.TriggerValidation, plugin.ReasonQuotaExceeded))
+	if sub.pool != nil {
+		return fmt.Errorf("%w: quota pool %s allows %d requests a %s", ErrQuotaExceeded, sub.pool.ID, w.limit, w.key.period)
+	}
+	return fmt.Errorf("%w: policy allows %d requests a %s", ErrQuotaExceeded, w.limit, w.key.period)
+}
+
+// syncQuotaWindow writes w's pending counts and reads back the total of
+// every engine. A failure is logged and the window is not synced again
+// before the next interval; its local view stands meanwhile. The caller
+// holds w.sync but not w.mu.
+func (e *Engine) syncQuotaWindow(ctx context.Context, w *quotaWindow, now time.Time) {
+	if err := e.writeQuotaWindow(ctx, w); err != nil {
+		w.mu.Lock()
+		w.backoff = now.Add(e.quotas.interval)
+		w.mu.Unlock()
+		e.logger.Warn("failed to write quota counts", log.Any("error", err))
+		return
+	}
+	counts, err := e.store.QuotaPools().ListUsage(ctx, w.filter())
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err != nil {
+		w.backoff = now.Add(e.quotas.interval)
+		e.logger.Warn("failed to read quota counts", log.Any("error", err))
+		return
+	}
+	var total int64
+	for _, c := range counts {
+		total += c.Count
+	}
+	// Counts written since the window was last read are mine or another
+	// engine's; either way the store's total now includes them. Counts made
+	// during the sync are still unsynced.
+	w.stored = total
+	w.syncedAt = now
+}
+
+// writeQuotaWindow writes w's pending counts, unless read-only mode refuses
+// usage writes. The counts are moved to w.writing under w.mu and written
+// after it is released; a failed write returns them to pending. The caller
+// holds w.sync but not w.mu.
+func (e *Engine) writeQuotaWindow(ctx context.Context, w *quotaWindow) error {
+	if e.checkUsageWritable() != nil {
+		return nil
+	}
+	w.mu.Lock()
+	n := w.unsynced
+	if n == 0 {
+		w.mu.Unlock()
+		return nil
+	}
+	w.pending, w.writing = w.writing, w.pending
+	w.written, w.unsynced = n, 0
+	usages := make([]*quotapool.Usage, 0, len(w.writing))
+	for kid, c := range w.writing {
+		usages = append(usages, &quotapool.Usage{
+			PoolID:      w.key.poolID,
+			KeyID:       kid,
+			Period:      w.key.period,
+			WindowStart: w.start,
+			Count:       c,
+		})
+	}
+	w.mu.Unlock()
+
+	err := e.store.QuotaPools().AddUsage(ctx, usages)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err != nil {
+		for kid, c := range w.writing {
+			w.pending[kid] += c
+		}
+		w.unsynced += n
+	} else {
+		w.stored += n
+	}
+	clear(w.writing)
+	w.written = 0
+	return err
+}
+
+// flushQuotaCounts writes the pending counts of every window, forgets the
+// windows that have ended, and purges counts past their retention once a
+// day. It returns the first write error; the other windows are still
+// written.
+func (e *Engine) flushQuotaCounts(ctx context.Context) error {
+	if e.checkUsageWritable() != nil {
+		return nil
+	}
+	now := e.now()
+	var first error
+	for _, w := range e.quotas.all() {
+		w.sync.Lock()
+		err := e.writeQuotaWindow(ctx, w)
+		w.mu.Lock()
+		ended := w.unsynced == 0 && !w.end.After(now)
+		w.mu.Unlock()
+		w.sync.Unlock()
+		if err != nil && first == nil {
+			first = fmt.Errorf("write quota counts: %w", err)
+		}
+		if ended {
+			e.quotas.forget(w)
+		}
+	}
+	if e.quotas.purgeDue(now) && e.checkWritable(ctx) == nil {
+		if _, err := e.store.QuotaPools().PurgeUsage(ctx, now.Add(-quotaCountRetention)); err != nil {
+			e.logger.Warn("failed to purge quota counts", log.Any("error", err))
+		}
+	}
+	return first
+}
+
+func (e *Engine) runQuotaFlush(ctx context.Context) {
+	if err := e.flushQuotaCounts(ctx); err != nil {
+		e.logger.Warn("quota count flush failed", log.Any("error", err))
+	}
+}
+
+// quotaZone returns the time zone named zone, else the tenant's quota time
+// zone, else UTC.
+func (e *Engine) quotaZone(ctx context.Context, tenantID, zone string) *time.Location {
+	if zone == "" && tenantID != "" {
+		zone = e.tenantSettings(ctx, tenantID).QuotaTimezone
+	}
+	loc, err := usage.LoadQuotaLocation(zone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// quotaBucket names the limiter bucket of a quota window in strict mode.
+func quotaBucket(k quotaWindowKey) string {
+	subject := "key:" + k.keyID.String()
+	if !k.poolID.IsNil() {
+		subject = "pool:" + k.poolID.String()
+	}
+	return "quota:" + subject + ":" + string(k.period) + ":" + strconv.FormatInt(k.start, 10)
+}
+
+// quotaMeter keeps an engine's view of the quota windows its validations
+// count toward. Each window holds the total the store had when it was last
+// read and the counts this engine has made since, which it writes in
+// batches. Reads and writes happen at least once per interval and after
+// every batch of counts, so the view omits at most a batch of each other
+// engine's counts and the shared total overshoots a limit by at most the
+// number of engines times the batch.
+type quotaMeter struct {
+	interval time.Duration
+	batch    int64
+	strict   bool
+
+	mu       sync.Mutex
+	windows  map[quotaWindowKey]*quotaWindow
+	purgedAt time.Time
+}
+
+func newQuotaMeter() *quotaMeter {
+	return &quotaMeter{
+		interval: DefaultQuotaSyncInterval,
+		batch:    DefaultQuotaSyncBatch,
+		windows:  make(map[quotaWindowKey]*quotaWindow),
+	}
+}
+
+// quotaWindowKey identifies a window: of a pool, or with a zero pool of
+// one key's policy quota.
+type quotaWindowKey struct {
+	poolID id.QuotaPoolID
+	keyID  id.KeyID
+	period quotapool.Period
+	start  int64
+}
+
+// quotaWindow is one day or month of a quota subject. Its fields after mu
+// are guarded by it.
+type quotaWindow struct {
+	key        quotaWindowKey
+	start, end time.Time
+
+	// sync is held while the window's counts are written to or read from
+	// the store, so that one sync of the window runs at a time. It is taken
+	// before mu, and mu is not held across store calls.
+	sync sync.Mutex
+
+	mu    sync.Mutex
+	limit int64
+
+	// stored is the store's total when last read plus the counts written
+	// since; pending holds the counts not yet written, unsynced their sum,
+	// and writing the counts being written, written their sum.
+	stored   int64
+	pending  map[id.KeyID]int64
+	unsynced int64
+	writing  map[id.KeyID]int64
+	written  int64
+
+	// syncedAt is when the total was last read; zero before the first
+	// read. No sync is attempted before backoff.
+	syncedAt time.Time
+	backoff  time.Time
+}
+
+// used returns the requests counted toward the window by every engine, as
+// far as this one knows. The caller holds w.mu.
+func (w *quotaWindow) used() int64 {
+	return w.stored + w.written + w.unsynced
+}
+
+// due reports whether the window's total must be read before it is
+// checked.
+func (w *quotaWindow) due(now time.Time, interval time.Duration) bool {
+	if now.Before(w.backoff) {
+		return false
+	}
+	return w.syncedAt.IsZero() || now.Sub(w.syncedAt) >= interval
+}
+
+// filter selects the window's counts in the store.
+func (w *quotaWindow) filter() *quotapool.UsageFilter {
+	f := &quotapool.UsageFilter{PoolID: w.key.poolID, Period: w.key.period, From: w.start, To: w.end}
+	if w.key.poolID.IsNil() {
+		kid := w.key.keyID
+		f.KeyID = &kid
+	}
+	return f
+}
+
+// windowsFor returns the current monthly and daily windows of sub, in
+// that order, with their limits refreshed. Both are counted even when only
+// one has a limit, so that forecasts can read the daily counts.
+func (m *quotaMeter) windowsFor(sub quotaSubject, keyID id.KeyID, now time.Time) []*quotaWindow {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make([]*quotaWindow, 0, 2)
+	add := func(period quotapool.Period, w usage.Window, limit int64) {
+		k := quotaWindowKey{period: period, start: w.Start.Unix()}
+		if sub.pool != nil {
+			k.poolID = sub.pool.ID
+		} else {
+			k.keyID = keyID
+		}
+		qw, ok := m.windows[k]
+		if !ok {
+			qw = &quotaWindow{
+				key:     k,
+				start:   w.Start,
+				end:     w.End,
+				pending: make(map[id.KeyID]int64),
+				writing: make(map[id.KeyID]int64),
+			}
+			m.windows[k] = qw
+		}
+		qw.mu.Lock()
+		qw.limit = limit
+		qw.mu.Unlock()
+		out = append(out, qw)
+	}
+	add(quotapool.PeriodMonth, usage.MonthWindow(now, sub.loc), sub.monthly)
+	add(quotapool.PeriodDay, usage.DayWindow(now, sub.loc), sub.daily)
+	return out
+}
+
+// pendingFor returns the counts of one window of the pool not yet written,
+// or being written, by key.
+func (m *quotaMeter) pendingFor(poolID id.QuotaPoolID, period quotapool.Period, start time.Time) map[id.KeyID]int64 {
+	m.mu.Lock()
+	w, ok := m.windows[quotaWindowKey{poolID: poolID, period: period, start: start.Unix()}]
+	m.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	out := maps.Clone(w.pending)
+	for kid, n := range w.writing {
+		out[kid] += n
+	}
+	return out
+}
+
+func (m *quotaMeter) all() []*quotaWindow {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]*quotaWindow, 0, len(m.windows))
+	for _, w := range m.windows {
+		out = append(out, w)
+	}
+	return out
+}
+
+func (m *quotaMeter) forget(w *quotaWindow) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.windows[w.key] == w {
+		delete(m.windows, w.key)
+	}
+}
+
+// purgeDue reports whether old counts are due for a purge, and records one
+// if so.
+func (m *quotaMeter) purgeDue(now time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.purgedAt.IsZero() && now.Sub(m.purgedAt) < quotaPurgeInterval {
+		return false
+	}
+	m.purgedAt = now
+	return true
+}
@@ -0,0 +1,248 @@
+package keysmith
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/quotapool"
+	"github.com/xraph/keysmith/usage"
+)
+
+// validateQuotaPool checks a pool's fields at write time.
+func validateQuotaPool(p *quotapool.Pool) error {
+	if err := p.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidQuotaPool, err)
+	}
+	return nil
+}
+
+// CreateQuotaPool creates a quota pool in the context's tenant and app.
+// Keys join it with AddKeysToQuotaPool or CreateKeyInput.PoolID.
+func (e *Engine) CreateQuotaPool(ctx context.Context, p *quotapool.Pool) error {
+	if err := e.checkWritable(ctx); err != nil {
+		return err
+	}
+	if err := validateQuotaPool(p); err != nil {
+		return err
+	}
+	sc := scopeFromContext(ctx)
+	p.ID = id.NewQuotaPoolID()
+	p.TenantID = sc.tenantID
+	p.AppID = sc.appID
+	now := time.Now()
+	p.CreatedAt = now
+	p.UpdatedAt = now
+	if err := e.store.QuotaPools().Create(ctx, p); err != nil {
+		return fmt.Errorf("create quota pool: %w", err)
+	}
+	return nil
+}
+
+// GetQuotaPool returns a quota pool by ID.
+func (e *Engine) GetQuotaPool(ctx context.Context, poolID id.QuotaPoolID) (*quotapool.Pool, error) {
+	return e.getQuotaPool(ctx, poolID)
+}
+
+// UpdateQuotaPool updates a quota pool's name, description, limits, and
+// time zone. New limits apply to the current windows: a pool whose limit is
+// lowered below its use refuses its members until the window ends.
+func (e *Engine) UpdateQuotaPool(ctx context.Context, p *quotapool.Pool) error {
+	if err := e.checkWritable(ctx); err != nil {
+		return err
+	}
+	if err := validateQuotaPool(p); err != nil {
+		return err
+	}
+	cur, err := e.getQuotaPool(ctx, p.ID)
+	if err != nil {
+		return fmt.Errorf("get quota pool: %w", err)
+	}
+	p.TenantID, p.AppID, p.CreatedAt = cur.TenantID, cur.AppID, cur.CreatedAt
+	p.UpdatedAt = time.Now()
+	if err := e.store.QuotaPools().Update(ctx, p); err != nil {
+		return fmt.Errorf("update quota pool: %w", err)
+	}
+	e.invalidateAll()
+	e.bumpRevision(ctx, p.TenantID)
+	return nil
+}
+
+// DeleteQuotaPool deletes a quota pool and its counts. Its members are left
+// intact and fall back to their policies' quotas.
+func (e *Engine) DeleteQuotaPool(ctx context.Context, poolID id.QuotaPoolID) error {
+	if err := e.checkWritable(ctx); err != nil {
+		return err
+	}
+	p, err := e.getQuotaPool(ctx, poolID)
+	if err != nil {
+		return fmt.Errorf("get quota pool: %w", err)
+	}
+	if err := e.store.QuotaPools().Delete(ctx, poolID); err != nil {
+		return fmt.Errorf("delete quota pool: %w", err)
+	}
+	e.invalidateAll()
+	e.bumpRevision(ctx, p.TenantID)
+	return nil
+}
+
+// ListQuotaPools returns a page of quota pools ordered by name, restricted
+// to the context's tenant and app. The filter's Limit defaults to
+// DefaultPageSize and is clamped by [Engine.PageLimit].
+func (e *Engine) ListQuotaPools(ctx context.Context, filter *quotapool.ListFilter) ([]*quotapool.Pool, error) {
+	f := quotaPoolFilter(ctx, filter)
+	f.Limit = e.PageLimit(f.Limit, DefaultPageSize)
+	return e.store.QuotaPools().List(ctx, f)
+}
+
+// QuotaPoolMembers returns a page of the keys in a quota pool, restricted
+// like ListKeys. The filter's PoolID is replaced by poolID.
+func (e *Engine) QuotaPoolMembers(ctx context.Context, poolID id.QuotaPoolID, filter *key.ListFilter) ([]*key.Key, error) {
+	if _, err := e.getQuotaPool(ctx, poolID); err != nil {
+		return nil, fmt.Errorf("get quota pool: %w", err)
+	}
+	f := keyFilter(ctx, filter)
+	f.PoolID = &poolID
+	f.Limit = e.PageLimit(f.Limit, DefaultPageSize)
+	return e.store.Keys().List(ctx, f)
+}
+
+// AddKeysToQuotaPool moves keys into a quota pool, whose quotas then
+// replace those of their policies. Every key must be in the pool's tenant
+// and app. A key can be in one pool only, so a key in another pool leaves
+// it; validations it already counted stay with that pool.
+func (e *Engine) AddKeysToQuotaPool(ctx context.Context, poolID id.QuotaPoolID, keyIDs []id.KeyID) error {
+	p, keys, err := e.preparePoolMembership(ctx, poolID, keyIDs)
+	if err != nil {
+		return err
+	}
+	return e.setKeysPool(ctx, p, keys, func(k *key.Key) bool {
+		return k.PoolID == nil || *k.PoolID != poolID
+	}, &poolID)
+}
+
+// RemoveKeysFromQuotaPool takes keys out of a quota pool; they fall back to
+// their policies' quotas. Keys that are not members are ignored.
+func (e *Engine) RemoveKeysFromQuotaPool(ctx context.Context, poolID id.QuotaPoolID, keyIDs []id.KeyID) error {
+	p, keys, err := e.preparePoolMembership(ctx, poolID, keyIDs)
+	if err != nil {
+		return err
+	}
+	return e.setKeysPool(ctx, p, keys, func(k *key.Key) bool {
+		return k.PoolID != nil && *k.PoolID == poolID
+	}, nil)
+}
+
+// preparePoolMembership loads the pool and keys, checking that each key is
+// visible and in the pool's tenant and app.
+func (e *Engine) preparePoolMembership(ctx context.Context, poolID id.QuotaPoolID, keyIDs []id.KeyID) (*quotapool.Pool, []*key.Key, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, nil, err
+	}
+	p, err := e.getQuotaPool(ctx, poolID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get quota pool: %w", err)
+	}
+	keys := make([]*key.Key, 0, len(keyIDs))
+	for _, kid := range keyIDs {
+		k, err := e.getKey(ctx, kid)
+		if err != nil {
+			return nil, nil, fmt.Errorf("get key: %w", err)
+		}
+		if k.TenantID != p.TenantID || k.AppID != p.AppID {
+			return nil, nil, fmt.Errorf("%w: key %s is not in the pool's tenant and app", ErrInvalidQuotaPool, kid)
+		}
+		keys = append(keys, k)
+	}
+	return p, keys, nil
+}
+
+// setKeysPool sets the PoolID of the keys change selects.
+func (e *Engine) setKeysPool(ctx context.Context, p *quotapool.Pool, keys []*key.Key, change func(*key.Key) bool, poolID *id.QuotaPoolID) error {
+	changed := false
+	for _, k := range keys {
+		if !change(k) {
+			continue
+		}
+		k.PoolID = poolID
+		k.UpdatedAt = time.Now()
+		if err := e.store.Keys().Update(ctx, k); err != nil {
+			return fmt.Errorf("update key: %w", err)
+		}
+		e.invalidateKey(k.ID)
+		changed = true
+	}
+	if changed {
+		e.bumpRevision(ctx, p.TenantID)
+	}
+	return nil
+}
+
+// checkPoolForKey checks that a key created in tenantID and appID may join
+// the pool.
+func (e *Engine) checkPoolForKey(ctx context.Context, poolID id.QuotaPoolID, tenantID, appID string) error {
+	p, err := e.getQuotaPool(ctx, poolID)
+	if err != nil {
+		return fmt.Errorf("get quota pool: %w", err)
+	}
+	if p.TenantID != tenantID || p.AppID != appID {
+		return fmt.Errorf("%w: pool %s is not in the key's tenant and app", ErrInvalidQuotaPool, poolID)
+	}
+	return nil
+}
+
+// QuotaPoolUsage reports a quota pool's use of its current day and month,
+// in the pool's time zone, with each key's share. It reads the counts every
+// engine has written and adds those this engine has not written yet, so a
+// busy pool served by several engines may be behind by their unwritten
+// counts; see [WithQuotaSync].
+func (e *Engine) QuotaPoolUsage(ctx context.Context, poolID id.QuotaPoolID) (*quotapool.Consumption, error) {
+	p, err := e.getQuotaPool(ctx, poolID)
+	if err != nil {
+		return nil, fmt.Errorf("get quota pool: %w", err)
+	}
+	now := e.now()
+	loc := e.quotaZone(ctx, p.TenantID, p.QuotaTimezone)
+	c := &quotapool.Consumption{PoolID: poolID, AsOf: now}
+	if c.Day, err = e.windowUsage(ctx, p, quotapool.PeriodDay, usage.DayWindow(now, loc), p.DailyLimit); err != nil {
+		return nil, err
+	}
+	if c.Month, err = e.windowUsage(ctx, p, quotapool.PeriodMonth, usage.MonthWindow(now, loc), p.MonthlyLimit); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// windowUsage sums the pool's counts in one window by key.
+func (e *Engine) windowUsage(ctx context.Context, p *quotapool.Pool, period quotapool.Period, w usage.Window, limit int64) (quotapool.WindowUsage, error) {
+	out := quotapool.WindowUsage{Period: period, Start: w.Start, End: w.End, Limit: limit, Members: []quotapool.MemberUsage{}}
+	counts, err := e.store.QuotaPools().ListUsage(ctx, &quotapool.UsageFilter{PoolID: p.ID, Period: period, From: w.Start, To: w.End})
+	if err != nil {
+		return out, fmt.Errorf("list quota counts: %w", err)
+	}
+	byKey := make(map[id.KeyID]int64)
+	for _, c := range counts {
+		byKey[c.KeyID] += c.Count
+	}
+	for kid, n := range e.quotas.pendingFor(p.ID, period, w.Start) {
+		byKey[kid] += n
+	}
+	for kid, n := range byKey {
+		out.Used += n
+		out.Members = append(out.Members, quotapool.MemberUsage{KeyID: kid, Used: n})
+	}
+	slices.SortFunc(out.Members, func(a, b quotapool.MemberUsage) int {
+		if c := cmp.Compare(b.Used, a.Used); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.KeyID.String(), b.KeyID.String())
+	})
+	if limit > 0 {
+		out.Remaining = max(limit-out.Used, 0)
+	}
+	return out, nil
+}
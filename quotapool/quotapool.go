@@ -0,0 +1,107 @@
+// Package quotapool defines quota pools: daily and monthly quotas that
+// several keys draw from together, such as a customer plan of "1M
+// requests a month" shared by all of the customer's keys.
+package quotapool
+
+import (
+	"time"
+
+	"github.com/xraph/keysmith/id"
+)
+
+// Pool is a quota shared by its member keys, which are the keys whose
+// PoolID names it. A member's validations count toward the pool instead of
+// its policy's quotas. Zero limits are unlimited.
+type Pool struct {
+	ID           id.QuotaPoolID `json:"id" db:"id"`
+	TenantID     string         `json:"tenant_id" db:"tenant_id"`
+	AppID        string         `json:"app_id" db:"app_id"`
+	Name         string         `json:"name" db:"name"`
+	Description  string         `json:"description,omitempty" db:"description"`
+	DailyLimit   int64          `json:"daily_limit,omitempty" db:"daily_limit"`
+	MonthlyLimit int64          `json:"monthly_limit,omitempty" db:"monthly_limit"`
+
+	// QuotaTimezone is the IANA time zone the pool's days and months count
+	// in. Empty uses the tenant's, else UTC.
+	QuotaTimezone string `json:"quota_timezone,omitempty" db:"quota_timezone"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ListFilter contains filters for listing pools.
+type ListFilter struct {
+	TenantID string `json:"tenant_id,omitempty"`
+	AppID    string `json:"app_id,omitempty"`
+
+	Limit  int `json:"limit,omitempty"`
+	Offset int `json:"offset,omitempty"`
+}
+
+// Period is the length of a quota window.
+type Period string
+
+const (
+	// PeriodDay is a day in the quota time zone.
+	PeriodDay Period = "day"
+
+	// PeriodMonth is a calendar month in the quota time zone.
+	PeriodMonth Period = "month"
+)
+
+// Usage is the number of validations one key made in one quota window.
+// Counts of pool members carry the pool's ID; a zero PoolID counts toward
+// the key's own policy quota.
+type Usage struct {
+	PoolID      id.QuotaPoolID `json:"pool_id" db:"pool_id"`
+	KeyID       id.KeyID       `json:"key_id" db:"key_id"`
+	Period      Period         `json:"period" db:"period"`
+	WindowStart time.Time      `json:"window_start" db:"window_start"`
+	Count       int64          `json:"count" db:"count"`
+}
+
+// UsageFilter selects the counts of one pool, or with a zero PoolID of
+// keys' own quotas.
+type UsageFilter struct {
+	PoolID id.QuotaPoolID `json:"pool_id"`
+
+	// KeyID restricts the counts to one key.
+	KeyID *id.KeyID `json:"key_id,omitempty"`
+
+	Period Period `json:"period,omitempty"`
+
+	// From and To restrict the counts to windows starting in [From, To).
+	// Zero bounds are open.
+	From time.Time `json:"from,omitzero"`
+	To   time.Time `json:"to,omitzero"`
+}
+
+// Consumption is a pool's use of its current day and month.
+type Consumption struct {
+	PoolID id.QuotaPoolID `json:"pool_id"`
+	AsOf   time.Time      `json:"as_of"`
+	Day    WindowUsage    `json:"day"`
+	Month  WindowUsage    `json:"month"`
+}
+
+// WindowUsage is a pool's use of one quota window. Members lists every key
+// that counted toward it, including keys that have since left the pool,
+// most used first.
+type WindowUsage struct {
+	Period Period    `json:"period"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+
+	// Limit is zero when the window is unlimited; Remaining is then zero.
+	Limit     int64 `json:"limit,omitempty"`
+	Used      int64 `json:"used"`
+	Remaining int64 `json:"remaining"`
+
+	Members []MemberUsage `json:"members"`
+}
+
+// MemberUsage is one key's share of a window.
+type MemberUsage struct {
+	KeyID id.KeyID `json:"key_id"`
+	Used  int64    `json:"used"`
+}
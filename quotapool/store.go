@@ -0,0 +1,36 @@
+package quotapool
+
+import (
+	"context"
+	"time"
+
+	"github.com/xraph/keysmith/id"
+)
+
+// Store is the persistence interface for quota pools and the validation
+// counts quotas are enforced from. Members are listed through
+// key.ListFilter.PoolID.
+type Store interface {
+	Create(ctx context.Context, p *Pool) error
+	Get(ctx context.Context, poolID id.QuotaPoolID) (*Pool, error)
+	Update(ctx context.Context, p *Pool) error
+
+	// Delete deletes the pool and its counts, and clears the PoolID of its
+	// members, in one transaction.
+	Delete(ctx context.Context, poolID id.QuotaPoolID) error
+
+	// List returns pools ordered by name.
+	List(ctx context.Context, filter *ListFilter) ([]*Pool, error)
+
+	// AddUsage adds each entry's Count to the stored count for its (pool,
+	// key, period, window start), creating it when absent.
+	AddUsage(ctx context.Context, usages []*Usage) error
+
+	// ListUsage returns the counts filter selects, ordered by window start
+	// and key.
+	ListUsage(ctx context.Context, filter *UsageFilter) ([]*Usage, error)
+
+	// PurgeUsage deletes counts of windows that started before before and
+	// returns how many it deleted.
+	PurgeUsage(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,31 @@
+package quotapool
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/xraph/keysmith/usage"
+)
+
+// Validate reports whether the pool's fields are internally consistent.
+func (p *Pool) Validate() error {
+	var problems []string
+
+	if strings.TrimSpace(p.Name) == "" {
+		problems = append(problems, "name is required")
+	}
+	if p.DailyLimit < 0 || p.MonthlyLimit < 0 {
+		problems = append(problems, "limits must not be negative")
+	}
+	if p.DailyLimit > 0 && p.MonthlyLimit > 0 && p.DailyLimit > p.MonthlyLimit {
+		problems = append(problems, "daily_limit must not exceed monthly_limit")
+	}
+	if _, err := usage.LoadQuotaLocation(p.QuotaTimezone); err != nil {
+		problems = append(problems, "quota_timezone: "+err.Error())
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(problems, "; "))
+}
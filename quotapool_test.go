@@ -0,0 +1,399 @@
+package keysmith_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keysmithtest"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/quotapool"
+	"github.com/xraph/keysmith/ratelimit"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+func createPool(t *testing.T, eng *keysmith.Engine, daily, monthly int64) *quotapool.Pool {
+	t.Helper()
+	p := &quotapool.Pool{Name: "Acme plan", DailyLimit: daily, MonthlyLimit: monthly}
+	require.NoError(t, eng.CreateQuotaPool(testCtx(), p))
+	return p
+}
+
+// admitted validates rawKey n times and returns how many were admitted.
+func admitted(t *testing.T, eng *keysmith.Engine, rawKey string, n int) int {
+	t.Helper()
+	ok := 0
+	for range n {
+		_, err := eng.ValidateKey(testCtx(), rawKey)
+		if err == nil {
+			ok++
+			continue
+		}
+		require.ErrorIs(t, err, keysmith.ErrQuotaExceeded)
+	}
+	return ok
+}
+
+func TestQuotaPool_MembersShareLimit(t *testing.T) {
+	rec := keysmithtest.NewRecorder()
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithExtension(rec))
+	require.NoError(t, err)
+	pool := createPool(t, eng, 0, 5)
+
+	a := keysmithtest.NewKey(eng).WithInput(func(in *keysmith.CreateKeyInput) { in.PoolID = &pool.ID }).MustCreate(t, testCtx())
+	b := keysmithtest.NewKey(eng).MustCreate(t, testCtx())
+	require.NoError(t, eng.AddKeysToQuotaPool(testCtx(), pool.ID, []id.KeyID{b.Key.ID}))
+
+	assert.Equal(t, 3, admitted(t, eng, a.RawKey, 3))
+	assert.Equal(t, 2, admitted(t, eng, b.RawKey, 3), "b draws from what a left")
+	assert.Zero(t, admitted(t, eng, a.RawKey, 1))
+
+	_, err = eng.ValidateKey(testCtx(), b.RawKey)
+	assert.ErrorContains(t, err, pool.ID.String())
+	exceeded := rec.Filter("KeyQuotaExceeded")
+	require.Len(t, exceeded, 3)
+	assert.Equal(t, plugin.ReasonQuotaExceeded, exceeded[0].Meta.ReasonCode)
+	require.NotNil(t, exceeded[0].Quota.PoolID)
+	assert.Equal(t, pool.ID, *exceeded[0].Quota.PoolID)
+	assert.EqualValues(t, 5, exceeded[0].Quota.Used)
+	assert.Zero(t, rec.Count("KeyRateLimited"), "a quota is not a rate limit")
+
+	members, err := eng.QuotaPoolMembers(testCtx(), pool.ID, nil)
+	require.NoError(t, err)
+	assert.Len(t, members, 2)
+}
+
+func TestQuotaPool_PolicyQuotaWithoutPool(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()))
+	require.NoError(t, err)
+	pol := &policy.Policy{Name: "Small", DailyQuota: 2}
+	require.NoError(t, eng.CreatePolicy(testCtx(), pol))
+	a := keysmithtest.NewKey(eng).WithPolicy(pol.ID).MustCreate(t, testCtx())
+	b := keysmithtest.NewKey(eng).WithPolicy(pol.ID).MustCreate(t, testCtx())
+
+	assert.Equal(t, 2, admitted(t, eng, a.RawKey, 4), "each key has its own policy quota")
+	assert.Equal(t, 2, admitted(t, eng, b.RawKey, 4))
+
+	// In a pool, the pool's limits replace the policy's.
+	pool := createPool(t, eng, 10, 0)
+	require.NoError(t, eng.AddKeysToQuotaPool(testCtx(), pool.ID, []id.KeyID{a.Key.ID}))
+	assert.Equal(t, 10, admitted(t, eng, a.RawKey, 12))
+
+	// Out of it, the key is back on its policy quota, already spent today.
+	require.NoError(t, eng.RemoveKeysFromQuotaPool(testCtx(), pool.ID, []id.KeyID{a.Key.ID}))
+	assert.Zero(t, admitted(t, eng, a.RawKey, 1))
+}
+
+// TestPolicyQuota_Boundary checks that the validation that reaches a quota
+// is admitted with none left, the next is refused without counting, and
+// both periods report what is left.
+func TestPolicyQuota_Boundary(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		strict bool
+	}{{"metered", false}, {"strict", true}} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := keysmithtest.NewRecorder()
+			opts := []keysmith.Option{keysmith.WithStore(memory.New()), keysmith.WithExtension(rec)}
+			if tc.strict {
+				opts = append(opts, keysmith.WithRateLimiter(newCountingLimiter()), keysmith.WithStrictQuotas())
+			}
+			eng, err := keysmith.NewEngine(opts...)
+			require.NoError(t, err)
+			pol := &policy.Policy{Name: "Metered", DailyQuota: 3, MonthlyQuota: 10}
+			require.NoError(t, eng.CreatePolicy(testCtx(), pol))
+			created := keysmithtest.NewKey(eng).WithPolicy(pol.ID).MustCreate(t, testCtx())
+
+			for i := range 3 {
+				res, err := eng.ValidateKey(testCtx(), created.RawKey)
+				require.NoError(t, err)
+				require.NotNil(t, res.Quota)
+				assert.Nil(t, res.Quota.PoolID)
+				assert.EqualValues(t, 3, res.Quota.DailyLimit)
+				assert.EqualValues(t, 2-i, res.Quota.DailyRemaining)
+				assert.EqualValues(t, 10, res.Quota.MonthlyLimit)
+				assert.EqualValues(t, 9-i, res.Quota.MonthlyRemaining)
+				assert.True(t, res.Quota.DailyReset.After(time.Now()))
+			}
+			assert.Zero(t, rec.Count("KeyQuotaExceeded"), "the request exactly at quota is admitted")
+
+			_, err = eng.ValidateKey(testCtx(), created.RawKey)
+			require.ErrorIs(t, err, keysmith.ErrQuotaExceeded)
+			assert.NotErrorIs(t, err, keysmith.ErrRateLimited)
+			exceeded := rec.Filter("KeyQuotaExceeded")
+			require.Len(t, exceeded, 1)
+			q := exceeded[0].Quota
+			assert.Equal(t, string(quotapool.PeriodDay), q.Period)
+			assert.EqualValues(t, 3, q.Limit)
+			assert.EqualValues(t, 3, q.Used)
+			assert.Nil(t, q.PoolID)
+			assert.Equal(t, created.Key.ID, exceeded[0].Key.ID)
+			assert.Equal(t, plugin.TriggerValidation, exceeded[0].Meta.Trigger)
+
+			_, err = eng.ValidateKey(testCtx(), created.RawKey)
+			require.ErrorIs(t, err, keysmith.ErrQuotaExceeded)
+			assert.EqualValues(t, 3, rec.Filter("KeyQuotaExceeded")[1].Quota.Used, "a refused validation is not counted")
+		})
+	}
+}
+
+func TestPolicyQuota_NoQuota(t *testing.T) {
+	eng := newTestEngine(t)
+	created := keysmithtest.NewKey(eng).MustCreate(t, testCtx())
+
+	res, err := eng.ValidateKey(testCtx(), created.RawKey)
+	require.NoError(t, err)
+	assert.Nil(t, res.Quota)
+}
+
+func TestQuotaPool_DeleteDetachesMembers(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()))
+	require.NoError(t, err)
+	pool := createPool(t, eng, 1, 0)
+	created := keysmithtest.NewKey(eng).WithInput(func(in *keysmith.CreateKeyInput) { in.PoolID = &pool.ID }).MustCreate(t, testCtx())
+	assert.Equal(t, 1, admitted(t, eng, created.RawKey, 2))
+
+	require.NoError(t, eng.DeleteQuotaPool(testCtx(), pool.ID))
+	k, err := eng.GetKey(testCtx(), created.Key.ID)
+	require.NoError(t, err)
+	assert.Nil(t, k.PoolID)
+	assert.Equal(t, 3, admitted(t, eng, created.RawKey, 3), "no quota without the pool")
+}
+
+func TestQuotaPool_UpdateAppliesToCurrentWindow(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithValidationCache(time.Minute, 100))
+	require.NoError(t, err)
+	pool := createPool(t, eng, 2, 0)
+	created := keysmithtest.NewKey(eng).WithInput(func(in *keysmith.CreateKeyInput) { in.PoolID = &pool.ID }).MustCreate(t, testCtx())
+	assert.Equal(t, 2, admitted(t, eng, created.RawKey, 3))
+
+	pool.DailyLimit = 4
+	require.NoError(t, eng.UpdateQuotaPool(testCtx(), pool))
+	assert.Equal(t, 2, admitted(t, eng, created.RawKey, 3), "cached validations see the new limit")
+}
+
+// TestQuotaPool_OvershootBound runs two engines on one store, each syncing
+// only after a batch of counts, and checks that together they admit no
+// more than the limit plus a batch per engine.
+func TestQuotaPool_OvershootBound(t *testing.T) {
+	const limit, batch = 50, 5
+	s := memory.New()
+	newEngine := func() *keysmith.Engine {
+		eng, err := keysmith.NewEngine(keysmith.WithStore(s), keysmith.WithQuotaSync(time.Hour, batch))
+		require.NoError(t, err)
+		return eng
+	}
+	e1, e2 := newEngine(), newEngine()
+	pool := createPool(t, e1, 0, limit)
+	a := keysmithtest.NewKey(e1).WithInput(func(in *keysmith.CreateKeyInput) { in.PoolID = &pool.ID }).MustCreate(t, testCtx())
+	b := keysmithtest.NewKey(e1).WithInput(func(in *keysmith.CreateKeyInput) { in.PoolID = &pool.ID }).MustCreate(t, testCtx())
+
+	total := 0
+	for range 2 * limit {
+		total += admitted(t, e1, a.RawKey, 1)
+		total += admitted(t, e2, b.RawKey, 1)
+	}
+	assert.GreaterOrEqual(t, total, limit)
+	assert.LessOrEqual(t, total, limit+2*batch)
+
+	// Once both engines have written their counts, the store holds the total.
+	require.NoError(t, e1.Stop(context.Background()))
+	require.NoError(t, e2.Stop(context.Background()))
+	usage, err := newEngine().QuotaPoolUsage(testCtx(), pool.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(total), usage.Month.Used)
+}
+
+// gatedQuotaStore holds the first AddUsage call until release is closed,
+// closing entered when it arrives.
+type gatedQuotaStore struct {
+	*memory.Store
+	once             sync.Once
+	entered, release chan struct{}
+}
+
+func (s *gatedQuotaStore) QuotaPools() quotapool.Store {
+	return &gatedQuotaPoolStore{Store: s.Store.QuotaPools(), parent: s}
+}
+
+type gatedQuotaPoolStore struct {
+	quotapool.Store
+	parent *gatedQuotaStore
+}
+
+func (s *gatedQuotaPoolStore) AddUsage(ctx context.Context, usages []*quotapool.Usage) error {
+	first := false
+	s.parent.once.Do(func() { first = true })
+	if first {
+		close(s.parent.entered)
+		<-s.parent.release
+	}
+	return s.Store.AddUsage(ctx, usages)
+}
+
+func TestPolicyQuota_SyncDoesNotBlockValidations(t *testing.T) {
+	gs := &gatedQuotaStore{Store: memory.New(), entered: make(chan struct{}), release: make(chan struct{})}
+	eng, err := keysmith.NewEngine(keysmith.WithStore(gs), keysmith.WithQuotaSync(time.Hour, 1))
+	require.NoError(t, err)
+	pol := &policy.Policy{Name: "Metered", DailyQuota: 10}
+	require.NoError(t, eng.CreatePolicy(testCtx(), pol))
+	created := keysmithtest.NewKey(eng).WithPolicy(pol.ID).MustCreate(t, testCtx())
+
+	// With a batch of 1 every validation writes its count; the first write
+	// is held.
+	first := make(chan error, 1)
+	go func() {
+		_, err := eng.ValidateKey(testCtx(), created.RawKey)
+		first <- err
+	}()
+	<-gs.entered
+
+	second := make(chan *keysmith.ValidationResult, 1)
+	go func() {
+		res, err := eng.ValidateKey(testCtx(), created.RawKey)
+		assert.NoError(t, err)
+		second <- res
+	}()
+	select {
+	case res := <-second:
+		require.NotNil(t, res)
+		assert.EqualValues(t, 8, res.Quota.DailyRemaining, "the count being written still counts")
+	case <-time.After(5 * time.Second):
+		t.Fatal("a validation waited for another's quota write")
+	}
+
+	close(gs.release)
+	require.NoError(t, <-first)
+	require.NoError(t, eng.Stop(context.Background()))
+	counts, err := gs.Store.QuotaPools().ListUsage(testCtx(), &quotapool.UsageFilter{KeyID: &created.Key.ID, Period: quotapool.PeriodDay})
+	require.NoError(t, err)
+	var total int64
+	for _, c := range counts {
+		total += c.Count
+	}
+	assert.EqualValues(t, 2, total)
+}
+
+func TestQuotaPool_StrictAcrossEngines(t *testing.T) {
+	const limit = 20
+	s, limiter := memory.New(), newCountingLimiter()
+	newEngine := func() *keysmith.Engine {
+		eng, err := keysmith.NewEngine(keysmith.WithStore(s), keysmith.WithRateLimiter(limiter),
+			keysmith.WithQuotaSync(time.Hour, 1000), keysmith.WithStrictQuotas())
+		require.NoError(t, err)
+		return eng
+	}
+	e1, e2 := newEngine(), newEngine()
+	pool := createPool(t, e1, 0, limit)
+	created := keysmithtest.NewKey(e1).WithInput(func(in *keysmith.CreateKeyInput) { in.PoolID = &pool.ID }).MustCreate(t, testCtx())
+
+	total := 0
+	for range limit {
+		total += admitted(t, e1, created.RawKey, 1)
+		total += admitted(t, e2, created.RawKey, 1)
+	}
+	assert.Equal(t, limit, total)
+	assert.Contains(t, limiter.buckets()[0], "quota:pool:"+pool.ID.String()+":month:")
+
+	for name, opts := range map[string][]keysmith.Option{
+		"no limiter":      nil,
+		"default limiter": {keysmith.WithDefaultRateLimiter()},
+		"token bucket":    {keysmith.WithRateLimiter(ratelimit.New())},
+		"reset":           {keysmith.WithRateLimiter(limiter), keysmith.WithDefaultRateLimiter()},
+	} {
+		_, err := keysmith.NewEngine(append(opts, keysmith.WithStore(s), keysmith.WithStrictQuotas())...)
+		assert.ErrorContains(t, err, "strict quotas require a fixed-window rate limiter", name)
+	}
+}
+
+func TestQuotaPoolUsage_Breakdown(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()))
+	require.NoError(t, err)
+	pool := createPool(t, eng, 10, 100)
+	a := keysmithtest.NewKey(eng).WithInput(func(in *keysmith.CreateKeyInput) { in.PoolID = &pool.ID }).MustCreate(t, testCtx())
+	b := keysmithtest.NewKey(eng).WithInput(func(in *keysmith.CreateKeyInput) { in.PoolID = &pool.ID }).MustCreate(t, testCtx())
+	admitted(t, eng, a.RawKey, 1)
+	admitted(t, eng, b.RawKey, 3)
+
+	u, err := eng.QuotaPoolUsage(testCtx(), pool.ID)
+	require.NoError(t, err)
+	assert.Equal(t, pool.ID, u.PoolID)
+	assert.Equal(t, quotapool.PeriodDay, u.Day.Period)
+	assert.Equal(t, int64(4), u.Day.Used)
+	assert.Equal(t, int64(6), u.Day.Remaining)
+	assert.Equal(t, int64(96), u.Month.Remaining)
+	assert.Equal(t, []quotapool.MemberUsage{{KeyID: b.Key.ID, Used: 3}, {KeyID: a.Key.ID, Used: 1}}, u.Day.Members)
+	assert.True(t, u.Month.Start.Before(u.Day.End))
+}
+
+func TestQuotaForecast_Pool(t *testing.T) {
+	start := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	clock := &fakeClock{t: start}
+	rec := keysmithtest.NewRecorder()
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithClock(clock.Now), keysmith.WithExtension(rec))
+	require.NoError(t, err)
+	pool := createPool(t, eng, 0, 100)
+	a := keysmithtest.NewKey(eng).WithInput(func(in *keysmith.CreateKeyInput) { in.PoolID = &pool.ID }).MustCreate(t, testCtx())
+	b := keysmithtest.NewKey(eng).WithInput(func(in *keysmith.CreateKeyInput) { in.PoolID = &pool.ID }).MustCreate(t, testCtx())
+	admitted(t, eng, a.RawKey, 20)
+	admitted(t, eng, b.RawKey, 20)
+	require.NoError(t, eng.Stop(context.Background()))
+
+	f, err := eng.QuotaForecast(testCtx(), a.Key.ID)
+	require.NoError(t, err)
+	require.NotNil(t, f.PoolID)
+	assert.Equal(t, pool.ID, *f.PoolID)
+	assert.Equal(t, int64(100), f.Quota)
+	assert.Equal(t, int64(40), f.Used)
+	require.NotNil(t, f.ProjectedExhaustion, "40 in ten days runs out before month end")
+
+	fired, err := eng.EvaluateQuotaForecasts(testCtx())
+	require.NoError(t, err)
+	assert.Equal(t, 2, fired, "every member is warned")
+	assert.Equal(t, 2, rec.Count("KeyQuotaForecastWarning"))
+
+	unlimited := createPool(t, eng, 10, 0)
+	require.NoError(t, eng.AddKeysToQuotaPool(testCtx(), unlimited.ID, []id.KeyID{a.Key.ID}))
+	_, err = eng.QuotaForecast(testCtx(), a.Key.ID)
+	assert.ErrorIs(t, err, keysmith.ErrNoMonthlyQuota)
+}
+
+func TestQuotaPool_Invalid(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()))
+	require.NoError(t, err)
+	for name, p := range map[string]*quotapool.Pool{
+		"no name":          {DailyLimit: 1},
+		"negative":         {Name: "p", MonthlyLimit: -1},
+		"daily over month": {Name: "p", DailyLimit: 10, MonthlyLimit: 5},
+		"unknown zone":     {Name: "p", QuotaTimezone: "Mars/Olympus"},
+	} {
+		assert.ErrorIs(t, eng.CreateQuotaPool(testCtx(), p), keysmith.ErrInvalidQuotaPool, name)
+	}
+
+	pool := createPool(t, eng, 0, 10)
+	otherCtx := keysmith.WithTenant(context.Background(), "app_test", "tenant_other")
+	other, err := eng.CreateKey(otherCtx, &keysmith.CreateKeyInput{Name: "Other", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	_, err = eng.GetQuotaPool(otherCtx, pool.ID)
+	assert.ErrorIs(t, err, keysmith.ErrQuotaPoolNotFound)
+	err = eng.AddKeysToQuotaPool(keysmith.WithTenant(context.Background(), "", ""), pool.ID, []id.KeyID{other.Key.ID})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidQuotaPool, "keys join pools of their own tenant")
+
+	otherPool := &quotapool.Pool{Name: "Other plan"}
+	require.NoError(t, eng.CreateQuotaPool(otherCtx, otherPool))
+	_, err = eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "K", Prefix: "sk", Environment: key.EnvTest, PoolID: &otherPool.ID})
+	assert.ErrorIs(t, err, keysmith.ErrQuotaPoolNotFound)
+
+	pools, err := eng.ListQuotaPools(testCtx(), nil)
+	require.NoError(t, err)
+	require.Len(t, pools, 1)
+	assert.Equal(t, pool.ID, pools[0].ID)
+}
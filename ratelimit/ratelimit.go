@@ -0,0 +1,215 @@
+// Package ratelimit provides the in-memory token-bucket rate limiter that
+// keysmith engines use when none is configured.
+//
+// Each bucket holds up to its burst in tokens and refills at limit tokens
+// per window, so a key limited to 100 requests a minute may spend its
+// budget at once or spread over the minute, but never more than 100 a
+// minute on average. A request takes one token; with none left it is
+// refused. The burst is the policy's BurstLimit, or the limit itself when
+// no burst is set:
+//
+//	l := ratelimit.New()
+//	ok, _ := l.AllowBurst(ctx, "akey_01h2x...", 100, 20, time.Minute)
+//
+// Buckets live in the process, so engines behind a load balancer each
+// apply the full limit; use a shared limiter, such as one backed by Redis,
+// to limit across instances. A bucket that has refilled completely holds
+// nothing a new bucket would not, so a periodic sweep drops it.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultSweepInterval is how often a Limiter drops the buckets that have
+// refilled.
+const DefaultSweepInterval = time.Minute
+
+// Option configures a Limiter.
+type Option func(*Limiter)
+
+// WithClock sets the clock buckets refill by. Defaults to time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(l *Limiter) {
+		if now != nil {
+			l.now = now
+		}
+	}
+}
+
+// WithSweepInterval sets how often idle buckets are dropped. Zero uses
+// DefaultSweepInterval.
+func WithSweepInterval(d time.Duration) Option {
+	return func(l *Limiter) {
+		if d > 0 {
+			l.sweepEvery = d
+		}
+	}
+}
+
+// Limiter is a token-bucket rate limiter keyed by bucket name, safe for
+// concurrent use. It implements keysmith.RateLimiter and
+// keysmith.BurstRateLimiter.
+type Limiter struct {
+	now        func() time.Time
+	sweepEvery time.Duration
+
+	mu      sync.RWMutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+// bucket is one key's tokens. Its fields are guarded by mu.
+type bucket struct {
+	mu       sync.Mutex
+	tokens   float64
+	capacity float64
+	rate     float64 // tokens per nanosecond
+	last     time.Time
+
+	// swept is set when the sweep dropped the bucket, so that a caller
+	// that found it just before looks it up again.
+	swept bool
+}
+
+// New returns an empty Limiter.
+func New(opts ...Option) *Limiter {
+	l := &Limiter{
+		now:        time.Now,
+		sweepEvery: DefaultSweepInterval,
+		buckets:    make(map[string]*bucket),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	l.swept = l.now()
+	return l
+}
+
+// Allow takes a token from the bucket named key, which refills at limit
+// tokens per window and holds at most limit. It reports false when the
+// bucket is empty. A limit or window that is not positive allows every
+// request.
+func (l *Limiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, error) {
+	return l.AllowBurst(ctx, key, limit, 0, window)
+}
+
+// AllowBurst is Allow with a bucket that holds at most burst tokens, or
+// limit when burst is not positive. A bucket's size and rate follow the
+// latest call, so a policy change applies to the tokens already held.
+func (l *Limiter) AllowBurst(_ context.Context, key string, limit, burst int, window time.Duration) (bool, error) {
+	if limit <= 0 || window <= 0 {
+		return true, nil
+	}
+	capacity := float64(limit)
+	if burst > 0 {
+		capacity = float64(burst)
+	}
+	rate := float64(limit) / float64(window)
+
+	now := l.now()
+	l.maybeSweep(now)
+	b := l.bucket(key, capacity, now)
+	b.mu.Lock()
+	for b.swept {
+		b.mu.Unlock()
+		b = l.bucket(key, capacity, now)
+		b.mu.Lock()
+	}
+	defer b.mu.Unlock()
+	b.capacity, b.rate = capacity, rate
+	b.refill(now)
+	if b.tokens < 1 {
+		return false, nil
+	}
+	b.tokens--
+	return true, nil
+}
+
+// Remaining returns the whole tokens left in the bucket named key, or limit
+// for a bucket not yet used. It takes no token.
+func (l *Limiter) Remaining(ctx context.Context, key string, limit int, window time.Duration) (int, error) {
+	return l.RemainingBurst(ctx, key, limit, 0, window)
+}
+
+// RemainingBurst is Remaining for a bucket used with AllowBurst: a bucket
+// not yet used reports the burst AllowBurst would grant, or limit when burst
+// is not positive.
+func (l *Limiter) RemainingBurst(_ context.Context, key string, limit, burst int, _ time.Duration) (int, error) {
+	l.mu.RLock()
+	b, ok := l.buckets[key]
+	l.mu.RUnlock()
+	if !ok {
+		if burst > 0 && limit > 0 {
+			return burst, nil
+		}
+		return max(limit, 0), nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(l.now())
+	return int(b.tokens), nil
+}
+
+// Len returns the number of buckets held.
+func (l *Limiter) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.buckets)
+}
+
+// bucket returns the bucket named key, creating a full one of capacity.
+func (l *Limiter) bucket(key string, capacity float64, now time.Time) *bucket {
+	l.mu.RLock()
+	b, ok := l.buckets[key]
+	l.mu.RUnlock()
+	if ok {
+		return b
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if b, ok := l.buckets[key]; ok {
+		return b
+	}
+	b = &bucket{tokens: capacity, capacity: capacity, last: now}
+	l.buckets[key] = b
+	return b
+}
+
+// maybeSweep drops the buckets that have refilled, at most once per sweep
+// interval.
+func (l *Limiter) maybeSweep(now time.Time) {
+	l.mu.RLock()
+	due := now.Sub(l.swept) >= l.sweepEvery
+	l.mu.RUnlock()
+	if !due {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.swept) < l.sweepEvery {
+		return
+	}
+	l.swept = now
+	for key, b := range l.buckets {
+		b.mu.Lock()
+		b.refill(now)
+		if b.tokens >= b.capacity {
+			b.swept = true
+			delete(l.buckets, key)
+		}
+		b.mu.Unlock()
+	}
+}
+
+// refill adds the tokens earned since the last refill. The caller holds
+// b.mu.
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += float64(elapsed) * b.rate
+		b.last = now
+	}
+	b.tokens = min(b.tokens, b.capacity)
+}
@@ -0,0 +1,152 @@
+package ratelimit_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith/ratelimit"
+)
+
+// clock is a settable time source.
+type clock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func newClock() *clock { return &clock{t: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)} }
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+// allowed takes n tokens from key and returns how many were granted.
+func allowed(t *testing.T, l *ratelimit.Limiter, key string, limit, burst, n int) int {
+	t.Helper()
+	ok := 0
+	for range n {
+		granted, err := l.AllowBurst(context.Background(), key, limit, burst, time.Minute)
+		require.NoError(t, err)
+		if granted {
+			ok++
+		}
+	}
+	return ok
+}
+
+func TestLimiter_WindowRollover(t *testing.T) {
+	c := newClock()
+	l := ratelimit.New(ratelimit.WithClock(c.Now))
+	ctx := context.Background()
+
+	assert.Equal(t, 10, allowed(t, l, "key:a", 10, 0, 12), "the limit is allowed at once")
+	rem, err := l.Remaining(ctx, "key:a", 10, time.Minute)
+	require.NoError(t, err)
+	assert.Zero(t, rem)
+
+	c.Advance(30 * time.Second)
+	assert.Equal(t, 5, allowed(t, l, "key:a", 10, 0, 10), "half a window refills half the limit")
+
+	c.Advance(time.Minute)
+	rem, err = l.Remaining(ctx, "key:a", 10, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 10, rem, "a full window refills the bucket")
+	c.Advance(time.Hour)
+	assert.Equal(t, 10, allowed(t, l, "key:a", 10, 0, 12), "idle time does not bank more than the bucket holds")
+
+	assert.Equal(t, 10, allowed(t, l, "key:b", 10, 0, 11), "buckets are independent")
+}
+
+func TestLimiter_Burst(t *testing.T) {
+	c := newClock()
+	l := ratelimit.New(ratelimit.WithClock(c.Now))
+
+	// A burst below the limit smooths the window: at most 2 at once, 60 a
+	// minute on average.
+	assert.Equal(t, 2, allowed(t, l, "key:smooth", 60, 2, 5))
+	c.Advance(time.Second)
+	assert.Equal(t, 1, allowed(t, l, "key:smooth", 60, 2, 5))
+	c.Advance(time.Minute)
+	assert.Equal(t, 2, allowed(t, l, "key:smooth", 60, 2, 5))
+
+	// A burst above the limit lets a quiet key spend more at once, then
+	// holds it to the limit.
+	assert.Equal(t, 30, allowed(t, l, "key:bursty", 10, 30, 40))
+	c.Advance(time.Minute)
+	assert.Equal(t, 10, allowed(t, l, "key:bursty", 10, 30, 40))
+
+	rem, err := l.Remaining(context.Background(), "key:unused", 10, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 10, rem)
+	rem, err = l.RemainingBurst(context.Background(), "key:unused", 10, 30, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 30, rem, "an unused bucket reports the burst AllowBurst grants")
+	assert.Equal(t, 30, allowed(t, l, "key:unused", 10, 30, 40))
+}
+
+func TestLimiter_NoLimit(t *testing.T) {
+	l := ratelimit.New()
+	assert.Equal(t, 5, allowed(t, l, "key:a", 0, 0, 5))
+	ok, err := l.Allow(context.Background(), "key:a", 1, 0)
+	require.NoError(t, err)
+	assert.True(t, ok, "no window, no limit")
+	assert.Zero(t, l.Len())
+}
+
+func TestLimiter_SweepsRefilledBuckets(t *testing.T) {
+	c := newClock()
+	l := ratelimit.New(ratelimit.WithClock(c.Now), ratelimit.WithSweepInterval(30*time.Second))
+
+	allowed(t, l, "key:a", 10, 0, 10)
+	allowed(t, l, "key:b", 600, 0, 1)
+	assert.Equal(t, 2, l.Len())
+
+	c.Advance(30 * time.Second)
+	allowed(t, l, "key:c", 10, 0, 1)
+	assert.Equal(t, 2, l.Len(), "b refilled and was dropped; a is half full")
+
+	c.Advance(2 * time.Minute)
+	allowed(t, l, "key:a", 10, 0, 1)
+	assert.Equal(t, 1, l.Len(), "only a, used again, remains")
+	rem, err := l.Remaining(context.Background(), "key:a", 10, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 9, rem, "a dropped bucket starts full again")
+}
+
+// TestLimiter_Concurrent runs on the real clock with a sweep on every call,
+// so that sweeps race the callers. An hour's window refills nothing
+// measurable meanwhile.
+func TestLimiter_Concurrent(t *testing.T) {
+	l := ratelimit.New(ratelimit.WithSweepInterval(time.Nanosecond))
+
+	var granted atomic.Int64
+	var wg sync.WaitGroup
+	for range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				if ok, _ := l.Allow(context.Background(), "key:shared", 100, time.Hour); ok {
+					granted.Add(1)
+				}
+				_, _ = l.Remaining(context.Background(), "key:shared", 100, time.Hour)
+				_, _ = l.Allow(context.Background(), "key:other", 1, time.Hour)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(100), granted.Load(), "exactly the limit is granted across goroutines")
+}
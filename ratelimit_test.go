@@ -0,0 +1,251 @@
+package keysmith_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keysmithtest"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/ratelimit"
+	"github.com/xraph/keysmith/store/memory"
+	"github.com/xraph/keysmith/tenant"
+)
+
+// countingLimiter allows limit requests per bucket and never resets.
+type countingLimiter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func newCountingLimiter() *countingLimiter {
+	return &countingLimiter{counts: make(map[string]int)}
+}
+
+func (l *countingLimiter) Allow(_ context.Context, bucket string, limit int, _ time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.counts[bucket] >= limit {
+		return false, nil
+	}
+	l.counts[bucket]++
+	return true, nil
+}
+
+func (l *countingLimiter) Remaining(_ context.Context, bucket string, limit int, _ time.Duration) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return limit - l.counts[bucket], nil
+}
+
+func (l *countingLimiter) buckets() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]string, 0, len(l.counts))
+	for b := range l.counts {
+		out = append(out, b)
+	}
+	return out
+}
+
+type tenantLimitRecorder struct{ tenants []string }
+
+func (r *tenantLimitRecorder) Name() string { return "tenant-limit-recorder" }
+
+func (r *tenantLimitRecorder) OnTenantRateLimited(_ context.Context, k *key.Key) error {
+	r.tenants = append(r.tenants, k.TenantID)
+	return nil
+}
+
+// newTenantLimitEngine returns an engine whose tenant_test tenant allows 5
+// validations in total and keys created against a policy allowing 10 each.
+func newTenantLimitEngine(t *testing.T) (*keysmith.Engine, *countingLimiter, *tenantLimitRecorder, *policy.Policy) {
+	t.Helper()
+	limiter := newCountingLimiter()
+	rec := &tenantLimitRecorder{}
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithRateLimiter(limiter),
+		keysmith.WithExtension(rec),
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, eng.SetTenantSettings(testCtx(), &tenant.Settings{
+		RateLimit:       5,
+		RateLimitWindow: time.Minute,
+	}))
+	pol := &policy.Policy{Name: "Per Key", RateLimit: 10, RateLimitWindow: time.Minute}
+	require.NoError(t, eng.CreatePolicy(testCtx(), pol))
+	return eng, limiter, rec, pol
+}
+
+func createLimitedKey(t *testing.T, ctx context.Context, eng *keysmith.Engine, pol *policy.Policy) string {
+	t.Helper()
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
+		Name:        "Limited",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+		PolicyID:    &pol.ID,
+	})
+	require.NoError(t, err)
+	return created.RawKey
+}
+
+func TestTenantRateLimit_CeilingAcrossKeys(t *testing.T) {
+	eng, limiter, rec, pol := newTenantLimitEngine(t)
+	raw := []string{
+		createLimitedKey(t, testCtx(), eng, pol),
+		createLimitedKey(t, testCtx(), eng, pol),
+		createLimitedKey(t, testCtx(), eng, pol),
+	}
+
+	// Five validations spread over three keys: each key stays far under its
+	// own limit of 10, but together they use up the tenant's budget.
+	for i := range 5 {
+		vr, err := eng.ValidateKey(testCtx(), raw[i%len(raw)])
+		require.NoError(t, err, "validation %d", i)
+		require.NotNil(t, vr.RateLimit)
+		assert.Equal(t, 10, vr.RateLimit.Limit)
+		assert.Equal(t, 5, vr.RateLimit.TenantLimit)
+		assert.Equal(t, 4-i, vr.RateLimit.TenantRemaining)
+	}
+
+	_, err := eng.ValidateKey(testCtx(), raw[2])
+	assert.ErrorIs(t, err, keysmith.ErrTenantRateLimited)
+	assert.NotErrorIs(t, err, keysmith.ErrRateLimited, "the tenant error is distinct from the key error")
+	assert.Equal(t, []string{"tenant_test"}, rec.tenants)
+
+	for _, b := range limiter.buckets() {
+		assert.Regexp(t, `^(akey_\w+|tenant:tenant_test)$`, b)
+	}
+}
+
+func TestTenantRateLimit_OtherTenantsUnaffected(t *testing.T) {
+	eng, _, _, pol := newTenantLimitEngine(t)
+	busy := createLimitedKey(t, testCtx(), eng, pol)
+	for range 5 {
+		_, err := eng.ValidateKey(testCtx(), busy)
+		require.NoError(t, err)
+	}
+	_, err := eng.ValidateKey(testCtx(), busy)
+	require.ErrorIs(t, err, keysmith.ErrTenantRateLimited)
+
+	otherCtx := keysmith.WithTenant(context.Background(), "app_test", "tenant_other")
+	other, err := eng.CreateKey(otherCtx, &keysmith.CreateKeyInput{Name: "Other", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	for range 10 {
+		vr, err := eng.ValidateKey(otherCtx, other.RawKey)
+		require.NoError(t, err)
+		assert.Nil(t, vr.RateLimit, "no limit applies to the other tenant")
+	}
+}
+
+func TestTenantRateLimit_KeyLimitCheckedFirst(t *testing.T) {
+	eng, limiter, rec, _ := newTenantLimitEngine(t)
+	tight := &policy.Policy{Name: "Tight", RateLimit: 1, RateLimitWindow: time.Minute}
+	require.NoError(t, eng.CreatePolicy(testCtx(), tight))
+	raw := createLimitedKey(t, testCtx(), eng, tight)
+
+	_, err := eng.ValidateKey(testCtx(), raw)
+	require.NoError(t, err)
+	_, err = eng.ValidateKey(testCtx(), raw)
+	assert.ErrorIs(t, err, keysmith.ErrRateLimited)
+	assert.Empty(t, rec.tenants)
+
+	// The refused validation did not spend tenant budget.
+	n, err := limiter.Remaining(testCtx(), "tenant:tenant_test", 5, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+}
+
+func TestSetTenantSettings_RejectsInvalidRateLimit(t *testing.T) {
+	eng := newTestEngine(t)
+	for _, ts := range []*tenant.Settings{
+		{RateLimit: 100},
+		{RateLimit: -1, RateLimitWindow: time.Minute},
+		{RateLimitWindow: -time.Minute},
+	} {
+		err := eng.SetTenantSettings(testCtx(), ts)
+		assert.ErrorIs(t, err, keysmith.ErrInvalidTenantSettings)
+	}
+}
+
+// newDefaultLimiterEngine returns an engine with no rate limiter configured
+// and a key under a policy allowing 3 validations a minute and burst.
+func newDefaultLimiterEngine(t *testing.T, burst int, opts ...keysmith.Option) (*keysmith.Engine, *fakeClock, *keysmithtest.Recorder, string) {
+	t.Helper()
+	clock := &fakeClock{t: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)}
+	rec := keysmithtest.NewRecorder()
+	eng, err := keysmith.NewEngine(append([]keysmith.Option{
+		keysmith.WithStore(memory.New()),
+		keysmith.WithClock(clock.Now),
+		keysmith.WithExtension(rec),
+	}, opts...)...)
+	require.NoError(t, err)
+
+	pol := &policy.Policy{Name: "Default Limited", RateLimit: 3, RateLimitWindow: time.Minute, BurstLimit: burst}
+	require.NoError(t, eng.CreatePolicy(testCtx(), pol))
+	return eng, clock, rec, createLimitedKey(t, testCtx(), eng, pol)
+}
+
+// validations returns how many of n validations of raw were allowed.
+func validations(t *testing.T, eng *keysmith.Engine, raw string, n int) int {
+	t.Helper()
+	ok := 0
+	for range n {
+		_, err := eng.ValidateKey(testCtx(), raw)
+		if err == nil {
+			ok++
+			continue
+		}
+		require.ErrorIs(t, err, keysmith.ErrRateLimited)
+	}
+	return ok
+}
+
+func TestDefaultRateLimiter_EnforcesPolicy(t *testing.T) {
+	eng, clock, rec, raw := newDefaultLimiterEngine(t, 0)
+
+	vr, err := eng.ValidateKey(testCtx(), raw)
+	require.NoError(t, err)
+	require.NotNil(t, vr.RateLimit, "the default limiter applies without WithRateLimiter")
+	assert.Equal(t, 3, vr.RateLimit.Limit)
+	assert.Equal(t, 2, vr.RateLimit.Remaining)
+
+	assert.Equal(t, 2, validations(t, eng, raw, 4))
+	assert.Equal(t, 2, rec.Count("KeyRateLimited"))
+
+	clock.Set(clock.Now().Add(time.Minute))
+	assert.Equal(t, 3, validations(t, eng, raw, 4), "the next window allows the limit again")
+}
+
+func TestDefaultRateLimiter_Burst(t *testing.T) {
+	eng, clock, _, raw := newDefaultLimiterEngine(t, 1)
+
+	assert.Equal(t, 1, validations(t, eng, raw, 3), "a burst of 1 allows one at a time")
+	clock.Set(clock.Now().Add(20 * time.Second))
+	assert.Equal(t, 1, validations(t, eng, raw, 3), "a token refills every 20 seconds")
+	clock.Set(clock.Now().Add(time.Hour))
+	assert.Equal(t, 1, validations(t, eng, raw, 3), "idle time banks no more than the burst")
+}
+
+func TestDefaultRateLimiter_ReplacedByWithRateLimiter(t *testing.T) {
+	limiter := newCountingLimiter()
+	eng, _, _, raw := newDefaultLimiterEngine(t, 0, keysmith.WithRateLimiter(limiter))
+
+	assert.Equal(t, 3, validations(t, eng, raw, 5))
+	assert.Len(t, limiter.buckets(), 1, "the configured limiter is used instead")
+
+	eng, _, _, raw = newDefaultLimiterEngine(t, 0,
+		keysmith.WithRateLimiter(limiter),
+		keysmith.WithDefaultRateLimiter(ratelimit.WithSweepInterval(time.Hour)),
+	)
+	assert.Equal(t, 3, validations(t, eng, raw, 5))
+	assert.Len(t, limiter.buckets(), 1, "a later WithDefaultRateLimiter restores the default")
+}
@@ -2,10 +2,21 @@ package keysmith
 
 import (
 	"context"
+	"fmt"
 	"time"
+
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/ratelimit"
 )
 
 // RateLimiter checks whether a request is allowed under rate limits.
+//
+// The engine keeps per-key, per-tenant, and per-scope budgets in separate
+// buckets of the same limiter: a key's bucket is named by its key ID alone,
+// as before tenant and scope limits existed, and the others
+// "tenant:<tenant ID>" and "scope:<key ID>:<scope name>".
 type RateLimiter interface {
 	// Allow returns true if the request is within rate limits.
 	Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, error)
@@ -13,3 +24,162 @@ type RateLimiter interface {
 	// Remaining returns the number of remaining requests in the current window.
 	Remaining(ctx context.Context, key string, limit int, window time.Duration) (int, error)
 }
+
+// BurstRateLimiter is a RateLimiter that can also bound how much of a
+// window's budget is spent at once. When the configured limiter implements
+// it, a policy's BurstLimit is passed for the key's bucket; otherwise
+// BurstLimit is ignored. The default limiter, [ratelimit.Limiter],
+// implements it.
+type BurstRateLimiter interface {
+	RateLimiter
+
+	// AllowBurst is Allow with a bucket holding at most burst requests, or
+	// limit when burst is not positive.
+	AllowBurst(ctx context.Context, key string, limit, burst int, window time.Duration) (bool, error)
+
+	// RemainingBurst is Remaining for a bucket used with AllowBurst.
+	RemainingBurst(ctx context.Context, key string, limit, burst int, window time.Duration) (int, error)
+}
+
+// RateLimitInfo describes the rate limits applied to a validation and the
+// budget left in each after it, for rate-limit response headers. A zero
+// limit means no limit of that kind applied.
+type RateLimitInfo struct {
+	Limit     int           `json:"limit,omitempty"`
+	Remaining int           `json:"remaining"`
+	Window    time.Duration `json:"window,omitempty"`
+
+	TenantLimit     int           `json:"tenant_limit,omitempty"`
+	TenantRemaining int           `json:"tenant_remaining"`
+	TenantWindow    time.Duration `json:"tenant_window,omitempty"`
+
+	// Penalty is set while the key's limit is reduced by adaptive limiting;
+	// Limit is then the reduced limit. See [WithAdaptiveLimiting].
+	Penalty *key.AdaptivePenalty `json:"penalty,omitempty"`
+}
+
+// ScopeRateLimitInfo describes a scope's rate limit applied to a request and
+// the budget the key has left under it, for rate-limit response headers.
+type ScopeRateLimitInfo struct {
+	Scope     string        `json:"scope"`
+	Limit     int           `json:"limit"`
+	Remaining int           `json:"remaining"`
+	Window    time.Duration `json:"window"`
+}
+
+// scopeLimit is a scope's rate limit, as loaded with the key's scopes.
+type scopeLimit struct {
+	limit  int
+	window time.Duration
+}
+
+// keyBucket, tenantBucket, and scopeBucket name the limiter buckets for a
+// key, a tenant, and a key's use of a scope. A key's bucket keeps the bare
+// key ID so that counters held by a custom limiter survive upgrades; key IDs
+// contain no colon, so the prefixes still keep the namespaces apart.
+func keyBucket(k *key.Key) string { return k.ID.String() }
+
+func tenantBucket(tenantID string) string { return "tenant:" + tenantID }
+
+func scopeBucket(k *key.Key, scopeName string) string {
+	return "scope:" + k.ID.String() + ":" + scopeName
+}
+
+var _ BurstRateLimiter = (*ratelimit.Limiter)(nil)
+
+// checkRateLimits applies the key's policy limit and then its tenant's
+// ceiling. The tenant budget is only spent once the key is within its own
+// limit. It returns nil info when no limit applied.
+func (e *Engine) checkRateLimits(ctx context.Context, k *key.Key, pol *policy.Policy) (*RateLimitInfo, error) {
+	var info RateLimitInfo
+
+	if pol != nil && pol.RateLimit > 0 {
+		limit := pol.RateLimit
+		if e.adaptive != nil {
+			p, started := e.adaptive.check(k.ID, limit, e.now())
+			if started {
+				_ = e.hooks.FireKeyAdaptivelyLimited(ctx, k, p, e.eventMeta(ctx, plugin.TriggerValidation, plugin.ReasonErrorRate))
+			}
+			if p != nil {
+				limit, info.Penalty = p.Limit, p
+			}
+		}
+		bucket := keyBucket(k)
+		bl, burst := e.ratelimiter.(BurstRateLimiter)
+		burst = burst && pol.BurstLimit > 0 && info.Penalty == nil
+		var allowed bool
+		var err error
+		if burst {
+			allowed, err = bl.AllowBurst(ctx, bucket, limit, pol.BurstLimit, pol.RateLimitWindow)
+		} else {
+			allowed, err = e.ratelimiter.Allow(ctx, bucket, limit, pol.RateLimitWindow)
+		}
+		if err != nil || !allowed {
+			_ = e.hooks.FireKeyRateLimited(ctx, k, e.eventMeta(ctx, plugin.TriggerValidation, plugin.ReasonRateLimited))
+			return nil, ErrRateLimited
+		}
+		info.Limit, info.Window = limit, pol.RateLimitWindow
+		if burst {
+			info.Remaining, _ = bl.RemainingBurst(ctx, bucket, limit, pol.BurstLimit, pol.RateLimitWindow)
+		} else {
+			info.Remaining, _ = e.ratelimiter.Remaining(ctx, bucket, limit, pol.RateLimitWindow)
+		}
+	}
+
+	if ts := e.tenantSettings(ctx, k.TenantID); ts.RateLimit > 0 {
+		bucket := tenantBucket(k.TenantID)
+		allowed, err := e.ratelimiter.Allow(ctx, bucket, ts.RateLimit, ts.RateLimitWindow)
+		if err != nil || !allowed {
+			_ = e.hooks.FireTenantRateLimited(ctx, k, e.eventMeta(ctx, plugin.TriggerValidation, plugin.ReasonTenantRateLimited))
+			return nil, ErrTenantRateLimited
+		}
+		info.TenantLimit, info.TenantWindow = ts.RateLimit, ts.RateLimitWindow
+		info.TenantRemaining, _ = e.ratelimiter.Remaining(ctx, bucket, ts.RateLimit, ts.RateLimitWindow)
+	}
+
+	if info.Limit == 0 && info.TenantLimit == 0 {
+		return nil, nil
+	}
+	// Copied so that info stays on the stack when no limit applies.
+	out := info
+	return &out, nil
+}
+
+// CheckScopeRateLimits applies the rate limits of the named scopes to a
+// request that exercises them, after result's validation has passed the
+// key's and tenant's limits. Each key has its own budget under each scope,
+// so an export scope limited to 10 requests a minute allows every key 10,
+// however generous the key's own limit. Scopes without a limit, and scopes
+// the key does not hold, are not checked. middleware.RequireScopes calls it
+// for the scopes a route requires.
+//
+// Scopes are checked in order, spending one request from each, until one
+// is over its limit; KeyRateLimited then fires with
+// [plugin.ReasonScopeRateLimited] and the scope in its meta, and the
+// returned error wraps ErrScopeRateLimited and names the scope. Budget spent
+// under the scopes before it is not returned. It returns nil info when no
+// scope limit applied.
+func (e *Engine) CheckScopeRateLimits(ctx context.Context, result *ValidationResult, scopes ...string) ([]ScopeRateLimitInfo, error) {
+	if len(result.scopeLimits) == 0 {
+		return nil, nil
+	}
+	var infos []ScopeRateLimitInfo
+	for _, name := range scopes {
+		sl, ok := result.scopeLimits[name]
+		if !ok {
+			continue
+		}
+		bucket := scopeBucket(result.Key, name)
+		allowed, err := e.ratelimiter.Allow(ctx, bucket, sl.limit, sl.window)
+		if err != nil || !allowed {
+			meta := e.eventMeta(ctx, plugin.TriggerValidation, plugin.ReasonScopeRateLimited)
+			meta.Scope = name
+			_ = e.hooks.FireKeyRateLimited(ctx, result.Key, meta)
+			return nil, fmt.Errorf("%w: %s", ErrScopeRateLimited, name)
+		}
+		info := ScopeRateLimitInfo{Scope: name, Limit: sl.limit, Window: sl.window}
+		info.Remaining, _ = e.ratelimiter.Remaining(ctx, bucket, sl.limit, sl.window)
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
@@ -0,0 +1,48 @@
+package keysmith
+
+import "context"
+
+// SetReadOnly turns read-only mode on or off, for database maintenance
+// windows. While it is on, every engine method that writes to the store —
+// key, policy, scope, and tenant settings changes, cleanups, purges, and
+// store maintenance — fails with ErrReadOnlyMode before doing anything, and
+// fires no hooks. Reads, dry runs, and ValidateKey keep working; validation
+// skips its own writes, the last-used timestamp and the state change of a
+// key found expired, and still rejects such keys. Usage recording, debug
+// captures, and endpoint activity are append-only and continue unless
+// [WithReadOnlyUsageRecording] refuses them too.
+//
+// The mode applies to this engine only and is not persisted; see
+// [WithReadOnly] to start an engine in it.
+func (e *Engine) SetReadOnly(on bool) {
+	e.readOnly.Store(on)
+}
+
+// ReadOnly reports whether read-only mode is on.
+func (e *Engine) ReadOnly() bool {
+	return e.readOnly.Load()
+}
+
+// UsageRecordingInReadOnly reports whether usage is still recorded while
+// read-only mode is on. See [WithReadOnlyUsageRecording].
+func (e *Engine) UsageRecordingInReadOnly() bool {
+	return !e.readOnlyBlocksUsage
+}
+
+// checkWritable returns ErrReadOnlyMode when read-only mode is on. Dry runs
+// write nothing and pass.
+func (e *Engine) checkWritable(ctx context.Context) error {
+	if e.readOnly.Load() && !IsDryRun(ctx) {
+		return ErrReadOnlyMode
+	}
+	return nil
+}
+
+// checkUsageWritable is checkWritable for usage records, debug captures,
+// and endpoint activity, which read-only mode allows by default.
+func (e *Engine) checkUsageWritable() error {
+	if e.readOnlyBlocksUsage && e.readOnly.Load() {
+		return ErrReadOnlyMode
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package keysmith_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keysmithtest"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/store/memory"
+	"github.com/xraph/keysmith/tenant"
+	"github.com/xraph/keysmith/usage"
+)
+
+func newReadOnlyEngine(t *testing.T, opts ...keysmith.Option) (*keysmith.Engine, *keysmithtest.Recorder) {
+	t.Helper()
+	rec := keysmithtest.NewRecorder()
+	eng, err := keysmith.NewEngine(append([]keysmith.Option{
+		keysmith.WithStore(memory.New()),
+		keysmith.WithExtension(rec),
+	}, opts...)...)
+	require.NoError(t, err)
+	return eng, rec
+}
+
+func TestReadOnly_RefusesMutations(t *testing.T) {
+	eng, rec := newReadOnlyEngine(t)
+	ctx := testCtx()
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Job", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	kid := created.Key.ID
+	pol := &policy.Policy{Name: "Basic"}
+	require.NoError(t, eng.CreatePolicy(ctx, pol))
+	require.NoError(t, eng.CreateScope(ctx, &scope.Scope{Name: "read"}))
+
+	eng.SetReadOnly(true)
+	require.True(t, eng.ReadOnly())
+	rec.Reset()
+
+	mutations := map[string]func() error{
+		"CreateKey": func() error {
+			_, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "New", Prefix: "sk", Environment: key.EnvTest})
+			return err
+		},
+		"UpdateKey": func() error {
+			name := "Renamed"
+			_, err := eng.UpdateKey(ctx, kid, &keysmith.UpdateKeyInput{Name: &name})
+			return err
+		},
+		"RotateKey": func() error {
+			_, err := eng.RotateKey(ctx, kid, rotation.ReasonManual)
+			return err
+		},
+		"RevokeKey":     func() error { return eng.RevokeKey(ctx, kid, "test") },
+		"SuspendKey":    func() error { return eng.SuspendKey(ctx, kid) },
+		"ReactivateKey": func() error { return eng.ReactivateKey(ctx, kid) },
+		"SetKeyDebug": func() error {
+			_, err := eng.SetKeyDebug(ctx, kid, 1, time.Hour)
+			return err
+		},
+		"CreatePolicy": func() error { return eng.CreatePolicy(ctx, &policy.Policy{Name: "Other"}) },
+		"UpdatePolicy": func() error { return eng.UpdatePolicy(ctx, pol) },
+		"DeletePolicy": func() error { return eng.DeletePolicy(ctx, pol.ID) },
+		"CreatePolicyFromTemplate": func() error {
+			_, err := eng.CreatePolicyFromTemplate(ctx, "free", nil)
+			return err
+		},
+		"CreateScope":  func() error { return eng.CreateScope(ctx, &scope.Scope{Name: "write"}) },
+		"DeleteScope":  func() error { return eng.DeleteScope(ctx, id.NewScopeID()) },
+		"AssignScopes": func() error { return eng.AssignScopes(ctx, kid, []string{"read"}) },
+		"RemoveScopes": func() error { return eng.RemoveScopes(ctx, kid, []string{"read"}) },
+		"SetTenantSettings": func() error {
+			return eng.SetTenantSettings(ctx, &tenant.Settings{DefaultScopes: []string{"read"}})
+		},
+		"CleanupExpiredKeys": func() error {
+			_, err := eng.CleanupExpiredKeys(ctx)
+			return err
+		},
+		"CleanupGraceExpired": func() error {
+			_, err := eng.CleanupGraceExpired(ctx)
+			return err
+		},
+		"InitiateKeyTransfer": func() error {
+			_, err := eng.InitiateKeyTransfer(ctx, kid, "tenant_other")
+			return err
+		},
+		"CleanupExpiredKeyTransfers": func() error {
+			_, err := eng.CleanupExpiredKeyTransfers(ctx)
+			return err
+		},
+		"PromoteInlinePolicy": func() error {
+			_, err := eng.PromoteInlinePolicy(ctx, kid, "Promoted")
+			return err
+		},
+		"RevokeByHashes": func() error {
+			_, err := eng.RevokeByHashes(context.Background(), []string{created.Key.KeyHash}, "leak")
+			return err
+		},
+		"ReportCompromise": func() error {
+			_, err := eng.ReportCompromise(ctx, kid, &key.CompromiseReport{Source: "github", Action: key.CompromiseRevoke})
+			return err
+		},
+		"PurgeRevocations": func() error {
+			_, err := eng.PurgeRevocations(ctx)
+			return err
+		},
+		"PurgeHashTombstones": func() error {
+			_, err := eng.PurgeHashTombstones(ctx)
+			return err
+		},
+		"PurgeCaptures": func() error {
+			_, err := eng.PurgeCaptures(ctx)
+			return err
+		},
+		"MaintainStore": func() error {
+			_, err := eng.MaintainStore(ctx)
+			return err
+		},
+		"CanonicalizeKeyHashes": func() error {
+			_, err := eng.CanonicalizeKeyHashes(ctx)
+			return err
+		},
+	}
+	for name, mutate := range mutations {
+		assert.ErrorIs(t, mutate(), keysmith.ErrReadOnlyMode, name)
+	}
+	assert.Empty(t, rec.Events(), "refused mutations fire no hooks")
+
+	// Reads and validation keep working.
+	got, err := eng.GetKey(ctx, kid)
+	require.NoError(t, err)
+	assert.Equal(t, key.StateActive, got.State)
+	assert.Equal(t, "Job", got.Name)
+	_, err = eng.ListPolicies(ctx, &policy.ListFilter{})
+	assert.NoError(t, err)
+	_, err = eng.ValidateKey(ctx, created.RawKey)
+	assert.NoError(t, err)
+	_, err = eng.MaintainStoreWith(ctx, store.MaintenanceOptions{StatsOnly: true})
+	assert.NotErrorIs(t, err, keysmith.ErrReadOnlyMode, "stats-only maintenance reads")
+	assert.NoError(t, eng.RevokeKey(keysmith.WithDryRun(ctx), kid, "preview"), "dry runs write nothing")
+	assert.True(t, eng.HealthReport(ctx).ReadOnly)
+	assert.NoError(t, eng.Health(ctx))
+
+	eng.SetReadOnly(false)
+	assert.False(t, eng.HealthReport(ctx).ReadOnly)
+	for _, name := range []string{"SuspendKey", "ReactivateKey", "CreatePolicy"} {
+		assert.NoError(t, mutations[name](), name)
+	}
+}
+
+func TestReadOnly_ValidationSkipsWrites(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)}
+	eng, rec := newReadOnlyEngine(t, keysmith.WithClock(clock.Now))
+	ctx := testCtx()
+	expires := clock.Now().Add(time.Hour)
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Job", Prefix: "sk", Environment: key.EnvTest, ExpiresAt: &expires})
+	require.NoError(t, err)
+
+	eng.SetReadOnly(true)
+	rec.Reset()
+	_, err = eng.ValidateKey(ctx, created.RawKey)
+	require.NoError(t, err)
+
+	clock.Set(expires.Add(time.Minute))
+	_, err = eng.ValidateKey(ctx, created.RawKey)
+	assert.ErrorIs(t, err, keysmith.ErrKeyExpired)
+	assert.Zero(t, rec.Count("KeyExpired"))
+
+	require.NoError(t, eng.Stop(context.Background()))
+	got, err := eng.Store().Keys().Get(ctx, created.Key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, key.StateActive, got.State, "the expiry is recorded once writes resume")
+	assert.Nil(t, got.LastUsedAt)
+}
+
+func TestReadOnly_UsageRecording(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		opts    []keysmith.Option
+		allowed bool
+	}{
+		{"default", nil, true},
+		{"allowed", []keysmith.Option{keysmith.WithReadOnlyUsageRecording(true)}, true},
+		{"refused", []keysmith.Option{keysmith.WithReadOnlyUsageRecording(false)}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			eng, _ := newReadOnlyEngine(t, tt.opts...)
+			ctx := testCtx()
+			created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Job", Prefix: "sk", Environment: key.EnvTest})
+			require.NoError(t, err)
+			record := func() error {
+				return eng.RecordUsage(ctx, &usage.Record{KeyID: created.Key.ID, Endpoint: "/v1/orders", Method: "GET", StatusCode: 200})
+			}
+
+			eng.SetReadOnly(true)
+			assert.Equal(t, tt.allowed, eng.UsageRecordingInReadOnly())
+			if tt.allowed {
+				require.NoError(t, record())
+				require.NoError(t, eng.FlushEndpointActivity(ctx))
+			} else {
+				assert.ErrorIs(t, record(), keysmith.ErrReadOnlyMode)
+				assert.ErrorIs(t, eng.FlushEndpointActivity(ctx), keysmith.ErrReadOnlyMode)
+			}
+
+			eng.SetReadOnly(false)
+			require.NoError(t, record(), "usage is recorded once read-only mode ends")
+			recs, err := eng.QueryUsage(ctx, &usage.QueryFilter{KeyID: &created.Key.ID})
+			require.NoError(t, err)
+			if tt.allowed {
+				assert.Len(t, recs, 2)
+			} else {
+				assert.Len(t, recs, 1)
+			}
+		})
+	}
+}
+
+func TestWithReadOnly(t *testing.T) {
+	eng, _ := newReadOnlyEngine(t, keysmith.WithReadOnly())
+	ctx := testCtx()
+	assert.True(t, eng.ReadOnly())
+	_, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Job", Prefix: "sk", Environment: key.EnvTest})
+	assert.ErrorIs(t, err, keysmith.ErrReadOnlyMode)
+
+	eng.SetReadOnly(false)
+	_, err = eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Job", Prefix: "sk", Environment: key.EnvTest})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,252 @@
+package keysmith
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultReplayWindow is how far a request timestamp may be from the
+	// engine clock.
+	DefaultReplayWindow = 5 * time.Minute
+
+	// DefaultReplayCacheSize is the number of nonces the in-memory replay
+	// cache holds.
+	DefaultReplayCacheSize = 100_000
+
+	// MaxNonceLength is the longest accepted nonce.
+	MaxNonceLength = 128
+)
+
+// NonceStore remembers the nonces of validation requests, for
+// [WithReplayProtection]. Implementations backed by a shared cache, such as
+// Redis SET NX with an expiry, extend replay protection across engine
+// instances.
+type NonceStore interface {
+	// Remember records nonce for ttl and reports whether it was not already
+	// recorded. It must be atomic: of concurrent calls with the same nonce,
+	// exactly one reports true.
+	Remember(ctx context.Context, nonce string, ttl time.Duration) (bool, error)
+}
+
+// ReplayProtection configures [WithReplayProtection]. Zero fields use the
+// defaults.
+type ReplayProtection struct {
+	// Window is how far a request's timestamp may be from the engine clock,
+	// in either direction. Nonces are remembered for twice the window, the
+	// span of timestamps that can be accepted. Defaults to
+	// [DefaultReplayWindow].
+	Window time.Duration `json:"window" mapstructure:"window" yaml:"window"`
+
+	// MaxNonces bounds the in-memory cache. When it is full the oldest
+	// nonce is dropped, so that a request older than the cache's reach could
+	// be replayed; size it for the validation rate times twice the window.
+	// Evictions are reported by [Engine.ReplayStats]. Defaults to
+	// [DefaultReplayCacheSize].
+	MaxNonces int `json:"max_nonces" mapstructure:"max_nonces" yaml:"max_nonces"`
+
+	// Store replaces the in-memory cache, for instance with one shared by
+	// every engine instance. MaxNonces then does not apply.
+	Store NonceStore `json:"-" mapstructure:"-" yaml:"-"`
+}
+
+func (c *ReplayProtection) validate() error {
+	if c.Window < 0 || c.MaxNonces < 0 {
+		return fmt.Errorf("%w: window and max nonces must not be negative", ErrInvalidReplayProtection)
+	}
+	return nil
+}
+
+func (c *ReplayProtection) withDefaults() ReplayProtection {
+	out := *c
+	if out.Window == 0 {
+		out.Window = DefaultReplayWindow
+	}
+	if out.MaxNonces == 0 {
+		out.MaxNonces = DefaultReplayCacheSize
+	}
+	return out
+}
+
+// ReplayStats counts replay checks since the engine was created. Size and
+// Evictions are zero with a custom NonceStore.
+type ReplayStats struct {
+	// Checked counts requests whose nonce was looked up, and Replays those
+	// whose nonce had already been seen.
+	Checked int64 `json:"checked"`
+	Replays int64 `json:"replays"`
+
+	// Stale counts requests rejected for their timestamp, before the nonce
+	// was looked up.
+	Stale int64 `json:"stale"`
+
+	// Size is the number of nonces held; Evictions counts nonces dropped
+	// before their expiry to make room.
+	Size      int   `json:"size"`
+	Evictions int64 `json:"evictions"`
+}
+
+// HitRate returns the fraction of checked nonces that were replays.
+func (s ReplayStats) HitRate() float64 {
+	if s.Checked == 0 {
+		return 0
+	}
+	return float64(s.Replays) / float64(s.Checked)
+}
+
+// replayGuard checks request nonces and timestamps.
+type replayGuard struct {
+	window time.Duration
+	store  NonceStore
+	cache  *nonceCache // the default store; nil with a custom one
+
+	checked, replays, stale atomic.Int64
+}
+
+func newReplayGuard(cfg ReplayProtection, now func() time.Time) *replayGuard {
+	cfg = cfg.withDefaults()
+	g := &replayGuard{window: cfg.Window, store: cfg.Store}
+	if g.store == nil {
+		g.cache = newNonceCache(cfg.MaxNonces, now)
+		g.store = g.cache
+	}
+	return g
+}
+
+// CheckReplay rejects a replayed validation request under
+// [WithReplayProtection]: nonce is required, timestamp must be within the
+// window of the engine clock, and the nonce must not have been used with
+// rawKey before. Nonces are scoped to the key, so two clients cannot
+// collide, and only a digest of the key and nonce is stored. It returns nil
+// when replay protection is off. The validate endpoint calls it before
+// ValidateKey; the middleware does not.
+func (e *Engine) CheckReplay(ctx context.Context, rawKey, nonce string, timestamp time.Time) error {
+	g := e.replay
+	if g == nil {
+		return nil
+	}
+	switch {
+	case nonce == "" || timestamp.IsZero():
+		return ErrNonceRequired
+	case len(nonce) > MaxNonceLength:
+		return fmt.Errorf("%w: nonce is longer than %d bytes", ErrNonceRequired, MaxNonceLength)
+	}
+	if d := e.now().Sub(timestamp); d > g.window || d < -g.window {
+		g.stale.Add(1)
+		return ErrRequestStale
+	}
+
+	g.checked.Add(1)
+	fresh, err := g.store.Remember(ctx, nonceDigest(rawKey, nonce), 2*g.window)
+	if err != nil {
+		return fmt.Errorf("remember nonce: %w", err)
+	}
+	if !fresh {
+		g.replays.Add(1)
+		return ErrRequestReplayed
+	}
+	return nil
+}
+
+// ReplayStats reports replay checks since the engine was created, or nil
+// when replay protection is off.
+func (e *Engine) ReplayStats() *ReplayStats {
+	g := e.replay
+	if g == nil {
+		return nil
+	}
+	st := &ReplayStats{
+		Checked: g.checked.Load(),
+		Replays: g.replays.Load(),
+		Stale:   g.stale.Load(),
+	}
+	if g.cache != nil {
+		st.Size, st.Evictions = g.cache.stats()
+	}
+	return st
+}
+
+// nonceDigest returns the NonceStore key for nonce used with rawKey.
+func nonceDigest(rawKey, nonce string) string {
+	h := sha256.New()
+	h.Write([]byte(rawKey))
+	h.Write([]byte{0})
+	h.Write([]byte(nonce))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// nonceCache is the in-memory NonceStore: nonces in insertion order with an
+// expiry each, bounded by max. A nonce is still remembered at its expiry,
+// when a timestamp at the edge of the window is still accepted.
+type nonceCache struct {
+	max int
+	now func() time.Time
+
+	mu        sync.Mutex
+	order     *list.List // of *nonceEntry, oldest at the back
+	entries   map[string]*list.Element
+	evictions int64
+}
+
+type nonceEntry struct {
+	nonce   string
+	expires time.Time
+}
+
+func newNonceCache(maxEntries int, now func() time.Time) *nonceCache {
+	return &nonceCache{
+		max:     maxEntries,
+		now:     now,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (c *nonceCache) Remember(_ context.Context, nonce string, ttl time.Duration) (bool, error) {
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[nonce]; ok {
+		if !now.After(el.Value.(*nonceEntry).expires) {
+			return false, nil
+		}
+		c.remove(el)
+	}
+	c.expire(now)
+	for len(c.entries) >= c.max {
+		c.remove(c.order.Back())
+		c.evictions++
+	}
+	c.entries[nonce] = c.order.PushFront(&nonceEntry{nonce: nonce, expires: now.Add(ttl)})
+	return true, nil
+}
+
+// expire drops expired nonces from the back. Every nonce has the same TTL,
+// so the back holds the earliest expiry. Callers hold c.mu.
+func (c *nonceCache) expire(now time.Time) {
+	for el := c.order.Back(); el != nil; el = c.order.Back() {
+		if !now.After(el.Value.(*nonceEntry).expires) {
+			return
+		}
+		c.remove(el)
+	}
+}
+
+// remove deletes one entry. Callers hold c.mu.
+func (c *nonceCache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*nonceEntry).nonce)
+}
+
+func (c *nonceCache) stats() (size int, evictions int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries), c.evictions
+}
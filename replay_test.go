@@ -0,0 +1,167 @@
+package keysmith_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+func newReplayEngine(t *testing.T, cfg keysmith.ReplayProtection) (*keysmith.Engine, *fakeClock, string) {
+	t.Helper()
+	clock := &fakeClock{t: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)}
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithClock(clock.Now),
+		keysmith.WithReplayProtection(cfg),
+	)
+	require.NoError(t, err)
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "Job", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	return eng, clock, created.RawKey
+}
+
+func TestCheckReplay_RejectsDuplicateNonce(t *testing.T) {
+	eng, clock, raw := newReplayEngine(t, keysmith.ReplayProtection{Window: time.Minute})
+	ctx := testCtx()
+
+	require.NoError(t, eng.CheckReplay(ctx, raw, "n-1", clock.Now()))
+	assert.ErrorIs(t, eng.CheckReplay(ctx, raw, "n-1", clock.Now()), keysmith.ErrRequestReplayed)
+	assert.NoError(t, eng.CheckReplay(ctx, raw, "n-2", clock.Now()))
+
+	other, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Other", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	assert.NoError(t, eng.CheckReplay(ctx, other.RawKey, "n-1", clock.Now()), "nonces are scoped to the key")
+
+	st := eng.ReplayStats()
+	require.NotNil(t, st)
+	assert.EqualValues(t, 4, st.Checked)
+	assert.EqualValues(t, 1, st.Replays)
+	assert.Equal(t, 3, st.Size)
+	assert.InDelta(t, 0.25, st.HitRate(), 1e-9)
+}
+
+func TestCheckReplay_RequiresNonceAndTimestamp(t *testing.T) {
+	eng, clock, raw := newReplayEngine(t, keysmith.ReplayProtection{})
+	ctx := testCtx()
+
+	assert.ErrorIs(t, eng.CheckReplay(ctx, raw, "", clock.Now()), keysmith.ErrNonceRequired)
+	assert.ErrorIs(t, eng.CheckReplay(ctx, raw, "n-1", time.Time{}), keysmith.ErrNonceRequired)
+	long := fmt.Sprintf("%0*d", keysmith.MaxNonceLength+1, 0)
+	assert.ErrorIs(t, eng.CheckReplay(ctx, raw, long, clock.Now()), keysmith.ErrNonceRequired)
+	assert.Zero(t, eng.ReplayStats().Checked)
+}
+
+func TestCheckReplay_Window(t *testing.T) {
+	eng, clock, raw := newReplayEngine(t, keysmith.ReplayProtection{Window: time.Minute})
+	ctx := testCtx()
+	start := clock.Now()
+
+	assert.ErrorIs(t, eng.CheckReplay(ctx, raw, "old", start.Add(-61*time.Second)), keysmith.ErrRequestStale)
+	assert.ErrorIs(t, eng.CheckReplay(ctx, raw, "future", start.Add(61*time.Second)), keysmith.ErrRequestStale)
+	require.NoError(t, eng.CheckReplay(ctx, raw, "edge", start.Add(time.Minute)))
+	assert.EqualValues(t, 2, eng.ReplayStats().Stale)
+
+	// The nonce is remembered for as long as its timestamp is accepted.
+	clock.Set(start.Add(2 * time.Minute))
+	assert.ErrorIs(t, eng.CheckReplay(ctx, raw, "edge", start.Add(time.Minute)), keysmith.ErrRequestReplayed)
+
+	// Once the window has passed, it is forgotten.
+	clock.Set(start.Add(3*time.Minute + time.Second))
+	require.NoError(t, eng.CheckReplay(ctx, raw, "later", clock.Now()))
+	assert.Equal(t, 1, eng.ReplayStats().Size, "expired nonces are dropped")
+	assert.Zero(t, eng.ReplayStats().Evictions)
+}
+
+func TestCheckReplay_EvictsOldestWhenFull(t *testing.T) {
+	eng, clock, raw := newReplayEngine(t, keysmith.ReplayProtection{Window: time.Minute, MaxNonces: 3})
+	ctx := testCtx()
+
+	for i := range 5 {
+		require.NoError(t, eng.CheckReplay(ctx, raw, fmt.Sprintf("n-%d", i), clock.Now()))
+	}
+	st := eng.ReplayStats()
+	assert.Equal(t, 3, st.Size)
+	assert.EqualValues(t, 2, st.Evictions)
+
+	for i := 2; i < 5; i++ {
+		assert.ErrorIs(t, eng.CheckReplay(ctx, raw, fmt.Sprintf("n-%d", i), clock.Now()), keysmith.ErrRequestReplayed)
+	}
+	assert.NoError(t, eng.CheckReplay(ctx, raw, "n-0", clock.Now()), "an evicted nonce is accepted again")
+}
+
+// sharedNonces is a NonceStore standing in for a distributed cache.
+type sharedNonces struct {
+	mu   sync.Mutex
+	seen map[string]time.Duration
+	err  error
+}
+
+func (s *sharedNonces) Remember(_ context.Context, nonce string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return false, s.err
+	}
+	if _, ok := s.seen[nonce]; ok {
+		return false, nil
+	}
+	s.seen[nonce] = ttl
+	return true, nil
+}
+
+func TestCheckReplay_CustomStore(t *testing.T) {
+	shared := &sharedNonces{seen: map[string]time.Duration{}}
+	cfg := keysmith.ReplayProtection{Window: time.Minute, Store: shared}
+	eng, clock, raw := newReplayEngine(t, cfg)
+	ctx := testCtx()
+
+	require.NoError(t, eng.CheckReplay(ctx, raw, "n-1", clock.Now()))
+	require.Len(t, shared.seen, 1)
+	for nonce, ttl := range shared.seen {
+		assert.NotContains(t, nonce, raw, "only a digest is stored")
+		assert.Equal(t, 2*time.Minute, ttl)
+	}
+
+	// A second instance sharing the store sees the nonce.
+	peer, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithClock(clock.Now), keysmith.WithReplayProtection(cfg))
+	require.NoError(t, err)
+	assert.ErrorIs(t, peer.CheckReplay(ctx, raw, "n-1", clock.Now()), keysmith.ErrRequestReplayed)
+	assert.Zero(t, peer.ReplayStats().Size)
+
+	shared.err = errors.New("cache unavailable")
+	err = eng.CheckReplay(ctx, raw, "n-2", clock.Now())
+	assert.ErrorIs(t, err, shared.err)
+	assert.NotErrorIs(t, err, keysmith.ErrRequestReplayed)
+}
+
+func TestCheckReplay_Disabled(t *testing.T) {
+	eng := newTestEngine(t)
+	ctx := testCtx()
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Job", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+
+	for range 2 {
+		assert.NoError(t, eng.CheckReplay(ctx, created.RawKey, "", time.Time{}))
+		assert.NoError(t, eng.CheckReplay(ctx, created.RawKey, "n-1", time.Now().Add(-time.Hour)))
+	}
+	assert.Nil(t, eng.ReplayStats())
+	assert.Nil(t, eng.HealthReport(ctx).Replay)
+}
+
+func TestWithReplayProtection_Invalid(t *testing.T) {
+	_, err := keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithReplayProtection(keysmith.ReplayProtection{Window: -time.Second}),
+	)
+	assert.ErrorIs(t, err, keysmith.ErrInvalidReplayProtection)
+}
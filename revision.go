@@ -0,0 +1,145 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/xraph/go-utils/log"
+
+	"github.com/xraph/keysmith/id"
+)
+
+// DefaultRevisionTTL is how long a tenant revision read from the store is
+// reused before it is read again. See [WithRevisionTTL].
+const DefaultRevisionTTL = 2 * time.Second
+
+// revisionCache holds the tenant revisions this engine last read or bumped,
+// so that conditional requests cost a map lookup instead of a store read.
+// Local bumps update an entry in place; bumps by other instances are seen
+// once the entry is older than ttl.
+type revisionCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]revisionEntry
+}
+
+// revisionEntry is a cached revision. A non-nil failed records a bump that
+// did not reach the store: the store's revision no longer reflects the
+// tenant's data, so it is not handed out until a later bump succeeds.
+type revisionEntry struct {
+	rev     uint64
+	fetched time.Time
+	failed  error
+}
+
+func newRevisionCache(ttl time.Duration) *revisionCache {
+	return &revisionCache{ttl: ttl, entries: make(map[string]revisionEntry)}
+}
+
+// get returns the cached revision of tenantID when it is fresh at now, or
+// the error of its last failed bump.
+func (c *revisionCache) get(tenantID string, now time.Time) (uint64, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ent, ok := c.entries[tenantID]
+	if !ok {
+		return 0, false, nil
+	}
+	if ent.failed != nil {
+		return 0, true, ent.failed
+	}
+	if c.ttl < 0 || now.Sub(ent.fetched) >= c.ttl {
+		return 0, false, nil
+	}
+	return ent.rev, true, nil
+}
+
+// put records rev for tenantID. A revision older than the one cached, as
+// when a slower read races a local bump, is kept out. Failed bumps stay
+// recorded until a bump succeeds.
+func (c *revisionCache) put(tenantID string, rev uint64, now time.Time, bumped bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ent, ok := c.entries[tenantID]
+	if ok && ent.failed != nil && !bumped {
+		return
+	}
+	if ok && ent.failed == nil && ent.rev > rev {
+		rev = ent.rev
+	}
+	c.entries[tenantID] = revisionEntry{rev: rev, fetched: now}
+}
+
+func (c *revisionCache) fail(tenantID string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[tenantID] = revisionEntry{failed: err}
+}
+
+// TenantRevision returns the revision of a tenant: a counter bumped by
+// every mutation the engine makes to the tenant's keys, policies, scopes,
+// and settings, for use as a cheap validator of cached responses. Last-used
+// times and usage records do not bump it. tenantID defaults to the
+// context's tenant.
+//
+// The revision is cached for the TTL set by [WithRevisionTTL]. Mutations
+// made through this engine are reflected at once; mutations made by other
+// engine instances sharing the store take up to the TTL. After a bump fails
+// to reach the store, TenantRevision fails for the tenant until a later
+// bump succeeds, since the stored revision no longer covers its data.
+func (e *Engine) TenantRevision(ctx context.Context, tenantID string) (uint64, error) {
+	if tenantID == "" {
+		tenantID = scopeFromContext(ctx).tenantID
+	}
+	now := e.now()
+	if rev, ok, err := e.revisions.get(tenantID, now); err != nil {
+		return 0, fmt.Errorf("tenant revision is stale after a failed bump: %w", err)
+	} else if ok {
+		return rev, nil
+	}
+	rev, err := e.store.Tenants().Revision(ctx, tenantID)
+	if err != nil {
+		return 0, fmt.Errorf("get tenant revision: %w", err)
+	}
+	e.revisions.put(tenantID, rev, now, false)
+	return rev, nil
+}
+
+// bumpRevision increments the revision of tenantID after a mutation. It is
+// best-effort like appendRevocation: the mutation has already been stored,
+// so a failure is logged rather than returned.
+func (e *Engine) bumpRevision(ctx context.Context, tenantID string) {
+	if IsDryRun(ctx) {
+		return
+	}
+	rev, err := e.store.Tenants().BumpRevision(context.WithoutCancel(ctx), tenantID)
+	if err != nil {
+		e.revisions.fail(tenantID, err)
+		e.logger.Warn("failed to bump tenant revision",
+			log.String("tenant_id", tenantID),
+			log.Any("error", err),
+		)
+		return
+	}
+	e.revisions.put(tenantID, rev, e.now(), true)
+}
+
+// bumpKeyRevision bumps the revision of the tenant that owns keyID: the
+// context's tenant, or the stored key's in a system-scoped context.
+func (e *Engine) bumpKeyRevision(ctx context.Context, keyID id.KeyID) {
+	tenantID := scopeFromContext(ctx).tenantID
+	if tenantID == "" {
+		k, err := e.store.Keys().Get(ctx, keyID)
+		if err != nil {
+			e.logger.Warn("failed to bump tenant revision",
+				log.String("key_id", keyID.String()),
+				log.Any("error", err),
+			)
+			return
+		}
+		tenantID = k.TenantID
+	}
+	e.bumpRevision(ctx, tenantID)
+}
@@ -0,0 +1,203 @@
+package keysmith_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/store/memory"
+	"github.com/xraph/keysmith/tenant"
+)
+
+func TestTenantRevision_BumpsOnMutations(t *testing.T) {
+	eng := newTestEngine(t)
+	ctx := testCtx()
+	other := keysmith.WithTenant(context.Background(), "app_test", "tenant_other")
+
+	rev := func() uint64 {
+		t.Helper()
+		r, err := eng.TenantRevision(ctx, "")
+		require.NoError(t, err)
+		return r
+	}
+	require.Zero(t, rev())
+
+	var k *key.Key
+	var pol *policy.Policy
+	var sc *scope.Scope
+	steps := []struct {
+		name string
+		fn   func() error
+	}{
+		{"CreateScope", func() error {
+			sc = &scope.Scope{Name: "read:orders"}
+			return eng.CreateScope(ctx, sc)
+		}},
+		{"CreatePolicy", func() error {
+			pol = &policy.Policy{Name: "Standard", RateLimit: 10, RateLimitWindow: time.Minute}
+			return eng.CreatePolicy(ctx, pol)
+		}},
+		{"UpdatePolicy", func() error {
+			pol.RateLimit = 20
+			return eng.UpdatePolicy(ctx, pol)
+		}},
+		{"CreateKey", func() error {
+			created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "k", Prefix: "sk", Environment: key.EnvTest})
+			if err == nil {
+				k = created.Key
+			}
+			return err
+		}},
+		{"UpdateKey", func() error {
+			name := "renamed"
+			_, err := eng.UpdateKey(ctx, k.ID, &keysmith.UpdateKeyInput{Name: &name})
+			return err
+		}},
+		{"AssignScopes", func() error { return eng.AssignScopes(ctx, k.ID, []string{"read:orders"}) }},
+		{"RemoveScopes", func() error { return eng.RemoveScopes(ctx, k.ID, []string{"read:orders"}) }},
+		{"DeleteScope", func() error { return eng.DeleteScope(ctx, sc.ID) }},
+		{"RotateKey", func() error {
+			_, err := eng.RotateKey(ctx, k.ID, rotation.ReasonManual)
+			return err
+		}},
+		{"SuspendKey", func() error { return eng.SuspendKey(ctx, k.ID) }},
+		{"ReactivateKey", func() error { return eng.ReactivateKey(ctx, k.ID) }},
+		{"RevokeKey", func() error { return eng.RevokeKey(ctx, k.ID, "retired") }},
+		{"DeletePolicy", func() error { return eng.DeletePolicy(ctx, pol.ID) }},
+		{"SetTenantSettings", func() error {
+			return eng.SetTenantSettings(ctx, &tenant.Settings{TenantID: "tenant_test"})
+		}},
+	}
+	for _, step := range steps {
+		before := rev()
+		require.NoError(t, step.fn(), step.name)
+		assert.Greater(t, rev(), before, "%s bumps the revision", step.name)
+	}
+
+	otherRev, err := eng.TenantRevision(other, "")
+	require.NoError(t, err)
+	assert.Zero(t, otherRev, "other tenants keep their revision")
+}
+
+func TestTenantRevision_ReadsDoNotBump(t *testing.T) {
+	eng := newTestEngine(t)
+	ctx := testCtx()
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "k", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	before, err := eng.TenantRevision(ctx, "")
+	require.NoError(t, err)
+
+	_, err = eng.ValidateKey(ctx, created.RawKey)
+	require.NoError(t, err)
+	_, err = eng.ListKeys(ctx, &key.ListFilter{})
+	require.NoError(t, err)
+	require.NoError(t, eng.RevokeKey(keysmith.WithDryRun(ctx), created.Key.ID, "dry run"))
+
+	after, err := eng.TenantRevision(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, before, after, "validation, reads, and dry runs leave the revision alone")
+}
+
+// TestTenantRevision_TwoInstances runs two engines over one store: each
+// sees its own mutations at once and the other's once its cached revision is
+// older than the TTL.
+func TestTenantRevision_TwoInstances(t *testing.T) {
+	const ttl = 2 * time.Second
+	ms := memory.New()
+	clock := &fakeClock{t: time.Now()}
+	newEngine := func() *keysmith.Engine {
+		eng, err := keysmith.NewEngine(keysmith.WithStore(ms), keysmith.WithClock(clock.Now), keysmith.WithRevisionTTL(ttl))
+		require.NoError(t, err)
+		return eng
+	}
+	a, b := newEngine(), newEngine()
+	ctx := testCtx()
+
+	revA, err := a.TenantRevision(ctx, "")
+	require.NoError(t, err)
+	require.NoError(t, b.CreateScope(ctx, &scope.Scope{Name: "read"}))
+	revB, err := b.TenantRevision(ctx, "")
+	require.NoError(t, err)
+	assert.Greater(t, revB, revA, "an instance sees its own mutation at once")
+
+	clock.Set(clock.Now().Add(ttl / 2))
+	got, err := a.TenantRevision(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, revA, got, "another instance's mutation is unseen within the TTL")
+
+	clock.Set(clock.Now().Add(ttl))
+	got, err = a.TenantRevision(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, revB, got, "and seen once the TTL has passed")
+
+	// Concurrent mutations on both instances each get their own revision.
+	const perEngine = 10
+	var wg sync.WaitGroup
+	for i, eng := range []*keysmith.Engine{a, b} {
+		for j := range perEngine {
+			wg.Go(func() {
+				assert.NoError(t, eng.CreateScope(ctx, &scope.Scope{Name: fmt.Sprintf("s%d-%d", i, j)}))
+			})
+		}
+	}
+	wg.Wait()
+	clock.Set(clock.Now().Add(ttl))
+	want := revB + 2*perEngine
+	for _, eng := range []*keysmith.Engine{a, b} {
+		got, err := eng.TenantRevision(ctx, "")
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+}
+
+// failingRevisions fails BumpRevision while fail is set.
+type failingRevisions struct {
+	*memory.Store
+	fail atomic.Bool
+}
+
+func (s *failingRevisions) Tenants() tenant.Store {
+	return &failingTenants{Store: s.Store.Tenants(), fail: &s.fail}
+}
+
+type failingTenants struct {
+	tenant.Store
+	fail *atomic.Bool
+}
+
+func (s *failingTenants) BumpRevision(ctx context.Context, tenantID string) (uint64, error) {
+	if s.fail.Load() {
+		return 0, errors.New("store down")
+	}
+	return s.Store.BumpRevision(ctx, tenantID)
+}
+
+func TestTenantRevision_FailedBump(t *testing.T) {
+	st := &failingRevisions{Store: memory.New()}
+	eng, err := keysmith.NewEngine(keysmith.WithStore(st))
+	require.NoError(t, err)
+	ctx := testCtx()
+
+	st.fail.Store(true)
+	require.NoError(t, eng.CreateScope(ctx, &scope.Scope{Name: "read"}), "the mutation itself succeeds")
+	_, err = eng.TenantRevision(ctx, "")
+	assert.Error(t, err, "a revision that missed a mutation is not handed out")
+
+	st.fail.Store(false)
+	require.NoError(t, eng.CreateScope(ctx, &scope.Scope{Name: "write"}))
+	rev, err := eng.TenantRevision(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), rev)
+}
@@ -0,0 +1,137 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/xraph/go-utils/log"
+
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/revocation"
+)
+
+// Revocation feed defaults.
+const (
+	// DefaultRevocationPageSize is the number of entries returned when the
+	// caller does not ask for a limit.
+	DefaultRevocationPageSize = 500
+
+	// MaxRevocationPageSize caps the number of entries in one page.
+	MaxRevocationPageSize = 1000
+
+	// DefaultRevocationLookback is how far back the feed can be read.
+	DefaultRevocationLookback = 30 * 24 * time.Hour
+)
+
+// RevocationPage is one page of the revocation feed.
+type RevocationPage struct {
+	// Entries are ordered oldest first.
+	Entries []*revocation.Entry `json:"entries"`
+
+	// Next is the cursor to resume from. It equals the requested cursor
+	// when there is nothing new, so it doubles as a validator for caching.
+	Next string `json:"next"`
+
+	// HasMore reports whether more entries follow Next.
+	HasMore bool `json:"has_more"`
+}
+
+// RevocationsSince returns feed entries recorded after the cursor for the
+// tenant in ctx, or for every tenant when ctx carries none. A zero cursor
+// starts at the oldest readable entry. Cursors older than the lookback
+// window (see [WithRevocationLookback]) return ErrRevocationRangeTooLarge:
+// the caller has missed purged entries and must rebuild its cache.
+func (e *Engine) RevocationsSince(ctx context.Context, after revocation.Cursor, limit int) (*RevocationPage, error) {
+	oldest := e.now().Add(-e.revocationLookback)
+	switch {
+	case after.At.IsZero():
+		after = revocation.Cursor{At: oldest}
+	case after.At.Before(oldest):
+		return nil, fmt.Errorf("%w: %s is older than %s", ErrRevocationRangeTooLarge, after.At.Format(time.RFC3339), e.revocationLookback)
+	}
+
+	if limit <= 0 {
+		limit = DefaultRevocationPageSize
+	}
+	if limit > MaxRevocationPageSize {
+		limit = MaxRevocationPageSize
+	}
+
+	// Fetch one extra entry to learn whether another page follows.
+	entries, err := e.store.Revocations().ListAfter(ctx, scopeFromContext(ctx).tenantID, after, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("list revocations: %w", err)
+	}
+
+	page := &RevocationPage{Entries: entries}
+	if len(entries) > limit {
+		page.Entries = entries[:limit]
+		page.HasMore = true
+	}
+	if n := len(page.Entries); n > 0 {
+		after = revocation.CursorOf(page.Entries[n-1])
+	}
+	page.Next = after.String()
+	return page, nil
+}
+
+// PurgeRevocations deletes feed entries older than the lookback window.
+func (e *Engine) PurgeRevocations(ctx context.Context) (int64, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return 0, err
+	}
+	n, err := e.store.Revocations().Purge(ctx, e.now().Add(-e.revocationLookback))
+	if err != nil {
+		return 0, fmt.Errorf("purge revocations: %w", err)
+	}
+	return n, nil
+}
+
+// recordRevocation appends a feed entry for a key that stopped validating,
+// or, when revoked is false, started validating again. The key change has
+// already been stored, so a failed append is logged rather than returned.
+func (e *Engine) recordRevocation(ctx context.Context, k *key.Key, state key.State, revoked bool) {
+	e.appendRevocation(ctx, &revocation.Entry{
+		KeyID:      k.ID,
+		TenantID:   k.TenantID,
+		HashPrefix: revocation.HashPrefix(k.KeyHash),
+		State:      state,
+		Revoked:    revoked,
+	})
+}
+
+// appendRevocation records a key state change in the feed and bumps the
+// key's tenant revision.
+func (e *Engine) appendRevocation(ctx context.Context, entry *revocation.Entry) {
+	entry.At = e.revocationClock.next(e.now())
+	if err := e.store.Revocations().Append(context.WithoutCancel(ctx), entry); err != nil {
+		e.logger.Warn("failed to record revocation",
+			log.String("key_id", entry.KeyID.String()),
+			log.Any("error", err),
+		)
+	}
+	e.bumpRevision(ctx, entry.TenantID)
+}
+
+// revocationClock issues strictly increasing entry times, so that a suspend
+// and reactivate of the same key in quick succession get distinct, ordered
+// positions in the feed.
+type revocationClock struct {
+	mu   sync.Mutex
+	last time.Time
+}
+
+func (c *revocationClock) next(now time.Time) time.Time {
+	// Stores keep microsecond precision; truncate so cursors round-trip.
+	t := now.UTC().Truncate(time.Microsecond)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !t.After(c.last) {
+		t = c.last.Add(time.Microsecond)
+	}
+	c.last = t
+	return t
+}
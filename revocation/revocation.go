@@ -0,0 +1,68 @@
+// Package revocation defines the revocation feed: an append-only log of keys
+// that stopped, or started again, validating. Partners who cache keys poll
+// the feed instead of validating every request.
+package revocation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+)
+
+// HashPrefixLength is the number of hex characters of the key hash carried in
+// an entry. It is enough to match a cached key but does not reveal the hash.
+const HashPrefixLength = 12
+
+// Entry records a change in whether a key validates. Revoked is false for
+// un-revoke entries, such as a suspended key being reactivated.
+type Entry struct {
+	KeyID      id.KeyID  `json:"key_id" db:"key_id"`
+	TenantID   string    `json:"tenant_id" db:"tenant_id"`
+	HashPrefix string    `json:"hash_prefix" db:"hash_prefix"`
+	State      key.State `json:"state" db:"state"`
+	Revoked    bool      `json:"revoked" db:"revoked"`
+	At         time.Time `json:"at" db:"at"`
+}
+
+// Cursor is a position in the feed. Entries are ordered by (At, KeyID), so a
+// cursor taken from the last entry of a page resumes exactly after it.
+type Cursor struct {
+	At    time.Time
+	KeyID string
+}
+
+// CursorOf returns the cursor positioned at e.
+func CursorOf(e *Entry) Cursor {
+	return Cursor{At: e.At, KeyID: e.KeyID.String()}
+}
+
+// String encodes the cursor as "<unix nanos>.<key id>".
+func (c Cursor) String() string {
+	return strconv.FormatInt(c.At.UnixNano(), 10) + "." + c.KeyID
+}
+
+// ParseCursor decodes a cursor produced by Cursor.String.
+func ParseCursor(s string) (Cursor, error) {
+	nanos, keyID, _ := strings.Cut(s, ".")
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("revocation: invalid cursor %q", s)
+	}
+	return Cursor{At: time.Unix(0, n).UTC(), KeyID: keyID}, nil
+}
+
+// HashPrefix returns the feed prefix of a key hash: the start of its
+// normalized digest, without the algorithm prefix, so legacy and canonical
+// forms of a hash share a prefix.
+func HashPrefix(hash string) string {
+	norm, _ := key.NormalizeHash(hash)
+	_, digest := key.SplitHash(norm)
+	if len(digest) > HashPrefixLength {
+		return digest[:HashPrefixLength]
+	}
+	return digest
+}
@@ -0,0 +1,19 @@
+package revocation
+
+import (
+	"context"
+	"time"
+)
+
+// Store is the persistence interface for the revocation feed.
+type Store interface {
+	// Append adds an entry to the feed.
+	Append(ctx context.Context, e *Entry) error
+
+	// ListAfter returns up to limit entries positioned after the cursor in
+	// (At, KeyID) order. An empty tenantID matches all tenants.
+	ListAfter(ctx context.Context, tenantID string, after Cursor, limit int) ([]*Entry, error)
+
+	// Purge deletes entries recorded before the given time.
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,150 @@
+package keysmith_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/revocation"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+func newRevocationEngine(t *testing.T, opts ...keysmith.Option) *keysmith.Engine {
+	t.Helper()
+	eng, err := keysmith.NewEngine(append([]keysmith.Option{keysmith.WithStore(memory.New())}, opts...)...)
+	require.NoError(t, err)
+	return eng
+}
+
+func createRevocationKey(t *testing.T, eng *keysmith.Engine) *key.CreateResult {
+	t.Helper()
+	result, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+		Name:        "Feed Key",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+	})
+	require.NoError(t, err)
+	return result
+}
+
+func TestRevocationsSince_Incremental(t *testing.T) {
+	eng := newRevocationEngine(t)
+	a := createRevocationKey(t, eng)
+	b := createRevocationKey(t, eng)
+
+	page, err := eng.RevocationsSince(testCtx(), revocation.Cursor{}, 0)
+	require.NoError(t, err)
+	assert.Empty(t, page.Entries)
+
+	require.NoError(t, eng.RevokeKey(testCtx(), a.Key.ID, "leaked"))
+	first, err := eng.RevocationsSince(testCtx(), revocation.Cursor{}, 0)
+	require.NoError(t, err)
+	require.Len(t, first.Entries, 1)
+	assert.Equal(t, a.Key.ID, first.Entries[0].KeyID)
+	assert.Equal(t, key.StateRevoked, first.Entries[0].State)
+	assert.True(t, first.Entries[0].Revoked)
+	assert.Equal(t, revocation.HashPrefix(a.Key.KeyHash), first.Entries[0].HashPrefix)
+
+	require.NoError(t, eng.SuspendKey(testCtx(), b.Key.ID))
+	cursor, err := revocation.ParseCursor(first.Next)
+	require.NoError(t, err)
+	second, err := eng.RevocationsSince(testCtx(), cursor, 0)
+	require.NoError(t, err)
+	require.Len(t, second.Entries, 1, "only entries after the cursor")
+	assert.Equal(t, b.Key.ID, second.Entries[0].KeyID)
+	assert.Equal(t, key.StateSuspended, second.Entries[0].State)
+}
+
+func TestRevocationsSince_EmptyDeltaKeepsCursor(t *testing.T) {
+	eng := newRevocationEngine(t)
+	a := createRevocationKey(t, eng)
+	require.NoError(t, eng.RevokeKey(testCtx(), a.Key.ID, "leaked"))
+
+	page, err := eng.RevocationsSince(testCtx(), revocation.Cursor{}, 0)
+	require.NoError(t, err)
+	cursor, err := revocation.ParseCursor(page.Next)
+	require.NoError(t, err)
+
+	empty, err := eng.RevocationsSince(testCtx(), cursor, 0)
+	require.NoError(t, err)
+	assert.Empty(t, empty.Entries)
+	assert.False(t, empty.HasMore)
+	assert.Equal(t, page.Next, empty.Next)
+}
+
+func TestRevocationsSince_Pagination(t *testing.T) {
+	eng := newRevocationEngine(t)
+	for range 3 {
+		k := createRevocationKey(t, eng)
+		require.NoError(t, eng.RevokeKey(testCtx(), k.Key.ID, "cleanup"))
+	}
+
+	page, err := eng.RevocationsSince(testCtx(), revocation.Cursor{}, 2)
+	require.NoError(t, err)
+	assert.Len(t, page.Entries, 2)
+	assert.True(t, page.HasMore)
+
+	cursor, err := revocation.ParseCursor(page.Next)
+	require.NoError(t, err)
+	rest, err := eng.RevocationsSince(testCtx(), cursor, 2)
+	require.NoError(t, err)
+	assert.Len(t, rest.Entries, 1)
+	assert.False(t, rest.HasMore)
+}
+
+func TestRevocationsSince_Reactivation(t *testing.T) {
+	eng := newRevocationEngine(t)
+	a := createRevocationKey(t, eng)
+	require.NoError(t, eng.SuspendKey(testCtx(), a.Key.ID))
+	require.NoError(t, eng.ReactivateKey(testCtx(), a.Key.ID))
+
+	page, err := eng.RevocationsSince(testCtx(), revocation.Cursor{}, 0)
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 2)
+	assert.True(t, page.Entries[0].Revoked)
+	assert.False(t, page.Entries[1].Revoked, "reactivation un-revokes the key")
+	assert.Equal(t, key.StateActive, page.Entries[1].State)
+}
+
+func TestRevocationsSince_Expiry(t *testing.T) {
+	now := time.Now()
+	eng := newRevocationEngine(t, keysmith.WithClock(func() time.Time { return now }))
+	expires := now.Add(time.Minute)
+	result, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+		Name:        "Expiring Key",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+		ExpiresAt:   &expires,
+	})
+	require.NoError(t, err)
+
+	now = now.Add(2 * time.Minute)
+	_, err = eng.ValidateKey(testCtx(), result.RawKey)
+	require.ErrorIs(t, err, keysmith.ErrKeyExpired)
+
+	page, err := eng.RevocationsSince(testCtx(), revocation.Cursor{}, 0)
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 1)
+	assert.Equal(t, key.StateExpired, page.Entries[0].State)
+}
+
+func TestRevocationsSince_RangeTooLarge(t *testing.T) {
+	eng := newRevocationEngine(t, keysmith.WithRevocationLookback(time.Hour))
+
+	_, err := eng.RevocationsSince(testCtx(), revocation.Cursor{At: time.Now().Add(-2 * time.Hour)}, 0)
+	assert.ErrorIs(t, err, keysmith.ErrRevocationRangeTooLarge)
+}
+
+func TestRevocationsSince_DryRunRecordsNothing(t *testing.T) {
+	eng := newRevocationEngine(t)
+	a := createRevocationKey(t, eng)
+	require.NoError(t, eng.RevokeKey(keysmith.WithDryRun(testCtx()), a.Key.ID, "test"))
+
+	page, err := eng.RevocationsSince(testCtx(), revocation.Cursor{}, 0)
+	require.NoError(t, err)
+	assert.Empty(t, page.Entries)
+}
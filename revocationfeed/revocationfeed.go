@@ -0,0 +1,256 @@
+// Package revocationfeed is a client for the keysmith revocation feed
+// (GET /v1/revocations). Third-party validators that cache keys run a Client
+// to learn, within one poll interval, that a cached key was revoked, expired,
+// or suspended, without calling keysmith on every request.
+//
+//	feed := revocationfeed.New("https://keys.example.com",
+//		revocationfeed.WithHeader("Authorization", "Bearer "+token),
+//	)
+//	go feed.Run(ctx)
+//
+//	if feed.IsRevoked(cached.KeyID) {
+//		// reject and evict
+//	}
+package revocationfeed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xraph/keysmith/revocation"
+)
+
+// DefaultInterval is the time between polls in Run.
+const DefaultInterval = 30 * time.Second
+
+// maxErrorBody bounds how much of an error response is kept in the error.
+const maxErrorBody = 512
+
+// Client polls a revocation feed and keeps the set of keys that currently do
+// not validate. It is safe for concurrent use.
+type Client struct {
+	endpoint string
+	http     *http.Client
+	header   http.Header
+	interval time.Duration
+	onError  func(error)
+
+	// pollMu serialises polls so the cursor advances in order.
+	pollMu sync.Mutex
+	cursor string
+
+	mu       sync.RWMutex
+	revoked  map[string]string // key ID → hash prefix
+	prefixes map[string]string // hash prefix → key ID
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for polling. Defaults to a client
+// with a 30-second timeout.
+func WithHTTPClient(c *http.Client) Option {
+	return func(cl *Client) { cl.http = c }
+}
+
+// WithHeader adds a header, typically Authorization, to every poll.
+func WithHeader(key, value string) Option {
+	return func(cl *Client) { cl.header.Add(key, value) }
+}
+
+// WithInterval sets the time between polls in Run. Defaults to
+// DefaultInterval.
+func WithInterval(d time.Duration) Option {
+	return func(cl *Client) {
+		if d > 0 {
+			cl.interval = d
+		}
+	}
+}
+
+// WithErrorHandler sets a function called with every failed poll in Run.
+func WithErrorHandler(fn func(error)) Option {
+	return func(cl *Client) { cl.onError = fn }
+}
+
+// New creates a Client for the keysmith API at baseURL, e.g.
+// "https://keys.example.com". The /v1/revocations path is appended.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		endpoint: strings.TrimRight(baseURL, "/") + "/v1/revocations",
+		http:     &http.Client{Timeout: 30 * time.Second},
+		header:   make(http.Header),
+		interval: DefaultInterval,
+		revoked:  make(map[string]string),
+		prefixes: make(map[string]string),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// IsRevoked reports whether the key with the given ID is known not to
+// validate.
+func (c *Client) IsRevoked(keyID string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.revoked[keyID]
+	return ok
+}
+
+// IsRevokedHash reports whether the key with the given hash, as computed by
+// the keysmith hasher, is known not to validate.
+func (c *Client) IsRevokedHash(hash string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.prefixes[revocation.HashPrefix(hash)]
+	return ok
+}
+
+// Cursor returns the feed position reached by the last successful poll.
+func (c *Client) Cursor() string {
+	c.pollMu.Lock()
+	defer c.pollMu.Unlock()
+	return c.cursor
+}
+
+// Run polls immediately and then every interval until ctx is done, and
+// returns ctx.Err().
+func (c *Client) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := c.Poll(ctx); err != nil && ctx.Err() == nil && c.onError != nil {
+			c.onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll fetches every entry recorded since the last poll and applies it to
+// the revoked set. It returns the number of entries applied; zero means the
+// feed had not changed. When the server rejects the cursor as older than
+// its lookback window, Poll discards the set and rebuilds it from the start
+// of the window.
+func (c *Client) Poll(ctx context.Context) (int, error) {
+	c.pollMu.Lock()
+	defer c.pollMu.Unlock()
+
+	applied := 0
+	for {
+		page, status, err := c.fetch(ctx, c.cursor)
+		if status == http.StatusBadRequest && c.cursor != "" {
+			c.reset()
+			applied = 0
+			continue
+		}
+		if err != nil {
+			return applied, err
+		}
+		if page == nil {
+			// 304 Not Modified.
+			return applied, nil
+		}
+
+		c.apply(page.Entries)
+		applied += len(page.Entries)
+		c.cursor = page.Next
+		if !page.HasMore || len(page.Entries) == 0 {
+			return applied, nil
+		}
+	}
+}
+
+// page is the wire form of one feed page.
+type page struct {
+	Entries []*revocation.Entry `json:"entries"`
+	Next    string              `json:"next"`
+	HasMore bool                `json:"has_more"`
+}
+
+// fetch requests the page after cursor. It returns a nil page for 304.
+func (c *Client) fetch(ctx context.Context, cursor string) (*page, int, error) {
+	u := c.endpoint
+	if cursor != "" {
+		u += "?cursor=" + url.QueryEscape(cursor)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return nil, 0, fmt.Errorf("revocationfeed: build request: %w", err)
+	}
+	for k, v := range c.header {
+		req.Header[k] = v
+	}
+	if cursor != "" {
+		// The server's ETag is the next cursor, which equals the request
+		// cursor when nothing changed.
+		req.Header.Set("If-None-Match", `"`+cursor+`"`)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("revocationfeed: poll: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil, resp.StatusCode, nil
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return nil, resp.StatusCode, fmt.Errorf("revocationfeed: poll: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var p page
+	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("revocationfeed: decode page: %w", err)
+	}
+	if p.Next == "" {
+		return nil, resp.StatusCode, errors.New("revocationfeed: page has no next cursor")
+	}
+	return &p, resp.StatusCode, nil
+}
+
+// apply folds entries into the revoked set in feed order, so a later
+// un-revoke entry for a key cancels an earlier revoke.
+func (c *Client) apply(entries []*revocation.Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range entries {
+		keyID := e.KeyID.String()
+		if prev, ok := c.revoked[keyID]; ok {
+			delete(c.prefixes, prev)
+		}
+		if !e.Revoked {
+			delete(c.revoked, keyID)
+			continue
+		}
+		c.revoked[keyID] = e.HashPrefix
+		if e.HashPrefix != "" {
+			c.prefixes[e.HashPrefix] = keyID
+		}
+	}
+}
+
+// reset forgets the cursor and the revoked set.
+func (c *Client) reset() {
+	c.cursor = ""
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.revoked = make(map[string]string)
+	c.prefixes = make(map[string]string)
+}
@@ -22,15 +22,46 @@ const (
 
 	// ReasonPolicy indicates a rotation forced by a policy change.
 	ReasonPolicy Reason = "policy"
+
+	// ReasonAdmin indicates a rotation an operator performed on a tenant's
+	// behalf, such as from an admin console.
+	ReasonAdmin Reason = "admin"
+
+	// ReasonAnomaly indicates a rotation triggered automatically by
+	// anomalous activity on the key.
+	ReasonAnomaly Reason = "automated_anomaly"
+
+	// ReasonImport indicates a rotation performed while importing the key
+	// from another system.
+	ReasonImport Reason = "import"
 )
 
+// Reasons returns every known reason.
+func Reasons() []Reason {
+	return []Reason{ReasonScheduled, ReasonManual, ReasonCompromise, ReasonPolicy, ReasonAdmin, ReasonAnomaly, ReasonImport}
+}
+
+// IsValid reports whether r is a known reason. Records written before a
+// reason was validated may hold other strings; they are read back as they
+// were stored.
+func (r Reason) IsValid() bool {
+	switch r {
+	case ReasonScheduled, ReasonManual, ReasonCompromise, ReasonPolicy, ReasonAdmin, ReasonAnomaly, ReasonImport:
+		return true
+	}
+	return false
+}
+
 // Record tracks a key rotation event.
 type Record struct {
 	ID         id.RotationID `json:"id" db:"id"`
 	KeyID      id.KeyID      `json:"key_id" db:"key_id"`
 	TenantID   string        `json:"tenant_id" db:"tenant_id"`
+	AppID      string        `json:"app_id,omitempty" db:"app_id"`
 	OldKeyHash string        `json:"-" db:"old_key_hash"`
 	NewKeyHash string        `json:"-" db:"new_key_hash"`
+	OldHint    string        `json:"old_hint,omitempty" db:"old_hint"`
+	NewHint    string        `json:"new_hint,omitempty" db:"new_hint"`
 	Reason     Reason        `json:"reason" db:"reason"`
 	GraceTTL   time.Duration `json:"grace_ttl" db:"grace_ttl_ms"`
 	GraceEnds  time.Time     `json:"grace_ends" db:"grace_ends"`
@@ -42,7 +73,20 @@ type Record struct {
 type ListFilter struct {
 	KeyID    *id.KeyID `json:"key_id,omitempty"`
 	TenantID string    `json:"tenant_id,omitempty"`
+	AppID    string    `json:"app_id,omitempty"`
 	Reason   Reason    `json:"reason,omitempty"`
-	Limit    int       `json:"limit,omitempty"`
-	Offset   int       `json:"offset,omitempty"`
+
+	// CreatedAfter and CreatedBefore restrict the results to rotations
+	// made within the range. Both bounds are exclusive.
+	CreatedAfter  *time.Time `json:"created_after,omitempty"`
+	CreatedBefore *time.Time `json:"created_before,omitempty"`
+
+	Limit  int `json:"limit,omitempty"`
+	Offset int `json:"offset,omitempty"`
+}
+
+// ReasonCount is how many rotations had one reason.
+type ReasonCount struct {
+	Reason Reason `json:"reason"`
+	Count  int64  `json:"count"`
 }
@@ -14,4 +14,9 @@ type Store interface {
 	List(ctx context.Context, filter *ListFilter) ([]*Record, error)
 	ListPendingGrace(ctx context.Context, now time.Time) ([]*Record, error)
 	LatestForKey(ctx context.Context, keyID id.KeyID) (*Record, error)
+
+	// CountByReason counts the records matching filter per reason, most
+	// frequent first and ties by reason. Limit and Offset are ignored, and
+	// reasons without records are omitted.
+	CountByReason(ctx context.Context, filter *ListFilter) ([]*ReasonCount, error)
 }
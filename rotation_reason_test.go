@@ -0,0 +1,125 @@
+package keysmith_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+func TestRotateKey_ReasonValidation(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()))
+	require.NoError(t, err)
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "Reasons", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	kid := created.Key.ID
+
+	for _, reason := range []rotation.Reason{"rotated by bob", "Manual", "expired"} {
+		_, err := eng.RotateKey(testCtx(), kid, reason)
+		assert.ErrorIs(t, err, keysmith.ErrInvalidRotationReason, "reason %q", reason)
+	}
+	recs, err := eng.ListRotations(testCtx(), &rotation.ListFilter{KeyID: &kid})
+	require.NoError(t, err)
+	assert.Empty(t, recs, "a refused reason rotates nothing")
+
+	for _, reason := range rotation.Reasons() {
+		_, err := eng.RotateKey(testCtx(), kid, reason)
+		require.NoError(t, err, "reason %q", reason)
+	}
+	_, err = eng.RotateKey(testCtx(), kid, "")
+	require.NoError(t, err)
+
+	latest, err := eng.ListRotations(testCtx(), &rotation.ListFilter{KeyID: &kid, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, latest, 1)
+	assert.Equal(t, rotation.ReasonManual, latest[0].Reason, "an empty reason is manual")
+}
+
+func TestListRotations_TenantWideFilters(t *testing.T) {
+	eng, clock, _ := newExpiryEngine(t)
+	base := clock.Now()
+	a := createAppKey(t, eng, "app_a")
+	b := createAppKey(t, eng, "app_b")
+	other, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "Other tenant", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+
+	rotate := func(ctx context.Context, kid id.KeyID, at time.Duration, reason rotation.Reason) {
+		t.Helper()
+		clock.Set(base.Add(at))
+		_, err := eng.RotateKey(ctx, kid, reason)
+		require.NoError(t, err)
+	}
+	rotate(appCtx("app_a"), a.Key.ID, time.Hour, rotation.ReasonCompromise)
+	rotate(appCtx("app_b"), b.Key.ID, 2*time.Hour, rotation.ReasonScheduled)
+	rotate(appCtx("app_b"), b.Key.ID, 3*time.Hour, rotation.ReasonCompromise)
+	rotate(testCtx(), other.Key.ID, 4*time.Hour, rotation.ReasonCompromise)
+
+	after, before := base.Add(90*time.Minute), base.Add(4*time.Hour)
+	recs, err := eng.ListRotations(tenantAdminCtx(), &rotation.ListFilter{Reason: rotation.ReasonCompromise})
+	require.NoError(t, err)
+	require.Len(t, recs, 2, "only the context's tenant")
+	assert.Equal(t, b.Key.ID.String(), recs[0].KeyID.String(), "newest first")
+
+	recs, err = eng.ListRotations(tenantAdminCtx(), &rotation.ListFilter{CreatedAfter: &after, CreatedBefore: &before})
+	require.NoError(t, err)
+	require.Len(t, recs, 2)
+	for _, r := range recs {
+		assert.Equal(t, b.Key.ID.String(), r.KeyID.String())
+	}
+
+	recs, err = eng.ListRotations(appCtx("app_a"), nil)
+	require.NoError(t, err)
+	require.Len(t, recs, 1, "an app sees only its own keys' rotations")
+	assert.Equal(t, a.Key.ID.String(), recs[0].KeyID.String())
+}
+
+func TestCountRotationsByReason(t *testing.T) {
+	ms := memory.New()
+	eng, err := keysmith.NewEngine(keysmith.WithStore(ms))
+	require.NoError(t, err)
+	a := createAppKey(t, eng, "app_a")
+	b := createAppKey(t, eng, "app_b")
+
+	for _, r := range []struct {
+		ctx    context.Context
+		kid    id.KeyID
+		reason rotation.Reason
+	}{
+		{appCtx("app_a"), a.Key.ID, rotation.ReasonCompromise},
+		{appCtx("app_a"), a.Key.ID, rotation.ReasonScheduled},
+		{appCtx("app_b"), b.Key.ID, rotation.ReasonCompromise},
+		{appCtx("app_b"), b.Key.ID, rotation.ReasonAnomaly},
+	} {
+		_, err := eng.RotateKey(r.ctx, r.kid, r.reason)
+		require.NoError(t, err)
+	}
+	// A row written before reasons were validated.
+	require.NoError(t, ms.Rotations().Create(context.Background(), &rotation.Record{
+		ID: id.NewRotationID(), KeyID: a.Key.ID, TenantID: "tenant_shared", AppID: "app_a",
+		Reason: "quarterly", CreatedAt: time.Now(),
+	}))
+
+	counts, err := eng.CountRotationsByReason(tenantAdminCtx(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []*rotation.ReasonCount{
+		{Reason: rotation.ReasonCompromise, Count: 2},
+		{Reason: rotation.ReasonAnomaly, Count: 1},
+		{Reason: "quarterly", Count: 1},
+		{Reason: rotation.ReasonScheduled, Count: 1},
+	}, counts)
+
+	counts, err = eng.CountRotationsByReason(appCtx("app_b"), &rotation.ListFilter{AppID: "app_a"})
+	require.NoError(t, err)
+	assert.Equal(t, []*rotation.ReasonCount{
+		{Reason: rotation.ReasonAnomaly, Count: 1},
+		{Reason: rotation.ReasonCompromise, Count: 1},
+	}, counts, "the caller's app overrides the filter")
+}
@@ -0,0 +1,55 @@
+package keysmith
+
+import (
+	"context"
+	"time"
+
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/policy"
+)
+
+// DefaultRotationDueWarning is the fraction of a policy's rotation period
+// after which validation results carry a rotation reminder.
+const DefaultRotationDueWarning = 0.8
+
+// rotationOverdueInterval is the minimum time between two
+// KeyRotationOverdue hooks for the same key.
+const rotationOverdueInterval = 24 * time.Hour
+
+// rotationDue returns the rotation reminder for k at now, or nil when k is
+// not active, pol sets no rotation period, or less than warning of it has
+// passed. The period runs from k's last rotation, or its creation when it
+// was never rotated.
+func rotationDue(k *key.Key, pol *policy.Policy, warning float64, now time.Time) *key.RotationDue {
+	if k.State != key.StateActive || pol == nil || pol.RotationPeriod <= 0 {
+		return nil
+	}
+	start := k.CreatedAt
+	if k.RotatedAt != nil {
+		start = *k.RotatedAt
+	}
+	if now.Before(start.Add(time.Duration(float64(pol.RotationPeriod) * warning))) {
+		return nil
+	}
+	dueAt := start.Add(pol.RotationPeriod)
+	return &key.RotationDue{
+		DueAt:         dueAt,
+		DaysRemaining: int(dueAt.Sub(now) / (24 * time.Hour)),
+		Overdue:       now.After(dueAt),
+	}
+}
+
+// checkRotationDue returns the rotation reminder for k and, for an overdue
+// key under WithRotationOverdueEvents, fires KeyRotationOverdue at most
+// once per key per day. It reads only the validation snapshot.
+func (e *Engine) checkRotationDue(ctx context.Context, k *key.Key, pol *policy.Policy, now time.Time) *key.RotationDue {
+	due := rotationDue(k, pol, e.rotationWarning, now)
+	if due == nil || !due.Overdue || e.rotationOverdue == nil {
+		return due
+	}
+	if e.rotationOverdue.shouldReport(k.ID, now) {
+		_ = e.hooks.FireKeyRotationOverdue(ctx, k, due, e.eventMeta(ctx, plugin.TriggerValidation, plugin.ReasonRotationOverdue))
+	}
+	return due
+}
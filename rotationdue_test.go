@@ -0,0 +1,156 @@
+package keysmith_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keysmithtest"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+const day = 24 * time.Hour
+
+type overdueRecorder struct {
+	mu  sync.Mutex
+	due []*key.RotationDue
+}
+
+func (r *overdueRecorder) Name() string { return "overdue-recorder" }
+
+func (r *overdueRecorder) OnKeyRotationOverdue(_ context.Context, _ *key.Key, due *key.RotationDue) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.due = append(r.due, due)
+	return nil
+}
+
+func (r *overdueRecorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.due)
+}
+
+// createRotatingKey creates a key whose policy asks for rotation every
+// 10 days.
+func createRotatingKey(t *testing.T, eng *keysmith.Engine) *key.CreateResult {
+	t.Helper()
+	pol := &policy.Policy{Name: "Rotating", RotationPeriod: 10 * day}
+	require.NoError(t, eng.CreatePolicy(testCtx(), pol))
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+		Name: "Rotating", Prefix: "sk", Environment: key.EnvTest, PolicyID: &pol.ID,
+	})
+	require.NoError(t, err)
+	return created
+}
+
+func TestRotationDue_Threshold(t *testing.T) {
+	clock := &fakeClock{t: time.Now()}
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithClock(clock.Now))
+	require.NoError(t, err)
+	created := createRotatingKey(t, eng)
+	start := created.Key.CreatedAt
+
+	due := func(at time.Time) *key.RotationDue {
+		t.Helper()
+		clock.Set(at)
+		vr, err := eng.ValidateKey(testCtx(), created.RawKey)
+		require.NoError(t, err)
+		return vr.RotationDue
+	}
+
+	assert.Nil(t, due(start.Add(7*day)), "less than 80% of the period has passed")
+
+	got := due(start.Add(8*day + time.Hour))
+	require.NotNil(t, got)
+	assert.Equal(t, start.Add(10*day), got.DueAt)
+	assert.Equal(t, 1, got.DaysRemaining)
+	assert.False(t, got.Overdue)
+
+	got = due(start.Add(10*day + time.Hour))
+	require.NotNil(t, got)
+	assert.Zero(t, got.DaysRemaining)
+	assert.True(t, got.Overdue)
+
+	got = due(start.Add(13*day + time.Hour))
+	require.NotNil(t, got)
+	assert.Equal(t, -3, got.DaysRemaining)
+}
+
+func TestRotationDue_RotationRestartsPeriod(t *testing.T) {
+	clock := &fakeClock{t: time.Now()}
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithClock(clock.Now))
+	require.NoError(t, err)
+	created := createRotatingKey(t, eng)
+
+	clock.Set(created.Key.CreatedAt.Add(9 * day))
+	next, err := eng.RotateKey(testCtx(), created.Key.ID, rotation.ReasonScheduled)
+	require.NoError(t, err)
+
+	vr, err := eng.ValidateKey(testCtx(), next.RawKey)
+	require.NoError(t, err)
+	assert.Nil(t, vr.RotationDue, "the period runs from the last rotation")
+}
+
+func TestRotationDue_WarningFraction(t *testing.T) {
+	clock := &fakeClock{t: time.Now()}
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithClock(clock.Now),
+		keysmith.WithRotationDueWarning(0.5))
+	require.NoError(t, err)
+	created := createRotatingKey(t, eng)
+
+	clock.Set(created.Key.CreatedAt.Add(5*day + time.Hour))
+	vr, err := eng.ValidateKey(testCtx(), created.RawKey)
+	require.NoError(t, err)
+	require.NotNil(t, vr.RotationDue)
+	assert.Equal(t, 4, vr.RotationDue.DaysRemaining)
+
+	plain := keysmithtest.NewKey(eng).MustCreate(t, testCtx())
+	vr, err = eng.ValidateKey(testCtx(), plain.RawKey)
+	require.NoError(t, err)
+	assert.Nil(t, vr.RotationDue, "a key without a rotation period gets no reminder")
+}
+
+func TestRotationDue_OverdueEventOncePerDay(t *testing.T) {
+	rec := &overdueRecorder{}
+	clock := &fakeClock{t: time.Now()}
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithClock(clock.Now),
+		keysmith.WithExtension(rec), keysmith.WithRotationOverdueEvents())
+	require.NoError(t, err)
+	created := createRotatingKey(t, eng)
+	validate := func(at time.Time) {
+		t.Helper()
+		clock.Set(at)
+		_, err := eng.ValidateKey(testCtx(), created.RawKey)
+		require.NoError(t, err)
+	}
+
+	validate(created.Key.CreatedAt.Add(9 * day))
+	assert.Zero(t, rec.count(), "a key due soon is not overdue")
+
+	overdue := created.Key.CreatedAt.Add(11 * day)
+	validate(overdue)
+	validate(overdue.Add(time.Hour))
+	validate(overdue.Add(23 * time.Hour))
+	assert.Equal(t, 1, rec.count())
+
+	validate(overdue.Add(day))
+	assert.Equal(t, 2, rec.count(), "the event repeats the next day")
+	assert.True(t, rec.due[1].Overdue)
+
+	quiet := &overdueRecorder{}
+	eng, err = keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithClock(clock.Now),
+		keysmith.WithExtension(quiet))
+	require.NoError(t, err)
+	created = createRotatingKey(t, eng)
+	validate(created.Key.CreatedAt.Add(11 * day))
+	assert.Zero(t, quiet.count(), "overdue events are off by default")
+}
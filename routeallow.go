@@ -0,0 +1,68 @@
+package keysmith
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/xraph/keysmith/policy"
+)
+
+// checkMethod rejects a validation whose request method is not in the
+// policy's AllowedMethods. Methods match ignoring case. A request without a
+// method is rejected when a list is set.
+func checkMethod(ctx context.Context, pol *policy.Policy) error {
+	if pol == nil || len(pol.AllowedMethods) == 0 {
+		return nil
+	}
+	method := ValidationRequestFromContext(ctx).Method
+	for _, m := range pol.AllowedMethods {
+		if method != "" && strings.EqualFold(m, method) {
+			return nil
+		}
+	}
+	return ErrMethodNotAllowed
+}
+
+// checkPath rejects a validation whose request path does not match the
+// policy's AllowedPaths. A request without a path is rejected when a list
+// is set.
+func checkPath(ctx context.Context, pol *policy.Policy) error {
+	if pol == nil || len(pol.AllowedPaths) == 0 {
+		return nil
+	}
+	p := ValidationRequestFromContext(ctx).Path
+	if p == "" || !pathAllowed(pol.AllowedPaths, path.Clean("/"+p)) {
+		return ErrPathNotAllowed
+	}
+	return nil
+}
+
+// pathAllowed reports whether the cleaned path p matches an entry of
+// allowed. An entry is a path.Match pattern, where "*" matches within one
+// segment, so "/v1/users/*" matches "/v1/users/42" but not
+// "/v1/users/42/keys". An entry ending in "/**" also matches everything
+// beneath it: "/v1/users/**" matches "/v1/users" and any path under it.
+func pathAllowed(allowed []string, p string) bool {
+	for _, entry := range allowed {
+		base, subtree := strings.CutSuffix(entry, "/**")
+		if subtree && base == "" {
+			return true
+		}
+		if ok, _ := path.Match(base, p); ok {
+			return true
+		}
+		if !subtree {
+			continue
+		}
+		for i := len(p) - 1; i > 0; i-- {
+			if p[i] != '/' {
+				continue
+			}
+			if ok, _ := path.Match(base, p[:i]); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package keysmith_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keysmithtest"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+func routeCtx(method, path string) context.Context {
+	return keysmith.WithValidationRequest(testCtx(), keysmith.ValidationRequest{Method: method, Path: path})
+}
+
+// createRouteKey returns a key under a new policy restricting it to methods
+// and paths.
+func createRouteKey(t *testing.T, eng *keysmith.Engine, methods, paths []string) *key.CreateResult {
+	t.Helper()
+	pol := &policy.Policy{Name: "Routes " + t.Name(), AllowedMethods: methods, AllowedPaths: paths}
+	require.NoError(t, eng.CreatePolicy(testCtx(), pol))
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+		Name:        "Route Key",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+		PolicyID:    &pol.ID,
+	})
+	require.NoError(t, err)
+	return created
+}
+
+func TestAllowedMethods(t *testing.T) {
+	eng := newTestEngine(t)
+	created := createRouteKey(t, eng, []string{"GET", "head"}, nil)
+
+	for _, m := range []string{"GET", "get", "HEAD"} {
+		_, err := eng.ValidateKey(routeCtx(m, "/v1/orders"), created.RawKey)
+		assert.NoError(t, err, m)
+	}
+	for _, m := range []string{"POST", "DELETE", "GETS", ""} {
+		_, err := eng.ValidateKey(routeCtx(m, "/v1/orders"), created.RawKey)
+		assert.ErrorIs(t, err, keysmith.ErrMethodNotAllowed, m)
+	}
+}
+
+func TestAllowedPaths(t *testing.T) {
+	eng := newTestEngine(t)
+	created := createRouteKey(t, eng, nil, []string{"/v1/users/*", "/v1/orders/**", "/status"})
+
+	for _, p := range []string{
+		"/v1/users/42",
+		"/v1/orders",
+		"/v1/orders/7/items/3",
+		"/status",
+		"/status/",
+		"//v1//users/42",
+	} {
+		_, err := eng.ValidateKey(routeCtx("GET", p), created.RawKey)
+		assert.NoError(t, err, p)
+	}
+	for _, p := range []string{
+		"/v1/users",
+		"/v1/users/42/keys",
+		"/v1/ordersx",
+		"/v1/users/42/../../admin",
+		"/admin",
+		"",
+	} {
+		_, err := eng.ValidateKey(routeCtx("GET", p), created.RawKey)
+		assert.ErrorIs(t, err, keysmith.ErrPathNotAllowed, p)
+	}
+
+	everything := createRouteKey(t, eng, nil, []string{"/**"})
+	_, err := eng.ValidateKey(routeCtx("GET", "/any/path"), everything.RawKey)
+	assert.NoError(t, err)
+}
+
+func TestAllowedRoutes_DistinctErrors(t *testing.T) {
+	eng := newTestEngine(t)
+	pol := &policy.Policy{
+		Name:           "Browser Reads",
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{"GET"},
+		AllowedPaths:   []string{"/v1/catalog/**"},
+	}
+	require.NoError(t, eng.CreatePolicy(testCtx(), pol))
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "Browser", Prefix: "pk", Environment: key.EnvTest, PolicyID: &pol.ID})
+	require.NoError(t, err)
+
+	validate := func(origin, method, path string) error {
+		ctx := keysmith.WithValidationRequest(testCtx(), keysmith.ValidationRequest{Origin: origin, Method: method, Path: path})
+		_, err := eng.ValidateKey(ctx, created.RawKey)
+		return err
+	}
+	assert.NoError(t, validate("https://app.example.com", "GET", "/v1/catalog/items"))
+	assert.ErrorIs(t, validate("https://evil.example", "GET", "/v1/catalog/items"), keysmith.ErrOriginNotAllowed)
+	assert.ErrorIs(t, validate("https://app.example.com", "POST", "/v1/catalog/items"), keysmith.ErrMethodNotAllowed)
+	assert.ErrorIs(t, validate("https://app.example.com", "GET", "/v1/admin"), keysmith.ErrPathNotAllowed)
+}
+
+func TestAllowedRoutes_FireValidationFailed(t *testing.T) {
+	rec := keysmithtest.NewRecorder()
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithExtension(rec))
+	require.NoError(t, err)
+	created := createRouteKey(t, eng, []string{"GET"}, []string{"/v1/**"})
+
+	_, err = eng.ValidateKey(routeCtx("POST", "/v1/orders"), created.RawKey)
+	require.ErrorIs(t, err, keysmith.ErrMethodNotAllowed)
+	_, err = eng.ValidateKey(routeCtx("GET", "/admin"), created.RawKey)
+	require.ErrorIs(t, err, keysmith.ErrPathNotAllowed)
+
+	evts := rec.Filter("KeyValidationFailed")
+	require.Len(t, evts, 2)
+	assert.ErrorIs(t, evts[0].Err, keysmith.ErrMethodNotAllowed)
+	assert.ErrorIs(t, evts[1].Err, keysmith.ErrPathNotAllowed)
+	for _, evt := range evts {
+		assert.Equal(t, plugin.ReasonRouteNotAllowed, evt.Meta.ReasonCode)
+		assert.NotEmpty(t, evt.Fingerprint)
+	}
+}
+
+func TestAllowedRoutes_InvalidEntriesRejected(t *testing.T) {
+	eng := newTestEngine(t)
+	for name, pol := range map[string]*policy.Policy{
+		"empty method":    {AllowedMethods: []string{""}},
+		"method w/ space": {AllowedMethods: []string{"GET POST"}},
+		"relative path":   {AllowedPaths: []string{"v1/users"}},
+		"bad pattern":     {AllowedPaths: []string{"/v1/[users"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			pol.Name = "Bad " + name
+			assert.ErrorIs(t, eng.CreatePolicy(testCtx(), pol), keysmith.ErrInvalidPolicy)
+		})
+	}
+
+	pol := &policy.Policy{Name: "Routes", AllowedMethods: []string{"GET"}, AllowedPaths: []string{"/v1/**"}}
+	require.NoError(t, eng.CreatePolicy(testCtx(), pol))
+	pol.AllowedPaths = []string{"/v1/[orders"}
+	err := eng.UpdatePolicy(testCtx(), pol)
+	assert.ErrorIs(t, err, keysmith.ErrInvalidPolicy)
+	assert.ErrorContains(t, err, "allowed_paths")
+}
@@ -0,0 +1,219 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/xraph/keysmith/plugin"
+)
+
+// MaxRuntimeDuration bounds every duration in a [RuntimeConfig].
+const MaxRuntimeDuration = 24 * time.Hour
+
+// RuntimeConfig is the subset of engine settings that can change while the
+// engine runs, with [Engine.UpdateRuntimeConfig]. NewEngine seeds it from
+// the options; a zero field is a feature that is off, which cannot be
+// turned on at runtime. Changes are not persisted: a restarted engine is
+// back to its options.
+type RuntimeConfig struct {
+	// ValidationCacheTTL is how long validation snapshots are cached. Set
+	// by WithValidationCache; new entries use the current value.
+	ValidationCacheTTL time.Duration `json:"validation_cache_ttl"`
+
+	// EndpointFlushInterval is how often buffered endpoint activity is
+	// written to the store. Set by WithEndpointActivity.
+	EndpointFlushInterval time.Duration `json:"endpoint_flush_interval"`
+
+	// CapturePurgeInterval is how often expired debug captures are
+	// deleted. Defaults to [DefaultCapturePurgeInterval].
+	CapturePurgeInterval time.Duration `json:"capture_purge_interval"`
+
+	// MaintenanceInterval is how often store maintenance runs. Set by
+	// WithStoreMaintenance.
+	MaintenanceInterval time.Duration `json:"maintenance_interval"`
+
+	// QuotaForecastInterval is how often quota forecasts are evaluated.
+	// Set by WithQuotaForecastWarnings.
+	QuotaForecastInterval time.Duration `json:"quota_forecast_interval"`
+
+	// FailureThreshold and FailureWindow are how many validation failures
+	// of one fingerprint, within the window, fire the
+	// SuspiciousValidationPattern hook. Set by WithFailureFingerprinting.
+	FailureThreshold int           `json:"failure_threshold"`
+	FailureWindow    time.Duration `json:"failure_window"`
+
+	// UsageSampleRate is the 1-in-N rate of sampled usage recording modes
+	// without a rate of their own. It is the SampleRate of the recording
+	// policy, which SetUsageRecording changes too.
+	UsageSampleRate int `json:"usage_sample_rate"`
+}
+
+// RuntimeConfigPatch changes some fields of a [RuntimeConfig]. Nil fields
+// are left as they are.
+type RuntimeConfigPatch struct {
+	ValidationCacheTTL    *time.Duration `json:"validation_cache_ttl,omitempty"`
+	EndpointFlushInterval *time.Duration `json:"endpoint_flush_interval,omitempty"`
+	CapturePurgeInterval  *time.Duration `json:"capture_purge_interval,omitempty"`
+	MaintenanceInterval   *time.Duration `json:"maintenance_interval,omitempty"`
+	QuotaForecastInterval *time.Duration `json:"quota_forecast_interval,omitempty"`
+	FailureThreshold      *int           `json:"failure_threshold,omitempty"`
+	FailureWindow         *time.Duration `json:"failure_window,omitempty"`
+	UsageSampleRate       *int           `json:"usage_sample_rate,omitempty"`
+}
+
+// runtimeField is one RuntimeConfig field, for diffing.
+type runtimeField struct {
+	name  string
+	value func(c *RuntimeConfig) string
+}
+
+var runtimeFields = []runtimeField{
+	{"validation_cache_ttl", func(c *RuntimeConfig) string { return c.ValidationCacheTTL.String() }},
+	{"endpoint_flush_interval", func(c *RuntimeConfig) string { return c.EndpointFlushInterval.String() }},
+	{"capture_purge_interval", func(c *RuntimeConfig) string { return c.CapturePurgeInterval.String() }},
+	{"maintenance_interval", func(c *RuntimeConfig) string { return c.MaintenanceInterval.String() }},
+	{"quota_forecast_interval", func(c *RuntimeConfig) string { return c.QuotaForecastInterval.String() }},
+	{"failure_threshold", func(c *RuntimeConfig) string { return strconv.Itoa(c.FailureThreshold) }},
+	{"failure_window", func(c *RuntimeConfig) string { return c.FailureWindow.String() }},
+	{"usage_sample_rate", func(c *RuntimeConfig) string { return strconv.Itoa(c.UsageSampleRate) }},
+}
+
+// initRuntimeConfig seeds the runtime configuration from the options.
+func (e *Engine) initRuntimeConfig() {
+	cfg := &RuntimeConfig{CapturePurgeInterval: max(e.purger.interval, 0)}
+	if e.cache != nil {
+		cfg.ValidationCacheTTL = e.cache.ttl
+	}
+	if e.endpoints != nil {
+		cfg.EndpointFlushInterval = max(e.endpoints.interval, 0)
+	}
+	cfg.MaintenanceInterval = max(e.maintenance.interval, 0)
+	cfg.QuotaForecastInterval = max(e.quotaForecasts.interval, 0)
+	if e.failures != nil {
+		cfg.FailureThreshold = e.failures.threshold
+		cfg.FailureWindow = e.failures.window
+	}
+	e.runtime.Store(cfg)
+}
+
+// runtimeConfig returns the current runtime configuration. Callers must not
+// modify it.
+func (e *Engine) runtimeConfig() *RuntimeConfig {
+	return e.runtime.Load()
+}
+
+// GetRuntimeConfig returns the runtime configuration in effect.
+func (e *Engine) GetRuntimeConfig() RuntimeConfig {
+	cfg := *e.runtimeConfig()
+	cfg.UsageSampleRate = e.UsageRecording().SampleRate
+	return cfg
+}
+
+// UpdateRuntimeConfig applies patch to the runtime configuration of this
+// engine, for every tenant. Background workers restart their wait with the
+// new interval, the validation cache uses a new TTL for the entries it
+// stores next, and failure fingerprinting counts new failures against the
+// new limits.
+//
+// Durations must be positive and at most [MaxRuntimeDuration], and counts
+// positive. A setting whose feature is off cannot be changed, since that
+// would not turn the feature on. An invalid patch returns
+// [ErrInvalidRuntimeConfig] and changes nothing. A patch that changes
+// anything fires the RuntimeConfigChanged hook with each setting's old and
+// new value. A dry run validates the patch and returns the configuration it
+// would produce.
+//
+// The change is not persisted: a restarted engine is back to its options,
+// so persist the hook's configuration to keep a change.
+func (e *Engine) UpdateRuntimeConfig(ctx context.Context, patch *RuntimeConfigPatch) (RuntimeConfig, error) {
+	e.runtimeMu.Lock()
+	defer e.runtimeMu.Unlock()
+
+	before := e.GetRuntimeConfig()
+	if patch == nil {
+		return before, nil
+	}
+	after, err := applyRuntimePatch(before, patch)
+	if err != nil {
+		return before, err
+	}
+	if IsDryRun(ctx) {
+		return after, nil
+	}
+
+	if after.UsageSampleRate != before.UsageSampleRate {
+		p := e.UsageRecording()
+		p.SampleRate = after.UsageSampleRate
+		if err := e.SetUsageRecording(p); err != nil {
+			return before, fmt.Errorf("%w: usage_sample_rate: %w", ErrInvalidRuntimeConfig, err)
+		}
+	}
+	next := after
+	e.runtime.Store(&next)
+	for _, j := range []*periodicJob{e.purger, e.maintenance, e.quotaForecasts} {
+		j.reschedule()
+	}
+	if e.endpoints != nil && e.endpoints.flusher != nil {
+		e.endpoints.flusher.reschedule()
+	}
+
+	change := &plugin.ConfigChange{Config: make(map[string]string, len(runtimeFields))}
+	for _, f := range runtimeFields {
+		old, cur := f.value(&before), f.value(&after)
+		if old != cur {
+			change.Changes = append(change.Changes, plugin.ConfigFieldChange{Field: f.name, Before: old, After: cur})
+		}
+		change.Config[f.name] = cur
+	}
+	if len(change.Changes) > 0 {
+		_ = e.hooks.FireRuntimeConfigChanged(ctx, change, e.eventMeta(ctx, plugin.TriggerManual, plugin.ReasonConfigChange))
+	}
+	return after, nil
+}
+
+// applyRuntimePatch returns cfg with patch applied, or an error for a value
+// out of range or a setting whose feature is off.
+func applyRuntimePatch(cfg RuntimeConfig, patch *RuntimeConfigPatch) (RuntimeConfig, error) {
+	durations := []struct {
+		name string
+		from *time.Duration
+		to   *time.Duration
+	}{
+		{"validation_cache_ttl", patch.ValidationCacheTTL, &cfg.ValidationCacheTTL},
+		{"endpoint_flush_interval", patch.EndpointFlushInterval, &cfg.EndpointFlushInterval},
+		{"capture_purge_interval", patch.CapturePurgeInterval, &cfg.CapturePurgeInterval},
+		{"maintenance_interval", patch.MaintenanceInterval, &cfg.MaintenanceInterval},
+		{"quota_forecast_interval", patch.QuotaForecastInterval, &cfg.QuotaForecastInterval},
+		{"failure_window", patch.FailureWindow, &cfg.FailureWindow},
+	}
+	for _, d := range durations {
+		if d.from == nil {
+			continue
+		}
+		if *d.to == 0 {
+			return cfg, fmt.Errorf("%w: %s: the feature is off", ErrInvalidRuntimeConfig, d.name)
+		}
+		if *d.from <= 0 || *d.from > MaxRuntimeDuration {
+			return cfg, fmt.Errorf("%w: %s: %s is not between 0 and %s", ErrInvalidRuntimeConfig, d.name, *d.from, MaxRuntimeDuration)
+		}
+		*d.to = *d.from
+	}
+	if patch.FailureThreshold != nil {
+		if cfg.FailureThreshold == 0 {
+			return cfg, fmt.Errorf("%w: failure_threshold: the feature is off", ErrInvalidRuntimeConfig)
+		}
+		if *patch.FailureThreshold <= 0 {
+			return cfg, fmt.Errorf("%w: failure_threshold: %d is not positive", ErrInvalidRuntimeConfig, *patch.FailureThreshold)
+		}
+		cfg.FailureThreshold = *patch.FailureThreshold
+	}
+	if patch.UsageSampleRate != nil {
+		if *patch.UsageSampleRate <= 0 {
+			return cfg, fmt.Errorf("%w: usage_sample_rate: %d is not positive", ErrInvalidRuntimeConfig, *patch.UsageSampleRate)
+		}
+		cfg.UsageSampleRate = *patch.UsageSampleRate
+	}
+	return cfg, nil
+}
@@ -0,0 +1,175 @@
+package keysmith_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keysmithtest"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/store/memory"
+	"github.com/xraph/keysmith/usage"
+)
+
+func durationPtr(d time.Duration) *time.Duration { return &d }
+
+func intPtr(n int) *int { return &n }
+
+func TestRuntimeConfig_SeededFromOptions(t *testing.T) {
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithValidationCache(45*time.Second, 0),
+		keysmith.WithEndpointActivity(10, 5*time.Second),
+		keysmith.WithFailureFingerprinting(7, time.Minute),
+		keysmith.WithUsageRecording(usage.RecordingPolicy{Default: usage.RecordSampled, SampleRate: 10}),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, keysmith.RuntimeConfig{
+		ValidationCacheTTL:    45 * time.Second,
+		EndpointFlushInterval: 5 * time.Second,
+		CapturePurgeInterval:  keysmith.DefaultCapturePurgeInterval,
+		FailureThreshold:      7,
+		FailureWindow:         time.Minute,
+		UsageSampleRate:       10,
+	}, eng.GetRuntimeConfig(), "features that are off read zero")
+}
+
+func TestUpdateRuntimeConfig(t *testing.T) {
+	rec := keysmithtest.NewRecorder()
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithExtension(rec),
+		keysmith.WithValidationCache(30*time.Second, 0),
+	)
+	require.NoError(t, err)
+
+	cfg, err := eng.UpdateRuntimeConfig(keysmith.WithActor(adminCtx(), "ops"), &keysmith.RuntimeConfigPatch{
+		ValidationCacheTTL: durationPtr(time.Minute),
+		FailureThreshold:   intPtr(50),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, cfg.ValidationCacheTTL)
+	assert.Equal(t, 50, cfg.FailureThreshold)
+	assert.Equal(t, cfg, eng.GetRuntimeConfig())
+
+	events := rec.Filter("RuntimeConfigChanged")
+	require.Len(t, events, 1)
+	assert.Equal(t, plugin.ReasonConfigChange, events[0].Meta.ReasonCode)
+	assert.Equal(t, "ops", events[0].Meta.ActorID)
+	assert.Equal(t, []plugin.ConfigFieldChange{
+		{Field: "validation_cache_ttl", Before: "30s", After: "1m0s"},
+		{Field: "failure_threshold", Before: "20", After: "50"},
+	}, events[0].ConfigChange.Changes)
+	assert.Equal(t, "1m0s", events[0].ConfigChange.Config["validation_cache_ttl"])
+	assert.Equal(t, "10m0s", events[0].ConfigChange.Config["capture_purge_interval"])
+
+	_, err = eng.UpdateRuntimeConfig(adminCtx(), &keysmith.RuntimeConfigPatch{ValidationCacheTTL: durationPtr(time.Minute)})
+	require.NoError(t, err)
+	assert.Len(t, rec.Filter("RuntimeConfigChanged"), 1, "a patch that changes nothing fires no hook")
+}
+
+func TestUpdateRuntimeConfig_Validation(t *testing.T) {
+	rec := keysmithtest.NewRecorder()
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithExtension(rec))
+	require.NoError(t, err)
+	before := eng.GetRuntimeConfig()
+
+	for name, patch := range map[string]*keysmith.RuntimeConfigPatch{
+		"negative":          {CapturePurgeInterval: durationPtr(-time.Second)},
+		"zero":              {FailureWindow: durationPtr(0)},
+		"too long":          {CapturePurgeInterval: durationPtr(48 * time.Hour)},
+		"zero threshold":    {FailureThreshold: intPtr(0)},
+		"zero sample rate":  {UsageSampleRate: intPtr(0)},
+		"cache off":         {ValidationCacheTTL: durationPtr(time.Minute)},
+		"maintenance off":   {MaintenanceInterval: durationPtr(time.Hour)},
+		"one bad, one good": {CapturePurgeInterval: durationPtr(time.Minute), FailureThreshold: intPtr(-1)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := eng.UpdateRuntimeConfig(adminCtx(), patch)
+			assert.ErrorIs(t, err, keysmith.ErrInvalidRuntimeConfig)
+			assert.Equal(t, before, eng.GetRuntimeConfig(), "an invalid patch changes nothing")
+		})
+	}
+
+	cfg, err := eng.UpdateRuntimeConfig(keysmith.WithDryRun(adminCtx()), &keysmith.RuntimeConfigPatch{CapturePurgeInterval: durationPtr(time.Minute)})
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, cfg.CapturePurgeInterval)
+	assert.Equal(t, before, eng.GetRuntimeConfig(), "a dry run changes nothing")
+
+	assert.Empty(t, rec.Filter("RuntimeConfigChanged"))
+}
+
+func TestUpdateRuntimeConfig_FlusherPicksUpInterval(t *testing.T) {
+	ms := memory.New()
+	eng, err := keysmith.NewEngine(keysmith.WithStore(ms), keysmith.WithEndpointActivity(10, time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, eng.Start(context.Background()))
+	t.Cleanup(func() { _ = eng.Stop(context.Background()) })
+
+	result, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "Flush", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	kid := result.Key.ID
+	require.NoError(t, eng.RecordUsage(testCtx(), &usage.Record{KeyID: kid, Method: "GET", Endpoint: "/v1/users", StatusCode: 200}))
+
+	stored, err := ms.Usages().ListEndpointActivity(context.Background(), kid)
+	require.NoError(t, err)
+	require.Empty(t, stored, "the hourly flusher has not run")
+
+	_, err = eng.UpdateRuntimeConfig(adminCtx(), &keysmith.RuntimeConfigPatch{EndpointFlushInterval: durationPtr(10 * time.Millisecond)})
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		stored, err := ms.Usages().ListEndpointActivity(context.Background(), kid)
+		return err == nil && len(stored) == 1
+	}, time.Second, 5*time.Millisecond, "the flusher runs on the new interval")
+}
+
+func TestUpdateRuntimeConfig_CacheTTL(t *testing.T) {
+	ms := memory.New()
+	eng, err := keysmith.NewEngine(keysmith.WithStore(ms), keysmith.WithValidationCache(time.Hour, 0))
+	require.NoError(t, err)
+	result, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "Cache", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+
+	_, err = eng.UpdateRuntimeConfig(adminCtx(), &keysmith.RuntimeConfigPatch{ValidationCacheTTL: durationPtr(time.Millisecond)})
+	require.NoError(t, err)
+
+	_, err = eng.ValidateKey(testCtx(), result.RawKey)
+	require.NoError(t, err)
+	// A change made behind the engine's back is seen once the entry expires.
+	require.NoError(t, ms.Keys().UpdateState(context.Background(), result.Key.ID, key.StateSuspended))
+	time.Sleep(5 * time.Millisecond)
+
+	_, err = eng.ValidateKey(testCtx(), result.RawKey)
+	assert.ErrorIs(t, err, keysmith.ErrKeyInactive)
+}
+
+func TestUpdateRuntimeConfig_FailureThreshold(t *testing.T) {
+	eng, rec := newFingerprintEngine(t, 100, time.Minute)
+
+	_, err := eng.UpdateRuntimeConfig(adminCtx(), &keysmith.RuntimeConfigPatch{FailureThreshold: intPtr(3)})
+	require.NoError(t, err)
+
+	failValidation(t, eng, "sk_live_0123456789abcdef", 3)
+	require.Len(t, rec.patterns, 1)
+	assert.Equal(t, 3, rec.patterns[0].Count)
+}
+
+func TestUpdateRuntimeConfig_UsageSampleRate(t *testing.T) {
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithUsageRecording(usage.RecordingPolicy{Default: usage.RecordSampled, SampleRate: 10}),
+	)
+	require.NoError(t, err)
+
+	_, err = eng.UpdateRuntimeConfig(adminCtx(), &keysmith.RuntimeConfigPatch{UsageSampleRate: intPtr(100)})
+	require.NoError(t, err)
+	assert.Equal(t, 100, eng.UsageRecording().SampleRate)
+	assert.Equal(t, usage.RecordSampled, eng.UsageRecording().Default)
+}
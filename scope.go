@@ -2,8 +2,8 @@ package keysmith
 
 import (
 	"context"
-
-	"github.com/xraph/forge"
+	"sync"
+	"sync/atomic"
 )
 
 type tenantScope struct {
@@ -13,6 +13,35 @@ type tenantScope struct {
 
 type ctxKeyApp struct{}
 type ctxKeyTenant struct{}
+type ctxKeyActor struct{}
+type ctxKeyRequestID struct{}
+
+// ScopeResolver reports the app and tenant a host framework has scoped ctx
+// to, and whether it has. See [RegisterScopeResolver].
+type ScopeResolver func(ctx context.Context) (appID, tenantID string, ok bool)
+
+var (
+	scopeResolversMu sync.Mutex
+	scopeResolvers   atomic.Pointer[[]ScopeResolver]
+)
+
+// RegisterScopeResolver adds a resolver for a framework's request scope.
+// Resolvers are asked in registration order, and the first that reports a
+// scope takes precedence over [WithTenant]. The api package registers one for
+// forge.Scope. It is typically called from an init function.
+func RegisterScopeResolver(r ScopeResolver) {
+	if r == nil {
+		panic("keysmith: scope resolver is nil")
+	}
+	scopeResolversMu.Lock()
+	defer scopeResolversMu.Unlock()
+	var rs []ScopeResolver
+	if cur := scopeResolvers.Load(); cur != nil {
+		rs = append(rs, *cur...)
+	}
+	rs = append(rs, r)
+	scopeResolvers.Store(&rs)
+}
 
 // WithTenant sets the tenant scope on the context for standalone usage
 // (without Forge). This is the non-Forge equivalent of forge.Scope.
@@ -22,14 +51,15 @@ func WithTenant(ctx context.Context, appID, tenantID string) context.Context {
 	return ctx
 }
 
-// scopeFromContext extracts tenant scope from forge.Scope or standalone context.
-// Falls back to explicit tenant if Forge scope is not set (standalone mode).
+// scopeFromContext extracts tenant scope from a registered resolver, such as
+// forge.Scope, or standalone context. Falls back to explicit tenant if no
+// resolver reports a scope (standalone mode).
 func scopeFromContext(ctx context.Context) tenantScope {
-	fscope, ok := forge.ScopeFrom(ctx)
-	if ok {
-		return tenantScope{
-			appID:    fscope.AppID(),
-			tenantID: fscope.OrgID(),
+	if rs := scopeResolvers.Load(); rs != nil {
+		for _, r := range *rs {
+			if appID, tenantID, ok := r(ctx); ok {
+				return tenantScope{appID: appID, tenantID: tenantID}
+			}
 		}
 	}
 	return tenantScope{
@@ -47,3 +77,35 @@ func tenantIDFromContext(ctx context.Context) string {
 	v, _ := ctx.Value(ctxKeyTenant{}).(string)
 	return v
 }
+
+// AppIDFromContext returns the app the context is scoped to, from a
+// [ScopeResolver] or [WithTenant].
+func AppIDFromContext(ctx context.Context) string { return scopeFromContext(ctx).appID }
+
+// TenantIDFromContext returns the tenant the context is scoped to, from a
+// [ScopeResolver] or [WithTenant].
+func TenantIDFromContext(ctx context.Context) string { return scopeFromContext(ctx).tenantID }
+
+// WithActor records who is making the request, such as a user or service
+// ID, for validators and hooks to read with [ActorFromContext].
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, ctxKeyActor{}, actor)
+}
+
+// ActorFromContext returns the actor set with [WithActor], or "".
+func ActorFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(ctxKeyActor{}).(string)
+	return v
+}
+
+// WithRequestID records the ID of the request being served, for hooks to
+// read from [plugin.EventMeta].
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, ctxKeyRequestID{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID set with [WithRequestID], or "".
+func RequestIDFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(ctxKeyRequestID{}).(string)
+	return v
+}
@@ -17,13 +17,27 @@ type Scope struct {
 	Description string         `json:"description,omitempty" db:"description"`
 	Parent      string         `json:"parent,omitempty" db:"parent"`
 	Metadata    map[string]any `json:"metadata,omitempty" db:"metadata"`
-	CreatedAt   time.Time      `json:"created_at" db:"created_at"`
+
+	// RateLimit and RateLimitWindow cap the requests each key may make
+	// under this scope, on top of the key's and tenant's own limits, for
+	// scopes guarding expensive operations. A zero RateLimit means no
+	// scope limit. The limit applies where a route requires the scope,
+	// such as with middleware.RequireScopes.
+	RateLimit       int           `json:"rate_limit,omitempty" db:"rate_limit"`
+	RateLimitWindow time.Duration `json:"rate_limit_window,omitempty" db:"rate_limit_window"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
 }
 
 // ListFilter contains filters for listing scopes.
 type ListFilter struct {
 	TenantID string `json:"tenant_id,omitempty"`
+	AppID    string `json:"app_id,omitempty"`
 	Parent   string `json:"parent,omitempty"`
-	Limit    int    `json:"limit,omitempty"`
-	Offset   int    `json:"offset,omitempty"`
+
+	// CreatedAfter restricts the results to scopes created after this time.
+	CreatedAfter *time.Time `json:"created_after,omitempty"`
+
+	Limit  int `json:"limit,omitempty"`
+	Offset int `json:"offset,omitempty"`
 }
@@ -15,6 +15,11 @@ type Store interface {
 	Delete(ctx context.Context, scopeID id.ScopeID) error
 	List(ctx context.Context, filter *ListFilter) ([]*Scope, error)
 	ListByKey(ctx context.Context, keyID id.KeyID) ([]*Scope, error)
+
+	// AssignToKey and RemoveFromKey also bump the key's UpdatedAt, so scope
+	// changes surface in key.ListFilter.UpdatedAfter queries. AssignToKey
+	// assigns all of scopeNames or, if one is not a scope of the key's
+	// tenant, none of them.
 	AssignToKey(ctx context.Context, keyID id.KeyID, scopeNames []string) error
 	RemoveFromKey(ctx context.Context, keyID id.KeyID, scopeNames []string) error
 }
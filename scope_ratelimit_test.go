@@ -0,0 +1,129 @@
+package keysmith_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+type scopeLimitRecorder struct{ metas []plugin.EventMeta }
+
+func (r *scopeLimitRecorder) Name() string { return "scope-limit-recorder" }
+
+func (r *scopeLimitRecorder) OnKeyRateLimitedV2(_ context.Context, _ *key.Key, meta plugin.EventMeta) error {
+	r.metas = append(r.metas, meta)
+	return nil
+}
+
+// newScopeLimitEngine returns an engine with an "export" scope allowing 2
+// requests per key, a "bulk" scope allowing 3, and an unlimited "read"
+// scope.
+func newScopeLimitEngine(t *testing.T) (*keysmith.Engine, *countingLimiter, *scopeLimitRecorder) {
+	t.Helper()
+	limiter := newCountingLimiter()
+	rec := &scopeLimitRecorder{}
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithRateLimiter(limiter),
+		keysmith.WithExtension(rec),
+	)
+	require.NoError(t, err)
+
+	for _, s := range []*scope.Scope{
+		{Name: "export", RateLimit: 2, RateLimitWindow: time.Minute},
+		{Name: "bulk", RateLimit: 3, RateLimitWindow: time.Minute},
+		{Name: "read"},
+	} {
+		require.NoError(t, eng.CreateScope(testCtx(), s))
+	}
+	return eng, limiter, rec
+}
+
+func validateScopedKey(t *testing.T, eng *keysmith.Engine) *keysmith.ValidationResult {
+	t.Helper()
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+		Name:        "Scoped",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+		Scopes:      []string{"export", "bulk", "read"},
+	})
+	require.NoError(t, err)
+	vr, err := eng.ValidateKey(testCtx(), created.RawKey)
+	require.NoError(t, err)
+	return vr
+}
+
+func TestScopeRateLimit_IndependentBuckets(t *testing.T) {
+	eng, limiter, _ := newScopeLimitEngine(t)
+	vr := validateScopedKey(t, eng)
+
+	for i := range 2 {
+		infos, err := eng.CheckScopeRateLimits(testCtx(), vr, "export")
+		require.NoError(t, err, "export request %d", i)
+		require.Len(t, infos, 1)
+		assert.Equal(t, keysmith.ScopeRateLimitInfo{Scope: "export", Limit: 2, Remaining: 1 - i, Window: time.Minute}, infos[0])
+	}
+	_, err := eng.CheckScopeRateLimits(testCtx(), vr, "export")
+	require.ErrorIs(t, err, keysmith.ErrScopeRateLimited)
+
+	// The exhausted export budget leaves bulk's untouched.
+	for i := range 3 {
+		infos, err := eng.CheckScopeRateLimits(testCtx(), vr, "bulk")
+		require.NoError(t, err, "bulk request %d", i)
+		assert.Equal(t, 2-i, infos[0].Remaining)
+	}
+
+	// Each key has its own budget under a scope.
+	other := validateScopedKey(t, eng)
+	_, err = eng.CheckScopeRateLimits(testCtx(), other, "export")
+	require.NoError(t, err)
+
+	for _, b := range limiter.buckets() {
+		assert.Regexp(t, `^scope:akey_\w+:(export|bulk)$`, b)
+	}
+}
+
+func TestScopeRateLimit_UnlimitedScopes(t *testing.T) {
+	eng, limiter, _ := newScopeLimitEngine(t)
+	vr := validateScopedKey(t, eng)
+
+	for range 10 {
+		infos, err := eng.CheckScopeRateLimits(testCtx(), vr, "read", "write")
+		require.NoError(t, err)
+		assert.Nil(t, infos)
+	}
+	assert.Empty(t, limiter.buckets(), "scopes without a limit spend no budget")
+}
+
+func TestScopeRateLimit_ErrorNamesScope(t *testing.T) {
+	eng, _, rec := newScopeLimitEngine(t)
+	vr := validateScopedKey(t, eng)
+
+	for range 2 {
+		_, err := eng.CheckScopeRateLimits(testCtx(), vr, "bulk", "export")
+		require.NoError(t, err)
+	}
+	_, err := eng.CheckScopeRateLimits(testCtx(), vr, "bulk", "export")
+	require.ErrorIs(t, err, keysmith.ErrScopeRateLimited)
+	assert.NotErrorIs(t, err, keysmith.ErrRateLimited, "the scope error is distinct from the key error")
+	assert.ErrorContains(t, err, ": export")
+
+	require.Len(t, rec.metas, 1)
+	assert.Equal(t, plugin.ReasonScopeRateLimited, rec.metas[0].ReasonCode)
+	assert.Equal(t, "export", rec.metas[0].Scope)
+}
+
+func TestScopeRateLimit_RequiresWindow(t *testing.T) {
+	eng, _, _ := newScopeLimitEngine(t)
+	err := eng.CreateScope(testCtx(), &scope.Scope{Name: "admin", RateLimit: 5})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidScope)
+}
@@ -0,0 +1,45 @@
+package keysmith_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+)
+
+// hostScope is a framework request scope, as a resolver would read it.
+type hostScope struct{ appID, tenantID string }
+
+type hostScopeKey struct{}
+
+func init() {
+	keysmith.RegisterScopeResolver(func(ctx context.Context) (string, string, bool) {
+		s, ok := ctx.Value(hostScopeKey{}).(hostScope)
+		return s.appID, s.tenantID, ok
+	})
+}
+
+func TestRegisterScopeResolver(t *testing.T) {
+	eng := newTestEngine(t)
+	host := context.WithValue(context.Background(), hostScopeKey{}, hostScope{"app_host", "tenant_host"})
+	assert.Equal(t, "app_host", keysmith.AppIDFromContext(host))
+	assert.Equal(t, "tenant_host", keysmith.TenantIDFromContext(host))
+
+	// The framework's scope wins over WithTenant.
+	both := keysmith.WithTenant(host, "app_test", "tenant_test")
+	created, err := eng.CreateKey(both, &keysmith.CreateKeyInput{Name: "Host", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	assert.Equal(t, "tenant_host", created.Key.TenantID)
+	assert.Equal(t, "app_host", created.Key.AppID)
+
+	// Without one, WithTenant applies.
+	assert.Equal(t, "tenant_test", keysmith.TenantIDFromContext(testCtx()))
+}
+
+func TestRegisterScopeResolver_Nil(t *testing.T) {
+	assert.Panics(t, func() { keysmith.RegisterScopeResolver(nil) })
+}
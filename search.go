@@ -0,0 +1,56 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xraph/keysmith/key"
+)
+
+const (
+	// MaxSearchTerms is the most terms and quoted phrases one SearchKeys
+	// query may have.
+	MaxSearchTerms = 16
+
+	// DefaultSearchLimit is the number of matches SearchKeys returns for a
+	// zero or negative SearchOptions.Limit.
+	DefaultSearchLimit = 20
+)
+
+// SearchOptions narrows a SearchKeys call.
+type SearchOptions struct {
+	// TenantID and AppID restrict the search like a ListKeys filter, and
+	// are narrowed to the context's scope the same way.
+	TenantID string
+	AppID    string
+
+	// Limit caps the matches returned. Zero is DefaultSearchLimit, and it
+	// is clamped to the max page size.
+	Limit int
+}
+
+// SearchKeys finds the keys whose name, description, or metadata values
+// mention every term of query, best match first. A query is words and
+// double-quoted phrases; words match whole and ignoring case, and a word
+// with punctuation such as acme-mobile matches as the phrase "acme mobile".
+// Every store returns the same matches, fields, and order for a query.
+//
+// A search is always confined to one tenant: the context's, or
+// opts.TenantID for an unscoped context. It fails with
+// ErrInvalidSearchQuery for a query without words, one with more than
+// MaxSearchTerms terms, or one without a tenant.
+func (e *Engine) SearchKeys(ctx context.Context, query string, opts SearchOptions) ([]*key.SearchMatch, error) {
+	terms := key.ParseSearchTerms(query)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("%w: the query has no words", ErrInvalidSearchQuery)
+	}
+	if len(terms) > MaxSearchTerms {
+		return nil, fmt.Errorf("%w: %d terms, at most %d are allowed", ErrInvalidSearchQuery, len(terms), MaxSearchTerms)
+	}
+	q := &key.SearchQuery{Terms: terms, Limit: e.PageLimit(opts.Limit, DefaultSearchLimit)}
+	q.TenantID, q.AppID = scopeFromContext(ctx).narrow(opts.TenantID, opts.AppID)
+	if q.TenantID == "" {
+		return nil, fmt.Errorf("%w: a search needs a tenant", ErrInvalidSearchQuery)
+	}
+	return e.store.Keys().Search(ctx, q)
+}
@@ -0,0 +1,76 @@
+package keysmith_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+)
+
+func createSearchableKey(t *testing.T, eng *keysmith.Engine, ctx context.Context, name, description string, metadata map[string]any) *key.Key {
+	t.Helper()
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
+		Name: name, Description: description, Prefix: "sk", Environment: key.EnvTest, Metadata: metadata,
+	})
+	require.NoError(t, err)
+	return created.Key
+}
+
+func searchNames(t *testing.T, eng *keysmith.Engine, ctx context.Context, query string, opts keysmith.SearchOptions) []string {
+	t.Helper()
+	matches, err := eng.SearchKeys(ctx, query, opts)
+	require.NoError(t, err)
+	out := make([]string, len(matches))
+	for i, m := range matches {
+		out[i] = m.Key.Name
+	}
+	return out
+}
+
+func TestSearchKeys(t *testing.T) {
+	eng := newTestEngine(t)
+	createSearchableKey(t, eng, testCtx(), "Acme Mobile", "", nil)
+	createSearchableKey(t, eng, testCtx(), "Billing", "Used by the acme-mobile app", map[string]any{"customer": "Acme"})
+	createSearchableKey(t, eng, testCtx(), "Worker", "", map[string]any{"notes": "ACME mobile on call"})
+
+	assert.Equal(t, []string{"Acme Mobile", "Billing", "Worker"}, searchNames(t, eng, testCtx(), "acme-mobile", keysmith.SearchOptions{}))
+	assert.Equal(t, []string{"Acme Mobile"}, searchNames(t, eng, testCtx(), "acme-mobile", keysmith.SearchOptions{Limit: 1}))
+	assert.Equal(t, []string{"Billing"}, searchNames(t, eng, testCtx(), `"mobile app" acme`, keysmith.SearchOptions{}))
+	assert.Empty(t, searchNames(t, eng, testCtx(), `"app mobile"`, keysmith.SearchOptions{}))
+	assert.Empty(t, searchNames(t, eng, testCtx(), "mob", keysmith.SearchOptions{}), "words match whole")
+
+	matches, err := eng.SearchKeys(testCtx(), "acme", keysmith.SearchOptions{})
+	require.NoError(t, err)
+	require.Len(t, matches, 3)
+	assert.Equal(t, "Billing", matches[0].Key.Name, "equal scores rank the newest key first")
+	assert.Equal(t, []string{"description", "metadata.customer"}, matches[0].Fields)
+	assert.Equal(t, 3, matches[0].Score)
+	assert.Equal(t, "Used by the acme-mobile app", matches[0].Snippet)
+}
+
+func TestSearchKeys_TenantScoped(t *testing.T) {
+	eng := newTestEngine(t)
+	createSearchableKey(t, eng, testCtx(), "Acme", "", nil)
+	other := keysmith.WithTenant(context.Background(), "app_test", "tenant_other")
+	createSearchableKey(t, eng, other, "Acme elsewhere", "", nil)
+
+	assert.Equal(t, []string{"Acme"}, searchNames(t, eng, testCtx(), "acme", keysmith.SearchOptions{TenantID: "tenant_other"}),
+		"the context's tenant wins over the options")
+	assert.Equal(t, []string{"Acme elsewhere"}, searchNames(t, eng, context.Background(), "acme", keysmith.SearchOptions{TenantID: "tenant_other"}))
+
+	_, err := eng.SearchKeys(context.Background(), "acme", keysmith.SearchOptions{})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidSearchQuery, "an unscoped search needs a tenant")
+}
+
+func TestSearchKeys_InvalidQuery(t *testing.T) {
+	eng := newTestEngine(t)
+	for _, q := range []string{"", "  ", `"" - !`, strings.Repeat("word ", keysmith.MaxSearchTerms+1)} {
+		_, err := eng.SearchKeys(testCtx(), q, keysmith.SearchOptions{})
+		assert.ErrorIs(t, err, keysmith.ErrInvalidSearchQuery, "%q", q)
+	}
+}
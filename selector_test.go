@@ -0,0 +1,133 @@
+package keysmith_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+)
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		in   string
+		want key.Selector
+		str  string
+	}{
+		{"", nil, ""},
+		{"team=payments", key.Selector{{Name: "team", Op: key.SelectorEquals, Values: []string{"payments"}}}, "team=payments"},
+		{"team == payments", key.Selector{{Name: "team", Op: key.SelectorEquals, Values: []string{"payments"}}}, "team=payments"},
+		{"team!=payments", key.Selector{{Name: "team", Op: key.SelectorNotEquals, Values: []string{"payments"}}}, "team!=payments"},
+		{"env in (prod, staging,prod)", key.Selector{{Name: "env", Op: key.SelectorIn, Values: []string{"prod", "staging"}}}, "env in (prod,staging)"},
+		{"env notin (dev)", key.Selector{{Name: "env", Op: key.SelectorNotIn, Values: []string{"dev"}}}, "env notin (dev)"},
+		{"example.com/owner", key.Selector{{Name: "example.com/owner", Op: key.SelectorExists}}, "example.com/owner"},
+		{"! deprecated", key.Selector{{Name: "deprecated", Op: key.SelectorDoesNotExist}}, "!deprecated"},
+		{"tier=", key.Selector{{Name: "tier", Op: key.SelectorEquals, Values: []string{""}}}, "tier="},
+		{
+			"team=payments, env in (prod), !deprecated",
+			key.Selector{
+				{Name: "team", Op: key.SelectorEquals, Values: []string{"payments"}},
+				{Name: "env", Op: key.SelectorIn, Values: []string{"prod"}},
+				{Name: "deprecated", Op: key.SelectorDoesNotExist},
+			},
+			"team=payments,env in (prod),!deprecated",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			sel, err := key.ParseSelector(tt.in)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, sel)
+			assert.Equal(t, tt.str, sel.String())
+
+			again, err := key.ParseSelector(sel.String())
+			require.NoError(t, err)
+			assert.Equal(t, sel, again, "String round-trips")
+		})
+	}
+}
+
+func TestParseSelector_Errors(t *testing.T) {
+	for _, in := range []string{
+		"team=payments,",
+		",team",
+		"env in (prod",
+		"env in prod)",
+		"env in ((prod))",
+		"env in ()",
+		"env within (prod)",
+		"team=pay ments",
+		"-team=payments",
+		"Example.com/team=x",
+		"team=" + strings.Repeat("a", key.MaxLabelValueLength+1),
+		"!",
+	} {
+		t.Run(in, func(t *testing.T) {
+			_, err := key.ParseSelector(in)
+			assert.ErrorIs(t, err, key.ErrInvalidSelector)
+		})
+	}
+
+	many := "a"
+	for range key.MaxSelectorRequirements {
+		many += ",a"
+	}
+	_, err := key.ParseSelector(many)
+	assert.ErrorIs(t, err, key.ErrInvalidSelector)
+
+	_, err = keysmith.ParseLabelSelector("env in (")
+	assert.True(t, errors.Is(err, keysmith.ErrInvalidLabelSelector) && errors.Is(err, key.ErrInvalidSelector))
+}
+
+func TestSelector_Matches(t *testing.T) {
+	labels := map[string]string{"team": "payments", "env": "prod"}
+	tests := []struct {
+		sel  string
+		want bool
+	}{
+		{"", true},
+		{"team=payments", true},
+		{"team=search", false},
+		{"team!=search", true},
+		{"owner!=ana", true},
+		{"env in (prod,staging)", true},
+		{"env notin (prod)", false},
+		{"owner notin (ana)", true},
+		{"team", true},
+		{"owner", false},
+		{"!owner", true},
+		{"!team", false},
+		{"team=payments,env=staging", false},
+	}
+	for _, tt := range tests {
+		sel, err := key.ParseSelector(tt.sel)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, sel.Matches(labels), tt.sel)
+	}
+}
+
+func TestLabels_Validate(t *testing.T) {
+	assert.NoError(t, key.Labels{"team": "payments", "example.com/tier": "", "a.b_c-d": "A1"}.Validate())
+
+	for name, labels := range map[string]key.Labels{
+		"empty name":        {"": "x"},
+		"bad character":     {"team!": "x"},
+		"leading dash":      {"team": "-x"},
+		"uppercase prefix":  {"Example.com/team": "x"},
+		"empty prefix":      {"/team": "x"},
+		"long value":        {"team": strings.Repeat("a", key.MaxLabelValueLength+1)},
+		"name after prefix": {"example.com/": "x"},
+	} {
+		assert.Error(t, labels.Validate(), name)
+	}
+
+	tooMany := key.Labels{}
+	for i := range key.MaxLabels + 1 {
+		tooMany[string(rune('a'+i%26))+string(rune('a'+i/26))] = "x"
+	}
+	assert.Error(t, tooMany.Validate())
+}
@@ -0,0 +1,86 @@
+package keysmith
+
+import (
+	"context"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/usage"
+)
+
+// The interfaces below cover the core of the engine, so code that uses
+// keysmith can depend on only what it calls and be tested against a mock
+// such as keysmithtest.MockService, or run against another implementation,
+// such as a client for a remote engine. [Engine] implements all of them.
+//
+// Compatibility: methods are never added to an interface once released,
+// since that would break every other implementation. A new capability gets
+// a new narrow interface, which [Engine] implements and callers check for
+// with a type assertion. Anything not covered here is available on
+// [Engine] itself.
+
+// Compile-time interface checks.
+var (
+	_ KeyService    = (*Engine)(nil)
+	_ KeyValidator  = (*Engine)(nil)
+	_ KeyManager    = (*Engine)(nil)
+	_ PolicyManager = (*Engine)(nil)
+	_ ScopeManager  = (*Engine)(nil)
+	_ UsageRecorder = (*Engine)(nil)
+)
+
+// KeyValidator validates raw API keys. See [Engine.ValidateKey].
+type KeyValidator interface {
+	ValidateKey(ctx context.Context, rawKey string) (*ValidationResult, error)
+}
+
+// KeyManager manages the lifecycle of API keys. See the [Engine] methods of
+// the same names.
+type KeyManager interface {
+	CreateKey(ctx context.Context, input *CreateKeyInput) (*key.CreateResult, error)
+	GetKey(ctx context.Context, keyID id.KeyID) (*key.Key, error)
+	ListKeys(ctx context.Context, filter *key.ListFilter) ([]*key.Key, error)
+	UpdateKey(ctx context.Context, keyID id.KeyID, input *UpdateKeyInput) (*key.Key, error)
+	RotateKey(ctx context.Context, keyID id.KeyID, reason rotation.Reason) (*key.CreateResult, error)
+	RevokeKey(ctx context.Context, keyID id.KeyID, reason string) error
+	SuspendKey(ctx context.Context, keyID id.KeyID) error
+	ReactivateKey(ctx context.Context, keyID id.KeyID) error
+}
+
+// PolicyManager manages policies. See the [Engine] methods of the same
+// names.
+type PolicyManager interface {
+	CreatePolicy(ctx context.Context, pol *policy.Policy) error
+	GetPolicy(ctx context.Context, polID id.PolicyID) (*policy.Policy, error)
+	UpdatePolicy(ctx context.Context, pol *policy.Policy) error
+	DeletePolicy(ctx context.Context, polID id.PolicyID) error
+	ListPolicies(ctx context.Context, filter *policy.ListFilter) ([]*policy.Policy, error)
+}
+
+// ScopeManager manages scopes and their assignment to keys. See the
+// [Engine] methods of the same names.
+type ScopeManager interface {
+	CreateScope(ctx context.Context, s *scope.Scope) error
+	ListScopes(ctx context.Context, filter *scope.ListFilter) ([]*scope.Scope, error)
+	DeleteScope(ctx context.Context, scopeID id.ScopeID) error
+	AssignScopes(ctx context.Context, keyID id.KeyID, scopeNames []string) error
+	RemoveScopes(ctx context.Context, keyID id.KeyID, scopeNames []string) error
+}
+
+// UsageRecorder records API usage. See [Engine.RecordUsage].
+type UsageRecorder interface {
+	RecordUsage(ctx context.Context, rec *usage.Record) error
+}
+
+// KeyService is the whole core of the engine: key validation and
+// management, policies, scopes, and usage recording.
+type KeyService interface {
+	KeyValidator
+	KeyManager
+	PolicyManager
+	ScopeManager
+	UsageRecorder
+}
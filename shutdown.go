@@ -0,0 +1,254 @@
+package keysmith
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/xraph/go-utils/log"
+
+	"github.com/xraph/keysmith/plugin"
+)
+
+// EngineState is the lifecycle state reported by [Engine.State].
+type EngineState int32
+
+const (
+	// EngineRunning is the state of a new engine until Stop is called.
+	EngineRunning EngineState = iota
+
+	// EngineStopping means Stop has begun: in-flight validations are being
+	// drained and background workers are shutting down.
+	EngineStopping
+
+	// EngineStopped means buffered writes have been flushed and no further
+	// background writes will be made. Shutdown hooks fire after this point.
+	EngineStopped
+)
+
+// String returns the state name.
+func (s EngineState) String() string {
+	switch s {
+	case EngineRunning:
+		return "running"
+	case EngineStopping:
+		return "stopping"
+	case EngineStopped:
+		return "stopped"
+	default:
+		return fmt.Sprintf("EngineState(%d)", int32(s))
+	}
+}
+
+// Default shutdown phase timeouts.
+const (
+	DefaultShutdownDrainTimeout   = 10 * time.Second
+	DefaultShutdownWorkersTimeout = 5 * time.Second
+	DefaultShutdownFlushTimeout   = 10 * time.Second
+	DefaultShutdownHooksTimeout   = 10 * time.Second
+)
+
+// ShutdownTimeouts bounds each phase of [Engine.Stop]. Every phase is also
+// bounded by the context passed to Stop. Zero fields use the defaults.
+type ShutdownTimeouts struct {
+	// Drain is how long to wait for in-flight ValidateKey calls.
+	Drain time.Duration
+
+	// Workers is how long to wait for background workers to exit.
+	Workers time.Duration
+
+	// Flush is how long to spend writing buffered last-used times and
+	// endpoint activity.
+	Flush time.Duration
+
+	// Hooks is how long plugin shutdown hooks may run.
+	Hooks time.Duration
+}
+
+func (t ShutdownTimeouts) withDefaults() ShutdownTimeouts {
+	if t.Drain <= 0 {
+		t.Drain = DefaultShutdownDrainTimeout
+	}
+	if t.Workers <= 0 {
+		t.Workers = DefaultShutdownWorkersTimeout
+	}
+	if t.Flush <= 0 {
+		t.Flush = DefaultShutdownFlushTimeout
+	}
+	if t.Hooks <= 0 {
+		t.Hooks = DefaultShutdownHooksTimeout
+	}
+	return t
+}
+
+// State returns the engine's lifecycle state.
+func (e *Engine) State() EngineState { return EngineState(e.state.Load()) }
+
+// Stopping reports whether Stop has been called. Middleware and health
+// checks use it to turn traffic away while the engine drains.
+func (e *Engine) Stopping() bool { return e.State() != EngineRunning }
+
+// Stop shuts the engine down in phases, logging each one:
+//
+//  1. drain: the engine is marked stopping and in-flight validations are
+//     waited for. New validations are refused with ErrEngineStopping when
+//     [WithRefuseValidationsWhenStopping] is set, and served otherwise.
+//  2. workers: the endpoint activity and quota count flushers, capture
+//     purger, and store maintenance job exit, and key event subscriptions
+//     are closed.
+//  3. flush: pending last-used writes finish and buffered endpoint activity
+//     and quota counts are written. The engine is then stopped and makes no
+//     further background writes.
+//  4. hooks: plugin shutdown hooks fire.
+//
+// A phase that runs past its [ShutdownTimeouts] entry or the context is
+// abandoned and the next phase starts. Stop returns the errors of every
+// phase joined; calling it again returns nil.
+func (e *Engine) Stop(ctx context.Context) error {
+	if !e.state.CompareAndSwap(int32(EngineRunning), int32(EngineStopping)) {
+		return nil
+	}
+	t := e.shutdownTimeouts.withDefaults()
+
+	errs := []error{
+		e.shutdownPhase(ctx, "drain", t.Drain, e.validations.wait),
+		e.shutdownPhase(ctx, "workers", t.Workers, func(ctx context.Context) error {
+			return waitFor(ctx, func() {
+				e.purger.shutdown()
+				e.maintenance.shutdown()
+				e.quotaForecasts.shutdown()
+				e.quotaFlusher.shutdown()
+				e.idleSweep.shutdown()
+				e.storageReports.shutdown()
+				e.telemetry.shutdown()
+				e.recoveryJob.shutdown()
+				e.events.closeAll()
+				if e.endpoints != nil {
+					e.endpoints.shutdown()
+				}
+			})
+		}),
+		e.shutdownPhase(ctx, "flush", t.Flush, e.flushForShutdown),
+	}
+	e.state.Store(int32(EngineStopped))
+	errs = append(errs, e.shutdownPhase(ctx, "hooks", t.Hooks, func(ctx context.Context) error {
+		return e.hooks.FireShutdown(ctx, e.eventMeta(ctx, plugin.TriggerManual, ""))
+	}))
+	return errors.Join(errs...)
+}
+
+// flushForShutdown closes the background write group, hurries the pending
+// last-used flush, waits for the writes already started, and writes the
+// quota counts and endpoint activity buffer one last time.
+func (e *Engine) flushForShutdown(ctx context.Context) error {
+	e.writes.close()
+	e.lastUsed.shutdown()
+	if err := e.writes.wait(ctx); err != nil {
+		return err
+	}
+	quotaErr := e.flushQuotaCounts(ctx)
+	if e.endpoints == nil {
+		return quotaErr
+	}
+	return errors.Join(quotaErr, e.endpoints.flush(ctx, e.store.Usages(), nil))
+}
+
+// shutdownPhase runs one phase of Stop under its own timeout.
+func (e *Engine) shutdownPhase(ctx context.Context, phase string, timeout time.Duration, fn func(context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := fn(ctx)
+	if err != nil {
+		e.logger.Warn("shutdown phase failed",
+			log.String("phase", phase),
+			log.Duration("elapsed", time.Since(start)),
+			log.Any("error", err),
+		)
+		return fmt.Errorf("shutdown %s: %w", phase, err)
+	}
+	e.logger.Info("shutdown phase complete",
+		log.String("phase", phase),
+		log.Duration("elapsed", time.Since(start)),
+	)
+	return nil
+}
+
+// waitFor runs fn and waits for it to return or ctx to end, whichever is
+// first. fn keeps running in the background if ctx ends first.
+func waitFor(ctx context.Context, fn func()) error {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// inflight counts running operations so that Stop can wait for them. A
+// closed group refuses new operations.
+type inflight struct {
+	mu     sync.Mutex
+	n      int
+	idle   chan struct{} // made by wait, closed when n drops to zero
+	closed bool
+}
+
+// add registers an operation. It returns false, and registers nothing, once
+// the group is closed.
+func (f *inflight) add() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return false
+	}
+	f.n++
+	return true
+}
+
+// done marks an operation registered with add as finished.
+func (f *inflight) done() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.n--
+	if f.n == 0 && f.idle != nil {
+		close(f.idle)
+		f.idle = nil
+	}
+}
+
+// close makes later add calls fail.
+func (f *inflight) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+}
+
+// wait blocks until no operations are running or ctx ends.
+func (f *inflight) wait(ctx context.Context) error {
+	f.mu.Lock()
+	if f.n == 0 {
+		f.mu.Unlock()
+		return nil
+	}
+	if f.idle == nil {
+		f.idle = make(chan struct{})
+	}
+	idle := f.idle
+	f.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
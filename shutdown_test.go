@@ -0,0 +1,196 @@
+package keysmith_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store/memory"
+	"github.com/xraph/keysmith/usage"
+)
+
+// eventLog records the order of shutdown-relevant writes and hooks.
+type eventLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *eventLog) add(ev string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, ev)
+}
+
+func (l *eventLog) list() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.events...)
+}
+
+// slowStore wraps the memory store with slow key lookups and last-used
+// writes, logging the writes Stop must flush.
+type slowStore struct {
+	*memory.Store
+	log *eventLog
+
+	// lookup, when set, is signalled each time GetByHash begins, which
+	// then blocks until release is closed. It needs a buffer of one.
+	lookup  chan struct{}
+	release chan struct{}
+}
+
+func (s *slowStore) Keys() key.Store { return &slowKeys{Store: s.Store.Keys(), parent: s} }
+
+func (s *slowStore) Usages() usage.Store { return &loggedUsages{Store: s.Store.Usages(), log: s.log} }
+
+type slowKeys struct {
+	key.Store
+	parent *slowStore
+}
+
+func (k *slowKeys) GetByHash(ctx context.Context, hash string) (*key.Key, error) {
+	if k.parent.lookup != nil {
+		select {
+		case k.parent.lookup <- struct{}{}:
+		default:
+		}
+		<-k.parent.release
+	}
+	return k.Store.GetByHash(ctx, hash)
+}
+
+func (k *slowKeys) UpdateLastUsed(ctx context.Context, keyID id.KeyID, at time.Time) error {
+	time.Sleep(30 * time.Millisecond)
+	k.parent.log.add("last_used")
+	return k.Store.UpdateLastUsed(ctx, keyID, at)
+}
+
+type loggedUsages struct {
+	usage.Store
+	log *eventLog
+}
+
+func (u *loggedUsages) UpsertEndpointActivity(ctx context.Context, acts []*usage.EndpointActivity) error {
+	if len(acts) > 0 {
+		u.log.add("endpoint_flush")
+	}
+	return u.Store.UpsertEndpointActivity(ctx, acts)
+}
+
+// shutdownRecorder logs OnShutdown and the engine state it saw.
+type shutdownRecorder struct {
+	log   *eventLog
+	eng   *keysmith.Engine
+	state keysmith.EngineState
+}
+
+func (*shutdownRecorder) Name() string { return "shutdown-recorder" }
+
+func (r *shutdownRecorder) OnShutdown(_ context.Context) error {
+	r.state = r.eng.State()
+	r.log.add("shutdown_hook")
+	return nil
+}
+
+func newShutdownEngine(t *testing.T, s *slowStore, opts ...keysmith.Option) (*keysmith.Engine, *shutdownRecorder, *key.CreateResult) {
+	t.Helper()
+	rec := &shutdownRecorder{log: s.log}
+	opts = append([]keysmith.Option{
+		keysmith.WithStore(s),
+		keysmith.WithEndpointActivity(10, 0),
+		keysmith.WithoutValidationCoalescing(),
+		keysmith.WithExtension(rec),
+	}, opts...)
+	eng, err := keysmith.NewEngine(opts...)
+	require.NoError(t, err)
+	rec.eng = eng
+
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+		Name:        "Shutdown",
+		Prefix:      "sk",
+		Environment: key.EnvTest,
+	})
+	require.NoError(t, err)
+	return eng, rec, created
+}
+
+func TestStop_Ordering(t *testing.T) {
+	s := &slowStore{Store: memory.New(), log: &eventLog{}, lookup: make(chan struct{}, 1), release: make(chan struct{})}
+	eng, rec, created := newShutdownEngine(t, s)
+	require.NoError(t, eng.Start(testCtx()))
+	assert.Equal(t, keysmith.EngineRunning, eng.State())
+
+	// Start a validation and hold it inside the store lookup.
+	validated := make(chan error, 1)
+	go func() {
+		_, err := eng.ValidateKey(testCtx(), created.RawKey)
+		validated <- err
+	}()
+	<-s.lookup
+	recordCall(t, eng, created.Key.ID, "GET", "/status", "")
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- eng.Stop(testCtx()) }()
+
+	require.Eventually(t, eng.Stopping, time.Second, time.Millisecond)
+	select {
+	case <-stopped:
+		t.Fatal("Stop returned before the in-flight validation finished")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(s.release)
+
+	require.NoError(t, <-validated, "in-flight validations complete")
+	require.NoError(t, <-stopped)
+	assert.Equal(t, []string{"last_used", "endpoint_flush", "shutdown_hook"}, s.log.list())
+	assert.Equal(t, keysmith.EngineStopped, rec.state, "hooks run after buffers are flushed")
+	assert.Equal(t, keysmith.EngineStopped, eng.State())
+
+	// Validations are still served by default, but nothing more is written.
+	_, err := eng.ValidateKey(testCtx(), created.RawKey)
+	require.NoError(t, err)
+	recordCall(t, eng, created.Key.ID, "GET", "/status", "")
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, []string{"last_used", "endpoint_flush", "shutdown_hook"}, s.log.list())
+
+	require.NoError(t, eng.Stop(testCtx()), "a second Stop is a no-op")
+	assert.Len(t, s.log.list(), 3)
+}
+
+func TestStop_RefusesValidationsWhenConfigured(t *testing.T) {
+	s := &slowStore{Store: memory.New(), log: &eventLog{}}
+	eng, _, created := newShutdownEngine(t, s, keysmith.WithRefuseValidationsWhenStopping())
+
+	require.NoError(t, eng.Health(testCtx()))
+	require.NoError(t, eng.Stop(testCtx()))
+
+	_, err := eng.ValidateKey(testCtx(), created.RawKey)
+	require.ErrorIs(t, err, keysmith.ErrEngineStopping)
+	require.ErrorIs(t, eng.Health(testCtx()), keysmith.ErrEngineStopping)
+	assert.True(t, eng.Stopping())
+}
+
+func TestStop_PhaseTimeouts(t *testing.T) {
+	s := &slowStore{Store: memory.New(), log: &eventLog{}, lookup: make(chan struct{}, 1), release: make(chan struct{})}
+	eng, _, created := newShutdownEngine(t, s,
+		keysmith.WithShutdownTimeouts(keysmith.ShutdownTimeouts{Drain: 30 * time.Millisecond}),
+	)
+	defer close(s.release)
+
+	go func() { _, _ = eng.ValidateKey(testCtx(), created.RawKey) }()
+	<-s.lookup
+
+	start := time.Now()
+	err := eng.Stop(testCtx())
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "shutdown drain")
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, []string{"shutdown_hook"}, s.log.list(), "later phases still run")
+}
@@ -0,0 +1,56 @@
+package keysmith
+
+import (
+	"errors"
+
+	"github.com/xraph/keysmith/slo"
+)
+
+// clientErrors are the ValidateKey errors DefaultSLOClassifier excludes from
+// error budgets: the key or the caller was at fault, not keysmith.
+var clientErrors = []error{
+	ErrInvalidKey,
+	ErrKeyInactive,
+	ErrKeyExpired,
+	ErrKeyRevoked,
+	ErrIPNotAllowed,
+	ErrOriginNotAllowed,
+	ErrMethodNotAllowed,
+	ErrPathNotAllowed,
+	ErrConsumerNotAllowed,
+	ErrTransportNotAllowed,
+	ErrClientCertMismatch,
+	ErrAuthzDenied,
+	ErrRateLimited,
+	ErrTenantRateLimited,
+	ErrQuotaExceeded,
+}
+
+// DefaultSLOClassifier counts a successful validation as good and excludes
+// rejections the client caused: unknown, inactive, expired, and revoked
+// keys, disallowed IPs, origins, methods, paths, consumers, and transports,
+// authorizer denials, rate limits, and exhausted quotas. Every other error,
+// such as a store failure while reading policies, ErrAuthorizerUnavailable,
+// or ErrEngineStopping, spends error budget. A store failure while looking
+// up the key itself is reported as ErrInvalidKey and so is excluded; supply
+// a classifier with WithSLOClassifier to change any of this.
+func DefaultSLOClassifier(err error) slo.Outcome {
+	if err == nil {
+		return slo.Good
+	}
+	for _, target := range clientErrors {
+		if errors.Is(err, target) {
+			return slo.Excluded
+		}
+	}
+	return slo.Bad
+}
+
+// SLOStatus returns the state of every objective set with WithSLO, in the
+// order they were configured. Nil when none are configured.
+func (e *Engine) SLOStatus() []slo.Status {
+	if e.slo == nil {
+		return nil
+	}
+	return e.slo.Status()
+}
@@ -0,0 +1,290 @@
+// Package slo tracks service-level objectives for key validation and reports
+// how fast each objective's error budget is burning.
+//
+// An [Objective] says what fraction of validations must be good over a
+// rolling window, optionally requiring them to finish within a latency
+// threshold. A [Tracker] counts good and bad validations in fixed-width
+// time buckets held in memory and reports, per objective, the budget left
+// over the window and the burn rate over shorter windows (5m, 1h, and 6h by
+// default), the inputs to multi-window burn-rate alerts.
+//
+// Example:
+//
+//	tr, err := slo.NewTracker(time.Now, slo.Objective{
+//	    Name:             "validation",
+//	    Target:           0.999,
+//	    LatencyThreshold: 20 * time.Millisecond,
+//	})
+//	tr.Record(elapsed, slo.Good)
+//	for _, st := range tr.Status() { ... }
+package slo
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Default objective settings.
+const (
+	// DefaultWindow is the rolling period an error budget covers.
+	DefaultWindow = 24 * time.Hour
+
+	// DefaultResolution is the width of the buckets validations are counted
+	// in, and so the granularity of every window.
+	DefaultResolution = 10 * time.Second
+)
+
+// DefaultBurnWindows are the windows burn rates are reported over.
+var DefaultBurnWindows = []time.Duration{5 * time.Minute, time.Hour, 6 * time.Hour}
+
+// ErrInvalidObjective is returned by NewTracker for an objective without a
+// name, with a target outside (0, 1), or with a negative duration.
+var ErrInvalidObjective = errors.New("slo: invalid objective")
+
+// Outcome classifies one validation for the error budget.
+type Outcome uint8
+
+const (
+	// Good counts towards the objective, unless it exceeds the objective's
+	// latency threshold.
+	Good Outcome = iota
+
+	// Bad spends error budget.
+	Bad
+
+	// Excluded is not counted at all, such as a rejection the client caused.
+	Excluded
+)
+
+// String returns the outcome's name.
+func (o Outcome) String() string {
+	switch o {
+	case Good:
+		return "good"
+	case Bad:
+		return "bad"
+	case Excluded:
+		return "excluded"
+	}
+	return fmt.Sprintf("Outcome(%d)", uint8(o))
+}
+
+// Objective is one service-level objective.
+type Objective struct {
+	// Name identifies the objective in status reports and metric names.
+	Name string
+
+	// Target is the fraction of validations that must be good, such as
+	// 0.999. The error budget is 1 - Target.
+	Target float64
+
+	// LatencyThreshold, when positive, counts a good validation slower
+	// than it as bad.
+	LatencyThreshold time.Duration
+
+	// Window is the rolling period the error budget covers. Defaults to
+	// DefaultWindow.
+	Window time.Duration
+
+	// BurnWindows are the windows burn rates are reported over. Defaults
+	// to DefaultBurnWindows.
+	BurnWindows []time.Duration
+
+	// Resolution is the bucket width. Windows are whole numbers of
+	// buckets, rounded up, and a validation stays in a window until the
+	// bucket it was counted in leaves it. Defaults to DefaultResolution.
+	Resolution time.Duration
+}
+
+func (o *Objective) normalize() error {
+	switch {
+	case o.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidObjective)
+	case o.Target <= 0 || o.Target >= 1:
+		return fmt.Errorf("%w: %s: target must be between 0 and 1", ErrInvalidObjective, o.Name)
+	case o.LatencyThreshold < 0 || o.Window < 0 || o.Resolution < 0:
+		return fmt.Errorf("%w: %s: durations must not be negative", ErrInvalidObjective, o.Name)
+	}
+	if o.Window == 0 {
+		o.Window = DefaultWindow
+	}
+	if o.Resolution == 0 {
+		o.Resolution = DefaultResolution
+	}
+	if o.BurnWindows == nil {
+		o.BurnWindows = DefaultBurnWindows
+	}
+	for _, w := range o.BurnWindows {
+		if w <= 0 {
+			return fmt.Errorf("%w: %s: burn windows must be positive", ErrInvalidObjective, o.Name)
+		}
+	}
+	o.BurnWindows = slices.Clone(o.BurnWindows)
+	return nil
+}
+
+// Status is an objective's state at one point in time.
+type Status struct {
+	Objective Objective
+
+	// Good and Bad count the validations over the objective's Window.
+	Good, Bad uint64
+
+	// BudgetRemaining is the fraction of the error budget left over the
+	// Window: 1 when nothing was bad, 0 when the budget is spent, and
+	// negative when it is overspent.
+	BudgetRemaining float64
+
+	// Burn holds the burn rate over each of the objective's BurnWindows,
+	// in the same order.
+	Burn []Burn
+}
+
+// Burn is the rate an error budget is spent at over one window.
+type Burn struct {
+	Window    time.Duration
+	Good, Bad uint64
+
+	// Rate is the error rate over the window divided by the error budget.
+	// At 1 the budget lasts exactly the objective's Window; at 14.4 a
+	// 30-day budget is spent in about two days. Zero when the window holds
+	// no validations.
+	Rate float64
+}
+
+// Tracker counts validations against a set of objectives. It is safe for
+// concurrent use.
+type Tracker struct {
+	now    func() time.Time
+	series []*series
+}
+
+// NewTracker returns a Tracker for objectives, reading the time from now;
+// a nil now uses time.Now. Objective names must be unique.
+func NewTracker(now func() time.Time, objectives ...Objective) (*Tracker, error) {
+	if now == nil {
+		now = time.Now
+	}
+	t := &Tracker{now: now, series: make([]*series, 0, len(objectives))}
+	seen := make(map[string]bool, len(objectives))
+	for _, obj := range objectives {
+		if err := obj.normalize(); err != nil {
+			return nil, err
+		}
+		if seen[obj.Name] {
+			return nil, fmt.Errorf("%w: duplicate name %q", ErrInvalidObjective, obj.Name)
+		}
+		seen[obj.Name] = true
+		t.series = append(t.series, newSeries(obj))
+	}
+	return t, nil
+}
+
+// Record counts one validation that took latency against every objective.
+func (t *Tracker) Record(latency time.Duration, outcome Outcome) {
+	if outcome == Excluded {
+		return
+	}
+	now := t.now()
+	for _, s := range t.series {
+		bad := outcome == Bad || (s.obj.LatencyThreshold > 0 && latency > s.obj.LatencyThreshold)
+		s.add(now, bad)
+	}
+}
+
+// Status returns the state of every objective, in the order they were
+// configured.
+func (t *Tracker) Status() []Status {
+	now := t.now()
+	out := make([]Status, len(t.series))
+	for i, s := range t.series {
+		out[i] = s.status(now)
+	}
+	return out
+}
+
+type bucket struct {
+	n         int64 // bucket number: Unix nanoseconds / resolution
+	good, bad uint64
+}
+
+// series is a ring of buckets for one objective, long enough for its
+// longest window.
+type series struct {
+	obj Objective
+	res int64
+
+	mu      sync.Mutex
+	buckets []bucket
+}
+
+func newSeries(obj Objective) *series {
+	res := int64(obj.Resolution)
+	longest := obj.Window
+	for _, w := range obj.BurnWindows {
+		longest = max(longest, w)
+	}
+	return &series{obj: obj, res: res, buckets: make([]bucket, spanOf(longest, res))}
+}
+
+// spanOf returns how many buckets of width res window w covers.
+func spanOf(w time.Duration, res int64) int64 {
+	return (int64(w) + res - 1) / res
+}
+
+func (s *series) add(now time.Time, bad bool) {
+	n := now.UnixNano() / s.res
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := &s.buckets[n%int64(len(s.buckets))]
+	if b.n != n {
+		*b = bucket{n: n}
+	}
+	if bad {
+		b.bad++
+	} else {
+		b.good++
+	}
+}
+
+// sum counts the validations in the buckets window w covers, ending with
+// the current one.
+func (s *series) sum(n int64, w time.Duration) (good, bad uint64) {
+	span := spanOf(w, s.res)
+	for i := range span {
+		b := s.buckets[(n-i)%int64(len(s.buckets))]
+		if b.n == n-i {
+			good += b.good
+			bad += b.bad
+		}
+	}
+	return good, bad
+}
+
+func (s *series) status(now time.Time) Status {
+	n := now.UnixNano() / s.res
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	budget := 1 - s.obj.Target
+	st := Status{Objective: s.obj, Burn: make([]Burn, len(s.obj.BurnWindows))}
+	st.Objective.BurnWindows = slices.Clone(s.obj.BurnWindows)
+	st.Good, st.Bad = s.sum(n, s.obj.Window)
+	st.BudgetRemaining = 1 - burnRate(st.Good, st.Bad, budget)
+	for i, w := range s.obj.BurnWindows {
+		good, bad := s.sum(n, w)
+		st.Burn[i] = Burn{Window: w, Good: good, Bad: bad, Rate: burnRate(good, bad, budget)}
+	}
+	return st
+}
+
+func burnRate(good, bad uint64, budget float64) float64 {
+	total := good + bad
+	if total == 0 {
+		return 0
+	}
+	return float64(bad) / float64(total) / budget
+}
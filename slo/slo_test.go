@@ -0,0 +1,186 @@
+package slo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith/slo"
+)
+
+type clock struct{ t time.Time }
+
+func (c *clock) Now() time.Time          { return c.t }
+func (c *clock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// start is aligned to every bucket width the tests use.
+var start = time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+func newTracker(t *testing.T, objectives ...slo.Objective) (*slo.Tracker, *clock) {
+	t.Helper()
+	c := &clock{t: start}
+	tr, err := slo.NewTracker(c.Now, objectives...)
+	require.NoError(t, err)
+	return tr, c
+}
+
+func record(tr *slo.Tracker, good, bad int) {
+	for range good {
+		tr.Record(time.Millisecond, slo.Good)
+	}
+	for range bad {
+		tr.Record(time.Millisecond, slo.Bad)
+	}
+}
+
+func TestTracker_BurnRate(t *testing.T) {
+	tr, _ := newTracker(t, slo.Objective{Name: "availability", Target: 0.99})
+
+	record(tr, 990, 10)
+	st := tr.Status()[0]
+	assert.Equal(t, uint64(990), st.Good)
+	assert.Equal(t, uint64(10), st.Bad)
+	// 1% errors against a 1% budget: burning at exactly 1x, budget spent.
+	assert.InDelta(t, 0, st.BudgetRemaining, 1e-9)
+	require.Len(t, st.Burn, 3)
+	for i, w := range slo.DefaultBurnWindows {
+		assert.Equal(t, w, st.Burn[i].Window)
+		assert.InDelta(t, 1, st.Burn[i].Rate, 1e-9)
+	}
+}
+
+func TestTracker_Empty(t *testing.T) {
+	tr, _ := newTracker(t, slo.Objective{Name: "availability", Target: 0.999})
+	st := tr.Status()[0]
+	assert.Equal(t, 1.0, st.BudgetRemaining)
+	assert.Zero(t, st.Burn[0].Rate)
+}
+
+func TestTracker_WindowBoundaries(t *testing.T) {
+	tr, c := newTracker(t, slo.Objective{
+		Name:       "availability",
+		Target:     0.9,
+		Window:     6 * time.Hour,
+		Resolution: time.Minute,
+	})
+
+	// An outage at the start, then clean traffic.
+	record(tr, 0, 10)
+	c.Advance(time.Minute)
+	record(tr, 90, 0)
+
+	rates := func() (m5, h1, h6 float64) {
+		b := tr.Status()[0].Burn
+		return b[0].Rate, b[1].Rate, b[2].Rate
+	}
+
+	// Both buckets are in every window: 10 bad of 100 burns a 10% budget at 1x.
+	m5, h1, h6 := rates()
+	assert.InDelta(t, 1, m5, 1e-9)
+	assert.InDelta(t, 1, h1, 1e-9)
+	assert.InDelta(t, 1, h6, 1e-9)
+
+	// The 5m window ends with the current bucket, so the outage bucket
+	// stays in it until 5 minutes after it started.
+	c.t = start.Add(5*time.Minute - time.Nanosecond)
+	m5, _, _ = rates()
+	assert.InDelta(t, 1, m5, 1e-9)
+
+	c.t = start.Add(5 * time.Minute)
+	m5, h1, h6 = rates()
+	assert.Zero(t, m5, "outage left the 5m window")
+	assert.InDelta(t, 1, h1, 1e-9)
+	assert.InDelta(t, 1, h6, 1e-9)
+
+	c.t = start.Add(time.Hour)
+	_, h1, h6 = rates()
+	assert.Zero(t, h1, "outage left the 1h window")
+	assert.InDelta(t, 1, h6, 1e-9)
+
+	// Past the 6h window nothing is left, and the good bucket left a minute
+	// after the bad one.
+	c.t = start.Add(6*time.Hour + time.Minute - time.Nanosecond)
+	st := tr.Status()[0]
+	assert.Equal(t, uint64(90), st.Good)
+	assert.Zero(t, st.Bad)
+	assert.Equal(t, 1.0, st.BudgetRemaining)
+
+	c.t = start.Add(6*time.Hour + time.Minute)
+	st = tr.Status()[0]
+	assert.Zero(t, st.Good+st.Bad)
+}
+
+func TestTracker_RingReuse(t *testing.T) {
+	tr, c := newTracker(t, slo.Objective{
+		Name:        "availability",
+		Target:      0.5,
+		Window:      time.Minute,
+		BurnWindows: []time.Duration{time.Minute},
+		Resolution:  10 * time.Second,
+	})
+
+	record(tr, 0, 5)
+	// Exactly one ring length later the slot is reused; stale counts must
+	// not leak into the new bucket.
+	c.Advance(time.Minute)
+	record(tr, 3, 1)
+	st := tr.Status()[0]
+	assert.Equal(t, uint64(3), st.Good)
+	assert.Equal(t, uint64(1), st.Bad)
+	assert.InDelta(t, 0.5, st.Burn[0].Rate, 1e-9)
+	assert.InDelta(t, 0.5, st.BudgetRemaining, 1e-9)
+}
+
+func TestTracker_SustainedBurn(t *testing.T) {
+	tr, c := newTracker(t, slo.Objective{Name: "availability", Target: 0.999})
+
+	// Six hours at 1.44% errors, 14.4x a 0.1% budget.
+	for range 6 * 60 {
+		record(tr, 9856, 144)
+		c.Advance(time.Minute)
+	}
+	st := tr.Status()[0]
+	for _, b := range st.Burn {
+		assert.InDelta(t, 14.4, b.Rate, 1e-6, b.Window)
+	}
+	assert.InDelta(t, 1-14.4, st.BudgetRemaining, 1e-6)
+}
+
+func TestTracker_Latency(t *testing.T) {
+	tr, _ := newTracker(t,
+		slo.Objective{Name: "availability", Target: 0.99},
+		slo.Objective{Name: "latency", Target: 0.99, LatencyThreshold: 20 * time.Millisecond},
+	)
+
+	tr.Record(5*time.Millisecond, slo.Good)
+	tr.Record(20*time.Millisecond, slo.Good)
+	tr.Record(21*time.Millisecond, slo.Good)
+	tr.Record(time.Second, slo.Excluded)
+
+	st := tr.Status()
+	assert.Equal(t, "availability", st[0].Objective.Name)
+	assert.Equal(t, uint64(3), st[0].Good)
+	assert.Zero(t, st[0].Bad)
+	assert.Equal(t, uint64(2), st[1].Good, "at the threshold is good")
+	assert.Equal(t, uint64(1), st[1].Bad)
+}
+
+func TestNewTracker_Invalid(t *testing.T) {
+	for name, obj := range map[string]slo.Objective{
+		"no name":        {Target: 0.99},
+		"zero target":    {Name: "a"},
+		"target of one":  {Name: "a", Target: 1},
+		"negative":       {Name: "a", Target: 0.99, Window: -time.Hour},
+		"bad burn width": {Name: "a", Target: 0.99, BurnWindows: []time.Duration{0}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := slo.NewTracker(nil, obj)
+			assert.ErrorIs(t, err, slo.ErrInvalidObjective)
+		})
+	}
+
+	_, err := slo.NewTracker(nil, slo.Objective{Name: "a", Target: 0.9}, slo.Objective{Name: "a", Target: 0.99})
+	assert.ErrorIs(t, err, slo.ErrInvalidObjective)
+}
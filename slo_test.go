@@ -0,0 +1,143 @@
+package keysmith_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/slo"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+// slowValidator advances the fake clock during each successful validation,
+// simulating a slow one.
+type slowValidator struct {
+	clock *fakeClock
+	delay time.Duration
+}
+
+func (s *slowValidator) Name() string { return "slow-validator" }
+
+func (s *slowValidator) OnKeyValidated(_ context.Context, _ *key.Key) error {
+	s.clock.Set(s.clock.Now().Add(s.delay))
+	return nil
+}
+
+func newSLOEngine(t *testing.T, opts ...keysmith.Option) (*keysmith.Engine, *fakeClock, *slowValidator) {
+	t.Helper()
+	clock := &fakeClock{t: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)}
+	slow := &slowValidator{clock: clock}
+	opts = append([]keysmith.Option{
+		keysmith.WithStore(memory.New()),
+		keysmith.WithClock(clock.Now),
+		keysmith.WithExtension(slow),
+		keysmith.WithSLO(slo.Objective{
+			Name:             "validation",
+			Target:           0.9,
+			LatencyThreshold: 20 * time.Millisecond,
+		}),
+	}, opts...)
+	eng, err := keysmith.NewEngine(opts...)
+	require.NoError(t, err)
+	return eng, clock, slow
+}
+
+func TestSLO_ClassifiesValidations(t *testing.T) {
+	eng, _, slow := newSLOEngine(t)
+	ctx := testCtx()
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "slo", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+
+	for range 9 {
+		_, err := eng.ValidateKey(ctx, created.RawKey)
+		require.NoError(t, err)
+	}
+	// Client errors do not count.
+	_, err = eng.ValidateKey(ctx, "sk_test_unknown")
+	require.ErrorIs(t, err, keysmith.ErrInvalidKey)
+
+	// A slow success spends budget.
+	slow.delay = 25 * time.Millisecond
+	_, err = eng.ValidateKey(ctx, created.RawKey)
+	require.NoError(t, err)
+
+	st := eng.SLOStatus()
+	require.Len(t, st, 1)
+	assert.Equal(t, uint64(9), st[0].Good)
+	assert.Equal(t, uint64(1), st[0].Bad)
+	assert.InDelta(t, 0, st[0].BudgetRemaining, 1e-9)
+	assert.InDelta(t, 1, st[0].Burn[0].Rate, 1e-9)
+
+	report := eng.HealthReport(ctx)
+	assert.NoError(t, report.Err)
+	assert.Equal(t, st, report.SLOs)
+}
+
+func TestSLO_CustomClassifier(t *testing.T) {
+	eng, _, _ := newSLOEngine(t, keysmith.WithSLOClassifier(func(err error) slo.Outcome {
+		if err != nil {
+			return slo.Bad
+		}
+		return slo.Good
+	}))
+
+	_, err := eng.ValidateKey(testCtx(), "sk_test_unknown")
+	require.Error(t, err)
+	assert.Equal(t, uint64(1), eng.SLOStatus()[0].Bad)
+}
+
+func TestSLO_BudgetRecovers(t *testing.T) {
+	eng, clock, _ := newSLOEngine(t, keysmith.WithSLOClassifier(func(err error) slo.Outcome {
+		if err != nil {
+			return slo.Bad
+		}
+		return slo.Good
+	}))
+	ctx := testCtx()
+
+	_, _ = eng.ValidateKey(ctx, "sk_test_unknown")
+	assert.InDelta(t, 10, eng.SLOStatus()[0].Burn[0].Rate, 1e-9, "one bad in one: 100% errors on a 10% budget")
+
+	clock.Set(clock.Now().Add(5 * time.Minute))
+	st := eng.SLOStatus()[0]
+	assert.Zero(t, st.Burn[0].Rate, "5m window has moved past it")
+	assert.InDelta(t, 10, st.Burn[1].Rate, 1e-9, "1h window still holds it")
+}
+
+func TestDefaultSLOClassifier(t *testing.T) {
+	assert.Equal(t, slo.Good, keysmith.DefaultSLOClassifier(nil))
+	for _, err := range []error{
+		keysmith.ErrInvalidKey,
+		keysmith.ErrKeyExpired,
+		keysmith.ErrRateLimited,
+		keysmith.ErrTenantRateLimited,
+		keysmith.ErrOriginNotAllowed,
+		keysmith.ErrIPNotAllowed,
+		keysmith.ErrQuotaExceeded,
+		fmt.Errorf("%w: policy allows 100 requests a day", keysmith.ErrQuotaExceeded),
+	} {
+		assert.Equal(t, slo.Excluded, keysmith.DefaultSLOClassifier(err), err)
+	}
+	assert.Equal(t, slo.Bad, keysmith.DefaultSLOClassifier(keysmith.ErrEngineStopping))
+	assert.Equal(t, slo.Bad, keysmith.DefaultSLOClassifier(context.DeadlineExceeded))
+}
+
+func TestSLO_NotConfigured(t *testing.T) {
+	eng := newTestEngine(t)
+	assert.Nil(t, eng.SLOStatus())
+	assert.Nil(t, eng.HealthReport(testCtx()).SLOs)
+}
+
+func TestNewEngine_InvalidSLO(t *testing.T) {
+	_, err := keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithSLO(slo.Objective{Name: "validation", Target: 99.9}),
+	)
+	assert.ErrorIs(t, err, slo.ErrInvalidObjective)
+}
@@ -0,0 +1,992 @@
+package keysmith
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/tenant"
+	"github.com/xraph/keysmith/usage"
+)
+
+// SnapshotVersion is the snapshot format written by Snapshot. Restore reads
+// snapshots up to this version and refuses newer ones with
+// ErrSnapshotVersionUnsupported.
+const SnapshotVersion = 1
+
+// Snapshot sections, in the order Snapshot writes them. Progress callbacks
+// name the section they report on.
+const (
+	SnapshotPolicies  = "policies"
+	SnapshotScopes    = "scopes"
+	SnapshotKeys      = "keys"
+	SnapshotRotations = "rotations"
+	SnapshotUsage     = "usage"
+	SnapshotTenants   = "tenants"
+)
+
+var snapshotSections = []string{
+	SnapshotPolicies, SnapshotScopes, SnapshotKeys, SnapshotRotations, SnapshotUsage, SnapshotTenants,
+}
+
+// A snapshot is snapshotMagic and the format version as a uvarint, then
+// frames of a kind byte, a uvarint payload length, and the payload:
+//
+//	H  header, JSON
+//	S  section start, the section name
+//	D  one entity of the current section, JSON
+//	E  section end, the section's entity count as a uvarint
+//	Z  SHA-256 of every byte before this frame
+//
+// Sections appear in snapshotSections order, the usage section only when the
+// header says so, and Z ends the stream.
+const snapshotMagic = "KEYSMITH-SNAPSHOT"
+
+const (
+	frameHeader     byte = 'H'
+	frameSection    byte = 'S'
+	frameEntity     byte = 'D'
+	frameSectionEnd byte = 'E'
+	frameChecksum   byte = 'Z'
+)
+
+// maxSnapshotFrame bounds one frame's payload, so that a corrupt length
+// fails Restore instead of allocating without limit.
+const maxSnapshotFrame = 64 << 20
+
+// snapshotProgressEvery is how many entities of a section pass between
+// progress callbacks.
+const snapshotProgressEvery = 1000
+
+// snapshotPageSize is the page size usage records are read in.
+const snapshotPageSize = 1000
+
+// SnapshotOptions configures Snapshot.
+type SnapshotOptions struct {
+	// Usage includes every usage record. Usage is usually most of a
+	// store's rows, so it is left out by default.
+	Usage bool
+
+	// Progress, when set, is called every thousand entities of a section
+	// and when the section ends, with the section and the entities written
+	// to it so far.
+	Progress func(section string, done int64)
+}
+
+// SnapshotCounts counts the entities of each section of a snapshot.
+type SnapshotCounts struct {
+	Policies  int64 `json:"policies"`
+	Scopes    int64 `json:"scopes"`
+	Keys      int64 `json:"keys"`
+	Rotations int64 `json:"rotations"`
+	Usage     int64 `json:"usage"`
+	Tenants   int64 `json:"tenants"`
+}
+
+func (c *SnapshotCounts) section(name string) *int64 {
+	switch name {
+	case SnapshotPolicies:
+		return &c.Policies
+	case SnapshotScopes:
+		return &c.Scopes
+	case SnapshotKeys:
+		return &c.Keys
+	case SnapshotRotations:
+		return &c.Rotations
+	case SnapshotUsage:
+		return &c.Usage
+	default:
+		return &c.Tenants
+	}
+}
+
+// RestoreConflict selects how Restore treats entities the target store
+// already has.
+type RestoreConflict string
+
+const (
+	// RestoreRequireEmpty, the default, refuses to restore into a store that
+	// holds any keys, policies, or scopes.
+	RestoreRequireEmpty RestoreConflict = ""
+
+	// RestoreSkipExisting keeps entities whose ID the store already has and
+	// restores the rest.
+	RestoreSkipExisting RestoreConflict = "skip"
+
+	// RestoreOverwrite replaces entities whose ID the store already has
+	// with the snapshot's. Rotation records are never rewritten.
+	RestoreOverwrite RestoreConflict = "overwrite"
+)
+
+// RestoreOptions configures Restore.
+type RestoreOptions struct {
+	// Conflict selects how entities the store already has are treated.
+	Conflict RestoreConflict
+
+	// Progress, when set, is called every thousand entities of a section
+	// and when the section ends, with the section and the entities read
+	// from it so far.
+	Progress func(section string, done int64)
+}
+
+// RestoreReport describes what Restore did.
+type RestoreReport struct {
+	// Restored counts the entities written to the store, or for a dry run
+	// the entities the snapshot holds.
+	Restored SnapshotCounts `json:"restored"`
+
+	// Skipped counts the entities the store already had and kept, under
+	// RestoreSkipExisting, and the rotation records it already had.
+	Skipped int64 `json:"skipped"`
+
+	DryRun bool `json:"dry_run"`
+}
+
+// snapshotHeader is the payload of the H frame.
+type snapshotHeader struct {
+	Usage bool `json:"usage"`
+}
+
+// snapshotKey is a key in a snapshot, with the hashes key.Key leaves out
+// of JSON.
+type snapshotKey struct {
+	Key     *key.Key           `json:"key"`
+	KeyHash string             `json:"key_hash"`
+	Hashes  []*key.HashVersion `json:"hashes,omitempty"`
+	Scopes  []string           `json:"scopes,omitempty"`
+}
+
+// snapshotRotation is a rotation record in a snapshot, with the hashes
+// rotation.Record leaves out of JSON.
+type snapshotRotation struct {
+	Rotation   *rotation.Record `json:"rotation"`
+	OldKeyHash string           `json:"old_key_hash"`
+	NewKeyHash string           `json:"new_key_hash"`
+}
+
+// Snapshot writes every policy, scope, key, rotation record, and tenant's
+// settings, and with opts.Usage every usage record, to w for Restore.
+// Keys carry their hashes and every hash version, so a restored key
+// validates with its original raw key on an engine with the same hasher.
+// A snapshot is therefore as sensitive as the keys themselves.
+//
+// Entities are written in ID order, so an unchanged store snapshots to the
+// same bytes. Sections are read one after another through the store
+// interfaces, not in one transaction; turn on read-only mode for a
+// consistent snapshot of a store in use. Debug captures, endpoint activity,
+// and the revocation feed are not included. Snapshot requires a
+// system-scoped context.
+func (e *Engine) Snapshot(ctx context.Context, w io.Writer, opts SnapshotOptions) (*SnapshotCounts, error) {
+	if scopeFromContext(ctx) != (tenantScope{}) {
+		return nil, fmt.Errorf("%w: snapshot", ErrSystemScopeRequired)
+	}
+	sw := newSnapshotWriter(w, opts.Progress)
+	header, err := json.Marshal(snapshotHeader{Usage: opts.Usage})
+	if err != nil {
+		return nil, fmt.Errorf("encode snapshot header: %w", err)
+	}
+	if err := sw.write(binary.AppendUvarint([]byte(snapshotMagic), SnapshotVersion)); err != nil {
+		return nil, err
+	}
+	if err := sw.frame(frameHeader, header); err != nil {
+		return nil, err
+	}
+
+	tenants := make(map[string]bool)
+	err = sw.section(SnapshotPolicies, func(put func(any) error) error {
+		pols, err := e.store.Policies().List(ctx, &policy.ListFilter{IncludeInline: true})
+		if err != nil {
+			return fmt.Errorf("list policies: %w", err)
+		}
+		slices.SortFunc(pols, func(a, b *policy.Policy) int { return strings.Compare(a.ID.String(), b.ID.String()) })
+		for _, pol := range pols {
+			tenants[pol.TenantID] = true
+			if err := put(pol); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = sw.section(SnapshotScopes, func(put func(any) error) error {
+		scopes, err := e.store.Scopes().List(ctx, &scope.ListFilter{})
+		if err != nil {
+			return fmt.Errorf("list scopes: %w", err)
+		}
+		slices.SortFunc(scopes, func(a, b *scope.Scope) int { return strings.Compare(a.ID.String(), b.ID.String()) })
+		for _, s := range scopes {
+			tenants[s.TenantID] = true
+			if err := put(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var keyIDs []id.KeyID
+	err = sw.section(SnapshotKeys, func(put func(any) error) error {
+		return e.store.Keys().Iterate(ctx, &key.ListFilter{}, func(k *key.Key) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			hashes, err := e.store.Keys().ListHashes(ctx, k.ID)
+			if err != nil {
+				return fmt.Errorf("list hashes of key %s: %w", k.ID, err)
+			}
+			slices.SortStableFunc(hashes, func(a, b *key.HashVersion) int {
+				if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+					return c
+				}
+				return strings.Compare(a.Hash, b.Hash)
+			})
+			assigned, err := e.store.Scopes().ListByKey(ctx, k.ID)
+			if err != nil {
+				return fmt.Errorf("list scopes of key %s: %w", k.ID, err)
+			}
+			names := make([]string, 0, len(assigned))
+			for _, s := range assigned {
+				names = append(names, s.Name)
+			}
+			slices.Sort(names)
+
+			keyIDs = append(keyIDs, k.ID)
+			tenants[k.TenantID] = true
+			cp := *k
+			cp.Scopes = nil
+			return put(&snapshotKey{Key: &cp, KeyHash: k.KeyHash, Hashes: hashes, Scopes: slices.Compact(names)})
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = sw.section(SnapshotRotations, func(put func(any) error) error {
+		for _, keyID := range keyIDs {
+			recs, err := e.store.Rotations().List(ctx, &rotation.ListFilter{KeyID: &keyID})
+			if err != nil {
+				return fmt.Errorf("list rotations of key %s: %w", keyID, err)
+			}
+			slices.SortFunc(recs, func(a, b *rotation.Record) int { return strings.Compare(a.ID.String(), b.ID.String()) })
+			for _, rec := range recs {
+				if err := put(&snapshotRotation{Rotation: rec, OldKeyHash: rec.OldKeyHash, NewKeyHash: rec.NewKeyHash}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Usage {
+		err = sw.section(SnapshotUsage, func(put func(any) error) error {
+			for _, keyID := range keyIDs {
+				var recs []*usage.Record
+				for offset := 0; ; offset += snapshotPageSize {
+					page, err := e.store.Usages().Query(ctx, &usage.QueryFilter{KeyID: &keyID, Limit: snapshotPageSize, Offset: offset})
+					if err != nil {
+						return fmt.Errorf("query usage of key %s: %w", keyID, err)
+					}
+					recs = append(recs, page...)
+					if len(page) < snapshotPageSize {
+						break
+					}
+				}
+				slices.SortFunc(recs, func(a, b *usage.Record) int { return strings.Compare(a.ID.String(), b.ID.String()) })
+				for _, rec := range recs {
+					if err := put(rec); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = sw.section(SnapshotTenants, func(put func(any) error) error {
+		for _, tenantID := range slices.Sorted(maps.Keys(tenants)) {
+			// A tenant without settings is the common case.
+			ts, err := e.store.Tenants().GetSettings(ctx, tenantID)
+			if err != nil {
+				continue
+			}
+			if err := put(ts); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sw.finish(); err != nil {
+		return nil, err
+	}
+	return &sw.counts, nil
+}
+
+// snapshotWriter writes frames to w and checksums them.
+type snapshotWriter struct {
+	w        *bufio.Writer
+	sum      hash.Hash
+	out      io.Writer
+	progress func(string, int64)
+	counts   SnapshotCounts
+}
+
+func newSnapshotWriter(w io.Writer, progress func(string, int64)) *snapshotWriter {
+	sw := &snapshotWriter{w: bufio.NewWriter(w), sum: sha256.New(), progress: progress}
+	sw.out = io.MultiWriter(sw.w, sw.sum)
+	return sw
+}
+
+func (sw *snapshotWriter) write(p []byte) error {
+	if _, err := sw.out.Write(p); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	return nil
+}
+
+func (sw *snapshotWriter) frame(kind byte, payload []byte) error {
+	if err := sw.write(binary.AppendUvarint([]byte{kind}, uint64(len(payload)))); err != nil {
+		return err
+	}
+	return sw.write(payload)
+}
+
+// section writes the frames of one section, with an entity frame for each
+// value fill passes to put.
+func (sw *snapshotWriter) section(name string, fill func(put func(any) error) error) error {
+	if err := sw.frame(frameSection, []byte(name)); err != nil {
+		return err
+	}
+	n := sw.counts.section(name)
+	put := func(v any) error {
+		payload, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("encode %s entity: %w", name, err)
+		}
+		if err := sw.frame(frameEntity, payload); err != nil {
+			return err
+		}
+		*n++
+		if sw.progress != nil && *n%snapshotProgressEvery == 0 {
+			sw.progress(name, *n)
+		}
+		return nil
+	}
+	if err := fill(put); err != nil {
+		return err
+	}
+	if err := sw.frame(frameSectionEnd, binary.AppendUvarint(nil, uint64(*n))); err != nil {
+		return err
+	}
+	if sw.progress != nil {
+		sw.progress(name, *n)
+	}
+	return nil
+}
+
+// finish writes the checksum frame and flushes.
+func (sw *snapshotWriter) finish() error {
+	sum := sw.sum.Sum(nil)
+	frame := append(binary.AppendUvarint([]byte{frameChecksum}, uint64(len(sum))), sum...)
+	if _, err := sw.w.Write(frame); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	if err := sw.w.Flush(); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	return nil
+}
+
+// Restore loads a snapshot written by Snapshot into the store through the
+// store interfaces, keeping every entity's ID, timestamps, and hashes, so
+// keys validate with their original raw keys. By default the store must
+// hold no keys, policies, or scopes; opts.Conflict merges into one that
+// does. Usage records are restored only for keys the restore created,
+// since records cannot be matched to ones the store already has. A key
+// whose hash is tombstoned fails the restore with ErrHashTombstoned, unless
+// the snapshot has it revoked.
+//
+// Section counts and the checksum are verified as the snapshot is read, so
+// a damaged snapshot fails with ErrInvalidSnapshot, but only after the
+// entities before the damage were written. With [WithDryRun] Restore reads
+// and verifies the whole snapshot and reports what it would write, without
+// writing; run it first when the snapshot's integrity is in doubt.
+// Snapshots newer than SnapshotVersion fail with
+// ErrSnapshotVersionUnsupported before anything is written.
+//
+// Restore fires no hooks: the entities are not new, only moved. It bumps
+// the revision of every tenant it wrote to and drops the engine's caches.
+// Restore requires a system-scoped context.
+func (e *Engine) Restore(ctx context.Context, r io.Reader, opts RestoreOptions) (*RestoreReport, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	if scopeFromContext(ctx) != (tenantScope{}) {
+		return nil, fmt.Errorf("%w: restore", ErrSystemScopeRequired)
+	}
+	switch opts.Conflict {
+	case RestoreRequireEmpty, RestoreSkipExisting, RestoreOverwrite:
+	default:
+		return nil, fmt.Errorf("%w: unknown conflict policy %q", ErrInvalidRestoreOptions, opts.Conflict)
+	}
+
+	sr := newSnapshotReader(r)
+	header, err := sr.begin()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Conflict == RestoreRequireEmpty {
+		if err := e.checkRestoreTargetEmpty(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	rs := &restoreState{
+		e:       e,
+		ctx:     ctx,
+		opts:    opts,
+		dryRun:  IsDryRun(ctx),
+		created: make(map[string]bool),
+		tenants: make(map[string]bool),
+	}
+	rs.report.DryRun = rs.dryRun
+	defer rs.finish()
+
+	for _, name := range snapshotSections {
+		if name == SnapshotUsage && !header.Usage {
+			continue
+		}
+		if err := sr.section(name, opts.Progress, rs.apply(name)); err != nil {
+			return &rs.report, err
+		}
+		if name == SnapshotUsage {
+			if err := rs.flushUsage(); err != nil {
+				return &rs.report, err
+			}
+		}
+	}
+	if err := sr.end(); err != nil {
+		return &rs.report, err
+	}
+	return &rs.report, nil
+}
+
+// checkRestoreTargetEmpty returns ErrRestoreTargetNotEmpty when the store
+// holds any keys, policies, or scopes.
+func (e *Engine) checkRestoreTargetEmpty(ctx context.Context) error {
+	keys, err := e.store.Keys().Count(ctx, &key.ListFilter{})
+	if err != nil {
+		return fmt.Errorf("count keys: %w", err)
+	}
+	pols, err := e.store.Policies().Count(ctx, &policy.ListFilter{IncludeInline: true})
+	if err != nil {
+		return fmt.Errorf("count policies: %w", err)
+	}
+	scopes, err := e.store.Scopes().List(ctx, &scope.ListFilter{Limit: 1})
+	if err != nil {
+		return fmt.Errorf("list scopes: %w", err)
+	}
+	if keys > 0 || pols > 0 || len(scopes) > 0 {
+		return fmt.Errorf("%w: %d keys, %d policies, and some scopes; set RestoreOptions.Conflict to merge",
+			ErrRestoreTargetNotEmpty, keys, pols)
+	}
+	return nil
+}
+
+// restoreState is one Restore in progress.
+type restoreState struct {
+	e      *Engine
+	ctx    context.Context
+	opts   RestoreOptions
+	dryRun bool
+	report RestoreReport
+
+	// created holds the IDs of the keys this restore created, the only
+	// keys usage records are restored for.
+	created map[string]bool
+	tenants map[string]bool
+	usage   []*usage.Record
+}
+
+// apply returns the function that restores one entity of section name.
+func (rs *restoreState) apply(name string) func([]byte) error {
+	switch name {
+	case SnapshotPolicies:
+		return rs.policy
+	case SnapshotScopes:
+		return rs.scope
+	case SnapshotKeys:
+		return rs.key
+	case SnapshotRotations:
+		return rs.rotation
+	case SnapshotUsage:
+		return rs.usageRecord
+	default:
+		return rs.tenant
+	}
+}
+
+// write counts an entity of section, stored or not as exists and the
+// conflict policy say, calling create or update unless this is a dry run.
+// It reports whether the entity is restored.
+func (rs *restoreState) write(section string, exists bool, create, update func() error) (bool, error) {
+	if exists && rs.opts.Conflict != RestoreOverwrite {
+		rs.report.Skipped++
+		return false, nil
+	}
+	if !rs.dryRun {
+		fn := create
+		if exists {
+			fn = update
+		}
+		if err := fn(); err != nil {
+			return false, err
+		}
+	}
+	*rs.report.Restored.section(section)++
+	return true, nil
+}
+
+func (rs *restoreState) policy(payload []byte) error {
+	var pol policy.Policy
+	if err := decodeSnapshotEntity(payload, &pol); err != nil {
+		return err
+	}
+	ps := rs.e.store.Policies()
+	_, err := ps.Get(rs.ctx, pol.ID)
+	ok, err := rs.write(SnapshotPolicies, err == nil,
+		func() error { return ps.Create(rs.ctx, &pol) },
+		func() error { return ps.Update(rs.ctx, &pol) })
+	if err != nil {
+		return fmt.Errorf("restore policy %s: %w", pol.ID, err)
+	}
+	if ok {
+		rs.tenants[pol.TenantID] = true
+	}
+	return nil
+}
+
+func (rs *restoreState) scope(payload []byte) error {
+	var s scope.Scope
+	if err := decodeSnapshotEntity(payload, &s); err != nil {
+		return err
+	}
+	ss := rs.e.store.Scopes()
+	_, err := ss.Get(rs.ctx, s.ID)
+	ok, err := rs.write(SnapshotScopes, err == nil,
+		func() error { return ss.Create(rs.ctx, &s) },
+		func() error { return ss.Update(rs.ctx, &s) })
+	if err != nil {
+		return fmt.Errorf("restore scope %s: %w", s.ID, err)
+	}
+	if ok {
+		rs.tenants[s.TenantID] = true
+	}
+	return nil
+}
+
+func (rs *restoreState) key(payload []byte) error {
+	var sk snapshotKey
+	if err := decodeSnapshotEntity(payload, &sk); err != nil {
+		return err
+	}
+	if sk.Key == nil || sk.KeyHash == "" {
+		return fmt.Errorf("%w: key entity without a key or key hash", ErrInvalidSnapshot)
+	}
+	k := sk.Key
+	k.KeyHash = sk.KeyHash
+	k.Scopes = nil
+	if err := rs.checkTombstones(k, &sk); err != nil {
+		return fmt.Errorf("restore key %s: %w", k.ID, err)
+	}
+
+	ks := rs.e.store.Keys()
+	_, err := ks.Get(rs.ctx, k.ID)
+	exists := err == nil
+	ok, err := rs.write(SnapshotKeys, exists,
+		func() error { return rs.writeKey(k, &sk, false) },
+		func() error { return rs.writeKey(k, &sk, true) })
+	if err != nil {
+		return fmt.Errorf("restore key %s: %w", k.ID, err)
+	}
+	if ok {
+		rs.tenants[k.TenantID] = true
+		if !exists {
+			rs.created[k.ID.String()] = true
+		}
+	}
+	return nil
+}
+
+// checkTombstones returns ErrHashTombstoned if k's hash or a hash version of
+// sk is tombstoned, unless k is revoked: restoring a revoked key keeps it
+// dead.
+func (rs *restoreState) checkTombstones(k *key.Key, sk *snapshotKey) error {
+	if k.State == key.StateRevoked {
+		return nil
+	}
+	hashes := []string{k.KeyHash}
+	for _, h := range sk.Hashes {
+		hashes = append(hashes, h.Hash)
+	}
+	return rs.e.checkTombstones(rs.ctx, hashes...)
+}
+
+// writeKey stores k with the hash versions and scopes of sk, over the
+// stored key when exists.
+func (rs *restoreState) writeKey(k *key.Key, sk *snapshotKey, exists bool) error {
+	ctx, ks := rs.ctx, rs.e.store.Keys()
+	if exists {
+		if err := ks.Update(ctx, k); err != nil {
+			return err
+		}
+	} else {
+		// Create dates the current hash version by the key's CreatedAt;
+		// date it as the snapshot does, and put CreatedAt back below.
+		created := *k
+		for _, h := range sk.Hashes {
+			if key.HashesEqual(h.Hash, k.KeyHash) {
+				created.CreatedAt = h.CreatedAt
+			}
+		}
+		if err := ks.Create(ctx, &created); err != nil {
+			return err
+		}
+	}
+	for _, h := range sk.Hashes {
+		if key.HashesEqual(h.Hash, k.KeyHash) {
+			continue
+		}
+		v := &key.HashVersion{KeyID: k.ID, Hash: h.Hash, Scheme: h.Scheme, Active: true, CreatedAt: h.CreatedAt}
+		if err := ks.AddHash(ctx, v); err != nil {
+			return fmt.Errorf("add hash version: %w", err)
+		}
+		if !h.Active {
+			if err := ks.DeactivateHash(ctx, k.ID, h.Hash); err != nil {
+				return fmt.Errorf("deactivate hash version: %w", err)
+			}
+		}
+	}
+
+	ss := rs.e.store.Scopes()
+	if exists {
+		current, err := ss.ListByKey(ctx, k.ID)
+		if err != nil {
+			return fmt.Errorf("list scopes: %w", err)
+		}
+		var stale []string
+		for _, s := range current {
+			if !slices.Contains(sk.Scopes, s.Name) {
+				stale = append(stale, s.Name)
+			}
+		}
+		if len(stale) > 0 {
+			if err := ss.RemoveFromKey(ctx, k.ID, stale); err != nil {
+				return fmt.Errorf("remove scopes: %w", err)
+			}
+		}
+	}
+	if len(sk.Scopes) > 0 {
+		if err := ss.AssignToKey(ctx, k.ID, sk.Scopes); err != nil {
+			return fmt.Errorf("assign scopes: %w", err)
+		}
+	}
+	// Scope changes bump UpdatedAt; put the snapshot's timestamps back.
+	if err := ks.Update(ctx, k); err != nil {
+		return err
+	}
+	rs.e.invalidateKey(k.ID)
+	return nil
+}
+
+func (rs *restoreState) rotation(payload []byte) error {
+	var sr snapshotRotation
+	if err := decodeSnapshotEntity(payload, &sr); err != nil {
+		return err
+	}
+	if sr.Rotation == nil {
+		return fmt.Errorf("%w: rotation entity without a record", ErrInvalidSnapshot)
+	}
+	rec := sr.Rotation
+	rec.OldKeyHash, rec.NewKeyHash = sr.OldKeyHash, sr.NewKeyHash
+
+	// Records are immutable, so one the store has is kept even under
+	// RestoreOverwrite.
+	if _, err := rs.e.store.Rotations().Get(rs.ctx, rec.ID); err == nil {
+		rs.report.Skipped++
+		return nil
+	}
+	if !rs.dryRun {
+		if err := rs.e.store.Rotations().Create(rs.ctx, rec); err != nil {
+			return fmt.Errorf("restore rotation %s: %w", rec.ID, err)
+		}
+	}
+	rs.report.Restored.Rotations++
+	return nil
+}
+
+func (rs *restoreState) usageRecord(payload []byte) error {
+	var rec usage.Record
+	if err := decodeSnapshotEntity(payload, &rec); err != nil {
+		return err
+	}
+	if !rs.created[rec.KeyID.String()] {
+		rs.report.Skipped++
+		return nil
+	}
+	rs.report.Restored.Usage++
+	if rs.dryRun {
+		return nil
+	}
+	rs.usage = append(rs.usage, &rec)
+	if len(rs.usage) >= snapshotPageSize {
+		return rs.flushUsage()
+	}
+	return nil
+}
+
+// flushUsage writes the buffered usage records in one batch.
+func (rs *restoreState) flushUsage() error {
+	if len(rs.usage) == 0 {
+		return nil
+	}
+	if err := rs.e.store.Usages().RecordBatch(rs.ctx, rs.usage); err != nil {
+		return fmt.Errorf("restore usage: %w", err)
+	}
+	rs.usage = rs.usage[:0]
+	return nil
+}
+
+func (rs *restoreState) tenant(payload []byte) error {
+	var ts tenant.Settings
+	if err := decodeSnapshotEntity(payload, &ts); err != nil {
+		return err
+	}
+	s := rs.e.store.Tenants()
+	_, err := s.GetSettings(rs.ctx, ts.TenantID)
+	upsert := func() error { return s.UpsertSettings(rs.ctx, &ts) }
+	ok, err := rs.write(SnapshotTenants, err == nil, upsert, upsert)
+	if err != nil {
+		return fmt.Errorf("restore settings of tenant %q: %w", ts.TenantID, err)
+	}
+	if ok {
+		rs.tenants[ts.TenantID] = true
+		rs.e.settings.invalidate(ts.TenantID)
+	}
+	return nil
+}
+
+// finish bumps the revisions of the tenants written to and drops the
+// caches, whether or not the restore completed.
+func (rs *restoreState) finish() {
+	if rs.dryRun {
+		return
+	}
+	for _, tenantID := range slices.Sorted(maps.Keys(rs.tenants)) {
+		rs.e.bumpRevision(rs.ctx, tenantID)
+	}
+	rs.e.effective.invalidate()
+	rs.e.invalidateAll()
+}
+
+func decodeSnapshotEntity(payload []byte, v any) error {
+	if err := json.Unmarshal(payload, v); err != nil {
+		return fmt.Errorf("%w: decode entity: %v", ErrInvalidSnapshot, err)
+	}
+	return nil
+}
+
+// snapshotReader reads frames and checksums them.
+type snapshotReader struct {
+	r       *bufio.Reader
+	sum     hash.Hash
+	hashing bool
+}
+
+func newSnapshotReader(r io.Reader) *snapshotReader {
+	return &snapshotReader{r: bufio.NewReader(r), sum: sha256.New(), hashing: true}
+}
+
+// ReadByte and Read feed what they read to the checksum.
+func (sr *snapshotReader) ReadByte() (byte, error) {
+	b, err := sr.r.ReadByte()
+	if err == nil && sr.hashing {
+		sr.sum.Write([]byte{b})
+	}
+	return b, err
+}
+
+func (sr *snapshotReader) Read(p []byte) (int, error) {
+	n, err := sr.r.Read(p)
+	if sr.hashing {
+		sr.sum.Write(p[:n])
+	}
+	return n, err
+}
+
+func truncatedSnapshot(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: truncated", ErrInvalidSnapshot)
+	}
+	return fmt.Errorf("read snapshot: %w", err)
+}
+
+// begin reads the magic, version, and header.
+func (sr *snapshotReader) begin() (*snapshotHeader, error) {
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(sr, magic); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("%w: not a keysmith snapshot", ErrInvalidSnapshot)
+		}
+		return nil, truncatedSnapshot(err)
+	}
+	if !bytes.Equal(magic, []byte(snapshotMagic)) {
+		return nil, fmt.Errorf("%w: not a keysmith snapshot", ErrInvalidSnapshot)
+	}
+	version, err := binary.ReadUvarint(sr)
+	if err != nil {
+		return nil, truncatedSnapshot(err)
+	}
+	switch {
+	case version < 1:
+		return nil, fmt.Errorf("%w: version %d", ErrInvalidSnapshot, version)
+	case version > SnapshotVersion:
+		return nil, fmt.Errorf("%w: version %d, this engine reads up to %d", ErrSnapshotVersionUnsupported, version, SnapshotVersion)
+	}
+
+	payload, err := sr.expect(frameHeader)
+	if err != nil {
+		return nil, err
+	}
+	var header snapshotHeader
+	if err := json.Unmarshal(payload, &header); err != nil {
+		return nil, fmt.Errorf("%w: decode header: %v", ErrInvalidSnapshot, err)
+	}
+	return &header, nil
+}
+
+// next reads one frame. The checksum covers every byte before a checksum
+// frame.
+func (sr *snapshotReader) next() (byte, []byte, error) {
+	kind, err := sr.r.ReadByte()
+	if err != nil {
+		return 0, nil, truncatedSnapshot(err)
+	}
+	if kind == frameChecksum {
+		sr.hashing = false
+	} else {
+		sr.sum.Write([]byte{kind})
+	}
+	n, err := binary.ReadUvarint(sr)
+	if err != nil {
+		return 0, nil, truncatedSnapshot(err)
+	}
+	if n > maxSnapshotFrame {
+		return 0, nil, fmt.Errorf("%w: %d-byte frame", ErrInvalidSnapshot, n)
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(sr, payload); err != nil {
+		return 0, nil, truncatedSnapshot(err)
+	}
+	return kind, payload, nil
+}
+
+// expect reads one frame of the given kind.
+func (sr *snapshotReader) expect(kind byte) ([]byte, error) {
+	got, payload, err := sr.next()
+	if err != nil {
+		return nil, err
+	}
+	if got != kind {
+		return nil, fmt.Errorf("%w: frame %q where %q belongs", ErrInvalidSnapshot, got, kind)
+	}
+	return payload, nil
+}
+
+// section reads section name, calling apply for each entity, and checks
+// its count.
+func (sr *snapshotReader) section(name string, progress func(string, int64), apply func([]byte) error) error {
+	payload, err := sr.expect(frameSection)
+	if err != nil {
+		return err
+	}
+	if string(payload) != name {
+		return fmt.Errorf("%w: section %q where %q belongs", ErrInvalidSnapshot, payload, name)
+	}
+	var n int64
+	for {
+		kind, payload, err := sr.next()
+		if err != nil {
+			return err
+		}
+		switch kind {
+		case frameEntity:
+			if err := apply(payload); err != nil {
+				return err
+			}
+			n++
+			if progress != nil && n%snapshotProgressEvery == 0 {
+				progress(name, n)
+			}
+		case frameSectionEnd:
+			want, m := binary.Uvarint(payload)
+			if m <= 0 || m != len(payload) {
+				return fmt.Errorf("%w: malformed %s count", ErrInvalidSnapshot, name)
+			}
+			if want != uint64(n) {
+				return fmt.Errorf("%w: %s section holds %d entities, its count says %d", ErrInvalidSnapshot, name, n, want)
+			}
+			if progress != nil {
+				progress(name, n)
+			}
+			return nil
+		default:
+			return fmt.Errorf("%w: frame %q inside the %s section", ErrInvalidSnapshot, kind, name)
+		}
+	}
+}
+
+// end reads the checksum frame and checks that nothing follows it.
+func (sr *snapshotReader) end() error {
+	want := sr.sum.Sum(nil)
+	got, err := sr.expect(frameChecksum)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(got, want) {
+		return fmt.Errorf("%w: checksum mismatch", ErrInvalidSnapshot)
+	}
+	if _, err := sr.r.ReadByte(); !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: data after the checksum", ErrInvalidSnapshot)
+	}
+	return nil
+}
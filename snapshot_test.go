@@ -0,0 +1,221 @@
+package keysmith_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/keysmithtest"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/tenant"
+	"github.com/xraph/keysmith/usage"
+)
+
+// seedSnapshot fills src with a policy, scopes, a rotated key with usage,
+// a plain key, and tenant settings, and returns the keys' raw keys.
+func seedSnapshot(t *testing.T, src *keysmith.Engine) []string {
+	t.Helper()
+	ctx := testCtx()
+	pol := keysmithtest.NewPolicy(src).WithName("standard").WithAllowedScopes("read", "write").MustCreate(t, ctx)
+	created := keysmithtest.NewKey(src).WithScopes("read", "write").WithPolicy(pol.ID).MustCreate(t, ctx)
+	rotated, err := src.RotateKey(ctx, created.Key.ID, rotation.ReasonManual)
+	require.NoError(t, err)
+	plain := keysmithtest.NewKey(src).WithName("plain").WithScopes("read").MustCreate(t, ctx)
+
+	now := time.Now()
+	keysmithtest.NewUsage(src, created.Key.ID).Count(3).Between(now.Add(-time.Hour), now).MustCreate(t, ctx)
+	require.NoError(t, src.SetTenantSettings(ctx, &tenant.Settings{DefaultScopes: []string{"read"}}))
+	return []string{rotated.RawKey, plain.RawKey}
+}
+
+func snapshotBytes(t *testing.T, eng *keysmith.Engine, opts keysmith.SnapshotOptions) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	_, err := eng.Snapshot(context.Background(), &buf, opts)
+	require.NoError(t, err)
+	return buf.Bytes()
+}
+
+func TestRestore_RoundTrip(t *testing.T) {
+	src := keysmithtest.NewEngine(t)
+	rawKeys := seedSnapshot(t, src)
+
+	var buf bytes.Buffer
+	counts, err := src.Snapshot(context.Background(), &buf, keysmith.SnapshotOptions{Usage: true})
+	require.NoError(t, err)
+	assert.Equal(t, keysmith.SnapshotCounts{Policies: 1, Scopes: 2, Keys: 2, Rotations: 1, Usage: 3, Tenants: 1}, *counts)
+
+	dst := keysmithtest.NewEngine(t)
+	report, err := dst.Restore(context.Background(), &buf, keysmith.RestoreOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, *counts, report.Restored)
+	assert.Zero(t, report.Skipped)
+
+	for _, raw := range rawKeys {
+		vr := keysmithtest.AssertValidates(t, dst, testCtx(), raw)
+		require.NotNil(t, vr)
+		want, err := src.GetKey(testCtx(), vr.Key.ID)
+		require.NoError(t, err)
+		assert.Equal(t, want.CreatedAt.UnixNano(), vr.Key.CreatedAt.UnixNano())
+	}
+
+	ts, err := dst.GetTenantSettings(testCtx(), "tenant_test")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read"}, ts.DefaultScopes)
+	recs, err := dst.Store().Usages().Query(context.Background(), &usage.QueryFilter{})
+	require.NoError(t, err)
+	assert.Len(t, recs, 3)
+
+	// The restored store snapshots to the same bytes.
+	assert.Equal(t, snapshotBytes(t, src, keysmith.SnapshotOptions{Usage: true}),
+		snapshotBytes(t, dst, keysmith.SnapshotOptions{Usage: true}))
+}
+
+func TestSnapshot_Deterministic(t *testing.T) {
+	src := keysmithtest.NewEngine(t)
+	seedSnapshot(t, src)
+
+	first := snapshotBytes(t, src, keysmith.SnapshotOptions{})
+	assert.Equal(t, first, snapshotBytes(t, src, keysmith.SnapshotOptions{}))
+
+	var buf bytes.Buffer
+	counts, err := src.Snapshot(context.Background(), &buf, keysmith.SnapshotOptions{})
+	require.NoError(t, err)
+	assert.Zero(t, counts.Usage, "usage is left out by default")
+}
+
+func TestRestore_RefusesNewerVersion(t *testing.T) {
+	data := snapshotBytes(t, keysmithtest.NewEngine(t), keysmith.SnapshotOptions{})
+	magic := len("KEYSMITH-SNAPSHOT")
+	newer := append(binary.AppendUvarint([]byte("KEYSMITH-SNAPSHOT"), keysmith.SnapshotVersion+1), data[magic+1:]...)
+
+	_, err := keysmithtest.NewEngine(t).Restore(context.Background(), bytes.NewReader(newer), keysmith.RestoreOptions{})
+	assert.ErrorIs(t, err, keysmith.ErrSnapshotVersionUnsupported)
+
+	_, err = keysmithtest.NewEngine(t).Restore(context.Background(), bytes.NewReader([]byte("{}")), keysmith.RestoreOptions{})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidSnapshot)
+}
+
+func TestRestore_DetectsCorruption(t *testing.T) {
+	src := keysmithtest.NewEngine(t)
+	seedSnapshot(t, src)
+	data := snapshotBytes(t, src, keysmith.SnapshotOptions{})
+
+	t.Run("truncated", func(t *testing.T) {
+		_, err := keysmithtest.NewEngine(t).Restore(keysmith.WithDryRun(context.Background()),
+			bytes.NewReader(data[:len(data)-10]), keysmith.RestoreOptions{})
+		assert.ErrorIs(t, err, keysmith.ErrInvalidSnapshot)
+		assert.ErrorContains(t, err, "truncated")
+	})
+
+	t.Run("flipped byte", func(t *testing.T) {
+		corrupt := bytes.Clone(data)
+		i := bytes.Index(corrupt, []byte(`"standard"`))
+		require.Positive(t, i)
+		corrupt[i+1] = 'S'
+		_, err := keysmithtest.NewEngine(t).Restore(keysmith.WithDryRun(context.Background()),
+			bytes.NewReader(corrupt), keysmith.RestoreOptions{})
+		assert.ErrorIs(t, err, keysmith.ErrInvalidSnapshot)
+		assert.ErrorContains(t, err, "checksum")
+	})
+
+	t.Run("trailing data", func(t *testing.T) {
+		_, err := keysmithtest.NewEngine(t).Restore(keysmith.WithDryRun(context.Background()),
+			bytes.NewReader(append(bytes.Clone(data), 0)), keysmith.RestoreOptions{})
+		assert.ErrorIs(t, err, keysmith.ErrInvalidSnapshot)
+	})
+}
+
+func TestRestore_RequiresEmptyTarget(t *testing.T) {
+	src := keysmithtest.NewEngine(t)
+	seedSnapshot(t, src)
+	data := snapshotBytes(t, src, keysmith.SnapshotOptions{})
+
+	dst := keysmithtest.NewEngine(t)
+	keysmithtest.NewKey(dst).MustCreate(t, testCtx())
+	_, err := dst.Restore(context.Background(), bytes.NewReader(data), keysmith.RestoreOptions{})
+	assert.ErrorIs(t, err, keysmith.ErrRestoreTargetNotEmpty)
+
+	_, err = dst.Restore(context.Background(), bytes.NewReader(data), keysmith.RestoreOptions{Conflict: "merge"})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidRestoreOptions)
+}
+
+func TestRestore_Conflicts(t *testing.T) {
+	src := keysmithtest.NewEngine(t)
+	rawKeys := seedSnapshot(t, src)
+	data := snapshotBytes(t, src, keysmith.SnapshotOptions{Usage: true})
+
+	dst := keysmithtest.NewEngine(t)
+	_, err := dst.Restore(context.Background(), bytes.NewReader(data), keysmith.RestoreOptions{})
+	require.NoError(t, err)
+
+	// Skipping keeps everything the store has.
+	report, err := dst.Restore(context.Background(), bytes.NewReader(data), keysmith.RestoreOptions{Conflict: keysmith.RestoreSkipExisting})
+	require.NoError(t, err)
+	assert.Equal(t, keysmith.SnapshotCounts{}, report.Restored)
+	assert.Equal(t, int64(10), report.Skipped, "every entity, usage included")
+
+	// Overwriting puts the snapshot's state back over a change.
+	vr := keysmithtest.AssertValidates(t, dst, testCtx(), rawKeys[1])
+	require.NotNil(t, vr)
+	require.NoError(t, dst.RevokeKey(testCtx(), vr.Key.ID, "test"))
+	require.NoError(t, dst.AssignScopes(testCtx(), vr.Key.ID, []string{"write"}))
+
+	report, err = dst.Restore(context.Background(), bytes.NewReader(data), keysmith.RestoreOptions{Conflict: keysmith.RestoreOverwrite})
+	require.NoError(t, err)
+	assert.Equal(t, keysmith.SnapshotCounts{Policies: 1, Scopes: 2, Keys: 2, Tenants: 1}, report.Restored)
+	assert.Equal(t, int64(4), report.Skipped, "rotation and usage records are not rewritten")
+
+	vr = keysmithtest.AssertValidates(t, dst, testCtx(), rawKeys[1])
+	require.NotNil(t, vr)
+	assert.Equal(t, []string{"read"}, vr.Scopes)
+}
+
+func TestRestore_DryRun(t *testing.T) {
+	src := keysmithtest.NewEngine(t)
+	seedSnapshot(t, src)
+	data := snapshotBytes(t, src, keysmith.SnapshotOptions{Usage: true})
+
+	dst := keysmithtest.NewEngine(t)
+	report, err := dst.Restore(keysmith.WithDryRun(context.Background()), bytes.NewReader(data), keysmith.RestoreOptions{})
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, int64(2), report.Restored.Keys)
+	assert.Equal(t, int64(3), report.Restored.Usage)
+
+	n, err := dst.Store().Keys().Count(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestSnapshot_Progress(t *testing.T) {
+	src := keysmithtest.NewEngine(t)
+	seedSnapshot(t, src)
+
+	got := map[string]int64{}
+	progress := func(section string, done int64) { got[section] = done }
+	var buf bytes.Buffer
+	_, err := src.Snapshot(context.Background(), &buf, keysmith.SnapshotOptions{Progress: progress})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"policies": 1, "scopes": 2, "keys": 2, "rotations": 1, "tenants": 1}, got)
+
+	got = map[string]int64{}
+	_, err = keysmithtest.NewEngine(t).Restore(context.Background(), &buf, keysmith.RestoreOptions{Progress: progress})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"policies": 1, "scopes": 2, "keys": 2, "rotations": 1, "tenants": 1}, got)
+}
+
+func TestSnapshot_RequiresSystemScope(t *testing.T) {
+	eng := keysmithtest.NewEngine(t)
+	var buf bytes.Buffer
+	_, err := eng.Snapshot(testCtx(), &buf, keysmith.SnapshotOptions{})
+	assert.ErrorIs(t, err, keysmith.ErrSystemScopeRequired)
+	_, err = eng.Restore(testCtx(), &buf, keysmith.RestoreOptions{})
+	assert.ErrorIs(t, err, keysmith.ErrSystemScopeRequired)
+}
@@ -0,0 +1,150 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/xraph/go-utils/log"
+
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/store"
+)
+
+// DefaultStorageReportTop is how many tenants TenantStorageReport ranks by
+// usage volume.
+const DefaultStorageReportTop = 10
+
+// StorageReportOptions controls a TenantStorageReportWith call.
+type StorageReportOptions struct {
+	// TenantID restricts the report to one tenant.
+	TenantID string
+
+	// Top is how many tenants to rank by usage volume. Zero uses
+	// DefaultStorageReportTop; a negative Top ranks every tenant.
+	Top int
+}
+
+// TenantStorageReport counts the keys by state, policies, scopes,
+// rotations, and usage records of every tenant, estimates the space their
+// usage takes, and ranks the DefaultStorageReportTop tenants with the most
+// usage records. Counting scans every table, so run it off-peak on large
+// stores. It requires a system-scoped context and returns
+// ErrStorageReportUnsupported when the store does not implement
+// store.StorageReporter.
+func (e *Engine) TenantStorageReport(ctx context.Context) (*store.StorageReport, error) {
+	return e.TenantStorageReportWith(ctx, StorageReportOptions{})
+}
+
+// TenantStorageReportWith is TenantStorageReport with explicit options.
+func (e *Engine) TenantStorageReportWith(ctx context.Context, opts StorageReportOptions) (*store.StorageReport, error) {
+	r, err := e.storageReporter(ctx, "storage report")
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	tenants, err := r.TenantStorage(ctx, opts.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("tenant storage: %w", err)
+	}
+	top := opts.Top
+	if top == 0 {
+		top = DefaultStorageReportTop
+	}
+	return newStorageReport(tenants, top, e.now(), time.Since(start)), nil
+}
+
+// TenantStorage returns the counts of one tenant, as in
+// TenantStorageReport; a tenant without rows has zero counts. It requires a
+// system-scoped context.
+func (e *Engine) TenantStorage(ctx context.Context, tenantID string) (*store.TenantStorage, error) {
+	r, err := e.storageReporter(ctx, "tenant storage")
+	if err != nil {
+		return nil, err
+	}
+	tenants, err := r.TenantStorage(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("tenant storage: %w", err)
+	}
+	for _, ts := range tenants {
+		if ts.TenantID == tenantID {
+			return ts, nil
+		}
+	}
+	return &store.TenantStorage{TenantID: tenantID, KeysByState: map[key.State]int64{}}, nil
+}
+
+// storageReporter checks that ctx is system-scoped, since storage reports
+// span tenants, and returns the store's StorageReporter.
+func (e *Engine) storageReporter(ctx context.Context, op string) (store.StorageReporter, error) {
+	if scopeFromContext(ctx) != (tenantScope{}) {
+		return nil, fmt.Errorf("%w: %s", ErrSystemScopeRequired, op)
+	}
+	r, ok := store.As[store.StorageReporter](e.store)
+	if !ok {
+		return nil, ErrStorageReportUnsupported
+	}
+	return r, nil
+}
+
+// newStorageReport totals tenants and ranks the top of them by usage rows,
+// then bytes, then tenant ID.
+func newStorageReport(tenants []*store.TenantStorage, top int, now time.Time, d time.Duration) *store.StorageReport {
+	report := &store.StorageReport{
+		Tenants:     tenants,
+		Total:       store.TenantStorage{KeysByState: map[key.State]int64{}},
+		TopUsage:    []store.TenantUsage{},
+		GeneratedAt: now,
+		Duration:    d,
+	}
+	for _, ts := range tenants {
+		t := &report.Total
+		t.Keys += ts.Keys
+		for state, n := range ts.KeysByState {
+			t.KeysByState[state] += n
+		}
+		t.Policies += ts.Policies
+		t.Scopes += ts.Scopes
+		t.Rotations += ts.Rotations
+		t.UsageRows += ts.UsageRows
+		t.UsageBytes += ts.UsageBytes
+		if ts.UsageRows > 0 {
+			report.TopUsage = append(report.TopUsage, store.TenantUsage{
+				TenantID: ts.TenantID, UsageRows: ts.UsageRows, UsageBytes: ts.UsageBytes,
+			})
+		}
+	}
+	sort.SliceStable(report.TopUsage, func(i, j int) bool {
+		a, b := report.TopUsage[i], report.TopUsage[j]
+		if a.UsageRows != b.UsageRows {
+			return a.UsageRows > b.UsageRows
+		}
+		if a.UsageBytes != b.UsageBytes {
+			return a.UsageBytes > b.UsageBytes
+		}
+		return a.TenantID < b.TenantID
+	})
+	if top >= 0 && len(report.TopUsage) > top {
+		report.TopUsage = report.TopUsage[:top]
+	}
+	return report
+}
+
+// runStorageReport is the body of the scheduled storage report job.
+func (e *Engine) runStorageReport(ctx context.Context) {
+	report, err := e.TenantStorageReport(ctx)
+	if err != nil {
+		e.logger.Warn("storage report failed", log.Any("error", err))
+		return
+	}
+	e.logger.Info("storage report generated",
+		log.Int("tenants", len(report.Tenants)),
+		log.Int64("keys", report.Total.Keys),
+		log.Int64("usage_rows", report.Total.UsageRows),
+		log.Int64("usage_bytes", report.Total.UsageBytes),
+		log.Duration("duration", report.Duration),
+	)
+	_ = e.hooks.FireStorageReportGenerated(ctx, report, e.eventMeta(ctx, plugin.TriggerSweep, plugin.ReasonStorageReport))
+}
@@ -0,0 +1,138 @@
+package keysmith_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keysmithtest"
+	"github.com/xraph/keysmith/plugin"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/store/memory"
+	"github.com/xraph/keysmith/usage"
+)
+
+// seedTenantStorage creates keys in tenant and records requests usage
+// records against the first.
+func seedTenantStorage(t *testing.T, eng *keysmith.Engine, tenantID string, keys, requests int) {
+	t.Helper()
+	ctx := keysmith.WithTenant(context.Background(), "app_test", tenantID)
+	var first *key.Key
+	for i := range keys {
+		res, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: fmt.Sprintf("K%d", i), Prefix: "sk", Environment: key.EnvTest})
+		require.NoError(t, err)
+		if first == nil {
+			first = res.Key
+		}
+	}
+	for range requests {
+		require.NoError(t, eng.RecordUsage(ctx, &usage.Record{KeyID: first.ID, Endpoint: "/v1/things", Method: "GET", StatusCode: 200}))
+	}
+}
+
+func TestTenantStorageReport(t *testing.T) {
+	eng := newTestEngine(t)
+	seedTenantStorage(t, eng, "tenant_a", 2, 3)
+	seedTenantStorage(t, eng, "tenant_b", 1, 5)
+	seedTenantStorage(t, eng, "tenant_c", 1, 0)
+	ctx := keysmith.WithTenant(context.Background(), "app_test", "tenant_a")
+	require.NoError(t, eng.CreatePolicy(ctx, &policy.Policy{Name: "Standard", RateLimit: 10, RateLimitWindow: time.Minute}))
+	keys, err := eng.ListKeys(ctx, &key.ListFilter{})
+	require.NoError(t, err)
+	require.NoError(t, eng.SuspendKey(ctx, keys[0].ID))
+
+	report, err := eng.TenantStorageReportWith(context.Background(), keysmith.StorageReportOptions{Top: 1})
+	require.NoError(t, err)
+	require.Len(t, report.Tenants, 3)
+	a := report.Tenants[0]
+	assert.Equal(t, "tenant_a", a.TenantID)
+	assert.EqualValues(t, 2, a.Keys)
+	assert.Equal(t, map[key.State]int64{key.StateActive: 1, key.StateSuspended: 1}, a.KeysByState)
+	assert.EqualValues(t, 1, a.Policies)
+	assert.EqualValues(t, 3, a.UsageRows)
+	assert.Positive(t, a.UsageBytes)
+
+	assert.EqualValues(t, 4, report.Total.Keys)
+	assert.EqualValues(t, 8, report.Total.UsageRows)
+	assert.Equal(t, map[key.State]int64{key.StateActive: 3, key.StateSuspended: 1}, report.Total.KeysByState)
+	require.Len(t, report.TopUsage, 1, "limited to top")
+	assert.Equal(t, "tenant_b", report.TopUsage[0].TenantID)
+	assert.EqualValues(t, 5, report.TopUsage[0].UsageRows)
+	assert.False(t, report.GeneratedAt.IsZero())
+
+	report, err = eng.TenantStorageReport(context.Background())
+	require.NoError(t, err)
+	require.Len(t, report.TopUsage, 2, "tenants without usage are not ranked")
+	assert.Equal(t, "tenant_a", report.TopUsage[1].TenantID)
+
+	report, err = eng.TenantStorageReportWith(context.Background(), keysmith.StorageReportOptions{TenantID: "tenant_c"})
+	require.NoError(t, err)
+	require.Len(t, report.Tenants, 1)
+	assert.EqualValues(t, 1, report.Total.Keys)
+	assert.Empty(t, report.TopUsage)
+}
+
+func TestTenantStorage(t *testing.T) {
+	eng := newTestEngine(t)
+	seedTenantStorage(t, eng, "tenant_a", 2, 3)
+	seedTenantStorage(t, eng, "tenant_b", 1, 1)
+
+	ts, err := eng.TenantStorage(context.Background(), "tenant_a")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, ts.Keys)
+	assert.EqualValues(t, 3, ts.UsageRows)
+
+	ts, err = eng.TenantStorage(context.Background(), "tenant_none")
+	require.NoError(t, err)
+	assert.Equal(t, "tenant_none", ts.TenantID)
+	assert.Zero(t, ts.Keys)
+}
+
+func TestTenantStorageReport_RequiresSystemScope(t *testing.T) {
+	eng := newTestEngine(t)
+
+	_, err := eng.TenantStorageReport(testCtx())
+	assert.ErrorIs(t, err, keysmith.ErrSystemScopeRequired)
+	_, err = eng.TenantStorage(testCtx(), "tenant_test")
+	assert.ErrorIs(t, err, keysmith.ErrSystemScopeRequired)
+}
+
+func TestTenantStorageReport_Unsupported(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(plainStore{memory.New()}))
+	require.NoError(t, err)
+
+	_, err = eng.TenantStorageReport(context.Background())
+	assert.ErrorIs(t, err, keysmith.ErrStorageReportUnsupported)
+}
+
+func TestWithStorageReports_FiresHook(t *testing.T) {
+	rec := keysmithtest.NewRecorder()
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithExtension(rec),
+		keysmith.WithStorageReports(10*time.Millisecond),
+	)
+	require.NoError(t, err)
+	seedTenantStorage(t, eng, "tenant_a", 1, 2)
+	require.NoError(t, eng.Start(context.Background()))
+
+	require.Eventually(t, func() bool { return rec.Count("StorageReportGenerated") >= 2 }, time.Second, 5*time.Millisecond)
+	require.NoError(t, eng.Stop(context.Background()))
+
+	evt := rec.Filter("StorageReportGenerated")[0]
+	require.NotNil(t, evt.StorageReport)
+	require.Len(t, evt.StorageReport.Tenants, 1)
+	assert.EqualValues(t, 2, evt.StorageReport.Tenants[0].UsageRows)
+	assert.Equal(t, plugin.TriggerSweep, evt.Meta.Trigger)
+	assert.Equal(t, plugin.ReasonStorageReport, evt.Meta.ReasonCode)
+
+	n := rec.Count("StorageReportGenerated")
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, n, rec.Count("StorageReportGenerated"), "the job stops with the engine")
+}
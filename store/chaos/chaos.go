@@ -0,0 +1,552 @@
+// Package chaos wraps any store.Store with scripted fault injection, for
+// testing how services behave when the keysmith backend degrades.
+//
+// Faults are described by [Rule] values set at runtime: each targets a
+// sub-store and method, fires with a probability, and adds latency, returns
+// an error, or returns an empty or stale result. Random draws come from a
+// seeded source, so a failing scenario can be replayed with [WithSeed], and
+// every fault that fires is logged for [Store.Injections].
+//
+//	s := chaos.New(memory.New(), chaos.WithSeed(42))
+//	eng, err := keysmith.NewEngine(keysmith.WithStore(s))
+//	err = s.Set(chaos.Rule{Name: "slow-lookups", Store: chaos.StoreKeys, Method: "GetByHash",
+//		Probability: 0.2, Latency: chaos.Latency{Min: 200 * time.Millisecond}, Error: chaos.ErrorTimeout})
+//
+// While no rules are set, the sub-store accessors return the wrapped
+// store's own sub-stores, so the wrapper adds nothing to a call. Sub-stores
+// obtained earlier keep following the rules as they change; the engine
+// fetches them on every call. Store-specific interfaces such as
+// store.Maintainer are reached through Unwrap and are not faulted.
+package chaos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xraph/keysmith/capture"
+	"github.com/xraph/keysmith/group"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keyevent"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/quotapool"
+	"github.com/xraph/keysmith/revocation"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/tenant"
+	"github.com/xraph/keysmith/tombstone"
+	"github.com/xraph/keysmith/transfer"
+	"github.com/xraph/keysmith/usage"
+)
+
+const (
+	// DefaultMaxInjections is how many injections Injections keeps by
+	// default.
+	DefaultMaxInjections = 1000
+
+	// maxStaleResults bounds the results a ResultStale rule remembers;
+	// calls with new arguments beyond it go through unremembered.
+	maxStaleResults = 10_000
+)
+
+var (
+	// ErrInjected is matched by every error a rule returns.
+	ErrInjected = errors.New("chaos: injected fault")
+
+	// ErrInvalidRule is returned by Set for a rule with no name, an unknown
+	// store, error kind, result mode, or latency distribution, a
+	// probability outside [0, 1], or a negative latency.
+	ErrInvalidRule = errors.New("chaos: invalid rule")
+)
+
+var (
+	_ store.Store     = (*Store)(nil)
+	_ store.Unwrapper = (*Store)(nil)
+)
+
+// Injection records a fault that fired.
+type Injection struct {
+	At     time.Time `json:"at"`
+	Rule   string    `json:"rule"`
+	Store  string    `json:"store"`
+	Method string    `json:"method"`
+
+	Latency time.Duration `json:"latency,omitempty"`
+	Error   string        `json:"error,omitempty"`
+
+	// Result is the result mode applied, if any.
+	Result ResultMode `json:"result,omitempty"`
+}
+
+// Option configures a Store.
+type Option func(*Store)
+
+// WithSeed seeds the random source behind probabilities and latencies. The
+// same seed and the same sequence of calls fire the same faults. By default
+// the seed is random; Seed reports it.
+func WithSeed(seed uint64) Option {
+	return func(s *Store) { s.seed = seed }
+}
+
+// WithMaxInjections sets how many of the most recent injections are kept.
+// Defaults to DefaultMaxInjections.
+func WithMaxInjections(n int) Option {
+	return func(s *Store) {
+		if n > 0 {
+			s.maxInjections = n
+		}
+	}
+}
+
+// Store injects faults into the calls it passes to the wrapped store.
+type Store struct {
+	inner store.Store
+
+	seed          uint64
+	maxInjections int
+
+	// rules is replaced wholesale by Set, Remove, and Clear, so calls read
+	// it with a single load. It is nil while no rules are set.
+	rules atomic.Pointer[[]*activeRule]
+
+	mu   sync.Mutex // guards rng, seq, and log, and serializes rule changes
+	rng  *rand.Rand
+	seq  int
+	log  []Injection
+	head int
+}
+
+type activeRule struct {
+	Rule
+
+	mu    sync.Mutex
+	stale map[string]any
+}
+
+// New wraps inner. It has no rules until Set is called.
+func New(inner store.Store, opts ...Option) *Store {
+	s := &Store{inner: inner, seed: rand.Uint64(), maxInjections: DefaultMaxInjections}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.rng = rand.New(rand.NewPCG(s.seed, s.seed))
+	return s
+}
+
+// Unwrap returns the wrapped store.
+func (s *Store) Unwrap() store.Store { return s.inner }
+
+// Seed returns the seed of the random source, for replaying a scenario
+// with WithSeed.
+func (s *Store) Seed() uint64 { return s.seed }
+
+// Set adds r, replacing any rule with the same name. Rules are checked in
+// the order they were first set; every matching rule rolls, their
+// latencies add up, and the first error or result mode among those that
+// fired applies.
+func (s *Store) Set(r Rule) error {
+	if err := r.validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var next []*activeRule
+	replaced := false
+	for _, ar := range s.active() {
+		if ar.Name == r.Name {
+			ar = &activeRule{Rule: r}
+			replaced = true
+		}
+		next = append(next, ar)
+	}
+	if !replaced {
+		next = append(next, &activeRule{Rule: r})
+	}
+	s.rules.Store(&next)
+	return nil
+}
+
+// Remove deletes the rule with the given name, if there is one.
+func (s *Store) Remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var next []*activeRule
+	for _, ar := range s.active() {
+		if ar.Name != name {
+			next = append(next, ar)
+		}
+	}
+	if len(next) == 0 {
+		s.rules.Store(nil)
+		return
+	}
+	s.rules.Store(&next)
+}
+
+// Clear deletes every rule, returning the store to pass-through.
+func (s *Store) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules.Store(nil)
+}
+
+// Rules returns the rules set, in the order they are checked.
+func (s *Store) Rules() []Rule {
+	active := s.active()
+	out := make([]Rule, len(active))
+	for i, ar := range active {
+		out[i] = ar.Rule
+	}
+	return out
+}
+
+// Injections returns the recorded injections, oldest first.
+func (s *Store) Injections() []Injection {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Injection, 0, len(s.log))
+	out = append(out, s.log[s.head:]...)
+	return append(out, s.log[:s.head]...)
+}
+
+// ResetInjections forgets the recorded injections.
+func (s *Store) ResetInjections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.log, s.head = nil, 0
+}
+
+func (s *Store) active() []*activeRule {
+	if p := s.rules.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+func (s *Store) record(in Injection) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.log) < s.maxInjections {
+		s.log = append(s.log, in)
+		return
+	}
+	s.log[s.head] = in
+	s.head = (s.head + 1) % len(s.log)
+}
+
+// fires rolls r and, if it fires, samples its latency.
+func (s *Store) fires(r *activeRule) (bool, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Probability > 0 && s.rng.Float64() >= r.Probability {
+		return false, 0
+	}
+	return true, r.Latency.sample(s.rng)
+}
+
+// Keys returns the key store.
+func (s *Store) Keys() key.Store {
+	if s.rules.Load() == nil {
+		return s.inner.Keys()
+	}
+	return &keyStore{inner: s.inner.Keys(), c: s}
+}
+
+// Policies returns the policy store.
+func (s *Store) Policies() policy.Store {
+	if s.rules.Load() == nil {
+		return s.inner.Policies()
+	}
+	return &policyStore{inner: s.inner.Policies(), c: s}
+}
+
+// Usages returns the usage store.
+func (s *Store) Usages() usage.Store {
+	if s.rules.Load() == nil {
+		return s.inner.Usages()
+	}
+	return &usageStore{inner: s.inner.Usages(), c: s}
+}
+
+// Rotations returns the rotation store.
+func (s *Store) Rotations() rotation.Store {
+	if s.rules.Load() == nil {
+		return s.inner.Rotations()
+	}
+	return &rotationStore{inner: s.inner.Rotations(), c: s}
+}
+
+// Scopes returns the scope store.
+func (s *Store) Scopes() scope.Store {
+	if s.rules.Load() == nil {
+		return s.inner.Scopes()
+	}
+	return &scopeStore{inner: s.inner.Scopes(), c: s}
+}
+
+// Tenants returns the tenant settings store.
+func (s *Store) Tenants() tenant.Store {
+	if s.rules.Load() == nil {
+		return s.inner.Tenants()
+	}
+	return &tenantStore{inner: s.inner.Tenants(), c: s}
+}
+
+// Revocations returns the revocation feed store.
+func (s *Store) Revocations() revocation.Store {
+	if s.rules.Load() == nil {
+		return s.inner.Revocations()
+	}
+	return &revocationStore{inner: s.inner.Revocations(), c: s}
+}
+
+// Captures returns the debug capture store.
+func (s *Store) Captures() capture.Store {
+	if s.rules.Load() == nil {
+		return s.inner.Captures()
+	}
+	return &captureStore{inner: s.inner.Captures(), c: s}
+}
+
+// Groups returns the key group store.
+func (s *Store) Groups() group.Store {
+	if s.rules.Load() == nil {
+		return s.inner.Groups()
+	}
+	return &groupStore{inner: s.inner.Groups(), c: s}
+}
+
+// KeyEvents returns the key event history store.
+func (s *Store) KeyEvents() keyevent.Store {
+	if s.rules.Load() == nil {
+		return s.inner.KeyEvents()
+	}
+	return &keyEventStore{inner: s.inner.KeyEvents(), c: s}
+}
+
+// Transfers returns the key transfer store.
+func (s *Store) Transfers() transfer.Store {
+	if s.rules.Load() == nil {
+		return s.inner.Transfers()
+	}
+	return &transferStore{inner: s.inner.Transfers(), c: s}
+}
+
+// Tombstones returns the hash tombstone store.
+func (s *Store) Tombstones() tombstone.Store {
+	if s.rules.Load() == nil {
+		return s.inner.Tombstones()
+	}
+	return &tombstoneStore{inner: s.inner.Tombstones(), c: s}
+}
+
+// QuotaPools returns the quota pool store.
+func (s *Store) QuotaPools() quotapool.Store {
+	if s.rules.Load() == nil {
+		return s.inner.QuotaPools()
+	}
+	return &quotaPoolStore{inner: s.inner.QuotaPools(), c: s}
+}
+
+// Migrate runs the wrapped store's migrations.
+func (s *Store) Migrate(ctx context.Context) error {
+	return exec(ctx, s, call{StoreLifecycle, "Migrate", kindWrite, nil}, func() error { return s.inner.Migrate(ctx) })
+}
+
+// Ping checks the wrapped store's connectivity.
+func (s *Store) Ping(ctx context.Context) error {
+	return exec(ctx, s, call{StoreLifecycle, "Ping", kindWrite, nil}, func() error { return s.inner.Ping(ctx) })
+}
+
+// Close closes the wrapped store. It is never faulted.
+func (s *Store) Close() error { return s.inner.Close() }
+
+// kind classifies a method by the result modes that apply to it.
+type kind int
+
+const (
+	kindWrite kind = iota // no result modes
+	kindRead              // ResultStale
+	kindList              // ResultEmpty and ResultStale
+	kindIter              // ResultEmpty
+)
+
+// call describes one method call for rule matching. args identify the
+// call's arguments for ResultStale.
+type call struct {
+	store  string
+	method string
+	kind   kind
+	args   []any
+}
+
+// exec is run for methods that return only an error.
+func exec(ctx context.Context, s *Store, c call, fn func() error) error {
+	_, err := run(ctx, s, c, func() (struct{}, error) { return struct{}{}, fn() })
+	return err
+}
+
+// run calls fn under the rules matching c.
+func run[T any](ctx context.Context, s *Store, c call, fn func() (T, error)) (T, error) {
+	var zero T
+	// A canceled call would not reach the backend, so no rule fires for it.
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+	rules := s.active()
+	if len(rules) == 0 {
+		return fn()
+	}
+
+	var (
+		delay    time.Duration
+		injected error
+		result   ResultMode
+		stale    []*activeRule
+		served   any
+		argKey   string
+	)
+	for _, r := range rules {
+		if !r.matches(c) {
+			continue
+		}
+		staleApplies := r.Result == ResultStale && c.kind != kindWrite && c.kind != kindIter
+		if staleApplies {
+			stale = append(stale, r)
+		}
+		fired, d := s.fires(r)
+		if !fired {
+			continue
+		}
+		in := Injection{At: time.Now(), Rule: r.Name, Store: c.store, Method: c.method, Latency: d}
+		delay += d
+		if injected == nil && result == ResultUnchanged {
+			if err := r.err(c.store, c.method); err != nil {
+				injected = err
+				in.Error = err.Error()
+			} else if r.Result == ResultEmpty && (c.kind == kindList || c.kind == kindIter) {
+				result = ResultEmpty
+				in.Result = ResultEmpty
+			} else if staleApplies {
+				if argKey == "" {
+					argKey = c.key()
+				}
+				if v, ok := r.remembered(argKey); ok {
+					result, served = ResultStale, v
+					in.Result = ResultStale
+				}
+			}
+		}
+		if in.Latency > 0 || in.Error != "" || in.Result != ResultUnchanged {
+			s.record(in)
+		}
+	}
+
+	if delay > 0 {
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return zero, ctx.Err()
+		}
+	}
+	switch {
+	case injected != nil:
+		return zero, injected
+	case result == ResultEmpty:
+		return empty[T](), nil
+	case result == ResultStale:
+		return snapshot(served).(T), nil
+	}
+
+	v, err := fn()
+	if err == nil && len(stale) > 0 {
+		if argKey == "" {
+			argKey = c.key()
+		}
+		for _, r := range stale {
+			r.remember(argKey, v)
+		}
+	}
+	return v, err
+}
+
+func (c call) key() string {
+	b, err := json.Marshal(c.args)
+	if err != nil {
+		return c.method
+	}
+	return c.method + "\x00" + string(b)
+}
+
+func (r *activeRule) remembered(k string) (any, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v, ok := r.stale[k]
+	return v, ok
+}
+
+func (r *activeRule) remember(k string, v any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stale == nil {
+		r.stale = make(map[string]any)
+	}
+	if _, ok := r.stale[k]; ok || len(r.stale) >= maxStaleResults {
+		return
+	}
+	r.stale[k] = snapshot(v)
+}
+
+// empty returns an empty slice for slice types and the zero value
+// otherwise.
+func empty[T any]() T {
+	var v T
+	rv := reflect.ValueOf(&v).Elem()
+	if rv.Kind() == reflect.Slice {
+		rv.Set(reflect.MakeSlice(rv.Type(), 0, 0))
+	}
+	return v
+}
+
+// snapshot copies the structs behind v, a pointer or a slice of pointers,
+// so that callers mutating a stale result do not change the remembered
+// one. Fields are copied shallowly.
+func snapshot(v any) any {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return v
+	}
+	return copyValue(rv).Interface()
+}
+
+func copyValue(rv reflect.Value) reflect.Value {
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return rv
+		}
+		cp := reflect.New(rv.Elem().Type())
+		cp.Elem().Set(rv.Elem())
+		return cp
+	case reflect.Slice:
+		if rv.IsNil() {
+			return rv
+		}
+		cp := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+		for i := range rv.Len() {
+			cp.Index(i).Set(copyValue(rv.Index(i)))
+		}
+		return cp
+	}
+	return rv
+}
@@ -0,0 +1,370 @@
+package chaos_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/store/chaos"
+	"github.com/xraph/keysmith/store/memory"
+	"github.com/xraph/keysmith/store/storetest"
+)
+
+func tenantCtx() context.Context {
+	return keysmith.WithTenant(context.Background(), "app_test", "tenant_test")
+}
+
+func seeded(t *testing.T, s *chaos.Store, raws ...string) []*key.Key {
+	t.Helper()
+	keys := make([]*key.Key, len(raws))
+	for i, raw := range raws {
+		keys[i] = storetest.NewKey("tenant_test", raw)
+		require.NoError(t, s.Keys().Create(context.Background(), keys[i]))
+	}
+	return keys
+}
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store { return chaos.New(memory.New()) })
+}
+
+func TestConformance_WithIdleRules(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		s := chaos.New(memory.New())
+		require.NoError(t, s.Set(chaos.Rule{Name: "unmatched", Store: chaos.StoreCaptures, Error: chaos.ErrorInjected}))
+		return s
+	})
+}
+
+func TestPassThrough(t *testing.T) {
+	inner := memory.New()
+	s := chaos.New(inner)
+	assert.IsType(t, inner.Keys(), s.Keys(), "no rules, no wrapper")
+	assert.Same(t, inner, s.Unwrap())
+
+	require.NoError(t, s.Set(chaos.Rule{Name: "r", Store: chaos.StoreKeys, Error: chaos.ErrorInjected}))
+	assert.NotEqual(t, inner.Keys(), s.Keys())
+
+	s.Clear()
+	assert.IsType(t, inner.Keys(), s.Keys())
+	seeded(t, s, "sk_test_a")
+	assert.Empty(t, s.Injections())
+}
+
+func TestErrorKinds(t *testing.T) {
+	for kind, cause := range map[chaos.ErrorKind]error{
+		chaos.ErrorInjected:      chaos.ErrInjected,
+		chaos.ErrorTimeout:       context.DeadlineExceeded,
+		chaos.ErrorConnReset:     syscall.ECONNRESET,
+		chaos.ErrorConnRefused:   syscall.ECONNREFUSED,
+		chaos.ErrorBadConn:       driver.ErrBadConn,
+		chaos.ErrorUnexpectedEOF: io.ErrUnexpectedEOF,
+	} {
+		s := chaos.New(memory.New())
+		require.NoError(t, s.Set(chaos.Rule{Name: "fail", Store: chaos.StoreKeys, Method: "Get", Error: kind}))
+
+		_, err := s.Keys().Get(context.Background(), id.NewKeyID())
+		assert.ErrorIs(t, err, chaos.ErrInjected, kind)
+		assert.ErrorIs(t, err, cause, kind)
+		var injected *chaos.Error
+		require.ErrorAs(t, err, &injected)
+		assert.Equal(t, "fail", injected.Rule)
+		assert.Equal(t, chaos.StoreKeys, injected.Store)
+		assert.Equal(t, "Get", injected.Method)
+	}
+	assert.Len(t, chaos.TransientErrorKinds, 5)
+}
+
+func TestRule_CustomError(t *testing.T) {
+	s := chaos.New(memory.New())
+	notFound := errors.New("key not found")
+	require.NoError(t, s.Set(chaos.Rule{Name: "gone", Store: chaos.StoreKeys, Method: "GetByHash", Err: notFound}))
+
+	_, err := s.Keys().GetByHash(context.Background(), "hash")
+	assert.ErrorIs(t, err, notFound)
+	assert.ErrorIs(t, err, chaos.ErrInjected)
+}
+
+func TestRule_Matching(t *testing.T) {
+	s := chaos.New(memory.New())
+	keys := seeded(t, s, "sk_test_a")
+	require.NoError(t, s.Set(chaos.Rule{Name: "lookups", Store: chaos.StoreKeys, Method: "GetByHash", Error: chaos.ErrorTimeout}))
+
+	_, err := s.Keys().Get(context.Background(), keys[0].ID)
+	assert.NoError(t, err, "other methods pass")
+	_, err = s.Keys().GetByHash(context.Background(), keys[0].KeyHash)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	require.NoError(t, s.Set(chaos.Rule{Name: "everything", Store: "*", Error: chaos.ErrorConnRefused}))
+	assert.ErrorIs(t, s.Ping(context.Background()), syscall.ECONNREFUSED)
+	_, err = s.Policies().List(context.Background(), nil)
+	assert.ErrorIs(t, err, syscall.ECONNREFUSED)
+
+	s.Remove("everything")
+	assert.NoError(t, s.Ping(context.Background()))
+	assert.Equal(t, []string{"lookups"}, ruleNames(s))
+}
+
+func TestRule_Writes(t *testing.T) {
+	s := chaos.New(memory.New())
+	keys := seeded(t, s, "sk_test_a")
+	require.NoError(t, s.Set(chaos.Rule{Name: "read-only", Writes: true, Error: chaos.ErrorInjected}))
+
+	_, err := s.Keys().Get(context.Background(), keys[0].ID)
+	assert.NoError(t, err, "reads pass")
+	_, err = s.Policies().List(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Ping(context.Background()), "lifecycle calls are not writes")
+
+	assert.ErrorIs(t, s.Keys().UpdateState(context.Background(), keys[0].ID, key.StateRevoked), chaos.ErrInjected)
+	assert.ErrorIs(t, s.Keys().DeactivateHash(context.Background(), keys[0].ID, keys[0].KeyHash), chaos.ErrInjected)
+	_, err = s.Tenants().BumpRevision(context.Background(), "tenant_test")
+	assert.ErrorIs(t, err, chaos.ErrInjected)
+
+	methods := make([]string, 0, 3)
+	for _, in := range s.Injections() {
+		methods = append(methods, in.Store+"."+in.Method)
+	}
+	assert.Equal(t, []string{"Keys.UpdateState", "Keys.DeactivateHash", "Tenants.BumpRevision"}, methods)
+}
+
+func TestRule_SetReplaces(t *testing.T) {
+	s := chaos.New(memory.New())
+	require.NoError(t, s.Set(chaos.Rule{Name: "a", Error: chaos.ErrorInjected}))
+	require.NoError(t, s.Set(chaos.Rule{Name: "b", Error: chaos.ErrorInjected}))
+	require.NoError(t, s.Set(chaos.Rule{Name: "a", Error: chaos.ErrorTimeout}))
+
+	assert.Equal(t, []string{"a", "b"}, ruleNames(s), "replacing keeps the order")
+	assert.Equal(t, chaos.ErrorTimeout, s.Rules()[0].Error)
+}
+
+func TestRule_Invalid(t *testing.T) {
+	s := chaos.New(memory.New())
+	for name, r := range map[string]chaos.Rule{
+		"no name":      {Error: chaos.ErrorInjected},
+		"store":        {Name: "r", Store: "Widgets"},
+		"probability":  {Name: "r", Probability: 1.5},
+		"negative":     {Name: "r", Latency: chaos.Latency{Min: -time.Second}},
+		"distribution": {Name: "r", Latency: chaos.Latency{Distribution: "pareto"}},
+		"error":        {Name: "r", Error: "meltdown"},
+		"result":       {Name: "r", Result: "garbage"},
+	} {
+		assert.ErrorIs(t, s.Set(r), chaos.ErrInvalidRule, name)
+	}
+	assert.Empty(t, s.Rules())
+}
+
+func TestProbability_SeedIsDeterministic(t *testing.T) {
+	pattern := func(seed uint64) []bool {
+		s := chaos.New(memory.New(), chaos.WithSeed(seed))
+		require.NoError(t, s.Set(chaos.Rule{Name: "flaky", Store: chaos.StoreLifecycle, Method: "Ping", Probability: 0.3, Error: chaos.ErrorConnReset}))
+		out := make([]bool, 500)
+		for i := range out {
+			out[i] = s.Ping(context.Background()) != nil
+		}
+		return out
+	}
+
+	first := pattern(7)
+	assert.Equal(t, first, pattern(7))
+	assert.NotEqual(t, first, pattern(8))
+
+	failed := 0
+	for _, f := range first {
+		if f {
+			failed++
+		}
+	}
+	assert.InDelta(t, 150, failed, 50)
+	assert.Equal(t, uint64(7), chaos.New(memory.New(), chaos.WithSeed(7)).Seed())
+}
+
+func TestLatency(t *testing.T) {
+	s := chaos.New(memory.New(), chaos.WithSeed(1))
+	require.NoError(t, s.Set(chaos.Rule{Name: "slow", Store: chaos.StoreLifecycle, Latency: chaos.Latency{Min: 20 * time.Millisecond}}))
+
+	start := time.Now()
+	require.NoError(t, s.Ping(context.Background()))
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, s.Ping(ctx), context.DeadlineExceeded, "the delay honors the context")
+
+	inj := s.Injections()
+	require.Len(t, inj, 2)
+	assert.Equal(t, 20*time.Millisecond, inj[0].Latency)
+	assert.Equal(t, "Ping", inj[0].Method)
+}
+
+func TestLatency_Distributions(t *testing.T) {
+	s := chaos.New(memory.New(), chaos.WithSeed(3))
+	require.NoError(t, s.Set(chaos.Rule{Name: "uniform", Store: chaos.StoreUsages, Latency: chaos.Latency{
+		Distribution: chaos.LatencyUniform, Min: time.Microsecond, Max: 50 * time.Microsecond,
+	}}))
+	require.NoError(t, s.Set(chaos.Rule{Name: "tail", Store: chaos.StoreScopes, Latency: chaos.Latency{
+		Distribution: chaos.LatencyExponential, Mean: 10 * time.Microsecond, Max: 40 * time.Microsecond,
+	}}))
+	for range 50 {
+		_, _ = s.Usages().Count(context.Background(), nil)
+		_, _ = s.Scopes().List(context.Background(), nil)
+	}
+
+	for _, in := range s.Injections() {
+		switch in.Rule {
+		case "uniform":
+			assert.GreaterOrEqual(t, in.Latency, time.Microsecond)
+			assert.Less(t, in.Latency, 50*time.Microsecond)
+		case "tail":
+			assert.LessOrEqual(t, in.Latency, 40*time.Microsecond)
+		}
+	}
+}
+
+func TestResultEmpty(t *testing.T) {
+	s := chaos.New(memory.New())
+	keys := seeded(t, s, "sk_test_a", "sk_test_b")
+	require.NoError(t, s.Set(chaos.Rule{Name: "empty", Store: chaos.StoreKeys, Result: chaos.ResultEmpty}))
+
+	list, err := s.Keys().List(context.Background(), &key.ListFilter{})
+	require.NoError(t, err)
+	assert.NotNil(t, list)
+	assert.Empty(t, list)
+	n, err := s.Keys().Count(context.Background(), &key.ListFilter{})
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	visited := 0
+	require.NoError(t, s.Keys().Iterate(context.Background(), &key.ListFilter{}, func(*key.Key) error {
+		visited++
+		return nil
+	}))
+	assert.Zero(t, visited)
+
+	_, err = s.Keys().Get(context.Background(), keys[0].ID)
+	assert.NoError(t, err, "single-item reads are unaffected")
+}
+
+func TestResultStale(t *testing.T) {
+	s := chaos.New(memory.New())
+	keys := seeded(t, s, "sk_test_a")
+	require.NoError(t, s.Set(chaos.Rule{Name: "replica-lag", Store: chaos.StoreKeys, Result: chaos.ResultStale}))
+
+	first, err := s.Keys().Get(context.Background(), keys[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, key.StateActive, first.State)
+	assert.Empty(t, s.Injections(), "the first call is remembered, not faulted")
+
+	require.NoError(t, s.Keys().UpdateState(context.Background(), keys[0].ID, key.StateRevoked))
+	got, err := s.Keys().Get(context.Background(), keys[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, key.StateActive, got.State, "the stale copy is served")
+	got.Name = "mutated"
+
+	again, err := s.Keys().Get(context.Background(), keys[0].ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, "mutated", again.Name, "callers cannot change the remembered result")
+
+	inj := s.Injections()
+	require.Len(t, inj, 2)
+	assert.Equal(t, chaos.ResultStale, inj[0].Result)
+
+	s.Clear()
+	got, err = s.Keys().Get(context.Background(), keys[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, key.StateRevoked, got.State)
+}
+
+func TestInjections_Bounded(t *testing.T) {
+	s := chaos.New(memory.New(), chaos.WithMaxInjections(3))
+	require.NoError(t, s.Set(chaos.Rule{Name: "down", Store: chaos.StoreLifecycle, Error: chaos.ErrorInjected}))
+	for range 5 {
+		_ = s.Ping(context.Background())
+	}
+	assert.Len(t, s.Injections(), 3)
+
+	s.ResetInjections()
+	assert.Empty(t, s.Injections())
+}
+
+// The scenarios below show engine behavior under injected faults.
+
+func TestScenario_ValidationCacheRidesOutLookupOutage(t *testing.T) {
+	s := chaos.New(memory.New())
+	eng, err := keysmith.NewEngine(keysmith.WithStore(s), keysmith.WithValidationCache(time.Minute, 100))
+	require.NoError(t, err)
+	ctx := tenantCtx()
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Job", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	_, err = eng.ValidateKey(ctx, created.RawKey)
+	require.NoError(t, err)
+
+	require.NoError(t, s.Set(chaos.Rule{Name: "lookups-down", Store: chaos.StoreKeys, Method: "GetByHash", Error: chaos.ErrorConnReset}))
+	_, err = eng.ValidateKey(ctx, created.RawKey)
+	assert.NoError(t, err, "cached keys keep validating")
+
+	other, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "New", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	_, err = eng.ValidateKey(ctx, other.RawKey)
+	assert.Error(t, err, "uncached keys need the store")
+}
+
+func TestScenario_HealthReportsStoreOutage(t *testing.T) {
+	s := chaos.New(memory.New())
+	eng, err := keysmith.NewEngine(keysmith.WithStore(s))
+	require.NoError(t, err)
+	require.NoError(t, eng.Health(context.Background()))
+
+	require.NoError(t, s.Set(chaos.Rule{Name: "db-down", Store: chaos.StoreLifecycle, Method: "Ping", Error: chaos.ErrorConnRefused}))
+	assert.ErrorIs(t, eng.Health(context.Background()), syscall.ECONNREFUSED)
+	assert.ErrorIs(t, eng.HealthReport(context.Background()).Err, chaos.ErrInjected)
+}
+
+func TestScenario_RetryingCallerSurvivesFlakyStore(t *testing.T) {
+	s := chaos.New(memory.New(), chaos.WithSeed(11))
+	eng, err := keysmith.NewEngine(keysmith.WithStore(s))
+	require.NoError(t, err)
+	ctx := tenantCtx()
+	created, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Job", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+
+	require.NoError(t, s.Set(chaos.Rule{
+		Name: "flaky", Store: chaos.StoreKeys, Method: "Get", Probability: 0.5,
+		Latency: chaos.Latency{Min: time.Millisecond}, Error: chaos.ErrorTimeout,
+	}))
+	validate := func() (attempts int, err error) {
+		for attempts = 1; attempts <= 8; attempts++ {
+			if _, err = eng.GetKey(ctx, created.Key.ID); !errors.Is(err, context.DeadlineExceeded) {
+				return attempts, err
+			}
+		}
+		return attempts, err
+	}
+
+	retried := false
+	for range 20 {
+		attempts, err := validate()
+		require.NoError(t, err, "seed %d", s.Seed())
+		retried = retried || attempts > 1
+	}
+	assert.True(t, retried)
+	assert.NotEmpty(t, s.Injections())
+}
+
+func ruleNames(s *chaos.Store) []string {
+	var names []string
+	for _, r := range s.Rules() {
+		names = append(names, r.Name)
+	}
+	return names
+}
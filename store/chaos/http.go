@@ -0,0 +1,69 @@
+//go:build chaoshttp
+
+package chaos
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// Handler returns a debug HTTP API for scripting scenarios from outside the
+// process, such as from a load test. It is compiled only with the chaoshttp
+// build tag, so production binaries cannot expose it by accident; mount it
+// on an internal listener.
+//
+//	GET    /rules          list the rules
+//	PUT    /rules/{name}   set a rule from a JSON Rule body
+//	DELETE /rules/{name}   remove a rule
+//	DELETE /rules          remove every rule
+//	GET    /injections     list the recorded injections
+//	DELETE /injections     forget them
+//
+// Durations in rule bodies are nanoseconds. Err cannot be set over HTTP;
+// use Error.
+func (s *Store) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /rules", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, s.Rules())
+	})
+	mux.HandleFunc("PUT /rules/{name}", func(w http.ResponseWriter, r *http.Request) {
+		var rule Rule
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		rule.Name = r.PathValue("name")
+		if err := s.Set(rule); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, ErrInvalidRule) {
+				status = http.StatusBadRequest
+			}
+			writeJSON(w, status, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, rule)
+	})
+	mux.HandleFunc("DELETE /rules/{name}", func(w http.ResponseWriter, r *http.Request) {
+		s.Remove(r.PathValue("name"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("DELETE /rules", func(w http.ResponseWriter, _ *http.Request) {
+		s.Clear()
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /injections", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, s.Injections())
+	})
+	mux.HandleFunc("DELETE /injections", func(w http.ResponseWriter, _ *http.Request) {
+		s.ResetInjections()
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,56 @@
+//go:build chaoshttp
+
+package chaos_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith/store/chaos"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+func TestHandler(t *testing.T) {
+	s := chaos.New(memory.New())
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	do := func(method, path, body string) *http.Response {
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	resp := do(http.MethodPut, "/rules/db-down", `{"store":"Store","method":"Ping","error":"connection_refused"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.ErrorIs(t, s.Ping(context.Background()), chaos.ErrInjected)
+
+	resp = do(http.MethodGet, "/injections", "")
+	var inj []chaos.Injection
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&inj))
+	require.Len(t, inj, 1)
+	assert.Equal(t, "db-down", inj[0].Rule)
+
+	resp = do(http.MethodPut, "/rules/bad", `{"probability":2}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = do(http.MethodGet, "/rules", "")
+	var rules []chaos.Rule
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&rules))
+	require.Len(t, rules, 1)
+
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/rules/db-down", "").StatusCode)
+	assert.NoError(t, s.Ping(context.Background()))
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/injections", "").StatusCode)
+	assert.Empty(t, s.Injections())
+}
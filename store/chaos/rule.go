@@ -0,0 +1,266 @@
+package chaos
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"syscall"
+	"time"
+)
+
+// Sub-store names a Rule can target, matching the store.Store accessors.
+// StoreLifecycle targets Migrate and Ping.
+const (
+	StoreKeys        = "Keys"
+	StorePolicies    = "Policies"
+	StoreUsages      = "Usages"
+	StoreRotations   = "Rotations"
+	StoreScopes      = "Scopes"
+	StoreTenants     = "Tenants"
+	StoreRevocations = "Revocations"
+	StoreCaptures    = "Captures"
+	StoreGroups      = "Groups"
+	StoreKeyEvents   = "KeyEvents"
+	StoreTransfers   = "Transfers"
+	StoreTombstones  = "Tombstones"
+	StoreQuotaPools  = "QuotaPools"
+	StoreLifecycle   = "Store"
+)
+
+var storeNames = []string{
+	StoreKeys, StorePolicies, StoreUsages, StoreRotations, StoreScopes,
+	StoreTenants, StoreRevocations, StoreCaptures, StoreGroups, StoreKeyEvents,
+	StoreTransfers, StoreTombstones, StoreQuotaPools, StoreLifecycle,
+}
+
+// ErrorKind selects the error a Rule returns.
+type ErrorKind string
+
+const (
+	// ErrorNone returns no error; the rule only adds latency or changes
+	// results.
+	ErrorNone ErrorKind = ""
+
+	// ErrorInjected returns ErrInjected alone.
+	ErrorInjected ErrorKind = "injected"
+
+	// ErrorTimeout wraps context.DeadlineExceeded.
+	ErrorTimeout ErrorKind = "timeout"
+
+	// ErrorConnReset wraps syscall.ECONNRESET.
+	ErrorConnReset ErrorKind = "connection_reset"
+
+	// ErrorConnRefused wraps syscall.ECONNREFUSED.
+	ErrorConnRefused ErrorKind = "connection_refused"
+
+	// ErrorBadConn wraps driver.ErrBadConn, which database/sql retries on
+	// another connection.
+	ErrorBadConn ErrorKind = "bad_conn"
+
+	// ErrorUnexpectedEOF wraps io.ErrUnexpectedEOF, as from a connection
+	// dropped mid-response.
+	ErrorUnexpectedEOF ErrorKind = "unexpected_eof"
+)
+
+// TransientErrorKinds lists the kinds a retrying caller should treat as
+// transient: every kind but ErrorInjected.
+var TransientErrorKinds = []ErrorKind{
+	ErrorTimeout, ErrorConnReset, ErrorConnRefused, ErrorBadConn, ErrorUnexpectedEOF,
+}
+
+func (k ErrorKind) cause() (error, bool) {
+	switch k {
+	case ErrorInjected:
+		return nil, true
+	case ErrorTimeout:
+		return context.DeadlineExceeded, true
+	case ErrorConnReset:
+		return syscall.ECONNRESET, true
+	case ErrorConnRefused:
+		return syscall.ECONNREFUSED, true
+	case ErrorBadConn:
+		return driver.ErrBadConn, true
+	case ErrorUnexpectedEOF:
+		return io.ErrUnexpectedEOF, true
+	}
+	return nil, false
+}
+
+// ResultMode selects how a Rule changes the result of a read.
+type ResultMode string
+
+const (
+	// ResultUnchanged leaves results alone.
+	ResultUnchanged ResultMode = ""
+
+	// ResultEmpty returns an empty list, a zero count, or an Iterate that
+	// visits nothing, without calling the wrapped store. Single-item reads
+	// and writes are unaffected.
+	ResultEmpty ResultMode = "empty"
+
+	// ResultStale returns the result of an earlier call with the same
+	// arguments, made while the rule was set, instead of calling the
+	// wrapped store. Calls with no earlier result go through and are
+	// remembered. Writes and Iterate are unaffected.
+	ResultStale ResultMode = "stale"
+)
+
+// Distribution is the shape of a Latency.
+type Distribution string
+
+const (
+	// LatencyFixed adds Min on every call.
+	LatencyFixed Distribution = "fixed"
+
+	// LatencyUniform adds a delay drawn uniformly from [Min, Max).
+	LatencyUniform Distribution = "uniform"
+
+	// LatencyExponential adds Min plus an exponentially distributed delay
+	// with mean Mean, capped at Max when Max is set. It models the long
+	// tail of a loaded database.
+	LatencyExponential Distribution = "exponential"
+)
+
+// Latency is a delay added before a call. The zero value adds none.
+type Latency struct {
+	// Distribution defaults to LatencyFixed.
+	Distribution Distribution `json:"distribution,omitempty"`
+
+	Min  time.Duration `json:"min,omitempty"`
+	Max  time.Duration `json:"max,omitempty"`
+	Mean time.Duration `json:"mean,omitempty"`
+}
+
+func (l Latency) sample(r *rand.Rand) time.Duration {
+	switch l.Distribution {
+	case LatencyUniform:
+		if l.Max <= l.Min {
+			return l.Min
+		}
+		return l.Min + time.Duration(r.Int64N(int64(l.Max-l.Min)))
+	case LatencyExponential:
+		d := l.Min + time.Duration(r.ExpFloat64()*float64(l.Mean))
+		if l.Max > 0 && d > l.Max {
+			d = l.Max
+		}
+		return d
+	}
+	return l.Min
+}
+
+func (l Latency) validate() error {
+	switch l.Distribution {
+	case "", LatencyFixed, LatencyUniform, LatencyExponential:
+	default:
+		return fmt.Errorf("unknown latency distribution %q", l.Distribution)
+	}
+	if l.Min < 0 || l.Max < 0 || l.Mean < 0 {
+		return errors.New("latency must not be negative")
+	}
+	return nil
+}
+
+// Rule injects a fault into the calls it matches. A matching call rolls
+// against Probability; when the rule fires, the call sleeps for Latency,
+// then fails with the rule's error or has its result changed by Result.
+// A rule with no error and no result mode only adds latency.
+type Rule struct {
+	// Name identifies the rule; setting a rule with the same name
+	// replaces it.
+	Name string `json:"name"`
+
+	// Store is the sub-store to target, one of the Store constants, and
+	// Method the method on it, such as "GetByHash". Empty or "*" matches
+	// any.
+	Store  string `json:"store,omitempty"`
+	Method string `json:"method,omitempty"`
+
+	// Writes restricts the rule to the methods that change stored data,
+	// such as Create, UpdateState, or Append. Migrate and Ping are not
+	// writes.
+	Writes bool `json:"writes,omitempty"`
+
+	// Probability is the chance, from 0 to 1, that the rule fires on a
+	// matching call. Zero fires on every call.
+	Probability float64 `json:"probability,omitempty"`
+
+	Latency Latency `json:"latency"`
+
+	// Error selects the error returned when the rule fires. Err, when
+	// set, is returned instead, for errors such as a backend's not-found
+	// sentinel. Either way the error matches ErrInjected.
+	Error ErrorKind `json:"error,omitempty"`
+	Err   error     `json:"-"`
+
+	Result ResultMode `json:"result,omitempty"`
+}
+
+func (r *Rule) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("%w: name is empty", ErrInvalidRule)
+	}
+	if r.Store != "" && r.Store != "*" && !slices.Contains(storeNames, r.Store) {
+		return fmt.Errorf("%w: %q: unknown store %q", ErrInvalidRule, r.Name, r.Store)
+	}
+	if math.IsNaN(r.Probability) || r.Probability < 0 || r.Probability > 1 {
+		return fmt.Errorf("%w: %q: probability must be between 0 and 1", ErrInvalidRule, r.Name)
+	}
+	if err := r.Latency.validate(); err != nil {
+		return fmt.Errorf("%w: %q: %w", ErrInvalidRule, r.Name, err)
+	}
+	if _, ok := r.Error.cause(); !ok && r.Error != ErrorNone {
+		return fmt.Errorf("%w: %q: unknown error kind %q", ErrInvalidRule, r.Name, r.Error)
+	}
+	switch r.Result {
+	case ResultUnchanged, ResultEmpty, ResultStale:
+	default:
+		return fmt.Errorf("%w: %q: unknown result mode %q", ErrInvalidRule, r.Name, r.Result)
+	}
+	return nil
+}
+
+func (r *Rule) matches(c call) bool {
+	return (r.Store == "" || r.Store == "*" || r.Store == c.store) &&
+		(r.Method == "" || r.Method == "*" || r.Method == c.method) &&
+		(!r.Writes || (c.kind == kindWrite && c.store != StoreLifecycle))
+}
+
+// err returns the error the rule injects, or nil when it injects none.
+func (r *Rule) err(sub, method string) error {
+	cause := r.Err
+	if cause == nil {
+		if r.Error == ErrorNone {
+			return nil
+		}
+		cause, _ = r.Error.cause()
+	}
+	return &Error{Rule: r.Name, Store: sub, Method: method, Err: cause}
+}
+
+// Error is an injected error. It matches ErrInjected and, through Unwrap,
+// the error it carries.
+type Error struct {
+	Rule   string
+	Store  string
+	Method string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("chaos: %s.%s: rule %q", e.Store, e.Method, e.Rule)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the carried error.
+func (e *Error) Unwrap() error { return e.Err }
+
+// Is reports whether target is ErrInjected.
+func (e *Error) Is(target error) bool { return target == ErrInjected }
@@ -0,0 +1,632 @@
+package chaos
+
+import (
+	"context"
+	"time"
+
+	"github.com/xraph/keysmith/capture"
+	"github.com/xraph/keysmith/group"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keyevent"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/quotapool"
+	"github.com/xraph/keysmith/revocation"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/tenant"
+	"github.com/xraph/keysmith/tombstone"
+	"github.com/xraph/keysmith/transfer"
+	"github.com/xraph/keysmith/usage"
+)
+
+// ──────────────────────────────────────────────────
+// Keys
+// ──────────────────────────────────────────────────
+
+type keyStore struct {
+	inner key.Store
+	c     *Store
+}
+
+func (s *keyStore) op(method string, k kind, args ...any) call {
+	return call{StoreKeys, method, k, args}
+}
+
+func (s *keyStore) Create(ctx context.Context, k *key.Key) error {
+	return exec(ctx, s.c, s.op("Create", kindWrite), func() error { return s.inner.Create(ctx, k) })
+}
+
+func (s *keyStore) Get(ctx context.Context, keyID id.KeyID) (*key.Key, error) {
+	return run(ctx, s.c, s.op("Get", kindRead, keyID), func() (*key.Key, error) { return s.inner.Get(ctx, keyID) })
+}
+
+func (s *keyStore) GetByHash(ctx context.Context, hash string) (*key.Key, error) {
+	return run(ctx, s.c, s.op("GetByHash", kindRead, hash), func() (*key.Key, error) { return s.inner.GetByHash(ctx, hash) })
+}
+
+func (s *keyStore) GetByHashes(ctx context.Context, hashes []string) ([]*key.Key, error) {
+	return run(ctx, s.c, s.op("GetByHashes", kindList, hashes), func() ([]*key.Key, error) { return s.inner.GetByHashes(ctx, hashes) })
+}
+
+func (s *keyStore) AddHash(ctx context.Context, h *key.HashVersion) error {
+	return exec(ctx, s.c, s.op("AddHash", kindWrite), func() error { return s.inner.AddHash(ctx, h) })
+}
+
+func (s *keyStore) ListHashes(ctx context.Context, keyID id.KeyID) ([]*key.HashVersion, error) {
+	return run(ctx, s.c, s.op("ListHashes", kindList, keyID), func() ([]*key.HashVersion, error) { return s.inner.ListHashes(ctx, keyID) })
+}
+
+func (s *keyStore) DeactivateHash(ctx context.Context, keyID id.KeyID, hash string) error {
+	return exec(ctx, s.c, s.op("DeactivateHash", kindWrite), func() error { return s.inner.DeactivateHash(ctx, keyID, hash) })
+}
+
+func (s *keyStore) ListByPrefixHint(ctx context.Context, prefix, hint string) ([]*key.Key, error) {
+	return run(ctx, s.c, s.op("ListByPrefixHint", kindList, prefix, hint), func() ([]*key.Key, error) {
+		return s.inner.ListByPrefixHint(ctx, prefix, hint)
+	})
+}
+
+func (s *keyStore) Update(ctx context.Context, k *key.Key) error {
+	return exec(ctx, s.c, s.op("Update", kindWrite), func() error { return s.inner.Update(ctx, k) })
+}
+
+func (s *keyStore) UpdateState(ctx context.Context, keyID id.KeyID, state key.State) error {
+	return exec(ctx, s.c, s.op("UpdateState", kindWrite), func() error { return s.inner.UpdateState(ctx, keyID, state) })
+}
+
+func (s *keyStore) UpdateStateIf(ctx context.Context, keyID id.KeyID, from, to key.State) (bool, error) {
+	return run(ctx, s.c, s.op("UpdateStateIf", kindWrite), func() (bool, error) { return s.inner.UpdateStateIf(ctx, keyID, from, to) })
+}
+
+func (s *keyStore) UpdateLastUsed(ctx context.Context, keyID id.KeyID, at time.Time) error {
+	return exec(ctx, s.c, s.op("UpdateLastUsed", kindWrite), func() error { return s.inner.UpdateLastUsed(ctx, keyID, at) })
+}
+
+func (s *keyStore) Delete(ctx context.Context, keyID id.KeyID) error {
+	return exec(ctx, s.c, s.op("Delete", kindWrite), func() error { return s.inner.Delete(ctx, keyID) })
+}
+
+func (s *keyStore) List(ctx context.Context, filter *key.ListFilter) ([]*key.Key, error) {
+	return run(ctx, s.c, s.op("List", kindList, filter), func() ([]*key.Key, error) { return s.inner.List(ctx, filter) })
+}
+
+func (s *keyStore) Count(ctx context.Context, filter *key.ListFilter) (int64, error) {
+	return run(ctx, s.c, s.op("Count", kindList, filter), func() (int64, error) { return s.inner.Count(ctx, filter) })
+}
+
+func (s *keyStore) Iterate(ctx context.Context, filter *key.ListFilter, fn func(*key.Key) error) error {
+	return exec(ctx, s.c, s.op("Iterate", kindIter), func() error { return s.inner.Iterate(ctx, filter, fn) })
+}
+
+func (s *keyStore) Overview(ctx context.Context, q *key.OverviewQuery) (*key.Overview, error) {
+	return run(ctx, s.c, s.op("Overview", kindRead, q), func() (*key.Overview, error) { return s.inner.Overview(ctx, q) })
+}
+
+func (s *keyStore) Search(ctx context.Context, q *key.SearchQuery) ([]*key.SearchMatch, error) {
+	return run(ctx, s.c, s.op("Search", kindList, q), func() ([]*key.SearchMatch, error) { return s.inner.Search(ctx, q) })
+}
+
+func (s *keyStore) ListExpired(ctx context.Context, before time.Time) ([]*key.Key, error) {
+	return run(ctx, s.c, s.op("ListExpired", kindList, before), func() ([]*key.Key, error) { return s.inner.ListExpired(ctx, before) })
+}
+
+func (s *keyStore) ListRecentlyUsed(ctx context.Context, limit int) ([]*key.Key, error) {
+	return run(ctx, s.c, s.op("ListRecentlyUsed", kindList, limit), func() ([]*key.Key, error) {
+		return s.inner.ListRecentlyUsed(ctx, limit)
+	})
+}
+
+func (s *keyStore) ListByPolicy(ctx context.Context, policyID id.PolicyID) ([]*key.Key, error) {
+	return run(ctx, s.c, s.op("ListByPolicy", kindList, policyID), func() ([]*key.Key, error) {
+		return s.inner.ListByPolicy(ctx, policyID)
+	})
+}
+
+func (s *keyStore) DeleteByTenant(ctx context.Context, tenantID string) error {
+	return exec(ctx, s.c, s.op("DeleteByTenant", kindWrite), func() error { return s.inner.DeleteByTenant(ctx, tenantID) })
+}
+
+// ──────────────────────────────────────────────────
+// Policies
+// ──────────────────────────────────────────────────
+
+type policyStore struct {
+	inner policy.Store
+	c     *Store
+}
+
+func (s *policyStore) op(method string, k kind, args ...any) call {
+	return call{StorePolicies, method, k, args}
+}
+
+func (s *policyStore) Create(ctx context.Context, pol *policy.Policy) error {
+	return exec(ctx, s.c, s.op("Create", kindWrite), func() error { return s.inner.Create(ctx, pol) })
+}
+
+func (s *policyStore) Get(ctx context.Context, polID id.PolicyID) (*policy.Policy, error) {
+	return run(ctx, s.c, s.op("Get", kindRead, polID), func() (*policy.Policy, error) { return s.inner.Get(ctx, polID) })
+}
+
+func (s *policyStore) GetByName(ctx context.Context, tenantID, name string) (*policy.Policy, error) {
+	return run(ctx, s.c, s.op("GetByName", kindRead, tenantID, name), func() (*policy.Policy, error) {
+		return s.inner.GetByName(ctx, tenantID, name)
+	})
+}
+
+func (s *policyStore) Update(ctx context.Context, pol *policy.Policy) error {
+	return exec(ctx, s.c, s.op("Update", kindWrite), func() error { return s.inner.Update(ctx, pol) })
+}
+
+func (s *policyStore) Delete(ctx context.Context, polID id.PolicyID) error {
+	return exec(ctx, s.c, s.op("Delete", kindWrite), func() error { return s.inner.Delete(ctx, polID) })
+}
+
+func (s *policyStore) List(ctx context.Context, filter *policy.ListFilter) ([]*policy.Policy, error) {
+	return run(ctx, s.c, s.op("List", kindList, filter), func() ([]*policy.Policy, error) { return s.inner.List(ctx, filter) })
+}
+
+func (s *policyStore) Count(ctx context.Context, filter *policy.ListFilter) (int64, error) {
+	return run(ctx, s.c, s.op("Count", kindList, filter), func() (int64, error) { return s.inner.Count(ctx, filter) })
+}
+
+// ──────────────────────────────────────────────────
+// Usages
+// ──────────────────────────────────────────────────
+
+type usageStore struct {
+	inner usage.Store
+	c     *Store
+}
+
+func (s *usageStore) op(method string, k kind, args ...any) call {
+	return call{StoreUsages, method, k, args}
+}
+
+func (s *usageStore) Record(ctx context.Context, rec *usage.Record) error {
+	return exec(ctx, s.c, s.op("Record", kindWrite), func() error { return s.inner.Record(ctx, rec) })
+}
+
+func (s *usageStore) RecordBatch(ctx context.Context, recs []*usage.Record) error {
+	return exec(ctx, s.c, s.op("RecordBatch", kindWrite), func() error { return s.inner.RecordBatch(ctx, recs) })
+}
+
+func (s *usageStore) Query(ctx context.Context, filter *usage.QueryFilter) ([]*usage.Record, error) {
+	return run(ctx, s.c, s.op("Query", kindList, filter), func() ([]*usage.Record, error) { return s.inner.Query(ctx, filter) })
+}
+
+func (s *usageStore) Aggregate(ctx context.Context, filter *usage.QueryFilter) ([]*usage.Aggregation, error) {
+	return run(ctx, s.c, s.op("Aggregate", kindList, filter), func() ([]*usage.Aggregation, error) {
+		return s.inner.Aggregate(ctx, filter)
+	})
+}
+
+func (s *usageStore) Count(ctx context.Context, filter *usage.QueryFilter) (int64, error) {
+	return run(ctx, s.c, s.op("Count", kindList, filter), func() (int64, error) { return s.inner.Count(ctx, filter) })
+}
+
+func (s *usageStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return run(ctx, s.c, s.op("Purge", kindWrite), func() (int64, error) { return s.inner.Purge(ctx, before) })
+}
+
+func (s *usageStore) DailyCount(ctx context.Context, keyID id.KeyID, w usage.Window) (int64, error) {
+	return run(ctx, s.c, s.op("DailyCount", kindList, keyID, w), func() (int64, error) {
+		return s.inner.DailyCount(ctx, keyID, w)
+	})
+}
+
+func (s *usageStore) MonthlyCount(ctx context.Context, keyID id.KeyID, w usage.Window) (int64, error) {
+	return run(ctx, s.c, s.op("MonthlyCount", kindList, keyID, w), func() (int64, error) {
+		return s.inner.MonthlyCount(ctx, keyID, w)
+	})
+}
+
+func (s *usageStore) UpsertEndpointActivity(ctx context.Context, acts []*usage.EndpointActivity) error {
+	return exec(ctx, s.c, s.op("UpsertEndpointActivity", kindWrite), func() error {
+		return s.inner.UpsertEndpointActivity(ctx, acts)
+	})
+}
+
+func (s *usageStore) ListEndpointActivity(ctx context.Context, keyID id.KeyID) ([]*usage.EndpointActivity, error) {
+	return run(ctx, s.c, s.op("ListEndpointActivity", kindList, keyID), func() ([]*usage.EndpointActivity, error) {
+		return s.inner.ListEndpointActivity(ctx, keyID)
+	})
+}
+
+func (s *usageStore) UpdateUsageIdentifiers(ctx context.Context, tenantID string, m *usage.IdentifierMatcher, limit int) (int64, error) {
+	return run(ctx, s.c, s.op("UpdateUsageIdentifiers", kindWrite), func() (int64, error) {
+		return s.inner.UpdateUsageIdentifiers(ctx, tenantID, m, limit)
+	})
+}
+
+// ──────────────────────────────────────────────────
+// Rotations
+// ──────────────────────────────────────────────────
+
+type rotationStore struct {
+	inner rotation.Store
+	c     *Store
+}
+
+func (s *rotationStore) op(method string, k kind, args ...any) call {
+	return call{StoreRotations, method, k, args}
+}
+
+func (s *rotationStore) Create(ctx context.Context, rec *rotation.Record) error {
+	return exec(ctx, s.c, s.op("Create", kindWrite), func() error { return s.inner.Create(ctx, rec) })
+}
+
+func (s *rotationStore) Get(ctx context.Context, rotID id.RotationID) (*rotation.Record, error) {
+	return run(ctx, s.c, s.op("Get", kindRead, rotID), func() (*rotation.Record, error) { return s.inner.Get(ctx, rotID) })
+}
+
+func (s *rotationStore) List(ctx context.Context, filter *rotation.ListFilter) ([]*rotation.Record, error) {
+	return run(ctx, s.c, s.op("List", kindList, filter), func() ([]*rotation.Record, error) { return s.inner.List(ctx, filter) })
+}
+
+func (s *rotationStore) ListPendingGrace(ctx context.Context, now time.Time) ([]*rotation.Record, error) {
+	return run(ctx, s.c, s.op("ListPendingGrace", kindList, now), func() ([]*rotation.Record, error) {
+		return s.inner.ListPendingGrace(ctx, now)
+	})
+}
+
+func (s *rotationStore) LatestForKey(ctx context.Context, keyID id.KeyID) (*rotation.Record, error) {
+	return run(ctx, s.c, s.op("LatestForKey", kindRead, keyID), func() (*rotation.Record, error) {
+		return s.inner.LatestForKey(ctx, keyID)
+	})
+}
+
+func (s *rotationStore) CountByReason(ctx context.Context, filter *rotation.ListFilter) ([]*rotation.ReasonCount, error) {
+	return run(ctx, s.c, s.op("CountByReason", kindList, filter), func() ([]*rotation.ReasonCount, error) {
+		return s.inner.CountByReason(ctx, filter)
+	})
+}
+
+// ──────────────────────────────────────────────────
+// Scopes
+// ──────────────────────────────────────────────────
+
+type scopeStore struct {
+	inner scope.Store
+	c     *Store
+}
+
+func (s *scopeStore) op(method string, k kind, args ...any) call {
+	return call{StoreScopes, method, k, args}
+}
+
+func (s *scopeStore) Create(ctx context.Context, sc *scope.Scope) error {
+	return exec(ctx, s.c, s.op("Create", kindWrite), func() error { return s.inner.Create(ctx, sc) })
+}
+
+func (s *scopeStore) Get(ctx context.Context, scopeID id.ScopeID) (*scope.Scope, error) {
+	return run(ctx, s.c, s.op("Get", kindRead, scopeID), func() (*scope.Scope, error) { return s.inner.Get(ctx, scopeID) })
+}
+
+func (s *scopeStore) GetByName(ctx context.Context, tenantID, name string) (*scope.Scope, error) {
+	return run(ctx, s.c, s.op("GetByName", kindRead, tenantID, name), func() (*scope.Scope, error) {
+		return s.inner.GetByName(ctx, tenantID, name)
+	})
+}
+
+func (s *scopeStore) Update(ctx context.Context, sc *scope.Scope) error {
+	return exec(ctx, s.c, s.op("Update", kindWrite), func() error { return s.inner.Update(ctx, sc) })
+}
+
+func (s *scopeStore) Delete(ctx context.Context, scopeID id.ScopeID) error {
+	return exec(ctx, s.c, s.op("Delete", kindWrite), func() error { return s.inner.Delete(ctx, scopeID) })
+}
+
+func (s *scopeStore) List(ctx context.Context, filter *scope.ListFilter) ([]*scope.Scope, error) {
+	return run(ctx, s.c, s.op("List", kindList, filter), func() ([]*scope.Scope, error) { return s.inner.List(ctx, filter) })
+}
+
+func (s *scopeStore) ListByKey(ctx context.Context, keyID id.KeyID) ([]*scope.Scope, error) {
+	return run(ctx, s.c, s.op("ListByKey", kindList, keyID), func() ([]*scope.Scope, error) { return s.inner.ListByKey(ctx, keyID) })
+}
+
+func (s *scopeStore) AssignToKey(ctx context.Context, keyID id.KeyID, scopeNames []string) error {
+	return exec(ctx, s.c, s.op("AssignToKey", kindWrite), func() error { return s.inner.AssignToKey(ctx, keyID, scopeNames) })
+}
+
+func (s *scopeStore) RemoveFromKey(ctx context.Context, keyID id.KeyID, scopeNames []string) error {
+	return exec(ctx, s.c, s.op("RemoveFromKey", kindWrite), func() error { return s.inner.RemoveFromKey(ctx, keyID, scopeNames) })
+}
+
+// ──────────────────────────────────────────────────
+// Tenants
+// ──────────────────────────────────────────────────
+
+type tenantStore struct {
+	inner tenant.Store
+	c     *Store
+}
+
+func (s *tenantStore) op(method string, k kind, args ...any) call {
+	return call{StoreTenants, method, k, args}
+}
+
+func (s *tenantStore) GetSettings(ctx context.Context, tenantID string) (*tenant.Settings, error) {
+	return run(ctx, s.c, s.op("GetSettings", kindRead, tenantID), func() (*tenant.Settings, error) {
+		return s.inner.GetSettings(ctx, tenantID)
+	})
+}
+
+func (s *tenantStore) UpsertSettings(ctx context.Context, st *tenant.Settings) error {
+	return exec(ctx, s.c, s.op("UpsertSettings", kindWrite), func() error { return s.inner.UpsertSettings(ctx, st) })
+}
+
+func (s *tenantStore) DeleteSettings(ctx context.Context, tenantID string) error {
+	return exec(ctx, s.c, s.op("DeleteSettings", kindWrite), func() error { return s.inner.DeleteSettings(ctx, tenantID) })
+}
+
+func (s *tenantStore) Revision(ctx context.Context, tenantID string) (uint64, error) {
+	return run(ctx, s.c, s.op("Revision", kindRead, tenantID), func() (uint64, error) {
+		return s.inner.Revision(ctx, tenantID)
+	})
+}
+
+func (s *tenantStore) BumpRevision(ctx context.Context, tenantID string) (uint64, error) {
+	return run(ctx, s.c, s.op("BumpRevision", kindWrite), func() (uint64, error) {
+		return s.inner.BumpRevision(ctx, tenantID)
+	})
+}
+
+// ──────────────────────────────────────────────────
+// Revocations
+// ──────────────────────────────────────────────────
+
+type revocationStore struct {
+	inner revocation.Store
+	c     *Store
+}
+
+func (s *revocationStore) op(method string, k kind, args ...any) call {
+	return call{StoreRevocations, method, k, args}
+}
+
+func (s *revocationStore) Append(ctx context.Context, e *revocation.Entry) error {
+	return exec(ctx, s.c, s.op("Append", kindWrite), func() error { return s.inner.Append(ctx, e) })
+}
+
+func (s *revocationStore) ListAfter(ctx context.Context, tenantID string, after revocation.Cursor, limit int) ([]*revocation.Entry, error) {
+	return run(ctx, s.c, s.op("ListAfter", kindList, tenantID, after, limit), func() ([]*revocation.Entry, error) {
+		return s.inner.ListAfter(ctx, tenantID, after, limit)
+	})
+}
+
+func (s *revocationStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return run(ctx, s.c, s.op("Purge", kindWrite), func() (int64, error) { return s.inner.Purge(ctx, before) })
+}
+
+// ──────────────────────────────────────────────────
+// Captures
+// ──────────────────────────────────────────────────
+
+type captureStore struct {
+	inner capture.Store
+	c     *Store
+}
+
+func (s *captureStore) op(method string, k kind, args ...any) call {
+	return call{StoreCaptures, method, k, args}
+}
+
+func (s *captureStore) Record(ctx context.Context, c *capture.Capture) error {
+	return exec(ctx, s.c, s.op("Record", kindWrite), func() error { return s.inner.Record(ctx, c) })
+}
+
+func (s *captureStore) List(ctx context.Context, keyID id.KeyID, limit int) ([]*capture.Capture, error) {
+	return run(ctx, s.c, s.op("List", kindList, keyID, limit), func() ([]*capture.Capture, error) {
+		return s.inner.List(ctx, keyID, limit)
+	})
+}
+
+func (s *captureStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return run(ctx, s.c, s.op("Purge", kindWrite), func() (int64, error) { return s.inner.Purge(ctx, before) })
+}
+
+// ──────────────────────────────────────────────────
+// Groups
+// ──────────────────────────────────────────────────
+
+type groupStore struct {
+	inner group.Store
+	c     *Store
+}
+
+func (s *groupStore) op(method string, k kind, args ...any) call {
+	return call{StoreGroups, method, k, args}
+}
+
+func (s *groupStore) Create(ctx context.Context, g *group.Group) error {
+	return exec(ctx, s.c, s.op("Create", kindWrite), func() error { return s.inner.Create(ctx, g) })
+}
+
+func (s *groupStore) Get(ctx context.Context, groupID id.GroupID) (*group.Group, error) {
+	return run(ctx, s.c, s.op("Get", kindRead, groupID), func() (*group.Group, error) { return s.inner.Get(ctx, groupID) })
+}
+
+func (s *groupStore) Update(ctx context.Context, g *group.Group) error {
+	return exec(ctx, s.c, s.op("Update", kindWrite), func() error { return s.inner.Update(ctx, g) })
+}
+
+func (s *groupStore) Delete(ctx context.Context, groupID id.GroupID) error {
+	return exec(ctx, s.c, s.op("Delete", kindWrite), func() error { return s.inner.Delete(ctx, groupID) })
+}
+
+func (s *groupStore) List(ctx context.Context, filter *group.ListFilter) ([]*group.Group, error) {
+	return run(ctx, s.c, s.op("List", kindList, filter), func() ([]*group.Group, error) { return s.inner.List(ctx, filter) })
+}
+
+func (s *groupStore) AddKeys(ctx context.Context, groupID id.GroupID, keyIDs []id.KeyID) error {
+	return exec(ctx, s.c, s.op("AddKeys", kindWrite), func() error { return s.inner.AddKeys(ctx, groupID, keyIDs) })
+}
+
+func (s *groupStore) RemoveKeys(ctx context.Context, groupID id.GroupID, keyIDs []id.KeyID) error {
+	return exec(ctx, s.c, s.op("RemoveKeys", kindWrite), func() error { return s.inner.RemoveKeys(ctx, groupID, keyIDs) })
+}
+
+func (s *groupStore) ListByKeys(ctx context.Context, keyIDs []id.KeyID) (map[id.KeyID][]id.GroupID, error) {
+	return run(ctx, s.c, s.op("ListByKeys", kindRead, keyIDs), func() (map[id.KeyID][]id.GroupID, error) {
+		return s.inner.ListByKeys(ctx, keyIDs)
+	})
+}
+
+// ──────────────────────────────────────────────────
+// Key events
+// ──────────────────────────────────────────────────
+
+type keyEventStore struct {
+	inner keyevent.Store
+	c     *Store
+}
+
+func (s *keyEventStore) op(method string, k kind, args ...any) call {
+	return call{StoreKeyEvents, method, k, args}
+}
+
+func (s *keyEventStore) Append(ctx context.Context, e *keyevent.Event) error {
+	return exec(ctx, s.c, s.op("Append", kindWrite), func() error { return s.inner.Append(ctx, e) })
+}
+
+func (s *keyEventStore) ListAfter(ctx context.Context, filter *keyevent.ListFilter) ([]*keyevent.Event, error) {
+	return run(ctx, s.c, s.op("ListAfter", kindList, filter), func() ([]*keyevent.Event, error) {
+		return s.inner.ListAfter(ctx, filter)
+	})
+}
+
+func (s *keyEventStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return run(ctx, s.c, s.op("Purge", kindWrite), func() (int64, error) { return s.inner.Purge(ctx, before) })
+}
+
+// ──────────────────────────────────────────────────
+// Transfers
+// ──────────────────────────────────────────────────
+
+type transferStore struct {
+	inner transfer.Store
+	c     *Store
+}
+
+func (s *transferStore) op(method string, k kind, args ...any) call {
+	return call{StoreTransfers, method, k, args}
+}
+
+func (s *transferStore) Create(ctx context.Context, t *transfer.Transfer) error {
+	return exec(ctx, s.c, s.op("Create", kindWrite), func() error { return s.inner.Create(ctx, t) })
+}
+
+func (s *transferStore) Get(ctx context.Context, transferID id.TransferID) (*transfer.Transfer, error) {
+	return run(ctx, s.c, s.op("Get", kindRead, transferID), func() (*transfer.Transfer, error) {
+		return s.inner.Get(ctx, transferID)
+	})
+}
+
+func (s *transferStore) GetPending(ctx context.Context, keyID id.KeyID) (*transfer.Transfer, error) {
+	return run(ctx, s.c, s.op("GetPending", kindRead, keyID), func() (*transfer.Transfer, error) {
+		return s.inner.GetPending(ctx, keyID)
+	})
+}
+
+func (s *transferStore) List(ctx context.Context, filter *transfer.ListFilter) ([]*transfer.Transfer, error) {
+	return run(ctx, s.c, s.op("List", kindList, filter), func() ([]*transfer.Transfer, error) {
+		return s.inner.List(ctx, filter)
+	})
+}
+
+func (s *transferStore) Resolve(ctx context.Context, transferID id.TransferID, state transfer.State, by string, at time.Time) (bool, error) {
+	return run(ctx, s.c, s.op("Resolve", kindWrite, transferID), func() (bool, error) {
+		return s.inner.Resolve(ctx, transferID, state, by, at)
+	})
+}
+
+func (s *transferStore) Accept(ctx context.Context, t *transfer.Transfer, remap *transfer.Remap) (bool, error) {
+	return run(ctx, s.c, s.op("Accept", kindWrite), func() (bool, error) { return s.inner.Accept(ctx, t, remap) })
+}
+
+// ──────────────────────────────────────────────────
+// Tombstones
+// ──────────────────────────────────────────────────
+
+type tombstoneStore struct {
+	inner tombstone.Store
+	c     *Store
+}
+
+func (s *tombstoneStore) op(method string, k kind, args ...any) call {
+	return call{StoreTombstones, method, k, args}
+}
+
+func (s *tombstoneStore) Create(ctx context.Context, t *tombstone.Tombstone) error {
+	return exec(ctx, s.c, s.op("Create", kindWrite), func() error { return s.inner.Create(ctx, t) })
+}
+
+func (s *tombstoneStore) Find(ctx context.Context, hashes []string) ([]*tombstone.Tombstone, error) {
+	return run(ctx, s.c, s.op("Find", kindRead, hashes), func() ([]*tombstone.Tombstone, error) {
+		return s.inner.Find(ctx, hashes)
+	})
+}
+
+func (s *tombstoneStore) List(ctx context.Context, filter *tombstone.ListFilter) ([]*tombstone.Tombstone, error) {
+	return run(ctx, s.c, s.op("List", kindList, filter), func() ([]*tombstone.Tombstone, error) {
+		return s.inner.List(ctx, filter)
+	})
+}
+
+func (s *tombstoneStore) CountByReason(ctx context.Context, filter *tombstone.ListFilter) ([]*tombstone.ReasonCount, error) {
+	return run(ctx, s.c, s.op("CountByReason", kindList, filter), func() ([]*tombstone.ReasonCount, error) {
+		return s.inner.CountByReason(ctx, filter)
+	})
+}
+
+func (s *tombstoneStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return run(ctx, s.c, s.op("Purge", kindWrite), func() (int64, error) { return s.inner.Purge(ctx, before) })
+}
+
+// ──────────────────────────────────────────────────
+// Quota pools
+// ──────────────────────────────────────────────────
+
+type quotaPoolStore struct {
+	inner quotapool.Store
+	c     *Store
+}
+
+func (s *quotaPoolStore) op(method string, k kind, args ...any) call {
+	return call{StoreQuotaPools, method, k, args}
+}
+
+func (s *quotaPoolStore) Create(ctx context.Context, p *quotapool.Pool) error {
+	return exec(ctx, s.c, s.op("Create", kindWrite), func() error { return s.inner.Create(ctx, p) })
+}
+
+func (s *quotaPoolStore) Get(ctx context.Context, poolID id.QuotaPoolID) (*quotapool.Pool, error) {
+	return run(ctx, s.c, s.op("Get", kindRead, poolID), func() (*quotapool.Pool, error) { return s.inner.Get(ctx, poolID) })
+}
+
+func (s *quotaPoolStore) Update(ctx context.Context, p *quotapool.Pool) error {
+	return exec(ctx, s.c, s.op("Update", kindWrite), func() error { return s.inner.Update(ctx, p) })
+}
+
+func (s *quotaPoolStore) Delete(ctx context.Context, poolID id.QuotaPoolID) error {
+	return exec(ctx, s.c, s.op("Delete", kindWrite, poolID), func() error { return s.inner.Delete(ctx, poolID) })
+}
+
+func (s *quotaPoolStore) List(ctx context.Context, filter *quotapool.ListFilter) ([]*quotapool.Pool, error) {
+	return run(ctx, s.c, s.op("List", kindList, filter), func() ([]*quotapool.Pool, error) { return s.inner.List(ctx, filter) })
+}
+
+func (s *quotaPoolStore) AddUsage(ctx context.Context, usages []*quotapool.Usage) error {
+	return exec(ctx, s.c, s.op("AddUsage", kindWrite), func() error { return s.inner.AddUsage(ctx, usages) })
+}
+
+func (s *quotaPoolStore) ListUsage(ctx context.Context, filter *quotapool.UsageFilter) ([]*quotapool.Usage, error) {
+	return run(ctx, s.c, s.op("ListUsage", kindList, filter), func() ([]*quotapool.Usage, error) {
+		return s.inner.ListUsage(ctx, filter)
+	})
+}
+
+func (s *quotaPoolStore) PurgeUsage(ctx context.Context, before time.Time) (int64, error) {
+	return run(ctx, s.c, s.op("PurgeUsage", kindWrite), func() (int64, error) { return s.inner.PurgeUsage(ctx, before) })
+}
@@ -0,0 +1,19 @@
+package store
+
+import "context"
+
+type ctxKeyPrimaryReads struct{}
+
+// WithPrimaryReads returns a context that makes replica-aware stores serve
+// every read from the primary. Use it for lookups that must observe writes
+// made moments earlier, such as idempotency checks. Stores without replicas
+// ignore it.
+func WithPrimaryReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxKeyPrimaryReads{}, true)
+}
+
+// PrimaryReads reports whether ctx was marked with WithPrimaryReads.
+func PrimaryReads(ctx context.Context) bool {
+	v, _ := ctx.Value(ctxKeyPrimaryReads{}).(bool)
+	return v
+}
@@ -0,0 +1,56 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// ErrUnknownDriver is returned by Open for a driver no store registered.
+var ErrUnknownDriver = errors.New("store: unknown driver")
+
+// OpenFunc builds a Store from a database handle. Each driver documents the
+// handle it takes, such as a *grove.DB.
+type OpenFunc func(db any) (Store, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]OpenFunc)
+)
+
+// RegisterDriver makes a store available to Open under name, such as the
+// grove driver name "pg". Store backends call it from an init function, so
+// importing a backend is enough to make it available; the Forge extension
+// builds its store this way. It panics when name is empty or registered
+// twice.
+func RegisterDriver(name string, open OpenFunc) {
+	if name == "" || open == nil {
+		panic("store: driver name and open function are required")
+	}
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	if _, dup := drivers[name]; dup {
+		panic("store: driver " + name + " registered twice")
+	}
+	drivers[name] = open
+}
+
+// Open builds a Store for db with the driver registered under name.
+func Open(name string, db any) (Store, error) {
+	driversMu.RLock()
+	open, ok := drivers[name]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q (registered: %v)", ErrUnknownDriver, name, Drivers())
+	}
+	return open(db)
+}
+
+// Drivers returns the registered driver names in sorted order.
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	return slices.Sorted(maps.Keys(drivers))
+}
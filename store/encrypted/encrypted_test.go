@@ -0,0 +1,261 @@
+package encrypted_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/store/encrypted"
+	"github.com/xraph/keysmith/store/memory"
+	"github.com/xraph/keysmith/store/storetest"
+)
+
+func secret(t *testing.T) []byte {
+	t.Helper()
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	require.NoError(t, err)
+	return b
+}
+
+func staticProvider(t *testing.T, current string, keys map[string][]byte) *encrypted.StaticKeyProvider {
+	t.Helper()
+	p, err := encrypted.NewStaticKeyProvider(current, keys)
+	require.NoError(t, err)
+	return p
+}
+
+func wrap(t *testing.T, inner store.Store, p encrypted.DataKeyProvider, indexKey []byte) *encrypted.Store {
+	t.Helper()
+	s, err := encrypted.New(inner, p, indexKey)
+	require.NoError(t, err)
+	return s
+}
+
+func tenantCtx() context.Context {
+	return keysmith.WithTenant(context.Background(), "app_test", "tenant_test")
+}
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		return wrap(t, memory.New(), staticProvider(t, "v1", map[string][]byte{"v1": secret(t)}), secret(t))
+	})
+}
+
+func TestNew_RejectsShortIndexKey(t *testing.T) {
+	p := staticProvider(t, "v1", map[string][]byte{"v1": secret(t)})
+	_, err := encrypted.New(memory.New(), p, []byte("short"))
+	assert.ErrorIs(t, err, encrypted.ErrInvalidKey)
+
+	_, err = encrypted.NewStaticKeyProvider("v2", map[string][]byte{"v1": secret(t)})
+	assert.ErrorIs(t, err, encrypted.ErrInvalidKey)
+	_, err = encrypted.NewStaticKeyProvider("v1", map[string][]byte{"v1": []byte("short")})
+	assert.ErrorIs(t, err, encrypted.ErrInvalidKey)
+}
+
+func TestHashesAreEncryptedAtRest(t *testing.T) {
+	inner := memory.New()
+	s := wrap(t, inner, staticProvider(t, "v1", map[string][]byte{"v1": secret(t)}), secret(t))
+	ctx := context.Background()
+
+	k := storetest.NewKey("t1", "sk_test_atrest000001")
+	require.NoError(t, s.Keys().Create(ctx, k))
+	assert.Equal(t, storetest.Hash("sk_test_atrest000001"), k.KeyHash, "the caller's key is not modified")
+
+	raw, err := inner.Keys().Get(ctx, k.ID)
+	require.NoError(t, err)
+	_, digest := key.SplitHash(k.KeyHash)
+	assert.True(t, strings.HasPrefix(raw.KeyHash, "ks-index:"), raw.KeyHash)
+	env, ok := raw.Metadata[encrypted.MetadataHashEnvelope].(string)
+	require.True(t, ok)
+	for _, v := range []string{raw.KeyHash, env} {
+		assert.NotContains(t, v, digest)
+	}
+	assert.Equal(t, "pro", raw.Metadata["plan"])
+
+	got, err := s.Keys().Get(ctx, k.ID)
+	require.NoError(t, err)
+	assert.Equal(t, k.KeyHash, got.KeyHash)
+	assert.NotContains(t, got.Metadata, encrypted.MetadataHashEnvelope)
+
+	rec := &rotation.Record{KeyID: k.ID, OldKeyHash: k.KeyHash, NewKeyHash: storetest.Hash("sk_test_atrest000002")}
+	require.NoError(t, s.Rotations().Create(ctx, rec))
+	stored, err := inner.Rotations().List(ctx, &rotation.ListFilter{})
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	assert.NotContains(t, stored[0].OldKeyHash, digest)
+	assert.True(t, strings.HasPrefix(stored[0].NewKeyHash, "ksenc1."))
+}
+
+func TestTamperedHashFailsToDecrypt(t *testing.T) {
+	inner := memory.New()
+	s := wrap(t, inner, staticProvider(t, "v1", map[string][]byte{"v1": secret(t)}), secret(t))
+	ctx := context.Background()
+	k := storetest.NewKey("t1", "sk_test_tamper000001")
+	require.NoError(t, s.Keys().Create(ctx, k))
+
+	raw, err := inner.Keys().Get(ctx, k.ID)
+	require.NoError(t, err)
+	env := []byte(raw.Metadata[encrypted.MetadataHashEnvelope].(string))
+	env[len(env)-2] ^= 'A' ^ 'B'
+	raw.Metadata[encrypted.MetadataHashEnvelope] = string(env)
+	require.NoError(t, inner.Keys().Update(ctx, raw))
+
+	_, err = s.Keys().Get(ctx, k.ID)
+	assert.ErrorIs(t, err, encrypted.ErrDecrypt)
+}
+
+func TestLookupsSurviveProviderRotation(t *testing.T) {
+	inner := memory.New()
+	indexKey := secret(t)
+	v1, v2 := secret(t), secret(t)
+	ctx := tenantCtx()
+
+	engine := func(p encrypted.DataKeyProvider) (*keysmith.Engine, *encrypted.Store) {
+		s := wrap(t, inner, p, indexKey)
+		eng, err := keysmith.NewEngine(keysmith.WithStore(s))
+		require.NoError(t, err)
+		return eng, s
+	}
+
+	eng, _ := engine(staticProvider(t, "v1", map[string][]byte{"v1": v1}))
+	first, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "First", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	rotated, err := eng.RotateKey(ctx, first.Key.ID, rotation.ReasonManual)
+	require.NoError(t, err)
+
+	// The provider moves to v2 and still holds v1.
+	eng, s := engine(staticProvider(t, "v2", map[string][]byte{"v1": v1, "v2": v2}))
+	_, err = eng.ValidateKey(ctx, rotated.RawKey)
+	require.NoError(t, err, "a v1 hash validates under v2")
+	second, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Second", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+
+	report, err := s.ReencryptAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Scanned)
+	assert.Equal(t, 1, report.Reencrypted, "the key written under v2 is left alone")
+	assert.Equal(t, 1, report.StaleRotations)
+
+	report, err = s.ReencryptAll(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, report.Reencrypted, "a second run has nothing to do")
+
+	// With v1 gone, every key still validates.
+	eng, s = engine(staticProvider(t, "v2", map[string][]byte{"v2": v2}))
+	for _, raw := range []string{rotated.RawKey, second.RawKey} {
+		_, err = eng.ValidateKey(ctx, raw)
+		assert.NoError(t, err)
+	}
+	_, err = s.Rotations().LatestForKey(ctx, first.Key.ID)
+	assert.ErrorIs(t, err, encrypted.ErrUnknownKeyVersion, "rotation records keep their version")
+}
+
+func TestReencryptAll_EncryptsExistingKeys(t *testing.T) {
+	inner := memory.New()
+	ctx := context.Background()
+	k := storetest.NewKey("t1", "sk_test_existing0001")
+	require.NoError(t, inner.Keys().Create(ctx, k))
+
+	s := wrap(t, inner, staticProvider(t, "v1", map[string][]byte{"v1": secret(t)}), secret(t))
+	got, err := s.Keys().GetByHash(ctx, k.KeyHash)
+	require.NoError(t, err, "unencrypted keys keep validating")
+	assert.Equal(t, k.ID.String(), got.ID.String())
+
+	report, err := s.ReencryptAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Reencrypted)
+
+	raw, err := inner.Keys().Get(ctx, k.ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, k.KeyHash, raw.KeyHash)
+	assert.True(t, raw.UpdatedAt.Equal(k.UpdatedAt), "UpdatedAt is unchanged")
+
+	got, err = s.Keys().GetByHash(ctx, k.KeyHash)
+	require.NoError(t, err)
+	assert.Equal(t, k.KeyHash, got.KeyHash)
+	_, err = inner.Keys().GetByHash(ctx, k.KeyHash)
+	assert.Error(t, err, "the plain hash is gone from the store")
+}
+
+func TestEngineLooksThroughWrapper(t *testing.T) {
+	s := wrap(t, memory.New(), staticProvider(t, "v1", map[string][]byte{"v1": secret(t)}), secret(t))
+	eng, err := keysmith.NewEngine(keysmith.WithStore(s))
+	require.NoError(t, err)
+
+	report, err := eng.MaintainStore(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "memory", report.Backend)
+}
+
+// fakeKMS wraps data keys by XOR with a per-key-ID pad, which is enough to
+// tell key IDs apart.
+type fakeKMS struct {
+	pads  map[string][]byte
+	calls map[string]int
+}
+
+func (f *fakeKMS) GenerateDataKey(_ context.Context, keyID string) ([]byte, []byte, error) {
+	f.calls["generate"]++
+	plaintext := make([]byte, encrypted.DataKeySize)
+	_, _ = rand.Read(plaintext)
+	return plaintext, xor(plaintext, f.pads[keyID]), nil
+}
+
+func (f *fakeKMS) Decrypt(_ context.Context, keyID string, blob []byte) ([]byte, error) {
+	f.calls["decrypt"]++
+	pad, ok := f.pads[keyID]
+	if !ok {
+		return nil, errors.New("AccessDeniedException")
+	}
+	return xor(blob, pad), nil
+}
+
+func xor(a, pad []byte) []byte {
+	out := bytes.Clone(a)
+	for i := range out {
+		out[i] ^= pad[i%len(pad)]
+	}
+	return out
+}
+
+func TestAWSKMSProvider(t *testing.T) {
+	kms := &fakeKMS{pads: map[string][]byte{"alias/old": secret(t), "alias/new": secret(t)}, calls: map[string]int{}}
+	inner := memory.New()
+	indexKey := secret(t)
+	ctx := context.Background()
+
+	s := wrap(t, inner, encrypted.NewAWSKMSProvider(kms, "alias/old"), indexKey)
+	a, b := storetest.NewKey("t1", "sk_test_kms000000001"), storetest.NewKey("t1", "sk_test_kms000000002")
+	require.NoError(t, s.Keys().Create(ctx, a))
+	require.NoError(t, s.Keys().Create(ctx, b))
+	assert.Equal(t, 1, kms.calls["generate"], "one data key serves every write")
+
+	s = wrap(t, inner, encrypted.NewAWSKMSProvider(kms, "alias/new"), indexKey)
+	for range 3 {
+		got, err := s.Keys().GetByHash(ctx, a.KeyHash)
+		require.NoError(t, err)
+		assert.Equal(t, a.ID.String(), got.ID.String())
+	}
+	assert.Equal(t, 1, kms.calls["decrypt"], "unwrapped data keys are cached")
+
+	report, err := s.ReencryptAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Reencrypted)
+
+	delete(kms.pads, "alias/old")
+	s = wrap(t, inner, encrypted.NewAWSKMSProvider(kms, "alias/new"), indexKey)
+	got, err := s.Keys().GetByHash(ctx, b.KeyHash)
+	require.NoError(t, err)
+	assert.Equal(t, b.KeyHash, got.KeyHash)
+}
@@ -0,0 +1,191 @@
+package encrypted
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/xraph/keysmith/key"
+)
+
+// envelopePrefix starts every encrypted value. An envelope reads
+//
+//	ksenc1.<version>.<wrapped data key>.<nonce and ciphertext>
+//
+// with each part in unpadded base64url, so versions such as KMS ARNs may
+// contain any character.
+const envelopePrefix = "ksenc1."
+
+// indexAlg is the algorithm prefix of the lookup index stored in place of a
+// key hash.
+const indexAlg = "ks-index"
+
+// maxCachedDataKeys bounds the unwrapped data keys kept in memory. Each
+// process writes with one data key per master key version, so the cache
+// only fills after many restarts; it is then cleared.
+const maxCachedDataKeys = 1024
+
+// Purposes bind a ciphertext to the field it was written to, so a value
+// copied into another field does not decrypt.
+const (
+	purposeKeyHash         = "key_hash"
+	purposeRotationOldHash = "rotation.old_key_hash"
+	purposeRotationNewHash = "rotation.new_key_hash"
+)
+
+// sealer encrypts and decrypts field values and computes lookup indexes.
+type sealer struct {
+	provider DataKeyProvider
+	indexKey []byte
+
+	mu      sync.Mutex
+	current *dataKey          // data key for new values
+	cache   map[string][]byte // version + wrapped key -> plaintext key
+}
+
+type dataKey struct {
+	version   string
+	plaintext []byte
+	wrapped   []byte
+}
+
+func newSealer(provider DataKeyProvider, indexKey []byte) *sealer {
+	return &sealer{provider: provider, indexKey: indexKey, cache: make(map[string][]byte)}
+}
+
+// index returns the deterministic lookup value of a key hash. Encodings of
+// the same hash share an index, as they share a canonical form.
+func (s *sealer) index(hash string) string {
+	norm, _ := key.NormalizeHash(hash)
+	mac := hmac.New(sha256.New, s.indexKey)
+	mac.Write([]byte(norm))
+	return key.FormatHash(indexAlg, mac.Sum(nil))
+}
+
+// isIndex reports whether v is a lookup index rather than a key hash.
+func isIndex(v string) bool {
+	alg, _ := key.SplitHash(v)
+	return alg == indexAlg
+}
+
+// seal encrypts value for purpose with the current data key.
+func (s *sealer) seal(ctx context.Context, value, purpose string) (string, error) {
+	dk, err := s.currentKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(dk.plaintext)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(aead, []byte(value), aad(dk.version, purpose))
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	return envelopePrefix + enc.EncodeToString([]byte(dk.version)) + "." +
+		enc.EncodeToString(dk.wrapped) + "." + enc.EncodeToString(sealed), nil
+}
+
+// open decrypts an envelope written for purpose. A value that is not an
+// envelope, stored before encryption was enabled, is returned as it is.
+func (s *sealer) open(ctx context.Context, value, purpose string) (string, error) {
+	if !isEnvelope(value) {
+		return value, nil
+	}
+	version, wrapped, sealed, err := parseEnvelope(value)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := s.unwrap(ctx, version, wrapped)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(plaintext)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrDecrypt, err)
+	}
+	out, err := open(aead, sealed, aad(version, purpose))
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// envelopeVersion returns the master key version of an envelope, or "" with
+// ok false for a plaintext value.
+func envelopeVersion(value string) (string, bool) {
+	if !isEnvelope(value) {
+		return "", false
+	}
+	version, _, _, err := parseEnvelope(value)
+	return version, err == nil
+}
+
+func isEnvelope(v string) bool { return strings.HasPrefix(v, envelopePrefix) }
+
+func parseEnvelope(v string) (version string, wrapped, sealed []byte, err error) {
+	parts := strings.Split(strings.TrimPrefix(v, envelopePrefix), ".")
+	if len(parts) != 3 {
+		return "", nil, nil, fmt.Errorf("%w: malformed envelope", ErrDecrypt)
+	}
+	decoded := make([][]byte, len(parts))
+	for i, p := range parts {
+		if decoded[i], err = base64.RawURLEncoding.DecodeString(p); err != nil {
+			return "", nil, nil, fmt.Errorf("%w: malformed envelope", ErrDecrypt)
+		}
+	}
+	return string(decoded[0]), decoded[1], decoded[2], nil
+}
+
+func aad(version, purpose string) []byte { return []byte(purpose + "\x00" + version) }
+
+// currentKey returns the data key for new values, generating one when the
+// provider's current version has changed.
+func (s *sealer) currentKey(ctx context.Context) (*dataKey, error) {
+	version := s.provider.CurrentVersion()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current != nil && s.current.version == version {
+		return s.current, nil
+	}
+	plaintext, wrapped, got, err := s.provider.GenerateDataKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.current = &dataKey{version: got, plaintext: plaintext, wrapped: wrapped}
+	s.cacheLocked(got, wrapped, plaintext)
+	return s.current, nil
+}
+
+// unwrap returns the plaintext of a wrapped data key, asking the provider
+// only on a cache miss.
+func (s *sealer) unwrap(ctx context.Context, version string, wrapped []byte) ([]byte, error) {
+	id := version + "\x00" + hex.EncodeToString(wrapped)
+	s.mu.Lock()
+	plaintext, ok := s.cache[id]
+	s.mu.Unlock()
+	if ok {
+		return plaintext, nil
+	}
+	plaintext, err := s.provider.DecryptDataKey(ctx, version, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.cacheLocked(version, wrapped, plaintext)
+	s.mu.Unlock()
+	return plaintext, nil
+}
+
+func (s *sealer) cacheLocked(version string, wrapped, plaintext []byte) {
+	if len(s.cache) >= maxCachedDataKeys {
+		clear(s.cache)
+	}
+	s.cache[version+"\x00"+hex.EncodeToString(wrapped)] = plaintext
+}
@@ -0,0 +1,317 @@
+package encrypted
+
+import (
+	"context"
+	"maps"
+	"time"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+)
+
+// MetadataHashEnvelope is the key metadata entry holding the encrypted key
+// hash. The key_hash column holds the lookup index instead. The entry is
+// removed from keys read through the Store.
+const MetadataHashEnvelope = "keysmith.hash_envelope"
+
+type keyStore struct {
+	inner key.Store
+	s     *sealer
+}
+
+// seal returns the stored form of k: a copy whose KeyHash is the lookup
+// index and whose metadata carries the encrypted hash.
+func (ks *keyStore) seal(ctx context.Context, k *key.Key) (*key.Key, error) {
+	cp := *k
+	if k.KeyHash == "" || isIndex(k.KeyHash) {
+		return &cp, nil
+	}
+	env, err := ks.s.seal(ctx, k.KeyHash, purposeKeyHash)
+	if err != nil {
+		return nil, err
+	}
+	cp.Metadata = make(map[string]any, len(k.Metadata)+1)
+	maps.Copy(cp.Metadata, k.Metadata)
+	cp.Metadata[MetadataHashEnvelope] = env
+	cp.KeyHash = ks.s.index(k.KeyHash)
+	return &cp, nil
+}
+
+// open restores the key hash of a stored key in place. A key stored before
+// encryption was enabled has no envelope and is returned as it is.
+func (ks *keyStore) open(ctx context.Context, k *key.Key) (*key.Key, error) {
+	env, ok := k.Metadata[MetadataHashEnvelope].(string)
+	if !ok {
+		return k, nil
+	}
+	hash, err := ks.s.open(ctx, env, purposeKeyHash)
+	if err != nil {
+		return nil, err
+	}
+	k.KeyHash = hash
+	meta := maps.Clone(k.Metadata)
+	delete(meta, MetadataHashEnvelope)
+	if len(meta) == 0 {
+		meta = nil
+	}
+	k.Metadata = meta
+	return k, nil
+}
+
+func (ks *keyStore) openAll(ctx context.Context, keys []*key.Key) ([]*key.Key, error) {
+	for _, k := range keys {
+		if _, err := ks.open(ctx, k); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// lookups returns the stored values matching hashes: their lookup indexes,
+// in every encoding the store matches, and the hashes themselves for keys
+// written before encryption was enabled.
+func (ks *keyStore) lookups(hashes []string) []string {
+	forms := key.LookupHashes(hashes...)
+	out := make([]string, 0, 2*len(forms))
+	seen := make(map[string]struct{}, cap(out))
+	for _, f := range forms {
+		for _, v := range []string{f, ks.s.index(f)} {
+			if _, ok := seen[v]; !ok {
+				seen[v] = struct{}{}
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
+
+func (ks *keyStore) Create(ctx context.Context, k *key.Key) error {
+	stored, err := ks.seal(ctx, k)
+	if err != nil {
+		return err
+	}
+	return ks.inner.Create(ctx, stored)
+}
+
+func (ks *keyStore) Get(ctx context.Context, keyID id.KeyID) (*key.Key, error) {
+	k, err := ks.inner.Get(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	return ks.open(ctx, k)
+}
+
+// GetByHash looks the key up by the index of hash and confirms the match
+// against the decrypted hash.
+func (ks *keyStore) GetByHash(ctx context.Context, hash string) (*key.Key, error) {
+	keys, err := ks.GetByHashes(ctx, []string{hash})
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, ErrKeyNotFound
+	}
+	return keys[0], nil
+}
+
+func (ks *keyStore) GetByHashes(ctx context.Context, hashes []string) ([]*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+	keys, err := ks.inner.GetByHashes(ctx, ks.lookups(hashes))
+	if err != nil {
+		return nil, err
+	}
+	out := keys[:0]
+	for _, k := range keys {
+		if _, err := ks.open(ctx, k); err != nil {
+			return nil, err
+		}
+		ok, err := ks.matches(ctx, k, hashes)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, k)
+		}
+	}
+	return out, nil
+}
+
+// matches reports whether one of hashes is the decrypted current hash of
+// k or, failing that, the index of one of its active additional versions.
+func (ks *keyStore) matches(ctx context.Context, k *key.Key, hashes []string) (bool, error) {
+	for _, h := range hashes {
+		if key.HashesEqual(k.KeyHash, h) {
+			return true, nil
+		}
+	}
+	versions, err := ks.inner.ListHashes(ctx, k.ID)
+	if err != nil {
+		return false, err
+	}
+	for _, v := range versions {
+		if !v.Active || !isIndex(v.Hash) {
+			continue
+		}
+		for _, h := range hashes {
+			if v.Hash == ks.s.index(h) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// AddHash stores the lookup index of h.Hash, so additional versions are no
+// more revealing than the current hash.
+func (ks *keyStore) AddHash(ctx context.Context, h *key.HashVersion) error {
+	cp := *h
+	if cp.Scheme == "" {
+		cp.Scheme = key.HashScheme(h.Hash)
+	}
+	if !isIndex(h.Hash) {
+		cp.Hash = ks.s.index(h.Hash)
+	}
+	return ks.inner.AddHash(ctx, &cp)
+}
+
+// ListHashes returns the key's versions. The current one carries the
+// decrypted hash; the others only have their lookup index.
+func (ks *keyStore) ListHashes(ctx context.Context, keyID id.KeyID) ([]*key.HashVersion, error) {
+	versions, err := ks.inner.ListHashes(ctx, keyID)
+	if err != nil || len(versions) == 0 {
+		return versions, err
+	}
+	k, err := ks.Get(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	current := ks.s.index(k.KeyHash)
+	for _, v := range versions {
+		if v.Hash == current {
+			v.Hash, v.Scheme = k.KeyHash, key.HashScheme(k.KeyHash)
+		}
+	}
+	return versions, nil
+}
+
+func (ks *keyStore) DeactivateHash(ctx context.Context, keyID id.KeyID, hash string) error {
+	if !isIndex(hash) {
+		hash = ks.s.index(hash)
+	}
+	return ks.inner.DeactivateHash(ctx, keyID, hash)
+}
+
+func (ks *keyStore) ListByPrefixHint(ctx context.Context, prefix, hint string) ([]*key.Key, error) {
+	keys, err := ks.inner.ListByPrefixHint(ctx, prefix, hint)
+	if err != nil {
+		return nil, err
+	}
+	return ks.openAll(ctx, keys)
+}
+
+func (ks *keyStore) Update(ctx context.Context, k *key.Key) error {
+	stored, err := ks.seal(ctx, k)
+	if err != nil {
+		return err
+	}
+	return ks.inner.Update(ctx, stored)
+}
+
+func (ks *keyStore) UpdateState(ctx context.Context, keyID id.KeyID, state key.State) error {
+	return ks.inner.UpdateState(ctx, keyID, state)
+}
+
+func (ks *keyStore) UpdateStateIf(ctx context.Context, keyID id.KeyID, from, to key.State) (bool, error) {
+	return ks.inner.UpdateStateIf(ctx, keyID, from, to)
+}
+
+func (ks *keyStore) UpdateLastUsed(ctx context.Context, keyID id.KeyID, at time.Time) error {
+	return ks.inner.UpdateLastUsed(ctx, keyID, at)
+}
+
+func (ks *keyStore) Delete(ctx context.Context, keyID id.KeyID) error {
+	return ks.inner.Delete(ctx, keyID)
+}
+
+func (ks *keyStore) List(ctx context.Context, filter *key.ListFilter) ([]*key.Key, error) {
+	keys, err := ks.inner.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return ks.openAll(ctx, keys)
+}
+
+func (ks *keyStore) Count(ctx context.Context, filter *key.ListFilter) (int64, error) {
+	return ks.inner.Count(ctx, filter)
+}
+
+func (ks *keyStore) Iterate(ctx context.Context, filter *key.ListFilter, fn func(*key.Key) error) error {
+	return ks.inner.Iterate(ctx, filter, func(k *key.Key) error {
+		if _, err := ks.open(ctx, k); err != nil {
+			return err
+		}
+		return fn(k)
+	})
+}
+
+// Overview reads only states, environments, and timestamps, which are
+// stored in the clear.
+func (ks *keyStore) Overview(ctx context.Context, q *key.OverviewQuery) (*key.Overview, error) {
+	return ks.inner.Overview(ctx, q)
+}
+
+// Search asks the inner store for every match, since the hash envelope in
+// the stored metadata is searchable there, and matches and ranks the keys
+// again once it is removed.
+func (ks *keyStore) Search(ctx context.Context, q *key.SearchQuery) ([]*key.SearchMatch, error) {
+	all := *q
+	all.Limit = 0
+	found, err := ks.inner.Search(ctx, &all)
+	if err != nil {
+		return nil, err
+	}
+	matches := make([]*key.SearchMatch, 0, len(found))
+	for _, m := range found {
+		k, err := ks.open(ctx, m.Key)
+		if err != nil {
+			return nil, err
+		}
+		if m := key.MatchSearch(k, q.Terms); m != nil {
+			matches = append(matches, m)
+		}
+	}
+	return key.RankSearch(matches, q.Limit), nil
+}
+
+func (ks *keyStore) ListExpired(ctx context.Context, before time.Time) ([]*key.Key, error) {
+	keys, err := ks.inner.ListExpired(ctx, before)
+	if err != nil {
+		return nil, err
+	}
+	return ks.openAll(ctx, keys)
+}
+
+func (ks *keyStore) ListRecentlyUsed(ctx context.Context, limit int) ([]*key.Key, error) {
+	keys, err := ks.inner.ListRecentlyUsed(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	return ks.openAll(ctx, keys)
+}
+
+func (ks *keyStore) ListByPolicy(ctx context.Context, policyID id.PolicyID) ([]*key.Key, error) {
+	keys, err := ks.inner.ListByPolicy(ctx, policyID)
+	if err != nil {
+		return nil, err
+	}
+	return ks.openAll(ctx, keys)
+}
+
+func (ks *keyStore) DeleteByTenant(ctx context.Context, tenantID string) error {
+	return ks.inner.DeleteByTenant(ctx, tenantID)
+}
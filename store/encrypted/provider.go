@@ -0,0 +1,117 @@
+package encrypted
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+)
+
+// DataKeySize is the size in bytes of the AES-256 data keys values are
+// encrypted with.
+const DataKeySize = 32
+
+// DataKeyProvider issues and unwraps the data keys that encrypt stored
+// values. Each master key the provider wraps data keys with is named by a
+// version, which is recorded in every ciphertext so that values written
+// under an earlier master key stay readable after the provider moves to a
+// new one.
+type DataKeyProvider interface {
+	// CurrentVersion names the master key new data keys are wrapped with.
+	CurrentVersion() string
+
+	// GenerateDataKey returns a new DataKeySize-byte data key, in plaintext
+	// and wrapped with the current master key, and that key's version.
+	GenerateDataKey(ctx context.Context) (plaintext, wrapped []byte, version string, err error)
+
+	// DecryptDataKey unwraps a data key wrapped with master key version.
+	DecryptDataKey(ctx context.Context, version string, wrapped []byte) ([]byte, error)
+}
+
+var _ DataKeyProvider = (*StaticKeyProvider)(nil)
+
+// StaticKeyProvider wraps data keys with AES-256-GCM master keys held in
+// process memory, such as keys loaded from a secrets file. To rotate, add a
+// new version, make it current, run [Store.ReencryptAll], and remove the old
+// version once no rotation record needs it.
+type StaticKeyProvider struct {
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// NewStaticKeyProvider returns a provider holding keys by version, wrapping
+// new data keys with keys[current]. Every key must be DataKeySize bytes.
+func NewStaticKeyProvider(current string, keys map[string][]byte) (*StaticKeyProvider, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("%w: no key for current version %q", ErrInvalidKey, current)
+	}
+	p := &StaticKeyProvider{current: current, keys: make(map[string]cipher.AEAD, len(keys))}
+	for version, k := range keys {
+		aead, err := newAEAD(k)
+		if err != nil {
+			return nil, fmt.Errorf("%w: version %q: %w", ErrInvalidKey, version, err)
+		}
+		p.keys[version] = aead
+	}
+	return p, nil
+}
+
+// CurrentVersion implements DataKeyProvider.
+func (p *StaticKeyProvider) CurrentVersion() string { return p.current }
+
+// GenerateDataKey implements DataKeyProvider.
+func (p *StaticKeyProvider) GenerateDataKey(_ context.Context) (plaintext, wrapped []byte, version string, err error) {
+	plaintext = make([]byte, DataKeySize)
+	if _, err := rand.Read(plaintext); err != nil {
+		return nil, nil, "", fmt.Errorf("generate data key: %w", err)
+	}
+	wrapped, err = seal(p.keys[p.current], plaintext, []byte(p.current))
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return plaintext, wrapped, p.current, nil
+}
+
+// DecryptDataKey implements DataKeyProvider.
+func (p *StaticKeyProvider) DecryptDataKey(_ context.Context, version string, wrapped []byte) ([]byte, error) {
+	aead, ok := p.keys[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKeyVersion, version)
+	}
+	return open(aead, wrapped, []byte(version))
+}
+
+func newAEAD(k []byte) (cipher.AEAD, error) {
+	if len(k) != DataKeySize {
+		return nil, fmt.Errorf("key is %d bytes, want %d", len(k), DataKeySize)
+	}
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext with a random nonce, which it prepends to the
+// ciphertext.
+func seal(aead cipher.AEAD, plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// open reverses seal.
+func open(aead cipher.AEAD, sealed, aad []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	nonce, ct := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ct, aad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
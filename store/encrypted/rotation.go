@@ -0,0 +1,92 @@
+package encrypted
+
+import (
+	"context"
+	"time"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/rotation"
+)
+
+// rotationStore encrypts the old and new key hashes of rotation records.
+// Records are never looked up by hash, so no index is kept.
+type rotationStore struct {
+	inner rotation.Store
+	s     *sealer
+}
+
+func (rs *rotationStore) open(ctx context.Context, rec *rotation.Record) (*rotation.Record, error) {
+	var err error
+	if rec.OldKeyHash, err = rs.s.open(ctx, rec.OldKeyHash, purposeRotationOldHash); err != nil {
+		return nil, err
+	}
+	if rec.NewKeyHash, err = rs.s.open(ctx, rec.NewKeyHash, purposeRotationNewHash); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+func (rs *rotationStore) openAll(ctx context.Context, recs []*rotation.Record) ([]*rotation.Record, error) {
+	for _, rec := range recs {
+		if _, err := rs.open(ctx, rec); err != nil {
+			return nil, err
+		}
+	}
+	return recs, nil
+}
+
+func (rs *rotationStore) sealHash(ctx context.Context, hash, purpose string) (string, error) {
+	if hash == "" || isEnvelope(hash) {
+		return hash, nil
+	}
+	return rs.s.seal(ctx, hash, purpose)
+}
+
+func (rs *rotationStore) Create(ctx context.Context, rec *rotation.Record) error {
+	cp := *rec
+	var err error
+	if cp.OldKeyHash, err = rs.sealHash(ctx, rec.OldKeyHash, purposeRotationOldHash); err != nil {
+		return err
+	}
+	if cp.NewKeyHash, err = rs.sealHash(ctx, rec.NewKeyHash, purposeRotationNewHash); err != nil {
+		return err
+	}
+	return rs.inner.Create(ctx, &cp)
+}
+
+func (rs *rotationStore) Get(ctx context.Context, rotID id.RotationID) (*rotation.Record, error) {
+	rec, err := rs.inner.Get(ctx, rotID)
+	if err != nil {
+		return nil, err
+	}
+	return rs.open(ctx, rec)
+}
+
+func (rs *rotationStore) List(ctx context.Context, filter *rotation.ListFilter) ([]*rotation.Record, error) {
+	recs, err := rs.inner.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return rs.openAll(ctx, recs)
+}
+
+func (rs *rotationStore) ListPendingGrace(ctx context.Context, now time.Time) ([]*rotation.Record, error) {
+	recs, err := rs.inner.ListPendingGrace(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	return rs.openAll(ctx, recs)
+}
+
+func (rs *rotationStore) LatestForKey(ctx context.Context, keyID id.KeyID) (*rotation.Record, error) {
+	rec, err := rs.inner.LatestForKey(ctx, keyID)
+	if err != nil || rec == nil {
+		return rec, err
+	}
+	return rs.open(ctx, rec)
+}
+
+// CountByReason reads only reasons, which are stored in the clear.
+func (rs *rotationStore) CountByReason(ctx context.Context, filter *rotation.ListFilter) ([]*rotation.ReasonCount, error) {
+	return rs.inner.CountByReason(ctx, filter)
+}
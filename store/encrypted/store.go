@@ -0,0 +1,164 @@
+// Package encrypted wraps any store.Store so that key hashes are encrypted at
+// rest, making a database dump useless without access to the key provider.
+//
+// Key hashes and the old and new hashes of rotation records are
+// envelope-encrypted: each value is sealed with AES-256-GCM under a data key,
+// and the data key is wrapped by a [DataKeyProvider] such as a KMS. The
+// provider's master key version is recorded in every value, so the provider
+// can move to a new master key while old values stay readable, and
+// [Store.ReencryptAll] rewrites key hashes under the new one.
+//
+// Validation needs an equality lookup by hash, which ciphertext cannot
+// serve. The key_hash column therefore holds a deterministic HMAC-SHA256 of
+// the hash under a separate index key, and the ciphertext is kept in the
+// key's [MetadataHashEnvelope] metadata entry. The index reveals which rows
+// share a hash, which distinct keys never do, and it cannot be computed or
+// reversed without the index key; a dump leaks neither hashes nor anything
+// to test guessed keys against. The index key is not rotated with the
+// provider, since every index would then have to be recomputed from
+// decrypted hashes; keep it in the same secret store as the provider's
+// credentials.
+//
+// Hash tombstones hold the lookup index in place of the hash.
+//
+// The revocation feed still carries the first characters of each revoked
+// key's hash, as its consumers need them to match keys.
+//
+//	provider, err := encrypted.NewStaticKeyProvider("2024-06", map[string][]byte{"2024-06": masterKey})
+//	s, err := encrypted.New(postgres.New(db), provider, indexKey)
+//	eng, err := keysmith.NewEngine(keysmith.WithStore(s))
+package encrypted
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/tombstone"
+)
+
+// MinIndexKeySize is the minimum size in bytes of the index key given to New.
+const MinIndexKeySize = 32
+
+var (
+	// ErrInvalidKey is returned for a master, data, or index key of the
+	// wrong size.
+	ErrInvalidKey = errors.New("encrypted: invalid key")
+
+	// ErrUnknownKeyVersion is returned when a value was encrypted under a
+	// master key version the provider does not hold.
+	ErrUnknownKeyVersion = errors.New("encrypted: unknown key version")
+
+	// ErrDecrypt is returned for a value that is malformed or fails
+	// authentication, such as one altered in the database.
+	ErrDecrypt = errors.New("encrypted: cannot decrypt value")
+
+	// ErrKeyNotFound is returned by GetByHash when no key has the hash.
+	ErrKeyNotFound = errors.New("encrypted: key not found")
+)
+
+var (
+	_ store.Store     = (*Store)(nil)
+	_ store.Unwrapper = (*Store)(nil)
+)
+
+// Store encrypts key and rotation hashes on their way to the wrapped store
+// and decrypts them on the way back, and indexes tombstoned hashes. Every
+// other subsystem store is the wrapped store's own.
+type Store struct {
+	store.Store
+	s *sealer
+}
+
+// New wraps inner. provider wraps the data keys, and indexKey, at least
+// MinIndexKeySize random bytes, keys the lookup index. Keys already in inner
+// keep working unencrypted until ReencryptAll rewrites them.
+func New(inner store.Store, provider DataKeyProvider, indexKey []byte) (*Store, error) {
+	if len(indexKey) < MinIndexKeySize {
+		return nil, fmt.Errorf("%w: index key is %d bytes, want at least %d", ErrInvalidKey, len(indexKey), MinIndexKeySize)
+	}
+	return &Store{Store: inner, s: newSealer(provider, append([]byte(nil), indexKey...))}, nil
+}
+
+// Unwrap returns the wrapped store.
+func (s *Store) Unwrap() store.Store { return s.Store }
+
+// Keys returns the key store.
+func (s *Store) Keys() key.Store { return &keyStore{inner: s.Store.Keys(), s: s.s} }
+
+// Tombstones returns the hash tombstone store.
+func (s *Store) Tombstones() tombstone.Store {
+	return &tombstoneStore{Store: s.Store.Tombstones(), s: s.s}
+}
+
+// Rotations returns the rotation store.
+func (s *Store) Rotations() rotation.Store {
+	return &rotationStore{inner: s.Store.Rotations(), s: s.s}
+}
+
+// ReencryptReport describes a ReencryptAll run.
+type ReencryptReport struct {
+	// Scanned counts the keys read, and Reencrypted the keys rewritten
+	// under the provider's current version, including keys stored before
+	// encryption was enabled.
+	Scanned     int `json:"scanned"`
+	Reencrypted int `json:"reencrypted"`
+
+	// StaleRotations counts rotation records still encrypted under another
+	// version. Rotation records are never rewritten, so the provider must
+	// keep those versions until the records are no longer needed.
+	StaleRotations int `json:"stale_rotations"`
+}
+
+// ReencryptAll rewrites every key hash not yet encrypted under the
+// provider's current version, leaving UpdatedAt unchanged. Run it after
+// moving the provider to a new master key, or after wrapping a store that
+// already holds keys. It can run while serving and can be run again after
+// an error.
+func (s *Store) ReencryptAll(ctx context.Context) (*ReencryptReport, error) {
+	current := s.s.provider.CurrentVersion()
+	keys := &keyStore{inner: s.Store.Keys(), s: s.s}
+	report := &ReencryptReport{}
+
+	err := keys.inner.Iterate(ctx, &key.ListFilter{}, func(k *key.Key) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		report.Scanned++
+		env, sealed := k.Metadata[MetadataHashEnvelope].(string)
+		if !sealed && k.KeyHash == "" {
+			return nil
+		}
+		if version, _ := envelopeVersion(env); sealed && version == current {
+			return nil
+		}
+		if _, err := keys.open(ctx, k); err != nil {
+			return fmt.Errorf("key %s: %w", k.ID, err)
+		}
+		if err := keys.Update(ctx, k); err != nil {
+			return fmt.Errorf("key %s: %w", k.ID, err)
+		}
+		report.Reencrypted++
+		return nil
+	})
+	if err != nil {
+		return report, fmt.Errorf("reencrypt keys: %w", err)
+	}
+
+	recs, err := s.Store.Rotations().List(ctx, &rotation.ListFilter{})
+	if err != nil {
+		return report, fmt.Errorf("list rotations: %w", err)
+	}
+	for _, rec := range recs {
+		for _, v := range []string{rec.OldKeyHash, rec.NewKeyHash} {
+			if version, ok := envelopeVersion(v); ok && version != current {
+				report.StaleRotations++
+				break
+			}
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,41 @@
+package encrypted
+
+import (
+	"context"
+
+	"github.com/xraph/keysmith/tombstone"
+)
+
+// tombstoneStore keeps the lookup index of tombstoned hashes in place of the
+// hashes, which Find needs only to compare. Tombstones read back from List
+// therefore carry the index as their Hash.
+type tombstoneStore struct {
+	tombstone.Store
+	s *sealer
+}
+
+func (ts *tombstoneStore) Create(ctx context.Context, t *tombstone.Tombstone) error {
+	cp := *t
+	cp.Hash = ts.s.index(t.Hash)
+	return ts.Store.Create(ctx, &cp)
+}
+
+func (ts *tombstoneStore) Find(ctx context.Context, hashes []string) ([]*tombstone.Tombstone, error) {
+	byIndex := make(map[string]string, len(hashes))
+	indexes := make([]string, 0, len(hashes))
+	for _, h := range hashes {
+		idx := ts.s.index(h)
+		if _, ok := byIndex[idx]; !ok {
+			byIndex[idx] = h
+			indexes = append(indexes, idx)
+		}
+	}
+	found, err := ts.Store.Find(ctx, indexes)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range found {
+		t.Hash = byIndex[t.Hash]
+	}
+	return found, nil
+}
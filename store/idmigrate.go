@@ -0,0 +1,66 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/xraph/keysmith/id"
+)
+
+// DefaultIDMigrationBatch is the number of IDs MigrateIDs rewrites per
+// transaction when IDMigrationOptions.BatchSize is zero.
+const DefaultIDMigrationBatch = 500
+
+// IDMigrator is implemented by stores that can rewrite stored IDs from one
+// id.Format to another, for switching an existing deployment to
+// keysmith.WithIDFormat. It is optional; the engine checks for it with a
+// type assertion.
+//
+// Each entity table is walked in batches. A batch rewrites the entity IDs
+// and every column referencing them in one transaction, so the store stays
+// consistent if the migration stops part way; running it again picks up the
+// rows still in another format. Reads accept every format meanwhile.
+// References to rows that no longer exist, such as usage aggregates of a
+// deleted key, keep their old format.
+type IDMigrator interface {
+	// MigrateIDs rewrites stored IDs into opts.To.
+	MigrateIDs(ctx context.Context, opts IDMigrationOptions) (*IDMigrationReport, error)
+}
+
+// IDMigrationOptions controls a MigrateIDs call.
+type IDMigrationOptions struct {
+	// To is the format IDs are rewritten into.
+	To id.Format `json:"to"`
+
+	// BatchSize is the number of entity IDs rewritten per transaction.
+	// Zero means DefaultIDMigrationBatch.
+	BatchSize int `json:"batch_size,omitempty"`
+
+	// Tables limits the migration to these entity tables, e.g.
+	// "keysmith_keys". Empty migrates all of them.
+	Tables []string `json:"tables,omitempty"`
+}
+
+// IDMigrationReport describes a MigrateIDs run.
+type IDMigrationReport struct {
+	// Backend names the store, e.g. "postgres".
+	Backend string `json:"backend"`
+
+	To id.Format `json:"to"`
+
+	// Columns holds the rows rewritten per column, in migration order.
+	Columns []IDColumnReport `json:"columns"`
+
+	// Batches is the number of transactions committed.
+	Batches int `json:"batches"`
+
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// IDColumnReport is the number of rows rewritten in one ID column.
+type IDColumnReport struct {
+	Table     string `json:"table"`
+	Column    string `json:"column"`
+	Rewritten int64  `json:"rewritten"`
+}
@@ -0,0 +1,15 @@
+package store
+
+import "context"
+
+// InstallIDStore is implemented by stores that keep an install ID: a random
+// value identifying one deployment, shared by every engine using the store,
+// and nothing else. Telemetry reports it so that replicas and restarts of
+// one deployment count once. It is optional; the engine checks for it with
+// a type assertion.
+type InstallIDStore interface {
+	// InstallID stores candidate as the install ID unless one is stored,
+	// and returns the stored ID. Concurrent calls with different
+	// candidates return the same ID.
+	InstallID(ctx context.Context, candidate string) (string, error)
+}
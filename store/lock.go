@@ -0,0 +1,46 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrLockHeld is returned by AcquireLock when another holder has the
+	// lock and its TTL has not run out.
+	ErrLockHeld = errors.New("store: lock is held elsewhere")
+
+	// ErrLockLost is returned by Renew when the lock expired and was taken
+	// by another holder, or was released.
+	ErrLockLost = errors.New("store: lock was lost")
+)
+
+// Locker is implemented by stores that hold named locks for every engine
+// sharing the store, so that background jobs run once across replicas
+// rather than once on each. It is optional; the engine checks for it with a
+// type assertion.
+//
+// A lock is held until it is released or its TTL runs out, whichever comes
+// first; a holder that crashes frees its locks when their TTLs pass. TTLs are
+// leases, not guarantees: a holder paused for longer than its TTL can find
+// the lock lost, and must check Renew's error before relying on it.
+type Locker interface {
+	// AcquireLock takes the lock called name for ttl. It returns
+	// ErrLockHeld when the lock is held by another holder, including
+	// another AcquireLock call in the same process.
+	AcquireLock(ctx context.Context, name string, ttl time.Duration) (Lock, error)
+}
+
+// Lock is a lock taken by [Locker.AcquireLock].
+type Lock interface {
+	// Name returns the name the lock was taken under.
+	Name() string
+
+	// Renew extends the lock to ttl from now. It returns ErrLockLost when
+	// the lock is no longer held by this holder.
+	Renew(ctx context.Context, ttl time.Duration) error
+
+	// Release frees the lock. Releasing a lost or released lock is a no-op.
+	Release(ctx context.Context) error
+}
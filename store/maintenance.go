@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// Maintainer is implemented by stores that support routine maintenance:
+// reclaiming space left by deleted rows, refreshing planner statistics, and
+// reporting table sizes. It is optional; the engine checks for it with a
+// type assertion.
+type Maintainer interface {
+	// Maintain runs the backend's maintenance and reports per-table
+	// statistics taken afterwards.
+	Maintain(ctx context.Context, opts MaintenanceOptions) (*MaintenanceReport, error)
+}
+
+// MaintenanceOptions controls a Maintain call.
+type MaintenanceOptions struct {
+	// StatsOnly reports table statistics without running any maintenance.
+	StatsOnly bool `json:"stats_only,omitempty"`
+
+	// Full requests the backend's thorough variant, which may lock tables
+	// while it runs: VACUUM FULL in PostgreSQL, a full VACUUM in SQLite, and
+	// compact in MongoDB.
+	Full bool `json:"full,omitempty"`
+}
+
+// MaintenanceReport describes a Maintain run.
+type MaintenanceReport struct {
+	// Backend names the store, e.g. "postgres".
+	Backend string `json:"backend"`
+
+	// Actions lists the maintenance statements run, in order.
+	Actions []string `json:"actions"`
+
+	// Tables holds statistics for each keysmith table or collection,
+	// ordered by name.
+	Tables []TableStats `json:"tables"`
+
+	// Advice holds index suggestions drawn from the backend's scan
+	// statistics, chiefly for the usage table. It is advisory; nothing is
+	// created automatically.
+	Advice []string `json:"advice,omitempty"`
+
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// TableStats describes one table or collection. Counts and sizes come from
+// the backend's statistics and may be approximate.
+type TableStats struct {
+	Name string `json:"name"`
+
+	// Rows is the number of live rows or documents.
+	Rows int64 `json:"rows"`
+
+	// DeadRows is the number of deleted rows not yet reclaimed. Only
+	// PostgreSQL reports it.
+	DeadRows int64 `json:"dead_rows,omitempty"`
+
+	// SizeBytes is the on-disk size including indexes.
+	SizeBytes int64 `json:"size_bytes"`
+}
@@ -3,43 +3,100 @@ package memory
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/json"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/xraph/keysmith/capture"
+	"github.com/xraph/keysmith/group"
 	"github.com/xraph/keysmith/id"
 	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keyevent"
 	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/quotapool"
+	"github.com/xraph/keysmith/revocation"
 	"github.com/xraph/keysmith/rotation"
 	"github.com/xraph/keysmith/scope"
 	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/tenant"
+	"github.com/xraph/keysmith/tombstone"
+	"github.com/xraph/keysmith/transfer"
 	"github.com/xraph/keysmith/usage"
 )
 
-var _ store.Store = (*Store)(nil)
+var (
+	_ store.Store           = (*Store)(nil)
+	_ store.Maintainer      = (*Store)(nil)
+	_ store.StorageReporter = (*Store)(nil)
+	_ store.Locker          = (*Store)(nil)
+	_ store.InstallIDStore  = (*Store)(nil)
+)
 
 // Store is an in-memory store implementation for testing.
 type Store struct {
 	mu sync.RWMutex
 
-	keys      map[string]*key.Key         // keyID string -> Key
-	hashIndex map[string]string           // keyHash -> keyID string
-	policies  map[string]*policy.Policy   // policyID string -> Policy
-	usages    []*usage.Record             // append-only
-	rotations map[string]*rotation.Record // rotationID string -> Record
-	scopes    map[string]*scope.Scope     // scopeID string -> Scope
-	keyScopes map[string]map[string]bool  // keyID -> set of scope names
+	keys      map[string]*key.Key           // keyID string -> Key
+	hashIndex map[string]*key.HashVersion   // hash -> version
+	keyHashes map[string][]*key.HashVersion // keyID -> versions, oldest first
+	policies  map[string]*policy.Policy     // policyID string -> Policy
+	usages    []*usage.Record               // append-only
+	rotations map[string]*rotation.Record   // rotationID string -> Record
+	scopes    map[string]*scope.Scope       // scopeID string -> Scope
+	keyScopes map[string]map[string]bool    // keyID -> set of scope names
+	tenants   map[string]*tenant.Settings   // tenantID -> Settings
+	revisions map[string]uint64             // tenantID -> revision
+
+	endpoints map[string]map[string]*usage.EndpointActivity // keyID -> "METHOD endpoint" -> activity
+
+	revocations []*revocation.Entry // ordered by (At, KeyID)
+
+	keyEvents []*keyevent.Event // ordered by (At, KeyID)
+
+	captures []*capture.Capture // append-only
+
+	groups       map[string]*group.Group    // groupID string -> Group
+	groupMembers map[string]map[string]bool // groupID -> set of key IDs
+
+	transfers map[string]*transfer.Transfer // transferID string -> Transfer
+
+	tombstones map[string]*tombstone.Tombstone // hash -> Tombstone
+
+	pools     map[string]*quotapool.Pool        // poolID string -> Pool
+	poolUsage map[poolUsageKey]*quotapool.Usage // counts
+
+	locks map[string]memoryLock // lock name -> holder
+
+	installID string
 }
 
 // New creates a new in-memory store.
 func New() *Store {
 	return &Store{
 		keys:      make(map[string]*key.Key),
-		hashIndex: make(map[string]string),
+		hashIndex: make(map[string]*key.HashVersion),
+		keyHashes: make(map[string][]*key.HashVersion),
 		policies:  make(map[string]*policy.Policy),
 		rotations: make(map[string]*rotation.Record),
 		scopes:    make(map[string]*scope.Scope),
 		keyScopes: make(map[string]map[string]bool),
+		endpoints: make(map[string]map[string]*usage.EndpointActivity),
+		tenants:   make(map[string]*tenant.Settings),
+		revisions: make(map[string]uint64),
+		locks:     make(map[string]memoryLock),
+
+		groups:       make(map[string]*group.Group),
+		groupMembers: make(map[string]map[string]bool),
+
+		transfers:  make(map[string]*transfer.Transfer),
+		tombstones: make(map[string]*tombstone.Tombstone),
+
+		pools:     make(map[string]*quotapool.Pool),
+		poolUsage: make(map[poolUsageKey]*quotapool.Usage),
 	}
 }
 
@@ -50,10 +107,159 @@ func (s *Store) Policies() policy.Store    { return (*policyStore)(s) }
 func (s *Store) Usages() usage.Store       { return (*usageStore)(s) }
 func (s *Store) Rotations() rotation.Store { return (*rotationStore)(s) }
 func (s *Store) Scopes() scope.Store       { return (*scopeStore)(s) }
+func (s *Store) Tenants() tenant.Store     { return (*tenantStore)(s) }
+
+func (s *Store) Revocations() revocation.Store { return (*revocationStore)(s) }
+func (s *Store) Captures() capture.Store       { return (*captureStore)(s) }
+func (s *Store) Groups() group.Store           { return (*groupStore)(s) }
+func (s *Store) KeyEvents() keyevent.Store     { return (*keyEventStore)(s) }
+func (s *Store) Transfers() transfer.Store     { return (*transferStore)(s) }
+func (s *Store) Tombstones() tombstone.Store   { return (*tombstoneStore)(s) }
+func (s *Store) QuotaPools() quotapool.Store   { return (*quotaPoolStore)(s) }
+
+func (s *Store) Migrate(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return nil
+}
+func (s *Store) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return nil
+}
+func (s *Store) Close() error { return nil }
+
+// Maintain has nothing to reclaim in memory; it reports the number of
+// entries held under each table name the SQL stores use. Sizes are zero.
+func (s *Store) Maintain(ctx context.Context, _ store.MaintenanceOptions) (*store.MaintenanceReport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	start := time.Now()
+	var keyScopes, endpoints, members int
+	for _, names := range s.keyScopes {
+		keyScopes += len(names)
+	}
+	for _, kids := range s.groupMembers {
+		members += len(kids)
+	}
+	for _, seen := range s.endpoints {
+		endpoints += len(seen)
+	}
+	counts := map[string]int{
+		"keysmith_debug_captures":    len(s.captures),
+		"keysmith_hash_tombstones":   len(s.tombstones),
+		"keysmith_key_endpoint_seen": endpoints,
+		"keysmith_key_events":        len(s.keyEvents),
+		"keysmith_key_group_members": members,
+		"keysmith_key_groups":        len(s.groups),
+		"keysmith_key_revocations":   len(s.revocations),
+		"keysmith_key_scopes":        keyScopes,
+		"keysmith_key_transfers":     len(s.transfers),
+		"keysmith_keys":              len(s.keys),
+		"keysmith_locks":             len(s.locks),
+		"keysmith_policies":          len(s.policies),
+		"keysmith_rotations":         len(s.rotations),
+		"keysmith_scopes":            len(s.scopes),
+		"keysmith_tenant_revisions":  len(s.revisions),
+		"keysmith_tenant_settings":   len(s.tenants),
+		"keysmith_usage":             len(s.usages),
+	}
+	tables := make([]store.TableStats, 0, len(counts))
+	for name, n := range counts {
+		tables = append(tables, store.TableStats{Name: name, Rows: int64(n)})
+	}
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Name < tables[j].Name })
+
+	return &store.MaintenanceReport{
+		Backend:   "memory",
+		Actions:   []string{},
+		Tables:    tables,
+		StartedAt: start,
+		Duration:  time.Since(start),
+	}, nil
+}
+
+// usageRecordOverhead is the size attributed to the numeric and time
+// fields of a usage record in UsageBytes estimates.
+const usageRecordOverhead = 48
+
+// TenantStorage counts the entries each tenant holds. A usage record's size
+// is the length of its strings and JSON-encoded metadata plus
+// usageRecordOverhead bytes.
+func (s *Store) TenantStorage(ctx context.Context, tenantID string) ([]*store.TenantStorage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	byTenant := make(map[string]*store.TenantStorage)
+	get := func(tid string) *store.TenantStorage {
+		if tenantID != "" && tid != tenantID {
+			return nil
+		}
+		ts, ok := byTenant[tid]
+		if !ok {
+			ts = &store.TenantStorage{TenantID: tid, KeysByState: make(map[key.State]int64)}
+			byTenant[tid] = ts
+		}
+		return ts
+	}
+	for _, k := range s.keys {
+		if ts := get(k.TenantID); ts != nil {
+			ts.Keys++
+			ts.KeysByState[k.State]++
+		}
+	}
+	for _, p := range s.policies {
+		if ts := get(p.TenantID); ts != nil {
+			ts.Policies++
+		}
+	}
+	for _, sc := range s.scopes {
+		if ts := get(sc.TenantID); ts != nil {
+			ts.Scopes++
+		}
+	}
+	for _, r := range s.rotations {
+		if ts := get(r.TenantID); ts != nil {
+			ts.Rotations++
+		}
+	}
+	for _, u := range s.usages {
+		if ts := get(u.TenantID); ts != nil {
+			ts.UsageRows++
+			ts.UsageBytes += usageRecordSize(u)
+		}
+	}
 
-func (s *Store) Migrate(_ context.Context) error { return nil }
-func (s *Store) Ping(_ context.Context) error    { return nil }
-func (s *Store) Close() error                    { return nil }
+	out := make([]*store.TenantStorage, 0, len(byTenant))
+	for _, ts := range byTenant {
+		out = append(out, ts)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].TenantID < out[j].TenantID })
+	return out, nil
+}
+
+// usageRecordSize estimates the stored size of u.
+func usageRecordSize(u *usage.Record) int64 {
+	n := usageRecordOverhead + len(u.ID.String()) + len(u.KeyID.String()) + len(u.TenantID) + len(u.AppID) +
+		len(u.Endpoint) + len(u.Method) + len(u.IPAddress) + len(u.UserAgent) + len(u.Environment)
+	if len(u.Metadata) > 0 {
+		if b, err := json.Marshal(u.Metadata); err == nil {
+			n += len(b)
+		}
+	}
+	return int64(n)
+}
 
 // ══════════════════════════════════════════════════
 // Key Store
@@ -63,18 +269,42 @@ type keyStore Store
 
 func (s *keyStore) store() *Store { return (*Store)(s) }
 
-func (s *keyStore) Create(_ context.Context, k *key.Key) error {
+func (s *keyStore) Create(ctx context.Context, k *key.Key) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	st := s.store()
 	st.mu.Lock()
 	defer st.mu.Unlock()
 
+	if k.ExternalRef != "" {
+		for _, other := range st.keys {
+			if other.TenantID == k.TenantID && other.ExternalRef == k.ExternalRef {
+				return key.ErrExternalRefInUse
+			}
+		}
+	}
 	cp := *k
+	cp.ExpiresAt = utcTime(k.ExpiresAt)
+	cp.AllowedOrigins = slices.Clone(k.AllowedOrigins)
+	cp.Flags = slices.Clone(k.Flags)
+	cp.Contacts = slices.Clone(k.Contacts)
+	cp.Labels = k.Labels.Clone()
 	st.keys[k.ID.String()] = &cp
-	st.hashIndex[k.KeyHash] = k.ID.String()
+	at := k.CreatedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+	st.addHashLocked(&key.HashVersion{KeyID: k.ID, Hash: k.KeyHash, CreatedAt: at})
 	return nil
 }
 
-func (s *keyStore) Get(_ context.Context, keyID id.KeyID) (*key.Key, error) {
+func (s *keyStore) Get(ctx context.Context, keyID id.KeyID) (*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	st := s.store()
 	st.mu.RLock()
 	defer st.mu.RUnlock()
@@ -87,38 +317,142 @@ func (s *keyStore) Get(_ context.Context, keyID id.KeyID) (*key.Key, error) {
 	return &cp, nil
 }
 
-func (s *keyStore) GetByHash(_ context.Context, hash string) (*key.Key, error) {
+// GetByHash finds the key by index and confirms the match with a
+// constant-time comparison. The index lookup itself can only leak timing
+// about hashes, which do not reveal raw keys.
+func (s *keyStore) GetByHash(ctx context.Context, hash string) (*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	st := s.store()
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	for _, form := range key.LookupHashes(hash) {
+		if k, ok := st.keyByHashLocked(form); ok && key.HashesEqual(form, hash) {
+			cp := *k
+			return &cp, nil
+		}
+	}
+	return nil, errNotFound("key")
+}
+
+func (s *keyStore) GetByHashes(ctx context.Context, hashes []string) ([]*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	st := s.store()
 	st.mu.RLock()
 	defer st.mu.RUnlock()
 
-	kid, ok := st.hashIndex[hash]
-	if !ok {
-		return nil, errNotFound("key")
+	result := make([]*key.Key, 0, len(hashes))
+	seen := make(map[string]bool, len(hashes))
+	for _, form := range key.LookupHashes(hashes...) {
+		k, ok := st.keyByHashLocked(form)
+		if !ok || seen[k.ID.String()] {
+			continue
+		}
+		seen[k.ID.String()] = true
+		cp := *k
+		result = append(result, &cp)
 	}
-	k, ok := st.keys[kid]
-	if !ok {
-		return nil, errNotFound("key")
+	return result, nil
+}
+
+func (s *keyStore) AddHash(ctx context.Context, h *key.HashVersion) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
-	cp := *k
-	return &cp, nil
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if _, ok := st.keys[h.KeyID.String()]; !ok {
+		return errNotFound("key")
+	}
+	if v, ok := st.hashIndex[h.Hash]; ok && v.KeyID.String() != h.KeyID.String() {
+		if _, live := st.keys[v.KeyID.String()]; live {
+			return key.ErrHashInUse
+		}
+	}
+	cp := *h
+	if cp.CreatedAt.IsZero() {
+		cp.CreatedAt = time.Now()
+	}
+	st.addHashLocked(&cp)
+	return nil
+}
+
+func (s *keyStore) ListHashes(ctx context.Context, keyID id.KeyID) ([]*key.HashVersion, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	st := s.store()
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	versions := st.keyHashes[keyID.String()]
+	result := make([]*key.HashVersion, 0, len(versions))
+	for _, v := range versions {
+		cp := *v
+		result = append(result, &cp)
+	}
+	return result, nil
+}
+
+func (s *keyStore) DeactivateHash(ctx context.Context, keyID id.KeyID, hash string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	k, ok := st.keys[keyID.String()]
+	if !ok || k.KeyHash == hash {
+		return nil
+	}
+	if v, ok := st.hashIndex[hash]; ok && v.KeyID.String() == keyID.String() {
+		v.Active = false
+	}
+	return nil
 }
 
-func (s *keyStore) GetByPrefix(_ context.Context, prefix, hint string) (*key.Key, error) {
+func (s *keyStore) ListByPrefixHint(ctx context.Context, prefix, hint string) ([]*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	st := s.store()
 	st.mu.RLock()
 	defer st.mu.RUnlock()
 
+	result := make([]*key.Key, 0)
 	for _, k := range st.keys {
 		if k.Prefix == prefix && k.Hint == hint {
 			cp := *k
-			return &cp, nil
+			result = append(result, &cp)
 		}
 	}
-	return nil, errNotFound("key")
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.Before(result[j].CreatedAt)
+		}
+		return result[i].ID.String() < result[j].ID.String()
+	})
+	return result, nil
 }
 
-func (s *keyStore) Update(_ context.Context, k *key.Key) error {
+func (s *keyStore) Update(ctx context.Context, k *key.Key) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	st := s.store()
 	st.mu.Lock()
 	defer st.mu.Unlock()
@@ -127,17 +461,28 @@ func (s *keyStore) Update(_ context.Context, k *key.Key) error {
 	if !ok {
 		return errNotFound("key")
 	}
-	// Update hash index if hash changed.
+	// A new hash replaces the secret, so the old one's versions retire.
 	if old.KeyHash != k.KeyHash {
-		delete(st.hashIndex, old.KeyHash)
-		st.hashIndex[k.KeyHash] = k.ID.String()
+		for _, v := range st.keyHashes[k.ID.String()] {
+			v.Active = false
+		}
+		st.addHashLocked(&key.HashVersion{KeyID: k.ID, Hash: k.KeyHash, CreatedAt: time.Now()})
 	}
 	cp := *k
+	cp.ExpiresAt = utcTime(k.ExpiresAt)
+	cp.AllowedOrigins = slices.Clone(k.AllowedOrigins)
+	cp.Flags = slices.Clone(k.Flags)
+	cp.Contacts = slices.Clone(k.Contacts)
+	cp.Labels = k.Labels.Clone()
 	st.keys[k.ID.String()] = &cp
 	return nil
 }
 
-func (s *keyStore) UpdateState(_ context.Context, keyID id.KeyID, state key.State) error {
+func (s *keyStore) UpdateState(ctx context.Context, keyID id.KeyID, state key.State) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	st := s.store()
 	st.mu.Lock()
 	defer st.mu.Unlock()
@@ -151,7 +496,29 @@ func (s *keyStore) UpdateState(_ context.Context, keyID id.KeyID, state key.Stat
 	return nil
 }
 
-func (s *keyStore) UpdateLastUsed(_ context.Context, keyID id.KeyID, at time.Time) error {
+func (s *keyStore) UpdateStateIf(ctx context.Context, keyID id.KeyID, from, to key.State) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	k, ok := st.keys[keyID.String()]
+	if !ok || k.State != from {
+		return false, nil
+	}
+	k.State = to
+	k.UpdatedAt = time.Now()
+	return true, nil
+}
+
+func (s *keyStore) UpdateLastUsed(ctx context.Context, keyID id.KeyID, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	st := s.store()
 	st.mu.Lock()
 	defer st.mu.Unlock()
@@ -164,29 +531,39 @@ func (s *keyStore) UpdateLastUsed(_ context.Context, keyID id.KeyID, at time.Tim
 	return nil
 }
 
-func (s *keyStore) Delete(_ context.Context, keyID id.KeyID) error {
+func (s *keyStore) Delete(ctx context.Context, keyID id.KeyID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	st := s.store()
 	st.mu.Lock()
 	defer st.mu.Unlock()
 
-	k, ok := st.keys[keyID.String()]
-	if !ok {
+	if _, ok := st.keys[keyID.String()]; !ok {
 		return errNotFound("key")
 	}
-	delete(st.hashIndex, k.KeyHash)
+	st.dropHashesLocked(keyID.String())
 	delete(st.keys, keyID.String())
 	delete(st.keyScopes, keyID.String())
+	delete(st.endpoints, keyID.String())
+	st.dropMembershipsLocked(keyID.String())
+	st.dropTransfersLocked(keyID)
 	return nil
 }
 
-func (s *keyStore) List(_ context.Context, filter *key.ListFilter) ([]*key.Key, error) {
+func (s *keyStore) List(ctx context.Context, filter *key.ListFilter) ([]*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	st := s.store()
 	st.mu.RLock()
 	defer st.mu.RUnlock()
 
 	result := make([]*key.Key, 0, len(st.keys))
 	for _, k := range st.keys {
-		if !matchKeyFilter(k, filter) {
+		if !st.matchKeyLocked(k, filter) {
 			continue
 		}
 		cp := *k
@@ -198,21 +575,141 @@ func (s *keyStore) List(_ context.Context, filter *key.ListFilter) ([]*key.Key,
 	return applyPagination(result, filter.Offset, filter.Limit), nil
 }
 
-func (s *keyStore) Count(_ context.Context, filter *key.ListFilter) (int64, error) {
+func (s *keyStore) Count(ctx context.Context, filter *key.ListFilter) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
 	st := s.store()
 	st.mu.RLock()
 	defer st.mu.RUnlock()
 
 	var count int64
 	for _, k := range st.keys {
-		if matchKeyFilter(k, filter) {
+		if st.matchKeyLocked(k, filter) {
 			count++
 		}
 	}
 	return count, nil
 }
 
-func (s *keyStore) ListExpired(_ context.Context, before time.Time) ([]*key.Key, error) {
+// Iterate snapshots the IDs of matching keys, then copies and visits one key
+// at a time without holding the lock, so fn may call back into the store.
+func (s *keyStore) Iterate(ctx context.Context, filter *key.ListFilter, fn func(*key.Key) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	st := s.store()
+	st.mu.RLock()
+	ids := make([]string, 0, len(st.keys))
+	for kid, k := range st.keys {
+		if st.matchKeyLocked(k, filter) {
+			ids = append(ids, kid)
+		}
+	}
+	st.mu.RUnlock()
+
+	sort.Strings(ids)
+	if filter != nil {
+		if filter.Offset > len(ids) {
+			return nil
+		}
+		ids = ids[filter.Offset:]
+		if filter.Limit > 0 && filter.Limit < len(ids) {
+			ids = ids[:filter.Limit]
+		}
+	}
+
+	for _, kid := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		st.mu.RLock()
+		k, ok := st.keys[kid]
+		var cp key.Key
+		if ok {
+			cp = *k
+		}
+		st.mu.RUnlock()
+		if !ok {
+			continue // deleted since the snapshot
+		}
+		if err := fn(&cp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *keyStore) Overview(ctx context.Context, q *key.OverviewQuery) (*key.Overview, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	st := s.store()
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	o := key.NewOverview(q)
+	filter := &key.ListFilter{TenantID: q.TenantID, AppID: q.AppID}
+	createdAfter := q.Now.Add(-q.CreatedWithin)
+	for _, k := range st.keys {
+		if !matchKeyFilter(k, filter) {
+			continue
+		}
+		o.Total++
+		o.ByState[k.State]++
+		o.ByEnvironment[k.Environment]++
+		if k.CreatedAt.After(createdAfter) {
+			o.CreatedRecently.Count++
+		}
+		if k.State != key.StateActive {
+			continue
+		}
+		if k.LastUsedAt == nil {
+			o.NeverUsed++
+		}
+		if k.ExpiresAt != nil && k.ExpiresAt.After(q.Now) {
+			for i, w := range q.ExpiringWithin {
+				if !k.ExpiresAt.After(q.Now.Add(w)) {
+					o.Expiring[i].Count++
+				}
+			}
+		}
+	}
+	return o, nil
+}
+
+// Search checks every key in the query's tenant and app.
+func (s *keyStore) Search(ctx context.Context, q *key.SearchQuery) ([]*key.SearchMatch, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	st := s.store()
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	var matches []*key.SearchMatch
+	filter := &key.ListFilter{TenantID: q.TenantID, AppID: q.AppID}
+	for _, k := range st.keys {
+		if !matchKeyFilter(k, filter) {
+			continue
+		}
+		cp := *k
+		if m := key.MatchSearch(&cp, q.Terms); m != nil {
+			matches = append(matches, m)
+		}
+	}
+	return key.RankSearch(matches, q.Limit), nil
+}
+
+func (s *keyStore) ListExpired(ctx context.Context, before time.Time) ([]*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	st := s.store()
 	st.mu.RLock()
 	defer st.mu.RUnlock()
@@ -227,7 +724,36 @@ func (s *keyStore) ListExpired(_ context.Context, before time.Time) ([]*key.Key,
 	return result, nil
 }
 
-func (s *keyStore) ListByPolicy(_ context.Context, policyID id.PolicyID) ([]*key.Key, error) {
+func (s *keyStore) ListRecentlyUsed(ctx context.Context, limit int) ([]*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	st := s.store()
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	var result []*key.Key
+	for _, k := range st.keys {
+		if k.LastUsedAt != nil {
+			cp := *k
+			result = append(result, &cp)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].LastUsedAt.After(*result[j].LastUsedAt)
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (s *keyStore) ListByPolicy(ctx context.Context, policyID id.PolicyID) ([]*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	st := s.store()
 	st.mu.RLock()
 	defer st.mu.RUnlock()
@@ -243,21 +769,46 @@ func (s *keyStore) ListByPolicy(_ context.Context, policyID id.PolicyID) ([]*key
 	return result, nil
 }
 
-func (s *keyStore) DeleteByTenant(_ context.Context, tenantID string) error {
+func (s *keyStore) DeleteByTenant(ctx context.Context, tenantID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	st := s.store()
 	st.mu.Lock()
 	defer st.mu.Unlock()
 
 	for kid, k := range st.keys {
 		if k.TenantID == tenantID {
-			delete(st.hashIndex, k.KeyHash)
+			st.dropHashesLocked(kid)
 			delete(st.keys, kid)
 			delete(st.keyScopes, kid)
+			delete(st.endpoints, kid)
+			st.dropMembershipsLocked(kid)
+			st.dropTransfersLocked(k.ID)
 		}
 	}
 	return nil
 }
 
+// utcTime normalizes t to UTC, matching what the persistent stores write.
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+// matchKeyLocked reports whether k matches f, including its group
+// membership. The caller holds st.mu.
+func (st *Store) matchKeyLocked(k *key.Key, f *key.ListFilter) bool {
+	if f != nil && f.GroupID != nil && !st.groupMembers[f.GroupID.String()][k.ID.String()] {
+		return false
+	}
+	return matchKeyFilter(k, f)
+}
+
 func matchKeyFilter(k *key.Key, f *key.ListFilter) bool {
 	if f == nil {
 		return true
@@ -265,6 +816,9 @@ func matchKeyFilter(k *key.Key, f *key.ListFilter) bool {
 	if f.TenantID != "" && k.TenantID != f.TenantID {
 		return false
 	}
+	if f.AppID != "" && k.AppID != f.AppID {
+		return false
+	}
 	if f.Environment != "" && k.Environment != f.Environment {
 		return false
 	}
@@ -274,12 +828,36 @@ func matchKeyFilter(k *key.Key, f *key.ListFilter) bool {
 	if f.PolicyID != nil && (k.PolicyID == nil || k.PolicyID.String() != f.PolicyID.String()) {
 		return false
 	}
+	if f.PoolID != nil && (k.PoolID == nil || k.PoolID.String() != f.PoolID.String()) {
+		return false
+	}
 	if f.CreatedBy != "" && k.CreatedBy != f.CreatedBy {
 		return false
 	}
-	return true
-}
-
+	if f.ExternalRef != "" && k.ExternalRef != f.ExternalRef {
+		return false
+	}
+	if f.Name != "" && !strings.EqualFold(k.Name, f.Name) {
+		return false
+	}
+	if f.ExpiresBefore != nil && (k.ExpiresAt == nil || !k.ExpiresAt.Before(*f.ExpiresBefore)) {
+		return false
+	}
+	if !inTimeRange(k.CreatedAt, f.CreatedAfter, f.CreatedBefore) {
+		return false
+	}
+	if f.UpdatedAfter != nil && !k.UpdatedAt.After(*f.UpdatedAfter) {
+		return false
+	}
+	if f.OutdatedTerms != "" && k.AcceptedTermsVersion == f.OutdatedTerms {
+		return false
+	}
+	if !f.LabelSelector.Matches(k.Labels) {
+		return false
+	}
+	return true
+}
+
 // ══════════════════════════════════════════════════
 // Policy Store
 // ══════════════════════════════════════════════════
@@ -288,7 +866,11 @@ type policyStore Store
 
 func (s *policyStore) store() *Store { return (*Store)(s) }
 
-func (s *policyStore) Create(_ context.Context, pol *policy.Policy) error {
+func (s *policyStore) Create(ctx context.Context, pol *policy.Policy) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	st := s.store()
 	st.mu.Lock()
 	defer st.mu.Unlock()
@@ -298,7 +880,11 @@ func (s *policyStore) Create(_ context.Context, pol *policy.Policy) error {
 	return nil
 }
 
-func (s *policyStore) Get(_ context.Context, polID id.PolicyID) (*policy.Policy, error) {
+func (s *policyStore) Get(ctx context.Context, polID id.PolicyID) (*policy.Policy, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	st := s.store()
 	st.mu.RLock()
 	defer st.mu.RUnlock()
@@ -311,7 +897,11 @@ func (s *policyStore) Get(_ context.Context, polID id.PolicyID) (*policy.Policy,
 	return &cp, nil
 }
 
-func (s *policyStore) GetByName(_ context.Context, tenantID, name string) (*policy.Policy, error) {
+func (s *policyStore) GetByName(ctx context.Context, tenantID, name string) (*policy.Policy, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	st := s.store()
 	st.mu.RLock()
 	defer st.mu.RUnlock()
@@ -325,7 +915,11 @@ func (s *policyStore) GetByName(_ context.Context, tenantID, name string) (*poli
 	return nil, errNotFound("policy")
 }
 
-func (s *policyStore) Update(_ context.Context, pol *policy.Policy) error {
+func (s *policyStore) Update(ctx context.Context, pol *policy.Policy) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	st := s.store()
 	st.mu.Lock()
 	defer st.mu.Unlock()
@@ -338,7 +932,11 @@ func (s *policyStore) Update(_ context.Context, pol *policy.Policy) error {
 	return nil
 }
 
-func (s *policyStore) Delete(_ context.Context, polID id.PolicyID) error {
+func (s *policyStore) Delete(ctx context.Context, polID id.PolicyID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	st := s.store()
 	st.mu.Lock()
 	defer st.mu.Unlock()
@@ -350,14 +948,18 @@ func (s *policyStore) Delete(_ context.Context, polID id.PolicyID) error {
 	return nil
 }
 
-func (s *policyStore) List(_ context.Context, filter *policy.ListFilter) ([]*policy.Policy, error) {
+func (s *policyStore) List(ctx context.Context, filter *policy.ListFilter) ([]*policy.Policy, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	st := s.store()
 	st.mu.RLock()
 	defer st.mu.RUnlock()
 
 	result := make([]*policy.Policy, 0, len(st.policies))
 	for _, p := range st.policies {
-		if filter != nil && filter.TenantID != "" && p.TenantID != filter.TenantID {
+		if !matchPolicyFilter(p, filter) {
 			continue
 		}
 		cp := *p
@@ -370,14 +972,18 @@ func (s *policyStore) List(_ context.Context, filter *policy.ListFilter) ([]*pol
 	return applyPagination(result, offset, limit), nil
 }
 
-func (s *policyStore) Count(_ context.Context, filter *policy.ListFilter) (int64, error) {
+func (s *policyStore) Count(ctx context.Context, filter *policy.ListFilter) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
 	st := s.store()
 	st.mu.RLock()
 	defer st.mu.RUnlock()
 
 	var count int64
 	for _, p := range st.policies {
-		if filter != nil && filter.TenantID != "" && p.TenantID != filter.TenantID {
+		if !matchPolicyFilter(p, filter) {
 			continue
 		}
 		count++
@@ -385,6 +991,37 @@ func (s *policyStore) Count(_ context.Context, filter *policy.ListFilter) (int64
 	return count, nil
 }
 
+func matchPolicyFilter(p *policy.Policy, f *policy.ListFilter) bool {
+	if f == nil {
+		return !p.Inline
+	}
+	if p.Inline && !f.IncludeInline {
+		return false
+	}
+	if f.TenantID != "" && p.TenantID != f.TenantID {
+		return false
+	}
+	if f.AppID != "" && p.AppID != f.AppID {
+		return false
+	}
+	if !inTimeRange(p.CreatedAt, f.CreatedAfter, f.CreatedBefore) {
+		return false
+	}
+	if f.UpdatedAfter != nil && !p.UpdatedAt.After(*f.UpdatedAfter) {
+		return false
+	}
+	return true
+}
+
+// inTimeRange reports whether t is strictly between after and before, either
+// of which may be nil.
+func inTimeRange(t time.Time, after, before *time.Time) bool {
+	if after != nil && !t.After(*after) {
+		return false
+	}
+	return before == nil || t.Before(*before)
+}
+
 // ══════════════════════════════════════════════════
 // Usage Store
 // ══════════════════════════════════════════════════
@@ -393,7 +1030,11 @@ type usageStore Store
 
 func (s *usageStore) store() *Store { return (*Store)(s) }
 
-func (s *usageStore) Record(_ context.Context, rec *usage.Record) error {
+func (s *usageStore) Record(ctx context.Context, rec *usage.Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	st := s.store()
 	st.mu.Lock()
 	defer st.mu.Unlock()
@@ -403,7 +1044,11 @@ func (s *usageStore) Record(_ context.Context, rec *usage.Record) error {
 	return nil
 }
 
-func (s *usageStore) RecordBatch(_ context.Context, recs []*usage.Record) error {
+func (s *usageStore) RecordBatch(ctx context.Context, recs []*usage.Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	st := s.store()
 	st.mu.Lock()
 	defer st.mu.Unlock()
@@ -415,7 +1060,11 @@ func (s *usageStore) RecordBatch(_ context.Context, recs []*usage.Record) error
 	return nil
 }
 
-func (s *usageStore) Query(_ context.Context, filter *usage.QueryFilter) ([]*usage.Record, error) {
+func (s *usageStore) Query(ctx context.Context, filter *usage.QueryFilter) ([]*usage.Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	st := s.store()
 	st.mu.RLock()
 	defer st.mu.RUnlock()
@@ -435,11 +1084,19 @@ func (s *usageStore) Query(_ context.Context, filter *usage.QueryFilter) ([]*usa
 	return applyPagination(result, offset, limit), nil
 }
 
-func (s *usageStore) Aggregate(_ context.Context, _ *usage.QueryFilter) ([]*usage.Aggregation, error) {
+func (s *usageStore) Aggregate(ctx context.Context, _ *usage.QueryFilter) ([]*usage.Aggregation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	return nil, nil
 }
 
-func (s *usageStore) Count(_ context.Context, filter *usage.QueryFilter) (int64, error) {
+func (s *usageStore) Count(ctx context.Context, filter *usage.QueryFilter) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
 	st := s.store()
 	st.mu.RLock()
 	defer st.mu.RUnlock()
@@ -453,7 +1110,11 @@ func (s *usageStore) Count(_ context.Context, filter *usage.QueryFilter) (int64,
 	return count, nil
 }
 
-func (s *usageStore) Purge(_ context.Context, before time.Time) (int64, error) {
+func (s *usageStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
 	st := s.store()
 	st.mu.Lock()
 	defer st.mu.Unlock()
@@ -471,42 +1132,120 @@ func (s *usageStore) Purge(_ context.Context, before time.Time) (int64, error) {
 	return purged, nil
 }
 
-func (s *usageStore) DailyCount(_ context.Context, keyID id.KeyID, date time.Time) (int64, error) {
+func (s *usageStore) DailyCount(ctx context.Context, keyID id.KeyID, w usage.Window) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
 	st := s.store()
 	st.mu.RLock()
 	defer st.mu.RUnlock()
 
 	kid := keyID.String()
-	dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
-	dayEnd := dayStart.Add(24 * time.Hour)
-
 	var count int64
 	for _, rec := range st.usages {
-		if rec.KeyID.String() == kid && !rec.CreatedAt.Before(dayStart) && rec.CreatedAt.Before(dayEnd) {
+		if rec.KeyID.String() == kid && w.Contains(rec.CreatedAt) {
 			count++
 		}
 	}
 	return count, nil
 }
 
-func (s *usageStore) MonthlyCount(_ context.Context, keyID id.KeyID, month time.Time) (int64, error) {
+func (s *usageStore) MonthlyCount(ctx context.Context, keyID id.KeyID, w usage.Window) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
 	st := s.store()
 	st.mu.RLock()
 	defer st.mu.RUnlock()
 
 	kid := keyID.String()
-	monthStart := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, month.Location())
-	monthEnd := monthStart.AddDate(0, 1, 0)
-
 	var count int64
 	for _, rec := range st.usages {
-		if rec.KeyID.String() == kid && !rec.CreatedAt.Before(monthStart) && rec.CreatedAt.Before(monthEnd) {
+		if rec.KeyID.String() == kid && w.Contains(rec.CreatedAt) {
 			count++
 		}
 	}
 	return count, nil
 }
 
+func (s *usageStore) UpsertEndpointActivity(ctx context.Context, acts []*usage.EndpointActivity) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for _, a := range acts {
+		kid := a.KeyID.String()
+		byEndpoint, ok := st.endpoints[kid]
+		if !ok {
+			byEndpoint = make(map[string]*usage.EndpointActivity)
+			st.endpoints[kid] = byEndpoint
+		}
+		k := a.Method + " " + a.Endpoint
+		existing, ok := byEndpoint[k]
+		if !ok {
+			cp := *a
+			byEndpoint[k] = &cp
+			continue
+		}
+		existing.Count += a.Count
+		if a.LastSeenAt.After(existing.LastSeenAt) {
+			existing.LastSeenAt = a.LastSeenAt
+		}
+	}
+	return nil
+}
+
+func (s *usageStore) ListEndpointActivity(ctx context.Context, keyID id.KeyID) ([]*usage.EndpointActivity, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	st := s.store()
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	byEndpoint := st.endpoints[keyID.String()]
+	result := make([]*usage.EndpointActivity, 0, len(byEndpoint))
+	for _, a := range byEndpoint {
+		cp := *a
+		result = append(result, &cp)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].LastSeenAt.After(result[j].LastSeenAt)
+	})
+	return result, nil
+}
+
+func (s *usageStore) UpdateUsageIdentifiers(ctx context.Context, tenantID string, m *usage.IdentifierMatcher, limit int) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	// Records are kept in insertion order, which is oldest first.
+	var updated int64
+	for _, rec := range st.usages {
+		if limit > 0 && updated >= int64(limit) {
+			break
+		}
+		if rec.TenantID != tenantID || !m.Matches(rec) {
+			continue
+		}
+		m.Redact(rec)
+		updated++
+	}
+	return updated, nil
+}
+
 func matchUsageFilter(rec *usage.Record, f *usage.QueryFilter) bool {
 	if f == nil {
 		return true
@@ -517,6 +1256,12 @@ func matchUsageFilter(rec *usage.Record, f *usage.QueryFilter) bool {
 	if f.TenantID != "" && rec.TenantID != f.TenantID {
 		return false
 	}
+	if f.AppID != "" && rec.AppID != f.AppID {
+		return false
+	}
+	if f.Environment != "" && rec.Environment != f.Environment {
+		return false
+	}
 	if f.After != nil && rec.CreatedAt.Before(*f.After) {
 		return false
 	}
@@ -534,7 +1279,11 @@ type rotationStore Store
 
 func (s *rotationStore) store() *Store { return (*Store)(s) }
 
-func (s *rotationStore) Create(_ context.Context, rec *rotation.Record) error {
+func (s *rotationStore) Create(ctx context.Context, rec *rotation.Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	st := s.store()
 	st.mu.Lock()
 	defer st.mu.Unlock()
@@ -544,7 +1293,11 @@ func (s *rotationStore) Create(_ context.Context, rec *rotation.Record) error {
 	return nil
 }
 
-func (s *rotationStore) Get(_ context.Context, rotID id.RotationID) (*rotation.Record, error) {
+func (s *rotationStore) Get(ctx context.Context, rotID id.RotationID) (*rotation.Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	st := s.store()
 	st.mu.RLock()
 	defer st.mu.RUnlock()
@@ -557,7 +1310,11 @@ func (s *rotationStore) Get(_ context.Context, rotID id.RotationID) (*rotation.R
 	return &cp, nil
 }
 
-func (s *rotationStore) List(_ context.Context, filter *rotation.ListFilter) ([]*rotation.Record, error) {
+func (s *rotationStore) List(ctx context.Context, filter *rotation.ListFilter) ([]*rotation.Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	st := s.store()
 	st.mu.RLock()
 	defer st.mu.RUnlock()
@@ -580,7 +1337,11 @@ func (s *rotationStore) List(_ context.Context, filter *rotation.ListFilter) ([]
 	return applyPagination(result, offset, limit), nil
 }
 
-func (s *rotationStore) ListPendingGrace(_ context.Context, now time.Time) ([]*rotation.Record, error) {
+func (s *rotationStore) ListPendingGrace(ctx context.Context, now time.Time) ([]*rotation.Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	st := s.store()
 	st.mu.RLock()
 	defer st.mu.RUnlock()
@@ -595,7 +1356,11 @@ func (s *rotationStore) ListPendingGrace(_ context.Context, now time.Time) ([]*r
 	return result, nil
 }
 
-func (s *rotationStore) LatestForKey(_ context.Context, keyID id.KeyID) (*rotation.Record, error) {
+func (s *rotationStore) LatestForKey(ctx context.Context, keyID id.KeyID) (*rotation.Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	st := s.store()
 	st.mu.RLock()
 	defer st.mu.RUnlock()
@@ -616,6 +1381,34 @@ func (s *rotationStore) LatestForKey(_ context.Context, keyID id.KeyID) (*rotati
 	return latest, nil
 }
 
+func (s *rotationStore) CountByReason(ctx context.Context, filter *rotation.ListFilter) ([]*rotation.ReasonCount, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	st := s.store()
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	counts := make(map[rotation.Reason]int64)
+	for _, r := range st.rotations {
+		if matchRotationFilter(r, filter) {
+			counts[r.Reason]++
+		}
+	}
+	result := make([]*rotation.ReasonCount, 0, len(counts))
+	for reason, n := range counts {
+		result = append(result, &rotation.ReasonCount{Reason: reason, Count: n})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Reason < result[j].Reason
+	})
+	return result, nil
+}
+
 func matchRotationFilter(r *rotation.Record, f *rotation.ListFilter) bool {
 	if f == nil {
 		return true
@@ -626,10 +1419,13 @@ func matchRotationFilter(r *rotation.Record, f *rotation.ListFilter) bool {
 	if f.TenantID != "" && r.TenantID != f.TenantID {
 		return false
 	}
+	if f.AppID != "" && r.AppID != f.AppID {
+		return false
+	}
 	if f.Reason != "" && r.Reason != f.Reason {
 		return false
 	}
-	return true
+	return inTimeRange(r.CreatedAt, f.CreatedAfter, f.CreatedBefore)
 }
 
 // ══════════════════════════════════════════════════
@@ -640,7 +1436,11 @@ type scopeStore Store
 
 func (s *scopeStore) store() *Store { return (*Store)(s) }
 
-func (s *scopeStore) Create(_ context.Context, sc *scope.Scope) error {
+func (s *scopeStore) Create(ctx context.Context, sc *scope.Scope) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	st := s.store()
 	st.mu.Lock()
 	defer st.mu.Unlock()
@@ -650,7 +1450,11 @@ func (s *scopeStore) Create(_ context.Context, sc *scope.Scope) error {
 	return nil
 }
 
-func (s *scopeStore) Get(_ context.Context, scopeID id.ScopeID) (*scope.Scope, error) {
+func (s *scopeStore) Get(ctx context.Context, scopeID id.ScopeID) (*scope.Scope, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	st := s.store()
 	st.mu.RLock()
 	defer st.mu.RUnlock()
@@ -663,7 +1467,11 @@ func (s *scopeStore) Get(_ context.Context, scopeID id.ScopeID) (*scope.Scope, e
 	return &cp, nil
 }
 
-func (s *scopeStore) GetByName(_ context.Context, tenantID, name string) (*scope.Scope, error) {
+func (s *scopeStore) GetByName(ctx context.Context, tenantID, name string) (*scope.Scope, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	st := s.store()
 	st.mu.RLock()
 	defer st.mu.RUnlock()
@@ -677,7 +1485,11 @@ func (s *scopeStore) GetByName(_ context.Context, tenantID, name string) (*scope
 	return nil, errNotFound("scope")
 }
 
-func (s *scopeStore) Update(_ context.Context, sc *scope.Scope) error {
+func (s *scopeStore) Update(ctx context.Context, sc *scope.Scope) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	st := s.store()
 	st.mu.Lock()
 	defer st.mu.Unlock()
@@ -690,7 +1502,11 @@ func (s *scopeStore) Update(_ context.Context, sc *scope.Scope) error {
 	return nil
 }
 
-func (s *scopeStore) Delete(_ context.Context, scopeID id.ScopeID) error {
+func (s *scopeStore) Delete(ctx context.Context, scopeID id.ScopeID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	st := s.store()
 	st.mu.Lock()
 	defer st.mu.Unlock()
@@ -702,7 +1518,11 @@ func (s *scopeStore) Delete(_ context.Context, scopeID id.ScopeID) error {
 	return nil
 }
 
-func (s *scopeStore) List(_ context.Context, filter *scope.ListFilter) ([]*scope.Scope, error) {
+func (s *scopeStore) List(ctx context.Context, filter *scope.ListFilter) ([]*scope.Scope, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	st := s.store()
 	st.mu.RLock()
 	defer st.mu.RUnlock()
@@ -713,13 +1533,22 @@ func (s *scopeStore) List(_ context.Context, filter *scope.ListFilter) ([]*scope
 			if filter.TenantID != "" && sc.TenantID != filter.TenantID {
 				continue
 			}
+			if filter.AppID != "" && sc.AppID != filter.AppID {
+				continue
+			}
 			if filter.Parent != "" && sc.Parent != filter.Parent {
 				continue
 			}
+			if filter.CreatedAfter != nil && !sc.CreatedAt.After(*filter.CreatedAfter) {
+				continue
+			}
 		}
 		cp := *sc
 		result = append(result, &cp)
 	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
 	offset, limit := 0, 0
 	if filter != nil {
 		offset, limit = filter.Offset, filter.Limit
@@ -727,38 +1556,84 @@ func (s *scopeStore) List(_ context.Context, filter *scope.ListFilter) ([]*scope
 	return applyPagination(result, offset, limit), nil
 }
 
-func (s *scopeStore) ListByKey(_ context.Context, keyID id.KeyID) ([]*scope.Scope, error) {
+func (s *scopeStore) ListByKey(ctx context.Context, keyID id.KeyID) ([]*scope.Scope, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	st := s.store()
 	st.mu.RLock()
 	defer st.mu.RUnlock()
 
+	// Scope names are per tenant, so a stored key matches only its
+	// tenant's scopes.
 	names := st.keyScopes[keyID.String()]
-	result := make([]*scope.Scope, 0, len(st.scopes))
+	tenantID := ""
+	if k, ok := st.keys[keyID.String()]; ok {
+		tenantID = k.TenantID
+	}
+	result := make([]*scope.Scope, 0, len(names))
 	for _, sc := range st.scopes {
-		if names[sc.Name] {
+		if names[sc.Name] && (tenantID == "" || sc.TenantID == tenantID) {
 			cp := *sc
 			result = append(result, &cp)
 		}
 	}
+	// By name, as the SQL stores return them.
+	slices.SortFunc(result, func(a, b *scope.Scope) int { return strings.Compare(a.Name, b.Name) })
 	return result, nil
 }
 
-func (s *scopeStore) AssignToKey(_ context.Context, keyID id.KeyID, scopeNames []string) error {
+func (s *scopeStore) AssignToKey(ctx context.Context, keyID id.KeyID, scopeNames []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	st := s.store()
 	st.mu.Lock()
 	defer st.mu.Unlock()
 
+	if len(scopeNames) == 0 {
+		return nil
+	}
 	kid := keyID.String()
+	// As in the SQL stores, every name must be a scope of the key's
+	// tenant, and nothing is assigned unless all are.
+	k, ok := st.keys[kid]
+	if !ok {
+		return errNotFound("key")
+	}
+	for _, name := range scopeNames {
+		if !st.hasScopeLocked(k.TenantID, name) {
+			return errNotFound("scope")
+		}
+	}
 	if st.keyScopes[kid] == nil {
 		st.keyScopes[kid] = make(map[string]bool)
 	}
 	for _, name := range scopeNames {
 		st.keyScopes[kid][name] = true
 	}
+	st.touchKeyLocked(kid)
 	return nil
 }
 
-func (s *scopeStore) RemoveFromKey(_ context.Context, keyID id.KeyID, scopeNames []string) error {
+// hasScopeLocked reports whether tenantID has a scope named name. The
+// caller holds st.mu.
+func (st *Store) hasScopeLocked(tenantID, name string) bool {
+	for _, sc := range st.scopes {
+		if sc.TenantID == tenantID && sc.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *scopeStore) RemoveFromKey(ctx context.Context, keyID id.KeyID, scopeNames []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	st := s.store()
 	st.mu.Lock()
 	defer st.mu.Unlock()
@@ -767,12 +1642,1203 @@ func (s *scopeStore) RemoveFromKey(_ context.Context, keyID id.KeyID, scopeNames
 	for _, name := range scopeNames {
 		delete(st.keyScopes[kid], name)
 	}
+	st.touchKeyLocked(kid)
 	return nil
 }
 
+// touchKeyLocked bumps a key's UpdatedAt, so scope changes show up in
+// incremental key listings. st.mu must be held.
+func (st *Store) touchKeyLocked(kid string) {
+	if k, ok := st.keys[kid]; ok {
+		k.UpdatedAt = time.Now()
+	}
+}
+
 // ══════════════════════════════════════════════════
-// Helpers
+// Tenant Store
+// ══════════════════════════════════════════════════
+
+type tenantStore Store
+
+func (s *tenantStore) store() *Store { return (*Store)(s) }
+
+func (s *tenantStore) GetSettings(ctx context.Context, tenantID string) (*tenant.Settings, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	st := s.store()
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	ts, ok := st.tenants[tenantID]
+	if !ok {
+		return nil, errNotFound("tenant settings")
+	}
+	cp := *ts
+	cp.DefaultScopes = append([]string(nil), ts.DefaultScopes...)
+	cp.DefaultContacts = slices.Clone(ts.DefaultContacts)
+	cp.MetadataSchema = ts.MetadataSchema.Clone()
+	cp.Lockdown = ts.Lockdown.Clone()
+	return &cp, nil
+}
+
+func (s *tenantStore) UpsertSettings(ctx context.Context, ts *tenant.Settings) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	cp := *ts
+	cp.DefaultScopes = append([]string(nil), ts.DefaultScopes...)
+	cp.DefaultContacts = slices.Clone(ts.DefaultContacts)
+	cp.MetadataSchema = ts.MetadataSchema.Clone()
+	cp.Lockdown = ts.Lockdown.Clone()
+	st.tenants[ts.TenantID] = &cp
+	return nil
+}
+
+func (s *tenantStore) DeleteSettings(ctx context.Context, tenantID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if _, ok := st.tenants[tenantID]; !ok {
+		return errNotFound("tenant settings")
+	}
+	delete(st.tenants, tenantID)
+	return nil
+}
+
+func (s *tenantStore) Revision(ctx context.Context, tenantID string) (uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	st := s.store()
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	return st.revisions[tenantID], nil
+}
+
+func (s *tenantStore) BumpRevision(ctx context.Context, tenantID string) (uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.revisions[tenantID]++
+	return st.revisions[tenantID], nil
+}
+
 // ══════════════════════════════════════════════════
+// Revocation Store
+// ══════════════════════════════════════════════════
+
+type revocationStore Store
+
+func (s *revocationStore) store() *Store { return (*Store)(s) }
+
+func (s *revocationStore) Append(ctx context.Context, e *revocation.Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	cp := *e
+	cp.At = e.At.UTC()
+	pos := sort.Search(len(st.revocations), func(i int) bool {
+		return revocationAfter(st.revocations[i], revocation.CursorOf(&cp))
+	})
+	if pos > 0 {
+		// Like the SQL stores, ignore a repeated (At, KeyID) entry.
+		if prev := st.revocations[pos-1]; prev.At.Equal(cp.At) && prev.KeyID == cp.KeyID {
+			return nil
+		}
+	}
+	st.revocations = append(st.revocations, nil)
+	copy(st.revocations[pos+1:], st.revocations[pos:])
+	st.revocations[pos] = &cp
+	return nil
+}
+
+func (s *revocationStore) ListAfter(ctx context.Context, tenantID string, after revocation.Cursor, limit int) ([]*revocation.Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	st := s.store()
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	start := sort.Search(len(st.revocations), func(i int) bool {
+		return revocationAfter(st.revocations[i], after)
+	})
+	var result []*revocation.Entry
+	for _, e := range st.revocations[start:] {
+		if tenantID != "" && e.TenantID != tenantID {
+			continue
+		}
+		cp := *e
+		result = append(result, &cp)
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+func (s *revocationStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	n := sort.Search(len(st.revocations), func(i int) bool {
+		return !st.revocations[i].At.Before(before)
+	})
+	st.revocations = append([]*revocation.Entry(nil), st.revocations[n:]...)
+	return int64(n), nil
+}
+
+// revocationAfter reports whether e is positioned after c in (At, KeyID)
+// order.
+func revocationAfter(e *revocation.Entry, c revocation.Cursor) bool {
+	if !e.At.Equal(c.At) {
+		return e.At.After(c.At)
+	}
+	return e.KeyID.String() > c.KeyID
+}
+
+// ══════════════════════════════════════════════════
+// Key Event Store
+// ══════════════════════════════════════════════════
+
+type keyEventStore Store
+
+func (s *keyEventStore) store() *Store { return (*Store)(s) }
+
+func (s *keyEventStore) Append(ctx context.Context, e *keyevent.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	cp := *e
+	cp.At = e.At.UTC()
+	pos := sort.Search(len(st.keyEvents), func(i int) bool {
+		return keyEventAfter(st.keyEvents[i], keyevent.CursorOf(&cp))
+	})
+	if pos > 0 {
+		if prev := st.keyEvents[pos-1]; prev.At.Equal(cp.At) && prev.KeyID == cp.KeyID {
+			return nil
+		}
+	}
+	st.keyEvents = append(st.keyEvents, nil)
+	copy(st.keyEvents[pos+1:], st.keyEvents[pos:])
+	st.keyEvents[pos] = &cp
+	return nil
+}
+
+func (s *keyEventStore) ListAfter(ctx context.Context, filter *keyevent.ListFilter) ([]*keyevent.Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	st := s.store()
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	if filter == nil {
+		filter = &keyevent.ListFilter{}
+	}
+	match := keyevent.Filter{Types: filter.Types, KeyID: filter.KeyID}
+	start := sort.Search(len(st.keyEvents), func(i int) bool {
+		return keyEventAfter(st.keyEvents[i], filter.After)
+	})
+	var result []*keyevent.Event
+	for _, e := range st.keyEvents[start:] {
+		if filter.TenantID != "" && e.TenantID != filter.TenantID {
+			continue
+		}
+		if filter.AppID != "" && e.AppID != filter.AppID {
+			continue
+		}
+		if !match.Matches(e) {
+			continue
+		}
+		cp := *e
+		result = append(result, &cp)
+		if filter.Limit > 0 && len(result) >= filter.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+func (s *keyEventStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	n := sort.Search(len(st.keyEvents), func(i int) bool {
+		return !st.keyEvents[i].At.Before(before)
+	})
+	st.keyEvents = append([]*keyevent.Event(nil), st.keyEvents[n:]...)
+	return int64(n), nil
+}
+
+// keyEventAfter reports whether e is positioned after c in (At, KeyID)
+// order.
+func keyEventAfter(e *keyevent.Event, c keyevent.Cursor) bool {
+	if !e.At.Equal(c.At) {
+		return e.At.After(c.At)
+	}
+	return e.KeyID.String() > c.KeyID
+}
+
+// ══════════════════════════════════════════════════
+// Capture Store
+// ══════════════════════════════════════════════════
+
+type captureStore Store
+
+func (s *captureStore) store() *Store { return (*Store)(s) }
+
+func (s *captureStore) Record(ctx context.Context, c *capture.Capture) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	cp := *c
+	cp.Headers = make(map[string]string, len(c.Headers))
+	for k, v := range c.Headers {
+		cp.Headers[k] = v
+	}
+	st.captures = append(st.captures, &cp)
+	return nil
+}
+
+func (s *captureStore) List(ctx context.Context, keyID id.KeyID, limit int) ([]*capture.Capture, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	st := s.store()
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	var result []*capture.Capture
+	for i := len(st.captures) - 1; i >= 0; i-- {
+		c := st.captures[i]
+		if c.KeyID != keyID {
+			continue
+		}
+		cp := *c
+		result = append(result, &cp)
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+func (s *captureStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	kept := st.captures[:0]
+	for _, c := range st.captures {
+		if !c.CapturedAt.Before(before) {
+			kept = append(kept, c)
+		}
+	}
+	n := int64(len(st.captures) - len(kept))
+	clear(st.captures[len(kept):])
+	st.captures = kept
+	return n, nil
+}
+
+// ══════════════════════════════════════════════════
+// Group Store
+// ══════════════════════════════════════════════════
+
+type groupStore Store
+
+func (s *groupStore) store() *Store { return (*Store)(s) }
+
+func (s *groupStore) Create(ctx context.Context, g *group.Group) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	cp := *g
+	st.groups[g.ID.String()] = &cp
+	return nil
+}
+
+func (s *groupStore) Get(ctx context.Context, groupID id.GroupID) (*group.Group, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	st := s.store()
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	g, ok := st.groups[groupID.String()]
+	if !ok {
+		return nil, errNotFound("group")
+	}
+	cp := *g
+	return &cp, nil
+}
+
+func (s *groupStore) Update(ctx context.Context, g *group.Group) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if _, ok := st.groups[g.ID.String()]; !ok {
+		return errNotFound("group")
+	}
+	cp := *g
+	st.groups[g.ID.String()] = &cp
+	return nil
+}
+
+func (s *groupStore) Delete(ctx context.Context, groupID id.GroupID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if _, ok := st.groups[groupID.String()]; !ok {
+		return errNotFound("group")
+	}
+	delete(st.groups, groupID.String())
+	delete(st.groupMembers, groupID.String())
+	return nil
+}
+
+func (s *groupStore) List(ctx context.Context, filter *group.ListFilter) ([]*group.Group, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	st := s.store()
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	result := make([]*group.Group, 0, len(st.groups))
+	for _, g := range st.groups {
+		if filter != nil {
+			if filter.TenantID != "" && g.TenantID != filter.TenantID {
+				continue
+			}
+			if filter.AppID != "" && g.AppID != filter.AppID {
+				continue
+			}
+		}
+		cp := *g
+		result = append(result, &cp)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].ID.String() < result[j].ID.String()
+	})
+	offset, limit := 0, 0
+	if filter != nil {
+		offset, limit = filter.Offset, filter.Limit
+	}
+	return applyPagination(result, offset, limit), nil
+}
+
+func (s *groupStore) AddKeys(ctx context.Context, groupID id.GroupID, keyIDs []id.KeyID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	gid := groupID.String()
+	if _, ok := st.groups[gid]; !ok {
+		return errNotFound("group")
+	}
+	if st.groupMembers[gid] == nil {
+		st.groupMembers[gid] = make(map[string]bool)
+	}
+	for _, keyID := range keyIDs {
+		st.groupMembers[gid][keyID.String()] = true
+	}
+	return nil
+}
+
+func (s *groupStore) RemoveKeys(ctx context.Context, groupID id.GroupID, keyIDs []id.KeyID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	gid := groupID.String()
+	if _, ok := st.groups[gid]; !ok {
+		return errNotFound("group")
+	}
+	for _, keyID := range keyIDs {
+		delete(st.groupMembers[gid], keyID.String())
+	}
+	return nil
+}
+
+func (s *groupStore) ListByKeys(ctx context.Context, keyIDs []id.KeyID) (map[id.KeyID][]id.GroupID, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	st := s.store()
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	result := make(map[id.KeyID][]id.GroupID)
+	for _, keyID := range keyIDs {
+		kid := keyID.String()
+		for gid, members := range st.groupMembers {
+			if members[kid] {
+				result[keyID] = append(result[keyID], st.groups[gid].ID)
+			}
+		}
+	}
+	for keyID, gids := range result {
+		sort.Slice(gids, func(i, j int) bool { return gids[i].String() < gids[j].String() })
+		result[keyID] = gids
+	}
+	return result, nil
+}
+
+// dropMembershipsLocked removes the key from every group. The caller holds
+// st.mu.
+func (st *Store) dropMembershipsLocked(kid string) {
+	for _, members := range st.groupMembers {
+		delete(members, kid)
+	}
+}
+
+// ══════════════════════════════════════════════════
+// Quota Pool Store
+// ══════════════════════════════════════════════════
+
+type quotaPoolStore Store
+
+// poolUsageKey identifies one stored count.
+type poolUsageKey struct {
+	poolID, keyID string
+	period        quotapool.Period
+	windowStart   int64 // UnixNano
+}
+
+func (s *quotaPoolStore) store() *Store { return (*Store)(s) }
+
+func (s *quotaPoolStore) Create(ctx context.Context, p *quotapool.Pool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	cp := *p
+	st.pools[p.ID.String()] = &cp
+	return nil
+}
+
+func (s *quotaPoolStore) Get(ctx context.Context, poolID id.QuotaPoolID) (*quotapool.Pool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	st := s.store()
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	p, ok := st.pools[poolID.String()]
+	if !ok {
+		return nil, errNotFound("quota pool")
+	}
+	cp := *p
+	return &cp, nil
+}
+
+func (s *quotaPoolStore) Update(ctx context.Context, p *quotapool.Pool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if _, ok := st.pools[p.ID.String()]; !ok {
+		return errNotFound("quota pool")
+	}
+	cp := *p
+	st.pools[p.ID.String()] = &cp
+	return nil
+}
+
+func (s *quotaPoolStore) Delete(ctx context.Context, poolID id.QuotaPoolID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	pid := poolID.String()
+	if _, ok := st.pools[pid]; !ok {
+		return errNotFound("quota pool")
+	}
+	delete(st.pools, pid)
+	for k := range st.poolUsage {
+		if k.poolID == pid {
+			delete(st.poolUsage, k)
+		}
+	}
+	for _, k := range st.keys {
+		if k.PoolID != nil && k.PoolID.String() == pid {
+			k.PoolID = nil
+		}
+	}
+	return nil
+}
+
+func (s *quotaPoolStore) List(ctx context.Context, filter *quotapool.ListFilter) ([]*quotapool.Pool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	st := s.store()
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	result := make([]*quotapool.Pool, 0, len(st.pools))
+	for _, p := range st.pools {
+		if filter != nil {
+			if filter.TenantID != "" && p.TenantID != filter.TenantID {
+				continue
+			}
+			if filter.AppID != "" && p.AppID != filter.AppID {
+				continue
+			}
+		}
+		cp := *p
+		result = append(result, &cp)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].ID.String() < result[j].ID.String()
+	})
+	offset, limit := 0, 0
+	if filter != nil {
+		offset, limit = filter.Offset, filter.Limit
+	}
+	return applyPagination(result, offset, limit), nil
+}
+
+func poolUsageKeyOf(u *quotapool.Usage) poolUsageKey {
+	return poolUsageKey{poolID: u.PoolID.String(), keyID: u.KeyID.String(), period: u.Period, windowStart: u.WindowStart.UnixNano()}
+}
+
+func (s *quotaPoolStore) AddUsage(ctx context.Context, usages []*quotapool.Usage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for _, u := range usages {
+		k := poolUsageKeyOf(u)
+		if stored, ok := st.poolUsage[k]; ok {
+			stored.Count += u.Count
+			continue
+		}
+		cp := *u
+		cp.WindowStart = u.WindowStart.UTC()
+		st.poolUsage[k] = &cp
+	}
+	return nil
+}
+
+func (s *quotaPoolStore) ListUsage(ctx context.Context, filter *quotapool.UsageFilter) ([]*quotapool.Usage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	st := s.store()
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	pid := filter.PoolID.String()
+	var result []*quotapool.Usage
+	for k, u := range st.poolUsage {
+		if k.poolID != pid {
+			continue
+		}
+		if filter.KeyID != nil && k.keyID != filter.KeyID.String() {
+			continue
+		}
+		if filter.Period != "" && u.Period != filter.Period {
+			continue
+		}
+		if !filter.From.IsZero() && u.WindowStart.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && !u.WindowStart.Before(filter.To) {
+			continue
+		}
+		cp := *u
+		result = append(result, &cp)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].WindowStart.Equal(result[j].WindowStart) {
+			return result[i].WindowStart.Before(result[j].WindowStart)
+		}
+		return result[i].KeyID.String() < result[j].KeyID.String()
+	})
+	return result, nil
+}
+
+func (s *quotaPoolStore) PurgeUsage(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var n int64
+	for k, u := range st.poolUsage {
+		if u.WindowStart.Before(before) {
+			delete(st.poolUsage, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// ══════════════════════════════════════════════════
+// Transfer Store
+// ══════════════════════════════════════════════════
+
+type transferStore Store
+
+func (s *transferStore) store() *Store { return (*Store)(s) }
+
+func copyTransfer(t *transfer.Transfer) *transfer.Transfer {
+	cp := *t
+	cp.UnmappedScopes = slices.Clone(t.UnmappedScopes)
+	cp.ResolvedAt = utcTime(t.ResolvedAt)
+	return &cp
+}
+
+func (s *transferStore) Create(ctx context.Context, t *transfer.Transfer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for _, other := range st.transfers {
+		if other.KeyID == t.KeyID && other.State == transfer.StatePending {
+			return transfer.ErrPendingTransfer
+		}
+	}
+	cp := copyTransfer(t)
+	cp.ExpiresAt = cp.ExpiresAt.UTC()
+	cp.CreatedAt = cp.CreatedAt.UTC()
+	st.transfers[t.ID.String()] = cp
+	return nil
+}
+
+func (s *transferStore) Get(ctx context.Context, transferID id.TransferID) (*transfer.Transfer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	st := s.store()
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	t, ok := st.transfers[transferID.String()]
+	if !ok {
+		return nil, errNotFound("transfer")
+	}
+	return copyTransfer(t), nil
+}
+
+func (s *transferStore) GetPending(ctx context.Context, keyID id.KeyID) (*transfer.Transfer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	st := s.store()
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	for _, t := range st.transfers {
+		if t.KeyID == keyID && t.State == transfer.StatePending {
+			return copyTransfer(t), nil
+		}
+	}
+	return nil, errNotFound("transfer")
+}
+
+func (s *transferStore) List(ctx context.Context, filter *transfer.ListFilter) ([]*transfer.Transfer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	st := s.store()
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	result := make([]*transfer.Transfer, 0, len(st.transfers))
+	for _, t := range st.transfers {
+		if filter != nil {
+			if filter.TenantID != "" && t.SourceTenantID != filter.TenantID && t.TargetTenantID != filter.TenantID {
+				continue
+			}
+			if filter.AppID != "" && t.AppID != filter.AppID {
+				continue
+			}
+			if filter.KeyID != nil && t.KeyID != *filter.KeyID {
+				continue
+			}
+			if filter.State != "" && t.State != filter.State {
+				continue
+			}
+			if filter.ExpiresBefore != nil && !t.ExpiresAt.Before(*filter.ExpiresBefore) {
+				continue
+			}
+		}
+		result = append(result, copyTransfer(t))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.After(result[j].CreatedAt)
+		}
+		return result[i].ID.String() > result[j].ID.String()
+	})
+	offset, limit := 0, 0
+	if filter != nil {
+		offset, limit = filter.Offset, filter.Limit
+	}
+	return applyPagination(result, offset, limit), nil
+}
+
+func (s *transferStore) Resolve(ctx context.Context, transferID id.TransferID, state transfer.State, by string, at time.Time) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	t, ok := st.transfers[transferID.String()]
+	if !ok || t.State != transfer.StatePending {
+		return false, nil
+	}
+	t.State = state
+	t.ResolvedBy = by
+	t.ResolvedAt = utcTime(&at)
+	return true, nil
+}
+
+func (s *transferStore) Accept(ctx context.Context, t *transfer.Transfer, remap *transfer.Remap) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	stored, ok := st.transfers[t.ID.String()]
+	if !ok || stored.State != transfer.StatePending {
+		return false, nil
+	}
+	kid := t.KeyID.String()
+	k, ok := st.keys[kid]
+	if !ok {
+		return false, errNotFound("key")
+	}
+
+	stored.State = transfer.StateAccepted
+	stored.ResolvedBy = t.ResolvedBy
+	stored.ResolvedAt = utcTime(t.ResolvedAt)
+	stored.UnmappedScopes = slices.Clone(t.UnmappedScopes)
+	stored.UnmappedPolicy = t.UnmappedPolicy
+
+	k.TenantID = t.TargetTenantID
+	k.PolicyID = nil
+	if remap.PolicyID != nil {
+		pid := *remap.PolicyID
+		k.PolicyID = &pid
+	}
+	if stored.ResolvedAt != nil {
+		k.UpdatedAt = *stored.ResolvedAt
+	}
+	names := make(map[string]bool, len(remap.Scopes))
+	for _, name := range remap.Scopes {
+		names[name] = true
+	}
+	st.keyScopes[kid] = names
+	st.dropMembershipsLocked(kid)
+	k.PoolID = nil
+	if t.RetagUsage {
+		for _, rec := range st.usages {
+			if rec.KeyID == t.KeyID {
+				rec.TenantID = t.TargetTenantID
+			}
+		}
+	}
+	return true, nil
+}
+
+// dropTransfersLocked removes the key's transfers. The caller holds st.mu.
+func (st *Store) dropTransfersLocked(keyID id.KeyID) {
+	for tid, t := range st.transfers {
+		if t.KeyID == keyID {
+			delete(st.transfers, tid)
+		}
+	}
+}
+
+// ══════════════════════════════════════════════════
+// Tombstone Store
+// ══════════════════════════════════════════════════
+
+type tombstoneStore Store
+
+func (s *tombstoneStore) store() *Store { return (*Store)(s) }
+
+func (s *tombstoneStore) Create(ctx context.Context, t *tombstone.Tombstone) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if _, ok := st.tombstones[t.Hash]; !ok {
+		cp := *t
+		cp.CreatedAt = t.CreatedAt.UTC()
+		st.tombstones[t.Hash] = &cp
+	}
+	return nil
+}
+
+func (s *tombstoneStore) Find(ctx context.Context, hashes []string) ([]*tombstone.Tombstone, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	st := s.store()
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	var result []*tombstone.Tombstone
+	seen := make(map[string]bool, len(hashes))
+	for _, h := range hashes {
+		if t, ok := st.tombstones[h]; ok && !seen[h] {
+			seen[h] = true
+			cp := *t
+			result = append(result, &cp)
+		}
+	}
+	return result, nil
+}
+
+func (s *tombstoneStore) List(ctx context.Context, filter *tombstone.ListFilter) ([]*tombstone.Tombstone, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	st := s.store()
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	result := make([]*tombstone.Tombstone, 0, len(st.tombstones))
+	for _, t := range st.tombstones {
+		if matchTombstoneFilter(t, filter) {
+			cp := *t
+			result = append(result, &cp)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.After(result[j].CreatedAt)
+		}
+		return result[i].Hash > result[j].Hash
+	})
+	offset, limit := 0, 0
+	if filter != nil {
+		offset, limit = filter.Offset, filter.Limit
+	}
+	return applyPagination(result, offset, limit), nil
+}
+
+func (s *tombstoneStore) CountByReason(ctx context.Context, filter *tombstone.ListFilter) ([]*tombstone.ReasonCount, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	st := s.store()
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	counts := make(map[string]int64)
+	for _, t := range st.tombstones {
+		if matchTombstoneFilter(t, filter) {
+			counts[t.Reason]++
+		}
+	}
+	result := make([]*tombstone.ReasonCount, 0, len(counts))
+	for reason, n := range counts {
+		result = append(result, &tombstone.ReasonCount{Reason: reason, Count: n})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Reason < result[j].Reason
+	})
+	return result, nil
+}
+
+func (s *tombstoneStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	st := s.store()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var n int64
+	for h, t := range st.tombstones {
+		if t.CreatedAt.Before(before) {
+			delete(st.tombstones, h)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func matchTombstoneFilter(t *tombstone.Tombstone, f *tombstone.ListFilter) bool {
+	if f == nil {
+		return true
+	}
+	if f.TenantID != "" && t.TenantID != f.TenantID {
+		return false
+	}
+	if f.AppID != "" && t.AppID != f.AppID {
+		return false
+	}
+	return f.Reason == "" || t.Reason == f.Reason
+}
+
+// ══════════════════════════════════════════════════
+// Install ID
+// ══════════════════════════════════════════════════
+
+// InstallID stores candidate as the install ID unless one is stored, and
+// returns the stored ID.
+func (st *Store) InstallID(ctx context.Context, candidate string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.installID == "" {
+		st.installID = candidate
+	}
+	return st.installID, nil
+}
+
+// ══════════════════════════════════════════════════
+// Locks
+// ══════════════════════════════════════════════════
+
+// memoryLock is a held entry in Store.locks.
+type memoryLock struct {
+	holder  string
+	expires time.Time
+}
+
+// AcquireLock takes the lock called name for ttl. Locks live in the Store
+// value, so they exclude only engines sharing it within one process; run
+// replicas against a database store.
+func (st *Store) AcquireLock(ctx context.Context, name string, ttl time.Duration) (store.Lock, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	now := time.Now()
+	if l, ok := st.locks[name]; ok && now.Before(l.expires) {
+		return nil, store.ErrLockHeld
+	}
+	holder := rand.Text()
+	st.locks[name] = memoryLock{holder: holder, expires: now.Add(ttl)}
+	return &lockHandle{st: st, name: name, holder: holder}, nil
+}
+
+type lockHandle struct {
+	st     *Store
+	name   string
+	holder string
+}
+
+func (l *lockHandle) Name() string { return l.name }
+
+func (l *lockHandle) Renew(ctx context.Context, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	l.st.mu.Lock()
+	defer l.st.mu.Unlock()
+
+	now := time.Now()
+	cur, ok := l.st.locks[l.name]
+	if !ok || cur.holder != l.holder || !now.Before(cur.expires) {
+		return store.ErrLockLost
+	}
+	l.st.locks[l.name] = memoryLock{holder: l.holder, expires: now.Add(ttl)}
+	return nil
+}
+
+func (l *lockHandle) Release(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	l.st.mu.Lock()
+	defer l.st.mu.Unlock()
+
+	if cur, ok := l.st.locks[l.name]; ok && cur.holder == l.holder {
+		delete(l.st.locks, l.name)
+	}
+	return nil
+}
+
+// ══════════════════════════════════════════════════
+// Helpers
+// ══════════════════════════════════════════════════
+
+// keyByHashLocked returns the key with an active version stored as hash.
+// The caller holds st.mu.
+func (st *Store) keyByHashLocked(hash string) (*key.Key, bool) {
+	v, ok := st.hashIndex[hash]
+	if !ok || !v.Active {
+		return nil, false
+	}
+	k, ok := st.keys[v.KeyID.String()]
+	return k, ok
+}
+
+// addHashLocked stores v as an active version of its key, or reactivates
+// the key's existing version of the same hash. The caller holds st.mu.
+func (st *Store) addHashLocked(v *key.HashVersion) {
+	kid := v.KeyID.String()
+	if cur, ok := st.hashIndex[v.Hash]; ok && cur.KeyID.String() == kid {
+		cur.Active = true
+		return
+	}
+	if v.Scheme == "" {
+		v.Scheme = key.HashScheme(v.Hash)
+	}
+	v.Active = true
+	v.CreatedAt = v.CreatedAt.UTC()
+	st.hashIndex[v.Hash] = v
+	st.keyHashes[kid] = append(st.keyHashes[kid], v)
+}
+
+// dropHashesLocked removes every version of the key. The caller holds
+// st.mu.
+func (st *Store) dropHashesLocked(kid string) {
+	for _, v := range st.keyHashes[kid] {
+		if cur, ok := st.hashIndex[v.Hash]; ok && cur == v {
+			delete(st.hashIndex, v.Hash)
+		}
+	}
+	delete(st.keyHashes, kid)
+}
 
 type notFoundError struct{ entity string }
 
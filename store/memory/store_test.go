@@ -2,23 +2,36 @@ package memory_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/xraph/keysmith/capture"
 	"github.com/xraph/keysmith/id"
 	"github.com/xraph/keysmith/key"
 	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/revocation"
 	"github.com/xraph/keysmith/rotation"
 	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/store"
 	"github.com/xraph/keysmith/store/memory"
+	"github.com/xraph/keysmith/store/storetest"
+	"github.com/xraph/keysmith/tenant"
 	"github.com/xraph/keysmith/usage"
 )
 
 func ctx() context.Context { return context.Background() }
 
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(*testing.T) store.Store { return memory.New() })
+}
+
 // ── Key Store ───────────────────────────────────────────
 
 func TestKeyStore_CreateAndGet(t *testing.T) {
@@ -64,7 +77,27 @@ func TestKeyStore_GetByHash_NotFound(t *testing.T) {
 	assert.Error(t, err)
 }
 
-func TestKeyStore_GetByPrefix(t *testing.T) {
+func TestKeyStore_GetByHashes(t *testing.T) {
+	s := memory.New()
+	k1 := &key.Key{ID: id.NewKeyID(), KeyHash: "hash_1"}
+	k2 := &key.Key{ID: id.NewKeyID(), KeyHash: "hash_2"}
+	require.NoError(t, s.Keys().Create(ctx(), k1))
+	require.NoError(t, s.Keys().Create(ctx(), k2))
+
+	got, err := s.Keys().GetByHashes(ctx(), []string{"hash_2", "missing", "hash_1", "hash_2"})
+	require.NoError(t, err)
+	ids := make([]string, len(got))
+	for i, k := range got {
+		ids[i] = k.ID.String()
+	}
+	assert.ElementsMatch(t, []string{k1.ID.String(), k2.ID.String()}, ids)
+
+	got, err = s.Keys().GetByHashes(ctx(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestKeyStore_ListByPrefixHint(t *testing.T) {
 	s := memory.New()
 	k := &key.Key{
 		ID:     id.NewKeyID(),
@@ -73,9 +106,41 @@ func TestKeyStore_GetByPrefix(t *testing.T) {
 	}
 	require.NoError(t, s.Keys().Create(ctx(), k))
 
-	got, err := s.Keys().GetByPrefix(ctx(), "sk", "abcd")
+	got, err := s.Keys().ListByPrefixHint(ctx(), "sk", "abcd")
 	require.NoError(t, err)
-	assert.Equal(t, k.ID.String(), got.ID.String())
+	require.Len(t, got, 1)
+	assert.Equal(t, k.ID.String(), got[0].ID.String())
+
+	got, err = s.Keys().ListByPrefixHint(ctx(), "sk", "zzzz")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestKeyStore_ListByPrefixHint_Collisions(t *testing.T) {
+	s := memory.New()
+	base := time.Now()
+	var want []string
+	for i := range 3 {
+		k := &key.Key{
+			ID:        id.NewKeyID(),
+			KeyHash:   fmt.Sprintf("hash_%d", i),
+			Prefix:    "sk",
+			Hint:      "abcd",
+			CreatedAt: base.Add(time.Duration(2-i) * time.Minute),
+		}
+		require.NoError(t, s.Keys().Create(ctx(), k))
+		want = append([]string{k.ID.String()}, want...)
+	}
+	require.NoError(t, s.Keys().Create(ctx(), &key.Key{
+		ID: id.NewKeyID(), KeyHash: "other_prefix", Prefix: "pk", Hint: "abcd",
+	}))
+
+	got, err := s.Keys().ListByPrefixHint(ctx(), "sk", "abcd")
+	require.NoError(t, err)
+	require.Len(t, got, 3, "every colliding key is returned")
+	for i, k := range got {
+		assert.Equal(t, want[i], k.ID.String(), "oldest first")
+	}
 }
 
 func TestKeyStore_Update(t *testing.T) {
@@ -120,6 +185,32 @@ func TestKeyStore_UpdateState(t *testing.T) {
 	assert.Equal(t, key.StateSuspended, got.State)
 }
 
+func TestKeyStore_UpdateStateIf(t *testing.T) {
+	s := memory.New()
+	k := &key.Key{
+		ID:    id.NewKeyID(),
+		State: key.StateActive,
+	}
+	require.NoError(t, s.Keys().Create(ctx(), k))
+
+	ok, err := s.Keys().UpdateStateIf(ctx(), k.ID, key.StateActive, key.StateExpired)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// The transition only succeeds once.
+	ok, err = s.Keys().UpdateStateIf(ctx(), k.ID, key.StateActive, key.StateExpired)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	got, err := s.Keys().Get(ctx(), k.ID)
+	require.NoError(t, err)
+	assert.Equal(t, key.StateExpired, got.State)
+
+	ok, err = s.Keys().UpdateStateIf(ctx(), id.NewKeyID(), key.StateActive, key.StateExpired)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
 func TestKeyStore_Delete(t *testing.T) {
 	s := memory.New()
 	k := &key.Key{
@@ -154,6 +245,31 @@ func TestKeyStore_List(t *testing.T) {
 	assert.Len(t, keys, 5)
 }
 
+func TestKeyStore_ListByApp(t *testing.T) {
+	s := memory.New()
+	for i, app := range []string{"app_a", "app_a", "app_b"} {
+		k := &key.Key{
+			ID:       id.NewKeyID(),
+			KeyHash:  fmt.Sprintf("hash_%d", i),
+			TenantID: "t1",
+			AppID:    app,
+		}
+		require.NoError(t, s.Keys().Create(ctx(), k))
+	}
+
+	keys, err := s.Keys().List(ctx(), &key.ListFilter{TenantID: "t1", AppID: "app_a"})
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+
+	n, err := s.Keys().Count(ctx(), &key.ListFilter{TenantID: "t1", AppID: "app_b"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	keys, err = s.Keys().List(ctx(), &key.ListFilter{TenantID: "t1"})
+	require.NoError(t, err)
+	assert.Len(t, keys, 3)
+}
+
 func TestKeyStore_ListWithPagination(t *testing.T) {
 	s := memory.New()
 	for i := 0; i < 5; i++ {
@@ -205,6 +321,160 @@ func TestKeyStore_ListExpired(t *testing.T) {
 	assert.Len(t, expired, 1)
 }
 
+func TestKeyStore_ListRecentlyUsed(t *testing.T) {
+	s := memory.New()
+	now := time.Now()
+	ids := make([]id.KeyID, 3)
+	for i := range ids {
+		ids[i] = id.NewKeyID()
+		used := now.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, s.Keys().Create(ctx(), &key.Key{
+			ID:         ids[i],
+			State:      key.StateActive,
+			LastUsedAt: &used,
+		}))
+	}
+	require.NoError(t, s.Keys().Create(ctx(), &key.Key{ID: id.NewKeyID(), State: key.StateActive}))
+
+	recent, err := s.Keys().ListRecentlyUsed(ctx(), 2)
+	require.NoError(t, err)
+	require.Len(t, recent, 2)
+	assert.Equal(t, ids[2], recent[0].ID)
+	assert.Equal(t, ids[1], recent[1].ID)
+
+	all, err := s.Keys().ListRecentlyUsed(ctx(), 10)
+	require.NoError(t, err)
+	assert.Len(t, all, 3, "never-used keys are excluded")
+}
+
+func TestKeyStore_Iterate(t *testing.T) {
+	s := memory.New()
+	var want []string
+	for i := 0; i < 5; i++ {
+		k := &key.Key{ID: id.NewKeyID(), TenantID: "t1", State: key.StateActive}
+		require.NoError(t, s.Keys().Create(ctx(), k))
+		want = append(want, k.ID.String())
+	}
+	require.NoError(t, s.Keys().Create(ctx(), &key.Key{ID: id.NewKeyID(), TenantID: "t2"}))
+	sort.Strings(want)
+
+	var got []string
+	err := s.Keys().Iterate(ctx(), &key.ListFilter{TenantID: "t1"}, func(k *key.Key) error {
+		got = append(got, k.ID.String())
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	got = nil
+	err = s.Keys().Iterate(ctx(), &key.ListFilter{TenantID: "t1", Offset: 1, Limit: 2}, func(k *key.Key) error {
+		got = append(got, k.ID.String())
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, want[1:3], got)
+}
+
+func TestKeyStore_Iterate_StopsOnError(t *testing.T) {
+	s := memory.New()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, s.Keys().Create(ctx(), &key.Key{ID: id.NewKeyID(), TenantID: "t1"}))
+	}
+
+	boom := errors.New("boom")
+	visited := 0
+	err := s.Keys().Iterate(ctx(), nil, func(*key.Key) error {
+		visited++
+		if visited == 2 {
+			return boom
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, boom)
+	assert.Equal(t, 2, visited)
+}
+
+func TestKeyStore_Iterate_CallbackWrites(t *testing.T) {
+	s := memory.New()
+	past := time.Now().Add(-time.Hour)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s.Keys().Create(ctx(), &key.Key{
+			ID:        id.NewKeyID(),
+			State:     key.StateActive,
+			ExpiresAt: &past,
+		}))
+	}
+
+	now := time.Now()
+	err := s.Keys().Iterate(ctx(), &key.ListFilter{State: key.StateActive, ExpiresBefore: &now}, func(k *key.Key) error {
+		return s.Keys().UpdateState(ctx(), k.ID, key.StateExpired)
+	})
+	require.NoError(t, err)
+
+	count, err := s.Keys().Count(ctx(), &key.ListFilter{State: key.StateExpired})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+}
+
+// BenchmarkKeyStore_ListVsIterate compares the peak live heap of materializing
+// a large tenant with List against walking it with Iterate.
+func BenchmarkKeyStore_ListVsIterate(b *testing.B) {
+	const n = 50_000
+	s := memory.New()
+	for i := 0; i < n; i++ {
+		require.NoError(b, s.Keys().Create(ctx(), &key.Key{
+			ID:       id.NewKeyID(),
+			TenantID: "t1",
+			Name:     "synthetic",
+			Metadata: map[string]any{"i": i},
+		}))
+	}
+	filter := &key.ListFilter{TenantID: "t1"}
+
+	// liveHeap forces a collection so only reachable memory is counted.
+	liveHeap := func() uint64 {
+		runtime.GC()
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		return ms.HeapAlloc
+	}
+
+	b.Run("List", func(b *testing.B) {
+		b.ReportAllocs()
+		var peak uint64
+		for i := 0; i < b.N; i++ {
+			base := liveHeap()
+			keys, err := s.Keys().List(ctx(), filter)
+			require.NoError(b, err)
+			if h := liveHeap(); h > base && h-base > peak {
+				peak = h - base
+			}
+			runtime.KeepAlive(keys)
+		}
+		b.ReportMetric(float64(peak)/1024, "peak-KiB")
+	})
+
+	b.Run("Iterate", func(b *testing.B) {
+		b.ReportAllocs()
+		var peak uint64
+		for i := 0; i < b.N; i++ {
+			base := liveHeap()
+			visited := 0
+			err := s.Keys().Iterate(ctx(), filter, func(*key.Key) error {
+				visited++
+				if visited%10_000 == 0 {
+					if h := liveHeap(); h > base && h-base > peak {
+						peak = h - base
+					}
+				}
+				return nil
+			})
+			require.NoError(b, err)
+		}
+		b.ReportMetric(float64(peak)/1024, "peak-KiB")
+	})
+}
+
 func TestKeyStore_ListByPolicy(t *testing.T) {
 	s := memory.New()
 	polID := id.NewPolicyID()
@@ -366,6 +636,74 @@ func TestUsageStore_Purge(t *testing.T) {
 	assert.Equal(t, int64(1), count)
 }
 
+func TestUsageStore_UpdateUsageIdentifiers(t *testing.T) {
+	s := memory.New()
+	now := time.Now()
+	for i, r := range []struct{ tenant, ip, ua string }{
+		{"t1", "192.0.2.1", "curl/8"},
+		{"t1", "192.0.2.1", "sdk/1"},
+		{"t1", "192.0.2.2", "curl/8"},
+		{"t2", "192.0.2.1", "curl/8"},
+	} {
+		require.NoError(t, s.Usages().Record(ctx(), &usage.Record{
+			ID: id.NewUsageID(), TenantID: r.tenant, IPAddress: r.ip, UserAgent: r.ua,
+			CreatedAt: now.Add(time.Duration(i) * time.Second),
+		}))
+	}
+
+	m := &usage.IdentifierMatcher{IPAddress: "192.0.2.1"}
+	n, err := s.Usages().UpdateUsageIdentifiers(ctx(), "t1", m, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	n, err = s.Usages().UpdateUsageIdentifiers(ctx(), "t1", m, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	n, err = s.Usages().UpdateUsageIdentifiers(ctx(), "t1", m, 10)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	recs, err := s.Usages().Query(ctx(), &usage.QueryFilter{TenantID: "t1"})
+	require.NoError(t, err)
+	require.Len(t, recs, 3)
+	for _, r := range recs {
+		assert.NotEqual(t, "192.0.2.1", r.IPAddress)
+		assert.NotEqual(t, usage.Redacted, r.UserAgent)
+	}
+
+	other, err := s.Usages().Query(ctx(), &usage.QueryFilter{TenantID: "t2"})
+	require.NoError(t, err)
+	require.Len(t, other, 1)
+	assert.Equal(t, "192.0.2.1", other[0].IPAddress, "other tenants are untouched")
+}
+
+func TestUsageStore_UpsertEndpointActivity(t *testing.T) {
+	s := memory.New()
+	kid := id.NewKeyID()
+	t0 := time.Now().Add(-time.Hour)
+	t1 := time.Now()
+
+	require.NoError(t, s.Usages().UpsertEndpointActivity(ctx(), []*usage.EndpointActivity{
+		{KeyID: kid, Endpoint: "/webhooks", Method: "POST", LastSeenAt: t1, Count: 3},
+		{KeyID: kid, Endpoint: "/users/:id", Method: "GET", LastSeenAt: t0, Count: 1},
+	}))
+	// Counts add up; an older timestamp does not move last_seen_at backwards.
+	require.NoError(t, s.Usages().UpsertEndpointActivity(ctx(), []*usage.EndpointActivity{
+		{KeyID: kid, Endpoint: "/webhooks", Method: "POST", LastSeenAt: t0, Count: 2},
+	}))
+
+	acts, err := s.Usages().ListEndpointActivity(ctx(), kid)
+	require.NoError(t, err)
+	require.Len(t, acts, 2)
+	assert.Equal(t, "/webhooks", acts[0].Endpoint)
+	assert.Equal(t, int64(5), acts[0].Count)
+	assert.True(t, acts[0].LastSeenAt.Equal(t1))
+	assert.Equal(t, "/users/:id", acts[1].Endpoint)
+
+	other, err := s.Usages().ListEndpointActivity(ctx(), id.NewKeyID())
+	require.NoError(t, err)
+	assert.Empty(t, other)
+}
+
 func TestUsageStore_DailyCount(t *testing.T) {
 	s := memory.New()
 	kid := id.NewKeyID()
@@ -378,7 +716,7 @@ func TestUsageStore_DailyCount(t *testing.T) {
 		ID: id.NewUsageID(), KeyID: kid, CreatedAt: now.Add(-48 * time.Hour),
 	}))
 
-	count, err := s.Usages().DailyCount(ctx(), kid, now)
+	count, err := s.Usages().DailyCount(ctx(), kid, usage.DayWindow(now, time.UTC))
 	require.NoError(t, err)
 	assert.Equal(t, int64(1), count)
 }
@@ -394,11 +732,47 @@ func TestUsageStore_MonthlyCount(t *testing.T) {
 		}))
 	}
 
-	count, err := s.Usages().MonthlyCount(ctx(), kid, now)
+	count, err := s.Usages().MonthlyCount(ctx(), kid, usage.MonthWindow(now, time.UTC))
 	require.NoError(t, err)
 	assert.Equal(t, int64(5), count)
 }
 
+func TestUsageStore_DailyCountAcrossDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// 2024-03-10 is 23 hours long in New York and 2024-11-03 is 25.
+	for _, dst := range []time.Time{
+		time.Date(2024, 3, 10, 12, 0, 0, 0, ny),
+		time.Date(2024, 11, 3, 12, 0, 0, 0, ny),
+	} {
+		s := memory.New()
+		kid := id.NewKeyID()
+		days := []usage.Window{
+			usage.DayWindow(dst.AddDate(0, 0, -1), ny),
+			usage.DayWindow(dst, ny),
+			usage.DayWindow(dst.AddDate(0, 0, 1), ny),
+		}
+		var total int64
+		for at := days[0].Start; at.Before(days[2].End); at = at.Add(30 * time.Minute) {
+			require.NoError(t, s.Usages().Record(ctx(), &usage.Record{ID: id.NewUsageID(), KeyID: kid, CreatedAt: at}))
+			total++
+		}
+
+		var sum int64
+		for i, w := range days {
+			if i > 0 {
+				assert.Equal(t, days[i-1].End, w.Start, "days are contiguous")
+			}
+			n, err := s.Usages().DailyCount(ctx(), kid, w)
+			require.NoError(t, err)
+			sum += n
+		}
+		assert.Equal(t, total, sum, "no record is counted twice or missed")
+		assert.NotEqual(t, 24*time.Hour, days[1].End.Sub(days[1].Start))
+	}
+}
+
 // ── Rotation Store ──────────────────────────────────────
 
 func TestRotationStore_CreateAndGet(t *testing.T) {
@@ -522,7 +896,10 @@ func TestScopeStore_GetByName(t *testing.T) {
 
 func TestScopeStore_AssignAndRemove(t *testing.T) {
 	s := memory.New()
-	kid := id.NewKeyID()
+	k := &key.Key{ID: id.NewKeyID(), TenantID: "t1", KeyHash: "hash-assign", State: key.StateActive}
+	require.NoError(t, s.Keys().Create(ctx(), k))
+	kid := k.ID
+	require.Error(t, s.Scopes().AssignToKey(ctx(), kid, []string{"read:users"}), "scopes must exist")
 
 	// Create scopes.
 	for _, name := range []string{"read:users", "write:users", "admin"} {
@@ -533,10 +910,14 @@ func TestScopeStore_AssignAndRemove(t *testing.T) {
 		}))
 	}
 
-	// Assign.
+	// Assign; an unknown name assigns nothing.
+	require.Error(t, s.Scopes().AssignToKey(ctx(), kid, []string{"read:users", "missing"}))
+	listed, err := s.Scopes().ListByKey(ctx(), kid)
+	require.NoError(t, err)
+	assert.Empty(t, listed)
 	require.NoError(t, s.Scopes().AssignToKey(ctx(), kid, []string{"read:users", "write:users"}))
 
-	listed, err := s.Scopes().ListByKey(ctx(), kid)
+	listed, err = s.Scopes().ListByKey(ctx(), kid)
 	require.NoError(t, err)
 	assert.Len(t, listed, 2)
 
@@ -564,6 +945,99 @@ func TestScopeStore_List(t *testing.T) {
 	assert.Len(t, scopes, 2)
 }
 
+// ── Tenant Store ────────────────────────────────────────
+
+func TestTenantStore_UpsertGetDelete(t *testing.T) {
+	s := memory.New()
+
+	_, err := s.Tenants().GetSettings(ctx(), "t1")
+	assert.Error(t, err)
+
+	ts := &tenant.Settings{TenantID: "t1", DefaultScopes: []string{"api:access"}}
+	require.NoError(t, s.Tenants().UpsertSettings(ctx(), ts))
+
+	ts.DefaultScopes = append(ts.DefaultScopes, "webhooks:receive")
+	require.NoError(t, s.Tenants().UpsertSettings(ctx(), ts))
+
+	got, err := s.Tenants().GetSettings(ctx(), "t1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api:access", "webhooks:receive"}, got.DefaultScopes)
+
+	require.NoError(t, s.Tenants().DeleteSettings(ctx(), "t1"))
+	assert.Error(t, s.Tenants().DeleteSettings(ctx(), "t1"))
+}
+
+// ── Revocation Store ────────────────────────────────────
+
+func TestRevocationStore_ListAfter(t *testing.T) {
+	s := memory.New()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	k1, k2, k3 := id.NewKeyID(), id.NewKeyID(), id.NewKeyID()
+
+	// Appended out of order; k1 and k2 share a timestamp.
+	for _, e := range []*revocation.Entry{
+		{KeyID: k3, TenantID: "t1", Revoked: true, At: base.Add(time.Second)},
+		{KeyID: k1, TenantID: "t1", Revoked: true, At: base},
+		{KeyID: k2, TenantID: "t2", Revoked: true, At: base},
+		{KeyID: k1, TenantID: "t1", Revoked: true, At: base}, // duplicate
+	} {
+		require.NoError(t, s.Revocations().Append(ctx(), e))
+	}
+
+	all, err := s.Revocations().ListAfter(ctx(), "", revocation.Cursor{}, 0)
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	assert.Equal(t, k3, all[2].KeyID)
+
+	after, err := s.Revocations().ListAfter(ctx(), "", revocation.CursorOf(all[0]), 0)
+	require.NoError(t, err)
+	require.Len(t, after, 2)
+	assert.Equal(t, all[1].KeyID, after[0].KeyID, "the entry sharing the cursor time is not skipped")
+
+	t1, err := s.Revocations().ListAfter(ctx(), "t1", revocation.Cursor{}, 1)
+	require.NoError(t, err)
+	require.Len(t, t1, 1)
+	assert.Equal(t, k1, t1[0].KeyID)
+
+	n, err := s.Revocations().Purge(ctx(), base.Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+}
+
+// ── Capture Store ───────────────────────────────────────
+
+func TestCaptureStore_ListAndPurge(t *testing.T) {
+	s := memory.New()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	k1, k2 := id.NewKeyID(), id.NewKeyID()
+
+	for i, kid := range []id.KeyID{k1, k2, k1, k1} {
+		require.NoError(t, s.Captures().Record(ctx(), &capture.Capture{
+			ID:         id.NewCaptureID(),
+			KeyID:      kid,
+			Path:       fmt.Sprintf("/%d", i),
+			CapturedAt: base.Add(time.Duration(i) * time.Minute),
+		}))
+	}
+
+	got, err := s.Captures().List(ctx(), k1, 2)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, "/3", got[0].Path, "newest first")
+	assert.Equal(t, "/2", got[1].Path)
+
+	n, err := s.Captures().Purge(ctx(), base.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	got, err = s.Captures().List(ctx(), k1, 0)
+	require.NoError(t, err)
+	assert.Len(t, got, 2)
+	got, err = s.Captures().List(ctx(), k2, 0)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
 // ── Lifecycle ───────────────────────────────────────────
 
 func TestStore_MigratePingClose(t *testing.T) {
@@ -572,3 +1046,24 @@ func TestStore_MigratePingClose(t *testing.T) {
 	require.NoError(t, s.Ping(ctx()))
 	require.NoError(t, s.Close())
 }
+
+func TestStore_Maintain(t *testing.T) {
+	s := memory.New()
+	require.NoError(t, s.Keys().Create(ctx(), &key.Key{ID: id.NewKeyID(), KeyHash: "h1"}))
+	require.NoError(t, s.Keys().Create(ctx(), &key.Key{ID: id.NewKeyID(), KeyHash: "h2"}))
+
+	report, err := s.Maintain(ctx(), store.MaintenanceOptions{Full: true})
+	require.NoError(t, err)
+	assert.Equal(t, "memory", report.Backend)
+	assert.Empty(t, report.Actions)
+
+	names := make([]string, 0, len(report.Tables))
+	rows := make(map[string]int64)
+	for _, tbl := range report.Tables {
+		names = append(names, tbl.Name)
+		rows[tbl.Name] = tbl.Rows
+	}
+	assert.True(t, sort.StringsAreSorted(names))
+	assert.Equal(t, int64(2), rows["keysmith_keys"])
+	assert.Contains(t, rows, "keysmith_usage")
+}
@@ -0,0 +1,72 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/xraph/grove/drivers/mongodriver"
+
+	"github.com/xraph/keysmith/capture"
+	"github.com/xraph/keysmith/id"
+)
+
+type captureStore struct {
+	mdb *mongodriver.MongoDB
+}
+
+func (s *captureStore) Record(ctx context.Context, c *capture.Capture) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, err := s.mdb.NewInsert(captureToModel(c)).Exec(ctx); err != nil {
+		return fmt.Errorf("keysmith/mongo: record capture: %w", err)
+	}
+	return nil
+}
+
+func (s *captureStore) List(ctx context.Context, keyID id.KeyID, limit int) ([]*capture.Capture, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var models []captureModel
+	q := s.mdb.NewFind(&models).
+		Filter(bson.M{"key_id": keyID.String()}).
+		Sort(bson.D{{Key: "captured_at", Value: -1}})
+	if limit > 0 {
+		q = q.Limit(int64(limit))
+	}
+
+	if err := q.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("keysmith/mongo: list captures: %w", err)
+	}
+
+	result := make([]*capture.Capture, 0, len(models))
+	for i := range models {
+		c, err := captureFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/mongo: convert capture: %w", err)
+		}
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+func (s *captureStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	res, err := s.mdb.NewDelete((*captureModel)(nil)).
+		Many().
+		Filter(bson.M{"captured_at": bson.M{"$lt": before.UTC()}}).
+		Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("keysmith/mongo: purge captures: %w", err)
+	}
+	return res.DeletedCount(), nil
+}
@@ -0,0 +1,37 @@
+//go:build integration
+
+package mongo_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/grove"
+	"github.com/xraph/grove/drivers/mongodriver"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/store/mongo"
+	"github.com/xraph/keysmith/store/storetest"
+)
+
+// TestEngineRotationGrace runs against the database in KEYSMITH_TEST_MONGO_URI,
+// which must name a database.
+func TestEngineRotationGrace(t *testing.T) {
+	uri := os.Getenv("KEYSMITH_TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("KEYSMITH_TEST_MONGO_URI is not set")
+	}
+	ctx := context.Background()
+	d := mongodriver.New()
+	require.NoError(t, d.Open(ctx, uri))
+	db, err := grove.Open(d)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	s := mongo.New(db)
+	require.NoError(t, s.Migrate(ctx))
+	storetest.CheckEngineRotationGrace(t, s, "engine-grace-"+id.NewKeyID().String())
+}
@@ -0,0 +1,234 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/xraph/grove/drivers/mongodriver"
+
+	"github.com/xraph/keysmith/group"
+	"github.com/xraph/keysmith/id"
+)
+
+type groupStore struct {
+	mdb *mongodriver.MongoDB
+}
+
+func (s *groupStore) Create(ctx context.Context, g *group.Group) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, err := s.mdb.NewInsert(groupToModel(g)).Exec(ctx); err != nil {
+		return fmt.Errorf("keysmith/mongo: create group: %w", err)
+	}
+	return nil
+}
+
+func (s *groupStore) Get(ctx context.Context, groupID id.GroupID) (*group.Group, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var m groupModel
+	err := s.mdb.NewFind(&m).
+		Filter(bson.M{"_id": groupID.String()}).
+		Scan(ctx)
+	if err != nil {
+		if isNoDocuments(err) {
+			return nil, errNotFound("group")
+		}
+		return nil, fmt.Errorf("keysmith/mongo: get group: %w", err)
+	}
+	return groupFromModel(&m)
+}
+
+func (s *groupStore) Update(ctx context.Context, g *group.Group) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m := groupToModel(g)
+	res, err := s.mdb.NewUpdate(m).
+		Filter(bson.M{"_id": m.ID}).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/mongo: update group: %w", err)
+	}
+	if res.MatchedCount() == 0 {
+		return errNotFound("group")
+	}
+	return nil
+}
+
+func (s *groupStore) Delete(ctx context.Context, groupID id.GroupID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	res, err := s.mdb.NewDelete((*groupModel)(nil)).
+		Filter(bson.M{"_id": groupID.String()}).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/mongo: delete group: %w", err)
+	}
+	if res.DeletedCount() == 0 {
+		return errNotFound("group")
+	}
+	_, err = s.mdb.NewDelete((*groupMemberModel)(nil)).
+		Many().
+		Filter(bson.M{"group_id": groupID.String()}).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/mongo: delete group members: %w", err)
+	}
+	return nil
+}
+
+func (s *groupStore) List(ctx context.Context, filter *group.ListFilter) ([]*group.Group, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var models []groupModel
+
+	f := bson.M{}
+	if filter != nil {
+		if filter.TenantID != "" {
+			f["tenant_id"] = filter.TenantID
+		}
+		if filter.AppID != "" {
+			f["app_id"] = filter.AppID
+		}
+	}
+
+	q := s.mdb.NewFind(&models).
+		Filter(f).
+		Sort(bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}})
+
+	if filter != nil {
+		if filter.Limit > 0 {
+			q = q.Limit(int64(filter.Limit))
+		}
+		if filter.Offset > 0 {
+			q = q.Skip(int64(filter.Offset))
+		}
+	}
+
+	if err := q.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("keysmith/mongo: list groups: %w", err)
+	}
+
+	result := make([]*group.Group, 0, len(models))
+	for i := range models {
+		g, err := groupFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/mongo: convert group: %w", err)
+		}
+		result = append(result, g)
+	}
+	return result, nil
+}
+
+func (s *groupStore) AddKeys(ctx context.Context, groupID id.GroupID, keyIDs []id.KeyID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := s.exists(ctx, groupID); err != nil {
+		return err
+	}
+
+	gid := groupID.String()
+	for _, keyID := range keyIDs {
+		m := &groupMemberModel{GroupID: gid, KeyID: keyID.String()}
+		// Use upsert to handle duplicates gracefully.
+		_, err := s.mdb.NewUpdate(m).
+			Filter(bson.M{"group_id": gid, "key_id": m.KeyID}).
+			Upsert().
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("keysmith/mongo: add group member: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *groupStore) RemoveKeys(ctx context.Context, groupID id.GroupID, keyIDs []id.KeyID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := s.exists(ctx, groupID); err != nil {
+		return err
+	}
+	if len(keyIDs) == 0 {
+		return nil
+	}
+
+	kids := make([]string, len(keyIDs))
+	for i, kid := range keyIDs {
+		kids[i] = kid.String()
+	}
+	_, err := s.mdb.NewDelete((*groupMemberModel)(nil)).
+		Many().
+		Filter(bson.M{"group_id": groupID.String(), "key_id": bson.M{"$in": kids}}).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/mongo: remove group members: %w", err)
+	}
+	return nil
+}
+
+// exists returns errNotFound when there is no group with groupID.
+func (s *groupStore) exists(ctx context.Context, groupID id.GroupID) error {
+	n, err := s.mdb.NewFind((*groupModel)(nil)).
+		Filter(bson.M{"_id": groupID.String()}).
+		Count(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/mongo: get group: %w", err)
+	}
+	if n == 0 {
+		return errNotFound("group")
+	}
+	return nil
+}
+
+func (s *groupStore) ListByKeys(ctx context.Context, keyIDs []id.KeyID) (map[id.KeyID][]id.GroupID, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make(map[id.KeyID][]id.GroupID)
+	if len(keyIDs) == 0 {
+		return result, nil
+	}
+
+	kids := make([]string, len(keyIDs))
+	for i, kid := range keyIDs {
+		kids[i] = kid.String()
+	}
+	var models []groupMemberModel
+	err := s.mdb.NewFind(&models).
+		Filter(bson.M{"key_id": bson.M{"$in": kids}}).
+		Sort(bson.D{{Key: "group_id", Value: 1}}).
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/mongo: list groups by keys: %w", err)
+	}
+
+	for i := range models {
+		kid, err := id.ParseKeyID(models[i].KeyID)
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/mongo: convert group member: %w", err)
+		}
+		gid, err := id.ParseGroupID(models[i].GroupID)
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/mongo: convert group member: %w", err)
+		}
+		result[kid] = append(result[kid], gid)
+	}
+	return result, nil
+}
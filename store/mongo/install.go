@@ -0,0 +1,41 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	mongod "go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/xraph/keysmith/store"
+)
+
+var _ store.InstallIDStore = (*Store)(nil)
+
+// InstallID stores candidate as the install ID unless one is stored, and
+// returns the stored ID. It is kept in the install_id document of
+// keysmith_meta, written with a $setOnInsert upsert; an upsert that loses a
+// race to insert it collides on _id and reads the winner's instead.
+func (s *Store) InstallID(ctx context.Context, candidate string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	var doc struct {
+		Value string `bson:"value"`
+	}
+	col := s.mdb.Collection(colMeta)
+	err := col.FindOneAndUpdate(ctx,
+		bson.M{"_id": "install_id"},
+		bson.M{"$setOnInsert": bson.M{"value": candidate}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&doc)
+	if mongod.IsDuplicateKeyError(err) {
+		err = col.FindOne(ctx, bson.M{"_id": "install_id"}).Decode(&doc)
+	}
+	if err != nil {
+		return "", fmt.Errorf("keysmith/mongo: install id: %w", err)
+	}
+	return doc.Value, nil
+}
@@ -3,9 +3,12 @@ package mongo
 import (
 	"context"
 	"fmt"
+	"maps"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
+	mongod "go.mongodb.org/mongo-driver/v2/mongo"
 
 	"github.com/xraph/grove/drivers/mongodriver"
 
@@ -13,20 +16,49 @@ import (
 	"github.com/xraph/keysmith/key"
 )
 
+// iterateBatchSize is the number of documents fetched per round trip by Iterate.
+const iterateBatchSize = 500
+
 type keyStore struct {
 	mdb *mongodriver.MongoDB
 }
 
 func (s *keyStore) Create(ctx context.Context, k *key.Key) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	m := keyToModel(k)
 	_, err := s.mdb.NewInsert(m).Exec(ctx)
 	if err != nil {
+		if k.ExternalRef != "" && mongod.IsDuplicateKeyError(err) && s.externalRefTaken(ctx, k) {
+			return key.ErrExternalRefInUse
+		}
 		return fmt.Errorf("keysmith/mongo: create key: %w", err)
 	}
+	if err := s.upsertKeyHash(ctx, keyHashToModel(&key.HashVersion{KeyID: k.ID, Hash: k.KeyHash, CreatedAt: m.CreatedAt})); err != nil {
+		// Without a version the key could never be found, so undo it.
+		_, _ = s.mdb.NewDelete((*keyModel)(nil)).Filter(bson.M{"_id": m.ID}).Exec(ctx)
+		return err
+	}
 	return nil
 }
 
+// externalRefTaken reports whether a key other than k has k's external
+// reference in its tenant, telling a conflict on the unique external_ref
+// index from one on another index.
+func (s *keyStore) externalRefTaken(ctx context.Context, k *key.Key) bool {
+	n, err := s.mdb.NewFind((*keyModel)(nil)).
+		Filter(bson.M{"tenant_id": k.TenantID, "external_ref": k.ExternalRef, "_id": bson.M{"$ne": k.ID.String()}}).
+		Count(ctx)
+	return err == nil && n > 0
+}
+
 func (s *keyStore) Get(ctx context.Context, keyID id.KeyID) (*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var m keyModel
 	err := s.mdb.NewFind(&m).
 		Filter(bson.M{"_id": keyID.String()}).
@@ -41,9 +73,20 @@ func (s *keyStore) Get(ctx context.Context, keyID id.KeyID) (*key.Key, error) {
 }
 
 func (s *keyStore) GetByHash(ctx context.Context, hash string) (*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ids, err := s.activeHashKeyIDs(ctx, key.LookupHashes(hash))
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/mongo: get key by hash: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, errNotFound("key")
+	}
 	var m keyModel
-	err := s.mdb.NewFind(&m).
-		Filter(bson.M{"key_hash": hash}).
+	err = s.mdb.NewFind(&m).
+		Filter(bson.M{"_id": bson.M{"$in": ids}}).
 		Scan(ctx)
 	if err != nil {
 		if isNoDocuments(err) {
@@ -54,21 +97,189 @@ func (s *keyStore) GetByHash(ctx context.Context, hash string) (*key.Key, error)
 	return keyFromModel(&m)
 }
 
-func (s *keyStore) GetByPrefix(ctx context.Context, prefix, hint string) (*key.Key, error) {
-	var m keyModel
-	err := s.mdb.NewFind(&m).
-		Filter(bson.M{"prefix": prefix, "hint": hint}).
+func (s *keyStore) GetByHashes(ctx context.Context, hashes []string) ([]*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(hashes) == 0 {
+		return []*key.Key{}, nil
+	}
+	ids, err := s.activeHashKeyIDs(ctx, key.LookupHashes(hashes...))
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/mongo: get keys by hash: %w", err)
+	}
+	if len(ids) == 0 {
+		return []*key.Key{}, nil
+	}
+	var models []keyModel
+	err = s.mdb.NewFind(&models).
+		Filter(bson.M{"_id": bson.M{"$in": ids}}).
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/mongo: get keys by hash: %w", err)
+	}
+
+	result := make([]*key.Key, 0, len(models))
+	for i := range models {
+		k, err := keyFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/mongo: convert key: %w", err)
+		}
+		result = append(result, k)
+	}
+	return result, nil
+}
+
+// activeHashKeyIDs returns the IDs of the keys with an active version among
+// hashes.
+func (s *keyStore) activeHashKeyIDs(ctx context.Context, hashes []string) ([]string, error) {
+	var models []keyHashModel
+	err := s.mdb.NewFind(&models).
+		Filter(bson.M{"_id": bson.M{"$in": hashes}, "active": true}).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(models))
+	for i := range models {
+		ids = append(ids, models[i].KeyID)
+	}
+	return ids, nil
+}
+
+func (s *keyStore) AddHash(ctx context.Context, h *key.HashVersion) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, err := s.Get(ctx, h.KeyID); err != nil {
+		return err
+	}
+	m := keyHashToModel(h)
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = now()
+	}
+	return s.upsertKeyHash(ctx, m)
+}
+
+func (s *keyStore) ListHashes(ctx context.Context, keyID id.KeyID) ([]*key.HashVersion, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var models []keyHashModel
+	err := s.mdb.NewFind(&models).
+		Filter(bson.M{"key_id": keyID.String()}).
+		Sort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/mongo: list key hashes: %w", err)
+	}
+
+	result := make([]*key.HashVersion, 0, len(models))
+	for i := range models {
+		h, err := keyHashFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/mongo: convert key hash: %w", err)
+		}
+		result = append(result, h)
+	}
+	return result, nil
+}
+
+func (s *keyStore) DeactivateHash(ctx context.Context, keyID id.KeyID, hash string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var k keyModel
+	err := s.mdb.NewFind(&k).
+		Filter(bson.M{"_id": keyID.String()}).
 		Scan(ctx)
 	if err != nil {
 		if isNoDocuments(err) {
-			return nil, errNotFound("key")
+			return nil
 		}
-		return nil, fmt.Errorf("keysmith/mongo: get key by prefix: %w", err)
+		return fmt.Errorf("keysmith/mongo: get key: %w", err)
 	}
-	return keyFromModel(&m)
+	// The current hash stays active.
+	if k.KeyHash == hash {
+		return nil
+	}
+	_, err = s.mdb.NewUpdate((*keyHashModel)(nil)).
+		Filter(bson.M{"_id": hash, "key_id": keyID.String()}).
+		Set("active", false).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/mongo: deactivate key hash: %w", err)
+	}
+	return nil
+}
+
+// upsertKeyHash stores m as an active version, reactivating it if the key
+// already has the hash. A hash of another key fails with key.ErrHashInUse.
+func (s *keyStore) upsertKeyHash(ctx context.Context, m *keyHashModel) error {
+	var existing keyHashModel
+	err := s.mdb.NewFind(&existing).
+		Filter(bson.M{"_id": m.Hash}).
+		Scan(ctx)
+	switch {
+	case isNoDocuments(err):
+		m.Active = true
+		if _, err := s.mdb.NewInsert(m).Exec(ctx); err != nil {
+			return fmt.Errorf("keysmith/mongo: store key hash: %w", err)
+		}
+		return nil
+	case err != nil:
+		return fmt.Errorf("keysmith/mongo: get key hash: %w", err)
+	case existing.KeyID != m.KeyID:
+		return key.ErrHashInUse
+	}
+	_, err = s.mdb.NewUpdate((*keyHashModel)(nil)).
+		Filter(bson.M{"_id": m.Hash}).
+		Set("active", true).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/mongo: store key hash: %w", err)
+	}
+	return nil
+}
+
+func (s *keyStore) ListByPrefixHint(ctx context.Context, prefix, hint string) ([]*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var models []keyModel
+	err := s.mdb.NewFind(&models).
+		Filter(bson.M{"prefix": prefix, "hint": hint}).
+		Sort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/mongo: list keys by prefix and hint: %w", err)
+	}
+
+	result := make([]*key.Key, 0, len(models))
+	for i := range models {
+		k, err := keyFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/mongo: convert key: %w", err)
+		}
+		result = append(result, k)
+	}
+	return result, nil
 }
 
 func (s *keyStore) Update(ctx context.Context, k *key.Key) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	prev, err := s.Get(ctx, k.ID)
+	if err != nil {
+		return err
+	}
 	m := keyToModel(k)
 	res, err := s.mdb.NewUpdate(m).
 		Filter(bson.M{"_id": m.ID}).
@@ -79,10 +290,26 @@ func (s *keyStore) Update(ctx context.Context, k *key.Key) error {
 	if res.MatchedCount() == 0 {
 		return errNotFound("key")
 	}
+	// A new hash replaces the secret, so the old one's versions retire.
+	if prev.KeyHash != k.KeyHash {
+		_, err := s.mdb.NewUpdate((*keyHashModel)(nil)).
+			Many().
+			Filter(bson.M{"key_id": m.ID}).
+			Set("active", false).
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("keysmith/mongo: retire key hashes: %w", err)
+		}
+		return s.upsertKeyHash(ctx, keyHashToModel(&key.HashVersion{KeyID: k.ID, Hash: k.KeyHash, CreatedAt: now()}))
+	}
 	return nil
 }
 
 func (s *keyStore) UpdateState(ctx context.Context, keyID id.KeyID, state key.State) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	res, err := s.mdb.NewUpdate((*keyModel)(nil)).
 		Filter(bson.M{"_id": keyID.String()}).
 		Set("state", string(state)).
@@ -97,7 +324,27 @@ func (s *keyStore) UpdateState(ctx context.Context, keyID id.KeyID, state key.St
 	return nil
 }
 
+func (s *keyStore) UpdateStateIf(ctx context.Context, keyID id.KeyID, from, to key.State) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	res, err := s.mdb.NewUpdate((*keyModel)(nil)).
+		Filter(bson.M{"_id": keyID.String(), "state": string(from)}).
+		Set("state", string(to)).
+		Set("updated_at", now()).
+		Exec(ctx)
+	if err != nil {
+		return false, fmt.Errorf("keysmith/mongo: update key state if: %w", err)
+	}
+	return res.MatchedCount() > 0, nil
+}
+
 func (s *keyStore) UpdateLastUsed(ctx context.Context, keyID id.KeyID, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	res, err := s.mdb.NewUpdate((*keyModel)(nil)).
 		Filter(bson.M{"_id": keyID.String()}).
 		Set("last_used_at", at).
@@ -112,6 +359,10 @@ func (s *keyStore) UpdateLastUsed(ctx context.Context, keyID id.KeyID, at time.T
 }
 
 func (s *keyStore) Delete(ctx context.Context, keyID id.KeyID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	res, err := s.mdb.NewDelete((*keyModel)(nil)).
 		Filter(bson.M{"_id": keyID.String()}).
 		Exec(ctx)
@@ -121,29 +372,40 @@ func (s *keyStore) Delete(ctx context.Context, keyID id.KeyID) error {
 	if res.DeletedCount() == 0 {
 		return errNotFound("key")
 	}
+	_, err = s.mdb.NewDelete((*keyHashModel)(nil)).
+		Many().
+		Filter(bson.M{"key_id": keyID.String()}).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/mongo: delete key hashes: %w", err)
+	}
+	_, err = s.mdb.NewDelete((*groupMemberModel)(nil)).
+		Many().
+		Filter(bson.M{"key_id": keyID.String()}).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/mongo: delete key group memberships: %w", err)
+	}
+	_, err = s.mdb.NewDelete((*transferModel)(nil)).
+		Many().
+		Filter(bson.M{"key_id": keyID.String()}).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/mongo: delete key transfers: %w", err)
+	}
 	return nil
 }
 
 func (s *keyStore) List(ctx context.Context, filter *key.ListFilter) ([]*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var models []keyModel
 
-	f := bson.M{}
-	if filter != nil {
-		if filter.TenantID != "" {
-			f["tenant_id"] = filter.TenantID
-		}
-		if filter.Environment != "" {
-			f["environment"] = string(filter.Environment)
-		}
-		if filter.State != "" {
-			f["state"] = string(filter.State)
-		}
-		if filter.PolicyID != nil {
-			f["policy_id"] = filter.PolicyID.String()
-		}
-		if filter.CreatedBy != "" {
-			f["created_by"] = filter.CreatedBy
-		}
+	f, err := s.filter(ctx, filter)
+	if err != nil {
+		return nil, err
 	}
 
 	q := s.mdb.NewFind(&models).
@@ -175,35 +437,198 @@ func (s *keyStore) List(ctx context.Context, filter *key.ListFilter) ([]*key.Key
 }
 
 func (s *keyStore) Count(ctx context.Context, filter *key.ListFilter) (int64, error) {
-	f := bson.M{}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	f, err := s.filter(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := s.mdb.NewFind((*keyModel)(nil)).
+		Filter(f).
+		Count(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("keysmith/mongo: count keys: %w", err)
+	}
+	return count, nil
+}
+
+// Iterate walks matching keys in batches of iterateBatchSize using keyset
+// pagination on _id, so documents updated by fn mid-iteration are neither
+// skipped nor revisited.
+func (s *keyStore) Iterate(ctx context.Context, filter *key.ListFilter, fn func(*key.Key) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var limit, offset int
 	if filter != nil {
-		if filter.TenantID != "" {
-			f["tenant_id"] = filter.TenantID
+		limit, offset = filter.Limit, filter.Offset
+	}
+
+	base, err := s.filter(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	var cursor string
+	visited := 0
+	for {
+		batch := iterateBatchSize
+		if limit > 0 && limit-visited < batch {
+			batch = limit - visited
 		}
-		if filter.Environment != "" {
-			f["environment"] = string(filter.Environment)
+
+		f := maps.Clone(base)
+		if cursor != "" {
+			cond := bson.M{}
+			if members, ok := base["_id"].(bson.M); ok {
+				cond = maps.Clone(members)
+			}
+			cond["$gt"] = cursor
+			f["_id"] = cond
 		}
-		if filter.State != "" {
-			f["state"] = string(filter.State)
+
+		var models []keyModel
+		q := s.mdb.NewFind(&models).
+			Filter(f).
+			Sort(bson.D{{Key: "_id", Value: 1}}).
+			Limit(int64(batch))
+		if cursor == "" && offset > 0 {
+			q = q.Skip(int64(offset))
+		}
+		if err := q.Scan(ctx); err != nil {
+			return fmt.Errorf("keysmith/mongo: iterate keys: %w", err)
 		}
-		if filter.PolicyID != nil {
-			f["policy_id"] = filter.PolicyID.String()
+
+		for i := range models {
+			k, err := keyFromModel(&models[i])
+			if err != nil {
+				return fmt.Errorf("keysmith/mongo: convert key: %w", err)
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(k); err != nil {
+				return err
+			}
 		}
-		if filter.CreatedBy != "" {
-			f["created_by"] = filter.CreatedBy
+
+		visited += len(models)
+		if len(models) < batch || (limit > 0 && visited >= limit) {
+			return nil
 		}
+		cursor = models[len(models)-1].ID
 	}
+}
 
-	count, err := s.mdb.NewFind((*keyModel)(nil)).
-		Filter(f).
-		Count(ctx)
+// Overview groups the matching keys by state and environment in one
+// aggregation, summing a conditional per window in each group. Keys without
+// last_used_at count as never used whether the field is null or missing.
+func (s *keyStore) Overview(ctx context.Context, q *key.OverviewQuery) (*key.Overview, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	active := bson.M{"$eq": bson.A{"$state", string(key.StateActive)}}
+	count := func(cond any) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{cond, 1, 0}}}
+	}
+	group := bson.M{
+		"_id":        bson.M{"state": "$state", "environment": "$environment"},
+		"total":      bson.M{"$sum": 1},
+		"created":    count(bson.M{"$gt": bson.A{"$created_at", q.Now.Add(-q.CreatedWithin)}}),
+		"never_used": count(bson.M{"$and": bson.A{active, bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$last_used_at", nil}}, nil}}}}),
+	}
+	expiring := make(bson.A, len(q.ExpiringWithin))
+	for i, w := range q.ExpiringWithin {
+		name := fmt.Sprintf("expiring_%d", i)
+		group[name] = count(bson.M{"$and": bson.A{
+			active,
+			bson.M{"$gt": bson.A{"$expires_at", q.Now}},
+			bson.M{"$lte": bson.A{"$expires_at", q.Now.Add(w)}},
+		}})
+		expiring[i] = "$" + name
+	}
+
+	cur, err := s.mdb.Collection(colKeys).Aggregate(ctx, bson.A{
+		bson.M{"$match": keyFilter(&key.ListFilter{TenantID: q.TenantID, AppID: q.AppID})},
+		bson.M{"$group": group},
+		bson.M{"$project": bson.M{"total": 1, "created": 1, "never_used": 1, "expiring": expiring}},
+	})
 	if err != nil {
-		return 0, fmt.Errorf("keysmith/mongo: count keys: %w", err)
+		return nil, fmt.Errorf("keysmith/mongo: key overview: %w", err)
 	}
-	return count, nil
+	var out []struct {
+		ID struct {
+			State       string `bson:"state"`
+			Environment string `bson:"environment"`
+		} `bson:"_id"`
+		Total     int64   `bson:"total"`
+		Created   int64   `bson:"created"`
+		NeverUsed int64   `bson:"never_used"`
+		Expiring  []int64 `bson:"expiring"`
+	}
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, fmt.Errorf("keysmith/mongo: key overview: %w", err)
+	}
+
+	o := key.NewOverview(q)
+	for _, g := range out {
+		o.Total += g.Total
+		o.ByState[key.State(g.ID.State)] += g.Total
+		o.ByEnvironment[key.Environment(g.ID.Environment)] += g.Total
+		o.CreatedRecently.Count += g.Created
+		o.NeverUsed += g.NeverUsed
+		for i, n := range g.Expiring {
+			o.Expiring[i].Count += n
+		}
+	}
+	return o, nil
+}
+
+// Search finds candidates with the search_text text index, one quoted
+// phrase per term so that mongo ANDs them, and checks them with
+// key.MatchSearch, since the index does not keep fields apart.
+func (s *keyStore) Search(ctx context.Context, q *key.SearchQuery) ([]*key.SearchMatch, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(q.Terms) == 0 {
+		return []*key.SearchMatch{}, nil
+	}
+	phrases := make([]string, len(q.Terms))
+	for i, t := range q.Terms {
+		phrases[i] = `"` + t.String() + `"`
+	}
+	f := keyFilter(&key.ListFilter{TenantID: q.TenantID, AppID: q.AppID})
+	f["$text"] = bson.M{"$search": strings.Join(phrases, " ")}
+	var models []keyModel
+	if err := s.mdb.NewFind(&models).Filter(f).Scan(ctx); err != nil {
+		return nil, fmt.Errorf("keysmith/mongo: search keys: %w", err)
+	}
+
+	matches := make([]*key.SearchMatch, 0, len(models))
+	for i := range models {
+		k, err := keyFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/mongo: convert key: %w", err)
+		}
+		if m := key.MatchSearch(k, q.Terms); m != nil {
+			matches = append(matches, m)
+		}
+	}
+	return key.RankSearch(matches, q.Limit), nil
 }
 
 func (s *keyStore) ListExpired(ctx context.Context, before time.Time) ([]*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var models []keyModel
 	err := s.mdb.NewFind(&models).
 		Filter(bson.M{
@@ -226,7 +651,38 @@ func (s *keyStore) ListExpired(ctx context.Context, before time.Time) ([]*key.Ke
 	return result, nil
 }
 
+func (s *keyStore) ListRecentlyUsed(ctx context.Context, limit int) ([]*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var models []keyModel
+	q := s.mdb.NewFind(&models).
+		Filter(bson.M{"last_used_at": bson.M{"$ne": nil}}).
+		Sort(bson.D{{Key: "last_used_at", Value: -1}})
+	if limit > 0 {
+		q = q.Limit(int64(limit))
+	}
+	if err := q.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("keysmith/mongo: list recently used: %w", err)
+	}
+
+	result := make([]*key.Key, 0, len(models))
+	for i := range models {
+		k, err := keyFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/mongo: convert key: %w", err)
+		}
+		result = append(result, k)
+	}
+	return result, nil
+}
+
 func (s *keyStore) ListByPolicy(ctx context.Context, policyID id.PolicyID) ([]*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var models []keyModel
 	err := s.mdb.NewFind(&models).
 		Filter(bson.M{"policy_id": policyID.String()}).
@@ -247,7 +703,46 @@ func (s *keyStore) ListByPolicy(ctx context.Context, policyID id.PolicyID) ([]*k
 }
 
 func (s *keyStore) DeleteByTenant(ctx context.Context, tenantID string) error {
-	_, err := s.mdb.NewDelete((*keyModel)(nil)).
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var models []keyModel
+	err := s.mdb.NewFind(&models).
+		Filter(bson.M{"tenant_id": tenantID}).
+		Scan(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/mongo: list keys by tenant: %w", err)
+	}
+	if len(models) > 0 {
+		ids := make([]string, 0, len(models))
+		for i := range models {
+			ids = append(ids, models[i].ID)
+		}
+		_, err := s.mdb.NewDelete((*keyHashModel)(nil)).
+			Many().
+			Filter(bson.M{"key_id": bson.M{"$in": ids}}).
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("keysmith/mongo: delete key hashes by tenant: %w", err)
+		}
+		_, err = s.mdb.NewDelete((*groupMemberModel)(nil)).
+			Many().
+			Filter(bson.M{"key_id": bson.M{"$in": ids}}).
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("keysmith/mongo: delete key group memberships by tenant: %w", err)
+		}
+		_, err = s.mdb.NewDelete((*transferModel)(nil)).
+			Many().
+			Filter(bson.M{"key_id": bson.M{"$in": ids}}).
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("keysmith/mongo: delete key transfers by tenant: %w", err)
+		}
+	}
+
+	_, err = s.mdb.NewDelete((*keyModel)(nil)).
 		Many().
 		Filter(bson.M{"tenant_id": tenantID}).
 		Exec(ctx)
@@ -256,3 +751,115 @@ func (s *keyStore) DeleteByTenant(ctx context.Context, tenantID string) error {
 	}
 	return nil
 }
+
+// filter returns keyFilter's query document, restricted for a group filter
+// to the IDs of the group's members, which live in their own collection.
+func (s *keyStore) filter(ctx context.Context, filter *key.ListFilter) (bson.M, error) {
+	f := keyFilter(filter)
+	if filter == nil || filter.GroupID == nil {
+		return f, nil
+	}
+	var members []groupMemberModel
+	err := s.mdb.NewFind(&members).
+		Filter(bson.M{"group_id": filter.GroupID.String()}).
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/mongo: list group members: %w", err)
+	}
+	ids := make([]string, len(members))
+	for i := range members {
+		ids[i] = members[i].KeyID
+	}
+	f["_id"] = bson.M{"$in": ids}
+	return f, nil
+}
+
+// keyFilter builds the query document shared by List, Count, and Iterate.
+func keyFilter(filter *key.ListFilter) bson.M {
+	f := bson.M{}
+	if filter == nil {
+		return f
+	}
+	if filter.TenantID != "" {
+		f["tenant_id"] = filter.TenantID
+	}
+	if filter.AppID != "" {
+		f["app_id"] = filter.AppID
+	}
+	if filter.Environment != "" {
+		f["environment"] = string(filter.Environment)
+	}
+	if filter.State != "" {
+		f["state"] = string(filter.State)
+	}
+	if filter.PolicyID != nil {
+		f["policy_id"] = filter.PolicyID.String()
+	}
+	if filter.PoolID != nil {
+		f["pool_id"] = filter.PoolID.String()
+	}
+	if filter.CreatedBy != "" {
+		f["created_by"] = filter.CreatedBy
+	}
+	if filter.ExternalRef != "" {
+		f["external_ref"] = filter.ExternalRef
+	}
+	if filter.Name != "" {
+		f["name_fold"] = strings.ToLower(filter.Name)
+	}
+	if filter.ExpiresBefore != nil {
+		f["expires_at"] = bson.M{"$ne": nil, "$lt": *filter.ExpiresBefore}
+	}
+	if created := timeRange(filter.CreatedAfter, filter.CreatedBefore); created != nil {
+		f["created_at"] = created
+	}
+	if filter.UpdatedAfter != nil {
+		f["updated_at"] = bson.M{"$gt": *filter.UpdatedAfter}
+	}
+	if filter.OutdatedTerms != "" {
+		f["accepted_terms_version"] = bson.M{"$ne": filter.OutdatedTerms}
+	}
+	if len(filter.LabelSelector) > 0 {
+		conds := make(bson.A, len(filter.LabelSelector))
+		for i, r := range filter.LabelSelector {
+			conds[i] = labelCondition(r)
+		}
+		f["$and"] = conds
+	}
+	return f
+}
+
+// labelCondition returns the query document for one selector requirement.
+// Negated requirements also match keys without the label.
+func labelCondition(r key.Requirement) bson.M {
+	match := func(values []string) bson.M {
+		return bson.M{"$elemMatch": bson.M{"name": r.Name, "value": bson.M{"$in": values}}}
+	}
+	switch r.Op {
+	case key.SelectorEquals, key.SelectorIn:
+		return bson.M{"labels": match(r.Values)}
+	case key.SelectorNotEquals, key.SelectorNotIn:
+		return bson.M{"labels": bson.M{"$not": match(r.Values)}}
+	case key.SelectorExists:
+		return bson.M{"labels.name": r.Name}
+	case key.SelectorDoesNotExist:
+		return bson.M{"labels.name": bson.M{"$ne": r.Name}}
+	}
+	return bson.M{"_id": bson.M{"$exists": false}}
+}
+
+// timeRange returns a bson condition for a field strictly between after and
+// before, either of which may be nil, or nil when both are.
+func timeRange(after, before *time.Time) bson.M {
+	if after == nil && before == nil {
+		return nil
+	}
+	cond := bson.M{}
+	if after != nil {
+		cond["$gt"] = *after
+	}
+	if before != nil {
+		cond["$lt"] = *before
+	}
+	return cond
+}
@@ -0,0 +1,102 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/xraph/grove/drivers/mongodriver"
+
+	"github.com/xraph/keysmith/keyevent"
+)
+
+type keyEventStore struct {
+	mdb *mongodriver.MongoDB
+}
+
+func (s *keyEventStore) Append(ctx context.Context, e *keyevent.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m := keyEventToModel(e)
+	// Upsert so a retried append does not duplicate the event.
+	_, err := s.mdb.NewUpdate(m).
+		Filter(bson.M{"at": m.At, "key_id": m.KeyID}).
+		Upsert().
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/mongo: append key event: %w", err)
+	}
+	return nil
+}
+
+func (s *keyEventStore) ListAfter(ctx context.Context, filter *keyevent.ListFilter) ([]*keyevent.Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if filter == nil {
+		filter = &keyevent.ListFilter{}
+	}
+	at := filter.After.At.UTC()
+	f := bson.M{"$or": bson.A{
+		bson.M{"at": bson.M{"$gt": at}},
+		bson.M{"at": at, "key_id": bson.M{"$gt": filter.After.KeyID}},
+	}}
+	if filter.TenantID != "" {
+		f["tenant_id"] = filter.TenantID
+	}
+	if filter.AppID != "" {
+		f["app_id"] = filter.AppID
+	}
+	if filter.KeyID != nil {
+		f["key_id"] = filter.KeyID.String()
+	}
+	if len(filter.Types) > 0 {
+		types := make(bson.A, len(filter.Types))
+		for i, t := range filter.Types {
+			types[i] = string(t)
+		}
+		f["type"] = bson.M{"$in": types}
+	}
+
+	var models []keyEventModel
+	q := s.mdb.NewFind(&models).
+		Filter(f).
+		Sort(bson.D{{Key: "at", Value: 1}, {Key: "key_id", Value: 1}})
+	if filter.Limit > 0 {
+		q = q.Limit(int64(filter.Limit))
+	}
+
+	if err := q.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("keysmith/mongo: list key events: %w", err)
+	}
+
+	result := make([]*keyevent.Event, 0, len(models))
+	for i := range models {
+		e, err := keyEventFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/mongo: convert key event: %w", err)
+		}
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+func (s *keyEventStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	res, err := s.mdb.NewDelete((*keyEventModel)(nil)).
+		Many().
+		Filter(bson.M{"at": bson.M{"$lt": before.UTC()}}).
+		Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("keysmith/mongo: purge key events: %w", err)
+	}
+	return res.DeletedCount(), nil
+}
@@ -0,0 +1,84 @@
+package mongo
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	mongod "go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/xraph/grove/drivers/mongodriver"
+
+	"github.com/xraph/keysmith/store"
+)
+
+var _ store.Locker = (*Store)(nil)
+
+// AcquireLock takes the lock called name for ttl, as a keysmith_locks
+// document keyed by name that holds a random token and the expiry. A single
+// findAndModify upsert takes over an expired document; when an unexpired one
+// exists, the upsert collides with it on _id and the lock is held. Expiries
+// come from each process's clock, so replica clocks must agree to well
+// within the TTL. A TTL index removes documents left by crashed holders,
+// although acquisition does not depend on it.
+func (s *Store) AcquireLock(ctx context.Context, name string, ttl time.Duration) (store.Lock, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	t := now()
+	holder := rand.Text()
+	err := s.mdb.Collection(colLocks).FindOneAndUpdate(ctx,
+		bson.M{"_id": name, "expires_at": bson.M{"$lte": t}},
+		bson.M{"$set": bson.M{"holder": holder, "expires_at": t.Add(ttl)}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Err()
+	if err != nil {
+		if mongod.IsDuplicateKeyError(err) {
+			return nil, store.ErrLockHeld
+		}
+		return nil, fmt.Errorf("keysmith/mongo: acquire lock %s: %w", name, err)
+	}
+	return &lock{mdb: s.mdb, name: name, holder: holder}, nil
+}
+
+type lock struct {
+	mdb    *mongodriver.MongoDB
+	name   string
+	holder string
+}
+
+func (l *lock) Name() string { return l.name }
+
+func (l *lock) Renew(ctx context.Context, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	t := now()
+	res, err := l.mdb.Collection(colLocks).UpdateOne(ctx,
+		bson.M{"_id": l.name, "holder": l.holder, "expires_at": bson.M{"$gt": t}},
+		bson.M{"$set": bson.M{"expires_at": t.Add(ttl)}},
+	)
+	if err != nil {
+		return fmt.Errorf("keysmith/mongo: renew lock %s: %w", l.name, err)
+	}
+	if res.MatchedCount == 0 {
+		return store.ErrLockLost
+	}
+	return nil
+}
+
+func (l *lock) Release(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, err := l.mdb.Collection(colLocks).DeleteOne(ctx, bson.M{"_id": l.name, "holder": l.holder}); err != nil {
+		return fmt.Errorf("keysmith/mongo: release lock %s: %w", l.name, err)
+	}
+	return nil
+}
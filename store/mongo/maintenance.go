@@ -0,0 +1,88 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/xraph/keysmith/store"
+)
+
+var _ store.Maintainer = (*Store)(nil)
+
+// Maintain reports document counts and storage sizes for every keysmith
+// collection. MongoDB reclaims space on its own, so the only maintenance it
+// runs is compact, and only when opts.Full is set.
+func (s *Store) Maintain(ctx context.Context, opts store.MaintenanceOptions) (*store.MaintenanceReport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	report := &store.MaintenanceReport{Backend: "mongo", Actions: []string{}, StartedAt: time.Now()}
+
+	names, err := s.mdb.Database().ListCollectionNames(ctx, bson.M{"name": bson.M{"$regex": "^keysmith_"}})
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/mongo: list collections: %w", err)
+	}
+	slices.Sort(names)
+
+	if opts.Full && !opts.StatsOnly {
+		for _, name := range names {
+			if err := s.mdb.Database().RunCommand(ctx, bson.D{{Key: "compact", Value: name}}).Err(); err != nil {
+				return nil, fmt.Errorf("keysmith/mongo: compact %s: %w", name, err)
+			}
+			report.Actions = append(report.Actions, "compact "+name)
+		}
+	}
+
+	for _, name := range names {
+		t, err := s.collectionStats(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		report.Tables = append(report.Tables, t)
+	}
+	report.Duration = time.Since(report.StartedAt)
+	return report, nil
+}
+
+// collectionStats reads a collection's document count and total size,
+// including indexes, from $collStats.
+func (s *Store) collectionStats(ctx context.Context, name string) (store.TableStats, error) {
+	t := store.TableStats{Name: name}
+	cur, err := s.mdb.Collection(name).Aggregate(ctx, bson.A{
+		bson.M{"$collStats": bson.M{"storageStats": bson.M{}}},
+	})
+	if err != nil {
+		return t, fmt.Errorf("keysmith/mongo: stats %s: %w", name, err)
+	}
+	var out []struct {
+		StorageStats bson.M `bson:"storageStats"`
+	}
+	if err := cur.All(ctx, &out); err != nil {
+		return t, fmt.Errorf("keysmith/mongo: stats %s: %w", name, err)
+	}
+	if len(out) > 0 {
+		t.Rows = toInt64(out[0].StorageStats["count"])
+		t.SizeBytes = toInt64(out[0].StorageStats["totalSize"])
+	}
+	return t, nil
+}
+
+// toInt64 converts the numeric types $collStats reports, which vary with
+// magnitude and server version.
+func toInt64(v any) int64 {
+	switch n := v.(type) {
+	case int32:
+		return int64(n)
+	case int64:
+		return n
+	case float64:
+		return int64(n)
+	default:
+		return 0
+	}
+}
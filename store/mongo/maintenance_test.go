@@ -0,0 +1,61 @@
+//go:build integration
+
+package mongo_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/grove"
+	"github.com/xraph/grove/drivers/mongodriver"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/store/mongo"
+)
+
+// TestMaintain runs against the database in KEYSMITH_TEST_MONGO_URI, which
+// must name a database.
+func TestMaintain(t *testing.T) {
+	uri := os.Getenv("KEYSMITH_TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("KEYSMITH_TEST_MONGO_URI is not set")
+	}
+	ctx := context.Background()
+	d := mongodriver.New()
+	require.NoError(t, d.Open(ctx, uri))
+	db, err := grove.Open(d)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	s := mongo.New(db)
+	require.NoError(t, s.Migrate(ctx))
+	now := time.Now()
+	k := &key.Key{
+		ID: id.NewKeyID(), KeyHash: "maintain-" + id.NewKeyID().String(), Prefix: "sk",
+		Environment: key.EnvTest, State: key.StateActive, CreatedAt: now, UpdatedAt: now,
+	}
+	require.NoError(t, s.Keys().Create(ctx, k))
+	t.Cleanup(func() { _ = s.Keys().Delete(ctx, k.ID) })
+
+	report, err := s.Maintain(ctx, store.MaintenanceOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "mongo", report.Backend)
+	assert.Empty(t, report.Actions, "compact only runs when full")
+
+	var found bool
+	for _, tbl := range report.Tables {
+		if tbl.Name == "keysmith_keys" {
+			found = true
+			assert.Positive(t, tbl.Rows)
+			assert.Positive(t, tbl.SizeBytes)
+		}
+	}
+	assert.True(t, found)
+}
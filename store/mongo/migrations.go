@@ -3,6 +3,7 @@ package mongo
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
@@ -10,6 +11,8 @@ import (
 
 	"github.com/xraph/grove/drivers/mongodriver/mongomigrate"
 	"github.com/xraph/grove/migrate"
+
+	"github.com/xraph/keysmith/key"
 )
 
 // Migrations is the grove migration group for the Keysmith mongo store.
@@ -219,5 +222,648 @@ func init() {
 				return mexec.DropCollection(ctx, (*rotationModel)(nil))
 			},
 		},
+		&migrate.Migration{
+			Name:    "create_keysmith_tenant_settings",
+			Version: "20240101000008",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				// Documents are keyed by tenant ID, so no secondary indexes are needed.
+				return mexec.CreateCollection(ctx, (*tenantSettingsModel)(nil))
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+				return mexec.DropCollection(ctx, (*tenantSettingsModel)(nil))
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_keysmith_key_endpoint_seen",
+			Version: "20240101000009",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				if err := mexec.CreateCollection(ctx, (*endpointSeenModel)(nil)); err != nil {
+					return err
+				}
+
+				return mexec.CreateIndexes(ctx, colEndpointSeen, []mongo.IndexModel{
+					{
+						Keys:    bson.D{{Key: "key_id", Value: 1}, {Key: "endpoint", Value: 1}, {Key: "method", Value: 1}},
+						Options: options.Index().SetUnique(true),
+					},
+					{Keys: bson.D{{Key: "key_id", Value: 1}, {Key: "last_seen_at", Value: -1}}},
+				})
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+				return mexec.DropCollection(ctx, (*endpointSeenModel)(nil))
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_keysmith_key_revocations",
+			Version: "20240101000010",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				if err := mexec.CreateCollection(ctx, (*revocationModel)(nil)); err != nil {
+					return err
+				}
+
+				return mexec.CreateIndexes(ctx, colRevocations, []mongo.IndexModel{
+					{
+						Keys:    bson.D{{Key: "at", Value: 1}, {Key: "key_id", Value: 1}},
+						Options: options.Index().SetUnique(true),
+					},
+					{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "at", Value: 1}, {Key: "key_id", Value: 1}}},
+				})
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+				return mexec.DropCollection(ctx, (*revocationModel)(nil))
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_keysmith_debug_captures",
+			Version: "20240101000011",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				if err := mexec.CreateCollection(ctx, (*captureModel)(nil)); err != nil {
+					return err
+				}
+
+				return mexec.CreateIndexes(ctx, colCaptures, []mongo.IndexModel{
+					{Keys: bson.D{{Key: "key_id", Value: 1}, {Key: "captured_at", Value: -1}}},
+					{Keys: bson.D{{Key: "captured_at", Value: 1}}},
+				})
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+				return mexec.DropCollection(ctx, (*captureModel)(nil))
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_app_scoping_indexes",
+			Version: "20240101000012",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				appIndex := []mongo.IndexModel{{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "app_id", Value: 1}}}}
+				for _, col := range []string{colKeys, colPolicies, colScopes} {
+					if err := mexec.CreateIndexes(ctx, col, appIndex); err != nil {
+						return err
+					}
+				}
+				return mexec.CreateIndexes(ctx, colUsage, []mongo.IndexModel{
+					{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "app_id", Value: 1}, {Key: "created_at", Value: -1}}},
+				})
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				for _, col := range []string{colKeys, colPolicies, colScopes} {
+					if err := mexec.DB().Collection(col).Indexes().DropOne(ctx, "tenant_id_1_app_id_1"); err != nil {
+						return err
+					}
+				}
+				return mexec.DB().Collection(colUsage).Indexes().DropOne(ctx, "tenant_id_1_app_id_1_created_at_-1")
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_key_updated_index",
+			Version: "20240101000013",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				return mexec.CreateIndexes(ctx, colKeys, []mongo.IndexModel{
+					{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "updated_at", Value: 1}}},
+				})
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				return mexec.DB().Collection(colKeys).Indexes().DropOne(ctx, "tenant_id_1_updated_at_1")
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_keysmith_key_hashes",
+			Version: "20240101000014",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				if err := mexec.CreateCollection(ctx, (*keyHashModel)(nil)); err != nil {
+					return err
+				}
+
+				return mexec.CreateIndexes(ctx, colKeyHashes, []mongo.IndexModel{
+					{Keys: bson.D{{Key: "key_id", Value: 1}, {Key: "created_at", Value: 1}}},
+				})
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+				return mexec.DropCollection(ctx, (*keyHashModel)(nil))
+			},
+		},
+		&migrate.Migration{
+			Name:    "backfill_keysmith_key_hashes",
+			Version: "20240101000015",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				// Every existing key gets its current hash as the first version.
+				cur, err := mexec.DB().Collection(colKeys).Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"key_hash": 1, "created_at": 1}))
+				if err != nil {
+					return err
+				}
+				defer cur.Close(ctx)
+
+				hashes := mexec.DB().Collection(colKeyHashes)
+				for cur.Next(ctx) {
+					var doc struct {
+						ID        string    `bson:"_id"`
+						KeyHash   string    `bson:"key_hash"`
+						CreatedAt time.Time `bson:"created_at"`
+					}
+					if err := cur.Decode(&doc); err != nil {
+						return err
+					}
+					_, err := hashes.UpdateOne(ctx, bson.M{"_id": doc.KeyHash}, bson.M{"$setOnInsert": bson.M{
+						"key_id":     doc.ID,
+						"scheme":     key.HashScheme(doc.KeyHash),
+						"active":     true,
+						"created_at": doc.CreatedAt,
+					}}, options.UpdateOne().SetUpsert(true))
+					if err != nil {
+						return err
+					}
+				}
+				return cur.Err()
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				_, err := mexec.DB().Collection(colKeyHashes).DeleteMany(ctx, bson.M{})
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_rotation_tenant_index",
+			Version: "20240101000016",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				return mexec.CreateIndexes(ctx, colRotations, []mongo.IndexModel{
+					{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "created_at", Value: -1}}},
+				})
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				return mexec.DB().Collection(colRotations).Indexes().DropOne(ctx, "tenant_id_1_created_at_-1")
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_key_labels_index",
+			Version: "20240101000017",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				return mexec.CreateIndexes(ctx, colKeys, []mongo.IndexModel{
+					{Keys: bson.D{{Key: "labels.name", Value: 1}, {Key: "labels.value", Value: 1}}},
+				})
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				return mexec.DB().Collection(colKeys).Indexes().DropOne(ctx, "labels.name_1_labels.value_1")
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_keysmith_locks",
+			Version: "20240101000018",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				// The TTL index only clears documents left by crashed holders;
+				// AcquireLock compares expires_at itself.
+				return mexec.CreateIndexes(ctx, colLocks, []mongo.IndexModel{
+					{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+				})
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				return mexec.DB().Collection(colLocks).Drop(ctx)
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_keysmith_key_groups",
+			Version: "20240101000019",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				if err := mexec.CreateCollection(ctx, (*groupModel)(nil)); err != nil {
+					return err
+				}
+				if err := mexec.CreateIndexes(ctx, colGroups, []mongo.IndexModel{
+					{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "app_id", Value: 1}, {Key: "name", Value: 1}}},
+				}); err != nil {
+					return err
+				}
+
+				if err := mexec.CreateCollection(ctx, (*groupMemberModel)(nil)); err != nil {
+					return err
+				}
+				return mexec.CreateIndexes(ctx, colGroupMembers, []mongo.IndexModel{
+					{
+						Keys:    bson.D{{Key: "group_id", Value: 1}, {Key: "key_id", Value: 1}},
+						Options: options.Index().SetUnique(true),
+					},
+					{Keys: bson.D{{Key: "key_id", Value: 1}}},
+				})
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+				if err := mexec.DropCollection(ctx, (*groupMemberModel)(nil)); err != nil {
+					return err
+				}
+				return mexec.DropCollection(ctx, (*groupModel)(nil))
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_key_external_ref_index",
+			Version: "20240101000020",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				// Keys without a reference omit the field and are not indexed.
+				return mexec.CreateIndexes(ctx, colKeys, []mongo.IndexModel{
+					{
+						Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "external_ref", Value: 1}},
+						Options: options.Index().SetUnique(true).
+							SetPartialFilterExpression(bson.M{"external_ref": bson.M{"$type": "string"}}),
+					},
+				})
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				return mexec.DB().Collection(colKeys).Indexes().DropOne(ctx, "tenant_id_1_external_ref_1")
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_keysmith_key_events",
+			Version: "20240101000021",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				if err := mexec.CreateCollection(ctx, (*keyEventModel)(nil)); err != nil {
+					return err
+				}
+
+				return mexec.CreateIndexes(ctx, colKeyEvents, []mongo.IndexModel{
+					{
+						Keys:    bson.D{{Key: "at", Value: 1}, {Key: "key_id", Value: 1}},
+						Options: options.Index().SetUnique(true),
+					},
+					{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "at", Value: 1}, {Key: "key_id", Value: 1}}},
+				})
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+				return mexec.DropCollection(ctx, (*keyEventModel)(nil))
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_key_name_fold",
+			Version: "20240101000022",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				// Backfill the lowercased name that case-insensitive name
+				// lookups match on.
+				_, err := mexec.DB().Collection(colKeys).UpdateMany(ctx,
+					bson.M{"name_fold": bson.M{"$exists": false}},
+					mongo.Pipeline{{{Key: "$set", Value: bson.M{"name_fold": bson.M{"$toLower": "$name"}}}}},
+				)
+				if err != nil {
+					return fmt.Errorf("backfill name_fold: %w", err)
+				}
+				return mexec.CreateIndexes(ctx, colKeys, []mongo.IndexModel{
+					{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "name_fold", Value: 1}}},
+				})
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				keys := mexec.DB().Collection(colKeys)
+				if err := keys.Indexes().DropOne(ctx, "tenant_id_1_name_fold_1"); err != nil {
+					return err
+				}
+				_, err := keys.UpdateMany(ctx, bson.M{}, bson.M{"$unset": bson.M{"name_fold": ""}})
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_usage_environment",
+			Version: "20240101000023",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				// Backfill each record's environment from its key.
+				db := mexec.DB()
+				cur, err := db.Collection(colKeys).Find(ctx, bson.M{},
+					options.Find().SetProjection(bson.M{"_id": 1, "environment": 1}))
+				if err != nil {
+					return fmt.Errorf("find keys: %w", err)
+				}
+				defer cur.Close(ctx)
+				for cur.Next(ctx) {
+					var k struct {
+						ID          string `bson:"_id"`
+						Environment string `bson:"environment"`
+					}
+					if err := cur.Decode(&k); err != nil {
+						return fmt.Errorf("decode key: %w", err)
+					}
+					_, err := db.Collection(colUsage).UpdateMany(ctx,
+						bson.M{"key_id": k.ID, "environment": bson.M{"$exists": false}},
+						bson.M{"$set": bson.M{"environment": k.Environment}},
+					)
+					if err != nil {
+						return fmt.Errorf("backfill usage environment: %w", err)
+					}
+				}
+				if err := cur.Err(); err != nil {
+					return fmt.Errorf("iterate keys: %w", err)
+				}
+				return mexec.CreateIndexes(ctx, colUsage, []mongo.IndexModel{
+					{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "environment", Value: 1}, {Key: "created_at", Value: -1}}},
+				})
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				usages := mexec.DB().Collection(colUsage)
+				if err := usages.Indexes().DropOne(ctx, "tenant_id_1_environment_1_created_at_-1"); err != nil {
+					return err
+				}
+				_, err := usages.UpdateMany(ctx, bson.M{}, bson.M{"$unset": bson.M{"environment": ""}})
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_keysmith_key_transfers",
+			Version: "20240101000024",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				if err := mexec.CreateCollection(ctx, (*transferModel)(nil)); err != nil {
+					return err
+				}
+
+				// Only pending transfers are unique per key.
+				return mexec.CreateIndexes(ctx, colTransfers, []mongo.IndexModel{
+					{
+						Keys: bson.D{{Key: "key_id", Value: 1}},
+						Options: options.Index().SetUnique(true).
+							SetPartialFilterExpression(bson.M{"state": "pending"}),
+					},
+					{Keys: bson.D{{Key: "source_tenant_id", Value: 1}, {Key: "created_at", Value: -1}}},
+					{Keys: bson.D{{Key: "target_tenant_id", Value: 1}, {Key: "created_at", Value: -1}}},
+					{Keys: bson.D{{Key: "state", Value: 1}, {Key: "expires_at", Value: 1}}},
+				})
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+				return mexec.DropCollection(ctx, (*transferModel)(nil))
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_keysmith_hash_tombstones",
+			Version: "20240101000025",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				if err := mexec.CreateCollection(ctx, (*tombstoneModel)(nil)); err != nil {
+					return err
+				}
+
+				return mexec.CreateIndexes(ctx, colTombstones, []mongo.IndexModel{
+					{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "created_at", Value: -1}}},
+					{Keys: bson.D{{Key: "created_at", Value: 1}}},
+				})
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+				return mexec.DropCollection(ctx, (*tombstoneModel)(nil))
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_key_search_text",
+			Version: "20240101000026",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				// Backfill the words of each key's searchable fields.
+				keys := mexec.DB().Collection(colKeys)
+				cur, err := keys.Find(ctx, bson.M{"search_text": bson.M{"$exists": false}},
+					options.Find().SetProjection(bson.M{"_id": 1, "name": 1, "description": 1, "metadata": 1}))
+				if err != nil {
+					return fmt.Errorf("find keys: %w", err)
+				}
+				defer cur.Close(ctx)
+				for cur.Next(ctx) {
+					var m keyModel
+					if err := cur.Decode(&m); err != nil {
+						return fmt.Errorf("decode key: %w", err)
+					}
+					text := key.SearchText(&key.Key{Name: m.Name, Description: m.Description, Metadata: m.Metadata})
+					if _, err := keys.UpdateByID(ctx, m.ID, bson.M{"$set": bson.M{"search_text": text}}); err != nil {
+						return fmt.Errorf("backfill search_text: %w", err)
+					}
+				}
+				if err := cur.Err(); err != nil {
+					return fmt.Errorf("iterate keys: %w", err)
+				}
+
+				// The words are already split and lowercased, so the index
+				// must not stem them or drop stop words.
+				return mexec.CreateIndexes(ctx, colKeys, []mongo.IndexModel{
+					{
+						Keys:    bson.D{{Key: "search_text", Value: "text"}},
+						Options: options.Index().SetName("search_text_text").SetDefaultLanguage("none"),
+					},
+				})
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				keys := mexec.DB().Collection(colKeys)
+				if err := keys.Indexes().DropOne(ctx, "search_text_text"); err != nil {
+					return err
+				}
+				_, err := keys.UpdateMany(ctx, bson.M{}, bson.M{"$unset": bson.M{"search_text": ""}})
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_keysmith_quota_pools",
+			Version: "20240101000027",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+
+				if err := mexec.CreateCollection(ctx, (*quotaPoolModel)(nil)); err != nil {
+					return err
+				}
+				if err := mexec.CreateCollection(ctx, (*quotaUsageModel)(nil)); err != nil {
+					return err
+				}
+				if err := mexec.CreateIndexes(ctx, colQuotaPools, []mongo.IndexModel{
+					{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "app_id", Value: 1}, {Key: "name", Value: 1}}},
+				}); err != nil {
+					return err
+				}
+				if err := mexec.CreateIndexes(ctx, colQuotaUsage, []mongo.IndexModel{
+					{
+						Keys:    bson.D{{Key: "pool_id", Value: 1}, {Key: "key_id", Value: 1}, {Key: "period", Value: 1}, {Key: "window_start", Value: 1}},
+						Options: options.Index().SetUnique(true),
+					},
+					{Keys: bson.D{{Key: "window_start", Value: 1}}},
+				}); err != nil {
+					return err
+				}
+				return mexec.CreateIndexes(ctx, colKeys, []mongo.IndexModel{
+					{Keys: bson.D{{Key: "pool_id", Value: 1}}, Options: options.Index().SetSparse(true)},
+				})
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				mexec, ok := exec.(*mongomigrate.Executor)
+				if !ok {
+					return fmt.Errorf("expected mongomigrate executor, got %T", exec)
+				}
+				if err := mexec.DropCollection(ctx, (*quotaUsageModel)(nil)); err != nil {
+					return err
+				}
+				return mexec.DropCollection(ctx, (*quotaPoolModel)(nil))
+			},
+		},
 	)
 }
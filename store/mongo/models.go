@@ -1,15 +1,27 @@
 package mongo
 
 import (
+	"maps"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/xraph/grove"
 
+	"github.com/xraph/keysmith/capture"
+	"github.com/xraph/keysmith/group"
 	"github.com/xraph/keysmith/id"
 	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keyevent"
+	"github.com/xraph/keysmith/metaschema"
 	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/quotapool"
+	"github.com/xraph/keysmith/revocation"
 	"github.com/xraph/keysmith/rotation"
 	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/tenant"
+	"github.com/xraph/keysmith/tombstone"
+	"github.com/xraph/keysmith/transfer"
 	"github.com/xraph/keysmith/usage"
 )
 
@@ -23,6 +35,7 @@ type keyModel struct {
 	TenantID        string         `grove:"tenant_id"      bson:"tenant_id"`
 	AppID           string         `grove:"app_id"         bson:"app_id"`
 	Name            string         `grove:"name"           bson:"name"`
+	NameFold        string         `grove:"name_fold"      bson:"name_fold"` // lowercased Name, for case-insensitive lookups
 	Description     string         `grove:"description"    bson:"description"`
 	Prefix          string         `grove:"prefix"         bson:"prefix"`
 	Hint            string         `grove:"hint"           bson:"hint"`
@@ -30,8 +43,22 @@ type keyModel struct {
 	Environment     string         `grove:"environment"    bson:"environment"`
 	State           string         `grove:"state"          bson:"state"`
 	PolicyID        *string        `grove:"policy_id"      bson:"policy_id,omitempty"`
+	PoolID          *string        `grove:"pool_id"        bson:"pool_id,omitempty"`
 	Metadata        map[string]any `grove:"metadata"       bson:"metadata,omitempty"`
 	CreatedBy       string         `grove:"created_by"     bson:"created_by"`
+	ExternalRef     string         `grove:"external_ref"   bson:"external_ref,omitempty"`
+	AllowedOrigins  []string       `grove:"allowed_origins" bson:"allowed_origins,omitempty"`
+	Consumer        string         `grove:"intended_consumer" bson:"intended_consumer"`
+	EnforceConsumer bool           `grove:"enforce_consumer" bson:"enforce_consumer"`
+	CertFingerprint string         `grove:"cert_fingerprint" bson:"cert_fingerprint,omitempty"`
+	TermsVersion    string         `grove:"accepted_terms_version" bson:"accepted_terms_version"`
+	TermsAt         *time.Time     `grove:"accepted_terms_at" bson:"accepted_terms_at,omitempty"`
+	Flags           key.Flags      `grove:"flags" bson:"flags"`
+	Contacts        key.Contacts   `grove:"contacts" bson:"contacts,omitempty"`
+	Labels          []labelDoc     `grove:"labels" bson:"labels,omitempty"`
+	DebugSampleRate float64        `grove:"debug_sample_rate" bson:"debug_sample_rate"`
+	DebugUntil      *time.Time     `grove:"debug_until"    bson:"debug_until"`
+	SearchText      string         `grove:"search_text"    bson:"search_text"` // key.SearchText, for the text index
 	ExpiresAt       *time.Time     `grove:"expires_at"     bson:"expires_at,omitempty"`
 	LastUsedAt      *time.Time     `grove:"last_used_at"   bson:"last_used_at,omitempty"`
 	RotatedAt       *time.Time     `grove:"rotated_at"     bson:"rotated_at,omitempty"`
@@ -46,6 +73,7 @@ func keyToModel(k *key.Key) *keyModel {
 		TenantID:    k.TenantID,
 		AppID:       k.AppID,
 		Name:        k.Name,
+		NameFold:    strings.ToLower(k.Name),
 		Description: k.Description,
 		Prefix:      k.Prefix,
 		Hint:        k.Hint,
@@ -54,20 +82,48 @@ func keyToModel(k *key.Key) *keyModel {
 		State:       string(k.State),
 		Metadata:    k.Metadata,
 		CreatedBy:   k.CreatedBy,
-		ExpiresAt:   k.ExpiresAt,
+		ExternalRef: k.ExternalRef,
+		ExpiresAt:   utcTime(k.ExpiresAt),
 		LastUsedAt:  k.LastUsedAt,
 		RotatedAt:   k.RotatedAt,
 		RevokedAt:   k.RevokedAt,
 		CreatedAt:   k.CreatedAt,
 		UpdatedAt:   k.UpdatedAt,
+
+		AllowedOrigins:  k.AllowedOrigins,
+		Consumer:        k.IntendedConsumer,
+		EnforceConsumer: k.EnforceConsumer,
+		CertFingerprint: k.CertFingerprint,
+		TermsVersion:    k.AcceptedTermsVersion,
+		TermsAt:         utcTime(k.AcceptedTermsAt),
+		Flags:           k.Flags,
+		Contacts:        k.Contacts,
+		Labels:          labelsToDocs(k.Labels),
+		DebugSampleRate: k.DebugSampleRate,
+		DebugUntil:      utcTime(k.DebugUntil),
+		SearchText:      key.SearchText(k),
 	}
 	if k.PolicyID != nil {
 		s := k.PolicyID.String()
 		m.PolicyID = &s
 	}
+	if k.PoolID != nil {
+		s := k.PoolID.String()
+		m.PoolID = &s
+	}
 	return m
 }
 
+// utcTime normalizes t to UTC so expiry comparisons do not depend on the
+// writer's local zone.
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
 func keyFromModel(m *keyModel) (*key.Key, error) {
 	kid, err := id.ParseKeyID(m.ID)
 	if err != nil {
@@ -86,12 +142,26 @@ func keyFromModel(m *keyModel) (*key.Key, error) {
 		State:       key.State(m.State),
 		Metadata:    m.Metadata,
 		CreatedBy:   m.CreatedBy,
+		ExternalRef: m.ExternalRef,
 		ExpiresAt:   m.ExpiresAt,
 		LastUsedAt:  m.LastUsedAt,
 		RotatedAt:   m.RotatedAt,
 		RevokedAt:   m.RevokedAt,
 		CreatedAt:   m.CreatedAt,
 		UpdatedAt:   m.UpdatedAt,
+
+		AllowedOrigins:  m.AllowedOrigins,
+		DebugSampleRate: m.DebugSampleRate,
+		DebugUntil:      m.DebugUntil,
+
+		IntendedConsumer:     m.Consumer,
+		EnforceConsumer:      m.EnforceConsumer,
+		CertFingerprint:      m.CertFingerprint,
+		AcceptedTermsVersion: m.TermsVersion,
+		AcceptedTermsAt:      m.TermsAt,
+		Flags:                m.Flags.Normalize(),
+		Contacts:             m.Contacts.Normalize(),
+		Labels:               labelsFromDocs(m.Labels),
 	}
 	if m.PolicyID != nil {
 		pid, err := id.ParsePolicyID(*m.PolicyID)
@@ -100,9 +170,46 @@ func keyFromModel(m *keyModel) (*key.Key, error) {
 		}
 		k.PolicyID = &pid
 	}
+	if m.PoolID != nil {
+		pid, err := id.ParseQuotaPoolID(*m.PoolID)
+		if err != nil {
+			return nil, err
+		}
+		k.PoolID = &pid
+	}
 	return k, nil
 }
 
+// labelDoc is one key label. Labels are stored as an array of name/value
+// documents rather than a map so that one multikey index on
+// labels.name and labels.value serves every selector.
+type labelDoc struct {
+	Name  string `bson:"name"`
+	Value string `bson:"value"`
+}
+
+func labelsToDocs(l key.Labels) []labelDoc {
+	if len(l) == 0 {
+		return nil
+	}
+	docs := make([]labelDoc, 0, len(l))
+	for _, name := range slices.Sorted(maps.Keys(l)) {
+		docs = append(docs, labelDoc{Name: name, Value: l[name]})
+	}
+	return docs
+}
+
+func labelsFromDocs(docs []labelDoc) key.Labels {
+	if len(docs) == 0 {
+		return nil
+	}
+	l := make(key.Labels, len(docs))
+	for _, d := range docs {
+		l[d.Name] = d.Value
+	}
+	return l
+}
+
 // ──────────────────────────────────────────────────
 // Policy model
 // ──────────────────────────────────────────────────
@@ -114,6 +221,7 @@ type policyModel struct {
 	AppID           string         `grove:"app_id"              bson:"app_id"`
 	Name            string         `grove:"name"                bson:"name"`
 	Description     string         `grove:"description"         bson:"description"`
+	BasePolicyID    *string        `grove:"base_policy_id"      bson:"base_policy_id,omitempty"`
 	RateLimit       int            `grove:"rate_limit"          bson:"rate_limit"`
 	RateLimitWindow int64          `grove:"rate_limit_window"   bson:"rate_limit_window_ms"`
 	BurstLimit      int            `grove:"burst_limit"         bson:"burst_limit"`
@@ -127,13 +235,17 @@ type policyModel struct {
 	GracePeriod     int64          `grove:"grace_period"        bson:"grace_period_ms"`
 	DailyQuota      int64          `grove:"daily_quota"         bson:"daily_quota"`
 	MonthlyQuota    int64          `grove:"monthly_quota"       bson:"monthly_quota"`
+	QuotaTimezone   string         `grove:"quota_timezone" bson:"quota_timezone,omitempty"`
+	MinTLSVersion   string         `grove:"min_tls_version" bson:"min_tls_version,omitempty"`
+	RequireMTLS     bool           `grove:"require_mtls" bson:"require_mtls,omitempty"`
+	Inline          bool           `grove:"inline" bson:"inline,omitempty"`
 	Metadata        map[string]any `grove:"metadata"            bson:"metadata,omitempty"`
 	CreatedAt       time.Time      `grove:"created_at"          bson:"created_at"`
 	UpdatedAt       time.Time      `grove:"updated_at"          bson:"updated_at"`
 }
 
 func policyToModel(pol *policy.Policy) *policyModel {
-	return &policyModel{
+	m := &policyModel{
 		ID:              pol.ID.String(),
 		TenantID:        pol.TenantID,
 		AppID:           pol.AppID,
@@ -152,10 +264,19 @@ func policyToModel(pol *policy.Policy) *policyModel {
 		GracePeriod:     pol.GracePeriod.Milliseconds(),
 		DailyQuota:      pol.DailyQuota,
 		MonthlyQuota:    pol.MonthlyQuota,
+		QuotaTimezone:   pol.QuotaTimezone,
+		MinTLSVersion:   pol.MinTLSVersion,
+		RequireMTLS:     pol.RequireMTLS,
+		Inline:          pol.Inline,
 		Metadata:        pol.Metadata,
 		CreatedAt:       pol.CreatedAt,
 		UpdatedAt:       pol.UpdatedAt,
 	}
+	if pol.BasePolicyID != nil {
+		bid := pol.BasePolicyID.String()
+		m.BasePolicyID = &bid
+	}
+	return m
 }
 
 func policyFromModel(m *policyModel) (*policy.Policy, error) {
@@ -163,12 +284,21 @@ func policyFromModel(m *policyModel) (*policy.Policy, error) {
 	if err != nil {
 		return nil, err
 	}
+	var basePolicyID *id.PolicyID
+	if m.BasePolicyID != nil {
+		bid, err := id.ParsePolicyID(*m.BasePolicyID)
+		if err != nil {
+			return nil, err
+		}
+		basePolicyID = &bid
+	}
 	return &policy.Policy{
 		ID:              pid,
 		TenantID:        m.TenantID,
 		AppID:           m.AppID,
 		Name:            m.Name,
 		Description:     m.Description,
+		BasePolicyID:    basePolicyID,
 		RateLimit:       m.RateLimit,
 		RateLimitWindow: time.Duration(m.RateLimitWindow) * time.Millisecond,
 		BurstLimit:      m.BurstLimit,
@@ -182,6 +312,10 @@ func policyFromModel(m *policyModel) (*policy.Policy, error) {
 		GracePeriod:     time.Duration(m.GracePeriod) * time.Millisecond,
 		DailyQuota:      m.DailyQuota,
 		MonthlyQuota:    m.MonthlyQuota,
+		QuotaTimezone:   m.QuotaTimezone,
+		MinTLSVersion:   m.MinTLSVersion,
+		RequireMTLS:     m.RequireMTLS,
+		Inline:          m.Inline,
 		Metadata:        m.Metadata,
 		CreatedAt:       m.CreatedAt,
 		UpdatedAt:       m.UpdatedAt,
@@ -194,14 +328,55 @@ func policyFromModel(m *policyModel) (*policy.Policy, error) {
 
 type scopeModel struct {
 	grove.BaseModel `grove:"table:keysmith_scopes"`
-	ID              string         `grove:"id,pk"       bson:"_id"`
-	TenantID        string         `grove:"tenant_id"   bson:"tenant_id"`
-	AppID           string         `grove:"app_id"      bson:"app_id"`
-	Name            string         `grove:"name"        bson:"name"`
-	Description     string         `grove:"description" bson:"description"`
-	Parent          *string        `grove:"parent"      bson:"parent,omitempty"`
-	Metadata        map[string]any `grove:"metadata"    bson:"metadata,omitempty"`
-	CreatedAt       time.Time      `grove:"created_at"  bson:"created_at"`
+	ID              string         `grove:"id,pk"             bson:"_id"`
+	TenantID        string         `grove:"tenant_id"         bson:"tenant_id"`
+	AppID           string         `grove:"app_id"            bson:"app_id"`
+	Name            string         `grove:"name"              bson:"name"`
+	Description     string         `grove:"description"       bson:"description"`
+	Parent          *string        `grove:"parent"            bson:"parent,omitempty"`
+	Metadata        map[string]any `grove:"metadata"          bson:"metadata,omitempty"`
+	RateLimit       int            `grove:"rate_limit"        bson:"rate_limit,omitempty"`
+	RateLimitWindow int64          `grove:"rate_limit_window" bson:"rate_limit_window_ms,omitempty"`
+	CreatedAt       time.Time      `grove:"created_at"        bson:"created_at"`
+}
+
+// keyHashModel is one hash version of a key. Lookups by hash go through
+// this collection; the key's key_hash is its current version.
+type keyHashModel struct {
+	grove.BaseModel `grove:"table:keysmith_key_hashes"`
+	Hash            string    `grove:"hash,pk"    bson:"_id"`
+	KeyID           string    `grove:"key_id"     bson:"key_id"`
+	Scheme          string    `grove:"scheme"     bson:"scheme"`
+	Active          bool      `grove:"active"     bson:"active"`
+	CreatedAt       time.Time `grove:"created_at" bson:"created_at"`
+}
+
+func keyHashToModel(h *key.HashVersion) *keyHashModel {
+	scheme := h.Scheme
+	if scheme == "" {
+		scheme = key.HashScheme(h.Hash)
+	}
+	return &keyHashModel{
+		Hash:      h.Hash,
+		KeyID:     h.KeyID.String(),
+		Scheme:    scheme,
+		Active:    h.Active,
+		CreatedAt: h.CreatedAt.UTC(),
+	}
+}
+
+func keyHashFromModel(m *keyHashModel) (*key.HashVersion, error) {
+	kid, err := id.ParseKeyID(m.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	return &key.HashVersion{
+		KeyID:     kid,
+		Hash:      m.Hash,
+		Scheme:    m.Scheme,
+		Active:    m.Active,
+		CreatedAt: m.CreatedAt,
+	}, nil
 }
 
 // keyScopeModel represents the join collection for key-scope assignments.
@@ -213,13 +388,15 @@ type keyScopeModel struct {
 
 func scopeToModel(sc *scope.Scope) *scopeModel {
 	m := &scopeModel{
-		ID:          sc.ID.String(),
-		TenantID:    sc.TenantID,
-		AppID:       sc.AppID,
-		Name:        sc.Name,
-		Description: sc.Description,
-		Metadata:    sc.Metadata,
-		CreatedAt:   sc.CreatedAt,
+		ID:              sc.ID.String(),
+		TenantID:        sc.TenantID,
+		AppID:           sc.AppID,
+		Name:            sc.Name,
+		Description:     sc.Description,
+		Metadata:        sc.Metadata,
+		RateLimit:       sc.RateLimit,
+		RateLimitWindow: sc.RateLimitWindow.Milliseconds(),
+		CreatedAt:       sc.CreatedAt,
 	}
 	if sc.Parent != "" {
 		m.Parent = &sc.Parent
@@ -233,13 +410,15 @@ func scopeFromModel(m *scopeModel) (*scope.Scope, error) {
 		return nil, err
 	}
 	sc := &scope.Scope{
-		ID:          sid,
-		TenantID:    m.TenantID,
-		AppID:       m.AppID,
-		Name:        m.Name,
-		Description: m.Description,
-		Metadata:    m.Metadata,
-		CreatedAt:   m.CreatedAt,
+		ID:              sid,
+		TenantID:        m.TenantID,
+		AppID:           m.AppID,
+		Name:            m.Name,
+		Description:     m.Description,
+		Metadata:        m.Metadata,
+		RateLimit:       m.RateLimit,
+		RateLimitWindow: time.Duration(m.RateLimitWindow) * time.Millisecond,
+		CreatedAt:       m.CreatedAt,
 	}
 	if m.Parent != nil {
 		sc.Parent = *m.Parent
@@ -256,6 +435,8 @@ type usageModel struct {
 	ID              string         `grove:"id,pk"        bson:"_id"`
 	KeyID           string         `grove:"key_id"       bson:"key_id"`
 	TenantID        string         `grove:"tenant_id"    bson:"tenant_id"`
+	AppID           string         `grove:"app_id"       bson:"app_id"`
+	Environment     string         `grove:"environment"  bson:"environment"`
 	Endpoint        string         `grove:"endpoint"     bson:"endpoint"`
 	Method          string         `grove:"method"       bson:"method"`
 	StatusCode      int            `grove:"status_code"  bson:"status_code"`
@@ -268,17 +449,19 @@ type usageModel struct {
 
 func usageToModel(rec *usage.Record) *usageModel {
 	return &usageModel{
-		ID:         rec.ID.String(),
-		KeyID:      rec.KeyID.String(),
-		TenantID:   rec.TenantID,
-		Endpoint:   rec.Endpoint,
-		Method:     rec.Method,
-		StatusCode: rec.StatusCode,
-		IPAddress:  rec.IPAddress,
-		UserAgent:  rec.UserAgent,
-		LatencyMs:  rec.Latency.Milliseconds(),
-		Metadata:   rec.Metadata,
-		CreatedAt:  rec.CreatedAt,
+		ID:          rec.ID.String(),
+		KeyID:       rec.KeyID.String(),
+		TenantID:    rec.TenantID,
+		AppID:       rec.AppID,
+		Environment: string(rec.Environment),
+		Endpoint:    rec.Endpoint,
+		Method:      rec.Method,
+		StatusCode:  rec.StatusCode,
+		IPAddress:   rec.IPAddress,
+		UserAgent:   rec.UserAgent,
+		LatencyMs:   rec.Latency.Milliseconds(),
+		Metadata:    rec.Metadata,
+		CreatedAt:   rec.CreatedAt,
 	}
 }
 
@@ -292,17 +475,19 @@ func usageFromModel(m *usageModel) (*usage.Record, error) {
 		return nil, err
 	}
 	return &usage.Record{
-		ID:         uid,
-		KeyID:      kid,
-		TenantID:   m.TenantID,
-		Endpoint:   m.Endpoint,
-		Method:     m.Method,
-		StatusCode: m.StatusCode,
-		IPAddress:  m.IPAddress,
-		UserAgent:  m.UserAgent,
-		Latency:    time.Duration(m.LatencyMs) * time.Millisecond,
-		Metadata:   m.Metadata,
-		CreatedAt:  m.CreatedAt,
+		ID:          uid,
+		KeyID:       kid,
+		TenantID:    m.TenantID,
+		AppID:       m.AppID,
+		Environment: key.Environment(m.Environment),
+		Endpoint:    m.Endpoint,
+		Method:      m.Method,
+		StatusCode:  m.StatusCode,
+		IPAddress:   m.IPAddress,
+		UserAgent:   m.UserAgent,
+		Latency:     time.Duration(m.LatencyMs) * time.Millisecond,
+		Metadata:    m.Metadata,
+		CreatedAt:   m.CreatedAt,
 	}, nil
 }
 
@@ -338,6 +523,30 @@ func aggFromModel(m *usageAggModel) (*usage.Aggregation, error) {
 	}, nil
 }
 
+// endpointSeenModel is the last-seen summary of one key endpoint.
+type endpointSeenModel struct {
+	grove.BaseModel `grove:"table:keysmith_key_endpoint_seen"`
+	KeyID           string    `grove:"key_id,pk"     bson:"key_id"`
+	Endpoint        string    `grove:"endpoint,pk"   bson:"endpoint"`
+	Method          string    `grove:"method,pk"     bson:"method"`
+	LastSeenAt      time.Time `grove:"last_seen_at"  bson:"last_seen_at"`
+	Count           int64     `grove:"count"         bson:"count"`
+}
+
+func endpointSeenFromModel(m *endpointSeenModel) (*usage.EndpointActivity, error) {
+	kid, err := id.ParseKeyID(m.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	return &usage.EndpointActivity{
+		KeyID:      kid,
+		Endpoint:   m.Endpoint,
+		Method:     m.Method,
+		LastSeenAt: m.LastSeenAt,
+		Count:      m.Count,
+	}, nil
+}
+
 // ──────────────────────────────────────────────────
 // Rotation model
 // ──────────────────────────────────────────────────
@@ -347,8 +556,11 @@ type rotationModel struct {
 	ID              string    `grove:"id,pk"         bson:"_id"`
 	KeyID           string    `grove:"key_id"        bson:"key_id"`
 	TenantID        string    `grove:"tenant_id"     bson:"tenant_id"`
+	AppID           string    `grove:"app_id"        bson:"app_id"`
 	OldKeyHash      string    `grove:"old_key_hash"  bson:"old_key_hash"`
 	NewKeyHash      string    `grove:"new_key_hash"  bson:"new_key_hash"`
+	OldHint         string    `grove:"old_hint"      bson:"old_hint"`
+	NewHint         string    `grove:"new_hint"      bson:"new_hint"`
 	Reason          string    `grove:"reason"        bson:"reason"`
 	GraceTTLMs      int64     `grove:"grace_ttl_ms"  bson:"grace_ttl_ms"`
 	GraceEnds       time.Time `grove:"grace_ends"    bson:"grace_ends"`
@@ -361,8 +573,11 @@ func rotationToModel(rec *rotation.Record) *rotationModel {
 		ID:         rec.ID.String(),
 		KeyID:      rec.KeyID.String(),
 		TenantID:   rec.TenantID,
+		AppID:      rec.AppID,
 		OldKeyHash: rec.OldKeyHash,
 		NewKeyHash: rec.NewKeyHash,
+		OldHint:    rec.OldHint,
+		NewHint:    rec.NewHint,
 		Reason:     string(rec.Reason),
 		GraceTTLMs: rec.GraceTTL.Milliseconds(),
 		GraceEnds:  rec.GraceEnds,
@@ -384,8 +599,11 @@ func rotationFromModel(m *rotationModel) (*rotation.Record, error) {
 		ID:         rid,
 		KeyID:      kid,
 		TenantID:   m.TenantID,
+		AppID:      m.AppID,
 		OldKeyHash: m.OldKeyHash,
 		NewKeyHash: m.NewKeyHash,
+		OldHint:    m.OldHint,
+		NewHint:    m.NewHint,
 		Reason:     rotation.Reason(m.Reason),
 		GraceTTL:   time.Duration(m.GraceTTLMs) * time.Millisecond,
 		GraceEnds:  m.GraceEnds,
@@ -393,3 +611,464 @@ func rotationFromModel(m *rotationModel) (*rotation.Record, error) {
 		CreatedAt:  m.CreatedAt,
 	}, nil
 }
+
+// ──────────────────────────────────────────────────
+// Tenant settings model
+// ──────────────────────────────────────────────────
+
+type tenantSettingsModel struct {
+	grove.BaseModel `grove:"table:keysmith_tenant_settings"`
+	TenantID        string             `grove:"tenant_id,pk"     bson:"_id"`
+	AppID           string             `grove:"app_id"           bson:"app_id"`
+	DefaultScopes   []string           `grove:"default_scopes"   bson:"default_scopes"`
+	DefaultContacts key.Contacts       `grove:"default_contacts" bson:"default_contacts,omitempty"`
+	MetadataSchema  *metaschema.Schema `grove:"metadata_schema"  bson:"metadata_schema,omitempty"`
+	RateLimit       int                `grove:"rate_limit"        bson:"rate_limit"`
+	RateLimitWindow int64              `grove:"rate_limit_window" bson:"rate_limit_window_ms"`
+	IPHandling      string             `grove:"ip_handling"       bson:"ip_handling,omitempty"`
+	QuotaTimezone   string             `grove:"quota_timezone" bson:"quota_timezone,omitempty"`
+	UniqueKeyNames  bool               `grove:"unique_key_names" bson:"unique_key_names,omitempty"`
+	Lockdown        *tenant.Lockdown   `grove:"lockdown"         bson:"lockdown,omitempty"`
+	CreatedAt       time.Time          `grove:"created_at"       bson:"created_at"`
+	UpdatedAt       time.Time          `grove:"updated_at"       bson:"updated_at"`
+}
+
+func tenantSettingsToModel(ts *tenant.Settings) *tenantSettingsModel {
+	return &tenantSettingsModel{
+		TenantID:        ts.TenantID,
+		AppID:           ts.AppID,
+		DefaultScopes:   ts.DefaultScopes,
+		DefaultContacts: ts.DefaultContacts,
+		MetadataSchema:  ts.MetadataSchema,
+		RateLimit:       ts.RateLimit,
+		RateLimitWindow: ts.RateLimitWindow.Milliseconds(),
+		IPHandling:      string(ts.IPHandling),
+		QuotaTimezone:   ts.QuotaTimezone,
+		UniqueKeyNames:  ts.UniqueKeyNames,
+		Lockdown:        ts.Lockdown,
+		CreatedAt:       ts.CreatedAt,
+		UpdatedAt:       ts.UpdatedAt,
+	}
+}
+
+func tenantSettingsFromModel(m *tenantSettingsModel) *tenant.Settings {
+	return &tenant.Settings{
+		TenantID:        m.TenantID,
+		AppID:           m.AppID,
+		DefaultScopes:   m.DefaultScopes,
+		DefaultContacts: m.DefaultContacts.Normalize(),
+		MetadataSchema:  m.MetadataSchema,
+		RateLimit:       m.RateLimit,
+		RateLimitWindow: time.Duration(m.RateLimitWindow) * time.Millisecond,
+		IPHandling:      usage.IPHandling(m.IPHandling),
+		QuotaTimezone:   m.QuotaTimezone,
+		UniqueKeyNames:  m.UniqueKeyNames,
+		Lockdown:        m.Lockdown,
+		CreatedAt:       m.CreatedAt,
+		UpdatedAt:       m.UpdatedAt,
+	}
+}
+
+// revocationModel is one entry of the revocation feed.
+type revocationModel struct {
+	grove.BaseModel `grove:"table:keysmith_key_revocations"`
+	At              time.Time `grove:"at"          bson:"at"`
+	KeyID           string    `grove:"key_id"      bson:"key_id"`
+	TenantID        string    `grove:"tenant_id"   bson:"tenant_id"`
+	HashPrefix      string    `grove:"hash_prefix" bson:"hash_prefix"`
+	State           string    `grove:"state"       bson:"state"`
+	Revoked         bool      `grove:"revoked"     bson:"revoked"`
+}
+
+func revocationToModel(e *revocation.Entry) *revocationModel {
+	return &revocationModel{
+		At:         e.At.UTC(),
+		KeyID:      e.KeyID.String(),
+		TenantID:   e.TenantID,
+		HashPrefix: e.HashPrefix,
+		State:      string(e.State),
+		Revoked:    e.Revoked,
+	}
+}
+
+func revocationFromModel(m *revocationModel) (*revocation.Entry, error) {
+	kid, err := id.ParseKeyID(m.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	return &revocation.Entry{
+		KeyID:      kid,
+		TenantID:   m.TenantID,
+		HashPrefix: m.HashPrefix,
+		State:      key.State(m.State),
+		Revoked:    m.Revoked,
+		At:         m.At,
+	}, nil
+}
+
+// ──────────────────────────────────────────────────
+// Key event model
+// ──────────────────────────────────────────────────
+
+// keyEventModel is one event of the key event history.
+type keyEventModel struct {
+	grove.BaseModel `grove:"table:keysmith_key_events"`
+	At              time.Time `grove:"at"          bson:"at"`
+	KeyID           string    `grove:"key_id"      bson:"key_id"`
+	Type            string    `grove:"type"        bson:"type"`
+	TenantID        string    `grove:"tenant_id"   bson:"tenant_id"`
+	AppID           string    `grove:"app_id"      bson:"app_id"`
+	State           string    `grove:"state"       bson:"state"`
+	Reason          string    `grove:"reason"      bson:"reason,omitempty"`
+	ReasonCode      string    `grove:"reason_code" bson:"reason_code,omitempty"`
+	ActorID         string    `grove:"actor_id"    bson:"actor_id,omitempty"`
+}
+
+func keyEventToModel(e *keyevent.Event) *keyEventModel {
+	return &keyEventModel{
+		At:         e.At.UTC(),
+		KeyID:      e.KeyID.String(),
+		Type:       string(e.Type),
+		TenantID:   e.TenantID,
+		AppID:      e.AppID,
+		State:      string(e.State),
+		Reason:     e.Reason,
+		ReasonCode: e.ReasonCode,
+		ActorID:    e.ActorID,
+	}
+}
+
+func keyEventFromModel(m *keyEventModel) (*keyevent.Event, error) {
+	kid, err := id.ParseKeyID(m.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	return &keyevent.Event{
+		Type:       keyevent.Type(m.Type),
+		KeyID:      kid,
+		TenantID:   m.TenantID,
+		AppID:      m.AppID,
+		State:      key.State(m.State),
+		Reason:     m.Reason,
+		ReasonCode: m.ReasonCode,
+		ActorID:    m.ActorID,
+		At:         m.At,
+	}, nil
+}
+
+// ──────────────────────────────────────────────────
+// Capture model
+// ──────────────────────────────────────────────────
+
+// captureModel is one sampled debug request.
+type captureModel struct {
+	grove.BaseModel `grove:"table:keysmith_debug_captures"`
+	ID              string            `grove:"id,pk"          bson:"_id"`
+	KeyID           string            `grove:"key_id"         bson:"key_id"`
+	TenantID        string            `grove:"tenant_id"      bson:"tenant_id"`
+	Method          string            `grove:"method"         bson:"method"`
+	Path            string            `grove:"path"           bson:"path"`
+	Headers         map[string]string `grove:"headers"        bson:"headers,omitempty"`
+	Body            string            `grove:"body"           bson:"body,omitempty"`
+	BodyTruncated   bool              `grove:"body_truncated" bson:"body_truncated"`
+	CapturedAt      time.Time         `grove:"captured_at"    bson:"captured_at"`
+}
+
+func captureToModel(c *capture.Capture) *captureModel {
+	return &captureModel{
+		ID:            c.ID.String(),
+		KeyID:         c.KeyID.String(),
+		TenantID:      c.TenantID,
+		Method:        c.Method,
+		Path:          c.Path,
+		Headers:       c.Headers,
+		Body:          c.Body,
+		BodyTruncated: c.BodyTruncated,
+		CapturedAt:    c.CapturedAt.UTC(),
+	}
+}
+
+func captureFromModel(m *captureModel) (*capture.Capture, error) {
+	cid, err := id.ParseCaptureID(m.ID)
+	if err != nil {
+		return nil, err
+	}
+	kid, err := id.ParseKeyID(m.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	return &capture.Capture{
+		ID:            cid,
+		KeyID:         kid,
+		TenantID:      m.TenantID,
+		Method:        m.Method,
+		Path:          m.Path,
+		Headers:       m.Headers,
+		Body:          m.Body,
+		BodyTruncated: m.BodyTruncated,
+		CapturedAt:    m.CapturedAt,
+	}, nil
+}
+
+// ──────────────────────────────────────────────────
+// Group model
+// ──────────────────────────────────────────────────
+
+type groupModel struct {
+	grove.BaseModel `grove:"table:keysmith_key_groups"`
+	ID              string    `grove:"id,pk"       bson:"_id"`
+	TenantID        string    `grove:"tenant_id"   bson:"tenant_id"`
+	AppID           string    `grove:"app_id"      bson:"app_id"`
+	Name            string    `grove:"name"        bson:"name"`
+	Description     string    `grove:"description" bson:"description"`
+	CreatedAt       time.Time `grove:"created_at"  bson:"created_at"`
+	UpdatedAt       time.Time `grove:"updated_at"  bson:"updated_at"`
+}
+
+// groupMemberModel represents the join collection for group memberships.
+type groupMemberModel struct {
+	grove.BaseModel `grove:"table:keysmith_key_group_members"`
+	GroupID         string `grove:"group_id,pk" bson:"group_id"`
+	KeyID           string `grove:"key_id,pk"   bson:"key_id"`
+}
+
+func groupToModel(g *group.Group) *groupModel {
+	return &groupModel{
+		ID:          g.ID.String(),
+		TenantID:    g.TenantID,
+		AppID:       g.AppID,
+		Name:        g.Name,
+		Description: g.Description,
+		CreatedAt:   g.CreatedAt,
+		UpdatedAt:   g.UpdatedAt,
+	}
+}
+
+func groupFromModel(m *groupModel) (*group.Group, error) {
+	gid, err := id.ParseGroupID(m.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &group.Group{
+		ID:          gid,
+		TenantID:    m.TenantID,
+		AppID:       m.AppID,
+		Name:        m.Name,
+		Description: m.Description,
+		CreatedAt:   m.CreatedAt,
+		UpdatedAt:   m.UpdatedAt,
+	}, nil
+}
+
+// ──────────────────────────────────────────────────
+// Transfer model
+// ──────────────────────────────────────────────────
+
+// transferModel is one key transfer between tenants.
+type transferModel struct {
+	grove.BaseModel `grove:"table:keysmith_key_transfers"`
+	ID              string     `grove:"id,pk"            bson:"_id"`
+	KeyID           string     `grove:"key_id"           bson:"key_id"`
+	AppID           string     `grove:"app_id"           bson:"app_id"`
+	SourceTenantID  string     `grove:"source_tenant_id" bson:"source_tenant_id"`
+	TargetTenantID  string     `grove:"target_tenant_id" bson:"target_tenant_id"`
+	State           string     `grove:"state"            bson:"state"`
+	RetagUsage      bool       `grove:"retag_usage"      bson:"retag_usage"`
+	InitiatedBy     string     `grove:"initiated_by"     bson:"initiated_by,omitempty"`
+	ResolvedBy      string     `grove:"resolved_by"      bson:"resolved_by,omitempty"`
+	UnmappedScopes  []string   `grove:"unmapped_scopes"  bson:"unmapped_scopes,omitempty"`
+	UnmappedPolicy  string     `grove:"unmapped_policy"  bson:"unmapped_policy,omitempty"`
+	ExpiresAt       time.Time  `grove:"expires_at"       bson:"expires_at"`
+	CreatedAt       time.Time  `grove:"created_at"       bson:"created_at"`
+	ResolvedAt      *time.Time `grove:"resolved_at"      bson:"resolved_at,omitempty"`
+}
+
+func transferToModel(t *transfer.Transfer) *transferModel {
+	m := &transferModel{
+		ID:             t.ID.String(),
+		KeyID:          t.KeyID.String(),
+		AppID:          t.AppID,
+		SourceTenantID: t.SourceTenantID,
+		TargetTenantID: t.TargetTenantID,
+		State:          string(t.State),
+		RetagUsage:     t.RetagUsage,
+		InitiatedBy:    t.InitiatedBy,
+		ResolvedBy:     t.ResolvedBy,
+		UnmappedScopes: t.UnmappedScopes,
+		UnmappedPolicy: t.UnmappedPolicy,
+		ExpiresAt:      t.ExpiresAt.UTC(),
+		CreatedAt:      t.CreatedAt.UTC(),
+	}
+	if t.ResolvedAt != nil {
+		at := t.ResolvedAt.UTC()
+		m.ResolvedAt = &at
+	}
+	return m
+}
+
+func transferFromModel(m *transferModel) (*transfer.Transfer, error) {
+	tid, err := id.ParseTransferID(m.ID)
+	if err != nil {
+		return nil, err
+	}
+	kid, err := id.ParseKeyID(m.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	var unmapped []string
+	if len(m.UnmappedScopes) > 0 {
+		unmapped = m.UnmappedScopes
+	}
+	return &transfer.Transfer{
+		ID:             tid,
+		KeyID:          kid,
+		AppID:          m.AppID,
+		SourceTenantID: m.SourceTenantID,
+		TargetTenantID: m.TargetTenantID,
+		State:          transfer.State(m.State),
+		RetagUsage:     m.RetagUsage,
+		InitiatedBy:    m.InitiatedBy,
+		ResolvedBy:     m.ResolvedBy,
+		UnmappedScopes: unmapped,
+		UnmappedPolicy: m.UnmappedPolicy,
+		ExpiresAt:      m.ExpiresAt,
+		CreatedAt:      m.CreatedAt,
+		ResolvedAt:     m.ResolvedAt,
+	}, nil
+}
+
+// ──────────────────────────────────────────────────
+// Tombstone model
+// ──────────────────────────────────────────────────
+
+// tombstoneModel is the tombstone of one key hash.
+type tombstoneModel struct {
+	grove.BaseModel `grove:"table:keysmith_hash_tombstones"`
+	Hash            string    `grove:"hash,pk"    bson:"_id"`
+	KeyID           string    `grove:"key_id"     bson:"key_id"`
+	TenantID        string    `grove:"tenant_id"  bson:"tenant_id"`
+	AppID           string    `grove:"app_id"     bson:"app_id"`
+	Reason          string    `grove:"reason"     bson:"reason"`
+	CreatedAt       time.Time `grove:"created_at" bson:"created_at"`
+}
+
+func tombstoneToModel(t *tombstone.Tombstone) *tombstoneModel {
+	return &tombstoneModel{
+		Hash:      t.Hash,
+		KeyID:     t.KeyID.String(),
+		TenantID:  t.TenantID,
+		AppID:     t.AppID,
+		Reason:    t.Reason,
+		CreatedAt: t.CreatedAt.UTC(),
+	}
+}
+
+func tombstoneFromModel(m *tombstoneModel) (*tombstone.Tombstone, error) {
+	kid, err := id.ParseKeyID(m.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	return &tombstone.Tombstone{
+		Hash:      m.Hash,
+		KeyID:     kid,
+		TenantID:  m.TenantID,
+		AppID:     m.AppID,
+		Reason:    m.Reason,
+		CreatedAt: m.CreatedAt,
+	}, nil
+}
+
+// ──────────────────────────────────────────────────
+// Quota pool models
+// ──────────────────────────────────────────────────
+
+type quotaPoolModel struct {
+	grove.BaseModel `grove:"table:keysmith_quota_pools"`
+	ID              string    `grove:"id,pk"          bson:"_id"`
+	TenantID        string    `grove:"tenant_id"      bson:"tenant_id"`
+	AppID           string    `grove:"app_id"         bson:"app_id"`
+	Name            string    `grove:"name"           bson:"name"`
+	Description     string    `grove:"description"    bson:"description"`
+	DailyLimit      int64     `grove:"daily_limit"    bson:"daily_limit"`
+	MonthlyLimit    int64     `grove:"monthly_limit"  bson:"monthly_limit"`
+	QuotaTimezone   string    `grove:"quota_timezone" bson:"quota_timezone"`
+	CreatedAt       time.Time `grove:"created_at"     bson:"created_at"`
+	UpdatedAt       time.Time `grove:"updated_at"     bson:"updated_at"`
+}
+
+// quotaUsageModel is the validation count of one key in one quota window.
+// PoolID is empty for counts toward the key's own policy quota.
+type quotaUsageModel struct {
+	grove.BaseModel `grove:"table:keysmith_quota_usage"`
+	PoolID          string    `grove:"pool_id,pk"      bson:"pool_id"`
+	KeyID           string    `grove:"key_id,pk"       bson:"key_id"`
+	Period          string    `grove:"period,pk"       bson:"period"`
+	WindowStart     time.Time `grove:"window_start,pk" bson:"window_start"`
+	Count           int64     `grove:"count"           bson:"count"`
+}
+
+func quotaPoolToModel(p *quotapool.Pool) *quotaPoolModel {
+	return &quotaPoolModel{
+		ID:            p.ID.String(),
+		TenantID:      p.TenantID,
+		AppID:         p.AppID,
+		Name:          p.Name,
+		Description:   p.Description,
+		DailyLimit:    p.DailyLimit,
+		MonthlyLimit:  p.MonthlyLimit,
+		QuotaTimezone: p.QuotaTimezone,
+		CreatedAt:     p.CreatedAt,
+		UpdatedAt:     p.UpdatedAt,
+	}
+}
+
+func quotaPoolFromModel(m *quotaPoolModel) (*quotapool.Pool, error) {
+	pid, err := id.ParseQuotaPoolID(m.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &quotapool.Pool{
+		ID:            pid,
+		TenantID:      m.TenantID,
+		AppID:         m.AppID,
+		Name:          m.Name,
+		Description:   m.Description,
+		DailyLimit:    m.DailyLimit,
+		MonthlyLimit:  m.MonthlyLimit,
+		QuotaTimezone: m.QuotaTimezone,
+		CreatedAt:     m.CreatedAt,
+		UpdatedAt:     m.UpdatedAt,
+	}, nil
+}
+
+func quotaUsageToModel(u *quotapool.Usage) *quotaUsageModel {
+	m := &quotaUsageModel{
+		KeyID:       u.KeyID.String(),
+		Period:      string(u.Period),
+		WindowStart: u.WindowStart.UTC(),
+		Count:       u.Count,
+	}
+	if !u.PoolID.IsNil() {
+		m.PoolID = u.PoolID.String()
+	}
+	return m
+}
+
+func quotaUsageFromModel(m *quotaUsageModel) (*quotapool.Usage, error) {
+	kid, err := id.ParseKeyID(m.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	u := &quotapool.Usage{
+		KeyID:       kid,
+		Period:      quotapool.Period(m.Period),
+		WindowStart: m.WindowStart,
+		Count:       m.Count,
+	}
+	if m.PoolID != "" {
+		if u.PoolID, err = id.ParseQuotaPoolID(m.PoolID); err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
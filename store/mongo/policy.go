@@ -17,6 +17,10 @@ type policyStore struct {
 }
 
 func (s *policyStore) Create(ctx context.Context, pol *policy.Policy) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	m := policyToModel(pol)
 	_, err := s.mdb.NewInsert(m).Exec(ctx)
 	if err != nil {
@@ -26,6 +30,10 @@ func (s *policyStore) Create(ctx context.Context, pol *policy.Policy) error {
 }
 
 func (s *policyStore) Get(ctx context.Context, polID id.PolicyID) (*policy.Policy, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var m policyModel
 	err := s.mdb.NewFind(&m).
 		Filter(bson.M{"_id": polID.String()}).
@@ -40,6 +48,10 @@ func (s *policyStore) Get(ctx context.Context, polID id.PolicyID) (*policy.Polic
 }
 
 func (s *policyStore) GetByName(ctx context.Context, tenantID, name string) (*policy.Policy, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var m policyModel
 	err := s.mdb.NewFind(&m).
 		Filter(bson.M{"tenant_id": tenantID, "name": name}).
@@ -54,6 +66,10 @@ func (s *policyStore) GetByName(ctx context.Context, tenantID, name string) (*po
 }
 
 func (s *policyStore) Update(ctx context.Context, pol *policy.Policy) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	m := policyToModel(pol)
 	res, err := s.mdb.NewUpdate(m).
 		Filter(bson.M{"_id": m.ID}).
@@ -68,6 +84,10 @@ func (s *policyStore) Update(ctx context.Context, pol *policy.Policy) error {
 }
 
 func (s *policyStore) Delete(ctx context.Context, polID id.PolicyID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	res, err := s.mdb.NewDelete((*policyModel)(nil)).
 		Filter(bson.M{"_id": polID.String()}).
 		Exec(ctx)
@@ -81,13 +101,29 @@ func (s *policyStore) Delete(ctx context.Context, polID id.PolicyID) error {
 }
 
 func (s *policyStore) List(ctx context.Context, filter *policy.ListFilter) ([]*policy.Policy, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var models []policyModel
 
 	f := bson.M{}
+	if filter == nil || !filter.IncludeInline {
+		f["inline"] = bson.M{"$ne": true}
+	}
 	if filter != nil {
 		if filter.TenantID != "" {
 			f["tenant_id"] = filter.TenantID
 		}
+		if filter.AppID != "" {
+			f["app_id"] = filter.AppID
+		}
+		if created := timeRange(filter.CreatedAfter, filter.CreatedBefore); created != nil {
+			f["created_at"] = created
+		}
+		if filter.UpdatedAfter != nil {
+			f["updated_at"] = bson.M{"$gt": *filter.UpdatedAfter}
+		}
 	}
 
 	q := s.mdb.NewFind(&models).
@@ -119,11 +155,27 @@ func (s *policyStore) List(ctx context.Context, filter *policy.ListFilter) ([]*p
 }
 
 func (s *policyStore) Count(ctx context.Context, filter *policy.ListFilter) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
 	f := bson.M{}
+	if filter == nil || !filter.IncludeInline {
+		f["inline"] = bson.M{"$ne": true}
+	}
 	if filter != nil {
 		if filter.TenantID != "" {
 			f["tenant_id"] = filter.TenantID
 		}
+		if filter.AppID != "" {
+			f["app_id"] = filter.AppID
+		}
+		if created := timeRange(filter.CreatedAfter, filter.CreatedBefore); created != nil {
+			f["created_at"] = created
+		}
+		if filter.UpdatedAfter != nil {
+			f["updated_at"] = bson.M{"$gt": *filter.UpdatedAfter}
+		}
 	}
 
 	count, err := s.mdb.NewFind((*policyModel)(nil)).
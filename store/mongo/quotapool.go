@@ -0,0 +1,232 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/xraph/grove/drivers/mongodriver"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/quotapool"
+)
+
+type quotaPoolStore struct {
+	mdb *mongodriver.MongoDB
+}
+
+func (s *quotaPoolStore) Create(ctx context.Context, p *quotapool.Pool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, err := s.mdb.NewInsert(quotaPoolToModel(p)).Exec(ctx); err != nil {
+		return fmt.Errorf("keysmith/mongo: create quota pool: %w", err)
+	}
+	return nil
+}
+
+func (s *quotaPoolStore) Get(ctx context.Context, poolID id.QuotaPoolID) (*quotapool.Pool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var m quotaPoolModel
+	err := s.mdb.NewFind(&m).
+		Filter(bson.M{"_id": poolID.String()}).
+		Scan(ctx)
+	if err != nil {
+		if isNoDocuments(err) {
+			return nil, errNotFound("quota pool")
+		}
+		return nil, fmt.Errorf("keysmith/mongo: get quota pool: %w", err)
+	}
+	return quotaPoolFromModel(&m)
+}
+
+func (s *quotaPoolStore) Update(ctx context.Context, p *quotapool.Pool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m := quotaPoolToModel(p)
+	res, err := s.mdb.NewUpdate(m).
+		Filter(bson.M{"_id": m.ID}).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/mongo: update quota pool: %w", err)
+	}
+	if res.MatchedCount() == 0 {
+		return errNotFound("quota pool")
+	}
+	return nil
+}
+
+// Delete removes the pool first, so that a failure part way leaves counts
+// and member references to a pool that no longer exists, which validation
+// treats as no pool, rather than a pool with missing counts.
+func (s *quotaPoolStore) Delete(ctx context.Context, poolID id.QuotaPoolID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	pid := poolID.String()
+	res, err := s.mdb.NewDelete((*quotaPoolModel)(nil)).
+		Filter(bson.M{"_id": pid}).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/mongo: delete quota pool: %w", err)
+	}
+	if res.DeletedCount() == 0 {
+		return errNotFound("quota pool")
+	}
+	_, err = s.mdb.NewUpdate((*keyModel)(nil)).
+		Many().
+		Filter(bson.M{"pool_id": pid}).
+		Set("pool_id", nil).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/mongo: detach quota pool members: %w", err)
+	}
+	_, err = s.mdb.NewDelete((*quotaUsageModel)(nil)).
+		Many().
+		Filter(bson.M{"pool_id": pid}).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/mongo: delete quota pool usage: %w", err)
+	}
+	return nil
+}
+
+func (s *quotaPoolStore) List(ctx context.Context, filter *quotapool.ListFilter) ([]*quotapool.Pool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var models []quotaPoolModel
+
+	f := bson.M{}
+	if filter != nil {
+		if filter.TenantID != "" {
+			f["tenant_id"] = filter.TenantID
+		}
+		if filter.AppID != "" {
+			f["app_id"] = filter.AppID
+		}
+	}
+
+	q := s.mdb.NewFind(&models).
+		Filter(f).
+		Sort(bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}})
+
+	if filter != nil {
+		if filter.Limit > 0 {
+			q = q.Limit(int64(filter.Limit))
+		}
+		if filter.Offset > 0 {
+			q = q.Skip(int64(filter.Offset))
+		}
+	}
+
+	if err := q.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("keysmith/mongo: list quota pools: %w", err)
+	}
+
+	result := make([]*quotapool.Pool, 0, len(models))
+	for i := range models {
+		p, err := quotaPoolFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/mongo: convert quota pool: %w", err)
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+func (s *quotaPoolStore) AddUsage(ctx context.Context, usages []*quotapool.Usage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for _, u := range usages {
+		m := quotaUsageToModel(u)
+		_, err := s.mdb.NewUpdate((*quotaUsageModel)(nil)).
+			Filter(bson.M{
+				"pool_id":      m.PoolID,
+				"key_id":       m.KeyID,
+				"period":       m.Period,
+				"window_start": m.WindowStart,
+			}).
+			SetUpdate(bson.M{"$inc": bson.M{"count": m.Count}}).
+			Upsert().
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("keysmith/mongo: add quota usage: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *quotaPoolStore) ListUsage(ctx context.Context, filter *quotapool.UsageFilter) ([]*quotapool.Usage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	pid := ""
+	if !filter.PoolID.IsNil() {
+		pid = filter.PoolID.String()
+	}
+	f := bson.M{"pool_id": pid}
+	if filter.KeyID != nil {
+		f["key_id"] = filter.KeyID.String()
+	}
+	if filter.Period != "" {
+		f["period"] = string(filter.Period)
+	}
+	window := bson.M{}
+	if !filter.From.IsZero() {
+		window["$gte"] = filter.From.UTC()
+	}
+	if !filter.To.IsZero() {
+		window["$lt"] = filter.To.UTC()
+	}
+	if len(window) > 0 {
+		f["window_start"] = window
+	}
+
+	var models []quotaUsageModel
+	err := s.mdb.NewFind(&models).
+		Filter(f).
+		Sort(bson.D{{Key: "window_start", Value: 1}, {Key: "key_id", Value: 1}}).
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/mongo: list quota usage: %w", err)
+	}
+
+	result := make([]*quotapool.Usage, 0, len(models))
+	for i := range models {
+		u, err := quotaUsageFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/mongo: convert quota usage: %w", err)
+		}
+		result = append(result, u)
+	}
+	return result, nil
+}
+
+func (s *quotaPoolStore) PurgeUsage(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	res, err := s.mdb.NewDelete((*quotaUsageModel)(nil)).
+		Many().
+		Filter(bson.M{"window_start": bson.M{"$lt": before.UTC()}}).
+		Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("keysmith/mongo: purge quota usage: %w", err)
+	}
+	return res.DeletedCount(), nil
+}
@@ -0,0 +1,86 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/xraph/grove/drivers/mongodriver"
+
+	"github.com/xraph/keysmith/revocation"
+)
+
+type revocationStore struct {
+	mdb *mongodriver.MongoDB
+}
+
+func (s *revocationStore) Append(ctx context.Context, e *revocation.Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m := revocationToModel(e)
+	// Upsert so a retried append does not duplicate the entry.
+	_, err := s.mdb.NewUpdate(m).
+		Filter(bson.M{"at": m.At, "key_id": m.KeyID}).
+		Upsert().
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/mongo: append revocation: %w", err)
+	}
+	return nil
+}
+
+func (s *revocationStore) ListAfter(ctx context.Context, tenantID string, after revocation.Cursor, limit int) ([]*revocation.Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	at := after.At.UTC()
+	f := bson.M{"$or": bson.A{
+		bson.M{"at": bson.M{"$gt": at}},
+		bson.M{"at": at, "key_id": bson.M{"$gt": after.KeyID}},
+	}}
+	if tenantID != "" {
+		f["tenant_id"] = tenantID
+	}
+
+	var models []revocationModel
+	q := s.mdb.NewFind(&models).
+		Filter(f).
+		Sort(bson.D{{Key: "at", Value: 1}, {Key: "key_id", Value: 1}})
+	if limit > 0 {
+		q = q.Limit(int64(limit))
+	}
+
+	if err := q.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("keysmith/mongo: list revocations: %w", err)
+	}
+
+	result := make([]*revocation.Entry, 0, len(models))
+	for i := range models {
+		e, err := revocationFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/mongo: convert revocation: %w", err)
+		}
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+func (s *revocationStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	res, err := s.mdb.NewDelete((*revocationModel)(nil)).
+		Many().
+		Filter(bson.M{"at": bson.M{"$lt": before.UTC()}}).
+		Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("keysmith/mongo: purge revocations: %w", err)
+	}
+	return res.DeletedCount(), nil
+}
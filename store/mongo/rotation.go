@@ -18,6 +18,10 @@ type rotationStore struct {
 }
 
 func (s *rotationStore) Create(ctx context.Context, rec *rotation.Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	m := rotationToModel(rec)
 	_, err := s.mdb.NewInsert(m).Exec(ctx)
 	if err != nil {
@@ -27,6 +31,10 @@ func (s *rotationStore) Create(ctx context.Context, rec *rotation.Record) error
 }
 
 func (s *rotationStore) Get(ctx context.Context, rotID id.RotationID) (*rotation.Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var m rotationModel
 	err := s.mdb.NewFind(&m).
 		Filter(bson.M{"_id": rotID.String()}).
@@ -41,23 +49,14 @@ func (s *rotationStore) Get(ctx context.Context, rotID id.RotationID) (*rotation
 }
 
 func (s *rotationStore) List(ctx context.Context, filter *rotation.ListFilter) ([]*rotation.Record, error) {
-	var models []rotationModel
-
-	f := bson.M{}
-	if filter != nil {
-		if filter.KeyID != nil {
-			f["key_id"] = filter.KeyID.String()
-		}
-		if filter.TenantID != "" {
-			f["tenant_id"] = filter.TenantID
-		}
-		if filter.Reason != "" {
-			f["reason"] = string(filter.Reason)
-		}
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
+	var models []rotationModel
+
 	q := s.mdb.NewFind(&models).
-		Filter(f).
+		Filter(rotationFilter(filter)).
 		Sort(bson.D{{Key: "created_at", Value: -1}})
 
 	if filter != nil {
@@ -85,6 +84,10 @@ func (s *rotationStore) List(ctx context.Context, filter *rotation.ListFilter) (
 }
 
 func (s *rotationStore) ListPendingGrace(ctx context.Context, now time.Time) ([]*rotation.Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var models []rotationModel
 	err := s.mdb.NewFind(&models).
 		Filter(bson.M{"grace_ends": bson.M{"$gt": now}}).
@@ -106,6 +109,10 @@ func (s *rotationStore) ListPendingGrace(ctx context.Context, now time.Time) ([]
 }
 
 func (s *rotationStore) LatestForKey(ctx context.Context, keyID id.KeyID) (*rotation.Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var m rotationModel
 	err := s.mdb.NewFind(&m).
 		Filter(bson.M{"key_id": keyID.String()}).
@@ -120,3 +127,56 @@ func (s *rotationStore) LatestForKey(ctx context.Context, keyID id.KeyID) (*rota
 	}
 	return rotationFromModel(&m)
 }
+
+func (s *rotationStore) CountByReason(ctx context.Context, filter *rotation.ListFilter) ([]*rotation.ReasonCount, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	cur, err := s.mdb.Collection(colRotations).Aggregate(ctx, bson.A{
+		bson.M{"$match": rotationFilter(filter)},
+		bson.M{"$group": bson.M{"_id": "$reason", "count": bson.M{"$sum": 1}}},
+		bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/mongo: count rotations by reason: %w", err)
+	}
+	var out []struct {
+		Reason string `bson:"_id"`
+		Count  int64  `bson:"count"`
+	}
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, fmt.Errorf("keysmith/mongo: count rotations by reason: %w", err)
+	}
+
+	result := make([]*rotation.ReasonCount, len(out))
+	for i, o := range out {
+		result[i] = &rotation.ReasonCount{Reason: rotation.Reason(o.Reason), Count: o.Count}
+	}
+	return result, nil
+}
+
+// rotationFilter builds the query document for filter, without its
+// pagination.
+func rotationFilter(filter *rotation.ListFilter) bson.M {
+	f := bson.M{}
+	if filter == nil {
+		return f
+	}
+	if filter.KeyID != nil {
+		f["key_id"] = filter.KeyID.String()
+	}
+	if filter.TenantID != "" {
+		f["tenant_id"] = filter.TenantID
+	}
+	if filter.AppID != "" {
+		f["app_id"] = filter.AppID
+	}
+	if filter.Reason != "" {
+		f["reason"] = string(filter.Reason)
+	}
+	if created := timeRange(filter.CreatedAfter, filter.CreatedBefore); created != nil {
+		f["created_at"] = created
+	}
+	return f
+}
@@ -0,0 +1,90 @@
+//go:build integration
+
+package mongo_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/grove"
+	"github.com/xraph/grove/drivers/mongodriver"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/store/mongo"
+	"github.com/xraph/keysmith/store/storetest"
+)
+
+// TestCountByReason runs against the database in KEYSMITH_TEST_MONGO_URI,
+// which must name a database.
+func TestCountByReason(t *testing.T) {
+	uri := os.Getenv("KEYSMITH_TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("KEYSMITH_TEST_MONGO_URI is not set")
+	}
+	ctx := context.Background()
+	d := mongodriver.New()
+	require.NoError(t, d.Open(ctx, uri))
+	db, err := grove.Open(d)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	s := mongo.New(db)
+	require.NoError(t, s.Migrate(ctx))
+	tenant := "rotcount-" + id.NewKeyID().String()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	k := &key.Key{
+		ID: id.NewKeyID(), TenantID: tenant, KeyHash: "rotcount-" + id.NewKeyID().String(), Prefix: "sk",
+		Environment: key.EnvTest, State: key.StateActive, CreatedAt: now, UpdatedAt: now,
+	}
+	require.NoError(t, s.Keys().Create(ctx, k))
+	t.Cleanup(func() { _ = s.Keys().Delete(ctx, k.ID) })
+	for i, reason := range []rotation.Reason{rotation.ReasonManual, rotation.ReasonCompromise, rotation.ReasonManual, "legacy-reason"} {
+		at := now.Add(time.Duration(i-4) * time.Hour)
+		require.NoError(t, s.Rotations().Create(ctx, &rotation.Record{
+			ID: id.NewRotationID(), KeyID: k.ID, TenantID: tenant, Reason: reason, GraceEnds: at, CreatedAt: at,
+		}))
+	}
+
+	counts, err := s.Rotations().CountByReason(ctx, &rotation.ListFilter{TenantID: tenant})
+	require.NoError(t, err)
+	assert.Equal(t, []*rotation.ReasonCount{
+		{Reason: rotation.ReasonManual, Count: 2},
+		{Reason: rotation.ReasonCompromise, Count: 1},
+		{Reason: "legacy-reason", Count: 1},
+	}, counts)
+
+	after := now.Add(-3*time.Hour - time.Minute)
+	counts, err = s.Rotations().CountByReason(ctx, &rotation.ListFilter{TenantID: tenant, CreatedAfter: &after})
+	require.NoError(t, err)
+	assert.Equal(t, []*rotation.ReasonCount{
+		{Reason: rotation.ReasonCompromise, Count: 1},
+		{Reason: "legacy-reason", Count: 1},
+		{Reason: rotation.ReasonManual, Count: 1},
+	}, counts)
+}
+
+// TestRotationGraceHash runs against the database in
+// KEYSMITH_TEST_MONGO_URI, which must name a database.
+func TestRotationGraceHash(t *testing.T) {
+	uri := os.Getenv("KEYSMITH_TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("KEYSMITH_TEST_MONGO_URI is not set")
+	}
+	ctx := context.Background()
+	d := mongodriver.New()
+	require.NoError(t, d.Open(ctx, uri))
+	db, err := grove.Open(d)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	s := mongo.New(db)
+	require.NoError(t, s.Migrate(ctx))
+	storetest.CheckRotationGraceHash(t, s, "grace-"+id.NewKeyID().String())
+}
@@ -17,6 +17,10 @@ type scopeStore struct {
 }
 
 func (s *scopeStore) Create(ctx context.Context, sc *scope.Scope) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	m := scopeToModel(sc)
 	_, err := s.mdb.NewInsert(m).Exec(ctx)
 	if err != nil {
@@ -26,6 +30,10 @@ func (s *scopeStore) Create(ctx context.Context, sc *scope.Scope) error {
 }
 
 func (s *scopeStore) Get(ctx context.Context, scopeID id.ScopeID) (*scope.Scope, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var m scopeModel
 	err := s.mdb.NewFind(&m).
 		Filter(bson.M{"_id": scopeID.String()}).
@@ -40,6 +48,10 @@ func (s *scopeStore) Get(ctx context.Context, scopeID id.ScopeID) (*scope.Scope,
 }
 
 func (s *scopeStore) GetByName(ctx context.Context, tenantID, name string) (*scope.Scope, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var m scopeModel
 	err := s.mdb.NewFind(&m).
 		Filter(bson.M{"tenant_id": tenantID, "name": name}).
@@ -54,6 +66,10 @@ func (s *scopeStore) GetByName(ctx context.Context, tenantID, name string) (*sco
 }
 
 func (s *scopeStore) Update(ctx context.Context, sc *scope.Scope) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	m := scopeToModel(sc)
 	res, err := s.mdb.NewUpdate(m).
 		Filter(bson.M{"_id": m.ID}).
@@ -68,6 +84,10 @@ func (s *scopeStore) Update(ctx context.Context, sc *scope.Scope) error {
 }
 
 func (s *scopeStore) Delete(ctx context.Context, scopeID id.ScopeID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	res, err := s.mdb.NewDelete((*scopeModel)(nil)).
 		Filter(bson.M{"_id": scopeID.String()}).
 		Exec(ctx)
@@ -81,6 +101,10 @@ func (s *scopeStore) Delete(ctx context.Context, scopeID id.ScopeID) error {
 }
 
 func (s *scopeStore) List(ctx context.Context, filter *scope.ListFilter) ([]*scope.Scope, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var models []scopeModel
 
 	f := bson.M{}
@@ -88,9 +112,15 @@ func (s *scopeStore) List(ctx context.Context, filter *scope.ListFilter) ([]*sco
 		if filter.TenantID != "" {
 			f["tenant_id"] = filter.TenantID
 		}
+		if filter.AppID != "" {
+			f["app_id"] = filter.AppID
+		}
 		if filter.Parent != "" {
 			f["parent"] = filter.Parent
 		}
+		if filter.CreatedAfter != nil {
+			f["created_at"] = bson.M{"$gt": *filter.CreatedAfter}
+		}
 	}
 
 	q := s.mdb.NewFind(&models).
@@ -122,6 +152,10 @@ func (s *scopeStore) List(ctx context.Context, filter *scope.ListFilter) ([]*sco
 }
 
 func (s *scopeStore) ListByKey(ctx context.Context, keyID id.KeyID) ([]*scope.Scope, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	// First, find all scope IDs assigned to this key.
 	var keyScopeModels []keyScopeModel
 	err := s.mdb.NewFind(&keyScopeModels).
@@ -162,6 +196,10 @@ func (s *scopeStore) ListByKey(ctx context.Context, keyID id.KeyID) ([]*scope.Sc
 }
 
 func (s *scopeStore) AssignToKey(ctx context.Context, keyID id.KeyID, scopeNames []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	if len(scopeNames) == 0 {
 		return nil
 	}
@@ -178,7 +216,10 @@ func (s *scopeStore) AssignToKey(ctx context.Context, keyID id.KeyID, scopeNames
 		return fmt.Errorf("keysmith/mongo: lookup key: %w", err)
 	}
 
+	// Every name is looked up before any is assigned, so an unknown name
+	// assigns nothing, as in the SQL stores' transactions.
 	kid := keyID.String()
+	scopeIDs := make([]string, 0, len(scopeNames))
 	for _, name := range scopeNames {
 		var sc scopeModel
 		err := s.mdb.NewFind(&sc).
@@ -190,21 +231,28 @@ func (s *scopeStore) AssignToKey(ctx context.Context, keyID id.KeyID, scopeNames
 			}
 			return fmt.Errorf("keysmith/mongo: lookup scope %q: %w", name, err)
 		}
+		scopeIDs = append(scopeIDs, sc.ID)
+	}
 
-		m := &keyScopeModel{KeyID: kid, ScopeID: sc.ID}
+	for _, scopeID := range scopeIDs {
+		m := &keyScopeModel{KeyID: kid, ScopeID: scopeID}
 		// Use upsert to handle duplicates gracefully.
 		_, err = s.mdb.NewUpdate(m).
-			Filter(bson.M{"key_id": kid, "scope_id": sc.ID}).
+			Filter(bson.M{"key_id": kid, "scope_id": scopeID}).
 			Upsert().
 			Exec(ctx)
 		if err != nil {
 			return fmt.Errorf("keysmith/mongo: assign scope: %w", err)
 		}
 	}
-	return nil
+	return s.touchKey(ctx, kid)
 }
 
 func (s *scopeStore) RemoveFromKey(ctx context.Context, keyID id.KeyID, scopeNames []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	if len(scopeNames) == 0 {
 		return nil
 	}
@@ -241,5 +289,18 @@ func (s *scopeStore) RemoveFromKey(ctx context.Context, keyID id.KeyID, scopeNam
 			return fmt.Errorf("keysmith/mongo: remove scope: %w", err)
 		}
 	}
+	return s.touchKey(ctx, kid)
+}
+
+// touchKey bumps a key's updated_at, so scope changes show up in
+// incremental key listings.
+func (s *scopeStore) touchKey(ctx context.Context, kid string) error {
+	_, err := s.mdb.NewUpdate((*keyModel)(nil)).
+		Filter(bson.M{"_id": kid}).
+		Set("updated_at", now()).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/mongo: touch key: %w", err)
+	}
 	return nil
 }
@@ -0,0 +1,107 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store"
+)
+
+var _ store.StorageReporter = (*Store)(nil)
+
+// TenantStorage counts each tenant's documents with one $group per
+// collection. UsageBytes sums the $bsonSize of the tenant's usage
+// documents, which needs MongoDB 4.4 or later and leaves out indexes.
+func (s *Store) TenantStorage(ctx context.Context, tenantID string) ([]*store.TenantStorage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	match := bson.M{}
+	if tenantID != "" {
+		match["tenant_id"] = tenantID
+	}
+	byTenant := make(map[string]*store.TenantStorage)
+	get := func(tid string) *store.TenantStorage {
+		ts, ok := byTenant[tid]
+		if !ok {
+			ts = &store.TenantStorage{TenantID: tid, KeysByState: make(map[key.State]int64)}
+			byTenant[tid] = ts
+		}
+		return ts
+	}
+
+	cur, err := s.mdb.Collection(colKeys).Aggregate(ctx, bson.A{
+		bson.M{"$match": match},
+		bson.M{"$group": bson.M{
+			"_id":   bson.M{"tenant_id": "$tenant_id", "state": "$state"},
+			"count": bson.M{"$sum": 1},
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/mongo: count keys by tenant: %w", err)
+	}
+	var keys []struct {
+		ID struct {
+			TenantID string `bson:"tenant_id"`
+			State    string `bson:"state"`
+		} `bson:"_id"`
+		Count int64 `bson:"count"`
+	}
+	if err := cur.All(ctx, &keys); err != nil {
+		return nil, fmt.Errorf("keysmith/mongo: count keys by tenant: %w", err)
+	}
+	for _, k := range keys {
+		ts := get(k.ID.TenantID)
+		ts.Keys += k.Count
+		ts.KeysByState[key.State(k.ID.State)] += k.Count
+	}
+
+	for _, c := range []struct {
+		col   string
+		size  bool
+		count func(ts *store.TenantStorage, n, size int64)
+	}{
+		{colPolicies, false, func(ts *store.TenantStorage, n, _ int64) { ts.Policies += n }},
+		{colScopes, false, func(ts *store.TenantStorage, n, _ int64) { ts.Scopes += n }},
+		{colRotations, false, func(ts *store.TenantStorage, n, _ int64) { ts.Rotations += n }},
+		{colUsage, true, func(ts *store.TenantStorage, n, size int64) {
+			ts.UsageRows += n
+			ts.UsageBytes += size
+		}},
+	} {
+		group := bson.M{"_id": "$tenant_id", "count": bson.M{"$sum": 1}}
+		if c.size {
+			group["size"] = bson.M{"$sum": bson.M{"$bsonSize": "$$ROOT"}}
+		}
+		cur, err := s.mdb.Collection(c.col).Aggregate(ctx, bson.A{
+			bson.M{"$match": match},
+			bson.M{"$group": group},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/mongo: count %s by tenant: %w", c.col, err)
+		}
+		var out []struct {
+			TenantID string `bson:"_id"`
+			Count    int64  `bson:"count"`
+			Size     int64  `bson:"size"`
+		}
+		if err := cur.All(ctx, &out); err != nil {
+			return nil, fmt.Errorf("keysmith/mongo: count %s by tenant: %w", c.col, err)
+		}
+		for _, o := range out {
+			c.count(get(o.TenantID), o.Count, o.Size)
+		}
+	}
+
+	list := make([]*store.TenantStorage, 0, len(byTenant))
+	for _, ts := range byTenant {
+		list = append(list, ts)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].TenantID < list[j].TenantID })
+	return list, nil
+}
@@ -13,11 +13,19 @@ import (
 	"github.com/xraph/grove"
 	"github.com/xraph/grove/drivers/mongodriver"
 
+	"github.com/xraph/keysmith/capture"
+	"github.com/xraph/keysmith/group"
 	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keyevent"
 	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/quotapool"
+	"github.com/xraph/keysmith/revocation"
 	"github.com/xraph/keysmith/rotation"
 	"github.com/xraph/keysmith/scope"
 	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/tenant"
+	"github.com/xraph/keysmith/tombstone"
+	"github.com/xraph/keysmith/transfer"
 	"github.com/xraph/keysmith/usage"
 )
 
@@ -30,6 +38,22 @@ const (
 	colUsage     = "keysmith_usage"
 	colUsageAgg  = "keysmith_usage_agg"
 	colRotations = "keysmith_rotations"
+
+	colKeyHashes    = "keysmith_key_hashes"
+	colEndpointSeen = "keysmith_key_endpoint_seen"
+	colRevocations  = "keysmith_key_revocations"
+	colKeyEvents    = "keysmith_key_events"
+	colCaptures     = "keysmith_debug_captures"
+	colGroups       = "keysmith_key_groups"
+	colGroupMembers = "keysmith_key_group_members"
+	colTransfers    = "keysmith_key_transfers"
+	colTombstones   = "keysmith_hash_tombstones"
+	colQuotaPools   = "keysmith_quota_pools"
+	colQuotaUsage   = "keysmith_quota_usage"
+
+	colTenantRevisions = "keysmith_tenant_revisions"
+	colLocks           = "keysmith_locks"
+	colMeta            = "keysmith_meta"
 )
 
 // compile-time interface check
@@ -67,8 +91,36 @@ func (s *Store) Rotations() rotation.Store { return &rotationStore{mdb: s.mdb} }
 // Scopes returns the scope store.
 func (s *Store) Scopes() scope.Store { return &scopeStore{mdb: s.mdb} }
 
+// Tenants returns the tenant settings store.
+func (s *Store) Tenants() tenant.Store { return &tenantStore{mdb: s.mdb} }
+
+// Revocations returns the revocation feed store.
+func (s *Store) Revocations() revocation.Store { return &revocationStore{mdb: s.mdb} }
+
+// Captures returns the debug capture store.
+func (s *Store) Captures() capture.Store { return &captureStore{mdb: s.mdb} }
+
+// Groups returns the key group store.
+func (s *Store) Groups() group.Store { return &groupStore{mdb: s.mdb} }
+
+// KeyEvents returns the key event history store.
+func (s *Store) KeyEvents() keyevent.Store { return &keyEventStore{mdb: s.mdb} }
+
+// Transfers returns the key transfer store.
+func (s *Store) Transfers() transfer.Store { return &transferStore{mdb: s.mdb} }
+
+// Tombstones returns the hash tombstone store.
+func (s *Store) Tombstones() tombstone.Store { return &tombstoneStore{mdb: s.mdb} }
+
+// QuotaPools returns the quota pool store.
+func (s *Store) QuotaPools() quotapool.Store { return &quotaPoolStore{mdb: s.mdb} }
+
 // Migrate creates indexes for all keysmith collections.
 func (s *Store) Migrate(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	indexes := migrationIndexes()
 
 	for col, models := range indexes {
@@ -87,6 +139,10 @@ func (s *Store) Migrate(ctx context.Context) error {
 
 // Ping checks database connectivity.
 func (s *Store) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	return s.db.Ping(ctx)
 }
 
@@ -121,9 +177,12 @@ func migrationIndexes() map[string][]mongod.IndexModel {
 			},
 			{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "state", Value: 1}}},
 			{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "environment", Value: 1}}},
+			{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "app_id", Value: 1}}},
 			{Keys: bson.D{{Key: "prefix", Value: 1}, {Key: "hint", Value: 1}}},
 			{Keys: bson.D{{Key: "policy_id", Value: 1}}},
+			{Keys: bson.D{{Key: "pool_id", Value: 1}}, Options: options.Index().SetSparse(true)},
 			{Keys: bson.D{{Key: "expires_at", Value: 1}}},
+			{Keys: bson.D{{Key: "labels.name", Value: 1}, {Key: "labels.value", Value: 1}}},
 		},
 		colPolicies: {
 			{
@@ -131,6 +190,7 @@ func migrationIndexes() map[string][]mongod.IndexModel {
 				Options: options.Index().SetUnique(true),
 			},
 			{Keys: bson.D{{Key: "tenant_id", Value: 1}}},
+			{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "app_id", Value: 1}}},
 		},
 		colScopes: {
 			{
@@ -138,6 +198,7 @@ func migrationIndexes() map[string][]mongod.IndexModel {
 				Options: options.Index().SetUnique(true),
 			},
 			{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "parent", Value: 1}}},
+			{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "app_id", Value: 1}}},
 		},
 		colKeyScopes: {
 			{
@@ -150,6 +211,7 @@ func migrationIndexes() map[string][]mongod.IndexModel {
 		colUsage: {
 			{Keys: bson.D{{Key: "key_id", Value: 1}, {Key: "created_at", Value: -1}}},
 			{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "created_at", Value: -1}}},
+			{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "app_id", Value: 1}, {Key: "created_at", Value: -1}}},
 		},
 		colUsageAgg: {
 			{
@@ -160,7 +222,73 @@ func migrationIndexes() map[string][]mongod.IndexModel {
 		},
 		colRotations: {
 			{Keys: bson.D{{Key: "key_id", Value: 1}, {Key: "created_at", Value: -1}}},
+			{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "created_at", Value: -1}}},
 			{Keys: bson.D{{Key: "grace_ends", Value: 1}}},
 		},
+		colKeyHashes: {
+			{Keys: bson.D{{Key: "key_id", Value: 1}, {Key: "created_at", Value: 1}}},
+		},
+		colEndpointSeen: {
+			{
+				Keys:    bson.D{{Key: "key_id", Value: 1}, {Key: "endpoint", Value: 1}, {Key: "method", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "key_id", Value: 1}, {Key: "last_seen_at", Value: -1}}},
+		},
+		colRevocations: {
+			{
+				Keys:    bson.D{{Key: "at", Value: 1}, {Key: "key_id", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "at", Value: 1}, {Key: "key_id", Value: 1}}},
+		},
+		colKeyEvents: {
+			{
+				Keys:    bson.D{{Key: "at", Value: 1}, {Key: "key_id", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "at", Value: 1}, {Key: "key_id", Value: 1}}},
+		},
+		colCaptures: {
+			{Keys: bson.D{{Key: "key_id", Value: 1}, {Key: "captured_at", Value: -1}}},
+			{Keys: bson.D{{Key: "captured_at", Value: 1}}},
+		},
+		colLocks: {
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		colGroups: {
+			{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "app_id", Value: 1}, {Key: "name", Value: 1}}},
+		},
+		colGroupMembers: {
+			{
+				Keys:    bson.D{{Key: "group_id", Value: 1}, {Key: "key_id", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "key_id", Value: 1}}},
+		},
+		colTransfers: {
+			{
+				Keys: bson.D{{Key: "key_id", Value: 1}},
+				Options: options.Index().SetUnique(true).
+					SetPartialFilterExpression(bson.M{"state": "pending"}),
+			},
+			{Keys: bson.D{{Key: "source_tenant_id", Value: 1}, {Key: "created_at", Value: -1}}},
+			{Keys: bson.D{{Key: "target_tenant_id", Value: 1}, {Key: "created_at", Value: -1}}},
+			{Keys: bson.D{{Key: "state", Value: 1}, {Key: "expires_at", Value: 1}}},
+		},
+		colTombstones: {
+			{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "created_at", Value: -1}}},
+			{Keys: bson.D{{Key: "created_at", Value: 1}}},
+		},
+		colQuotaPools: {
+			{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "app_id", Value: 1}, {Key: "name", Value: 1}}},
+		},
+		colQuotaUsage: {
+			{
+				Keys:    bson.D{{Key: "pool_id", Value: 1}, {Key: "key_id", Value: 1}, {Key: "period", Value: 1}, {Key: "window_start", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "window_start", Value: 1}}},
+		},
 	}
 }
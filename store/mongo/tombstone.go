@@ -0,0 +1,149 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	mongod "go.mongodb.org/mongo-driver/v2/mongo"
+
+	"github.com/xraph/grove/drivers/mongodriver"
+
+	"github.com/xraph/keysmith/tombstone"
+)
+
+type tombstoneStore struct {
+	mdb *mongodriver.MongoDB
+}
+
+// Create keys the document by hash, so a second tombstone for the hash is a
+// duplicate key error and the first is kept.
+func (s *tombstoneStore) Create(ctx context.Context, t *tombstone.Tombstone) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, err := s.mdb.NewInsert(tombstoneToModel(t)).Exec(ctx); err != nil {
+		if mongod.IsDuplicateKeyError(err) {
+			return nil
+		}
+		return fmt.Errorf("keysmith/mongo: create tombstone: %w", err)
+	}
+	return nil
+}
+
+func (s *tombstoneStore) Find(ctx context.Context, hashes []string) ([]*tombstone.Tombstone, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+	var models []tombstoneModel
+	err := s.mdb.NewFind(&models).
+		Filter(bson.M{"_id": bson.M{"$in": hashes}}).
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/mongo: find tombstones: %w", err)
+	}
+	return tombstonesFromModels(models)
+}
+
+func (s *tombstoneStore) List(ctx context.Context, filter *tombstone.ListFilter) ([]*tombstone.Tombstone, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var models []tombstoneModel
+	q := s.mdb.NewFind(&models).
+		Filter(tombstoneFilter(filter)).
+		Sort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	if filter != nil {
+		if filter.Limit > 0 {
+			q = q.Limit(int64(filter.Limit))
+		}
+		if filter.Offset > 0 {
+			q = q.Skip(int64(filter.Offset))
+		}
+	}
+	if err := q.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("keysmith/mongo: list tombstones: %w", err)
+	}
+	return tombstonesFromModels(models)
+}
+
+func (s *tombstoneStore) CountByReason(ctx context.Context, filter *tombstone.ListFilter) ([]*tombstone.ReasonCount, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	cur, err := s.mdb.Collection(colTombstones).Aggregate(ctx, bson.A{
+		bson.M{"$match": tombstoneFilter(filter)},
+		bson.M{"$group": bson.M{"_id": "$reason", "count": bson.M{"$sum": 1}}},
+		bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/mongo: count tombstones by reason: %w", err)
+	}
+	var out []struct {
+		Reason string `bson:"_id"`
+		Count  int64  `bson:"count"`
+	}
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, fmt.Errorf("keysmith/mongo: count tombstones by reason: %w", err)
+	}
+
+	result := make([]*tombstone.ReasonCount, len(out))
+	for i, o := range out {
+		result[i] = &tombstone.ReasonCount{Reason: o.Reason, Count: o.Count}
+	}
+	return result, nil
+}
+
+func (s *tombstoneStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	res, err := s.mdb.NewDelete((*tombstoneModel)(nil)).
+		Many().
+		Filter(bson.M{"created_at": bson.M{"$lt": before.UTC()}}).
+		Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("keysmith/mongo: purge tombstones: %w", err)
+	}
+	return res.DeletedCount(), nil
+}
+
+// tombstoneFilter builds the query document for filter, without its
+// pagination.
+func tombstoneFilter(filter *tombstone.ListFilter) bson.M {
+	f := bson.M{}
+	if filter == nil {
+		return f
+	}
+	if filter.TenantID != "" {
+		f["tenant_id"] = filter.TenantID
+	}
+	if filter.AppID != "" {
+		f["app_id"] = filter.AppID
+	}
+	if filter.Reason != "" {
+		f["reason"] = filter.Reason
+	}
+	return f
+}
+
+func tombstonesFromModels(models []tombstoneModel) ([]*tombstone.Tombstone, error) {
+	result := make([]*tombstone.Tombstone, 0, len(models))
+	for i := range models {
+		t, err := tombstoneFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/mongo: convert tombstone: %w", err)
+		}
+		result = append(result, t)
+	}
+	return result, nil
+}
@@ -0,0 +1,240 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	mongod "go.mongodb.org/mongo-driver/v2/mongo"
+
+	"github.com/xraph/grove/drivers/mongodriver"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/transfer"
+)
+
+type transferStore struct {
+	mdb *mongodriver.MongoDB
+}
+
+// Create relies on the unique partial index over pending transfers' key_id
+// to reject a second pending transfer for the key.
+func (s *transferStore) Create(ctx context.Context, t *transfer.Transfer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, err := s.mdb.NewInsert(transferToModel(t)).Exec(ctx); err != nil {
+		if mongod.IsDuplicateKeyError(err) {
+			return transfer.ErrPendingTransfer
+		}
+		return fmt.Errorf("keysmith/mongo: create transfer: %w", err)
+	}
+	return nil
+}
+
+func (s *transferStore) Get(ctx context.Context, transferID id.TransferID) (*transfer.Transfer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var m transferModel
+	err := s.mdb.NewFind(&m).
+		Filter(bson.M{"_id": transferID.String()}).
+		Scan(ctx)
+	if err != nil {
+		if isNoDocuments(err) {
+			return nil, errNotFound("transfer")
+		}
+		return nil, fmt.Errorf("keysmith/mongo: get transfer: %w", err)
+	}
+	return transferFromModel(&m)
+}
+
+func (s *transferStore) GetPending(ctx context.Context, keyID id.KeyID) (*transfer.Transfer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var m transferModel
+	err := s.mdb.NewFind(&m).
+		Filter(bson.M{"key_id": keyID.String(), "state": string(transfer.StatePending)}).
+		Scan(ctx)
+	if err != nil {
+		if isNoDocuments(err) {
+			return nil, errNotFound("transfer")
+		}
+		return nil, fmt.Errorf("keysmith/mongo: get pending transfer: %w", err)
+	}
+	return transferFromModel(&m)
+}
+
+func (s *transferStore) List(ctx context.Context, filter *transfer.ListFilter) ([]*transfer.Transfer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var models []transferModel
+
+	f := bson.M{}
+	if filter != nil {
+		if filter.TenantID != "" {
+			f["$or"] = bson.A{
+				bson.M{"source_tenant_id": filter.TenantID},
+				bson.M{"target_tenant_id": filter.TenantID},
+			}
+		}
+		if filter.AppID != "" {
+			f["app_id"] = filter.AppID
+		}
+		if filter.KeyID != nil {
+			f["key_id"] = filter.KeyID.String()
+		}
+		if filter.State != "" {
+			f["state"] = string(filter.State)
+		}
+		if filter.ExpiresBefore != nil {
+			f["expires_at"] = bson.M{"$lt": filter.ExpiresBefore.UTC()}
+		}
+	}
+
+	q := s.mdb.NewFind(&models).
+		Filter(f).
+		Sort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+
+	if filter != nil {
+		if filter.Limit > 0 {
+			q = q.Limit(int64(filter.Limit))
+		}
+		if filter.Offset > 0 {
+			q = q.Skip(int64(filter.Offset))
+		}
+	}
+
+	if err := q.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("keysmith/mongo: list transfers: %w", err)
+	}
+
+	result := make([]*transfer.Transfer, 0, len(models))
+	for i := range models {
+		t, err := transferFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/mongo: convert transfer: %w", err)
+		}
+		result = append(result, t)
+	}
+	return result, nil
+}
+
+func (s *transferStore) Resolve(ctx context.Context, transferID id.TransferID, state transfer.State, by string, at time.Time) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	res, err := s.mdb.NewUpdate((*transferModel)(nil)).
+		Filter(bson.M{"_id": transferID.String(), "state": string(transfer.StatePending)}).
+		Set("state", string(state)).
+		Set("resolved_by", by).
+		Set("resolved_at", at.UTC()).
+		Exec(ctx)
+	if err != nil {
+		return false, fmt.Errorf("keysmith/mongo: resolve transfer: %w", err)
+	}
+	return res.MatchedCount() > 0, nil
+}
+
+// Accept claims the transfer with a conditional update first, so that of
+// concurrent accepts and cancels exactly one wins, then moves the key. The
+// steps are not transactional: a failure after the claim leaves the
+// transfer accepted with the key partly moved, and is returned.
+func (s *transferStore) Accept(ctx context.Context, t *transfer.Transfer, remap *transfer.Remap) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	m := transferToModel(t)
+	res, err := s.mdb.NewUpdate((*transferModel)(nil)).
+		Filter(bson.M{"_id": m.ID, "state": string(transfer.StatePending)}).
+		Set("state", string(transfer.StateAccepted)).
+		Set("resolved_by", m.ResolvedBy).
+		Set("resolved_at", m.ResolvedAt).
+		Set("unmapped_scopes", m.UnmappedScopes).
+		Set("unmapped_policy", m.UnmappedPolicy).
+		Exec(ctx)
+	if err != nil {
+		return false, fmt.Errorf("keysmith/mongo: accept transfer: %w", err)
+	}
+	if res.MatchedCount() == 0 {
+		return false, nil
+	}
+
+	var policyID *string
+	if remap.PolicyID != nil {
+		pid := remap.PolicyID.String()
+		policyID = &pid
+	}
+	updatedAt := now()
+	if m.ResolvedAt != nil {
+		updatedAt = *m.ResolvedAt
+	}
+	res, err = s.mdb.NewUpdate((*keyModel)(nil)).
+		Filter(bson.M{"_id": m.KeyID}).
+		Set("tenant_id", m.TargetTenantID).
+		Set("policy_id", policyID).
+		Set("pool_id", nil).
+		Set("updated_at", updatedAt).
+		Exec(ctx)
+	if err != nil {
+		return false, fmt.Errorf("keysmith/mongo: move key: %w", err)
+	}
+	if res.MatchedCount() == 0 {
+		return false, errNotFound("key")
+	}
+
+	_, err = s.mdb.NewDelete((*keyScopeModel)(nil)).
+		Many().
+		Filter(bson.M{"key_id": m.KeyID}).
+		Exec(ctx)
+	if err != nil {
+		return false, fmt.Errorf("keysmith/mongo: clear key scopes: %w", err)
+	}
+	for _, name := range remap.Scopes {
+		var sc scopeModel
+		err := s.mdb.NewFind(&sc).
+			Filter(bson.M{"tenant_id": m.TargetTenantID, "name": name}).
+			Scan(ctx)
+		if err != nil {
+			if isNoDocuments(err) {
+				continue
+			}
+			return false, fmt.Errorf("keysmith/mongo: lookup scope %q: %w", name, err)
+		}
+		_, err = s.mdb.NewUpdate(&keyScopeModel{KeyID: m.KeyID, ScopeID: sc.ID}).
+			Filter(bson.M{"key_id": m.KeyID, "scope_id": sc.ID}).
+			Upsert().
+			Exec(ctx)
+		if err != nil {
+			return false, fmt.Errorf("keysmith/mongo: remap scope %q: %w", name, err)
+		}
+	}
+	_, err = s.mdb.NewDelete((*groupMemberModel)(nil)).
+		Many().
+		Filter(bson.M{"key_id": m.KeyID}).
+		Exec(ctx)
+	if err != nil {
+		return false, fmt.Errorf("keysmith/mongo: remove group members: %w", err)
+	}
+	if m.RetagUsage {
+		for _, col := range []string{colUsage, colUsageAgg} {
+			_, err := s.mdb.Collection(col).UpdateMany(ctx,
+				bson.M{"key_id": m.KeyID},
+				bson.M{"$set": bson.M{"tenant_id": m.TargetTenantID}},
+			)
+			if err != nil {
+				return false, fmt.Errorf("keysmith/mongo: retag %s: %w", col, err)
+			}
+		}
+	}
+	return true, nil
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/xraph/grove/drivers/mongodriver"
 
 	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
 	"github.com/xraph/keysmith/usage"
 )
 
@@ -18,6 +19,10 @@ type usageStore struct {
 }
 
 func (s *usageStore) Record(ctx context.Context, rec *usage.Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	m := usageToModel(rec)
 	_, err := s.mdb.NewInsert(m).Exec(ctx)
 	if err != nil {
@@ -27,6 +32,10 @@ func (s *usageStore) Record(ctx context.Context, rec *usage.Record) error {
 }
 
 func (s *usageStore) RecordBatch(ctx context.Context, recs []*usage.Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	if len(recs) == 0 {
 		return nil
 	}
@@ -44,6 +53,10 @@ func (s *usageStore) RecordBatch(ctx context.Context, recs []*usage.Record) erro
 }
 
 func (s *usageStore) Query(ctx context.Context, filter *usage.QueryFilter) ([]*usage.Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var models []usageModel
 
 	f := bson.M{}
@@ -54,6 +67,12 @@ func (s *usageStore) Query(ctx context.Context, filter *usage.QueryFilter) ([]*u
 		if filter.TenantID != "" {
 			f["tenant_id"] = filter.TenantID
 		}
+		if filter.AppID != "" {
+			f["app_id"] = filter.AppID
+		}
+		if filter.Environment != "" {
+			f["environment"] = string(filter.Environment)
+		}
 		if filter.After != nil || filter.Before != nil {
 			dateFilter := bson.M{}
 			if filter.After != nil {
@@ -95,6 +114,10 @@ func (s *usageStore) Query(ctx context.Context, filter *usage.QueryFilter) ([]*u
 }
 
 func (s *usageStore) Aggregate(ctx context.Context, filter *usage.QueryFilter) ([]*usage.Aggregation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var models []usageAggModel
 
 	f := bson.M{}
@@ -105,6 +128,21 @@ func (s *usageStore) Aggregate(ctx context.Context, filter *usage.QueryFilter) (
 		if filter.TenantID != "" {
 			f["tenant_id"] = filter.TenantID
 		}
+		if filter.AppID != "" {
+			f["app_id"] = filter.AppID
+		}
+		if filter.Environment != "" {
+			// Aggregates are per key, so a key's environment is theirs.
+			ids, err := s.keyIDsIn(ctx, filter.Environment)
+			if err != nil {
+				return nil, err
+			}
+			in := bson.M{"$in": ids}
+			if filter.KeyID != nil {
+				in["$eq"] = filter.KeyID.String()
+			}
+			f["key_id"] = in
+		}
 		if filter.Period != "" {
 			f["period"] = filter.Period
 		}
@@ -148,7 +186,28 @@ func (s *usageStore) Aggregate(ctx context.Context, filter *usage.QueryFilter) (
 	return result, nil
 }
 
+// keyIDsIn returns the IDs of the keys in env.
+func (s *usageStore) keyIDsIn(ctx context.Context, env key.Environment) (bson.A, error) {
+	var keys []keyModel
+	err := s.mdb.NewFind(&keys).
+		Filter(bson.M{"environment": string(env)}).
+		Project(bson.M{"_id": 1}).
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/mongo: find %s keys: %w", env, err)
+	}
+	ids := make(bson.A, len(keys))
+	for i := range keys {
+		ids[i] = keys[i].ID
+	}
+	return ids, nil
+}
+
 func (s *usageStore) Count(ctx context.Context, filter *usage.QueryFilter) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
 	f := bson.M{}
 	if filter != nil {
 		if filter.KeyID != nil {
@@ -157,6 +216,12 @@ func (s *usageStore) Count(ctx context.Context, filter *usage.QueryFilter) (int6
 		if filter.TenantID != "" {
 			f["tenant_id"] = filter.TenantID
 		}
+		if filter.AppID != "" {
+			f["app_id"] = filter.AppID
+		}
+		if filter.Environment != "" {
+			f["environment"] = string(filter.Environment)
+		}
 		if filter.After != nil || filter.Before != nil {
 			dateFilter := bson.M{}
 			if filter.After != nil {
@@ -179,6 +244,10 @@ func (s *usageStore) Count(ctx context.Context, filter *usage.QueryFilter) (int6
 }
 
 func (s *usageStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
 	res, err := s.mdb.NewDelete((*usageModel)(nil)).
 		Many().
 		Filter(bson.M{"created_at": bson.M{"$lt": before}}).
@@ -189,9 +258,12 @@ func (s *usageStore) Purge(ctx context.Context, before time.Time) (int64, error)
 	return res.DeletedCount(), nil
 }
 
-func (s *usageStore) DailyCount(ctx context.Context, keyID id.KeyID, date time.Time) (int64, error) {
-	dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
-	dayEnd := dayStart.Add(24 * time.Hour)
+func (s *usageStore) DailyCount(ctx context.Context, keyID id.KeyID, w usage.Window) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	dayStart, dayEnd := w.Start.UTC(), w.End.UTC()
 
 	count, err := s.mdb.NewFind((*usageModel)(nil)).
 		Filter(bson.M{
@@ -205,9 +277,12 @@ func (s *usageStore) DailyCount(ctx context.Context, keyID id.KeyID, date time.T
 	return count, nil
 }
 
-func (s *usageStore) MonthlyCount(ctx context.Context, keyID id.KeyID, month time.Time) (int64, error) {
-	monthStart := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
-	monthEnd := monthStart.AddDate(0, 1, 0)
+func (s *usageStore) MonthlyCount(ctx context.Context, keyID id.KeyID, w usage.Window) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	monthStart, monthEnd := w.Start.UTC(), w.End.UTC()
 
 	count, err := s.mdb.NewFind((*usageModel)(nil)).
 		Filter(bson.M{
@@ -220,3 +295,107 @@ func (s *usageStore) MonthlyCount(ctx context.Context, keyID id.KeyID, month tim
 	}
 	return count, nil
 }
+
+func (s *usageStore) UpsertEndpointActivity(ctx context.Context, acts []*usage.EndpointActivity) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for _, a := range acts {
+		_, err := s.mdb.NewUpdate((*endpointSeenModel)(nil)).
+			Filter(bson.M{
+				"key_id":   a.KeyID.String(),
+				"endpoint": a.Endpoint,
+				"method":   a.Method,
+			}).
+			SetUpdate(bson.M{
+				"$inc": bson.M{"count": a.Count},
+				"$max": bson.M{"last_seen_at": a.LastSeenAt},
+			}).
+			Upsert().
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("keysmith/mongo: upsert endpoint activity: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *usageStore) ListEndpointActivity(ctx context.Context, keyID id.KeyID) ([]*usage.EndpointActivity, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var models []endpointSeenModel
+	err := s.mdb.NewFind(&models).
+		Filter(bson.M{"key_id": keyID.String()}).
+		Sort(bson.D{{Key: "last_seen_at", Value: -1}}).
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/mongo: list endpoint activity: %w", err)
+	}
+
+	result := make([]*usage.EndpointActivity, 0, len(models))
+	for i := range models {
+		a, err := endpointSeenFromModel(&models[i])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, a)
+	}
+	return result, nil
+}
+
+func (s *usageStore) UpdateUsageIdentifiers(ctx context.Context, tenantID string, m *usage.IdentifierMatcher, limit int) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var or bson.A
+	if m.IPAddress != "" {
+		or = append(or, bson.M{"ip_address": m.IPAddress})
+	}
+	if m.UserAgent != "" {
+		or = append(or, bson.M{"user_agent": m.UserAgent})
+	}
+	f := bson.M{"tenant_id": tenantID, "$or": or}
+	if m.Before != nil {
+		f["created_at"] = bson.M{"$lt": *m.Before}
+	}
+
+	// Update has no limit, so pick the batch first and rewrite each
+	// identifier within it.
+	var batch []usageModel
+	q := s.mdb.NewFind(&batch).
+		Filter(f).
+		Project(bson.M{"_id": 1}).
+		Sort(bson.D{{Key: "created_at", Value: 1}})
+	if limit > 0 {
+		q = q.Limit(int64(limit))
+	}
+	if err := q.Scan(ctx); err != nil {
+		return 0, fmt.Errorf("keysmith/mongo: find usage identifiers: %w", err)
+	}
+	if len(batch) == 0 {
+		return 0, nil
+	}
+	ids := make(bson.A, len(batch))
+	for i := range batch {
+		ids[i] = batch[i].ID
+	}
+
+	for field, value := range map[string]string{"ip_address": m.IPAddress, "user_agent": m.UserAgent} {
+		if value == "" {
+			continue
+		}
+		_, err := s.mdb.NewUpdate((*usageModel)(nil)).
+			Many().
+			Filter(bson.M{"_id": bson.M{"$in": ids}, field: value}).
+			Set(field, usage.Redacted).
+			Exec(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("keysmith/mongo: update usage identifiers: %w", err)
+		}
+	}
+	return int64(len(batch)), nil
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xraph/grove/drivers/pgdriver"
+
+	"github.com/xraph/keysmith/capture"
+	"github.com/xraph/keysmith/id"
+)
+
+type captureStore struct {
+	db *pgdriver.PgDB
+	rs *replicaSet
+}
+
+func (s *captureStore) Record(ctx context.Context, c *capture.Capture) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, err := s.db.NewInsert(captureToModel(c)).Exec(ctx); err != nil {
+		return fmt.Errorf("keysmith/postgres: record capture: %w", err)
+	}
+	return nil
+}
+
+func (s *captureStore) List(ctx context.Context, keyID id.KeyID, limit int) ([]*capture.Capture, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var models []captureModel
+	err := s.rs.read(ctx, s.db, func(db *pgdriver.PgDB) error {
+		models = nil
+		q := db.NewSelect(&models).
+			Where("key_id = ?", keyID.String()).
+			OrderExpr("captured_at DESC")
+		if limit > 0 {
+			q = q.Limit(limit)
+		}
+		return q.Scan(ctx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/postgres: list captures: %w", err)
+	}
+
+	result := make([]*capture.Capture, 0, len(models))
+	for i := range models {
+		c, err := captureFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/postgres: convert capture: %w", err)
+		}
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+func (s *captureStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	res, err := s.db.NewDelete((*captureModel)(nil)).
+		Where("captured_at < ?", before.UTC()).
+		Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("keysmith/postgres: purge captures: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	return affected, nil
+}
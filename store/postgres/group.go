@@ -0,0 +1,222 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/xraph/grove/driver"
+	"github.com/xraph/grove/drivers/pgdriver"
+
+	"github.com/xraph/keysmith/group"
+	"github.com/xraph/keysmith/id"
+)
+
+type groupStore struct {
+	db *pgdriver.PgDB
+	rs *replicaSet
+}
+
+func (s *groupStore) Create(ctx context.Context, g *group.Group) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, err := s.db.NewInsert(groupToModel(g)).Exec(ctx); err != nil {
+		return fmt.Errorf("keysmith/postgres: create group: %w", err)
+	}
+	return nil
+}
+
+func (s *groupStore) Get(ctx context.Context, groupID id.GroupID) (*group.Group, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m := new(groupModel)
+	err := s.db.NewSelect(m).Where("id = ?", groupID.String()).Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errNotFound("group")
+		}
+		return nil, fmt.Errorf("keysmith/postgres: get group: %w", err)
+	}
+	return groupFromModel(m)
+}
+
+func (s *groupStore) Update(ctx context.Context, g *group.Group) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	res, err := s.db.NewUpdate(groupToModel(g)).WherePK().Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/postgres: update group: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return errNotFound("group")
+	}
+	return nil
+}
+
+// Delete relies on ON DELETE CASCADE to remove the group's memberships.
+func (s *groupStore) Delete(ctx context.Context, groupID id.GroupID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	res, err := s.db.NewDelete((*groupModel)(nil)).
+		Where("id = ?", groupID.String()).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/postgres: delete group: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return errNotFound("group")
+	}
+	return nil
+}
+
+func (s *groupStore) List(ctx context.Context, filter *group.ListFilter) ([]*group.Group, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var models []groupModel
+	q := s.db.NewSelect(&models).OrderExpr("name ASC, id ASC")
+
+	if filter != nil {
+		if filter.TenantID != "" {
+			q = q.Where("tenant_id = ?", filter.TenantID)
+		}
+		if filter.AppID != "" {
+			q = q.Where("app_id = ?", filter.AppID)
+		}
+		if filter.Limit > 0 {
+			q = q.Limit(filter.Limit)
+		}
+		if filter.Offset > 0 {
+			q = q.Offset(filter.Offset)
+		}
+	}
+
+	if err := q.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("keysmith/postgres: list groups: %w", err)
+	}
+
+	result := make([]*group.Group, 0, len(models))
+	for i := range models {
+		g, err := groupFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/postgres: convert group: %w", err)
+		}
+		result = append(result, g)
+	}
+	return result, nil
+}
+
+func (s *groupStore) AddKeys(ctx context.Context, groupID id.GroupID, keyIDs []id.KeyID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTxQuery(ctx, &driver.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("keysmith/postgres: begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	gid, err := findGroup(ctx, tx, groupID)
+	if err != nil {
+		return err
+	}
+	for _, keyID := range keyIDs {
+		m := &groupMemberModel{GroupID: gid, KeyID: keyID.String()}
+		if _, err := tx.NewInsert(m).OnConflict("DO NOTHING").Exec(ctx); err != nil {
+			return fmt.Errorf("keysmith/postgres: add group member: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *groupStore) RemoveKeys(ctx context.Context, groupID id.GroupID, keyIDs []id.KeyID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTxQuery(ctx, &driver.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("keysmith/postgres: begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	gid, err := findGroup(ctx, tx, groupID)
+	if err != nil {
+		return err
+	}
+	for _, keyID := range keyIDs {
+		_, err := tx.NewRaw(`DELETE FROM keysmith_key_group_members WHERE group_id = $1 AND key_id = $2`,
+			gid, keyID.String()).Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("keysmith/postgres: remove group member: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// findGroup locks the group's row in tx and returns its ID, or errNotFound
+// when there is no such group.
+func findGroup(ctx context.Context, tx *pgdriver.PgTx, groupID id.GroupID) (string, error) {
+	var gid string
+	err := tx.NewRaw(`SELECT id FROM keysmith_key_groups WHERE id = $1 FOR UPDATE`, groupID.String()).Scan(ctx, &gid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", errNotFound("group")
+		}
+		return "", fmt.Errorf("keysmith/postgres: get group: %w", err)
+	}
+	return gid, nil
+}
+
+func (s *groupStore) ListByKeys(ctx context.Context, keyIDs []id.KeyID) (map[id.KeyID][]id.GroupID, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make(map[id.KeyID][]id.GroupID)
+	if len(keyIDs) == 0 {
+		return result, nil
+	}
+
+	kids := make([]string, len(keyIDs))
+	for i, kid := range keyIDs {
+		kids[i] = kid.String()
+	}
+	var models []groupMemberModel
+	err := s.rs.read(ctx, s.db, func(db *pgdriver.PgDB) error {
+		models = nil
+		return db.NewSelect(&models).
+			Where("key_id = ANY(?)", kids).
+			OrderExpr("group_id ASC").
+			Scan(ctx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/postgres: list groups by keys: %w", err)
+	}
+
+	for i := range models {
+		kid, err := id.ParseKeyID(models[i].KeyID)
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/postgres: convert group member: %w", err)
+		}
+		gid, err := id.ParseGroupID(models[i].GroupID)
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/postgres: convert group member: %w", err)
+		}
+		result[kid] = append(result[kid], gid)
+	}
+	return result, nil
+}
@@ -0,0 +1,30 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/store/postgres"
+	"github.com/xraph/keysmith/store/storetest"
+)
+
+// TestHashTombstones runs against the database in KEYSMITH_TEST_POSTGRES_DSN.
+func TestHashTombstones(t *testing.T) {
+	dsn := os.Getenv("KEYSMITH_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("KEYSMITH_TEST_POSTGRES_DSN is not set")
+	}
+	ctx := context.Background()
+	s, err := postgres.NewFromDSN(ctx, dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+	require.NoError(t, s.Migrate(ctx))
+
+	storetest.CheckHashTombstones(t, s, "tombstone-"+id.NewKeyID().String())
+}
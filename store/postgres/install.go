@@ -0,0 +1,27 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xraph/keysmith/store"
+)
+
+var _ store.InstallIDStore = (*Store)(nil)
+
+// InstallID stores candidate as the install ID unless one is stored, and
+// returns the stored ID. It is kept in the install_id row of keysmith_meta.
+func (s *Store) InstallID(ctx context.Context, candidate string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	if _, err := s.db.Exec(ctx, `INSERT INTO keysmith_meta (name, value) VALUES ('install_id', $1) ON CONFLICT (name) DO NOTHING`, candidate); err != nil {
+		return "", fmt.Errorf("keysmith/postgres: store install id: %w", err)
+	}
+	var stored string
+	if err := s.db.QueryRow(ctx, `SELECT value FROM keysmith_meta WHERE name = 'install_id'`).Scan(&stored); err != nil {
+		return "", fmt.Errorf("keysmith/postgres: read install id: %w", err)
+	}
+	return stored, nil
+}
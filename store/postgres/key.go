@@ -5,30 +5,70 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/xraph/grove/driver"
 	"github.com/xraph/grove/drivers/pgdriver"
 
 	"github.com/xraph/keysmith/id"
 	"github.com/xraph/keysmith/key"
 )
 
+// iterateBatchSize is the number of rows fetched per round trip by Iterate.
+const iterateBatchSize = 500
+
+// externalRefConflict skips the insert of a key whose external reference is
+// taken in its tenant, leaving no row affected. Conflicts on other
+// constraints still fail.
+const externalRefConflict = "(tenant_id, external_ref) WHERE external_ref <> '' DO NOTHING"
+
 type keyStore struct {
 	db *pgdriver.PgDB
+	rs *replicaSet
 }
 
 func (s *keyStore) Create(ctx context.Context, k *key.Key) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer s.rs.wroteKey()
+
+	tx, err := s.db.BeginTxQuery(ctx, &driver.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("keysmith/postgres: begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
 	m := keyToModel(k)
-	_, err := s.db.NewInsert(m).Exec(ctx)
+	res, err := tx.NewInsert(m).OnConflict(externalRefConflict).Exec(ctx)
 	if err != nil {
 		return fmt.Errorf("keysmith/postgres: create key: %w", err)
 	}
-	return nil
+	if rows, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("keysmith/postgres: create key rows: %w", err)
+	} else if rows == 0 {
+		return key.ErrExternalRefInUse
+	}
+	if err := upsertKeyHash(ctx, tx, keyHashToModel(&key.HashVersion{KeyID: k.ID, Hash: k.KeyHash, CreatedAt: m.CreatedAt})); err != nil {
+		return err
+	}
+	if err := replaceKeyLabels(ctx, tx, m.ID, k.Labels); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (s *keyStore) Get(ctx context.Context, keyID id.KeyID) (*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	m := new(keyModel)
-	err := s.db.NewSelect(m).Where("id = ?", keyID.String()).Scan(ctx)
+	err := s.rs.read(ctx, s.db, func(db *pgdriver.PgDB) error {
+		return db.NewSelect(m).Where("id = ?", keyID.String()).Scan(ctx)
+	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, errNotFound("key")
@@ -39,8 +79,14 @@ func (s *keyStore) Get(ctx context.Context, keyID id.KeyID) (*key.Key, error) {
 }
 
 func (s *keyStore) GetByHash(ctx context.Context, hash string) (*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	m := new(keyModel)
-	err := s.db.NewSelect(m).Where("key_hash = ?", hash).Scan(ctx)
+	err := s.rs.read(ctx, s.db, func(db *pgdriver.PgDB) error {
+		return db.NewSelect(m).Where(activeHashIn, key.LookupHashes(hash)).Limit(1).Scan(ctx)
+	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, errNotFound("key")
@@ -50,35 +96,234 @@ func (s *keyStore) GetByHash(ctx context.Context, hash string) (*key.Key, error)
 	return keyFromModel(m)
 }
 
-func (s *keyStore) GetByPrefix(ctx context.Context, prefix, hint string) (*key.Key, error) {
-	m := new(keyModel)
-	err := s.db.NewSelect(m).
-		Where("prefix = ?", prefix).
-		Where("hint = ?", hint).
-		Scan(ctx)
+func (s *keyStore) GetByHashes(ctx context.Context, hashes []string) ([]*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(hashes) == 0 {
+		return []*key.Key{}, nil
+	}
+	var models []keyModel
+	err := s.rs.read(ctx, s.db, func(db *pgdriver.PgDB) error {
+		models = nil
+		return db.NewSelect(&models).Where(activeHashIn, key.LookupHashes(hashes...)).Scan(ctx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/postgres: get keys by hash: %w", err)
+	}
+
+	result := make([]*key.Key, 0, len(models))
+	for i := range models {
+		k, err := keyFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/postgres: convert key: %w", err)
+		}
+		result = append(result, k)
+	}
+	return result, nil
+}
+
+// activeHashIn matches keys with an active hash version in an array of
+// hashes.
+const activeHashIn = "id IN (SELECT key_id FROM keysmith_key_hashes WHERE active AND hash = ANY(?))"
+
+func (s *keyStore) AddHash(ctx context.Context, h *key.HashVersion) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer s.rs.wroteKey()
+
+	tx, err := s.db.BeginTxQuery(ctx, &driver.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("keysmith/postgres: begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var current string
+	err = tx.NewRaw(`SELECT key_hash FROM keysmith_keys WHERE id = $1`, h.KeyID.String()).Scan(ctx, &current)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errNotFound("key")
+			return errNotFound("key")
 		}
-		return nil, fmt.Errorf("keysmith/postgres: get key by prefix: %w", err)
+		return fmt.Errorf("keysmith/postgres: get key: %w", err)
 	}
-	return keyFromModel(m)
+	m := keyHashToModel(h)
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now().UTC()
+	}
+	if err := upsertKeyHash(ctx, tx, m); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *keyStore) ListHashes(ctx context.Context, keyID id.KeyID) ([]*key.HashVersion, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var models []keyHashModel
+	err := s.rs.read(ctx, s.db, func(db *pgdriver.PgDB) error {
+		models = nil
+		return db.NewSelect(&models).
+			Where("key_id = ?", keyID.String()).
+			OrderExpr("created_at ASC, hash ASC").
+			Scan(ctx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/postgres: list key hashes: %w", err)
+	}
+
+	result := make([]*key.HashVersion, 0, len(models))
+	for i := range models {
+		h, err := keyHashFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/postgres: convert key hash: %w", err)
+		}
+		result = append(result, h)
+	}
+	return result, nil
+}
+
+func (s *keyStore) DeactivateHash(ctx context.Context, keyID id.KeyID, hash string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer s.rs.wroteKey()
+
+	_, err := s.db.NewRaw(`
+		UPDATE keysmith_key_hashes SET active = FALSE
+		WHERE key_id = $1 AND hash = $2
+		AND hash <> (SELECT key_hash FROM keysmith_keys WHERE id = $1)`, keyID.String(), hash).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/postgres: deactivate key hash: %w", err)
+	}
+	return nil
+}
+
+// upsertKeyHash stores m as an active version, reactivating it if the key
+// already has the hash. A hash of another key fails with key.ErrHashInUse.
+func upsertKeyHash(ctx context.Context, tx *pgdriver.PgTx, m *keyHashModel) error {
+	res, err := tx.NewRaw(`
+		INSERT INTO keysmith_key_hashes (hash, key_id, scheme, active, created_at)
+		VALUES ($1, $2, $3, TRUE, $4)
+		ON CONFLICT (hash) DO UPDATE SET active = TRUE
+		WHERE keysmith_key_hashes.key_id = EXCLUDED.key_id`, m.Hash, m.KeyID, m.Scheme, m.CreatedAt).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/postgres: store key hash: %w", err)
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return key.ErrHashInUse
+	}
+	return nil
+}
+
+// replaceKeyLabels rewrites the keysmith_key_labels rows of a key, which
+// selectors query, to match labels. The labels column holds the same pairs
+// for reading the key back.
+func replaceKeyLabels(ctx context.Context, tx *pgdriver.PgTx, keyID string, labels key.Labels) error {
+	if _, err := tx.NewRaw(`DELETE FROM keysmith_key_labels WHERE key_id = $1`, keyID).Exec(ctx); err != nil {
+		return fmt.Errorf("keysmith/postgres: clear key labels: %w", err)
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(labels))
+	values := make([]string, 0, len(labels))
+	for name, value := range labels {
+		names = append(names, name)
+		values = append(values, value)
+	}
+	_, err := tx.NewRaw(`
+		INSERT INTO keysmith_key_labels (key_id, name, value)
+		SELECT $1, name, value FROM unnest($2::text[], $3::text[]) AS l(name, value)`, keyID, names, values).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/postgres: store key labels: %w", err)
+	}
+	return nil
+}
+
+func (s *keyStore) ListByPrefixHint(ctx context.Context, prefix, hint string) ([]*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var models []keyModel
+	err := s.rs.read(ctx, s.db, func(db *pgdriver.PgDB) error {
+		models = nil
+		return db.NewSelect(&models).
+			Where("prefix = ?", prefix).
+			Where("hint = ?", hint).
+			OrderExpr("created_at ASC, id ASC").
+			Scan(ctx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/postgres: list keys by prefix and hint: %w", err)
+	}
+
+	result := make([]*key.Key, 0, len(models))
+	for i := range models {
+		k, err := keyFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/postgres: convert key: %w", err)
+		}
+		result = append(result, k)
+	}
+	return result, nil
 }
 
 func (s *keyStore) Update(ctx context.Context, k *key.Key) error {
-	m := keyToModel(k)
-	res, err := s.db.NewUpdate(m).WherePK().Exec(ctx)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer s.rs.wroteKey()
+
+	tx, err := s.db.BeginTxQuery(ctx, &driver.TxOptions{})
 	if err != nil {
+		return fmt.Errorf("keysmith/postgres: begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var prev string
+	err = tx.NewRaw(`SELECT key_hash FROM keysmith_keys WHERE id = $1 FOR UPDATE`, k.ID.String()).Scan(ctx, &prev)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errNotFound("key")
+		}
+		return fmt.Errorf("keysmith/postgres: get key: %w", err)
+	}
+
+	m := keyToModel(k)
+	if _, err := tx.NewUpdate(m).WherePK().Exec(ctx); err != nil {
 		return fmt.Errorf("keysmith/postgres: update key: %w", err)
 	}
-	affected, _ := res.RowsAffected()
-	if affected == 0 {
-		return errNotFound("key")
+	if err := replaceKeyLabels(ctx, tx, m.ID, k.Labels); err != nil {
+		return err
 	}
-	return nil
+	// A new hash replaces the secret, so the old one's versions retire.
+	if prev != k.KeyHash {
+		_, err := tx.NewRaw(`UPDATE keysmith_key_hashes SET active = FALSE WHERE key_id = $1 AND active`, m.ID).Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("keysmith/postgres: retire key hashes: %w", err)
+		}
+		if err := upsertKeyHash(ctx, tx, keyHashToModel(&key.HashVersion{KeyID: k.ID, Hash: k.KeyHash, CreatedAt: time.Now()})); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
 func (s *keyStore) UpdateState(ctx context.Context, keyID id.KeyID, state key.State) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer s.rs.wroteKey()
+
 	res, err := s.db.NewUpdate((*keyModel)(nil)).
 		Set("state = ?", string(state)).
 		Set("updated_at = ?", time.Now().UTC()).
@@ -94,7 +339,31 @@ func (s *keyStore) UpdateState(ctx context.Context, keyID id.KeyID, state key.St
 	return nil
 }
 
+func (s *keyStore) UpdateStateIf(ctx context.Context, keyID id.KeyID, from, to key.State) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	defer s.rs.wroteKey()
+
+	res, err := s.db.NewUpdate((*keyModel)(nil)).
+		Set("state = ?", string(to)).
+		Set("updated_at = ?", time.Now().UTC()).
+		Where("id = ?", keyID.String()).
+		Where("state = ?", string(from)).
+		Exec(ctx)
+	if err != nil {
+		return false, fmt.Errorf("keysmith/postgres: update key state if: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	return affected > 0, nil
+}
+
 func (s *keyStore) UpdateLastUsed(ctx context.Context, keyID id.KeyID, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	res, err := s.db.NewUpdate((*keyModel)(nil)).
 		Set("last_used_at = ?", at).
 		Where("id = ?", keyID.String()).
@@ -110,6 +379,12 @@ func (s *keyStore) UpdateLastUsed(ctx context.Context, keyID id.KeyID, at time.T
 }
 
 func (s *keyStore) Delete(ctx context.Context, keyID id.KeyID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer s.rs.wroteKey()
+
 	res, err := s.db.NewDelete((*keyModel)(nil)).
 		Where("id = ?", keyID.String()).
 		Exec(ctx)
@@ -124,34 +399,27 @@ func (s *keyStore) Delete(ctx context.Context, keyID id.KeyID) error {
 }
 
 func (s *keyStore) List(ctx context.Context, filter *key.ListFilter) ([]*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var models []keyModel
-	q := s.db.NewSelect(&models).OrderExpr("created_at DESC")
+	err := s.rs.read(ctx, s.db, func(db *pgdriver.PgDB) error {
+		models = nil
+		q := applyKeyFilter(db.NewSelect(&models).OrderExpr("created_at DESC"), filter)
 
-	if filter != nil {
-		if filter.TenantID != "" {
-			q = q.Where("tenant_id = ?", filter.TenantID)
-		}
-		if filter.Environment != "" {
-			q = q.Where("environment = ?", string(filter.Environment))
-		}
-		if filter.State != "" {
-			q = q.Where("state = ?", string(filter.State))
-		}
-		if filter.PolicyID != nil {
-			q = q.Where("policy_id = ?", filter.PolicyID.String())
-		}
-		if filter.CreatedBy != "" {
-			q = q.Where("created_by = ?", filter.CreatedBy)
-		}
-		if filter.Limit > 0 {
-			q = q.Limit(filter.Limit)
+		if filter != nil {
+			if filter.Limit > 0 {
+				q = q.Limit(filter.Limit)
+			}
+			if filter.Offset > 0 {
+				q = q.Offset(filter.Offset)
+			}
 		}
-		if filter.Offset > 0 {
-			q = q.Offset(filter.Offset)
-		}
-	}
 
-	if err := q.Scan(ctx); err != nil {
+		return q.Scan(ctx)
+	})
+	if err != nil {
 		return nil, fmt.Errorf("keysmith/postgres: list keys: %w", err)
 	}
 
@@ -167,39 +435,186 @@ func (s *keyStore) List(ctx context.Context, filter *key.ListFilter) ([]*key.Key
 }
 
 func (s *keyStore) Count(ctx context.Context, filter *key.ListFilter) (int64, error) {
-	q := s.db.NewSelect((*keyModel)(nil))
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
 
+	var count int64
+	err := s.rs.read(ctx, s.db, func(db *pgdriver.PgDB) error {
+		q := applyKeyFilter(db.NewSelect((*keyModel)(nil)), filter)
+
+		var err error
+		count, err = q.Count(ctx)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("keysmith/postgres: count keys: %w", err)
+	}
+	return count, nil
+}
+
+// Iterate walks matching keys in batches of iterateBatchSize using keyset
+// pagination on the primary key, so rows updated by fn mid-iteration are
+// neither skipped nor revisited.
+func (s *keyStore) Iterate(ctx context.Context, filter *key.ListFilter, fn func(*key.Key) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var limit, offset int
 	if filter != nil {
-		if filter.TenantID != "" {
-			q = q.Where("tenant_id = ?", filter.TenantID)
+		limit, offset = filter.Limit, filter.Offset
+	}
+
+	var cursor string
+	visited := 0
+	for {
+		batch := iterateBatchSize
+		if limit > 0 && limit-visited < batch {
+			batch = limit - visited
 		}
-		if filter.Environment != "" {
-			q = q.Where("environment = ?", string(filter.Environment))
+
+		var models []keyModel
+		q := applyKeyFilter(s.db.NewSelect(&models), filter).
+			OrderExpr("id ASC").
+			Limit(batch)
+		if cursor != "" {
+			q = q.Where("id > ?", cursor)
+		} else if offset > 0 {
+			q = q.Offset(offset)
 		}
-		if filter.State != "" {
-			q = q.Where("state = ?", string(filter.State))
+		if err := q.Scan(ctx); err != nil {
+			return fmt.Errorf("keysmith/postgres: iterate keys: %w", err)
 		}
-		if filter.PolicyID != nil {
-			q = q.Where("policy_id = ?", filter.PolicyID.String())
+
+		for i := range models {
+			k, err := keyFromModel(&models[i])
+			if err != nil {
+				return fmt.Errorf("keysmith/postgres: convert key: %w", err)
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(k); err != nil {
+				return err
+			}
 		}
-		if filter.CreatedBy != "" {
-			q = q.Where("created_by = ?", filter.CreatedBy)
+
+		visited += len(models)
+		if len(models) < batch || (limit > 0 && visited >= limit) {
+			return nil
 		}
+		cursor = models[len(models)-1].ID
 	}
+}
+
+// Overview groups the matching keys by state and environment and sums a
+// CASE expression per window in each group, so one scan serves every count.
+func (s *keyStore) Overview(ctx context.Context, q *key.OverviewQuery) (*key.Overview, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	now := q.Now.UTC()
+	active := string(key.StateActive)
+	o := key.NewOverview(q)
+	err := s.rs.read(ctx, s.db, func(db *pgdriver.PgDB) error {
+		sel := applyKeyFilter(db.NewSelect((*keyModel)(nil)), &key.ListFilter{TenantID: q.TenantID, AppID: q.AppID}).
+			ColumnExpr("state").
+			ColumnExpr("environment").
+			ColumnExpr("COUNT(*)").
+			ColumnExpr("COUNT(*) FILTER (WHERE created_at > ?)", now.Add(-q.CreatedWithin)).
+			ColumnExpr("COUNT(*) FILTER (WHERE state = ? AND last_used_at IS NULL)", active)
+		for _, w := range q.ExpiringWithin {
+			sel = sel.ColumnExpr("COUNT(*) FILTER (WHERE state = ? AND expires_at > ? AND expires_at <= ?)",
+				active, now, now.Add(w))
+		}
+		query, args, err := sel.GroupExpr("state, environment").Build()
+		if err != nil {
+			return err
+		}
+
+		rows, err := db.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
 
-	count, err := q.Count(ctx)
+		var state, env string
+		counts := make([]int64, 3+len(q.ExpiringWithin))
+		dest := []any{&state, &env}
+		for i := range counts {
+			dest = append(dest, &counts[i])
+		}
+		for rows.Next() {
+			if err := rows.Scan(dest...); err != nil {
+				return err
+			}
+			o.Total += counts[0]
+			o.ByState[key.State(state)] += counts[0]
+			o.ByEnvironment[key.Environment(env)] += counts[0]
+			o.CreatedRecently.Count += counts[1]
+			o.NeverUsed += counts[2]
+			for i := range o.Expiring {
+				o.Expiring[i].Count += counts[3+i]
+			}
+		}
+		return rows.Err()
+	})
 	if err != nil {
-		return 0, fmt.Errorf("keysmith/postgres: count keys: %w", err)
+		return nil, fmt.Errorf("keysmith/postgres: key overview: %w", err)
 	}
-	return count, nil
+	return o, nil
+}
+
+// Search finds candidates with the search_tsv index, ANDing one phrase
+// query per term, and checks them with key.MatchSearch, since the index
+// does not keep fields apart.
+func (s *keyStore) Search(ctx context.Context, q *key.SearchQuery) ([]*key.SearchMatch, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(q.Terms) == 0 {
+		return []*key.SearchMatch{}, nil
+	}
+	phrases := make([]string, len(q.Terms))
+	args := make([]any, len(q.Terms))
+	for i, t := range q.Terms {
+		phrases[i] = "phraseto_tsquery('simple', ?)"
+		args[i] = t.String()
+	}
+	var models []keyModel
+	err := applyKeyFilter(s.db.NewSelect(&models), &key.ListFilter{TenantID: q.TenantID, AppID: q.AppID}).
+		Where("search_tsv @@ ("+strings.Join(phrases, " && ")+")", args...).
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/postgres: search keys: %w", err)
+	}
+
+	matches := make([]*key.SearchMatch, 0, len(models))
+	for i := range models {
+		k, err := keyFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/postgres: convert key: %w", err)
+		}
+		if m := key.MatchSearch(k, q.Terms); m != nil {
+			matches = append(matches, m)
+		}
+	}
+	return key.RankSearch(matches, q.Limit), nil
 }
 
 func (s *keyStore) ListExpired(ctx context.Context, before time.Time) ([]*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var models []keyModel
 	err := s.db.NewSelect(&models).
 		Where("state = ?", string(key.StateActive)).
 		Where("expires_at IS NOT NULL").
-		Where("expires_at < ?", before).
+		Where("expires_at < ?", before.UTC()).
 		Scan(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("keysmith/postgres: list expired: %w", err)
@@ -216,7 +631,41 @@ func (s *keyStore) ListExpired(ctx context.Context, before time.Time) ([]*key.Ke
 	return result, nil
 }
 
+func (s *keyStore) ListRecentlyUsed(ctx context.Context, limit int) ([]*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var models []keyModel
+	err := s.rs.read(ctx, s.db, func(db *pgdriver.PgDB) error {
+		q := db.NewSelect(&models).
+			Where("last_used_at IS NOT NULL").
+			OrderExpr("last_used_at DESC")
+		if limit > 0 {
+			q = q.Limit(limit)
+		}
+		return q.Scan(ctx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/postgres: list recently used: %w", err)
+	}
+
+	result := make([]*key.Key, 0, len(models))
+	for i := range models {
+		k, err := keyFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/postgres: convert key: %w", err)
+		}
+		result = append(result, k)
+	}
+	return result, nil
+}
+
 func (s *keyStore) ListByPolicy(ctx context.Context, policyID id.PolicyID) ([]*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var models []keyModel
 	err := s.db.NewSelect(&models).
 		Where("policy_id = ?", policyID.String()).
@@ -237,6 +686,12 @@ func (s *keyStore) ListByPolicy(ctx context.Context, policyID id.PolicyID) ([]*k
 }
 
 func (s *keyStore) DeleteByTenant(ctx context.Context, tenantID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer s.rs.wroteKey()
+
 	_, err := s.db.NewDelete((*keyModel)(nil)).
 		Where("tenant_id = ?", tenantID).
 		Exec(ctx)
@@ -245,3 +700,77 @@ func (s *keyStore) DeleteByTenant(ctx context.Context, tenantID string) error {
 	}
 	return nil
 }
+
+// applyKeyFilter adds the WHERE clauses shared by List, Count, and Iterate.
+func applyKeyFilter(q *pgdriver.SelectQuery, filter *key.ListFilter) *pgdriver.SelectQuery {
+	if filter == nil {
+		return q
+	}
+	if filter.TenantID != "" {
+		q = q.Where("tenant_id = ?", filter.TenantID)
+	}
+	if filter.AppID != "" {
+		q = q.Where("app_id = ?", filter.AppID)
+	}
+	if filter.Environment != "" {
+		q = q.Where("environment = ?", string(filter.Environment))
+	}
+	if filter.State != "" {
+		q = q.Where("state = ?", string(filter.State))
+	}
+	if filter.PolicyID != nil {
+		q = q.Where("policy_id = ?", filter.PolicyID.String())
+	}
+	if filter.CreatedBy != "" {
+		q = q.Where("created_by = ?", filter.CreatedBy)
+	}
+	if filter.ExternalRef != "" {
+		q = q.Where("external_ref = ?", filter.ExternalRef)
+	}
+	if filter.Name != "" {
+		q = q.Where("lower(name) = lower(?)", filter.Name)
+	}
+	if filter.ExpiresBefore != nil {
+		q = q.Where("expires_at IS NOT NULL").Where("expires_at < ?", filter.ExpiresBefore.UTC())
+	}
+	if filter.CreatedAfter != nil {
+		q = q.Where("created_at > ?", filter.CreatedAfter.UTC())
+	}
+	if filter.CreatedBefore != nil {
+		q = q.Where("created_at < ?", filter.CreatedBefore.UTC())
+	}
+	if filter.UpdatedAfter != nil {
+		q = q.Where("updated_at > ?", filter.UpdatedAfter.UTC())
+	}
+	if filter.OutdatedTerms != "" {
+		q = q.Where("accepted_terms_version <> ?", filter.OutdatedTerms)
+	}
+	for _, r := range filter.LabelSelector {
+		cond, args := labelCondition(r)
+		q = q.Where(cond, args...)
+	}
+	if filter.GroupID != nil {
+		q = q.Where("id IN (SELECT key_id FROM keysmith_key_group_members WHERE group_id = ?)", filter.GroupID.String())
+	}
+	if filter.PoolID != nil {
+		q = q.Where("pool_id = ?", filter.PoolID.String())
+	}
+	return q
+}
+
+// labelCondition returns the WHERE clause for one selector requirement.
+// Each is a semi-join on keysmith_key_labels served by its (name, value)
+// index; negated requirements also match keys without the label.
+func labelCondition(r key.Requirement) (string, []any) {
+	switch r.Op {
+	case key.SelectorEquals, key.SelectorIn:
+		return "id IN (SELECT key_id FROM keysmith_key_labels WHERE name = ? AND value = ANY(?))", []any{r.Name, r.Values}
+	case key.SelectorNotEquals, key.SelectorNotIn:
+		return "id NOT IN (SELECT key_id FROM keysmith_key_labels WHERE name = ? AND value = ANY(?))", []any{r.Name, r.Values}
+	case key.SelectorExists:
+		return "id IN (SELECT key_id FROM keysmith_key_labels WHERE name = ?)", []any{r.Name}
+	case key.SelectorDoesNotExist:
+		return "id NOT IN (SELECT key_id FROM keysmith_key_labels WHERE name = ?)", []any{r.Name}
+	}
+	return "FALSE", nil
+}
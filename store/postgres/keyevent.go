@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xraph/grove/drivers/pgdriver"
+
+	"github.com/xraph/keysmith/keyevent"
+)
+
+type keyEventStore struct {
+	db *pgdriver.PgDB
+	rs *replicaSet
+}
+
+func (s *keyEventStore) Append(ctx context.Context, e *keyevent.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	_, err := s.db.NewInsert(keyEventToModel(e)).
+		OnConflict("(at, key_id) DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/postgres: append key event: %w", err)
+	}
+	return nil
+}
+
+func (s *keyEventStore) ListAfter(ctx context.Context, filter *keyevent.ListFilter) ([]*keyevent.Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if filter == nil {
+		filter = &keyevent.ListFilter{}
+	}
+	after := filter.After
+	var models []keyEventModel
+	err := s.rs.read(ctx, s.db, func(db *pgdriver.PgDB) error {
+		models = nil
+		q := db.NewSelect(&models).
+			Where("(at > ? OR (at = ? AND key_id > ?))", after.At, after.At, after.KeyID).
+			OrderExpr("at ASC, key_id ASC")
+		if filter.TenantID != "" {
+			q = q.Where("tenant_id = ?", filter.TenantID)
+		}
+		if filter.AppID != "" {
+			q = q.Where("app_id = ?", filter.AppID)
+		}
+		if filter.KeyID != nil {
+			q = q.Where("key_id = ?", filter.KeyID.String())
+		}
+		if len(filter.Types) > 0 {
+			types := make([]string, len(filter.Types))
+			for i, t := range filter.Types {
+				types[i] = string(t)
+			}
+			q = q.Where("type = ANY(?)", types)
+		}
+		if filter.Limit > 0 {
+			q = q.Limit(filter.Limit)
+		}
+		return q.Scan(ctx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/postgres: list key events: %w", err)
+	}
+
+	result := make([]*keyevent.Event, 0, len(models))
+	for i := range models {
+		e, err := keyEventFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/postgres: convert key event: %w", err)
+		}
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+func (s *keyEventStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	res, err := s.db.NewDelete((*keyEventModel)(nil)).
+		Where("at < ?", before.UTC()).
+		Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("keysmith/postgres: purge key events: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	return affected, nil
+}
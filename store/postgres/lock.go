@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/xraph/grove/drivers/pgdriver"
+
+	"github.com/xraph/keysmith/store"
+)
+
+var _ store.Locker = (*Store)(nil)
+
+// AcquireLock takes the lock called name for ttl, as a row in keysmith_locks
+// holding a random token and the expiry. An expired row is taken over in the
+// same statement. Expiries are computed from the database clock, so replicas
+// with skewed clocks agree on them. A table is used rather than advisory
+// locks, which belong to a session and would pin a pooled connection for as
+// long as a job runs.
+func (s *Store) AcquireLock(ctx context.Context, name string, ttl time.Duration) (store.Lock, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	holder := rand.Text()
+	res, err := s.db.Exec(ctx, `
+		INSERT INTO keysmith_locks (name, holder, expires_at) VALUES ($1, $2, NOW() + $3 * INTERVAL '1 millisecond')
+		ON CONFLICT (name) DO UPDATE SET holder = EXCLUDED.holder, expires_at = EXCLUDED.expires_at
+		WHERE keysmith_locks.expires_at <= NOW()`,
+		name, holder, ttl.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/postgres: acquire lock %s: %w", name, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, store.ErrLockHeld
+	}
+	return &lock{db: s.db, name: name, holder: holder}, nil
+}
+
+type lock struct {
+	db     *pgdriver.PgDB
+	name   string
+	holder string
+}
+
+func (l *lock) Name() string { return l.name }
+
+func (l *lock) Renew(ctx context.Context, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	res, err := l.db.Exec(ctx, `
+		UPDATE keysmith_locks SET expires_at = NOW() + $3 * INTERVAL '1 millisecond'
+		WHERE name = $1 AND holder = $2 AND expires_at > NOW()`,
+		l.name, l.holder, ttl.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("keysmith/postgres: renew lock %s: %w", l.name, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return store.ErrLockLost
+	}
+	return nil
+}
+
+func (l *lock) Release(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, err := l.db.Exec(ctx, `DELETE FROM keysmith_locks WHERE name = $1 AND holder = $2`, l.name, l.holder); err != nil {
+		return fmt.Errorf("keysmith/postgres: release lock %s: %w", l.name, err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/xraph/keysmith/store"
+)
+
+var _ store.Maintainer = (*Store)(nil)
+
+// usageAdviceRows is the usage table size below which no index advice is
+// given; scans of smaller tables are cheap.
+const usageAdviceRows = 100_000
+
+// Maintain vacuums and analyzes every keysmith table on the primary, then
+// reports row counts, dead tuples, and total relation sizes from
+// pg_stat_user_tables. Replicas are maintained by replication.
+func (s *Store) Maintain(ctx context.Context, opts store.MaintenanceOptions) (*store.MaintenanceReport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	report := &store.MaintenanceReport{Backend: "postgres", Actions: []string{}, StartedAt: time.Now()}
+
+	if !opts.StatsOnly {
+		tables, err := s.tableStats(ctx)
+		if err != nil {
+			return nil, err
+		}
+		vacuum := "VACUUM (ANALYZE)"
+		if opts.Full {
+			vacuum = "VACUUM (FULL, ANALYZE)"
+		}
+		for _, t := range tables {
+			stmt := vacuum + " " + t.Name
+			if _, err := s.db.Exec(ctx, stmt); err != nil {
+				return nil, fmt.Errorf("keysmith/postgres: %s: %w", stmt, err)
+			}
+			report.Actions = append(report.Actions, stmt)
+		}
+	}
+
+	tables, err := s.tableStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.Tables = tables
+	report.Advice, err = s.usageAdvice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.Duration = time.Since(report.StartedAt)
+	return report, nil
+}
+
+// tableStats reads the statistics of the keysmith tables in the current
+// schema search path.
+func (s *Store) tableStats(ctx context.Context) ([]store.TableStats, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT relname, n_live_tup, n_dead_tup, pg_total_relation_size(relid)
+		FROM pg_stat_user_tables
+		WHERE relname LIKE 'keysmith\_%'
+		ORDER BY relname`)
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/postgres: read table stats: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []store.TableStats
+	for rows.Next() {
+		var t store.TableStats
+		if err := rows.Scan(&t.Name, &t.Rows, &t.DeadRows, &t.SizeBytes); err != nil {
+			return nil, fmt.Errorf("keysmith/postgres: scan table stats: %w", err)
+		}
+		tables = append(tables, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("keysmith/postgres: read table stats: %w", err)
+	}
+	return tables, nil
+}
+
+// usageAdvice suggests indexes for the usage table once it is large enough
+// for full scans to matter: one led by created_at for PurgeUsage, which
+// the bundled indexes do not cover, and a warning when sequential scans
+// outnumber index scans.
+func (s *Store) usageAdvice(ctx context.Context) ([]string, error) {
+	var seqScans, idxScans, rows int64
+	var hasCreatedIndex bool
+	err := s.db.QueryRow(ctx, `
+		SELECT seq_scan, COALESCE(idx_scan, 0), n_live_tup,
+			EXISTS (SELECT 1 FROM pg_indexes
+				WHERE tablename = 'keysmith_usage' AND indexdef LIKE '%(created_at%')
+		FROM pg_stat_user_tables
+		WHERE relname = 'keysmith_usage'`).Scan(&seqScans, &idxScans, &rows, &hasCreatedIndex)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("keysmith/postgres: read usage scan stats: %w", err)
+	}
+	if rows < usageAdviceRows {
+		return nil, nil
+	}
+
+	var advice []string
+	if !hasCreatedIndex {
+		advice = append(advice, "keysmith_usage has no index led by created_at, so usage purges scan the whole table; consider CREATE INDEX CONCURRENTLY idx_keysmith_usage_created ON keysmith_usage (created_at)")
+	}
+	if seqScans > idxScans {
+		advice = append(advice, fmt.Sprintf("keysmith_usage has had %d sequential scans and %d index scans; queries that filter on neither key_id nor tenant_id read the whole table", seqScans, idxScans))
+	}
+	return advice, nil
+}
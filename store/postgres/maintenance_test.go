@@ -0,0 +1,59 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/store/postgres"
+)
+
+// TestMaintain runs against the database in KEYSMITH_TEST_POSTGRES_DSN.
+func TestMaintain(t *testing.T) {
+	dsn := os.Getenv("KEYSMITH_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("KEYSMITH_TEST_POSTGRES_DSN is not set")
+	}
+	ctx := context.Background()
+	s, err := postgres.NewFromDSN(ctx, dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+	require.NoError(t, s.Migrate(ctx))
+
+	now := time.Now()
+	k := &key.Key{
+		ID: id.NewKeyID(), KeyHash: "maintain-" + id.NewKeyID().String(), Prefix: "sk",
+		Environment: key.EnvTest, State: key.StateActive, CreatedAt: now, UpdatedAt: now,
+	}
+	require.NoError(t, s.Keys().Create(ctx, k))
+	t.Cleanup(func() { _ = s.Keys().Delete(ctx, k.ID) })
+
+	report, err := s.Maintain(ctx, store.MaintenanceOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "postgres", report.Backend)
+	assert.Contains(t, report.Actions, "VACUUM (ANALYZE) keysmith_keys")
+
+	var found bool
+	for _, tbl := range report.Tables {
+		if tbl.Name == "keysmith_keys" {
+			found = true
+			assert.Positive(t, tbl.Rows, "ANALYZE refreshes the live row estimate")
+			assert.Positive(t, tbl.SizeBytes)
+		}
+	}
+	assert.True(t, found)
+
+	report, err = s.Maintain(ctx, store.MaintenanceOptions{StatsOnly: true})
+	require.NoError(t, err)
+	assert.Empty(t, report.Actions)
+	assert.NotEmpty(t, report.Tables)
+}
@@ -206,6 +206,801 @@ CREATE INDEX IF NOT EXISTS idx_keysmith_rotations_grace ON keysmith_rotations (g
 				return err
 			},
 		},
+		&migrate.Migration{
+			Name:    "create_tenant_settings",
+			Version: "20240101000006",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS keysmith_tenant_settings (
+    tenant_id       TEXT PRIMARY KEY,
+    app_id          TEXT NOT NULL,
+    default_scopes  JSONB NOT NULL DEFAULT '[]',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `DROP TABLE IF EXISTS keysmith_tenant_settings`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_key_endpoint_seen",
+			Version: "20240101000007",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS keysmith_key_endpoint_seen (
+    key_id       TEXT NOT NULL REFERENCES keysmith_keys(id) ON DELETE CASCADE,
+    endpoint     TEXT NOT NULL,
+    method       TEXT NOT NULL,
+    last_seen_at TIMESTAMPTZ NOT NULL,
+    count        BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (key_id, endpoint, method)
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_endpoint_seen_last ON keysmith_key_endpoint_seen (key_id, last_seen_at DESC);
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `DROP TABLE IF EXISTS keysmith_key_endpoint_seen`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_key_revocations",
+			Version: "20240101000008",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS keysmith_key_revocations (
+    at          TIMESTAMPTZ NOT NULL,
+    key_id      TEXT NOT NULL,
+    tenant_id   TEXT NOT NULL,
+    hash_prefix TEXT NOT NULL,
+    state       TEXT NOT NULL,
+    revoked     BOOLEAN NOT NULL,
+    PRIMARY KEY (at, key_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_revocations_tenant ON keysmith_key_revocations (tenant_id, at, key_id);
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `DROP TABLE IF EXISTS keysmith_key_revocations`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_debug_captures",
+			Version: "20240101000009",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS debug_sample_rate DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS debug_until TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS keysmith_debug_captures (
+    id             TEXT PRIMARY KEY,
+    key_id         TEXT NOT NULL,
+    tenant_id      TEXT NOT NULL,
+    method         TEXT NOT NULL,
+    path           TEXT NOT NULL,
+    headers        JSONB,
+    body           TEXT,
+    body_truncated BOOLEAN NOT NULL DEFAULT FALSE,
+    captured_at    TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_debug_captures_key ON keysmith_debug_captures (key_id, captured_at DESC);
+CREATE INDEX IF NOT EXISTS idx_keysmith_debug_captures_captured ON keysmith_debug_captures (captured_at);
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+DROP TABLE IF EXISTS keysmith_debug_captures;
+ALTER TABLE keysmith_keys DROP COLUMN IF EXISTS debug_until;
+ALTER TABLE keysmith_keys DROP COLUMN IF EXISTS debug_sample_rate;
+`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_rotation_hints",
+			Version: "20240101000010",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_rotations ADD COLUMN IF NOT EXISTS old_hint TEXT;
+ALTER TABLE keysmith_rotations ADD COLUMN IF NOT EXISTS new_hint TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_keys_prefix_hint ON keysmith_keys (prefix, hint);
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+DROP INDEX IF EXISTS idx_keysmith_keys_prefix_hint;
+ALTER TABLE keysmith_rotations DROP COLUMN IF EXISTS new_hint;
+ALTER TABLE keysmith_rotations DROP COLUMN IF EXISTS old_hint;
+`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_app_scoping",
+			Version: "20240101000011",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_usage ADD COLUMN IF NOT EXISTS app_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE keysmith_rotations ADD COLUMN IF NOT EXISTS app_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_keys_app ON keysmith_keys (tenant_id, app_id);
+CREATE INDEX IF NOT EXISTS idx_keysmith_policies_app ON keysmith_policies (tenant_id, app_id);
+CREATE INDEX IF NOT EXISTS idx_keysmith_scopes_app ON keysmith_scopes (tenant_id, app_id);
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+DROP INDEX IF EXISTS idx_keysmith_scopes_app;
+DROP INDEX IF EXISTS idx_keysmith_policies_app;
+DROP INDEX IF EXISTS idx_keysmith_keys_app;
+ALTER TABLE keysmith_rotations DROP COLUMN IF EXISTS app_id;
+ALTER TABLE keysmith_usage DROP COLUMN IF EXISTS app_id;
+`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_tenant_metadata_schema",
+			Version: "20240101000012",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_tenant_settings ADD COLUMN IF NOT EXISTS metadata_schema JSONB`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_tenant_settings DROP COLUMN IF EXISTS metadata_schema`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_key_allowed_origins",
+			Version: "20240101000013",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS allowed_origins JSONB NOT NULL DEFAULT '[]'`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_keys DROP COLUMN IF EXISTS allowed_origins`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_tenant_rate_limit",
+			Version: "20240101000014",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_tenant_settings ADD COLUMN IF NOT EXISTS rate_limit INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE keysmith_tenant_settings ADD COLUMN IF NOT EXISTS rate_limit_window BIGINT NOT NULL DEFAULT 0;
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_tenant_settings DROP COLUMN IF EXISTS rate_limit;
+ALTER TABLE keysmith_tenant_settings DROP COLUMN IF EXISTS rate_limit_window;
+`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_key_intended_consumer",
+			Version: "20240101000015",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS intended_consumer TEXT NOT NULL DEFAULT '';
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS enforce_consumer BOOLEAN NOT NULL DEFAULT FALSE;
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_keys DROP COLUMN IF EXISTS intended_consumer;
+ALTER TABLE keysmith_keys DROP COLUMN IF EXISTS enforce_consumer;
+`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_key_flags",
+			Version: "20240101000016",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS flags JSONB NOT NULL DEFAULT '[]'`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_keys DROP COLUMN IF EXISTS flags`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			// Lets MigrateIDs rewrite a key or scope ID and the rows
+			// referencing it in one transaction.
+			Name:    "deferrable_key_refs",
+			Version: "20240101000017",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_key_scopes ALTER CONSTRAINT keysmith_key_scopes_key_id_fkey DEFERRABLE INITIALLY IMMEDIATE;
+ALTER TABLE keysmith_key_scopes ALTER CONSTRAINT keysmith_key_scopes_scope_id_fkey DEFERRABLE INITIALLY IMMEDIATE;
+ALTER TABLE keysmith_usage ALTER CONSTRAINT keysmith_usage_key_id_fkey DEFERRABLE INITIALLY IMMEDIATE;
+ALTER TABLE keysmith_rotations ALTER CONSTRAINT keysmith_rotations_key_id_fkey DEFERRABLE INITIALLY IMMEDIATE;
+ALTER TABLE keysmith_key_endpoint_seen ALTER CONSTRAINT keysmith_key_endpoint_seen_key_id_fkey DEFERRABLE INITIALLY IMMEDIATE;
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_key_scopes ALTER CONSTRAINT keysmith_key_scopes_key_id_fkey NOT DEFERRABLE;
+ALTER TABLE keysmith_key_scopes ALTER CONSTRAINT keysmith_key_scopes_scope_id_fkey NOT DEFERRABLE;
+ALTER TABLE keysmith_usage ALTER CONSTRAINT keysmith_usage_key_id_fkey NOT DEFERRABLE;
+ALTER TABLE keysmith_rotations ALTER CONSTRAINT keysmith_rotations_key_id_fkey NOT DEFERRABLE;
+ALTER TABLE keysmith_key_endpoint_seen ALTER CONSTRAINT keysmith_key_endpoint_seen_key_id_fkey NOT DEFERRABLE;
+`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_policy_base",
+			Version: "20240101000018",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_policies ADD COLUMN IF NOT EXISTS base_policy_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_keysmith_policies_base ON keysmith_policies (base_policy_id) WHERE base_policy_id IS NOT NULL;
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+DROP INDEX IF EXISTS idx_keysmith_policies_base;
+ALTER TABLE keysmith_policies DROP COLUMN IF EXISTS base_policy_id;
+`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_key_updated_index",
+			Version: "20240101000019",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_keysmith_keys_updated ON keysmith_keys (tenant_id, updated_at);`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `DROP INDEX IF EXISTS idx_keysmith_keys_updated;`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_key_accepted_terms",
+			Version: "20240101000020",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS accepted_terms_version TEXT NOT NULL DEFAULT '';
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS accepted_terms_at TIMESTAMPTZ;
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_keys DROP COLUMN IF EXISTS accepted_terms_version;
+ALTER TABLE keysmith_keys DROP COLUMN IF EXISTS accepted_terms_at;
+`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_contacts",
+			Version: "20240101000021",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS contacts JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE keysmith_tenant_settings ADD COLUMN IF NOT EXISTS default_contacts JSONB NOT NULL DEFAULT '[]';
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_keys DROP COLUMN IF EXISTS contacts;
+ALTER TABLE keysmith_tenant_settings DROP COLUMN IF EXISTS default_contacts;
+`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_tenant_ip_handling",
+			Version: "20240101000022",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_tenant_settings ADD COLUMN IF NOT EXISTS ip_handling TEXT NOT NULL DEFAULT ''`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_tenant_settings DROP COLUMN IF EXISTS ip_handling`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_quota_timezone",
+			Version: "20240101000023",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_policies ADD COLUMN IF NOT EXISTS quota_timezone TEXT NOT NULL DEFAULT '';
+ALTER TABLE keysmith_tenant_settings ADD COLUMN IF NOT EXISTS quota_timezone TEXT NOT NULL DEFAULT '';
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_policies DROP COLUMN IF EXISTS quota_timezone;
+ALTER TABLE keysmith_tenant_settings DROP COLUMN IF EXISTS quota_timezone;
+`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_keysmith_key_hashes",
+			Version: "20240101000024",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS keysmith_key_hashes (
+    hash       TEXT PRIMARY KEY,
+    key_id     TEXT NOT NULL REFERENCES keysmith_keys(id) ON DELETE CASCADE DEFERRABLE INITIALLY IMMEDIATE,
+    scheme     TEXT NOT NULL DEFAULT '',
+    active     BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_hashes_key ON keysmith_key_hashes (key_id, created_at);
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `DROP TABLE IF EXISTS keysmith_key_hashes`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "backfill_keysmith_key_hashes",
+			Version: "20240101000025",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				// Every existing key gets an active version for its current
+				// hash, so lookups through the table find it.
+				_, err := exec.Exec(ctx, `
+INSERT INTO keysmith_key_hashes (hash, key_id, scheme, active, created_at)
+SELECT key_hash, id,
+    CASE WHEN position(':' IN key_hash) > 0 THEN lower(split_part(key_hash, ':', 1)) ELSE '' END,
+    TRUE, created_at
+FROM keysmith_keys
+ON CONFLICT (hash) DO NOTHING;
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `DELETE FROM keysmith_key_hashes`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_rotation_tenant_index",
+			Version: "20240101000026",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_keysmith_rotations_tenant ON keysmith_rotations (tenant_id, created_at DESC);`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `DROP INDEX IF EXISTS idx_keysmith_rotations_tenant;`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_transport_requirements",
+			Version: "20240101000027",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS cert_fingerprint TEXT NOT NULL DEFAULT '';
+ALTER TABLE keysmith_policies ADD COLUMN IF NOT EXISTS min_tls_version TEXT NOT NULL DEFAULT '';
+ALTER TABLE keysmith_policies ADD COLUMN IF NOT EXISTS require_mtls BOOLEAN NOT NULL DEFAULT FALSE;
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_keys DROP COLUMN IF EXISTS cert_fingerprint;
+ALTER TABLE keysmith_policies DROP COLUMN IF EXISTS min_tls_version;
+ALTER TABLE keysmith_policies DROP COLUMN IF EXISTS require_mtls;
+`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_keysmith_key_labels",
+			Version: "20240101000028",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}';
+
+CREATE TABLE IF NOT EXISTS keysmith_key_labels (
+    key_id TEXT NOT NULL REFERENCES keysmith_keys(id) ON DELETE CASCADE DEFERRABLE INITIALLY IMMEDIATE,
+    name   TEXT NOT NULL,
+    value  TEXT NOT NULL,
+    PRIMARY KEY (key_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_labels_name_value ON keysmith_key_labels (name, value, key_id);
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+DROP TABLE IF EXISTS keysmith_key_labels;
+ALTER TABLE keysmith_keys DROP COLUMN IF EXISTS labels;
+`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_keysmith_tenant_revisions",
+			Version: "20240101000029",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS keysmith_tenant_revisions (
+    tenant_id  TEXT PRIMARY KEY,
+    revision   BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `DROP TABLE IF EXISTS keysmith_tenant_revisions`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_keysmith_locks",
+			Version: "20240101000030",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS keysmith_locks (
+    name       TEXT PRIMARY KEY,
+    holder     TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `DROP TABLE IF EXISTS keysmith_locks`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_scope_rate_limit",
+			Version: "20240101000031",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_scopes ADD COLUMN IF NOT EXISTS rate_limit INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE keysmith_scopes ADD COLUMN IF NOT EXISTS rate_limit_window BIGINT NOT NULL DEFAULT 0;
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_scopes DROP COLUMN IF EXISTS rate_limit;
+ALTER TABLE keysmith_scopes DROP COLUMN IF EXISTS rate_limit_window;
+`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_keysmith_key_groups",
+			Version: "20240101000032",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS keysmith_key_groups (
+    id          TEXT PRIMARY KEY,
+    tenant_id   TEXT NOT NULL,
+    app_id      TEXT NOT NULL,
+    name        TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_groups_tenant ON keysmith_key_groups (tenant_id, app_id, name);
+
+CREATE TABLE IF NOT EXISTS keysmith_key_group_members (
+    group_id TEXT NOT NULL REFERENCES keysmith_key_groups(id) ON DELETE CASCADE DEFERRABLE INITIALLY IMMEDIATE,
+    key_id   TEXT NOT NULL REFERENCES keysmith_keys(id) ON DELETE CASCADE DEFERRABLE INITIALLY IMMEDIATE,
+    PRIMARY KEY (group_id, key_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_group_members_key ON keysmith_key_group_members (key_id);
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+DROP TABLE IF EXISTS keysmith_key_group_members;
+DROP TABLE IF EXISTS keysmith_key_groups;
+`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_key_external_ref",
+			Version: "20240101000033",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS external_ref TEXT NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_keysmith_keys_external_ref
+    ON keysmith_keys (tenant_id, external_ref) WHERE external_ref <> '';
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+DROP INDEX IF EXISTS idx_keysmith_keys_external_ref;
+ALTER TABLE keysmith_keys DROP COLUMN IF EXISTS external_ref;
+`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_key_events",
+			Version: "20240101000034",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS keysmith_key_events (
+    at          TIMESTAMPTZ NOT NULL,
+    key_id      TEXT NOT NULL,
+    type        TEXT NOT NULL,
+    tenant_id   TEXT NOT NULL,
+    app_id      TEXT NOT NULL DEFAULT '',
+    state       TEXT NOT NULL,
+    reason      TEXT NOT NULL DEFAULT '',
+    reason_code TEXT NOT NULL DEFAULT '',
+    actor_id    TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (at, key_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_events_tenant ON keysmith_key_events (tenant_id, at, key_id);
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `DROP TABLE IF EXISTS keysmith_key_events`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_unique_key_names",
+			Version: "20240101000035",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_tenant_settings ADD COLUMN IF NOT EXISTS unique_key_names BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_keys_tenant_name ON keysmith_keys (tenant_id, lower(name));
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+DROP INDEX IF EXISTS idx_keysmith_keys_tenant_name;
+ALTER TABLE keysmith_tenant_settings DROP COLUMN IF EXISTS unique_key_names;
+`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_usage_environment",
+			Version: "20240101000036",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_usage ADD COLUMN IF NOT EXISTS environment TEXT NOT NULL DEFAULT '';
+
+UPDATE keysmith_usage u SET environment = k.environment
+FROM keysmith_keys k
+WHERE u.key_id = k.id AND u.environment = '';
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_usage_tenant_env ON keysmith_usage (tenant_id, environment, created_at);
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+DROP INDEX IF EXISTS idx_keysmith_usage_tenant_env;
+ALTER TABLE keysmith_usage DROP COLUMN IF EXISTS environment;
+`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_key_transfers",
+			Version: "20240101000037",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS keysmith_key_transfers (
+    id               TEXT PRIMARY KEY,
+    key_id           TEXT NOT NULL REFERENCES keysmith_keys(id) ON DELETE CASCADE DEFERRABLE INITIALLY IMMEDIATE,
+    app_id           TEXT NOT NULL,
+    source_tenant_id TEXT NOT NULL,
+    target_tenant_id TEXT NOT NULL,
+    state            TEXT NOT NULL,
+    retag_usage      BOOLEAN NOT NULL DEFAULT FALSE,
+    initiated_by     TEXT NOT NULL DEFAULT '',
+    resolved_by      TEXT NOT NULL DEFAULT '',
+    unmapped_scopes  JSONB NOT NULL DEFAULT '[]',
+    unmapped_policy  TEXT NOT NULL DEFAULT '',
+    expires_at       TIMESTAMPTZ NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    resolved_at      TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_keysmith_key_transfers_pending
+    ON keysmith_key_transfers (key_id) WHERE state = 'pending';
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_transfers_source ON keysmith_key_transfers (source_tenant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_transfers_target ON keysmith_key_transfers (target_tenant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_transfers_expiry ON keysmith_key_transfers (expires_at) WHERE state = 'pending';
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `DROP TABLE IF EXISTS keysmith_key_transfers`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_policy_inline",
+			Version: "20240101000038",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_policies ADD COLUMN IF NOT EXISTS inline BOOLEAN NOT NULL DEFAULT FALSE`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_policies DROP COLUMN IF EXISTS inline`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_keysmith_hash_tombstones",
+			Version: "20240101000039",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS keysmith_hash_tombstones (
+    hash       TEXT PRIMARY KEY,
+    key_id     TEXT NOT NULL,
+    tenant_id  TEXT NOT NULL,
+    app_id     TEXT NOT NULL,
+    reason     TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_hash_tombstones_tenant ON keysmith_hash_tombstones (tenant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_keysmith_hash_tombstones_created ON keysmith_hash_tombstones (created_at);
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `DROP TABLE IF EXISTS keysmith_hash_tombstones`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_keysmith_keys_search",
+			Version: "20240101000040",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS search_text TEXT NOT NULL DEFAULT '';
+
+-- Keys stored before search existed are indexed from their raw columns
+-- until their next update writes the exact text.
+UPDATE keysmith_keys
+SET search_text = lower(regexp_replace(name || ' ' || coalesce(description, '') || ' ' || metadata::text, '[^[:alnum:]]+', ' ', 'g'))
+WHERE search_text = '';
+
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS search_tsv tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple'::regconfig, search_text)) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_keys_search ON keysmith_keys USING GIN (search_tsv);
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+DROP INDEX IF EXISTS idx_keysmith_keys_search;
+ALTER TABLE keysmith_keys DROP COLUMN IF EXISTS search_tsv;
+ALTER TABLE keysmith_keys DROP COLUMN IF EXISTS search_text;
+`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_tenant_lockdown",
+			Version: "20240101000041",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_tenant_settings ADD COLUMN IF NOT EXISTS lockdown JSONB`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_tenant_settings DROP COLUMN IF EXISTS lockdown`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_keysmith_quota_pools",
+			Version: "20240101000042",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS keysmith_quota_pools (
+    id             TEXT PRIMARY KEY,
+    tenant_id      TEXT NOT NULL,
+    app_id         TEXT NOT NULL,
+    name           TEXT NOT NULL,
+    description    TEXT,
+    daily_limit    BIGINT NOT NULL DEFAULT 0,
+    monthly_limit  BIGINT NOT NULL DEFAULT 0,
+    quota_timezone TEXT NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_quota_pools_tenant ON keysmith_quota_pools (tenant_id, app_id, name);
+
+CREATE TABLE IF NOT EXISTS keysmith_quota_usage (
+    pool_id      TEXT NOT NULL DEFAULT '',
+    key_id       TEXT NOT NULL,
+    period       TEXT NOT NULL,
+    window_start TIMESTAMPTZ NOT NULL,
+    count        BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (pool_id, key_id, period, window_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_quota_usage_window ON keysmith_quota_usage (window_start);
+
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS pool_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_keysmith_keys_pool ON keysmith_keys (pool_id) WHERE pool_id IS NOT NULL;
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+DROP INDEX IF EXISTS idx_keysmith_keys_pool;
+ALTER TABLE keysmith_keys DROP COLUMN IF EXISTS pool_id;
+DROP TABLE IF EXISTS keysmith_quota_usage;
+DROP TABLE IF EXISTS keysmith_quota_pools;
+`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_keysmith_meta",
+			Version: "20240101000043",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS keysmith_meta (
+    name  TEXT PRIMARY KEY,
+    value TEXT NOT NULL
+);
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `DROP TABLE IF EXISTS keysmith_meta`)
+				return err
+			},
+		},
 	)
 }
 
@@ -344,4 +1139,323 @@ CREATE INDEX IF NOT EXISTS idx_keysmith_usage_agg_tenant ON keysmith_usage_agg (
 
 CREATE INDEX IF NOT EXISTS idx_keysmith_rotations_key ON keysmith_rotations (key_id, created_at DESC);
 CREATE INDEX IF NOT EXISTS idx_keysmith_rotations_grace ON keysmith_rotations (grace_ends) WHERE grace_ends IS NOT NULL;`,
+
+	// 006_tenant_settings.sql
+	`CREATE TABLE IF NOT EXISTS keysmith_tenant_settings (
+    tenant_id       TEXT PRIMARY KEY,
+    app_id          TEXT NOT NULL,
+    default_scopes  JSONB NOT NULL DEFAULT '[]',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);`,
+
+	// 007_key_endpoint_seen.sql
+	`CREATE TABLE IF NOT EXISTS keysmith_key_endpoint_seen (
+    key_id       TEXT NOT NULL REFERENCES keysmith_keys(id) ON DELETE CASCADE,
+    endpoint     TEXT NOT NULL,
+    method       TEXT NOT NULL,
+    last_seen_at TIMESTAMPTZ NOT NULL,
+    count        BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (key_id, endpoint, method)
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_endpoint_seen_last ON keysmith_key_endpoint_seen (key_id, last_seen_at DESC);`,
+
+	// 008_key_revocations.sql
+	`CREATE TABLE IF NOT EXISTS keysmith_key_revocations (
+    at          TIMESTAMPTZ NOT NULL,
+    key_id      TEXT NOT NULL,
+    tenant_id   TEXT NOT NULL,
+    hash_prefix TEXT NOT NULL,
+    state       TEXT NOT NULL,
+    revoked     BOOLEAN NOT NULL,
+    PRIMARY KEY (at, key_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_revocations_tenant ON keysmith_key_revocations (tenant_id, at, key_id);`,
+
+	// 009_debug_captures.sql
+	`ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS debug_sample_rate DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS debug_until TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS keysmith_debug_captures (
+    id             TEXT PRIMARY KEY,
+    key_id         TEXT NOT NULL,
+    tenant_id      TEXT NOT NULL,
+    method         TEXT NOT NULL,
+    path           TEXT NOT NULL,
+    headers        JSONB,
+    body           TEXT,
+    body_truncated BOOLEAN NOT NULL DEFAULT FALSE,
+    captured_at    TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_debug_captures_key ON keysmith_debug_captures (key_id, captured_at DESC);
+CREATE INDEX IF NOT EXISTS idx_keysmith_debug_captures_captured ON keysmith_debug_captures (captured_at);`,
+
+	// 010_rotation_hints.sql
+	`ALTER TABLE keysmith_rotations ADD COLUMN IF NOT EXISTS old_hint TEXT;
+ALTER TABLE keysmith_rotations ADD COLUMN IF NOT EXISTS new_hint TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_keys_prefix_hint ON keysmith_keys (prefix, hint);`,
+
+	// 011_app_scoping.sql
+	`ALTER TABLE keysmith_usage ADD COLUMN IF NOT EXISTS app_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE keysmith_rotations ADD COLUMN IF NOT EXISTS app_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_keys_app ON keysmith_keys (tenant_id, app_id);
+CREATE INDEX IF NOT EXISTS idx_keysmith_policies_app ON keysmith_policies (tenant_id, app_id);
+CREATE INDEX IF NOT EXISTS idx_keysmith_scopes_app ON keysmith_scopes (tenant_id, app_id);`,
+
+	// 012_tenant_metadata_schema.sql
+	`ALTER TABLE keysmith_tenant_settings ADD COLUMN IF NOT EXISTS metadata_schema JSONB;`,
+
+	// 013_key_allowed_origins.sql
+	`ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS allowed_origins JSONB NOT NULL DEFAULT '[]';`,
+
+	// 014_tenant_rate_limit.sql
+	`ALTER TABLE keysmith_tenant_settings ADD COLUMN IF NOT EXISTS rate_limit INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE keysmith_tenant_settings ADD COLUMN IF NOT EXISTS rate_limit_window BIGINT NOT NULL DEFAULT 0;`,
+
+	// 015_key_intended_consumer.sql
+	`ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS intended_consumer TEXT NOT NULL DEFAULT '';
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS enforce_consumer BOOLEAN NOT NULL DEFAULT FALSE;`,
+
+	// 016_key_flags.sql
+	`ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS flags JSONB NOT NULL DEFAULT '[]';`,
+
+	// 017_deferrable_key_refs.sql
+	`ALTER TABLE keysmith_key_scopes ALTER CONSTRAINT keysmith_key_scopes_key_id_fkey DEFERRABLE INITIALLY IMMEDIATE;
+ALTER TABLE keysmith_key_scopes ALTER CONSTRAINT keysmith_key_scopes_scope_id_fkey DEFERRABLE INITIALLY IMMEDIATE;
+ALTER TABLE keysmith_usage ALTER CONSTRAINT keysmith_usage_key_id_fkey DEFERRABLE INITIALLY IMMEDIATE;
+ALTER TABLE keysmith_rotations ALTER CONSTRAINT keysmith_rotations_key_id_fkey DEFERRABLE INITIALLY IMMEDIATE;
+ALTER TABLE keysmith_key_endpoint_seen ALTER CONSTRAINT keysmith_key_endpoint_seen_key_id_fkey DEFERRABLE INITIALLY IMMEDIATE;`,
+
+	// 018_policy_base.sql
+	`ALTER TABLE keysmith_policies ADD COLUMN IF NOT EXISTS base_policy_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_keysmith_policies_base ON keysmith_policies (base_policy_id) WHERE base_policy_id IS NOT NULL;`,
+
+	// 019_key_updated_index.sql
+	`CREATE INDEX IF NOT EXISTS idx_keysmith_keys_updated ON keysmith_keys (tenant_id, updated_at);`,
+
+	// 020_key_accepted_terms.sql
+	`ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS accepted_terms_version TEXT NOT NULL DEFAULT '';
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS accepted_terms_at TIMESTAMPTZ;`,
+
+	// 021_contacts.sql
+	`ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS contacts JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE keysmith_tenant_settings ADD COLUMN IF NOT EXISTS default_contacts JSONB NOT NULL DEFAULT '[]';`,
+
+	// 022_tenant_ip_handling.sql
+	`ALTER TABLE keysmith_tenant_settings ADD COLUMN IF NOT EXISTS ip_handling TEXT NOT NULL DEFAULT '';`,
+
+	// 023_quota_timezone.sql
+	`ALTER TABLE keysmith_policies ADD COLUMN IF NOT EXISTS quota_timezone TEXT NOT NULL DEFAULT '';
+ALTER TABLE keysmith_tenant_settings ADD COLUMN IF NOT EXISTS quota_timezone TEXT NOT NULL DEFAULT '';`,
+	// 024_key_hashes.sql
+	`CREATE TABLE IF NOT EXISTS keysmith_key_hashes (
+    hash       TEXT PRIMARY KEY,
+    key_id     TEXT NOT NULL REFERENCES keysmith_keys(id) ON DELETE CASCADE DEFERRABLE INITIALLY IMMEDIATE,
+    scheme     TEXT NOT NULL DEFAULT '',
+    active     BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_hashes_key ON keysmith_key_hashes (key_id, created_at);`,
+
+	// 025_key_hashes_backfill.sql
+	`INSERT INTO keysmith_key_hashes (hash, key_id, scheme, active, created_at)
+SELECT key_hash, id,
+    CASE WHEN position(':' IN key_hash) > 0 THEN lower(split_part(key_hash, ':', 1)) ELSE '' END,
+    TRUE, created_at
+FROM keysmith_keys
+ON CONFLICT (hash) DO NOTHING;`,
+
+	// 026_rotation_tenant_index.sql
+	`CREATE INDEX IF NOT EXISTS idx_keysmith_rotations_tenant ON keysmith_rotations (tenant_id, created_at DESC);`,
+
+	// 027_transport_requirements.sql
+	`ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS cert_fingerprint TEXT NOT NULL DEFAULT '';
+ALTER TABLE keysmith_policies ADD COLUMN IF NOT EXISTS min_tls_version TEXT NOT NULL DEFAULT '';
+ALTER TABLE keysmith_policies ADD COLUMN IF NOT EXISTS require_mtls BOOLEAN NOT NULL DEFAULT FALSE;`,
+
+	// 028_key_labels.sql
+	`ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}';
+
+CREATE TABLE IF NOT EXISTS keysmith_key_labels (
+    key_id TEXT NOT NULL REFERENCES keysmith_keys(id) ON DELETE CASCADE DEFERRABLE INITIALLY IMMEDIATE,
+    name   TEXT NOT NULL,
+    value  TEXT NOT NULL,
+    PRIMARY KEY (key_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_labels_name_value ON keysmith_key_labels (name, value, key_id);`,
+
+	// 029_tenant_revisions.sql
+	`CREATE TABLE IF NOT EXISTS keysmith_tenant_revisions (
+    tenant_id  TEXT PRIMARY KEY,
+    revision   BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);`,
+
+	// 030_locks.sql
+	`CREATE TABLE IF NOT EXISTS keysmith_locks (
+    name       TEXT PRIMARY KEY,
+    holder     TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);`,
+
+	// 031_scope_rate_limit.sql
+	`ALTER TABLE keysmith_scopes ADD COLUMN IF NOT EXISTS rate_limit INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE keysmith_scopes ADD COLUMN IF NOT EXISTS rate_limit_window BIGINT NOT NULL DEFAULT 0;`,
+
+	// 032_key_groups.sql
+	`CREATE TABLE IF NOT EXISTS keysmith_key_groups (
+    id          TEXT PRIMARY KEY,
+    tenant_id   TEXT NOT NULL,
+    app_id      TEXT NOT NULL,
+    name        TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_groups_tenant ON keysmith_key_groups (tenant_id, app_id, name);
+
+CREATE TABLE IF NOT EXISTS keysmith_key_group_members (
+    group_id TEXT NOT NULL REFERENCES keysmith_key_groups(id) ON DELETE CASCADE DEFERRABLE INITIALLY IMMEDIATE,
+    key_id   TEXT NOT NULL REFERENCES keysmith_keys(id) ON DELETE CASCADE DEFERRABLE INITIALLY IMMEDIATE,
+    PRIMARY KEY (group_id, key_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_group_members_key ON keysmith_key_group_members (key_id);`,
+
+	// 033_key_external_ref.sql
+	`ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS external_ref TEXT NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_keysmith_keys_external_ref
+    ON keysmith_keys (tenant_id, external_ref) WHERE external_ref <> '';`,
+
+	// 034_key_events.sql
+	`CREATE TABLE IF NOT EXISTS keysmith_key_events (
+    at          TIMESTAMPTZ NOT NULL,
+    key_id      TEXT NOT NULL,
+    type        TEXT NOT NULL,
+    tenant_id   TEXT NOT NULL,
+    app_id      TEXT NOT NULL DEFAULT '',
+    state       TEXT NOT NULL,
+    reason      TEXT NOT NULL DEFAULT '',
+    reason_code TEXT NOT NULL DEFAULT '',
+    actor_id    TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (at, key_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_events_tenant ON keysmith_key_events (tenant_id, at, key_id);`,
+
+	// 035_unique_key_names.sql
+	`ALTER TABLE keysmith_tenant_settings ADD COLUMN IF NOT EXISTS unique_key_names BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_keys_tenant_name ON keysmith_keys (tenant_id, lower(name));`,
+
+	// 036_usage_environment.sql
+	`ALTER TABLE keysmith_usage ADD COLUMN IF NOT EXISTS environment TEXT NOT NULL DEFAULT '';
+
+UPDATE keysmith_usage u SET environment = k.environment
+FROM keysmith_keys k
+WHERE u.key_id = k.id AND u.environment = '';
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_usage_tenant_env ON keysmith_usage (tenant_id, environment, created_at);`,
+
+	// 037_key_transfers.sql
+	`CREATE TABLE IF NOT EXISTS keysmith_key_transfers (
+    id               TEXT PRIMARY KEY,
+    key_id           TEXT NOT NULL REFERENCES keysmith_keys(id) ON DELETE CASCADE DEFERRABLE INITIALLY IMMEDIATE,
+    app_id           TEXT NOT NULL,
+    source_tenant_id TEXT NOT NULL,
+    target_tenant_id TEXT NOT NULL,
+    state            TEXT NOT NULL,
+    retag_usage      BOOLEAN NOT NULL DEFAULT FALSE,
+    initiated_by     TEXT NOT NULL DEFAULT '',
+    resolved_by      TEXT NOT NULL DEFAULT '',
+    unmapped_scopes  JSONB NOT NULL DEFAULT '[]',
+    unmapped_policy  TEXT NOT NULL DEFAULT '',
+    expires_at       TIMESTAMPTZ NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    resolved_at      TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_keysmith_key_transfers_pending
+    ON keysmith_key_transfers (key_id) WHERE state = 'pending';
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_transfers_source ON keysmith_key_transfers (source_tenant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_transfers_target ON keysmith_key_transfers (target_tenant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_transfers_expiry ON keysmith_key_transfers (expires_at) WHERE state = 'pending';`,
+
+	// 038_policy_inline.sql
+	`ALTER TABLE keysmith_policies ADD COLUMN IF NOT EXISTS inline BOOLEAN NOT NULL DEFAULT FALSE;`,
+
+	// 039_hash_tombstones.sql
+	`CREATE TABLE IF NOT EXISTS keysmith_hash_tombstones (
+    hash       TEXT PRIMARY KEY,
+    key_id     TEXT NOT NULL,
+    tenant_id  TEXT NOT NULL,
+    app_id     TEXT NOT NULL,
+    reason     TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_hash_tombstones_tenant ON keysmith_hash_tombstones (tenant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_keysmith_hash_tombstones_created ON keysmith_hash_tombstones (created_at);`,
+
+	// 040_key_search.sql
+	`ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS search_text TEXT NOT NULL DEFAULT '';
+
+-- Keys stored before search existed are indexed from their raw columns
+-- until their next update writes the exact text.
+UPDATE keysmith_keys
+SET search_text = lower(regexp_replace(name || ' ' || coalesce(description, '') || ' ' || metadata::text, '[^[:alnum:]]+', ' ', 'g'))
+WHERE search_text = '';
+
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS search_tsv tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple'::regconfig, search_text)) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_keys_search ON keysmith_keys USING GIN (search_tsv);`,
+
+	// 041_tenant_lockdown.sql
+	`ALTER TABLE keysmith_tenant_settings ADD COLUMN IF NOT EXISTS lockdown JSONB;`,
+
+	// 042_quota_pools.sql
+	`CREATE TABLE IF NOT EXISTS keysmith_quota_pools (
+    id             TEXT PRIMARY KEY,
+    tenant_id      TEXT NOT NULL,
+    app_id         TEXT NOT NULL,
+    name           TEXT NOT NULL,
+    description    TEXT,
+    daily_limit    BIGINT NOT NULL DEFAULT 0,
+    monthly_limit  BIGINT NOT NULL DEFAULT 0,
+    quota_timezone TEXT NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_quota_pools_tenant ON keysmith_quota_pools (tenant_id, app_id, name);
+
+CREATE TABLE IF NOT EXISTS keysmith_quota_usage (
+    pool_id      TEXT NOT NULL DEFAULT '',
+    key_id       TEXT NOT NULL,
+    period       TEXT NOT NULL,
+    window_start TIMESTAMPTZ NOT NULL,
+    count        BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (pool_id, key_id, period, window_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_quota_usage_window ON keysmith_quota_usage (window_start);
+
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS pool_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_keysmith_keys_pool ON keysmith_keys (pool_id) WHERE pool_id IS NOT NULL;`,
+
+	// 043_meta.sql
+	`CREATE TABLE IF NOT EXISTS keysmith_meta (
+    name  TEXT PRIMARY KEY,
+    value TEXT NOT NULL
+);`,
 }
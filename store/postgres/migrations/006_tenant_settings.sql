@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS keysmith_tenant_settings (
+    tenant_id       TEXT PRIMARY KEY,
+    app_id          TEXT NOT NULL,
+    default_scopes  JSONB NOT NULL DEFAULT '[]',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS keysmith_key_endpoint_seen (
+    key_id       TEXT NOT NULL REFERENCES keysmith_keys(id) ON DELETE CASCADE,
+    endpoint     TEXT NOT NULL,
+    method       TEXT NOT NULL,
+    last_seen_at TIMESTAMPTZ NOT NULL,
+    count        BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (key_id, endpoint, method)
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_endpoint_seen_last ON keysmith_key_endpoint_seen (key_id, last_seen_at DESC);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS keysmith_key_revocations (
+    at          TIMESTAMPTZ NOT NULL,
+    key_id      TEXT NOT NULL,
+    tenant_id   TEXT NOT NULL,
+    hash_prefix TEXT NOT NULL,
+    state       TEXT NOT NULL,
+    revoked     BOOLEAN NOT NULL,
+    PRIMARY KEY (at, key_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_revocations_tenant ON keysmith_key_revocations (tenant_id, at, key_id);
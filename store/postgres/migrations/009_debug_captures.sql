@@ -0,0 +1,17 @@
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS debug_sample_rate DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS debug_until TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS keysmith_debug_captures (
+    id             TEXT PRIMARY KEY,
+    key_id         TEXT NOT NULL,
+    tenant_id      TEXT NOT NULL,
+    method         TEXT NOT NULL,
+    path           TEXT NOT NULL,
+    headers        JSONB,
+    body           TEXT,
+    body_truncated BOOLEAN NOT NULL DEFAULT FALSE,
+    captured_at    TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_debug_captures_key ON keysmith_debug_captures (key_id, captured_at DESC);
+CREATE INDEX IF NOT EXISTS idx_keysmith_debug_captures_captured ON keysmith_debug_captures (captured_at);
@@ -0,0 +1,4 @@
+ALTER TABLE keysmith_rotations ADD COLUMN IF NOT EXISTS old_hint TEXT;
+ALTER TABLE keysmith_rotations ADD COLUMN IF NOT EXISTS new_hint TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_keys_prefix_hint ON keysmith_keys (prefix, hint);
@@ -0,0 +1,6 @@
+ALTER TABLE keysmith_usage ADD COLUMN IF NOT EXISTS app_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE keysmith_rotations ADD COLUMN IF NOT EXISTS app_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_keys_app ON keysmith_keys (tenant_id, app_id);
+CREATE INDEX IF NOT EXISTS idx_keysmith_policies_app ON keysmith_policies (tenant_id, app_id);
+CREATE INDEX IF NOT EXISTS idx_keysmith_scopes_app ON keysmith_scopes (tenant_id, app_id);
@@ -0,0 +1 @@
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS allowed_origins JSONB NOT NULL DEFAULT '[]';
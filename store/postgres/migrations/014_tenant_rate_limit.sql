@@ -0,0 +1,2 @@
+ALTER TABLE keysmith_tenant_settings ADD COLUMN IF NOT EXISTS rate_limit INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE keysmith_tenant_settings ADD COLUMN IF NOT EXISTS rate_limit_window BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS intended_consumer TEXT NOT NULL DEFAULT '';
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS enforce_consumer BOOLEAN NOT NULL DEFAULT FALSE;
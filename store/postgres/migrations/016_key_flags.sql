@@ -0,0 +1 @@
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS flags JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,5 @@
+ALTER TABLE keysmith_key_scopes ALTER CONSTRAINT keysmith_key_scopes_key_id_fkey DEFERRABLE INITIALLY IMMEDIATE;
+ALTER TABLE keysmith_key_scopes ALTER CONSTRAINT keysmith_key_scopes_scope_id_fkey DEFERRABLE INITIALLY IMMEDIATE;
+ALTER TABLE keysmith_usage ALTER CONSTRAINT keysmith_usage_key_id_fkey DEFERRABLE INITIALLY IMMEDIATE;
+ALTER TABLE keysmith_rotations ALTER CONSTRAINT keysmith_rotations_key_id_fkey DEFERRABLE INITIALLY IMMEDIATE;
+ALTER TABLE keysmith_key_endpoint_seen ALTER CONSTRAINT keysmith_key_endpoint_seen_key_id_fkey DEFERRABLE INITIALLY IMMEDIATE;
@@ -0,0 +1,2 @@
+ALTER TABLE keysmith_policies ADD COLUMN IF NOT EXISTS base_policy_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_keysmith_policies_base ON keysmith_policies (base_policy_id) WHERE base_policy_id IS NOT NULL;
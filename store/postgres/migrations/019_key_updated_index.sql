@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_keysmith_keys_updated ON keysmith_keys (tenant_id, updated_at);
@@ -0,0 +1,2 @@
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS accepted_terms_version TEXT NOT NULL DEFAULT '';
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS accepted_terms_at TIMESTAMPTZ;
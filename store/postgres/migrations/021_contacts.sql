@@ -0,0 +1,2 @@
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS contacts JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE keysmith_tenant_settings ADD COLUMN IF NOT EXISTS default_contacts JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1 @@
+ALTER TABLE keysmith_tenant_settings ADD COLUMN IF NOT EXISTS ip_handling TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE keysmith_policies ADD COLUMN IF NOT EXISTS quota_timezone TEXT NOT NULL DEFAULT '';
+ALTER TABLE keysmith_tenant_settings ADD COLUMN IF NOT EXISTS quota_timezone TEXT NOT NULL DEFAULT '';
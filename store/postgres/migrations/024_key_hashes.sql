@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS keysmith_key_hashes (
+    hash       TEXT PRIMARY KEY,
+    key_id     TEXT NOT NULL REFERENCES keysmith_keys(id) ON DELETE CASCADE DEFERRABLE INITIALLY IMMEDIATE,
+    scheme     TEXT NOT NULL DEFAULT '',
+    active     BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_hashes_key ON keysmith_key_hashes (key_id, created_at);
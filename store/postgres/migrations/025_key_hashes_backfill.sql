@@ -0,0 +1,6 @@
+INSERT INTO keysmith_key_hashes (hash, key_id, scheme, active, created_at)
+SELECT key_hash, id,
+    CASE WHEN position(':' IN key_hash) > 0 THEN lower(split_part(key_hash, ':', 1)) ELSE '' END,
+    TRUE, created_at
+FROM keysmith_keys
+ON CONFLICT (hash) DO NOTHING;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_keysmith_rotations_tenant ON keysmith_rotations (tenant_id, created_at DESC);
@@ -0,0 +1,3 @@
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS cert_fingerprint TEXT NOT NULL DEFAULT '';
+ALTER TABLE keysmith_policies ADD COLUMN IF NOT EXISTS min_tls_version TEXT NOT NULL DEFAULT '';
+ALTER TABLE keysmith_policies ADD COLUMN IF NOT EXISTS require_mtls BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,10 @@
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}';
+
+CREATE TABLE IF NOT EXISTS keysmith_key_labels (
+    key_id TEXT NOT NULL REFERENCES keysmith_keys(id) ON DELETE CASCADE DEFERRABLE INITIALLY IMMEDIATE,
+    name   TEXT NOT NULL,
+    value  TEXT NOT NULL,
+    PRIMARY KEY (key_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_labels_name_value ON keysmith_key_labels (name, value, key_id);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS keysmith_tenant_revisions (
+    tenant_id  TEXT PRIMARY KEY,
+    revision   BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
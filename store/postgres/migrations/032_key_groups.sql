@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS keysmith_key_groups (
+    id          TEXT PRIMARY KEY,
+    tenant_id   TEXT NOT NULL,
+    app_id      TEXT NOT NULL,
+    name        TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_groups_tenant ON keysmith_key_groups (tenant_id, app_id, name);
+
+CREATE TABLE IF NOT EXISTS keysmith_key_group_members (
+    group_id TEXT NOT NULL REFERENCES keysmith_key_groups(id) ON DELETE CASCADE DEFERRABLE INITIALLY IMMEDIATE,
+    key_id   TEXT NOT NULL REFERENCES keysmith_keys(id) ON DELETE CASCADE DEFERRABLE INITIALLY IMMEDIATE,
+    PRIMARY KEY (group_id, key_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_group_members_key ON keysmith_key_group_members (key_id);
@@ -0,0 +1,4 @@
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS external_ref TEXT NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_keysmith_keys_external_ref
+    ON keysmith_keys (tenant_id, external_ref) WHERE external_ref <> '';
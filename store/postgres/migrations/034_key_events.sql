@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS keysmith_key_events (
+    at          TIMESTAMPTZ NOT NULL,
+    key_id      TEXT NOT NULL,
+    type        TEXT NOT NULL,
+    tenant_id   TEXT NOT NULL,
+    app_id      TEXT NOT NULL DEFAULT '',
+    state       TEXT NOT NULL,
+    reason      TEXT NOT NULL DEFAULT '',
+    reason_code TEXT NOT NULL DEFAULT '',
+    actor_id    TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (at, key_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_events_tenant ON keysmith_key_events (tenant_id, at, key_id);
@@ -0,0 +1,3 @@
+ALTER TABLE keysmith_tenant_settings ADD COLUMN IF NOT EXISTS unique_key_names BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_keys_tenant_name ON keysmith_keys (tenant_id, lower(name));
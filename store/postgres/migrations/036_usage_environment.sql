@@ -0,0 +1,7 @@
+ALTER TABLE keysmith_usage ADD COLUMN IF NOT EXISTS environment TEXT NOT NULL DEFAULT '';
+
+UPDATE keysmith_usage u SET environment = k.environment
+FROM keysmith_keys k
+WHERE u.key_id = k.id AND u.environment = '';
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_usage_tenant_env ON keysmith_usage (tenant_id, environment, created_at);
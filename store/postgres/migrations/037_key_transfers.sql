@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS keysmith_key_transfers (
+    id               TEXT PRIMARY KEY,
+    key_id           TEXT NOT NULL REFERENCES keysmith_keys(id) ON DELETE CASCADE DEFERRABLE INITIALLY IMMEDIATE,
+    app_id           TEXT NOT NULL,
+    source_tenant_id TEXT NOT NULL,
+    target_tenant_id TEXT NOT NULL,
+    state            TEXT NOT NULL,
+    retag_usage      BOOLEAN NOT NULL DEFAULT FALSE,
+    initiated_by     TEXT NOT NULL DEFAULT '',
+    resolved_by      TEXT NOT NULL DEFAULT '',
+    unmapped_scopes  JSONB NOT NULL DEFAULT '[]',
+    unmapped_policy  TEXT NOT NULL DEFAULT '',
+    expires_at       TIMESTAMPTZ NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    resolved_at      TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_keysmith_key_transfers_pending
+    ON keysmith_key_transfers (key_id) WHERE state = 'pending';
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_transfers_source ON keysmith_key_transfers (source_tenant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_transfers_target ON keysmith_key_transfers (target_tenant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_transfers_expiry ON keysmith_key_transfers (expires_at) WHERE state = 'pending';
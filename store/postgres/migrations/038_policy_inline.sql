@@ -0,0 +1 @@
+ALTER TABLE keysmith_policies ADD COLUMN IF NOT EXISTS inline BOOLEAN NOT NULL DEFAULT FALSE;
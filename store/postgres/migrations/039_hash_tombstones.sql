@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS keysmith_hash_tombstones (
+    hash       TEXT PRIMARY KEY,
+    key_id     TEXT NOT NULL,
+    tenant_id  TEXT NOT NULL,
+    app_id     TEXT NOT NULL,
+    reason     TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_hash_tombstones_tenant ON keysmith_hash_tombstones (tenant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_keysmith_hash_tombstones_created ON keysmith_hash_tombstones (created_at);
@@ -0,0 +1,12 @@
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS search_text TEXT NOT NULL DEFAULT '';
+
+-- Keys stored before search existed are indexed from their raw columns
+-- until their next update writes the exact text.
+UPDATE keysmith_keys
+SET search_text = lower(regexp_replace(name || ' ' || coalesce(description, '') || ' ' || metadata::text, '[^[:alnum:]]+', ' ', 'g'))
+WHERE search_text = '';
+
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS search_tsv tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple'::regconfig, search_text)) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_keys_search ON keysmith_keys USING GIN (search_tsv);
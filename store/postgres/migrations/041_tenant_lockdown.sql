@@ -0,0 +1 @@
+ALTER TABLE keysmith_tenant_settings ADD COLUMN IF NOT EXISTS lockdown JSONB;
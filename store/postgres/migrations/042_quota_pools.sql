@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS keysmith_quota_pools (
+    id             TEXT PRIMARY KEY,
+    tenant_id      TEXT NOT NULL,
+    app_id         TEXT NOT NULL,
+    name           TEXT NOT NULL,
+    description    TEXT,
+    daily_limit    BIGINT NOT NULL DEFAULT 0,
+    monthly_limit  BIGINT NOT NULL DEFAULT 0,
+    quota_timezone TEXT NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_quota_pools_tenant ON keysmith_quota_pools (tenant_id, app_id, name);
+
+CREATE TABLE IF NOT EXISTS keysmith_quota_usage (
+    pool_id      TEXT NOT NULL DEFAULT '',
+    key_id       TEXT NOT NULL,
+    period       TEXT NOT NULL,
+    window_start TIMESTAMPTZ NOT NULL,
+    count        BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (pool_id, key_id, period, window_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_quota_usage_window ON keysmith_quota_usage (window_start);
+
+ALTER TABLE keysmith_keys ADD COLUMN IF NOT EXISTS pool_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_keysmith_keys_pool ON keysmith_keys (pool_id) WHERE pool_id IS NOT NULL;
@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS keysmith_meta (
+    name  TEXT PRIMARY KEY,
+    value TEXT NOT NULL
+);
@@ -5,11 +5,20 @@ import (
 
 	"github.com/xraph/grove"
 
+	"github.com/xraph/keysmith/capture"
+	"github.com/xraph/keysmith/group"
 	"github.com/xraph/keysmith/id"
 	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keyevent"
+	"github.com/xraph/keysmith/metaschema"
 	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/quotapool"
+	"github.com/xraph/keysmith/revocation"
 	"github.com/xraph/keysmith/rotation"
 	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/tenant"
+	"github.com/xraph/keysmith/tombstone"
+	"github.com/xraph/keysmith/transfer"
 	"github.com/xraph/keysmith/usage"
 )
 
@@ -30,8 +39,22 @@ type keyModel struct {
 	Environment     string         `grove:"environment,notnull"`
 	State           string         `grove:"state,notnull"`
 	PolicyID        *string        `grove:"policy_id"`
+	PoolID          *string        `grove:"pool_id"`
 	Metadata        map[string]any `grove:"metadata,type:jsonb"`
+	Labels          key.Labels     `grove:"labels,type:jsonb"` // read copy of keysmith_key_labels
 	CreatedBy       string         `grove:"created_by"`
+	ExternalRef     string         `grove:"external_ref,notnull"`
+	AllowedOrigins  []string       `grove:"allowed_origins,type:jsonb"`
+	Consumer        string         `grove:"intended_consumer,notnull"`
+	EnforceConsumer bool           `grove:"enforce_consumer,notnull"`
+	CertFingerprint string         `grove:"cert_fingerprint,notnull"`
+	TermsVersion    string         `grove:"accepted_terms_version,notnull"`
+	TermsAt         *time.Time     `grove:"accepted_terms_at"`
+	Flags           key.Flags      `grove:"flags,type:jsonb"`
+	Contacts        key.Contacts   `grove:"contacts,type:jsonb"`
+	DebugSampleRate float64        `grove:"debug_sample_rate,notnull"`
+	DebugUntil      *time.Time     `grove:"debug_until"`
+	SearchText      string         `grove:"search_text,notnull"` // key.SearchText; search_tsv is generated from it
 	ExpiresAt       *time.Time     `grove:"expires_at"`
 	LastUsedAt      *time.Time     `grove:"last_used_at"`
 	RotatedAt       *time.Time     `grove:"rotated_at"`
@@ -53,21 +76,61 @@ func keyToModel(k *key.Key) *keyModel {
 		Environment: string(k.Environment),
 		State:       string(k.State),
 		Metadata:    k.Metadata,
+		Labels:      k.Labels,
 		CreatedBy:   k.CreatedBy,
-		ExpiresAt:   k.ExpiresAt,
+		ExternalRef: k.ExternalRef,
+		ExpiresAt:   utcTime(k.ExpiresAt),
 		LastUsedAt:  k.LastUsedAt,
 		RotatedAt:   k.RotatedAt,
 		RevokedAt:   k.RevokedAt,
 		CreatedAt:   k.CreatedAt,
 		UpdatedAt:   k.UpdatedAt,
+
+		AllowedOrigins:  k.AllowedOrigins,
+		Consumer:        k.IntendedConsumer,
+		EnforceConsumer: k.EnforceConsumer,
+		CertFingerprint: k.CertFingerprint,
+		TermsVersion:    k.AcceptedTermsVersion,
+		TermsAt:         utcTime(k.AcceptedTermsAt),
+		Flags:           k.Flags,
+		Contacts:        k.Contacts,
+		DebugSampleRate: k.DebugSampleRate,
+		DebugUntil:      utcTime(k.DebugUntil),
+		SearchText:      key.SearchText(k),
+	}
+	if m.AllowedOrigins == nil {
+		m.AllowedOrigins = []string{}
+	}
+	if m.Flags == nil {
+		m.Flags = key.Flags{}
+	}
+	if m.Contacts == nil {
+		m.Contacts = key.Contacts{}
+	}
+	if m.Labels == nil {
+		m.Labels = key.Labels{}
 	}
 	if k.PolicyID != nil {
 		s := k.PolicyID.String()
 		m.PolicyID = &s
 	}
+	if k.PoolID != nil {
+		s := k.PoolID.String()
+		m.PoolID = &s
+	}
 	return m
 }
 
+// utcTime normalizes t to UTC so expiry comparisons do not depend on the
+// writer's local zone.
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
 func keyFromModel(m *keyModel) (*key.Key, error) {
 	kid, err := id.ParseKeyID(m.ID)
 	if err != nil {
@@ -85,13 +148,30 @@ func keyFromModel(m *keyModel) (*key.Key, error) {
 		Environment: key.Environment(m.Environment),
 		State:       key.State(m.State),
 		Metadata:    m.Metadata,
+		Labels:      m.Labels.Clone(),
 		CreatedBy:   m.CreatedBy,
+		ExternalRef: m.ExternalRef,
 		ExpiresAt:   m.ExpiresAt,
 		LastUsedAt:  m.LastUsedAt,
 		RotatedAt:   m.RotatedAt,
 		RevokedAt:   m.RevokedAt,
 		CreatedAt:   m.CreatedAt,
 		UpdatedAt:   m.UpdatedAt,
+
+		AllowedOrigins:  m.AllowedOrigins,
+		DebugSampleRate: m.DebugSampleRate,
+		DebugUntil:      m.DebugUntil,
+
+		IntendedConsumer:     m.Consumer,
+		EnforceConsumer:      m.EnforceConsumer,
+		CertFingerprint:      m.CertFingerprint,
+		AcceptedTermsVersion: m.TermsVersion,
+		AcceptedTermsAt:      m.TermsAt,
+		Flags:                m.Flags.Normalize(),
+		Contacts:             m.Contacts.Normalize(),
+	}
+	if len(k.AllowedOrigins) == 0 {
+		k.AllowedOrigins = nil
 	}
 	if m.PolicyID != nil {
 		pid, err := id.ParsePolicyID(*m.PolicyID)
@@ -100,6 +180,13 @@ func keyFromModel(m *keyModel) (*key.Key, error) {
 		}
 		k.PolicyID = &pid
 	}
+	if m.PoolID != nil {
+		pid, err := id.ParseQuotaPoolID(*m.PoolID)
+		if err != nil {
+			return nil, err
+		}
+		k.PoolID = &pid
+	}
 	return k, nil
 }
 
@@ -114,6 +201,7 @@ type policyModel struct {
 	AppID           string         `grove:"app_id,notnull"`
 	Name            string         `grove:"name,notnull"`
 	Description     string         `grove:"description"`
+	BasePolicyID    *string        `grove:"base_policy_id"`
 	RateLimit       int            `grove:"rate_limit,notnull"`
 	RateLimitWindow int64          `grove:"rate_limit_window,notnull"`
 	BurstLimit      int            `grove:"burst_limit,notnull"`
@@ -127,13 +215,17 @@ type policyModel struct {
 	GracePeriod     int64          `grove:"grace_period,notnull"`
 	DailyQuota      int64          `grove:"daily_quota,notnull"`
 	MonthlyQuota    int64          `grove:"monthly_quota,notnull"`
+	QuotaTimezone   string         `grove:"quota_timezone,notnull"`
+	MinTLSVersion   string         `grove:"min_tls_version,notnull"`
+	RequireMTLS     bool           `grove:"require_mtls,notnull"`
+	Inline          bool           `grove:"inline,notnull"`
 	Metadata        map[string]any `grove:"metadata,type:jsonb"`
 	CreatedAt       time.Time      `grove:"created_at,notnull"`
 	UpdatedAt       time.Time      `grove:"updated_at,notnull"`
 }
 
 func policyToModel(pol *policy.Policy) *policyModel {
-	return &policyModel{
+	m := &policyModel{
 		ID:              pol.ID.String(),
 		TenantID:        pol.TenantID,
 		AppID:           pol.AppID,
@@ -152,10 +244,19 @@ func policyToModel(pol *policy.Policy) *policyModel {
 		GracePeriod:     pol.GracePeriod.Milliseconds(),
 		DailyQuota:      pol.DailyQuota,
 		MonthlyQuota:    pol.MonthlyQuota,
+		QuotaTimezone:   pol.QuotaTimezone,
+		MinTLSVersion:   pol.MinTLSVersion,
+		RequireMTLS:     pol.RequireMTLS,
+		Inline:          pol.Inline,
 		Metadata:        pol.Metadata,
 		CreatedAt:       pol.CreatedAt,
 		UpdatedAt:       pol.UpdatedAt,
 	}
+	if pol.BasePolicyID != nil {
+		bid := pol.BasePolicyID.String()
+		m.BasePolicyID = &bid
+	}
+	return m
 }
 
 func policyFromModel(m *policyModel) (*policy.Policy, error) {
@@ -163,12 +264,21 @@ func policyFromModel(m *policyModel) (*policy.Policy, error) {
 	if err != nil {
 		return nil, err
 	}
+	var basePolicyID *id.PolicyID
+	if m.BasePolicyID != nil {
+		bid, err := id.ParsePolicyID(*m.BasePolicyID)
+		if err != nil {
+			return nil, err
+		}
+		basePolicyID = &bid
+	}
 	return &policy.Policy{
 		ID:              pid,
 		TenantID:        m.TenantID,
 		AppID:           m.AppID,
 		Name:            m.Name,
 		Description:     m.Description,
+		BasePolicyID:    basePolicyID,
 		RateLimit:       m.RateLimit,
 		RateLimitWindow: time.Duration(m.RateLimitWindow) * time.Millisecond,
 		BurstLimit:      m.BurstLimit,
@@ -182,6 +292,10 @@ func policyFromModel(m *policyModel) (*policy.Policy, error) {
 		GracePeriod:     time.Duration(m.GracePeriod) * time.Millisecond,
 		DailyQuota:      m.DailyQuota,
 		MonthlyQuota:    m.MonthlyQuota,
+		QuotaTimezone:   m.QuotaTimezone,
+		MinTLSVersion:   m.MinTLSVersion,
+		RequireMTLS:     m.RequireMTLS,
+		Inline:          m.Inline,
 		Metadata:        m.Metadata,
 		CreatedAt:       m.CreatedAt,
 		UpdatedAt:       m.UpdatedAt,
@@ -201,9 +315,50 @@ type scopeModel struct {
 	Description     string         `grove:"description"`
 	Parent          *string        `grove:"parent"`
 	Metadata        map[string]any `grove:"metadata,type:jsonb"`
+	RateLimit       int            `grove:"rate_limit,notnull"`
+	RateLimitWindow int64          `grove:"rate_limit_window,notnull"`
 	CreatedAt       time.Time      `grove:"created_at,notnull"`
 }
 
+// keyHashModel is one hash version of a key. Lookups by hash go through
+// this table; the key's key_hash is its current version.
+type keyHashModel struct {
+	grove.BaseModel `grove:"table:keysmith_key_hashes"`
+	Hash            string    `grove:"hash,pk"`
+	KeyID           string    `grove:"key_id,notnull"`
+	Scheme          string    `grove:"scheme,notnull"`
+	Active          bool      `grove:"active,notnull"`
+	CreatedAt       time.Time `grove:"created_at,notnull"`
+}
+
+func keyHashToModel(h *key.HashVersion) *keyHashModel {
+	scheme := h.Scheme
+	if scheme == "" {
+		scheme = key.HashScheme(h.Hash)
+	}
+	return &keyHashModel{
+		Hash:      h.Hash,
+		KeyID:     h.KeyID.String(),
+		Scheme:    scheme,
+		Active:    h.Active,
+		CreatedAt: h.CreatedAt.UTC(),
+	}
+}
+
+func keyHashFromModel(m *keyHashModel) (*key.HashVersion, error) {
+	kid, err := id.ParseKeyID(m.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	return &key.HashVersion{
+		KeyID:     kid,
+		Hash:      m.Hash,
+		Scheme:    m.Scheme,
+		Active:    m.Active,
+		CreatedAt: m.CreatedAt,
+	}, nil
+}
+
 // keyScopeModel represents the join table for key-scope assignments.
 type keyScopeModel struct {
 	grove.BaseModel `grove:"table:keysmith_key_scopes"`
@@ -213,13 +368,15 @@ type keyScopeModel struct {
 
 func scopeToModel(sc *scope.Scope) *scopeModel {
 	m := &scopeModel{
-		ID:          sc.ID.String(),
-		TenantID:    sc.TenantID,
-		AppID:       sc.AppID,
-		Name:        sc.Name,
-		Description: sc.Description,
-		Metadata:    sc.Metadata,
-		CreatedAt:   sc.CreatedAt,
+		ID:              sc.ID.String(),
+		TenantID:        sc.TenantID,
+		AppID:           sc.AppID,
+		Name:            sc.Name,
+		Description:     sc.Description,
+		Metadata:        sc.Metadata,
+		RateLimit:       sc.RateLimit,
+		RateLimitWindow: sc.RateLimitWindow.Milliseconds(),
+		CreatedAt:       sc.CreatedAt,
 	}
 	if sc.Parent != "" {
 		m.Parent = &sc.Parent
@@ -233,13 +390,15 @@ func scopeFromModel(m *scopeModel) (*scope.Scope, error) {
 		return nil, err
 	}
 	sc := &scope.Scope{
-		ID:          sid,
-		TenantID:    m.TenantID,
-		AppID:       m.AppID,
-		Name:        m.Name,
-		Description: m.Description,
-		Metadata:    m.Metadata,
-		CreatedAt:   m.CreatedAt,
+		ID:              sid,
+		TenantID:        m.TenantID,
+		AppID:           m.AppID,
+		Name:            m.Name,
+		Description:     m.Description,
+		Metadata:        m.Metadata,
+		RateLimit:       m.RateLimit,
+		RateLimitWindow: time.Duration(m.RateLimitWindow) * time.Millisecond,
+		CreatedAt:       m.CreatedAt,
 	}
 	if m.Parent != nil {
 		sc.Parent = *m.Parent
@@ -256,6 +415,8 @@ type usageModel struct {
 	ID              string         `grove:"id,pk"`
 	KeyID           string         `grove:"key_id,notnull"`
 	TenantID        string         `grove:"tenant_id,notnull"`
+	AppID           string         `grove:"app_id"`
+	Environment     string         `grove:"environment,notnull"`
 	Endpoint        string         `grove:"endpoint,notnull"`
 	Method          string         `grove:"method,notnull"`
 	StatusCode      int            `grove:"status_code,notnull"`
@@ -268,17 +429,19 @@ type usageModel struct {
 
 func usageToModel(rec *usage.Record) *usageModel {
 	return &usageModel{
-		ID:         rec.ID.String(),
-		KeyID:      rec.KeyID.String(),
-		TenantID:   rec.TenantID,
-		Endpoint:   rec.Endpoint,
-		Method:     rec.Method,
-		StatusCode: rec.StatusCode,
-		IPAddress:  rec.IPAddress,
-		UserAgent:  rec.UserAgent,
-		LatencyMs:  rec.Latency.Milliseconds(),
-		Metadata:   rec.Metadata,
-		CreatedAt:  rec.CreatedAt,
+		ID:          rec.ID.String(),
+		KeyID:       rec.KeyID.String(),
+		TenantID:    rec.TenantID,
+		AppID:       rec.AppID,
+		Environment: string(rec.Environment),
+		Endpoint:    rec.Endpoint,
+		Method:      rec.Method,
+		StatusCode:  rec.StatusCode,
+		IPAddress:   rec.IPAddress,
+		UserAgent:   rec.UserAgent,
+		LatencyMs:   rec.Latency.Milliseconds(),
+		Metadata:    rec.Metadata,
+		CreatedAt:   rec.CreatedAt,
 	}
 }
 
@@ -292,17 +455,19 @@ func usageFromModel(m *usageModel) (*usage.Record, error) {
 		return nil, err
 	}
 	return &usage.Record{
-		ID:         uid,
-		KeyID:      kid,
-		TenantID:   m.TenantID,
-		Endpoint:   m.Endpoint,
-		Method:     m.Method,
-		StatusCode: m.StatusCode,
-		IPAddress:  m.IPAddress,
-		UserAgent:  m.UserAgent,
-		Latency:    time.Duration(m.LatencyMs) * time.Millisecond,
-		Metadata:   m.Metadata,
-		CreatedAt:  m.CreatedAt,
+		ID:          uid,
+		KeyID:       kid,
+		TenantID:    m.TenantID,
+		AppID:       m.AppID,
+		Environment: key.Environment(m.Environment),
+		Endpoint:    m.Endpoint,
+		Method:      m.Method,
+		StatusCode:  m.StatusCode,
+		IPAddress:   m.IPAddress,
+		UserAgent:   m.UserAgent,
+		Latency:     time.Duration(m.LatencyMs) * time.Millisecond,
+		Metadata:    m.Metadata,
+		CreatedAt:   m.CreatedAt,
 	}, nil
 }
 
@@ -338,6 +503,40 @@ func aggFromModel(m *usageAggModel) (*usage.Aggregation, error) {
 	}, nil
 }
 
+// endpointSeenModel is the last-seen summary of one key endpoint.
+type endpointSeenModel struct {
+	grove.BaseModel `grove:"table:keysmith_key_endpoint_seen"`
+	KeyID           string    `grove:"key_id,pk"`
+	Endpoint        string    `grove:"endpoint,pk"`
+	Method          string    `grove:"method,pk"`
+	LastSeenAt      time.Time `grove:"last_seen_at,notnull"`
+	Count           int64     `grove:"count,notnull"`
+}
+
+func endpointSeenToModel(a *usage.EndpointActivity) *endpointSeenModel {
+	return &endpointSeenModel{
+		KeyID:      a.KeyID.String(),
+		Endpoint:   a.Endpoint,
+		Method:     a.Method,
+		LastSeenAt: a.LastSeenAt,
+		Count:      a.Count,
+	}
+}
+
+func endpointSeenFromModel(m *endpointSeenModel) (*usage.EndpointActivity, error) {
+	kid, err := id.ParseKeyID(m.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	return &usage.EndpointActivity{
+		KeyID:      kid,
+		Endpoint:   m.Endpoint,
+		Method:     m.Method,
+		LastSeenAt: m.LastSeenAt,
+		Count:      m.Count,
+	}, nil
+}
+
 // ──────────────────────────────────────────────────
 // Rotation model
 // ──────────────────────────────────────────────────
@@ -347,8 +546,11 @@ type rotationModel struct {
 	ID              string    `grove:"id,pk"`
 	KeyID           string    `grove:"key_id,notnull"`
 	TenantID        string    `grove:"tenant_id,notnull"`
+	AppID           string    `grove:"app_id"`
 	OldKeyHash      string    `grove:"old_key_hash,notnull"`
 	NewKeyHash      string    `grove:"new_key_hash,notnull"`
+	OldHint         string    `grove:"old_hint"`
+	NewHint         string    `grove:"new_hint"`
 	Reason          string    `grove:"reason,notnull"`
 	GraceTTLMs      int64     `grove:"grace_ttl_ms,notnull"`
 	GraceEnds       time.Time `grove:"grace_ends,notnull"`
@@ -361,8 +563,11 @@ func rotationToModel(rec *rotation.Record) *rotationModel {
 		ID:         rec.ID.String(),
 		KeyID:      rec.KeyID.String(),
 		TenantID:   rec.TenantID,
+		AppID:      rec.AppID,
 		OldKeyHash: rec.OldKeyHash,
 		NewKeyHash: rec.NewKeyHash,
+		OldHint:    rec.OldHint,
+		NewHint:    rec.NewHint,
 		Reason:     string(rec.Reason),
 		GraceTTLMs: rec.GraceTTL.Milliseconds(),
 		GraceEnds:  rec.GraceEnds,
@@ -384,8 +589,11 @@ func rotationFromModel(m *rotationModel) (*rotation.Record, error) {
 		ID:         rid,
 		KeyID:      kid,
 		TenantID:   m.TenantID,
+		AppID:      m.AppID,
 		OldKeyHash: m.OldKeyHash,
 		NewKeyHash: m.NewKeyHash,
+		OldHint:    m.OldHint,
+		NewHint:    m.NewHint,
 		Reason:     rotation.Reason(m.Reason),
 		GraceTTL:   time.Duration(m.GraceTTLMs) * time.Millisecond,
 		GraceEnds:  m.GraceEnds,
@@ -393,3 +601,480 @@ func rotationFromModel(m *rotationModel) (*rotation.Record, error) {
 		CreatedAt:  m.CreatedAt,
 	}, nil
 }
+
+// ──────────────────────────────────────────────────
+// Tenant settings model
+// ──────────────────────────────────────────────────
+
+type tenantSettingsModel struct {
+	grove.BaseModel `grove:"table:keysmith_tenant_settings"`
+	TenantID        string             `grove:"tenant_id,pk"`
+	AppID           string             `grove:"app_id,notnull"`
+	DefaultScopes   []string           `grove:"default_scopes,type:jsonb"`
+	DefaultContacts key.Contacts       `grove:"default_contacts,type:jsonb"`
+	MetadataSchema  *metaschema.Schema `grove:"metadata_schema,type:jsonb"`
+	RateLimit       int                `grove:"rate_limit,notnull"`
+	RateLimitWindow int64              `grove:"rate_limit_window,notnull"`
+	IPHandling      string             `grove:"ip_handling,notnull"`
+	QuotaTimezone   string             `grove:"quota_timezone,notnull"`
+	UniqueKeyNames  bool               `grove:"unique_key_names,notnull"`
+	Lockdown        *tenant.Lockdown   `grove:"lockdown,type:jsonb"`
+	CreatedAt       time.Time          `grove:"created_at,notnull"`
+	UpdatedAt       time.Time          `grove:"updated_at,notnull"`
+}
+
+func tenantSettingsToModel(ts *tenant.Settings) *tenantSettingsModel {
+	scopes := ts.DefaultScopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	contacts := ts.DefaultContacts
+	if contacts == nil {
+		contacts = key.Contacts{}
+	}
+	return &tenantSettingsModel{
+		TenantID:        ts.TenantID,
+		AppID:           ts.AppID,
+		DefaultScopes:   scopes,
+		DefaultContacts: contacts,
+		MetadataSchema:  ts.MetadataSchema,
+		RateLimit:       ts.RateLimit,
+		RateLimitWindow: ts.RateLimitWindow.Milliseconds(),
+		IPHandling:      string(ts.IPHandling),
+		QuotaTimezone:   ts.QuotaTimezone,
+		UniqueKeyNames:  ts.UniqueKeyNames,
+		Lockdown:        ts.Lockdown,
+		CreatedAt:       ts.CreatedAt,
+		UpdatedAt:       ts.UpdatedAt,
+	}
+}
+
+func tenantSettingsFromModel(m *tenantSettingsModel) *tenant.Settings {
+	return &tenant.Settings{
+		TenantID:        m.TenantID,
+		AppID:           m.AppID,
+		DefaultScopes:   m.DefaultScopes,
+		DefaultContacts: m.DefaultContacts.Normalize(),
+		MetadataSchema:  m.MetadataSchema,
+		RateLimit:       m.RateLimit,
+		RateLimitWindow: time.Duration(m.RateLimitWindow) * time.Millisecond,
+		IPHandling:      usage.IPHandling(m.IPHandling),
+		QuotaTimezone:   m.QuotaTimezone,
+		UniqueKeyNames:  m.UniqueKeyNames,
+		Lockdown:        m.Lockdown,
+		CreatedAt:       m.CreatedAt,
+		UpdatedAt:       m.UpdatedAt,
+	}
+}
+
+// ──────────────────────────────────────────────────
+// Revocation model
+// ──────────────────────────────────────────────────
+
+// revocationModel is one entry of the revocation feed.
+type revocationModel struct {
+	grove.BaseModel `grove:"table:keysmith_key_revocations"`
+	At              time.Time `grove:"at,pk"`
+	KeyID           string    `grove:"key_id,pk"`
+	TenantID        string    `grove:"tenant_id,notnull"`
+	HashPrefix      string    `grove:"hash_prefix,notnull"`
+	State           string    `grove:"state,notnull"`
+	Revoked         bool      `grove:"revoked,notnull"`
+}
+
+func revocationToModel(e *revocation.Entry) *revocationModel {
+	return &revocationModel{
+		At:         e.At.UTC(),
+		KeyID:      e.KeyID.String(),
+		TenantID:   e.TenantID,
+		HashPrefix: e.HashPrefix,
+		State:      string(e.State),
+		Revoked:    e.Revoked,
+	}
+}
+
+func revocationFromModel(m *revocationModel) (*revocation.Entry, error) {
+	kid, err := id.ParseKeyID(m.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	return &revocation.Entry{
+		KeyID:      kid,
+		TenantID:   m.TenantID,
+		HashPrefix: m.HashPrefix,
+		State:      key.State(m.State),
+		Revoked:    m.Revoked,
+		At:         m.At,
+	}, nil
+}
+
+// ──────────────────────────────────────────────────
+// Key event model
+// ──────────────────────────────────────────────────
+
+// keyEventModel is one event of the key event history.
+type keyEventModel struct {
+	grove.BaseModel `grove:"table:keysmith_key_events"`
+	At              time.Time `grove:"at,pk"`
+	KeyID           string    `grove:"key_id,pk"`
+	Type            string    `grove:"type,notnull"`
+	TenantID        string    `grove:"tenant_id,notnull"`
+	AppID           string    `grove:"app_id,notnull"`
+	State           string    `grove:"state,notnull"`
+	Reason          string    `grove:"reason,notnull"`
+	ReasonCode      string    `grove:"reason_code,notnull"`
+	ActorID         string    `grove:"actor_id,notnull"`
+}
+
+func keyEventToModel(e *keyevent.Event) *keyEventModel {
+	return &keyEventModel{
+		At:         e.At.UTC(),
+		KeyID:      e.KeyID.String(),
+		Type:       string(e.Type),
+		TenantID:   e.TenantID,
+		AppID:      e.AppID,
+		State:      string(e.State),
+		Reason:     e.Reason,
+		ReasonCode: e.ReasonCode,
+		ActorID:    e.ActorID,
+	}
+}
+
+func keyEventFromModel(m *keyEventModel) (*keyevent.Event, error) {
+	kid, err := id.ParseKeyID(m.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	return &keyevent.Event{
+		Type:       keyevent.Type(m.Type),
+		KeyID:      kid,
+		TenantID:   m.TenantID,
+		AppID:      m.AppID,
+		State:      key.State(m.State),
+		Reason:     m.Reason,
+		ReasonCode: m.ReasonCode,
+		ActorID:    m.ActorID,
+		At:         m.At,
+	}, nil
+}
+
+// ──────────────────────────────────────────────────
+// Capture model
+// ──────────────────────────────────────────────────
+
+// captureModel is one sampled debug request.
+type captureModel struct {
+	grove.BaseModel `grove:"table:keysmith_debug_captures"`
+	ID              string            `grove:"id,pk"`
+	KeyID           string            `grove:"key_id,notnull"`
+	TenantID        string            `grove:"tenant_id,notnull"`
+	Method          string            `grove:"method,notnull"`
+	Path            string            `grove:"path,notnull"`
+	Headers         map[string]string `grove:"headers,type:jsonb"`
+	Body            string            `grove:"body"`
+	BodyTruncated   bool              `grove:"body_truncated,notnull"`
+	CapturedAt      time.Time         `grove:"captured_at,notnull"`
+}
+
+func captureToModel(c *capture.Capture) *captureModel {
+	return &captureModel{
+		ID:            c.ID.String(),
+		KeyID:         c.KeyID.String(),
+		TenantID:      c.TenantID,
+		Method:        c.Method,
+		Path:          c.Path,
+		Headers:       c.Headers,
+		Body:          c.Body,
+		BodyTruncated: c.BodyTruncated,
+		CapturedAt:    c.CapturedAt.UTC(),
+	}
+}
+
+func captureFromModel(m *captureModel) (*capture.Capture, error) {
+	cid, err := id.ParseCaptureID(m.ID)
+	if err != nil {
+		return nil, err
+	}
+	kid, err := id.ParseKeyID(m.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	return &capture.Capture{
+		ID:            cid,
+		KeyID:         kid,
+		TenantID:      m.TenantID,
+		Method:        m.Method,
+		Path:          m.Path,
+		Headers:       m.Headers,
+		Body:          m.Body,
+		BodyTruncated: m.BodyTruncated,
+		CapturedAt:    m.CapturedAt,
+	}, nil
+}
+
+// ──────────────────────────────────────────────────
+// Group model
+// ──────────────────────────────────────────────────
+
+type groupModel struct {
+	grove.BaseModel `grove:"table:keysmith_key_groups"`
+	ID              string    `grove:"id,pk"`
+	TenantID        string    `grove:"tenant_id,notnull"`
+	AppID           string    `grove:"app_id,notnull"`
+	Name            string    `grove:"name,notnull"`
+	Description     string    `grove:"description"`
+	CreatedAt       time.Time `grove:"created_at,notnull"`
+	UpdatedAt       time.Time `grove:"updated_at,notnull"`
+}
+
+// groupMemberModel represents the join table for group memberships.
+type groupMemberModel struct {
+	grove.BaseModel `grove:"table:keysmith_key_group_members"`
+	GroupID         string `grove:"group_id,pk"`
+	KeyID           string `grove:"key_id,pk"`
+}
+
+func groupToModel(g *group.Group) *groupModel {
+	return &groupModel{
+		ID:          g.ID.String(),
+		TenantID:    g.TenantID,
+		AppID:       g.AppID,
+		Name:        g.Name,
+		Description: g.Description,
+		CreatedAt:   g.CreatedAt,
+		UpdatedAt:   g.UpdatedAt,
+	}
+}
+
+func groupFromModel(m *groupModel) (*group.Group, error) {
+	gid, err := id.ParseGroupID(m.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &group.Group{
+		ID:          gid,
+		TenantID:    m.TenantID,
+		AppID:       m.AppID,
+		Name:        m.Name,
+		Description: m.Description,
+		CreatedAt:   m.CreatedAt,
+		UpdatedAt:   m.UpdatedAt,
+	}, nil
+}
+
+// ──────────────────────────────────────────────────
+// Transfer model
+// ──────────────────────────────────────────────────
+
+// transferModel is one key transfer between tenants.
+type transferModel struct {
+	grove.BaseModel `grove:"table:keysmith_key_transfers"`
+	ID              string     `grove:"id,pk"`
+	KeyID           string     `grove:"key_id,notnull"`
+	AppID           string     `grove:"app_id,notnull"`
+	SourceTenantID  string     `grove:"source_tenant_id,notnull"`
+	TargetTenantID  string     `grove:"target_tenant_id,notnull"`
+	State           string     `grove:"state,notnull"`
+	RetagUsage      bool       `grove:"retag_usage,notnull"`
+	InitiatedBy     string     `grove:"initiated_by,notnull"`
+	ResolvedBy      string     `grove:"resolved_by,notnull"`
+	UnmappedScopes  []string   `grove:"unmapped_scopes,type:jsonb"`
+	UnmappedPolicy  string     `grove:"unmapped_policy,notnull"`
+	ExpiresAt       time.Time  `grove:"expires_at,notnull"`
+	CreatedAt       time.Time  `grove:"created_at,notnull"`
+	ResolvedAt      *time.Time `grove:"resolved_at"`
+}
+
+func transferToModel(t *transfer.Transfer) *transferModel {
+	unmapped := t.UnmappedScopes
+	if unmapped == nil {
+		unmapped = []string{}
+	}
+	m := &transferModel{
+		ID:             t.ID.String(),
+		KeyID:          t.KeyID.String(),
+		AppID:          t.AppID,
+		SourceTenantID: t.SourceTenantID,
+		TargetTenantID: t.TargetTenantID,
+		State:          string(t.State),
+		RetagUsage:     t.RetagUsage,
+		InitiatedBy:    t.InitiatedBy,
+		ResolvedBy:     t.ResolvedBy,
+		UnmappedScopes: unmapped,
+		UnmappedPolicy: t.UnmappedPolicy,
+		ExpiresAt:      t.ExpiresAt.UTC(),
+		CreatedAt:      t.CreatedAt.UTC(),
+	}
+	if t.ResolvedAt != nil {
+		at := t.ResolvedAt.UTC()
+		m.ResolvedAt = &at
+	}
+	return m
+}
+
+func transferFromModel(m *transferModel) (*transfer.Transfer, error) {
+	tid, err := id.ParseTransferID(m.ID)
+	if err != nil {
+		return nil, err
+	}
+	kid, err := id.ParseKeyID(m.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	var unmapped []string
+	if len(m.UnmappedScopes) > 0 {
+		unmapped = m.UnmappedScopes
+	}
+	return &transfer.Transfer{
+		ID:             tid,
+		KeyID:          kid,
+		AppID:          m.AppID,
+		SourceTenantID: m.SourceTenantID,
+		TargetTenantID: m.TargetTenantID,
+		State:          transfer.State(m.State),
+		RetagUsage:     m.RetagUsage,
+		InitiatedBy:    m.InitiatedBy,
+		ResolvedBy:     m.ResolvedBy,
+		UnmappedScopes: unmapped,
+		UnmappedPolicy: m.UnmappedPolicy,
+		ExpiresAt:      m.ExpiresAt,
+		CreatedAt:      m.CreatedAt,
+		ResolvedAt:     m.ResolvedAt,
+	}, nil
+}
+
+// ──────────────────────────────────────────────────
+// Tombstone model
+// ──────────────────────────────────────────────────
+
+// tombstoneModel is the tombstone of one key hash.
+type tombstoneModel struct {
+	grove.BaseModel `grove:"table:keysmith_hash_tombstones"`
+	Hash            string    `grove:"hash,pk"`
+	KeyID           string    `grove:"key_id,notnull"`
+	TenantID        string    `grove:"tenant_id,notnull"`
+	AppID           string    `grove:"app_id,notnull"`
+	Reason          string    `grove:"reason,notnull"`
+	CreatedAt       time.Time `grove:"created_at,notnull"`
+}
+
+func tombstoneToModel(t *tombstone.Tombstone) *tombstoneModel {
+	return &tombstoneModel{
+		Hash:      t.Hash,
+		KeyID:     t.KeyID.String(),
+		TenantID:  t.TenantID,
+		AppID:     t.AppID,
+		Reason:    t.Reason,
+		CreatedAt: t.CreatedAt.UTC(),
+	}
+}
+
+func tombstoneFromModel(m *tombstoneModel) (*tombstone.Tombstone, error) {
+	kid, err := id.ParseKeyID(m.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	return &tombstone.Tombstone{
+		Hash:      m.Hash,
+		KeyID:     kid,
+		TenantID:  m.TenantID,
+		AppID:     m.AppID,
+		Reason:    m.Reason,
+		CreatedAt: m.CreatedAt,
+	}, nil
+}
+
+// ──────────────────────────────────────────────────
+// Quota pool models
+// ──────────────────────────────────────────────────
+
+type quotaPoolModel struct {
+	grove.BaseModel `grove:"table:keysmith_quota_pools"`
+	ID              string    `grove:"id,pk"`
+	TenantID        string    `grove:"tenant_id,notnull"`
+	AppID           string    `grove:"app_id,notnull"`
+	Name            string    `grove:"name,notnull"`
+	Description     string    `grove:"description"`
+	DailyLimit      int64     `grove:"daily_limit,notnull"`
+	MonthlyLimit    int64     `grove:"monthly_limit,notnull"`
+	QuotaTimezone   string    `grove:"quota_timezone,notnull"`
+	CreatedAt       time.Time `grove:"created_at,notnull"`
+	UpdatedAt       time.Time `grove:"updated_at,notnull"`
+}
+
+// quotaUsageModel is the validation count of one key in one quota window.
+// PoolID is empty for counts toward the key's own policy quota.
+type quotaUsageModel struct {
+	grove.BaseModel `grove:"table:keysmith_quota_usage"`
+	PoolID          string    `grove:"pool_id,pk"`
+	KeyID           string    `grove:"key_id,pk"`
+	Period          string    `grove:"period,pk"`
+	WindowStart     time.Time `grove:"window_start,pk"`
+	Count           int64     `grove:"count,notnull"`
+}
+
+func quotaPoolToModel(p *quotapool.Pool) *quotaPoolModel {
+	return &quotaPoolModel{
+		ID:            p.ID.String(),
+		TenantID:      p.TenantID,
+		AppID:         p.AppID,
+		Name:          p.Name,
+		Description:   p.Description,
+		DailyLimit:    p.DailyLimit,
+		MonthlyLimit:  p.MonthlyLimit,
+		QuotaTimezone: p.QuotaTimezone,
+		CreatedAt:     p.CreatedAt,
+		UpdatedAt:     p.UpdatedAt,
+	}
+}
+
+func quotaPoolFromModel(m *quotaPoolModel) (*quotapool.Pool, error) {
+	pid, err := id.ParseQuotaPoolID(m.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &quotapool.Pool{
+		ID:            pid,
+		TenantID:      m.TenantID,
+		AppID:         m.AppID,
+		Name:          m.Name,
+		Description:   m.Description,
+		DailyLimit:    m.DailyLimit,
+		MonthlyLimit:  m.MonthlyLimit,
+		QuotaTimezone: m.QuotaTimezone,
+		CreatedAt:     m.CreatedAt,
+		UpdatedAt:     m.UpdatedAt,
+	}, nil
+}
+
+func quotaUsageToModel(u *quotapool.Usage) *quotaUsageModel {
+	m := &quotaUsageModel{
+		KeyID:       u.KeyID.String(),
+		Period:      string(u.Period),
+		WindowStart: u.WindowStart.UTC(),
+		Count:       u.Count,
+	}
+	if !u.PoolID.IsNil() {
+		m.PoolID = u.PoolID.String()
+	}
+	return m
+}
+
+func quotaUsageFromModel(m *quotaUsageModel) (*quotapool.Usage, error) {
+	kid, err := id.ParseKeyID(m.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	u := &quotapool.Usage{
+		KeyID:       kid,
+		Period:      quotapool.Period(m.Period),
+		WindowStart: m.WindowStart,
+		Count:       m.Count,
+	}
+	if m.PoolID != "" {
+		if u.PoolID, err = id.ParseQuotaPoolID(m.PoolID); err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
@@ -14,9 +14,14 @@ import (
 
 type policyStore struct {
 	db *pgdriver.PgDB
+	rs *replicaSet
 }
 
 func (s *policyStore) Create(ctx context.Context, pol *policy.Policy) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	m := policyToModel(pol)
 	_, err := s.db.NewInsert(m).Exec(ctx)
 	if err != nil {
@@ -26,8 +31,14 @@ func (s *policyStore) Create(ctx context.Context, pol *policy.Policy) error {
 }
 
 func (s *policyStore) Get(ctx context.Context, polID id.PolicyID) (*policy.Policy, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	m := new(policyModel)
-	err := s.db.NewSelect(m).Where("id = ?", polID.String()).Scan(ctx)
+	err := s.rs.read(ctx, s.db, func(db *pgdriver.PgDB) error {
+		return db.NewSelect(m).Where("id = ?", polID.String()).Scan(ctx)
+	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, errNotFound("policy")
@@ -38,6 +49,10 @@ func (s *policyStore) Get(ctx context.Context, polID id.PolicyID) (*policy.Polic
 }
 
 func (s *policyStore) GetByName(ctx context.Context, tenantID, name string) (*policy.Policy, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	m := new(policyModel)
 	err := s.db.NewSelect(m).
 		Where("tenant_id = ?", tenantID).
@@ -53,6 +68,10 @@ func (s *policyStore) GetByName(ctx context.Context, tenantID, name string) (*po
 }
 
 func (s *policyStore) Update(ctx context.Context, pol *policy.Policy) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	m := policyToModel(pol)
 	res, err := s.db.NewUpdate(m).WherePK().Exec(ctx)
 	if err != nil {
@@ -66,6 +85,10 @@ func (s *policyStore) Update(ctx context.Context, pol *policy.Policy) error {
 }
 
 func (s *policyStore) Delete(ctx context.Context, polID id.PolicyID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	res, err := s.db.NewDelete((*policyModel)(nil)).
 		Where("id = ?", polID.String()).
 		Exec(ctx)
@@ -80,13 +103,32 @@ func (s *policyStore) Delete(ctx context.Context, polID id.PolicyID) error {
 }
 
 func (s *policyStore) List(ctx context.Context, filter *policy.ListFilter) ([]*policy.Policy, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var models []policyModel
 	q := s.db.NewSelect(&models).OrderExpr("created_at DESC")
+	if filter == nil || !filter.IncludeInline {
+		q = q.Where("NOT inline")
+	}
 
 	if filter != nil {
 		if filter.TenantID != "" {
 			q = q.Where("tenant_id = ?", filter.TenantID)
 		}
+		if filter.AppID != "" {
+			q = q.Where("app_id = ?", filter.AppID)
+		}
+		if filter.CreatedAfter != nil {
+			q = q.Where("created_at > ?", filter.CreatedAfter.UTC())
+		}
+		if filter.CreatedBefore != nil {
+			q = q.Where("created_at < ?", filter.CreatedBefore.UTC())
+		}
+		if filter.UpdatedAfter != nil {
+			q = q.Where("updated_at > ?", filter.UpdatedAfter.UTC())
+		}
 		if filter.Limit > 0 {
 			q = q.Limit(filter.Limit)
 		}
@@ -111,12 +153,31 @@ func (s *policyStore) List(ctx context.Context, filter *policy.ListFilter) ([]*p
 }
 
 func (s *policyStore) Count(ctx context.Context, filter *policy.ListFilter) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
 	q := s.db.NewSelect((*policyModel)(nil))
+	if filter == nil || !filter.IncludeInline {
+		q = q.Where("NOT inline")
+	}
 
 	if filter != nil {
 		if filter.TenantID != "" {
 			q = q.Where("tenant_id = ?", filter.TenantID)
 		}
+		if filter.AppID != "" {
+			q = q.Where("app_id = ?", filter.AppID)
+		}
+		if filter.CreatedAfter != nil {
+			q = q.Where("created_at > ?", filter.CreatedAfter.UTC())
+		}
+		if filter.CreatedBefore != nil {
+			q = q.Where("created_at < ?", filter.CreatedBefore.UTC())
+		}
+		if filter.UpdatedAfter != nil {
+			q = q.Where("updated_at > ?", filter.UpdatedAfter.UTC())
+		}
 	}
 
 	count, err := q.Count(ctx)
@@ -0,0 +1,230 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xraph/grove/drivers/pgdriver"
+
+	"github.com/xraph/keysmith/store"
+)
+
+// DefaultReplicaRetryAfter is how long a replica that failed a read is
+// excluded from rotation before it is tried again.
+const DefaultReplicaRetryAfter = 30 * time.Second
+
+// ReplicaOption configures replica routing for NewWithReplicas.
+type ReplicaOption func(*replicaSet)
+
+// WithReplicaRetryAfter sets how long a failing replica is excluded before
+// reads are routed to it again. Defaults to DefaultReplicaRetryAfter.
+func WithReplicaRetryAfter(d time.Duration) ReplicaOption {
+	return func(r *replicaSet) {
+		if d > 0 {
+			r.retryAfter = d
+		}
+	}
+}
+
+// WithPrimaryAfterKeyWrite routes all reads to the primary for d after any key
+// write made through this store, so a GetByHash right after Create sees the
+// new key despite replica lag. Set d to at least the expected replica lag.
+// Disabled by default.
+func WithPrimaryAfterKeyWrite(d time.Duration) ReplicaOption {
+	return func(r *replicaSet) { r.primaryAfterWrite = d }
+}
+
+// PoolStats describes one connection pool of a replica-aware store.
+type PoolStats struct {
+	Role          string    `json:"role"` // "primary" or "replica"
+	Index         int       `json:"index"`
+	Healthy       bool      `json:"healthy"`
+	Reads         int64     `json:"reads"`
+	Failures      int64     `json:"failures"`
+	LastError     string    `json:"last_error,omitempty"`
+	ExcludedUntil time.Time `json:"excluded_until,omitzero"`
+}
+
+type replica struct {
+	db *pgdriver.PgDB
+
+	reads    atomic.Int64
+	failures atomic.Int64
+
+	mu            sync.Mutex
+	excludedUntil time.Time
+	lastErr       string
+}
+
+func (r *replica) available(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !now.Before(r.excludedUntil)
+}
+
+func (r *replica) fail(err error, until time.Time) {
+	r.failures.Add(1)
+	r.mu.Lock()
+	r.excludedUntil = until
+	r.lastErr = err.Error()
+	r.mu.Unlock()
+}
+
+func (r *replica) recover() {
+	r.mu.Lock()
+	r.excludedUntil = time.Time{}
+	r.mu.Unlock()
+}
+
+// replicaSet routes reads round-robin across healthy replicas and falls back
+// to the primary. A nil *replicaSet sends every read to the primary.
+type replicaSet struct {
+	replicas          []*replica
+	next              atomic.Uint64
+	retryAfter        time.Duration
+	primaryAfterWrite time.Duration
+	lastKeyWrite      atomic.Int64 // unix nanos
+	primaryReads      atomic.Int64
+	now               func() time.Time
+
+	primaryMu  sync.Mutex
+	primaryErr string
+}
+
+func newReplicaSet(dbs []*pgdriver.PgDB, opts ...ReplicaOption) *replicaSet {
+	r := &replicaSet{retryAfter: DefaultReplicaRetryAfter, now: time.Now}
+	for _, db := range dbs {
+		if db != nil {
+			r.replicas = append(r.replicas, &replica{db: db})
+		}
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// pick returns the next available replica, or nil when reads for ctx must go
+// to the primary.
+func (r *replicaSet) pick(ctx context.Context) *replica {
+	if len(r.replicas) == 0 || store.PrimaryReads(ctx) {
+		return nil
+	}
+	now := r.now()
+	if r.primaryAfterWrite > 0 && now.Sub(time.Unix(0, r.lastKeyWrite.Load())) < r.primaryAfterWrite {
+		return nil
+	}
+	n := uint64(len(r.replicas))
+	start := r.next.Add(1)
+	for i := range n {
+		rep := r.replicas[(start+i)%n]
+		if rep.available(now) {
+			return rep
+		}
+	}
+	return nil
+}
+
+// read runs fn against a replica, or against primary when none is available.
+// A replica that fails for any reason other than a missing row or the
+// caller's context is excluded, and the read is retried on the primary.
+func (r *replicaSet) read(ctx context.Context, primary *pgdriver.PgDB, fn func(db *pgdriver.PgDB) error) error {
+	if r == nil {
+		return fn(primary)
+	}
+	if rep := r.pick(ctx); rep != nil {
+		rep.reads.Add(1)
+		err := fn(rep.db)
+		if err == nil || errors.Is(err, sql.ErrNoRows) || ctx.Err() != nil {
+			return err
+		}
+		rep.fail(err, r.now().Add(r.retryAfter))
+	}
+	r.primaryReads.Add(1)
+	return fn(primary)
+}
+
+// wroteKey records a key write for WithPrimaryAfterKeyWrite.
+func (r *replicaSet) wroteKey() {
+	if r == nil || r.primaryAfterWrite <= 0 {
+		return
+	}
+	r.lastKeyWrite.Store(r.now().UnixNano())
+}
+
+// ping checks the primary and every replica. Replicas that fail are excluded
+// and those that answer are readmitted; only a primary failure is returned.
+func (r *replicaSet) ping(ctx context.Context, primary *pgdriver.PgDB) error {
+	err := primary.Ping(ctx)
+	if r == nil {
+		return err
+	}
+	r.primaryMu.Lock()
+	r.primaryErr = ""
+	if err != nil {
+		r.primaryErr = err.Error()
+	}
+	r.primaryMu.Unlock()
+
+	for _, rep := range r.replicas {
+		if err := rep.db.Ping(ctx); err != nil {
+			rep.fail(err, r.now().Add(r.retryAfter))
+			continue
+		}
+		rep.recover()
+	}
+	return err
+}
+
+func (r *replicaSet) close() error {
+	if r == nil {
+		return nil
+	}
+	var errs []error
+	for i, rep := range r.replicas {
+		if err := rep.db.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("replica %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *replicaSet) stats() []PoolStats {
+	if r == nil {
+		return nil
+	}
+	now := r.now()
+	out := make([]PoolStats, 0, len(r.replicas)+1)
+
+	r.primaryMu.Lock()
+	out = append(out, PoolStats{
+		Role:      "primary",
+		Healthy:   r.primaryErr == "",
+		Reads:     r.primaryReads.Load(),
+		LastError: r.primaryErr,
+	})
+	r.primaryMu.Unlock()
+
+	for i, rep := range r.replicas {
+		rep.mu.Lock()
+		st := PoolStats{
+			Role:      "replica",
+			Index:     i,
+			Healthy:   !now.Before(rep.excludedUntil),
+			Reads:     rep.reads.Load(),
+			Failures:  rep.failures.Load(),
+			LastError: rep.lastErr,
+		}
+		if !st.Healthy {
+			st.ExcludedUntil = rep.excludedUntil
+		}
+		rep.mu.Unlock()
+		out = append(out, st)
+	}
+	return out
+}
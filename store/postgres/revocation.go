@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xraph/grove/drivers/pgdriver"
+
+	"github.com/xraph/keysmith/revocation"
+)
+
+type revocationStore struct {
+	db *pgdriver.PgDB
+	rs *replicaSet
+}
+
+func (s *revocationStore) Append(ctx context.Context, e *revocation.Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	_, err := s.db.NewInsert(revocationToModel(e)).
+		OnConflict("(at, key_id) DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/postgres: append revocation: %w", err)
+	}
+	return nil
+}
+
+func (s *revocationStore) ListAfter(ctx context.Context, tenantID string, after revocation.Cursor, limit int) ([]*revocation.Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var models []revocationModel
+	err := s.rs.read(ctx, s.db, func(db *pgdriver.PgDB) error {
+		models = nil
+		q := db.NewSelect(&models).
+			Where("(at > ? OR (at = ? AND key_id > ?))", after.At, after.At, after.KeyID).
+			OrderExpr("at ASC, key_id ASC")
+		if tenantID != "" {
+			q = q.Where("tenant_id = ?", tenantID)
+		}
+		if limit > 0 {
+			q = q.Limit(limit)
+		}
+		return q.Scan(ctx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/postgres: list revocations: %w", err)
+	}
+
+	result := make([]*revocation.Entry, 0, len(models))
+	for i := range models {
+		e, err := revocationFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/postgres: convert revocation: %w", err)
+		}
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+func (s *revocationStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	res, err := s.db.NewDelete((*revocationModel)(nil)).
+		Where("at < ?", before.UTC()).
+		Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("keysmith/postgres: purge revocations: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	return affected, nil
+}
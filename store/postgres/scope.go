@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/xraph/grove/driver"
 	"github.com/xraph/grove/drivers/pgdriver"
@@ -15,9 +16,14 @@ import (
 
 type scopeStore struct {
 	db *pgdriver.PgDB
+	rs *replicaSet
 }
 
 func (s *scopeStore) Create(ctx context.Context, sc *scope.Scope) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	m := scopeToModel(sc)
 	_, err := s.db.NewInsert(m).Exec(ctx)
 	if err != nil {
@@ -27,6 +33,10 @@ func (s *scopeStore) Create(ctx context.Context, sc *scope.Scope) error {
 }
 
 func (s *scopeStore) Get(ctx context.Context, scopeID id.ScopeID) (*scope.Scope, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	m := new(scopeModel)
 	err := s.db.NewSelect(m).Where("id = ?", scopeID.String()).Scan(ctx)
 	if err != nil {
@@ -39,6 +49,10 @@ func (s *scopeStore) Get(ctx context.Context, scopeID id.ScopeID) (*scope.Scope,
 }
 
 func (s *scopeStore) GetByName(ctx context.Context, tenantID, name string) (*scope.Scope, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	m := new(scopeModel)
 	err := s.db.NewSelect(m).
 		Where("tenant_id = ?", tenantID).
@@ -54,6 +68,10 @@ func (s *scopeStore) GetByName(ctx context.Context, tenantID, name string) (*sco
 }
 
 func (s *scopeStore) Update(ctx context.Context, sc *scope.Scope) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	m := scopeToModel(sc)
 	res, err := s.db.NewUpdate(m).WherePK().Exec(ctx)
 	if err != nil {
@@ -67,6 +85,10 @@ func (s *scopeStore) Update(ctx context.Context, sc *scope.Scope) error {
 }
 
 func (s *scopeStore) Delete(ctx context.Context, scopeID id.ScopeID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	res, err := s.db.NewDelete((*scopeModel)(nil)).
 		Where("id = ?", scopeID.String()).
 		Exec(ctx)
@@ -81,6 +103,10 @@ func (s *scopeStore) Delete(ctx context.Context, scopeID id.ScopeID) error {
 }
 
 func (s *scopeStore) List(ctx context.Context, filter *scope.ListFilter) ([]*scope.Scope, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var models []scopeModel
 	q := s.db.NewSelect(&models).OrderExpr("name ASC")
 
@@ -88,9 +114,15 @@ func (s *scopeStore) List(ctx context.Context, filter *scope.ListFilter) ([]*sco
 		if filter.TenantID != "" {
 			q = q.Where("tenant_id = ?", filter.TenantID)
 		}
+		if filter.AppID != "" {
+			q = q.Where("app_id = ?", filter.AppID)
+		}
 		if filter.Parent != "" {
 			q = q.Where("parent = ?", filter.Parent)
 		}
+		if filter.CreatedAfter != nil {
+			q = q.Where("created_at > ?", filter.CreatedAfter.UTC())
+		}
 		if filter.Limit > 0 {
 			q = q.Limit(filter.Limit)
 		}
@@ -115,12 +147,19 @@ func (s *scopeStore) List(ctx context.Context, filter *scope.ListFilter) ([]*sco
 }
 
 func (s *scopeStore) ListByKey(ctx context.Context, keyID id.KeyID) ([]*scope.Scope, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var models []scopeModel
-	err := s.db.NewSelect(&models).
-		Join("INNER JOIN", "keysmith_key_scopes AS ks", "ks.scope_id = keysmith_scopes.id").
-		Where("ks.key_id = ?", keyID.String()).
-		OrderExpr("keysmith_scopes.name ASC").
-		Scan(ctx)
+	err := s.rs.read(ctx, s.db, func(db *pgdriver.PgDB) error {
+		models = nil
+		return db.NewSelect(&models).
+			Join("INNER JOIN", "keysmith_key_scopes AS ks", "ks.scope_id = keysmith_scopes.id").
+			Where("ks.key_id = ?", keyID.String()).
+			OrderExpr("keysmith_scopes.name ASC").
+			Scan(ctx)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("keysmith/postgres: list scopes by key: %w", err)
 	}
@@ -137,6 +176,10 @@ func (s *scopeStore) ListByKey(ctx context.Context, keyID id.KeyID) ([]*scope.Sc
 }
 
 func (s *scopeStore) AssignToKey(ctx context.Context, keyID id.KeyID, scopeNames []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	if len(scopeNames) == 0 {
 		return nil
 	}
@@ -169,10 +212,17 @@ func (s *scopeStore) AssignToKey(ctx context.Context, keyID id.KeyID, scopeNames
 		}
 	}
 
+	if err := touchKey(ctx, tx, kid); err != nil {
+		return err
+	}
 	return tx.Commit()
 }
 
 func (s *scopeStore) RemoveFromKey(ctx context.Context, keyID id.KeyID, scopeNames []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	if len(scopeNames) == 0 {
 		return nil
 	}
@@ -197,5 +247,21 @@ func (s *scopeStore) RemoveFromKey(ctx context.Context, keyID id.KeyID, scopeNam
 		}
 	}
 
+	if err := touchKey(ctx, tx, kid); err != nil {
+		return err
+	}
 	return tx.Commit()
 }
+
+// touchKey bumps a key's updated_at in tx, so scope changes show up in
+// incremental key listings.
+func touchKey(ctx context.Context, tx *pgdriver.PgTx, kid string) error {
+	_, err := tx.NewUpdate((*keyModel)(nil)).
+		Set("updated_at = ?", time.Now().UTC()).
+		Where("id = ?", kid).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/postgres: touch key: %w", err)
+	}
+	return nil
+}
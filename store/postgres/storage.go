@@ -0,0 +1,162 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/xraph/grove/driver"
+
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store"
+)
+
+var _ store.StorageReporter = (*Store)(nil)
+
+// StorageSampleRows is about how many usage rows TenantStorage samples to
+// estimate their size. Tables with fewer rows, by the planner's estimate,
+// are measured in full.
+const StorageSampleRows = 1000
+
+// TenantStorage counts each tenant's rows with one GROUP BY per table on the
+// primary. UsageBytes multiplies a tenant's usage rows by their average
+// pg_column_size in a TABLESAMPLE SYSTEM sample of about StorageSampleRows
+// rows, or by the average of the whole sample for a tenant with no rows in
+// it. The sample is drawn by page and sized from the planner's row estimate,
+// so run ANALYZE, or Maintain, on a new table for it to apply.
+func (s *Store) TenantStorage(ctx context.Context, tenantID string) ([]*store.TenantStorage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	where, args := "", []any{}
+	if tenantID != "" {
+		where, args = " WHERE tenant_id = $1", []any{tenantID}
+	}
+	byTenant := make(map[string]*store.TenantStorage)
+	get := func(tid string) *store.TenantStorage {
+		ts, ok := byTenant[tid]
+		if !ok {
+			ts = &store.TenantStorage{TenantID: tid, KeysByState: make(map[key.State]int64)}
+			byTenant[tid] = ts
+		}
+		return ts
+	}
+
+	if err := s.countKeysByTenant(ctx, where, args, get); err != nil {
+		return nil, err
+	}
+	for _, c := range []struct {
+		table string
+		count func(*store.TenantStorage) *int64
+	}{
+		{"keysmith_policies", func(ts *store.TenantStorage) *int64 { return &ts.Policies }},
+		{"keysmith_scopes", func(ts *store.TenantStorage) *int64 { return &ts.Scopes }},
+		{"keysmith_rotations", func(ts *store.TenantStorage) *int64 { return &ts.Rotations }},
+		{"keysmith_usage", func(ts *store.TenantStorage) *int64 { return &ts.UsageRows }},
+	} {
+		rows, err := s.db.Query(ctx, `SELECT tenant_id, COUNT(*), 0::bigint FROM `+c.table+where+` GROUP BY tenant_id`, args...)
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/postgres: count %s by tenant: %w", c.table, err)
+		}
+		err = scanTenantCounts(rows, func(tid string, n, _ int64) { *c.count(get(tid)) += n })
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/postgres: count %s by tenant: %w", c.table, err)
+		}
+	}
+
+	avg, overall, err := s.sampleUsageSizes(ctx, where, args)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*store.TenantStorage, 0, len(byTenant))
+	for _, ts := range byTenant {
+		size, ok := avg[ts.TenantID]
+		if !ok {
+			size = overall
+		}
+		ts.UsageBytes = int64(float64(ts.UsageRows) * size)
+		out = append(out, ts)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].TenantID < out[j].TenantID })
+	return out, nil
+}
+
+// countKeysByTenant adds the key counts by tenant and state to the entries
+// get returns.
+func (s *Store) countKeysByTenant(ctx context.Context, where string, args []any, get func(string) *store.TenantStorage) error {
+	rows, err := s.db.Query(ctx, `SELECT tenant_id, state, COUNT(*) FROM keysmith_keys`+where+` GROUP BY tenant_id, state`, args...)
+	if err != nil {
+		return fmt.Errorf("keysmith/postgres: count keys by tenant: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tid, state string
+		var n int64
+		if err := rows.Scan(&tid, &state, &n); err != nil {
+			return fmt.Errorf("keysmith/postgres: count keys by tenant: %w", err)
+		}
+		ts := get(tid)
+		ts.Keys += n
+		ts.KeysByState[key.State(state)] += n
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("keysmith/postgres: count keys by tenant: %w", err)
+	}
+	return nil
+}
+
+// sampleUsageSizes returns the average pg_column_size of the sampled usage
+// rows of each tenant and of the whole sample. When a partial sample finds
+// no rows, it measures every row instead.
+func (s *Store) sampleUsageSizes(ctx context.Context, where string, args []any) (map[string]float64, float64, error) {
+	var estimate float64
+	if err := s.db.QueryRow(ctx, `SELECT GREATEST(reltuples, 0) FROM pg_class WHERE oid = 'keysmith_usage'::regclass`).Scan(&estimate); err != nil {
+		return nil, 0, fmt.Errorf("keysmith/postgres: read usage row estimate: %w", err)
+	}
+	percent := 100.0
+	if estimate > StorageSampleRows {
+		percent = 100 * StorageSampleRows / estimate
+	}
+
+	for {
+		rows, err := s.db.Query(ctx, fmt.Sprintf(
+			`SELECT tenant_id, COUNT(*), SUM(pg_column_size(u.*))::bigint FROM keysmith_usage u TABLESAMPLE SYSTEM (%f)`+where+` GROUP BY tenant_id`,
+			percent), args...)
+		if err != nil {
+			return nil, 0, fmt.Errorf("keysmith/postgres: sample usage sizes: %w", err)
+		}
+		avg := make(map[string]float64)
+		var sampled, total int64
+		err = scanTenantCounts(rows, func(tid string, n, size int64) {
+			avg[tid] = float64(size) / float64(n)
+			sampled += n
+			total += size
+		})
+		if err != nil {
+			return nil, 0, fmt.Errorf("keysmith/postgres: sample usage sizes: %w", err)
+		}
+		if sampled > 0 {
+			return avg, float64(total) / float64(sampled), nil
+		}
+		if percent == 100 {
+			return avg, 0, nil
+		}
+		percent = 100
+	}
+}
+
+// scanTenantCounts reads rows of (tenant_id, count, size) into fn and closes
+// rows.
+func scanTenantCounts(rows driver.Rows, fn func(tenantID string, n, size int64)) error {
+	defer rows.Close()
+	for rows.Next() {
+		var tid string
+		var n, size int64
+		if err := rows.Scan(&tid, &n, &size); err != nil {
+			return err
+		}
+		fn(tid, n, size)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,78 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/store/postgres"
+	"github.com/xraph/keysmith/store/storetest"
+	"github.com/xraph/keysmith/usage"
+)
+
+// TestTenantStorage runs against the database in KEYSMITH_TEST_POSTGRES_DSN.
+func TestTenantStorage(t *testing.T) {
+	dsn := os.Getenv("KEYSMITH_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("KEYSMITH_TEST_POSTGRES_DSN is not set")
+	}
+	ctx := context.Background()
+	s, err := postgres.NewFromDSN(ctx, dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+	require.NoError(t, s.Migrate(ctx))
+
+	storetest.CheckTenantStorage(t, s, "storage-"+id.NewKeyID().String())
+}
+
+// TestTenantStorage_Sampled checks the usage size estimate once the usage
+// table outgrows StorageSampleRows, so that it is drawn from a sample.
+func TestTenantStorage_Sampled(t *testing.T) {
+	dsn := os.Getenv("KEYSMITH_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("KEYSMITH_TEST_POSTGRES_DSN is not set")
+	}
+	ctx := context.Background()
+	s, err := postgres.NewFromDSN(ctx, dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+	require.NoError(t, s.Migrate(ctx))
+
+	tenantID := "storage-sampled-" + id.NewKeyID().String()
+	k := storetest.NewKey(tenantID, "sk_test_"+tenantID+"_sample1")
+	require.NoError(t, s.Keys().Create(ctx, k))
+	t.Cleanup(func() { _ = s.Keys().Delete(ctx, k.ID) })
+
+	const rows = 20 * postgres.StorageSampleRows
+	now := time.Now().UTC()
+	recs := make([]*usage.Record, 0, 1000)
+	for i := range rows {
+		recs = append(recs, &usage.Record{
+			ID: id.NewUsageID(), KeyID: k.ID, TenantID: tenantID, AppID: k.AppID, Environment: key.EnvTest,
+			Endpoint: "/v1/things", Method: "GET", StatusCode: 200, CreatedAt: now.Add(time.Duration(i) * time.Microsecond),
+		})
+		if len(recs) == cap(recs) {
+			require.NoError(t, s.Usages().RecordBatch(ctx, recs))
+			recs = recs[:0]
+		}
+	}
+	_, err = s.Maintain(ctx, store.MaintenanceOptions{})
+	require.NoError(t, err, "ANALYZE gives the planner the row estimate the sample is sized from")
+
+	got, err := s.TenantStorage(ctx, tenantID)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.EqualValues(t, rows, got[0].UsageRows, "rows are counted exactly")
+	perRow := got[0].UsageBytes / rows
+	assert.Greater(t, perRow, int64(50), "a usage row holds at least its IDs")
+	assert.Less(t, perRow, int64(1000))
+}
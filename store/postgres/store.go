@@ -3,15 +3,24 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/xraph/grove/drivers/pgdriver"
 
+	"github.com/xraph/keysmith/capture"
+	"github.com/xraph/keysmith/group"
 	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keyevent"
 	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/quotapool"
+	"github.com/xraph/keysmith/revocation"
 	"github.com/xraph/keysmith/rotation"
 	"github.com/xraph/keysmith/scope"
 	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/tenant"
+	"github.com/xraph/keysmith/tombstone"
+	"github.com/xraph/keysmith/transfer"
 	"github.com/xraph/keysmith/usage"
 )
 
@@ -20,11 +29,27 @@ var _ store.Store = (*Store)(nil)
 // Store is the PostgreSQL-backed store implementation using grove ORM.
 type Store struct {
 	db *pgdriver.PgDB
+	rs *replicaSet
 }
 
 // New creates a new PostgreSQL store with the given grove pgdriver instance.
 func New(db *pgdriver.PgDB) *Store {
-	return &Store{db: db}
+	return &Store{db: db, rs: newReplicaSet(nil)}
+}
+
+// NewWithReplicas creates a PostgreSQL store that sends writes to primary and
+// spreads validation-path reads (key, policy, scope, and rotation lookups and
+// usage queries and counts) round-robin across replicas. A replica that
+// fails a read is excluded for a while and the read is retried on the
+// primary; with no healthy replica, reads go to the primary.
+//
+// Replica reads may lag the primary by the replication delay, so a key
+// revoked moments ago can still validate on a replica for that long. Callers
+// needing strict consistency mark the context with [store.WithPrimaryReads];
+// WithPrimaryAfterKeyWrite covers read-after-write in tests and single-node
+// setups.
+func NewWithReplicas(primary *pgdriver.PgDB, replicas []*pgdriver.PgDB, opts ...ReplicaOption) *Store {
+	return &Store{db: primary, rs: newReplicaSet(replicas, opts...)}
 }
 
 // NewFromDSN creates a new PostgreSQL store by connecting to the given DSN.
@@ -33,26 +58,54 @@ func NewFromDSN(ctx context.Context, dsn string) (*Store, error) {
 	if err := db.Open(ctx, dsn); err != nil {
 		return nil, fmt.Errorf("keysmith/postgres: connect: %w", err)
 	}
-	return &Store{db: db}, nil
+	return New(db), nil
 }
 
 // Keys returns the key store.
-func (s *Store) Keys() key.Store { return &keyStore{db: s.db} }
+func (s *Store) Keys() key.Store { return &keyStore{db: s.db, rs: s.rs} }
 
 // Policies returns the policy store.
-func (s *Store) Policies() policy.Store { return &policyStore{db: s.db} }
+func (s *Store) Policies() policy.Store { return &policyStore{db: s.db, rs: s.rs} }
 
 // Usages returns the usage store.
-func (s *Store) Usages() usage.Store { return &usageStore{db: s.db} }
+func (s *Store) Usages() usage.Store { return &usageStore{db: s.db, rs: s.rs} }
 
 // Rotations returns the rotation store.
-func (s *Store) Rotations() rotation.Store { return &rotationStore{db: s.db} }
+func (s *Store) Rotations() rotation.Store { return &rotationStore{db: s.db, rs: s.rs} }
 
 // Scopes returns the scope store.
-func (s *Store) Scopes() scope.Store { return &scopeStore{db: s.db} }
+func (s *Store) Scopes() scope.Store { return &scopeStore{db: s.db, rs: s.rs} }
+
+// Tenants returns the tenant settings store.
+func (s *Store) Tenants() tenant.Store { return &tenantStore{db: s.db} }
+
+// Revocations returns the revocation feed store.
+func (s *Store) Revocations() revocation.Store { return &revocationStore{db: s.db, rs: s.rs} }
+
+// Captures returns the debug capture store.
+func (s *Store) Captures() capture.Store { return &captureStore{db: s.db, rs: s.rs} }
+
+// Groups returns the key group store.
+func (s *Store) Groups() group.Store { return &groupStore{db: s.db, rs: s.rs} }
+
+// KeyEvents returns the key event history store.
+func (s *Store) KeyEvents() keyevent.Store { return &keyEventStore{db: s.db, rs: s.rs} }
+
+// Transfers returns the key transfer store.
+func (s *Store) Transfers() transfer.Store { return &transferStore{db: s.db, rs: s.rs} }
+
+// Tombstones returns the hash tombstone store.
+func (s *Store) Tombstones() tombstone.Store { return &tombstoneStore{db: s.db} }
+
+// QuotaPools returns the quota pool store.
+func (s *Store) QuotaPools() quotapool.Store { return &quotaPoolStore{db: s.db} }
 
 // Migrate runs all embedded SQL migration statements in order.
 func (s *Store) Migrate(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	for i, sql := range migrationSQL {
 		if _, err := s.db.Exec(ctx, sql); err != nil {
 			return fmt.Errorf("keysmith/postgres: exec migration %d: %w", i+1, err)
@@ -61,12 +114,24 @@ func (s *Store) Migrate(ctx context.Context) error {
 	return nil
 }
 
-// Ping checks database connectivity.
+// Ping checks database connectivity. Replicas are pinged too: failing ones
+// are excluded from reads and recovered ones readmitted, but only a primary
+// failure is returned.
 func (s *Store) Ping(ctx context.Context) error {
-	return s.db.Ping(ctx)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.rs.ping(ctx, s.db)
+}
+
+// Stats reports the health and read counts of the primary and each replica,
+// as of the last Ping or read.
+func (s *Store) Stats() []PoolStats {
+	return s.rs.stats()
 }
 
-// Close releases the connection pool.
+// Close releases the connection pools.
 func (s *Store) Close() error {
-	return s.db.Close()
+	return errors.Join(s.db.Close(), s.rs.close())
 }
@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/xraph/grove/drivers/pgdriver"
+
+	"github.com/xraph/keysmith/tenant"
+)
+
+type tenantStore struct {
+	db *pgdriver.PgDB
+}
+
+func (s *tenantStore) GetSettings(ctx context.Context, tenantID string) (*tenant.Settings, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m := new(tenantSettingsModel)
+	err := s.db.NewSelect(m).Where("tenant_id = ?", tenantID).Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errNotFound("tenant settings")
+		}
+		return nil, fmt.Errorf("keysmith/postgres: get tenant settings: %w", err)
+	}
+	return tenantSettingsFromModel(m), nil
+}
+
+func (s *tenantStore) UpsertSettings(ctx context.Context, ts *tenant.Settings) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m := tenantSettingsToModel(ts)
+	_, err := s.db.NewInsert(m).
+		OnConflict("(tenant_id) DO UPDATE").
+		Set("app_id = EXCLUDED.app_id").
+		Set("default_scopes = EXCLUDED.default_scopes").
+		Set("default_contacts = EXCLUDED.default_contacts").
+		Set("metadata_schema = EXCLUDED.metadata_schema").
+		Set("rate_limit = EXCLUDED.rate_limit").
+		Set("rate_limit_window = EXCLUDED.rate_limit_window").
+		Set("ip_handling = EXCLUDED.ip_handling").
+		Set("quota_timezone = EXCLUDED.quota_timezone").
+		Set("unique_key_names = EXCLUDED.unique_key_names").
+		Set("lockdown = EXCLUDED.lockdown").
+		Set("updated_at = EXCLUDED.updated_at").
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/postgres: upsert tenant settings: %w", err)
+	}
+	return nil
+}
+
+func (s *tenantStore) DeleteSettings(ctx context.Context, tenantID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	res, err := s.db.NewDelete((*tenantSettingsModel)(nil)).
+		Where("tenant_id = ?", tenantID).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/postgres: delete tenant settings: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return errNotFound("tenant settings")
+	}
+	return nil
+}
+
+func (s *tenantStore) Revision(ctx context.Context, tenantID string) (uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var rev int64
+	err := s.db.QueryRow(ctx, `SELECT revision FROM keysmith_tenant_revisions WHERE tenant_id = $1`, tenantID).Scan(&rev)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("keysmith/postgres: get tenant revision: %w", err)
+	}
+	return uint64(rev), nil //nolint:gosec // revisions only count up from zero
+}
+
+func (s *tenantStore) BumpRevision(ctx context.Context, tenantID string) (uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var rev int64
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO keysmith_tenant_revisions (tenant_id, revision, updated_at) VALUES ($1, 1, NOW())
+		ON CONFLICT (tenant_id) DO UPDATE
+			SET revision = keysmith_tenant_revisions.revision + 1, updated_at = NOW()
+		RETURNING revision`, tenantID).Scan(&rev)
+	if err != nil {
+		return 0, fmt.Errorf("keysmith/postgres: bump tenant revision: %w", err)
+	}
+	return uint64(rev), nil //nolint:gosec // revisions only count up from zero
+}
@@ -0,0 +1,35 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith/id"
+)
+
+func TestTenantRevision(t *testing.T) {
+	db, s := openPostgres(t)
+	ctx := context.Background()
+	tenant := "revisions-" + id.NewKeyID().String()
+	t.Cleanup(func() {
+		_, _ = db.Exec(ctx, `DELETE FROM keysmith_tenant_revisions WHERE tenant_id = $1`, tenant)
+	})
+
+	rev, err := s.Tenants().Revision(ctx, tenant)
+	require.NoError(t, err)
+	assert.Zero(t, rev)
+
+	for want := uint64(1); want <= 3; want++ {
+		rev, err = s.Tenants().BumpRevision(ctx, tenant)
+		require.NoError(t, err)
+		assert.Equal(t, want, rev)
+	}
+	rev, err = s.Tenants().Revision(ctx, tenant)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), rev)
+}
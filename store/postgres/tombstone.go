@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xraph/grove/drivers/pgdriver"
+
+	"github.com/xraph/keysmith/tombstone"
+)
+
+type tombstoneStore struct {
+	db *pgdriver.PgDB
+}
+
+func (s *tombstoneStore) Create(ctx context.Context, t *tombstone.Tombstone) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	_, err := s.db.NewInsert(tombstoneToModel(t)).OnConflict("(hash) DO NOTHING").Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/postgres: create tombstone: %w", err)
+	}
+	return nil
+}
+
+func (s *tombstoneStore) Find(ctx context.Context, hashes []string) ([]*tombstone.Tombstone, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+	// Read from the primary: a replica may not have a tombstone yet.
+	var models []tombstoneModel
+	err := s.db.NewSelect(&models).Where("hash = ANY(?)", hashes).Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/postgres: find tombstones: %w", err)
+	}
+	return tombstonesFromModels(models)
+}
+
+func (s *tombstoneStore) List(ctx context.Context, filter *tombstone.ListFilter) ([]*tombstone.Tombstone, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var models []tombstoneModel
+	q := s.db.NewSelect(&models).OrderExpr("created_at DESC, hash DESC")
+	if filter != nil {
+		q = tombstoneWhere(q, filter)
+		if filter.Limit > 0 {
+			q = q.Limit(filter.Limit)
+		}
+		if filter.Offset > 0 {
+			q = q.Offset(filter.Offset)
+		}
+	}
+	if err := q.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("keysmith/postgres: list tombstones: %w", err)
+	}
+	return tombstonesFromModels(models)
+}
+
+func (s *tombstoneStore) CountByReason(ctx context.Context, filter *tombstone.ListFilter) ([]*tombstone.ReasonCount, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	q := s.db.NewSelect((*tombstoneModel)(nil)).
+		ColumnExpr("reason").
+		ColumnExpr("COUNT(*)").
+		GroupExpr("reason").
+		OrderExpr("COUNT(*) DESC, reason ASC")
+	if filter != nil {
+		q = tombstoneWhere(q, filter)
+	}
+	query, args, err := q.Build()
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/postgres: count tombstones by reason: %w", err)
+	}
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/postgres: count tombstones by reason: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*tombstone.ReasonCount
+	for rows.Next() {
+		rc := new(tombstone.ReasonCount)
+		if err := rows.Scan(&rc.Reason, &rc.Count); err != nil {
+			return nil, fmt.Errorf("keysmith/postgres: count tombstones by reason: %w", err)
+		}
+		result = append(result, rc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("keysmith/postgres: count tombstones by reason: %w", err)
+	}
+	return result, nil
+}
+
+func (s *tombstoneStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	res, err := s.db.NewDelete((*tombstoneModel)(nil)).
+		Where("created_at < ?", before.UTC()).
+		Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("keysmith/postgres: purge tombstones: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	return affected, nil
+}
+
+func tombstoneWhere(q *pgdriver.SelectQuery, filter *tombstone.ListFilter) *pgdriver.SelectQuery {
+	if filter.TenantID != "" {
+		q = q.Where("tenant_id = ?", filter.TenantID)
+	}
+	if filter.AppID != "" {
+		q = q.Where("app_id = ?", filter.AppID)
+	}
+	if filter.Reason != "" {
+		q = q.Where("reason = ?", filter.Reason)
+	}
+	return q
+}
+
+func tombstonesFromModels(models []tombstoneModel) ([]*tombstone.Tombstone, error) {
+	result := make([]*tombstone.Tombstone, 0, len(models))
+	for i := range models {
+		t, err := tombstoneFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/postgres: convert tombstone: %w", err)
+		}
+		result = append(result, t)
+	}
+	return result, nil
+}
@@ -3,6 +3,8 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/xraph/grove/driver"
@@ -14,9 +16,14 @@ import (
 
 type usageStore struct {
 	db *pgdriver.PgDB
+	rs *replicaSet
 }
 
 func (s *usageStore) Record(ctx context.Context, rec *usage.Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	m := usageToModel(rec)
 	_, err := s.db.NewInsert(m).Exec(ctx)
 	if err != nil {
@@ -26,6 +33,10 @@ func (s *usageStore) Record(ctx context.Context, rec *usage.Record) error {
 }
 
 func (s *usageStore) RecordBatch(ctx context.Context, recs []*usage.Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	if len(recs) == 0 {
 		return nil
 	}
@@ -48,31 +59,45 @@ func (s *usageStore) RecordBatch(ctx context.Context, recs []*usage.Record) erro
 }
 
 func (s *usageStore) Query(ctx context.Context, filter *usage.QueryFilter) ([]*usage.Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var models []usageModel
-	q := s.db.NewSelect(&models).OrderExpr("created_at DESC")
+	err := s.rs.read(ctx, s.db, func(db *pgdriver.PgDB) error {
+		models = nil
+		q := db.NewSelect(&models).OrderExpr("created_at DESC")
 
-	if filter != nil {
-		if filter.KeyID != nil {
-			q = q.Where("key_id = ?", filter.KeyID.String())
-		}
-		if filter.TenantID != "" {
-			q = q.Where("tenant_id = ?", filter.TenantID)
-		}
-		if filter.After != nil {
-			q = q.Where("created_at >= ?", *filter.After)
-		}
-		if filter.Before != nil {
-			q = q.Where("created_at < ?", *filter.Before)
-		}
-		if filter.Limit > 0 {
-			q = q.Limit(filter.Limit)
-		}
-		if filter.Offset > 0 {
-			q = q.Offset(filter.Offset)
+		if filter != nil {
+			if filter.KeyID != nil {
+				q = q.Where("key_id = ?", filter.KeyID.String())
+			}
+			if filter.TenantID != "" {
+				q = q.Where("tenant_id = ?", filter.TenantID)
+			}
+			if filter.AppID != "" {
+				q = q.Where("app_id = ?", filter.AppID)
+			}
+			if filter.Environment != "" {
+				q = q.Where("environment = ?", string(filter.Environment))
+			}
+			if filter.After != nil {
+				q = q.Where("created_at >= ?", *filter.After)
+			}
+			if filter.Before != nil {
+				q = q.Where("created_at < ?", *filter.Before)
+			}
+			if filter.Limit > 0 {
+				q = q.Limit(filter.Limit)
+			}
+			if filter.Offset > 0 {
+				q = q.Offset(filter.Offset)
+			}
 		}
-	}
 
-	if err := q.Scan(ctx); err != nil {
+		return q.Scan(ctx)
+	})
+	if err != nil {
 		return nil, fmt.Errorf("keysmith/postgres: query usage: %w", err)
 	}
 
@@ -88,34 +113,49 @@ func (s *usageStore) Query(ctx context.Context, filter *usage.QueryFilter) ([]*u
 }
 
 func (s *usageStore) Aggregate(ctx context.Context, filter *usage.QueryFilter) ([]*usage.Aggregation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var models []usageAggModel
-	q := s.db.NewSelect(&models).OrderExpr("period_start DESC")
+	err := s.rs.read(ctx, s.db, func(db *pgdriver.PgDB) error {
+		models = nil
+		q := db.NewSelect(&models).OrderExpr("period_start DESC")
 
-	if filter != nil {
-		if filter.KeyID != nil {
-			q = q.Where("key_id = ?", filter.KeyID.String())
-		}
-		if filter.TenantID != "" {
-			q = q.Where("tenant_id = ?", filter.TenantID)
-		}
-		if filter.Period != "" {
-			q = q.Where("period = ?", filter.Period)
-		}
-		if filter.After != nil {
-			q = q.Where("period_start >= ?", *filter.After)
-		}
-		if filter.Before != nil {
-			q = q.Where("period_start < ?", *filter.Before)
-		}
-		if filter.Limit > 0 {
-			q = q.Limit(filter.Limit)
-		}
-		if filter.Offset > 0 {
-			q = q.Offset(filter.Offset)
+		if filter != nil {
+			if filter.KeyID != nil {
+				q = q.Where("key_id = ?", filter.KeyID.String())
+			}
+			if filter.TenantID != "" {
+				q = q.Where("tenant_id = ?", filter.TenantID)
+			}
+			if filter.AppID != "" {
+				q = q.Where("app_id = ?", filter.AppID)
+			}
+			if filter.Environment != "" {
+				// Aggregates are per key, so a key's environment is theirs.
+				q = q.Where("key_id IN (SELECT id FROM keysmith_keys WHERE environment = ?)", string(filter.Environment))
+			}
+			if filter.Period != "" {
+				q = q.Where("period = ?", filter.Period)
+			}
+			if filter.After != nil {
+				q = q.Where("period_start >= ?", *filter.After)
+			}
+			if filter.Before != nil {
+				q = q.Where("period_start < ?", *filter.Before)
+			}
+			if filter.Limit > 0 {
+				q = q.Limit(filter.Limit)
+			}
+			if filter.Offset > 0 {
+				q = q.Offset(filter.Offset)
+			}
 		}
-	}
 
-	if err := q.Scan(ctx); err != nil {
+		return q.Scan(ctx)
+	})
+	if err != nil {
 		return nil, fmt.Errorf("keysmith/postgres: aggregate usage: %w", err)
 	}
 
@@ -131,24 +171,39 @@ func (s *usageStore) Aggregate(ctx context.Context, filter *usage.QueryFilter) (
 }
 
 func (s *usageStore) Count(ctx context.Context, filter *usage.QueryFilter) (int64, error) {
-	q := s.db.NewSelect((*usageModel)(nil))
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
 
-	if filter != nil {
-		if filter.KeyID != nil {
-			q = q.Where("key_id = ?", filter.KeyID.String())
-		}
-		if filter.TenantID != "" {
-			q = q.Where("tenant_id = ?", filter.TenantID)
-		}
-		if filter.After != nil {
-			q = q.Where("created_at >= ?", *filter.After)
-		}
-		if filter.Before != nil {
-			q = q.Where("created_at < ?", *filter.Before)
+	var count int64
+	err := s.rs.read(ctx, s.db, func(db *pgdriver.PgDB) error {
+		q := db.NewSelect((*usageModel)(nil))
+
+		if filter != nil {
+			if filter.KeyID != nil {
+				q = q.Where("key_id = ?", filter.KeyID.String())
+			}
+			if filter.TenantID != "" {
+				q = q.Where("tenant_id = ?", filter.TenantID)
+			}
+			if filter.AppID != "" {
+				q = q.Where("app_id = ?", filter.AppID)
+			}
+			if filter.Environment != "" {
+				q = q.Where("environment = ?", string(filter.Environment))
+			}
+			if filter.After != nil {
+				q = q.Where("created_at >= ?", *filter.After)
+			}
+			if filter.Before != nil {
+				q = q.Where("created_at < ?", *filter.Before)
+			}
 		}
-	}
 
-	count, err := q.Count(ctx)
+		var err error
+		count, err = q.Count(ctx)
+		return err
+	})
 	if err != nil {
 		return 0, fmt.Errorf("keysmith/postgres: count usage: %w", err)
 	}
@@ -156,6 +211,10 @@ func (s *usageStore) Count(ctx context.Context, filter *usage.QueryFilter) (int6
 }
 
 func (s *usageStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
 	res, err := s.db.NewDelete((*usageModel)(nil)).
 		Where("created_at < ?", before).
 		Exec(ctx)
@@ -166,34 +225,148 @@ func (s *usageStore) Purge(ctx context.Context, before time.Time) (int64, error)
 	return affected, nil
 }
 
-func (s *usageStore) DailyCount(ctx context.Context, keyID id.KeyID, date time.Time) (int64, error) {
-	dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
-	dayEnd := dayStart.Add(24 * time.Hour)
+func (s *usageStore) DailyCount(ctx context.Context, keyID id.KeyID, w usage.Window) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
 
-	q := s.db.NewSelect((*usageModel)(nil)).
-		Where("key_id = ?", keyID.String()).
-		Where("created_at >= ?", dayStart).
-		Where("created_at < ?", dayEnd)
+	dayStart, dayEnd := w.Start.UTC(), w.End.UTC()
 
-	count, err := q.Count(ctx)
+	var count int64
+	err := s.rs.read(ctx, s.db, func(db *pgdriver.PgDB) error {
+		var err error
+		count, err = db.NewSelect((*usageModel)(nil)).
+			Where("key_id = ?", keyID.String()).
+			Where("created_at >= ?", dayStart).
+			Where("created_at < ?", dayEnd).
+			Count(ctx)
+		return err
+	})
 	if err != nil {
 		return 0, fmt.Errorf("keysmith/postgres: daily count: %w", err)
 	}
 	return count, nil
 }
 
-func (s *usageStore) MonthlyCount(ctx context.Context, keyID id.KeyID, month time.Time) (int64, error) {
-	monthStart := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
-	monthEnd := monthStart.AddDate(0, 1, 0)
+func (s *usageStore) MonthlyCount(ctx context.Context, keyID id.KeyID, w usage.Window) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
 
-	q := s.db.NewSelect((*usageModel)(nil)).
-		Where("key_id = ?", keyID.String()).
-		Where("created_at >= ?", monthStart).
-		Where("created_at < ?", monthEnd)
+	monthStart, monthEnd := w.Start.UTC(), w.End.UTC()
 
-	count, err := q.Count(ctx)
+	var count int64
+	err := s.rs.read(ctx, s.db, func(db *pgdriver.PgDB) error {
+		var err error
+		count, err = db.NewSelect((*usageModel)(nil)).
+			Where("key_id = ?", keyID.String()).
+			Where("created_at >= ?", monthStart).
+			Where("created_at < ?", monthEnd).
+			Count(ctx)
+		return err
+	})
 	if err != nil {
 		return 0, fmt.Errorf("keysmith/postgres: monthly count: %w", err)
 	}
 	return count, nil
 }
+
+func (s *usageStore) UpsertEndpointActivity(ctx context.Context, acts []*usage.EndpointActivity) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if len(acts) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTxQuery(ctx, &driver.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("keysmith/postgres: begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, a := range acts {
+		_, err := tx.NewInsert(endpointSeenToModel(a)).
+			OnConflict("(key_id, endpoint, method) DO UPDATE").
+			Set("count = keysmith_key_endpoint_seen.count + EXCLUDED.count").
+			Set("last_seen_at = GREATEST(keysmith_key_endpoint_seen.last_seen_at, EXCLUDED.last_seen_at)").
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("keysmith/postgres: upsert endpoint activity: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *usageStore) ListEndpointActivity(ctx context.Context, keyID id.KeyID) ([]*usage.EndpointActivity, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var models []endpointSeenModel
+	err := s.db.NewSelect(&models).
+		Where("key_id = ?", keyID.String()).
+		OrderExpr("last_seen_at DESC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/postgres: list endpoint activity: %w", err)
+	}
+
+	result := make([]*usage.EndpointActivity, 0, len(models))
+	for i := range models {
+		a, err := endpointSeenFromModel(&models[i])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, a)
+	}
+	return result, nil
+}
+
+func (s *usageStore) UpdateUsageIdentifiers(ctx context.Context, tenantID string, m *usage.IdentifierMatcher, limit int) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	query, args := identifierUpdateSQL(tenantID, m, limit)
+	res, err := s.db.NewRaw(query, args...).Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("keysmith/postgres: update usage identifiers: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	return affected, nil
+}
+
+// identifierUpdateSQL builds the erasure UPDATE. The batch is picked by a
+// subquery, since Postgres has no UPDATE ... LIMIT.
+func identifierUpdateSQL(tenantID string, m *usage.IdentifierMatcher, limit int) (string, []any) {
+	args := []any{tenantID, usage.Redacted}
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	var sets, matches []string
+	for _, c := range []struct{ column, value string }{
+		{"ip_address", m.IPAddress},
+		{"user_agent", m.UserAgent},
+	} {
+		if c.value == "" {
+			continue
+		}
+		p := arg(c.value)
+		sets = append(sets, fmt.Sprintf("%[1]s = CASE WHEN %[1]s = %[2]s THEN $2 ELSE %[1]s END", c.column, p))
+		matches = append(matches, c.column+" = "+p)
+	}
+	where := "tenant_id = $1 AND (" + strings.Join(matches, " OR ") + ")"
+	if m.Before != nil {
+		where += " AND created_at < " + arg(*m.Before)
+	}
+	query := "UPDATE keysmith_usage SET " + strings.Join(sets, ", ") +
+		" WHERE id IN (SELECT id FROM keysmith_usage WHERE " + where + " ORDER BY created_at"
+	if limit > 0 {
+		query += " LIMIT " + arg(limit)
+	}
+	return query + ")", args
+}
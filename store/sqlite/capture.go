@@ -0,0 +1,70 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xraph/grove/drivers/sqlitedriver"
+
+	"github.com/xraph/keysmith/capture"
+	"github.com/xraph/keysmith/id"
+)
+
+type captureStore struct {
+	sdb *sqlitedriver.SqliteDB
+}
+
+func (s *captureStore) Record(ctx context.Context, c *capture.Capture) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, err := s.sdb.NewInsert(captureToModel(c)).Exec(ctx); err != nil {
+		return fmt.Errorf("keysmith/sqlite: record capture: %w", err)
+	}
+	return nil
+}
+
+func (s *captureStore) List(ctx context.Context, keyID id.KeyID, limit int) ([]*capture.Capture, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var models []captureModel
+	q := s.sdb.NewSelect(&models).
+		Where("key_id = ?", keyID.String()).
+		OrderExpr("captured_at DESC")
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+
+	if err := q.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: list captures: %w", err)
+	}
+
+	result := make([]*capture.Capture, 0, len(models))
+	for i := range models {
+		c, err := captureFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/sqlite: convert capture: %w", err)
+		}
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+func (s *captureStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	res, err := s.sdb.NewDelete((*captureModel)(nil)).
+		Where("captured_at < ?", before.UTC()).
+		Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("keysmith/sqlite: purge captures: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	return affected, nil
+}
@@ -0,0 +1,24 @@
+package sqlite
+
+import (
+	"fmt"
+
+	"github.com/xraph/grove"
+
+	"github.com/xraph/keysmith/store"
+)
+
+// DriverName is the grove driver name the store registers under, so that
+// store.Open builds it from a *grove.DB using that driver.
+const DriverName = "sqlite"
+
+func init() { store.RegisterDriver(DriverName, openGrove) }
+
+// openGrove builds a Store from a *grove.DB.
+func openGrove(db any) (store.Store, error) {
+	gdb, ok := db.(*grove.DB)
+	if !ok {
+		return nil, fmt.Errorf("keysmith/sqlite: open: want *grove.DB, got %T", db)
+	}
+	return New(gdb), nil
+}
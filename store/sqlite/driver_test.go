@@ -0,0 +1,38 @@
+//go:build integration
+
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/grove"
+	"github.com/xraph/grove/drivers/sqlitedriver"
+
+	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/store/sqlite"
+)
+
+func TestOpenDriver(t *testing.T) {
+	ctx := context.Background()
+	d := sqlitedriver.New()
+	require.NoError(t, d.Open(ctx, filepath.Join(t.TempDir(), "keysmith.db")))
+	db, err := grove.Open(d)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	assert.Contains(t, store.Drivers(), sqlite.DriverName)
+	s, err := store.Open(db.Driver().Name(), db)
+	require.NoError(t, err)
+	assert.IsType(t, &sqlite.Store{}, s)
+	require.NoError(t, s.Migrate(ctx))
+
+	_, err = store.Open(sqlite.DriverName, "not a grove.DB")
+	require.Error(t, err)
+	_, err = store.Open("nosuchdriver", db)
+	require.ErrorIs(t, err, store.ErrUnknownDriver)
+}
@@ -0,0 +1,31 @@
+//go:build integration
+
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/grove"
+	"github.com/xraph/grove/drivers/sqlitedriver"
+	_ "github.com/xraph/grove/drivers/sqlitedriver/sqlitemigrate"
+
+	"github.com/xraph/keysmith/store/sqlite"
+	"github.com/xraph/keysmith/store/storetest"
+)
+
+func TestExternalRefs(t *testing.T) {
+	ctx := context.Background()
+	d := sqlitedriver.New()
+	require.NoError(t, d.Open(ctx, filepath.Join(t.TempDir(), "keysmith.db")))
+	db, err := grove.Open(d)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	s := sqlite.New(db)
+	require.NoError(t, s.Migrate(ctx))
+	storetest.CheckExternalRefs(t, s, "t1")
+}
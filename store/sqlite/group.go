@@ -0,0 +1,227 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/xraph/grove/driver"
+	"github.com/xraph/grove/drivers/sqlitedriver"
+
+	"github.com/xraph/keysmith/group"
+	"github.com/xraph/keysmith/id"
+)
+
+type groupStore struct {
+	sdb *sqlitedriver.SqliteDB
+}
+
+func (s *groupStore) Create(ctx context.Context, g *group.Group) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, err := s.sdb.NewInsert(groupToModel(g)).Exec(ctx); err != nil {
+		return fmt.Errorf("keysmith/sqlite: create group: %w", err)
+	}
+	return nil
+}
+
+func (s *groupStore) Get(ctx context.Context, groupID id.GroupID) (*group.Group, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m := new(groupModel)
+	err := s.sdb.NewSelect(m).Where("id = ?", groupID.String()).Scan(ctx)
+	if err != nil {
+		if isNoRows(err) {
+			return nil, errNotFound("group")
+		}
+		return nil, fmt.Errorf("keysmith/sqlite: get group: %w", err)
+	}
+	return groupFromModel(m)
+}
+
+func (s *groupStore) Update(ctx context.Context, g *group.Group) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	res, err := s.sdb.NewUpdate(groupToModel(g)).WherePK().Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: update group: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: update group rows: %w", err)
+	}
+	if rows == 0 {
+		return errNotFound("group")
+	}
+	return nil
+}
+
+func (s *groupStore) Delete(ctx context.Context, groupID id.GroupID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Foreign keys may be off, so memberships are removed explicitly.
+	_, err := s.sdb.NewRaw(`DELETE FROM keysmith_key_group_members WHERE group_id = ?`, groupID.String()).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: delete group members: %w", err)
+	}
+	res, err := s.sdb.NewDelete((*groupModel)(nil)).
+		Where("id = ?", groupID.String()).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: delete group: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: delete group rows: %w", err)
+	}
+	if rows == 0 {
+		return errNotFound("group")
+	}
+	return nil
+}
+
+func (s *groupStore) List(ctx context.Context, filter *group.ListFilter) ([]*group.Group, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var models []groupModel
+	q := s.sdb.NewSelect(&models).OrderExpr("name ASC, id ASC")
+
+	if filter != nil {
+		if filter.TenantID != "" {
+			q = q.Where("tenant_id = ?", filter.TenantID)
+		}
+		if filter.AppID != "" {
+			q = q.Where("app_id = ?", filter.AppID)
+		}
+		if filter.Limit > 0 {
+			q = q.Limit(filter.Limit)
+		}
+		if filter.Offset > 0 {
+			q = q.Offset(filter.Offset)
+		}
+	}
+
+	if err := q.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: list groups: %w", err)
+	}
+
+	result := make([]*group.Group, 0, len(models))
+	for i := range models {
+		g, err := groupFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/sqlite: convert group: %w", err)
+		}
+		result = append(result, g)
+	}
+	return result, nil
+}
+
+func (s *groupStore) AddKeys(ctx context.Context, groupID id.GroupID, keyIDs []id.KeyID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tx, err := s.sdb.BeginTxQuery(ctx, &driver.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	gid, err := findGroup(ctx, tx, groupID)
+	if err != nil {
+		return err
+	}
+	for _, keyID := range keyIDs {
+		m := &groupMemberModel{GroupID: gid, KeyID: keyID.String()}
+		if _, err := tx.NewInsert(m).OnConflict("DO NOTHING").Exec(ctx); err != nil {
+			return fmt.Errorf("keysmith/sqlite: add group member: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *groupStore) RemoveKeys(ctx context.Context, groupID id.GroupID, keyIDs []id.KeyID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tx, err := s.sdb.BeginTxQuery(ctx, &driver.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	gid, err := findGroup(ctx, tx, groupID)
+	if err != nil {
+		return err
+	}
+	for _, keyID := range keyIDs {
+		_, err := tx.NewRaw(`DELETE FROM keysmith_key_group_members WHERE group_id = ? AND key_id = ?`,
+			gid, keyID.String()).Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("keysmith/sqlite: remove group member: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// findGroup returns the group's ID, or errNotFound when there is no such
+// group.
+func findGroup(ctx context.Context, tx *sqlitedriver.SqliteTx, groupID id.GroupID) (string, error) {
+	var gid string
+	err := tx.NewRaw(`SELECT id FROM keysmith_key_groups WHERE id = ?`, groupID.String()).Scan(ctx, &gid)
+	if err != nil {
+		if isNoRows(err) {
+			return "", errNotFound("group")
+		}
+		return "", fmt.Errorf("keysmith/sqlite: get group: %w", err)
+	}
+	return gid, nil
+}
+
+func (s *groupStore) ListByKeys(ctx context.Context, keyIDs []id.KeyID) (map[id.KeyID][]id.GroupID, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make(map[id.KeyID][]id.GroupID)
+	if len(keyIDs) == 0 {
+		return result, nil
+	}
+
+	args := make([]any, len(keyIDs))
+	for i, kid := range keyIDs {
+		args[i] = kid.String()
+	}
+	var models []groupMemberModel
+	err := s.sdb.NewSelect(&models).
+		Where("key_id IN ("+strings.Repeat("?, ", len(args)-1)+"?)", args...).
+		OrderExpr("group_id ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: list groups by keys: %w", err)
+	}
+
+	for i := range models {
+		kid, err := id.ParseKeyID(models[i].KeyID)
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/sqlite: convert group member: %w", err)
+		}
+		gid, err := id.ParseGroupID(models[i].GroupID)
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/sqlite: convert group member: %w", err)
+		}
+		result[kid] = append(result[kid], gid)
+	}
+	return result, nil
+}
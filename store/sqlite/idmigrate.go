@@ -0,0 +1,183 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/xraph/grove/driver"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/store"
+)
+
+var _ store.IDMigrator = (*Store)(nil)
+
+// idColumn is a column holding keysmith IDs.
+type idColumn struct{ table, column string }
+
+// idEntity is a table keyed by keysmith IDs and the columns referencing it.
+type idEntity struct {
+	table  string
+	prefix id.Prefix
+	refs   []idColumn
+}
+
+// idEntities lists every table whose id column MigrateIDs rewrites.
+var idEntities = []idEntity{
+	{table: "keysmith_keys", prefix: id.PrefixKey, refs: []idColumn{
+		{"keysmith_key_scopes", "key_id"},
+		{"keysmith_key_hashes", "key_id"},
+		{"keysmith_key_labels", "key_id"},
+		{"keysmith_usage", "key_id"},
+		{"keysmith_usage_agg", "key_id"},
+		{"keysmith_key_endpoint_seen", "key_id"},
+		{"keysmith_rotations", "key_id"},
+		{"keysmith_key_revocations", "key_id"},
+		{"keysmith_key_events", "key_id"},
+		{"keysmith_debug_captures", "key_id"},
+		{"keysmith_key_group_members", "key_id"},
+		{"keysmith_key_transfers", "key_id"},
+		{"keysmith_hash_tombstones", "key_id"},
+		{"keysmith_quota_usage", "key_id"},
+	}},
+	{table: "keysmith_policies", prefix: id.PrefixPolicy, refs: []idColumn{{"keysmith_keys", "policy_id"}, {"keysmith_policies", "base_policy_id"}}},
+	{table: "keysmith_scopes", prefix: id.PrefixScope, refs: []idColumn{{"keysmith_key_scopes", "scope_id"}}},
+	{table: "keysmith_usage", prefix: id.PrefixUsage},
+	{table: "keysmith_rotations", prefix: id.PrefixRotation},
+	{table: "keysmith_debug_captures", prefix: id.PrefixCapture},
+	{table: "keysmith_key_groups", prefix: id.PrefixGroup, refs: []idColumn{{"keysmith_key_group_members", "group_id"}}},
+	{table: "keysmith_key_transfers", prefix: id.PrefixTransfer},
+	{table: "keysmith_quota_pools", prefix: id.PrefixQuotaPool, refs: []idColumn{{"keysmith_keys", "pool_id"}, {"keysmith_quota_usage", "pool_id"}}},
+}
+
+// MigrateIDs rewrites stored IDs into opts.To, walking each entity table in
+// id order. Foreign keys are checked when each batch commits, so an ID and
+// the rows referencing it can be rewritten one after the other.
+func (s *Store) MigrateIDs(ctx context.Context, opts store.IDMigrationOptions) (*store.IDMigrationReport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if !opts.To.Valid() {
+		return nil, fmt.Errorf("keysmith/sqlite: unknown id format %d", opts.To)
+	}
+	batch := opts.BatchSize
+	if batch <= 0 {
+		batch = store.DefaultIDMigrationBatch
+	}
+	report := &store.IDMigrationReport{Backend: "sqlite", To: opts.To, Columns: []store.IDColumnReport{}, StartedAt: time.Now()}
+
+	for _, ent := range idEntities {
+		if len(opts.Tables) > 0 && !slices.Contains(opts.Tables, ent.table) {
+			continue
+		}
+		cols := append([]idColumn{{ent.table, "id"}}, ent.refs...)
+		first := len(report.Columns)
+		for _, c := range cols {
+			report.Columns = append(report.Columns, store.IDColumnReport{Table: c.table, Column: c.column})
+		}
+
+		cursor := ""
+		for {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			ids, err := s.idBatch(ctx, ent.table, cursor, batch)
+			if err != nil {
+				return nil, err
+			}
+			if len(ids) == 0 {
+				break
+			}
+			cursor = ids[len(ids)-1]
+
+			rewritten, err := s.rewriteIDs(ctx, ent.prefix, cols, ids, opts.To)
+			if err != nil {
+				return nil, err
+			}
+			if rewritten != nil {
+				report.Batches++
+				for i, n := range rewritten {
+					report.Columns[first+i].Rewritten += n
+				}
+			}
+			if len(ids) < batch {
+				break
+			}
+		}
+	}
+
+	report.Duration = time.Since(report.StartedAt)
+	return report, nil
+}
+
+// idBatch returns up to limit ids of table after cursor, in order.
+func (s *Store) idBatch(ctx context.Context, table, cursor string, limit int) ([]string, error) {
+	// Table names come from idEntities, so concatenating them is safe.
+	rows, err := s.sdb.Query(ctx, `SELECT id FROM `+table+` WHERE id > ? ORDER BY id LIMIT ?`, cursor, limit)
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: list %s ids: %w", table, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	ids := make([]string, 0, limit)
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, fmt.Errorf("keysmith/sqlite: list %s ids: %w", table, err)
+		}
+		ids = append(ids, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: list %s ids: %w", table, err)
+	}
+	return ids, nil
+}
+
+// rewriteIDs rewrites ids, and every column in cols holding them, into to
+// in one transaction. It returns the rows changed per column, or nil when
+// every ID was already in the target format.
+func (s *Store) rewriteIDs(ctx context.Context, prefix id.Prefix, cols []idColumn, ids []string, to id.Format) ([]int64, error) {
+	type change struct{ from, to string }
+	var changes []change
+	for _, old := range ids {
+		next, err := id.Reformat(old, prefix, to)
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/sqlite: rewrite id %q: %w", old, err)
+		}
+		if next != old {
+			changes = append(changes, change{old, next})
+		}
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	tx, err := s.sdb.BeginTxQuery(ctx, &driver.TxOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Resets at commit; a no-op when foreign keys are not enforced.
+	if _, err := tx.NewRaw(`PRAGMA defer_foreign_keys = ON`).Exec(ctx); err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: defer foreign keys: %w", err)
+	}
+	rewritten := make([]int64, len(cols))
+	for _, ch := range changes {
+		for i, c := range cols {
+			res, err := tx.NewRaw(`UPDATE `+c.table+` SET `+c.column+` = ? WHERE `+c.column+` = ?`, ch.to, ch.from).Exec(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("keysmith/sqlite: rewrite %s.%s: %w", c.table, c.column, err)
+			}
+			affected, _ := res.RowsAffected()
+			rewritten[i] += affected
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: commit id rewrite: %w", err)
+	}
+	return rewritten, nil
+}
@@ -0,0 +1,96 @@
+//go:build integration
+
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/grove"
+	"github.com/xraph/grove/drivers/sqlitedriver"
+	_ "github.com/xraph/grove/drivers/sqlitedriver/sqlitemigrate"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/store/sqlite"
+	"github.com/xraph/keysmith/usage"
+)
+
+func TestMigrateIDs(t *testing.T) {
+	ctx := context.Background()
+	d := sqlitedriver.New()
+	require.NoError(t, d.Open(ctx, filepath.Join(t.TempDir(), "keysmith.db")))
+	db, err := grove.Open(d)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	sdb := sqlitedriver.Unwrap(db)
+	_, err = sdb.Exec(ctx, `PRAGMA foreign_keys = ON`)
+	require.NoError(t, err)
+
+	s := sqlite.New(db)
+	require.NoError(t, s.Migrate(ctx))
+	now := time.Now()
+	keyIDs := make([]id.KeyID, 3)
+	for i := range keyIDs {
+		keyIDs[i] = id.NewKeyID()
+		require.NoError(t, s.Keys().Create(ctx, &key.Key{
+			ID: keyIDs[i], TenantID: "t1", KeyHash: "h" + keyIDs[i].String(), Prefix: "sk",
+			Environment: key.EnvTest, State: key.StateActive, CreatedAt: now, UpdatedAt: now,
+		}))
+	}
+	sc := &scope.Scope{ID: id.NewScopeID(), TenantID: "t1", Name: "read", CreatedAt: now}
+	require.NoError(t, s.Scopes().Create(ctx, sc))
+	require.NoError(t, s.Scopes().AssignToKey(ctx, keyIDs[0], []string{"read"}))
+	require.NoError(t, s.Usages().Record(ctx, &usage.Record{
+		ID: id.NewUsageID(), KeyID: keyIDs[0], TenantID: "t1", Endpoint: "/v1", Method: "GET", CreatedAt: now,
+	}))
+
+	report, err := s.MigrateIDs(ctx, store.IDMigrationOptions{To: id.FormatUUID, BatchSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "sqlite", report.Backend)
+	rewritten := make(map[string]int64)
+	for _, c := range report.Columns {
+		rewritten[c.Table+"."+c.Column] = c.Rewritten
+	}
+	assert.Equal(t, int64(3), rewritten["keysmith_keys.id"])
+	assert.Equal(t, int64(1), rewritten["keysmith_key_scopes.key_id"])
+	assert.Equal(t, int64(1), rewritten["keysmith_key_scopes.scope_id"])
+	assert.Equal(t, int64(1), rewritten["keysmith_usage.key_id"])
+	assert.Equal(t, int64(1), rewritten["keysmith_usage.id"])
+	assert.Equal(t, 4, report.Batches, "two key batches, one scope batch, one usage batch")
+
+	var keyID, scopeID string
+	require.NoError(t, sdb.QueryRow(ctx, `SELECT key_id, scope_id FROM keysmith_key_scopes`).Scan(&keyID, &scopeID))
+	assert.Equal(t, keyIDs[0].UUID(), keyID)
+	assert.Equal(t, sc.ID.UUID(), scopeID)
+	var violations int
+	rows, err := sdb.Query(ctx, `PRAGMA foreign_key_check`)
+	require.NoError(t, err)
+	for rows.Next() {
+		violations++
+	}
+	require.NoError(t, rows.Close())
+	assert.Zero(t, violations)
+
+	report, err = s.MigrateIDs(ctx, store.IDMigrationOptions{To: id.FormatUUID})
+	require.NoError(t, err)
+	assert.Zero(t, report.Batches, "a second run has nothing to rewrite")
+
+	report, err = s.MigrateIDs(ctx, store.IDMigrationOptions{To: id.FormatTypeID, Tables: []string{"keysmith_keys"}})
+	require.NoError(t, err)
+	require.NotEmpty(t, report.Columns)
+	assert.Equal(t, "keysmith_keys", report.Columns[0].Table)
+	for _, c := range report.Columns {
+		assert.NotEqual(t, "keysmith_scopes", c.Table)
+	}
+	require.NoError(t, sdb.QueryRow(ctx, `SELECT key_id, scope_id FROM keysmith_key_scopes`).Scan(&keyID, &scopeID))
+	assert.Equal(t, keyIDs[0].String(), keyID)
+	assert.Equal(t, sc.ID.UUID(), scopeID, "scopes were not selected")
+}
@@ -3,28 +3,63 @@ package sqlite
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/xraph/grove/driver"
 	"github.com/xraph/grove/drivers/sqlitedriver"
 
 	"github.com/xraph/keysmith/id"
 	"github.com/xraph/keysmith/key"
 )
 
+// iterateBatchSize is the number of rows fetched per round trip by Iterate.
+const iterateBatchSize = 500
+
+// externalRefConflict skips the insert of a key whose external reference is
+// taken in its tenant, leaving no row affected. Conflicts on other
+// constraints still fail.
+const externalRefConflict = "(tenant_id, external_ref) WHERE external_ref <> '' DO NOTHING"
+
 type keyStore struct {
 	sdb *sqlitedriver.SqliteDB
 }
 
 func (s *keyStore) Create(ctx context.Context, k *key.Key) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tx, err := s.sdb.BeginTxQuery(ctx, &driver.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
 	m := keyToModel(k)
-	_, err := s.sdb.NewInsert(m).Exec(ctx)
+	res, err := tx.NewInsert(m).OnConflict(externalRefConflict).Exec(ctx)
 	if err != nil {
 		return fmt.Errorf("keysmith/sqlite: create key: %w", err)
 	}
-	return nil
+	if rows, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("keysmith/sqlite: create key rows: %w", err)
+	} else if rows == 0 {
+		return key.ErrExternalRefInUse
+	}
+	if err := upsertKeyHash(ctx, tx, keyHashToModel(&key.HashVersion{KeyID: k.ID, Hash: k.KeyHash, CreatedAt: k.CreatedAt})); err != nil {
+		return err
+	}
+	if err := replaceKeyLabels(ctx, tx, m.ID, k.Labels); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (s *keyStore) Get(ctx context.Context, keyID id.KeyID) (*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	m := new(keyModel)
 	err := s.sdb.NewSelect(m).Where("id = ?", keyID.String()).Scan(ctx)
 	if err != nil {
@@ -37,8 +72,13 @@ func (s *keyStore) Get(ctx context.Context, keyID id.KeyID) (*key.Key, error) {
 }
 
 func (s *keyStore) GetByHash(ctx context.Context, hash string) (*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	m := new(keyModel)
-	err := s.sdb.NewSelect(m).Where("key_hash = ?", hash).Scan(ctx)
+	cond, args := hashIn(key.LookupHashes(hash))
+	err := s.sdb.NewSelect(m).Where(cond, args...).Limit(1).Scan(ctx)
 	if err != nil {
 		if isNoRows(err) {
 			return nil, errNotFound("key")
@@ -48,38 +88,226 @@ func (s *keyStore) GetByHash(ctx context.Context, hash string) (*key.Key, error)
 	return keyFromModel(m)
 }
 
-func (s *keyStore) GetByPrefix(ctx context.Context, prefix, hint string) (*key.Key, error) {
-	m := new(keyModel)
-	err := s.sdb.NewSelect(m).
+func (s *keyStore) GetByHashes(ctx context.Context, hashes []string) ([]*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(hashes) == 0 {
+		return []*key.Key{}, nil
+	}
+	cond, args := hashIn(key.LookupHashes(hashes...))
+	var models []keyModel
+	err := s.sdb.NewSelect(&models).
+		Where(cond, args...).
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: get keys by hash: %w", err)
+	}
+
+	result := make([]*key.Key, 0, len(models))
+	for i := range models {
+		k, err := keyFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/sqlite: convert key: %w", err)
+		}
+		result = append(result, k)
+	}
+	return result, nil
+}
+
+// hashIn returns a condition matching keys with an active hash version in
+// hashes, and its arguments.
+func hashIn(hashes []string) (string, []any) {
+	args := make([]any, len(hashes))
+	for i, h := range hashes {
+		args[i] = h
+	}
+	return "id IN (SELECT key_id FROM keysmith_key_hashes WHERE active = 1 AND hash IN (" +
+		strings.Repeat("?, ", len(hashes)-1) + "?))", args
+}
+
+func (s *keyStore) AddHash(ctx context.Context, h *key.HashVersion) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tx, err := s.sdb.BeginTxQuery(ctx, &driver.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var kid string
+	err = tx.NewRaw(`SELECT id FROM keysmith_keys WHERE id = ?`, h.KeyID.String()).Scan(ctx, &kid)
+	if err != nil {
+		if isNoRows(err) {
+			return errNotFound("key")
+		}
+		return fmt.Errorf("keysmith/sqlite: get key: %w", err)
+	}
+	m := keyHashToModel(h)
+	if time.Time(m.CreatedAt).IsZero() {
+		m.CreatedAt = sqliteTime(time.Now().UTC())
+	}
+	if err := upsertKeyHash(ctx, tx, m); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *keyStore) ListHashes(ctx context.Context, keyID id.KeyID) ([]*key.HashVersion, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var models []keyHashModel
+	err := s.sdb.NewSelect(&models).
+		Where("key_id = ?", keyID.String()).
+		OrderExpr("created_at ASC, hash ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: list key hashes: %w", err)
+	}
+
+	result := make([]*key.HashVersion, 0, len(models))
+	for i := range models {
+		h, err := keyHashFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/sqlite: convert key hash: %w", err)
+		}
+		result = append(result, h)
+	}
+	return result, nil
+}
+
+func (s *keyStore) DeactivateHash(ctx context.Context, keyID id.KeyID, hash string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	kid := keyID.String()
+	_, err := s.sdb.NewRaw(`
+		UPDATE keysmith_key_hashes SET active = 0
+		WHERE key_id = ? AND hash = ?
+		AND hash <> (SELECT key_hash FROM keysmith_keys WHERE id = ?)`, kid, hash, kid).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: deactivate key hash: %w", err)
+	}
+	return nil
+}
+
+// upsertKeyHash stores m as an active version, reactivating it if the key
+// already has the hash. A hash of another key fails with key.ErrHashInUse.
+func upsertKeyHash(ctx context.Context, tx *sqlitedriver.SqliteTx, m *keyHashModel) error {
+	var owner string
+	err := tx.NewRaw(`SELECT key_id FROM keysmith_key_hashes WHERE hash = ?`, m.Hash).Scan(ctx, &owner)
+	switch {
+	case isNoRows(err):
+		m.Active = true
+		if _, err := tx.NewInsert(m).Exec(ctx); err != nil {
+			return fmt.Errorf("keysmith/sqlite: store key hash: %w", err)
+		}
+		return nil
+	case err != nil:
+		return fmt.Errorf("keysmith/sqlite: get key hash: %w", err)
+	case owner != m.KeyID:
+		return key.ErrHashInUse
+	}
+	_, err = tx.NewRaw(`UPDATE keysmith_key_hashes SET active = 1 WHERE hash = ?`, m.Hash).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: store key hash: %w", err)
+	}
+	return nil
+}
+
+// replaceKeyLabels rewrites the keysmith_key_labels rows of a key, which
+// selectors query, to match labels. The labels column holds the same pairs
+// for reading the key back.
+func replaceKeyLabels(ctx context.Context, tx *sqlitedriver.SqliteTx, keyID string, labels key.Labels) error {
+	if _, err := tx.NewRaw(`DELETE FROM keysmith_key_labels WHERE key_id = ?`, keyID).Exec(ctx); err != nil {
+		return fmt.Errorf("keysmith/sqlite: clear key labels: %w", err)
+	}
+	for name, value := range labels {
+		_, err := tx.NewRaw(`INSERT INTO keysmith_key_labels (key_id, name, value) VALUES (?, ?, ?)`, keyID, name, value).Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("keysmith/sqlite: store key labels: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *keyStore) ListByPrefixHint(ctx context.Context, prefix, hint string) ([]*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var models []keyModel
+	err := s.sdb.NewSelect(&models).
 		Where("prefix = ?", prefix).
 		Where("hint = ?", hint).
+		OrderExpr("created_at ASC, id ASC").
 		Scan(ctx)
 	if err != nil {
-		if isNoRows(err) {
-			return nil, errNotFound("key")
+		return nil, fmt.Errorf("keysmith/sqlite: list keys by prefix and hint: %w", err)
+	}
+
+	result := make([]*key.Key, 0, len(models))
+	for i := range models {
+		k, err := keyFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/sqlite: convert key: %w", err)
 		}
-		return nil, fmt.Errorf("keysmith/sqlite: get key by prefix: %w", err)
+		result = append(result, k)
 	}
-	return keyFromModel(m)
+	return result, nil
 }
 
 func (s *keyStore) Update(ctx context.Context, k *key.Key) error {
-	m := keyToModel(k)
-	res, err := s.sdb.NewUpdate(m).WherePK().Exec(ctx)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tx, err := s.sdb.BeginTxQuery(ctx, &driver.TxOptions{})
 	if err != nil {
-		return fmt.Errorf("keysmith/sqlite: update key: %w", err)
+		return fmt.Errorf("keysmith/sqlite: begin tx: %w", err)
 	}
-	rows, err := res.RowsAffected()
+	defer func() { _ = tx.Rollback() }()
+
+	var prev string
+	err = tx.NewRaw(`SELECT key_hash FROM keysmith_keys WHERE id = ?`, k.ID.String()).Scan(ctx, &prev)
 	if err != nil {
-		return fmt.Errorf("keysmith/sqlite: update key rows: %w", err)
+		if isNoRows(err) {
+			return errNotFound("key")
+		}
+		return fmt.Errorf("keysmith/sqlite: get key: %w", err)
 	}
-	if rows == 0 {
-		return errNotFound("key")
+
+	m := keyToModel(k)
+	if _, err := tx.NewUpdate(m).WherePK().Exec(ctx); err != nil {
+		return fmt.Errorf("keysmith/sqlite: update key: %w", err)
 	}
-	return nil
+	if err := replaceKeyLabels(ctx, tx, m.ID, k.Labels); err != nil {
+		return err
+	}
+	// A new hash replaces the secret, so the old one's versions retire.
+	if prev != k.KeyHash {
+		_, err := tx.NewRaw(`UPDATE keysmith_key_hashes SET active = 0 WHERE key_id = ?`, m.ID).Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("keysmith/sqlite: retire key hashes: %w", err)
+		}
+		if err := upsertKeyHash(ctx, tx, keyHashToModel(&key.HashVersion{KeyID: k.ID, Hash: k.KeyHash, CreatedAt: time.Now()})); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
 func (s *keyStore) UpdateState(ctx context.Context, keyID id.KeyID, state key.State) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	res, err := s.sdb.NewUpdate((*keyModel)(nil)).
 		Set("state = ?", string(state)).
 		Set("updated_at = ?", time.Now().UTC()).
@@ -98,7 +326,32 @@ func (s *keyStore) UpdateState(ctx context.Context, keyID id.KeyID, state key.St
 	return nil
 }
 
+func (s *keyStore) UpdateStateIf(ctx context.Context, keyID id.KeyID, from, to key.State) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	res, err := s.sdb.NewUpdate((*keyModel)(nil)).
+		Set("state = ?", string(to)).
+		Set("updated_at = ?", time.Now().UTC()).
+		Where("id = ?", keyID.String()).
+		Where("state = ?", string(from)).
+		Exec(ctx)
+	if err != nil {
+		return false, fmt.Errorf("keysmith/sqlite: update key state if: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("keysmith/sqlite: update key state if rows: %w", err)
+	}
+	return rows > 0, nil
+}
+
 func (s *keyStore) UpdateLastUsed(ctx context.Context, keyID id.KeyID, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	res, err := s.sdb.NewUpdate((*keyModel)(nil)).
 		Set("last_used_at = ?", at).
 		Where("id = ?", keyID.String()).
@@ -117,6 +370,28 @@ func (s *keyStore) UpdateLastUsed(ctx context.Context, keyID id.KeyID, at time.T
 }
 
 func (s *keyStore) Delete(ctx context.Context, keyID id.KeyID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Foreign keys may be off, so hash versions, labels, group
+	// memberships, and transfers are removed explicitly.
+	_, err := s.sdb.NewRaw(`DELETE FROM keysmith_key_hashes WHERE key_id = ?`, keyID.String()).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: delete key hashes: %w", err)
+	}
+	_, err = s.sdb.NewRaw(`DELETE FROM keysmith_key_labels WHERE key_id = ?`, keyID.String()).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: delete key labels: %w", err)
+	}
+	_, err = s.sdb.NewRaw(`DELETE FROM keysmith_key_group_members WHERE key_id = ?`, keyID.String()).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: delete key group memberships: %w", err)
+	}
+	_, err = s.sdb.NewRaw(`DELETE FROM keysmith_key_transfers WHERE key_id = ?`, keyID.String()).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: delete key transfers: %w", err)
+	}
 	res, err := s.sdb.NewDelete((*keyModel)(nil)).
 		Where("id = ?", keyID.String()).
 		Exec(ctx)
@@ -134,25 +409,14 @@ func (s *keyStore) Delete(ctx context.Context, keyID id.KeyID) error {
 }
 
 func (s *keyStore) List(ctx context.Context, filter *key.ListFilter) ([]*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var models []keyModel
-	q := s.sdb.NewSelect(&models).OrderExpr("created_at DESC")
+	q := applyKeyFilter(s.sdb.NewSelect(&models).OrderExpr("created_at DESC"), filter)
 
 	if filter != nil {
-		if filter.TenantID != "" {
-			q = q.Where("tenant_id = ?", filter.TenantID)
-		}
-		if filter.Environment != "" {
-			q = q.Where("environment = ?", string(filter.Environment))
-		}
-		if filter.State != "" {
-			q = q.Where("state = ?", string(filter.State))
-		}
-		if filter.PolicyID != nil {
-			q = q.Where("policy_id = ?", filter.PolicyID.String())
-		}
-		if filter.CreatedBy != "" {
-			q = q.Where("created_by = ?", filter.CreatedBy)
-		}
 		if filter.Limit > 0 {
 			q = q.Limit(filter.Limit)
 		}
@@ -177,39 +441,176 @@ func (s *keyStore) List(ctx context.Context, filter *key.ListFilter) ([]*key.Key
 }
 
 func (s *keyStore) Count(ctx context.Context, filter *key.ListFilter) (int64, error) {
-	q := s.sdb.NewSelect((*keyModel)(nil))
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	q := applyKeyFilter(s.sdb.NewSelect((*keyModel)(nil)), filter)
+
+	count, err := q.Count(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("keysmith/sqlite: count keys: %w", err)
+	}
+	return count, nil
+}
 
+// Iterate walks matching keys in batches of iterateBatchSize using keyset
+// pagination on the primary key, so rows updated by fn mid-iteration are
+// neither skipped nor revisited.
+func (s *keyStore) Iterate(ctx context.Context, filter *key.ListFilter, fn func(*key.Key) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var limit, offset int
 	if filter != nil {
-		if filter.TenantID != "" {
-			q = q.Where("tenant_id = ?", filter.TenantID)
+		limit, offset = filter.Limit, filter.Offset
+	}
+
+	var cursor string
+	visited := 0
+	for {
+		batch := iterateBatchSize
+		if limit > 0 && limit-visited < batch {
+			batch = limit - visited
 		}
-		if filter.Environment != "" {
-			q = q.Where("environment = ?", string(filter.Environment))
+
+		var models []keyModel
+		q := applyKeyFilter(s.sdb.NewSelect(&models), filter).
+			OrderExpr("id ASC").
+			Limit(batch)
+		if cursor != "" {
+			q = q.Where("id > ?", cursor)
+		} else if offset > 0 {
+			q = q.Offset(offset)
 		}
-		if filter.State != "" {
-			q = q.Where("state = ?", string(filter.State))
+		if err := q.Scan(ctx); err != nil {
+			return fmt.Errorf("keysmith/sqlite: iterate keys: %w", err)
 		}
-		if filter.PolicyID != nil {
-			q = q.Where("policy_id = ?", filter.PolicyID.String())
+
+		for i := range models {
+			k, err := keyFromModel(&models[i])
+			if err != nil {
+				return fmt.Errorf("keysmith/sqlite: convert key: %w", err)
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(k); err != nil {
+				return err
+			}
 		}
-		if filter.CreatedBy != "" {
-			q = q.Where("created_by = ?", filter.CreatedBy)
+
+		visited += len(models)
+		if len(models) < batch || (limit > 0 && visited >= limit) {
+			return nil
 		}
+		cursor = models[len(models)-1].ID
 	}
+}
 
-	count, err := q.Count(ctx)
+// Overview groups the matching keys by state and environment and sums a
+// CASE expression per window in each group, so one scan serves every count.
+func (s *keyStore) Overview(ctx context.Context, q *key.OverviewQuery) (*key.Overview, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	now := q.Now.UTC()
+	active := string(key.StateActive)
+	sel := applyKeyFilter(s.sdb.NewSelect((*keyModel)(nil)), &key.ListFilter{TenantID: q.TenantID, AppID: q.AppID}).
+		ColumnExpr("state").
+		ColumnExpr("environment").
+		ColumnExpr("COUNT(*)").
+		ColumnExpr("SUM(CASE WHEN created_at > ? THEN 1 ELSE 0 END)", now.Add(-q.CreatedWithin)).
+		ColumnExpr("SUM(CASE WHEN state = ? AND last_used_at IS NULL THEN 1 ELSE 0 END)", active)
+	for _, w := range q.ExpiringWithin {
+		sel = sel.ColumnExpr("SUM(CASE WHEN state = ? AND expires_at > ? AND expires_at <= ? THEN 1 ELSE 0 END)",
+			active, now, now.Add(w))
+	}
+	query, args, err := sel.GroupExpr("state, environment").Build()
 	if err != nil {
-		return 0, fmt.Errorf("keysmith/sqlite: count keys: %w", err)
+		return nil, fmt.Errorf("keysmith/sqlite: key overview: %w", err)
 	}
-	return count, nil
+
+	rows, err := s.sdb.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: key overview: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	o := key.NewOverview(q)
+	var state, env string
+	counts := make([]int64, 3+len(q.ExpiringWithin))
+	dest := []any{&state, &env}
+	for i := range counts {
+		dest = append(dest, &counts[i])
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("keysmith/sqlite: key overview: %w", err)
+		}
+		o.Total += counts[0]
+		o.ByState[key.State(state)] += counts[0]
+		o.ByEnvironment[key.Environment(env)] += counts[0]
+		o.CreatedRecently.Count += counts[1]
+		o.NeverUsed += counts[2]
+		for i := range o.Expiring {
+			o.Expiring[i].Count += counts[3+i]
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: key overview: %w", err)
+	}
+	return o, nil
+}
+
+// Search finds candidates with the keysmith_keys_fts index, one quoted
+// FTS5 phrase per term, and checks them with key.MatchSearch, since the
+// index does not keep fields apart.
+func (s *keyStore) Search(ctx context.Context, q *key.SearchQuery) ([]*key.SearchMatch, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(q.Terms) == 0 {
+		return []*key.SearchMatch{}, nil
+	}
+	phrases := make([]string, len(q.Terms))
+	for i, t := range q.Terms {
+		phrases[i] = `"` + t.String() + `"`
+	}
+	var models []keyModel
+	err := applyKeyFilter(s.sdb.NewSelect(&models), &key.ListFilter{TenantID: q.TenantID, AppID: q.AppID}).
+		Where("rowid IN (SELECT rowid FROM keysmith_keys_fts WHERE keysmith_keys_fts MATCH ?)", strings.Join(phrases, " ")).
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: search keys: %w", err)
+	}
+
+	matches := make([]*key.SearchMatch, 0, len(models))
+	for i := range models {
+		k, err := keyFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/sqlite: convert key: %w", err)
+		}
+		if m := key.MatchSearch(k, q.Terms); m != nil {
+			matches = append(matches, m)
+		}
+	}
+	return key.RankSearch(matches, q.Limit), nil
 }
 
 func (s *keyStore) ListExpired(ctx context.Context, before time.Time) ([]*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var models []keyModel
 	err := s.sdb.NewSelect(&models).
 		Where("state = ?", string(key.StateActive)).
 		Where("expires_at IS NOT NULL").
-		Where("expires_at < ?", before).
+		Where("expires_at < ?", before.UTC()).
 		Scan(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("keysmith/sqlite: list expired: %w", err)
@@ -226,7 +627,38 @@ func (s *keyStore) ListExpired(ctx context.Context, before time.Time) ([]*key.Ke
 	return result, nil
 }
 
+func (s *keyStore) ListRecentlyUsed(ctx context.Context, limit int) ([]*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var models []keyModel
+	q := s.sdb.NewSelect(&models).
+		Where("last_used_at IS NOT NULL").
+		OrderExpr("last_used_at DESC")
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	if err := q.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: list recently used: %w", err)
+	}
+
+	result := make([]*key.Key, 0, len(models))
+	for i := range models {
+		k, err := keyFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/sqlite: convert key: %w", err)
+		}
+		result = append(result, k)
+	}
+	return result, nil
+}
+
 func (s *keyStore) ListByPolicy(ctx context.Context, policyID id.PolicyID) ([]*key.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var models []keyModel
 	err := s.sdb.NewSelect(&models).
 		Where("policy_id = ?", policyID.String()).
@@ -247,7 +679,35 @@ func (s *keyStore) ListByPolicy(ctx context.Context, policyID id.PolicyID) ([]*k
 }
 
 func (s *keyStore) DeleteByTenant(ctx context.Context, tenantID string) error {
-	_, err := s.sdb.NewDelete((*keyModel)(nil)).
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	_, err := s.sdb.NewRaw(`
+		DELETE FROM keysmith_key_hashes
+		WHERE key_id IN (SELECT id FROM keysmith_keys WHERE tenant_id = ?)`, tenantID).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: delete key hashes by tenant: %w", err)
+	}
+	_, err = s.sdb.NewRaw(`
+		DELETE FROM keysmith_key_labels
+		WHERE key_id IN (SELECT id FROM keysmith_keys WHERE tenant_id = ?)`, tenantID).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: delete key labels by tenant: %w", err)
+	}
+	_, err = s.sdb.NewRaw(`
+		DELETE FROM keysmith_key_group_members
+		WHERE key_id IN (SELECT id FROM keysmith_keys WHERE tenant_id = ?)`, tenantID).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: delete key group memberships by tenant: %w", err)
+	}
+	_, err = s.sdb.NewRaw(`
+		DELETE FROM keysmith_key_transfers
+		WHERE key_id IN (SELECT id FROM keysmith_keys WHERE tenant_id = ?)`, tenantID).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: delete key transfers by tenant: %w", err)
+	}
+	_, err = s.sdb.NewDelete((*keyModel)(nil)).
 		Where("tenant_id = ?", tenantID).
 		Exec(ctx)
 	if err != nil {
@@ -255,3 +715,92 @@ func (s *keyStore) DeleteByTenant(ctx context.Context, tenantID string) error {
 	}
 	return nil
 }
+
+// applyKeyFilter adds the WHERE clauses shared by List, Count, and Iterate.
+func applyKeyFilter(q *sqlitedriver.SelectQuery, filter *key.ListFilter) *sqlitedriver.SelectQuery {
+	if filter == nil {
+		return q
+	}
+	if filter.TenantID != "" {
+		q = q.Where("tenant_id = ?", filter.TenantID)
+	}
+	if filter.AppID != "" {
+		q = q.Where("app_id = ?", filter.AppID)
+	}
+	if filter.Environment != "" {
+		q = q.Where("environment = ?", string(filter.Environment))
+	}
+	if filter.State != "" {
+		q = q.Where("state = ?", string(filter.State))
+	}
+	if filter.PolicyID != nil {
+		q = q.Where("policy_id = ?", filter.PolicyID.String())
+	}
+	if filter.CreatedBy != "" {
+		q = q.Where("created_by = ?", filter.CreatedBy)
+	}
+	if filter.ExternalRef != "" {
+		q = q.Where("external_ref = ?", filter.ExternalRef)
+	}
+	if filter.Name != "" {
+		q = q.Where("lower(name) = lower(?)", filter.Name)
+	}
+	if filter.ExpiresBefore != nil {
+		q = q.Where("expires_at IS NOT NULL").Where("expires_at < ?", filter.ExpiresBefore.UTC())
+	}
+	if filter.CreatedAfter != nil {
+		q = q.Where("created_at > ?", filter.CreatedAfter.UTC())
+	}
+	if filter.CreatedBefore != nil {
+		q = q.Where("created_at < ?", filter.CreatedBefore.UTC())
+	}
+	if filter.UpdatedAfter != nil {
+		q = q.Where("updated_at > ?", filter.UpdatedAfter.UTC())
+	}
+	if filter.OutdatedTerms != "" {
+		q = q.Where("accepted_terms_version <> ?", filter.OutdatedTerms)
+	}
+	for _, r := range filter.LabelSelector {
+		cond, args := labelCondition(r)
+		q = q.Where(cond, args...)
+	}
+	if filter.GroupID != nil {
+		q = q.Where("id IN (SELECT key_id FROM keysmith_key_group_members WHERE group_id = ?)", filter.GroupID.String())
+	}
+	if filter.PoolID != nil {
+		q = q.Where("pool_id = ?", filter.PoolID.String())
+	}
+	return q
+}
+
+// labelCondition returns the WHERE clause for one selector requirement.
+// Each is a semi-join on keysmith_key_labels served by its (name, value)
+// index; negated requirements also match keys without the label.
+func labelCondition(r key.Requirement) (string, []any) {
+	args := make([]any, 0, 1+len(r.Values))
+	args = append(args, r.Name)
+	for _, v := range r.Values {
+		args = append(args, v)
+	}
+	switch r.Op {
+	case key.SelectorEquals, key.SelectorIn, key.SelectorNotEquals, key.SelectorNotIn:
+		negated := r.Op == key.SelectorNotEquals || r.Op == key.SelectorNotIn
+		if len(r.Values) == 0 {
+			if negated {
+				return "1", nil
+			}
+			return "0", nil
+		}
+		op := "IN"
+		if negated {
+			op = "NOT IN"
+		}
+		return "id " + op + " (SELECT key_id FROM keysmith_key_labels WHERE name = ? AND value IN (" +
+			strings.Repeat("?, ", len(r.Values)-1) + "?))", args
+	case key.SelectorExists:
+		return "id IN (SELECT key_id FROM keysmith_key_labels WHERE name = ?)", args[:1]
+	case key.SelectorDoesNotExist:
+		return "id NOT IN (SELECT key_id FROM keysmith_key_labels WHERE name = ?)", args[:1]
+	}
+	return "0", nil
+}
@@ -0,0 +1,59 @@
+//go:build integration
+
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/grove"
+	"github.com/xraph/grove/drivers/sqlitedriver"
+	_ "github.com/xraph/grove/drivers/sqlitedriver/sqlitemigrate"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store/sqlite"
+)
+
+func TestKeyTimestamps(t *testing.T) {
+	ctx := context.Background()
+	d := sqlitedriver.New()
+	require.NoError(t, d.Open(ctx, filepath.Join(t.TempDir(), "keysmith.db")))
+	db, err := grove.Open(d)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	s := sqlite.New(db)
+	require.NoError(t, s.Migrate(ctx))
+
+	// time.Now carries a monotonic reading, which the driver writes out.
+	now := time.Now()
+	expires := now.Add(time.Hour).UTC()
+	k := &key.Key{
+		ID: id.NewKeyID(), TenantID: "t1", KeyHash: "h1", Prefix: "sk", Environment: key.EnvTest,
+		State: key.StateActive, ExpiresAt: &expires, CreatedAt: now, UpdatedAt: now,
+	}
+	require.NoError(t, s.Keys().Create(ctx, k))
+
+	got, err := s.Keys().Get(ctx, k.ID)
+	require.NoError(t, err)
+	assert.True(t, now.Equal(got.CreatedAt), "created %v, read %v", now, got.CreatedAt)
+	require.NotNil(t, got.ExpiresAt)
+	assert.True(t, expires.Equal(*got.ExpiresAt))
+	assert.Nil(t, got.RevokedAt)
+
+	keys, err := s.Keys().List(ctx, &key.ListFilter{TenantID: "t1"})
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.True(t, now.Equal(keys[0].UpdatedAt))
+
+	hashes, err := s.Keys().ListHashes(ctx, k.ID)
+	require.NoError(t, err)
+	require.Len(t, hashes, 1)
+	assert.True(t, now.Equal(hashes[0].CreatedAt))
+}
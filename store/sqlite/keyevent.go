@@ -0,0 +1,93 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xraph/grove/drivers/sqlitedriver"
+
+	"github.com/xraph/keysmith/keyevent"
+)
+
+type keyEventStore struct {
+	sdb *sqlitedriver.SqliteDB
+}
+
+func (s *keyEventStore) Append(ctx context.Context, e *keyevent.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	_, err := s.sdb.NewInsert(keyEventToModel(e)).
+		OnConflict("(at, key_id) DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: append key event: %w", err)
+	}
+	return nil
+}
+
+func (s *keyEventStore) ListAfter(ctx context.Context, filter *keyevent.ListFilter) ([]*keyevent.Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if filter == nil {
+		filter = &keyevent.ListFilter{}
+	}
+	after := filter.After
+	var models []keyEventModel
+	q := s.sdb.NewSelect(&models).
+		Where("(at > ? OR (at = ? AND key_id > ?))", after.At.UTC(), after.At.UTC(), after.KeyID).
+		OrderExpr("at ASC, key_id ASC")
+	if filter.TenantID != "" {
+		q = q.Where("tenant_id = ?", filter.TenantID)
+	}
+	if filter.AppID != "" {
+		q = q.Where("app_id = ?", filter.AppID)
+	}
+	if filter.KeyID != nil {
+		q = q.Where("key_id = ?", filter.KeyID.String())
+	}
+	if len(filter.Types) > 0 {
+		args := make([]any, len(filter.Types))
+		for i, t := range filter.Types {
+			args[i] = string(t)
+		}
+		q = q.Where("type IN ("+strings.Repeat("?, ", len(args)-1)+"?)", args...)
+	}
+	if filter.Limit > 0 {
+		q = q.Limit(filter.Limit)
+	}
+
+	if err := q.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: list key events: %w", err)
+	}
+
+	result := make([]*keyevent.Event, 0, len(models))
+	for i := range models {
+		e, err := keyEventFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/sqlite: convert key event: %w", err)
+		}
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+func (s *keyEventStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	res, err := s.sdb.NewDelete((*keyEventModel)(nil)).
+		Where("at < ?", before.UTC()).
+		Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("keysmith/sqlite: purge key events: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	return affected, nil
+}
@@ -0,0 +1,67 @@
+//go:build integration
+
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/grove"
+	"github.com/xraph/grove/drivers/sqlitedriver"
+	_ "github.com/xraph/grove/drivers/sqlitedriver/sqlitemigrate"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store/sqlite"
+)
+
+func TestLabelSelector(t *testing.T) {
+	ctx := context.Background()
+	d := sqlitedriver.New()
+	require.NoError(t, d.Open(ctx, filepath.Join(t.TempDir(), "keysmith.db")))
+	db, err := grove.Open(d)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	sdb := sqlitedriver.Unwrap(db)
+
+	s := sqlite.New(db)
+	require.NoError(t, s.Migrate(ctx))
+	now := time.Now().UTC()
+	newKey := func(hash string, labels key.Labels) *key.Key {
+		k := &key.Key{
+			ID: id.NewKeyID(), TenantID: "t1", KeyHash: hash, Prefix: "sk", Environment: key.EnvTest,
+			State: key.StateActive, Labels: labels, CreatedAt: now, UpdatedAt: now,
+		}
+		require.NoError(t, s.Keys().Create(ctx, k))
+		return k
+	}
+	a := newKey("h1", key.Labels{"team": "payments", "env": "prod"})
+	b := newKey("h2", key.Labels{"team": "payments", "env": "staging"})
+	newKey("h3", nil)
+
+	sel, err := key.ParseSelector("team=payments,env notin (staging)")
+	require.NoError(t, err)
+	n, err := s.Keys().Count(ctx, &key.ListFilter{TenantID: "t1", LabelSelector: sel})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+
+	sel, err = key.ParseSelector("!team")
+	require.NoError(t, err)
+	n, err = s.Keys().Count(ctx, &key.ListFilter{TenantID: "t1", LabelSelector: sel})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+
+	var rows int
+	require.NoError(t, sdb.QueryRow(ctx, `SELECT COUNT(*) FROM keysmith_key_labels WHERE key_id = ?`, a.ID.String()).Scan(&rows))
+	assert.Equal(t, 2, rows)
+
+	// Deleting a key removes its index rows with it.
+	require.NoError(t, s.Keys().Delete(ctx, b.ID))
+	require.NoError(t, sdb.QueryRow(ctx, `SELECT COUNT(*) FROM keysmith_key_labels WHERE key_id = ?`, b.ID.String()).Scan(&rows))
+	assert.Zero(t, rows)
+}
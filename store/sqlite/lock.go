@@ -0,0 +1,90 @@
+package sqlite
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/xraph/grove/drivers/sqlitedriver"
+
+	"github.com/xraph/keysmith/store"
+)
+
+var _ store.Locker = (*Store)(nil)
+
+// AcquireLock takes the lock called name for ttl, as a row in keysmith_locks
+// holding a random token and the expiry in Unix milliseconds. An expired row
+// is taken over in the same statement. Expiries are read from each
+// process's clock, which is shared by every process using one database file.
+// A write that finds the database locked means another holder is acquiring
+// at the same moment, so it returns store.ErrLockHeld too.
+func (s *Store) AcquireLock(ctx context.Context, name string, ttl time.Duration) (store.Lock, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	holder := rand.Text()
+	res, err := s.sdb.Exec(ctx, `
+		INSERT INTO keysmith_locks (name, holder, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET holder = excluded.holder, expires_at = excluded.expires_at
+		WHERE keysmith_locks.expires_at <= ?`,
+		name, holder, now.Add(ttl).UnixMilli(), now.UnixMilli())
+	if isBusy(err) {
+		return nil, store.ErrLockHeld
+	}
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: acquire lock %s: %w", name, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: acquire lock %s: %w", name, err)
+	}
+	if n == 0 {
+		return nil, store.ErrLockHeld
+	}
+	return &lock{sdb: s.sdb, name: name, holder: holder}, nil
+}
+
+type lock struct {
+	sdb    *sqlitedriver.SqliteDB
+	name   string
+	holder string
+}
+
+func (l *lock) Name() string { return l.name }
+
+func (l *lock) Renew(ctx context.Context, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	res, err := l.sdb.Exec(ctx, `
+		UPDATE keysmith_locks SET expires_at = ?
+		WHERE name = ? AND holder = ? AND expires_at > ?`,
+		now.Add(ttl).UnixMilli(), l.name, l.holder, now.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: renew lock %s: %w", l.name, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: renew lock %s: %w", l.name, err)
+	}
+	if n == 0 {
+		return store.ErrLockLost
+	}
+	return nil
+}
+
+func (l *lock) Release(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, err := l.sdb.Exec(ctx, `DELETE FROM keysmith_locks WHERE name = ? AND holder = ?`, l.name, l.holder); err != nil {
+		return fmt.Errorf("keysmith/sqlite: release lock %s: %w", l.name, err)
+	}
+	return nil
+}
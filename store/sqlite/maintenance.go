@@ -0,0 +1,98 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xraph/keysmith/store"
+)
+
+var _ store.Maintainer = (*Store)(nil)
+
+// Maintain returns free pages to the file system with incremental_vacuum,
+// which only has an effect when the database uses auto_vacuum=INCREMENTAL,
+// and refreshes planner statistics with ANALYZE. With opts.Full it runs a
+// full VACUUM instead, which rewrites the database file and blocks writers
+// while it runs. Sizes come from the dbstat virtual table.
+func (s *Store) Maintain(ctx context.Context, opts store.MaintenanceOptions) (*store.MaintenanceReport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	report := &store.MaintenanceReport{Backend: "sqlite", Actions: []string{}, StartedAt: time.Now()}
+
+	if !opts.StatsOnly {
+		stmts := []string{"PRAGMA incremental_vacuum", "ANALYZE"}
+		if opts.Full {
+			stmts = []string{"VACUUM", "ANALYZE"}
+		}
+		for _, stmt := range stmts {
+			if _, err := s.sdb.Exec(ctx, stmt); err != nil {
+				return nil, fmt.Errorf("keysmith/sqlite: %s: %w", stmt, err)
+			}
+			report.Actions = append(report.Actions, stmt)
+		}
+	}
+
+	tables, err := s.tableStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.Tables = tables
+	report.Duration = time.Since(report.StartedAt)
+	return report, nil
+}
+
+// tableStats counts the rows of every keysmith table and sums the pages of
+// the table and its indexes.
+func (s *Store) tableStats(ctx context.Context) ([]store.TableStats, error) {
+	names, err := s.tableNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := make([]store.TableStats, 0, len(names))
+	for _, name := range names {
+		t := store.TableStats{Name: name}
+		// Table names come from sqlite_master and match the filter of
+		// tableNames, so quoting them is safe.
+		if err := s.sdb.QueryRow(ctx, `SELECT COUNT(*) FROM "`+name+`"`).Scan(&t.Rows); err != nil {
+			return nil, fmt.Errorf("keysmith/sqlite: count %s: %w", name, err)
+		}
+		if err := s.sdb.QueryRow(ctx, `
+			SELECT COALESCE(SUM(pgsize), 0) FROM dbstat
+			WHERE name = ? OR name IN (SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = ?)`,
+			name, name).Scan(&t.SizeBytes); err != nil {
+			return nil, fmt.Errorf("keysmith/sqlite: size %s: %w", name, err)
+		}
+		tables = append(tables, t)
+	}
+	return tables, nil
+}
+
+// tableNames lists the keysmith tables. The rows are closed before it
+// returns, so the per-table queries that follow get the connection.
+func (s *Store) tableNames(ctx context.Context) ([]string, error) {
+	rows, err := s.sdb.Query(ctx, `
+		SELECT name FROM sqlite_master
+		WHERE type = 'table' AND name LIKE 'keysmith\_%' ESCAPE '\'
+		ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: list tables: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("keysmith/sqlite: list tables: %w", err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: list tables: %w", err)
+	}
+	return names, nil
+}
@@ -0,0 +1,58 @@
+//go:build integration
+
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/grove"
+	"github.com/xraph/grove/drivers/sqlitedriver"
+	_ "github.com/xraph/grove/drivers/sqlitedriver/sqlitemigrate"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/store/sqlite"
+)
+
+func TestMaintain(t *testing.T) {
+	ctx := context.Background()
+	d := sqlitedriver.New()
+	require.NoError(t, d.Open(ctx, filepath.Join(t.TempDir(), "keysmith.db")))
+	db, err := grove.Open(d)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	s := sqlite.New(db)
+	require.NoError(t, s.Migrate(ctx))
+	now := time.Now()
+	require.NoError(t, s.Keys().Create(ctx, &key.Key{
+		ID: id.NewKeyID(), KeyHash: "h1", Prefix: "sk", Environment: key.EnvTest,
+		State: key.StateActive, CreatedAt: now, UpdatedAt: now,
+	}))
+
+	for _, opts := range []store.MaintenanceOptions{{}, {Full: true}, {StatsOnly: true}} {
+		report, err := s.Maintain(ctx, opts)
+		require.NoError(t, err)
+		assert.Equal(t, "sqlite", report.Backend)
+		if opts.StatsOnly {
+			assert.Empty(t, report.Actions)
+		} else {
+			assert.Contains(t, report.Actions, "ANALYZE")
+		}
+
+		rows := make(map[string]store.TableStats)
+		for _, tbl := range report.Tables {
+			rows[tbl.Name] = tbl
+		}
+		require.Contains(t, rows, "keysmith_usage")
+		assert.Equal(t, int64(1), rows["keysmith_keys"].Rows)
+		assert.Positive(t, rows["keysmith_keys"].SizeBytes)
+	}
+}
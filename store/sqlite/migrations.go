@@ -201,5 +201,784 @@ CREATE INDEX IF NOT EXISTS idx_keysmith_rotations_key ON keysmith_rotations (key
 				return err
 			},
 		},
+		&migrate.Migration{
+			Name:    "create_tenant_settings",
+			Version: "20240101000006",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS keysmith_tenant_settings (
+    tenant_id       TEXT PRIMARY KEY,
+    app_id          TEXT NOT NULL,
+    default_scopes  TEXT NOT NULL DEFAULT '[]',
+    created_at      TEXT NOT NULL DEFAULT (datetime('now')),
+    updated_at      TEXT NOT NULL DEFAULT (datetime('now'))
+);
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `DROP TABLE IF EXISTS keysmith_tenant_settings`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_key_endpoint_seen",
+			Version: "20240101000007",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS keysmith_key_endpoint_seen (
+    key_id       TEXT NOT NULL REFERENCES keysmith_keys(id) ON DELETE CASCADE,
+    endpoint     TEXT NOT NULL,
+    method       TEXT NOT NULL,
+    last_seen_at TEXT NOT NULL,
+    count        INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (key_id, endpoint, method)
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_endpoint_seen_last ON keysmith_key_endpoint_seen (key_id, last_seen_at DESC);
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `DROP TABLE IF EXISTS keysmith_key_endpoint_seen`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_key_revocations",
+			Version: "20240101000008",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS keysmith_key_revocations (
+    at          TEXT NOT NULL,
+    key_id      TEXT NOT NULL,
+    tenant_id   TEXT NOT NULL,
+    hash_prefix TEXT NOT NULL,
+    state       TEXT NOT NULL,
+    revoked     INTEGER NOT NULL,
+    PRIMARY KEY (at, key_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_revocations_tenant ON keysmith_key_revocations (tenant_id, at, key_id);
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `DROP TABLE IF EXISTS keysmith_key_revocations`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_debug_captures",
+			Version: "20240101000009",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_keys ADD COLUMN debug_sample_rate REAL NOT NULL DEFAULT 0;
+ALTER TABLE keysmith_keys ADD COLUMN debug_until TEXT;
+
+CREATE TABLE IF NOT EXISTS keysmith_debug_captures (
+    id             TEXT PRIMARY KEY,
+    key_id         TEXT NOT NULL,
+    tenant_id      TEXT NOT NULL,
+    method         TEXT NOT NULL,
+    path           TEXT NOT NULL,
+    headers        TEXT,
+    body           TEXT,
+    body_truncated INTEGER NOT NULL DEFAULT 0,
+    captured_at    TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_debug_captures_key ON keysmith_debug_captures (key_id, captured_at DESC);
+CREATE INDEX IF NOT EXISTS idx_keysmith_debug_captures_captured ON keysmith_debug_captures (captured_at);
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+DROP TABLE IF EXISTS keysmith_debug_captures;
+ALTER TABLE keysmith_keys DROP COLUMN debug_until;
+ALTER TABLE keysmith_keys DROP COLUMN debug_sample_rate;
+`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_rotation_hints",
+			Version: "20240101000010",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_rotations ADD COLUMN old_hint TEXT;
+ALTER TABLE keysmith_rotations ADD COLUMN new_hint TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_keys_prefix_hint ON keysmith_keys (prefix, hint);
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+DROP INDEX IF EXISTS idx_keysmith_keys_prefix_hint;
+ALTER TABLE keysmith_rotations DROP COLUMN new_hint;
+ALTER TABLE keysmith_rotations DROP COLUMN old_hint;
+`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_app_scoping",
+			Version: "20240101000011",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_usage ADD COLUMN app_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE keysmith_rotations ADD COLUMN app_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_keys_app ON keysmith_keys (tenant_id, app_id);
+CREATE INDEX IF NOT EXISTS idx_keysmith_policies_app ON keysmith_policies (tenant_id, app_id);
+CREATE INDEX IF NOT EXISTS idx_keysmith_scopes_app ON keysmith_scopes (tenant_id, app_id);
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+DROP INDEX IF EXISTS idx_keysmith_scopes_app;
+DROP INDEX IF EXISTS idx_keysmith_policies_app;
+DROP INDEX IF EXISTS idx_keysmith_keys_app;
+ALTER TABLE keysmith_rotations DROP COLUMN app_id;
+ALTER TABLE keysmith_usage DROP COLUMN app_id;
+`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_tenant_metadata_schema",
+			Version: "20240101000012",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_tenant_settings ADD COLUMN metadata_schema TEXT`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_tenant_settings DROP COLUMN metadata_schema`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_key_allowed_origins",
+			Version: "20240101000013",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_keys ADD COLUMN allowed_origins TEXT NOT NULL DEFAULT '[]'`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_keys DROP COLUMN allowed_origins`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_tenant_rate_limit",
+			Version: "20240101000014",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				if _, err := exec.Exec(ctx, `ALTER TABLE keysmith_tenant_settings ADD COLUMN rate_limit INTEGER NOT NULL DEFAULT 0`); err != nil {
+					return err
+				}
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_tenant_settings ADD COLUMN rate_limit_window INTEGER NOT NULL DEFAULT 0`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				if _, err := exec.Exec(ctx, `ALTER TABLE keysmith_tenant_settings DROP COLUMN rate_limit`); err != nil {
+					return err
+				}
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_tenant_settings DROP COLUMN rate_limit_window`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_key_intended_consumer",
+			Version: "20240101000015",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				if _, err := exec.Exec(ctx, `ALTER TABLE keysmith_keys ADD COLUMN intended_consumer TEXT NOT NULL DEFAULT ''`); err != nil {
+					return err
+				}
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_keys ADD COLUMN enforce_consumer INTEGER NOT NULL DEFAULT 0`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				if _, err := exec.Exec(ctx, `ALTER TABLE keysmith_keys DROP COLUMN intended_consumer`); err != nil {
+					return err
+				}
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_keys DROP COLUMN enforce_consumer`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_key_flags",
+			Version: "20240101000016",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_keys ADD COLUMN flags TEXT NOT NULL DEFAULT '[]'`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_keys DROP COLUMN flags`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_policy_base",
+			Version: "20240101000017",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_policies ADD COLUMN base_policy_id TEXT`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_policies DROP COLUMN base_policy_id`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_key_updated_index",
+			Version: "20240101000018",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_keysmith_keys_updated ON keysmith_keys (tenant_id, updated_at)`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `DROP INDEX IF EXISTS idx_keysmith_keys_updated`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_key_accepted_terms",
+			Version: "20240101000019",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				if _, err := exec.Exec(ctx, `ALTER TABLE keysmith_keys ADD COLUMN accepted_terms_version TEXT NOT NULL DEFAULT ''`); err != nil {
+					return err
+				}
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_keys ADD COLUMN accepted_terms_at TEXT`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				if _, err := exec.Exec(ctx, `ALTER TABLE keysmith_keys DROP COLUMN accepted_terms_version`); err != nil {
+					return err
+				}
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_keys DROP COLUMN accepted_terms_at`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_contacts",
+			Version: "20240101000020",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				if _, err := exec.Exec(ctx, `ALTER TABLE keysmith_keys ADD COLUMN contacts TEXT NOT NULL DEFAULT '[]'`); err != nil {
+					return err
+				}
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_tenant_settings ADD COLUMN default_contacts TEXT NOT NULL DEFAULT '[]'`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				if _, err := exec.Exec(ctx, `ALTER TABLE keysmith_keys DROP COLUMN contacts`); err != nil {
+					return err
+				}
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_tenant_settings DROP COLUMN default_contacts`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_tenant_ip_handling",
+			Version: "20240101000021",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_tenant_settings ADD COLUMN ip_handling TEXT NOT NULL DEFAULT ''`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_tenant_settings DROP COLUMN ip_handling`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_quota_timezone",
+			Version: "20240101000022",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				if _, err := exec.Exec(ctx, `ALTER TABLE keysmith_policies ADD COLUMN quota_timezone TEXT NOT NULL DEFAULT ''`); err != nil {
+					return err
+				}
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_tenant_settings ADD COLUMN quota_timezone TEXT NOT NULL DEFAULT ''`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				if _, err := exec.Exec(ctx, `ALTER TABLE keysmith_policies DROP COLUMN quota_timezone`); err != nil {
+					return err
+				}
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_tenant_settings DROP COLUMN quota_timezone`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_keysmith_key_hashes",
+			Version: "20240101000023",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS keysmith_key_hashes (
+    hash       TEXT PRIMARY KEY,
+    key_id     TEXT NOT NULL REFERENCES keysmith_keys(id) ON DELETE CASCADE,
+    scheme     TEXT NOT NULL DEFAULT '',
+    active     INTEGER NOT NULL DEFAULT 1,
+    created_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_hashes_key ON keysmith_key_hashes (key_id, created_at);
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `DROP TABLE IF EXISTS keysmith_key_hashes`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "backfill_keysmith_key_hashes",
+			Version: "20240101000024",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				// Every existing key gets an active version for its current
+				// hash, so lookups through the table find it.
+				_, err := exec.Exec(ctx, `
+INSERT OR IGNORE INTO keysmith_key_hashes (hash, key_id, scheme, active, created_at)
+SELECT key_hash, id,
+    CASE WHEN instr(key_hash, ':') > 0 THEN lower(substr(key_hash, 1, instr(key_hash, ':') - 1)) ELSE '' END,
+    1, created_at
+FROM keysmith_keys
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `DELETE FROM keysmith_key_hashes`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_rotation_tenant_index",
+			Version: "20240101000025",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_keysmith_rotations_tenant ON keysmith_rotations (tenant_id, created_at DESC);`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `DROP INDEX IF EXISTS idx_keysmith_rotations_tenant;`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_transport_requirements",
+			Version: "20240101000026",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				if _, err := exec.Exec(ctx, `ALTER TABLE keysmith_keys ADD COLUMN cert_fingerprint TEXT NOT NULL DEFAULT ''`); err != nil {
+					return err
+				}
+				if _, err := exec.Exec(ctx, `ALTER TABLE keysmith_policies ADD COLUMN min_tls_version TEXT NOT NULL DEFAULT ''`); err != nil {
+					return err
+				}
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_policies ADD COLUMN require_mtls INTEGER NOT NULL DEFAULT 0`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				if _, err := exec.Exec(ctx, `ALTER TABLE keysmith_keys DROP COLUMN cert_fingerprint`); err != nil {
+					return err
+				}
+				if _, err := exec.Exec(ctx, `ALTER TABLE keysmith_policies DROP COLUMN min_tls_version`); err != nil {
+					return err
+				}
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_policies DROP COLUMN require_mtls`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_keysmith_key_labels",
+			Version: "20240101000027",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				if _, err := exec.Exec(ctx, `ALTER TABLE keysmith_keys ADD COLUMN labels TEXT NOT NULL DEFAULT '{}'`); err != nil {
+					return err
+				}
+				_, err := exec.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS keysmith_key_labels (
+    key_id TEXT NOT NULL REFERENCES keysmith_keys(id) ON DELETE CASCADE,
+    name   TEXT NOT NULL,
+    value  TEXT NOT NULL,
+    PRIMARY KEY (key_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_labels_name_value ON keysmith_key_labels (name, value, key_id);
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				if _, err := exec.Exec(ctx, `DROP TABLE IF EXISTS keysmith_key_labels`); err != nil {
+					return err
+				}
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_keys DROP COLUMN labels`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_keysmith_tenant_revisions",
+			Version: "20240101000028",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS keysmith_tenant_revisions (
+    tenant_id  TEXT PRIMARY KEY,
+    revision   INTEGER NOT NULL DEFAULT 0,
+    updated_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `DROP TABLE IF EXISTS keysmith_tenant_revisions`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_keysmith_locks",
+			Version: "20240101000029",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS keysmith_locks (
+    name       TEXT PRIMARY KEY,
+    holder     TEXT NOT NULL,
+    expires_at INTEGER NOT NULL
+)`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `DROP TABLE IF EXISTS keysmith_locks`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_scope_rate_limit",
+			Version: "20240101000030",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				if _, err := exec.Exec(ctx, `ALTER TABLE keysmith_scopes ADD COLUMN rate_limit INTEGER NOT NULL DEFAULT 0`); err != nil {
+					return err
+				}
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_scopes ADD COLUMN rate_limit_window INTEGER NOT NULL DEFAULT 0`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				if _, err := exec.Exec(ctx, `ALTER TABLE keysmith_scopes DROP COLUMN rate_limit`); err != nil {
+					return err
+				}
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_scopes DROP COLUMN rate_limit_window`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_keysmith_key_groups",
+			Version: "20240101000031",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS keysmith_key_groups (
+    id          TEXT PRIMARY KEY,
+    tenant_id   TEXT NOT NULL,
+    app_id      TEXT NOT NULL,
+    name        TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at  TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_groups_tenant ON keysmith_key_groups (tenant_id, app_id, name);
+
+CREATE TABLE IF NOT EXISTS keysmith_key_group_members (
+    group_id TEXT NOT NULL REFERENCES keysmith_key_groups(id) ON DELETE CASCADE,
+    key_id   TEXT NOT NULL REFERENCES keysmith_keys(id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, key_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_group_members_key ON keysmith_key_group_members (key_id);
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+DROP TABLE IF EXISTS keysmith_key_group_members;
+DROP TABLE IF EXISTS keysmith_key_groups;
+`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_key_external_ref",
+			Version: "20240101000032",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				if _, err := exec.Exec(ctx, `ALTER TABLE keysmith_keys ADD COLUMN external_ref TEXT NOT NULL DEFAULT ''`); err != nil {
+					return err
+				}
+				_, err := exec.Exec(ctx, `
+CREATE UNIQUE INDEX IF NOT EXISTS idx_keysmith_keys_external_ref
+ON keysmith_keys (tenant_id, external_ref) WHERE external_ref <> ''`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				if _, err := exec.Exec(ctx, `DROP INDEX IF EXISTS idx_keysmith_keys_external_ref`); err != nil {
+					return err
+				}
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_keys DROP COLUMN external_ref`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_key_events",
+			Version: "20240101000033",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS keysmith_key_events (
+    at          TEXT NOT NULL,
+    key_id      TEXT NOT NULL,
+    type        TEXT NOT NULL,
+    tenant_id   TEXT NOT NULL,
+    app_id      TEXT NOT NULL DEFAULT '',
+    state       TEXT NOT NULL,
+    reason      TEXT NOT NULL DEFAULT '',
+    reason_code TEXT NOT NULL DEFAULT '',
+    actor_id    TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (at, key_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_events_tenant ON keysmith_key_events (tenant_id, at, key_id);
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `DROP TABLE IF EXISTS keysmith_key_events`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_unique_key_names",
+			Version: "20240101000034",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				if _, err := exec.Exec(ctx, `ALTER TABLE keysmith_tenant_settings ADD COLUMN unique_key_names INTEGER NOT NULL DEFAULT 0`); err != nil {
+					return err
+				}
+				_, err := exec.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_keysmith_keys_tenant_name ON keysmith_keys (tenant_id, lower(name))`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				if _, err := exec.Exec(ctx, `DROP INDEX IF EXISTS idx_keysmith_keys_tenant_name`); err != nil {
+					return err
+				}
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_tenant_settings DROP COLUMN unique_key_names`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_usage_environment",
+			Version: "20240101000035",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				if _, err := exec.Exec(ctx, `ALTER TABLE keysmith_usage ADD COLUMN environment TEXT NOT NULL DEFAULT ''`); err != nil {
+					return err
+				}
+				if _, err := exec.Exec(ctx, `
+UPDATE keysmith_usage SET environment = COALESCE(
+    (SELECT environment FROM keysmith_keys WHERE keysmith_keys.id = keysmith_usage.key_id), '')
+WHERE environment = ''`); err != nil {
+					return err
+				}
+				_, err := exec.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_keysmith_usage_tenant_env ON keysmith_usage (tenant_id, environment, created_at)`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				if _, err := exec.Exec(ctx, `DROP INDEX IF EXISTS idx_keysmith_usage_tenant_env`); err != nil {
+					return err
+				}
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_usage DROP COLUMN environment`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_keysmith_key_transfers",
+			Version: "20240101000036",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS keysmith_key_transfers (
+    id               TEXT PRIMARY KEY,
+    key_id           TEXT NOT NULL REFERENCES keysmith_keys(id) ON DELETE CASCADE,
+    app_id           TEXT NOT NULL,
+    source_tenant_id TEXT NOT NULL,
+    target_tenant_id TEXT NOT NULL,
+    state            TEXT NOT NULL,
+    retag_usage      INTEGER NOT NULL DEFAULT 0,
+    initiated_by     TEXT NOT NULL DEFAULT '',
+    resolved_by      TEXT NOT NULL DEFAULT '',
+    unmapped_scopes  TEXT NOT NULL DEFAULT '[]',
+    unmapped_policy  TEXT NOT NULL DEFAULT '',
+    expires_at       TEXT NOT NULL,
+    created_at       TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    resolved_at      TEXT
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_keysmith_key_transfers_pending
+    ON keysmith_key_transfers (key_id) WHERE state = 'pending';
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_transfers_source ON keysmith_key_transfers (source_tenant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_transfers_target ON keysmith_key_transfers (target_tenant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_keysmith_key_transfers_expiry ON keysmith_key_transfers (expires_at) WHERE state = 'pending';
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `DROP TABLE IF EXISTS keysmith_key_transfers`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_policy_inline",
+			Version: "20240101000037",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_policies ADD COLUMN inline INTEGER NOT NULL DEFAULT 0`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_policies DROP COLUMN inline`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_keysmith_hash_tombstones",
+			Version: "20240101000038",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS keysmith_hash_tombstones (
+    hash       TEXT PRIMARY KEY,
+    key_id     TEXT NOT NULL,
+    tenant_id  TEXT NOT NULL,
+    app_id     TEXT NOT NULL,
+    reason     TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_hash_tombstones_tenant ON keysmith_hash_tombstones (tenant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_keysmith_hash_tombstones_created ON keysmith_hash_tombstones (created_at);
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `DROP TABLE IF EXISTS keysmith_hash_tombstones`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_keysmith_keys_fts",
+			Version: "20240101000039",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				// Keys stored before search existed are indexed from their raw
+				// columns until their next update writes the exact text.
+				_, err := exec.Exec(ctx, `
+ALTER TABLE keysmith_keys ADD COLUMN search_text TEXT NOT NULL DEFAULT '';
+UPDATE keysmith_keys SET search_text = lower(name || ' ' || coalesce(description, '') || ' ' || metadata);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS keysmith_keys_fts USING fts5(
+    search_text,
+    content = 'keysmith_keys',
+    content_rowid = 'rowid',
+    tokenize = 'unicode61 remove_diacritics 0'
+);
+
+CREATE TRIGGER IF NOT EXISTS keysmith_keys_fts_insert AFTER INSERT ON keysmith_keys BEGIN
+    INSERT INTO keysmith_keys_fts (rowid, search_text) VALUES (new.rowid, new.search_text);
+END;
+CREATE TRIGGER IF NOT EXISTS keysmith_keys_fts_delete AFTER DELETE ON keysmith_keys BEGIN
+    INSERT INTO keysmith_keys_fts (keysmith_keys_fts, rowid, search_text) VALUES ('delete', old.rowid, old.search_text);
+END;
+CREATE TRIGGER IF NOT EXISTS keysmith_keys_fts_update AFTER UPDATE OF search_text ON keysmith_keys BEGIN
+    INSERT INTO keysmith_keys_fts (keysmith_keys_fts, rowid, search_text) VALUES ('delete', old.rowid, old.search_text);
+    INSERT INTO keysmith_keys_fts (rowid, search_text) VALUES (new.rowid, new.search_text);
+END;
+
+INSERT INTO keysmith_keys_fts (keysmith_keys_fts) VALUES ('rebuild');
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+DROP TRIGGER IF EXISTS keysmith_keys_fts_update;
+DROP TRIGGER IF EXISTS keysmith_keys_fts_delete;
+DROP TRIGGER IF EXISTS keysmith_keys_fts_insert;
+DROP TABLE IF EXISTS keysmith_keys_fts;
+ALTER TABLE keysmith_keys DROP COLUMN search_text;
+`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "add_tenant_lockdown",
+			Version: "20240101000040",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_tenant_settings ADD COLUMN lockdown TEXT`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `ALTER TABLE keysmith_tenant_settings DROP COLUMN lockdown`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_keysmith_quota_pools",
+			Version: "20240101000041",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS keysmith_quota_pools (
+    id             TEXT PRIMARY KEY,
+    tenant_id      TEXT NOT NULL,
+    app_id         TEXT NOT NULL,
+    name           TEXT NOT NULL,
+    description    TEXT,
+    daily_limit    INTEGER NOT NULL DEFAULT 0,
+    monthly_limit  INTEGER NOT NULL DEFAULT 0,
+    quota_timezone TEXT NOT NULL DEFAULT '',
+    created_at     TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at     TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_quota_pools_tenant ON keysmith_quota_pools (tenant_id, app_id, name);
+
+CREATE TABLE IF NOT EXISTS keysmith_quota_usage (
+    pool_id      TEXT NOT NULL DEFAULT '',
+    key_id       TEXT NOT NULL,
+    period       TEXT NOT NULL,
+    window_start TEXT NOT NULL,
+    count        INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (pool_id, key_id, period, window_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_keysmith_quota_usage_window ON keysmith_quota_usage (window_start);
+
+ALTER TABLE keysmith_keys ADD COLUMN pool_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_keysmith_keys_pool ON keysmith_keys (pool_id) WHERE pool_id IS NOT NULL;
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+DROP INDEX IF EXISTS idx_keysmith_keys_pool;
+ALTER TABLE keysmith_keys DROP COLUMN pool_id;
+DROP TABLE IF EXISTS keysmith_quota_usage;
+DROP TABLE IF EXISTS keysmith_quota_pools;
+`)
+				return err
+			},
+		},
+		&migrate.Migration{
+			Name:    "create_keysmith_meta",
+			Version: "20240101000042",
+			Up: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS keysmith_meta (
+    name  TEXT PRIMARY KEY,
+    value TEXT NOT NULL
+);
+`)
+				return err
+			},
+			Down: func(ctx context.Context, exec migrate.Executor) error {
+				_, err := exec.Exec(ctx, `DROP TABLE IF EXISTS keysmith_meta`)
+				return err
+			},
+		},
 	)
 }
@@ -1,16 +1,28 @@
 package sqlite
 
 import (
+	"database/sql/driver"
 	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/xraph/grove"
 
+	"github.com/xraph/keysmith/capture"
+	"github.com/xraph/keysmith/group"
 	"github.com/xraph/keysmith/id"
 	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keyevent"
+	"github.com/xraph/keysmith/metaschema"
 	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/quotapool"
+	"github.com/xraph/keysmith/revocation"
 	"github.com/xraph/keysmith/rotation"
 	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/tenant"
+	"github.com/xraph/keysmith/tombstone"
+	"github.com/xraph/keysmith/transfer"
 	"github.com/xraph/keysmith/usage"
 )
 
@@ -20,29 +32,63 @@ import (
 
 type keyModel struct {
 	grove.BaseModel `grove:"table:keysmith_keys"`
-	ID              string     `grove:"id,pk"`
-	TenantID        string     `grove:"tenant_id,notnull"`
-	AppID           string     `grove:"app_id,notnull"`
-	Name            string     `grove:"name,notnull"`
-	Description     string     `grove:"description"`
-	Prefix          string     `grove:"prefix,notnull"`
-	Hint            string     `grove:"hint,notnull"`
-	KeyHash         string     `grove:"key_hash,notnull"`
-	Environment     string     `grove:"environment,notnull"`
-	State           string     `grove:"state,notnull"`
-	PolicyID        *string    `grove:"policy_id"`
-	Metadata        string     `grove:"metadata"` // JSON TEXT
-	CreatedBy       string     `grove:"created_by"`
-	ExpiresAt       *time.Time `grove:"expires_at"`
-	LastUsedAt      *time.Time `grove:"last_used_at"`
-	RotatedAt       *time.Time `grove:"rotated_at"`
-	RevokedAt       *time.Time `grove:"revoked_at"`
-	CreatedAt       time.Time  `grove:"created_at,notnull"`
-	UpdatedAt       time.Time  `grove:"updated_at,notnull"`
+	ID              string      `grove:"id,pk"`
+	TenantID        string      `grove:"tenant_id,notnull"`
+	AppID           string      `grove:"app_id,notnull"`
+	Name            string      `grove:"name,notnull"`
+	Description     string      `grove:"description"`
+	Prefix          string      `grove:"prefix,notnull"`
+	Hint            string      `grove:"hint,notnull"`
+	KeyHash         string      `grove:"key_hash,notnull"`
+	Environment     string      `grove:"environment,notnull"`
+	State           string      `grove:"state,notnull"`
+	PolicyID        *string     `grove:"policy_id"`
+	PoolID          *string     `grove:"pool_id"`
+	Metadata        string      `grove:"metadata"` // JSON TEXT
+	Labels          string      `grove:"labels"`   // JSON TEXT, read copy of keysmith_key_labels
+	CreatedBy       string      `grove:"created_by"`
+	ExternalRef     string      `grove:"external_ref,notnull"`
+	AllowedOrigins  string      `grove:"allowed_origins"` // JSON TEXT
+	Consumer        string      `grove:"intended_consumer,notnull"`
+	EnforceConsumer bool        `grove:"enforce_consumer,notnull"`
+	CertFingerprint string      `grove:"cert_fingerprint,notnull"`
+	TermsVersion    string      `grove:"accepted_terms_version,notnull"`
+	TermsAt         *sqliteTime `grove:"accepted_terms_at"`
+	Flags           string      `grove:"flags"`    // JSON TEXT
+	Contacts        string      `grove:"contacts"` // JSON TEXT
+	DebugSampleRate float64     `grove:"debug_sample_rate,notnull"`
+	DebugUntil      *sqliteTime `grove:"debug_until"`
+	SearchText      string      `grove:"search_text,notnull"` // key.SearchText, indexed by keysmith_keys_fts
+	ExpiresAt       *sqliteTime `grove:"expires_at"`
+	LastUsedAt      *sqliteTime `grove:"last_used_at"`
+	RotatedAt       *sqliteTime `grove:"rotated_at"`
+	RevokedAt       *sqliteTime `grove:"revoked_at"`
+	CreatedAt       sqliteTime  `grove:"created_at,notnull"`
+	UpdatedAt       sqliteTime  `grove:"updated_at,notnull"`
 }
 
 func keyToModel(k *key.Key) *keyModel {
 	metadata, _ := json.Marshal(k.Metadata)
+	origins := k.AllowedOrigins
+	if origins == nil {
+		origins = []string{}
+	}
+	allowedOrigins, _ := json.Marshal(origins)
+	flags := k.Flags
+	if flags == nil {
+		flags = key.Flags{}
+	}
+	flagsJSON, _ := json.Marshal(flags)
+	contacts := k.Contacts
+	if contacts == nil {
+		contacts = key.Contacts{}
+	}
+	contactsJSON, _ := json.Marshal(contacts)
+	labels := k.Labels
+	if labels == nil {
+		labels = key.Labels{}
+	}
+	labelsJSON, _ := json.Marshal(labels)
 	m := &keyModel{
 		ID:          k.ID.String(),
 		TenantID:    k.TenantID,
@@ -55,21 +101,101 @@ func keyToModel(k *key.Key) *keyModel {
 		Environment: string(k.Environment),
 		State:       string(k.State),
 		Metadata:    string(metadata),
+		Labels:      string(labelsJSON),
 		CreatedBy:   k.CreatedBy,
-		ExpiresAt:   k.ExpiresAt,
-		LastUsedAt:  k.LastUsedAt,
-		RotatedAt:   k.RotatedAt,
-		RevokedAt:   k.RevokedAt,
-		CreatedAt:   k.CreatedAt,
-		UpdatedAt:   k.UpdatedAt,
+		ExternalRef: k.ExternalRef,
+		ExpiresAt:   utcTime(k.ExpiresAt),
+		LastUsedAt:  (*sqliteTime)(k.LastUsedAt),
+		RotatedAt:   (*sqliteTime)(k.RotatedAt),
+		RevokedAt:   (*sqliteTime)(k.RevokedAt),
+		CreatedAt:   sqliteTime(k.CreatedAt),
+		UpdatedAt:   sqliteTime(k.UpdatedAt),
+
+		AllowedOrigins:  string(allowedOrigins),
+		Consumer:        k.IntendedConsumer,
+		EnforceConsumer: k.EnforceConsumer,
+		CertFingerprint: k.CertFingerprint,
+		TermsVersion:    k.AcceptedTermsVersion,
+		TermsAt:         utcTime(k.AcceptedTermsAt),
+		Flags:           string(flagsJSON),
+		Contacts:        string(contactsJSON),
+		DebugSampleRate: k.DebugSampleRate,
+		DebugUntil:      utcTime(k.DebugUntil),
+		SearchText:      key.SearchText(k),
 	}
 	if k.PolicyID != nil {
 		s := k.PolicyID.String()
 		m.PolicyID = &s
 	}
+	if k.PoolID != nil {
+		s := k.PoolID.String()
+		m.PoolID = &s
+	}
 	return m
 }
 
+// utcTime normalizes t to UTC so expiry comparisons do not depend on the
+// writer's local zone.
+func utcTime(t *time.Time) *sqliteTime {
+	if t == nil {
+		return nil
+	}
+	u := sqliteTime(t.UTC())
+	return &u
+}
+
+// sqliteTime is a timestamp column. The migrations declare timestamps
+// TEXT, which the driver reads back as strings, so sqliteTime parses them;
+// it writes them as time.Time, which the driver stores as Time.String.
+type sqliteTime time.Time
+
+// sqliteTimeFormats are the layouts timestamps are stored in: the
+// driver's, RFC 3339, and SQLite's datetime('now') that column defaults
+// use.
+var sqliteTimeFormats = []string{
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// Scan implements sql.Scanner.
+func (t *sqliteTime) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*t = sqliteTime{}
+		return nil
+	case time.Time:
+		*t = sqliteTime(v.UTC())
+		return nil
+	case []byte:
+		return t.parse(string(v))
+	case string:
+		return t.parse(v)
+	default:
+		return fmt.Errorf("keysmith/sqlite: cannot scan %T into a timestamp", src)
+	}
+}
+
+func (t *sqliteTime) parse(s string) error {
+	// Time.String appends the monotonic clock reading of a time.Now.
+	if i := strings.Index(s, " m="); i >= 0 {
+		s = s[:i]
+	}
+	for _, layout := range sqliteTimeFormats {
+		if v, err := time.Parse(layout, s); err == nil {
+			*t = sqliteTime(v.UTC())
+			return nil
+		}
+	}
+	return fmt.Errorf("keysmith/sqlite: parse timestamp %q", s)
+}
+
+// Value implements driver.Valuer.
+func (t sqliteTime) Value() (driver.Value, error) {
+	return time.Time(t), nil
+}
+
 func keyFromModel(m *keyModel) (*key.Key, error) {
 	kid, err := id.ParseKeyID(m.ID)
 	if err != nil {
@@ -80,6 +206,25 @@ func keyFromModel(m *keyModel) (*key.Key, error) {
 	if m.Metadata != "" {
 		_ = json.Unmarshal([]byte(m.Metadata), &metadata)
 	}
+	var allowedOrigins []string
+	if m.AllowedOrigins != "" {
+		_ = json.Unmarshal([]byte(m.AllowedOrigins), &allowedOrigins)
+	}
+	if len(allowedOrigins) == 0 {
+		allowedOrigins = nil
+	}
+	var flags key.Flags
+	if m.Flags != "" {
+		_ = json.Unmarshal([]byte(m.Flags), &flags)
+	}
+	var contacts key.Contacts
+	if m.Contacts != "" {
+		_ = json.Unmarshal([]byte(m.Contacts), &contacts)
+	}
+	var labels key.Labels
+	if m.Labels != "" {
+		_ = json.Unmarshal([]byte(m.Labels), &labels)
+	}
 
 	k := &key.Key{
 		ID:          kid,
@@ -93,13 +238,27 @@ func keyFromModel(m *keyModel) (*key.Key, error) {
 		Environment: key.Environment(m.Environment),
 		State:       key.State(m.State),
 		Metadata:    metadata,
+		Labels:      labels.Clone(),
 		CreatedBy:   m.CreatedBy,
-		ExpiresAt:   m.ExpiresAt,
-		LastUsedAt:  m.LastUsedAt,
-		RotatedAt:   m.RotatedAt,
-		RevokedAt:   m.RevokedAt,
-		CreatedAt:   m.CreatedAt,
-		UpdatedAt:   m.UpdatedAt,
+		ExternalRef: m.ExternalRef,
+		ExpiresAt:   (*time.Time)(m.ExpiresAt),
+		LastUsedAt:  (*time.Time)(m.LastUsedAt),
+		RotatedAt:   (*time.Time)(m.RotatedAt),
+		RevokedAt:   (*time.Time)(m.RevokedAt),
+		CreatedAt:   time.Time(m.CreatedAt),
+		UpdatedAt:   time.Time(m.UpdatedAt),
+
+		AllowedOrigins:  allowedOrigins,
+		DebugSampleRate: m.DebugSampleRate,
+		DebugUntil:      (*time.Time)(m.DebugUntil),
+
+		IntendedConsumer:     m.Consumer,
+		EnforceConsumer:      m.EnforceConsumer,
+		CertFingerprint:      m.CertFingerprint,
+		AcceptedTermsVersion: m.TermsVersion,
+		AcceptedTermsAt:      (*time.Time)(m.TermsAt),
+		Flags:                flags.Normalize(),
+		Contacts:             contacts.Normalize(),
 	}
 	if m.PolicyID != nil {
 		pid, err := id.ParsePolicyID(*m.PolicyID)
@@ -108,6 +267,13 @@ func keyFromModel(m *keyModel) (*key.Key, error) {
 		}
 		k.PolicyID = &pid
 	}
+	if m.PoolID != nil {
+		pid, err := id.ParseQuotaPoolID(*m.PoolID)
+		if err != nil {
+			return nil, err
+		}
+		k.PoolID = &pid
+	}
 	return k, nil
 }
 
@@ -117,27 +283,32 @@ func keyFromModel(m *keyModel) (*key.Key, error) {
 
 type policyModel struct {
 	grove.BaseModel `grove:"table:keysmith_policies"`
-	ID              string    `grove:"id,pk"`
-	TenantID        string    `grove:"tenant_id,notnull"`
-	AppID           string    `grove:"app_id,notnull"`
-	Name            string    `grove:"name,notnull"`
-	Description     string    `grove:"description"`
-	RateLimit       int       `grove:"rate_limit,notnull"`
-	RateLimitWindow int64     `grove:"rate_limit_window,notnull"`
-	BurstLimit      int       `grove:"burst_limit,notnull"`
-	AllowedScopes   string    `grove:"allowed_scopes"` // JSON TEXT
-	AllowedIPs      string    `grove:"allowed_ips"`
-	AllowedOrigins  string    `grove:"allowed_origins"`
-	AllowedMethods  string    `grove:"allowed_methods"`
-	AllowedPaths    string    `grove:"allowed_paths"`
-	MaxKeyLifetime  int64     `grove:"max_key_lifetime,notnull"`
-	RotationPeriod  int64     `grove:"rotation_period,notnull"`
-	GracePeriod     int64     `grove:"grace_period,notnull"`
-	DailyQuota      int64     `grove:"daily_quota,notnull"`
-	MonthlyQuota    int64     `grove:"monthly_quota,notnull"`
-	Metadata        string    `grove:"metadata"` // JSON TEXT
-	CreatedAt       time.Time `grove:"created_at,notnull"`
-	UpdatedAt       time.Time `grove:"updated_at,notnull"`
+	ID              string     `grove:"id,pk"`
+	TenantID        string     `grove:"tenant_id,notnull"`
+	AppID           string     `grove:"app_id,notnull"`
+	Name            string     `grove:"name,notnull"`
+	Description     string     `grove:"description"`
+	BasePolicyID    *string    `grove:"base_policy_id"`
+	RateLimit       int        `grove:"rate_limit,notnull"`
+	RateLimitWindow int64      `grove:"rate_limit_window,notnull"`
+	BurstLimit      int        `grove:"burst_limit,notnull"`
+	AllowedScopes   string     `grove:"allowed_scopes"` // JSON TEXT
+	AllowedIPs      string     `grove:"allowed_ips"`
+	AllowedOrigins  string     `grove:"allowed_origins"`
+	AllowedMethods  string     `grove:"allowed_methods"`
+	AllowedPaths    string     `grove:"allowed_paths"`
+	MaxKeyLifetime  int64      `grove:"max_key_lifetime,notnull"`
+	RotationPeriod  int64      `grove:"rotation_period,notnull"`
+	GracePeriod     int64      `grove:"grace_period,notnull"`
+	DailyQuota      int64      `grove:"daily_quota,notnull"`
+	MonthlyQuota    int64      `grove:"monthly_quota,notnull"`
+	QuotaTimezone   string     `grove:"quota_timezone,notnull"`
+	MinTLSVersion   string     `grove:"min_tls_version,notnull"`
+	RequireMTLS     bool       `grove:"require_mtls,notnull"`
+	Inline          bool       `grove:"inline,notnull"`
+	Metadata        string     `grove:"metadata"` // JSON TEXT
+	CreatedAt       sqliteTime `grove:"created_at,notnull"`
+	UpdatedAt       sqliteTime `grove:"updated_at,notnull"`
 }
 
 func policyToModel(pol *policy.Policy) *policyModel {
@@ -148,7 +319,7 @@ func policyToModel(pol *policy.Policy) *policyModel {
 	allowedPaths, _ := json.Marshal(pol.AllowedPaths)
 	metadata, _ := json.Marshal(pol.Metadata)
 
-	return &policyModel{
+	m := &policyModel{
 		ID:              pol.ID.String(),
 		TenantID:        pol.TenantID,
 		AppID:           pol.AppID,
@@ -167,10 +338,19 @@ func policyToModel(pol *policy.Policy) *policyModel {
 		GracePeriod:     pol.GracePeriod.Milliseconds(),
 		DailyQuota:      pol.DailyQuota,
 		MonthlyQuota:    pol.MonthlyQuota,
+		QuotaTimezone:   pol.QuotaTimezone,
+		MinTLSVersion:   pol.MinTLSVersion,
+		RequireMTLS:     pol.RequireMTLS,
+		Inline:          pol.Inline,
 		Metadata:        string(metadata),
-		CreatedAt:       pol.CreatedAt,
-		UpdatedAt:       pol.UpdatedAt,
+		CreatedAt:       sqliteTime(pol.CreatedAt),
+		UpdatedAt:       sqliteTime(pol.UpdatedAt),
+	}
+	if pol.BasePolicyID != nil {
+		bid := pol.BasePolicyID.String()
+		m.BasePolicyID = &bid
 	}
+	return m
 }
 
 func policyFromModel(m *policyModel) (*policy.Policy, error) {
@@ -179,6 +359,15 @@ func policyFromModel(m *policyModel) (*policy.Policy, error) {
 		return nil, err
 	}
 
+	var basePolicyID *id.PolicyID
+	if m.BasePolicyID != nil {
+		bid, err := id.ParsePolicyID(*m.BasePolicyID)
+		if err != nil {
+			return nil, err
+		}
+		basePolicyID = &bid
+	}
+
 	var allowedScopes []string
 	if m.AllowedScopes != "" {
 		_ = json.Unmarshal([]byte(m.AllowedScopes), &allowedScopes)
@@ -210,6 +399,7 @@ func policyFromModel(m *policyModel) (*policy.Policy, error) {
 		AppID:           m.AppID,
 		Name:            m.Name,
 		Description:     m.Description,
+		BasePolicyID:    basePolicyID,
 		RateLimit:       m.RateLimit,
 		RateLimitWindow: time.Duration(m.RateLimitWindow) * time.Millisecond,
 		BurstLimit:      m.BurstLimit,
@@ -223,9 +413,13 @@ func policyFromModel(m *policyModel) (*policy.Policy, error) {
 		GracePeriod:     time.Duration(m.GracePeriod) * time.Millisecond,
 		DailyQuota:      m.DailyQuota,
 		MonthlyQuota:    m.MonthlyQuota,
+		QuotaTimezone:   m.QuotaTimezone,
+		MinTLSVersion:   m.MinTLSVersion,
+		RequireMTLS:     m.RequireMTLS,
+		Inline:          m.Inline,
 		Metadata:        metadata,
-		CreatedAt:       m.CreatedAt,
-		UpdatedAt:       m.UpdatedAt,
+		CreatedAt:       time.Time(m.CreatedAt),
+		UpdatedAt:       time.Time(m.UpdatedAt),
 	}, nil
 }
 
@@ -235,14 +429,55 @@ func policyFromModel(m *policyModel) (*policy.Policy, error) {
 
 type scopeModel struct {
 	grove.BaseModel `grove:"table:keysmith_scopes"`
-	ID              string    `grove:"id,pk"`
-	TenantID        string    `grove:"tenant_id,notnull"`
-	AppID           string    `grove:"app_id,notnull"`
-	Name            string    `grove:"name,notnull"`
-	Description     string    `grove:"description"`
-	Parent          *string   `grove:"parent"`
-	Metadata        string    `grove:"metadata"` // JSON TEXT
-	CreatedAt       time.Time `grove:"created_at,notnull"`
+	ID              string     `grove:"id,pk"`
+	TenantID        string     `grove:"tenant_id,notnull"`
+	AppID           string     `grove:"app_id,notnull"`
+	Name            string     `grove:"name,notnull"`
+	Description     string     `grove:"description"`
+	Parent          *string    `grove:"parent"`
+	Metadata        string     `grove:"metadata"` // JSON TEXT
+	RateLimit       int        `grove:"rate_limit,notnull"`
+	RateLimitWindow int64      `grove:"rate_limit_window,notnull"`
+	CreatedAt       sqliteTime `grove:"created_at,notnull"`
+}
+
+// keyHashModel is one hash version of a key. Lookups by hash go through
+// this table; the key's key_hash is its current version.
+type keyHashModel struct {
+	grove.BaseModel `grove:"table:keysmith_key_hashes"`
+	Hash            string     `grove:"hash,pk"`
+	KeyID           string     `grove:"key_id,notnull"`
+	Scheme          string     `grove:"scheme,notnull"`
+	Active          bool       `grove:"active,notnull"`
+	CreatedAt       sqliteTime `grove:"created_at,notnull"`
+}
+
+func keyHashToModel(h *key.HashVersion) *keyHashModel {
+	scheme := h.Scheme
+	if scheme == "" {
+		scheme = key.HashScheme(h.Hash)
+	}
+	return &keyHashModel{
+		Hash:      h.Hash,
+		KeyID:     h.KeyID.String(),
+		Scheme:    scheme,
+		Active:    h.Active,
+		CreatedAt: sqliteTime(h.CreatedAt.UTC()),
+	}
+}
+
+func keyHashFromModel(m *keyHashModel) (*key.HashVersion, error) {
+	kid, err := id.ParseKeyID(m.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	return &key.HashVersion{
+		KeyID:     kid,
+		Hash:      m.Hash,
+		Scheme:    m.Scheme,
+		Active:    m.Active,
+		CreatedAt: time.Time(m.CreatedAt),
+	}, nil
 }
 
 // keyScopeModel represents the join table for key-scope assignments.
@@ -255,13 +490,15 @@ type keyScopeModel struct {
 func scopeToModel(sc *scope.Scope) *scopeModel {
 	metadata, _ := json.Marshal(sc.Metadata)
 	m := &scopeModel{
-		ID:          sc.ID.String(),
-		TenantID:    sc.TenantID,
-		AppID:       sc.AppID,
-		Name:        sc.Name,
-		Description: sc.Description,
-		Metadata:    string(metadata),
-		CreatedAt:   sc.CreatedAt,
+		ID:              sc.ID.String(),
+		TenantID:        sc.TenantID,
+		AppID:           sc.AppID,
+		Name:            sc.Name,
+		Description:     sc.Description,
+		Metadata:        string(metadata),
+		RateLimit:       sc.RateLimit,
+		RateLimitWindow: sc.RateLimitWindow.Milliseconds(),
+		CreatedAt:       sqliteTime(sc.CreatedAt),
 	}
 	if sc.Parent != "" {
 		m.Parent = &sc.Parent
@@ -281,13 +518,15 @@ func scopeFromModel(m *scopeModel) (*scope.Scope, error) {
 	}
 
 	sc := &scope.Scope{
-		ID:          sid,
-		TenantID:    m.TenantID,
-		AppID:       m.AppID,
-		Name:        m.Name,
-		Description: m.Description,
-		Metadata:    metadata,
-		CreatedAt:   m.CreatedAt,
+		ID:              sid,
+		TenantID:        m.TenantID,
+		AppID:           m.AppID,
+		Name:            m.Name,
+		Description:     m.Description,
+		Metadata:        metadata,
+		RateLimit:       m.RateLimit,
+		RateLimitWindow: time.Duration(m.RateLimitWindow) * time.Millisecond,
+		CreatedAt:       time.Time(m.CreatedAt),
 	}
 	if m.Parent != nil {
 		sc.Parent = *m.Parent
@@ -301,33 +540,37 @@ func scopeFromModel(m *scopeModel) (*scope.Scope, error) {
 
 type usageModel struct {
 	grove.BaseModel `grove:"table:keysmith_usage"`
-	ID              string    `grove:"id,pk"`
-	KeyID           string    `grove:"key_id,notnull"`
-	TenantID        string    `grove:"tenant_id,notnull"`
-	Endpoint        string    `grove:"endpoint,notnull"`
-	Method          string    `grove:"method,notnull"`
-	StatusCode      int       `grove:"status_code,notnull"`
-	IPAddress       string    `grove:"ip_address"`
-	UserAgent       string    `grove:"user_agent"`
-	LatencyMs       int64     `grove:"latency_ms,notnull"`
-	Metadata        string    `grove:"metadata"` // JSON TEXT
-	CreatedAt       time.Time `grove:"created_at,notnull"`
+	ID              string     `grove:"id,pk"`
+	KeyID           string     `grove:"key_id,notnull"`
+	TenantID        string     `grove:"tenant_id,notnull"`
+	AppID           string     `grove:"app_id"`
+	Environment     string     `grove:"environment,notnull"`
+	Endpoint        string     `grove:"endpoint,notnull"`
+	Method          string     `grove:"method,notnull"`
+	StatusCode      int        `grove:"status_code,notnull"`
+	IPAddress       string     `grove:"ip_address"`
+	UserAgent       string     `grove:"user_agent"`
+	LatencyMs       int64      `grove:"latency_ms,notnull"`
+	Metadata        string     `grove:"metadata"` // JSON TEXT
+	CreatedAt       sqliteTime `grove:"created_at,notnull"`
 }
 
 func usageToModel(rec *usage.Record) *usageModel {
 	metadata, _ := json.Marshal(rec.Metadata)
 	return &usageModel{
-		ID:         rec.ID.String(),
-		KeyID:      rec.KeyID.String(),
-		TenantID:   rec.TenantID,
-		Endpoint:   rec.Endpoint,
-		Method:     rec.Method,
-		StatusCode: rec.StatusCode,
-		IPAddress:  rec.IPAddress,
-		UserAgent:  rec.UserAgent,
-		LatencyMs:  rec.Latency.Milliseconds(),
-		Metadata:   string(metadata),
-		CreatedAt:  rec.CreatedAt,
+		ID:          rec.ID.String(),
+		KeyID:       rec.KeyID.String(),
+		TenantID:    rec.TenantID,
+		AppID:       rec.AppID,
+		Environment: string(rec.Environment),
+		Endpoint:    rec.Endpoint,
+		Method:      rec.Method,
+		StatusCode:  rec.StatusCode,
+		IPAddress:   rec.IPAddress,
+		UserAgent:   rec.UserAgent,
+		LatencyMs:   rec.Latency.Milliseconds(),
+		Metadata:    string(metadata),
+		CreatedAt:   sqliteTime(rec.CreatedAt),
 	}
 }
 
@@ -347,32 +590,34 @@ func usageFromModel(m *usageModel) (*usage.Record, error) {
 	}
 
 	return &usage.Record{
-		ID:         uid,
-		KeyID:      kid,
-		TenantID:   m.TenantID,
-		Endpoint:   m.Endpoint,
-		Method:     m.Method,
-		StatusCode: m.StatusCode,
-		IPAddress:  m.IPAddress,
-		UserAgent:  m.UserAgent,
-		Latency:    time.Duration(m.LatencyMs) * time.Millisecond,
-		Metadata:   metadata,
-		CreatedAt:  m.CreatedAt,
+		ID:          uid,
+		KeyID:       kid,
+		TenantID:    m.TenantID,
+		AppID:       m.AppID,
+		Environment: key.Environment(m.Environment),
+		Endpoint:    m.Endpoint,
+		Method:      m.Method,
+		StatusCode:  m.StatusCode,
+		IPAddress:   m.IPAddress,
+		UserAgent:   m.UserAgent,
+		Latency:     time.Duration(m.LatencyMs) * time.Millisecond,
+		Metadata:    metadata,
+		CreatedAt:   time.Time(m.CreatedAt),
 	}, nil
 }
 
 // usageAggModel represents aggregated usage statistics.
 type usageAggModel struct {
 	grove.BaseModel `grove:"table:keysmith_usage_agg"`
-	KeyID           string    `grove:"key_id,pk"`
-	TenantID        string    `grove:"tenant_id,notnull"`
-	Period          string    `grove:"period,pk"`
-	PeriodStart     time.Time `grove:"period_start,pk"`
-	RequestCount    int64     `grove:"request_count,notnull"`
-	ErrorCount      int64     `grove:"error_count,notnull"`
-	TotalLatency    int64     `grove:"total_latency,notnull"`
-	P50Latency      int64     `grove:"p50_latency,notnull"`
-	P99Latency      int64     `grove:"p99_latency,notnull"`
+	KeyID           string     `grove:"key_id,pk"`
+	TenantID        string     `grove:"tenant_id,notnull"`
+	Period          string     `grove:"period,pk"`
+	PeriodStart     sqliteTime `grove:"period_start,pk"`
+	RequestCount    int64      `grove:"request_count,notnull"`
+	ErrorCount      int64      `grove:"error_count,notnull"`
+	TotalLatency    int64      `grove:"total_latency,notnull"`
+	P50Latency      int64      `grove:"p50_latency,notnull"`
+	P99Latency      int64      `grove:"p99_latency,notnull"`
 }
 
 func aggFromModel(m *usageAggModel) (*usage.Aggregation, error) {
@@ -384,7 +629,7 @@ func aggFromModel(m *usageAggModel) (*usage.Aggregation, error) {
 		KeyID:        kid,
 		TenantID:     m.TenantID,
 		Period:       m.Period,
-		PeriodStart:  m.PeriodStart,
+		PeriodStart:  time.Time(m.PeriodStart),
 		RequestCount: m.RequestCount,
 		ErrorCount:   m.ErrorCount,
 		TotalLatency: m.TotalLatency,
@@ -393,22 +638,60 @@ func aggFromModel(m *usageAggModel) (*usage.Aggregation, error) {
 	}, nil
 }
 
+// endpointSeenModel is the last-seen summary of one key endpoint.
+type endpointSeenModel struct {
+	grove.BaseModel `grove:"table:keysmith_key_endpoint_seen"`
+	KeyID           string     `grove:"key_id,pk"`
+	Endpoint        string     `grove:"endpoint,pk"`
+	Method          string     `grove:"method,pk"`
+	LastSeenAt      sqliteTime `grove:"last_seen_at,notnull"`
+	Count           int64      `grove:"count,notnull"`
+}
+
+func endpointSeenToModel(a *usage.EndpointActivity) *endpointSeenModel {
+	return &endpointSeenModel{
+		KeyID:    a.KeyID.String(),
+		Endpoint: a.Endpoint,
+		Method:   a.Method,
+		// Stored as TEXT; UTC keeps MAX() comparisons chronological.
+		LastSeenAt: sqliteTime(a.LastSeenAt.UTC()),
+		Count:      a.Count,
+	}
+}
+
+func endpointSeenFromModel(m *endpointSeenModel) (*usage.EndpointActivity, error) {
+	kid, err := id.ParseKeyID(m.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	return &usage.EndpointActivity{
+		KeyID:      kid,
+		Endpoint:   m.Endpoint,
+		Method:     m.Method,
+		LastSeenAt: time.Time(m.LastSeenAt),
+		Count:      m.Count,
+	}, nil
+}
+
 // ──────────────────────────────────────────────────
 // Rotation model
 // ──────────────────────────────────────────────────
 
 type rotationModel struct {
 	grove.BaseModel `grove:"table:keysmith_rotations"`
-	ID              string    `grove:"id,pk"`
-	KeyID           string    `grove:"key_id,notnull"`
-	TenantID        string    `grove:"tenant_id,notnull"`
-	OldKeyHash      string    `grove:"old_key_hash,notnull"`
-	NewKeyHash      string    `grove:"new_key_hash,notnull"`
-	Reason          string    `grove:"reason,notnull"`
-	GraceTTLMs      int64     `grove:"grace_ttl_ms,notnull"`
-	GraceEnds       time.Time `grove:"grace_ends,notnull"`
-	RotatedBy       string    `grove:"rotated_by"`
-	CreatedAt       time.Time `grove:"created_at,notnull"`
+	ID              string     `grove:"id,pk"`
+	KeyID           string     `grove:"key_id,notnull"`
+	TenantID        string     `grove:"tenant_id,notnull"`
+	AppID           string     `grove:"app_id"`
+	OldKeyHash      string     `grove:"old_key_hash,notnull"`
+	NewKeyHash      string     `grove:"new_key_hash,notnull"`
+	OldHint         string     `grove:"old_hint"`
+	NewHint         string     `grove:"new_hint"`
+	Reason          string     `grove:"reason,notnull"`
+	GraceTTLMs      int64      `grove:"grace_ttl_ms,notnull"`
+	GraceEnds       sqliteTime `grove:"grace_ends,notnull"`
+	RotatedBy       string     `grove:"rotated_by"`
+	CreatedAt       sqliteTime `grove:"created_at,notnull"`
 }
 
 func rotationToModel(rec *rotation.Record) *rotationModel {
@@ -416,13 +699,16 @@ func rotationToModel(rec *rotation.Record) *rotationModel {
 		ID:         rec.ID.String(),
 		KeyID:      rec.KeyID.String(),
 		TenantID:   rec.TenantID,
+		AppID:      rec.AppID,
 		OldKeyHash: rec.OldKeyHash,
 		NewKeyHash: rec.NewKeyHash,
+		OldHint:    rec.OldHint,
+		NewHint:    rec.NewHint,
 		Reason:     string(rec.Reason),
 		GraceTTLMs: rec.GraceTTL.Milliseconds(),
-		GraceEnds:  rec.GraceEnds,
+		GraceEnds:  sqliteTime(rec.GraceEnds),
 		RotatedBy:  rec.RotatedBy,
-		CreatedAt:  rec.CreatedAt,
+		CreatedAt:  sqliteTime(rec.CreatedAt),
 	}
 }
 
@@ -439,12 +725,539 @@ func rotationFromModel(m *rotationModel) (*rotation.Record, error) {
 		ID:         rid,
 		KeyID:      kid,
 		TenantID:   m.TenantID,
+		AppID:      m.AppID,
 		OldKeyHash: m.OldKeyHash,
 		NewKeyHash: m.NewKeyHash,
+		OldHint:    m.OldHint,
+		NewHint:    m.NewHint,
 		Reason:     rotation.Reason(m.Reason),
 		GraceTTL:   time.Duration(m.GraceTTLMs) * time.Millisecond,
-		GraceEnds:  m.GraceEnds,
+		GraceEnds:  time.Time(m.GraceEnds),
 		RotatedBy:  m.RotatedBy,
-		CreatedAt:  m.CreatedAt,
+		CreatedAt:  time.Time(m.CreatedAt),
+	}, nil
+}
+
+// ──────────────────────────────────────────────────
+// Tenant settings model
+// ──────────────────────────────────────────────────
+
+type tenantSettingsModel struct {
+	grove.BaseModel `grove:"table:keysmith_tenant_settings"`
+	TenantID        string     `grove:"tenant_id,pk"`
+	AppID           string     `grove:"app_id,notnull"`
+	DefaultScopes   string     `grove:"default_scopes"`   // JSON TEXT
+	DefaultContacts string     `grove:"default_contacts"` // JSON TEXT
+	MetadataSchema  *string    `grove:"metadata_schema"`  // JSON TEXT
+	RateLimit       int        `grove:"rate_limit,notnull"`
+	RateLimitWindow int64      `grove:"rate_limit_window,notnull"`
+	IPHandling      string     `grove:"ip_handling,notnull"`
+	QuotaTimezone   string     `grove:"quota_timezone,notnull"`
+	UniqueKeyNames  bool       `grove:"unique_key_names,notnull"`
+	Lockdown        *string    `grove:"lockdown"` // JSON TEXT
+	CreatedAt       sqliteTime `grove:"created_at,notnull"`
+	UpdatedAt       sqliteTime `grove:"updated_at,notnull"`
+}
+
+func tenantSettingsToModel(ts *tenant.Settings) *tenantSettingsModel {
+	scopes := ts.DefaultScopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	defaultScopes, _ := json.Marshal(scopes)
+	contacts := ts.DefaultContacts
+	if contacts == nil {
+		contacts = key.Contacts{}
+	}
+	defaultContacts, _ := json.Marshal(contacts)
+
+	var schema *string
+	if ts.MetadataSchema != nil {
+		b, _ := json.Marshal(ts.MetadataSchema)
+		s := string(b)
+		schema = &s
+	}
+	var lockdown *string
+	if ts.Lockdown != nil {
+		b, _ := json.Marshal(ts.Lockdown)
+		s := string(b)
+		lockdown = &s
+	}
+
+	return &tenantSettingsModel{
+		TenantID:        ts.TenantID,
+		AppID:           ts.AppID,
+		DefaultScopes:   string(defaultScopes),
+		DefaultContacts: string(defaultContacts),
+		MetadataSchema:  schema,
+		RateLimit:       ts.RateLimit,
+		RateLimitWindow: ts.RateLimitWindow.Milliseconds(),
+		IPHandling:      string(ts.IPHandling),
+		QuotaTimezone:   ts.QuotaTimezone,
+		UniqueKeyNames:  ts.UniqueKeyNames,
+		Lockdown:        lockdown,
+		CreatedAt:       sqliteTime(ts.CreatedAt),
+		UpdatedAt:       sqliteTime(ts.UpdatedAt),
+	}
+}
+
+func tenantSettingsFromModel(m *tenantSettingsModel) *tenant.Settings {
+	var defaultScopes []string
+	if m.DefaultScopes != "" {
+		_ = json.Unmarshal([]byte(m.DefaultScopes), &defaultScopes)
+	}
+	var defaultContacts key.Contacts
+	if m.DefaultContacts != "" {
+		_ = json.Unmarshal([]byte(m.DefaultContacts), &defaultContacts)
+	}
+
+	var schema *metaschema.Schema
+	if m.MetadataSchema != nil && *m.MetadataSchema != "" {
+		schema = new(metaschema.Schema)
+		if err := json.Unmarshal([]byte(*m.MetadataSchema), schema); err != nil {
+			schema = nil
+		}
+	}
+	var lockdown *tenant.Lockdown
+	if m.Lockdown != nil && *m.Lockdown != "" {
+		lockdown = new(tenant.Lockdown)
+		if err := json.Unmarshal([]byte(*m.Lockdown), lockdown); err != nil {
+			lockdown = nil
+		}
+	}
+
+	return &tenant.Settings{
+		TenantID:        m.TenantID,
+		AppID:           m.AppID,
+		DefaultScopes:   defaultScopes,
+		DefaultContacts: defaultContacts.Normalize(),
+		MetadataSchema:  schema,
+		RateLimit:       m.RateLimit,
+		RateLimitWindow: time.Duration(m.RateLimitWindow) * time.Millisecond,
+		IPHandling:      usage.IPHandling(m.IPHandling),
+		QuotaTimezone:   m.QuotaTimezone,
+		UniqueKeyNames:  m.UniqueKeyNames,
+		Lockdown:        lockdown,
+		CreatedAt:       time.Time(m.CreatedAt),
+		UpdatedAt:       time.Time(m.UpdatedAt),
+	}
+}
+
+// ──────────────────────────────────────────────────
+// Revocation model
+// ──────────────────────────────────────────────────
+
+// revocationModel is one entry of the revocation feed.
+type revocationModel struct {
+	grove.BaseModel `grove:"table:keysmith_key_revocations"`
+	At              sqliteTime `grove:"at,pk"`
+	KeyID           string     `grove:"key_id,pk"`
+	TenantID        string     `grove:"tenant_id,notnull"`
+	HashPrefix      string     `grove:"hash_prefix,notnull"`
+	State           string     `grove:"state,notnull"`
+	Revoked         bool       `grove:"revoked,notnull"`
+}
+
+func revocationToModel(e *revocation.Entry) *revocationModel {
+	return &revocationModel{
+		At:         sqliteTime(e.At.UTC()),
+		KeyID:      e.KeyID.String(),
+		TenantID:   e.TenantID,
+		HashPrefix: e.HashPrefix,
+		State:      string(e.State),
+		Revoked:    e.Revoked,
+	}
+}
+
+func revocationFromModel(m *revocationModel) (*revocation.Entry, error) {
+	kid, err := id.ParseKeyID(m.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	return &revocation.Entry{
+		KeyID:      kid,
+		TenantID:   m.TenantID,
+		HashPrefix: m.HashPrefix,
+		State:      key.State(m.State),
+		Revoked:    m.Revoked,
+		At:         time.Time(m.At),
+	}, nil
+}
+
+// ──────────────────────────────────────────────────
+// Key event model
+// ──────────────────────────────────────────────────
+
+// keyEventModel is one event of the key event history.
+type keyEventModel struct {
+	grove.BaseModel `grove:"table:keysmith_key_events"`
+	At              sqliteTime `grove:"at,pk"`
+	KeyID           string     `grove:"key_id,pk"`
+	Type            string     `grove:"type,notnull"`
+	TenantID        string     `grove:"tenant_id,notnull"`
+	AppID           string     `grove:"app_id,notnull"`
+	State           string     `grove:"state,notnull"`
+	Reason          string     `grove:"reason,notnull"`
+	ReasonCode      string     `grove:"reason_code,notnull"`
+	ActorID         string     `grove:"actor_id,notnull"`
+}
+
+func keyEventToModel(e *keyevent.Event) *keyEventModel {
+	return &keyEventModel{
+		At:         sqliteTime(e.At.UTC()),
+		KeyID:      e.KeyID.String(),
+		Type:       string(e.Type),
+		TenantID:   e.TenantID,
+		AppID:      e.AppID,
+		State:      string(e.State),
+		Reason:     e.Reason,
+		ReasonCode: e.ReasonCode,
+		ActorID:    e.ActorID,
+	}
+}
+
+func keyEventFromModel(m *keyEventModel) (*keyevent.Event, error) {
+	kid, err := id.ParseKeyID(m.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	return &keyevent.Event{
+		Type:       keyevent.Type(m.Type),
+		KeyID:      kid,
+		TenantID:   m.TenantID,
+		AppID:      m.AppID,
+		State:      key.State(m.State),
+		Reason:     m.Reason,
+		ReasonCode: m.ReasonCode,
+		ActorID:    m.ActorID,
+		At:         time.Time(m.At),
+	}, nil
+}
+
+// ──────────────────────────────────────────────────
+// Capture model
+// ──────────────────────────────────────────────────
+
+// captureModel is one sampled debug request.
+type captureModel struct {
+	grove.BaseModel `grove:"table:keysmith_debug_captures"`
+	ID              string     `grove:"id,pk"`
+	KeyID           string     `grove:"key_id,notnull"`
+	TenantID        string     `grove:"tenant_id,notnull"`
+	Method          string     `grove:"method,notnull"`
+	Path            string     `grove:"path,notnull"`
+	Headers         string     `grove:"headers"` // JSON TEXT
+	Body            string     `grove:"body"`
+	BodyTruncated   bool       `grove:"body_truncated,notnull"`
+	CapturedAt      sqliteTime `grove:"captured_at,notnull"`
+}
+
+func captureToModel(c *capture.Capture) *captureModel {
+	headers, _ := json.Marshal(c.Headers)
+	return &captureModel{
+		ID:            c.ID.String(),
+		KeyID:         c.KeyID.String(),
+		TenantID:      c.TenantID,
+		Method:        c.Method,
+		Path:          c.Path,
+		Headers:       string(headers),
+		Body:          c.Body,
+		BodyTruncated: c.BodyTruncated,
+		CapturedAt:    sqliteTime(c.CapturedAt.UTC()),
+	}
+}
+
+func captureFromModel(m *captureModel) (*capture.Capture, error) {
+	cid, err := id.ParseCaptureID(m.ID)
+	if err != nil {
+		return nil, err
+	}
+	kid, err := id.ParseKeyID(m.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var headers map[string]string
+	if m.Headers != "" {
+		_ = json.Unmarshal([]byte(m.Headers), &headers)
+	}
+
+	return &capture.Capture{
+		ID:            cid,
+		KeyID:         kid,
+		TenantID:      m.TenantID,
+		Method:        m.Method,
+		Path:          m.Path,
+		Headers:       headers,
+		Body:          m.Body,
+		BodyTruncated: m.BodyTruncated,
+		CapturedAt:    time.Time(m.CapturedAt),
 	}, nil
 }
+
+// ──────────────────────────────────────────────────
+// Group model
+// ──────────────────────────────────────────────────
+
+type groupModel struct {
+	grove.BaseModel `grove:"table:keysmith_key_groups"`
+	ID              string     `grove:"id,pk"`
+	TenantID        string     `grove:"tenant_id,notnull"`
+	AppID           string     `grove:"app_id,notnull"`
+	Name            string     `grove:"name,notnull"`
+	Description     string     `grove:"description"`
+	CreatedAt       sqliteTime `grove:"created_at,notnull"`
+	UpdatedAt       sqliteTime `grove:"updated_at,notnull"`
+}
+
+// groupMemberModel represents the join table for group memberships.
+type groupMemberModel struct {
+	grove.BaseModel `grove:"table:keysmith_key_group_members"`
+	GroupID         string `grove:"group_id,pk"`
+	KeyID           string `grove:"key_id,pk"`
+}
+
+func groupToModel(g *group.Group) *groupModel {
+	return &groupModel{
+		ID:          g.ID.String(),
+		TenantID:    g.TenantID,
+		AppID:       g.AppID,
+		Name:        g.Name,
+		Description: g.Description,
+		CreatedAt:   sqliteTime(g.CreatedAt.UTC()),
+		UpdatedAt:   sqliteTime(g.UpdatedAt.UTC()),
+	}
+}
+
+func groupFromModel(m *groupModel) (*group.Group, error) {
+	gid, err := id.ParseGroupID(m.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &group.Group{
+		ID:          gid,
+		TenantID:    m.TenantID,
+		AppID:       m.AppID,
+		Name:        m.Name,
+		Description: m.Description,
+		CreatedAt:   time.Time(m.CreatedAt),
+		UpdatedAt:   time.Time(m.UpdatedAt),
+	}, nil
+}
+
+// ──────────────────────────────────────────────────
+// Transfer model
+// ──────────────────────────────────────────────────
+
+// transferModel is one key transfer between tenants.
+type transferModel struct {
+	grove.BaseModel `grove:"table:keysmith_key_transfers"`
+	ID              string      `grove:"id,pk"`
+	KeyID           string      `grove:"key_id,notnull"`
+	AppID           string      `grove:"app_id,notnull"`
+	SourceTenantID  string      `grove:"source_tenant_id,notnull"`
+	TargetTenantID  string      `grove:"target_tenant_id,notnull"`
+	State           string      `grove:"state,notnull"`
+	RetagUsage      bool        `grove:"retag_usage,notnull"`
+	InitiatedBy     string      `grove:"initiated_by,notnull"`
+	ResolvedBy      string      `grove:"resolved_by,notnull"`
+	UnmappedScopes  string      `grove:"unmapped_scopes,notnull"` // JSON TEXT
+	UnmappedPolicy  string      `grove:"unmapped_policy,notnull"`
+	ExpiresAt       sqliteTime  `grove:"expires_at,notnull"`
+	CreatedAt       sqliteTime  `grove:"created_at,notnull"`
+	ResolvedAt      *sqliteTime `grove:"resolved_at"`
+}
+
+func transferToModel(t *transfer.Transfer) *transferModel {
+	unmapped := t.UnmappedScopes
+	if unmapped == nil {
+		unmapped = []string{}
+	}
+	unmappedScopes, _ := json.Marshal(unmapped)
+	return &transferModel{
+		ID:             t.ID.String(),
+		KeyID:          t.KeyID.String(),
+		AppID:          t.AppID,
+		SourceTenantID: t.SourceTenantID,
+		TargetTenantID: t.TargetTenantID,
+		State:          string(t.State),
+		RetagUsage:     t.RetagUsage,
+		InitiatedBy:    t.InitiatedBy,
+		ResolvedBy:     t.ResolvedBy,
+		UnmappedScopes: string(unmappedScopes),
+		UnmappedPolicy: t.UnmappedPolicy,
+		ExpiresAt:      sqliteTime(t.ExpiresAt.UTC()),
+		CreatedAt:      sqliteTime(t.CreatedAt.UTC()),
+		ResolvedAt:     utcTime(t.ResolvedAt),
+	}
+}
+
+func transferFromModel(m *transferModel) (*transfer.Transfer, error) {
+	tid, err := id.ParseTransferID(m.ID)
+	if err != nil {
+		return nil, err
+	}
+	kid, err := id.ParseKeyID(m.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	var unmapped []string
+	if m.UnmappedScopes != "" {
+		_ = json.Unmarshal([]byte(m.UnmappedScopes), &unmapped)
+	}
+	if len(unmapped) == 0 {
+		unmapped = nil
+	}
+	return &transfer.Transfer{
+		ID:             tid,
+		KeyID:          kid,
+		AppID:          m.AppID,
+		SourceTenantID: m.SourceTenantID,
+		TargetTenantID: m.TargetTenantID,
+		State:          transfer.State(m.State),
+		RetagUsage:     m.RetagUsage,
+		InitiatedBy:    m.InitiatedBy,
+		ResolvedBy:     m.ResolvedBy,
+		UnmappedScopes: unmapped,
+		UnmappedPolicy: m.UnmappedPolicy,
+		ExpiresAt:      time.Time(m.ExpiresAt),
+		CreatedAt:      time.Time(m.CreatedAt),
+		ResolvedAt:     (*time.Time)(m.ResolvedAt),
+	}, nil
+}
+
+// ──────────────────────────────────────────────────
+// Tombstone model
+// ──────────────────────────────────────────────────
+
+// tombstoneModel is the tombstone of one key hash.
+type tombstoneModel struct {
+	grove.BaseModel `grove:"table:keysmith_hash_tombstones"`
+	Hash            string     `grove:"hash,pk"`
+	KeyID           string     `grove:"key_id,notnull"`
+	TenantID        string     `grove:"tenant_id,notnull"`
+	AppID           string     `grove:"app_id,notnull"`
+	Reason          string     `grove:"reason,notnull"`
+	CreatedAt       sqliteTime `grove:"created_at,notnull"`
+}
+
+func tombstoneToModel(t *tombstone.Tombstone) *tombstoneModel {
+	return &tombstoneModel{
+		Hash:      t.Hash,
+		KeyID:     t.KeyID.String(),
+		TenantID:  t.TenantID,
+		AppID:     t.AppID,
+		Reason:    t.Reason,
+		CreatedAt: sqliteTime(t.CreatedAt.UTC()),
+	}
+}
+
+func tombstoneFromModel(m *tombstoneModel) (*tombstone.Tombstone, error) {
+	kid, err := id.ParseKeyID(m.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	return &tombstone.Tombstone{
+		Hash:      m.Hash,
+		KeyID:     kid,
+		TenantID:  m.TenantID,
+		AppID:     m.AppID,
+		Reason:    m.Reason,
+		CreatedAt: time.Time(m.CreatedAt),
+	}, nil
+}
+
+// ──────────────────────────────────────────────────
+// Quota pool models
+// ──────────────────────────────────────────────────
+
+type quotaPoolModel struct {
+	grove.BaseModel `grove:"table:keysmith_quota_pools"`
+	ID              string     `grove:"id,pk"`
+	TenantID        string     `grove:"tenant_id,notnull"`
+	AppID           string     `grove:"app_id,notnull"`
+	Name            string     `grove:"name,notnull"`
+	Description     string     `grove:"description"`
+	DailyLimit      int64      `grove:"daily_limit,notnull"`
+	MonthlyLimit    int64      `grove:"monthly_limit,notnull"`
+	QuotaTimezone   string     `grove:"quota_timezone,notnull"`
+	CreatedAt       sqliteTime `grove:"created_at,notnull"`
+	UpdatedAt       sqliteTime `grove:"updated_at,notnull"`
+}
+
+// quotaUsageModel is the validation count of one key in one quota window.
+// PoolID is empty for counts toward the key's own policy quota.
+type quotaUsageModel struct {
+	grove.BaseModel `grove:"table:keysmith_quota_usage"`
+	PoolID          string     `grove:"pool_id,pk"`
+	KeyID           string     `grove:"key_id,pk"`
+	Period          string     `grove:"period,pk"`
+	WindowStart     sqliteTime `grove:"window_start,pk"`
+	Count           int64      `grove:"count,notnull"`
+}
+
+func quotaPoolToModel(p *quotapool.Pool) *quotaPoolModel {
+	return &quotaPoolModel{
+		ID:            p.ID.String(),
+		TenantID:      p.TenantID,
+		AppID:         p.AppID,
+		Name:          p.Name,
+		Description:   p.Description,
+		DailyLimit:    p.DailyLimit,
+		MonthlyLimit:  p.MonthlyLimit,
+		QuotaTimezone: p.QuotaTimezone,
+		CreatedAt:     sqliteTime(p.CreatedAt.UTC()),
+		UpdatedAt:     sqliteTime(p.UpdatedAt.UTC()),
+	}
+}
+
+func quotaPoolFromModel(m *quotaPoolModel) (*quotapool.Pool, error) {
+	pid, err := id.ParseQuotaPoolID(m.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &quotapool.Pool{
+		ID:            pid,
+		TenantID:      m.TenantID,
+		AppID:         m.AppID,
+		Name:          m.Name,
+		Description:   m.Description,
+		DailyLimit:    m.DailyLimit,
+		MonthlyLimit:  m.MonthlyLimit,
+		QuotaTimezone: m.QuotaTimezone,
+		CreatedAt:     time.Time(m.CreatedAt),
+		UpdatedAt:     time.Time(m.UpdatedAt),
+	}, nil
+}
+
+func quotaUsageToModel(u *quotapool.Usage) *quotaUsageModel {
+	m := &quotaUsageModel{
+		KeyID:       u.KeyID.String(),
+		Period:      string(u.Period),
+		WindowStart: sqliteTime(u.WindowStart.UTC()),
+		Count:       u.Count,
+	}
+	if !u.PoolID.IsNil() {
+		m.PoolID = u.PoolID.String()
+	}
+	return m
+}
+
+func quotaUsageFromModel(m *quotaUsageModel) (*quotapool.Usage, error) {
+	kid, err := id.ParseKeyID(m.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	u := &quotapool.Usage{
+		KeyID:       kid,
+		Period:      quotapool.Period(m.Period),
+		WindowStart: time.Time(m.WindowStart),
+		Count:       m.Count,
+	}
+	if m.PoolID != "" {
+		if u.PoolID, err = id.ParseQuotaPoolID(m.PoolID); err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
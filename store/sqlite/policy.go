@@ -15,6 +15,10 @@ type policyStore struct {
 }
 
 func (s *policyStore) Create(ctx context.Context, pol *policy.Policy) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	m := policyToModel(pol)
 	_, err := s.sdb.NewInsert(m).Exec(ctx)
 	if err != nil {
@@ -24,6 +28,10 @@ func (s *policyStore) Create(ctx context.Context, pol *policy.Policy) error {
 }
 
 func (s *policyStore) Get(ctx context.Context, polID id.PolicyID) (*policy.Policy, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	m := new(policyModel)
 	err := s.sdb.NewSelect(m).Where("id = ?", polID.String()).Scan(ctx)
 	if err != nil {
@@ -36,6 +44,10 @@ func (s *policyStore) Get(ctx context.Context, polID id.PolicyID) (*policy.Polic
 }
 
 func (s *policyStore) GetByName(ctx context.Context, tenantID, name string) (*policy.Policy, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	m := new(policyModel)
 	err := s.sdb.NewSelect(m).
 		Where("tenant_id = ?", tenantID).
@@ -51,6 +63,10 @@ func (s *policyStore) GetByName(ctx context.Context, tenantID, name string) (*po
 }
 
 func (s *policyStore) Update(ctx context.Context, pol *policy.Policy) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	m := policyToModel(pol)
 	res, err := s.sdb.NewUpdate(m).WherePK().Exec(ctx)
 	if err != nil {
@@ -67,6 +83,10 @@ func (s *policyStore) Update(ctx context.Context, pol *policy.Policy) error {
 }
 
 func (s *policyStore) Delete(ctx context.Context, polID id.PolicyID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	res, err := s.sdb.NewDelete((*policyModel)(nil)).
 		Where("id = ?", polID.String()).
 		Exec(ctx)
@@ -84,13 +104,32 @@ func (s *policyStore) Delete(ctx context.Context, polID id.PolicyID) error {
 }
 
 func (s *policyStore) List(ctx context.Context, filter *policy.ListFilter) ([]*policy.Policy, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var models []policyModel
 	q := s.sdb.NewSelect(&models).OrderExpr("created_at DESC")
+	if filter == nil || !filter.IncludeInline {
+		q = q.Where("NOT inline")
+	}
 
 	if filter != nil {
 		if filter.TenantID != "" {
 			q = q.Where("tenant_id = ?", filter.TenantID)
 		}
+		if filter.AppID != "" {
+			q = q.Where("app_id = ?", filter.AppID)
+		}
+		if filter.CreatedAfter != nil {
+			q = q.Where("created_at > ?", filter.CreatedAfter.UTC())
+		}
+		if filter.CreatedBefore != nil {
+			q = q.Where("created_at < ?", filter.CreatedBefore.UTC())
+		}
+		if filter.UpdatedAfter != nil {
+			q = q.Where("updated_at > ?", filter.UpdatedAfter.UTC())
+		}
 		if filter.Limit > 0 {
 			q = q.Limit(filter.Limit)
 		}
@@ -115,12 +154,31 @@ func (s *policyStore) List(ctx context.Context, filter *policy.ListFilter) ([]*p
 }
 
 func (s *policyStore) Count(ctx context.Context, filter *policy.ListFilter) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
 	q := s.sdb.NewSelect((*policyModel)(nil))
+	if filter == nil || !filter.IncludeInline {
+		q = q.Where("NOT inline")
+	}
 
 	if filter != nil {
 		if filter.TenantID != "" {
 			q = q.Where("tenant_id = ?", filter.TenantID)
 		}
+		if filter.AppID != "" {
+			q = q.Where("app_id = ?", filter.AppID)
+		}
+		if filter.CreatedAfter != nil {
+			q = q.Where("created_at > ?", filter.CreatedAfter.UTC())
+		}
+		if filter.CreatedBefore != nil {
+			q = q.Where("created_at < ?", filter.CreatedBefore.UTC())
+		}
+		if filter.UpdatedAfter != nil {
+			q = q.Where("updated_at > ?", filter.UpdatedAfter.UTC())
+		}
 	}
 
 	count, err := q.Count(ctx)
@@ -0,0 +1,216 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xraph/grove/driver"
+	"github.com/xraph/grove/drivers/sqlitedriver"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/quotapool"
+)
+
+type quotaPoolStore struct {
+	sdb *sqlitedriver.SqliteDB
+}
+
+func (s *quotaPoolStore) Create(ctx context.Context, p *quotapool.Pool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, err := s.sdb.NewInsert(quotaPoolToModel(p)).Exec(ctx); err != nil {
+		return fmt.Errorf("keysmith/sqlite: create quota pool: %w", err)
+	}
+	return nil
+}
+
+func (s *quotaPoolStore) Get(ctx context.Context, poolID id.QuotaPoolID) (*quotapool.Pool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m := new(quotaPoolModel)
+	err := s.sdb.NewSelect(m).Where("id = ?", poolID.String()).Scan(ctx)
+	if err != nil {
+		if isNoRows(err) {
+			return nil, errNotFound("quota pool")
+		}
+		return nil, fmt.Errorf("keysmith/sqlite: get quota pool: %w", err)
+	}
+	return quotaPoolFromModel(m)
+}
+
+func (s *quotaPoolStore) Update(ctx context.Context, p *quotapool.Pool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	res, err := s.sdb.NewUpdate(quotaPoolToModel(p)).WherePK().Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: update quota pool: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: update quota pool rows: %w", err)
+	}
+	if rows == 0 {
+		return errNotFound("quota pool")
+	}
+	return nil
+}
+
+func (s *quotaPoolStore) Delete(ctx context.Context, poolID id.QuotaPoolID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tx, err := s.sdb.BeginTxQuery(ctx, &driver.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	pid := poolID.String()
+	res, err := tx.NewDelete((*quotaPoolModel)(nil)).Where("id = ?", pid).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: delete quota pool: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: delete quota pool rows: %w", err)
+	}
+	if rows == 0 {
+		return errNotFound("quota pool")
+	}
+	if _, err := tx.NewRaw(`DELETE FROM keysmith_quota_usage WHERE pool_id = ?`, pid).Exec(ctx); err != nil {
+		return fmt.Errorf("keysmith/sqlite: delete quota pool usage: %w", err)
+	}
+	if _, err := tx.NewRaw(`UPDATE keysmith_keys SET pool_id = NULL WHERE pool_id = ?`, pid).Exec(ctx); err != nil {
+		return fmt.Errorf("keysmith/sqlite: detach quota pool members: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (s *quotaPoolStore) List(ctx context.Context, filter *quotapool.ListFilter) ([]*quotapool.Pool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var models []quotaPoolModel
+	q := s.sdb.NewSelect(&models).OrderExpr("name ASC, id ASC")
+
+	if filter != nil {
+		if filter.TenantID != "" {
+			q = q.Where("tenant_id = ?", filter.TenantID)
+		}
+		if filter.AppID != "" {
+			q = q.Where("app_id = ?", filter.AppID)
+		}
+		if filter.Limit > 0 {
+			q = q.Limit(filter.Limit)
+		}
+		if filter.Offset > 0 {
+			q = q.Offset(filter.Offset)
+		}
+	}
+
+	if err := q.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: list quota pools: %w", err)
+	}
+
+	result := make([]*quotapool.Pool, 0, len(models))
+	for i := range models {
+		p, err := quotaPoolFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/sqlite: convert quota pool: %w", err)
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+func (s *quotaPoolStore) AddUsage(ctx context.Context, usages []*quotapool.Usage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if len(usages) == 0 {
+		return nil
+	}
+
+	tx, err := s.sdb.BeginTxQuery(ctx, &driver.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, u := range usages {
+		_, err := tx.NewInsert(quotaUsageToModel(u)).
+			OnConflict("(pool_id, key_id, period, window_start) DO UPDATE").
+			Set("count = keysmith_quota_usage.count + excluded.count").
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("keysmith/sqlite: add quota usage: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *quotaPoolStore) ListUsage(ctx context.Context, filter *quotapool.UsageFilter) ([]*quotapool.Usage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	pid := ""
+	if !filter.PoolID.IsNil() {
+		pid = filter.PoolID.String()
+	}
+	var models []quotaUsageModel
+	q := s.sdb.NewSelect(&models).
+		Where("pool_id = ?", pid).
+		OrderExpr("window_start ASC, key_id ASC")
+	if filter.KeyID != nil {
+		q = q.Where("key_id = ?", filter.KeyID.String())
+	}
+	if filter.Period != "" {
+		q = q.Where("period = ?", string(filter.Period))
+	}
+	if !filter.From.IsZero() {
+		q = q.Where("window_start >= ?", filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		q = q.Where("window_start < ?", filter.To.UTC())
+	}
+	if err := q.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: list quota usage: %w", err)
+	}
+
+	result := make([]*quotapool.Usage, 0, len(models))
+	for i := range models {
+		u, err := quotaUsageFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/sqlite: convert quota usage: %w", err)
+		}
+		result = append(result, u)
+	}
+	return result, nil
+}
+
+func (s *quotaPoolStore) PurgeUsage(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	res, err := s.sdb.NewDelete((*quotaUsageModel)(nil)).
+		Where("window_start < ?", before.UTC()).
+		Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("keysmith/sqlite: purge quota usage: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	return affected, nil
+}
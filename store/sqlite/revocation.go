@@ -0,0 +1,75 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xraph/grove/drivers/sqlitedriver"
+
+	"github.com/xraph/keysmith/revocation"
+)
+
+type revocationStore struct {
+	sdb *sqlitedriver.SqliteDB
+}
+
+func (s *revocationStore) Append(ctx context.Context, e *revocation.Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	_, err := s.sdb.NewInsert(revocationToModel(e)).
+		OnConflict("(at, key_id) DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: append revocation: %w", err)
+	}
+	return nil
+}
+
+func (s *revocationStore) ListAfter(ctx context.Context, tenantID string, after revocation.Cursor, limit int) ([]*revocation.Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var models []revocationModel
+	q := s.sdb.NewSelect(&models).
+		Where("(at > ? OR (at = ? AND key_id > ?))", after.At.UTC(), after.At.UTC(), after.KeyID).
+		OrderExpr("at ASC, key_id ASC")
+	if tenantID != "" {
+		q = q.Where("tenant_id = ?", tenantID)
+	}
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+
+	if err := q.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: list revocations: %w", err)
+	}
+
+	result := make([]*revocation.Entry, 0, len(models))
+	for i := range models {
+		e, err := revocationFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/sqlite: convert revocation: %w", err)
+		}
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+func (s *revocationStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	res, err := s.sdb.NewDelete((*revocationModel)(nil)).
+		Where("at < ?", before.UTC()).
+		Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("keysmith/sqlite: purge revocations: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	return affected, nil
+}
@@ -16,6 +16,10 @@ type rotationStore struct {
 }
 
 func (s *rotationStore) Create(ctx context.Context, rec *rotation.Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	m := rotationToModel(rec)
 	_, err := s.sdb.NewInsert(m).Exec(ctx)
 	if err != nil {
@@ -25,6 +29,10 @@ func (s *rotationStore) Create(ctx context.Context, rec *rotation.Record) error
 }
 
 func (s *rotationStore) Get(ctx context.Context, rotID id.RotationID) (*rotation.Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	m := new(rotationModel)
 	err := s.sdb.NewSelect(m).Where("id = ?", rotID.String()).Scan(ctx)
 	if err != nil {
@@ -37,19 +45,15 @@ func (s *rotationStore) Get(ctx context.Context, rotID id.RotationID) (*rotation
 }
 
 func (s *rotationStore) List(ctx context.Context, filter *rotation.ListFilter) ([]*rotation.Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var models []rotationModel
 	q := s.sdb.NewSelect(&models).OrderExpr("created_at DESC")
 
 	if filter != nil {
-		if filter.KeyID != nil {
-			q = q.Where("key_id = ?", filter.KeyID.String())
-		}
-		if filter.TenantID != "" {
-			q = q.Where("tenant_id = ?", filter.TenantID)
-		}
-		if filter.Reason != "" {
-			q = q.Where("reason = ?", string(filter.Reason))
-		}
+		q = rotationWhere(q, filter)
 		if filter.Limit > 0 {
 			q = q.Limit(filter.Limit)
 		}
@@ -74,6 +78,10 @@ func (s *rotationStore) List(ctx context.Context, filter *rotation.ListFilter) (
 }
 
 func (s *rotationStore) ListPendingGrace(ctx context.Context, now time.Time) ([]*rotation.Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var models []rotationModel
 	err := s.sdb.NewSelect(&models).
 		Where("grace_ends > ?", now).
@@ -95,6 +103,10 @@ func (s *rotationStore) ListPendingGrace(ctx context.Context, now time.Time) ([]
 }
 
 func (s *rotationStore) LatestForKey(ctx context.Context, keyID id.KeyID) (*rotation.Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	m := new(rotationModel)
 	err := s.sdb.NewSelect(m).
 		Where("key_id = ?", keyID.String()).
@@ -109,3 +121,67 @@ func (s *rotationStore) LatestForKey(ctx context.Context, keyID id.KeyID) (*rota
 	}
 	return rotationFromModel(m)
 }
+
+func (s *rotationStore) CountByReason(ctx context.Context, filter *rotation.ListFilter) ([]*rotation.ReasonCount, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	q := s.sdb.NewSelect((*rotationModel)(nil)).
+		ColumnExpr("reason").
+		ColumnExpr("COUNT(*)").
+		GroupExpr("reason").
+		OrderExpr("COUNT(*) DESC, reason ASC")
+	if filter != nil {
+		q = rotationWhere(q, filter)
+	}
+	query, args, err := q.Build()
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: count rotations by reason: %w", err)
+	}
+
+	rows, err := s.sdb.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: count rotations by reason: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var result []*rotation.ReasonCount
+	for rows.Next() {
+		var (
+			reason string
+			n      int64
+		)
+		if err := rows.Scan(&reason, &n); err != nil {
+			return nil, fmt.Errorf("keysmith/sqlite: count rotations by reason: %w", err)
+		}
+		result = append(result, &rotation.ReasonCount{Reason: rotation.Reason(reason), Count: n})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: count rotations by reason: %w", err)
+	}
+	return result, nil
+}
+
+// rotationWhere applies the conditions of filter other than its pagination.
+func rotationWhere(q *sqlitedriver.SelectQuery, filter *rotation.ListFilter) *sqlitedriver.SelectQuery {
+	if filter.KeyID != nil {
+		q = q.Where("key_id = ?", filter.KeyID.String())
+	}
+	if filter.TenantID != "" {
+		q = q.Where("tenant_id = ?", filter.TenantID)
+	}
+	if filter.AppID != "" {
+		q = q.Where("app_id = ?", filter.AppID)
+	}
+	if filter.Reason != "" {
+		q = q.Where("reason = ?", string(filter.Reason))
+	}
+	if filter.CreatedAfter != nil {
+		q = q.Where("created_at > ?", filter.CreatedAfter.UTC())
+	}
+	if filter.CreatedBefore != nil {
+		q = q.Where("created_at < ?", filter.CreatedBefore.UTC())
+	}
+	return q
+}
@@ -0,0 +1,77 @@
+//go:build integration
+
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/grove"
+	"github.com/xraph/grove/drivers/sqlitedriver"
+	_ "github.com/xraph/grove/drivers/sqlitedriver/sqlitemigrate"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/store/sqlite"
+	"github.com/xraph/keysmith/store/storetest"
+)
+
+func TestCountByReason(t *testing.T) {
+	ctx := context.Background()
+	d := sqlitedriver.New()
+	require.NoError(t, d.Open(ctx, filepath.Join(t.TempDir(), "keysmith.db")))
+	db, err := grove.Open(d)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	s := sqlite.New(db)
+	require.NoError(t, s.Migrate(ctx))
+	now := time.Now().UTC()
+	k := &key.Key{
+		ID: id.NewKeyID(), TenantID: "t1", KeyHash: "h1", Prefix: "sk", Environment: key.EnvTest,
+		State: key.StateActive, CreatedAt: now, UpdatedAt: now,
+	}
+	require.NoError(t, s.Keys().Create(ctx, k))
+	for i, reason := range []rotation.Reason{rotation.ReasonManual, rotation.ReasonCompromise, rotation.ReasonManual, "legacy-reason"} {
+		at := now.Add(time.Duration(i-4) * time.Hour)
+		require.NoError(t, s.Rotations().Create(ctx, &rotation.Record{
+			ID: id.NewRotationID(), KeyID: k.ID, TenantID: k.TenantID, Reason: reason, GraceEnds: at, CreatedAt: at,
+		}))
+	}
+
+	counts, err := s.Rotations().CountByReason(ctx, &rotation.ListFilter{TenantID: "t1"})
+	require.NoError(t, err)
+	assert.Equal(t, []*rotation.ReasonCount{
+		{Reason: rotation.ReasonManual, Count: 2},
+		{Reason: rotation.ReasonCompromise, Count: 1},
+		{Reason: "legacy-reason", Count: 1},
+	}, counts)
+
+	after := now.Add(-3*time.Hour - time.Minute)
+	counts, err = s.Rotations().CountByReason(ctx, &rotation.ListFilter{TenantID: "t1", CreatedAfter: &after})
+	require.NoError(t, err)
+	assert.Equal(t, []*rotation.ReasonCount{
+		{Reason: rotation.ReasonCompromise, Count: 1},
+		{Reason: "legacy-reason", Count: 1},
+		{Reason: rotation.ReasonManual, Count: 1},
+	}, counts)
+}
+
+func TestRotationGraceHash(t *testing.T) {
+	ctx := context.Background()
+	d := sqlitedriver.New()
+	require.NoError(t, d.Open(ctx, filepath.Join(t.TempDir(), "keysmith.db")))
+	db, err := grove.Open(d)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	s := sqlite.New(db)
+	require.NoError(t, s.Migrate(ctx))
+	storetest.CheckRotationGraceHash(t, s, "t1")
+}
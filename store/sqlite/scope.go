@@ -3,6 +3,7 @@ package sqlite
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/xraph/grove/driver"
 	"github.com/xraph/grove/drivers/sqlitedriver"
@@ -16,6 +17,10 @@ type scopeStore struct {
 }
 
 func (s *scopeStore) Create(ctx context.Context, sc *scope.Scope) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	m := scopeToModel(sc)
 	_, err := s.sdb.NewInsert(m).Exec(ctx)
 	if err != nil {
@@ -25,6 +30,10 @@ func (s *scopeStore) Create(ctx context.Context, sc *scope.Scope) error {
 }
 
 func (s *scopeStore) Get(ctx context.Context, scopeID id.ScopeID) (*scope.Scope, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	m := new(scopeModel)
 	err := s.sdb.NewSelect(m).Where("id = ?", scopeID.String()).Scan(ctx)
 	if err != nil {
@@ -37,6 +46,10 @@ func (s *scopeStore) Get(ctx context.Context, scopeID id.ScopeID) (*scope.Scope,
 }
 
 func (s *scopeStore) GetByName(ctx context.Context, tenantID, name string) (*scope.Scope, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	m := new(scopeModel)
 	err := s.sdb.NewSelect(m).
 		Where("tenant_id = ?", tenantID).
@@ -52,6 +65,10 @@ func (s *scopeStore) GetByName(ctx context.Context, tenantID, name string) (*sco
 }
 
 func (s *scopeStore) Update(ctx context.Context, sc *scope.Scope) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	m := scopeToModel(sc)
 	res, err := s.sdb.NewUpdate(m).WherePK().Exec(ctx)
 	if err != nil {
@@ -68,6 +85,10 @@ func (s *scopeStore) Update(ctx context.Context, sc *scope.Scope) error {
 }
 
 func (s *scopeStore) Delete(ctx context.Context, scopeID id.ScopeID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	res, err := s.sdb.NewDelete((*scopeModel)(nil)).
 		Where("id = ?", scopeID.String()).
 		Exec(ctx)
@@ -85,6 +106,10 @@ func (s *scopeStore) Delete(ctx context.Context, scopeID id.ScopeID) error {
 }
 
 func (s *scopeStore) List(ctx context.Context, filter *scope.ListFilter) ([]*scope.Scope, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var models []scopeModel
 	q := s.sdb.NewSelect(&models).OrderExpr("name ASC")
 
@@ -92,9 +117,15 @@ func (s *scopeStore) List(ctx context.Context, filter *scope.ListFilter) ([]*sco
 		if filter.TenantID != "" {
 			q = q.Where("tenant_id = ?", filter.TenantID)
 		}
+		if filter.AppID != "" {
+			q = q.Where("app_id = ?", filter.AppID)
+		}
 		if filter.Parent != "" {
 			q = q.Where("parent = ?", filter.Parent)
 		}
+		if filter.CreatedAfter != nil {
+			q = q.Where("created_at > ?", filter.CreatedAfter.UTC())
+		}
 		if filter.Limit > 0 {
 			q = q.Limit(filter.Limit)
 		}
@@ -119,6 +150,10 @@ func (s *scopeStore) List(ctx context.Context, filter *scope.ListFilter) ([]*sco
 }
 
 func (s *scopeStore) ListByKey(ctx context.Context, keyID id.KeyID) ([]*scope.Scope, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var models []scopeModel
 	err := s.sdb.NewSelect(&models).
 		Join("INNER JOIN", "keysmith_key_scopes AS ks", "ks.scope_id = keysmith_scopes.id").
@@ -141,6 +176,10 @@ func (s *scopeStore) ListByKey(ctx context.Context, keyID id.KeyID) ([]*scope.Sc
 }
 
 func (s *scopeStore) AssignToKey(ctx context.Context, keyID id.KeyID, scopeNames []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	if len(scopeNames) == 0 {
 		return nil
 	}
@@ -172,10 +211,17 @@ func (s *scopeStore) AssignToKey(ctx context.Context, keyID id.KeyID, scopeNames
 		}
 	}
 
+	if err := touchKey(ctx, tx, kid); err != nil {
+		return err
+	}
 	return tx.Commit()
 }
 
 func (s *scopeStore) RemoveFromKey(ctx context.Context, keyID id.KeyID, scopeNames []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	if len(scopeNames) == 0 {
 		return nil
 	}
@@ -200,5 +246,21 @@ func (s *scopeStore) RemoveFromKey(ctx context.Context, keyID id.KeyID, scopeNam
 		}
 	}
 
+	if err := touchKey(ctx, tx, kid); err != nil {
+		return err
+	}
 	return tx.Commit()
 }
+
+// touchKey bumps a key's updated_at in tx, so scope changes show up in
+// incremental key listings.
+func touchKey(ctx context.Context, tx *sqlitedriver.SqliteTx, kid string) error {
+	_, err := tx.NewUpdate((*keyModel)(nil)).
+		Set("updated_at = ?", time.Now().UTC()).
+		Where("id = ?", kid).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: touch key: %w", err)
+	}
+	return nil
+}
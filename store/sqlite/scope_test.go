@@ -0,0 +1,49 @@
+//go:build integration
+
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/grove"
+	"github.com/xraph/grove/drivers/sqlitedriver"
+	_ "github.com/xraph/grove/drivers/sqlitedriver/sqlitemigrate"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/store/sqlite"
+)
+
+func TestScopeRateLimit(t *testing.T) {
+	ctx := context.Background()
+	d := sqlitedriver.New()
+	require.NoError(t, d.Open(ctx, filepath.Join(t.TempDir(), "keysmith.db")))
+	db, err := grove.Open(d)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	s := sqlite.New(db)
+	require.NoError(t, s.Migrate(ctx))
+
+	sc := &scope.Scope{
+		ID:              id.NewScopeID(),
+		TenantID:        "t1",
+		AppID:           "a1",
+		Name:            "export",
+		RateLimit:       10,
+		RateLimitWindow: time.Hour,
+		CreatedAt:       time.Now(),
+	}
+	require.NoError(t, s.Scopes().Create(ctx, sc))
+
+	got, err := s.Scopes().GetByName(ctx, "t1", "export")
+	require.NoError(t, err)
+	assert.Equal(t, 10, got.RateLimit)
+	assert.Equal(t, time.Hour, got.RateLimitWindow)
+}
@@ -0,0 +1,127 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/xraph/grove/driver"
+
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store"
+)
+
+var _ store.StorageReporter = (*Store)(nil)
+
+// usageRowOverhead is the size attributed to the integer columns of a usage
+// row in UsageBytes estimates.
+const usageRowOverhead = 24
+
+// TenantStorage counts each tenant's rows with one GROUP BY per table. A
+// usage row's size is the length of its text columns plus
+// usageRowOverhead bytes, which leaves out indexes and page overhead.
+func (s *Store) TenantStorage(ctx context.Context, tenantID string) ([]*store.TenantStorage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	where, args := "", []any{}
+	if tenantID != "" {
+		where, args = " WHERE tenant_id = ?", []any{tenantID}
+	}
+	byTenant := make(map[string]*store.TenantStorage)
+	get := func(tid string) *store.TenantStorage {
+		ts, ok := byTenant[tid]
+		if !ok {
+			ts = &store.TenantStorage{TenantID: tid, KeysByState: make(map[key.State]int64)}
+			byTenant[tid] = ts
+		}
+		return ts
+	}
+
+	if err := s.countKeysByTenant(ctx, where, args, get); err != nil {
+		return nil, err
+	}
+
+	for _, c := range []struct {
+		table string
+		count func(*store.TenantStorage) *int64
+	}{
+		{"keysmith_policies", func(ts *store.TenantStorage) *int64 { return &ts.Policies }},
+		{"keysmith_scopes", func(ts *store.TenantStorage) *int64 { return &ts.Scopes }},
+		{"keysmith_rotations", func(ts *store.TenantStorage) *int64 { return &ts.Rotations }},
+	} {
+		rows, err := s.sdb.Query(ctx, `SELECT tenant_id, COUNT(*), 0 FROM `+c.table+where+` GROUP BY tenant_id`, args...)
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/sqlite: count %s by tenant: %w", c.table, err)
+		}
+		err = scanTenantCounts(rows, func(tid string, n, _ int64) { *c.count(get(tid)) += n })
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/sqlite: count %s by tenant: %w", c.table, err)
+		}
+	}
+
+	rows, err := s.sdb.Query(ctx, fmt.Sprintf(`
+		SELECT tenant_id, COUNT(*), COALESCE(SUM(
+			length(id) + length(key_id) + length(tenant_id) + length(app_id) +
+			length(endpoint) + length(method) + length(ip_address) + length(user_agent) +
+			length(metadata) + length(created_at) + length(environment) + %d), 0)
+		FROM keysmith_usage`+where+` GROUP BY tenant_id`, usageRowOverhead), args...)
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: count usage by tenant: %w", err)
+	}
+	err = scanTenantCounts(rows, func(tid string, n, size int64) {
+		ts := get(tid)
+		ts.UsageRows += n
+		ts.UsageBytes += size
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: count usage by tenant: %w", err)
+	}
+
+	out := make([]*store.TenantStorage, 0, len(byTenant))
+	for _, ts := range byTenant {
+		out = append(out, ts)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].TenantID < out[j].TenantID })
+	return out, nil
+}
+
+// countKeysByTenant adds the key counts by tenant and state to the entries
+// get returns.
+func (s *Store) countKeysByTenant(ctx context.Context, where string, args []any, get func(string) *store.TenantStorage) error {
+	rows, err := s.sdb.Query(ctx, `SELECT tenant_id, state, COUNT(*) FROM keysmith_keys`+where+` GROUP BY tenant_id, state`, args...)
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: count keys by tenant: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var tid, state string
+		var n int64
+		if err := rows.Scan(&tid, &state, &n); err != nil {
+			return fmt.Errorf("keysmith/sqlite: count keys by tenant: %w", err)
+		}
+		ts := get(tid)
+		ts.Keys += n
+		ts.KeysByState[key.State(state)] += n
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("keysmith/sqlite: count keys by tenant: %w", err)
+	}
+	return nil
+}
+
+// scanTenantCounts reads rows of (tenant_id, count, size) into fn and closes
+// rows, so the query that follows gets the connection.
+func scanTenantCounts(rows driver.Rows, fn func(tenantID string, n, size int64)) error {
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var tid string
+		var n, size int64
+		if err := rows.Scan(&tid, &n, &size); err != nil {
+			return err
+		}
+		fn(tid, n, size)
+	}
+	return rows.Err()
+}
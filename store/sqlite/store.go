@@ -10,11 +10,19 @@ import (
 	"github.com/xraph/grove/drivers/sqlitedriver"
 	"github.com/xraph/grove/migrate"
 
+	"github.com/xraph/keysmith/capture"
+	"github.com/xraph/keysmith/group"
 	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keyevent"
 	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/quotapool"
+	"github.com/xraph/keysmith/revocation"
 	"github.com/xraph/keysmith/rotation"
 	"github.com/xraph/keysmith/scope"
 	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/tenant"
+	"github.com/xraph/keysmith/tombstone"
+	"github.com/xraph/keysmith/transfer"
 	"github.com/xraph/keysmith/usage"
 )
 
@@ -53,8 +61,36 @@ func (s *Store) Rotations() rotation.Store { return &rotationStore{sdb: s.sdb} }
 // Scopes returns the scope store.
 func (s *Store) Scopes() scope.Store { return &scopeStore{sdb: s.sdb} }
 
+// Tenants returns the tenant settings store.
+func (s *Store) Tenants() tenant.Store { return &tenantStore{sdb: s.sdb} }
+
+// Revocations returns the revocation feed store.
+func (s *Store) Revocations() revocation.Store { return &revocationStore{sdb: s.sdb} }
+
+// Captures returns the debug capture store.
+func (s *Store) Captures() capture.Store { return &captureStore{sdb: s.sdb} }
+
+// Groups returns the key group store.
+func (s *Store) Groups() group.Store { return &groupStore{sdb: s.sdb} }
+
+// KeyEvents returns the key event history store.
+func (s *Store) KeyEvents() keyevent.Store { return &keyEventStore{sdb: s.sdb} }
+
+// Transfers returns the key transfer store.
+func (s *Store) Transfers() transfer.Store { return &transferStore{sdb: s.sdb} }
+
+// Tombstones returns the hash tombstone store.
+func (s *Store) Tombstones() tombstone.Store { return &tombstoneStore{sdb: s.sdb} }
+
+// QuotaPools returns the quota pool store.
+func (s *Store) QuotaPools() quotapool.Store { return &quotaPoolStore{sdb: s.sdb} }
+
 // Migrate creates the required tables and indexes using the grove orchestrator.
 func (s *Store) Migrate(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	executor, err := migrate.NewExecutorFor(s.sdb)
 	if err != nil {
 		return fmt.Errorf("keysmith/sqlite: create migration executor: %w", err)
@@ -68,6 +104,10 @@ func (s *Store) Migrate(ctx context.Context) error {
 
 // Ping checks database connectivity.
 func (s *Store) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	return s.db.Ping(ctx)
 }
 
@@ -86,3 +126,13 @@ func errNotFound(entity string) error { return &notFoundError{entity: entity} }
 func isNoRows(err error) bool {
 	return errors.Is(err, sql.ErrNoRows)
 }
+
+// sqliteBusy is SQLITE_BUSY, the primary result code of a write that found
+// the database locked by another connection.
+const sqliteBusy = 5
+
+// isBusy reports whether err is SQLITE_BUSY or one of its extended codes.
+func isBusy(err error) bool {
+	var coded interface{ Code() int }
+	return errors.As(err, &coded) && coded.Code()&0xff == sqliteBusy
+}
@@ -0,0 +1,110 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xraph/grove/drivers/sqlitedriver"
+
+	"github.com/xraph/keysmith/tenant"
+)
+
+type tenantStore struct {
+	sdb *sqlitedriver.SqliteDB
+}
+
+func (s *tenantStore) GetSettings(ctx context.Context, tenantID string) (*tenant.Settings, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m := new(tenantSettingsModel)
+	err := s.sdb.NewSelect(m).Where("tenant_id = ?", tenantID).Scan(ctx)
+	if err != nil {
+		if isNoRows(err) {
+			return nil, errNotFound("tenant settings")
+		}
+		return nil, fmt.Errorf("keysmith/sqlite: get tenant settings: %w", err)
+	}
+	return tenantSettingsFromModel(m), nil
+}
+
+func (s *tenantStore) UpsertSettings(ctx context.Context, ts *tenant.Settings) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m := tenantSettingsToModel(ts)
+	_, err := s.sdb.NewInsert(m).
+		OnConflict("(tenant_id) DO UPDATE").
+		Set("app_id = excluded.app_id").
+		Set("default_scopes = excluded.default_scopes").
+		Set("default_contacts = excluded.default_contacts").
+		Set("metadata_schema = excluded.metadata_schema").
+		Set("rate_limit = excluded.rate_limit").
+		Set("rate_limit_window = excluded.rate_limit_window").
+		Set("ip_handling = excluded.ip_handling").
+		Set("quota_timezone = excluded.quota_timezone").
+		Set("unique_key_names = excluded.unique_key_names").
+		Set("lockdown = excluded.lockdown").
+		Set("updated_at = excluded.updated_at").
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: upsert tenant settings: %w", err)
+	}
+	return nil
+}
+
+func (s *tenantStore) DeleteSettings(ctx context.Context, tenantID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	res, err := s.sdb.NewDelete((*tenantSettingsModel)(nil)).
+		Where("tenant_id = ?", tenantID).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: delete tenant settings: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: delete tenant settings rows: %w", err)
+	}
+	if rows == 0 {
+		return errNotFound("tenant settings")
+	}
+	return nil
+}
+
+func (s *tenantStore) Revision(ctx context.Context, tenantID string) (uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var rev int64
+	err := s.sdb.QueryRow(ctx, `SELECT revision FROM keysmith_tenant_revisions WHERE tenant_id = ?`, tenantID).Scan(&rev)
+	if err != nil {
+		if isNoRows(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("keysmith/sqlite: get tenant revision: %w", err)
+	}
+	return uint64(rev), nil //nolint:gosec // revisions only count up from zero
+}
+
+func (s *tenantStore) BumpRevision(ctx context.Context, tenantID string) (uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var rev int64
+	err := s.sdb.QueryRow(ctx, `
+		INSERT INTO keysmith_tenant_revisions (tenant_id, revision, updated_at) VALUES (?, 1, CURRENT_TIMESTAMP)
+		ON CONFLICT (tenant_id) DO UPDATE
+			SET revision = keysmith_tenant_revisions.revision + 1, updated_at = CURRENT_TIMESTAMP
+		RETURNING revision`, tenantID).Scan(&rev)
+	if err != nil {
+		return 0, fmt.Errorf("keysmith/sqlite: bump tenant revision: %w", err)
+	}
+	return uint64(rev), nil //nolint:gosec // revisions only count up from zero
+}
@@ -0,0 +1,47 @@
+//go:build integration
+
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/grove"
+	"github.com/xraph/grove/drivers/sqlitedriver"
+	_ "github.com/xraph/grove/drivers/sqlitedriver/sqlitemigrate"
+
+	"github.com/xraph/keysmith/store/sqlite"
+)
+
+func TestTenantRevision(t *testing.T) {
+	ctx := context.Background()
+	d := sqlitedriver.New()
+	require.NoError(t, d.Open(ctx, filepath.Join(t.TempDir(), "keysmith.db")))
+	db, err := grove.Open(d)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	s := sqlite.New(db)
+	require.NoError(t, s.Migrate(ctx))
+
+	rev, err := s.Tenants().Revision(ctx, "t1")
+	require.NoError(t, err)
+	assert.Zero(t, rev)
+
+	for want := uint64(1); want <= 3; want++ {
+		rev, err = s.Tenants().BumpRevision(ctx, "t1")
+		require.NoError(t, err)
+		assert.Equal(t, want, rev)
+	}
+	rev, err = s.Tenants().Revision(ctx, "t1")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), rev)
+
+	rev, err = s.Tenants().Revision(ctx, "t2")
+	require.NoError(t, err)
+	assert.Zero(t, rev)
+}
@@ -0,0 +1,226 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xraph/grove/driver"
+	"github.com/xraph/grove/drivers/sqlitedriver"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/transfer"
+)
+
+// pendingTransferConflict skips the insert of a transfer for a key that
+// already has a pending one, leaving no row affected.
+const pendingTransferConflict = "(key_id) WHERE state = 'pending' DO NOTHING"
+
+type transferStore struct {
+	sdb *sqlitedriver.SqliteDB
+}
+
+func (s *transferStore) Create(ctx context.Context, t *transfer.Transfer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	res, err := s.sdb.NewInsert(transferToModel(t)).OnConflict(pendingTransferConflict).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: create transfer: %w", err)
+	}
+	if rows, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("keysmith/sqlite: create transfer rows: %w", err)
+	} else if rows == 0 {
+		return transfer.ErrPendingTransfer
+	}
+	return nil
+}
+
+func (s *transferStore) Get(ctx context.Context, transferID id.TransferID) (*transfer.Transfer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m := new(transferModel)
+	err := s.sdb.NewSelect(m).Where("id = ?", transferID.String()).Scan(ctx)
+	if err != nil {
+		if isNoRows(err) {
+			return nil, errNotFound("transfer")
+		}
+		return nil, fmt.Errorf("keysmith/sqlite: get transfer: %w", err)
+	}
+	return transferFromModel(m)
+}
+
+func (s *transferStore) GetPending(ctx context.Context, keyID id.KeyID) (*transfer.Transfer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m := new(transferModel)
+	err := s.sdb.NewSelect(m).
+		Where("key_id = ?", keyID.String()).
+		Where("state = ?", string(transfer.StatePending)).
+		Scan(ctx)
+	if err != nil {
+		if isNoRows(err) {
+			return nil, errNotFound("transfer")
+		}
+		return nil, fmt.Errorf("keysmith/sqlite: get pending transfer: %w", err)
+	}
+	return transferFromModel(m)
+}
+
+func (s *transferStore) List(ctx context.Context, filter *transfer.ListFilter) ([]*transfer.Transfer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var models []transferModel
+	q := s.sdb.NewSelect(&models).OrderExpr("created_at DESC, id DESC")
+
+	if filter != nil {
+		if filter.TenantID != "" {
+			q = q.Where("(source_tenant_id = ? OR target_tenant_id = ?)", filter.TenantID, filter.TenantID)
+		}
+		if filter.AppID != "" {
+			q = q.Where("app_id = ?", filter.AppID)
+		}
+		if filter.KeyID != nil {
+			q = q.Where("key_id = ?", filter.KeyID.String())
+		}
+		if filter.State != "" {
+			q = q.Where("state = ?", string(filter.State))
+		}
+		if filter.ExpiresBefore != nil {
+			q = q.Where("expires_at < ?", filter.ExpiresBefore.UTC())
+		}
+		if filter.Limit > 0 {
+			q = q.Limit(filter.Limit)
+		}
+		if filter.Offset > 0 {
+			q = q.Offset(filter.Offset)
+		}
+	}
+
+	if err := q.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: list transfers: %w", err)
+	}
+
+	result := make([]*transfer.Transfer, 0, len(models))
+	for i := range models {
+		t, err := transferFromModel(&models[i])
+		if err != nil {
+			return nil, fmt.Errorf("keysmith/sqlite: convert transfer: %w", err)
+		}
+		result = append(result, t)
+	}
+	return result, nil
+}
+
+func (s *transferStore) Resolve(ctx context.Context, transferID id.TransferID, state transfer.State, by string, at time.Time) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	res, err := s.sdb.NewUpdate((*transferModel)(nil)).
+		Set("state = ?", string(state)).
+		Set("resolved_by = ?", by).
+		Set("resolved_at = ?", at.UTC()).
+		Where("id = ?", transferID.String()).
+		Where("state = ?", string(transfer.StatePending)).
+		Exec(ctx)
+	if err != nil {
+		return false, fmt.Errorf("keysmith/sqlite: resolve transfer: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("keysmith/sqlite: resolve transfer rows: %w", err)
+	}
+	return rows > 0, nil
+}
+
+func (s *transferStore) Accept(ctx context.Context, t *transfer.Transfer, remap *transfer.Remap) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	m := transferToModel(t)
+	tx, err := s.sdb.BeginTxQuery(ctx, &driver.TxOptions{})
+	if err != nil {
+		return false, fmt.Errorf("keysmith/sqlite: begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.NewUpdate((*transferModel)(nil)).
+		Set("state = ?", string(transfer.StateAccepted)).
+		Set("resolved_by = ?", m.ResolvedBy).
+		Set("resolved_at = ?", m.ResolvedAt).
+		Set("unmapped_scopes = ?", m.UnmappedScopes).
+		Set("unmapped_policy = ?", m.UnmappedPolicy).
+		Where("id = ?", m.ID).
+		Where("state = ?", string(transfer.StatePending)).
+		Exec(ctx)
+	if err != nil {
+		return false, fmt.Errorf("keysmith/sqlite: accept transfer: %w", err)
+	}
+	if rows, err := res.RowsAffected(); err != nil {
+		return false, fmt.Errorf("keysmith/sqlite: accept transfer rows: %w", err)
+	} else if rows == 0 {
+		return false, nil
+	}
+
+	var policyID *string
+	if remap.PolicyID != nil {
+		pid := remap.PolicyID.String()
+		policyID = &pid
+	}
+	updatedAt := time.Now().UTC()
+	if t.ResolvedAt != nil {
+		updatedAt = t.ResolvedAt.UTC()
+	}
+	res, err = tx.NewUpdate((*keyModel)(nil)).
+		Set("tenant_id = ?", m.TargetTenantID).
+		Set("policy_id = ?", policyID).
+		Set("pool_id = NULL").
+		Set("updated_at = ?", updatedAt).
+		Where("id = ?", m.KeyID).
+		Exec(ctx)
+	if err != nil {
+		return false, fmt.Errorf("keysmith/sqlite: move key: %w", err)
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return false, errNotFound("key")
+	}
+
+	if _, err := tx.NewRaw(`DELETE FROM keysmith_key_scopes WHERE key_id = ?`, m.KeyID).Exec(ctx); err != nil {
+		return false, fmt.Errorf("keysmith/sqlite: clear key scopes: %w", err)
+	}
+	for _, name := range remap.Scopes {
+		_, err := tx.NewRaw(`
+			INSERT INTO keysmith_key_scopes (key_id, scope_id)
+			SELECT ?, s.id FROM keysmith_scopes s
+			WHERE s.tenant_id = ? AND s.name = ?
+			ON CONFLICT DO NOTHING`, m.KeyID, m.TargetTenantID, name).Exec(ctx)
+		if err != nil {
+			return false, fmt.Errorf("keysmith/sqlite: remap scope %q: %w", name, err)
+		}
+	}
+	if _, err := tx.NewRaw(`DELETE FROM keysmith_key_group_members WHERE key_id = ?`, m.KeyID).Exec(ctx); err != nil {
+		return false, fmt.Errorf("keysmith/sqlite: remove group members: %w", err)
+	}
+	if m.RetagUsage {
+		for _, table := range []string{"keysmith_usage", "keysmith_usage_agg"} {
+			_, err := tx.NewRaw(`UPDATE `+table+` SET tenant_id = ? WHERE key_id = ?`, m.TargetTenantID, m.KeyID).Exec(ctx)
+			if err != nil {
+				return false, fmt.Errorf("keysmith/sqlite: retag %s: %w", table, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("keysmith/sqlite: commit transfer: %w", err)
+	}
+	return true, nil
+}
@@ -3,6 +3,7 @@ package sqlite
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/xraph/grove/driver"
@@ -17,6 +18,10 @@ type usageStore struct {
 }
 
 func (s *usageStore) Record(ctx context.Context, rec *usage.Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	m := usageToModel(rec)
 	_, err := s.sdb.NewInsert(m).Exec(ctx)
 	if err != nil {
@@ -26,6 +31,10 @@ func (s *usageStore) Record(ctx context.Context, rec *usage.Record) error {
 }
 
 func (s *usageStore) RecordBatch(ctx context.Context, recs []*usage.Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	if len(recs) == 0 {
 		return nil
 	}
@@ -48,6 +57,10 @@ func (s *usageStore) RecordBatch(ctx context.Context, recs []*usage.Record) erro
 }
 
 func (s *usageStore) Query(ctx context.Context, filter *usage.QueryFilter) ([]*usage.Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var models []usageModel
 	q := s.sdb.NewSelect(&models).OrderExpr("created_at DESC")
 
@@ -58,6 +71,12 @@ func (s *usageStore) Query(ctx context.Context, filter *usage.QueryFilter) ([]*u
 		if filter.TenantID != "" {
 			q = q.Where("tenant_id = ?", filter.TenantID)
 		}
+		if filter.AppID != "" {
+			q = q.Where("app_id = ?", filter.AppID)
+		}
+		if filter.Environment != "" {
+			q = q.Where("environment = ?", string(filter.Environment))
+		}
 		if filter.After != nil {
 			q = q.Where("created_at >= ?", *filter.After)
 		}
@@ -88,6 +107,10 @@ func (s *usageStore) Query(ctx context.Context, filter *usage.QueryFilter) ([]*u
 }
 
 func (s *usageStore) Aggregate(ctx context.Context, filter *usage.QueryFilter) ([]*usage.Aggregation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var models []usageAggModel
 	q := s.sdb.NewSelect(&models).OrderExpr("period_start DESC")
 
@@ -98,6 +121,13 @@ func (s *usageStore) Aggregate(ctx context.Context, filter *usage.QueryFilter) (
 		if filter.TenantID != "" {
 			q = q.Where("tenant_id = ?", filter.TenantID)
 		}
+		if filter.AppID != "" {
+			q = q.Where("app_id = ?", filter.AppID)
+		}
+		if filter.Environment != "" {
+			// Aggregates are per key, so a key's environment is theirs.
+			q = q.Where("key_id IN (SELECT id FROM keysmith_keys WHERE environment = ?)", string(filter.Environment))
+		}
 		if filter.Period != "" {
 			q = q.Where("period = ?", filter.Period)
 		}
@@ -131,6 +161,10 @@ func (s *usageStore) Aggregate(ctx context.Context, filter *usage.QueryFilter) (
 }
 
 func (s *usageStore) Count(ctx context.Context, filter *usage.QueryFilter) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
 	q := s.sdb.NewSelect((*usageModel)(nil))
 
 	if filter != nil {
@@ -140,6 +174,12 @@ func (s *usageStore) Count(ctx context.Context, filter *usage.QueryFilter) (int6
 		if filter.TenantID != "" {
 			q = q.Where("tenant_id = ?", filter.TenantID)
 		}
+		if filter.AppID != "" {
+			q = q.Where("app_id = ?", filter.AppID)
+		}
+		if filter.Environment != "" {
+			q = q.Where("environment = ?", string(filter.Environment))
+		}
 		if filter.After != nil {
 			q = q.Where("created_at >= ?", *filter.After)
 		}
@@ -156,6 +196,10 @@ func (s *usageStore) Count(ctx context.Context, filter *usage.QueryFilter) (int6
 }
 
 func (s *usageStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
 	res, err := s.sdb.NewDelete((*usageModel)(nil)).
 		Where("created_at < ?", before).
 		Exec(ctx)
@@ -169,9 +213,12 @@ func (s *usageStore) Purge(ctx context.Context, before time.Time) (int64, error)
 	return rows, nil
 }
 
-func (s *usageStore) DailyCount(ctx context.Context, keyID id.KeyID, date time.Time) (int64, error) {
-	dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
-	dayEnd := dayStart.Add(24 * time.Hour)
+func (s *usageStore) DailyCount(ctx context.Context, keyID id.KeyID, w usage.Window) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	dayStart, dayEnd := w.Start.UTC(), w.End.UTC()
 
 	q := s.sdb.NewSelect((*usageModel)(nil)).
 		Where("key_id = ?", keyID.String()).
@@ -185,9 +232,12 @@ func (s *usageStore) DailyCount(ctx context.Context, keyID id.KeyID, date time.T
 	return count, nil
 }
 
-func (s *usageStore) MonthlyCount(ctx context.Context, keyID id.KeyID, month time.Time) (int64, error) {
-	monthStart := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
-	monthEnd := monthStart.AddDate(0, 1, 0)
+func (s *usageStore) MonthlyCount(ctx context.Context, keyID id.KeyID, w usage.Window) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	monthStart, monthEnd := w.Start.UTC(), w.End.UTC()
 
 	q := s.sdb.NewSelect((*usageModel)(nil)).
 		Where("key_id = ?", keyID.String()).
@@ -200,3 +250,107 @@ func (s *usageStore) MonthlyCount(ctx context.Context, keyID id.KeyID, month tim
 	}
 	return count, nil
 }
+
+func (s *usageStore) UpsertEndpointActivity(ctx context.Context, acts []*usage.EndpointActivity) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if len(acts) == 0 {
+		return nil
+	}
+
+	tx, err := s.sdb.BeginTxQuery(ctx, &driver.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("keysmith/sqlite: begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, a := range acts {
+		_, err := tx.NewInsert(endpointSeenToModel(a)).
+			OnConflict("(key_id, endpoint, method) DO UPDATE").
+			Set("count = keysmith_key_endpoint_seen.count + excluded.count").
+			Set("last_seen_at = MAX(keysmith_key_endpoint_seen.last_seen_at, excluded.last_seen_at)").
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("keysmith/sqlite: upsert endpoint activity: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *usageStore) ListEndpointActivity(ctx context.Context, keyID id.KeyID) ([]*usage.EndpointActivity, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var models []endpointSeenModel
+	err := s.sdb.NewSelect(&models).
+		Where("key_id = ?", keyID.String()).
+		OrderExpr("last_seen_at DESC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("keysmith/sqlite: list endpoint activity: %w", err)
+	}
+
+	result := make([]*usage.EndpointActivity, 0, len(models))
+	for i := range models {
+		a, err := endpointSeenFromModel(&models[i])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, a)
+	}
+	return result, nil
+}
+
+func (s *usageStore) UpdateUsageIdentifiers(ctx context.Context, tenantID string, m *usage.IdentifierMatcher, limit int) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	query, args := identifierUpdateSQL(tenantID, m, limit)
+	res, err := s.sdb.NewRaw(query, args...).Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("keysmith/sqlite: update usage identifiers: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("keysmith/sqlite: update usage identifiers rows: %w", err)
+	}
+	return rows, nil
+}
+
+// identifierUpdateSQL builds the erasure UPDATE. The batch is picked by a
+// subquery, since UPDATE ... LIMIT needs a compile-time SQLite option.
+func identifierUpdateSQL(tenantID string, m *usage.IdentifierMatcher, limit int) (string, []any) {
+	var sets, matches []string
+	var setArgs, matchArgs []any
+	for _, c := range []struct{ column, value string }{
+		{"ip_address", m.IPAddress},
+		{"user_agent", m.UserAgent},
+	} {
+		if c.value == "" {
+			continue
+		}
+		sets = append(sets, fmt.Sprintf("%[1]s = CASE WHEN %[1]s = ? THEN ? ELSE %[1]s END", c.column))
+		setArgs = append(setArgs, c.value, usage.Redacted)
+		matches = append(matches, c.column+" = ?")
+		matchArgs = append(matchArgs, c.value)
+	}
+	args := append(setArgs, tenantID)
+	args = append(args, matchArgs...)
+	where := "tenant_id = ? AND (" + strings.Join(matches, " OR ") + ")"
+	if m.Before != nil {
+		where += " AND created_at < ?"
+		args = append(args, *m.Before)
+	}
+	query := "UPDATE keysmith_usage SET " + strings.Join(sets, ", ") +
+		" WHERE id IN (SELECT id FROM keysmith_usage WHERE " + where + " ORDER BY created_at"
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+	return query + ")", args
+}
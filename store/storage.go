@@ -0,0 +1,66 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/xraph/keysmith/key"
+)
+
+// StorageReporter is implemented by stores that can attribute their rows to
+// tenants, for billing and capacity planning. It is optional; the engine
+// checks for it with a type assertion.
+type StorageReporter interface {
+	// TenantStorage counts the rows each tenant holds. An empty tenantID
+	// reports every tenant with at least one row, ordered by tenant ID;
+	// otherwise only that tenant is reported, and a tenant without rows
+	// yields no entries.
+	TenantStorage(ctx context.Context, tenantID string) ([]*TenantStorage, error)
+}
+
+// TenantStorage counts the rows one tenant holds, across all of its apps.
+type TenantStorage struct {
+	TenantID string `json:"tenant_id"`
+
+	// Keys counts the tenant's keys and KeysByState breaks them down by
+	// state; states without keys are omitted.
+	Keys        int64               `json:"keys"`
+	KeysByState map[key.State]int64 `json:"keys_by_state"`
+
+	// Policies counts named and inline policies.
+	Policies  int64 `json:"policies"`
+	Scopes    int64 `json:"scopes"`
+	Rotations int64 `json:"rotations"`
+
+	// UsageRows counts the tenant's usage records and UsageBytes estimates
+	// the space they take. The estimate is backend-specific and leaves out
+	// indexes: PostgreSQL samples pg_column_size, SQLite sums the lengths
+	// of the stored values, MongoDB sums $bsonSize, and the memory store
+	// sums the lengths of each record's strings and encoded metadata plus
+	// a fixed size for its other fields.
+	UsageRows  int64 `json:"usage_rows"`
+	UsageBytes int64 `json:"usage_bytes"`
+}
+
+// StorageReport attributes a store's rows to its tenants.
+type StorageReport struct {
+	// Tenants holds one entry per tenant, ordered by tenant ID.
+	Tenants []*TenantStorage `json:"tenants"`
+
+	// Total sums every tenant's counts; its TenantID is empty.
+	Total TenantStorage `json:"total"`
+
+	// TopUsage lists the tenants with the most usage records, most first,
+	// up to the report's limit.
+	TopUsage []TenantUsage `json:"top_usage"`
+
+	GeneratedAt time.Time     `json:"generated_at"`
+	Duration    time.Duration `json:"duration"`
+}
+
+// TenantUsage is one tenant's usage volume in a StorageReport.
+type TenantUsage struct {
+	TenantID   string `json:"tenant_id"`
+	UsageRows  int64  `json:"usage_rows"`
+	UsageBytes int64  `json:"usage_bytes"`
+}
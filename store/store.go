@@ -4,10 +4,18 @@ package store
 import (
 	"context"
 
+	"github.com/xraph/keysmith/capture"
+	"github.com/xraph/keysmith/group"
 	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keyevent"
 	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/quotapool"
+	"github.com/xraph/keysmith/revocation"
 	"github.com/xraph/keysmith/rotation"
 	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/tenant"
+	"github.com/xraph/keysmith/tombstone"
+	"github.com/xraph/keysmith/transfer"
 	"github.com/xraph/keysmith/usage"
 )
 
@@ -29,6 +37,30 @@ type Store interface {
 	// Scopes returns the scope store.
 	Scopes() scope.Store
 
+	// Tenants returns the tenant settings store.
+	Tenants() tenant.Store
+
+	// Revocations returns the revocation feed store.
+	Revocations() revocation.Store
+
+	// Captures returns the debug capture store.
+	Captures() capture.Store
+
+	// Groups returns the key group store.
+	Groups() group.Store
+
+	// KeyEvents returns the key event history store.
+	KeyEvents() keyevent.Store
+
+	// Transfers returns the key transfer store.
+	Transfers() transfer.Store
+
+	// Tombstones returns the hash tombstone store.
+	Tombstones() tombstone.Store
+
+	// QuotaPools returns the quota pool store.
+	QuotaPools() quotapool.Store
+
 	// Migrate runs database migrations.
 	Migrate(ctx context.Context) error
 
@@ -38,3 +70,28 @@ type Store interface {
 	// Close releases database resources.
 	Close() error
 }
+
+// Unwrapper is implemented by stores that wrap another store, such as the
+// encrypting store in store/encrypted. The engine looks through wrappers
+// for the optional interfaces it checks for, such as [Maintainer].
+type Unwrapper interface {
+	// Unwrap returns the wrapped store.
+	Unwrap() Store
+}
+
+// As returns the first store in the wrapper chain starting at s that
+// implements T, and whether there is one.
+func As[T any](s Store) (T, bool) {
+	for s != nil {
+		if t, ok := s.(T); ok {
+			return t, true
+		}
+		u, ok := s.(Unwrapper)
+		if !ok {
+			break
+		}
+		s = u.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
@@ -0,0 +1,86 @@
+package storetest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/store"
+)
+
+// clock is a settable time source for engines under test.
+type clock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+func testEngineRotationGrace(t *testing.T, s store.Store) { CheckEngineRotationGrace(t, s, "t1") }
+
+// CheckEngineRotationGrace runs an engine on s through a rotation with a
+// grace period: the old secret validates within it, is refused with
+// ErrKeyRevoked after it on every attempt, and stops matching the key once
+// CleanupGraceExpired retires it. Backends whose tests share a database call
+// it with a tenant of their own.
+func CheckEngineRotationGrace(t *testing.T, s store.Store, tenantID string) {
+	t.Helper()
+	c := &clock{t: time.Now()}
+	eng, err := keysmith.NewEngine(keysmith.WithStore(s), keysmith.WithClock(c.Now))
+	require.NoError(t, err)
+	tctx := keysmith.WithTenant(context.Background(), "app_conformance", tenantID)
+
+	original, err := eng.CreateKey(tctx, &keysmith.CreateKeyInput{Name: "Grace", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	oldHash := original.Key.KeyHash
+	rotated, err := eng.RotateKey(tctx, original.Key.ID, rotation.ReasonManual)
+	require.NoError(t, err)
+
+	c.Advance(23 * time.Hour)
+	vr, err := eng.ValidateKey(tctx, original.RawKey)
+	require.NoError(t, err, "within grace")
+	assert.True(t, vr.UsingRotatedKey)
+	assert.Equal(t, key.StateRotated, vr.Key.State)
+	reports, err := eng.BulkValidateHashes(tctx, []string{oldHash})
+	require.NoError(t, err)
+	assert.Equal(t, keysmith.HashFound, reports[0].Status, "the old hash is found while it validates")
+
+	c.Advance(2 * time.Hour)
+	for range 3 {
+		_, err = eng.ValidateKey(tctx, original.RawKey)
+		assert.ErrorIs(t, err, keysmith.ErrKeyRevoked, "after grace")
+	}
+
+	res, err := eng.CleanupGraceExpired(tctx)
+	require.NoError(t, err)
+	assert.Contains(t, res.KeyIDs, original.Key.ID)
+	_, err = eng.ValidateKey(tctx, original.RawKey)
+	assert.ErrorIs(t, err, keysmith.ErrInvalidKey, "the retired hash no longer matches")
+	reports, err = eng.BulkValidateHashes(tctx, []string{oldHash})
+	require.NoError(t, err)
+	assert.Equal(t, keysmith.HashNotFound, reports[0].Status)
+	res, err = eng.CleanupGraceExpired(tctx)
+	require.NoError(t, err)
+	assert.NotContains(t, res.KeyIDs, original.Key.ID, "a retired hash is not retired again")
+
+	vr, err = eng.ValidateKey(tctx, rotated.RawKey)
+	require.NoError(t, err, "the new secret is unaffected")
+	assert.Equal(t, key.StateActive, vr.Key.State)
+}
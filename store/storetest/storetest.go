@@ -0,0 +1,1950 @@
+// Package storetest is a conformance suite for store.Store implementations.
+// It checks the behavior the engine relies on, most of all key lookup by
+// hash, so that a new backend or a wrapping store can be verified against
+// the same expectations as the built-in ones:
+//
+//	func TestConformance(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) store.Store { return mystore.New(...) })
+//	}
+//
+// open is called once per subtest and must return an empty, migrated store.
+package storetest
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith/group"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/keyevent"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/quotapool"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/store"
+	"github.com/xraph/keysmith/tenant"
+	"github.com/xraph/keysmith/tombstone"
+	"github.com/xraph/keysmith/transfer"
+	"github.com/xraph/keysmith/usage"
+)
+
+// Run runs the conformance suite against stores returned by open.
+func Run(t *testing.T, open func(t *testing.T) store.Store) {
+	t.Helper()
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s store.Store)
+	}{
+		{"KeyRoundTrip", testKeyRoundTrip},
+		{"GetByHash", testGetByHash},
+		{"GetByHashLegacyForms", testGetByHashLegacyForms},
+		{"GetByHashes", testGetByHashes},
+		{"UpdateHash", testUpdateHash},
+		{"HashVersions", testHashVersions},
+		{"UpdateHashRetiresVersions", testUpdateHashRetiresVersions},
+		{"RotationGraceHash", testRotationGraceHash},
+		{"EngineRotationGrace", testEngineRotationGrace},
+		{"AddHashErrors", testAddHashErrors},
+		{"Delete", testDelete},
+		{"ListAndIterate", testListAndIterate},
+		{"ListLimits", testListLimits},
+		{"KeyOverview", testKeyOverview},
+		{"KeySearch", testKeySearch},
+		{"ListByPrefixHint", testListByPrefixHint},
+		{"Labels", testLabels},
+		{"LabelSelectors", testLabelSelectors},
+		{"UpdateStateIf", testUpdateStateIf},
+		{"RotationHashes", testRotationHashes},
+		{"RotationFilters", testRotationFilters},
+		{"RotationCountByReason", testRotationCountByReason},
+		{"TenantRevisions", testTenantRevisions},
+		{"Groups", testGroups},
+		{"ExternalRefs", testExternalRefs},
+		{"KeyNames", testKeyNames},
+		{"TenantLockdown", testTenantLockdown},
+		{"InlinePolicies", testInlinePolicies},
+		{"ScopeAssignment", testScopeAssignment},
+		{"UsageEnvironments", testUsageEnvironments},
+		{"KeyEvents", testKeyEvents},
+		{"KeyTransfers", testKeyTransfers},
+		{"HashTombstones", testHashTombstones},
+		{"QuotaPools", testQuotaPools},
+		{"TenantStorage", testTenantStorage},
+		{"Locks", testLocks},
+		{"InstallID", testInstallID},
+		{"ContextCancellation", testContextCancellation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) { tt.fn(t, open(t)) })
+	}
+}
+
+// Hash returns the canonical SHA-256 hash of raw, as the default hasher
+// stores it.
+func Hash(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return key.FormatHash(key.HashAlgSHA256, sum[:])
+}
+
+// NewKey returns an active key in tenant with the hash of raw.
+func NewKey(tenant, raw string) *key.Key {
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	return &key.Key{
+		ID:          id.NewKeyID(),
+		TenantID:    tenant,
+		AppID:       "app_conformance",
+		Name:        "Conformance " + raw,
+		Prefix:      "sk",
+		Hint:        raw[len(raw)-4:],
+		KeyHash:     Hash(raw),
+		Environment: key.EnvTest,
+		State:       key.StateActive,
+		Metadata:    map[string]any{"plan": "pro"},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+func ctx() context.Context { return context.Background() }
+
+func create(t *testing.T, s store.Store, keys ...*key.Key) {
+	t.Helper()
+	for _, k := range keys {
+		require.NoError(t, s.Keys().Create(ctx(), k))
+	}
+}
+
+func ids(keys []*key.Key) []string {
+	out := make([]string, len(keys))
+	for i, k := range keys {
+		out[i] = k.ID.String()
+	}
+	return out
+}
+
+func testKeyRoundTrip(t *testing.T, s store.Store) {
+	k := NewKey("t1", "sk_test_roundtrip0001")
+	create(t, s, k)
+
+	got, err := s.Keys().Get(ctx(), k.ID)
+	require.NoError(t, err)
+	assert.Equal(t, k.ID.String(), got.ID.String())
+	assert.Equal(t, k.KeyHash, got.KeyHash)
+	assert.Equal(t, k.Name, got.Name)
+	assert.Equal(t, k.TenantID, got.TenantID)
+	assert.Equal(t, k.State, got.State)
+	assert.Equal(t, map[string]any{"plan": "pro"}, got.Metadata)
+	assert.True(t, k.CreatedAt.Equal(got.CreatedAt))
+}
+
+func testGetByHash(t *testing.T, s store.Store) {
+	k := NewKey("t1", "sk_test_byhash000001")
+	create(t, s, k, NewKey("t1", "sk_test_byhash000002"))
+
+	got, err := s.Keys().GetByHash(ctx(), k.KeyHash)
+	require.NoError(t, err)
+	assert.Equal(t, k.ID.String(), got.ID.String())
+	assert.Equal(t, k.KeyHash, got.KeyHash)
+
+	_, err = s.Keys().GetByHash(ctx(), Hash("sk_test_missing00001"))
+	assert.Error(t, err)
+}
+
+func testGetByHashLegacyForms(t *testing.T, s store.Store) {
+	k := NewKey("t1", "sk_test_legacy000001")
+	canonical := k.KeyHash
+	_, digest := key.SplitHash(canonical)
+	k.KeyHash = digest // stored before algorithm prefixes
+	create(t, s, k)
+
+	for _, h := range []string{canonical, digest} {
+		got, err := s.Keys().GetByHash(ctx(), h)
+		require.NoError(t, err, h)
+		assert.Equal(t, k.ID.String(), got.ID.String(), h)
+	}
+}
+
+func testGetByHashes(t *testing.T, s store.Store) {
+	a := NewKey("t1", "sk_test_batch0000001")
+	b := NewKey("t2", "sk_test_batch0000002")
+	create(t, s, a, b, NewKey("t1", "sk_test_batch0000003"))
+
+	keys, err := s.Keys().GetByHashes(ctx(), []string{a.KeyHash, b.KeyHash, Hash("sk_test_missing00001")})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{a.ID.String(), b.ID.String()}, ids(keys))
+	for _, k := range keys {
+		assert.Contains(t, []string{a.KeyHash, b.KeyHash}, k.KeyHash)
+	}
+
+	keys, err = s.Keys().GetByHashes(ctx(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func testUpdateHash(t *testing.T, s store.Store) {
+	k := NewKey("t1", "sk_test_update000001")
+	create(t, s, k)
+	oldHash := k.KeyHash
+
+	k.KeyHash = Hash("sk_test_update000002")
+	k.Name = "Renamed"
+	require.NoError(t, s.Keys().Update(ctx(), k))
+
+	got, err := s.Keys().GetByHash(ctx(), k.KeyHash)
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", got.Name)
+	assert.Equal(t, k.KeyHash, got.KeyHash)
+	_, err = s.Keys().GetByHash(ctx(), oldHash)
+	assert.Error(t, err, "the replaced hash no longer matches")
+
+	require.NoError(t, s.Keys().UpdateState(ctx(), k.ID, key.StateSuspended))
+	got, err = s.Keys().GetByHash(ctx(), k.KeyHash)
+	require.NoError(t, err)
+	assert.Equal(t, key.StateSuspended, got.State)
+	assert.Equal(t, k.KeyHash, got.KeyHash)
+}
+
+// hmacHash returns an HMAC-SHA256 style hash of raw, standing in for the
+// output of a second hasher.
+func hmacHash(raw string) string {
+	sum := sha256.Sum256([]byte("hmac:" + raw))
+	return key.FormatHash(key.HashAlgHMACSHA256, sum[:])
+}
+
+func testHashVersions(t *testing.T, s store.Store) {
+	k := NewKey("t1", "sk_test_versions0001")
+	create(t, s, k)
+
+	versions, err := s.Keys().ListHashes(ctx(), k.ID)
+	require.NoError(t, err)
+	require.Len(t, versions, 1, "Create stores the first version")
+	assert.True(t, versions[0].Active)
+	assert.Equal(t, key.HashAlgSHA256, versions[0].Scheme)
+
+	extra := hmacHash("sk_test_versions0001")
+	require.NoError(t, s.Keys().AddHash(ctx(), &key.HashVersion{KeyID: k.ID, Hash: extra}))
+
+	for _, h := range []string{k.KeyHash, extra} {
+		got, err := s.Keys().GetByHash(ctx(), h)
+		require.NoError(t, err)
+		assert.Equal(t, k.ID.String(), got.ID.String())
+	}
+	found, err := s.Keys().GetByHashes(ctx(), []string{extra})
+	require.NoError(t, err)
+	assert.Equal(t, []string{k.ID.String()}, ids(found))
+
+	versions, err = s.Keys().ListHashes(ctx(), k.ID)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, key.HashAlgSHA256, versions[0].Scheme, "oldest first")
+	assert.Equal(t, key.HashAlgHMACSHA256, versions[1].Scheme)
+	assert.True(t, versions[0].Active)
+	assert.True(t, versions[1].Active)
+
+	require.NoError(t, s.Keys().DeactivateHash(ctx(), k.ID, extra))
+	_, err = s.Keys().GetByHash(ctx(), extra)
+	assert.Error(t, err, "an inactive version does not match")
+	require.NoError(t, s.Keys().DeactivateHash(ctx(), k.ID, k.KeyHash))
+	_, err = s.Keys().GetByHash(ctx(), k.KeyHash)
+	require.NoError(t, err, "the current hash stays active")
+
+	require.NoError(t, s.Keys().AddHash(ctx(), &key.HashVersion{KeyID: k.ID, Hash: extra}))
+	_, err = s.Keys().GetByHash(ctx(), extra)
+	require.NoError(t, err, "adding the hash again reactivates it")
+	versions, err = s.Keys().ListHashes(ctx(), k.ID)
+	require.NoError(t, err)
+	assert.Len(t, versions, 2)
+
+	require.NoError(t, s.Keys().Delete(ctx(), k.ID))
+	versions, err = s.Keys().ListHashes(ctx(), k.ID)
+	require.NoError(t, err)
+	assert.Empty(t, versions, "Delete removes the versions")
+}
+
+func testUpdateHashRetiresVersions(t *testing.T, s store.Store) {
+	k := NewKey("t1", "sk_test_retire000001")
+	create(t, s, k)
+	oldHash := k.KeyHash
+	extra := hmacHash("sk_test_retire000001")
+	require.NoError(t, s.Keys().AddHash(ctx(), &key.HashVersion{KeyID: k.ID, Hash: extra}))
+
+	k.KeyHash = Hash("sk_test_retire000002")
+	require.NoError(t, s.Keys().Update(ctx(), k))
+
+	for _, h := range []string{oldHash, extra} {
+		_, err := s.Keys().GetByHash(ctx(), h)
+		assert.Error(t, err, "versions of the replaced secret retire")
+	}
+	got, err := s.Keys().GetByHash(ctx(), k.KeyHash)
+	require.NoError(t, err)
+	assert.Equal(t, k.ID.String(), got.ID.String())
+
+	versions, err := s.Keys().ListHashes(ctx(), k.ID)
+	require.NoError(t, err)
+	require.Len(t, versions, 3)
+	var active int
+	for _, v := range versions {
+		if v.Active {
+			active++
+		}
+	}
+	assert.Equal(t, 1, active)
+}
+
+func testRotationGraceHash(t *testing.T, s store.Store) { CheckRotationGraceHash(t, s, "t1") }
+
+// CheckRotationGraceHash follows the engine's rotation of a key with a
+// grace period: the replaced hash is added back after Update, so that it
+// matches the key through the grace period along with the new one, and is
+// deactivated when the period ends. Backends whose tests share a database
+// call it with a tenant of their own.
+func CheckRotationGraceHash(t *testing.T, s store.Store, tenantID string) {
+	t.Helper()
+	k := NewKey(tenantID, "sk_test_"+tenantID+"_grace1")
+	require.NoError(t, s.Keys().Create(ctx(), k))
+	t.Cleanup(func() { _ = s.Keys().Delete(context.Background(), k.ID) })
+	oldHash := k.KeyHash
+
+	k.KeyHash = Hash("sk_test_" + tenantID + "_grace2")
+	require.NoError(t, s.Keys().Update(ctx(), k))
+	require.NoError(t, s.Keys().AddHash(ctx(), &key.HashVersion{KeyID: k.ID, Hash: oldHash, Active: true}))
+	for _, h := range []string{oldHash, k.KeyHash} {
+		got, err := s.Keys().GetByHash(ctx(), h)
+		require.NoError(t, err)
+		assert.Equal(t, k.ID.String(), got.ID.String())
+		assert.Equal(t, k.KeyHash, got.KeyHash, "the key holds its current hash")
+	}
+
+	require.NoError(t, s.Keys().DeactivateHash(ctx(), k.ID, oldHash))
+	_, err := s.Keys().GetByHash(ctx(), oldHash)
+	assert.Error(t, err, "the replaced hash retires after grace")
+	_, err = s.Keys().GetByHash(ctx(), k.KeyHash)
+	require.NoError(t, err)
+}
+
+func testAddHashErrors(t *testing.T, s store.Store) {
+	a := NewKey("t1", "sk_test_addhash00001")
+	b := NewKey("t1", "sk_test_addhash00002")
+	create(t, s, a, b)
+
+	err := s.Keys().AddHash(ctx(), &key.HashVersion{KeyID: id.NewKeyID(), Hash: hmacHash("sk_test_addhash00003")})
+	assert.Error(t, err, "the key must exist")
+
+	err = s.Keys().AddHash(ctx(), &key.HashVersion{KeyID: a.ID, Hash: b.KeyHash})
+	assert.ErrorIs(t, err, key.ErrHashInUse)
+	got, err := s.Keys().GetByHash(ctx(), b.KeyHash)
+	require.NoError(t, err)
+	assert.Equal(t, b.ID.String(), got.ID.String())
+}
+
+func testDelete(t *testing.T, s store.Store) {
+	k := NewKey("t1", "sk_test_delete000001")
+	create(t, s, k)
+	require.NoError(t, s.Keys().Delete(ctx(), k.ID))
+
+	_, err := s.Keys().Get(ctx(), k.ID)
+	assert.Error(t, err)
+	_, err = s.Keys().GetByHash(ctx(), k.KeyHash)
+	assert.Error(t, err)
+}
+
+func testListAndIterate(t *testing.T, s store.Store) {
+	a := NewKey("t1", "sk_test_list00000001")
+	b := NewKey("t1", "sk_test_list00000002")
+	create(t, s, a, b, NewKey("t2", "sk_test_list00000003"))
+
+	filter := &key.ListFilter{TenantID: "t1"}
+	keys, err := s.Keys().List(ctx(), filter)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{a.ID.String(), b.ID.String()}, ids(keys))
+	for _, k := range keys {
+		assert.Contains(t, []string{a.KeyHash, b.KeyHash}, k.KeyHash)
+	}
+
+	n, err := s.Keys().Count(ctx(), filter)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+
+	var visited []*key.Key
+	require.NoError(t, s.Keys().Iterate(ctx(), filter, func(k *key.Key) error {
+		visited = append(visited, k)
+		return nil
+	}))
+	assert.ElementsMatch(t, []string{a.ID.String(), b.ID.String()}, ids(visited))
+	for _, k := range visited {
+		assert.Contains(t, []string{a.KeyHash, b.KeyHash}, k.KeyHash)
+	}
+}
+
+func testListLimits(t *testing.T, s store.Store) { CheckListLimits(t, s, "t1") }
+
+// listLimitFixtures is how many keys and scopes CheckListLimits creates.
+const listLimitFixtures = 5
+
+// CheckListLimits creates listLimitFixtures keys and scopes in tenant and
+// checks how List treats the limits the engine passes it: a positive Limit
+// caps the page, including one at or past the number of rows, while zero
+// and negative limits return every row. Count ignores Limit and Offset, so
+// a clamped page never changes the total. Backends whose tests share a
+// database call it with a tenant of their own.
+func CheckListLimits(t *testing.T, s store.Store, tenant string) {
+	t.Helper()
+	const n = listLimitFixtures
+	for i := range n {
+		create(t, s, NewKey(tenant, fmt.Sprintf("sk_test_%s_limit%04d", tenant, i)))
+		require.NoError(t, s.Scopes().Create(ctx(), &scope.Scope{
+			ID:        id.NewScopeID(),
+			TenantID:  tenant,
+			AppID:     "app_conformance",
+			Name:      fmt.Sprintf("limit:%d", i),
+			CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+		}))
+	}
+
+	for _, tt := range []struct {
+		name   string
+		limit  int
+		offset int
+		want   int
+	}{
+		{"Smaller", 2, 0, 2},
+		{"Exact", n, 0, n},
+		{"OnePast", n + 1, 0, n},
+		{"Zero", 0, 0, n},
+		{"Negative", -1, 0, n},
+		{"OffsetShortPage", 2, n - 1, 1},
+		{"OffsetPastEnd", 2, n, 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			kf := &key.ListFilter{TenantID: tenant, Limit: tt.limit, Offset: tt.offset}
+			keys, err := s.Keys().List(ctx(), kf)
+			require.NoError(t, err)
+			assert.Len(t, keys, tt.want, "keys")
+
+			count, err := s.Keys().Count(ctx(), kf)
+			require.NoError(t, err)
+			assert.EqualValues(t, n, count, "Count ignores Limit and Offset")
+
+			scopes, err := s.Scopes().List(ctx(), &scope.ListFilter{TenantID: tenant, Limit: tt.limit, Offset: tt.offset})
+			require.NoError(t, err)
+			assert.Len(t, scopes, tt.want, "scopes")
+		})
+	}
+}
+
+func testKeyOverview(t *testing.T, s store.Store) { CheckKeyOverview(t, s, "t1") }
+
+// CheckKeyOverview creates a fixed set of keys in tenant, one of them in
+// another app, and one more in a second tenant, and checks the counts of
+// Keys().Overview against them. Backends whose tests share a database call
+// it with a tenant of their own, so every backend is held to the same
+// fixtures without an empty store.
+func CheckKeyOverview(t *testing.T, s store.Store, tenant string) {
+	t.Helper()
+	const day = 24 * time.Hour
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	at := func(d time.Duration) *time.Time {
+		v := now.Add(d)
+		return &v
+	}
+	fixture := func(n int, state key.State, env key.Environment, created time.Duration, expires, used *time.Time) *key.Key {
+		k := NewKey(tenant, fmt.Sprintf("sk_test_%s_overview%04d", tenant, n))
+		k.State, k.Environment = state, env
+		k.CreatedAt, k.UpdatedAt = now.Add(created), now.Add(created)
+		k.ExpiresAt, k.LastUsedAt = expires, used
+		return k
+	}
+	other := fixture(8, key.StateActive, key.EnvTest, -time.Hour, nil, nil)
+	other.AppID = "app_other"
+	elsewhere := fixture(9, key.StateActive, key.EnvLive, -time.Hour, at(day), nil)
+	elsewhere.TenantID = tenant + "_other"
+	keys := []*key.Key{
+		fixture(1, key.StateActive, key.EnvLive, -day, at(3*day), nil),
+		fixture(2, key.StateActive, key.EnvTest, -40*day, at(20*day), at(-time.Hour)),
+		// On both window bounds: expiring within 7 days, not created within 30.
+		fixture(3, key.StateActive, key.EnvTest, -30*day, at(7*day), at(-time.Hour)),
+		fixture(4, key.StateRevoked, key.EnvLive, -2*day, at(2*day), nil),
+		// Past its expiry but not yet swept.
+		fixture(5, key.StateActive, key.EnvLive, -100*day, at(-time.Hour), nil),
+		fixture(6, key.StateSuspended, key.EnvStaging, -60*day, nil, nil),
+		other,
+		elsewhere,
+	}
+	create(t, s, keys...)
+	t.Cleanup(func() {
+		for _, k := range keys {
+			_ = s.Keys().Delete(ctx(), k.ID)
+		}
+	})
+
+	q := &key.OverviewQuery{TenantID: tenant, Now: now, ExpiringWithin: []time.Duration{7 * day, 30 * day, 90 * day}, CreatedWithin: 30 * day}
+	got, err := s.Keys().Overview(ctx(), q)
+	require.NoError(t, err)
+	assert.Equal(t, &key.Overview{
+		Total:         7,
+		ByState:       map[key.State]int64{key.StateActive: 5, key.StateRevoked: 1, key.StateSuspended: 1},
+		ByEnvironment: map[key.Environment]int64{key.EnvLive: 3, key.EnvTest: 3, key.EnvStaging: 1},
+		Expiring: []key.WindowCount{
+			{Within: 7 * day, Count: 2},
+			{Within: 30 * day, Count: 3},
+			{Within: 90 * day, Count: 3},
+		},
+		NeverUsed:       3,
+		CreatedRecently: key.WindowCount{Within: 30 * day, Count: 3},
+	}, got)
+
+	q.AppID = "app_conformance"
+	got, err = s.Keys().Overview(ctx(), q)
+	require.NoError(t, err)
+	assert.EqualValues(t, 6, got.Total)
+	assert.Equal(t, map[key.Environment]int64{key.EnvLive: 3, key.EnvTest: 2, key.EnvStaging: 1}, got.ByEnvironment)
+	assert.EqualValues(t, 2, got.NeverUsed)
+	assert.EqualValues(t, 2, got.CreatedRecently.Count)
+
+	empty := &key.OverviewQuery{TenantID: tenant + "_none", Now: now, ExpiringWithin: []time.Duration{day}}
+	got, err = s.Keys().Overview(ctx(), empty)
+	require.NoError(t, err)
+	assert.Equal(t, key.NewOverview(empty), got)
+}
+
+func testKeySearch(t *testing.T, s store.Store) { CheckKeySearch(t, s, "t1") }
+
+// CheckKeySearch creates a fixed set of keys in tenant, one of them in
+// another app, and one more in a second tenant, and checks that
+// Keys().Search returns the same matches, fields, and order for each of a
+// set of fixture queries. Every backend is held to these results whatever
+// full-text index it uses to find candidates.
+func CheckKeySearch(t *testing.T, s store.Store, tenant string) {
+	t.Helper()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	fixture := func(n int, name, description string, metadata map[string]any) *key.Key {
+		k := NewKey(tenant, fmt.Sprintf("sk_test_%s_search%04d", tenant, n))
+		k.Name, k.Description, k.Metadata = name, description, metadata
+		k.CreatedAt = now.Add(-time.Duration(n) * time.Hour)
+		k.UpdatedAt = k.CreatedAt
+		return k
+	}
+	ios := fixture(1, "Acme Mobile iOS", "Production key for the acme-mobile app",
+		map[string]any{"customer": "Acme Corp", "ticket": 4521})
+	billing := fixture(2, "Billing worker", "Charges cards for ACME", map[string]any{"team": "payments"})
+	analytics := fixture(3, "Mobile analytics", "",
+		map[string]any{"notes": "Rotated after the acme-mobile incident, see the runbook for details on how to rotate it again"})
+	dashboard := fixture(4, "Internal dashboard", "ops", map[string]any{
+		"tags":  []any{"blue", "green"},
+		"owner": map[string]any{"team": "platform"},
+	})
+	android := fixture(5, "Acme Mobile Android", "", nil)
+	android.AppID = "app_other"
+	elsewhere := fixture(6, "Acme Mobile staging", "", nil)
+	elsewhere.TenantID = tenant + "_other"
+	keys := []*key.Key{ios, billing, analytics, dashboard, android, elsewhere}
+	create(t, s, keys...)
+	t.Cleanup(func() {
+		for _, k := range keys {
+			_ = s.Keys().Delete(ctx(), k.ID)
+		}
+	})
+
+	type result struct {
+		id     id.KeyID
+		fields []string
+		score  int
+	}
+	search := func(q string, app string, limit int) []result {
+		t.Helper()
+		got, err := s.Keys().Search(ctx(), &key.SearchQuery{TenantID: tenant, AppID: app, Terms: key.ParseSearchTerms(q), Limit: limit})
+		require.NoError(t, err, q)
+		out := make([]result, len(got))
+		for i, m := range got {
+			out[i] = result{m.Key.ID, m.Fields, m.Score}
+		}
+		return out
+	}
+
+	tests := []struct {
+		query string
+		app   string
+		limit int
+		want  []result
+	}{
+		{"acme-mobile", "", 0, []result{
+			{ios.ID, []string{"name", "description"}, 5},
+			{android.ID, []string{"name"}, 3},
+			{analytics.ID, []string{"metadata.notes"}, 1},
+		}},
+		{`"Mobile Acme"`, "", 0, []result{}},
+		{"acme payments", "", 0, []result{{billing.ID, []string{"description", "metadata.team"}, 3}}},
+		{"ACME", "", 2, []result{
+			{ios.ID, []string{"name", "description", "metadata.customer"}, 6},
+			{android.ID, []string{"name"}, 3},
+		}},
+		{"acme", "", 0, []result{
+			{ios.ID, []string{"name", "description", "metadata.customer"}, 6},
+			{android.ID, []string{"name"}, 3},
+			{billing.ID, []string{"description"}, 2},
+			{analytics.ID, []string{"metadata.notes"}, 1},
+		}},
+		{"green", "", 0, []result{{dashboard.ID, []string{"metadata.tags"}, 1}}},
+		{"4521", "", 0, []result{{ios.ID, []string{"metadata.ticket"}, 1}}},
+		{"acm", "", 0, []result{}},
+		{"platform", "", 0, []result{}},
+		{"staging", "", 0, []result{}},
+		{"acme-mobile", "app_conformance", 0, []result{
+			{ios.ID, []string{"name", "description"}, 5},
+			{analytics.ID, []string{"metadata.notes"}, 1},
+		}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, search(tt.query, tt.app, tt.limit), "query %q in app %q", tt.query, tt.app)
+	}
+
+	got, err := s.Keys().Search(ctx(), &key.SearchQuery{TenantID: tenant, Terms: key.ParseSearchTerms("runbook")})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "…the acme-mobile incident, see the runbook for details on how to rotate…", got[0].Snippet)
+
+	billing.Description = "Charges cards"
+	billing.UpdatedAt = now
+	require.NoError(t, s.Keys().Update(ctx(), billing))
+	assert.Empty(t, search("acme payments", "", 0), "an update re-indexes the key")
+	require.NoError(t, s.Keys().Delete(ctx(), ios.ID))
+	assert.Equal(t, []result{{analytics.ID, []string{"metadata.notes"}, 1}}, search("acme-mobile", "app_conformance", 0))
+}
+
+func testLabels(t *testing.T, s store.Store) {
+	k := NewKey("t1", "sk_test_labels000001")
+	k.Labels = key.Labels{"team": "payments", "example.com/tier": "gold", "empty": ""}
+	create(t, s, k)
+
+	got, err := s.Keys().Get(ctx(), k.ID)
+	require.NoError(t, err)
+	assert.Equal(t, k.Labels, got.Labels)
+	got, err = s.Keys().GetByHash(ctx(), k.KeyHash)
+	require.NoError(t, err)
+	assert.Equal(t, k.Labels, got.Labels)
+
+	k.Labels = key.Labels{"team": "billing"}
+	require.NoError(t, s.Keys().Update(ctx(), k))
+	got, err = s.Keys().Get(ctx(), k.ID)
+	require.NoError(t, err)
+	assert.Equal(t, key.Labels{"team": "billing"}, got.Labels)
+
+	sel, err := key.ParseSelector("team=payments")
+	require.NoError(t, err)
+	keys, err := s.Keys().List(ctx(), &key.ListFilter{TenantID: "t1", LabelSelector: sel})
+	require.NoError(t, err)
+	assert.Empty(t, keys, "replaced labels no longer match")
+
+	k.Labels = nil
+	require.NoError(t, s.Keys().Update(ctx(), k))
+	got, err = s.Keys().Get(ctx(), k.ID)
+	require.NoError(t, err)
+	assert.Empty(t, got.Labels)
+}
+
+func testLabelSelectors(t *testing.T, s store.Store) {
+	a := NewKey("t1", "sk_test_selector0001")
+	a.Labels = key.Labels{"team": "payments", "env": "prod"}
+	b := NewKey("t1", "sk_test_selector0002")
+	b.Labels = key.Labels{"team": "payments", "env": "staging", "owner": "ana"}
+	c := NewKey("t1", "sk_test_selector0003")
+	c.Labels = key.Labels{"team": "search"}
+	d := NewKey("t1", "sk_test_selector0004")
+	other := NewKey("t2", "sk_test_selector0005")
+	other.Labels = key.Labels{"team": "payments"}
+	create(t, s, a, b, c, d, other)
+
+	tests := []struct {
+		selector string
+		want     []*key.Key
+	}{
+		{"team=payments", []*key.Key{a, b}},
+		{"team==payments,env=prod", []*key.Key{a}},
+		{"team!=payments", []*key.Key{c, d}},
+		{"env in (prod,staging)", []*key.Key{a, b}},
+		{"env notin (prod)", []*key.Key{b, c, d}},
+		{"owner", []*key.Key{b}},
+		{"!owner", []*key.Key{a, c, d}},
+		{"team=payments,!owner", []*key.Key{a}},
+		{"team=none", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			sel, err := key.ParseSelector(tt.selector)
+			require.NoError(t, err)
+			filter := &key.ListFilter{TenantID: "t1", LabelSelector: sel}
+
+			keys, err := s.Keys().List(ctx(), filter)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, ids(tt.want), ids(keys))
+
+			n, err := s.Keys().Count(ctx(), filter)
+			require.NoError(t, err)
+			assert.EqualValues(t, len(tt.want), n)
+
+			var visited []*key.Key
+			require.NoError(t, s.Keys().Iterate(ctx(), filter, func(k *key.Key) error {
+				visited = append(visited, k)
+				return nil
+			}))
+			assert.ElementsMatch(t, ids(tt.want), ids(visited))
+		})
+	}
+}
+
+func testListByPrefixHint(t *testing.T, s store.Store) {
+	a := NewKey("t1", "sk_test_hint0000abcd")
+	b := NewKey("t1", "sk_test_hint0001abcd")
+	b.CreatedAt = a.CreatedAt.Add(time.Second)
+	create(t, s, b, a, NewKey("t1", "sk_test_hint0000wxyz"))
+
+	keys, err := s.Keys().ListByPrefixHint(ctx(), "sk", "abcd")
+	require.NoError(t, err)
+	assert.Equal(t, []string{a.ID.String(), b.ID.String()}, ids(keys), "oldest first")
+	assert.Equal(t, a.KeyHash, keys[0].KeyHash)
+
+	keys, err = s.Keys().ListByPrefixHint(ctx(), "sk", "none")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func testUpdateStateIf(t *testing.T, s store.Store) {
+	k := NewKey("t1", "sk_test_stateif00001")
+	create(t, s, k)
+
+	ok, err := s.Keys().UpdateStateIf(ctx(), k.ID, key.StateActive, key.StateRevoked)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = s.Keys().UpdateStateIf(ctx(), k.ID, key.StateActive, key.StateRevoked)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func testRotationHashes(t *testing.T, s store.Store) {
+	k := NewKey("t1", "sk_test_rotate000001")
+	create(t, s, k)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	rec := &rotation.Record{
+		ID:         id.NewRotationID(),
+		KeyID:      k.ID,
+		TenantID:   k.TenantID,
+		OldKeyHash: k.KeyHash,
+		NewKeyHash: Hash("sk_test_rotate000002"),
+		OldHint:    "0001",
+		NewHint:    "0002",
+		Reason:     rotation.ReasonManual,
+		GraceTTL:   time.Hour,
+		GraceEnds:  now.Add(time.Hour),
+		CreatedAt:  now,
+	}
+	require.NoError(t, s.Rotations().Create(ctx(), rec))
+
+	got, err := s.Rotations().Get(ctx(), rec.ID)
+	require.NoError(t, err)
+	assert.Equal(t, rec.OldKeyHash, got.OldKeyHash)
+	assert.Equal(t, rec.NewKeyHash, got.NewKeyHash)
+
+	latest, err := s.Rotations().LatestForKey(ctx(), k.ID)
+	require.NoError(t, err)
+	assert.Equal(t, rec.ID.String(), latest.ID.String())
+	assert.Equal(t, rec.NewKeyHash, latest.NewKeyHash)
+
+	kid := k.ID
+	recs, err := s.Rotations().List(ctx(), &rotation.ListFilter{KeyID: &kid})
+	require.NoError(t, err)
+	require.Len(t, recs, 1)
+	assert.Equal(t, rec.OldKeyHash, recs[0].OldKeyHash)
+}
+
+// createRotations stores one rotation of k per reason, an hour apart from
+// base, and returns them.
+func createRotations(t *testing.T, s store.Store, k *key.Key, base time.Time, reasons ...rotation.Reason) []*rotation.Record {
+	t.Helper()
+	recs := make([]*rotation.Record, len(reasons))
+	for i, reason := range reasons {
+		at := base.Add(time.Duration(i) * time.Hour)
+		recs[i] = &rotation.Record{
+			ID:         id.NewRotationID(),
+			KeyID:      k.ID,
+			TenantID:   k.TenantID,
+			AppID:      k.AppID,
+			OldKeyHash: k.KeyHash,
+			NewKeyHash: Hash(fmt.Sprintf("sk_test_rot_%s_%06d", k.ID, i)),
+			Reason:     reason,
+			GraceTTL:   time.Hour,
+			GraceEnds:  at.Add(time.Hour),
+			CreatedAt:  at,
+		}
+		require.NoError(t, s.Rotations().Create(ctx(), recs[i]))
+	}
+	return recs
+}
+
+func rotationIDs(recs []*rotation.Record) []string {
+	out := make([]string, len(recs))
+	for i, r := range recs {
+		out[i] = r.ID.String()
+	}
+	return out
+}
+
+func testRotationFilters(t *testing.T, s store.Store) {
+	k := NewKey("t1", "sk_test_rotfilter001")
+	other := NewKey("t2", "sk_test_rotfilter002")
+	create(t, s, k, other)
+	base := time.Now().UTC().Truncate(time.Millisecond).Add(-24 * time.Hour)
+	recs := createRotations(t, s, k, base, rotation.ReasonManual, rotation.ReasonCompromise, rotation.ReasonCompromise, "legacy-reason")
+	createRotations(t, s, other, base, rotation.ReasonCompromise)
+
+	got, err := s.Rotations().List(ctx(), &rotation.ListFilter{TenantID: "t1", Reason: rotation.ReasonCompromise})
+	require.NoError(t, err)
+	assert.Equal(t, []string{recs[2].ID.String(), recs[1].ID.String()}, rotationIDs(got), "newest first")
+
+	// Bounds are exclusive.
+	after, before := recs[0].CreatedAt, recs[3].CreatedAt
+	got, err = s.Rotations().List(ctx(), &rotation.ListFilter{TenantID: "t1", CreatedAfter: &after, CreatedBefore: &before})
+	require.NoError(t, err)
+	assert.Equal(t, []string{recs[2].ID.String(), recs[1].ID.String()}, rotationIDs(got))
+
+	got, err = s.Rotations().List(ctx(), &rotation.ListFilter{TenantID: "t1", CreatedAfter: &after, Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{recs[2].ID.String()}, rotationIDs(got))
+
+	// A reason stored before validation round-trips unmodified.
+	got, err = s.Rotations().List(ctx(), &rotation.ListFilter{TenantID: "t1", Reason: "legacy-reason"})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, rotation.Reason("legacy-reason"), got[0].Reason)
+}
+
+func testRotationCountByReason(t *testing.T, s store.Store) {
+	k := NewKey("t1", "sk_test_rotcount0001")
+	other := NewKey("t2", "sk_test_rotcount0002")
+	create(t, s, k, other)
+	base := time.Now().UTC().Truncate(time.Millisecond).Add(-24 * time.Hour)
+	recs := createRotations(t, s, k, base,
+		rotation.ReasonScheduled, rotation.ReasonCompromise, rotation.ReasonScheduled,
+		"legacy-reason", rotation.ReasonCompromise, rotation.ReasonScheduled, rotation.ReasonManual)
+	createRotations(t, s, other, base, rotation.ReasonManual, rotation.ReasonManual)
+
+	counts, err := s.Rotations().CountByReason(ctx(), &rotation.ListFilter{TenantID: "t1", Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []*rotation.ReasonCount{
+		{Reason: rotation.ReasonScheduled, Count: 3},
+		{Reason: rotation.ReasonCompromise, Count: 2},
+		{Reason: "legacy-reason", Count: 1},
+		{Reason: rotation.ReasonManual, Count: 1},
+	}, counts, "most frequent first, ties by reason, ignoring Limit")
+
+	after, before := recs[0].CreatedAt, recs[5].CreatedAt
+	counts, err = s.Rotations().CountByReason(ctx(), &rotation.ListFilter{TenantID: "t1", CreatedAfter: &after, CreatedBefore: &before})
+	require.NoError(t, err)
+	assert.Equal(t, []*rotation.ReasonCount{
+		{Reason: rotation.ReasonCompromise, Count: 2},
+		{Reason: "legacy-reason", Count: 1},
+		{Reason: rotation.ReasonScheduled, Count: 1},
+	}, counts)
+
+	counts, err = s.Rotations().CountByReason(ctx(), &rotation.ListFilter{TenantID: "t3"})
+	require.NoError(t, err)
+	assert.Empty(t, counts)
+}
+
+func testTenantRevisions(t *testing.T, s store.Store) {
+	rev, err := s.Tenants().Revision(ctx(), "t1")
+	require.NoError(t, err)
+	assert.Zero(t, rev, "an unknown tenant is at revision zero")
+
+	for want := uint64(1); want <= 3; want++ {
+		rev, err = s.Tenants().BumpRevision(ctx(), "t1")
+		require.NoError(t, err)
+		assert.Equal(t, want, rev)
+	}
+	rev, err = s.Tenants().Revision(ctx(), "t1")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), rev)
+
+	rev, err = s.Tenants().Revision(ctx(), "t2")
+	require.NoError(t, err)
+	assert.Zero(t, rev, "revisions are per tenant")
+
+	const bumps = 20
+	var wg sync.WaitGroup
+	got := make([]uint64, bumps)
+	for i := range bumps {
+		wg.Go(func() {
+			got[i], _ = s.Tenants().BumpRevision(ctx(), "t2")
+		})
+	}
+	wg.Wait()
+	seen := make(map[uint64]bool, bumps)
+	for _, r := range got {
+		seen[r] = true
+	}
+	assert.Len(t, seen, bumps, "concurrent bumps each get their own revision")
+	rev, err = s.Tenants().Revision(ctx(), "t2")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(bumps), rev)
+}
+
+func testGroups(t *testing.T, s store.Store) { CheckGroups(t, s, "t1") }
+
+// CheckGroups creates two groups in tenant and checks membership: adding a
+// member twice or removing a non-member is a no-op, key.ListFilter.GroupID
+// lists exactly the members, ListByKeys reports every group of a key, and
+// deleting a group drops its memberships but not its keys. Backends whose
+// tests share a database call it with a tenant of their own.
+func CheckGroups(t *testing.T, s store.Store, tenant string) {
+	t.Helper()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	newGroup := func(name string) *group.Group {
+		g := &group.Group{
+			ID:        id.NewGroupID(),
+			TenantID:  tenant,
+			AppID:     "app_conformance",
+			Name:      name,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		require.NoError(t, s.Groups().Create(ctx(), g))
+		return g
+	}
+	mobile, partner := newGroup("Mobile apps"), newGroup("Partner X")
+	k1 := NewKey(tenant, "sk_test_"+tenant+"_group0001")
+	k2 := NewKey(tenant, "sk_test_"+tenant+"_group0002")
+	k3 := NewKey(tenant, "sk_test_"+tenant+"_group0003")
+	create(t, s, k1, k2, k3)
+
+	got, err := s.Groups().Get(ctx(), mobile.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Mobile apps", got.Name)
+	assert.Equal(t, tenant, got.TenantID)
+
+	mobile.Description = "iOS and Android"
+	require.NoError(t, s.Groups().Update(ctx(), mobile))
+	got, err = s.Groups().Get(ctx(), mobile.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "iOS and Android", got.Description)
+
+	groups, err := s.Groups().List(ctx(), &group.ListFilter{TenantID: tenant})
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	assert.Equal(t, "Mobile apps", groups[0].Name, "groups are ordered by name")
+
+	require.NoError(t, s.Groups().AddKeys(ctx(), mobile.ID, []id.KeyID{k1.ID, k2.ID}))
+	require.NoError(t, s.Groups().AddKeys(ctx(), mobile.ID, []id.KeyID{k2.ID}), "adding a member twice is a no-op")
+	require.NoError(t, s.Groups().AddKeys(ctx(), partner.ID, []id.KeyID{k2.ID, k3.ID}))
+	require.NoError(t, s.Groups().RemoveKeys(ctx(), partner.ID, []id.KeyID{k1.ID, k3.ID}), "removing a non-member is a no-op")
+	assert.Error(t, s.Groups().AddKeys(ctx(), id.NewGroupID(), []id.KeyID{k1.ID}), "unknown group")
+
+	members, err := s.Keys().List(ctx(), &key.ListFilter{TenantID: tenant, GroupID: &mobile.ID})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{k1.ID.String(), k2.ID.String()}, ids(members))
+
+	byKey, err := s.Groups().ListByKeys(ctx(), []id.KeyID{k1.ID, k2.ID, k3.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []id.GroupID{mobile.ID}, byKey[k1.ID])
+	assert.ElementsMatch(t, []id.GroupID{mobile.ID, partner.ID}, byKey[k2.ID])
+	assert.NotContains(t, byKey, k3.ID, "keys in no group are absent")
+
+	require.NoError(t, s.Groups().Delete(ctx(), mobile.ID))
+	_, err = s.Groups().Get(ctx(), mobile.ID)
+	assert.Error(t, err)
+	byKey, err = s.Groups().ListByKeys(ctx(), []id.KeyID{k1.ID, k2.ID})
+	require.NoError(t, err)
+	assert.NotContains(t, byKey, k1.ID, "deleting a group drops its memberships")
+	assert.Equal(t, []id.GroupID{partner.ID}, byKey[k2.ID])
+	_, err = s.Keys().Get(ctx(), k1.ID)
+	require.NoError(t, err, "deleting a group keeps its keys")
+}
+
+func testExternalRefs(t *testing.T, s store.Store) { CheckExternalRefs(t, s, "t1") }
+
+// CheckExternalRefs checks that Create rejects a second key with the same
+// ExternalRef in tenant with key.ErrExternalRefInUse, while another tenant
+// and keys without a reference are unaffected, and that
+// key.ListFilter.ExternalRef finds the key. Backends whose tests share a
+// database call it with a tenant of their own.
+func CheckExternalRefs(t *testing.T, s store.Store, tenant string) {
+	t.Helper()
+	other := tenant + "-other"
+	first := NewKey(tenant, "sk_test_"+tenant+"_ref0001")
+	first.ExternalRef = "provision-42"
+	create(t, s, first)
+
+	dup := NewKey(tenant, "sk_test_"+tenant+"_ref0002")
+	dup.ExternalRef = "provision-42"
+	require.ErrorIs(t, s.Keys().Create(ctx(), dup), key.ErrExternalRefInUse)
+	_, err := s.Keys().Get(ctx(), dup.ID)
+	assert.Error(t, err, "the duplicate is not stored")
+	_, err = s.Keys().GetByHash(ctx(), dup.KeyHash)
+	assert.Error(t, err, "nor is its hash")
+
+	elsewhere := NewKey(other, "sk_test_"+tenant+"_ref0003")
+	elsewhere.ExternalRef = "provision-42"
+	create(t, s, elsewhere)
+	create(t, s,
+		NewKey(tenant, "sk_test_"+tenant+"_ref0004"),
+		NewKey(tenant, "sk_test_"+tenant+"_ref0005"),
+	)
+
+	got, err := s.Keys().Get(ctx(), first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "provision-42", got.ExternalRef)
+
+	keys, err := s.Keys().List(ctx(), &key.ListFilter{TenantID: tenant, ExternalRef: "provision-42"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{first.ID.String()}, ids(keys))
+	keys, err = s.Keys().List(ctx(), &key.ListFilter{TenantID: other, ExternalRef: "provision-42"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{elsewhere.ID.String()}, ids(keys))
+}
+
+func testKeyEvents(t *testing.T, s store.Store) { CheckKeyEvents(t, s, "t1") }
+
+// CheckKeyEvents appends key events in tenant and another tenant and checks
+// that ListAfter returns them in (At, KeyID) order after the cursor,
+// filtered by tenant, app, key, and type and capped by the limit, that a
+// repeated (At, KeyID) is ignored, and that Purge deletes the events before
+// a time. Backends whose tests share a database call it with a tenant of
+// their own.
+func CheckKeyEvents(t *testing.T, s store.Store, tenant string) {
+	t.Helper()
+	other := tenant + "-other"
+	base := time.Now().UTC().Truncate(time.Microsecond).Add(-time.Hour)
+	k1, k2 := id.NewKeyID(), id.NewKeyID()
+	events := []*keyevent.Event{
+		{Type: keyevent.TypeCreated, KeyID: k1, TenantID: tenant, AppID: "app_a", State: key.StateActive, At: base},
+		{Type: keyevent.TypeCreated, KeyID: k2, TenantID: tenant, AppID: "app_b", State: key.StateActive, At: base.Add(time.Second)},
+		{Type: keyevent.TypeRevoked, KeyID: k1, TenantID: tenant, AppID: "app_a", State: key.StateRevoked, Reason: "leaked", ReasonCode: "compromised", ActorID: "user_1", At: base.Add(2 * time.Second)},
+		{Type: keyevent.TypeCreated, KeyID: id.NewKeyID(), TenantID: other, AppID: "app_a", State: key.StateActive, At: base.Add(3 * time.Second)},
+	}
+	for _, e := range events {
+		require.NoError(t, s.KeyEvents().Append(ctx(), e))
+	}
+	require.NoError(t, s.KeyEvents().Append(ctx(), events[0]), "a repeated (At, KeyID) is ignored")
+
+	types := func(list []*keyevent.Event) []keyevent.Type {
+		out := make([]keyevent.Type, len(list))
+		for i, e := range list {
+			out[i] = e.Type
+		}
+		return out
+	}
+	all, err := s.KeyEvents().ListAfter(ctx(), &keyevent.ListFilter{TenantID: tenant})
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	assert.Equal(t, []keyevent.Type{keyevent.TypeCreated, keyevent.TypeCreated, keyevent.TypeRevoked}, types(all))
+	assert.Equal(t, k1, all[2].KeyID)
+	assert.Equal(t, "leaked", all[2].Reason)
+	assert.Equal(t, "compromised", all[2].ReasonCode)
+	assert.Equal(t, "user_1", all[2].ActorID)
+	assert.Equal(t, key.StateRevoked, all[2].State)
+	assert.True(t, base.Add(2*time.Second).Equal(all[2].At))
+
+	after, err := s.KeyEvents().ListAfter(ctx(), &keyevent.ListFilter{TenantID: tenant, After: keyevent.CursorOf(all[0])})
+	require.NoError(t, err)
+	assert.Len(t, after, 2, "the cursor's own event is excluded")
+	limited, err := s.KeyEvents().ListAfter(ctx(), &keyevent.ListFilter{TenantID: tenant, Limit: 2})
+	require.NoError(t, err)
+	assert.Len(t, limited, 2)
+
+	byApp, err := s.KeyEvents().ListAfter(ctx(), &keyevent.ListFilter{TenantID: tenant, AppID: "app_b"})
+	require.NoError(t, err)
+	require.Len(t, byApp, 1)
+	assert.Equal(t, k2, byApp[0].KeyID)
+	byKey, err := s.KeyEvents().ListAfter(ctx(), &keyevent.ListFilter{TenantID: tenant, KeyID: &k1})
+	require.NoError(t, err)
+	assert.Len(t, byKey, 2)
+	byType, err := s.KeyEvents().ListAfter(ctx(), &keyevent.ListFilter{TenantID: tenant, Types: []keyevent.Type{keyevent.TypeRevoked, keyevent.TypeExpired}})
+	require.NoError(t, err)
+	assert.Equal(t, []keyevent.Type{keyevent.TypeRevoked}, types(byType))
+
+	_, err = s.KeyEvents().Purge(ctx(), base.Add(2*time.Second))
+	require.NoError(t, err)
+	all, err = s.KeyEvents().ListAfter(ctx(), &keyevent.ListFilter{TenantID: tenant})
+	require.NoError(t, err)
+	assert.Equal(t, []keyevent.Type{keyevent.TypeRevoked}, types(all), "events before the time are purged")
+}
+
+func testKeyTransfers(t *testing.T, s store.Store) { CheckKeyTransfers(t, s, "t1") }
+
+// CheckKeyTransfers transfers a key from tenant to another tenant and checks
+// that a key has at most one pending transfer, that List filters by either
+// tenant, key, state, and expiry, that Resolve and Accept only act on a
+// pending transfer, and that Accept moves the key with the remapped scopes
+// and policy, drops its group memberships, and retags its usage. Backends
+// whose tests share a database call it with a tenant of their own.
+func CheckKeyTransfers(t *testing.T, s store.Store, tenant string) {
+	t.Helper()
+	target := tenant + "-target"
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	newScope := func(tenantID, name string) {
+		require.NoError(t, s.Scopes().Create(ctx(), &scope.Scope{
+			ID:        id.NewScopeID(),
+			TenantID:  tenantID,
+			AppID:     "app_conformance",
+			Name:      name,
+			CreatedAt: now,
+		}))
+	}
+	newScope(tenant, "read:things")
+	newScope(tenant, "write:things")
+	newScope(target, "read:things")
+
+	k := NewKey(tenant, "sk_test_"+tenant+"_xfer0001")
+	other := NewKey(tenant, "sk_test_"+tenant+"_xfer0002")
+	create(t, s, k, other)
+	require.NoError(t, s.Scopes().AssignToKey(ctx(), k.ID, []string{"read:things", "write:things"}))
+	g := &group.Group{ID: id.NewGroupID(), TenantID: tenant, AppID: "app_conformance", Name: "Movers", CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, s.Groups().Create(ctx(), g))
+	require.NoError(t, s.Groups().AddKeys(ctx(), g.ID, []id.KeyID{k.ID}))
+	require.NoError(t, s.Usages().RecordBatch(ctx(), []*usage.Record{{
+		ID:          id.NewUsageID(),
+		KeyID:       k.ID,
+		TenantID:    tenant,
+		AppID:       k.AppID,
+		Environment: k.Environment,
+		Endpoint:    "/v1/things",
+		Method:      "GET",
+		StatusCode:  200,
+		CreatedAt:   now,
+	}}))
+
+	newTransfer := func(k *key.Key, expiresIn time.Duration) *transfer.Transfer {
+		return &transfer.Transfer{
+			ID:             id.NewTransferID(),
+			KeyID:          k.ID,
+			AppID:          k.AppID,
+			SourceTenantID: tenant,
+			TargetTenantID: target,
+			State:          transfer.StatePending,
+			RetagUsage:     true,
+			InitiatedBy:    "user_source",
+			ExpiresAt:      now.Add(expiresIn),
+			CreatedAt:      now,
+		}
+	}
+	xfer := newTransfer(k, time.Hour)
+	require.NoError(t, s.Transfers().Create(ctx(), xfer))
+	assert.ErrorIs(t, s.Transfers().Create(ctx(), newTransfer(k, time.Hour)), transfer.ErrPendingTransfer,
+		"a key has at most one pending transfer")
+
+	pending, err := s.Transfers().GetPending(ctx(), k.ID)
+	require.NoError(t, err)
+	assert.Equal(t, xfer.ID, pending.ID)
+	assert.Equal(t, target, pending.TargetTenantID)
+	assert.True(t, pending.RetagUsage)
+	assert.Equal(t, "user_source", pending.InitiatedBy)
+	assert.True(t, xfer.ExpiresAt.Equal(pending.ExpiresAt))
+	assert.Nil(t, pending.ResolvedAt)
+	_, err = s.Transfers().GetPending(ctx(), other.ID)
+	assert.Error(t, err, "a key without a pending transfer")
+
+	cancelled := newTransfer(other, -time.Minute)
+	cancelled.CreatedAt = now.Add(time.Millisecond)
+	require.NoError(t, s.Transfers().Create(ctx(), cancelled))
+	stale, err := s.Transfers().List(ctx(), &transfer.ListFilter{TenantID: tenant, State: transfer.StatePending, ExpiresBefore: &now})
+	require.NoError(t, err)
+	require.Len(t, stale, 1)
+	assert.Equal(t, cancelled.ID, stale[0].ID)
+	ok, err := s.Transfers().Resolve(ctx(), cancelled.ID, transfer.StateCancelled, "user_target", now)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = s.Transfers().Resolve(ctx(), cancelled.ID, transfer.StateExpired, "", now)
+	require.NoError(t, err)
+	assert.False(t, ok, "a resolved transfer stays resolved")
+	got, err := s.Transfers().Get(ctx(), cancelled.ID)
+	require.NoError(t, err)
+	assert.Equal(t, transfer.StateCancelled, got.State)
+	assert.Equal(t, "user_target", got.ResolvedBy)
+	require.NotNil(t, got.ResolvedAt)
+	ok, err = s.Transfers().Accept(ctx(), got, &transfer.Remap{})
+	require.NoError(t, err)
+	assert.False(t, ok, "a cancelled transfer cannot be accepted")
+
+	pid := id.NewPolicyID()
+	at := now.Add(time.Second)
+	xfer.ResolvedBy = "user_target"
+	xfer.ResolvedAt = &at
+	xfer.UnmappedScopes = []string{"write:things"}
+	xfer.UnmappedPolicy = "Premium"
+	ok, err = s.Transfers().Accept(ctx(), xfer, &transfer.Remap{Scopes: []string{"read:things"}, PolicyID: &pid})
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = s.Transfers().Accept(ctx(), xfer, &transfer.Remap{})
+	require.NoError(t, err)
+	assert.False(t, ok, "a transfer is accepted once")
+
+	got, err = s.Transfers().Get(ctx(), xfer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, transfer.StateAccepted, got.State)
+	assert.Equal(t, []string{"write:things"}, got.UnmappedScopes)
+	assert.Equal(t, "Premium", got.UnmappedPolicy)
+	require.NotNil(t, got.ResolvedAt)
+	assert.True(t, at.Equal(*got.ResolvedAt))
+
+	moved, err := s.Keys().Get(ctx(), k.ID)
+	require.NoError(t, err)
+	assert.Equal(t, target, moved.TenantID)
+	require.NotNil(t, moved.PolicyID)
+	assert.Equal(t, pid, *moved.PolicyID)
+	scopes, err := s.Scopes().ListByKey(ctx(), k.ID)
+	require.NoError(t, err)
+	require.Len(t, scopes, 1)
+	assert.Equal(t, "read:things", scopes[0].Name)
+	assert.Equal(t, target, scopes[0].TenantID, "the key holds the target tenant's scope")
+	byKey, err := s.Groups().ListByKeys(ctx(), []id.KeyID{k.ID})
+	require.NoError(t, err)
+	assert.NotContains(t, byKey, k.ID, "the key leaves the source tenant's groups")
+	n, err := s.Usages().Count(ctx(), &usage.QueryFilter{TenantID: target, KeyID: &k.ID})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n, "usage moves with the key")
+
+	for _, tenantID := range []string{tenant, target} {
+		list, err := s.Transfers().List(ctx(), &transfer.ListFilter{TenantID: tenantID})
+		require.NoError(t, err)
+		require.Len(t, list, 2, "both tenants see the transfers")
+		assert.Equal(t, cancelled.ID, list[0].ID, "newest first")
+	}
+	list, err := s.Transfers().List(ctx(), &transfer.ListFilter{TenantID: tenant, KeyID: &k.ID})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, xfer.ID, list[0].ID)
+
+	require.NoError(t, s.Transfers().Create(ctx(), newTransfer(k, time.Hour)),
+		"a key may be transferred again once its transfer is resolved")
+}
+
+func testKeyNames(t *testing.T, s store.Store) { CheckKeyNames(t, s, "t1") }
+
+// CheckKeyNames checks that key.ListFilter.Name matches key names in tenantID
+// case-insensitively, in List and Count, and follows renames, and that
+// tenant settings keep UniqueKeyNames. Backends whose tests share a
+// database call it with a tenant of their own.
+func CheckKeyNames(t *testing.T, s store.Store, tenantID string) {
+	t.Helper()
+	a := NewKey(tenantID, "sk_test_"+tenantID+"_name0001")
+	a.Name = "Production Key"
+	b := NewKey(tenantID, "sk_test_"+tenantID+"_name0002")
+	b.Name = "production key"
+	c := NewKey(tenantID, "sk_test_"+tenantID+"_name0003")
+	c.Name = "Production Keys"
+	elsewhere := NewKey(tenantID+"-other", "sk_test_"+tenantID+"_name0004")
+	elsewhere.Name = "Production Key"
+	create(t, s, a, b, c, elsewhere)
+
+	filter := &key.ListFilter{TenantID: tenantID, Name: "PRODUCTION KEY"}
+	keys, err := s.Keys().List(ctx(), filter)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{a.ID.String(), b.ID.String()}, ids(keys))
+	n, err := s.Keys().Count(ctx(), filter)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+
+	b.Name = "Staging Key"
+	require.NoError(t, s.Keys().Update(ctx(), b))
+	keys, err = s.Keys().List(ctx(), filter)
+	require.NoError(t, err)
+	assert.Equal(t, []string{a.ID.String()}, ids(keys))
+	keys, err = s.Keys().List(ctx(), &key.ListFilter{TenantID: tenantID, Name: "staging key"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{b.ID.String()}, ids(keys))
+
+	require.NoError(t, s.Tenants().UpsertSettings(ctx(), &tenant.Settings{
+		TenantID: tenantID, UniqueKeyNames: true, CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}))
+	ts, err := s.Tenants().GetSettings(ctx(), tenantID)
+	require.NoError(t, err)
+	assert.True(t, ts.UniqueKeyNames)
+}
+
+func testTenantLockdown(t *testing.T, s store.Store) { CheckTenantLockdown(t, s, "t1") }
+
+// CheckTenantLockdown checks that tenant settings keep a tenant.Lockdown and
+// its recovery, and that upserting settings without one clears it. Backends
+// whose tests share a database call it with a tenant of their own.
+func CheckTenantLockdown(t *testing.T, s store.Store, tenantID string) {
+	t.Helper()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	halted := now.Add(time.Minute)
+	ts := &tenant.Settings{
+		TenantID: tenantID, AppID: "app", CreatedAt: now, UpdatedAt: now,
+		Lockdown: &tenant.Lockdown{
+			Phase: tenant.LockdownHalted, Reason: "credential leak", ActorID: "usr_1",
+			LockedAt: now, Suspended: 3,
+			Recovery: &tenant.Recovery{
+				StartedAt: now, BatchSize: 2, BatchInterval: 5 * time.Minute,
+				WatchSince: now, NextBatchAt: now.Add(5 * time.Minute),
+				Batches: 1, Reactivated: 2, Remaining: 1,
+				HaltedAt: &halted, HaltReason: "compromised",
+			},
+		},
+	}
+	require.NoError(t, s.Tenants().UpsertSettings(ctx(), ts))
+	got, err := s.Tenants().GetSettings(ctx(), tenantID)
+	require.NoError(t, err)
+	require.NotNil(t, got.Lockdown)
+	assert.Equal(t, tenant.LockdownHalted, got.Lockdown.Phase)
+	assert.Equal(t, "credential leak", got.Lockdown.Reason)
+	assert.Equal(t, "usr_1", got.Lockdown.ActorID)
+	assert.True(t, now.Equal(got.Lockdown.LockedAt), "LockedAt = %s", got.Lockdown.LockedAt)
+	assert.Equal(t, 3, got.Lockdown.Suspended)
+	require.NotNil(t, got.Lockdown.Recovery)
+	r := got.Lockdown.Recovery
+	assert.Equal(t, 5*time.Minute, r.BatchInterval)
+	assert.Equal(t, []int{2, 1, 2, 1}, []int{r.BatchSize, r.Batches, r.Reactivated, r.Remaining})
+	require.NotNil(t, r.HaltedAt)
+	assert.True(t, halted.Equal(*r.HaltedAt), "HaltedAt = %s", *r.HaltedAt)
+	assert.Equal(t, "compromised", r.HaltReason)
+	assert.Nil(t, r.CompletedAt)
+
+	ts.Lockdown = nil
+	require.NoError(t, s.Tenants().UpsertSettings(ctx(), ts))
+	got, err = s.Tenants().GetSettings(ctx(), tenantID)
+	require.NoError(t, err)
+	assert.Nil(t, got.Lockdown)
+}
+
+func testInlinePolicies(t *testing.T, s store.Store) { CheckInlinePolicies(t, s, "t1") }
+
+// CheckInlinePolicies checks that policy.Policy.Inline is kept, and that
+// List and Count leave inline policies out unless
+// policy.ListFilter.IncludeInline is set. Backends whose tests share a
+// database call it with a tenant of their own.
+func CheckInlinePolicies(t *testing.T, s store.Store, tenantID string) {
+	t.Helper()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	named := &policy.Policy{
+		ID: id.NewPolicyID(), TenantID: tenantID, AppID: "app", Name: "Standard",
+		RateLimit: 10, RateLimitWindow: time.Minute, CreatedAt: now, UpdatedAt: now,
+	}
+	inline := &policy.Policy{
+		ID: id.NewPolicyID(), TenantID: tenantID, AppID: "app", Name: "inline-" + tenantID,
+		RateLimit: 5, RateLimitWindow: time.Minute, Inline: true, CreatedAt: now, UpdatedAt: now,
+	}
+	require.NoError(t, s.Policies().Create(ctx(), named))
+	require.NoError(t, s.Policies().Create(ctx(), inline))
+
+	got, err := s.Policies().Get(ctx(), inline.ID)
+	require.NoError(t, err)
+	assert.True(t, got.Inline)
+
+	polIDs := func(pols []*policy.Policy) []string {
+		out := make([]string, 0, len(pols))
+		for _, p := range pols {
+			out = append(out, p.ID.String())
+		}
+		return out
+	}
+	filter := &policy.ListFilter{TenantID: tenantID}
+	pols, err := s.Policies().List(ctx(), filter)
+	require.NoError(t, err)
+	assert.Equal(t, []string{named.ID.String()}, polIDs(pols))
+	n, err := s.Policies().Count(ctx(), filter)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+
+	filter.IncludeInline = true
+	pols, err = s.Policies().List(ctx(), filter)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{named.ID.String(), inline.ID.String()}, polIDs(pols))
+	n, err = s.Policies().Count(ctx(), filter)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+
+	inline.Inline = false
+	inline.Name = "Promoted"
+	require.NoError(t, s.Policies().Update(ctx(), inline))
+	n, err = s.Policies().Count(ctx(), &policy.ListFilter{TenantID: tenantID})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, n, "a promoted policy is listed")
+}
+
+func testScopeAssignment(t *testing.T, s store.Store) { CheckScopeAssignment(t, s, "t1") }
+
+// CheckScopeAssignment checks that AssignToKey assigns all of the names it
+// is given or, when one is not a scope of the key's tenant, none of them.
+// Backends whose tests share a database call it with a tenant of their own.
+func CheckScopeAssignment(t *testing.T, s store.Store, tenantID string) {
+	t.Helper()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	for _, name := range []string{"read", "write"} {
+		require.NoError(t, s.Scopes().Create(ctx(), &scope.Scope{
+			ID: id.NewScopeID(), TenantID: tenantID, AppID: "app_conformance", Name: name, CreatedAt: now,
+		}))
+	}
+	k := NewKey(tenantID, "sk_test_"+tenantID+"_assign01")
+	create(t, s, k)
+
+	err := s.Scopes().AssignToKey(ctx(), k.ID, []string{"read", "missing", "write"})
+	require.Error(t, err)
+	assigned, err := s.Scopes().ListByKey(ctx(), k.ID)
+	require.NoError(t, err)
+	assert.Empty(t, assigned, "a failed assignment assigns nothing")
+
+	require.NoError(t, s.Scopes().AssignToKey(ctx(), k.ID, []string{"read", "write"}))
+	assigned, err = s.Scopes().ListByKey(ctx(), k.ID)
+	require.NoError(t, err)
+	assert.Len(t, assigned, 2)
+}
+
+func testHashTombstones(t *testing.T, s store.Store) { CheckHashTombstones(t, s, "t1") }
+
+// CheckHashTombstones checks that a hash keeps its first tombstone, that
+// Find returns only tombstoned hashes, that List and CountByReason filter
+// by tenant and reason, and that Purge removes tombstones older than its
+// cutoff. Backends whose tests share a database call it with a tenant of
+// their own.
+func CheckHashTombstones(t *testing.T, s store.Store, tenantID string) {
+	t.Helper()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	tomb := func(raw, reason string, at time.Time) *tombstone.Tombstone {
+		return &tombstone.Tombstone{
+			Hash: Hash(raw + tenantID), KeyID: id.NewKeyID(), TenantID: tenantID, AppID: "app",
+			Reason: reason, CreatedAt: at,
+		}
+	}
+	old := tomb("old", "compromised", now.Add(-365*24*time.Hour))
+	leaked := tomb("leaked", "leaked_hash", now.Add(-time.Minute))
+	compromised := tomb("compromised", "compromised", now)
+	for _, tb := range []*tombstone.Tombstone{old, leaked, compromised} {
+		require.NoError(t, s.Tombstones().Create(ctx(), tb))
+	}
+	again := *leaked
+	again.Reason = "manual"
+	require.NoError(t, s.Tombstones().Create(ctx(), &again), "a second tombstone is not an error")
+
+	found, err := s.Tombstones().Find(ctx(), []string{leaked.Hash, Hash("missing" + tenantID)})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, leaked.Hash, found[0].Hash)
+	assert.Equal(t, leaked.KeyID.String(), found[0].KeyID.String())
+	assert.Equal(t, "leaked_hash", found[0].Reason, "the first tombstone is kept")
+	found, err = s.Tombstones().Find(ctx(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	// Wrapping stores may keep something other than the hash, so List is
+	// checked by key.
+	keyIDs := func(tbs []*tombstone.Tombstone) []string {
+		out := make([]string, 0, len(tbs))
+		for _, tb := range tbs {
+			out = append(out, tb.KeyID.String())
+		}
+		return out
+	}
+	list, err := s.Tombstones().List(ctx(), &tombstone.ListFilter{TenantID: tenantID})
+	require.NoError(t, err)
+	assert.Equal(t, []string{compromised.KeyID.String(), leaked.KeyID.String(), old.KeyID.String()}, keyIDs(list), "newest first")
+	list, err = s.Tombstones().List(ctx(), &tombstone.ListFilter{TenantID: tenantID, Reason: "compromised", Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{old.KeyID.String()}, keyIDs(list))
+	list, err = s.Tombstones().List(ctx(), &tombstone.ListFilter{TenantID: tenantID + "-other"})
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	counts, err := s.Tombstones().CountByReason(ctx(), &tombstone.ListFilter{TenantID: tenantID, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []*tombstone.ReasonCount{{Reason: "compromised", Count: 2}, {Reason: "leaked_hash", Count: 1}}, counts)
+
+	n, err := s.Tombstones().Purge(ctx(), now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, n, int64(1))
+	list, err = s.Tombstones().List(ctx(), &tombstone.ListFilter{TenantID: tenantID})
+	require.NoError(t, err)
+	assert.Equal(t, []string{compromised.KeyID.String(), leaked.KeyID.String()}, keyIDs(list))
+}
+
+func testQuotaPools(t *testing.T, s store.Store) { CheckQuotaPools(t, s, "t1") }
+
+// CheckQuotaPools checks pool CRUD and listing by name, that keys reference
+// their pool and list by it, that AddUsage adds to stored counts kept apart
+// per pool, key, period, and window, that ListUsage filters them, that
+// Delete detaches the members and removes the counts, and that PurgeUsage
+// removes old windows. Backends whose tests share a database call it with a
+// tenant of their own.
+func CheckQuotaPools(t *testing.T, s store.Store, tenantID string) {
+	t.Helper()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	pool := func(name string) *quotapool.Pool {
+		return &quotapool.Pool{
+			ID: id.NewQuotaPoolID(), TenantID: tenantID, AppID: "app_conformance", Name: name,
+			DailyLimit: 1000, MonthlyLimit: 20000, QuotaTimezone: "Asia/Tokyo", CreatedAt: now, UpdatedAt: now,
+		}
+	}
+	pro, basic := pool("Pro plan"), pool("Basic plan")
+	for _, p := range []*quotapool.Pool{pro, basic} {
+		require.NoError(t, s.QuotaPools().Create(ctx(), p))
+	}
+
+	got, err := s.QuotaPools().Get(ctx(), pro.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Pro plan", got.Name)
+	assert.Equal(t, int64(1000), got.DailyLimit)
+	assert.Equal(t, int64(20000), got.MonthlyLimit)
+	assert.Equal(t, "Asia/Tokyo", got.QuotaTimezone)
+	_, err = s.QuotaPools().Get(ctx(), id.NewQuotaPoolID())
+	assert.Error(t, err)
+
+	got.MonthlyLimit = 50000
+	require.NoError(t, s.QuotaPools().Update(ctx(), got))
+	got, err = s.QuotaPools().Get(ctx(), pro.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(50000), got.MonthlyLimit)
+
+	pools, err := s.QuotaPools().List(ctx(), &quotapool.ListFilter{TenantID: tenantID})
+	require.NoError(t, err)
+	require.Len(t, pools, 2)
+	assert.Equal(t, []string{"Basic plan", "Pro plan"}, []string{pools[0].Name, pools[1].Name}, "ordered by name")
+
+	member := NewKey(tenantID, "sk_test_pool_member_"+tenantID)
+	member.PoolID = &pro.ID
+	other := NewKey(tenantID, "sk_test_pool_other_"+tenantID)
+	create(t, s, member, other)
+	k, err := s.Keys().Get(ctx(), member.ID)
+	require.NoError(t, err)
+	require.NotNil(t, k.PoolID)
+	assert.Equal(t, pro.ID.String(), k.PoolID.String())
+	listed, err := s.Keys().List(ctx(), &key.ListFilter{TenantID: tenantID, PoolID: &pro.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []string{member.ID.String()}, ids(listed))
+
+	day := time.Date(2026, 3, 1, 15, 0, 0, 0, time.UTC)
+	next := day.Add(24 * time.Hour)
+	usages := []*quotapool.Usage{
+		{PoolID: pro.ID, KeyID: member.ID, Period: quotapool.PeriodDay, WindowStart: day, Count: 3},
+		{PoolID: pro.ID, KeyID: member.ID, Period: quotapool.PeriodMonth, WindowStart: day, Count: 3},
+		{PoolID: pro.ID, KeyID: other.ID, Period: quotapool.PeriodDay, WindowStart: next, Count: 1},
+		{KeyID: other.ID, Period: quotapool.PeriodDay, WindowStart: day, Count: 7},
+	}
+	require.NoError(t, s.QuotaPools().AddUsage(ctx(), usages))
+	require.NoError(t, s.QuotaPools().AddUsage(ctx(), []*quotapool.Usage{
+		{PoolID: pro.ID, KeyID: member.ID, Period: quotapool.PeriodDay, WindowStart: day, Count: 2},
+	}))
+	require.NoError(t, s.QuotaPools().AddUsage(ctx(), nil))
+
+	counts := func(f *quotapool.UsageFilter) []int64 {
+		t.Helper()
+		list, err := s.QuotaPools().ListUsage(ctx(), f)
+		require.NoError(t, err)
+		out := make([]int64, 0, len(list))
+		for _, u := range list {
+			out = append(out, u.Count)
+		}
+		return out
+	}
+	assert.Equal(t, []int64{5, 1}, counts(&quotapool.UsageFilter{PoolID: pro.ID, Period: quotapool.PeriodDay}), "added to the stored count, ordered by window")
+	assert.Equal(t, []int64{5}, counts(&quotapool.UsageFilter{PoolID: pro.ID, Period: quotapool.PeriodDay, From: day, To: next}))
+	assert.Equal(t, []int64{1}, counts(&quotapool.UsageFilter{PoolID: pro.ID, KeyID: &other.ID}))
+	assert.Equal(t, []int64{3}, counts(&quotapool.UsageFilter{PoolID: pro.ID, Period: quotapool.PeriodMonth}))
+	assert.Equal(t, []int64{7}, counts(&quotapool.UsageFilter{KeyID: &other.ID}), "key quotas are counted apart from pools")
+	list, err := s.QuotaPools().ListUsage(ctx(), &quotapool.UsageFilter{PoolID: pro.ID, KeyID: &member.ID, Period: quotapool.PeriodDay})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, pro.ID.String(), list[0].PoolID.String())
+	assert.Equal(t, member.ID.String(), list[0].KeyID.String())
+	assert.True(t, list[0].WindowStart.Equal(day))
+
+	require.NoError(t, s.QuotaPools().Delete(ctx(), pro.ID))
+	_, err = s.QuotaPools().Get(ctx(), pro.ID)
+	assert.Error(t, err)
+	assert.Error(t, s.QuotaPools().Delete(ctx(), pro.ID))
+	k, err = s.Keys().Get(ctx(), member.ID)
+	require.NoError(t, err)
+	assert.Nil(t, k.PoolID, "members are detached")
+	assert.Empty(t, counts(&quotapool.UsageFilter{PoolID: pro.ID}))
+	assert.Equal(t, []int64{7}, counts(&quotapool.UsageFilter{KeyID: &other.ID}))
+
+	old := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, s.QuotaPools().AddUsage(ctx(), []*quotapool.Usage{
+		{PoolID: basic.ID, KeyID: other.ID, Period: quotapool.PeriodMonth, WindowStart: old, Count: 1},
+	}))
+	n, err := s.QuotaPools().PurgeUsage(ctx(), old.Add(time.Hour))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, n, int64(1))
+	assert.Empty(t, counts(&quotapool.UsageFilter{PoolID: basic.ID}))
+	assert.Equal(t, []int64{7}, counts(&quotapool.UsageFilter{KeyID: &other.ID}))
+}
+
+func testUsageEnvironments(t *testing.T, s store.Store) { CheckUsageEnvironments(t, s, "t1") }
+
+// CheckUsageEnvironments checks that usage records keep their Environment
+// and that usage.QueryFilter.Environment restricts Query and Count to it,
+// and is accepted by Aggregate. Backends whose tests share a database call
+// it with a tenant of their own.
+func CheckUsageEnvironments(t *testing.T, s store.Store, tenantID string) {
+	t.Helper()
+	live := NewKey(tenantID, "sk_live_"+tenantID+"_env00001")
+	live.Environment = key.EnvLive
+	sandbox := NewKey(tenantID, "sk_test_"+tenantID+"_env00002")
+	create(t, s, live, sandbox)
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	var recs []*usage.Record
+	for i, k := range []*key.Key{live, sandbox, sandbox} {
+		recs = append(recs, &usage.Record{
+			ID:          id.NewUsageID(),
+			KeyID:       k.ID,
+			TenantID:    tenantID,
+			AppID:       k.AppID,
+			Environment: k.Environment,
+			Endpoint:    "/v1/things",
+			Method:      "GET",
+			StatusCode:  200,
+			CreatedAt:   now.Add(time.Duration(i) * time.Millisecond),
+		})
+	}
+	require.NoError(t, s.Usages().RecordBatch(ctx(), recs))
+
+	filter := &usage.QueryFilter{TenantID: tenantID, Environment: key.EnvTest}
+	got, err := s.Usages().Query(ctx(), filter)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	for _, rec := range got {
+		assert.Equal(t, sandbox.ID.String(), rec.KeyID.String())
+		assert.Equal(t, key.EnvTest, rec.Environment)
+	}
+	n, err := s.Usages().Count(ctx(), filter)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+
+	n, err = s.Usages().Count(ctx(), &usage.QueryFilter{TenantID: tenantID, Environment: key.EnvLive})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+	n, err = s.Usages().Count(ctx(), &usage.QueryFilter{TenantID: tenantID})
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, n)
+
+	_, err = s.Usages().Aggregate(ctx(), &usage.QueryFilter{TenantID: tenantID, KeyID: &live.ID, Environment: key.EnvLive})
+	require.NoError(t, err)
+}
+
+func testTenantStorage(t *testing.T, s store.Store) {
+	if _, ok := store.As[store.StorageReporter](s); !ok {
+		t.Skip("store does not implement store.StorageReporter")
+	}
+	CheckTenantStorage(t, s, "t1")
+}
+
+// CheckTenantStorage checks that the StorageReporter of s counts the keys
+// by state, policies, scopes, rotations, and usage records of two
+// tenants named after prefix apart, that a tenant with more usage has a
+// larger UsageBytes, that a report of every tenant includes both in tenant
+// order, and that a tenant without rows yields no entry. Backends whose
+// tests share a database call it with a prefix of their own.
+func CheckTenantStorage(t *testing.T, s store.Store, prefix string) {
+	t.Helper()
+	r, ok := store.As[store.StorageReporter](s)
+	require.True(t, ok, "store implements store.StorageReporter")
+	busy, quiet := prefix+"-busy", prefix+"-quiet"
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	active := NewKey(busy, "sk_test_"+prefix+"_storage01")
+	active2 := NewKey(busy, "sk_test_"+prefix+"_storage02")
+	revoked := NewKey(busy, "sk_test_"+prefix+"_storage03")
+	revoked.State = key.StateRevoked
+	suspended := NewKey(quiet, "sk_test_"+prefix+"_storage04")
+	suspended.State = key.StateSuspended
+	create(t, s, active, active2, revoked, suspended)
+
+	require.NoError(t, s.Policies().Create(ctx(), &policy.Policy{
+		ID: id.NewPolicyID(), TenantID: busy, AppID: "app_conformance", Name: "Storage",
+		RateLimit: 10, RateLimitWindow: time.Minute, CreatedAt: now, UpdatedAt: now,
+	}))
+	for _, name := range []string{"read:storage", "write:storage"} {
+		require.NoError(t, s.Scopes().Create(ctx(), &scope.Scope{
+			ID: id.NewScopeID(), TenantID: busy, AppID: "app_conformance", Name: name, CreatedAt: now,
+		}))
+	}
+	require.NoError(t, s.Rotations().Create(ctx(), &rotation.Record{
+		ID: id.NewRotationID(), KeyID: active.ID, TenantID: busy, AppID: active.AppID,
+		OldKeyHash: active.KeyHash, NewKeyHash: Hash("sk_test_" + prefix + "_storage05"),
+		Reason: rotation.ReasonManual, GraceTTL: time.Hour, GraceEnds: now.Add(time.Hour), CreatedAt: now,
+	}))
+	var recs []*usage.Record
+	for i, k := range []*key.Key{active, active, active, active2, suspended} {
+		recs = append(recs, &usage.Record{
+			ID: id.NewUsageID(), KeyID: k.ID, TenantID: k.TenantID, AppID: k.AppID, Environment: k.Environment,
+			Endpoint: "/v1/things", Method: "GET", StatusCode: 200, IPAddress: "203.0.113.7", UserAgent: "storetest/1.0",
+			CreatedAt: now.Add(time.Duration(i) * time.Millisecond),
+		})
+	}
+	require.NoError(t, s.Usages().RecordBatch(ctx(), recs))
+
+	got, err := r.TenantStorage(ctx(), busy)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	b := got[0]
+	assert.Equal(t, busy, b.TenantID)
+	assert.EqualValues(t, 3, b.Keys)
+	assert.Equal(t, map[key.State]int64{key.StateActive: 2, key.StateRevoked: 1}, b.KeysByState)
+	assert.EqualValues(t, 1, b.Policies)
+	assert.EqualValues(t, 2, b.Scopes)
+	assert.EqualValues(t, 1, b.Rotations)
+	assert.EqualValues(t, 4, b.UsageRows)
+	assert.Positive(t, b.UsageBytes)
+
+	got, err = r.TenantStorage(ctx(), quiet)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	q := got[0]
+	assert.EqualValues(t, 1, q.Keys)
+	assert.Equal(t, map[key.State]int64{key.StateSuspended: 1}, q.KeysByState)
+	assert.Zero(t, q.Policies)
+	assert.Zero(t, q.Scopes)
+	assert.Zero(t, q.Rotations)
+	assert.EqualValues(t, 1, q.UsageRows)
+	assert.Less(t, q.UsageBytes, b.UsageBytes, "bytes follow usage volume")
+
+	all, err := r.TenantStorage(ctx(), "")
+	require.NoError(t, err)
+	var tenants []string
+	for _, ts := range all {
+		if ts.TenantID == busy || ts.TenantID == quiet {
+			tenants = append(tenants, ts.TenantID)
+			want := map[string]*store.TenantStorage{busy: b, quiet: q}[ts.TenantID]
+			assert.Equal(t, want.KeysByState, ts.KeysByState)
+			assert.Equal(t, want.UsageRows, ts.UsageRows)
+		}
+	}
+	assert.Equal(t, []string{busy, quiet}, tenants, "every tenant, in tenant order")
+	for i := 1; i < len(all); i++ {
+		assert.Less(t, all[i-1].TenantID, all[i].TenantID)
+	}
+
+	got, err = r.TenantStorage(ctx(), prefix+"-empty")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func testLocks(t *testing.T, s store.Store) {
+	l, ok := store.As[store.Locker](s)
+	if !ok {
+		t.Skip("store does not implement store.Locker")
+	}
+	CheckLocker(t, l, "job")
+}
+
+// CheckLocker checks the locks of l under names starting with prefix: that
+// a held lock is refused to other holders, that renewal and release apply
+// only to the current holder, that an expired lock is taken over, and that
+// of concurrent acquisitions exactly one succeeds.
+func CheckLocker(t *testing.T, l store.Locker, prefix string) {
+	t.Helper()
+	name := prefix + ":a"
+
+	a, err := l.AcquireLock(ctx(), name, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, name, a.Name())
+	_, err = l.AcquireLock(ctx(), name, time.Minute)
+	require.ErrorIs(t, err, store.ErrLockHeld)
+	other, err := l.AcquireLock(ctx(), prefix+":b", time.Minute)
+	require.NoError(t, err, "locks are per name")
+	require.NoError(t, other.Release(ctx()))
+	require.NoError(t, a.Renew(ctx(), time.Minute))
+
+	require.NoError(t, a.Release(ctx()))
+	b, err := l.AcquireLock(ctx(), name, time.Minute)
+	require.NoError(t, err, "a released lock is free")
+	require.ErrorIs(t, a.Renew(ctx(), time.Minute), store.ErrLockLost)
+	require.NoError(t, a.Release(ctx()), "releasing a lost lock is a no-op")
+	_, err = l.AcquireLock(ctx(), name, time.Minute)
+	require.ErrorIs(t, err, store.ErrLockHeld, "a stale release leaves the new holder's lock alone")
+	require.NoError(t, b.Release(ctx()))
+
+	// A holder that stops renewing, as a crashed one does, loses the lock
+	// once its TTL passes.
+	crashed, err := l.AcquireLock(ctx(), name, 100*time.Millisecond)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		c, err := l.AcquireLock(ctx(), name, time.Minute)
+		if err != nil {
+			return false
+		}
+		b = c
+		return true
+	}, 5*time.Second, 50*time.Millisecond, "an expired lock is taken over")
+	require.ErrorIs(t, crashed.Renew(ctx(), time.Minute), store.ErrLockLost)
+	require.NoError(t, b.Release(ctx()))
+
+	const contenders = 10
+	var wg sync.WaitGroup
+	locks := make([]store.Lock, contenders)
+	errs := make([]error, contenders)
+	for i := range contenders {
+		wg.Go(func() {
+			locks[i], errs[i] = l.AcquireLock(ctx(), prefix+":race", time.Minute)
+		})
+	}
+	wg.Wait()
+	won := 0
+	for i, err := range errs {
+		if err == nil {
+			won++
+			require.NoError(t, locks[i].Release(ctx()))
+			continue
+		}
+		assert.ErrorIs(t, err, store.ErrLockHeld)
+	}
+	assert.Equal(t, 1, won, "exactly one concurrent acquisition succeeds")
+}
+
+func testInstallID(t *testing.T, s store.Store) {
+	is, ok := store.As[store.InstallIDStore](s)
+	if !ok {
+		t.Skip("store does not implement store.InstallIDStore")
+	}
+	got, err := is.InstallID(ctx(), "install-first")
+	require.NoError(t, err)
+	assert.Equal(t, "install-first", got, "a new store keeps the first candidate")
+	CheckInstallID(t, is)
+}
+
+// CheckInstallID checks that is keeps one install ID: later and concurrent
+// calls with other candidates all return the ID stored first. It does not
+// check that a new store stores the first candidate, so that backends whose
+// tests share a database can call it.
+func CheckInstallID(t *testing.T, is store.InstallIDStore) {
+	t.Helper()
+	first, err := is.InstallID(ctx(), "install-"+id.NewKeyID().String())
+	require.NoError(t, err)
+	require.NotEmpty(t, first)
+
+	got, err := is.InstallID(ctx(), "install-"+id.NewKeyID().String())
+	require.NoError(t, err)
+	assert.Equal(t, first, got, "a stored install ID is kept")
+
+	const n = 8
+	ids := make([]string, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := is.InstallID(ctx(), fmt.Sprintf("install-concurrent-%d", i))
+			if err != nil {
+				got = err.Error()
+			}
+			ids[i] = got
+		}()
+	}
+	wg.Wait()
+	for i, got := range ids {
+		assert.Equal(t, first, got, "concurrent call %d", i)
+	}
+}
+
+func testContextCancellation(t *testing.T, s store.Store) { CheckContextCancellation(t, s, "t1") }
+
+// CheckContextCancellation checks that every method of the sub-stores, of s
+// itself, and of the optional Maintainer, StorageReporter, IDMigrator,
+// InstallIDStore, Locker, and Lock interfaces returns context.Canceled for a canceled
+// context, and that key.Store.Iterate stops before the key after its
+// context is canceled.
+// Backends whose tests share a database call it with a tenant of their own.
+func CheckContextCancellation(t *testing.T, s store.Store, tenantID string) {
+	t.Helper()
+	canceled, cancel := context.WithCancel(ctx())
+	cancel()
+
+	sv := reflect.ValueOf(&s).Elem()
+	checkCanceled(t, sv, canceled)
+	for i := range sv.NumMethod() {
+		// The sub-store accessors, such as Keys, but not Close.
+		m := sv.Type().Method(i).Type
+		if m.NumIn() == 0 && m.NumOut() == 1 && m.Out(0).Kind() == reflect.Interface && m.Out(0) != reflect.TypeFor[error]() {
+			checkCanceled(t, sv.Method(i).Call(nil)[0], canceled)
+		}
+	}
+	if m, ok := store.As[store.Maintainer](s); ok {
+		checkCanceled(t, reflect.ValueOf(&m).Elem(), canceled)
+	}
+	if r, ok := store.As[store.StorageReporter](s); ok {
+		checkCanceled(t, reflect.ValueOf(&r).Elem(), canceled)
+	}
+	if m, ok := store.As[store.IDMigrator](s); ok {
+		checkCanceled(t, reflect.ValueOf(&m).Elem(), canceled)
+	}
+	if is, ok := store.As[store.InstallIDStore](s); ok {
+		checkCanceled(t, reflect.ValueOf(&is).Elem(), canceled)
+	}
+	if l, ok := store.As[store.Locker](s); ok {
+		checkCanceled(t, reflect.ValueOf(&l).Elem(), canceled)
+		lock, err := l.AcquireLock(ctx(), tenantID+":canceled", time.Minute)
+		require.NoError(t, err)
+		checkCanceled(t, reflect.ValueOf(&lock).Elem(), canceled)
+		require.NoError(t, lock.Release(ctx()))
+	}
+
+	for i := range 5 {
+		create(t, s, NewKey(tenantID, fmt.Sprintf("sk_test_%s_cancel%04d", tenantID, i)))
+	}
+	iterCtx, stop := context.WithCancel(ctx())
+	defer stop()
+	visited := 0
+	err := s.Keys().Iterate(iterCtx, &key.ListFilter{TenantID: tenantID}, func(*key.Key) error {
+		visited++
+		if visited == 2 {
+			stop()
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 2, visited, "Iterate stops before the key after the cancellation")
+}
+
+// checkCanceled calls every method of the interface value v that takes a
+// context first and returns an error last with ctx and zero arguments, and
+// checks that it returns context.Canceled.
+func checkCanceled(t *testing.T, v reflect.Value, ctx context.Context) {
+	t.Helper()
+	contextType, errorType := reflect.TypeFor[context.Context](), reflect.TypeFor[error]()
+	for i := range v.NumMethod() {
+		m := v.Type().Method(i)
+		ft := m.Type
+		if ft.NumIn() == 0 || ft.In(0) != contextType || ft.NumOut() == 0 || ft.Out(ft.NumOut()-1) != errorType {
+			continue
+		}
+		args := []reflect.Value{reflect.ValueOf(ctx)}
+		for j := 1; j < ft.NumIn(); j++ {
+			args = append(args, zeroArg(ft.In(j)))
+		}
+		var out []reflect.Value
+		if ft.IsVariadic() {
+			out = v.Method(i).CallSlice(args)
+		} else {
+			out = v.Method(i).Call(args)
+		}
+		err, _ := out[len(out)-1].Interface().(error)
+		assert.ErrorIs(t, err, context.Canceled, "%s.%s", v.Type(), m.Name)
+	}
+}
+
+// zeroArg returns a zero argument of type typ that is safe to use: a pointer
+// to a zero value, a function returning zero values, or the zero value.
+func zeroArg(typ reflect.Type) reflect.Value {
+	switch typ.Kind() {
+	case reflect.Pointer:
+		return reflect.New(typ.Elem())
+	case reflect.Func:
+		return reflect.MakeFunc(typ, func([]reflect.Value) []reflect.Value {
+			out := make([]reflect.Value, typ.NumOut())
+			for i := range out {
+				out[i] = reflect.Zero(typ.Out(i))
+			}
+			return out
+		})
+	default:
+		return reflect.Zero(typ)
+	}
+}
@@ -0,0 +1,367 @@
+package keysmith
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/xraph/go-utils/log"
+
+	"github.com/xraph/keysmith/store"
+)
+
+const (
+	// DefaultTelemetryInterval is how often WithTelemetry sends a payload.
+	DefaultTelemetryInterval = 24 * time.Hour
+
+	// TelemetryDisableEnv is the environment variable that turns telemetry
+	// off whatever the engine options say: set it to a true value such as
+	// "1" or "true". DO_NOT_TRACK is honored the same way.
+	TelemetryDisableEnv = "KEYSMITH_TELEMETRY_DISABLED"
+
+	// TelemetrySchemaVersion is the version of TelemetryPayload, raised when
+	// its fields change.
+	TelemetrySchemaVersion = 1
+)
+
+// modulePath is the keysmith module, looked up in the build info for the
+// version telemetry reports.
+const modulePath = "github.com/xraph/keysmith"
+
+// TelemetryOptions configures [WithTelemetry]. Zero fields use the
+// defaults.
+type TelemetryOptions struct {
+	// Interval is how often a payload is sent. Defaults to
+	// [DefaultTelemetryInterval].
+	Interval time.Duration `json:"interval" mapstructure:"interval" yaml:"interval"`
+
+	// DryRun logs each payload, exactly as it would be sent, instead of
+	// sending it. The endpoint may then be empty.
+	DryRun bool `json:"dry_run" mapstructure:"dry_run" yaml:"dry_run"`
+
+	// Client sends the payloads. Defaults to a client with a ten second
+	// timeout.
+	Client *http.Client `json:"-" mapstructure:"-" yaml:"-"`
+}
+
+func (o *TelemetryOptions) validate(endpoint string) error {
+	if o.Interval < 0 {
+		return fmt.Errorf("%w: interval must not be negative", ErrInvalidTelemetry)
+	}
+	if o.DryRun && endpoint == "" {
+		return nil
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: endpoint %q is not an absolute http or https URL", ErrInvalidTelemetry, endpoint)
+	}
+	return nil
+}
+
+// TelemetryPayload is what WithTelemetry sends. It is built only from the
+// fields below, none of which names or counts a tenant, app, key, or
+// endpoint: the install ID is random, feature flags are booleans, and
+// counts are orders of magnitude.
+type TelemetryPayload struct {
+	// SchemaVersion is TelemetrySchemaVersion.
+	SchemaVersion int `json:"schema_version"`
+
+	// InstallID is drawn at random once and kept in the store, so that the
+	// engines sharing a store report as one install. Engines whose store
+	// does not implement store.InstallIDStore draw one each.
+	InstallID string `json:"install_id"`
+
+	// Version is the keysmith module version, or "unknown".
+	Version string `json:"version"`
+
+	// Store is the store driver: the name of a keysmith store package,
+	// such as "postgres", or "custom" for any other.
+	Store string `json:"store"`
+
+	// Features reports which engine options are in use.
+	Features map[string]bool `json:"features"`
+
+	// Counts holds entity counts as magnitude buckets. Nil when the store
+	// does not implement store.StorageReporter.
+	Counts *TelemetryCounts `json:"counts,omitempty"`
+}
+
+// TelemetryCounts holds entity counts as buckets: "0", "1-9", "10-99", and
+// so on up to "1000000+".
+type TelemetryCounts struct {
+	Tenants      string `json:"tenants"`
+	Keys         string `json:"keys"`
+	Policies     string `json:"policies"`
+	UsageRecords string `json:"usage_records"`
+}
+
+// TelemetryPayload builds the payload WithTelemetry sends, whether or not
+// telemetry is on, so that it can be reviewed before opting in. Counting
+// scans the store as TenantStorageReport does. It requires a system-scoped
+// context.
+func (e *Engine) TelemetryPayload(ctx context.Context) (*TelemetryPayload, error) {
+	if scopeFromContext(ctx) != (tenantScope{}) {
+		return nil, fmt.Errorf("%w: telemetry payload", ErrSystemScopeRequired)
+	}
+	installID, err := e.telemetryInstallID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p := &TelemetryPayload{
+		SchemaVersion: TelemetrySchemaVersion,
+		InstallID:     installID,
+		Version:       moduleVersion(),
+		Store:         storeDriver(e.store),
+		Features:      e.telemetryFeatures(),
+	}
+	if r, ok := store.As[store.StorageReporter](e.store); ok {
+		tenants, err := r.TenantStorage(ctx, "")
+		if err != nil {
+			return nil, fmt.Errorf("tenant storage: %w", err)
+		}
+		var keys, policies, usage int64
+		for _, ts := range tenants {
+			keys += ts.Keys
+			policies += ts.Policies
+			usage += ts.UsageRows
+		}
+		p.Counts = &TelemetryCounts{
+			Tenants:      magnitudeBucket(int64(len(tenants))),
+			Keys:         magnitudeBucket(keys),
+			Policies:     magnitudeBucket(policies),
+			UsageRecords: magnitudeBucket(usage),
+		}
+	}
+	return p, nil
+}
+
+// telemetryInstallID returns the install ID, read from or created in the
+// store on first use.
+func (e *Engine) telemetryInstallID(ctx context.Context) (string, error) {
+	e.installMu.Lock()
+	defer e.installMu.Unlock()
+	if e.installID != "" {
+		return e.installID, nil
+	}
+	installID := rand.Text()
+	if s, ok := store.As[store.InstallIDStore](e.store); ok {
+		var err error
+		if installID, err = s.InstallID(ctx, installID); err != nil {
+			return "", fmt.Errorf("install id: %w", err)
+		}
+	}
+	e.installID = installID
+	return installID, nil
+}
+
+// telemetryFeatures reports which options the engine was configured with.
+// Keys are fixed here, never derived from configuration values.
+func (e *Engine) telemetryFeatures() map[string]bool {
+	_, locker := store.As[store.Locker](e.store)
+	return map[string]bool{
+		"validation_cache":        e.cache != nil,
+		"cache_warmup":            e.warmup != nil,
+		"rate_limiter":            e.customLimiter,
+		"strict_quotas":           e.quotas.strict,
+		"authorizer":              e.authorizer != nil,
+		"authorizer_cache":        e.authzCache != nil,
+		"failure_fingerprinting":  e.failures != nil,
+		"endpoint_activity":       e.endpoints != nil,
+		"read_only":               e.ReadOnly(),
+		"adaptive_limiting":       e.adaptive != nil,
+		"replay_protection":       e.replay != nil,
+		"slo":                     e.slo != nil,
+		"live_stats":              e.liveStats != nil,
+		"live_debug_capture":      e.liveCapture,
+		"usage_recording_policy":  e.recordingOpt != nil,
+		"terms":                   e.termsVersion != "",
+		"prefix_rules":            len(e.prefixRules) > 0,
+		"strict_prefixes":         e.strictPrefixes,
+		"environment_profiles":    len(e.envProfiles) > 0,
+		"key_formats":             len(e.keyFormats) > 0,
+		"key_format_deprecations": len(e.formatDeprecations) > 0,
+		"id_format":               e.idFormat != nil,
+		"store_maintenance":       e.maintenance.period() > 0,
+		"quota_forecasts":         e.quotaForecasts.period() > 0,
+		"storage_reports":         e.storageReports.period() > 0,
+		"idle_suspension":         e.idleSweep.period() > 0,
+		"job_locks":               locker && e.jobLockTTL > 0,
+		"validators":              len(e.createValidators)+len(e.updateValidators)+len(e.rotateValidators) > 0,
+		"encrypted_store":         wrapsStore(e.store, "encrypted"),
+		"chaos_store":             wrapsStore(e.store, "chaos"),
+		"fips":                    FIPSMode,
+	}
+}
+
+// runTelemetry is the body of the telemetry job.
+func (e *Engine) runTelemetry(ctx context.Context) {
+	p, err := e.TelemetryPayload(ctx)
+	if err != nil {
+		e.logger.Warn("telemetry payload failed", log.Any("error", err))
+		return
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		e.logger.Warn("telemetry payload failed", log.Any("error", err))
+		return
+	}
+	if e.telemetryOpt.DryRun {
+		e.logger.Info("telemetry dry run: payload not sent", log.String("payload", string(body)))
+		return
+	}
+	if err := e.sendTelemetry(ctx, body); err != nil {
+		e.logger.Warn("telemetry not sent", log.Any("error", err))
+	}
+}
+
+func (e *Engine) sendTelemetry(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.telemetryEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "keysmith/"+moduleVersion())
+	resp, err := e.telemetryOpt.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("endpoint responded %s", resp.Status)
+	}
+	return nil
+}
+
+// telemetryDisabled reports whether the kill switch is set in the
+// environment.
+func telemetryDisabled() bool {
+	for _, name := range []string{TelemetryDisableEnv, "DO_NOT_TRACK"} {
+		if v, err := strconv.ParseBool(os.Getenv(name)); err == nil && v {
+			return true
+		}
+	}
+	return false
+}
+
+// magnitudeBucket reports n as its order of magnitude.
+func magnitudeBucket(n int64) string {
+	if n <= 0 {
+		return "0"
+	}
+	lo := int64(1)
+	for range 6 {
+		if n < lo*10 {
+			return strconv.FormatInt(lo, 10) + "-" + strconv.FormatInt(lo*10-1, 10)
+		}
+		lo *= 10
+	}
+	return "1000000+"
+}
+
+// moduleVersion returns the keysmith version from the build info. Replaced
+// modules report the version they replace, never the replacement path.
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	version := ""
+	if info.Main.Path == modulePath {
+		version = info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			version = dep.Version
+		}
+	}
+	if !strings.HasPrefix(version, "v") {
+		return "unknown"
+	}
+	return version
+}
+
+// storeDriver names the innermost store of s by its keysmith package, or
+// "custom" for a store from any other package, whose path could identify
+// the install.
+func storeDriver(s store.Store) string {
+	for s != nil {
+		u, ok := s.(store.Unwrapper)
+		if !ok {
+			break
+		}
+		s = u.Unwrap()
+	}
+	if name, ok := storePackage(s); ok {
+		return name
+	}
+	return "custom"
+}
+
+// wrapsStore reports whether a store in the Unwrap chain of s comes from
+// the keysmith store package name.
+func wrapsStore(s store.Store, name string) bool {
+	for s != nil {
+		if pkg, ok := storePackage(s); ok && pkg == name {
+			return true
+		}
+		u, ok := s.(store.Unwrapper)
+		if !ok {
+			break
+		}
+		s = u.Unwrap()
+	}
+	return false
+}
+
+// storePackage returns the name of the keysmith store package s is
+// declared in.
+func storePackage(s store.Store) (string, bool) {
+	t := reflect.TypeOf(s)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil {
+		return "", false
+	}
+	name, ok := strings.CutPrefix(t.PkgPath(), modulePath+"/store/")
+	if !ok || name == "" || strings.Contains(name, "/") {
+		return "", false
+	}
+	return name, true
+}
+
+// initTelemetry checks the WithTelemetry options and schedules the job,
+// unless the kill switch is set.
+func (e *Engine) initTelemetry() error {
+	o := e.telemetryOpt
+	if o == nil {
+		return nil
+	}
+	if err := o.validate(e.telemetryEndpoint); err != nil {
+		return err
+	}
+	if telemetryDisabled() {
+		e.logger.Info("telemetry disabled by the environment", log.String("env", TelemetryDisableEnv))
+		return nil
+	}
+	if o.Interval == 0 {
+		o.Interval = DefaultTelemetryInterval
+	}
+	if o.Client == nil {
+		o.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	e.telemetry.interval = o.Interval
+	return nil
+}
@@ -0,0 +1,293 @@
+package keysmith_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	log "github.com/xraph/go-utils/log"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+var telemetryBucket = regexp.MustCompile(`^(0|1-9|10-99|100-999|1000-9999|10000-99999|100000-999999|1000000\+)$`)
+
+// TestTelemetryPayload_NoSensitiveFields seeds an engine with identifying
+// names and secrets in every place the payload could draw from, and checks
+// that none of them reaches the marshaled payload and that it holds only
+// the allowed fields.
+func TestTelemetryPayload_NoSensitiveFields(t *testing.T) {
+	secrets := []string{
+		"tenant-acme-secret", "app-acme-secret", "Acme Billing Key", "owner@acme.example",
+		"meta-secret-value", "terms-2024-acme", "acmeprefix", "ip-hash-secret-acme",
+		"telemetry-token-acme", "Acme Gold Policy", "/v1/acme/private",
+	}
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithTermsVersion("terms-2024-acme"),
+		keysmith.WithPrefixRule("acmeprefix", keysmith.PrefixRule{DefaultPolicyName: "Acme Gold Policy"}),
+		keysmith.WithIPHashSecret([]byte("ip-hash-secret-acme")),
+		keysmith.WithTelemetry("https://telemetry.example/ingest?token=telemetry-token-acme", keysmith.TelemetryOptions{}),
+	)
+	require.NoError(t, err)
+	ctx := keysmith.WithTenant(context.Background(), "app-acme-secret", "tenant-acme-secret")
+	require.NoError(t, eng.CreatePolicy(ctx, &policy.Policy{Name: "Acme Gold Policy", RateLimit: 10, RateLimitWindow: time.Minute}))
+	res, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
+		Name:                 "Acme Billing Key",
+		Prefix:               "acmeprefix",
+		Environment:          key.EnvLive,
+		Metadata:             map[string]any{"owner": "owner@acme.example", "note": "meta-secret-value"},
+		AcceptedTermsVersion: "terms-2024-acme",
+	})
+	require.NoError(t, err)
+	secrets = append(secrets, res.RawKey, res.Key.ID.String(), res.Key.KeyHash)
+	_, err = eng.ValidateKey(ctx, res.RawKey)
+	require.NoError(t, err)
+
+	p, err := eng.TelemetryPayload(context.Background())
+	require.NoError(t, err)
+	data, err := json.Marshal(p)
+	require.NoError(t, err)
+	for _, s := range secrets {
+		assert.NotContains(t, string(data), s)
+	}
+	bare := strings.ToLower(res.Key.ID.UUID())
+	assert.NotContains(t, strings.ToLower(string(data)), bare)
+
+	var doc map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(data, &doc))
+	allowed := map[string]bool{"schema_version": true, "install_id": true, "version": true, "store": true, "features": true, "counts": true}
+	for field := range doc {
+		assert.True(t, allowed[field], "unexpected field %q", field)
+	}
+
+	var features map[string]any
+	require.NoError(t, json.Unmarshal(doc["features"], &features))
+	for name, v := range features {
+		assert.Regexp(t, `^[a-z_]+$`, name)
+		assert.IsType(t, true, v, "feature %q", name)
+	}
+	assert.Equal(t, true, features["terms"])
+	assert.Equal(t, true, features["prefix_rules"])
+
+	var counts map[string]any
+	require.NoError(t, json.Unmarshal(doc["counts"], &counts))
+	assert.Len(t, counts, 4)
+	for name, v := range counts {
+		s, ok := v.(string)
+		require.True(t, ok, "count %q is not a bucket", name)
+		assert.Regexp(t, telemetryBucket, s, "count %q", name)
+	}
+	assert.Equal(t, "1-9", counts["keys"])
+
+	assert.Equal(t, keysmith.TelemetrySchemaVersion, p.SchemaVersion)
+	assert.Equal(t, "memory", p.Store)
+	assert.NotEmpty(t, p.InstallID)
+	assert.NotEmpty(t, p.Version)
+}
+
+func TestTelemetryPayload_CustomStore(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(plainStore{memory.New()}))
+	require.NoError(t, err)
+
+	p, err := eng.TelemetryPayload(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "custom", p.Store, "the package path of a custom store is not reported")
+	assert.Nil(t, p.Counts)
+	assert.NotEmpty(t, p.InstallID)
+}
+
+func TestTelemetryPayload_RateLimiterFeature(t *testing.T) {
+	for name, tc := range map[string]struct {
+		opts []keysmith.Option
+		want bool
+	}{
+		"default limiter":    {nil, false},
+		"configured limiter": {[]keysmith.Option{keysmith.WithRateLimiter(newCountingLimiter())}, true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			eng, err := keysmith.NewEngine(append(tc.opts, keysmith.WithStore(memory.New()))...)
+			require.NoError(t, err)
+			p, err := eng.TelemetryPayload(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, p.Features["rate_limiter"])
+		})
+	}
+}
+
+func TestTelemetryPayload_RequiresSystemScope(t *testing.T) {
+	eng := newTestEngine(t)
+
+	_, err := eng.TelemetryPayload(testCtx())
+	assert.ErrorIs(t, err, keysmith.ErrSystemScopeRequired)
+}
+
+func TestTelemetryPayload_InstallIDPersisted(t *testing.T) {
+	ms := memory.New()
+	first, err := keysmith.NewEngine(keysmith.WithStore(ms))
+	require.NoError(t, err)
+	second, err := keysmith.NewEngine(keysmith.WithStore(ms))
+	require.NoError(t, err)
+
+	a, err := first.TelemetryPayload(context.Background())
+	require.NoError(t, err)
+	b, err := second.TelemetryPayload(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, a.InstallID, b.InstallID, "engines sharing a store share the install ID")
+	again, err := first.TelemetryPayload(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, a.InstallID, again.InstallID)
+}
+
+// telemetryServer records the payloads posted to it.
+type telemetryServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	payloads []keysmith.TelemetryPayload
+	agents   []string
+}
+
+func newTelemetryServer(t *testing.T) *telemetryServer {
+	t.Helper()
+	s := &telemetryServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var p keysmith.TelemetryPayload
+		if err := json.Unmarshal(body, &p); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		s.payloads = append(s.payloads, p)
+		s.agents = append(s.agents, r.Header.Get("User-Agent"))
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *telemetryServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.payloads)
+}
+
+func TestWithTelemetry_Sends(t *testing.T) {
+	srv := newTelemetryServer(t)
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithTelemetry(srv.URL, keysmith.TelemetryOptions{Interval: 10 * time.Millisecond}),
+	)
+	require.NoError(t, err)
+	require.NoError(t, eng.Start(context.Background()))
+
+	require.Eventually(t, func() bool { return srv.count() >= 2 }, time.Second, 5*time.Millisecond)
+	require.NoError(t, eng.Stop(context.Background()))
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	assert.Equal(t, "memory", srv.payloads[0].Store)
+	assert.Equal(t, srv.payloads[0].InstallID, srv.payloads[1].InstallID)
+	assert.True(t, strings.HasPrefix(srv.agents[0], "keysmith/"))
+}
+
+func TestWithTelemetry_OffByDefault(t *testing.T) {
+	srv := newTelemetryServer(t)
+	eng := newTestEngine(t)
+	require.NoError(t, eng.Start(context.Background()))
+	time.Sleep(30 * time.Millisecond)
+	require.NoError(t, eng.Stop(context.Background()))
+	assert.Zero(t, srv.count())
+}
+
+func TestWithTelemetry_DryRun(t *testing.T) {
+	srv := newTelemetryServer(t)
+	logger := log.NewTestLogger().(*log.TestLogger)
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithLogger(logger),
+		keysmith.WithTelemetry(srv.URL, keysmith.TelemetryOptions{Interval: 10 * time.Millisecond, DryRun: true}),
+	)
+	require.NoError(t, err)
+	require.NoError(t, eng.Start(context.Background()))
+
+	require.Eventually(t, func() bool { return logger.AssertHasLog("INFO", "telemetry dry run: payload not sent") }, time.Second, 5*time.Millisecond)
+	require.NoError(t, eng.Stop(context.Background()))
+	assert.Zero(t, srv.count(), "a dry run sends nothing")
+
+	var logged string
+	for _, entry := range logger.GetLogs() {
+		if entry.Message == "telemetry dry run: payload not sent" {
+			logged = fieldValue(entry, "payload")
+		}
+	}
+	var p keysmith.TelemetryPayload
+	require.NoError(t, json.Unmarshal([]byte(logged), &p), "the exact payload is logged")
+	assert.Equal(t, "memory", p.Store)
+
+	_, err = keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithTelemetry("", keysmith.TelemetryOptions{DryRun: true}),
+	)
+	assert.NoError(t, err, "a dry run needs no endpoint")
+}
+
+// fieldValue returns the value of the logged field named name.
+func fieldValue(entry log.LogEntry, name string) string {
+	for _, v := range entry.Fields {
+		if f, ok := v.(log.Field); ok && f.Key() == name {
+			s, _ := f.Value().(string)
+			return s
+		}
+	}
+	return ""
+}
+
+func TestWithTelemetry_KillSwitch(t *testing.T) {
+	for _, env := range []string{keysmith.TelemetryDisableEnv, "DO_NOT_TRACK"} {
+		t.Run(env, func(t *testing.T) {
+			t.Setenv(env, "1")
+			srv := newTelemetryServer(t)
+			eng, err := keysmith.NewEngine(
+				keysmith.WithStore(memory.New()),
+				keysmith.WithTelemetry(srv.URL, keysmith.TelemetryOptions{Interval: 5 * time.Millisecond}),
+			)
+			require.NoError(t, err)
+			require.NoError(t, eng.Start(context.Background()))
+			time.Sleep(30 * time.Millisecond)
+			require.NoError(t, eng.Stop(context.Background()))
+			assert.Zero(t, srv.count())
+		})
+	}
+}
+
+func TestWithTelemetry_Invalid(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		endpoint string
+		opts     keysmith.TelemetryOptions
+	}{
+		{"empty endpoint", "", keysmith.TelemetryOptions{}},
+		{"relative endpoint", "/ingest", keysmith.TelemetryOptions{}},
+		{"other scheme", "ftp://telemetry.example", keysmith.TelemetryOptions{}},
+		{"negative interval", "https://telemetry.example", keysmith.TelemetryOptions{Interval: -time.Second}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithTelemetry(tc.endpoint, tc.opts))
+			assert.ErrorIs(t, err, keysmith.ErrInvalidTelemetry)
+		})
+	}
+}
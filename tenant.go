@@ -0,0 +1,202 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/xraph/keysmith/metaschema"
+	"github.com/xraph/keysmith/tenant"
+	"github.com/xraph/keysmith/usage"
+)
+
+// settingsTTL is how long tenant settings read from the store are cached.
+// Validation reads settings for the tenant rate limit, so they are cached,
+// but briefly, so that settings written by another instance, or directly in
+// the store, and a transient store error are picked up soon. A tenant
+// without stored settings is cached as such for as long.
+const settingsTTL = 30 * time.Second
+
+// settingsCache holds tenant settings in memory so that key creation and
+// validation do not hit the store for every call. Entries expire after
+// settingsTTL and are invalidated when settings change through the engine.
+type settingsCache struct {
+	mu      sync.RWMutex
+	entries map[string]settingsEntry
+}
+
+// settingsEntry is a cached settings value.
+type settingsEntry struct {
+	ts      *tenant.Settings
+	expires time.Time
+}
+
+func newSettingsCache() *settingsCache {
+	return &settingsCache{entries: make(map[string]settingsEntry)}
+}
+
+// get returns the cached settings of tenantID when they are fresh at now.
+func (c *settingsCache) get(tenantID string, now time.Time) (*tenant.Settings, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	ent, ok := c.entries[tenantID]
+	if !ok || !now.Before(ent.expires) {
+		return nil, false
+	}
+	return ent.ts, true
+}
+
+// put caches ts, read from the store at now, for settingsTTL.
+func (c *settingsCache) put(ts *tenant.Settings, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[ts.TenantID] = settingsEntry{ts: ts, expires: now.Add(settingsTTL)}
+}
+
+// putMiss caches empty settings for a tenant for settingsTTL.
+func (c *settingsCache) putMiss(tenantID string, now time.Time) *tenant.Settings {
+	ts := &tenant.Settings{TenantID: tenantID}
+	c.put(ts, now)
+	return ts
+}
+
+func (c *settingsCache) invalidate(tenantID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, tenantID)
+}
+
+// GetTenantSettings returns the settings for a tenant. A tenant without
+// stored settings gets an empty settings value rather than an error.
+func (e *Engine) GetTenantSettings(ctx context.Context, tenantID string) (*tenant.Settings, error) {
+	if tenantID == "" {
+		tenantID = scopeFromContext(ctx).tenantID
+	}
+	return cloneSettings(e.tenantSettings(ctx, tenantID)), nil
+}
+
+// SetTenantSettings creates or replaces the settings for a tenant. Every
+// default scope must already exist in the tenant, so a typo is rejected here
+// instead of failing each subsequent CreateKey. A rate limit requires a
+// window. The stored lockdown is kept whatever ts.Lockdown says; only
+// LockdownTenant and RecoverTenant change it.
+func (e *Engine) SetTenantSettings(ctx context.Context, ts *tenant.Settings) error {
+	if err := e.checkWritable(ctx); err != nil {
+		return err
+	}
+	if ts.RateLimit < 0 || ts.RateLimitWindow < 0 || (ts.RateLimit > 0 && ts.RateLimitWindow <= 0) {
+		return fmt.Errorf("%w: rate_limit must not be negative and needs a positive rate_limit_window", ErrInvalidTenantSettings)
+	}
+	if !ts.IPHandling.Valid() {
+		return fmt.Errorf("%w: unknown ip_handling %q", ErrInvalidTenantSettings, ts.IPHandling)
+	}
+	if _, err := usage.LoadQuotaLocation(ts.QuotaTimezone); err != nil {
+		return fmt.Errorf("%w: quota_timezone: %w", ErrInvalidTenantSettings, err)
+	}
+	if err := validateContacts(ts.DefaultContacts); err != nil {
+		return err
+	}
+	ts.DefaultContacts = ts.DefaultContacts.Normalize()
+	if ts.MetadataSchema != nil {
+		if err := ts.MetadataSchema.Check(); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidMetadataSchema, err)
+		}
+	}
+	sc := scopeFromContext(ctx)
+	if ts.TenantID == "" {
+		ts.TenantID = sc.tenantID
+	}
+	if ts.AppID == "" {
+		ts.AppID = sc.appID
+	}
+	ts.DefaultScopes = mergeScopes(ts.DefaultScopes, nil)
+
+	for _, name := range ts.DefaultScopes {
+		if _, err := e.store.Scopes().GetByName(ctx, ts.TenantID, name); err != nil {
+			return fmt.Errorf("%w: default scope %q", ErrScopeNotFound, name)
+		}
+	}
+
+	now := time.Now()
+	ts.CreatedAt = now
+	ts.Lockdown = nil
+	if existing, err := e.store.Tenants().GetSettings(ctx, ts.TenantID); err == nil {
+		ts.CreatedAt = existing.CreatedAt
+		ts.Lockdown = existing.Lockdown
+	}
+	ts.UpdatedAt = now
+
+	if err := e.store.Tenants().UpsertSettings(ctx, ts); err != nil {
+		return fmt.Errorf("save tenant settings: %w", err)
+	}
+	e.settings.invalidate(ts.TenantID)
+	e.bumpRevision(ctx, ts.TenantID)
+	return nil
+}
+
+// SetMetadataSchema replaces the metadata schema of a tenant and keeps its
+// other settings. A nil schema removes it. The schema applies to keys
+// written from now on; existing keys are not re-checked.
+func (e *Engine) SetMetadataSchema(ctx context.Context, tenantID string, schema *metaschema.Schema) (*tenant.Settings, error) {
+	if err := e.checkWritable(ctx); err != nil {
+		return nil, err
+	}
+	ts, err := e.GetTenantSettings(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	ts.MetadataSchema = schema.Clone()
+	if err := e.SetTenantSettings(ctx, ts); err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+// tenantSettings returns the cached settings for a tenant, loading them from
+// the store on a miss or once they are settingsTTL old. The returned value
+// is shared and must not be mutated.
+func (e *Engine) tenantSettings(ctx context.Context, tenantID string) *tenant.Settings {
+	now := e.now()
+	if ts, ok := e.settings.get(tenantID, now); ok {
+		return ts
+	}
+	ts, err := e.store.Tenants().GetSettings(ctx, tenantID)
+	if err != nil {
+		// Missing settings are the common case, and are cached like
+		// stored ones, so a transient store error is retried soon.
+		return e.settings.putMiss(tenantID, now)
+	}
+	e.settings.put(ts, now)
+	return ts
+}
+
+// mergeScopes returns base followed by any extra scopes not already present,
+// dropping duplicates and empty names.
+func mergeScopes(base, extra []string) []string {
+	if len(base) == 0 && len(extra) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool, len(base)+len(extra))
+	out := make([]string, 0, len(base)+len(extra))
+	for _, list := range [][]string{base, extra} {
+		for _, name := range list {
+			if name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+func cloneSettings(ts *tenant.Settings) *tenant.Settings {
+	cp := *ts
+	cp.DefaultScopes = append([]string(nil), ts.DefaultScopes...)
+	cp.DefaultContacts = slices.Clone(ts.DefaultContacts)
+	cp.MetadataSchema = ts.MetadataSchema.Clone()
+	cp.Lockdown = ts.Lockdown.Clone()
+	return &cp
+}
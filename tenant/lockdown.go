@@ -0,0 +1,85 @@
+package tenant
+
+import "time"
+
+// LockdownPhase is how far a tenant's lockdown has got.
+type LockdownPhase string
+
+const (
+	// LockdownLocked is a tenant whose keys were suspended by a lockdown
+	// and whose recovery has not started.
+	LockdownLocked LockdownPhase = "locked"
+
+	// LockdownRecovering is a tenant whose keys are being reactivated in
+	// batches.
+	LockdownRecovering LockdownPhase = "recovering"
+
+	// LockdownHalted is a recovery stopped because renewed trouble was
+	// detected. The tenant stays locked until the recovery is resumed.
+	LockdownHalted LockdownPhase = "halted"
+
+	// LockdownRecovered is a recovery that reactivated every key the
+	// lockdown suspended. The tenant is no longer locked.
+	LockdownRecovered LockdownPhase = "recovered"
+)
+
+// Lockdown marks a tenant locked down for an incident: its active keys
+// were suspended in bulk and no key can be created in it until it has
+// recovered.
+type Lockdown struct {
+	Phase   LockdownPhase `json:"phase"`
+	Reason  string        `json:"reason"`
+	ActorID string        `json:"actor_id,omitempty"`
+
+	// LockedAt is when the tenant was first locked down. Locking down a
+	// locked tenant again keeps it.
+	LockedAt time.Time `json:"locked_at"`
+
+	// Suspended counts the keys the lockdown suspended, including those
+	// suspended again by a later lockdown of the same incident.
+	Suspended int `json:"suspended"`
+
+	// Recovery is the staged recovery, once one has started.
+	Recovery *Recovery `json:"recovery,omitempty"`
+}
+
+// Recovery is the progress of a staged recovery from a lockdown.
+type Recovery struct {
+	StartedAt     time.Time     `json:"started_at"`
+	BatchSize     int           `json:"batch_size"`
+	BatchInterval time.Duration `json:"batch_interval"`
+
+	// WatchSince is when the recovery started or was last resumed. The
+	// anomaly check before each batch looks at what happened since then.
+	WatchSince time.Time `json:"watch_since"`
+
+	// NextBatchAt is when the next batch is due.
+	NextBatchAt time.Time `json:"next_batch_at"`
+
+	// Batches and Reactivated count the batches run and the keys they
+	// reactivated. Remaining is how many of the lockdown's keys were
+	// still suspended after the last batch.
+	Batches     int `json:"batches"`
+	Reactivated int `json:"reactivated"`
+	Remaining   int `json:"remaining"`
+
+	// HaltedAt and HaltReason are set while the recovery is halted.
+	HaltedAt   *time.Time `json:"halted_at,omitempty"`
+	HaltReason string     `json:"halt_reason,omitempty"`
+
+	// CompletedAt is set once every key has been reactivated.
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// Clone returns a deep copy of l. It returns nil for a nil l.
+func (l *Lockdown) Clone() *Lockdown {
+	if l == nil {
+		return nil
+	}
+	cp := *l
+	if l.Recovery != nil {
+		r := *l.Recovery
+		cp.Recovery = &r
+	}
+	return &cp
+}
@@ -0,0 +1,24 @@
+package tenant
+
+import "context"
+
+// Store is the persistence interface for tenant settings.
+type Store interface {
+	// GetSettings returns the settings for a tenant.
+	GetSettings(ctx context.Context, tenantID string) (*Settings, error)
+
+	// UpsertSettings creates or replaces the settings for a tenant.
+	UpsertSettings(ctx context.Context, s *Settings) error
+
+	// DeleteSettings removes the settings for a tenant.
+	DeleteSettings(ctx context.Context, tenantID string) error
+
+	// Revision returns the tenant's revision, a counter the engine bumps on
+	// every mutation of the tenant's keys, policies, scopes, and settings.
+	// A tenant that has never been bumped is at revision zero.
+	Revision(ctx context.Context, tenantID string) (uint64, error)
+
+	// BumpRevision atomically increments the tenant's revision and returns
+	// the new value.
+	BumpRevision(ctx context.Context, tenantID string) (uint64, error)
+}
@@ -0,0 +1,54 @@
+// Package tenant defines per-tenant settings applied by the engine.
+package tenant
+
+import (
+	"time"
+
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/metaschema"
+	"github.com/xraph/keysmith/usage"
+)
+
+// Settings holds tenant-wide configuration applied to every key created
+// within the tenant.
+type Settings struct {
+	TenantID      string   `json:"tenant_id" db:"tenant_id"`
+	AppID         string   `json:"app_id" db:"app_id"`
+	DefaultScopes []string `json:"default_scopes,omitempty" db:"default_scopes"`
+
+	// DefaultContacts receive notifications about the tenant's keys that
+	// have no contacts of their own.
+	DefaultContacts key.Contacts `json:"default_contacts,omitempty" db:"default_contacts"`
+
+	// MetadataSchema, when set, is checked against the metadata of every
+	// key written in the tenant. Existing keys are not re-checked.
+	MetadataSchema *metaschema.Schema `json:"metadata_schema,omitempty" db:"metadata_schema"`
+
+	// RateLimit caps validations across all of the tenant's keys per
+	// RateLimitWindow, on top of each key's own limit. Zero means no
+	// tenant ceiling.
+	RateLimit       int           `json:"rate_limit,omitempty" db:"rate_limit"`
+	RateLimitWindow time.Duration `json:"rate_limit_window,omitempty" db:"rate_limit_window"`
+
+	// IPHandling selects how client IPs are kept in the tenant's usage
+	// records. Empty means [usage.IPStore].
+	IPHandling usage.IPHandling `json:"ip_handling,omitempty" db:"ip_handling"`
+
+	// QuotaTimezone is the IANA time zone the daily and monthly quotas of
+	// the tenant's keys reset in, unless their policy sets its own. Empty
+	// means UTC.
+	QuotaTimezone string `json:"quota_timezone,omitempty" db:"quota_timezone"`
+
+	// UniqueKeyNames rejects a key created or renamed with the name of
+	// another of the tenant's keys, compared case-insensitively. Keys that
+	// already share a name keep it.
+	UniqueKeyNames bool `json:"unique_key_names,omitempty" db:"unique_key_names"`
+
+	// Lockdown is set while the tenant is locked down. It is written by
+	// the engine's LockdownTenant and RecoverTenant only; replacing the
+	// settings keeps it.
+	Lockdown *Lockdown `json:"lockdown,omitempty" db:"lockdown"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
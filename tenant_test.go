@@ -0,0 +1,146 @@
+package keysmith_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/store/memory"
+	"github.com/xraph/keysmith/tenant"
+)
+
+func createScopes(t *testing.T, eng *keysmith.Engine, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		require.NoError(t, eng.CreateScope(testCtx(), &scope.Scope{Name: name}))
+	}
+}
+
+func TestCreateKey_MergesTenantDefaultScopes(t *testing.T) {
+	eng := newTestEngine(t)
+	ctx := testCtx()
+	createScopes(t, eng, "api:access", "webhooks:receive", "read:users")
+
+	err := eng.SetTenantSettings(ctx, &tenant.Settings{
+		DefaultScopes: []string{"api:access", "webhooks:receive"},
+	})
+	require.NoError(t, err)
+
+	result, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
+		Name:        "Defaults",
+		Prefix:      "sk",
+		Environment: key.EnvLive,
+		Scopes:      []string{"read:users", "api:access"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read:users", "api:access", "webhooks:receive"}, result.Key.Scopes)
+
+	vr, err := eng.ValidateKey(ctx, result.RawKey)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"read:users", "api:access", "webhooks:receive"}, vr.Scopes)
+}
+
+func TestCreateKey_SkipDefaultScopes(t *testing.T) {
+	eng := newTestEngine(t)
+	ctx := testCtx()
+	createScopes(t, eng, "api:access", "read:users")
+
+	require.NoError(t, eng.SetTenantSettings(ctx, &tenant.Settings{
+		DefaultScopes: []string{"api:access"},
+	}))
+
+	result, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
+		Name:              "No defaults",
+		Prefix:            "sk",
+		Environment:       key.EnvLive,
+		Scopes:            []string{"read:users"},
+		SkipDefaultScopes: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read:users"}, result.Key.Scopes)
+}
+
+func TestSetTenantSettings_UnknownScopeFailsFast(t *testing.T) {
+	eng := newTestEngine(t)
+	ctx := testCtx()
+	createScopes(t, eng, "api:access")
+
+	err := eng.SetTenantSettings(ctx, &tenant.Settings{
+		DefaultScopes: []string{"api:access", "webhooks:recieve"},
+	})
+	require.ErrorIs(t, err, keysmith.ErrScopeNotFound)
+	assert.Contains(t, err.Error(), "webhooks:recieve")
+
+	// Nothing was saved, so key creation is unaffected.
+	ts, err := eng.GetTenantSettings(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, ts.DefaultScopes)
+
+	result, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{
+		Name:        "Unaffected",
+		Prefix:      "sk",
+		Environment: key.EnvLive,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, result.Key.Scopes)
+}
+
+func TestSetTenantSettings_InvalidatesCache(t *testing.T) {
+	eng := newTestEngine(t)
+	ctx := testCtx()
+	createScopes(t, eng, "api:access", "webhooks:receive")
+
+	require.NoError(t, eng.SetTenantSettings(ctx, &tenant.Settings{
+		DefaultScopes: []string{"api:access"},
+	}))
+	first, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "First", Prefix: "sk", Environment: key.EnvLive})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api:access"}, first.Key.Scopes)
+
+	require.NoError(t, eng.SetTenantSettings(ctx, &tenant.Settings{
+		DefaultScopes: []string{"webhooks:receive"},
+	}))
+	second, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Second", Prefix: "sk", Environment: key.EnvLive})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"webhooks:receive"}, second.Key.Scopes)
+
+	ts, err := eng.GetTenantSettings(ctx, "tenant_test")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"webhooks:receive"}, ts.DefaultScopes)
+	assert.False(t, ts.CreatedAt.IsZero())
+}
+
+func TestTenantSettings_SeenByOtherEngines(t *testing.T) {
+	clock := &fakeClock{t: time.Now()}
+	shared := memory.New()
+	engA, err := keysmith.NewEngine(keysmith.WithStore(shared), keysmith.WithClock(clock.Now))
+	require.NoError(t, err)
+	engB, err := keysmith.NewEngine(keysmith.WithStore(shared), keysmith.WithClock(clock.Now))
+	require.NoError(t, err)
+	ctx := testCtx()
+	createScopes(t, engA, "api:access")
+
+	ts, err := engB.GetTenantSettings(ctx, "tenant_test")
+	require.NoError(t, err)
+	require.Empty(t, ts.DefaultScopes)
+	require.NoError(t, engA.SetTenantSettings(ctx, &tenant.Settings{DefaultScopes: []string{"api:access"}}))
+
+	clock.Set(clock.Now().Add(time.Minute))
+	ts, err = engB.GetTenantSettings(ctx, "tenant_test")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api:access"}, ts.DefaultScopes, "engine B rereads settings once its cache entry expires")
+	created, err := engB.CreateKey(ctx, &keysmith.CreateKeyInput{Name: "Other Engine", Prefix: "sk", Environment: key.EnvLive})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api:access"}, created.Key.Scopes)
+
+	require.NoError(t, engA.SetTenantSettings(ctx, &tenant.Settings{}))
+	clock.Set(clock.Now().Add(time.Minute))
+	ts, err = engB.GetTenantSettings(ctx, "tenant_test")
+	require.NoError(t, err)
+	assert.Empty(t, ts.DefaultScopes, "stored settings expire too, not only misses")
+}
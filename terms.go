@@ -0,0 +1,78 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xraph/keysmith/key"
+)
+
+// TermsEnforcement selects how ValidateKey treats a key that accepted a terms
+// version other than the one set with [WithTermsVersion].
+type TermsEnforcement int
+
+const (
+	// TermsIgnore leaves validation unaffected. This is the default: terms
+	// gate key creation only.
+	TermsIgnore TermsEnforcement = iota
+
+	// TermsWarn sets ValidationResult.OutdatedTerms and lets the key
+	// through.
+	TermsWarn
+
+	// TermsStrict rejects the key with ErrTermsOutdated, including keys
+	// created before terms were tracked. Every key must be re-issued after
+	// each version bump.
+	TermsStrict
+)
+
+// ListKeysWithOutdatedTerms returns the keys visible to ctx whose accepted
+// terms version is not currentVersion, including keys that accepted none.
+// An empty currentVersion means the version set with [WithTermsVersion].
+func (e *Engine) ListKeysWithOutdatedTerms(ctx context.Context, currentVersion string) ([]*key.Key, error) {
+	version := strings.TrimSpace(currentVersion)
+	if version == "" {
+		version = e.termsVersion
+	}
+	if version == "" {
+		return nil, ErrTermsVersionRequired
+	}
+	return e.store.Keys().List(ctx, keyFilter(ctx, &key.ListFilter{OutdatedTerms: version}))
+}
+
+// checkAcceptedTerms returns the terms version to record for a new key, and
+// an error wrapping ErrTermsNotAccepted when the engine requires a version
+// the input did not accept.
+func (e *Engine) checkAcceptedTerms(input *CreateKeyInput) (string, error) {
+	accepted := strings.TrimSpace(input.AcceptedTermsVersion)
+	if e.termsVersion == "" || accepted == e.termsVersion {
+		return accepted, nil
+	}
+	if accepted == "" {
+		return "", fmt.Errorf("%w: terms version %q must be accepted", ErrTermsNotAccepted, e.termsVersion)
+	}
+	return "", fmt.Errorf("%w: accepted terms version %q, current is %q", ErrTermsNotAccepted, accepted, e.termsVersion)
+}
+
+// acceptedTermsAt returns when a key created at now accepted version, or nil
+// when it accepted none.
+func acceptedTermsAt(version string, now time.Time) *time.Time {
+	if version == "" {
+		return nil
+	}
+	return &now
+}
+
+// checkTerms applies the engine's TermsEnforcement to k, reporting whether
+// its accepted terms are outdated.
+func (e *Engine) checkTerms(k *key.Key) (bool, error) {
+	if e.termsVersion == "" || e.termsEnforcement == TermsIgnore || k.AcceptedTermsVersion == e.termsVersion {
+		return false, nil
+	}
+	if e.termsEnforcement == TermsStrict {
+		return false, fmt.Errorf("%w: key accepted %q, current is %q", ErrTermsOutdated, k.AcceptedTermsVersion, e.termsVersion)
+	}
+	return true, nil
+}
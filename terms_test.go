@@ -0,0 +1,148 @@
+package keysmith_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	audithook "github.com/xraph/keysmith/audit_hook"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+func termsInput(version string) *keysmith.CreateKeyInput {
+	return &keysmith.CreateKeyInput{Name: "Terms", Prefix: "sk", Environment: key.EnvTest, AcceptedTermsVersion: version}
+}
+
+func TestCreateKey_RequiresCurrentTerms(t *testing.T) {
+	audit := &auditCapture{}
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithExtension(audithook.New(audit)),
+		keysmith.WithTermsVersion("2024-06"),
+	)
+	require.NoError(t, err)
+	ctx := testCtx()
+
+	_, err = eng.CreateKey(ctx, termsInput(""))
+	require.ErrorIs(t, err, keysmith.ErrTermsNotAccepted)
+	assert.Contains(t, err.Error(), `"2024-06"`)
+
+	_, err = eng.CreateKey(ctx, termsInput("2024-01"))
+	require.ErrorIs(t, err, keysmith.ErrTermsNotAccepted)
+	assert.Contains(t, err.Error(), `"2024-01"`)
+
+	created, err := eng.CreateKey(ctx, termsInput(" 2024-06 "))
+	require.NoError(t, err)
+	assert.Equal(t, "2024-06", created.Key.AcceptedTermsVersion)
+	require.NotNil(t, created.Key.AcceptedTermsAt)
+	assert.True(t, created.Key.AcceptedTermsAt.Equal(created.Key.CreatedAt))
+
+	stored, err := eng.GetKey(ctx, created.Key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-06", stored.AcceptedTermsVersion)
+
+	require.Len(t, audit.recorded(), 1)
+	assert.Equal(t, "2024-06", audit.recorded()[0].Metadata["accepted_terms_version"])
+	assert.NotEmpty(t, audit.recorded()[0].Metadata["accepted_terms_at"])
+}
+
+func TestCreateKey_TermsOptionalWithoutVersion(t *testing.T) {
+	eng := newTestEngine(t)
+
+	created, err := eng.CreateKey(testCtx(), termsInput(""))
+	require.NoError(t, err)
+	assert.Empty(t, created.Key.AcceptedTermsVersion)
+	assert.Nil(t, created.Key.AcceptedTermsAt)
+
+	created, err = eng.CreateKey(testCtx(), termsInput("2024-01"))
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01", created.Key.AcceptedTermsVersion, "an acceptance is recorded even when not required")
+}
+
+func TestListKeysWithOutdatedTerms(t *testing.T) {
+	ms := memory.New()
+	ctx := testCtx()
+	old, err := keysmith.NewEngine(keysmith.WithStore(ms), keysmith.WithTermsVersion("v1"))
+	require.NoError(t, err)
+	legacy, err := keysmith.NewEngine(keysmith.WithStore(ms))
+	require.NoError(t, err)
+
+	v1, err := old.CreateKey(ctx, termsInput("v1"))
+	require.NoError(t, err)
+	none, err := legacy.CreateKey(ctx, termsInput(""))
+	require.NoError(t, err)
+
+	// Bumping the version is a new engine configuration.
+	eng, err := keysmith.NewEngine(keysmith.WithStore(ms), keysmith.WithTermsVersion("v2"))
+	require.NoError(t, err)
+	v2, err := eng.CreateKey(ctx, termsInput("v2"))
+	require.NoError(t, err)
+	_, err = eng.CreateKey(keysmith.WithTenant(ctx, "app_test", "tenant_other"), termsInput("v2"))
+	require.NoError(t, err)
+
+	keys, err := eng.ListKeysWithOutdatedTerms(ctx, "")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{v1.Key.ID.String(), none.Key.ID.String()}, keyIDs(keys))
+
+	keys, err = eng.ListKeysWithOutdatedTerms(ctx, "v1")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{v2.Key.ID.String(), none.Key.ID.String()}, keyIDs(keys))
+
+	// The bump leaves stored acceptances as they were.
+	stored, err := eng.GetKey(ctx, v1.Key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", stored.AcceptedTermsVersion)
+	assert.True(t, stored.AcceptedTermsAt.Equal(*v1.Key.AcceptedTermsAt))
+
+	_, err = legacy.ListKeysWithOutdatedTerms(ctx, " ")
+	assert.ErrorIs(t, err, keysmith.ErrTermsVersionRequired)
+}
+
+func TestValidateKey_OutdatedTerms(t *testing.T) {
+	ms := memory.New()
+	ctx := testCtx()
+	old, err := keysmith.NewEngine(keysmith.WithStore(ms), keysmith.WithTermsVersion("v1"))
+	require.NoError(t, err)
+	outdated, err := old.CreateKey(ctx, termsInput("v1"))
+	require.NoError(t, err)
+
+	engine := func(opts ...keysmith.Option) *keysmith.Engine {
+		t.Helper()
+		eng, err := keysmith.NewEngine(append([]keysmith.Option{keysmith.WithStore(ms), keysmith.WithTermsVersion("v2")}, opts...)...)
+		require.NoError(t, err)
+		return eng
+	}
+
+	t.Run("ignored by default", func(t *testing.T) {
+		res, err := engine().ValidateKey(ctx, outdated.RawKey)
+		require.NoError(t, err)
+		assert.False(t, res.OutdatedTerms)
+	})
+
+	t.Run("warn", func(t *testing.T) {
+		eng := engine(keysmith.WithTermsEnforcement(keysmith.TermsWarn))
+		res, err := eng.ValidateKey(ctx, outdated.RawKey)
+		require.NoError(t, err)
+		assert.True(t, res.OutdatedTerms)
+
+		current, err := eng.CreateKey(ctx, termsInput("v2"))
+		require.NoError(t, err)
+		res, err = eng.ValidateKey(ctx, current.RawKey)
+		require.NoError(t, err)
+		assert.False(t, res.OutdatedTerms)
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		eng := engine(keysmith.WithTermsEnforcement(keysmith.TermsStrict))
+		_, err := eng.ValidateKey(ctx, outdated.RawKey)
+		assert.ErrorIs(t, err, keysmith.ErrTermsOutdated)
+
+		current, err := eng.CreateKey(ctx, termsInput("v2"))
+		require.NoError(t, err)
+		_, err = eng.ValidateKey(ctx, current.RawKey)
+		assert.NoError(t, err)
+	})
+}
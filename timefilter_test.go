@@ -0,0 +1,140 @@
+package keysmith_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/scope"
+)
+
+// cutoff returns a time after everything written so far and before anything
+// written next.
+func cutoff() time.Time {
+	t := time.Now()
+	time.Sleep(time.Millisecond)
+	return t
+}
+
+func updatedSince(t *testing.T, eng *keysmith.Engine, ctx context.Context, since time.Time) []string {
+	t.Helper()
+	keys, err := eng.ListKeys(ctx, &key.ListFilter{UpdatedAfter: &since})
+	require.NoError(t, err)
+	return keyIDs(keys)
+}
+
+func TestListKeys_UpdatedAfter(t *testing.T) {
+	eng := newTestEngine(t)
+	ctx := testCtx()
+	require.NoError(t, eng.CreateScope(ctx, &scope.Scope{Name: "read:users"}))
+
+	create := func(name string) *key.CreateResult {
+		res, err := eng.CreateKey(ctx, &keysmith.CreateKeyInput{Name: name, Prefix: "sk", Environment: key.EnvTest})
+		require.NoError(t, err)
+		return res
+	}
+	suspended := create("suspended")
+	scoped := create("scoped")
+	tagged := create("tagged")
+	validated := create("validated")
+
+	since := cutoff()
+	assert.Empty(t, updatedSince(t, eng, ctx, since))
+
+	// A validation only records last use, which is not a change.
+	_, err := eng.ValidateKey(ctx, validated.RawKey)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		k, err := eng.GetKey(ctx, validated.Key.ID)
+		return err == nil && k.LastUsedAt != nil
+	}, time.Second, 5*time.Millisecond)
+	assert.Empty(t, updatedSince(t, eng, ctx, since), "a validation does not surface")
+
+	require.NoError(t, eng.SuspendKey(ctx, suspended.Key.ID))
+	assert.Equal(t, []string{suspended.Key.ID.String()}, updatedSince(t, eng, ctx, since))
+
+	require.NoError(t, eng.AssignScopes(ctx, scoped.Key.ID, []string{"read:users"}))
+	assert.ElementsMatch(t, []string{suspended.Key.ID.String(), scoped.Key.ID.String()}, updatedSince(t, eng, ctx, since))
+
+	_, err = eng.UpdateKey(ctx, tagged.Key.ID, &keysmith.UpdateKeyInput{Metadata: map[string]any{"plan": "pro"}})
+	require.NoError(t, err)
+	assert.ElementsMatch(t,
+		[]string{suspended.Key.ID.String(), scoped.Key.ID.String(), tagged.Key.ID.String()},
+		updatedSince(t, eng, ctx, since))
+
+	since = cutoff()
+	require.NoError(t, eng.RemoveScopes(ctx, scoped.Key.ID, []string{"read:users"}))
+	assert.Equal(t, []string{scoped.Key.ID.String()}, updatedSince(t, eng, ctx, since))
+}
+
+func TestListKeys_CreatedRange(t *testing.T) {
+	eng := newTestEngine(t)
+	ctx := testCtx()
+
+	older := createByCreator(t, eng, ctx, "")
+	mid := cutoff()
+	newer := createByCreator(t, eng, ctx, "")
+	end := cutoff()
+	createByCreator(t, eng, ctx, "")
+
+	keys, err := eng.ListKeys(ctx, &key.ListFilter{CreatedBefore: &mid})
+	require.NoError(t, err)
+	assert.Equal(t, []string{older.ID.String()}, keyIDs(keys))
+
+	keys, err = eng.ListKeys(ctx, &key.ListFilter{CreatedAfter: &mid, CreatedBefore: &end})
+	require.NoError(t, err)
+	assert.Equal(t, []string{newer.ID.String()}, keyIDs(keys))
+
+	keys, err = eng.ListKeys(ctx, &key.ListFilter{CreatedAfter: &mid})
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+}
+
+func TestListPolicies_TimeFilters(t *testing.T) {
+	eng := newTestEngine(t)
+	ctx := testCtx()
+
+	older := &policy.Policy{Name: "older"}
+	require.NoError(t, eng.CreatePolicy(ctx, older))
+	mid := cutoff()
+	newer := &policy.Policy{Name: "newer"}
+	require.NoError(t, eng.CreatePolicy(ctx, newer))
+
+	pols, err := eng.ListPolicies(ctx, &policy.ListFilter{CreatedAfter: &mid})
+	require.NoError(t, err)
+	require.Len(t, pols, 1)
+	assert.Equal(t, newer.ID.String(), pols[0].ID.String())
+
+	pols, err = eng.ListPolicies(ctx, &policy.ListFilter{CreatedBefore: &mid})
+	require.NoError(t, err)
+	require.Len(t, pols, 1)
+	assert.Equal(t, older.ID.String(), pols[0].ID.String())
+
+	since := cutoff()
+	older.Description = "edited"
+	require.NoError(t, eng.UpdatePolicy(ctx, older))
+	pols, err = eng.ListPolicies(ctx, &policy.ListFilter{UpdatedAfter: &since})
+	require.NoError(t, err)
+	require.Len(t, pols, 1)
+	assert.Equal(t, older.ID.String(), pols[0].ID.String())
+}
+
+func TestListScopes_CreatedAfter(t *testing.T) {
+	eng := newTestEngine(t)
+	ctx := testCtx()
+
+	require.NoError(t, eng.CreateScope(ctx, &scope.Scope{Name: "read:users"}))
+	since := cutoff()
+	require.NoError(t, eng.CreateScope(ctx, &scope.Scope{Name: "write:users"}))
+
+	scopes, err := eng.ListScopes(ctx, &scope.ListFilter{CreatedAfter: &since})
+	require.NoError(t, err)
+	require.Len(t, scopes, 1)
+	assert.Equal(t, "write:users", scopes[0].Name)
+}
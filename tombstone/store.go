@@ -0,0 +1,27 @@
+package tombstone
+
+import (
+	"context"
+	"time"
+)
+
+// Store is the persistence interface for hash tombstones.
+type Store interface {
+	// Create stores t. A hash that is already tombstoned keeps its first
+	// tombstone, and Create returns no error.
+	Create(ctx context.Context, t *Tombstone) error
+
+	// Find returns the tombstones of those of hashes that have one, in no
+	// particular order. The hashes are normalized.
+	Find(ctx context.Context, hashes []string) ([]*Tombstone, error)
+
+	// List returns tombstones newest first.
+	List(ctx context.Context, filter *ListFilter) ([]*Tombstone, error)
+
+	// CountByReason counts the tombstones matching filter per reason, most
+	// frequent first and ties by reason. Limit and Offset are ignored.
+	CountByReason(ctx context.Context, filter *ListFilter) ([]*ReasonCount, error)
+
+	// Purge deletes tombstones created before the given time.
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
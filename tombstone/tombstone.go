@@ -0,0 +1,38 @@
+// Package tombstone defines hash tombstones: the hashes of keys revoked for
+// a security reason. No key may be created, imported, or restored with a
+// tombstoned hash, so a compromised key cannot be brought back.
+package tombstone
+
+import (
+	"time"
+
+	"github.com/xraph/keysmith/id"
+)
+
+// Tombstone records that no key may have Hash again. Hash is normalized, as
+// key.NormalizeHash returns it, and KeyID, TenantID, and AppID are those of
+// the revoked key. Reason is the reason code of the revocation.
+type Tombstone struct {
+	Hash      string    `json:"-" db:"hash"`
+	KeyID     id.KeyID  `json:"key_id" db:"key_id"`
+	TenantID  string    `json:"tenant_id" db:"tenant_id"`
+	AppID     string    `json:"app_id" db:"app_id"`
+	Reason    string    `json:"reason" db:"reason"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// ListFilter selects tombstones. Empty fields match every tombstone.
+type ListFilter struct {
+	TenantID string `json:"tenant_id,omitempty"`
+	AppID    string `json:"app_id,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+
+	Limit  int `json:"limit,omitempty"`
+	Offset int `json:"offset,omitempty"`
+}
+
+// ReasonCount is how many tombstones had one reason.
+type ReasonCount struct {
+	Reason string `json:"reason"`
+	Count  int64  `json:"count"`
+}
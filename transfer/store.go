@@ -0,0 +1,42 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/xraph/keysmith/id"
+)
+
+// ErrPendingTransfer is returned by Store.Create for a key that already has
+// a pending transfer.
+var ErrPendingTransfer = errors.New("transfer: key has a pending transfer")
+
+// Store is the persistence interface for key transfers.
+type Store interface {
+	// Create stores a pending transfer. A key with another pending transfer
+	// fails with ErrPendingTransfer, atomically with the insert.
+	Create(ctx context.Context, t *Transfer) error
+	Get(ctx context.Context, transferID id.TransferID) (*Transfer, error)
+
+	// GetPending returns the key's pending transfer, or not found when it
+	// has none.
+	GetPending(ctx context.Context, keyID id.KeyID) (*Transfer, error)
+
+	// List returns transfers newest first.
+	List(ctx context.Context, filter *ListFilter) ([]*Transfer, error)
+
+	// Resolve moves a pending transfer to state, cancelled or expired,
+	// recording by and at. It reports false, changing nothing, when the
+	// transfer is no longer pending.
+	Resolve(ctx context.Context, transferID id.TransferID, state State, by string, at time.Time) (bool, error)
+
+	// Accept completes the pending transfer t in one transaction: it
+	// records t accepted with its ResolvedBy, ResolvedAt, UnmappedScopes,
+	// and UnmappedPolicy; moves the key to t.TargetTenantID with remap's
+	// scopes and policy; removes the key from its groups and quota pool,
+	// which belong to the source tenant; and, when t.RetagUsage, moves the key's usage
+	// records to the target tenant. It reports false, changing nothing,
+	// when t is no longer pending.
+	Accept(ctx context.Context, t *Transfer, remap *Remap) (bool, error)
+}
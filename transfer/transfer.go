@@ -0,0 +1,88 @@
+// Package transfer defines key transfers: the handover of a key from one
+// tenant to another, which the target tenant must accept before it expires.
+// The key keeps its secret, so it validates throughout and after.
+package transfer
+
+import (
+	"time"
+
+	"github.com/xraph/keysmith/id"
+)
+
+// State is the state of a key transfer.
+type State string
+
+const (
+	// StatePending is a transfer awaiting the target tenant.
+	StatePending State = "pending"
+
+	// StateAccepted is a transfer the target tenant accepted; the key is
+	// now theirs.
+	StateAccepted State = "accepted"
+
+	// StateCancelled is a transfer either tenant called off.
+	StateCancelled State = "cancelled"
+
+	// StateExpired is a transfer not accepted in time.
+	StateExpired State = "expired"
+)
+
+// Transfer is a handover of one key from SourceTenantID to TargetTenantID,
+// within the key's app. A key has at most one pending transfer.
+type Transfer struct {
+	ID             id.TransferID `json:"id" db:"id"`
+	KeyID          id.KeyID      `json:"key_id" db:"key_id"`
+	AppID          string        `json:"app_id" db:"app_id"`
+	SourceTenantID string        `json:"source_tenant_id" db:"source_tenant_id"`
+	TargetTenantID string        `json:"target_tenant_id" db:"target_tenant_id"`
+	State          State         `json:"state" db:"state"`
+
+	// RetagUsage moves the key's usage records to the target tenant on
+	// acceptance. Otherwise they stay under the source tenant.
+	RetagUsage bool `json:"retag_usage" db:"retag_usage"`
+
+	// InitiatedBy and ResolvedBy are the actors who initiated the transfer
+	// and who accepted or cancelled it.
+	InitiatedBy string `json:"initiated_by,omitempty" db:"initiated_by"`
+	ResolvedBy  string `json:"resolved_by,omitempty" db:"resolved_by"`
+
+	// UnmappedScopes are the key's scopes that the target tenant has no
+	// scope of the same name for, which the key lost on acceptance.
+	UnmappedScopes []string `json:"unmapped_scopes,omitempty" db:"unmapped_scopes"`
+
+	// UnmappedPolicy is the name of the key's policy when the target tenant
+	// has no policy of that name, so the key was left without one.
+	UnmappedPolicy string `json:"unmapped_policy,omitempty" db:"unmapped_policy"`
+
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty" db:"resolved_at"`
+}
+
+// Remap is how accepting a transfer changes its key, worked out by the
+// engine from the target tenant's scopes and policies.
+type Remap struct {
+	// Scopes are the names of the key's scopes that the target tenant has.
+	// They replace the key's assignments; its other scopes are dropped.
+	Scopes []string
+
+	// PolicyID replaces the key's policy. Nil leaves the key without one.
+	PolicyID *id.PolicyID
+}
+
+// ListFilter contains filters for listing transfers.
+type ListFilter struct {
+	// TenantID restricts the results to transfers from or to the tenant.
+	TenantID string `json:"tenant_id,omitempty"`
+	AppID    string `json:"app_id,omitempty"`
+
+	KeyID *id.KeyID `json:"key_id,omitempty"`
+	State State     `json:"state,omitempty"`
+
+	// ExpiresBefore restricts the results to transfers expiring before
+	// this time.
+	ExpiresBefore *time.Time `json:"expires_before,omitempty"`
+
+	Limit  int `json:"limit,omitempty"`
+	Offset int `json:"offset,omitempty"`
+}
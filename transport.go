@@ -0,0 +1,46 @@
+package keysmith
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/policy"
+)
+
+// normalizeCertFingerprint checks a cert fingerprint at write time and
+// returns it in canonical form.
+func normalizeCertFingerprint(fp string) (string, error) {
+	norm, err := key.NormalizeCertFingerprint(fp)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidCertFingerprint, err)
+	}
+	return norm, nil
+}
+
+// checkTransport rejects a validation whose connection does not meet the
+// policy's MinTLSVersion or RequireMTLS, with ErrTransportNotAllowed, or
+// lacks the client certificate k is pinned to, with ErrClientCertMismatch.
+// Plain HTTP has TLS version zero, so it fails any minimum.
+func checkTransport(ctx context.Context, k *key.Key, pol *policy.Policy) error {
+	vreq := ValidationRequestFromContext(ctx)
+	if pol != nil && pol.MinTLSVersion != "" {
+		// A version that no longer parses fails closed.
+		minVersion, err := policy.ParseTLSVersion(pol.MinTLSVersion)
+		if err != nil || vreq.TLSVersion < minVersion {
+			return fmt.Errorf("%w: TLS %s required, got %s", ErrTransportNotAllowed, pol.MinTLSVersion, policy.TLSVersionName(vreq.TLSVersion))
+		}
+	}
+	if pol != nil && pol.RequireMTLS && vreq.ClientCertFingerprint == "" {
+		return fmt.Errorf("%w: client certificate required", ErrTransportNotAllowed)
+	}
+	if k.CertFingerprint == "" {
+		return nil
+	}
+	presented, err := key.NormalizeCertFingerprint(vreq.ClientCertFingerprint)
+	if err != nil || subtle.ConstantTimeCompare([]byte(presented), []byte(k.CertFingerprint)) != 1 {
+		return ErrClientCertMismatch
+	}
+	return nil
+}
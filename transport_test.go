@@ -0,0 +1,98 @@
+package keysmith_test
+
+import (
+	"crypto/tls"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+var (
+	pinnedCert = key.CertFingerprint([]byte("pinned client certificate"))
+	otherCert  = key.CertFingerprint([]byte("another client certificate"))
+)
+
+func TestValidateKey_TransportPolicy(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()))
+	require.NoError(t, err)
+
+	strict := &policy.Policy{Name: "TLS 1.3", MinTLSVersion: "1.3"}
+	require.NoError(t, eng.CreatePolicy(testCtx(), strict))
+	mtls := &policy.Policy{Name: "mTLS", MinTLSVersion: "1.2", RequireMTLS: true}
+	require.NoError(t, eng.CreatePolicy(testCtx(), mtls))
+
+	newKey := func(pol *policy.Policy) string {
+		created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: pol.Name, Prefix: "sk", Environment: key.EnvTest, PolicyID: &pol.ID})
+		require.NoError(t, err)
+		return created.RawKey
+	}
+	strictKey, mtlsKey := newKey(strict), newKey(mtls)
+
+	tests := []struct {
+		name string
+		raw  string
+		req  keysmith.ValidationRequest
+		want error
+	}{
+		{"TLS 1.3", strictKey, keysmith.ValidationRequest{TLSVersion: tls.VersionTLS13}, nil},
+		{"TLS 1.2 below minimum", strictKey, keysmith.ValidationRequest{TLSVersion: tls.VersionTLS12}, keysmith.ErrTransportNotAllowed},
+		{"plain HTTP", strictKey, keysmith.ValidationRequest{}, keysmith.ErrTransportNotAllowed},
+		{"client cert", mtlsKey, keysmith.ValidationRequest{TLSVersion: tls.VersionTLS12, ClientCertFingerprint: otherCert}, nil},
+		{"no client cert", mtlsKey, keysmith.ValidationRequest{TLSVersion: tls.VersionTLS13}, keysmith.ErrTransportNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := eng.ValidateKey(keysmith.WithValidationRequest(testCtx(), tt.req), tt.raw)
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+func TestValidateKey_PinnedClientCert(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()))
+	require.NoError(t, err)
+
+	colons := strings.ToUpper(pinnedCert[:2]) + ":" + strings.ToUpper(pinnedCert[2:4]) + ":" + pinnedCert[4:]
+	created, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "Pinned", Prefix: "sk", Environment: key.EnvTest, CertFingerprint: colons})
+	require.NoError(t, err)
+	assert.Equal(t, pinnedCert, created.Key.CertFingerprint, "the fingerprint is stored in canonical form")
+
+	validate := func(fp string) error {
+		ctx := keysmith.WithValidationRequest(testCtx(), keysmith.ValidationRequest{TLSVersion: tls.VersionTLS13, ClientCertFingerprint: fp})
+		_, err := eng.ValidateKey(ctx, created.RawKey)
+		return err
+	}
+	assert.NoError(t, validate(pinnedCert))
+	assert.ErrorIs(t, validate(otherCert), keysmith.ErrClientCertMismatch)
+	assert.ErrorIs(t, validate(""), keysmith.ErrClientCertMismatch)
+
+	empty := ""
+	_, err = eng.UpdateKey(testCtx(), created.Key.ID, &keysmith.UpdateKeyInput{CertFingerprint: &empty})
+	require.NoError(t, err)
+	assert.NoError(t, validate(otherCert), "an unpinned key accepts any certificate")
+
+	bad := "not-a-fingerprint"
+	_, err = eng.UpdateKey(testCtx(), created.Key.ID, &keysmith.UpdateKeyInput{CertFingerprint: &bad})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidCertFingerprint)
+	_, err = eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "Bad", Prefix: "sk", Environment: key.EnvTest, CertFingerprint: pinnedCert[:40]})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidCertFingerprint)
+}
+
+func TestCreatePolicy_InvalidMinTLSVersion(t *testing.T) {
+	eng, err := keysmith.NewEngine(keysmith.WithStore(memory.New()))
+	require.NoError(t, err)
+
+	err = eng.CreatePolicy(testCtx(), &policy.Policy{Name: "Bad TLS", MinTLSVersion: "1.4"})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidPolicy)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/xraph/keysmith/id"
 	"github.com/xraph/keysmith/key"
 	"github.com/xraph/keysmith/policy"
+	"github.com/xraph/keysmith/rotation"
 )
 
 // CreateKeyInput contains the parameters for creating a new API key.
@@ -17,14 +18,174 @@ type CreateKeyInput struct {
 	PolicyID    *id.PolicyID    `json:"policy_id,omitempty"`
 	Scopes      []string        `json:"scopes,omitempty"`
 	Metadata    map[string]any  `json:"metadata,omitempty"`
-	CreatedBy   string          `json:"created_by,omitempty"`
-	TenantID    string          `json:"tenant_id,omitempty"`
-	ExpiresAt   *time.Time      `json:"expires_at,omitempty"`
+
+	// InlinePolicy gives the key a policy of its own instead of PolicyID,
+	// with which it cannot be combined, or a default policy of the prefix
+	// or environment. Only the rate limit, its window and
+	// burst, the quotas and their time zone, and the allowed IPs and
+	// origins may be set. The engine stores it as an inline policy, which
+	// is hidden from policy listings and deleted with the key; see
+	// [Engine.PromoteInlinePolicy] to share it.
+	InlinePolicy *policy.Policy `json:"inline_policy,omitempty"`
+
+	// Labels are indexed key=value pairs to organize and select keys by.
+	// See [key.Labels].
+	Labels key.Labels `json:"labels,omitempty"`
+
+	// PoolID puts the key in a quota pool of its tenant and app, whose
+	// quotas replace its policy's. See [Engine.AddKeysToQuotaPool].
+	PoolID *id.QuotaPoolID `json:"pool_id,omitempty"`
+
+	// CreatedBy records who created the key. It defaults to the actor on
+	// the context, set with [WithActor].
+	CreatedBy string `json:"created_by,omitempty"`
+
+	// ExternalRef is the caller's own identifier for the key, unique within
+	// the tenant. Creating a key with a reference the tenant already uses
+	// returns the existing key instead, so a provisioning system can retry
+	// safely. See [key.CreateResult.DuplicateOfExisting].
+	ExternalRef string `json:"external_ref,omitempty"`
+
+	TenantID  string     `json:"tenant_id,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// AllowedOrigins restricts the browser origins the key validates from,
+	// overriding the policy's list. See [key.Key.AllowedOrigins].
+	AllowedOrigins []string `json:"allowed_origins,omitempty"`
+
+	// IntendedConsumer and EnforceConsumer describe the service the key is
+	// issued to. See [key.Key.IntendedConsumer].
+	IntendedConsumer string `json:"intended_consumer,omitempty"`
+	EnforceConsumer  bool   `json:"enforce_consumer,omitempty"`
+
+	// CertFingerprint pins the key to a client certificate. See
+	// [key.Key.CertFingerprint]; colon-separated and uppercase hex are
+	// accepted.
+	CertFingerprint string `json:"cert_fingerprint,omitempty"`
+
+	// Flags sets per-key exceptions to validation. Flags that skip a check
+	// can only be set from an admin context. See [key.Flag].
+	Flags key.Flags `json:"flags,omitempty"`
+
+	// Contacts receive notifications about the key instead of the tenant's
+	// default contacts. See [key.EffectiveContacts].
+	Contacts key.Contacts `json:"contacts,omitempty"`
+
+	// AcceptedTermsVersion is the terms version the key's holder accepted.
+	// With [WithTermsVersion] set it must equal that version.
+	AcceptedTermsVersion string `json:"accepted_terms_version,omitempty"`
+
+	// SkipDefaultScopes suppresses the tenant's default scopes so that the
+	// key receives only Scopes.
+	SkipDefaultScopes bool `json:"skip_default_scopes,omitempty"`
+
+	// DeliverTo writes the raw key to a secrets manager instead of returning
+	// it. See [SecretDestination].
+	DeliverTo *SecretDestination `json:"-"`
+}
+
+// UpdateKeyInput contains the key fields to change. Nil fields are left as
+// they are.
+type UpdateKeyInput struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+
+	// Metadata replaces the key's metadata. Reserved entries already on the
+	// key must be passed back unchanged.
+	Metadata map[string]any `json:"metadata,omitempty"`
+
+	// AllowedOrigins replaces the key's origin allowlist. An empty, non-nil
+	// slice clears it so the policy's list applies again.
+	AllowedOrigins []string `json:"allowed_origins,omitempty"`
+
+	// IntendedConsumer replaces the key's intended consumer service. An
+	// empty string clears it, which disables the consumer check.
+	IntendedConsumer *string `json:"intended_consumer,omitempty"`
+	EnforceConsumer  *bool   `json:"enforce_consumer,omitempty"`
+
+	// CertFingerprint replaces the client certificate the key is pinned to.
+	// An empty string unpins it.
+	CertFingerprint *string `json:"cert_fingerprint,omitempty"`
+
+	// Flags replaces the key's flags. An empty, non-nil slice clears them.
+	// Adding a flag that skips a check requires an admin context.
+	Flags key.Flags `json:"flags,omitempty"`
+
+	// Contacts replaces the key's contacts. An empty, non-nil slice clears
+	// them so the tenant's default contacts apply again.
+	Contacts key.Contacts `json:"contacts,omitempty"`
+
+	// Labels replaces the key's labels. An empty, non-nil map clears them.
+	Labels key.Labels `json:"labels,omitempty"`
+
+	// InlinePolicy replaces the values of the key's inline policy, with the
+	// fields CreateKeyInput.InlinePolicy allows. A key with a shared policy
+	// or none is ErrInvalidPolicy.
+	InlinePolicy *policy.Policy `json:"inline_policy,omitempty"`
 }
 
 // ValidationResult is returned from key validation.
 type ValidationResult struct {
-	Key    *key.Key       `json:"key"`
+	Key *key.Key `json:"key"`
+
+	// Scopes may be shared with the validation cache and later results,
+	// so it must not be modified; copy it first.
 	Scopes []string       `json:"scopes"`
 	Policy *policy.Policy `json:"policy,omitempty"`
+
+	// RateLimit reports the key and tenant budgets left after this
+	// validation. It is nil when no rate limit applied.
+	RateLimit *RateLimitInfo `json:"rate_limit,omitempty"`
+
+	// Quota reports the daily and monthly quota left after this
+	// validation. It is nil when no quota applied.
+	Quota *QuotaInfo `json:"quota,omitempty"`
+
+	// ConsumerMismatch is set when the request's consumer service differs
+	// from the key's intended consumer on a key that does not enforce it.
+	ConsumerMismatch bool `json:"consumer_mismatch,omitempty"`
+
+	// OutdatedTerms is set under [TermsWarn] when the key accepted a terms
+	// version other than the current one.
+	OutdatedTerms bool `json:"outdated_terms,omitempty"`
+
+	// AppliedFlags lists the key's flags that changed the outcome of this
+	// validation: a skip flag whose check would otherwise have run, or
+	// extended grace keeping a rotated key valid.
+	AppliedFlags []key.Flag `json:"applied_flags,omitempty"`
+
+	// RotationDue is set once the key has used most of its policy's
+	// rotation period; see WithRotationDueWarning.
+	RotationDue *key.RotationDue `json:"rotation_due,omitempty"`
+
+	// UsingRotatedKey is set when the raw key is the one a rotation
+	// replaced, still accepted until the rotation's grace period ends. Key
+	// is then in StateRotated.
+	UsingRotatedKey bool `json:"using_rotated_key,omitempty"`
+
+	// KeyDeprecation is set when the key is in a raw key format flagged
+	// deprecated; see WithKeyFormatDeprecation.
+	KeyDeprecation *key.FormatDeprecation `json:"key_deprecation,omitempty"`
+
+	// scopeLimits are the per-scope rate limits that
+	// Engine.CheckScopeRateLimits applies; shared like Scopes.
+	scopeLimits map[string]scopeLimit
+}
+
+// CompromiseResult describes the outcome of a compromise report.
+type CompromiseResult struct {
+	Key    *key.Key             `json:"key"`
+	Action key.CompromiseAction `json:"action"`
+
+	// RawKey and Rotation are set only when the key was rotated.
+	RawKey   string           `json:"raw_key,omitempty"`
+	Rotation *rotation.Record `json:"rotation,omitempty"`
+}
+
+// CleanupResult describes the keys changed by a cleanup job. Under
+// [WithDryRun], KeyIDs lists the keys that would have been changed.
+type CleanupResult struct {
+	KeyIDs []id.KeyID `json:"key_ids"`
+	Failed int        `json:"failed"`
+	DryRun bool       `json:"dry_run"`
 }
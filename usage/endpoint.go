@@ -0,0 +1,82 @@
+package usage
+
+import (
+	"strings"
+	"time"
+
+	"github.com/xraph/keysmith/id"
+)
+
+// Overflow bucket used once a key has reached its distinct-endpoint cap.
+const (
+	OverflowEndpoint = "_other"
+	OverflowMethod   = "*"
+)
+
+// EndpointActivity is the last-seen summary of one (key, endpoint, method)
+// combination.
+type EndpointActivity struct {
+	KeyID      id.KeyID  `json:"key_id" db:"key_id"`
+	Endpoint   string    `json:"endpoint" db:"endpoint"`
+	Method     string    `json:"method" db:"method"`
+	LastSeenAt time.Time `json:"last_seen_at" db:"last_seen_at"`
+	Count      int64     `json:"count" db:"count"`
+}
+
+// IsOverflow reports whether a is the overflow bucket.
+func (a *EndpointActivity) IsOverflow() bool {
+	return a.Endpoint == OverflowEndpoint && a.Method == OverflowMethod
+}
+
+// NormalizeEndpoint returns the endpoint to track for rec. The route template
+// is used when set; otherwise the query string is dropped and path segments
+// that look like identifiers are replaced with ":id".
+func NormalizeEndpoint(rec *Record) string {
+	if rec.Route != "" {
+		return rec.Route
+	}
+	path := rec.Endpoint
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		if looksLikeID(seg) {
+			segs[i] = ":id"
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+// looksLikeID reports whether a path segment is numeric, a UUID, or a
+// prefixed ID such as "akey_01h2xcejqtf2nbrexx3vqjhp41".
+func looksLikeID(seg string) bool {
+	if seg == "" {
+		return false
+	}
+	if strings.Trim(seg, "0123456789") == "" {
+		return true
+	}
+	if len(seg) == 36 && strings.Count(seg, "-") == 4 && isHexOrDash(seg) {
+		return true
+	}
+	if i := strings.LastIndexByte(seg, '_'); i > 0 && len(seg)-i-1 >= 16 && hasDigit(seg[i+1:]) {
+		return true
+	}
+	return false
+}
+
+func isHexOrDash(s string) bool {
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9', c >= 'a' && c <= 'f', c >= 'A' && c <= 'F', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func hasDigit(s string) bool {
+	return strings.ContainsAny(s, "0123456789")
+}
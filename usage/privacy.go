@@ -0,0 +1,124 @@
+package usage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/netip"
+	"time"
+)
+
+// IPHandling selects how a tenant's client IPs are kept in usage records.
+type IPHandling string
+
+const (
+	// IPStore keeps the full address. It is the default.
+	IPStore IPHandling = "store"
+
+	// IPTruncate zeroes the last octet of an IPv4 address and the last 80
+	// bits of an IPv6 address.
+	IPTruncate IPHandling = "truncate"
+
+	// IPHash replaces the address with a keyed hash whose salt changes
+	// daily, so requests from one client can be correlated within a day
+	// but not across days.
+	IPHash IPHandling = "hash"
+
+	// IPDrop keeps no address.
+	IPDrop IPHandling = "drop"
+)
+
+// Valid reports whether h is a known mode. The empty mode is valid and
+// means IPStore.
+func (h IPHandling) Valid() bool {
+	switch h {
+	case "", IPStore, IPTruncate, IPHash, IPDrop:
+		return true
+	}
+	return false
+}
+
+// Redacted replaces identifiers removed by an erasure.
+const Redacted = "redacted"
+
+// TruncateIP zeroes the host part of ip: the last octet for IPv4 and the
+// last 80 bits for IPv6. Values that do not parse as an address are
+// returned empty, since they cannot be truncated safely.
+func TruncateIP(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return ""
+	}
+	return prefix.Addr().String()
+}
+
+// HashIP returns a keyed hash of ip for the UTC day of at. The day's salt
+// is derived from secret, so hashes are stable within a day on every
+// instance sharing the secret and unlinkable across days.
+func HashIP(ip string, secret []byte, at time.Time) string {
+	day := hmac.New(sha256.New, secret)
+	day.Write([]byte(at.UTC().Format(time.DateOnly)))
+	mac := hmac.New(sha256.New, day.Sum(nil))
+	mac.Write([]byte(ip))
+	return "h:" + hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// IdentifierMatcher selects usage records whose identifiers an erasure
+// removes. At least one of IPAddress and UserAgent must be set; each is
+// compared with the stored value exactly. Before, when set, limits the
+// erasure to records created before it.
+type IdentifierMatcher struct {
+	IPAddress string     `json:"ip_address,omitempty"`
+	UserAgent string     `json:"user_agent,omitempty"`
+	Before    *time.Time `json:"before,omitempty"`
+}
+
+// Matches reports whether rec holds an identifier m selects.
+func (m *IdentifierMatcher) Matches(rec *Record) bool {
+	if m.Before != nil && !rec.CreatedAt.Before(*m.Before) {
+		return false
+	}
+	return (m.IPAddress != "" && rec.IPAddress == m.IPAddress) ||
+		(m.UserAgent != "" && rec.UserAgent == m.UserAgent)
+}
+
+// Redact replaces the identifiers of rec that m selects with [Redacted].
+func (m *IdentifierMatcher) Redact(rec *Record) {
+	if m.IPAddress != "" && rec.IPAddress == m.IPAddress {
+		rec.IPAddress = Redacted
+	}
+	if m.UserAgent != "" && rec.UserAgent == m.UserAgent {
+		rec.UserAgent = Redacted
+	}
+}
+
+// Erasure summarizes an identifier erasure run. It says which kinds of
+// identifier were matched, never their values, so it can be audited.
+type Erasure struct {
+	TenantID    string     `json:"tenant_id"`
+	ByIP        bool       `json:"by_ip"`
+	ByUserAgent bool       `json:"by_user_agent"`
+	Before      *time.Time `json:"before,omitempty"`
+
+	// Records is how many records were rewritten and Batches in how many
+	// store calls.
+	Records int64 `json:"records"`
+	Batches int   `json:"batches"`
+
+	// Complete is false when the run stopped early, such as on a cancelled
+	// context. Running the same erasure again resumes it, because records
+	// already rewritten no longer match.
+	Complete bool `json:"complete"`
+
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
@@ -0,0 +1,148 @@
+package usage
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// RecordMode selects how much of a request Engine.RecordUsage keeps.
+type RecordMode string
+
+const (
+	// RecordFull writes a usage record and counts endpoint activity for
+	// every request.
+	RecordFull RecordMode = "full"
+
+	// RecordSampled writes a usage record for one request in SampleRate and
+	// counts endpoint activity for all of them.
+	RecordSampled RecordMode = "sampled"
+
+	// RecordCounterOnly counts endpoint activity without writing a usage
+	// record.
+	RecordCounterOnly RecordMode = "counter_only"
+
+	// RecordOff keeps nothing.
+	RecordOff RecordMode = "off"
+)
+
+// ErrInvalidRecordingPolicy is returned for a recording policy with an
+// unknown mode, a malformed pattern, or a sampled mode without a positive
+// sample rate.
+var ErrInvalidRecordingPolicy = errors.New("usage: invalid recording policy")
+
+// RecordingRule maps requests whose path matches Pattern to a mode.
+//
+// Pattern uses [path.Match] syntax against the record's route template and
+// against its endpoint path with the query string dropped; a match on either
+// applies the rule. A trailing "/**" also matches the prefix itself and
+// anything beneath it, so "/static/**" covers "/static/css/app.css".
+type RecordingRule struct {
+	Pattern    string     `json:"pattern" mapstructure:"pattern" yaml:"pattern"`
+	Mode       RecordMode `json:"mode" mapstructure:"mode" yaml:"mode"`
+	SampleRate int        `json:"sample_rate,omitempty" mapstructure:"sample_rate" yaml:"sample_rate"`
+}
+
+// RecordingPolicy decides per request how usage is recorded. Rules are
+// evaluated in order and the first match wins; requests matching no rule use
+// Default, which is [RecordFull] when empty. SampleRate is the default
+// rate for Default and for sampled rules that set none.
+type RecordingPolicy struct {
+	Rules      []RecordingRule `json:"rules,omitempty" mapstructure:"rules" yaml:"rules"`
+	Default    RecordMode      `json:"default,omitempty" mapstructure:"default" yaml:"default"`
+	SampleRate int             `json:"sample_rate,omitempty" mapstructure:"sample_rate" yaml:"sample_rate"`
+}
+
+// Validate reports whether every mode is known, every pattern is well formed,
+// and every sampled mode has a positive sample rate.
+func (p *RecordingPolicy) Validate() error {
+	if err := p.checkMode("default", p.Default, 0); err != nil {
+		return err
+	}
+	for i, r := range p.Rules {
+		name := fmt.Sprintf("rule %d (%q)", i, r.Pattern)
+		if r.Pattern == "" {
+			return fmt.Errorf("%w: %s: pattern is required", ErrInvalidRecordingPolicy, name)
+		}
+		if _, err := path.Match(strings.TrimSuffix(r.Pattern, "/**"), ""); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidRecordingPolicy, name, err)
+		}
+		if r.Mode == "" {
+			return fmt.Errorf("%w: %s: mode is required", ErrInvalidRecordingPolicy, name)
+		}
+		if err := p.checkMode(name, r.Mode, r.SampleRate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *RecordingPolicy) checkMode(name string, mode RecordMode, rate int) error {
+	switch mode {
+	case "", RecordFull, RecordCounterOnly, RecordOff:
+		return nil
+	case RecordSampled:
+		if rate < 0 || p.SampleRate < 0 || (rate == 0 && p.SampleRate == 0) {
+			return fmt.Errorf("%w: %s: sampled mode needs a positive sample_rate", ErrInvalidRecordingPolicy, name)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %s: unknown mode %q", ErrInvalidRecordingPolicy, name, mode)
+	}
+}
+
+// Match returns the index of the rule that applies to rec, or -1 for the
+// default, with its mode and sample rate. The default is rec.DefaultMode
+// when set, sampled at rec.DefaultSampleRate when that is set. The policy
+// must be valid.
+func (p *RecordingPolicy) Match(rec *Record) (rule int, mode RecordMode, sampleRate int) {
+	endpoint := rec.Endpoint
+	if i := strings.IndexAny(endpoint, "?#"); i >= 0 {
+		endpoint = endpoint[:i]
+	}
+	for i, r := range p.Rules {
+		if (rec.Route != "" && matchPattern(r.Pattern, rec.Route)) || matchPattern(r.Pattern, endpoint) {
+			return i, r.Mode, p.rate(r.SampleRate)
+		}
+	}
+	switch {
+	case rec.DefaultMode != "":
+		return -1, rec.DefaultMode, p.rate(rec.DefaultSampleRate)
+	case p.Default == "":
+		return -1, RecordFull, 1
+	}
+	return -1, p.Default, p.rate(0)
+}
+
+func (p *RecordingPolicy) rate(n int) int {
+	if n > 0 {
+		return n
+	}
+	if p.SampleRate > 0 {
+		return p.SampleRate
+	}
+	return 1
+}
+
+// matchPattern matches name against a path.Match pattern, with a trailing
+// "/**" matching the prefix and everything beneath it.
+func matchPattern(pattern, name string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/**"); ok {
+		segs := strings.Count(prefix, "/")
+		for i, c := range name {
+			if c != '/' {
+				continue
+			}
+			if segs == 0 {
+				ok, _ := path.Match(prefix, name[:i])
+				return ok
+			}
+			segs--
+		}
+		ok, _ := path.Match(prefix, name)
+		return ok
+	}
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
@@ -15,6 +15,23 @@ type Store interface {
 	Aggregate(ctx context.Context, filter *QueryFilter) ([]*Aggregation, error)
 	Count(ctx context.Context, filter *QueryFilter) (int64, error)
 	Purge(ctx context.Context, before time.Time) (int64, error)
-	DailyCount(ctx context.Context, keyID id.KeyID, date time.Time) (int64, error)
-	MonthlyCount(ctx context.Context, keyID id.KeyID, month time.Time) (int64, error)
+
+	// DailyCount and MonthlyCount count a key's records created within w,
+	// a day or month window the engine computed in the quota's time zone.
+	// Stores compare timestamps only; they do not truncate dates.
+	DailyCount(ctx context.Context, keyID id.KeyID, w Window) (int64, error)
+	MonthlyCount(ctx context.Context, keyID id.KeyID, w Window) (int64, error)
+
+	// UpsertEndpointActivity adds each entry's Count to the stored count for
+	// its (key, endpoint, method) and advances LastSeenAt if it is newer.
+	UpsertEndpointActivity(ctx context.Context, acts []*EndpointActivity) error
+	// ListEndpointActivity returns a key's endpoint activity, most recently
+	// seen first.
+	ListEndpointActivity(ctx context.Context, keyID id.KeyID) ([]*EndpointActivity, error)
+
+	// UpdateUsageIdentifiers rewrites the identifiers m selects to
+	// [Redacted] in at most limit of the tenant's records, oldest first,
+	// and returns how many records it rewrote. Other columns are left
+	// unchanged, so counts and aggregates are preserved.
+	UpdateUsageIdentifiers(ctx context.Context, tenantID string, m *IdentifierMatcher, limit int) (int64, error)
 }
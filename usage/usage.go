@@ -5,14 +5,19 @@ import (
 	"time"
 
 	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
 )
 
-// Record is a single usage event for a key.
+// Record is a single usage event for a key. Route, when set, is the matched
+// route template (e.g., "/users/:id"); it is used to group endpoint activity
+// and is not persisted with the record.
 type Record struct {
 	ID         id.UsageID     `json:"id" db:"id"`
 	KeyID      id.KeyID       `json:"key_id" db:"key_id"`
 	TenantID   string         `json:"tenant_id" db:"tenant_id"`
+	AppID      string         `json:"app_id,omitempty" db:"app_id"`
 	Endpoint   string         `json:"endpoint" db:"endpoint"`
+	Route      string         `json:"route,omitempty" db:"-"`
 	Method     string         `json:"method" db:"method"`
 	StatusCode int            `json:"status_code" db:"status_code"`
 	IPAddress  string         `json:"ip_address" db:"ip_address"`
@@ -20,6 +25,20 @@ type Record struct {
 	Latency    time.Duration  `json:"latency" db:"latency_ms"`
 	Metadata   map[string]any `json:"metadata,omitempty" db:"metadata"`
 	CreatedAt  time.Time      `json:"created_at" db:"created_at"`
+
+	// Environment is the key's environment, copied onto the record when it
+	// is written so that usage can be filtered by environment without a
+	// join.
+	Environment key.Environment `json:"environment,omitempty" db:"environment"`
+
+	// DefaultMode, when set, replaces the recording policy's Default for
+	// this record, so that a caller can record a kind of request more
+	// lightly unless a rule says otherwise. It is not stored.
+	DefaultMode RecordMode `json:"-" db:"-"`
+
+	// DefaultSampleRate is the 1-in-N rate of a sampled DefaultMode. Zero
+	// uses the recording policy's SampleRate. It is not stored.
+	DefaultSampleRate int `json:"-" db:"-"`
 }
 
 // Aggregation represents aggregated usage statistics.
@@ -39,9 +58,14 @@ type Aggregation struct {
 type QueryFilter struct {
 	KeyID    *id.KeyID  `json:"key_id,omitempty"`
 	TenantID string     `json:"tenant_id,omitempty"`
+	AppID    string     `json:"app_id,omitempty"`
 	After    *time.Time `json:"after,omitempty"`
 	Before   *time.Time `json:"before,omitempty"`
 	Period   string     `json:"period,omitempty"`
-	Limit    int        `json:"limit,omitempty"`
-	Offset   int        `json:"offset,omitempty"`
+
+	// Environment restricts the results to keys in this environment.
+	Environment key.Environment `json:"environment,omitempty"`
+
+	Limit  int `json:"limit,omitempty"`
+	Offset int `json:"offset,omitempty"`
 }
@@ -0,0 +1,59 @@
+package usage
+
+import (
+	"fmt"
+	"time"
+)
+
+// Window is the half-open time range [Start, End) a quota counts usage in.
+// The engine computes it in the quota's time zone, so stores only compare
+// timestamps and never truncate dates themselves.
+type Window struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// DayWindow returns the calendar day in loc that contains t. Around a
+// daylight saving change the day is 23 or 25 hours long, so adjacent days
+// neither overlap nor leave a gap. A nil loc is UTC.
+func DayWindow(t time.Time, loc *time.Location) Window {
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	return Window{Start: start, End: time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)}
+}
+
+// MonthWindow returns the calendar month in loc that contains t. A nil loc
+// is UTC.
+func MonthWindow(t time.Time, loc *time.Location) Window {
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+	return Window{Start: start, End: time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)}
+}
+
+// Contains reports whether t is within w.
+func (w Window) Contains(t time.Time) bool {
+	return !t.Before(w.Start) && t.Before(w.End)
+}
+
+// LoadQuotaLocation resolves a quota time zone: an IANA name such as
+// "America/New_York", or "" for UTC. "Local" is refused, since it would
+// differ between hosts.
+func LoadQuotaLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	if name == "Local" {
+		return nil, fmt.Errorf("time zone %q depends on the host", name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return loc, nil
+}
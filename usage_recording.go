@@ -0,0 +1,68 @@
+package keysmith
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/xraph/keysmith/usage"
+)
+
+// recordingState is an installed recording policy with a request counter per
+// rule, and one for the default, for 1-in-N sampling.
+type recordingState struct {
+	policy usage.RecordingPolicy
+	seen   []atomic.Uint64
+}
+
+func newRecordingState(p usage.RecordingPolicy) (*recordingState, error) {
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("keysmith: %w", err)
+	}
+	p.Rules = append([]usage.RecordingRule(nil), p.Rules...)
+	return &recordingState{policy: p, seen: make([]atomic.Uint64, len(p.Rules)+1)}, nil
+}
+
+// defaultRecording applies when no policy is installed, recording in full
+// unless the record sets its own DefaultMode.
+var defaultRecording = &recordingState{seen: make([]atomic.Uint64, 1)}
+
+// decide returns what to keep of rec: whether to write the usage record and
+// whether to count its endpoint activity.
+func (s *recordingState) decide(rec *usage.Record) (row, count bool) {
+	rule, mode, rate := s.policy.Match(rec)
+	switch mode {
+	case usage.RecordOff:
+		return false, false
+	case usage.RecordCounterOnly:
+		return false, true
+	case usage.RecordSampled:
+		n := s.seen[rule+1].Add(1)
+		return (n-1)%uint64(rate) == 0, true
+	default:
+		return true, true
+	}
+}
+
+// SetUsageRecording replaces the usage recording policy at runtime. Sampling
+// restarts from the first request under the new policy. An invalid policy
+// returns [usage.ErrInvalidRecordingPolicy] and leaves the current one in
+// place.
+func (e *Engine) SetUsageRecording(p usage.RecordingPolicy) error {
+	s, err := newRecordingState(p)
+	if err != nil {
+		return err
+	}
+	e.recording.Store(s)
+	return nil
+}
+
+// UsageRecording returns the usage recording policy in effect.
+func (e *Engine) UsageRecording() usage.RecordingPolicy {
+	s := e.recording.Load()
+	if s == nil {
+		return usage.RecordingPolicy{}
+	}
+	p := s.policy
+	p.Rules = append([]usage.RecordingRule(nil), p.Rules...)
+	return p
+}
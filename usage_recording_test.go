@@ -0,0 +1,183 @@
+package keysmith_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/store/memory"
+	"github.com/xraph/keysmith/usage"
+)
+
+var testRecordingPolicy = usage.RecordingPolicy{
+	Rules: []usage.RecordingRule{
+		{Pattern: "/healthz", Mode: usage.RecordOff},
+		{Pattern: "/static/**", Mode: usage.RecordCounterOnly},
+		{Pattern: "/v1/search", Mode: usage.RecordSampled, SampleRate: 5},
+	},
+	Default: usage.RecordFull,
+}
+
+func newRecordingEngine(t *testing.T, p usage.RecordingPolicy) (*keysmith.Engine, *memory.Store, id.KeyID) {
+	t.Helper()
+	ms := memory.New()
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(ms),
+		keysmith.WithEndpointActivity(10, 0),
+		keysmith.WithUsageRecording(p),
+	)
+	require.NoError(t, err)
+
+	result, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{Name: "Recording", Prefix: "sk", Environment: key.EnvTest})
+	require.NoError(t, err)
+	return eng, ms, result.Key.ID
+}
+
+// sendTraffic records ten calls to each of a health check, a static asset, a
+// search, and a user lookup.
+func sendTraffic(t *testing.T, eng *keysmith.Engine, kid id.KeyID) {
+	t.Helper()
+	for range 10 {
+		recordCall(t, eng, kid, "GET", "/healthz", "")
+		recordCall(t, eng, kid, "GET", "/static/css/app.css", "")
+		recordCall(t, eng, kid, "GET", "/v1/search?q=keys", "")
+		recordCall(t, eng, kid, "GET", "/v1/users/42", "/v1/users/:id")
+	}
+}
+
+// usageCounts returns the stored usage rows and the endpoint activity count
+// per endpoint.
+func usageCounts(t *testing.T, eng *keysmith.Engine, ms *memory.Store, kid id.KeyID) (int64, map[string]int64) {
+	t.Helper()
+	rows, err := ms.Usages().Count(testCtx(), &usage.QueryFilter{KeyID: &kid})
+	require.NoError(t, err)
+
+	acts, err := eng.ListEndpointActivity(testCtx(), kid)
+	require.NoError(t, err)
+	counts := make(map[string]int64, len(acts))
+	for _, a := range acts {
+		counts[a.Endpoint] = a.Count
+	}
+	return rows, counts
+}
+
+func TestUsageRecording_Modes(t *testing.T) {
+	eng, ms, kid := newRecordingEngine(t, testRecordingPolicy)
+	sendTraffic(t, eng, kid)
+
+	rows, counts := usageCounts(t, eng, ms, kid)
+	assert.Equal(t, int64(2+10), rows, "two sampled searches and every user lookup")
+	assert.Equal(t, map[string]int64{
+		"/static/css/app.css": 10,
+		"/v1/search":          10,
+		"/v1/users/:id":       10,
+	}, counts, "counter-only and sampled requests are all counted; off is not")
+
+	recs, err := ms.Usages().Query(testCtx(), &usage.QueryFilter{KeyID: &kid})
+	require.NoError(t, err)
+	for _, r := range recs {
+		assert.NotContains(t, []string{"/healthz", "/static/css/app.css"}, r.Endpoint)
+	}
+}
+
+func TestUsageRecording_DefaultIsFull(t *testing.T) {
+	eng, ms, kid := newEndpointEngine(t, 10)
+	sendTraffic(t, eng, kid)
+
+	rows, counts := usageCounts(t, eng, ms, kid)
+	assert.Equal(t, int64(40), rows)
+	assert.Len(t, counts, 4)
+	assert.Equal(t, usage.RecordingPolicy{}, eng.UsageRecording())
+}
+
+func TestUsageRecording_DefaultMode(t *testing.T) {
+	eng, ms, kid := newRecordingEngine(t, usage.RecordingPolicy{
+		Rules:   []usage.RecordingRule{{Pattern: "/v1/users/*", Mode: usage.RecordFull}},
+		Default: usage.RecordCounterOnly,
+	})
+	sendTraffic(t, eng, kid)
+
+	rows, counts := usageCounts(t, eng, ms, kid)
+	assert.Equal(t, int64(10), rows)
+	assert.Len(t, counts, 4)
+	assert.Equal(t, int64(10), counts["/healthz"])
+}
+
+func TestUsageRecording_RuntimeChange(t *testing.T) {
+	eng, ms, kid := newRecordingEngine(t, usage.RecordingPolicy{Default: usage.RecordOff})
+	sendTraffic(t, eng, kid)
+	rows, counts := usageCounts(t, eng, ms, kid)
+	assert.Zero(t, rows)
+	assert.Empty(t, counts)
+
+	require.NoError(t, eng.SetUsageRecording(testRecordingPolicy))
+	assert.Equal(t, testRecordingPolicy, eng.UsageRecording())
+	sendTraffic(t, eng, kid)
+	rows, _ = usageCounts(t, eng, ms, kid)
+	assert.Equal(t, int64(12), rows)
+
+	// An invalid policy leaves the current one in place.
+	err := eng.SetUsageRecording(usage.RecordingPolicy{Default: "sometimes"})
+	require.ErrorIs(t, err, usage.ErrInvalidRecordingPolicy)
+	assert.Equal(t, testRecordingPolicy, eng.UsageRecording())
+}
+
+func TestUsageRecording_InvalidPolicy(t *testing.T) {
+	for name, p := range map[string]usage.RecordingPolicy{
+		"unknown mode":     {Default: "sometimes"},
+		"missing pattern":  {Rules: []usage.RecordingRule{{Mode: usage.RecordOff}}},
+		"missing mode":     {Rules: []usage.RecordingRule{{Pattern: "/healthz"}}},
+		"bad pattern":      {Rules: []usage.RecordingRule{{Pattern: "/static/[", Mode: usage.RecordOff}}},
+		"sampled, no rate": {Rules: []usage.RecordingRule{{Pattern: "/search", Mode: usage.RecordSampled}}},
+		"negative rate":    {Default: usage.RecordSampled, SampleRate: -1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := keysmith.NewEngine(keysmith.WithStore(memory.New()), keysmith.WithUsageRecording(p))
+			assert.ErrorIs(t, err, usage.ErrInvalidRecordingPolicy)
+		})
+	}
+}
+
+func TestRecordingPolicy_Match(t *testing.T) {
+	p := usage.RecordingPolicy{
+		Rules: []usage.RecordingRule{
+			{Pattern: "/static/**", Mode: usage.RecordCounterOnly},
+			{Pattern: "/v1/*/export", Mode: usage.RecordSampled},
+			{Pattern: "/v1/users/:id", Mode: usage.RecordOff},
+		},
+		Default:    usage.RecordFull,
+		SampleRate: 10,
+	}
+	for _, tc := range []struct {
+		endpoint, route string
+		rule            int
+		mode            usage.RecordMode
+	}{
+		{endpoint: "/static", rule: 0, mode: usage.RecordCounterOnly},
+		{endpoint: "/static/css/app.css", rule: 0, mode: usage.RecordCounterOnly},
+		{endpoint: "/statics/app.css", rule: -1, mode: usage.RecordFull},
+		{endpoint: "/v1/keys/export?limit=5", rule: 1, mode: usage.RecordSampled},
+		{endpoint: "/v1/users/42", route: "/v1/users/:id", rule: 2, mode: usage.RecordOff},
+		{endpoint: "/v1/users/42", rule: -1, mode: usage.RecordFull},
+	} {
+		rule, mode, rate := p.Match(&usage.Record{Endpoint: tc.endpoint, Route: tc.route})
+		assert.Equal(t, tc.rule, rule, tc.endpoint)
+		assert.Equal(t, tc.mode, mode, tc.endpoint)
+		assert.Equal(t, 10, rate, "rules without a rate use the policy's")
+	}
+}
+
+func TestUsageRecording_SampledKeepsFirstOfEach(t *testing.T) {
+	eng, ms, kid := newRecordingEngine(t, usage.RecordingPolicy{Default: usage.RecordSampled, SampleRate: 3})
+	for range 7 {
+		recordCall(t, eng, kid, "GET", "/v1/search", "")
+	}
+
+	recs, err := ms.Usages().Query(testCtx(), &usage.QueryFilter{KeyID: &kid})
+	require.NoError(t, err)
+	assert.Len(t, recs, 3, "calls 1, 4, and 7")
+}
@@ -0,0 +1,166 @@
+package keysmith
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/xraph/keysmith/id"
+)
+
+// Default validation cache settings.
+const (
+	DefaultValidationCacheTTL  = 30 * time.Second
+	DefaultValidationCacheSize = 10_000
+)
+
+// validationCache holds validation snapshots by key hash for a short TTL so
+// that repeat validations skip the store. Engine mutations invalidate the
+// affected entries; other engine instances sharing the store see changes
+// once their entries expire. When full it evicts the least recently used
+// entry.
+type validationCache struct {
+	ttl time.Duration
+	max int
+
+	mu      sync.Mutex
+	order   *list.List // of *cacheEntry, most recently used at the front
+	entries map[string]*list.Element
+	byKey   map[id.KeyID]map[string]struct{} // key → cached hashes
+
+	// gen counts invalidations. A load that started before an invalidation
+	// may have read stale data, so put discards it.
+	gen uint64
+}
+
+type cacheEntry struct {
+	hash    string
+	snap    *validationSnapshot
+	expires time.Time
+}
+
+func newValidationCache(ttl time.Duration, maxEntries int) *validationCache {
+	if ttl <= 0 {
+		ttl = DefaultValidationCacheTTL
+	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultValidationCacheSize
+	}
+	return &validationCache{
+		ttl:     ttl,
+		max:     maxEntries,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+		byKey:   make(map[id.KeyID]map[string]struct{}),
+	}
+}
+
+// get returns the cached snapshot for hash and marks it recently used. The
+// snapshot is shared and read-only; callers copy what they hand out. hash
+// is a byte slice so that the lookup does not allocate a string.
+func (c *validationCache) get(hash []byte) (*validationSnapshot, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[string(hash)]
+	if !ok {
+		return nil, false
+	}
+	ent := el.Value.(*cacheEntry)
+	if time.Now().After(ent.expires) {
+		c.remove(el)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return ent.snap, true
+}
+
+// generation returns the invalidation count to pass to put.
+func (c *validationCache) generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+// put caches snap under hash for ttl unless an invalidation happened since
+// gen was read, evicting the least recently used entry when the cache is
+// full.
+func (c *validationCache) put(hash string, snap *validationSnapshot, gen uint64, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		return
+	}
+
+	if old, ok := c.entries[hash]; ok {
+		c.remove(old)
+	}
+	for len(c.entries) >= c.max {
+		c.remove(c.order.Back())
+	}
+
+	c.entries[hash] = c.order.PushFront(&cacheEntry{hash: hash, snap: snap, expires: time.Now().Add(ttl)})
+	hashes := c.byKey[snap.key.ID]
+	if hashes == nil {
+		hashes = make(map[string]struct{}, 1)
+		c.byKey[snap.key.ID] = hashes
+	}
+	hashes[hash] = struct{}{}
+}
+
+// invalidate drops every entry for the key.
+func (c *validationCache) invalidate(keyID id.KeyID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for hash := range c.byKey[keyID] {
+		if el, ok := c.entries[hash]; ok {
+			c.order.Remove(el)
+			delete(c.entries, hash)
+		}
+	}
+	delete(c.byKey, keyID)
+}
+
+// invalidateAll drops every entry, for changes such as a policy update that
+// can affect any number of keys.
+func (c *validationCache) invalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+	c.byKey = make(map[id.KeyID]map[string]struct{})
+}
+
+func (c *validationCache) size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// remove deletes one entry. Callers hold c.mu.
+func (c *validationCache) remove(el *list.Element) {
+	ent := c.order.Remove(el).(*cacheEntry)
+	delete(c.entries, ent.hash)
+	keyID := ent.snap.key.ID
+	if hashes := c.byKey[keyID]; hashes != nil {
+		delete(hashes, ent.hash)
+		if len(hashes) == 0 {
+			delete(c.byKey, keyID)
+		}
+	}
+}
+
+// invalidateKey drops cached validation state for a key after a mutation.
+func (e *Engine) invalidateKey(keyID id.KeyID) {
+	if e.cache != nil {
+		e.cache.invalidate(keyID)
+	}
+}
+
+// invalidateAll drops all cached validation state.
+func (e *Engine) invalidateAll() {
+	if e.cache != nil {
+		e.cache.invalidateAll()
+	}
+}
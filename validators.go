@@ -0,0 +1,152 @@
+package keysmith
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/rotation"
+)
+
+// CreateKeyValidator checks a CreateKey input before the key is generated.
+// Register validators with [WithCreateKeyValidator]; the tenant, app, and
+// actor are available from ctx through [TenantIDFromContext],
+// [AppIDFromContext], and [ActorFromContext]. Validators must not modify
+// input.
+type CreateKeyValidator func(ctx context.Context, input *CreateKeyInput) error
+
+// UpdateKeyValidator checks an UpdateKey input against the key as currently
+// stored, before any change is applied. Register validators with
+// [WithUpdateKeyValidator].
+type UpdateKeyValidator func(ctx context.Context, k *key.Key, input *UpdateKeyInput) error
+
+// RotateKeyValidator checks a RotateKey call before a new secret is
+// generated. Register validators with [WithRotateKeyValidator]. Rotations
+// made to remediate a compromise do not run validators.
+type RotateKeyValidator func(ctx context.Context, k *key.Key, reason rotation.Reason) error
+
+// InputError is returned when one or more registered validators reject an
+// input. Errors holds each validator's error in registration order. It
+// matches ErrInvalidKeyInput with errors.Is, as well as any of the wrapped
+// errors.
+type InputError struct {
+	Errors []error
+}
+
+func (e *InputError) Error() string {
+	parts := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		parts[i] = err.Error()
+	}
+	return ErrInvalidKeyInput.Error() + ": " + strings.Join(parts, "; ")
+}
+
+// Is reports whether target is ErrInvalidKeyInput.
+func (e *InputError) Is(target error) bool { return target == ErrInvalidKeyInput }
+
+// Unwrap returns the validators' errors.
+func (e *InputError) Unwrap() []error { return e.Errors }
+
+// runValidators calls each validator in order and collects the errors. All
+// validators run, so a caller sees every problem at once.
+func runValidators[V any](validators []V, call func(V) error) error {
+	var errs []error
+	for _, v := range validators {
+		if err := call(v); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &InputError{Errors: errs}
+}
+
+func (e *Engine) validateCreateInput(ctx context.Context, input *CreateKeyInput) error {
+	return runValidators(e.createValidators, func(v CreateKeyValidator) error { return v(ctx, input) })
+}
+
+func (e *Engine) validateUpdateInput(ctx context.Context, k *key.Key, input *UpdateKeyInput) error {
+	return runValidators(e.updateValidators, func(v UpdateKeyValidator) error { return v(ctx, k, input) })
+}
+
+func (e *Engine) validateRotateInput(ctx context.Context, k *key.Key, reason rotation.Reason) error {
+	return runValidators(e.rotateValidators, func(v RotateKeyValidator) error { return v(ctx, k, reason) })
+}
+
+// KeyNameRule is a built-in validator for key names. Zero fields are not
+// checked. Register its methods for the operations that set a name:
+//
+//	rule := keysmith.KeyNameRule{MinLength: 3, MaxLength: 64, Pattern: regexp.MustCompile(`^[a-z0-9-]+$`)}
+//	keysmith.WithCreateKeyValidator(rule.ValidateCreate)
+//	keysmith.WithUpdateKeyValidator(rule.ValidateUpdate)
+type KeyNameRule struct {
+	// MinLength and MaxLength bound the name's length in characters.
+	MinLength int
+	MaxLength int
+
+	// Pattern, when set, must match the whole name.
+	Pattern *regexp.Regexp
+}
+
+// ValidateCreate is a [CreateKeyValidator].
+func (r KeyNameRule) ValidateCreate(_ context.Context, input *CreateKeyInput) error {
+	return r.check(input.Name)
+}
+
+// ValidateUpdate is an [UpdateKeyValidator]. Updates that leave the name
+// unchanged are accepted.
+func (r KeyNameRule) ValidateUpdate(_ context.Context, _ *key.Key, input *UpdateKeyInput) error {
+	if input.Name == nil {
+		return nil
+	}
+	return r.check(*input.Name)
+}
+
+func (r KeyNameRule) check(name string) error {
+	n := utf8.RuneCountInString(name)
+	switch {
+	case r.MinLength > 0 && n < r.MinLength:
+		return fmt.Errorf("name %q is shorter than %d characters", name, r.MinLength)
+	case r.MaxLength > 0 && n > r.MaxLength:
+		return fmt.Errorf("name %q is longer than %d characters", name, r.MaxLength)
+	case r.Pattern != nil && !r.Pattern.MatchString(name):
+		return fmt.Errorf("name %q does not match %s", name, r.Pattern)
+	}
+	return nil
+}
+
+// PrefixAllowList is a built-in validator that restricts the key prefixes
+// each tenant may use, for example to reserve a prefix for internal teams.
+// It maps tenant IDs to their allowed prefixes; the "*" entry applies to
+// tenants that are not listed. Tenants with no entry, when there is no "*"
+// entry, may use any prefix.
+//
+//	keysmith.WithCreateKeyValidator(keysmith.PrefixAllowList{
+//		"tenant_platform": {"sk", "int"},
+//		"*":               {"sk", "pk"},
+//	}.ValidateCreate)
+type PrefixAllowList map[string][]string
+
+// ValidateCreate is a [CreateKeyValidator]. The tenant is taken from ctx,
+// falling back to input.TenantID as CreateKey does.
+func (l PrefixAllowList) ValidateCreate(ctx context.Context, input *CreateKeyInput) error {
+	tenantID := TenantIDFromContext(ctx)
+	if tenantID == "" {
+		tenantID = input.TenantID
+	}
+	allowed, ok := l[tenantID]
+	if !ok {
+		if allowed, ok = l["*"]; !ok {
+			return nil
+		}
+	}
+	if !slices.Contains(allowed, input.Prefix) {
+		return fmt.Errorf("prefix %q is not allowed for tenant %q", input.Prefix, tenantID)
+	}
+	return nil
+}
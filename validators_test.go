@@ -0,0 +1,186 @@
+package keysmith_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/rotation"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+func newValidatorEngine(t *testing.T, opts ...keysmith.Option) *keysmith.Engine {
+	t.Helper()
+	eng, err := keysmith.NewEngine(append([]keysmith.Option{keysmith.WithStore(memory.New())}, opts...)...)
+	require.NoError(t, err)
+	return eng
+}
+
+func validatorInput(name, prefix string) *keysmith.CreateKeyInput {
+	return &keysmith.CreateKeyInput{Name: name, Prefix: prefix, Environment: key.EnvTest}
+}
+
+var errNoCostCenter = errors.New("metadata must include cost_center")
+
+func requireCostCenter(_ context.Context, input *keysmith.CreateKeyInput) error {
+	if _, ok := input.Metadata["cost_center"]; !ok {
+		return errNoCostCenter
+	}
+	return nil
+}
+
+func TestCreateKeyValidator_AccumulatesInOrder(t *testing.T) {
+	var order []string
+	record := func(name string, err error) keysmith.CreateKeyValidator {
+		return func(context.Context, *keysmith.CreateKeyInput) error {
+			order = append(order, name)
+			return err
+		}
+	}
+	errFirst, errThird := errors.New("first"), errors.New("third")
+	eng := newValidatorEngine(t,
+		keysmith.WithCreateKeyValidator(record("a", errFirst)),
+		keysmith.WithCreateKeyValidator(record("b", nil)),
+		keysmith.WithCreateKeyValidator(record("c", errThird)),
+	)
+
+	_, err := eng.CreateKey(testCtx(), validatorInput("Key", "sk"))
+	require.Error(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, order, "every validator runs, in registration order")
+	assert.ErrorIs(t, err, keysmith.ErrInvalidKeyInput)
+	assert.ErrorIs(t, err, errFirst)
+	assert.ErrorIs(t, err, errThird)
+
+	var inputErr *keysmith.InputError
+	require.ErrorAs(t, err, &inputErr)
+	assert.Equal(t, []error{errFirst, errThird}, inputErr.Errors)
+	assert.Equal(t, "keysmith: invalid key input: first; third", err.Error())
+
+	keys, err := eng.ListKeys(testCtx(), &key.ListFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, keys, "no key is created")
+}
+
+func TestCreateKeyValidator_ReadsContext(t *testing.T) {
+	var tenantID, appID, actor string
+	eng := newValidatorEngine(t, keysmith.WithCreateKeyValidator(func(ctx context.Context, _ *keysmith.CreateKeyInput) error {
+		tenantID = keysmith.TenantIDFromContext(ctx)
+		appID = keysmith.AppIDFromContext(ctx)
+		actor = keysmith.ActorFromContext(ctx)
+		return nil
+	}))
+
+	_, err := eng.CreateKey(keysmith.WithActor(testCtx(), "user_42"), validatorInput("Key", "sk"))
+	require.NoError(t, err)
+	assert.Equal(t, "tenant_test", tenantID)
+	assert.Equal(t, "app_test", appID)
+	assert.Equal(t, "user_42", actor)
+}
+
+func TestBuiltinValidators_ComposeWithCustom(t *testing.T) {
+	names := keysmith.KeyNameRule{MinLength: 3, MaxLength: 20, Pattern: regexp.MustCompile(`^[a-z0-9-]+$`)}
+	prefixes := keysmith.PrefixAllowList{
+		"tenant_internal": {"int", "sk"},
+		"*":               {"sk", "pk"},
+	}
+	eng := newValidatorEngine(t,
+		keysmith.WithCreateKeyValidator(names.ValidateCreate),
+		keysmith.WithCreateKeyValidator(prefixes.ValidateCreate),
+		keysmith.WithCreateKeyValidator(requireCostCenter),
+	)
+
+	_, err := eng.CreateKey(testCtx(), validatorInput("Bad Name!", "int"))
+	var inputErr *keysmith.InputError
+	require.ErrorAs(t, err, &inputErr)
+	require.Len(t, inputErr.Errors, 3)
+	assert.Contains(t, inputErr.Errors[0].Error(), "does not match")
+	assert.Contains(t, inputErr.Errors[1].Error(), `prefix "int" is not allowed for tenant "tenant_test"`)
+	assert.ErrorIs(t, inputErr.Errors[2], errNoCostCenter)
+
+	ok := validatorInput("billing-worker", "sk")
+	ok.Metadata = map[string]any{"cost_center": "cc-42"}
+	_, err = eng.CreateKey(testCtx(), ok)
+	require.NoError(t, err)
+
+	internal := validatorInput("ops-tool", "int")
+	internal.Metadata = map[string]any{"cost_center": "cc-1"}
+	_, err = eng.CreateKey(keysmith.WithTenant(context.Background(), "app_test", "tenant_internal"), internal)
+	require.NoError(t, err, "the reserved prefix is allowed for its tenant")
+}
+
+func TestKeyNameRule(t *testing.T) {
+	rule := keysmith.KeyNameRule{MinLength: 2, MaxLength: 5}
+	for _, tt := range []struct {
+		name string
+		ok   bool
+	}{
+		{"ab", true},
+		{"ключ", true},
+		{"a", false},
+		{"abcdef", false},
+	} {
+		err := rule.ValidateCreate(testCtx(), validatorInput(tt.name, "sk"))
+		assert.Equal(t, tt.ok, err == nil, tt.name)
+	}
+}
+
+func TestPrefixAllowList_UnlistedTenantsUnrestricted(t *testing.T) {
+	list := keysmith.PrefixAllowList{"tenant_internal": {"int"}}
+	assert.NoError(t, list.ValidateCreate(testCtx(), validatorInput("Key", "anything")))
+
+	input := validatorInput("Key", "sk")
+	input.TenantID = "tenant_internal"
+	assert.Error(t, list.ValidateCreate(context.Background(), input), "input.TenantID is used without a scoped context")
+}
+
+func TestUpdateKeyValidator(t *testing.T) {
+	names := keysmith.KeyNameRule{MaxLength: 8}
+	var seen string
+	eng := newValidatorEngine(t,
+		keysmith.WithUpdateKeyValidator(names.ValidateUpdate),
+		keysmith.WithUpdateKeyValidator(func(_ context.Context, k *key.Key, _ *keysmith.UpdateKeyInput) error {
+			seen = k.Name
+			return nil
+		}),
+	)
+	created, err := eng.CreateKey(testCtx(), validatorInput("original", "sk"))
+	require.NoError(t, err)
+
+	long := "much too long"
+	_, err = eng.UpdateKey(testCtx(), created.Key.ID, &keysmith.UpdateKeyInput{Name: &long})
+	assert.ErrorIs(t, err, keysmith.ErrInvalidKeyInput)
+	assert.Equal(t, "original", seen, "validators see the stored key")
+
+	fetched, err := eng.GetKey(testCtx(), created.Key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "original", fetched.Name)
+
+	desc := "unchanged name"
+	_, err = eng.UpdateKey(testCtx(), created.Key.ID, &keysmith.UpdateKeyInput{Description: &desc})
+	require.NoError(t, err)
+}
+
+func TestRotateKeyValidator(t *testing.T) {
+	errFrozen := errors.New("rotations are frozen")
+	eng := newValidatorEngine(t, keysmith.WithRotateKeyValidator(func(_ context.Context, _ *key.Key, reason rotation.Reason) error {
+		if reason == rotation.ReasonManual {
+			return errFrozen
+		}
+		return nil
+	}))
+	created, err := eng.CreateKey(testCtx(), validatorInput("Key", "sk"))
+	require.NoError(t, err)
+
+	_, err = eng.RotateKey(testCtx(), created.Key.ID, rotation.ReasonManual)
+	assert.ErrorIs(t, err, keysmith.ErrInvalidKeyInput)
+	assert.ErrorIs(t, err, errFrozen)
+
+	_, err = eng.RotateKey(testCtx(), created.Key.ID, rotation.ReasonScheduled)
+	require.NoError(t, err)
+}
@@ -0,0 +1,145 @@
+package keysmith
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/xraph/go-utils/log"
+
+	"github.com/xraph/keysmith/id"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/policy"
+)
+
+// DefaultWarmupTimeout bounds the cache warm-up run by Start.
+const DefaultWarmupTimeout = 10 * time.Second
+
+// errWarmupFull stops a warm-up strategy once the cache is full.
+var errWarmupFull = errors.New("keysmith: validation cache full")
+
+// WarmupStrategy lists the keys to preload into the validation cache,
+// calling fn for each. It stops and returns fn's error when fn fails.
+type WarmupStrategy func(ctx context.Context, keys key.Store, fn func(*key.Key) error) error
+
+// WarmRecentlyUsed preloads the n most recently used keys.
+func WarmRecentlyUsed(n int) WarmupStrategy {
+	return func(ctx context.Context, keys key.Store, fn func(*key.Key) error) error {
+		list, err := keys.ListRecentlyUsed(ctx, n)
+		if err != nil {
+			return fmt.Errorf("list recently used keys: %w", err)
+		}
+		for _, k := range list {
+			if err := fn(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// WarmActiveForTenants preloads every active key of the given tenants.
+func WarmActiveForTenants(tenantIDs ...string) WarmupStrategy {
+	return func(ctx context.Context, keys key.Store, fn func(*key.Key) error) error {
+		for _, t := range tenantIDs {
+			if err := keys.Iterate(ctx, &key.ListFilter{TenantID: t, State: key.StateActive}, fn); err != nil {
+				return fmt.Errorf("iterate keys of tenant %s: %w", t, err)
+			}
+		}
+		return nil
+	}
+}
+
+// WarmupReport describes the cache warm-up run by Start.
+type WarmupReport struct {
+	// Loaded is the number of keys placed in the validation cache.
+	Loaded int `json:"loaded"`
+
+	// Duration is how long the warm-up took.
+	Duration time.Duration `json:"duration"`
+
+	// Err is set when the warm-up stopped early, e.g., on timeout. Keys
+	// loaded before the failure stay cached.
+	Err error `json:"-"`
+}
+
+// warmup holds the configured warm-up and the report of its last run.
+type warmup struct {
+	strategy WarmupStrategy
+	timeout  time.Duration
+
+	mu     sync.Mutex
+	report *WarmupReport
+}
+
+// WarmupReport returns the result of the cache warm-up run by Start, or nil
+// when no warm-up is configured or Start has not run.
+func (e *Engine) WarmupReport() *WarmupReport {
+	if e.warmup == nil {
+		return nil
+	}
+	e.warmup.mu.Lock()
+	defer e.warmup.mu.Unlock()
+	if e.warmup.report == nil {
+		return nil
+	}
+	r := *e.warmup.report
+	return &r
+}
+
+// runWarmup preloads the validation cache. It is bounded by the warm-up
+// timeout and never fails Start: errors are logged and reported.
+func (e *Engine) runWarmup(ctx context.Context) {
+	if e.warmup == nil {
+		return
+	}
+	if e.cache == nil {
+		e.logger.Warn("cache warm-up configured without a validation cache; skipping")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.warmup.timeout)
+	defer cancel()
+
+	start := time.Now()
+	report := &WarmupReport{}
+	policies := make(map[id.PolicyID]*policy.Policy)
+	err := e.warmup.strategy(ctx, e.store.Keys(), func(k *key.Key) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if k.State != key.StateActive && k.State != key.StateRotated {
+			return nil
+		}
+		if e.cache.size() >= e.cache.max {
+			return errWarmupFull
+		}
+		gen := e.cache.generation()
+		e.cache.put(e.canonicalHash(k.KeyHash), e.snapshotFor(ctx, k, policies), gen, e.runtimeConfig().ValidationCacheTTL)
+		report.Loaded++
+		return nil
+	})
+	if errors.Is(err, errWarmupFull) {
+		err = nil
+	}
+	report.Duration = time.Since(start)
+	report.Err = err
+
+	e.warmup.mu.Lock()
+	e.warmup.report = report
+	e.warmup.mu.Unlock()
+
+	if err != nil {
+		e.logger.Warn("validation cache warm-up stopped early",
+			log.Int("loaded", report.Loaded),
+			log.Any("error", err),
+		)
+		return
+	}
+	e.logger.Info("validation cache warmed",
+		log.Int("loaded", report.Loaded),
+		log.Duration("duration", report.Duration),
+	)
+}
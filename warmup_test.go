@@ -0,0 +1,205 @@
+package keysmith_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith"
+	"github.com/xraph/keysmith/key"
+	"github.com/xraph/keysmith/scope"
+	"github.com/xraph/keysmith/store/memory"
+)
+
+// seedUsedKeys creates n keys in cs, marks each as used, and returns their
+// raw values.
+func seedUsedKeys(t *testing.T, cs *countingStore, n int) []string {
+	t.Helper()
+	seeder, err := keysmith.NewEngine(keysmith.WithStore(cs))
+	require.NoError(t, err)
+
+	raws := make([]string, n)
+	for i := range raws {
+		result, err := seeder.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+			Name:        "Hot Key",
+			Prefix:      "sk",
+			Environment: key.EnvLive,
+		})
+		require.NoError(t, err)
+		used := time.Now().Add(time.Duration(i) * time.Minute)
+		require.NoError(t, cs.Store.Keys().UpdateLastUsed(testCtx(), result.Key.ID, used))
+		raws[i] = result.RawKey
+	}
+	return raws
+}
+
+func TestCacheWarmup_RecentlyUsed(t *testing.T) {
+	cs := &countingStore{Store: memory.New()}
+	raws := seedUsedKeys(t, cs, 3)
+
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(cs),
+		keysmith.WithValidationCache(time.Minute, 100),
+		keysmith.WithCacheWarmup(keysmith.WarmRecentlyUsed(2), 0),
+	)
+	require.NoError(t, err)
+	require.NoError(t, eng.Start(context.Background()))
+
+	report := eng.WarmupReport()
+	require.NotNil(t, report)
+	assert.Equal(t, 2, report.Loaded)
+	assert.NoError(t, report.Err)
+
+	// The two most recently used keys validate from the cache.
+	for _, raw := range raws[1:] {
+		_, err := eng.ValidateKey(testCtx(), raw)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int64(0), cs.calls.Load())
+
+	// The oldest one was not preloaded.
+	_, err = eng.ValidateKey(testCtx(), raws[0])
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), cs.calls.Load())
+}
+
+func TestCacheWarmup_ActiveForTenants(t *testing.T) {
+	cs := &countingStore{Store: memory.New()}
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(cs),
+		keysmith.WithValidationCache(time.Minute, 100),
+		keysmith.WithCacheWarmup(keysmith.WarmActiveForTenants("tenant_test"), time.Second),
+	)
+	require.NoError(t, err)
+
+	result, err := eng.CreateKey(testCtx(), &keysmith.CreateKeyInput{
+		Name:        "Tenant Key",
+		Prefix:      "sk",
+		Environment: key.EnvLive,
+	})
+	require.NoError(t, err)
+	require.NoError(t, eng.Start(context.Background()))
+	assert.Equal(t, 1, eng.WarmupReport().Loaded)
+
+	_, err = eng.ValidateKey(testCtx(), result.RawKey)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), cs.calls.Load())
+}
+
+func TestCacheWarmup_TimeoutIsNotFatal(t *testing.T) {
+	blocking := func(ctx context.Context, _ key.Store, _ func(*key.Key) error) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	eng, err := keysmith.NewEngine(
+		keysmith.WithStore(memory.New()),
+		keysmith.WithValidationCache(time.Minute, 100),
+		keysmith.WithCacheWarmup(blocking, 10*time.Millisecond),
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, eng.Start(context.Background()))
+	report := eng.WarmupReport()
+	require.NotNil(t, report)
+	assert.True(t, errors.Is(report.Err, context.DeadlineExceeded))
+	assert.Zero(t, report.Loaded)
+}
+
+func TestValidationCache_InvalidatedOnRevoke(t *testing.T) {
+	cs := &countingStore{Store: memory.New()}
+	eng, raw := newCountingEngine(t, cs, keysmith.WithValidationCache(time.Minute, 100))
+
+	res, err := eng.ValidateKey(testCtx(), raw)
+	require.NoError(t, err)
+	_, err = eng.ValidateKey(testCtx(), raw)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), cs.calls.Load(), "the second validation is served from the cache")
+
+	require.NoError(t, eng.RevokeKey(testCtx(), res.Key.ID, "test"))
+	_, err = eng.ValidateKey(testCtx(), raw)
+	assert.ErrorIs(t, err, keysmith.ErrKeyInactive)
+}
+
+func TestValidationCache_InvalidatedOnScopeChange(t *testing.T) {
+	cs := &countingStore{Store: memory.New()}
+	eng, raw := newCountingEngine(t, cs, keysmith.WithValidationCache(time.Minute, 100))
+	require.NoError(t, eng.CreateScope(testCtx(), &scope.Scope{Name: "read:data"}))
+
+	res, err := eng.ValidateKey(testCtx(), raw)
+	require.NoError(t, err)
+	assert.Empty(t, res.Scopes)
+
+	require.NoError(t, eng.AssignScopes(testCtx(), res.Key.ID, []string{"read:data"}))
+	res, err = eng.ValidateKey(testCtx(), raw)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read:data"}, res.Scopes)
+}
+
+func TestValidationCache_InvalidatedOnSuspendAndRotate(t *testing.T) {
+	cs := &countingStore{Store: memory.New()}
+	eng, raw := newCountingEngine(t, cs, keysmith.WithValidationCache(time.Minute, 100))
+
+	res, err := eng.ValidateKey(testCtx(), raw)
+	require.NoError(t, err)
+	require.NoError(t, eng.SuspendKey(testCtx(), res.Key.ID))
+	_, err = eng.ValidateKey(testCtx(), raw)
+	assert.ErrorIs(t, err, keysmith.ErrKeyInactive)
+
+	require.NoError(t, eng.ReactivateKey(testCtx(), res.Key.ID))
+	_, err = eng.ValidateKey(testCtx(), raw)
+	require.NoError(t, err)
+	rotated, err := eng.RotateKey(testCtx(), res.Key.ID, "")
+	require.NoError(t, err)
+	res, err = eng.ValidateKey(testCtx(), raw)
+	require.NoError(t, err)
+	assert.True(t, res.UsingRotatedKey, "the cached entry of the old secret was dropped")
+	_, err = eng.ValidateKey(testCtx(), rotated.RawKey)
+	assert.NoError(t, err)
+}
+
+func TestValidationCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cs := &countingStore{Store: memory.New()}
+	raws := seedUsedKeys(t, cs, 3)
+	eng, err := keysmith.NewEngine(keysmith.WithStore(cs), keysmith.WithValidationCache(time.Minute, 2))
+	require.NoError(t, err)
+	validate := func(raw string) int64 {
+		before := cs.calls.Load()
+		_, err := eng.ValidateKey(testCtx(), raw)
+		require.NoError(t, err)
+		return cs.calls.Load() - before
+	}
+
+	validate(raws[0])
+	validate(raws[1])
+	assert.Zero(t, validate(raws[0]), "a is cached")
+	validate(raws[2]) // evicts b, used less recently than a
+	assert.Zero(t, validate(raws[0]))
+	assert.Zero(t, validate(raws[2]))
+	assert.Equal(t, int64(1), validate(raws[1]), "b was evicted")
+}
+
+func TestValidationCache_BypassPerCall(t *testing.T) {
+	cs := &countingStore{Store: memory.New()}
+	eng, raw := newCountingEngine(t, cs, keysmith.WithValidationCache(time.Minute, 100))
+	bypass := keysmith.WithValidationRequest(testCtx(), keysmith.ValidationRequest{BypassCache: true})
+
+	res, err := eng.ValidateKey(testCtx(), raw)
+	require.NoError(t, err)
+	_, err = eng.ValidateKey(bypass, raw)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), cs.calls.Load(), "a bypassing call reads the store")
+
+	// A change made by another engine is seen by a bypassing call, which
+	// also refreshes the cached entry for everyone else.
+	require.NoError(t, cs.Store.Keys().UpdateState(testCtx(), res.Key.ID, key.StateSuspended))
+	_, err = eng.ValidateKey(testCtx(), raw)
+	require.NoError(t, err, "the cache still holds the active key")
+	_, err = eng.ValidateKey(bypass, raw)
+	assert.ErrorIs(t, err, keysmith.ErrKeyInactive)
+	_, err = eng.ValidateKey(testCtx(), raw)
+	assert.ErrorIs(t, err, keysmith.ErrKeyInactive)
+}
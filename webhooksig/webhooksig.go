@@ -0,0 +1,248 @@
+// Package webhooksig signs and verifies the webhooks keysmith sends, such as
+// the notify hook's notifications to webhook contacts. Receivers import it
+// to check that a delivery came from keysmith and is not a replay:
+//
+//	err := webhooksig.VerifySignature(r.Header, body, []webhooksig.Secret{
+//		{ID: "2024-06", Key: newSecret},
+//		{ID: "2024-01", Key: oldSecret},
+//	}, 5*time.Minute)
+//
+// A signature is the lowercase hex HMAC-SHA256, under a secret's Key, of the
+// canonical string
+//
+//	<timestamp>.<body>
+//
+// where timestamp is the delivery time in Unix seconds and body the exact
+// request body. The timestamp is signed too, so a captured delivery cannot
+// be replayed outside the receiver's tolerance.
+//
+// Two header formats are supported, chosen per endpoint by the sender:
+//
+//	SchemeHMAC:   X-Keysmith-Timestamp: 1700000000
+//	              X-Keysmith-Signature: 2024-06=5f1c..., 2024-01=9ab2...
+//	SchemeStripe: X-Keysmith-Signature: t=1700000000,v1=5f1c...,v1=9ab2...
+//
+// The sender signs with its newest secret first and, while a rotation's
+// overlap lasts, with the secrets it is retiring, so receivers can switch
+// secrets at their own pace. SchemeHMAC names each signature's secret, so a
+// receiver checks only the secrets with those IDs; the Stripe-style header
+// has no room for IDs, so each v1 signature is checked against every secret.
+package webhooksig
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Header names set by Sign.
+const (
+	SignatureHeader = "X-Keysmith-Signature"
+	TimestampHeader = "X-Keysmith-Timestamp"
+)
+
+// DefaultTolerance is how far a delivery's timestamp may be from the
+// receiver's clock, either way, with VerifySignature.
+const DefaultTolerance = 5 * time.Minute
+
+// Scheme selects the signature header format.
+type Scheme string
+
+const (
+	// SchemeHMAC sends the timestamp in TimestampHeader and the signatures
+	// in SignatureHeader as comma-separated id=signature pairs.
+	SchemeHMAC Scheme = "hmac"
+
+	// SchemeStripe sends a Stripe-style composite SignatureHeader,
+	// "t=<timestamp>,v1=<signature>", with one v1 per signature.
+	SchemeStripe Scheme = "stripe"
+)
+
+// Errors returned by Verify.
+var (
+	ErrMissingSignature = errors.New("webhooksig: missing or malformed signature header")
+	ErrTimestamp        = errors.New("webhooksig: timestamp outside tolerance")
+	ErrMismatch         = errors.New("webhooksig: no signature matches")
+	ErrUnknownScheme    = errors.New("webhooksig: unknown signature scheme")
+	ErrInvalidSecret    = errors.New("webhooksig: invalid secret")
+)
+
+// Secret is a signing secret. ID names it in SchemeHMAC headers and must
+// be non-empty and not "t", without commas, equals signs, or spaces. A secret with a
+// non-zero Expires neither signs nor verifies after that time.
+type Secret struct {
+	ID      string
+	Key     []byte
+	Expires time.Time
+}
+
+// Validate returns ErrInvalidSecret if s has an empty key or an ID that
+// cannot be sent in a header.
+func (s Secret) Validate() error {
+	if s.ID == "" || s.ID == "t" || strings.ContainsAny(s.ID, ",= \t\r\n") {
+		return fmt.Errorf("%w: id %q", ErrInvalidSecret, s.ID)
+	}
+	if len(s.Key) == 0 {
+		return fmt.Errorf("%w: %s has an empty key", ErrInvalidSecret, s.ID)
+	}
+	return nil
+}
+
+func (s Secret) activeAt(t time.Time) bool {
+	return s.Expires.IsZero() || t.Before(s.Expires)
+}
+
+// ValidScheme reports whether s is a known scheme.
+func ValidScheme(s Scheme) bool {
+	return s == SchemeHMAC || s == SchemeStripe
+}
+
+// Compute returns the signature of body sent at t under secret.
+func Compute(secret, body []byte, t time.Time) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(t.Unix(), 10)))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Sign sets the signature headers of scheme on h for body sent at t, with
+// a signature for each of secrets that is active at t, in order. Secrets
+// should be given newest first. With no active secret it sets nothing.
+func Sign(h http.Header, scheme Scheme, body []byte, secrets []Secret, t time.Time) error {
+	if !ValidScheme(scheme) {
+		return fmt.Errorf("%w: %q", ErrUnknownScheme, scheme)
+	}
+	for _, s := range secrets {
+		if err := s.Validate(); err != nil {
+			return err
+		}
+	}
+	ts := strconv.FormatInt(t.Unix(), 10)
+	var parts []string
+	if scheme == SchemeStripe {
+		parts = append(parts, "t="+ts)
+	}
+	signed := false
+	for _, s := range secrets {
+		if !s.activeAt(t) {
+			continue
+		}
+		sig := Compute(s.Key, body, t)
+		if scheme == SchemeStripe {
+			parts = append(parts, "v1="+sig)
+		} else {
+			parts = append(parts, s.ID+"="+sig)
+		}
+		signed = true
+	}
+	if !signed {
+		return nil
+	}
+	if scheme == SchemeStripe {
+		h.Set(SignatureHeader, strings.Join(parts, ","))
+		return nil
+	}
+	h.Set(TimestampHeader, ts)
+	h.Set(SignatureHeader, strings.Join(parts, ", "))
+	return nil
+}
+
+// VerifySignature checks the signature headers in h against body with a
+// Verifier using the system clock.
+func VerifySignature(h http.Header, body []byte, secrets []Secret, tolerance time.Duration) error {
+	return (&Verifier{Secrets: secrets, Tolerance: tolerance}).Verify(h, body)
+}
+
+// Verifier checks signed deliveries. The zero Tolerance is DefaultTolerance
+// and a nil Now is time.Now.
+type Verifier struct {
+	Secrets   []Secret
+	Tolerance time.Duration
+	Now       func() time.Time
+}
+
+// Verify returns nil if one of the signatures in h is body's signature
+// under one of v's active secrets, and the timestamp is within tolerance
+// of now. The scheme is read from the header: a SignatureHeader starting
+// with "t=" is SchemeStripe.
+func (v *Verifier) Verify(h http.Header, body []byte) error {
+	now := time.Now
+	if v.Now != nil {
+		now = v.Now
+	}
+	tolerance := v.Tolerance
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+
+	ts, sigs, err := parseHeaders(h)
+	if err != nil {
+		return err
+	}
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: timestamp %q", ErrMissingSignature, ts)
+	}
+	sent, at := time.Unix(sec, 0), now()
+	if d := at.Sub(sent); d > tolerance || d < -tolerance {
+		return fmt.Errorf("%w: sent %s", ErrTimestamp, sent.UTC().Format(time.RFC3339))
+	}
+
+	for _, sig := range sigs {
+		for _, s := range v.Secrets {
+			if !s.activeAt(at) || (sig.id != "" && sig.id != s.ID) {
+				continue
+			}
+			if hmac.Equal([]byte(Compute(s.Key, body, sent)), []byte(sig.value)) {
+				return nil
+			}
+		}
+	}
+	return ErrMismatch
+}
+
+// signature is one signature from a header, with the ID of its secret
+// when the scheme names it.
+type signature struct {
+	id    string
+	value string
+}
+
+func parseHeaders(h http.Header) (string, []signature, error) {
+	raw := strings.TrimSpace(h.Get(SignatureHeader))
+	if raw == "" {
+		return "", nil, ErrMissingSignature
+	}
+	var ts string
+	var sigs []signature
+	if strings.HasPrefix(raw, "t=") {
+		for _, part := range strings.Split(raw, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			switch name {
+			case "t":
+				ts = value
+			case "v1":
+				sigs = append(sigs, signature{value: value})
+			}
+		}
+	} else {
+		ts = strings.TrimSpace(h.Get(TimestampHeader))
+		for _, part := range strings.Split(raw, ",") {
+			id, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+			if ok && id != "" {
+				sigs = append(sigs, signature{id: id, value: value})
+			}
+		}
+	}
+	if ts == "" || len(sigs) == 0 {
+		return "", nil, ErrMissingSignature
+	}
+	return ts, sigs, nil
+}
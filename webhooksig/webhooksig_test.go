@@ -0,0 +1,154 @@
+package webhooksig_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xraph/keysmith/webhooksig"
+)
+
+var (
+	body    = []byte(`{"id":"evt_1","event":"keysmith.key.expired"}`)
+	sentAt  = time.Unix(1700000000, 0)
+	secrets = []webhooksig.Secret{
+		{ID: "2024-06", Key: []byte("whsec_new")},
+		{ID: "2024-01", Key: []byte("whsec_old")},
+	}
+)
+
+const (
+	newSig = "aa11671f53089d315c8191c32469e9b7dc1b723afd738c85d7583d824e9f5a54"
+	oldSig = "0a70d28f4e226b35f4121afe187cb8a00fe5db9f0c8bcf66e94c9ae3177815c0"
+)
+
+func verifier(secrets []webhooksig.Secret, at time.Time) *webhooksig.Verifier {
+	return &webhooksig.Verifier{Secrets: secrets, Now: func() time.Time { return at }}
+}
+
+func TestSign_HMAC(t *testing.T) {
+	h := make(http.Header)
+	require.NoError(t, webhooksig.Sign(h, webhooksig.SchemeHMAC, body, secrets, sentAt))
+	assert.Equal(t, "1700000000", h.Get(webhooksig.TimestampHeader))
+	assert.Equal(t, "2024-06="+newSig+", 2024-01="+oldSig, h.Get(webhooksig.SignatureHeader))
+}
+
+func TestSign_Stripe(t *testing.T) {
+	h := make(http.Header)
+	require.NoError(t, webhooksig.Sign(h, webhooksig.SchemeStripe, body, secrets[:1], sentAt))
+	assert.Equal(t, "t=1700000000,v1="+newSig, h.Get(webhooksig.SignatureHeader))
+	assert.Empty(t, h.Get(webhooksig.TimestampHeader), "the timestamp is in the composite header")
+}
+
+func TestSign_SkipsExpiredSecrets(t *testing.T) {
+	retired := []webhooksig.Secret{secrets[0], {ID: "2024-01", Key: []byte("whsec_old"), Expires: sentAt}}
+	h := make(http.Header)
+	require.NoError(t, webhooksig.Sign(h, webhooksig.SchemeHMAC, body, retired, sentAt))
+	assert.Equal(t, "2024-06="+newSig, h.Get(webhooksig.SignatureHeader))
+
+	h = make(http.Header)
+	require.NoError(t, webhooksig.Sign(h, webhooksig.SchemeHMAC, body, retired[1:], sentAt))
+	assert.Empty(t, h, "nothing is signed without an active secret")
+}
+
+func TestSign_Invalid(t *testing.T) {
+	h := make(http.Header)
+	assert.ErrorIs(t, webhooksig.Sign(h, "sha1", body, secrets, sentAt), webhooksig.ErrUnknownScheme)
+	for _, s := range []webhooksig.Secret{{Key: []byte("k")}, {ID: "t", Key: []byte("k")}, {ID: "a=b", Key: []byte("k")}, {ID: "a"}} {
+		assert.ErrorIs(t, webhooksig.Sign(h, webhooksig.SchemeHMAC, body, []webhooksig.Secret{s}, sentAt), webhooksig.ErrInvalidSecret)
+	}
+}
+
+func TestVerify_DualSecretOverlap(t *testing.T) {
+	for _, scheme := range []webhooksig.Scheme{webhooksig.SchemeHMAC, webhooksig.SchemeStripe} {
+		t.Run(string(scheme), func(t *testing.T) {
+			h := make(http.Header)
+			require.NoError(t, webhooksig.Sign(h, scheme, body, secrets, sentAt))
+
+			assert.NoError(t, verifier(secrets[1:], sentAt).Verify(h, body), "a receiver still on the old secret")
+			assert.NoError(t, verifier(secrets[:1], sentAt).Verify(h, body), "a receiver on the new secret")
+			other := []webhooksig.Secret{{ID: "2024-06", Key: []byte("whsec_other")}}
+			assert.ErrorIs(t, verifier(other, sentAt).Verify(h, body), webhooksig.ErrMismatch)
+			assert.ErrorIs(t, verifier(secrets, sentAt).Verify(h, []byte(`{"id":"evt_9"}`)), webhooksig.ErrMismatch)
+		})
+	}
+}
+
+func TestVerify_SecretIDs(t *testing.T) {
+	h := make(http.Header)
+	require.NoError(t, webhooksig.Sign(h, webhooksig.SchemeHMAC, body, secrets[:1], sentAt))
+	renamed := []webhooksig.Secret{{ID: "2023-12", Key: []byte("whsec_new")}}
+	assert.ErrorIs(t, verifier(renamed, sentAt).Verify(h, body), webhooksig.ErrMismatch,
+		"only the secret the signature names is checked")
+
+	expired := []webhooksig.Secret{{ID: "2024-06", Key: []byte("whsec_new"), Expires: sentAt}}
+	assert.ErrorIs(t, verifier(expired, sentAt).Verify(h, body), webhooksig.ErrMismatch)
+}
+
+func TestVerify_TimestampTolerance(t *testing.T) {
+	h := make(http.Header)
+	require.NoError(t, webhooksig.Sign(h, webhooksig.SchemeStripe, body, secrets, sentAt))
+
+	assert.NoError(t, verifier(secrets, sentAt.Add(webhooksig.DefaultTolerance)).Verify(h, body))
+	assert.ErrorIs(t, verifier(secrets, sentAt.Add(webhooksig.DefaultTolerance+time.Second)).Verify(h, body), webhooksig.ErrTimestamp)
+	assert.ErrorIs(t, verifier(secrets, sentAt.Add(-webhooksig.DefaultTolerance-time.Second)).Verify(h, body), webhooksig.ErrTimestamp)
+
+	v := verifier(secrets, sentAt.Add(time.Hour))
+	v.Tolerance = 2 * time.Hour
+	assert.NoError(t, v.Verify(h, body))
+
+	assert.ErrorIs(t, webhooksig.VerifySignature(h, body, secrets, time.Minute), webhooksig.ErrTimestamp,
+		"the system clock is far past the signing time")
+}
+
+func TestVerify_Malformed(t *testing.T) {
+	for name, h := range map[string]http.Header{
+		"no header":            {},
+		"no timestamp":         {webhooksig.SignatureHeader: {"2024-06=" + newSig}},
+		"no signature":         {webhooksig.SignatureHeader: {"t=1700000000"}},
+		"bad timestamp":        {webhooksig.SignatureHeader: {"t=yesterday,v1=" + newSig}},
+		"signature without id": {webhooksig.SignatureHeader: {newSig}, webhooksig.TimestampHeader: {"1700000000"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorIs(t, verifier(secrets, sentAt).Verify(h, body), webhooksig.ErrMissingSignature)
+		})
+	}
+}
+
+// fixture is a delivery captured from a webhook receiver.
+type fixture struct {
+	ReceivedAt int64             `json:"received_at"`
+	Headers    map[string]string `json:"headers"`
+	Body       string            `json:"body"`
+}
+
+func TestVerify_Fixtures(t *testing.T) {
+	files, err := filepath.Glob("testdata/*.json")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			raw, err := os.ReadFile(file)
+			require.NoError(t, err)
+			var f fixture
+			require.NoError(t, json.Unmarshal(raw, &f))
+			h := make(http.Header)
+			for name, value := range f.Headers {
+				h.Set(name, value)
+			}
+			at := time.Unix(f.ReceivedAt, 0)
+
+			for _, s := range secrets {
+				assert.NoError(t, verifier([]webhooksig.Secret{s}, at).Verify(h, []byte(f.Body)), s.ID)
+			}
+			assert.ErrorIs(t, verifier(secrets, at).Verify(h, []byte(f.Body+" ")), webhooksig.ErrMismatch)
+			assert.ErrorIs(t, verifier(secrets, at.Add(time.Hour)).Verify(h, []byte(f.Body)), webhooksig.ErrTimestamp)
+		})
+	}
+}